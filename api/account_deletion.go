@@ -0,0 +1,98 @@
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/auth"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountDeletion 删除账户的结果摘要
+type accountDeletion struct {
+	StoppedTraders int              `json:"stopped_traders"`
+	DecisionLogs   int              `json:"decision_logs"`
+	Deleted        map[string]int64 `json:"deleted"`
+}
+
+// deleteUserAccount 删除用户账户（自助注销和管理员删除用户共用），actorID/targetID 用于审计记录
+// 顺序：停止并卸载交易员 → 删除决策日志 → 在一个事务中清除凭证、删除全部数据并匿名化审计日志 → 使已签发的token失效
+// 每一步都可重复执行，中途失败后重试即可
+func (s *Server) deleteUserAccount(c *gin.Context, userID, actorID, targetID string) (*accountDeletion, error) {
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易员列表失败: %w", err)
+	}
+
+	// 先停止交易员，避免删除过程中继续下单或写入决策日志
+	result := &accountDeletion{StoppedTraders: s.unloadUserTraders(userID)}
+	for _, t := range traders {
+		if err := logger.RemoveDecisionLogs(trader.DecisionLogDir(t.ID)); err != nil {
+			return nil, err
+		}
+		result.DecisionLogs++
+	}
+
+	if result.Deleted, err = s.database.DeleteUserAccount(userID); err != nil {
+		return nil, fmt.Errorf("删除账户失败: %w", err)
+	}
+
+	auth.RevokeUserTokens(userID)
+	s.subscriptions.invalidate(userID)
+	s.traderManager.InvalidateCompetitionCache()
+	s.audit(c, actorID, auditUserDelete, targetID, result)
+	return result, nil
+}
+
+// handleDeleteAccount 删除当前用户账户（需密码和OTP确认），账户已删除时直接返回成功
+func (s *Server) handleDeleteAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		Password string `json:"password" binding:"required"`
+		OTPCode  string `json:"otp_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "需要提供密码和OTP验证码"})
+		return
+	}
+
+	user, err := s.database.GetUserByID(userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		auth.RevokeUserTokens(userID)
+		c.JSON(http.StatusOK, gin.H{"message": "账户已删除", "already_deleted": true})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户信息失败"})
+		return
+	}
+	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "密码错误"})
+		return
+	}
+	if !s.verifySecondFactor(user, req.OTPCode) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	// 审计日志中已删除用户的记录会被匿名化，本次操作同样不关联用户ID
+	result, err := s.deleteUserAccount(c, userID, config.DeletedUserAuditID, "")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("🗑 用户 %s 已删除账户（停止 %d 个交易员，删除 %d 个交易员的决策日志）", user.Email, result.StoppedTraders, result.DecisionLogs)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "账户已删除",
+		"stopped_traders": result.StoppedTraders,
+		"decision_logs":   result.DecisionLogs,
+		"deleted":         result.Deleted,
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/auth"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/notify"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// TestDeleteAccount 删除账户需密码和OTP，删除后密钥、交易员、决策日志全部清除，已签发的token失效
+func TestDeleteAccount(t *testing.T) {
+	t.Chdir(t.TempDir())
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	otpSecret, _ := auth.GenerateOTPSecret()
+	hash, _ := auth.HashPassword("secret123")
+	if err := s.database.CreateUser(&config.User{ID: "user-del", Email: "del@test.com", PasswordHash: hash, OTPSecret: otpSecret, OTPVerified: true}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	if err := s.database.UpdateExchange("user-del", "binance", true, "api-key", "secret-key", false, "", "", "", ""); err != nil {
+		t.Fatalf("保存交易所失败: %v", err)
+	}
+	if err := s.database.CreateTrader(&config.TraderRecord{ID: "trader-del", UserID: "user-del", Name: "D", AIModelID: "deepseek", ExchangeID: "binance"}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	logger.OpenDecisionLogger(trader.DecisionLogDir("trader-del"))
+	if err := s.database.CreateNotificationChannel(&config.NotificationChannel{UserID: "user-del", Type: "telegram", Enabled: true,
+		Config: notify.ChannelConfig{BotToken: "bot-token", ChatID: "1"}}, time.Now()); err != nil {
+		t.Fatalf("创建通知通道失败: %v", err)
+	}
+	if err := s.database.CreateWebhook(&config.Webhook{UserID: "user-del", URL: "https://example.com/hook?token=x", Secret: "hook-secret", Enabled: true}, time.Now()); err != nil {
+		t.Fatalf("创建Webhook失败: %v", err)
+	}
+
+	token, _ := auth.GenerateJWT("user-del", "del@test.com", "")
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	code, _ := totp.GenerateCode(otpSecret, time.Now())
+	if w := do(http.MethodDelete, "/api/user", `{"password":"wrong-pass","otp_code":"`+code+`"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("密码错误应返回401，实际 %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/user", `{"password":"secret123","otp_code":"000000"}`); w.Code != http.StatusUnauthorized {
+		t.Fatalf("验证码错误应返回401，实际 %d", w.Code)
+	}
+
+	w := do(http.MethodDelete, "/api/user", `{"password":"secret123","otp_code":"`+code+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("删除账户应返回200: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		DecisionLogs int              `json:"decision_logs"`
+		Deleted      map[string]int64 `json:"deleted"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.DecisionLogs != 1 || resp.Deleted["traders"] != 1 || resp.Deleted["exchanges"] != 1 || resp.Deleted["users"] != 1 ||
+		resp.Deleted["notification_channels"] != 1 || resp.Deleted["webhooks"] != 1 {
+		t.Errorf("删除摘要不正确: %s", w.Body.String())
+	}
+
+	if _, err := os.Stat(trader.DecisionLogDir("trader-del")); !os.IsNotExist(err) {
+		t.Errorf("决策日志目录应被删除: %v", err)
+	}
+	if exchanges, _ := s.database.GetExchanges("user-del"); len(exchanges) != 0 {
+		t.Errorf("交易所配置应被删除: %d", len(exchanges))
+	}
+	if traders, _ := s.database.GetTraders("user-a"); len(traders) != 1 {
+		t.Errorf("不应影响其他用户的数据: %d", len(traders))
+	}
+
+	// 删除后旧token失效
+	if w := do(http.MethodGet, "/api/my-traders", ""); w.Code != http.StatusUnauthorized {
+		t.Errorf("删除账户后旧token应失效，实际 %d", w.Code)
+	}
+
+	// 重试是安全的：账户已不存在时直接返回成功，并清理残留数据
+	deleted, err := s.database.DeleteUserAccount("user-del")
+	if err != nil || len(deleted) != 0 {
+		t.Errorf("重复删除应无副作用: %v %v", deleted, err)
+	}
+}
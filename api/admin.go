@@ -0,0 +1,253 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/auth"
+	"nofx/config"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminMiddleware 管理员权限中间件（需在 authMiddleware 之后使用）
+func (s *Server) adminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != config.UserRoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		// token 中的角色可能已过时（被取消管理员后旧token仍在有效期内），以数据库为准
+		if user, err := s.database.GetUserByID(c.GetString("user_id")); err != nil || user.Role != config.UserRoleAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// handleAdminSetUserRole 设置用户角色（user/admin），不能修改自己的角色，避免误操作后没有管理员
+func (s *Server) handleAdminSetUserRole(c *gin.Context) {
+	targetID := c.Param("id")
+	adminID := c.GetString("user_id")
+	if targetID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能修改自己的角色"})
+		return
+	}
+
+	var req struct {
+		Role string `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Role != config.UserRoleUser && req.Role != config.UserRoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的角色: %s（可选 user/admin）", req.Role)})
+		return
+	}
+
+	if err := s.database.SetUserRole(targetID, req.Role); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.audit(c, adminID, auditUserRoleChange, targetID, gin.H{"role": req.Role})
+	requestLogf(c, "👑 管理员 %s 将用户 %s 的角色设为 %s", adminID, targetID, req.Role)
+	c.JSON(http.StatusOK, gin.H{"message": "角色已更新（取消管理员立即生效，授予管理员需用户重新登录或刷新token）", "role": req.Role})
+}
+
+// handleAdminListUsers 列出所有用户及其交易员数量
+func (s *Server) handleAdminListUsers(c *gin.Context) {
+	users, err := s.database.ListUsersWithTraderCounts()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"count": len(users),
+	})
+}
+
+// handleAdminDisableUser 禁用用户并停止其所有运行中的交易员
+func (s *Server) handleAdminDisableUser(c *gin.Context) {
+	targetID := c.Param("id")
+	if targetID == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能禁用自己的账户"})
+		return
+	}
+
+	if err := s.database.SetUserDisabled(targetID, true); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	stopped := s.unloadUserTraders(targetID)
+	if err := s.database.RevokeUserRefreshTokens(targetID); err != nil {
+		log.Printf("⚠️ 撤销用户 %s 的刷新token失败: %v", targetID, err)
+	}
+
+	requestLogf(c, "🚫 管理员 %s 已禁用用户 %s，停止 %d 个交易员", c.GetString("user_id"), targetID, stopped)
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "用户已禁用",
+		"stopped_traders": stopped,
+	})
+}
+
+// handleAdminEnableUser 启用用户（交易员需用户手动重新启动）
+func (s *Server) handleAdminEnableUser(c *gin.Context) {
+	targetID := c.Param("id")
+
+	if err := s.database.SetUserDisabled(targetID, false); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "✅ 管理员 %s 已启用用户 %s", c.GetString("user_id"), targetID)
+	c.JSON(http.StatusOK, gin.H{"message": "用户已启用"})
+}
+
+// handleAdminResetUserOTP 重置用户OTP，用户下次登录需重新绑定
+func (s *Server) handleAdminResetUserOTP(c *gin.Context) {
+	targetID := c.Param("id")
+
+	user, err := s.database.GetUserByID(targetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	otpSecret, err := auth.GenerateOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成OTP密钥失败"})
+		return
+	}
+
+	if err := s.database.ResetUserOTP(targetID, otpSecret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 重置OTP后用户需重新登录并绑定
+	if _, err := s.revokeOtherSessions(targetID, ""); err != nil {
+		log.Printf("⚠️ 撤销用户 %s 的会话失败: %v", targetID, err)
+	}
+
+	requestLogf(c, "🔑 管理员 %s 已重置用户 %s 的OTP", c.GetString("user_id"), targetID)
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "OTP已重置，用户下次登录需重新绑定",
+		"otp_secret":  otpSecret,
+		"qr_code_url": auth.GetOTPQRCodeURL(otpSecret, user.Email),
+	})
+}
+
+// handleAdminDeleteUser 删除用户及其全部交易员、决策日志和配置（与用户自助注销相同的清理流程）
+func (s *Server) handleAdminDeleteUser(c *gin.Context) {
+	targetID := c.Param("id")
+	if targetID == c.GetString("user_id") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "不能删除自己的账户"})
+		return
+	}
+
+	if _, err := s.database.GetUserByID(targetID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	adminID := c.GetString("user_id")
+	result, err := s.deleteUserAccount(c, targetID, adminID, targetID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除用户失败: %v", err)})
+		return
+	}
+
+	requestLogf(c, "🗑 管理员 %s 已删除用户 %s（停止 %d 个交易员，删除 %d 个交易员的决策日志）", adminID, targetID, result.StoppedTraders, result.DecisionLogs)
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "用户已删除",
+		"stopped_traders": result.StoppedTraders,
+		"decision_logs":   result.DecisionLogs,
+		"deleted":         result.Deleted,
+	})
+}
+
+// unloadUserTraders 停止用户所有运行中的交易员并从内存移除，返回停止的数量
+func (s *Server) unloadUserTraders(userID string) int {
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		log.Printf("⚠️ 获取用户 %s 的交易员失败: %v", userID, err)
+		return 0
+	}
+
+	stopped := 0
+	for _, t := range traders {
+		if at, err := s.traderManager.GetTrader(t.ID); err == nil {
+			if isRunning, ok := at.GetStatus()["is_running"].(bool); ok && isRunning {
+				at.Stop()
+				stopped++
+			}
+			s.traderManager.RemoveTrader(t.ID)
+		}
+		if t.IsRunning {
+			if err := s.database.UpdateTraderStatus(userID, t.ID, false); err != nil {
+				log.Printf("⚠️ 更新交易员 %s 状态失败: %v", t.ID, err)
+			}
+		}
+	}
+	return stopped
+}
+
+// handleAdminGenerateBetaCodes 批量生成内测码
+func (s *Server) handleAdminGenerateBetaCodes(c *gin.Context) {
+	var req struct {
+		Count     int        `json:"count" binding:"required"`
+		MaxUses   int        `json:"max_uses"`   // 每个码的最大使用次数（默认1）
+		ExpiresAt *time.Time `json:"expires_at"` // 过期时间（RFC3339，可选）
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := s.database.GenerateBetaCodes(req.Count, req.MaxUses, req.ExpiresAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "🎟 管理员 %s 生成了 %d 个内测码", c.GetString("user_id"), len(codes))
+	c.JSON(http.StatusOK, gin.H{
+		"codes": codes,
+		"count": len(codes),
+	})
+}
+
+// handleAdminListBetaCodes 列出内测码（?status=used|unused 筛选）
+func (s *Server) handleAdminListBetaCodes(c *gin.Context) {
+	codes, err := s.database.ListBetaCodes(c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"codes": codes,
+		"count": len(codes),
+	})
+}
+
+// handleAdminRevokeBetaCode 作废内测码
+func (s *Server) handleAdminRevokeBetaCode(c *gin.Context) {
+	code := c.Param("code")
+	if err := s.database.RevokeBetaCode(code); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "🎟 管理员 %s 作废了内测码 %s", c.GetString("user_id"), code)
+	c.JSON(http.StatusOK, gin.H{"message": "内测码已作废"})
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"nofx/auth"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAdminRoutes_RoleAndDisable 测试管理员路由的权限校验以及禁用用户后token失效
+func TestAdminRoutes_RoleAndDisable(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	if err := s.database.SetUserRole("user-a", config.UserRoleAdmin); err != nil {
+		t.Fatalf("设置管理员失败: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	adminToken, _ := auth.GenerateJWT("user-a", "user-a@test.com", config.UserRoleAdmin)
+	userToken, _ := auth.GenerateJWT("user-b", "user-b@test.com", "")
+
+	do := func(method, path, token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 旧token（无role）视为普通用户
+	if code := do(http.MethodGet, "/api/admin/users", userToken); code != http.StatusForbidden {
+		t.Errorf("普通用户访问管理员接口应返回403，实际 %d", code)
+	}
+	if code := do(http.MethodGet, "/api/admin/users", adminToken); code != http.StatusOK {
+		t.Errorf("管理员访问应返回200，实际 %d", code)
+	}
+
+	if code := do(http.MethodPost, "/api/admin/users/user-a/disable", adminToken); code != http.StatusBadRequest {
+		t.Errorf("禁用自己应返回400，实际 %d", code)
+	}
+	if code := do(http.MethodPost, "/api/admin/users/user-b/disable", adminToken); code != http.StatusOK {
+		t.Fatalf("禁用用户应返回200，实际 %d", code)
+	}
+
+	// 被禁用用户的token立即失效
+	if code := do(http.MethodGet, "/api/my-traders", userToken); code != http.StatusForbidden {
+		t.Errorf("被禁用用户应返回403，实际 %d", code)
+	}
+
+	if code := do(http.MethodPost, "/api/admin/users/user-b/enable", adminToken); code != http.StatusOK {
+		t.Errorf("启用用户应返回200，实际 %d", code)
+	}
+	if code := do(http.MethodGet, "/api/my-traders", userToken); code == http.StatusForbidden {
+		t.Error("重新启用后不应返回403")
+	}
+}
+
+// TestAdminSetUserRole 管理员修改角色；取消管理员后旧token中的角色立即失效
+func TestAdminSetUserRole(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	for _, id := range []string{"user-a", "user-b"} {
+		if err := s.database.SetUserRole(id, config.UserRoleAdmin); err != nil {
+			t.Fatalf("设置管理员失败: %v", err)
+		}
+	}
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	tokenA, _ := auth.GenerateJWT("user-a", "user-a@test.com", config.UserRoleAdmin)
+	tokenB, _ := auth.GenerateJWT("user-b", "user-b@test.com", config.UserRoleAdmin)
+	do := func(method, path, token, body string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := do(http.MethodPut, "/api/admin/users/user-a/role", tokenA, `{"role":"user"}`); code != http.StatusBadRequest {
+		t.Errorf("修改自己的角色应返回400，实际 %d", code)
+	}
+	if code := do(http.MethodPut, "/api/admin/users/user-b/role", tokenA, `{"role":"root"}`); code != http.StatusBadRequest {
+		t.Errorf("无效角色应返回400，实际 %d", code)
+	}
+	if code := do(http.MethodPut, "/api/admin/users/user-b/role", tokenA, `{"role":"user"}`); code != http.StatusOK {
+		t.Fatalf("修改角色应返回200，实际 %d", code)
+	}
+	if code := do(http.MethodGet, "/api/admin/users", tokenB, ""); code != http.StatusForbidden {
+		t.Errorf("取消管理员后旧token应返回403，实际 %d", code)
+	}
+
+	if _, err := s.database.PromoteAdminByEmail("user-b@test.com"); err != nil {
+		t.Fatalf("设置管理员失败: %v", err)
+	}
+	if code := do(http.MethodGet, "/api/admin/users", tokenB, ""); code != http.StatusOK {
+		t.Errorf("重新设为管理员后应返回200，实际 %d", code)
+	}
+	if _, err := s.database.PromoteAdminByEmail("nobody@test.com"); err == nil {
+		t.Error("不存在的邮箱应返回错误")
+	}
+}
+
+// TestAdminDeleteUser 管理员删除用户与自助注销走同一流程：删除决策日志、token失效、审计日志匿名化，并记录管理员的操作
+func TestAdminDeleteUser(t *testing.T) {
+	t.Chdir(t.TempDir())
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	if err := s.database.SetUserRole("user-a", config.UserRoleAdmin); err != nil {
+		t.Fatalf("设置管理员失败: %v", err)
+	}
+	// 使用单独的用户：token 撤销是全局状态，不能影响其他测试使用的 user-b
+	if err := s.database.CreateUser(&config.User{ID: "user-gone", Email: "gone@test.com", PasswordHash: "x"}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	if err := s.database.CreateTrader(&config.TraderRecord{ID: "trader-gone", UserID: "user-gone", Name: "G", AIModelID: "deepseek", ExchangeID: "binance"}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	s.auditLog = newAuditLogger(s.database)
+	if err := s.database.InsertAuditEntries([]*config.AuditEntry{{UserID: "user-gone", Action: auditExchangeUpdate}}); err != nil {
+		t.Fatalf("写入审计日志失败: %v", err)
+	}
+	logger.OpenDecisionLogger(trader.DecisionLogDir("trader-gone"))
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	adminToken, _ := auth.GenerateJWT("user-a", "user-a@test.com", config.UserRoleAdmin)
+	userToken, _ := auth.GenerateJWT("user-gone", "gone@test.com", "")
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodDelete, "/api/admin/users/user-gone", adminToken)
+	if w.Code != http.StatusOK {
+		t.Fatalf("删除用户应返回200: %d %s", w.Code, w.Body.String())
+	}
+	s.auditLog.Close()
+
+	if !strings.Contains(w.Body.String(), `"decision_logs":1`) {
+		t.Errorf("应删除交易员的决策日志: %s", w.Body.String())
+	}
+	if _, err := os.Stat(trader.DecisionLogDir("trader-gone")); !os.IsNotExist(err) {
+		t.Errorf("决策日志目录应被删除: %v", err)
+	}
+	if w := do(http.MethodGet, "/api/my-traders", userToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("被删除用户的token应失效，实际 %d", w.Code)
+	}
+
+	filter := config.AuditLogFilter{Limit: 10}
+	if entries, _, _ := s.database.ListAuditEntries("user-gone", filter); len(entries) != 0 {
+		t.Errorf("被删除用户的审计日志应匿名化，剩余 %d 条", len(entries))
+	}
+	if entries, _, _ := s.database.ListAuditEntries(config.DeletedUserAuditID, filter); len(entries) != 1 {
+		t.Errorf("审计日志应保留并匿名化，实际 %d 条", len(entries))
+	}
+	entries, _, _ := s.database.ListAuditEntries("user-a", filter)
+	if len(entries) != 1 || entries[0].Action != auditUserDelete || entries[0].TargetID != "user-gone" {
+		t.Errorf("应记录管理员删除用户的审计日志: %+v", entries)
+	}
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/config"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 审计动作
+const (
+	auditExchangeUpdate     = "exchange.update"
+	auditExchangeUpdateKeys = "exchange.update_keys"
+	auditExchangeDelete     = "exchange.delete_account"
+	auditModelUpdate        = "model.update"
+	auditModelUpdateKeys    = "model.update_keys"
+	auditTraderCreate       = "trader.create"
+	auditTraderUpdate       = "trader.update"
+	auditTraderDelete       = "trader.delete"
+	auditTraderStart        = "trader.start"
+	auditTraderStop         = "trader.stop"
+	auditTraderGuardrails   = "trader.guardrails"
+	auditEmergencyStop      = "trader.emergency_stop"
+	auditPasswordReset      = "user.password_reset"
+	auditUserRoleChange     = "user.role_change"
+	auditUserDelete         = "user.delete"
+	auditSessionRevoke      = "session.revoke"
+	auditPromptCreate       = "prompt_template.create"
+	auditPromptUpdate       = "prompt_template.update"
+	auditPromptDelete       = "prompt_template.delete"
+	auditSystemConfigUpdate = "system_config.update"
+	auditLoginLockout       = "auth.login_lockout"
+	auditLoginUnlock        = "auth.login_unlock"
+	auditCryptoKeyRotate    = "crypto.key_rotate"
+	auditNotificationCreate = "notification.create"
+	auditNotificationUpdate = "notification.update"
+	auditNotificationDelete = "notification.delete"
+	auditWebhookCreate      = "webhook.create"
+	auditWebhookUpdate      = "webhook.update"
+	auditWebhookDelete      = "webhook.delete"
+)
+
+const (
+	auditBufferSize    = 1024        // 待写入队列长度（写满后丢弃并记录日志，避免阻塞请求）
+	auditBatchSize     = 100         // 单次批量写入的最大条数
+	auditFlushInterval = time.Second // 批量写入间隔
+
+	defaultAuditPageSize = 50
+	maxAuditPageSize     = 200
+)
+
+// auditLogger 异步批量写入审计日志，不增加请求处理延迟
+type auditLogger struct {
+	database *config.Database
+	entries  chan *config.AuditEntry
+	done     chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// newAuditLogger 创建审计日志写入器并启动后台写入协程
+func newAuditLogger(database *config.Database) *auditLogger {
+	l := &auditLogger{
+		database: database,
+		entries:  make(chan *config.AuditEntry, auditBufferSize),
+		done:     make(chan struct{}),
+	}
+	go l.run()
+	return l
+}
+
+// record 将审计记录放入队列（非阻塞）
+func (l *auditLogger) record(entry *config.AuditEntry) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.entries <- entry:
+	default:
+		log.Printf("⚠️ 审计日志队列已满，丢弃记录: %s %s (UserID: %s)", entry.Action, entry.TargetID, entry.UserID)
+	}
+}
+
+// run 后台批量写入，队列关闭后写完剩余记录
+func (l *auditLogger) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(auditFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*config.AuditEntry, 0, auditBatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := l.database.InsertAuditEntries(batch); err != nil {
+			log.Printf("❌ 写入 %d 条审计日志失败: %v", len(batch), err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case entry, ok := <-l.entries:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= auditBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Close 停止接收新记录并等待队列中的记录写入完成
+func (l *auditLogger) Close() {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.closed = true
+	close(l.entries)
+	l.mu.Unlock()
+
+	<-l.done
+}
+
+// audit 记录一条审计日志（summary 必须是已脱敏的内容）
+func (s *Server) audit(c *gin.Context, userID, action, targetID string, summary interface{}) {
+	if s.auditLog == nil {
+		return
+	}
+
+	var text string
+	switch v := summary.(type) {
+	case nil:
+	case string:
+		text = v
+	default:
+		if data, err := json.Marshal(v); err == nil {
+			text = string(data)
+		} else {
+			text = fmt.Sprintf("%v", v)
+		}
+	}
+
+	s.auditLog.record(&config.AuditEntry{
+		UserID:    userID,
+		Action:    action,
+		TargetID:  targetID,
+		IPAddress: c.ClientIP(),
+		Summary:   text,
+		CreatedAt: time.Now(),
+	})
+}
+
+// handleGetAuditLog 分页查询当前用户的审计日志
+// 参数: from/to（RFC3339、YYYY-MM-DD 或 Unix 时间戳）、action、limit（默认50，最大200）、offset
+func (s *Server) handleGetAuditLog(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	filter := config.AuditLogFilter{
+		Action: c.Query("action"),
+		Limit:  defaultAuditPageSize,
+	}
+	for param, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if raw := c.Query(param); raw != "" {
+			t, err := parseEquityTime(raw)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的%s参数: %v", param, err)})
+				return
+			}
+			*target = t
+		}
+	}
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的limit参数"})
+			return
+		}
+		filter.Limit = min(n, maxAuditPageSize)
+	}
+	if raw := c.Query("offset"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的offset参数"})
+			return
+		}
+		filter.Offset = n
+	}
+
+	entries, total, err := s.database.ListAuditEntries(userID, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+		"limit":   filter.Limit,
+		"offset":  filter.Offset,
+	})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAuditLogger_FlushOnClose 测试异步写入的审计日志在关闭时全部落库，且只能查询自己的记录
+func TestAuditLogger_FlushOnClose(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	s.auditLog = newAuditLogger(s.database)
+
+	gin.SetMode(gin.TestMode)
+	newContext := func(userID, query string) (*gin.Context, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/audit-log"+query, nil)
+		c.Request.RemoteAddr = "10.0.0.1:1234"
+		c.Set("user_id", userID)
+		return c, w
+	}
+
+	c, _ := newContext("user-a", "")
+	s.audit(c, "user-a", auditTraderStart, "trader-a", nil)
+	s.audit(c, "user-a", auditExchangeUpdate, "", gin.H{"binance": gin.H{"api_key": MaskSensitiveString("abcdefgh12345678")}})
+	s.audit(c, "user-b", auditTraderStop, "trader-b", nil)
+	s.auditLog.Close()
+
+	c, w := newContext("user-a", "?limit=1")
+	s.handleGetAuditLog(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, body=%s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Entries []*config.AuditEntry `json:"entries"`
+		Total   int                  `json:"total"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Total != 2 || len(resp.Entries) != 1 {
+		t.Fatalf("user-a 应有2条记录（本页1条），实际 total=%d len=%d", resp.Total, len(resp.Entries))
+	}
+	if resp.Entries[0].IPAddress != "10.0.0.1" {
+		t.Errorf("IP地址 = %q, want 10.0.0.1", resp.Entries[0].IPAddress)
+	}
+
+	c, w = newContext("user-a", "?action="+auditExchangeUpdate)
+	s.handleGetAuditLog(c)
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Total != 1 || resp.Entries[0].Summary != `{"binance":{"api_key":"abcd****5678"}}` {
+		t.Errorf("摘要应只包含脱敏后的密钥: %+v", resp.Entries)
+	}
+
+	// 关闭后记录被忽略，不会panic
+	s.audit(c, "user-a", auditTraderStart, "trader-a", nil)
+
+	c, w = newContext("user-a", "?from=not-a-time")
+	s.handleGetAuditLog(c)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("无效时间参数应返回400，实际 %d", w.Code)
+	}
+}
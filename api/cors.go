@@ -0,0 +1,130 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	corsAllowMethods = "GET, POST, PUT, DELETE, OPTIONS"
+	corsAllowHeaders = "Content-Type, Authorization, " + requestIDHeader + ", " + responseKeyHeader
+	// corsMaxAge 预检请求结果的缓存时间（秒），减少 OPTIONS 请求
+	corsMaxAge = 3600
+)
+
+// corsConfig CORS配置
+type corsConfig struct {
+	Origins          map[string]bool // 允许的来源（为空表示允许任意来源 "*"）
+	AllowCredentials bool            // 是否允许携带凭证（仅在配置了具体来源时生效）
+}
+
+// allowAll 是否允许任意来源
+func (c corsConfig) allowAll() bool {
+	return len(c.Origins) == 0
+}
+
+// allowsOrigin 判断来源是否被允许（空 Origin 表示非浏览器请求，总是允许）
+func (c corsConfig) allowsOrigin(origin string) bool {
+	return origin == "" || c.allowAll() || c.Origins[normalizeOrigin(origin)]
+}
+
+// normalizeOrigin 统一来源格式（小写、去掉末尾斜杠）
+func normalizeOrigin(origin string) string {
+	return strings.TrimRight(strings.ToLower(strings.TrimSpace(origin)), "/")
+}
+
+// parseCORSConfig 解析逗号分隔的来源列表，包含 "*" 或为空时允许任意来源
+func parseCORSConfig(rawOrigins, rawCredentials string) corsConfig {
+	cfg := corsConfig{Origins: make(map[string]bool)}
+	for _, origin := range strings.Split(rawOrigins, ",") {
+		origin = normalizeOrigin(origin)
+		if origin == "" {
+			continue
+		}
+		if origin == "*" {
+			cfg.Origins = nil
+			break
+		}
+		cfg.Origins[origin] = true
+	}
+	if len(cfg.Origins) == 0 {
+		cfg.Origins = nil
+	}
+
+	allowCredentials, _ := strconv.ParseBool(strings.TrimSpace(rawCredentials))
+	if allowCredentials && cfg.allowAll() {
+		log.Printf("⚠️ cors_allow_credentials 需要配置具体的 cors_allowed_origins，通配符 * 下已忽略")
+		allowCredentials = false
+	}
+	cfg.AllowCredentials = allowCredentials
+	return cfg
+}
+
+// loadCORSConfig 从系统配置读取 cors_allowed_origins 和 cors_allow_credentials（重启后生效）
+func (s *Server) loadCORSConfig() corsConfig {
+	if s.database == nil {
+		return corsConfig{}
+	}
+	sysConfig := s.database.SystemConfig()
+	return parseCORSConfig(sysConfig.String("cors_allowed_origins", ""), sysConfig.String("cors_allow_credentials", ""))
+}
+
+// String 便于日志输出
+func (c corsConfig) String() string {
+	if c.allowAll() {
+		return "允许任意来源 (*)"
+	}
+	origins := make([]string, 0, len(c.Origins))
+	for origin := range c.Origins {
+		origins = append(origins, origin)
+	}
+	return "允许来源 " + strings.Join(origins, ", ") + "，携带凭证: " + strconv.FormatBool(c.AllowCredentials)
+}
+
+// corsMiddleware CORS中间件：通配符模式返回 *，否则回显匹配的 Origin 并设置 Vary: Origin
+func corsMiddleware(cfg corsConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		header := c.Writer.Header()
+
+		allowed := false
+		if cfg.allowAll() {
+			header.Set("Access-Control-Allow-Origin", "*")
+			allowed = true
+		} else {
+			// 响应随 Origin 变化，避免缓存把一个来源的响应返回给另一个来源
+			header.Add("Vary", "Origin")
+			if origin != "" && cfg.allowsOrigin(origin) {
+				header.Set("Access-Control-Allow-Origin", origin)
+				if cfg.AllowCredentials {
+					header.Set("Access-Control-Allow-Credentials", "true")
+				}
+				allowed = true
+			}
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			if !allowed && origin != "" {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			header.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			header.Set("Access-Control-Max-Age", strconv.Itoa(corsMaxAge))
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if allowed {
+			header.Set("Access-Control-Allow-Methods", corsAllowMethods)
+			header.Set("Access-Control-Allow-Headers", corsAllowHeaders)
+			// 前端可读取请求ID（反馈问题时定位日志）和响应是否已加密
+			header.Set("Access-Control-Expose-Headers", requestIDHeader+", "+encryptedResponseHeader)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCORSRouter 创建只挂载CORS中间件的测试路由
+func newCORSRouter(cfg corsConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(corsMiddleware(cfg))
+	router.GET("/api/ping", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+// doCORSRequest 发送带 Origin 的请求
+func doCORSRequest(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/ping", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestCORSMiddleware_Wildcard 测试默认通配符模式
+func TestCORSMiddleware_Wildcard(t *testing.T) {
+	router := newCORSRouter(parseCORSConfig("*", "false"))
+
+	w := doCORSRequest(router, http.MethodGet, "https://any.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Allow-Origin = %q, want *", got)
+	}
+	if got := w.Header().Get("Vary"); got != "" {
+		t.Errorf("通配符模式不应设置 Vary，实际 %q", got)
+	}
+}
+
+// TestCORSMiddleware_AllowedOrigin 测试配置具体来源时回显 Origin 并携带凭证
+func TestCORSMiddleware_AllowedOrigin(t *testing.T) {
+	router := newCORSRouter(parseCORSConfig("https://app.example.com/, https://admin.example.com", "true"))
+
+	w := doCORSRequest(router, http.MethodGet, "https://app.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Allow-Origin = %q, want https://app.example.com", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Allow-Credentials = %q, want true", got)
+	}
+	if got := w.Header().Get("Vary"); got != "Origin" {
+		t.Errorf("Vary = %q, want Origin", got)
+	}
+}
+
+// TestCORSMiddleware_DisallowedOrigin 测试未配置的来源不返回 CORS 头，预检被拒绝
+func TestCORSMiddleware_DisallowedOrigin(t *testing.T) {
+	router := newCORSRouter(parseCORSConfig("https://app.example.com", "false"))
+
+	w := doCORSRequest(router, http.MethodGet, "https://evil.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("未允许的来源不应返回 Allow-Origin，实际 %q", got)
+	}
+
+	w = doCORSRequest(router, http.MethodOptions, "https://evil.example.com")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("预检状态码 = %d, want 403", w.Code)
+	}
+
+	// 非浏览器请求（无 Origin）不受影响
+	w = doCORSRequest(router, http.MethodGet, "")
+	if w.Code != http.StatusOK {
+		t.Errorf("无 Origin 请求状态码 = %d, want 200", w.Code)
+	}
+}
+
+// TestCORSMiddleware_Preflight 测试预检请求返回204和缓存时间
+func TestCORSMiddleware_Preflight(t *testing.T) {
+	router := newCORSRouter(parseCORSConfig("https://app.example.com", "false"))
+
+	w := doCORSRequest(router, http.MethodOptions, "https://app.example.com")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("预检状态码 = %d, want 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "3600" {
+		t.Errorf("Max-Age = %q, want 3600", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != corsAllowMethods {
+		t.Errorf("Allow-Methods = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("未启用凭证时不应返回 Allow-Credentials，实际 %q", got)
+	}
+}
+
+// TestParseCORSConfig 测试来源列表解析
+func TestParseCORSConfig(t *testing.T) {
+	if cfg := parseCORSConfig("", "true"); !cfg.allowAll() || cfg.AllowCredentials {
+		t.Errorf("空配置应允许任意来源且忽略凭证: %+v", cfg)
+	}
+	if cfg := parseCORSConfig("https://a.com, *", "true"); !cfg.allowAll() || cfg.AllowCredentials {
+		t.Errorf("包含 * 应允许任意来源且忽略凭证: %+v", cfg)
+	}
+
+	cfg := parseCORSConfig(" HTTPS://A.com/ ,,https://b.com", "false")
+	if cfg.allowAll() || len(cfg.Origins) != 2 {
+		t.Fatalf("应解析出2个来源: %+v", cfg)
+	}
+	if !cfg.allowsOrigin("https://a.com") || !cfg.allowsOrigin("https://B.com/") {
+		t.Errorf("应允许已配置的来源（忽略大小写和末尾斜杠）")
+	}
+	if cfg.allowsOrigin("https://c.com") {
+		t.Errorf("不应允许未配置的来源")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"log"
 	"net/http"
 	"nofx/crypto"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -22,39 +23,48 @@ func NewCryptoHandler(cryptoService *crypto.CryptoService) *CryptoHandler {
 
 // ==================== 公鑰端點 ====================
 
-// HandleGetPublicKey 獲取伺服器公鑰
+// HandleGetPublicKey 獲取伺服器當前公鑰及其標識（客戶端加密時放入 kid）
 func (h *CryptoHandler) HandleGetPublicKey(c *gin.Context) {
 	publicKey := h.cryptoService.GetPublicKeyPEM()
 
 	c.JSON(http.StatusOK, map[string]string{
 		"public_key": publicKey,
+		"key_id":     h.cryptoService.CurrentKeyID(),
 		"algorithm":  "RSA-OAEP-2048",
 	})
 }
 
-// ==================== 加密數據解密端點 ====================
+// ==================== 密鑰輪換端點 ====================
 
-// HandleDecryptSensitiveData 解密客戶端傳送的加密数据
-func (h *CryptoHandler) HandleDecryptSensitiveData(c *gin.Context) {
-	var payload crypto.EncryptedPayload
-	if err := c.ShouldBindJSON(&payload); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
-		return
-	}
+// handleAdminListRSAKeys 查看當前公鑰和寬限期內的舊公鑰（僅管理員）
+func (s *Server) handleAdminListRSAKeys(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"keys": s.cryptoHandler.cryptoService.Keys()})
+}
 
-	// 解密
-	decrypted, err := h.cryptoService.DecryptSensitiveData(&payload)
+// handleAdminRotateRSAKey 輪換 RSA 傳輸公鑰，舊公鑰在 rsa_key_grace_hours 內仍可解密（僅管理員）
+func (s *Server) handleAdminRotateRSAKey(c *gin.Context) {
+	cryptoService := s.cryptoHandler.cryptoService
+	oldKeyID := cryptoService.CurrentKeyID()
+	grace := time.Duration(s.database.SystemConfig().Int("rsa_key_grace_hours", 24)) * time.Hour
+
+	keyID, err := cryptoService.RotateKey(grace)
 	if err != nil {
-		log.Printf("❌ 解密失敗: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Decryption failed"})
+		log.Printf("❌ 輪換RSA公鑰失敗: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "輪換公鑰失敗"})
 		return
 	}
 
-	c.JSON(http.StatusOK, map[string]string{
-		"plaintext": decrypted,
+	userID := c.GetString("user_id")
+	s.audit(c, userID, auditCryptoKeyRotate, keyID, gin.H{"retired": oldKeyID, "grace_hours": grace.Hours()})
+	requestLogf(c, "🔑 管理員 %s 輪換RSA公鑰: %s -> %s", userID, oldKeyID, keyID)
+	c.JSON(http.StatusOK, gin.H{
+		"key_id": keyID,
+		"keys":   cryptoService.Keys(),
 	})
 }
 
+// 加密數據只在服務端處理請求時解密（bindSensitiveJSON），不提供公開的解密端點
+
 // ==================== 審計日誌查詢端點 ====================
 
 // 删除审计日志相关功能，在当前简化的实现中不需要
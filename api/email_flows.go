@@ -0,0 +1,262 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"nofx/auth"
+	"nofx/config"
+	"nofx/mailer"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	emailVerifyTokenTTL     = 24 * time.Hour   // 邮箱验证链接有效期
+	passwordResetTokenTTL   = 30 * time.Minute // 重置密码链接有效期
+	defaultEmailSendPerHour = 5                // 每个邮箱每小时最多发送的验证/重置邮件数
+)
+
+var (
+	errMailNotConfigured = errors.New("未配置邮件服务")
+	errEmailRateLimited  = errors.New("邮件发送过于频繁，请稍后再试")
+)
+
+// emailSender 当前的邮件发送器（测试时注入；否则按系统配置的SMTP发送，未配置 smtp_host 时返回 nil）
+func (s *Server) emailSender() mailer.Sender {
+	if s.mailSender != nil {
+		return s.mailSender
+	}
+	sysConfig := s.database.SystemConfig()
+	host := sysConfig.String("smtp_host", "")
+	if host == "" {
+		return nil
+	}
+	return mailer.NewSMTPSender(mailer.SMTPConfig{
+		Host:     host,
+		Port:     sysConfig.Int("smtp_port", 587),
+		Username: sysConfig.String("smtp_username", ""),
+		Password: sysConfig.String("smtp_password", ""),
+		From:     sysConfig.String("smtp_from", ""),
+	})
+}
+
+// emailVerificationRequired 新用户是否需要验证邮箱（未配置邮件服务时不要求）
+func (s *Server) emailVerificationRequired() bool {
+	return s.database.SystemConfig().Bool("email_verification_required", true) && s.emailSender() != nil
+}
+
+// emailUnverified 账户是否因邮箱未验证而不可用
+func (s *Server) emailUnverified(user *config.User) bool {
+	return !user.EmailVerified && s.emailVerificationRequired()
+}
+
+// sendEmailToken 生成一次性令牌并通过邮件发送（按邮箱限制发送频率）
+func (s *Server) sendEmailToken(user *config.User, purpose string) error {
+	sender := s.emailSender()
+	if sender == nil {
+		return errMailNotConfigured
+	}
+
+	now := time.Now()
+	limit := s.database.SystemConfig().Int("email_send_per_hour", defaultEmailSendPerHour)
+	sent, err := s.database.CountEmailTokensSince(user.ID, purpose, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if sent >= limit {
+		return errEmailRateLimited
+	}
+
+	ttl := emailVerifyTokenTTL
+	if purpose == config.EmailTokenPasswordReset {
+		ttl = passwordResetTokenTTL
+	}
+	token, err := s.database.CreateEmailToken(user.ID, purpose, ttl, now)
+	if err != nil {
+		return err
+	}
+	if err := sender.Send(s.buildTokenEmail(user.Email, purpose, token, ttl)); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return nil
+}
+
+// buildTokenEmail 验证邮箱/重置密码邮件（配置了 app_base_url 时附带链接）
+func (s *Server) buildTokenEmail(to, purpose, token string, ttl time.Duration) *mailer.Message {
+	path, subject, action := "/verify-email", "NOFX 邮箱验证", "验证邮箱"
+	if purpose == config.EmailTokenPasswordReset {
+		path, subject, action = "/reset-password", "NOFX 重置密码", "重置密码（需同时输入 Google Authenticator 验证码）"
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "你好，\n\n请在 %s 内完成%s。\n\n", formatTTL(ttl), action)
+	if base := strings.TrimRight(s.database.SystemConfig().String("app_base_url", ""), "/"); base != "" {
+		fmt.Fprintf(&body, "点击链接：\n%s%s?token=%s\n\n或在页面中输入以下令牌：\n", base, path, url.QueryEscape(token))
+	} else {
+		body.WriteString("请在页面中输入以下令牌：\n")
+	}
+	fmt.Fprintf(&body, "%s\n\n链接只能使用一次。如果不是你本人操作，请忽略此邮件。\n", token)
+	return &mailer.Message{To: to, Subject: subject, Body: body.String()}
+}
+
+// formatTTL 有效期的中文描述
+func formatTTL(ttl time.Duration) string {
+	if ttl >= time.Hour && ttl%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", int(ttl/time.Hour))
+	}
+	return fmt.Sprintf("%d分钟", int(ttl/time.Minute))
+}
+
+// sendVerificationEmail 发送邮箱验证邮件，返回是否已发送（失败只记录日志，用户可重新发送）
+func (s *Server) sendVerificationEmail(user *config.User) bool {
+	if err := s.sendEmailToken(user, config.EmailTokenVerify); err != nil {
+		log.Printf("⚠️ 发送验证邮件到 %s 失败: %v", user.Email, err)
+		return false
+	}
+	log.Printf("📧 已发送验证邮件到 %s", user.Email)
+	return true
+}
+
+// handleVerifyEmail 通过邮件中的令牌验证邮箱
+func (s *Server) handleVerifyEmail(c *gin.Context) {
+	var req struct {
+		Token string `json:"token" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, err := s.database.ConsumeEmailToken(strings.TrimSpace(req.Token), config.EmailTokenVerify, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": config.ErrEmailTokenInvalid.Error()})
+		return
+	}
+	if err := s.database.SetUserEmailVerified(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新用户状态失败"})
+		return
+	}
+
+	requestLogf(c, "✅ 用户 %s 已验证邮箱", userID)
+	c.JSON(http.StatusOK, gin.H{"message": "邮箱验证成功", "user_id": userID})
+}
+
+// handleResendVerification 重新发送验证邮件（无论邮箱是否存在都返回相同结果）
+func (s *Server) handleResendVerification(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if s.emailSender() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errMailNotConfigured.Error()})
+		return
+	}
+
+	if user, err := s.database.GetUserByEmail(req.Email); err == nil && !user.EmailVerified {
+		s.sendVerificationEmail(user)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册且尚未验证，将收到验证邮件"})
+}
+
+// handleForgotPassword 发送重置密码邮件（无论邮箱是否存在都返回相同结果）
+func (s *Server) handleForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if s.emailSender() == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": errMailNotConfigured.Error()})
+		return
+	}
+
+	if user, err := s.database.GetUserByEmail(req.Email); err == nil && !user.Disabled {
+		if err := s.sendEmailToken(user, config.EmailTokenPasswordReset); err != nil {
+			log.Printf("⚠️ 发送重置密码邮件到 %s 失败: %v", user.Email, err)
+		} else {
+			requestLogf(c, "📧 已发送重置密码邮件到 %s", user.Email)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "如果该邮箱已注册，将收到重置密码邮件"})
+}
+
+// handleResetPassword 重置密码（邮件中的一次性令牌 + OTP 验证）
+func (s *Server) handleResetPassword(c *gin.Context) {
+	var req struct {
+		Token       string `json:"token" binding:"required"`
+		NewPassword string `json:"new_password" binding:"required,min=6"`
+		OTPCode     string `json:"otp_code" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	token := strings.TrimSpace(req.Token)
+
+	// 先查找令牌，OTP 验证通过后才消耗（输错验证码不会使链接失效）
+	userID, err := s.database.LookupEmailToken(token, config.EmailTokenPasswordReset, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": config.ErrEmailTokenInvalid.Error()})
+		return
+	}
+	user, err := s.database.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": config.ErrEmailTokenInvalid.Error()})
+		return
+	}
+
+	// 验证 OTP（也接受一次性恢复码），与登录共用失败计数和锁定
+	if s.loginBlocked(c, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Google Authenticator 验证码或恢复码错误"})
+		return
+	}
+	if !s.verifySecondFactor(user, req.OTPCode) {
+		s.recordLoginFailure(c, user.ID, user.Email)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Google Authenticator 验证码或恢复码错误"})
+		return
+	}
+	if _, err := s.database.ConsumeEmailToken(token, config.EmailTokenPasswordReset, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": config.ErrEmailTokenInvalid.Error()})
+		return
+	}
+
+	// 生成新密码哈希
+	newPasswordHash, err := auth.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码处理失败"})
+		return
+	}
+
+	// 更新密码
+	if err := s.database.UpdateUserPassword(user.ID, newPasswordHash); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码更新失败"})
+		return
+	}
+
+	// 能收到重置邮件说明邮箱属于该用户
+	if !user.EmailVerified {
+		if err := s.database.SetUserEmailVerified(user.ID); err != nil {
+			log.Printf("⚠️ 标记用户 %s 邮箱已验证失败: %v", user.ID, err)
+		}
+	}
+	// 所有设备需使用新密码重新登录
+	if _, err := s.revokeOtherSessions(user.ID, ""); err != nil {
+		log.Printf("⚠️ 撤销用户 %s 的会话失败: %v", user.ID, err)
+	}
+	s.resetLoginFailures(user.Email)
+
+	log.Printf("✓ 用户 %s 密码已重置", user.Email)
+	s.audit(c, user.ID, auditPasswordReset, user.ID, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "密码重置成功，请使用新密码登录"})
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/auth"
+	"nofx/mailer"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// emailTokenPattern 邮件正文中的令牌
+var emailTokenPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// TestEmailVerificationAndPasswordReset 注册后需验证邮箱才能完成注册；忘记密码邮件中的令牌配合OTP重置密码，令牌只能使用一次
+func TestEmailVerificationAndPasswordReset(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	sender := &mailer.MockSender{}
+	s.mailSender = sender
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/register", s.handleRegister)
+	router.POST("/api/complete-registration", s.handleCompleteRegistration)
+	router.POST("/api/login", s.handleLogin)
+	router.POST("/api/verify-email", s.handleVerifyEmail)
+	router.POST("/api/forgot-password", s.handleForgotPassword)
+	router.POST("/api/reset-password", s.handleResetPassword)
+
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(string(data)))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	lastToken := func(subject string) string {
+		msg := sender.Last("new@test.com")
+		if msg == nil || msg.Subject != subject {
+			t.Fatalf("应发送邮件「%s」: %+v", subject, msg)
+		}
+		return emailTokenPattern.FindString(msg.Body)
+	}
+
+	w := post("/api/register", map[string]string{"email": "new@test.com", "password": "secret123"})
+	var reg struct {
+		UserID                    string `json:"user_id"`
+		OTPSecret                 string `json:"otp_secret"`
+		RequiresEmailVerification bool   `json:"requires_email_verification"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &reg)
+	if w.Code != http.StatusOK || !reg.RequiresEmailVerification {
+		t.Fatalf("注册应要求验证邮箱: %d %s", w.Code, w.Body.String())
+	}
+	verifyToken := lastToken("NOFX 邮箱验证")
+
+	otpCode := func() string {
+		code, _ := totp.GenerateCode(reg.OTPSecret, time.Now())
+		return code
+	}
+	if w := post("/api/complete-registration", map[string]string{"user_id": reg.UserID, "otp_code": otpCode()}); w.Code != http.StatusForbidden {
+		t.Fatalf("邮箱未验证时不能完成注册，实际 %d", w.Code)
+	}
+	if w := post("/api/verify-email", map[string]string{"token": verifyToken}); w.Code != http.StatusOK {
+		t.Fatalf("验证邮箱应返回200: %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/api/verify-email", map[string]string{"token": verifyToken}); w.Code != http.StatusBadRequest {
+		t.Errorf("验证令牌只能使用一次，实际 %d", w.Code)
+	}
+	if w := post("/api/complete-registration", map[string]string{"user_id": reg.UserID, "otp_code": otpCode()}); w.Code != http.StatusOK {
+		t.Fatalf("验证邮箱后应能完成注册: %d %s", w.Code, w.Body.String())
+	}
+
+	// 不存在的邮箱返回相同结果且不发信
+	sent := len(sender.Messages())
+	if w := post("/api/forgot-password", map[string]string{"email": "nobody@test.com"}); w.Code != http.StatusOK || len(sender.Messages()) != sent {
+		t.Errorf("不存在的邮箱应返回200且不发送邮件: %d", w.Code)
+	}
+	if w := post("/api/forgot-password", map[string]string{"email": "new@test.com"}); w.Code != http.StatusOK {
+		t.Fatalf("忘记密码应返回200: %d", w.Code)
+	}
+	resetToken := lastToken("NOFX 重置密码")
+
+	if stored, _ := s.database.LookupEmailToken(resetToken, "password_reset", time.Now()); stored != reg.UserID {
+		t.Fatalf("重置令牌应对应注册用户")
+	}
+	if w := post("/api/reset-password", map[string]string{"token": resetToken, "otp_code": "000000", "new_password": "newsecret"}); w.Code != http.StatusBadRequest {
+		t.Errorf("OTP错误应返回400，实际 %d", w.Code)
+	}
+	// OTP 错误不消耗令牌
+	if w := post("/api/reset-password", map[string]string{"token": resetToken, "otp_code": otpCode(), "new_password": "newsecret"}); w.Code != http.StatusOK {
+		t.Fatalf("令牌和OTP正确时应重置成功: %d %s", w.Code, w.Body.String())
+	}
+	if w := post("/api/reset-password", map[string]string{"token": resetToken, "otp_code": otpCode(), "new_password": "another"}); w.Code != http.StatusBadRequest {
+		t.Errorf("重置令牌只能使用一次，实际 %d", w.Code)
+	}
+	if w := post("/api/login", map[string]string{"email": "new@test.com", "password": "newsecret"}); w.Code != http.StatusOK {
+		t.Errorf("应能使用新密码登录: %d %s", w.Code, w.Body.String())
+	}
+
+	// 按邮箱限制发信频率
+	if err := s.database.SetSystemConfig("email_send_per_hour", "2"); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	sent = len(sender.Messages())
+	for i := 0; i < 3; i++ {
+		post("/api/forgot-password", map[string]string{"email": "new@test.com"})
+	}
+	if got := len(sender.Messages()) - sent; got != 1 {
+		t.Errorf("每小时限制2封时应只再发送1封，实际 %d", got)
+	}
+}
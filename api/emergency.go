@@ -0,0 +1,184 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/trader"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// emergencyStopRequest 紧急停止请求（close_positions 也可通过 query 参数传入）
+type emergencyStopRequest struct {
+	ClosePositions bool `json:"close_positions"`
+}
+
+// emergencyStopResult 单个交易员的紧急停止结果
+type emergencyStopResult struct {
+	TraderID        string                       `json:"trader_id"`
+	Name            string                       `json:"name"`
+	UserID          string                       `json:"user_id,omitempty"`
+	WasRunning      bool                         `json:"was_running"`
+	Positions       []trader.PositionCloseResult `json:"positions,omitempty"`
+	FailedPositions int                          `json:"failed_positions"`
+	Error           string                       `json:"error,omitempty"`
+}
+
+// bindEmergencyStopRequest 解析紧急停止参数（请求体可为空）
+func bindEmergencyStopRequest(c *gin.Context) (emergencyStopRequest, error) {
+	var req emergencyStopRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return req, err
+		}
+	}
+	if c.Query("close_positions") == "true" {
+		req.ClosePositions = true
+	}
+	return req, nil
+}
+
+// handleEmergencyStop 紧急停止当前用户的所有交易员，可选市价平掉全部持仓
+func (s *Server) handleEmergencyStop(c *gin.Context) {
+	userID := c.GetString("user_id")
+	req, err := bindEmergencyStopRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	records, err := s.database.GetTraders(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
+		return
+	}
+
+	requestLogf(c, "🚨 用户 %s 触发紧急停止（平仓: %v），共 %d 个交易员", userID, req.ClosePositions, len(records))
+	results := s.emergencyStopTraders(records, req.ClosePositions)
+	s.audit(c, userID, auditEmergencyStop, "", gin.H{"close_positions": req.ClosePositions, "traders": len(records)})
+	c.JSON(http.StatusOK, emergencyStopResponse(results))
+}
+
+// handleAdminEmergencyStop 紧急停止所有用户的交易员（维护窗口使用）
+func (s *Server) handleAdminEmergencyStop(c *gin.Context) {
+	req, err := bindEmergencyStopRequest(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userIDs, err := s.database.GetAllUsers()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取用户列表失败"})
+		return
+	}
+	var records []*config.TraderRecord
+	for _, userID := range userIDs {
+		traders, err := s.database.GetTraders(userID)
+		if err != nil {
+			requestLogf(c, "⚠️ 获取用户 %s 的交易员失败: %v", userID, err)
+			continue
+		}
+		records = append(records, traders...)
+	}
+
+	adminID := c.GetString("user_id")
+	requestLogf(c, "🚨 管理员 %s 触发全局紧急停止（平仓: %v），共 %d 个交易员", adminID, req.ClosePositions, len(records))
+	results := s.emergencyStopTraders(records, req.ClosePositions)
+	stopped := make([]string, 0, len(results))
+	for i := range results {
+		results[i].UserID = records[i].UserID
+		if results[i].WasRunning {
+			stopped = append(stopped, results[i].TraderID)
+		}
+	}
+	s.audit(c, adminID, auditEmergencyStop, "", gin.H{
+		"scope":           "all",
+		"close_positions": req.ClosePositions,
+		"traders":         len(records),
+		"stopped_traders": stopped,
+	})
+	c.JSON(http.StatusOK, emergencyStopResponse(results))
+}
+
+// emergencyStopTraders 并发停止交易员并等待其当前周期结束，按需平仓，返回与 records 顺序一致的结果
+func (s *Server) emergencyStopTraders(records []*config.TraderRecord, closePositions bool) []emergencyStopResult {
+	results := make([]emergencyStopResult, len(records))
+	var wg sync.WaitGroup
+	for i, record := range records {
+		wg.Add(1)
+		go func(i int, record *config.TraderRecord) {
+			defer wg.Done()
+			results[i] = s.emergencyStopTrader(record, closePositions)
+		}(i, record)
+	}
+	wg.Wait()
+	return results
+}
+
+// emergencyStopTrader 停止单个交易员（Stop 会等待进行中的交易周期结束，返回后不会再触发新周期）
+func (s *Server) emergencyStopTrader(record *config.TraderRecord, closePositions bool) emergencyStopResult {
+	result := emergencyStopResult{TraderID: record.ID, Name: record.Name, WasRunning: record.IsRunning}
+
+	at, err := s.traderManager.GetTrader(record.ID)
+	if err == nil {
+		result.WasRunning = at.IsRunning()
+		at.Stop()
+	}
+
+	if record.IsRunning || result.WasRunning {
+		if err := s.database.UpdateTraderStatus(record.UserID, record.ID, false); err != nil {
+			log.Printf("⚠️ 更新交易员 %s 状态失败: %v", record.ID, err)
+		}
+	}
+
+	if !closePositions {
+		return result
+	}
+	if at == nil {
+		result.Error = "交易员未加载，无法平仓"
+		return result
+	}
+
+	positions, err := at.CloseAllPositions()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Positions = positions
+	for _, p := range positions {
+		if !p.Closed {
+			result.FailedPositions++
+		}
+	}
+	log.Printf("🚨 交易员 %s 已紧急停止，平仓 %d 个（失败 %d 个）", record.Name, len(positions)-result.FailedPositions, result.FailedPositions)
+	return result
+}
+
+// emergencyStopResponse 汇总紧急停止结果
+func emergencyStopResponse(results []emergencyStopResult) gin.H {
+	stopped, failedPositions, failedTraders := 0, 0, 0
+	for _, r := range results {
+		if r.WasRunning {
+			stopped++
+		}
+		failedPositions += r.FailedPositions
+		if r.Error != "" {
+			failedTraders++
+		}
+	}
+
+	message := "所有交易员已停止"
+	if failedPositions > 0 || failedTraders > 0 {
+		message = "所有交易员已停止，但部分持仓平仓失败，请重试"
+	}
+	return gin.H{
+		"message":          message,
+		"stopped_traders":  stopped,
+		"failed_positions": failedPositions,
+		"failed_traders":   failedTraders,
+		"traders":          results,
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEmergencyStop_OwnTradersOnly 测试紧急停止只影响当前用户的交易员，管理员版本影响所有用户
+func TestEmergencyStop_OwnTradersOnly(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	if err := s.database.SetUserRole("user-a", config.UserRoleAdmin); err != nil {
+		t.Fatalf("设置管理员失败: %v", err)
+	}
+	for _, tr := range []struct{ userID, traderID string }{{"user-a", "trader-a"}, {"user-b", "trader-b"}} {
+		if err := s.database.UpdateTraderStatus(tr.userID, tr.traderID, true); err != nil {
+			t.Fatalf("设置运行状态失败: %v", err)
+		}
+	}
+
+	s.auditLog = newAuditLogger(s.database)
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	isRunning := func(userID, traderID string) bool {
+		traders, err := s.database.GetTraders(userID)
+		if err != nil || len(traders) == 0 {
+			t.Fatalf("获取交易员失败: %v", err)
+		}
+		return traders[0].IsRunning
+	}
+	do := func(path, userID, role string) map[string]interface{} {
+		token, _ := auth.GenerateJWT(userID, userID+"@test.com", role)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s 返回 %d: %s", path, w.Code, w.Body.String())
+		}
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp
+	}
+
+	resp := do("/api/emergency-stop", "user-b", "")
+	if traders := resp["traders"].([]interface{}); len(traders) != 1 {
+		t.Fatalf("应只处理自己的1个交易员，实际 %v", traders)
+	}
+	if isRunning("user-b", "trader-b") {
+		t.Error("trader-b 应已标记为停止")
+	}
+	if !isRunning("user-a", "trader-a") {
+		t.Error("trader-a 不应受 user-b 紧急停止影响")
+	}
+
+	// 未加载到内存的交易员无法平仓，应在结果中报告
+	resp = do("/api/admin/emergency-stop?close_positions=true", "user-a", config.UserRoleAdmin)
+	if isRunning("user-a", "trader-a") {
+		t.Error("管理员紧急停止后 trader-a 应已标记为停止")
+	}
+	if resp["failed_traders"].(float64) != 2 {
+		t.Errorf("未加载的交易员应报告平仓失败，实际 %v", resp["failed_traders"])
+	}
+
+	// 全局紧急停止记录管理员的审计日志，包含被停止的交易员
+	s.auditLog.Close()
+	entries, _, _ := s.database.ListAuditEntries("user-a", config.AuditLogFilter{Action: auditEmergencyStop, Limit: 10})
+	if len(entries) != 1 || !strings.Contains(entries[0].Summary, `"stopped_traders":["trader-a"]`) {
+		t.Errorf("应记录全局紧急停止的审计日志: %+v", entries)
+	}
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"fmt"
+	"nofx/config"
+	"nofx/logger"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultEquityRange 未指定时间范围时默认返回最近7天
+	defaultEquityRange = 7 * 24 * time.Hour
+
+	// equityHistoryMaxRecords 单次从决策记录读取的最大条数（时间范围内超出时均匀抽样）
+	equityHistoryMaxRecords = 10000
+)
+
+// equityResolutions 支持的降采样粒度
+var equityResolutions = map[string]time.Duration{
+	"raw": 0,
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"30m": 30 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// equityPoint 收益率历史数据点
+type equityPoint struct {
+	Timestamp        string  `json:"timestamp"`
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值（wallet + unrealized）
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+	TotalPnL         float64 `json:"total_pnl"`         // 总盈亏（相对初始余额）
+	TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
+	PositionCount    int     `json:"position_count"`    // 持仓数量
+	MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
+	CycleNumber      int     `json:"cycle_number"`      // 决策周期编号（来自权益快照时为0）
+}
+
+// equityQuery 收益率历史查询参数
+type equityQuery struct {
+	From       time.Time
+	To         time.Time
+	Resolution string        // 粒度名称（如 5m/1h/1d/raw）
+	Bucket     time.Duration // 粒度时长（0 表示不降采样）
+}
+
+// parseEquityQuery 解析 from/to/resolution 参数
+// from/to 支持 RFC3339、YYYY-MM-DD 或 Unix 时间戳（秒/毫秒）；resolution 未指定时按时间范围自动选择
+func parseEquityQuery(c *gin.Context, now time.Time) (*equityQuery, error) {
+	q := &equityQuery{To: now}
+
+	if raw := c.Query("to"); raw != "" {
+		t, err := parseEquityTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("无效的to参数: %w", err)
+		}
+		q.To = t
+	}
+
+	q.From = q.To.Add(-defaultEquityRange)
+	if raw := c.Query("from"); raw != "" {
+		t, err := parseEquityTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("无效的from参数: %w", err)
+		}
+		q.From = t
+	}
+
+	if !q.From.Before(q.To) {
+		return nil, fmt.Errorf("from必须早于to")
+	}
+
+	q.Resolution = strings.ToLower(c.Query("resolution"))
+	if q.Resolution == "" {
+		q.Resolution = autoEquityResolution(q.To.Sub(q.From))
+	}
+	bucket, ok := equityResolutions[q.Resolution]
+	if !ok {
+		return nil, fmt.Errorf("不支持的resolution: %s（可选 raw/1m/5m/15m/30m/1h/4h/1d）", q.Resolution)
+	}
+	q.Bucket = bucket
+
+	return q, nil
+}
+
+// parseEquityTime 解析时间参数
+func parseEquityTime(raw string) (time.Time, error) {
+	if n, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		// 大于 1e12 视为毫秒时间戳
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("2006-01-02", raw); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间 %q", raw)
+}
+
+// autoEquityResolution 根据时间范围自动选择粒度（控制在约数百个数据点）
+func autoEquityResolution(span time.Duration) string {
+	switch {
+	case span <= 24*time.Hour:
+		return "5m"
+	case span <= 3*24*time.Hour:
+		return "15m"
+	case span <= 14*24*time.Hour:
+		return "1h"
+	case span <= 60*24*time.Hour:
+		return "4h"
+	default:
+		return "1d"
+	}
+}
+
+// downsampleEquityRecords 过滤时间范围并按粒度降采样（每个时间桶保留最后一条记录）
+// records 需按时间正序排列
+func downsampleEquityRecords(records []*logger.DecisionRecord, q *equityQuery) []*logger.DecisionRecord {
+	result := make([]*logger.DecisionRecord, 0)
+	var lastBucket int64
+	for _, record := range records {
+		if record.Timestamp.Before(q.From) || !record.Timestamp.Before(q.To) {
+			continue
+		}
+
+		if q.Bucket <= 0 {
+			result = append(result, record)
+			continue
+		}
+
+		bucket := record.Timestamp.UnixNano() / int64(q.Bucket)
+		if len(result) > 0 && bucket == lastBucket {
+			// 同一时间桶内用更新的记录替换
+			result[len(result)-1] = record
+			continue
+		}
+		result = append(result, record)
+		lastBucket = bucket
+	}
+	return result
+}
+
+// downsampleEquitySnapshots 按粒度降采样权益快照（每个时间桶保留最后一条），snapshots 需按时间正序排列
+func downsampleEquitySnapshots(snapshots []*config.EquitySnapshot, q *equityQuery) []*config.EquitySnapshot {
+	if q.Bucket <= 0 {
+		return snapshots
+	}
+	result := make([]*config.EquitySnapshot, 0)
+	var lastBucket int64
+	for _, snapshot := range snapshots {
+		bucket := snapshot.Timestamp.UnixNano() / int64(q.Bucket)
+		if len(result) > 0 && bucket == lastBucket {
+			result[len(result)-1] = snapshot
+			continue
+		}
+		result = append(result, snapshot)
+		lastBucket = bucket
+	}
+	return result
+}
+
+// equityPointsFromSnapshots 将权益快照转换为收益率历史数据点
+func equityPointsFromSnapshots(snapshots []*config.EquitySnapshot) []equityPoint {
+	history := make([]equityPoint, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		history = append(history, equityPoint{
+			Timestamp:        snapshot.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			TotalEquity:      snapshot.TotalEquity,
+			AvailableBalance: snapshot.AvailableBalance,
+			TotalPnL:         snapshot.TotalPnL,
+			TotalPnLPct:      snapshot.TotalPnLPct,
+			PositionCount:    snapshot.PositionCount,
+			MarginUsedPct:    snapshot.MarginUsedPct,
+		})
+	}
+	return history
+}
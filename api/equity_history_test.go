@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"nofx/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newEquityQueryContext(query string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/equity-history"+query, nil)
+	return c
+}
+
+// TestParseEquityQuery 测试时间范围和粒度参数解析
+func TestParseEquityQuery(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+
+	// 默认最近7天，自动选择1h粒度
+	q, err := parseEquityQuery(newEquityQueryContext(""), now)
+	if err != nil {
+		t.Fatalf("解析默认参数失败: %v", err)
+	}
+	if !q.To.Equal(now) || !q.From.Equal(now.Add(-7*24*time.Hour)) || q.Resolution != "1h" {
+		t.Errorf("默认参数不正确: %+v", q)
+	}
+
+	q, err = parseEquityQuery(newEquityQueryContext("?from=2026-10-01&to=1791590400&resolution=1D"), now)
+	if err != nil {
+		t.Fatalf("解析参数失败: %v", err)
+	}
+	if !q.From.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) || q.To.Unix() != 1791590400 || q.Bucket != 24*time.Hour {
+		t.Errorf("参数解析不正确: %+v", q)
+	}
+
+	for _, bad := range []string{"?resolution=7m", "?from=yesterday", "?from=2026-10-10&to=2026-10-01"} {
+		if _, err := parseEquityQuery(newEquityQueryContext(bad), now); err == nil {
+			t.Errorf("%s 应返回错误", bad)
+		}
+	}
+}
+
+// TestDownsampleEquityRecords 测试范围过滤和每个时间桶保留最后一条
+func TestDownsampleEquityRecords(t *testing.T) {
+	start := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+	records := make([]*logger.DecisionRecord, 0)
+	// 每3分钟一条，共2小时
+	for i := 0; i < 40; i++ {
+		records = append(records, &logger.DecisionRecord{
+			Timestamp:   start.Add(time.Duration(i) * 3 * time.Minute),
+			CycleNumber: i + 1,
+		})
+	}
+
+	q := &equityQuery{From: start, To: start.Add(2 * time.Hour), Bucket: time.Hour}
+	got := downsampleEquityRecords(records, q)
+	if len(got) != 2 {
+		t.Fatalf("1h粒度应返回2个点，实际 %d", len(got))
+	}
+	// 00:57 和 01:57 是各自时间桶的最后一条
+	if got[0].CycleNumber != 20 || got[1].CycleNumber != 40 {
+		t.Errorf("应保留每个时间桶的最后一条记录，实际 cycle %d, %d", got[0].CycleNumber, got[1].CycleNumber)
+	}
+
+	// raw 粒度只做范围过滤（[from, to)）
+	q = &equityQuery{From: start.Add(30 * time.Minute), To: start.Add(time.Hour)}
+	got = downsampleEquityRecords(records, q)
+	if len(got) != 10 || got[0].CycleNumber != 11 {
+		t.Errorf("范围过滤不正确: len=%d", len(got))
+	}
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"nofx/config"
+	"nofx/notify"
+)
+
+// eventSubscriptionTTL 用户事件订阅缓存的有效期（增删改时立即失效，过期重新读取作为兜底）
+const eventSubscriptionTTL = time.Minute
+
+// webhookTarget 已启用的 Webhook 及其发送通道
+type webhookTarget struct {
+	webhook *config.Webhook
+	channel notify.Channel
+}
+
+// notifyTarget 已启用的通知通道
+type notifyTarget struct {
+	name    string
+	channel notify.Channel
+}
+
+// userSubscriptions 用户已启用的 Webhook 和通知通道
+type userSubscriptions struct {
+	webhooks []webhookTarget
+	channels []notifyTarget
+	loadedAt time.Time
+}
+
+// eventSubscriptions 按用户缓存事件订阅，避免在事件转发协程中每个事件都查询数据库、解析地址
+// （处理过慢会被事件总线断开）
+type eventSubscriptions struct {
+	mu    sync.Mutex
+	users map[string]*userSubscriptions
+}
+
+// get 返回用户的事件订阅，缓存不存在或已过期时调用 load 重新读取
+func (e *eventSubscriptions) get(userID string, now time.Time, load func(userID string) (*userSubscriptions, error)) (*userSubscriptions, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if subs, ok := e.users[userID]; ok && now.Sub(subs.loadedAt) < eventSubscriptionTTL {
+		return subs, nil
+	}
+	subs, err := load(userID)
+	if err != nil {
+		return nil, err
+	}
+	subs.loadedAt = now
+	if e.users == nil {
+		e.users = make(map[string]*userSubscriptions)
+	}
+	e.users[userID] = subs
+	return subs, nil
+}
+
+// invalidate 丢弃用户的缓存（Webhook、通知通道变更或账户删除后调用）
+func (e *eventSubscriptions) invalidate(userID string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.users, userID)
+}
+
+// userSubscriptions 获取用户已启用的 Webhook 和通知通道（带缓存）
+func (s *Server) userSubscriptions(userID string) (*userSubscriptions, error) {
+	return s.subscriptions.get(userID, time.Now(), s.loadUserSubscriptions)
+}
+
+// loadUserSubscriptions 从数据库读取用户已启用的 Webhook 和通知通道并创建发送通道（配置无效的跳过）
+func (s *Server) loadUserSubscriptions(userID string) (*userSubscriptions, error) {
+	webhooks, err := s.database.ListWebhooks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("读取Webhook失败: %w", err)
+	}
+	channels, err := s.database.ListNotificationChannels(userID)
+	if err != nil {
+		return nil, fmt.Errorf("读取通知通道失败: %w", err)
+	}
+
+	subs := &userSubscriptions{}
+	for _, w := range webhooks {
+		if !w.Enabled {
+			continue
+		}
+		channel, err := notify.NewChannel(notify.ChannelWebhook, notify.ChannelConfig{WebhookURL: w.URL, Secret: w.Secret}, nil)
+		if err != nil {
+			log.Printf("⚠️ Webhook %s 配置无效: %v", w.ID, err)
+			continue
+		}
+		subs.webhooks = append(subs.webhooks, webhookTarget{webhook: w, channel: channel})
+	}
+	for _, ch := range channels {
+		if !ch.Enabled {
+			continue
+		}
+		channel, err := notify.NewChannel(ch.Type, ch.Config, nil)
+		if err != nil {
+			log.Printf("⚠️ 通知通道 %s 配置无效: %v", ch.Name, err)
+			continue
+		}
+		subs.channels = append(subs.channels, notifyTarget{name: ch.Name, channel: channel})
+	}
+	return subs, nil
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEventSubscriptions_CachesUntilInvalidated 同一用户的订阅只读取一次，失效或过期后重新读取
+func TestEventSubscriptions_CachesUntilInvalidated(t *testing.T) {
+	var cache eventSubscriptions
+	loads := 0
+	load := func(userID string) (*userSubscriptions, error) {
+		loads++
+		return &userSubscriptions{}, nil
+	}
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := cache.get("user-a", now, load); err != nil {
+			t.Fatalf("读取订阅失败: %v", err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("缓存有效期内应只读取一次，实际 %d 次", loads)
+	}
+
+	cache.invalidate("user-a")
+	cache.get("user-a", now, load)
+	if loads != 2 {
+		t.Errorf("失效后应重新读取，实际 %d 次", loads)
+	}
+
+	cache.get("user-a", now.Add(eventSubscriptionTTL), load)
+	if loads != 3 {
+		t.Errorf("过期后应重新读取，实际 %d 次", loads)
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestExchangeAccounts_CreateReferenceDelete 测试新建交易所子账户、交易员引用子账户以及删除账户的限制
+func TestExchangeAccounts_CreateReferenceDelete(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	if err := s.database.CreateAIModel("user-a", "user-a_deepseek", "DeepSeek", "deepseek", true, "sk-test", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	stubExchangeTrader(s, &fakeBalanceTrader{}, nil)
+	gin.SetMode(gin.TestMode)
+
+	// 新建子账户，同时通过旧的 exchanges 字段更新默认账户
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/exchanges", strings.NewReader(`{
+		"exchanges": {"binance": {"enabled": true, "api_key": "main-key", "secret_key": "main-secret"}},
+		"accounts": [{"exchange_id": "binance", "label": "子账户", "enabled": true, "api_key": "sub-key", "secret_key": "sub-secret"}]
+	}`))
+	c.Set("user_id", "user-a")
+	s.handleUpdateExchangeConfigs(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("更新交易所配置状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		CreatedAccounts []string `json:"created_accounts"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.CreatedAccounts) != 1 {
+		t.Fatalf("应返回新建的账户ID: %s", w.Body.String())
+	}
+	subID := resp.CreatedAccounts[0]
+
+	exchanges, _ := s.database.GetExchanges("user-a")
+	if main := config.FindExchangeAccount(exchanges, "binance", ""); main == nil || main.APIKey != "main-key" {
+		t.Fatalf("默认账户未更新: %+v", main)
+	}
+	if sub := config.FindExchangeAccount(exchanges, "binance", subID); sub == nil || sub.APIKey != "sub-key" || sub.Label != "子账户" {
+		t.Fatalf("子账户未创建: %+v", sub)
+	}
+
+	// 更新不存在的账户返回404
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/exchanges", strings.NewReader(`{"accounts": [{"exchange_id": "binance", "account_id": "binance_missing"}]}`))
+	c.Set("user_id", "user-a")
+	s.handleUpdateExchangeConfigs(c)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("更新不存在的账户状态码 = %d, want 404", w.Code)
+	}
+
+	// 交易员引用子账户；他人的账户ID不可引用
+	if errs, err := s.validateTraderReferences("user-a", "user-a_deepseek", "binance", subID); err != nil || len(errs) != 0 {
+		t.Errorf("引用自己的子账户应通过: %+v, %v", errs, err)
+	}
+	if errs, _ := s.validateTraderReferences("user-b", "user-a_deepseek", "binance", subID); len(errs) == 0 || errs[len(errs)-1].Field != "exchange_account_id" {
+		t.Errorf("引用他人的子账户应返回 exchange_account_id 错误: %+v", errs)
+	}
+	if err := s.database.CreateTrader(&config.TraderRecord{ID: "trader-sub", UserID: "user-a", Name: "Sub", AIModelID: "user-a_deepseek", ExchangeID: "binance", ExchangeAccountID: subID}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	deleteAccount := func(accountID string) int {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodDelete, "/api/exchanges/binance/accounts/"+accountID, nil)
+		c.Params = gin.Params{{Key: "exchange_id", Value: "binance"}, {Key: "account_id", Value: accountID}}
+		c.Set("user_id", "user-a")
+		s.handleDeleteExchangeAccount(c)
+		return w.Code
+	}
+	if code := deleteAccount(config.DefaultExchangeAccountID); code != http.StatusBadRequest {
+		t.Errorf("删除默认账户状态码 = %d, want 400", code)
+	}
+	if code := deleteAccount(subID); code != http.StatusConflict {
+		t.Errorf("删除使用中的账户状态码 = %d, want 409", code)
+	}
+	if err := s.database.DeleteTrader("user-a", "trader-sub"); err != nil {
+		t.Fatalf("删除交易员失败: %v", err)
+	}
+	if code := deleteAccount(subID); code != http.StatusOK {
+		t.Errorf("删除未使用的账户状态码 = %d, want 200", code)
+	}
+	exchanges, _ = s.database.GetExchanges("user-a")
+	if config.FindExchangeAccount(exchanges, "binance", subID) != nil {
+		t.Error("账户删除后仍然存在")
+	}
+}
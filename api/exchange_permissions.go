@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangePermissionResult 保存密钥时检测到的交易所账户API权限
+type ExchangePermissionResult struct {
+	ExchangeID string `json:"exchange_id"`
+	AccountID  string `json:"account_id"`
+	*trader.APIPermissions
+}
+
+// detectExchangePermissions 检测已保存的交易所账户API密钥权限并记录到配置中
+// 凭证不完整或账户未启用时不检测（返回 nil）；检测超时或交易所不支持时记为 unknown
+func (s *Server) detectExchangePermissions(c *gin.Context, userID, exchangeID, accountID string) *ExchangePermissionResult {
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		requestLogf(c, "⚠️ 检测API权限时获取交易所配置失败: %v", err)
+		return nil
+	}
+	cfg := config.FindExchangeAccount(exchanges, exchangeID, accountID)
+	if cfg == nil || !cfg.Enabled || validateExchangeCredentials(exchangeID, cfg) != nil {
+		return nil
+	}
+
+	done := make(chan *trader.APIPermissions, 1)
+	go func() {
+		t, err := s.newExchangeTrader(exchangeID, cfg, userID)
+		if err != nil {
+			done <- &trader.APIPermissions{FuturesTrading: trader.TradePermissionUnknown, Detail: redactSecrets(err.Error(), cfg)}
+			return
+		}
+		done <- trader.DetectAPIPermissions(t)
+	}()
+
+	var permissions *trader.APIPermissions
+	select {
+	case permissions = <-done:
+	case <-time.After(exchangeTestTimeout):
+		permissions = &trader.APIPermissions{FuturesTrading: trader.TradePermissionUnknown, Detail: "检测API权限超时"}
+	}
+	permissions.Detail = redactSecrets(permissions.Detail, cfg)
+
+	if err := s.database.UpdateExchangeTradePermission(userID, exchangeID, cfg.AccountID, string(permissions.FuturesTrading)); err != nil {
+		requestLogf(c, "⚠️ 记录交易所 %s/%s API权限失败: %v", exchangeID, cfg.AccountID, err)
+	}
+	requestLogf(c, "🔐 交易所 %s/%s API权限: 合约交易=%s, 提现=%v, IP白名单=%v %s",
+		exchangeID, cfg.AccountID, permissions.FuturesTrading, permissions.Withdrawals, permissions.IPRestricted, permissions.Detail)
+
+	return &ExchangePermissionResult{ExchangeID: exchangeID, AccountID: cfg.AccountID, APIPermissions: permissions}
+}
+
+// permissionWarnings 根据检测到的权限生成提示（未开启合约交易时交易员下单会失败）
+func permissionWarnings(results []*ExchangePermissionResult) []string {
+	var warnings []string
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		name := result.ExchangeID
+		if result.AccountID != config.DefaultExchangeAccountID {
+			name = fmt.Sprintf("%s/%s", result.ExchangeID, result.AccountID)
+		}
+		if result.FuturesTrading == trader.TradePermissionDisabled {
+			warnings = append(warnings, fmt.Sprintf("交易所 %s 的API密钥未开启合约交易权限，可以查看余额但交易员下单会失败，请在交易所开启合约交易权限", name))
+		}
+		if result.Withdrawals {
+			warnings = append(warnings, fmt.Sprintf("交易所 %s 的API密钥开启了提现权限，建议关闭", name))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/config"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakePermissionTrader 返回固定API权限的测试 trader
+type fakePermissionTrader struct {
+	trader.Trader
+	permissions *trader.APIPermissions
+}
+
+func (f *fakePermissionTrader) GetAPIPermissions() (*trader.APIPermissions, error) {
+	return f.permissions, nil
+}
+
+// TestUpdateExchangeConfigs_ReadOnlyKeyWarning 测试保存未开启合约交易权限的密钥时返回警告并标记交易所配置
+func TestUpdateExchangeConfigs_ReadOnlyKeyWarning(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	s.traderFactory = func(exchangeID string, cfg *config.ExchangeConfig, userID string) (trader.Trader, error) {
+		if exchangeID == "binance" {
+			return &fakePermissionTrader{permissions: &trader.APIPermissions{FuturesTrading: trader.TradePermissionDisabled}}, nil
+		}
+		return &fakeBalanceTrader{}, nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/exchanges", strings.NewReader(`{"exchanges": {
+		"binance": {"enabled": true, "api_key": "read-only-key", "secret_key": "secret"},
+		"bybit": {"enabled": true, "api_key": "bybit-key", "secret_key": "secret"}
+	}}`))
+	c.Set("user_id", "user-a")
+	s.handleUpdateExchangeConfigs(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Permissions []ExchangePermissionResult `json:"permissions"`
+		Warnings    []string                   `json:"warnings"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(resp.Permissions) != 2 {
+		t.Fatalf("应返回2个账户的检测结果: %s", w.Body.String())
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0], "binance") {
+		t.Errorf("只有币安应提示未开启合约交易权限: %v", resp.Warnings)
+	}
+
+	exchanges, _ := s.database.GetExchanges("user-a")
+	if got := config.FindExchangeAccount(exchanges, "binance", "").TradePermission; got != string(trader.TradePermissionDisabled) {
+		t.Errorf("binance trade_permission = %s, want disabled", got)
+	}
+	// 不提供权限查询的交易所标记为 unknown
+	if got := config.FindExchangeAccount(exchanges, "bybit", "").TradePermission; got != string(trader.TradePermissionUnknown) {
+		t.Errorf("bybit trade_permission = %s, want unknown", got)
+	}
+
+	// 更换密钥后重新检测前恢复为 unknown
+	if err := s.database.UpdateExchange("user-a", "binance", true, "new-key", "", false, "", "", "", ""); err != nil {
+		t.Fatalf("更新密钥失败: %v", err)
+	}
+	exchanges, _ = s.database.GetExchanges("user-a")
+	if got := config.FindExchangeAccount(exchanges, "binance", "").TradePermission; got != string(trader.TradePermissionUnknown) {
+		t.Errorf("更换密钥后 trade_permission = %s, want unknown", got)
+	}
+}
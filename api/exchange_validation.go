@@ -0,0 +1,215 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/trader"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exchangeTestTimeout 测试交易所连接的超时时间
+var exchangeTestTimeout = 10 * time.Second
+
+// TestExchangeConnectionRequest 测试交易所连接的请求（留空的字段使用已保存的配置）
+type TestExchangeConnectionRequest struct {
+	AccountID             string `json:"account_id"` // 交易所账户ID（为空时使用默认账户）
+	APIKey                string `json:"api_key"`
+	SecretKey             string `json:"secret_key"`
+	Testnet               *bool  `json:"testnet"`
+	HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr"`
+	AsterUser             string `json:"aster_user"`
+	AsterSigner           string `json:"aster_signer"`
+	AsterPrivateKey       string `json:"aster_private_key"`
+}
+
+// exchangeTraderFunc 根据交易所配置创建临时 trader
+type exchangeTraderFunc func(exchangeID string, cfg *config.ExchangeConfig, userID string) (trader.Trader, error)
+
+// newExchangeTrader 创建临时 trader（测试时使用注入的 Server.traderFactory）
+func (s *Server) newExchangeTrader(exchangeID string, cfg *config.ExchangeConfig, userID string) (trader.Trader, error) {
+	if s.traderFactory != nil {
+		return s.traderFactory(exchangeID, cfg, userID)
+	}
+	return defaultExchangeTrader(exchangeID, cfg, userID)
+}
+
+// defaultExchangeTrader 根据交易所类型创建真实的 trader（构造函数不修改账户持仓模式等设置）
+func defaultExchangeTrader(exchangeID string, cfg *config.ExchangeConfig, userID string) (trader.Trader, error) {
+	switch exchangeID {
+	case "binance":
+		return trader.NewFuturesTrader(cfg.APIKey, cfg.SecretKey, userID, cfg.Testnet), nil
+	case "bybit":
+		return trader.NewBybitTrader(cfg.APIKey, cfg.SecretKey, cfg.Testnet), nil
+	case "hyperliquid":
+		return trader.NewHyperliquidTrader(
+			cfg.APIKey, // private key
+			cfg.HyperliquidWalletAddr,
+			cfg.Testnet,
+		)
+	case "aster":
+		return trader.NewAsterTrader(cfg.AsterUser, cfg.AsterSigner, cfg.AsterPrivateKey)
+	default:
+		return nil, fmt.Errorf("不支持的交易所类型: %s", exchangeID)
+	}
+}
+
+// handleTestExchangeConnection 使用提交的（或已保存的）凭证查询一次余额，验证API密钥是否可用
+func (s *Server) handleTestExchangeConnection(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exchangeID := c.Param("exchange_id")
+
+	var req TestExchangeConnectionRequest
+	if err := s.bindSensitiveJSON(c, "交易所测试凭证", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 以已保存的配置为基础，用提交的字段覆盖
+	cfg := &config.ExchangeConfig{ID: exchangeID}
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
+		return
+	}
+	if saved := config.FindExchangeAccount(exchanges, exchangeID, req.AccountID); saved != nil {
+		copied := *saved
+		cfg = &copied
+	}
+	req.applyTo(cfg)
+
+	if err := validateExchangeCredentials(exchangeID, cfg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+
+	type testResult struct {
+		balance *trader.Balance
+		err     error
+	}
+	done := make(chan testResult, 1)
+	go func() {
+		t, err := s.newExchangeTrader(exchangeID, cfg, userID)
+		if err != nil {
+			done <- testResult{err: err}
+			return
+		}
+		balance, err := trader.FetchBalance(t)
+		done <- testResult{balance: balance, err: err}
+	}()
+
+	var result testResult
+	select {
+	case result = <-done:
+	case <-time.After(exchangeTestTimeout):
+		requestLogf(c, "⚠️ 测试交易所 %s 连接超时 (UserID: %s)", exchangeID, userID)
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"success":    false,
+			"error_code": "timeout",
+			"error":      fmt.Sprintf("连接交易所超时（%v），请检查网络或稍后重试", exchangeTestTimeout),
+		})
+		return
+	}
+
+	if result.err != nil {
+		code, message := classifyExchangeError(result.err)
+		detail := redactSecrets(result.err.Error(), cfg)
+		requestLogf(c, "❌ 测试交易所 %s 连接失败 (UserID: %s): %s", exchangeID, userID, detail)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":    false,
+			"error_code": code,
+			"error":      message,
+			"detail":     detail,
+		})
+		return
+	}
+
+	equity := result.balance.Equity()
+	requestLogf(c, "✅ 测试交易所 %s 连接成功 (UserID: %s)，净值: %.2f USDT", exchangeID, userID, equity)
+	c.JSON(http.StatusOK, gin.H{
+		"success":           true,
+		"equity":            equity,
+		"available_balance": result.balance.AvailableBalance,
+	})
+}
+
+// applyTo 将请求中非空的字段覆盖到配置上
+func (r *TestExchangeConnectionRequest) applyTo(cfg *config.ExchangeConfig) {
+	override := func(target *string, value string) {
+		if value = strings.TrimSpace(value); value != "" {
+			*target = value
+		}
+	}
+	override(&cfg.APIKey, r.APIKey)
+	override(&cfg.SecretKey, r.SecretKey)
+	override(&cfg.HyperliquidWalletAddr, r.HyperliquidWalletAddr)
+	override(&cfg.AsterUser, r.AsterUser)
+	override(&cfg.AsterSigner, r.AsterSigner)
+	override(&cfg.AsterPrivateKey, r.AsterPrivateKey)
+	if r.Testnet != nil {
+		cfg.Testnet = *r.Testnet
+	}
+}
+
+// validateExchangeCredentials 检查各交易所必填的凭证字段
+func validateExchangeCredentials(exchangeID string, cfg *config.ExchangeConfig) error {
+	switch exchangeID {
+	case "binance", "bybit":
+		if cfg.APIKey == "" || cfg.SecretKey == "" {
+			return fmt.Errorf("请填写API Key和Secret Key")
+		}
+	case "hyperliquid":
+		if cfg.APIKey == "" || cfg.HyperliquidWalletAddr == "" {
+			return fmt.Errorf("请填写私钥和主钱包地址")
+		}
+	case "aster":
+		if cfg.AsterUser == "" || cfg.AsterSigner == "" || cfg.AsterPrivateKey == "" {
+			return fmt.Errorf("请填写Aster的User、Signer和私钥")
+		}
+	default:
+		return fmt.Errorf("不支持的交易所类型: %s", exchangeID)
+	}
+	return nil
+}
+
+// classifyExchangeError 将交易所返回的错误归类为错误码和用户可读的提示
+func classifyExchangeError(err error) (code, message string) {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "code=-2015"), strings.Contains(msg, "retcode=10005"):
+		return "permission_denied", "API密钥无效、IP不在白名单中或未开启合约交易权限"
+	case strings.Contains(msg, "code=-2014"), strings.Contains(msg, "code=-2008"),
+		strings.Contains(msg, "retcode=10003"),
+		strings.Contains(msg, "api-key format invalid"), strings.Contains(msg, "invalid api-key"):
+		return "invalid_api_key", "API Key无效，请检查是否填写正确"
+	case strings.Contains(msg, "code=-1022"), strings.Contains(msg, "retcode=10004"), strings.Contains(msg, "signature"):
+		return "invalid_signature", "签名校验失败，请检查Secret Key或私钥是否正确"
+	case strings.Contains(msg, "code=-1021"), strings.Contains(msg, "retcode=10002"), strings.Contains(msg, "timestamp"):
+		return "clock_skew", "请求时间戳超出允许范围，请校准服务器时间"
+	case strings.Contains(msg, "whitelist"), strings.Contains(msg, "retcode=10010"), strings.Contains(msg, "ip address"):
+		return "ip_not_whitelisted", "当前服务器IP不在API白名单中"
+	case strings.Contains(msg, "permission"), strings.Contains(msg, "unauthorized"), strings.Contains(msg, "forbidden"):
+		return "permission_denied", "API密钥权限不足，请开启读取和合约交易权限"
+	case strings.Contains(msg, "does not exist"), strings.Contains(msg, "not found"):
+		return "account_not_found", "账户不存在，请检查钱包地址或用户地址"
+	case strings.Contains(msg, "private key"), strings.Contains(msg, "私钥"):
+		return "invalid_private_key", "私钥格式无效"
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "connection refused"), strings.Contains(msg, "no such host"):
+		return "network_error", "无法连接交易所，请检查网络"
+	default:
+		return "unknown", "连接交易所失败"
+	}
+}
+
+// redactSecrets 从错误信息中去除提交的密钥，避免回显
+func redactSecrets(message string, cfg *config.ExchangeConfig) string {
+	for _, secret := range []string{cfg.APIKey, cfg.SecretKey, cfg.AsterPrivateKey} {
+		if len(secret) >= 6 {
+			message = strings.ReplaceAll(message, secret, MaskSensitiveString(secret))
+		}
+	}
+	return message
+}
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeBalanceTrader 只实现 GetBalance 的测试 trader
+type fakeBalanceTrader struct {
+	trader.Trader
+	balance map[string]interface{}
+	err     error
+	delay   time.Duration
+}
+
+func (f *fakeBalanceTrader) GetBalance() (map[string]interface{}, error) {
+	time.Sleep(f.delay)
+	return f.balance, f.err
+}
+
+// stubExchangeTrader 向服务器注入返回 fake 的 trader 工厂，记录收到的配置
+func stubExchangeTrader(s *Server, fake *fakeBalanceTrader, got **config.ExchangeConfig) {
+	s.traderFactory = func(exchangeID string, cfg *config.ExchangeConfig, userID string) (trader.Trader, error) {
+		if got != nil {
+			*got = cfg
+		}
+		return fake, nil
+	}
+}
+
+// testExchangeConnection 调用测试连接接口并返回状态码和响应
+func testExchangeConnection(t *testing.T, s *Server, exchangeID, body string) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/exchanges/"+exchangeID+"/test", strings.NewReader(body))
+	c.Params = gin.Params{{Key: "exchange_id", Value: exchangeID}}
+	c.Set("user_id", "user-a")
+
+	s.handleTestExchangeConnection(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return w.Code, resp
+}
+
+// TestTestExchangeConnection_Success 测试成功时返回净值，且使用提交的凭证
+func TestTestExchangeConnection_Success(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	var got *config.ExchangeConfig
+	stubExchangeTrader(s, &fakeBalanceTrader{balance: map[string]interface{}{
+		"totalWalletBalance":    1000.0,
+		"totalUnrealizedProfit": -50.0,
+		"availableBalance":      800.0,
+	}}, &got)
+
+	code, resp := testExchangeConnection(t, s, "binance", `{"api_key":"new-key","secret_key":"new-secret"}`)
+	if code != http.StatusOK {
+		t.Fatalf("状态码 = %d, want 200, body=%v", code, resp)
+	}
+	if resp["success"] != true || resp["equity"] != 950.0 || resp["available_balance"] != 800.0 {
+		t.Errorf("响应不符合预期: %v", resp)
+	}
+	if got.APIKey != "new-key" || got.SecretKey != "new-secret" {
+		t.Errorf("应使用提交的凭证，实际 %s/%s", got.APIKey, got.SecretKey)
+	}
+}
+
+// TestTestExchangeConnection_FallbackToSaved 测试未提交的字段使用已保存的配置
+func TestTestExchangeConnection_FallbackToSaved(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	if err := s.database.UpdateExchange("user-a", "binance", true, "saved-key", "saved-secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("保存交易所配置失败: %v", err)
+	}
+	var got *config.ExchangeConfig
+	stubExchangeTrader(s, &fakeBalanceTrader{balance: map[string]interface{}{"totalWalletBalance": 100.0}}, &got)
+
+	code, resp := testExchangeConnection(t, s, "binance", `{"secret_key":"new-secret"}`)
+	if code != http.StatusOK {
+		t.Fatalf("状态码 = %d, want 200, body=%v", code, resp)
+	}
+	if got.APIKey != "saved-key" || got.SecretKey != "new-secret" {
+		t.Errorf("凭证合并错误: %s/%s", got.APIKey, got.SecretKey)
+	}
+}
+
+// TestTestExchangeConnection_MissingCredentials 测试缺少凭证时直接返回400
+func TestTestExchangeConnection_MissingCredentials(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	stubExchangeTrader(s, &fakeBalanceTrader{}, nil)
+
+	code, resp := testExchangeConnection(t, s, "binance", `{"api_key":"only-key"}`)
+	if code != http.StatusBadRequest || resp["success"] != false {
+		t.Errorf("缺少Secret Key应返回400，实际 %d %v", code, resp)
+	}
+}
+
+// TestTestExchangeConnection_ErrorRedacted 测试错误被分类且不回显密钥
+func TestTestExchangeConnection_ErrorRedacted(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	stubExchangeTrader(s, &fakeBalanceTrader{
+		err: errors.New("<APIError> code=-2015, msg=Invalid API-key, IP, or permissions for action, key=secretapikey123"),
+	}, nil)
+
+	code, resp := testExchangeConnection(t, s, "binance", `{"api_key":"secretapikey123","secret_key":"secretvalue456"}`)
+	if code != http.StatusBadRequest {
+		t.Fatalf("状态码 = %d, want 400", code)
+	}
+	if resp["error_code"] != "permission_denied" {
+		t.Errorf("error_code = %v, want permission_denied", resp["error_code"])
+	}
+	raw, _ := json.Marshal(resp)
+	if strings.Contains(string(raw), "secretapikey123") || strings.Contains(string(raw), "secretvalue456") {
+		t.Errorf("响应中不应包含密钥: %s", raw)
+	}
+}
+
+// TestTestExchangeConnection_Timeout 测试交易所无响应时返回504
+func TestTestExchangeConnection_Timeout(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	orig := exchangeTestTimeout
+	exchangeTestTimeout = 20 * time.Millisecond
+	t.Cleanup(func() { exchangeTestTimeout = orig })
+	stubExchangeTrader(s, &fakeBalanceTrader{delay: 200 * time.Millisecond}, nil)
+
+	code, resp := testExchangeConnection(t, s, "binance", `{"api_key":"k","secret_key":"s"}`)
+	if code != http.StatusGatewayTimeout || resp["error_code"] != "timeout" {
+		t.Errorf("应返回504超时，实际 %d %v", code, resp)
+	}
+}
+
+// TestClassifyExchangeError 测试交易所错误分类
+func TestClassifyExchangeError(t *testing.T) {
+	tests := []struct {
+		err  string
+		want string
+	}{
+		{"<APIError> code=-2014, msg=API-key format invalid.", "invalid_api_key"},
+		{"<APIError> code=-1022, msg=Signature for this request is not valid.", "invalid_signature"},
+		{"<APIError> code=-1021, msg=Timestamp for this request is outside of the recvWindow.", "clock_skew"},
+		{"<APIError> code=-2015, msg=Invalid API-key, IP, or permissions for action", "permission_denied"},
+		{"User or API Wallet 0xabc does not exist.", "account_not_found"},
+		{"dial tcp: lookup fapi.binance.com: no such host", "network_error"},
+		{"something else", "unknown"},
+	}
+	for _, tt := range tests {
+		if got, _ := classifyExchangeError(errors.New(tt.err)); got != tt.want {
+			t.Errorf("classifyExchangeError(%q) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
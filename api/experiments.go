@@ -0,0 +1,128 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/trader"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultExperimentLookback A/B实验对比默认统计的周期数
+const defaultExperimentLookback = 1000
+
+// validateExperimentSettings 校验A/B实验配置（开启时两组模板都必须存在且不能相同，比例0表示默认50%）
+func validateExperimentSettings(userID string, enabled bool, templateA, templateB string, splitRatio float64, mode string) []traderFieldError {
+	var errs []traderFieldError
+	if mode != "" && mode != trader.ExperimentModeAlternate && mode != trader.ExperimentModeRandom {
+		errs = append(errs, traderFieldError{"experiment_mode", "实验分组方式必须是 alternate 或 random"})
+	}
+	if splitRatio < 0 || splitRatio >= 1 {
+		errs = append(errs, traderFieldError{"split_ratio", "A组占比必须在0-1之间（不含1，0表示默认0.5）"})
+	}
+	if !enabled {
+		return errs
+	}
+
+	for _, field := range []struct{ name, template string }{{"template_a", templateA}, {"template_b", templateB}} {
+		if field.template == "" {
+			errs = append(errs, traderFieldError{field.name, "开启A/B实验时必须指定两组模板"})
+			continue
+		}
+		if _, err := decision.GetPromptTemplateForUser(userID, field.template); err != nil {
+			errs = append(errs, traderFieldError{field.name, fmt.Sprintf("提示词模板不存在: %s", field.template)})
+		}
+	}
+	if templateA != "" && templateA == templateB {
+		errs = append(errs, traderFieldError{"template_b", "A/B实验的两组模板不能相同"})
+	}
+	return errs
+}
+
+// withExperimentDefaults 为未填写的实验参数补上默认值（A组占比50%，轮流分配）
+func withExperimentDefaults(splitRatio float64, mode string) (float64, string) {
+	if splitRatio == 0 {
+		splitRatio = 0.5
+	}
+	if mode == "" {
+		mode = trader.ExperimentModeAlternate
+	}
+	return splitRatio, mode
+}
+
+// handleExperiments A/B实验对比：按决策记录中的分组标签统计两组模板的胜率、盈亏和盈亏比
+// 分组归属在决策时写入记录，中途关闭或修改实验不会改变历史周期的归属
+func (s *Server) handleExperiments(c *gin.Context) {
+	userID := c.GetString("user_id")
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	lookback := defaultExperimentLookback
+	if raw := c.Query("lookback"); raw != "" {
+		if lookback, err = strconv.Atoi(raw); err != nil || lookback <= 0 || lookback > 10000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "lookback 必须是1-10000之间的整数"})
+			return
+		}
+	}
+
+	record, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	decisionLogger := at.GetDecisionLogger()
+	stats, err := decisionLogger.GetStatistics()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取统计信息失败: %v", err)})
+		return
+	}
+	analysis, err := decisionLogger.AnalyzePerformance(lookback)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("分析历史表现失败: %v", err)})
+		return
+	}
+
+	// 两组都返回（没有交易的分组为空统计），便于前端直接对比
+	variants := analysis.VariantStats
+	if variants == nil {
+		variants = make(map[string]*logger.VariantPerformance)
+	}
+	cycles := stats.VariantCycles
+	if cycles == nil {
+		cycles = make(map[string]int)
+	}
+	for _, variant := range []string{trader.VariantA, trader.VariantB} {
+		if _, ok := variants[variant]; !ok {
+			variants[variant] = &logger.VariantPerformance{Variant: variant, Templates: []string{}}
+		}
+		if _, ok := cycles[variant]; !ok {
+			cycles[variant] = 0
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id": traderID,
+		"experiment": gin.H{
+			"enabled":     record.ExperimentEnabled,
+			"template_a":  record.TemplateA,
+			"template_b":  record.TemplateB,
+			"split_ratio": record.SplitRatio,
+			"mode":        record.ExperimentMode,
+		},
+		"lookback_cycles": lookback,
+		"cycles":          cycles,
+		"variants":        variants,
+	})
+}
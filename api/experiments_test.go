@@ -0,0 +1,65 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"nofx/config"
+	"nofx/decision"
+)
+
+// TestValidateExperimentSettings 开启实验时两组模板必须存在且不同
+func TestValidateExperimentSettings(t *testing.T) {
+	decision.SetUserPromptTemplate("exp-user", "exp_a", "A {{account_state}} {{market_data}}", 1)
+	decision.SetUserPromptTemplate("exp-user", "exp_b", "B {{account_state}} {{market_data}}", 1)
+	t.Cleanup(func() {
+		decision.RemoveUserPromptTemplate("exp-user", "exp_a")
+		decision.RemoveUserPromptTemplate("exp-user", "exp_b")
+	})
+
+	tests := []struct {
+		name       string
+		userID     string
+		enabled    bool
+		a, b       string
+		ratio      float64
+		mode       string
+		wantFields []string
+	}{
+		{"关闭时不校验模板", "exp-user", false, "", "missing", 0, "", nil},
+		{"合法配置", "exp-user", true, "exp_a", "exp_b", 0.3, "random", nil},
+		{"缺少模板", "exp-user", true, "exp_a", "", 0, "", []string{"template_b"}},
+		{"模板不存在", "exp-user", true, "missing", "exp_b", 0, "", []string{"template_a"}},
+		{"其他用户的模板不可用", "other-user", true, "exp_a", "exp_b", 0, "", []string{"template_a", "template_b"}},
+		{"两组相同", "exp-user", true, "exp_a", "exp_a", 0, "", []string{"template_b"}},
+		{"比例和方式", "exp-user", false, "", "", 1, "weekly", []string{"experiment_mode", "split_ratio"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := validateExperimentSettings(tt.userID, tt.enabled, tt.a, tt.b, tt.ratio, tt.mode)
+			var fields []string
+			for _, err := range errs {
+				fields = append(fields, err.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("fields = %v, want %v (%v)", fields, tt.wantFields, errs)
+			}
+		})
+	}
+}
+
+// TestApplyTraderUpdateLive_Experiment 运行中开启/关闭实验直接热更新
+func TestApplyTraderUpdateLive_Experiment(t *testing.T) {
+	old := &config.TraderRecord{Name: "A", ExperimentEnabled: true, TemplateA: "a", TemplateB: "b", SplitRatio: 0.5, ExperimentMode: "alternate"}
+	updated := *old
+	updated.ExperimentEnabled = false
+
+	at := &fakeLiveTrader{}
+	applied, _ := applyTraderUpdateLive(at, old, &updated)
+	if !reflect.DeepEqual(applied, []string{"experiment"}) {
+		t.Errorf("applied = %v, want [experiment]", applied)
+	}
+	if at.experiment.Enabled || at.experiment.TemplateA != "a" {
+		t.Errorf("实验配置未正确下发: %+v", at.experiment)
+	}
+}
@@ -0,0 +1,347 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"nofx/market"
+	"nofx/mcp"
+	"nofx/trader"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// healthCheckTimeout 单个组件检查的超时时间
+	healthCheckTimeout = 2 * time.Second
+	// marketStreamStaleAfter 行情流超过该时间无消息视为异常（组合流读取超时为60秒）
+	marketStreamStaleAfter = 90 * time.Second
+	// marketStreamConfirmGrace 连接超过该时间后仍有未确认的订阅视为降级（确认超时10秒并会重发一次）
+	marketStreamConfirmGrace = time.Minute
+	// aiProbeCacheTTL AI服务可达性检查结果的缓存时间
+	aiProbeCacheTTL = 60 * time.Second
+)
+
+// 组件健康状态
+const (
+	healthStatusOK       = "ok"
+	healthStatusDegraded = "degraded"
+	healthStatusDown     = "down"
+)
+
+// componentHealth 单个组件的检查结果
+type componentHealth struct {
+	Status    string      `json:"status"`
+	Message   string      `json:"message,omitempty"`
+	Details   interface{} `json:"details,omitempty"`
+	LatencyMs int64       `json:"latency_ms"`
+	Cached    bool        `json:"cached,omitempty"` // 是否为缓存结果
+}
+
+// healthCheck 组件检查函数
+type healthCheck func(ctx context.Context) componentHealth
+
+// aiProbeCache 缓存AI服务可达性检查结果，避免每次健康检查都请求外部服务
+type aiProbeCache struct {
+	mu        sync.Mutex
+	checkedAt time.Time
+	result    componentHealth
+}
+
+// handleDeepHealth 深度健康检查（仅管理员）：数据库、交易员、行情流和AI服务，全部正常返回200，否则返回503
+func (s *Server) handleDeepHealth(c *gin.Context) {
+	checks := map[string]healthCheck{
+		"database":      s.checkDatabaseHealth,
+		"traders":       s.checkTradersHealth,
+		"market_stream": s.checkMarketStreamHealth,
+		"ai_providers":  s.checkAIProvidersHealth,
+		"clock_skew":    s.checkClockSkewHealth,
+	}
+
+	components := runHealthChecks(c.Request.Context(), checks, healthCheckTimeout)
+
+	status := healthStatusOK
+	for _, comp := range components {
+		if comp.Status != healthStatusOK {
+			status = healthStatusDegraded
+			break
+		}
+	}
+
+	code := http.StatusOK
+	if status != healthStatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, gin.H{
+		"status":     status,
+		"time":       time.Now().Unix(),
+		"components": components,
+	})
+}
+
+// runHealthChecks 并发执行所有检查，超时未返回的组件标记为 down
+func runHealthChecks(parent context.Context, checks map[string]healthCheck, timeout time.Duration) map[string]componentHealth {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	type result struct {
+		name   string
+		health componentHealth
+	}
+	results := make(chan result, len(checks))
+	for name, check := range checks {
+		go func(name string, check healthCheck) {
+			start := time.Now()
+			h := check(ctx)
+			h.LatencyMs = time.Since(start).Milliseconds()
+			results <- result{name: name, health: h}
+		}(name, check)
+	}
+
+	components := make(map[string]componentHealth, len(checks))
+	for len(components) < len(checks) {
+		select {
+		case r := <-results:
+			components[r.name] = r.health
+		case <-ctx.Done():
+			for name := range checks {
+				if _, ok := components[name]; !ok {
+					components[name] = componentHealth{
+						Status:    healthStatusDown,
+						Message:   "检查超时",
+						LatencyMs: timeout.Milliseconds(),
+					}
+				}
+			}
+		}
+	}
+	return components
+}
+
+// checkDatabaseHealth 检查数据库连接
+func (s *Server) checkDatabaseHealth(ctx context.Context) componentHealth {
+	if s.database == nil {
+		return componentHealth{Status: healthStatusDown, Message: "数据库未初始化"}
+	}
+	if err := s.database.Ping(ctx); err != nil {
+		return componentHealth{Status: healthStatusDown, Message: fmt.Sprintf("数据库不可用: %v", err)}
+	}
+	return componentHealth{Status: healthStatusOK}
+}
+
+// clockSkewWarnings 汇总各交易员与交易所的时钟偏移，返回最大偏移和超过阈值的交易员
+func (s *Server) clockSkewWarnings() (int64, []gin.H) {
+	var maxSkewMs int64
+	warnings := make([]gin.H, 0)
+	if s.traderManager == nil {
+		return maxSkewMs, warnings
+	}
+	for id, at := range s.traderManager.GetAllTraders() {
+		skew, ok := at.ClockSkew()
+		if !ok {
+			continue
+		}
+		offset := skew.OffsetMs
+		if offset < 0 {
+			offset = -offset
+		}
+		if offset > maxSkewMs {
+			maxSkewMs = offset
+		}
+		if skew.Warning {
+			warnings = append(warnings, gin.H{
+				"trader_id": id,
+				"offset_ms": skew.OffsetMs,
+				"message":   fmt.Sprintf("本地时钟与交易所偏移 %dms，超过阈值 %v", skew.OffsetMs, trader.ClockSkewWarnThreshold),
+			})
+		}
+	}
+	return maxSkewMs, warnings
+}
+
+// checkClockSkewHealth 检查各交易员与交易所的时钟偏移，有交易员超过阈值时为降级
+func (s *Server) checkClockSkewHealth(ctx context.Context) componentHealth {
+	maxSkewMs, warnings := s.clockSkewWarnings()
+	details := gin.H{"max_clock_skew_ms": maxSkewMs, "warnings": warnings}
+	if len(warnings) > 0 {
+		return componentHealth{Status: healthStatusDegraded, Message: fmt.Sprintf("%d 个交易员的时钟偏移超过阈值", len(warnings)), Details: details}
+	}
+	return componentHealth{Status: healthStatusOK, Details: details}
+}
+
+// checkTradersHealth 统计已加载和运行中的交易员
+func (s *Server) checkTradersHealth(ctx context.Context) componentHealth {
+	if s.traderManager == nil {
+		return componentHealth{Status: healthStatusDown, Message: "交易员管理器未初始化"}
+	}
+	traders := s.traderManager.GetAllTraders()
+	running := 0
+	for _, at := range traders {
+		if isRunning, ok := at.GetStatus()["is_running"].(bool); ok && isRunning {
+			running++
+		}
+	}
+	return componentHealth{
+		Status: healthStatusOK,
+		Details: gin.H{
+			"loaded":  len(traders),
+			"running": running,
+		},
+	}
+}
+
+// checkMarketStreamHealth 检查行情WebSocket是否连接且持续有数据
+func (s *Server) checkMarketStreamHealth(ctx context.Context) componentHealth {
+	return evaluateStreamHealth(market.GetStreamHealth(), time.Now())
+}
+
+// evaluateStreamHealth 根据行情流状态和最近消息时间判断健康状态
+func evaluateStreamHealth(h market.StreamHealth, now time.Time) componentHealth {
+	details := gin.H{
+		"connected":        h.Connected,
+		"symbols":          h.Symbols,
+		"reconnecting":     h.Reconnecting,
+		"connections":      h.Connections,
+		"messages_per_sec": h.MessagesPerSec,
+		"dropped":          h.Dropped,
+		"reconnects":       h.Reconnects,
+		"subscribed":       h.Subscribed,
+		"unconfirmed":      len(h.Unconfirmed),
+	}
+	if !h.LastConnectedAt.IsZero() {
+		details["last_connected_at"] = h.LastConnectedAt.Unix()
+	}
+	if !h.LastMessageAt.IsZero() {
+		details["last_message_at"] = h.LastMessageAt.Unix()
+		details["last_message_age_s"] = int64(now.Sub(h.LastMessageAt).Seconds())
+	}
+
+	switch {
+	case !h.Started:
+		return componentHealth{Status: healthStatusDown, Message: "行情监控未启动", Details: details}
+	case !h.Connected && h.Reconnecting:
+		return componentHealth{Status: healthStatusDown, Message: "行情WebSocket重连中", Details: details}
+	case !h.Connected:
+		return componentHealth{Status: healthStatusDown, Message: "行情WebSocket未连接", Details: details}
+	case h.LastMessageAt.IsZero():
+		return componentHealth{Status: healthStatusDegraded, Message: "尚未收到行情消息", Details: details}
+	case now.Sub(h.LastMessageAt) > marketStreamStaleAfter:
+		return componentHealth{
+			Status:  healthStatusDown,
+			Message: fmt.Sprintf("行情流已 %v 无消息", now.Sub(h.LastMessageAt).Truncate(time.Second)),
+			Details: details,
+		}
+	case len(h.Unconfirmed) > 0 && now.Sub(h.LastConnectedAt) > marketStreamConfirmGrace:
+		details["unconfirmed_streams"] = h.Unconfirmed
+		return componentHealth{
+			Status:  healthStatusDegraded,
+			Message: fmt.Sprintf("%d 个行情流未收到订阅确认", len(h.Unconfirmed)),
+			Details: details,
+		}
+	}
+	return componentHealth{Status: healthStatusOK, Details: details}
+}
+
+// checkAIProvidersHealth 检查已启用AI服务的可达性（结果缓存 aiProbeCacheTTL）
+func (s *Server) checkAIProvidersHealth(ctx context.Context) componentHealth {
+	s.aiProbe.mu.Lock()
+	defer s.aiProbe.mu.Unlock()
+
+	if !s.aiProbe.checkedAt.IsZero() && time.Since(s.aiProbe.checkedAt) < aiProbeCacheTTL {
+		cached := s.aiProbe.result
+		cached.Cached = true
+		return cached
+	}
+
+	result := s.probeAIProviders(ctx)
+	// 超时导致的失败不缓存，下次重新检查
+	if ctx.Err() == nil {
+		s.aiProbe.checkedAt = time.Now()
+		s.aiProbe.result = result
+	}
+	return result
+}
+
+// probeAIProviders 并发请求各AI服务地址，只要收到HTTP响应即视为可达
+func (s *Server) probeAIProviders(ctx context.Context) componentHealth {
+	if s.database == nil {
+		return componentHealth{Status: healthStatusOK, Message: "未配置AI服务"}
+	}
+	models, err := s.database.GetEnabledAIProviderEndpoints()
+	if err != nil {
+		return componentHealth{Status: healthStatusDegraded, Message: fmt.Sprintf("获取AI配置失败: %v", err)}
+	}
+
+	urls := make(map[string]bool)
+	for _, m := range models {
+		if u := aiProviderProbeURL(m.Provider, m.CustomAPIURL); u != "" {
+			urls[u] = true
+		}
+	}
+	if len(urls) == 0 {
+		return componentHealth{Status: healthStatusOK, Message: "未配置AI服务"}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	endpoints := make(gin.H, len(urls))
+	unreachable := 0
+	for u := range urls {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			status := "ok"
+			if err := probeHTTP(ctx, u); err != nil {
+				status = err.Error()
+			}
+			mu.Lock()
+			endpoints[u] = status
+			if status != "ok" {
+				unreachable++
+			}
+			mu.Unlock()
+		}(u)
+	}
+	wg.Wait()
+
+	h := componentHealth{Status: healthStatusOK, Details: gin.H{"endpoints": endpoints}}
+	if unreachable > 0 {
+		h.Status = healthStatusDegraded
+		h.Message = fmt.Sprintf("%d/%d 个AI服务不可达", unreachable, len(urls))
+	}
+	return h
+}
+
+// aiProviderProbeURL 返回用于可达性检查的AI服务地址
+func aiProviderProbeURL(provider, customURL string) string {
+	if customURL != "" {
+		return strings.TrimSuffix(customURL, "#")
+	}
+	switch provider {
+	case "deepseek":
+		return mcp.DefaultDeepSeekBaseURL
+	case "qwen":
+		return mcp.DefaultQwenBaseURL
+	}
+	return ""
+}
+
+// probeHTTP 发送 HEAD 请求，任何HTTP响应（包括4xx）都说明服务可达
+func probeHTTP(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return fmt.Errorf("无效地址: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("不可达: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("服务异常: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"nofx/auth"
+	"nofx/config"
+	"nofx/market"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestEvaluateStreamHealth 测试行情流健康判断
+func TestEvaluateStreamHealth(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		health market.StreamHealth
+		want   string
+	}{
+		{"未启动", market.StreamHealth{}, healthStatusDown},
+		{"未连接", market.StreamHealth{Started: true}, healthStatusDown},
+		{"无消息", market.StreamHealth{Started: true, Connected: true}, healthStatusDegraded},
+		{"消息过期", market.StreamHealth{Started: true, Connected: true, LastMessageAt: now.Add(-2 * marketStreamStaleAfter)}, healthStatusDown},
+		{"正常", market.StreamHealth{Started: true, Connected: true, LastMessageAt: now.Add(-time.Second)}, healthStatusOK},
+		{"刚连接等待订阅确认", market.StreamHealth{Started: true, Connected: true, LastMessageAt: now, LastConnectedAt: now.Add(-time.Second), Unconfirmed: []string{"btcusdt@kline_3m"}}, healthStatusOK},
+		{"订阅未确认", market.StreamHealth{Started: true, Connected: true, LastMessageAt: now, LastConnectedAt: now.Add(-2 * marketStreamConfirmGrace), Unconfirmed: []string{"btcusdt@kline_3m"}}, healthStatusDegraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := evaluateStreamHealth(tt.health, now).Status; got != tt.want {
+				t.Errorf("状态 = %s, 期望 %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRunHealthChecks_Timeout 测试超时的组件被标记为 down 且不阻塞整体返回
+func TestRunHealthChecks_Timeout(t *testing.T) {
+	checks := map[string]healthCheck{
+		"fast": func(ctx context.Context) componentHealth { return componentHealth{Status: healthStatusOK} },
+		"slow": func(ctx context.Context) componentHealth {
+			time.Sleep(time.Second)
+			return componentHealth{Status: healthStatusOK}
+		},
+	}
+
+	start := time.Now()
+	components := runHealthChecks(context.Background(), checks, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("检查耗时 %v，应在超时后立即返回", elapsed)
+	}
+	if components["fast"].Status != healthStatusOK {
+		t.Errorf("fast 应为 ok，实际 %+v", components["fast"])
+	}
+	if components["slow"].Status != healthStatusDown {
+		t.Errorf("slow 应因超时为 down，实际 %+v", components["slow"])
+	}
+}
+
+// TestHandleDeepHealth 测试深度健康检查的组件输出、503状态和AI检查缓存
+func TestHandleDeepHealth(t *testing.T) {
+	var probes atomic.Int32
+	aiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes.Add(1)
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer aiServer.Close()
+
+	s := setupTraderAccessServer(t)
+	if err := s.database.CreateAIModel("user-a", "custom", "Custom", "deepseek", true, "key", aiServer.URL); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/health/deep", s.handleDeepHealth)
+
+	call := func() (int, map[string]componentHealth) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health/deep", nil))
+		var resp struct {
+			Components map[string]componentHealth `json:"components"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("解析响应失败: %v", err)
+		}
+		return w.Code, resp.Components
+	}
+
+	code, components := call()
+	// 测试环境未启动行情监控，整体应为503
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("行情流未启动时应返回503，实际 %d", code)
+	}
+	for _, name := range []string{"database", "traders", "ai_providers", "clock_skew"} {
+		if components[name].Status != healthStatusOK {
+			t.Errorf("%s 应为 ok，实际 %+v", name, components[name])
+		}
+	}
+	if components["market_stream"].Status != healthStatusDown {
+		t.Errorf("market_stream 应为 down，实际 %+v", components["market_stream"])
+	}
+
+	// 第二次请求使用缓存，不再访问AI服务
+	_, components = call()
+	if !components["ai_providers"].Cached {
+		t.Error("AI检查结果应来自缓存")
+	}
+	if n := probes.Load(); n != 1 {
+		t.Errorf("AI服务应只被探测1次，实际 %d 次", n)
+	}
+}
+
+// TestHandleHealth_PublicSummary 公开健康检查只返回时钟偏移告警数量，不包含交易员ID等详情
+func TestHandleHealth_PublicSummary(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/health", s.handleHealth)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/health", nil))
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp["clock_skew_warning_count"] != float64(0) {
+		t.Errorf("应返回时钟偏移告警数量: %s", w.Body.String())
+	}
+	if _, ok := resp["clock_skew_warnings"]; ok {
+		t.Errorf("公开接口不应返回各交易员的偏移详情: %s", w.Body.String())
+	}
+}
+
+// TestDeepHealthRequiresAdmin 深度健康检查会访问用户配置的AI地址，匿名和普通用户不可访问
+func TestDeepHealthRequiresAdmin(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	if err := s.database.SetUserRole("user-a", config.UserRoleAdmin); err != nil {
+		t.Fatalf("设置管理员失败: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	do := func(token string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/api/health/deep", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		s.router.ServeHTTP(w, req)
+		return w.Code
+	}
+	userToken, _ := auth.GenerateJWT("user-b", "user-b@test.com", "")
+	adminToken, _ := auth.GenerateJWT("user-a", "user-a@test.com", config.UserRoleAdmin)
+	if code := do(""); code != http.StatusUnauthorized {
+		t.Errorf("未登录应返回401，实际 %d", code)
+	}
+	if code := do(userToken); code != http.StatusForbidden {
+		t.Errorf("普通用户应返回403，实际 %d", code)
+	}
+	if code := do(adminToken); code == http.StatusUnauthorized || code == http.StatusForbidden {
+		t.Errorf("管理员应可访问，实际 %d", code)
+	}
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"nofx/config"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 默认登录锁定策略（可通过 system_config 中的 login_* 调整，立即生效）
+	defaultLoginMaxFailures   = 5  // 同一账号窗口内最多失败次数
+	defaultLoginIPMaxFailures = 20 // 同一IP窗口内最多失败次数
+	defaultLoginWindowMinutes = 15 // 失败计数窗口（分钟）
+	defaultLoginLockoutMinute = 15 // 锁定时长（分钟）
+)
+
+// loginSubject 登录失败计数的对象
+type loginSubject struct {
+	scope   string
+	subject string
+}
+
+// loginSubjects 本次登录尝试对应的计数对象：账号（小写邮箱，未知邮箱同样计数，避免泄露账号是否存在）和客户端IP
+func loginSubjects(c *gin.Context, email string) []loginSubject {
+	subjects := []loginSubject{{scope: config.LoginScopeIP, subject: c.ClientIP()}}
+	if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+		subjects = append(subjects, loginSubject{scope: config.LoginScopeAccount, subject: email})
+	}
+	return subjects
+}
+
+// loginLockoutPolicy 当前的登录锁定策略
+func (s *Server) loginLockoutPolicy(scope string) config.LoginLockoutPolicy {
+	sysConfig := s.database.SystemConfig()
+	maxFailures := sysConfig.Int("login_max_failures", defaultLoginMaxFailures)
+	if scope == config.LoginScopeIP {
+		maxFailures = sysConfig.Int("login_ip_max_failures", defaultLoginIPMaxFailures)
+	}
+	return config.LoginLockoutPolicy{
+		MaxFailures: maxFailures,
+		Window:      time.Duration(sysConfig.Int("login_failure_window_minutes", defaultLoginWindowMinutes)) * time.Minute,
+		Lockout:     time.Duration(sysConfig.Int("login_lockout_minutes", defaultLoginLockoutMinute)) * time.Minute,
+	}
+}
+
+// loginBlocked 账号或IP是否处于锁定或渐进延迟中（被拦截的尝试不校验凭证，也不增加失败计数）
+func (s *Server) loginBlocked(c *gin.Context, email string) bool {
+	now := time.Now()
+	for _, sub := range loginSubjects(c, email) {
+		throttle, err := s.database.GetLoginThrottle(sub.scope, sub.subject)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+			continue
+		}
+		if blocked, wait := throttle.Blocked(now); blocked {
+			requestLogf(c, "⏳ 登录尝试被拦截 [%s=%s]，剩余 %v", sub.scope, sub.subject, wait.Round(time.Second))
+			return true
+		}
+	}
+	return false
+}
+
+// recordLoginFailure 记录登录失败，触发锁定时记录日志和审计（userID 为空表示账号不存在）
+func (s *Server) recordLoginFailure(c *gin.Context, userID, email string) {
+	now := time.Now()
+	for _, sub := range loginSubjects(c, email) {
+		policy := s.loginLockoutPolicy(sub.scope)
+		throttle, err := s.database.RecordLoginFailure(sub.scope, sub.subject, now, policy)
+		if err != nil {
+			log.Printf("⚠️ %v", err)
+			continue
+		}
+		if !throttle.JustLocked {
+			continue
+		}
+		requestLogf(c, "🔒 登录失败 %d 次，锁定 [%s=%s] 至 %s", throttle.Failures, sub.scope, sub.subject, throttle.LockedUntil.Format(time.RFC3339))
+		auditUser := ""
+		if sub.scope == config.LoginScopeAccount {
+			auditUser = userID
+		}
+		s.audit(c, auditUser, auditLoginLockout, sub.scope+":"+sub.subject, gin.H{
+			"failures":     throttle.Failures,
+			"locked_until": throttle.LockedUntil,
+			"ip":           c.ClientIP(),
+		})
+	}
+}
+
+// resetLoginFailures 登录成功后清除账号的失败计数（IP计数到窗口结束自然过期，避免用自己的账号为其他账号的爆破解锁）
+func (s *Server) resetLoginFailures(email string) {
+	if _, err := s.database.ClearLoginThrottle(config.LoginScopeAccount, strings.ToLower(strings.TrimSpace(email))); err != nil {
+		log.Printf("⚠️ %v", err)
+	}
+}
+
+// handleAdminListLoginLockouts 列出当前被锁定的账号和IP
+func (s *Server) handleAdminListLoginLockouts(c *gin.Context) {
+	lockouts, err := s.database.ListLoginLockouts(time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"lockouts": lockouts})
+}
+
+// handleAdminClearLoginLockout 解除账号或IP的登录锁定（scope=account 时 subject 为邮箱，scope=ip 时为IP）
+func (s *Server) handleAdminClearLoginLockout(c *gin.Context) {
+	scope := c.Query("scope")
+	subject := strings.TrimSpace(c.Query("subject"))
+	if scope == config.LoginScopeAccount {
+		subject = strings.ToLower(subject)
+	}
+	if (scope != config.LoginScopeAccount && scope != config.LoginScopeIP) || subject == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "scope 必须为 account 或 ip，且 subject 不能为空"})
+		return
+	}
+
+	cleared, err := s.database.ClearLoginThrottle(scope, subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !cleared {
+		c.JSON(http.StatusNotFound, gin.H{"error": "没有该对象的登录失败记录"})
+		return
+	}
+
+	adminID := c.GetString("user_id")
+	s.audit(c, adminID, auditLoginUnlock, scope+":"+subject, nil)
+	requestLogf(c, "🔓 管理员 %s 解除登录锁定 [%s=%s]", adminID, scope, subject)
+	c.JSON(http.StatusOK, gin.H{"message": "已解除登录锁定"})
+}
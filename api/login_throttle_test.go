@@ -0,0 +1,88 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestLogin_LockoutAndAdminClear 连续登录失败后锁定账号（返回与密码错误相同的错误），管理员解除后可正常登录
+func TestLogin_LockoutAndAdminClear(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	hash, _ := auth.HashPassword("secret123")
+	if err := s.database.UpdateUserPassword("user-a", hash); err != nil {
+		t.Fatalf("设置密码失败: %v", err)
+	}
+	if err := s.database.UpdateUserOTPVerified("user-a", true); err != nil {
+		t.Fatalf("设置OTP失败: %v", err)
+	}
+	if err := s.database.SetSystemConfig("login_max_failures", "3"); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/login", s.handleLogin)
+	router.GET("/api/admin/login-lockouts", s.handleAdminListLoginLockouts)
+	router.DELETE("/api/admin/login-lockouts", s.handleAdminClearLoginLockout)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	login := func(email, password string) *httptest.ResponseRecorder {
+		return do(http.MethodPost, "/api/login", `{"email":"`+email+`","password":"`+password+`"}`)
+	}
+
+	wrong := login("user-a@test.com", "wrong")
+	if wrong.Code != http.StatusUnauthorized {
+		t.Fatalf("密码错误应返回401，实际 %d", wrong.Code)
+	}
+	// 补足失败次数（时间放在过去，跳过渐进延迟）
+	policy := s.loginLockoutPolicy(config.LoginScopeAccount)
+	for i := 0; i < 2; i++ {
+		if _, err := s.database.RecordLoginFailure(config.LoginScopeAccount, "user-a@test.com", time.Now().Add(-time.Minute), policy); err != nil {
+			t.Fatalf("记录登录失败失败: %v", err)
+		}
+	}
+
+	locked := login("USER-A@test.com", "secret123")
+	if locked.Code != wrong.Code || locked.Body.String() != wrong.Body.String() {
+		t.Errorf("锁定中应返回与密码错误相同的响应: %d %s", locked.Code, locked.Body.String())
+	}
+
+	if w := do(http.MethodGet, "/api/admin/login-lockouts", ""); !strings.Contains(w.Body.String(), `"subject":"user-a@test.com"`) {
+		t.Errorf("锁定列表应包含该账号: %s", w.Body.String())
+	}
+	if w := do(http.MethodDelete, "/api/admin/login-lockouts?scope=account&subject=User-A@test.com", ""); w.Code != http.StatusOK {
+		t.Fatalf("解除锁定应返回200: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodDelete, "/api/admin/login-lockouts?scope=account&subject=user-a@test.com", ""); w.Code != http.StatusNotFound {
+		t.Errorf("没有记录时应返回404，实际 %d", w.Code)
+	}
+	if w := do(http.MethodDelete, "/api/admin/login-lockouts?scope=user&subject=x", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("无效的 scope 应返回400，实际 %d", w.Code)
+	}
+
+	if w := login("user-a@test.com", "secret123"); w.Code != http.StatusOK {
+		t.Errorf("解除锁定后应能登录: %d %s", w.Code, w.Body.String())
+	}
+
+	// 不存在的账号同样计数，不泄露账号是否存在
+	if w := login("nobody@test.com", "wrong"); w.Code != wrong.Code || w.Body.String() != wrong.Body.String() {
+		t.Errorf("不存在的账号应返回与密码错误相同的响应: %d %s", w.Code, w.Body.String())
+	}
+	if throttle, _ := s.database.GetLoginThrottle(config.LoginScopeAccount, "nobody@test.com"); throttle == nil || throttle.Failures != 1 {
+		t.Errorf("不存在的账号也应记录失败次数: %+v", throttle)
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/market"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultLiquidationLimit 未指定交易对时默认返回强平金额最大的交易对数量
+	defaultLiquidationLimit = 20
+	// defaultStreamStatusLimit 行情状态默认返回的流明细数量（丢弃数多的在前）
+	defaultStreamStatusLimit = 50
+	// defaultTickerLimit 默认返回24小时成交额最高的交易对数量
+	defaultTickerLimit = 20
+	// maxTickerLimit 单次最多返回的交易对数量
+	maxTickerLimit = 500
+)
+
+// handleMarketTickers 24小时行情统计（公开）：GET /api/market/tickers?symbol=BTCUSDT 或 ?limit=20（按成交额排序）
+func (s *Server) handleMarketTickers(c *gin.Context) {
+	if symbol := c.Query("symbol"); symbol != "" {
+		ticker, ok := market.GetTicker(symbol)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "暂无该交易对的24小时行情"})
+			return
+		}
+		c.JSON(http.StatusOK, ticker)
+		return
+	}
+
+	limit := defaultTickerLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > maxTickerLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit 必须为 1-%d 的整数", maxTickerLimit)})
+			return
+		}
+		limit = n
+	}
+	c.JSON(http.StatusOK, gin.H{"tickers": market.TopByQuoteVolume(limit)})
+}
+
+// handleMarketStatus 行情流健康状态：GET /api/market/status?limit=50
+// 返回连接状态、消息速率、丢弃消息数和重连次数，以及各流明细（用于区分行情断流和交易员停止）
+func (s *Server) handleMarketStatus(c *gin.Context) {
+	limit := defaultStreamStatusLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须为非负整数"})
+			return
+		}
+		limit = n
+	}
+
+	status := market.GetMarketStatus()
+	if len(status.Streams) > limit {
+		status.Streams = status.Streams[:limit]
+	}
+	health := evaluateStreamHealth(status.StreamHealth, time.Now())
+	c.JSON(http.StatusOK, gin.H{
+		"status":  health.Status,
+		"message": health.Message,
+		"market":  status,
+	})
+}
+
+// handleMarketLiquidations 最近1m/5m/15m的强平量：GET /api/market/liquidations?symbol=BTCUSDT 或 ?limit=20
+func (s *Server) handleMarketLiquidations(c *gin.Context) {
+	if symbol := c.Query("symbol"); symbol != "" {
+		symbol = market.Normalize(symbol)
+		stats := market.GetLiquidationStats(symbol)
+		if stats == nil {
+			stats = &market.LiquidationStats{Symbol: symbol, Windows: []market.LiquidationWindow{}}
+		}
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+
+	limit := defaultLiquidationLimit
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须为正整数"})
+			return
+		}
+		limit = n
+	}
+	all := market.GetAllLiquidationStats()
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	c.JSON(http.StatusOK, gin.H{"symbols": all})
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/metrics"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// metricsConfig /metrics 暴露方式（system_config 中的 metrics_token / metrics_port，重启后生效）
+type metricsConfig struct {
+	Token string // 非空时在API端口暴露 /metrics，需携带 Authorization: Bearer <token>
+	Port  int    // 大于0时在独立端口暴露 /metrics（无认证，应仅对内网开放）
+}
+
+// loadMetricsConfig 从系统配置读取 metrics_token 和 metrics_port
+func (s *Server) loadMetricsConfig() metricsConfig {
+	if s.database == nil {
+		return metricsConfig{}
+	}
+	sysConfig := s.database.SystemConfig()
+	return metricsConfig{
+		Token: sysConfig.String("metrics_token", ""),
+		Port:  sysConfig.Int("metrics_port", 0),
+	}
+}
+
+// String 便于日志输出
+func (c metricsConfig) String() string {
+	var parts []string
+	if c.Token != "" {
+		parts = append(parts, "API端口 /metrics（Bearer token）")
+	}
+	if c.Port > 0 {
+		parts = append(parts, fmt.Sprintf("独立端口 :%d/metrics", c.Port))
+	}
+	if len(parts) == 0 {
+		return "未启用（配置 metrics_token 或 metrics_port 开启）"
+	}
+	return strings.Join(parts, "，")
+}
+
+// metricsMiddleware 记录每个请求的耗时（按路由模板统计，避免路径参数导致指标爆炸）
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.HTTPRequestDuration.Observe(time.Since(start).Seconds(),
+			c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+// metricsAuthMiddleware 校验 /metrics 的 Bearer token
+func metricsAuthMiddleware(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		got := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "无效的metrics token"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// startMetricsServer 在独立端口启动指标服务
+func (s *Server) startMetricsServer() {
+	if s.metrics.Port <= 0 {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	s.metricsServer = &http.Server{
+		Addr:    fmt.Sprintf(":%d", s.metrics.Port),
+		Handler: mux,
+	}
+	go func() {
+		if err := s.metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("❌ 指标服务启动失败: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestMetricsMiddleware_RecordsRouteTemplate 测试请求耗时按路由模板记录
+func TestMetricsMiddleware_RecordsRouteTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(metricsMiddleware())
+	router.GET("/api/traders/:id/config", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	before := metrics.HTTPRequestDuration.Count(http.MethodGet, "/api/traders/:id/config", "204")
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/traders/abc/config", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/traders/xyz/config", nil))
+
+	if got := metrics.HTTPRequestDuration.Count(http.MethodGet, "/api/traders/:id/config", "204"); got != before+2 {
+		t.Errorf("Count = %d, want %d", got, before+2)
+	}
+}
+
+// TestMetricsEndpoint_RequiresToken 测试 /metrics 需要正确的 Bearer token
+func TestMetricsEndpoint_RequiresToken(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	s.metrics = metricsConfig{Token: "metrics-secret"}
+	s.router = gin.New()
+	s.setupRoutes()
+
+	for _, tt := range []struct {
+		auth string
+		want int
+	}{
+		{"", http.StatusUnauthorized},
+		{"Bearer wrong", http.StatusUnauthorized},
+		{"Bearer metrics-secret", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if tt.auth != "" {
+			req.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		s.router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("Authorization=%q 状态码 = %d, want %d", tt.auth, w.Code, tt.want)
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), "# TYPE nofx_traders_running gauge") {
+			t.Errorf("响应缺少交易员指标:\n%s", w.Body.String())
+		}
+	}
+}
+
+// TestMetricsEndpoint_DisabledWithoutToken 测试未配置 token 时不暴露 /metrics
+func TestMetricsEndpoint_DisabledWithoutToken(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("状态码 = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/mcp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// aiModelTestTimeout 测试AI模型连接的超时时间
+var aiModelTestTimeout = 20 * time.Second
+
+// localModelTestTimeout 测试本地模型的超时时间（首次请求需要加载模型，耗时较长）
+var localModelTestTimeout = 120 * time.Second
+
+// AI模型请求参数的允许范围（超出时调整到边界并在响应中提示）
+const (
+	minAITemperature           = 0.0
+	maxAITemperature           = 2.0
+	minAIMaxTokens             = 256
+	maxAIMaxTokens             = 32768
+	minAIRequestTimeoutSeconds = 10
+	maxAIRequestTimeoutSeconds = 600
+	minAIContextTokens         = 4096
+	maxAIContextTokens         = 1048576
+)
+
+// TestAIModelRequest 测试AI模型的请求（留空的字段使用已保存的配置）
+type TestAIModelRequest struct {
+	APIKey          string `json:"api_key"`
+	CustomAPIURL    string `json:"custom_api_url"`
+	CustomModelName string `json:"custom_model_name"`
+}
+
+// aiModelConfigured 模型是否已配置可用的凭证：本地模型无需 API Key，只需要API地址
+func aiModelConfigured(model *config.AIModelConfig) bool {
+	if mcp.IsLocalProvider(model.Provider) {
+		return model.CustomAPIURL != ""
+	}
+	return model.APIKey != ""
+}
+
+// clampAIModelParams 把AI模型请求参数限制在允许范围内，返回调整说明（max_tokens/超时/上下文窗口为0表示使用默认值，不调整）
+func clampAIModelParams(modelID string, params *config.AIModelParams) []string {
+	var warnings []string
+	if t := params.Temperature; t != nil && (*t < minAITemperature || *t > maxAITemperature) {
+		clamped := min(max(*t, minAITemperature), maxAITemperature)
+		warnings = append(warnings, fmt.Sprintf("%s: temperature %g 超出范围 [%g, %g]，已调整为 %g", modelID, *t, minAITemperature, maxAITemperature, clamped))
+		params.Temperature = &clamped
+	}
+	if n := params.MaxTokens; n != 0 && (n < minAIMaxTokens || n > maxAIMaxTokens) {
+		params.MaxTokens = min(max(n, minAIMaxTokens), maxAIMaxTokens)
+		warnings = append(warnings, fmt.Sprintf("%s: max_tokens %d 超出范围 [%d, %d]，已调整为 %d", modelID, n, minAIMaxTokens, maxAIMaxTokens, params.MaxTokens))
+	}
+	if s := params.RequestTimeoutSeconds; s != 0 && (s < minAIRequestTimeoutSeconds || s > maxAIRequestTimeoutSeconds) {
+		params.RequestTimeoutSeconds = min(max(s, minAIRequestTimeoutSeconds), maxAIRequestTimeoutSeconds)
+		warnings = append(warnings, fmt.Sprintf("%s: request_timeout_seconds %d 超出范围 [%d, %d]，已调整为 %d", modelID, s, minAIRequestTimeoutSeconds, maxAIRequestTimeoutSeconds, params.RequestTimeoutSeconds))
+	}
+	if n := params.ContextTokens; n != 0 && (n < minAIContextTokens || n > maxAIContextTokens) {
+		params.ContextTokens = min(max(n, minAIContextTokens), maxAIContextTokens)
+		warnings = append(warnings, fmt.Sprintf("%s: context_tokens %d 超出范围 [%d, %d]，已调整为 %d", modelID, n, minAIContextTokens, maxAIContextTokens, params.ContextTokens))
+	}
+	return warnings
+}
+
+// isLocalModelID 根据模型ID判断是否为本地模型（ID 格式为 provider 或 userID_provider，与数据库推断规则一致）
+func isLocalModelID(modelID string) bool {
+	parts := strings.Split(modelID, "_")
+	return mcp.IsLocalProvider(parts[len(parts)-1])
+}
+
+// newTestAIClient 根据 provider 创建用于测试的AI客户端（不重试、短超时、少量token）
+func newTestAIClient(cfg *config.AIModelConfig) mcp.AIClient {
+	timeout := aiModelTestTimeout
+	if mcp.IsLocalProvider(cfg.Provider) {
+		timeout = localModelTestTimeout
+	}
+	opts := []mcp.ClientOption{
+		mcp.WithTimeout(timeout),
+		mcp.WithMaxRetries(1),
+		mcp.WithMaxTokens(16),
+	}
+
+	var client mcp.AIClient
+	switch cfg.Provider {
+	case mcp.ProviderLocal, mcp.ProviderOllama:
+		client = mcp.NewLocalClientWithOptions(opts...)
+	case "qwen":
+		client = mcp.NewQwenClientWithOptions(opts...)
+	case "deepseek":
+		client = mcp.NewDeepSeekClientWithOptions(opts...)
+	default:
+		client = mcp.NewClient(opts...)
+	}
+	client.SetAPIKey(cfg.APIKey, cfg.CustomAPIURL, cfg.CustomModelName)
+	return client
+}
+
+// handleTestAIModel 使用提交的（或已保存的）密钥发送一次最小对话请求，验证AI模型是否可用
+func (s *Server) handleTestAIModel(c *gin.Context) {
+	userID := c.GetString("user_id")
+	modelID := c.Param("model_id")
+
+	var req TestAIModelRequest
+	if err := s.bindSensitiveJSON(c, "模型测试凭证", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI模型配置失败"})
+		return
+	}
+	var cfg *config.AIModelConfig
+	for _, model := range models {
+		if model.ID == modelID || (cfg == nil && model.Provider == modelID) {
+			saved := *model
+			cfg = &saved
+		}
+	}
+	if cfg == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "AI模型配置不存在"})
+		return
+	}
+	req.applyTo(cfg)
+
+	if mcp.IsLocalProvider(cfg.Provider) {
+		if cfg.CustomAPIURL == "" || cfg.CustomModelName == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "本地模型需要填写API地址和模型名称"})
+			return
+		}
+	} else if cfg.APIKey == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "请填写API Key"})
+		return
+	} else if cfg.Provider != "qwen" && cfg.Provider != "deepseek" && (cfg.CustomAPIURL == "" || cfg.CustomModelName == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "自定义模型需要填写API地址和模型名称"})
+		return
+	}
+
+	client := newTestAIClient(cfg)
+	start := time.Now()
+	_, err = client.CallWithMessages("", "ping")
+	latency := time.Since(start)
+	if err != nil {
+		code, message := classifyAIModelError(err)
+		detail := err.Error()
+		if cfg.APIKey != "" {
+			detail = strings.ReplaceAll(detail, cfg.APIKey, MaskSensitiveString(cfg.APIKey))
+		}
+		requestLogf(c, "❌ 测试AI模型 %s 失败 (UserID: %s): %s", modelID, userID, detail)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success":    false,
+			"error_code": code,
+			"error":      message,
+			"detail":     detail,
+		})
+		return
+	}
+
+	model := cfg.CustomModelName
+	if reporter, ok := client.(mcp.UsageReporter); ok && reporter.LastUsage().Model != "" {
+		model = reporter.LastUsage().Model
+	}
+	requestLogf(c, "✅ 测试AI模型 %s 成功 (UserID: %s)，模型: %s，耗时 %v", modelID, userID, model, latency)
+	c.JSON(http.StatusOK, gin.H{
+		"success":    true,
+		"model":      model,
+		"latency_ms": latency.Milliseconds(),
+	})
+}
+
+// applyTo 将请求中非空的字段覆盖到配置上
+func (r *TestAIModelRequest) applyTo(cfg *config.AIModelConfig) {
+	if v := strings.TrimSpace(r.APIKey); v != "" {
+		cfg.APIKey = v
+	}
+	if v := strings.TrimSpace(r.CustomAPIURL); v != "" {
+		cfg.CustomAPIURL = v
+	}
+	if v := strings.TrimSpace(r.CustomModelName); v != "" {
+		cfg.CustomModelName = v
+	}
+}
+
+// classifyAIModelError 将AI接口返回的错误归类为错误码和用户可读的提示
+func classifyAIModelError(err error) (code, message string) {
+	if mcp.IsInsufficientBalanceError(err) {
+		return "insufficient_balance", "AI账户余额不足，请充值后重试"
+	}
+	if mcp.IsAuthError(err) {
+		return "invalid_api_key", "API Key无效或无权限访问该模型"
+	}
+	if mcp.IsRateLimitError(err) {
+		return "rate_limited", "请求过于频繁，请稍后重试"
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "status 401"), strings.Contains(msg, "status 403"),
+		strings.Contains(msg, "invalid api key"), strings.Contains(msg, "incorrect api key"),
+		strings.Contains(msg, "authentication"):
+		return "invalid_api_key", "API Key无效或无权限访问该模型"
+	case strings.Contains(msg, "status 402"):
+		return "insufficient_balance", "AI账户余额不足，请充值后重试"
+	case strings.Contains(msg, "status 404"), strings.Contains(msg, "model_not_found"),
+		strings.Contains(msg, "model not exist"), strings.Contains(msg, "does not exist"):
+		return "model_not_found", "模型不存在或API地址错误，请检查模型名称和自定义API地址"
+	case strings.Contains(msg, "status 429"):
+		return "rate_limited", "请求过于频繁，请稍后重试"
+	case strings.Contains(msg, "timeout"), strings.Contains(msg, "deadline exceeded"),
+		strings.Contains(msg, "connection refused"), strings.Contains(msg, "no such host"),
+		strings.Contains(msg, "发送请求失败"):
+		return "network_error", "无法连接AI服务，请检查网络或API地址"
+	default:
+		return "unknown", "AI模型调用失败"
+	}
+}
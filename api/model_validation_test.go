@@ -0,0 +1,218 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"nofx/config"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newFakeAIServer 创建模拟的 OpenAI 兼容接口，记录收到的 Authorization 头
+func newFakeAIServer(t *testing.T, status int, body string, gotAuth *string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if gotAuth != nil {
+			*gotAuth = r.Header.Get("Authorization")
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// testAIModel 调用测试模型接口并返回状态码和响应
+func testAIModel(t *testing.T, s *Server, modelID, body string) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/models/"+modelID+"/test", strings.NewReader(body))
+	c.Params = gin.Params{{Key: "model_id", Value: modelID}}
+	c.Set("user_id", "user-a")
+
+	s.handleTestAIModel(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return w.Code, resp
+}
+
+// TestTestAIModel_SuccessWithCustomURL 测试使用已保存的自定义地址和提交的密钥调用成功
+func TestTestAIModel_SuccessWithCustomURL(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	var gotAuth string
+	srv := newFakeAIServer(t, http.StatusOK,
+		`{"model":"my-model-0601","choices":[{"message":{"content":"pong"}}],"usage":{"prompt_tokens":1,"completion_tokens":1}}`, &gotAuth)
+	if err := s.database.UpdateAIModel("user-a", "deepseek", true, "saved-key", srv.URL, "my-model", nil); err != nil {
+		t.Fatalf("保存模型配置失败: %v", err)
+	}
+
+	code, resp := testAIModel(t, s, "deepseek", `{"api_key":"sk-new-key"}`)
+	if code != http.StatusOK || resp["success"] != true {
+		t.Fatalf("应测试成功，实际 %d %v", code, resp)
+	}
+	if resp["model"] != "my-model-0601" {
+		t.Errorf("model = %v, want my-model-0601", resp["model"])
+	}
+	if gotAuth != "Bearer sk-new-key" {
+		t.Errorf("应使用提交的密钥，实际 Authorization=%s", gotAuth)
+	}
+}
+
+// TestTestAIModel_InvalidKey 测试无效密钥被归类且不回显
+func TestTestAIModel_InvalidKey(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	srv := newFakeAIServer(t, http.StatusUnauthorized,
+		`{"error":{"message":"Authentication Fails, Your api key: sk-bad-key-123 is invalid"}}`, nil)
+	if err := s.database.UpdateAIModel("user-a", "deepseek", true, "", srv.URL, "", nil); err != nil {
+		t.Fatalf("保存模型配置失败: %v", err)
+	}
+
+	code, resp := testAIModel(t, s, "deepseek", `{"api_key":"sk-bad-key-123"}`)
+	if code != http.StatusBadRequest || resp["error_code"] != "invalid_api_key" {
+		t.Fatalf("应返回 invalid_api_key，实际 %d %v", code, resp)
+	}
+	raw, _ := json.Marshal(resp)
+	if strings.Contains(string(raw), "sk-bad-key-123") {
+		t.Errorf("响应中不应包含密钥: %s", raw)
+	}
+}
+
+// TestTestAIModel_LocalWithoutKey 测试本地模型无需API Key：测试连接不发送认证头，交易员可以引用
+func TestTestAIModel_LocalWithoutKey(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	gotAuth := "unset"
+	srv := newFakeAIServer(t, http.StatusOK, `{"choices":[{"message":{"content":"pong"}}]}`, &gotAuth)
+	if err := s.database.UpdateAIModel("user-a", "local", true, "", srv.URL, "llama3.1:8b", nil); err != nil {
+		t.Fatalf("保存模型配置失败: %v", err)
+	}
+
+	code, resp := testAIModel(t, s, "local", `{}`)
+	if code != http.StatusOK || resp["success"] != true {
+		t.Fatalf("本地模型应测试成功，实际 %d %v", code, resp)
+	}
+	if gotAuth != "" {
+		t.Errorf("无密钥时不应发送 Authorization，实际 %s", gotAuth)
+	}
+
+	if errs, err := s.validateTraderReferences("user-a", "user-a_local", "binance", ""); err != nil || hasFieldError(errs, "ai_model_id") {
+		t.Errorf("未配置API Key的本地模型应视为已配置: %+v, %v", errs, err)
+	}
+
+	// 未填写API地址的本地模型不可启用
+	if err := s.database.UpdateAIModel("user-a", "user-a_local", true, "", "", "llama3.1:8b", nil); err != nil {
+		t.Fatalf("保存模型配置失败: %v", err)
+	}
+	if errs, _ := s.validateTraderReferences("user-a", "user-a_local", "binance", ""); !hasFieldError(errs, "ai_model_id") {
+		t.Errorf("缺少API地址的本地模型应返回 ai_model_id 错误: %+v", errs)
+	}
+}
+
+// TestTestAIModel_NotFound 测试不存在的模型返回404
+func TestTestAIModel_NotFound(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	code, _ := testAIModel(t, s, "unknown-model", `{"api_key":"sk-x"}`)
+	if code != http.StatusNotFound {
+		t.Errorf("状态码 = %d, want 404", code)
+	}
+}
+
+// TestClassifyAIModelError 测试AI错误分类
+func TestClassifyAIModelError(t *testing.T) {
+	tests := []struct {
+		err  string
+		want string
+	}{
+		{`API返回错误 (status 402): {"error":{"message":"Insufficient Balance"}}`, "insufficient_balance"},
+		{`API返回错误 (status 401): {"error":{"message":"invalid"}}`, "invalid_api_key"},
+		{`API返回错误 (status 404): {"error":{"code":"model_not_found"}}`, "model_not_found"},
+		{`发送请求失败: dial tcp: lookup api.example.com: no such host`, "network_error"},
+		{`API返回错误 (status 500): oops`, "unknown"},
+	}
+	for _, tt := range tests {
+		if got, _ := classifyAIModelError(errors.New(tt.err)); got != tt.want {
+			t.Errorf("classifyAIModelError(%q) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+// updateModelConfigs 调用更新模型配置接口并返回状态码和响应
+func updateModelConfigs(t *testing.T, s *Server, body string) (int, map[string]interface{}) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/models", strings.NewReader(body))
+	c.Set("user_id", "user-a")
+
+	s.handleUpdateModelConfigs(c)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	return w.Code, resp
+}
+
+// TestUpdateModelConfigs_ClampsParams 测试请求参数超出范围时调整到边界并返回提示，省略时保持原值
+func TestUpdateModelConfigs_ClampsParams(t *testing.T) {
+	s := setupTraderAccessServer(t)
+
+	code, resp := updateModelConfigs(t, s, `{"models":{"deepseek":{"enabled":true,"api_key":"sk-a","temperature":3.5,"max_tokens":100,"request_timeout_seconds":60}}}`)
+	if code != http.StatusOK {
+		t.Fatalf("期望 200，实际 %d: %v", code, resp)
+	}
+	warnings, _ := resp["warnings"].([]interface{})
+	if len(warnings) != 2 {
+		t.Fatalf("期望 2 条调整提示，实际 %v", resp["warnings"])
+	}
+
+	models, err := s.database.GetAIModels("user-a")
+	if err != nil {
+		t.Fatalf("获取模型失败: %v", err)
+	}
+	model := findUserAIModel(models, "deepseek")
+	if model == nil || model.Temperature == nil || *model.Temperature != maxAITemperature {
+		t.Fatalf("temperature 应调整为 %v: %+v", maxAITemperature, model)
+	}
+	if model.MaxTokens != minAIMaxTokens || model.RequestTimeoutSeconds != 60 {
+		t.Errorf("max_tokens/超时保存错误: %d/%d", model.MaxTokens, model.RequestTimeoutSeconds)
+	}
+
+	// 省略参数时保持已保存的值，且不返回提示
+	code, resp = updateModelConfigs(t, s, `{"models":{"deepseek":{"enabled":true,"api_key":"sk-a"}}}`)
+	if code != http.StatusOK || resp["warnings"] != nil {
+		t.Fatalf("期望 200 且无提示，实际 %d: %v", code, resp)
+	}
+	models, _ = s.database.GetAIModels("user-a")
+	model = findUserAIModel(models, "deepseek")
+	if model.Temperature == nil || *model.Temperature != maxAITemperature || model.MaxTokens != minAIMaxTokens {
+		t.Errorf("省略参数后应保持原值: %+v", model.AIModelParams)
+	}
+}
+
+// TestClampAIModelParams_DefaultsUntouched 测试未设置的参数（nil/0）不做调整
+func TestClampAIModelParams_DefaultsUntouched(t *testing.T) {
+	params := &config.AIModelParams{}
+	if warnings := clampAIModelParams("deepseek", params); len(warnings) != 0 {
+		t.Errorf("未设置参数不应产生提示: %v", warnings)
+	}
+	if params.Temperature != nil || params.MaxTokens != 0 || params.RequestTimeoutSeconds != 0 {
+		t.Errorf("未设置参数应保持默认: %+v", params)
+	}
+}
+
+// hasFieldError 是否包含指定字段的校验错误
+func hasFieldError(errs []traderFieldError, field string) bool {
+	for _, e := range errs {
+		if e.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"nofx/config"
+	"nofx/logger"
+	"nofx/notify"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// balanceAlertCooldown 同一交易员的AI余额不足通知间隔（每个决策周期都会触发，避免刷屏）
+const balanceAlertCooldown = time.Hour
+
+// notificationCooldown 按 key 限制通知频率
+type notificationCooldown struct {
+	mu   sync.Mutex
+	last map[string]time.Time
+}
+
+// allow 距离上次发送超过 interval 时返回 true 并记录本次发送时间
+func (n *notificationCooldown) allow(key string, now time.Time, interval time.Duration) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.last == nil {
+		n.last = make(map[string]time.Time)
+	}
+	if last, ok := n.last[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+	n.last[key] = now
+	return true
+}
+
+// notificationChannelResponse 接口返回的通知通道（凭证脱敏）
+func notificationChannelResponse(ch *config.NotificationChannel) *config.NotificationChannel {
+	masked := *ch
+	masked.Config = ch.Config.Masked()
+	return &masked
+}
+
+// handleListNotificationChannels 获取当前用户的通知通道（凭证脱敏）
+func (s *Server) handleListNotificationChannels(c *gin.Context) {
+	channels, err := s.database.ListNotificationChannels(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := make([]*config.NotificationChannel, 0, len(channels))
+	for _, ch := range channels {
+		result = append(result, notificationChannelResponse(ch))
+	}
+	c.JSON(http.StatusOK, gin.H{"channels": result, "types": notify.ChannelTypes})
+}
+
+// handleCreateNotificationChannel 新建通知通道（凭证可加密传输）
+func (s *Server) handleCreateNotificationChannel(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		Type    string               `json:"type"`
+		Name    string               `json:"name"`
+		Enabled *bool                `json:"enabled"`
+		Config  notify.ChannelConfig `json:"config"`
+	}
+	if err := s.bindSensitiveJSON(c, "通知通道", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ch := &config.NotificationChannel{
+		UserID:  userID,
+		Type:    strings.ToLower(strings.TrimSpace(req.Type)),
+		Name:    req.Name,
+		Enabled: req.Enabled == nil || *req.Enabled,
+		Config:  req.Config,
+	}
+	if err := ch.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ch.Name == "" {
+		ch.Name = ch.Type
+	}
+	if err := s.database.CreateNotificationChannel(ch, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.subscriptions.invalidate(userID)
+	s.audit(c, userID, auditNotificationCreate, ch.ID, gin.H{"type": ch.Type, "name": ch.Name})
+	requestLogf(c, "🔔 用户 %s 新建通知通道 %s (%s)", userID, ch.Name, ch.Type)
+	c.JSON(http.StatusOK, notificationChannelResponse(ch))
+}
+
+// handleUpdateNotificationChannel 更新通知通道（config 中留空的字段保留原值）
+func (s *Server) handleUpdateNotificationChannel(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ch, err := s.database.GetNotificationChannel(userID, c.Param("id"))
+	if errors.Is(err, config.ErrNotificationChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		Name    *string              `json:"name"`
+		Enabled *bool                `json:"enabled"`
+		Config  notify.ChannelConfig `json:"config"`
+	}
+	if err := s.bindSensitiveJSON(c, "通知通道", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		ch.Name = *req.Name
+	}
+	if req.Enabled != nil {
+		ch.Enabled = *req.Enabled
+	}
+	if req.Config.BotToken != "" {
+		ch.Config.BotToken = req.Config.BotToken
+	}
+	if req.Config.ChatID != "" {
+		ch.Config.ChatID = req.Config.ChatID
+	}
+	if req.Config.WebhookURL != "" {
+		ch.Config.WebhookURL = req.Config.WebhookURL
+	}
+	if req.Config.Secret != "" {
+		ch.Config.Secret = req.Config.Secret
+	}
+	if err := ch.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.database.UpdateNotificationChannel(ch, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.subscriptions.invalidate(userID)
+	s.audit(c, userID, auditNotificationUpdate, ch.ID, gin.H{"name": ch.Name, "enabled": ch.Enabled})
+	requestLogf(c, "🔔 用户 %s 更新通知通道 %s", userID, ch.Name)
+	c.JSON(http.StatusOK, notificationChannelResponse(ch))
+}
+
+// handleDeleteNotificationChannel 删除通知通道
+func (s *Server) handleDeleteNotificationChannel(c *gin.Context) {
+	userID := c.GetString("user_id")
+	id := c.Param("id")
+	err := s.database.DeleteNotificationChannel(userID, id)
+	if errors.Is(err, config.ErrNotificationChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.subscriptions.invalidate(userID)
+	s.audit(c, userID, auditNotificationDelete, id, nil)
+	requestLogf(c, "🔔 用户 %s 删除通知通道 %s", userID, id)
+	c.JSON(http.StatusOK, gin.H{"message": "通知通道已删除"})
+}
+
+// handleTestNotificationChannel 同步发送一条测试通知，返回发送结果（通道未启用也可测试）
+func (s *Server) handleTestNotificationChannel(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ch, err := s.database.GetNotificationChannel(userID, c.Param("id"))
+	if errors.Is(err, config.ErrNotificationChannelNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	channel, err := notify.NewChannel(ch.Type, ch.Config, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+	msg := &notify.Message{
+		Event: "test",
+		Title: "🔔 NOFX 测试通知",
+		Text:  fmt.Sprintf("通知通道「%s」配置正确", ch.Name),
+		Time:  time.Now(),
+	}
+	if err := channel.Send(ctx, msg); err != nil {
+		requestLogf(c, "⚠️ 用户 %s 测试通知通道 %s 失败: %v", userID, ch.Name, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": fmt.Sprintf("发送测试通知失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "测试通知已发送"})
+}
+
+// handleTraderEvent 把交易员事件投递到所属用户订阅的 Webhook，并转换为通知发送到已启用的通道（在事件转发协程中调用）
+func (s *Server) handleTraderEvent(at *trader.AutoTrader, event trader.TraderEvent) {
+	s.dispatchWebhooks(at.GetUserID(), event)
+
+	if event.Type == trader.EventAIBalanceAlert {
+		key := at.GetID() + "|" + event.Type
+		if !s.alertCooldown.allow(key, time.Now(), balanceAlertCooldown) {
+			return
+		}
+	}
+	msg := traderEventMessage(at.GetName(), event)
+	if msg == nil {
+		return
+	}
+	s.notifyUser(at.GetUserID(), msg)
+}
+
+// notifyUser 把通知加入用户每个已启用通道的发送队列（不等待发送结果）
+func (s *Server) notifyUser(userID string, msg *notify.Message) {
+	if s.notifier == nil || userID == "" {
+		return
+	}
+	subs, err := s.userSubscriptions(userID)
+	if err != nil {
+		log.Printf("⚠️ 读取用户 %s 的事件订阅失败: %v", userID, err)
+		return
+	}
+	for _, target := range subs.channels {
+		s.notifier.Enqueue(target.channel, target.name, msg)
+	}
+}
+
+// positionActionNames 开平仓动作的中文名称
+var positionActionNames = map[string]string{
+	"open_long":     "开多",
+	"open_short":    "开空",
+	"close_long":    "平多",
+	"close_short":   "平空",
+	"partial_close": "部分平仓",
+}
+
+// traderEventMessage 按事件类型生成通知内容，不需要通知的事件返回 nil
+func traderEventMessage(traderName string, event trader.TraderEvent) *notify.Message {
+	msg := &notify.Message{
+		Event:    event.Type,
+		TraderID: event.TraderID,
+		Time:     event.Timestamp,
+		Data:     event.Data,
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+
+	switch event.Type {
+	case trader.EventPositionOpened, trader.EventPositionClosed:
+		action, ok := event.Data.(*logger.DecisionAction)
+		if !ok {
+			return nil
+		}
+		icon := "📈"
+		if event.Type == trader.EventPositionClosed {
+			icon = "📉"
+		}
+		name := positionActionNames[action.Action]
+		if name == "" {
+			name = action.Action
+		}
+		msg.Title = fmt.Sprintf("%s [%s] %s %s", icon, traderName, name, action.Symbol)
+		text := fmt.Sprintf("价格: %.4f", action.Price)
+		if action.Quantity > 0 {
+			text += fmt.Sprintf("  数量: %.4f", action.Quantity)
+		}
+		if action.Leverage > 0 {
+			text += fmt.Sprintf("  杠杆: %dx", action.Leverage)
+		}
+		msg.Text = text
+
+	case trader.EventTraderError:
+		data, _ := event.Data.(map[string]interface{})
+		msg.Title = fmt.Sprintf("❌ [%s] 交易员出错停止", traderName)
+		msg.Text = fmt.Sprintf("错误: %v\n已重启次数: %v", data["error"], data["restarts"])
+
+	case trader.EventCircuitBreaker:
+		cb, ok := event.Data.(*logger.CircuitBreakerEvent)
+		if !ok {
+			return nil
+		}
+		msg.Title = fmt.Sprintf("⛔ [%s] 日亏损熔断", traderName)
+		msg.Text = fmt.Sprintf("当日亏损 %.2f%%（阈值 %.2f%%），净值 %.2f → %.2f，暂停开仓至 %s",
+			cb.LossPct, cb.LimitPct, cb.DayStartEquity, cb.Equity, cb.ResumeAt.Format("2006-01-02 15:04 MST"))
+		if cb.Flattened {
+			msg.Text += "，已平掉所有持仓"
+		}
+
+	case trader.EventAIBalanceAlert:
+		data, _ := event.Data.(map[string]interface{})
+		msg.Title = fmt.Sprintf("💳 [%s] AI余额不足", traderName)
+		msg.Text = fmt.Sprintf("模型 %v 的API余额不足，请充值或更换密钥\n错误: %v", data["model"], data["error"])
+
+	case trader.EventSpendAlert:
+		data, _ := event.Data.(map[string]interface{})
+		msg.Title = fmt.Sprintf("💸 [%s] AI花费告警", traderName)
+		msg.Text = fmt.Sprintf("本月已花费 $%.2f / 预算 $%.2f (%.1f%%)，已超过 %v%% 阈值",
+			data["spent"], data["budget"], data["used_pct"], data["threshold"])
+
+	default:
+		return nil
+	}
+	return msg
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/logger"
+	"nofx/notify"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestNotificationChannels_CRUDAndDelivery 通道增删改查（凭证脱敏、不能访问其他用户的通道）、测试通知和交易员事件异步投递
+func TestNotificationChannels_CRUDAndDelivery(t *testing.T) {
+	received := make(chan notify.Message, 4)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg notify.Message
+		json.Unmarshal(body, &msg)
+		received <- msg
+	}))
+	defer webhook.Close()
+
+	s := setupTraderAccessServer(t)
+	s.notifier = notify.NewDispatcher(1, 8)
+	defer s.notifier.Close()
+
+	gin.SetMode(gin.TestMode)
+	newRouter := func(userID string) *gin.Engine {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+		router.GET("/api/user/notifications", s.handleListNotificationChannels)
+		router.POST("/api/user/notifications", s.handleCreateNotificationChannel)
+		router.PUT("/api/user/notifications/:id", s.handleUpdateNotificationChannel)
+		router.DELETE("/api/user/notifications/:id", s.handleDeleteNotificationChannel)
+		router.POST("/api/user/notifications/:id/test", s.handleTestNotificationChannel)
+		return router
+	}
+	userA, userB := newRouter("user-a"), newRouter("user-b")
+	do := func(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(userA, http.MethodPost, "/api/user/notifications", `{"type":"telegram","config":{"bot_token":"123"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("缺少 chat_id 应返回400，实际 %d", w.Code)
+	}
+	if w := do(userA, http.MethodPost, "/api/user/notifications", `{"type":"webhook","config":{"webhook_url":"`+webhook.URL+`"}}`); w.Code != http.StatusBadRequest {
+		t.Errorf("回环地址的 webhook_url 应返回400，实际 %d", w.Code)
+	}
+	// 测试服务器监听在回环地址，以下允许发送到内网地址
+	notify.SetAllowPrivateTargets(true)
+	defer notify.SetAllowPrivateTargets(false)
+	w := do(userA, http.MethodPost, "/api/user/notifications",
+		`{"type":"webhook","name":"hook","config":{"webhook_url":"`+webhook.URL+`/secret-path","secret":"webhook-secret"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("新建通道应返回200: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = do(userA, http.MethodGet, "/api/user/notifications", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), created.ID) {
+		t.Fatalf("获取通道列表失败: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "secret-path") || strings.Contains(w.Body.String(), "webhook-secret") {
+		t.Errorf("通道列表不应包含凭证: %s", w.Body.String())
+	}
+
+	// 其他用户看不到也不能修改、删除、测试该通道
+	for _, req := range []struct{ method, path string }{
+		{http.MethodPut, "/api/user/notifications/" + created.ID},
+		{http.MethodDelete, "/api/user/notifications/" + created.ID},
+		{http.MethodPost, "/api/user/notifications/" + created.ID + "/test"},
+	} {
+		if w := do(userB, req.method, req.path, `{}`); w.Code != http.StatusNotFound {
+			t.Errorf("%s %s 其他用户应返回404，实际 %d", req.method, req.path, w.Code)
+		}
+	}
+
+	// 测试通知同步发送
+	if w := do(userA, http.MethodPost, "/api/user/notifications/"+created.ID+"/test", ""); w.Code != http.StatusOK {
+		t.Fatalf("测试通知应返回200: %d %s", w.Code, w.Body.String())
+	}
+	if msg := <-received; msg.Event != "test" {
+		t.Errorf("应收到测试通知: %+v", msg)
+	}
+
+	// 只改名称时保留原有凭证
+	if w := do(userA, http.MethodPut, "/api/user/notifications/"+created.ID, `{"name":"renamed"}`); w.Code != http.StatusOK {
+		t.Fatalf("更新通道应返回200: %d %s", w.Code, w.Body.String())
+	}
+	stored, err := s.database.GetNotificationChannel("user-a", created.ID)
+	if err != nil || stored.Name != "renamed" || stored.Config.Secret != "webhook-secret" {
+		t.Fatalf("更新后应保留原有凭证: %+v %v", stored, err)
+	}
+
+	// 交易员事件异步投递到用户的通道
+	msg := traderEventMessage("A", trader.TraderEvent{
+		Type:      trader.EventPositionOpened,
+		TraderID:  "trader-a",
+		Timestamp: time.Now(),
+		Data:      &logger.DecisionAction{Action: "open_long", Symbol: "BTCUSDT", Price: 65000, Leverage: 5},
+	})
+	if msg == nil || !strings.Contains(msg.Title, "开多 BTCUSDT") {
+		t.Fatalf("开仓通知内容不正确: %+v", msg)
+	}
+	if traderEventMessage("A", trader.TraderEvent{Type: trader.EventCycleCompleted}) != nil {
+		t.Error("决策周期完成不应发送通知")
+	}
+	s.notifyUser("user-b", msg)
+	s.notifyUser("user-a", msg)
+	select {
+	case got := <-received:
+		if got.Event != trader.EventPositionOpened || got.TraderID != "trader-a" {
+			t.Errorf("收到的通知不正确: %+v", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到交易员事件通知")
+	}
+
+	// 停用后不再投递
+	do(userA, http.MethodPut, "/api/user/notifications/"+created.ID, `{"enabled":false}`)
+	s.notifyUser("user-a", msg)
+	select {
+	case got := <-received:
+		t.Errorf("停用的通道不应收到通知: %+v", got)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if w := do(userA, http.MethodDelete, "/api/user/notifications/"+created.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("删除通道应返回200，实际 %d", w.Code)
+	}
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/logger"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pnlBaselineLookback 向前查找前一日收盘净值的最长时间
+const pnlBaselineLookback = 7 * 24 * time.Hour
+
+// pnlDay 日历中某一天的盈亏
+type pnlDay struct {
+	Date        string  `json:"date"`         // 日期（按请求时区，YYYY-MM-DD）
+	PnL         float64 `json:"pnl"`          // 当日盈亏（已实现+未实现）= 当日最后净值 - 前一日最后净值 - 初始余额调整
+	PnLPct      float64 `json:"pnl_pct"`      // 相对前一日最后净值的百分比
+	StartEquity float64 `json:"start_equity"` // 前一日最后净值（没有前一日数据时为当日第一个净值）
+	EndEquity   float64 `json:"end_equity"`   // 当日最后净值
+	Trades      int     `json:"trades"`       // 当日平仓的交易数
+	WinRate     float64 `json:"win_rate"`     // 当日平仓交易的胜率
+}
+
+// pnlEquityPoint 计算每日盈亏使用的净值点
+type pnlEquityPoint struct {
+	Time           time.Time
+	Equity         float64
+	InitialBalance float64 // 用户调整初始余额（入金/出金后重新校准）时，差额不计入盈亏
+}
+
+// pnlDailyCache 已结束日期的每日盈亏缓存（过去的日期不会再变化）
+type pnlDailyCache struct {
+	mu   sync.Mutex
+	days map[string]*pnlDay // traderID|时区|日期 -> 当日盈亏（nil 表示当日没有数据）
+}
+
+// get 读取缓存，ok 表示该日期已缓存（包括没有数据的日期）
+func (c *pnlDailyCache) get(key string) (day *pnlDay, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day, ok = c.days[key]
+	return day, ok
+}
+
+// put 写入缓存
+func (c *pnlDailyCache) put(key string, day *pnlDay) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.days == nil {
+		c.days = make(map[string]*pnlDay)
+	}
+	c.days[key] = day
+}
+
+// computeDailyPnL 按时区的日期边界汇总 [from, to) 内每天的盈亏（points 需按时间正序，可包含 from 之前的点作为基准）
+// 当日盈亏 = 当日最后净值 - 前一日最后净值，初始余额的变化视为入金/出金从盈亏中扣除；没有净值数据的日期只统计交易
+func computeDailyPnL(points []pnlEquityPoint, trades []logger.TradeOutcome, loc *time.Location, from, to time.Time) map[string]*pnlDay {
+	days := make(map[string]*pnlDay)
+	dayOf := func(date string) *pnlDay {
+		day, ok := days[date]
+		if !ok {
+			day = &pnlDay{Date: date}
+			days[date] = day
+		}
+		return day
+	}
+
+	// 按日期分组，记录每天第一个和最后一个净值点
+	type dayPoints struct {
+		date        string
+		first, last *pnlEquityPoint
+	}
+	var baseline *pnlEquityPoint // from 之前的最后净值
+	var ordered []*dayPoints
+	for i := range points {
+		p := &points[i]
+		if p.Equity <= 0 || !p.Time.Before(to) {
+			continue
+		}
+		if p.Time.Before(from) {
+			baseline = p
+			continue
+		}
+		date := p.Time.In(loc).Format("2006-01-02")
+		if len(ordered) == 0 || ordered[len(ordered)-1].date != date {
+			ordered = append(ordered, &dayPoints{date: date, first: p})
+		}
+		ordered[len(ordered)-1].last = p
+	}
+
+	for _, dp := range ordered {
+		base := baseline
+		if base == nil {
+			base = dp.first
+		}
+		day := dayOf(dp.date)
+		day.StartEquity = base.Equity
+		day.EndEquity = dp.last.Equity
+		day.PnL = dp.last.Equity - base.Equity
+		if base.InitialBalance > 0 && dp.last.InitialBalance > 0 {
+			day.PnL -= dp.last.InitialBalance - base.InitialBalance
+		}
+		day.PnLPct = day.PnL / base.Equity * 100
+		baseline = dp.last
+	}
+
+	wins := make(map[string]int)
+	for _, trade := range trades {
+		if trade.CloseTime.Before(from) || !trade.CloseTime.Before(to) {
+			continue
+		}
+		day := dayOf(trade.CloseTime.In(loc).Format("2006-01-02"))
+		day.Trades++
+		if trade.PnL > 0 {
+			wins[day.Date]++
+		}
+	}
+	for date, day := range days {
+		if day.Trades > 0 {
+			day.WinRate = float64(wins[date]) / float64(day.Trades) * 100
+		}
+	}
+	return days
+}
+
+// pnlCacheKey 每日盈亏缓存键
+func pnlCacheKey(traderID string, loc *time.Location, date string) string {
+	return traderID + "|" + loc.String() + "|" + date
+}
+
+// handleDailyPnL 每日盈亏日历：GET /api/pnl-daily?trader_id=xxx&month=YYYY-MM[&tz=Asia/Shanghai]
+func (s *Server) handleDailyPnL(c *gin.Context) {
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	// 未指定 tz 时按用户设置的时区划分日期
+	loc, err := s.requestLocation(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	now := time.Now().In(loc)
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+	if month := c.Query("month"); month != "" {
+		parsed, err := time.ParseInLocation("2006-01", month, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month 参数格式错误，应为 YYYY-MM"})
+			return
+		}
+		monthStart = parsed
+	}
+	monthEnd := monthStart.AddDate(0, 1, 0)
+
+	trader, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 已结束的日期优先使用缓存，只计算第一个未缓存的日期到今天
+	days := make(map[string]*pnlDay)
+	computeFrom := time.Time{}
+	for d := monthStart; d.Before(monthEnd) && d.Before(now); d = d.AddDate(0, 0, 1) {
+		date := d.Format("2006-01-02")
+		if day, ok := s.pnlDaily.get(pnlCacheKey(traderID, loc, date)); ok {
+			if day != nil {
+				days[date] = day
+			}
+			continue
+		}
+		computeFrom = d
+		break
+	}
+
+	if !computeFrom.IsZero() {
+		computeTo := monthEnd
+		if now.Before(computeTo) {
+			computeTo = now
+		}
+		computed, err := s.computeTraderDailyPnL(traderID, trader.GetDecisionLogger(), loc, computeFrom, computeTo)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("计算每日盈亏失败: %v", err)})
+			return
+		}
+		for d := computeFrom; d.Before(computeTo); d = d.AddDate(0, 0, 1) {
+			date := d.Format("2006-01-02")
+			day := computed[date]
+			if day != nil {
+				days[date] = day
+			}
+			// 当天结束后才缓存（今天的数据还会变化）
+			if !d.AddDate(0, 0, 1).After(now) {
+				s.pnlDaily.put(pnlCacheKey(traderID, loc, date), day)
+			}
+		}
+	}
+
+	result := make([]*pnlDay, 0, len(days))
+	totalPnL := 0.0
+	winningDays, losingDays := 0, 0
+	for _, day := range days {
+		result = append(result, day)
+		totalPnL += day.PnL
+		if day.PnL > 0 {
+			winningDays++
+		} else if day.PnL < 0 {
+			losingDays++
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+
+	c.JSON(http.StatusOK, gin.H{
+		"trader_id":    traderID,
+		"month":        monthStart.Format("2006-01"),
+		"timezone":     loc.String(),
+		"days":         result,
+		"total_pnl":    totalPnL,
+		"winning_days": winningDays,
+		"losing_days":  losingDays,
+	})
+}
+
+// computeTraderDailyPnL 计算交易员 [from, to) 内每天的盈亏
+func (s *Server) computeTraderDailyPnL(traderID string, decisionLogger logger.IDecisionLogger, loc *time.Location, from, to time.Time) (map[string]*pnlDay, error) {
+	_, points, trades, err := s.loadTraderPnLData(traderID, decisionLogger, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return computeDailyPnL(points, trades, loc, from, to), nil
+}
+
+// loadTraderPnLData 读取计算 [from, to) 盈亏所需的数据：范围内的决策记录、净值点（含范围开始前的基准）和已平仓交易
+// 净值优先使用权益快照，没有快照时使用决策记录；交易来自决策记录中配对的开平仓
+func (s *Server) loadTraderPnLData(traderID string, decisionLogger logger.IDecisionLogger, from, to time.Time) ([]*logger.DecisionRecord, []pnlEquityPoint, []logger.TradeOutcome, error) {
+	lookbackFrom := from.Add(-pnlBaselineLookback)
+	records, err := decisionLogger.GetRecordsByTimeRange(lookbackFrom, to, 0)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+
+	var points []pnlEquityPoint
+	snapshots, err := s.database.GetEquitySnapshots(traderID, lookbackFrom, to)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("读取权益快照失败: %w", err)
+	}
+	for _, snapshot := range snapshots {
+		points = append(points, pnlEquityPoint{Time: snapshot.Timestamp, Equity: snapshot.TotalEquity, InitialBalance: snapshot.InitialBalance})
+	}
+	if len(points) == 0 {
+		for _, record := range records {
+			points = append(points, pnlEquityPoint{
+				Time:           record.Timestamp,
+				Equity:         record.AccountState.TotalBalance + record.AccountState.TotalUnrealizedProfit,
+				InitialBalance: record.AccountState.InitialBalance,
+			})
+		}
+	}
+
+	// 范围开始前的记录只用于恢复仍在持仓的开仓信息
+	split := sort.Search(len(records), func(i int) bool { return !records[i].Timestamp.Before(from) })
+	trades := logger.PairRecordTrades(records[:split], records[split:])
+
+	return records[split:], points, trades, nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"nofx/logger"
+)
+
+// TestComputeDailyPnL 按时区划分日期，前一日最后净值为基准，初始余额变化视为入金扣除
+func TestComputeDailyPnL(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	utc := func(day, hour, minute int) time.Time { return time.Date(2026, 5, day, hour, minute, 0, 0, time.UTC) }
+	points := []pnlEquityPoint{
+		{Time: utc(31, 15, 0), Equity: 1000, InitialBalance: 1000},  // 上海 5/31 23:00（基准）
+		{Time: utc(31, 16, 30), Equity: 1010, InitialBalance: 1000}, // 上海 6/1 00:30
+		{Time: utc(32, 10, 0), Equity: 1050, InitialBalance: 1000},  // 上海 6/1 18:00
+		{Time: utc(32, 17, 0), Equity: 1020, InitialBalance: 1000},  // 上海 6/2 01:00
+		{Time: utc(33, 12, 0), Equity: 1530, InitialBalance: 1500},  // 上海 6/2 20:00，入金500
+		{Time: utc(35, 2, 0), Equity: 1545, InitialBalance: 1500},   // 上海 6/4 10:00
+	}
+	trades := []logger.TradeOutcome{
+		{CloseTime: utc(31, 10, 0), PnL: 99}, // 上海 5/31，不在范围内
+		{CloseTime: utc(31, 17, 0), PnL: 10},
+		{CloseTime: utc(32, 20, 0), PnL: -5},
+		{CloseTime: utc(33, 1, 0), PnL: 3},
+		{CloseTime: utc(34, 3, 0), PnL: -1}, // 上海 6/3，当日没有净值数据
+	}
+	from := time.Date(2026, 6, 1, 0, 0, 0, 0, loc)
+	days := computeDailyPnL(points, trades, loc, from, from.AddDate(0, 1, 0))
+
+	want := map[string]pnlDay{
+		"2026-06-01": {Date: "2026-06-01", PnL: 50, PnLPct: 5, StartEquity: 1000, EndEquity: 1050, Trades: 1, WinRate: 100},
+		"2026-06-02": {Date: "2026-06-02", PnL: -20, PnLPct: -20.0 / 1050 * 100, StartEquity: 1050, EndEquity: 1530, Trades: 2, WinRate: 50},
+		"2026-06-03": {Date: "2026-06-03", Trades: 1},
+		"2026-06-04": {Date: "2026-06-04", PnL: 15, PnLPct: 15.0 / 1530 * 100, StartEquity: 1530, EndEquity: 1545},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("应有 %d 天，实际 %d: %v", len(want), len(days), days)
+	}
+	for date, w := range want {
+		got := days[date]
+		if got == nil {
+			t.Errorf("缺少 %s", date)
+			continue
+		}
+		if math.Abs(got.PnL-w.PnL) > 1e-9 || math.Abs(got.PnLPct-w.PnLPct) > 1e-9 || got.StartEquity != w.StartEquity ||
+			got.EndEquity != w.EndEquity || got.Trades != w.Trades || got.WinRate != w.WinRate {
+			t.Errorf("%s = %+v, want %+v", date, *got, w)
+		}
+	}
+
+	// 没有基准时以当日第一个净值为起点
+	days = computeDailyPnL(points[1:3], nil, loc, from, from.AddDate(0, 1, 0))
+	if day := days["2026-06-01"]; day == nil || day.PnL != 40 || day.StartEquity != 1010 {
+		t.Errorf("没有前一日数据时应以当日第一个净值为基准: %+v", day)
+	}
+}
@@ -0,0 +1,239 @@
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"nofx/config"
+	"nofx/decision"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loadUserPromptTemplates 启动时把数据库中的用户模板加载到 decision 包
+func (s *Server) loadUserPromptTemplates() {
+	templates, err := s.database.GetAllUserPromptTemplates()
+	if err != nil {
+		log.Printf("⚠️  加载用户提示词模板失败: %v", err)
+		return
+	}
+	for _, t := range templates {
+		decision.SetUserPromptTemplate(t.UserID, t.Name, t.Content, t.Version)
+	}
+	if len(templates) > 0 {
+		log.Printf("✓ 已加载 %d 个用户提示词模板", len(templates))
+	}
+}
+
+// promptTemplateValidationResponse 模板校验失败的响应（包含缺少的占位符）
+func promptTemplateValidationResponse(c *gin.Context, err error) {
+	var templateErr *decision.PromptTemplateError
+	if errors.As(err, &templateErr) && len(templateErr.MissingPlaceholders) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":                templateErr.Error(),
+			"missing_placeholders": templateErr.MissingPlaceholders,
+		})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// handleGetUserPromptTemplates 获取当前用户的自定义提示词模板
+func (s *Server) handleGetUserPromptTemplates(c *gin.Context) {
+	templates, err := s.database.GetUserPromptTemplates(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取提示词模板失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"templates":             templates,
+		"required_placeholders": decision.RequiredPromptPlaceholders,
+		"max_size":              decision.MaxPromptTemplateSize,
+	})
+}
+
+// handleCreatePromptTemplate 创建用户提示词模板（与系统模板同名时，该用户的交易员优先使用此模板）
+func (s *Server) handleCreatePromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		Name    string `json:"name" binding:"required"`
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := decision.ValidatePromptTemplateName(req.Name); err != nil {
+		promptTemplateValidationResponse(c, err)
+		return
+	}
+	if err := decision.ValidatePromptTemplate(req.Content); err != nil {
+		promptTemplateValidationResponse(c, err)
+		return
+	}
+
+	version, err := s.database.CreatePromptTemplate(userID, req.Name, req.Content)
+	if err != nil {
+		if errors.Is(err, config.ErrPromptTemplateExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "同名提示词模板已存在，请使用 PUT 更新"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "保存提示词模板失败"})
+		return
+	}
+	decision.SetUserPromptTemplate(userID, req.Name, req.Content, version)
+
+	requestLogf(c, "📝 用户 %s 创建提示词模板: %s", userID, req.Name)
+	s.audit(c, userID, auditPromptCreate, req.Name, gin.H{"size": len(req.Content)})
+	c.JSON(http.StatusCreated, gin.H{"message": "提示词模板已创建", "name": req.Name, "version": version})
+}
+
+// handleUpdatePromptTemplate 保存用户提示词模板的新版本（运行中的交易员继续使用启动时固定的版本，重启后生效）
+func (s *Server) handleUpdatePromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := c.Param("name")
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := decision.ValidatePromptTemplate(req.Content); err != nil {
+		promptTemplateValidationResponse(c, err)
+		return
+	}
+
+	version, err := s.database.UpdatePromptTemplate(userID, name, req.Content)
+	if err != nil {
+		if errors.Is(err, config.ErrPromptTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "提示词模板不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "更新提示词模板失败"})
+		return
+	}
+	decision.SetUserPromptTemplate(userID, name, req.Content, version)
+
+	requestLogf(c, "📝 用户 %s 更新提示词模板: %s (v%d)", userID, name, version)
+	s.audit(c, userID, auditPromptUpdate, name, gin.H{"size": len(req.Content), "version": version})
+	c.JSON(http.StatusOK, gin.H{"message": "提示词模板已更新", "version": version})
+}
+
+// handleGetPromptTemplateVersions 获取用户模板的历史版本列表（从新到旧）
+func (s *Server) handleGetPromptTemplateVersions(c *gin.Context) {
+	name := c.Param("name")
+	versions, err := s.database.GetPromptTemplateVersions(c.GetString("user_id"), name)
+	if err != nil {
+		if errors.Is(err, config.ErrPromptTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "提示词模板不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取模板版本失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":           name,
+		"latest_version": versions[0].Version,
+		"versions":       versions,
+	})
+}
+
+// handleDiffPromptTemplateVersions 比较用户模板的两个版本（?from=1&to=2，to 默认为最新版本）
+func (s *Server) handleDiffPromptTemplateVersions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := c.Param("name")
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from 必须是正整数版本号"})
+		return
+	}
+	var to int
+	if c.Query("to") != "" {
+		if to, err = strconv.Atoi(c.Query("to")); err != nil || to <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to 必须是正整数版本号"})
+			return
+		}
+	} else {
+		latest, err := s.database.GetPromptTemplate(userID, name)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "提示词模板不存在"})
+			return
+		}
+		to = latest.Version
+	}
+
+	fromVersion, err := s.database.GetPromptTemplateVersion(userID, name, from)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "版本不存在: v" + strconv.Itoa(from)})
+		return
+	}
+	toVersion, err := s.database.GetPromptTemplateVersion(userID, name, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "版本不存在: v" + strconv.Itoa(to)})
+		return
+	}
+
+	lines := decision.DiffPromptTemplates(fromVersion.Content, toVersion.Content)
+	added, removed := 0, 0
+	for _, line := range lines {
+		switch line.Op {
+		case decision.DiffAdd:
+			added++
+		case decision.DiffRemove:
+			removed++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"name":    name,
+		"from":    from,
+		"to":      to,
+		"added":   added,
+		"removed": removed,
+		"lines":   lines,
+		"diff":    decision.FormatDiff(lines),
+	})
+}
+
+// handleDeletePromptTemplate 删除用户提示词模板（仍被交易员或开启中的A/B实验使用且没有同名系统模板时拒绝）
+func (s *Server) handleDeletePromptTemplate(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := c.Param("name")
+
+	if _, err := decision.GetPromptTemplate(name); err != nil {
+		traders, err := s.database.GetTraders(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
+			return
+		}
+		var usedBy []string
+		for _, trader := range traders {
+			inExperiment := trader.ExperimentEnabled && (trader.TemplateA == name || trader.TemplateB == name)
+			if trader.SystemPromptTemplate == name || inExperiment {
+				usedBy = append(usedBy, trader.ID)
+			}
+		}
+		if len(usedBy) > 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "该模板仍被交易员使用，请先修改这些交易员的模板", "trader_ids": usedBy})
+			return
+		}
+	}
+
+	if err := s.database.DeletePromptTemplate(userID, name); err != nil {
+		if errors.Is(err, config.ErrPromptTemplateNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "提示词模板不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "删除提示词模板失败"})
+		return
+	}
+	decision.RemoveUserPromptTemplate(userID, name)
+
+	requestLogf(c, "🗑️ 用户 %s 删除提示词模板: %s", userID, name)
+	s.audit(c, userID, auditPromptDelete, name, nil)
+	c.JSON(http.StatusOK, gin.H{"message": "提示词模板已删除"})
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/config"
+	"nofx/decision"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestPromptTemplates_CreateUpdateDelete 测试用户提示词模板的增删改以及占位符校验
+func TestPromptTemplates_CreateUpdateDelete(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+	t.Cleanup(func() { decision.RemoveUserPromptTemplate("user-a", "my_strategy") })
+
+	call := func(method, name, body string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/api/prompt-templates", strings.NewReader(body))
+		c.Set("user_id", "user-a")
+		if name != "" {
+			c.Params = gin.Params{{Key: "name", Value: name}}
+		}
+		handler(c)
+		return w
+	}
+	content := `策略\n{{account_state}}\n{{market_data}}`
+
+	// 缺少占位符时返回缺少的项
+	w := call(http.MethodPost, "", `{"name": "my_strategy", "content": "只有 {{account_state}}"}`, s.handleCreatePromptTemplate)
+	var errResp struct {
+		MissingPlaceholders []string `json:"missing_placeholders"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &errResp)
+	if w.Code != http.StatusBadRequest || len(errResp.MissingPlaceholders) != 1 || errResp.MissingPlaceholders[0] != decision.PlaceholderMarketData {
+		t.Fatalf("缺少占位符应返回400和缺少的项: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := call(http.MethodPost, "", `{"name": "my_strategy", "content": "`+content+`"}`, s.handleCreatePromptTemplate); w.Code != http.StatusCreated {
+		t.Fatalf("创建模板状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	if w := call(http.MethodPost, "", `{"name": "my_strategy", "content": "`+content+`"}`, s.handleCreatePromptTemplate); w.Code != http.StatusConflict {
+		t.Errorf("重复创建状态码 = %d, want 409", w.Code)
+	}
+	if tmpl, err := decision.GetPromptTemplateForUser("user-a", "my_strategy"); err != nil || tmpl.Source != decision.PromptSourceUser {
+		t.Fatalf("创建后应可解析到用户模板: %+v, %v", tmpl, err)
+	}
+	if _, err := decision.GetPromptTemplateForUser("user-b", "my_strategy"); err == nil {
+		t.Error("其他用户不能解析到该模板")
+	}
+
+	// 更新
+	if w := call(http.MethodPut, "my_strategy", `{"content": "新策略 `+content+`"}`, s.handleUpdatePromptTemplate); w.Code != http.StatusOK {
+		t.Fatalf("更新模板状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	if tmpl, _ := decision.GetPromptTemplateForUser("user-a", "my_strategy"); tmpl == nil || !strings.HasPrefix(tmpl.Content, "新策略") || tmpl.Version != 2 {
+		t.Errorf("更新后内存中的模板未刷新: %+v", tmpl)
+	}
+
+	// 版本列表和版本差异
+	w = call(http.MethodGet, "my_strategy", "", s.handleGetPromptTemplateVersions)
+	var versionsResp struct {
+		LatestVersion int `json:"latest_version"`
+		Versions      []struct {
+			Version int `json:"version"`
+		} `json:"versions"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &versionsResp)
+	if w.Code != http.StatusOK || versionsResp.LatestVersion != 2 || len(versionsResp.Versions) != 2 {
+		t.Errorf("应返回2个版本: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/prompt-templates/my_strategy/diff?from=1", nil)
+	c.Params = gin.Params{{Key: "name", Value: "my_strategy"}}
+	c.Set("user_id", "user-a")
+	s.handleDiffPromptTemplateVersions(c)
+	var diffResp struct {
+		To      int    `json:"to"`
+		Added   int    `json:"added"`
+		Removed int    `json:"removed"`
+		Diff    string `json:"diff"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &diffResp)
+	if w.Code != http.StatusOK || diffResp.To != 2 || diffResp.Added != 1 || diffResp.Removed != 1 || !strings.Contains(diffResp.Diff, "+新策略 策略") {
+		t.Errorf("版本差异不正确: %d %s", w.Code, w.Body.String())
+	}
+	if w := call(http.MethodPut, "missing", `{"content": "`+content+`"}`, s.handleUpdatePromptTemplate); w.Code != http.StatusNotFound {
+		t.Errorf("更新不存在的模板状态码 = %d, want 404", w.Code)
+	}
+
+	// 被交易员使用时不能删除
+	if err := s.database.CreateTrader(&config.TraderRecord{ID: "trader-prompt", UserID: "user-a", Name: "P", AIModelID: "m", ExchangeID: "binance", SystemPromptTemplate: "my_strategy"}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	if w := call(http.MethodDelete, "my_strategy", "", s.handleDeletePromptTemplate); w.Code != http.StatusConflict {
+		t.Errorf("删除使用中的模板状态码 = %d, want 409", w.Code)
+	}
+	if err := s.database.DeleteTrader("user-a", "trader-prompt"); err != nil {
+		t.Fatalf("删除交易员失败: %v", err)
+	}
+	if w := call(http.MethodDelete, "my_strategy", "", s.handleDeletePromptTemplate); w.Code != http.StatusOK {
+		t.Fatalf("删除模板状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := decision.GetPromptTemplateForUser("user-a", "my_strategy"); err == nil {
+		t.Error("删除后不应再解析到该模板")
+	}
+}
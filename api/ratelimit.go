@@ -0,0 +1,255 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 默认限流配置（可通过 system_config 中的 rate_limit_* 调整，重启后生效）
+	defaultAuthRateLimit    = 5  // 认证接口每分钟请求数
+	defaultPublicRateLimit  = 60 // 公开数据接口每分钟请求数
+	defaultRefreshRateLimit = 30 // 刷新令牌接口每分钟请求数
+	defaultMaxLockoutMinute = 60 // 认证接口最长锁定时间（分钟）
+
+	// authLockoutBase 认证接口首次超限的锁定时间，之后每次超限翻倍
+	authLockoutBase = time.Minute
+	// rateLimitIdleTTL 桶空闲超过该时间后被清理
+	rateLimitIdleTTL = 30 * time.Minute
+	// rateLimitSweepEvery 清理空闲桶的间隔
+	rateLimitSweepEvery = 5 * time.Minute
+	// maxAuthBodyBytes 提取账号标识时最多读取的请求体大小
+	maxAuthBodyBytes = 64 << 10
+)
+
+// tokenBucket 单个限流键的令牌桶
+type tokenBucket struct {
+	tokens      float64
+	last        time.Time
+	violations  int       // 连续超限次数（用于指数锁定）
+	lockedUntil time.Time // 锁定截止时间（锁定结束后 maxLockout 内无超限则清零违规计数）
+}
+
+// rateLimiter 按键计数的令牌桶限流器，可选超限后指数锁定
+type rateLimiter struct {
+	mu         sync.Mutex
+	rate       float64 // 每秒补充的令牌数
+	burst      float64 // 桶容量
+	maxLockout time.Duration
+	buckets    map[string]*tokenBucket
+	lastSweep  time.Time
+	now        func() time.Time
+}
+
+// newRateLimiter 创建限流器：每分钟 perMinute 次，maxLockout>0 时超限后按指数锁定
+func newRateLimiter(perMinute int, maxLockout time.Duration) *rateLimiter {
+	if perMinute <= 0 {
+		perMinute = 1
+	}
+	return &rateLimiter{
+		rate:       float64(perMinute) / 60,
+		burst:      float64(perMinute),
+		maxLockout: maxLockout,
+		buckets:    make(map[string]*tokenBucket),
+		now:        time.Now,
+	}
+}
+
+// allow 消耗一个令牌，返回是否放行以及被拒绝时建议的重试等待时间
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	if now.Before(b.lockedUntil) {
+		return false, b.lockedUntil.Sub(now)
+	}
+	if b.violations > 0 && now.Sub(b.lockedUntil) > l.maxLockout {
+		b.violations = 0
+	}
+
+	// 按流逝时间补充令牌
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	retryAfter := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+	if l.maxLockout > 0 {
+		b.violations++
+		lockout := authLockoutBase << uint(min(b.violations-1, 16))
+		if lockout > l.maxLockout {
+			lockout = l.maxLockout
+		}
+		b.lockedUntil = now.Add(lockout)
+		retryAfter = lockout
+	}
+	return false, retryAfter
+}
+
+// sweep 定期清理长时间空闲且未锁定的桶，避免内存无限增长
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepEvery {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > rateLimitIdleTTL && now.After(b.lockedUntil) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// rateLimitKeyFunc 从请求中提取限流键，返回空字符串表示不参与该维度限流
+type rateLimitKeyFunc func(c *gin.Context) string
+
+// rateLimitMiddleware 限流中间件，任一维度超限即返回429并设置 Retry-After
+func rateLimitMiddleware(limiter *rateLimiter, keyFuncs ...rateLimitKeyFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, keyFunc := range keyFuncs {
+			key := keyFunc(c)
+			if key == "" {
+				continue
+			}
+			if ok, retryAfter := limiter.allow(key); !ok {
+				seconds := int(math.Ceil(retryAfter.Seconds()))
+				if seconds < 1 {
+					seconds = 1
+				}
+				log.Printf("⚠️ 请求过于频繁被限流: %s %s (%s)", c.Request.Method, c.FullPath(), key)
+				c.Header("Retry-After", strconv.Itoa(seconds))
+				c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+					"error":       "请求过于频繁，请稍后再试",
+					"retry_after": seconds,
+				})
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// clientIPKey 按客户端IP限流（只有来自 trusted_proxies 的请求才采用 X-Forwarded-For 中的地址）
+func clientIPKey(c *gin.Context) string {
+	return "ip:" + c.ClientIP()
+}
+
+// userIDKey 按登录用户限流（需在认证中间件之后使用）
+func userIDKey(c *gin.Context) string {
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return ""
+}
+
+// authIdentityKey 按请求体中的账号标识（email 或 user_id）限流，读取后恢复请求体
+func authIdentityKey(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxAuthBodyBytes))
+	if err != nil {
+		return ""
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	var req struct {
+		Email  string `json:"email"`
+		UserID string `json:"user_id"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	if email := strings.ToLower(strings.TrimSpace(req.Email)); email != "" {
+		return "email:" + email
+	}
+	if req.UserID != "" {
+		return "user:" + req.UserID
+	}
+	return ""
+}
+
+// rateLimitConfig 限流配置
+type rateLimitConfig struct {
+	AuthPerMinute    int
+	PublicPerMinute  int
+	RefreshPerMinute int
+	MaxLockout       time.Duration
+}
+
+// loadRateLimitConfig 从系统配置读取限流参数，缺失或非法时使用默认值
+func (s *Server) loadRateLimitConfig() rateLimitConfig {
+	cfg := rateLimitConfig{
+		AuthPerMinute:    defaultAuthRateLimit,
+		PublicPerMinute:  defaultPublicRateLimit,
+		RefreshPerMinute: defaultRefreshRateLimit,
+		MaxLockout:       defaultMaxLockoutMinute * time.Minute,
+	}
+	if s.database == nil {
+		return cfg
+	}
+
+	sysConfig := s.database.SystemConfig()
+	cfg.AuthPerMinute = sysConfig.Int("rate_limit_auth", defaultAuthRateLimit)
+	cfg.PublicPerMinute = sysConfig.Int("rate_limit_public", defaultPublicRateLimit)
+	cfg.RefreshPerMinute = sysConfig.Int("rate_limit_refresh", defaultRefreshRateLimit)
+	cfg.MaxLockout = time.Duration(sysConfig.Int("rate_limit_lockout", defaultMaxLockoutMinute)) * time.Minute
+	return cfg
+}
+
+// String 便于日志输出
+func (c rateLimitConfig) String() string {
+	return fmt.Sprintf("认证 %d次/分钟（最长锁定 %v），刷新令牌 %d次/分钟，公开接口 %d次/分钟", c.AuthPerMinute, c.MaxLockout, c.RefreshPerMinute, c.PublicPerMinute)
+}
+
+// loadTrustedProxies 从系统配置读取 trusted_proxies（逗号分隔的IP或CIDR，重启后生效），为空表示不信任任何代理
+func (s *Server) loadTrustedProxies() []string {
+	if s.database == nil {
+		return nil
+	}
+	var proxies []string
+	for _, entry := range strings.Split(s.database.SystemConfig().String("trusted_proxies", ""), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			proxies = append(proxies, entry)
+		}
+	}
+	return proxies
+}
+
+// applyTrustedProxies 设置信任的反向代理：默认不信任任何代理，客户端IP取连接的对端地址，
+// 避免伪造 X-Forwarded-For 绕过按IP限流；配置无效时同样不信任任何代理
+func (s *Server) applyTrustedProxies() {
+	proxies := s.loadTrustedProxies()
+	if err := s.router.SetTrustedProxies(proxies); err != nil {
+		log.Printf("⚠️ trusted_proxies 配置无效，不信任任何代理: %v", err)
+		s.router.SetTrustedProxies(nil)
+		return
+	}
+	if len(proxies) == 0 {
+		log.Printf("🛡 未配置信任的反向代理，客户端IP取连接地址")
+		return
+	}
+	log.Printf("🛡 信任的反向代理: %s", strings.Join(proxies, ", "))
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeClock 可手动推进的时钟
+type fakeClock struct{ t time.Time }
+
+func (f *fakeClock) now() time.Time          { return f.t }
+func (f *fakeClock) advance(d time.Duration) { f.t = f.t.Add(d) }
+
+// TestRateLimiter_Refill 测试令牌桶耗尽后按速率补充
+func TestRateLimiter_Refill(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := newRateLimiter(60, 0) // 每秒补充1个，容量60
+	l.now = clock.now
+
+	for i := 0; i < 60; i++ {
+		if ok, _ := l.allow("k"); !ok {
+			t.Fatalf("第%d次请求应放行", i+1)
+		}
+	}
+	ok, retryAfter := l.allow("k")
+	if ok {
+		t.Fatal("令牌耗尽后应拒绝")
+	}
+	if retryAfter <= 0 || retryAfter > time.Second {
+		t.Errorf("重试等待应在(0,1s]之间，实际 %v", retryAfter)
+	}
+
+	// 0.5秒不足以补充一个令牌
+	clock.advance(500 * time.Millisecond)
+	if ok, _ := l.allow("k"); ok {
+		t.Error("补充不足1个令牌时应拒绝")
+	}
+
+	// 再过1秒补充1个令牌
+	clock.advance(time.Second)
+	if ok, _ := l.allow("k"); !ok {
+		t.Error("补充令牌后应放行")
+	}
+	if ok, _ := l.allow("k"); ok {
+		t.Error("补充的令牌用完后应拒绝")
+	}
+
+	// 不同的键互不影响
+	if ok, _ := l.allow("other"); !ok {
+		t.Error("其他键应有独立的令牌桶")
+	}
+
+	// 长时间空闲后补满但不超过容量
+	clock.advance(10 * time.Minute)
+	for i := 0; i < 60; i++ {
+		l.allow("k")
+	}
+	if ok, _ := l.allow("k"); ok {
+		t.Error("补充不应超过桶容量")
+	}
+}
+
+// TestRateLimiter_ExponentialLockout 测试认证限流超限后指数锁定
+func TestRateLimiter_ExponentialLockout(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l := newRateLimiter(5, 3*time.Minute)
+	l.now = clock.now
+
+	// exhaust 用完桶内令牌后再请求一次，返回超限后的锁定时间
+	exhaust := func() time.Duration {
+		for i := 0; i < 5; i++ {
+			if ok, _ := l.allow("k"); !ok {
+				t.Fatalf("锁定结束后第%d次请求应放行", i+1)
+			}
+		}
+		ok, retryAfter := l.allow("k")
+		if ok {
+			t.Fatal("令牌耗尽后应拒绝")
+		}
+		return retryAfter
+	}
+
+	for i, want := range []time.Duration{time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		lockout := exhaust()
+		if lockout != want {
+			t.Errorf("第%d次超限锁定时间应为 %v，实际 %v", i+1, want, lockout)
+		}
+		// 锁定期间即使令牌已补充也拒绝
+		clock.advance(lockout - time.Second)
+		if ok, _ := l.allow("k"); ok {
+			t.Errorf("第%d次锁定期间应拒绝", i+1)
+		}
+		clock.advance(time.Second)
+	}
+
+	// 锁定结束后一段时间内无超限，违规计数清零，锁定时间回到基础值
+	clock.advance(3*time.Minute + time.Second)
+	if lockout := exhaust(); lockout != time.Minute {
+		t.Errorf("违规计数清零后锁定时间应为 1m，实际 %v", lockout)
+	}
+}
+
+// TestRateLimitMiddleware 测试中间件返回429和Retry-After，并按账号维度限流
+func TestRateLimitMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	limiter := newRateLimiter(2, 0)
+	router.POST("/login", rateLimitMiddleware(limiter, authIdentityKey), func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		c.String(http.StatusOK, string(body))
+	})
+
+	post := func(email string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"email":"` + email + `"}`
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", bytes.NewBufferString(body)))
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		w := post("a@test.com")
+		if w.Code != http.StatusOK {
+			t.Fatalf("第%d次请求应放行，实际 %d", i+1, w.Code)
+		}
+		if w.Body.String() != `{"email":"a@test.com"}` {
+			t.Errorf("请求体应被恢复给后续处理器，实际 %q", w.Body.String())
+		}
+	}
+
+	w := post("A@test.com")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("超限应返回429（邮箱不区分大小写），实际 %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("429响应应包含 Retry-After 头")
+	}
+
+	if w := post("b@test.com"); w.Code != http.StatusOK {
+		t.Errorf("其他账号不受影响，实际 %d", w.Code)
+	}
+}
+
+// TestTrustedProxies 默认不信任任何代理，伪造 X-Forwarded-For 不能绕过按IP限流；配置 trusted_proxies 后采用代理转发的客户端IP
+func TestTrustedProxies(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+
+	clientIP := func() string {
+		s.router = gin.New()
+		s.applyTrustedProxies()
+		var ip string
+		s.router.GET("/ip", func(c *gin.Context) { ip = clientIPKey(c) })
+		req := httptest.NewRequest(http.MethodGet, "/ip", nil) // RemoteAddr 为 192.0.2.1
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		s.router.ServeHTTP(httptest.NewRecorder(), req)
+		return ip
+	}
+
+	if ip := clientIP(); ip != "ip:192.0.2.1" {
+		t.Errorf("未配置信任代理时应使用连接地址，实际 %s", ip)
+	}
+
+	if err := s.database.SetSystemConfig("trusted_proxies", "192.0.2.0/24"); err != nil {
+		t.Fatalf("设置信任代理失败: %v", err)
+	}
+	if ip := clientIP(); ip != "ip:203.0.113.7" {
+		t.Errorf("来自信任代理的请求应使用 X-Forwarded-For，实际 %s", ip)
+	}
+
+	if err := s.database.SetSystemConfig("trusted_proxies", "not-an-ip"); err == nil {
+		t.Error("无效的 trusted_proxies 应拒绝保存")
+	}
+}
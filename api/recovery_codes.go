@@ -0,0 +1,91 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// verifySecondFactor 校验OTP码或一次性恢复码（恢复码校验成功即被消费）
+func (s *Server) verifySecondFactor(user *config.User, code string) bool {
+	if auth.VerifyOTP(user.OTPSecret, code) {
+		return true
+	}
+	if !auth.IsRecoveryCodeFormat(code) {
+		return false
+	}
+
+	ok, err := s.database.ConsumeRecoveryCode(user.ID, auth.HashRecoveryCode(code))
+	if err != nil {
+		log.Printf("⚠️ 校验用户 %s 的恢复码失败: %v", user.ID, err)
+		return false
+	}
+	if ok {
+		remaining, _ := s.database.CountUnusedRecoveryCodes(user.ID)
+		log.Printf("🔑 用户 %s 使用了恢复码，剩余 %d 个", user.Email, remaining)
+	}
+	return ok
+}
+
+// issueRecoveryCodes 为用户生成新的恢复码（旧码全部失效），返回明文（仅此一次）
+func (s *Server) issueRecoveryCodes(userID string) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = auth.HashRecoveryCode(code)
+	}
+	if err := s.database.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// handleRegenerateRecoveryCodes 重新生成恢复码（需验证当前OTP码或一个未使用的恢复码）
+func (s *Server) handleRegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		OTPCode string `json:"otp_code" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := s.database.GetUserByID(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
+		return
+	}
+
+	if !s.verifySecondFactor(user, req.OTPCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	codes, err := s.issueRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+
+	log.Printf("🔑 用户 %s 重新生成了恢复码", user.Email)
+	c.JSON(http.StatusOK, gin.H{
+		"recovery_codes": codes,
+		"message":        "恢复码已重新生成，旧恢复码已全部失效，请妥善保存",
+	})
+}
+
+// handleGetRecoveryCodeStatus 获取剩余可用的恢复码数量
+func (s *Server) handleGetRecoveryCodeStatus(c *gin.Context) {
+	remaining, err := s.database.CountUnusedRecoveryCodes(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"remaining": remaining})
+}
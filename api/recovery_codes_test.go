@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nofx/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestVerifyOTP_RecoveryCode 测试登录时使用恢复码，且同一恢复码不能重复使用
+func TestVerifyOTP_RecoveryCode(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/verify-otp", s.handleVerifyOTP)
+
+	codes, err := s.issueRecoveryCodes("user-a")
+	if err != nil {
+		t.Fatalf("生成恢复码失败: %v", err)
+	}
+
+	verify := func(code string) int {
+		body, _ := json.Marshal(map[string]string{"user_id": "user-a", "otp_code": code})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/verify-otp", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := verify(codes[0]); code != http.StatusOK {
+		t.Fatalf("使用恢复码登录应返回200，实际 %d", code)
+	}
+	if code := verify(codes[0]); code != http.StatusBadRequest {
+		t.Errorf("重复使用恢复码应返回400，实际 %d", code)
+	}
+	if code := verify("ZZZZZ-ZZZZZ"); code != http.StatusBadRequest {
+		t.Errorf("无效恢复码应返回400，实际 %d", code)
+	}
+	if n, _ := s.database.CountUnusedRecoveryCodes("user-a"); n != auth.RecoveryCodeCount-1 {
+		t.Errorf("剩余恢复码应为 %d，实际 %d", auth.RecoveryCodeCount-1, n)
+	}
+}
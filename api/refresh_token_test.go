@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nofx/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRefreshTokenFlow 测试刷新token轮换、重用检测和登出撤销
+func TestRefreshTokenFlow(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.wsHub = newWSHub()
+	s.setupRoutes()
+
+	user, err := s.database.GetUserByID("user-a")
+	if err != nil {
+		t.Fatalf("获取用户失败: %v", err)
+	}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/verify-otp", nil)
+	pair, err := s.issueTokenPair(c, user)
+	if err != nil {
+		t.Fatalf("签发token失败: %v", err)
+	}
+	first := pair["refresh_token"].(string)
+
+	refresh := func(token string) (int, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]string{"refresh_token": token})
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/refresh", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		s.router.ServeHTTP(w, req)
+		var resp map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return w.Code, resp
+	}
+
+	code, resp := refresh(first)
+	if code != http.StatusOK {
+		t.Fatalf("刷新应返回200，实际 %d: %v", code, resp)
+	}
+	second, _ := resp["refresh_token"].(string)
+	accessToken, _ := resp["token"].(string)
+	if second == "" || second == first || accessToken == "" {
+		t.Fatalf("刷新响应不正确: %v", resp)
+	}
+	if claims, err := auth.ValidateJWT(accessToken); err != nil || claims.UserID != "user-a" {
+		t.Errorf("新的访问token无效: %v", err)
+	}
+
+	// 重用旧token：拒绝并撤销整条链
+	if code, _ := refresh(first); code != http.StatusUnauthorized {
+		t.Errorf("重用旧token应返回401，实际 %d", code)
+	}
+	if code, _ := refresh(second); code != http.StatusUnauthorized {
+		t.Errorf("重用检测后同链新token应失效，实际 %d", code)
+	}
+
+	// 登出时撤销刷新token
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/verify-otp", nil)
+	pair, _ = s.issueTokenPair(c, user)
+	body, _ := json.Marshal(map[string]string{"refresh_token": pair["refresh_token"].(string)})
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+pair["token"].(string))
+	s.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("登出应返回200，实际 %d: %s", w.Code, w.Body.String())
+	}
+	if code, _ := refresh(pair["refresh_token"].(string)); code != http.StatusUnauthorized {
+		t.Errorf("登出后刷新token应失效，实际 %d", code)
+	}
+}
+
+// TestRefreshTokenRateLimited 测试刷新接口按IP限流，使用独立于登录、注册的 rate_limit_refresh
+func TestRefreshTokenRateLimited(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	if err := s.database.SetSystemConfig("rate_limit_auth", "1"); err != nil {
+		t.Fatalf("设置限流配置失败: %v", err)
+	}
+	if err := s.database.SetSystemConfig("rate_limit_refresh", "2"); err != nil {
+		t.Fatalf("设置限流配置失败: %v", err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.wsHub = newWSHub()
+	s.setupRoutes()
+
+	codes := make([]int, 3)
+	for i := range codes {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/refresh", bytes.NewBufferString(`{"refresh_token":"invalid"}`))
+		req.Header.Set("Content-Type", "application/json")
+		s.router.ServeHTTP(w, req)
+		codes[i] = w.Code
+	}
+	if codes[0] != http.StatusUnauthorized || codes[1] != http.StatusUnauthorized || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("超过限流后应返回429，实际 %v", codes)
+	}
+}
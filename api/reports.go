@@ -0,0 +1,421 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/config"
+	"nofx/logger"
+	"nofx/mailer"
+	"nofx/notify"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// reportCheckInterval 定时报告调度器的检查间隔
+	reportCheckInterval = 5 * time.Minute
+	// defaultReportListLimit GET /api/reports 默认返回的报告数
+	defaultReportListLimit = 30
+	// maxReportListLimit GET /api/reports 单次最多返回的报告数
+	maxReportListLimit = 100
+	// reportEvent 报告通知的事件类型（通用 Webhook 通道收到的 event 字段）
+	reportEvent = "performance_report"
+)
+
+// reportPeriodNames 报告周期的中文名称
+var reportPeriodNames = map[string]string{
+	config.ReportPeriodDaily:  "日报",
+	config.ReportPeriodWeekly: "周报",
+}
+
+// traderReport 单个交易员在报告周期内的表现
+type traderReport struct {
+	TraderID      string  `json:"trader_id"`
+	TraderName    string  `json:"trader_name"`
+	PnL           float64 `json:"pnl"`            // 周期盈亏（净值变化，已扣除入金/出金）
+	Trades        int     `json:"trades"`         // 周期内平仓的交易数
+	WinRate       float64 `json:"win_rate"`       // 平仓交易的胜率（百分比）
+	BiggestWin    float64 `json:"biggest_win"`    // 单笔最大盈利
+	BiggestLoss   float64 `json:"biggest_loss"`   // 单笔最大亏损（负数）
+	Fees          float64 `json:"fees"`           // 交易手续费（来自交易所成交记录，不支持查询的交易所为0）
+	FundingFees   float64 `json:"funding_fees"`   // 平仓交易的资金费（正数为收到）
+	AICostUSD     float64 `json:"ai_cost_usd"`    // AI调用花费
+	Cycles        int     `json:"cycles"`         // 决策周期数
+	SkippedCycles int     `json:"skipped_cycles"` // 未请求AI的周期（熔断、决策前扩展点跳过等）
+}
+
+// active 周期内是否有交易活动（没有周期也没有平仓的交易员不出现在报告中）
+func (r *traderReport) active() bool {
+	return r.Cycles > 0 || r.Trades > 0
+}
+
+// performanceReport 用户一个报告周期的表现汇总
+type performanceReport struct {
+	Period         string          `json:"period"`
+	PeriodStart    string          `json:"period_start"` // 开始日期（含）
+	PeriodEnd      string          `json:"period_end"`   // 结束日期（不含）
+	Timezone       string          `json:"timezone"`
+	TotalPnL       float64         `json:"total_pnl"`
+	TotalTrades    int             `json:"total_trades"`
+	TotalFees      float64         `json:"total_fees"`
+	TotalAICostUSD float64         `json:"total_ai_cost_usd"`
+	Traders        []*traderReport `json:"traders"`
+}
+
+// title 报告标题（周报显示起止日期）
+func (r *performanceReport) title() string {
+	title := fmt.Sprintf("📊 NOFX %s %s", reportPeriodNames[r.Period], r.PeriodStart)
+	if r.Period == config.ReportPeriodWeekly {
+		if end, err := time.Parse("2006-01-02", r.PeriodEnd); err == nil {
+			title += " ~ " + end.AddDate(0, 0, -1).Format("2006-01-02")
+		}
+	}
+	return title + "（" + r.Timezone + "）"
+}
+
+// reportDuePeriod 最近一个已到发送时间的报告周期 [from, to)：
+// 日报为前一天，周报为上一个自然周（周一开始）；周期结束当天 reportHour 点之后才发送，未到时间时取再前一个周期
+func reportDuePeriod(period string, now time.Time, loc *time.Location, reportHour int) (time.Time, time.Time) {
+	local := now.In(loc)
+	to := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	days := 1
+	if period == config.ReportPeriodWeekly {
+		days = 7
+		to = to.AddDate(0, 0, -((int(to.Weekday()) + 6) % 7))
+	}
+	if now.Before(time.Date(to.Year(), to.Month(), to.Day(), reportHour, 0, 0, 0, loc)) {
+		to = to.AddDate(0, 0, -days)
+	}
+	return to.AddDate(0, 0, -days), to
+}
+
+// isSkippedCycle 周期在请求AI之前结束（熔断且无持仓、决策前扩展点跳过等）
+func isSkippedCycle(record *logger.DecisionRecord) bool {
+	return record.Success && record.AIModelUsed == "" && record.DecisionJSON == ""
+}
+
+// summarizeTraderReport 汇总交易员 [from, to) 内的盈亏、交易和周期（records 为范围内的决策记录）
+func summarizeTraderReport(records []*logger.DecisionRecord, points []pnlEquityPoint, trades []logger.TradeOutcome, loc *time.Location, from, to time.Time) *traderReport {
+	report := &traderReport{}
+	for _, day := range computeDailyPnL(points, trades, loc, from, to) {
+		report.PnL += day.PnL
+	}
+
+	wins := 0
+	for _, trade := range trades {
+		if trade.CloseTime.Before(from) || !trade.CloseTime.Before(to) {
+			continue
+		}
+		report.Trades++
+		report.FundingFees += trade.FundingFees
+		if trade.PnL > 0 {
+			wins++
+		}
+		report.BiggestWin = math.Max(report.BiggestWin, trade.PnL)
+		report.BiggestLoss = math.Min(report.BiggestLoss, trade.PnL)
+	}
+	if report.Trades > 0 {
+		report.WinRate = float64(wins) / float64(report.Trades) * 100
+	}
+
+	for _, record := range records {
+		if record.DryRun || record.Timestamp.Before(from) || !record.Timestamp.Before(to) {
+			continue
+		}
+		report.Cycles++
+		if isSkippedCycle(record) {
+			report.SkippedCycles++
+		}
+	}
+	return report
+}
+
+// tradeCommission 交易所成交记录中 [from, to) 的手续费合计（只统计 USDT/USDC 计价的手续费，BNB 等抵扣的手续费无法按 USDT 汇总）
+func tradeCommission(t trader.Trader, from, to time.Time) (float64, error) {
+	provider, ok := t.(trader.TradeHistoryProvider)
+	if !ok {
+		return 0, nil
+	}
+	history, err := provider.GetAllTradeHistory(int(time.Since(from).Hours()/24) + 1)
+	if err != nil {
+		return 0, err
+	}
+	total := 0.0
+	for _, trades := range history {
+		for _, trade := range trades {
+			if trade.Time < from.UnixMilli() || trade.Time >= to.UnixMilli() {
+				continue
+			}
+			switch strings.ToUpper(trade.CommissionAsset) {
+			case "", "USDT", "USDC":
+				total += math.Abs(trade.Commission)
+			}
+		}
+	}
+	return total, nil
+}
+
+// reportTradeCommission 交易员 [from, to) 的手续费：已加载的交易员使用其交易所连接，
+// 否则按交易员使用的交易所账户创建临时 trader（账户不存在或未启用时不统计）
+func (s *Server) reportTradeCommission(record *config.TraderRecord, from, to time.Time) (float64, error) {
+	if at, err := s.traderManager.GetTrader(record.ID); err == nil {
+		return tradeCommission(at.GetTrader(), from, to)
+	}
+	exchanges, err := s.database.GetExchanges(record.UserID)
+	if err != nil {
+		return 0, fmt.Errorf("获取交易所配置失败: %w", err)
+	}
+	ex := config.FindExchangeAccount(exchanges, record.ExchangeID, record.ExchangeAccountID)
+	if ex == nil || !ex.Enabled {
+		return 0, nil
+	}
+	t, err := s.newExchangeTrader(record.ExchangeID, ex, record.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return tradeCommission(t, from, to)
+}
+
+// buildPerformanceReport 汇总用户全部交易员 [from, to) 的表现，没有任何交易活动时返回 nil
+func (s *Server) buildPerformanceReport(userID, period string, loc *time.Location, from, to time.Time) (*performanceReport, error) {
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易员失败: %w", err)
+	}
+	spends, err := s.database.GetUserSpendByTrader(userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	aiCost := make(map[string]float64, len(spends))
+	for _, spend := range spends {
+		aiCost[spend.TraderID] = spend.CostUSD
+	}
+
+	report := &performanceReport{
+		Period:      period,
+		PeriodStart: from.Format("2006-01-02"),
+		PeriodEnd:   to.Format("2006-01-02"),
+		Timezone:    loc.String(),
+		Traders:     []*traderReport{},
+	}
+	for _, record := range traders {
+		// 按数据库中的交易员统计（已停止或未加载的交易员同样计入），从未运行过的交易员没有决策日志目录
+		logDir := trader.DecisionLogDir(record.ID)
+		if _, err := os.Stat(logDir); err != nil {
+			continue
+		}
+		records, points, trades, err := s.loadTraderPnLData(record.ID, logger.OpenDecisionLogger(logDir), from, to)
+		if err != nil {
+			return nil, fmt.Errorf("统计交易员 %s 失败: %w", record.Name, err)
+		}
+		tr := summarizeTraderReport(records, points, trades, loc, from, to)
+		if !tr.active() {
+			continue
+		}
+		tr.TraderID, tr.TraderName, tr.AICostUSD = record.ID, record.Name, aiCost[record.ID]
+		if fees, err := s.reportTradeCommission(record, from, to); err != nil {
+			log.Printf("⚠️ [%s] 获取成交记录失败，报告不含手续费: %v", record.Name, err)
+		} else {
+			tr.Fees = fees
+		}
+
+		report.Traders = append(report.Traders, tr)
+		report.TotalPnL += tr.PnL
+		report.TotalTrades += tr.Trades
+		report.TotalFees += tr.Fees
+		report.TotalAICostUSD += tr.AICostUSD
+	}
+	if len(report.Traders) == 0 {
+		return nil, nil
+	}
+	return report, nil
+}
+
+// renderReportText 纯文本正文（Telegram / Discord）
+func renderReportText(r *performanceReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "总盈亏: %+.2f USDT | 平仓 %d 笔 | 手续费 %.2f USDT | AI花费 $%.2f\n",
+		r.TotalPnL, r.TotalTrades, r.TotalFees, r.TotalAICostUSD)
+	for _, t := range r.Traders {
+		fmt.Fprintf(&b, "\n【%s】\n", t.TraderName)
+		fmt.Fprintf(&b, "盈亏 %+.2f USDT | 胜率 %.1f%%（%d 笔）\n", t.PnL, t.WinRate, t.Trades)
+		if t.Trades > 0 {
+			fmt.Fprintf(&b, "最大盈利 %+.2f | 最大亏损 %+.2f\n", t.BiggestWin, t.BiggestLoss)
+		}
+		fmt.Fprintf(&b, "手续费 %.2f | 资金费 %+.2f | AI花费 $%.2f\n", t.Fees, t.FundingFees, t.AICostUSD)
+		fmt.Fprintf(&b, "周期 %d（跳过 %d）\n", t.Cycles, t.SkippedCycles)
+	}
+	return b.String()
+}
+
+// reportHTMLTemplate 邮件 HTML 正文
+var reportHTMLTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"money":  func(v float64) string { return fmt.Sprintf("%+.2f", v) },
+	"amount": func(v float64) string { return fmt.Sprintf("%.2f", v) },
+	"pct":    func(v float64) string { return fmt.Sprintf("%.1f%%", v) },
+}).Parse(`<!DOCTYPE html>
+<html><body style="font-family:sans-serif">
+<h2>{{.Title}}</h2>
+<p>总盈亏 <b>{{money .Report.TotalPnL}} USDT</b>，平仓 {{.Report.TotalTrades}} 笔，手续费 {{amount .Report.TotalFees}} USDT，AI花费 ${{amount .Report.TotalAICostUSD}}</p>
+<table border="1" cellpadding="6" cellspacing="0" style="border-collapse:collapse">
+<tr><th>交易员</th><th>盈亏</th><th>交易</th><th>胜率</th><th>最大盈利</th><th>最大亏损</th><th>手续费</th><th>资金费</th><th>AI花费</th><th>周期（跳过）</th></tr>
+{{range .Report.Traders}}<tr><td>{{.TraderName}}</td><td>{{money .PnL}}</td><td>{{.Trades}}</td><td>{{pct .WinRate}}</td><td>{{money .BiggestWin}}</td><td>{{money .BiggestLoss}}</td><td>{{amount .Fees}}</td><td>{{money .FundingFees}}</td><td>${{amount .AICostUSD}}</td><td>{{.Cycles}}（{{.SkippedCycles}}）</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+// renderReportHTML HTML 正文（邮件）
+func renderReportHTML(r *performanceReport) (string, error) {
+	var buf bytes.Buffer
+	if err := reportHTMLTemplate.Execute(&buf, map[string]interface{}{"Title": r.title(), "Report": r}); err != nil {
+		return "", fmt.Errorf("生成HTML报告失败: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// saveAndDeliverReport 保存报告并发送到用户的通知通道和邮箱
+func (s *Server) saveAndDeliverReport(userID string, report *performanceReport, now time.Time) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("序列化报告失败: %w", err)
+	}
+	html, err := renderReportHTML(report)
+	if err != nil {
+		return err
+	}
+	stored := &config.PerformanceReport{
+		UserID:      userID,
+		Period:      report.Period,
+		PeriodStart: report.PeriodStart,
+		PeriodEnd:   report.PeriodEnd,
+		Timezone:    report.Timezone,
+		Data:        data,
+		Text:        renderReportText(report),
+		HTML:        html,
+		CreatedAt:   now,
+	}
+	if err := s.database.SavePerformanceReport(stored); err != nil {
+		return err
+	}
+
+	title := report.title()
+	s.notifyUser(userID, &notify.Message{Event: reportEvent, Title: title, Text: stored.Text, Time: now, Data: report})
+
+	// 配置了邮件服务时同时发送到账户邮箱（未验证的邮箱不发送）
+	if sender := s.emailSender(); sender != nil {
+		user, err := s.database.GetUserByID(userID)
+		if err == nil && user.Email != "" && !s.emailUnverified(user) {
+			if err := sender.Send(&mailer.Message{To: user.Email, Subject: strings.TrimPrefix(title, "📊 "), Body: stored.Text, HTML: html}); err != nil {
+				log.Printf("⚠️ 发送%s邮件失败 (用户: %s): %v", reportPeriodNames[report.Period], userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// runDueReports 为开启了定时报告的用户生成并发送已到时间、尚未处理的报告
+// 处理过的周期记录在 report_markers 中（没有交易活动时不发送但同样记录），重启后不会重复发送
+func (s *Server) runDueReports(now time.Time) {
+	userIDs, err := s.database.GetAllUsers()
+	if err != nil {
+		log.Printf("⚠️ 定时报告: 获取用户列表失败: %v", err)
+		return
+	}
+	for _, userID := range userIDs {
+		settings, err := s.database.GetUserSettings(userID)
+		if err != nil {
+			log.Printf("⚠️ 定时报告: 获取用户 %s 设置失败: %v", userID, err)
+			continue
+		}
+		loc := settings.Location()
+		enabled := map[string]bool{
+			config.ReportPeriodDaily:  settings.Notifications.DailyReport,
+			config.ReportPeriodWeekly: settings.Notifications.WeeklyReport,
+		}
+		for _, period := range []string{config.ReportPeriodDaily, config.ReportPeriodWeekly} {
+			if !enabled[period] {
+				continue
+			}
+			from, to := reportDuePeriod(period, now, loc, settings.Notifications.ReportHour)
+			periodStart := from.Format("2006-01-02")
+			marker, err := s.database.GetReportMarker(userID, period)
+			if err != nil {
+				log.Printf("⚠️ 定时报告: %v", err)
+				continue
+			}
+			if marker >= periodStart {
+				continue
+			}
+
+			report, err := s.buildPerformanceReport(userID, period, loc, from, to)
+			if err != nil {
+				log.Printf("⚠️ 生成%s失败 (用户: %s): %v", reportPeriodNames[period], userID, err)
+				continue
+			}
+			if report != nil {
+				if err := s.saveAndDeliverReport(userID, report, now); err != nil {
+					log.Printf("⚠️ 发送%s失败 (用户: %s): %v", reportPeriodNames[period], userID, err)
+					continue
+				}
+				log.Printf("📊 已发送%s %s (用户: %s, %d 个交易员)", reportPeriodNames[period], periodStart, userID, len(report.Traders))
+			}
+			if err := s.database.SetReportMarker(userID, period, periodStart); err != nil {
+				log.Printf("⚠️ 定时报告: %v", err)
+			}
+		}
+	}
+}
+
+// RunReportScheduler 定时检查并发送用户的日报/周报；阻塞直到 ctx 取消
+func (s *Server) RunReportScheduler(ctx context.Context) {
+	log.Printf("📊 定时报告已启动，检查间隔 %v", reportCheckInterval)
+	s.runDueReports(time.Now())
+
+	ticker := time.NewTicker(reportCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.runDueReports(now)
+		}
+	}
+}
+
+// handleListReports 已生成的定时报告：GET /api/reports?period=daily|weekly&limit=30
+func (s *Server) handleListReports(c *gin.Context) {
+	period := c.DefaultQuery("period", config.ReportPeriodDaily)
+	if _, ok := reportPeriodNames[period]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "period 只能是 daily 或 weekly"})
+		return
+	}
+	limit := defaultReportListLimit
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit 必须是正整数"})
+			return
+		}
+		limit = min(parsed, maxReportListLimit)
+	}
+
+	reports, err := s.database.ListPerformanceReports(c.GetString("user_id"), period, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"reports": reports})
+}
@@ -0,0 +1,229 @@
+package api
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/logger"
+	"nofx/mailer"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestReportDuePeriod 日报为前一天、周报为上一个自然周，未到发送时间时取再前一个周期
+func TestReportDuePeriod(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Skipf("缺少时区数据: %v", err)
+	}
+	tests := []struct {
+		name     string
+		period   string
+		now      time.Time
+		loc      *time.Location
+		wantFrom string
+		wantTo   string
+	}{
+		{"日报已到发送时间", config.ReportPeriodDaily, time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC), time.UTC, "2025-03-04", "2025-03-05"},
+		{"日报未到发送时间", config.ReportPeriodDaily, time.Date(2025, 3, 5, 7, 59, 0, 0, time.UTC), time.UTC, "2025-03-03", "2025-03-04"},
+		{"按用户时区划分日期", config.ReportPeriodDaily, time.Date(2025, 3, 5, 1, 0, 0, 0, time.UTC), shanghai, "2025-03-04", "2025-03-05"},
+		{"周报周一发送", config.ReportPeriodWeekly, time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC), time.UTC, "2025-03-03", "2025-03-10"},
+		{"周报周一未到发送时间", config.ReportPeriodWeekly, time.Date(2025, 3, 10, 7, 0, 0, 0, time.UTC), time.UTC, "2025-02-24", "2025-03-03"},
+		{"周报周日", config.ReportPeriodWeekly, time.Date(2025, 3, 9, 23, 0, 0, 0, time.UTC), time.UTC, "2025-02-24", "2025-03-03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to := reportDuePeriod(tt.period, tt.now, tt.loc, 8)
+			if from.Format("2006-01-02") != tt.wantFrom || to.Format("2006-01-02") != tt.wantTo {
+				t.Errorf("周期 = [%s, %s), want [%s, %s)", from.Format("2006-01-02"), to.Format("2006-01-02"), tt.wantFrom, tt.wantTo)
+			}
+			if from.Location() != tt.loc {
+				t.Errorf("周期应使用用户时区: %v", from.Location())
+			}
+		})
+	}
+}
+
+// TestSummarizeTraderReport 汇总盈亏、胜率、最大盈亏、资金费和周期数（不含预演和范围外的记录）
+func TestSummarizeTraderReport(t *testing.T) {
+	from := time.Date(2025, 3, 4, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+	at := func(hour int) time.Time { return from.Add(time.Duration(hour) * time.Hour) }
+
+	points := []pnlEquityPoint{
+		{Time: at(-1), Equity: 1000, InitialBalance: 1000},
+		{Time: at(12), Equity: 1030, InitialBalance: 1000},
+		{Time: at(23), Equity: 1025, InitialBalance: 1000},
+	}
+	trades := []logger.TradeOutcome{
+		{CloseTime: at(-2), PnL: 500}, // 范围外
+		{CloseTime: at(3), PnL: 40, FundingFees: -1},
+		{CloseTime: at(5), PnL: -12, FundingFees: 0.5},
+		{CloseTime: at(9), PnL: 7},
+	}
+	records := []*logger.DecisionRecord{
+		{Timestamp: at(1), Success: true, AIModelUsed: "deepseek", DecisionJSON: "[]"},
+		{Timestamp: at(2), Success: true}, // 熔断跳过
+		{Timestamp: at(3), Success: false, ErrorMessage: "AI调用失败"},
+		{Timestamp: at(4), Success: true, DryRun: true},
+		{Timestamp: at(25), Success: true, AIModelUsed: "deepseek"},
+	}
+
+	report := summarizeTraderReport(records, points, trades, time.UTC, from, to)
+	if report.PnL != 25 || report.Trades != 3 || report.BiggestWin != 40 || report.BiggestLoss != -12 {
+		t.Errorf("盈亏统计不正确: %+v", report)
+	}
+	if math.Abs(report.WinRate-200.0/3) > 1e-9 || report.FundingFees != -0.5 {
+		t.Errorf("胜率/资金费不正确: %+v", report)
+	}
+	if report.Cycles != 3 || report.SkippedCycles != 1 {
+		t.Errorf("周期统计不正确: %+v", report)
+	}
+	if !report.active() {
+		t.Error("有周期的交易员应视为有活动")
+	}
+	if (&traderReport{}).active() {
+		t.Error("没有周期和交易的交易员不应视为有活动")
+	}
+}
+
+// TestRunDueReports 没有交易活动时不生成报告但记录已处理，未开启报告的用户不处理
+func TestRunDueReports(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	settings, _ := s.database.GetUserSettings("user-a")
+	settings.Notifications.DailyReport = true
+	settings.Notifications.WeeklyReport = true
+	if err := s.database.UpdateUserSettings(settings); err != nil {
+		t.Fatalf("保存用户设置失败: %v", err)
+	}
+
+	now := time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC)
+	s.runDueReports(now)
+
+	if marker, _ := s.database.GetReportMarker("user-a", config.ReportPeriodDaily); marker != "2025-03-04" {
+		t.Errorf("日报应记录已处理: %q", marker)
+	}
+	if marker, _ := s.database.GetReportMarker("user-a", config.ReportPeriodWeekly); marker != "2025-02-24" {
+		t.Errorf("周报应记录已处理: %q", marker)
+	}
+	if marker, _ := s.database.GetReportMarker("user-b", config.ReportPeriodDaily); marker != "" {
+		t.Errorf("未开启报告的用户不应处理: %q", marker)
+	}
+	if reports, _ := s.database.ListPerformanceReports("user-a", config.ReportPeriodDaily, 0); len(reports) != 0 {
+		t.Errorf("没有交易活动时不应生成报告: %d", len(reports))
+	}
+}
+
+// TestSaveAndDeliverReport 报告保存后可通过 GET /api/reports 获取，并发送到已验证的账户邮箱
+func TestSaveAndDeliverReport(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	sender := &mailer.MockSender{}
+	s.mailSender = sender
+	if err := s.database.SetUserEmailVerified("user-a"); err != nil {
+		t.Fatalf("验证邮箱失败: %v", err)
+	}
+
+	report := &performanceReport{
+		Period:      config.ReportPeriodWeekly,
+		PeriodStart: "2025-02-24",
+		PeriodEnd:   "2025-03-03",
+		Timezone:    "UTC",
+		TotalPnL:    12.5,
+		TotalTrades: 2,
+		Traders: []*traderReport{{
+			TraderID: "trader-a", TraderName: "<A>", PnL: 12.5, Trades: 2, WinRate: 50, BiggestWin: 20, BiggestLoss: -7.5, Cycles: 10, SkippedCycles: 2,
+		}},
+	}
+	if err := s.saveAndDeliverReport("user-a", report, time.Now()); err != nil {
+		t.Fatalf("发送报告失败: %v", err)
+	}
+
+	mail := sender.Last("user-a@test.com")
+	if mail == nil {
+		t.Fatal("应发送报告邮件")
+	}
+	if !strings.Contains(mail.Subject, "周报 2025-02-24 ~ 2025-03-02") || !strings.Contains(mail.Body, "+12.50") {
+		t.Errorf("邮件内容不正确: %s\n%s", mail.Subject, mail.Body)
+	}
+	if !strings.Contains(mail.HTML, "&lt;A&gt;") {
+		t.Errorf("HTML 正文应转义交易员名称: %s", mail.HTML)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-a") })
+	router.GET("/api/reports", s.handleListReports)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reports?period=weekly", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("获取报告失败: %d %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Reports []struct {
+			PeriodStart string            `json:"period_start"`
+			Text        string            `json:"text"`
+			Data        performanceReport `json:"data"`
+		} `json:"reports"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if len(resp.Reports) != 1 || resp.Reports[0].PeriodStart != "2025-02-24" || resp.Reports[0].Data.Traders[0].SkippedCycles != 2 {
+		t.Fatalf("报告列表不正确: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reports?period=daily", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"reports":[]`) {
+		t.Errorf("日报列表应为空: %s", w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reports?period=monthly", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("不支持的周期应返回400: %d", w.Code)
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/reports?period=weekly&limit=100000", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "2025-02-24") {
+		t.Errorf("超过上限的 limit 应按上限返回: %d %s", w.Code, w.Body.String())
+	}
+}
+
+// TestBuildPerformanceReport_UnloadedTrader 未加载到内存的交易员按数据库记录和决策日志目录统计
+func TestBuildPerformanceReport_UnloadedTrader(t *testing.T) {
+	t.Chdir(t.TempDir())
+	s := setupTraderAccessServer(t)
+	stubExchangeTrader(s, &fakeBalanceTrader{}, nil)
+
+	logDir := trader.DecisionLogDir("trader-a")
+	decisionLogger := logger.OpenDecisionLogger(logDir)
+	t.Cleanup(func() { logger.RemoveDecisionLogs(logDir) })
+	if err := decisionLogger.LogDecision(&logger.DecisionRecord{Success: true, AIModelUsed: "deepseek", DecisionJSON: "[]"}); err != nil {
+		t.Fatalf("写入决策记录失败: %v", err)
+	}
+
+	now := time.Now()
+	report, err := s.buildPerformanceReport("user-a", config.ReportPeriodDaily, time.UTC, now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("生成报告失败: %v", err)
+	}
+	if report == nil || len(report.Traders) != 1 || report.Traders[0].TraderID != "trader-a" || report.Traders[0].Cycles != 1 {
+		t.Fatalf("报告应包含未加载的交易员: %+v", report)
+	}
+
+	if report, err := s.buildPerformanceReport("user-b", config.ReportPeriodDaily, time.UTC, now.Add(-time.Hour), now.Add(time.Hour)); err != nil || report != nil {
+		t.Errorf("没有决策日志的交易员不应生成报告: %+v %v", report, err)
+	}
+	if _, err := os.Stat(trader.DecisionLogDir("trader-b")); !os.IsNotExist(err) {
+		t.Errorf("生成报告不应为从未运行的交易员创建日志目录: %v", err)
+	}
+}
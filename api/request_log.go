@@ -0,0 +1,126 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// requestIDHeader 请求ID的请求头/响应头
+	requestIDHeader = "X-Request-ID"
+	// requestIDKey 请求ID在 gin.Context 中的键
+	requestIDKey = "request_id"
+	// maxRequestIDLength 客户端传入的请求ID最大长度（超出或包含非法字符时重新生成）
+	maxRequestIDLength = 128
+)
+
+// sensitiveQueryParams 记录请求日志时需要脱敏的 query 参数
+var sensitiveQueryParams = map[string]bool{
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"api_key":       true,
+	"secret_key":    true,
+	"private_key":   true,
+	"password":      true,
+	"otp_code":      true,
+}
+
+// requestLogger 请求日志输出（JSON格式，便于日志系统按字段检索）
+var requestLogger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// requestLogMiddleware 为每个请求分配请求ID（优先使用合法的 X-Request-ID），写入响应头，
+// 请求结束后以 JSON 记录方法、路径、用户、状态码和耗时
+func requestLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = uuid.New().String()
+		}
+		c.Set(requestIDKey, requestID)
+		c.Header(requestIDHeader, requestID)
+
+		c.Next()
+
+		attrs := []any{
+			slog.String("request_id", requestID),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", c.Writer.Status()),
+			slog.Int64("latency_ms", time.Since(start).Milliseconds()),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if query := sanitizeQuery(c.Request.URL.Query()); query != "" {
+			attrs = append(attrs, slog.String("query", query))
+		}
+		if userID := c.GetString("user_id"); userID != "" {
+			attrs = append(attrs, slog.String("user_id", userID))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		requestLogger.Log(c.Request.Context(), level, "http_request", attrs...)
+	}
+}
+
+// validRequestID 校验客户端传入的请求ID（只允许字母、数字和 -_.: ，防止日志注入）
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// sanitizeQuery 序列化 query 参数，敏感参数使用 MaskSensitiveString 脱敏
+func sanitizeQuery(values url.Values) string {
+	if len(values) == 0 {
+		return ""
+	}
+	safe := make(url.Values, len(values))
+	for key, vals := range values {
+		for _, v := range vals {
+			if sensitiveQueryParams[strings.ToLower(key)] {
+				v = MaskSensitiveString(v)
+			}
+			safe.Add(key, v)
+		}
+	}
+	return safe.Encode()
+}
+
+// requestID 返回当前请求的ID（未经过中间件时为空）
+func requestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
+
+// requestLogf 输出带请求ID前缀的日志，便于按请求ID检索同一请求的所有日志
+func requestLogf(c *gin.Context, format string, args ...interface{}) {
+	if id := requestID(c); id != "" {
+		log.Printf("[req:%s] %s", id, fmt.Sprintf(format, args...))
+		return
+	}
+	log.Printf(format, args...)
+}
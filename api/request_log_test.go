@@ -0,0 +1,107 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// captureRequestLog 将请求日志和普通日志重定向到缓冲区
+func captureRequestLog(t *testing.T) (*bytes.Buffer, *bytes.Buffer) {
+	var jsonBuf, textBuf bytes.Buffer
+	oldLogger := requestLogger
+	requestLogger = slog.New(slog.NewJSONHandler(&jsonBuf, nil))
+	oldOutput := log.Writer()
+	log.SetOutput(&textBuf)
+	t.Cleanup(func() {
+		requestLogger = oldLogger
+		log.SetOutput(oldOutput)
+	})
+	return &jsonBuf, &textBuf
+}
+
+// newRequestLogRouter 创建挂载请求日志中间件的测试路由
+func newRequestLogRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(requestLogMiddleware())
+	router.GET("/api/ping", func(c *gin.Context) {
+		c.Set("user_id", "user-a")
+		requestLogf(c, "✓ 处理 %s", "ping")
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	return router
+}
+
+// TestRequestLogMiddleware_GeneratesID 测试未携带请求ID时自动生成并写入日志
+func TestRequestLogMiddleware_GeneratesID(t *testing.T) {
+	jsonBuf, textBuf := captureRequestLog(t)
+	router := newRequestLogRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/ping?token=secret-token-123456&trader_id=t1", nil))
+
+	id := w.Header().Get(requestIDHeader)
+	if id == "" {
+		t.Fatal("响应头应包含 X-Request-ID")
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &entry); err != nil {
+		t.Fatalf("请求日志应为JSON: %v (%s)", err, jsonBuf.String())
+	}
+	for key, want := range map[string]interface{}{
+		"msg":        "http_request",
+		"request_id": id,
+		"method":     http.MethodGet,
+		"path":       "/api/ping",
+		"status":     float64(http.StatusOK),
+		"user_id":    "user-a",
+	} {
+		if entry[key] != want {
+			t.Errorf("%s = %v, want %v", key, entry[key], want)
+		}
+	}
+	if _, ok := entry["latency_ms"]; !ok {
+		t.Error("请求日志应包含 latency_ms")
+	}
+	if strings.Contains(jsonBuf.String(), "secret-token-123456") {
+		t.Errorf("请求日志不应包含原始token: %s", jsonBuf.String())
+	}
+	if !strings.Contains(entry["query"].(string), "trader_id=t1") {
+		t.Errorf("非敏感参数应保留: %v", entry["query"])
+	}
+
+	if !strings.Contains(textBuf.String(), "[req:"+id+"] ✓ 处理 ping") {
+		t.Errorf("处理器日志应带请求ID前缀: %s", textBuf.String())
+	}
+}
+
+// TestRequestLogMiddleware_HonorsIncomingID 测试沿用合法的请求ID，拒绝非法的请求ID
+func TestRequestLogMiddleware_HonorsIncomingID(t *testing.T) {
+	captureRequestLog(t)
+	router := newRequestLogRouter()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set(requestIDHeader, "lb-abc_123.4:5")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get(requestIDHeader); got != "lb-abc_123.4:5" {
+		t.Errorf("应沿用传入的请求ID，实际 %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/ping", nil)
+	req.Header.Set(requestIDHeader, "bad id\n{\"injected\":true}")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if got := w.Header().Get(requestIDHeader); got == "" || strings.Contains(got, "injected") {
+		t.Errorf("非法请求ID应被替换，实际 %q", got)
+	}
+}
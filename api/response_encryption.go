@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"nofx/crypto"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// responseKeyHeader 客户端临时RSA公钥（base64 SPKI DER），携带时敏感配置响应加密返回
+	responseKeyHeader = "X-Response-Encryption-Key"
+	// encryptedResponseHeader 响应体为 crypto.EncryptedPayload 时设置为 1
+	encryptedResponseHeader = "X-Response-Encrypted"
+)
+
+// respondSensitiveJSON 返回敏感配置：请求携带 X-Response-Encryption-Key 时用该公钥加密整个JSON，否则按明文返回
+func (s *Server) respondSensitiveJSON(c *gin.Context, status int, obj interface{}) {
+	encodedKey := c.GetHeader(responseKeyHeader)
+	if encodedKey == "" {
+		c.JSON(status, obj)
+		return
+	}
+
+	publicKey, err := crypto.ParseClientPublicKey(encodedKey)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "响应加密公钥无效: " + err.Error()})
+		return
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "序列化响应失败"})
+		return
+	}
+	payload, err := crypto.EncryptForClient(publicKey, data)
+	if err != nil {
+		requestLogf(c, "❌ 加密响应失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "加密响应失败"})
+		return
+	}
+
+	c.Header(encryptedResponseHeader, "1")
+	c.JSON(status, payload)
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/crypto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestRespondSensitiveJSON_RoundTrip 携带临时公钥时响应加密，客户端按 RSA-OAEP + AES-GCM 解密后与明文响应一致；不携带时保持明文
+func TestRespondSensitiveJSON_RoundTrip(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-a") })
+	router.GET("/api/exchanges", s.handleGetExchangeConfigs)
+	router.GET("/api/user/signal-sources", s.handleGetUserSignalSource)
+
+	// 客户端临时密钥对（对应前端 WebCrypto generateKey + exportKey('spki')）
+	clientKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("生成密钥失败: %v", err)
+	}
+	spki, _ := x509.MarshalPKIXPublicKey(&clientKey.PublicKey)
+
+	get := func(path, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if key != "" {
+			req.Header.Set(responseKeyHeader, key)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decrypt := func(body []byte) string {
+		var payload crypto.EncryptedPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.WrappedKey == "" {
+			t.Fatalf("响应应为加密payload: %s", body)
+		}
+		decode := func(s string) []byte {
+			b, err := base64.RawURLEncoding.DecodeString(s)
+			if err != nil {
+				t.Fatalf("base64url解码失败: %v", err)
+			}
+			return b
+		}
+		aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, clientKey, decode(payload.WrappedKey), nil)
+		if err != nil {
+			t.Fatalf("解包AES密钥失败: %v", err)
+		}
+		block, _ := aes.NewCipher(aesKey)
+		gcm, _ := cipher.NewGCM(block)
+		aad := decode(payload.AAD)
+		if !strings.Contains(string(aad), crypto.ResponseEncryptionPurpose) {
+			t.Errorf("AAD应标明用途: %s", aad)
+		}
+		plaintext, err := gcm.Open(nil, decode(payload.IV), decode(payload.Ciphertext), aad)
+		if err != nil {
+			t.Fatalf("解密响应失败: %v", err)
+		}
+		return string(plaintext)
+	}
+
+	for _, path := range []string{"/api/exchanges", "/api/user/signal-sources"} {
+		plain := get(path, "")
+		if plain.Code != http.StatusOK || plain.Header().Get(encryptedResponseHeader) != "" {
+			t.Fatalf("%s 默认应返回明文: %d", path, plain.Code)
+		}
+
+		for name, key := range map[string]string{
+			"base64":    base64.StdEncoding.EncodeToString(spki),
+			"base64url": base64.RawURLEncoding.EncodeToString(spki),
+		} {
+			w := get(path, key)
+			if w.Code != http.StatusOK || w.Header().Get(encryptedResponseHeader) != "1" {
+				t.Fatalf("%s 携带%s公钥应返回加密响应: %d %s", path, name, w.Code, w.Body.String())
+			}
+			if got := decrypt(w.Body.Bytes()); got != plain.Body.String() {
+				t.Errorf("%s 解密结果应与明文响应一致:\n%s\n%s", path, got, plain.Body.String())
+			}
+		}
+	}
+
+	if w := get("/api/exchanges", "not-a-key"); w.Code != http.StatusBadRequest {
+		t.Errorf("无效公钥应返回400，实际 %d", w.Code)
+	}
+	weakKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	weakSPKI, _ := x509.MarshalPKIXPublicKey(&weakKey.PublicKey)
+	if w := get("/api/exchanges", base64.StdEncoding.EncodeToString(weakSPKI)); w.Code != http.StatusBadRequest {
+		t.Errorf("长度不足的公钥应返回400，实际 %d", w.Code)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -14,7 +15,12 @@ import (
 	"nofx/decision"
 	"nofx/hook"
 	"nofx/logger"
+	"nofx/mailer"
 	"nofx/manager"
+	"nofx/mcp"
+	"nofx/metrics"
+	"nofx/notify"
+	"nofx/signalsource"
 	"nofx/trader"
 	"strconv"
 	"strings"
@@ -31,6 +37,19 @@ type Server struct {
 	traderManager *manager.TraderManager
 	database      *config.Database
 	cryptoHandler *CryptoHandler
+	wsHub         *wsHub               // 活跃的WebSocket连接（登出时断开）
+	aiProbe       aiProbeCache         // AI服务可达性检查缓存
+	pnlDaily      pnlDailyCache        // 已结束日期的每日盈亏缓存
+	auditLog      *auditLogger         // 审计日志异步写入器
+	cors          corsConfig           // CORS来源配置（WebSocket 同样按此校验 Origin）
+	metrics       metricsConfig        // /metrics 暴露方式
+	metricsServer *http.Server         // 独立端口的指标服务（metrics_port > 0 时）
+	mailSender    mailer.Sender        // 邮件发送器（测试时注入，为空时按系统配置的SMTP发送）
+	traderFactory exchangeTraderFunc   // 临时交易所 trader 工厂（测试时注入，为空时按交易所类型创建）
+	notifier      *notify.Dispatcher   // 用户通知异步发送器
+	webhooks      *notify.Dispatcher   // 出站 Webhook 异步发送器
+	alertCooldown notificationCooldown // 告警类通知的发送间隔
+	subscriptions eventSubscriptions   // 用户 Webhook 和通知通道缓存（事件投递时使用）
 	port          int
 }
 
@@ -39,10 +58,10 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 	// 设置为Release模式（减少日志输出）
 	gin.SetMode(gin.ReleaseMode)
 
-	router := gin.Default()
-
-	// 启用CORS
-	router.Use(corsMiddleware())
+	router := gin.New()
+	router.Use(gin.Recovery())
+	// 请求ID和结构化请求日志（替代 gin 默认的访问日志）
+	router.Use(requestLogMiddleware())
 
 	// 创建加密处理器
 	cryptoHandler := NewCryptoHandler(cryptoService)
@@ -52,38 +71,66 @@ func NewServer(traderManager *manager.TraderManager, database *config.Database,
 		traderManager: traderManager,
 		database:      database,
 		cryptoHandler: cryptoHandler,
+		wsHub:         newWSHub(),
+		auditLog:      newAuditLogger(database),
+		notifier:      notify.NewDispatcher(0, 0),
+		webhooks:      newWebhookDispatcher(),
 		port:          port,
 	}
 
-	// 设置路由
-	s.setupRoutes()
+	// 交易员事件转发为用户通知（开平仓、出错、熔断、余额告警）和出站 Webhook
+	if traderManager != nil {
+		traderManager.SetEventHandler(s.handleTraderEvent)
+	}
 
-	return s
-}
+	// 信任的反向代理（决定 ClientIP 是否采用 X-Forwarded-For，按IP限流依赖它）
+	s.applyTrustedProxies()
 
-// corsMiddleware CORS中间件
-func corsMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+	// 记录请求耗时指标
+	router.Use(metricsMiddleware())
+	s.metrics = s.loadMetricsConfig()
+	log.Printf("📈 Prometheus 指标: %s", s.metrics)
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusOK)
-			return
-		}
+	// 启用CORS（来源由系统配置 cors_allowed_origins 控制）
+	s.cors = s.loadCORSConfig()
+	router.Use(corsMiddleware(s.cors))
+	log.Printf("🌍 CORS: %s", s.cors)
 
-		c.Next()
-	}
+	// 加载用户自定义提示词模板（交易员按用户优先解析模板名称）
+	s.loadUserPromptTemplates()
+
+	// 设置路由
+	s.setupRoutes()
+
+	return s
 }
 
 // setupRoutes 设置路由
 func (s *Server) setupRoutes() {
+	// 限流：认证接口按IP和账号严格限流（超限指数锁定），公开数据接口按IP宽松限流
+	rl := s.loadRateLimitConfig()
+	log.Printf("🚦 接口限流: %s", rl)
+	authLimit := rateLimitMiddleware(newRateLimiter(rl.AuthPerMinute, rl.MaxLockout), clientIPKey, authIdentityKey)
+	// 刷新令牌是客户端的例行操作，单独按IP宽松限流（与认证接口共用会导致同一出口IP的用户被登出）
+	refreshLimit := rateLimitMiddleware(newRateLimiter(rl.RefreshPerMinute, 0), clientIPKey)
+	publicLimit := rateLimitMiddleware(newRateLimiter(rl.PublicPerMinute, 0), clientIPKey)
+	// 决策预演会消耗AI token，按用户限流
+	dryRunLimit := rateLimitMiddleware(newRateLimiter(dryRunPerMinute, 0), userIDKey)
+	// 删除账户需校验密码和OTP，按用户严格限流
+	accountLimit := rateLimitMiddleware(newRateLimiter(rl.AuthPerMinute, rl.MaxLockout), userIDKey)
+
+	// Prometheus 指标（配置 metrics_token 后启用，需 Bearer token）
+	if s.metrics.Token != "" {
+		s.router.GET("/metrics", metricsAuthMiddleware(s.metrics.Token), gin.WrapH(metrics.Handler()))
+	}
+
 	// API路由组
 	api := s.router.Group("/api")
 	{
 		// 健康检查
 		api.Any("/health", s.handleHealth)
+		// 深度检查会访问各用户配置的AI服务地址，仅管理员可用
+		api.GET("/health/deep", s.authMiddleware(), s.adminMiddleware(), s.handleDeepHealth)
 
 		// 管理员登录（管理员模式下使用，公共）
 
@@ -96,25 +143,35 @@ func (s *Server) setupRoutes() {
 
 		// 加密相关接口（无需认证）
 		api.GET("/crypto/public-key", s.cryptoHandler.HandleGetPublicKey)
-		api.POST("/crypto/decrypt", s.cryptoHandler.HandleDecryptSensitiveData)
 
-		// 系统提示词模板管理（无需认证）
+		// 系统提示词模板（无需认证）
 		api.GET("/prompt-templates", s.handleGetPromptTemplates)
 		api.GET("/prompt-templates/:name", s.handleGetPromptTemplate)
 
 		// 公开的竞赛数据（无需认证）
-		api.GET("/traders", s.handlePublicTraderList)
-		api.GET("/competition", s.handlePublicCompetition)
-		api.GET("/top-traders", s.handleTopTraders)
-		api.GET("/equity-history", s.handleEquityHistory)
-		api.POST("/equity-history-batch", s.handleEquityHistoryBatch)
-		api.GET("/traders/:id/public-config", s.handleGetPublicTraderConfig)
+		api.GET("/traders", publicLimit, s.handlePublicTraderList)
+		api.GET("/competition", publicLimit, s.handlePublicCompetition)
+		api.GET("/top-traders", publicLimit, s.handleTopTraders)
+		api.GET("/equity-history", publicLimit, s.handleEquityHistory)
+		api.POST("/equity-history-batch", publicLimit, s.handleEquityHistoryBatch)
+		api.GET("/traders/:id/public-config", publicLimit, s.handleGetPublicTraderConfig)
+
+		// 公开的24小时行情统计（无需认证）
+		api.GET("/market/tickers", publicLimit, s.handleMarketTickers)
 
 		// 认证相关路由（无需认证）
-		api.POST("/register", s.handleRegister)
-		api.POST("/login", s.handleLogin)
-		api.POST("/verify-otp", s.handleVerifyOTP)
-		api.POST("/complete-registration", s.handleCompleteRegistration)
+		api.POST("/register", authLimit, s.handleRegister)
+		api.POST("/login", authLimit, s.handleLogin)
+		api.POST("/verify-otp", authLimit, s.handleVerifyOTP)
+		api.POST("/refresh", refreshLimit, s.handleRefreshToken)
+		api.POST("/complete-registration", authLimit, s.handleCompleteRegistration)
+		api.POST("/verify-email", authLimit, s.handleVerifyEmail)
+		api.POST("/resend-verification", authLimit, s.handleResendVerification)
+		api.POST("/forgot-password", authLimit, s.handleForgotPassword)
+		api.POST("/reset-password", authLimit, s.handleResetPassword)
+
+		// 实时事件推送（WebSocket，通过 query 参数或首条消息中的 JWT 认证）
+		api.GET("/ws", s.handleWebSocket)
 
 		// 需要认证的路由
 		protected := api.Group("/", s.authMiddleware())
@@ -122,6 +179,14 @@ func (s *Server) setupRoutes() {
 			// 注销（加入黑名单）
 			protected.POST("/logout", s.handleLogout)
 
+			// 删除账户（需密码和OTP确认，同时清除全部交易员、密钥和决策日志）
+			protected.DELETE("/user", accountLimit, s.handleDeleteAccount)
+
+			// 登录会话（设备）管理
+			protected.GET("/sessions", s.handleListSessions)
+			protected.DELETE("/sessions/:id", s.handleRevokeSession)
+			protected.POST("/sessions/revoke-others", s.handleRevokeOtherSessions)
+
 			// 服务器IP查询（需要认证，用于白名单配置）
 			protected.GET("/server-ip", s.handleGetServerIP)
 
@@ -129,25 +194,70 @@ func (s *Server) setupRoutes() {
 			protected.GET("/my-traders", s.handleTraderList)
 			protected.GET("/traders/:id/config", s.handleGetTraderConfig)
 			protected.POST("/traders", s.handleCreateTrader)
+			protected.POST("/traders/import", s.handleImportTrader)
+			protected.GET("/traders/:id/export", s.handleExportTrader)
 			protected.PUT("/traders/:id", s.handleUpdateTrader)
 			protected.DELETE("/traders/:id", s.handleDeleteTrader)
 			protected.POST("/traders/:id/start", s.handleStartTrader)
 			protected.POST("/traders/:id/stop", s.handleStopTrader)
 			protected.PUT("/traders/:id/prompt", s.handleUpdateTraderPrompt)
+			protected.POST("/traders/:id/dry-run", dryRunLimit, s.handleDryRunTrader)
+			protected.POST("/traders/:id/trigger-cycle", s.handleTriggerCycle)
+			protected.GET("/traders/:id/guardrails", s.handleGetTraderGuardrails)
+			protected.PUT("/traders/:id/guardrails", s.handleUpdateTraderGuardrails)
 
 			// AI模型配置
 			protected.GET("/models", s.handleGetModelConfigs)
 			protected.PUT("/models", s.handleUpdateModelConfigs)
 			protected.POST("/models/update-keys", s.handleUpdateAIModelKeysOnly)
+			protected.POST("/models/:model_id/test", s.handleTestAIModel)
 
 			// 交易所配置
 			protected.GET("/exchanges", s.handleGetExchangeConfigs)
 			protected.PUT("/exchanges", s.handleUpdateExchangeConfigs)
 			protected.POST("/exchanges/:exchange_id/update-keys", s.handleUpdateExchangeKeysOnly)
+			protected.POST("/exchanges/:exchange_id/test", s.handleTestExchangeConnection)
+			protected.DELETE("/exchanges/:exchange_id/accounts/:account_id", s.handleDeleteExchangeAccount)
+
+			// 用户自定义提示词模板
+			protected.GET("/user/prompt-templates", s.handleGetUserPromptTemplates)
+			protected.POST("/prompt-templates", s.handleCreatePromptTemplate)
+			protected.PUT("/prompt-templates/:name", s.handleUpdatePromptTemplate)
+			protected.DELETE("/prompt-templates/:name", s.handleDeletePromptTemplate)
+			protected.GET("/prompt-templates/:name/versions", s.handleGetPromptTemplateVersions)
+			protected.GET("/prompt-templates/:name/diff", s.handleDiffPromptTemplateVersions)
 
 			// 用户信号源配置
 			protected.GET("/user/signal-sources", s.handleGetUserSignalSource)
 			protected.POST("/user/signal-sources", s.handleSaveUserSignalSource)
+			protected.POST("/user/signal-sources/test", s.handleTestSignalSource)
+
+			// AI花费预算
+			protected.GET("/user/spend", s.handleGetUserSpend)
+			protected.GET("/user/spend-settings", s.handleGetUserSpendSettings)
+			protected.PUT("/user/spend-settings", s.handleUpdateUserSpendSettings)
+
+			// 个人设置（时区、显示币种、通知偏好）
+			protected.GET("/user/settings", s.handleGetUserSettings)
+			protected.PUT("/user/settings", s.handleUpdateUserSettings)
+
+			// 通知通道（Telegram / Discord / Webhook）
+			protected.GET("/user/notifications", s.handleListNotificationChannels)
+			protected.POST("/user/notifications", s.handleCreateNotificationChannel)
+			protected.PUT("/user/notifications/:id", s.handleUpdateNotificationChannel)
+			protected.DELETE("/user/notifications/:id", s.handleDeleteNotificationChannel)
+			protected.POST("/user/notifications/:id/test", s.handleTestNotificationChannel)
+
+			// 出站 Webhook（交易员生命周期和决策事件，HMAC 签名）
+			protected.GET("/webhooks", s.handleListWebhooks)
+			protected.POST("/webhooks", s.handleCreateWebhook)
+			protected.PUT("/webhooks/:id", s.handleUpdateWebhook)
+			protected.DELETE("/webhooks/:id", s.handleDeleteWebhook)
+			protected.GET("/webhooks/:id/deliveries", s.handleGetWebhookDeliveries)
+
+			// OTP恢复码
+			protected.GET("/user/recovery-codes", s.handleGetRecoveryCodeStatus)
+			protected.POST("/user/recovery-codes/regenerate", s.handleRegenerateRecoveryCodes)
 
 			// 指定trader的数据（使用query参数 ?trader_id=xxx）
 			protected.GET("/status", s.handleStatus)
@@ -157,76 +267,91 @@ func (s *Server) setupRoutes() {
 			protected.GET("/decisions/latest", s.handleLatestDecisions)
 			protected.GET("/statistics", s.handleStatistics)
 			protected.GET("/performance", s.handlePerformance)
+			protected.GET("/pnl-daily", s.handleDailyPnL)
+			protected.GET("/reports", s.handleListReports)
+			protected.GET("/market/liquidations", s.handleMarketLiquidations)
+			protected.GET("/market/status", s.handleMarketStatus)
+			protected.GET("/experiments", s.handleExperiments)
+
+			// 紧急停止（停止当前用户所有交易员，可选一键平仓）
+			protected.POST("/emergency-stop", s.handleEmergencyStop)
+
+			// 审计日志（敏感配置变更记录）
+			protected.GET("/audit-log", s.handleGetAuditLog)
+
+			// 管理员接口
+			admin := protected.Group("/admin", s.adminMiddleware())
+			{
+				admin.GET("/users", s.handleAdminListUsers)
+				admin.POST("/users/:id/disable", s.handleAdminDisableUser)
+				admin.POST("/users/:id/enable", s.handleAdminEnableUser)
+				admin.POST("/users/:id/reset-otp", s.handleAdminResetUserOTP)
+				admin.PUT("/users/:id/role", s.handleAdminSetUserRole)
+				admin.DELETE("/users/:id", s.handleAdminDeleteUser)
+
+				admin.POST("/beta-codes", s.handleAdminGenerateBetaCodes)
+				admin.GET("/beta-codes", s.handleAdminListBetaCodes)
+				admin.DELETE("/beta-codes/:code", s.handleAdminRevokeBetaCode)
+
+				admin.POST("/emergency-stop", s.handleAdminEmergencyStop)
+
+				admin.GET("/system-config", s.handleAdminGetSystemConfig)
+				admin.PUT("/system-config", s.handleAdminUpdateSystemConfig)
+
+				admin.GET("/login-lockouts", s.handleAdminListLoginLockouts)
+				admin.DELETE("/login-lockouts", s.handleAdminClearLoginLockout)
+
+				admin.GET("/crypto/keys", s.handleAdminListRSAKeys)
+				admin.POST("/crypto/rotate-key", s.handleAdminRotateRSAKey)
+			}
 		}
 	}
 }
 
-// handleHealth 健康检查
+// handleHealth 健康检查（公开接口只返回时钟偏移告警的数量，各交易员的偏移详情见管理员深度检查）
 func (s *Server) handleHealth(c *gin.Context) {
+	_, warnings := s.clockSkewWarnings()
 	c.JSON(http.StatusOK, gin.H{
-		"status": "ok",
-		"time":   c.Request.Context().Value("time"),
+		"status":                   "ok",
+		"time":                     time.Now().Unix(),
+		"clock_skew_warning_count": len(warnings),
 	})
 }
 
 // handleGetSystemConfig 获取系统配置（客户端需要知道的配置）
 func (s *Server) handleGetSystemConfig(c *gin.Context) {
-	// 获取默认币种
-	defaultCoinsStr, _ := s.database.GetSystemConfig("default_coins")
-	var defaultCoins []string
-	if defaultCoinsStr != "" {
-		json.Unmarshal([]byte(defaultCoinsStr), &defaultCoins)
-	}
-	if len(defaultCoins) == 0 {
-		// 使用硬编码的默认币种
-		defaultCoins = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"}
-	}
-
-	// 获取杠杆配置
-	btcEthLeverageStr, _ := s.database.GetSystemConfig("btc_eth_leverage")
-	altcoinLeverageStr, _ := s.database.GetSystemConfig("altcoin_leverage")
-
-	btcEthLeverage := 5
-	if val, err := strconv.Atoi(btcEthLeverageStr); err == nil && val > 0 {
-		btcEthLeverage = val
-	}
-
-	altcoinLeverage := 5
-	if val, err := strconv.Atoi(altcoinLeverageStr); err == nil && val > 0 {
-		altcoinLeverage = val
-	}
-
-	// 获取内测模式配置
-	betaModeStr, _ := s.database.GetSystemConfig("beta_mode")
-	betaMode := betaModeStr == "true"
-
-	regEnabledStr, err := s.database.GetSystemConfig("registration_enabled")
-	registrationEnabled := true
-	if err == nil {
-		registrationEnabled = strings.ToLower(regEnabledStr) != "false"
-	}
-
+	sysConfig := s.database.SystemConfig()
 	c.JSON(http.StatusOK, gin.H{
-		"beta_mode":            betaMode,
-		"default_coins":        defaultCoins,
-		"btc_eth_leverage":     btcEthLeverage,
-		"altcoin_leverage":     altcoinLeverage,
-		"registration_enabled": registrationEnabled,
+		"beta_mode":            sysConfig.Bool("beta_mode", false),
+		"default_coins":        sysConfig.StringSlice("default_coins", []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"}),
+		"btc_eth_leverage":     sysConfig.Int("btc_eth_leverage", defaultLeverage),
+		"altcoin_leverage":     sysConfig.Int("altcoin_leverage", defaultLeverage),
+		"registration_enabled": sysConfig.Bool("registration_enabled", true),
 	})
 }
 
 // handleGetServerIP 获取服务器IP地址（用于白名单配置）
 func (s *Server) handleGetServerIP(c *gin.Context) {
 
-	// 首先尝试从Hook获取用户专用IP
-	userIP := hook.HookExec[hook.IpResult](hook.GETIP, c.GetString("user_id"))
-	if userIP != nil && userIP.Error() == nil {
+	// 首先尝试从Hook获取用户专用IP（兼容通过 RegisterHook 注册的旧版 GETIP）
+	var ipOut hook.GetIPOutput
+	if err := hook.GetIP.Run(c.Request.Context(), hook.GetIPInput{UserID: c.GetString("user_id")}, &ipOut); err == nil && ipOut.IP != "" {
 		c.JSON(http.StatusOK, gin.H{
-			"public_ip": userIP.GetResult(),
+			"public_ip": ipOut.IP,
 			"message":   "请将此IP地址添加到白名单中",
 		})
 		return
 	}
+	if _, legacy := hook.Hooks[hook.GETIP]; legacy {
+		userIP := hook.HookExec[hook.IpResult](hook.GETIP, c.GetString("user_id"))
+		if userIP != nil && userIP.Error() == nil {
+			c.JSON(http.StatusOK, gin.H{
+				"public_ip": userIP.GetResult(),
+				"message":   "请将此IP地址添加到白名单中",
+			})
+			return
+		}
+	}
 
 	// 尝试通过第三方API获取公网IP
 	publicIP := getPublicIPFromAPI()
@@ -353,7 +478,19 @@ func isPrivateIP(ip net.IP) bool {
 	return false
 }
 
-// getTraderFromQuery 从query参数获取trader
+// errTraderForbidden 请求的交易员不属于当前用户
+var errTraderForbidden = errors.New("无权访问该交易员")
+
+// traderQueryErrorStatus getTraderFromQuery 错误对应的HTTP状态码
+func traderQueryErrorStatus(err error) int {
+	if errors.Is(err, errTraderForbidden) {
+		return http.StatusForbidden
+	}
+	return http.StatusBadRequest
+}
+
+// getTraderFromQuery 从query参数获取当前用户的trader
+// 未指定trader_id时返回该用户的第一个trader；指定的trader不属于当前用户时返回 errTraderForbidden
 func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, string, error) {
 	userID := c.GetString("user_id")
 	traderID := c.Query("trader_id")
@@ -364,41 +501,86 @@ func (s *Server) getTraderFromQuery(c *gin.Context) (*manager.TraderManager, str
 		log.Printf("⚠️ 加载用户 %s 的交易员失败: %v", userID, err)
 	}
 
+	userTraders, err := s.database.GetTraders(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("获取交易员列表失败: %w", err)
+	}
+
 	if traderID == "" {
-		// 如果没有指定trader_id，返回该用户的第一个trader
-		ids := s.traderManager.GetTraderIDs()
-		if len(ids) == 0 {
+		// 如果没有指定trader_id，返回该用户的第一个trader（不回退到其他用户的交易员）
+		if len(userTraders) == 0 {
 			return nil, "", fmt.Errorf("没有可用的trader")
 		}
+		return s.traderManager, userTraders[0].ID, nil
+	}
 
-		// 获取用户的交易员列表，优先返回用户自己的交易员
-		userTraders, err := s.database.GetTraders(userID)
-		if err == nil && len(userTraders) > 0 {
-			traderID = userTraders[0].ID
-		} else {
-			traderID = ids[0]
+	for _, t := range userTraders {
+		if t.ID == traderID {
+			return s.traderManager, traderID, nil
 		}
 	}
 
-	return s.traderManager, traderID, nil
+	log.Printf("⚠️ 用户 %s 尝试访问不属于自己的交易员 %s", userID, traderID)
+	return nil, "", errTraderForbidden
+}
+
+// getPublicTraderFromQuery 从query参数获取trader（公开接口使用，不校验归属）
+// 未指定trader_id时返回第一个 viewerID 可访问的交易员（公开的或自己的）
+func (s *Server) getPublicTraderFromQuery(c *gin.Context, viewerID string) (string, error) {
+	traderID := c.Query("trader_id")
+	if traderID != "" {
+		return traderID, nil
+	}
+
+	for _, id := range s.traderManager.GetTraderIDs() {
+		if _, ok := s.getVisibleTrader(id, viewerID); ok {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("没有可用的trader")
 }
 
 // AI交易员管理相关结构体
 type CreateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"` // 系统提示词模板名称
-	IsCrossMargin        *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
-	UseCoinPool          bool    `json:"use_coin_pool"`
-	UseOITop             bool    `json:"use_oi_top"`
+	Name                     string  `json:"name" binding:"required"`
+	AIModelID                string  `json:"ai_model_id" binding:"required"`
+	ExchangeID               string  `json:"exchange_id" binding:"required"`
+	ExchangeAccountID        string  `json:"exchange_account_id"` // 交易所账户ID（为空时使用默认账户）
+	InitialBalance           float64 `json:"initial_balance"`
+	ScanIntervalMinutes      int     `json:"scan_interval_minutes"`
+	BTCETHLeverage           int     `json:"btc_eth_leverage"`
+	AltcoinLeverage          int     `json:"altcoin_leverage"`
+	TradingSymbols           string  `json:"trading_symbols"`
+	ExcludedSymbols          string  `json:"excluded_symbols"` // 排除的币种，逗号分隔（对币种池/OI Top等所有来源生效）
+	CustomPrompt             string  `json:"custom_prompt"`
+	OverrideBasePrompt       bool    `json:"override_base_prompt"`
+	SystemPromptTemplate     string  `json:"system_prompt_template"` // 系统提示词模板名称
+	IsCrossMargin            *bool   `json:"is_cross_margin"`        // 指针类型，nil表示使用默认值true
+	UseCoinPool              bool    `json:"use_coin_pool"`
+	UseOITop                 bool    `json:"use_oi_top"`
+	IsPublic                 bool    `json:"is_public"`                   // 是否在公开排行榜中展示，默认不公开
+	MaxDailyLossPct          float64 `json:"max_daily_loss_pct"`          // 最大日亏损百分比，超过后熔断停止开新仓（0=关闭）
+	DailyLossFlatten         bool    `json:"daily_loss_flatten"`          // 熔断时是否平掉所有持仓
+	MaxPositionValueUSDT     float64 `json:"max_position_value_usdt"`     // 单币种仓位价值上限（USDT，含已有持仓，0=不限制）
+	MaxTotalExposurePct      float64 `json:"max_total_exposure_pct"`      // 所有持仓价值合计占净值的百分比上限（0=不限制）
+	DefaultStopLossPct       float64 `json:"default_stop_loss_pct"`       // 默认止损百分比（AI未给出止损时使用，0=不使用）
+	DefaultTakeProfitPct     float64 `json:"default_take_profit_pct"`     // 默认止盈百分比（AI未给出止盈时使用，0=不使用）
+	CooldownMinutesAfterLoss int     `json:"cooldown_minutes_after_loss"` // 同币种亏损平仓后禁止开仓的分钟数（0=关闭）
+	ExecutionMode            string  `json:"execution_mode"`              // 开仓执行方式 market/limit（默认market）
+	LimitOffsetBps           float64 `json:"limit_offset_bps"`            // 限价偏移（基点，正数向对手价让价）
+	LimitTimeoutSeconds      int     `json:"limit_timeout_seconds"`       // 限价单等待成交时间（秒，默认30）
+	LimitFallback            string  `json:"limit_fallback"`              // 限价单超时后剩余数量的处理 market/cancel（默认market）
+	ExperimentEnabled        bool    `json:"experiment_enabled"`          // 是否开启提示词模板A/B实验
+	TemplateA                string  `json:"template_a"`                  // A组模板名称
+	TemplateB                string  `json:"template_b"`                  // B组模板名称
+	SplitRatio               float64 `json:"split_ratio"`                 // A组周期占比（0-1，默认0.5）
+	ExperimentMode           string  `json:"experiment_mode"`             // 分组方式 alternate/random（默认alternate）
+	FallbackAIModelID        string  `json:"fallback_ai_model_id"`        // 备用AI模型ID（主模型故障时本周期改用，为空表示不使用）
+	FallbackStickyMinutes    int     `json:"fallback_sticky_minutes"`     // 切换后持续使用备用模型的分钟数（0=每周期先尝试主模型）
+	EnsembleModelIDs         string  `json:"ensemble_model_ids"`          // 多模型共识的模型ID，逗号分隔（2-3个，为空表示不启用）
+	ConsensusRule            string  `json:"consensus_rule"`              // 共识规则 all/majority（默认majority）
+
+	Guardrails []trader.GuardrailConfig `json:"guardrails,omitempty"` // 护栏规则（按顺序执行，也可通过 /traders/:id/guardrails 管理）
 }
 
 type ModelConfig struct {
@@ -418,6 +600,9 @@ type SafeModelConfig struct {
 	Enabled         bool   `json:"enabled"`
 	CustomAPIURL    string `json:"customApiUrl"`    // 自定义API URL（通常不敏感）
 	CustomModelName string `json:"customModelName"` // 自定义模型名（不敏感）
+	Configured      bool   `json:"configured"`      // 凭证是否已配置（本地模型无需API Key）
+
+	config.AIModelParams // 请求参数（未设置时不返回，使用默认值）
 }
 
 type ExchangeConfig struct {
@@ -433,6 +618,8 @@ type ExchangeConfig struct {
 // SafeExchangeConfig 安全的交易所配置结构（不包含敏感信息）
 type SafeExchangeConfig struct {
 	ID                    string `json:"id"`
+	AccountID             string `json:"account_id,omitempty"` // 交易所账户ID（同一交易所可配置多个账户）
+	Label                 string `json:"label,omitempty"`      // 账户备注名
 	Name                  string `json:"name"`
 	Type                  string `json:"type"` // "cex" or "dex"
 	Enabled               bool   `json:"enabled"`
@@ -440,14 +627,20 @@ type SafeExchangeConfig struct {
 	HyperliquidWalletAddr string `json:"hyperliquidWalletAddr"` // Hyperliquid钱包地址（不敏感）
 	AsterUser             string `json:"asterUser"`             // Aster用户名（不敏感）
 	AsterSigner           string `json:"asterSigner"`           // Aster签名者（不敏感）
+	// API密钥合约交易权限（enabled/disabled/unknown），保存密钥时检测
+	TradePermission string `json:"trade_permission,omitempty"`
 }
 
 type UpdateModelConfigRequest struct {
 	Models map[string]struct {
-		Enabled         bool   `json:"enabled"`
-		APIKey          string `json:"api_key"`
-		CustomAPIURL    string `json:"custom_api_url"`
-		CustomModelName string `json:"custom_model_name"`
+		Enabled         bool     `json:"enabled"`
+		APIKey          string   `json:"api_key"`
+		CustomAPIURL    string   `json:"custom_api_url"`
+		CustomModelName string   `json:"custom_model_name"`
+		Temperature     *float64 `json:"temperature"`             // nil表示保持原值
+		MaxTokens       *int     `json:"max_tokens"`              // nil表示保持原值，0表示使用默认值
+		RequestTimeout  *int     `json:"request_timeout_seconds"` // nil表示保持原值，0表示使用默认值
+		ContextTokens   *int     `json:"context_tokens"`          // nil表示保持原值，0表示按服务商默认
 	} `json:"models"`
 }
 
@@ -461,7 +654,23 @@ type UpdateExchangeConfigRequest struct {
 		AsterUser             string `json:"aster_user"`
 		AsterSigner           string `json:"aster_signer"`
 		AsterPrivateKey       string `json:"aster_private_key"`
-	} `json:"exchanges"`
+	} `json:"exchanges"` // 按交易所ID更新默认账户（兼容单账户客户端）
+	Accounts []ExchangeAccountUpdate `json:"accounts"` // 按账户更新，account_id 为空时新建账户
+}
+
+// ExchangeAccountUpdate 单个交易所账户的配置
+type ExchangeAccountUpdate struct {
+	ExchangeID            string `json:"exchange_id"`
+	AccountID             string `json:"account_id"`
+	Label                 string `json:"label"`
+	Enabled               bool   `json:"enabled"`
+	APIKey                string `json:"api_key"`
+	SecretKey             string `json:"secret_key"`
+	Testnet               bool   `json:"testnet"`
+	HyperliquidWalletAddr string `json:"hyperliquid_wallet_addr"`
+	AsterUser             string `json:"aster_user"`
+	AsterSigner           string `json:"aster_signer"`
+	AsterPrivateKey       string `json:"aster_private_key"`
 }
 
 // handleCreateTrader 创建新的AI交易员
@@ -473,28 +682,223 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		return
 	}
 
+	if errs := validateCreateTraderRequest(&req); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs[0].Message, "field": errs[0].Field})
+		return
+	}
+	if errs := validateExperimentSettings(userID, req.ExperimentEnabled, req.TemplateA, req.TemplateB, req.SplitRatio, req.ExperimentMode); len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs[0].Message, "field": errs[0].Field})
+		return
+	}
+
+	// 校验引用的AI模型和交易所属于当前用户且可用
+	errs, err := s.validateTraderReferences(userID, req.AIModelID, req.ExchangeID, req.ExchangeAccountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs[0].Message, "field": errs[0].Field})
+		return
+	}
+	fallbackModelID, errs, err := s.validateFallbackModel(userID, req.AIModelID, req.FallbackAIModelID, req.FallbackStickyMinutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs[0].Message, "field": errs[0].Field})
+		return
+	}
+	req.FallbackAIModelID = fallbackModelID
+	ensembleModelIDs, consensusRule, errs, err := s.validateEnsembleModels(userID, req.EnsembleModelIDs, req.ConsensusRule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(errs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errs[0].Message, "field": errs[0].Field})
+		return
+	}
+	req.EnsembleModelIDs, req.ConsensusRule = ensembleModelIDs, consensusRule
+
+	s.createTrader(c, userID, &req)
+}
+
+// traderFieldError 交易员配置的字段级校验错误
+type traderFieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// validateCreateTraderRequest 校验创建交易员请求的字段（杠杆范围、币种格式）
+func validateCreateTraderRequest(req *CreateTraderRequest) []traderFieldError {
+	var errs []traderFieldError
+
 	// 校验杠杆值
 	if req.BTCETHLeverage < 0 || req.BTCETHLeverage > 50 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "BTC/ETH杠杆必须在1-50倍之间"})
-		return
+		errs = append(errs, traderFieldError{"btc_eth_leverage", "BTC/ETH杠杆必须在1-50倍之间"})
 	}
 	if req.AltcoinLeverage < 0 || req.AltcoinLeverage > 20 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "山寨币杠杆必须在1-20倍之间"})
-		return
+		errs = append(errs, traderFieldError{"altcoin_leverage", "山寨币杠杆必须在1-20倍之间"})
+	}
+	if err := validateMaxDailyLossPct(req.MaxDailyLossPct); err != nil {
+		errs = append(errs, *err)
+	}
+	errs = append(errs, validateExposureLimits(req.MaxPositionValueUSDT, req.MaxTotalExposurePct)...)
+	errs = append(errs, validateDefaultProtection(req.DefaultStopLossPct, req.DefaultTakeProfitPct)...)
+	if err := validateLossCooldown(req.CooldownMinutesAfterLoss); err != nil {
+		errs = append(errs, *err)
+	}
+	errs = append(errs, validateExecutionSettings(req.ExecutionMode, req.LimitOffsetBps, req.LimitTimeoutSeconds, req.LimitFallback)...)
+	if err := validateGuardrails(req.Guardrails); err != nil {
+		errs = append(errs, *err)
 	}
 
-	// 校验交易币种格式
-	if req.TradingSymbols != "" {
-		symbols := strings.Split(req.TradingSymbols, ",")
-		for _, symbol := range symbols {
-			symbol = strings.TrimSpace(symbol)
-			if symbol != "" && !strings.HasSuffix(strings.ToUpper(symbol), "USDT") {
-				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("无效的币种格式: %s，必须以USDT结尾", symbol)})
-				return
-			}
+	// 校验交易币种和排除币种格式
+	if err := validateSymbolFormat("trading_symbols", req.TradingSymbols); err != nil {
+		errs = append(errs, *err)
+	}
+	if err := validateSymbolFormat("excluded_symbols", req.ExcludedSymbols); err != nil {
+		errs = append(errs, *err)
+	}
+
+	return errs
+}
+
+// validateMaxDailyLossPct 校验最大日亏损百分比（0表示关闭熔断）
+func validateMaxDailyLossPct(pct float64) *traderFieldError {
+	if pct < 0 || pct >= 100 {
+		return &traderFieldError{"max_daily_loss_pct", "最大日亏损百分比必须在0-100之间（0表示关闭）"}
+	}
+	return nil
+}
+
+// validateExposureLimits 校验单币种仓位价值上限和总敞口上限（0表示不限制）
+func validateExposureLimits(maxPositionValueUSDT, maxTotalExposurePct float64) []traderFieldError {
+	var errs []traderFieldError
+	if maxPositionValueUSDT < 0 {
+		errs = append(errs, traderFieldError{"max_position_value_usdt", "单币种仓位价值上限不能为负数（0表示不限制）"})
+	}
+	if maxTotalExposurePct < 0 || maxTotalExposurePct > 5000 {
+		errs = append(errs, traderFieldError{"max_total_exposure_pct", "总敞口上限必须在0-5000%之间（0表示不限制）"})
+	}
+	return errs
+}
+
+// validateDefaultProtection 校验默认止损/止盈百分比（0表示不使用）
+func validateDefaultProtection(stopLossPct, takeProfitPct float64) []traderFieldError {
+	var errs []traderFieldError
+	if stopLossPct < 0 || stopLossPct >= 100 {
+		errs = append(errs, traderFieldError{"default_stop_loss_pct", "默认止损百分比必须在0-100之间（0表示不使用）"})
+	}
+	if takeProfitPct < 0 || takeProfitPct >= 100 {
+		errs = append(errs, traderFieldError{"default_take_profit_pct", "默认止盈百分比必须在0-100之间（0表示不使用）"})
+	}
+	return errs
+}
+
+// validateLossCooldown 校验亏损冷却分钟数（0表示关闭，最长7天）
+func validateLossCooldown(minutes int) *traderFieldError {
+	if minutes < 0 || minutes > 7*24*60 {
+		return &traderFieldError{"cooldown_minutes_after_loss", "亏损冷却时间必须在0-10080分钟之间（0表示关闭）"}
+	}
+	return nil
+}
+
+// validateExecutionSettings 校验开仓执行方式和限价参数（空值和0使用默认值）
+func validateExecutionSettings(mode string, offsetBps float64, timeoutSeconds int, fallback string) []traderFieldError {
+	var errs []traderFieldError
+	if mode != "" && mode != "market" && mode != "limit" {
+		errs = append(errs, traderFieldError{"execution_mode", "执行方式必须是 market 或 limit"})
+	}
+	if offsetBps < -50 || offsetBps > 50 {
+		errs = append(errs, traderFieldError{"limit_offset_bps", "限价偏移必须在-50到50基点之间"})
+	}
+	if timeoutSeconds != 0 && (timeoutSeconds < 5 || timeoutSeconds > 600) {
+		errs = append(errs, traderFieldError{"limit_timeout_seconds", "限价单等待时间必须在5-600秒之间"})
+	}
+	if fallback != "" && fallback != "market" && fallback != "cancel" {
+		errs = append(errs, traderFieldError{"limit_fallback", "超时处理方式必须是 market 或 cancel"})
+	}
+	return errs
+}
+
+// withExecutionDefaults 为未填写的执行参数补上默认值（市价开仓，限价等待30秒，超时转市价）
+func withExecutionDefaults(mode string, timeoutSeconds int, fallback string) (string, int, string) {
+	if mode == "" {
+		mode = "market"
+	}
+	if timeoutSeconds == 0 {
+		timeoutSeconds = 30
+	}
+	if fallback == "" {
+		fallback = "market"
+	}
+	return mode, timeoutSeconds, fallback
+}
+
+// validateTraderReferences 校验交易员引用的AI模型和交易所：必须是当前用户已配置并启用的记录，AI模型还需配置API Key。
+// 管理员模式下的 "admin_deepseek" 这类ID就是 admin 用户自己的模型ID，按ID精确匹配；旧数据中以 provider 作为模型ID的仍按 provider 匹配
+// exchangeAccountID 为空时校验交易所的默认账户
+func (s *Server) validateTraderReferences(userID, aiModelID, exchangeID, exchangeAccountID string) ([]traderFieldError, error) {
+	errs := make([]traderFieldError, 0)
+
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	switch model := findUserAIModel(models, aiModelID); {
+	case model == nil:
+		errs = append(errs, traderFieldError{"ai_model_id", fmt.Sprintf("AI模型不存在: %s", aiModelID)})
+	case !model.Enabled:
+		errs = append(errs, traderFieldError{"ai_model_id", fmt.Sprintf("AI模型未启用: %s", aiModelID)})
+	case mcp.IsLocalProvider(model.Provider) && model.CustomAPIURL == "":
+		errs = append(errs, traderFieldError{"ai_model_id", fmt.Sprintf("本地AI模型未配置API地址: %s", aiModelID)})
+	case !aiModelConfigured(model):
+		errs = append(errs, traderFieldError{"ai_model_id", fmt.Sprintf("AI模型未配置API Key: %s", aiModelID)})
+	}
+
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取交易所配置失败: %w", err)
+	}
+	exchange := config.FindExchangeAccount(exchanges, exchangeID, exchangeAccountID)
+	if exchange == nil && exchangeID == config.PaperExchangeID {
+		exchange = config.PaperExchangeConfig(userID) // 模拟盘无需配置API密钥
+	}
+	switch {
+	case exchange == nil && exchangeAccountID != "" && exchangeAccountID != config.DefaultExchangeAccountID:
+		errs = append(errs, traderFieldError{"exchange_account_id", fmt.Sprintf("交易所账户不存在: %s/%s", exchangeID, exchangeAccountID)})
+	case exchange == nil:
+		errs = append(errs, traderFieldError{"exchange_id", fmt.Sprintf("交易所不存在: %s", exchangeID)})
+	case !exchange.Enabled:
+		errs = append(errs, traderFieldError{"exchange_id", fmt.Sprintf("交易所未启用: %s", exchangeID)})
+	}
+
+	return errs, nil
+}
+
+// findUserAIModel 查找用户的AI模型：优先精确匹配ID，其次兼容旧数据按 provider 匹配（与 TraderManager 加载逻辑一致）
+func findUserAIModel(models []*config.AIModelConfig, id string) *config.AIModelConfig {
+	for _, model := range models {
+		if model.ID == id {
+			return model
 		}
 	}
+	for _, model := range models {
+		if model.Provider == id {
+			return model
+		}
+	}
+	return nil
+}
+
+// defaultPaperInitialBalance 模拟盘未填写初始资金时的默认虚拟资金（USDT）
+const defaultPaperInitialBalance = 10000.0
 
+// createTrader 根据已校验的请求创建交易员并加载到内存
+func (s *Server) createTrader(c *gin.Context, userID string, req *CreateTraderRequest) {
 	// 生成交易员ID (使用 UUID 确保唯一性，解决 Issue #893)
 	// 保留前缀以便调试和日志追踪
 	traderID := fmt.Sprintf("%s_%s_%s", req.ExchangeID, req.AIModelID, uuid.New().String())
@@ -505,28 +909,14 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 		isCrossMargin = *req.IsCrossMargin
 	}
 
-	// 设置杠杆默认值（从系统配置获取）
-	btcEthLeverage := 5
-	altcoinLeverage := 5
-	if req.BTCETHLeverage > 0 {
-		btcEthLeverage = req.BTCETHLeverage
-	} else {
-		// 从系统配置获取默认值
-		if btcEthLeverageStr, _ := s.database.GetSystemConfig("btc_eth_leverage"); btcEthLeverageStr != "" {
-			if val, err := strconv.Atoi(btcEthLeverageStr); err == nil && val > 0 {
-				btcEthLeverage = val
-			}
-		}
+	// 未指定杠杆时使用系统配置的默认值
+	btcEthLeverage := req.BTCETHLeverage
+	if btcEthLeverage <= 0 {
+		btcEthLeverage = s.database.SystemConfig().Int("btc_eth_leverage", defaultLeverage)
 	}
-	if req.AltcoinLeverage > 0 {
-		altcoinLeverage = req.AltcoinLeverage
-	} else {
-		// 从系统配置获取默认值
-		if altcoinLeverageStr, _ := s.database.GetSystemConfig("altcoin_leverage"); altcoinLeverageStr != "" {
-			if val, err := strconv.Atoi(altcoinLeverageStr); err == nil && val > 0 {
-				altcoinLeverage = val
-			}
-		}
+	altcoinLeverage := req.AltcoinLeverage
+	if altcoinLeverage <= 0 {
+		altcoinLeverage = s.database.SystemConfig().Int("altcoin_leverage", defaultLeverage)
 	}
 
 	// 设置系统提示词模板默认值
@@ -545,22 +935,22 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	actualBalance := req.InitialBalance // 默认使用用户输入
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
-		log.Printf("⚠️ 获取交易所配置失败，使用用户输入的初始资金: %v", err)
+		requestLogf(c, "⚠️ 获取交易所配置失败，使用用户输入的初始资金: %v", err)
 	}
 
-	// 查找匹配的交易所配置
-	var exchangeCfg *config.ExchangeConfig
-	for _, ex := range exchanges {
-		if ex.ID == req.ExchangeID {
-			exchangeCfg = ex
-			break
-		}
-	}
+	// 查找交易员使用的交易所账户配置
+	exchangeCfg := config.FindExchangeAccount(exchanges, req.ExchangeID, req.ExchangeAccountID)
 
-	if exchangeCfg == nil {
-		log.Printf("⚠️ 未找到交易所 %s 的配置，使用用户输入的初始资金", req.ExchangeID)
+	if req.ExchangeID == config.PaperExchangeID {
+		// 模拟盘没有真实账户，使用用户输入的虚拟初始资金
+		if actualBalance <= 0 {
+			actualBalance = defaultPaperInitialBalance
+		}
+		requestLogf(c, "🧪 模拟盘交易员，虚拟初始资金: %.2f USDT", actualBalance)
+	} else if exchangeCfg == nil {
+		requestLogf(c, "⚠️ 未找到交易所 %s 的配置，使用用户输入的初始资金", req.ExchangeID)
 	} else if !exchangeCfg.Enabled {
-		log.Printf("⚠️ 交易所 %s 未启用，使用用户输入的初始资金", req.ExchangeID)
+		requestLogf(c, "⚠️ 交易所 %s 未启用，使用用户输入的初始资金", req.ExchangeID)
 	} else {
 		// 根据交易所类型创建临时 trader 查询余额
 		var tempTrader trader.Trader
@@ -568,7 +958,9 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 
 		switch req.ExchangeID {
 		case "binance":
-			tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID)
+			tempTrader = trader.NewFuturesTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, userID, exchangeCfg.Testnet)
+		case "bybit":
+			tempTrader = trader.NewBybitTrader(exchangeCfg.APIKey, exchangeCfg.SecretKey, exchangeCfg.Testnet)
 		case "hyperliquid":
 			tempTrader, createErr = trader.NewHyperliquidTrader(
 				exchangeCfg.APIKey, // private key
@@ -582,71 +974,87 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 				exchangeCfg.AsterPrivateKey,
 			)
 		default:
-			log.Printf("⚠️ 不支持的交易所类型: %s，使用用户输入的初始资金", req.ExchangeID)
+			requestLogf(c, "⚠️ 不支持的交易所类型: %s，使用用户输入的初始资金", req.ExchangeID)
 		}
 
 		if createErr != nil {
-			log.Printf("⚠️ 创建临时 trader 失败，使用用户输入的初始资金: %v", createErr)
+			requestLogf(c, "⚠️ 创建临时 trader 失败，使用用户输入的初始资金: %v", createErr)
 		} else if tempTrader != nil {
 			// 查询实际余额
-			balanceInfo, balanceErr := tempTrader.GetBalance()
+			balance, balanceErr := trader.FetchBalance(tempTrader)
 			if balanceErr != nil {
-				log.Printf("⚠️ 查询交易所余额失败，使用用户输入的初始资金: %v", balanceErr)
+				requestLogf(c, "⚠️ 查询交易所余额失败，使用用户输入的初始资金: %v", balanceErr)
 			} else {
 				// 🔧 计算Total Equity = Wallet Balance + Unrealized Profit
 				// 这是账户的真实净值，用作Initial Balance的基准
-				var totalWalletBalance float64
-				var totalUnrealizedProfit float64
-
-				// 提取钱包余额
-				if wb, ok := balanceInfo["totalWalletBalance"].(float64); ok {
-					totalWalletBalance = wb
-				} else if wb, ok := balanceInfo["wallet_balance"].(float64); ok {
-					totalWalletBalance = wb
-				} else if wb, ok := balanceInfo["balance"].(float64); ok {
-					totalWalletBalance = wb
-				}
-
-				// 提取未实现盈亏
-				if up, ok := balanceInfo["totalUnrealizedProfit"].(float64); ok {
-					totalUnrealizedProfit = up
-				} else if up, ok := balanceInfo["unrealized_profit"].(float64); ok {
-					totalUnrealizedProfit = up
-				}
-
-				// 计算总净值
-				totalEquity := totalWalletBalance + totalUnrealizedProfit
-
-				if totalEquity > 0 {
+				if totalEquity := balance.Equity(); totalEquity > 0 {
 					actualBalance = totalEquity
-					log.Printf("✅ 查询到交易所实际净值: %.2f USDT (钱包: %.2f + 未实现: %.2f, 用户输入: %.2f)",
-						actualBalance, totalWalletBalance, totalUnrealizedProfit, req.InitialBalance)
+					requestLogf(c, "✅ 查询到交易所实际净值: %.2f USDT (钱包: %.2f + 未实现: %.2f, 用户输入: %.2f)",
+						actualBalance, balance.WalletBalance, balance.UnrealizedPnL, req.InitialBalance)
 				} else {
-					log.Printf("⚠️ 无法从余额信息中计算净值，使用用户输入的初始资金")
+					requestLogf(c, "⚠️ 无法从余额信息中计算净值，使用用户输入的初始资金")
 				}
 			}
+
+			// 校验交易币种在交易所存在且可交易
+			if issues := checkSymbolsOnExchange(c, tempTrader, req.TradingSymbols); len(issues) > 0 {
+				respondInvalidSymbols(c, issues)
+				return
+			}
 		}
 	}
 
+	executionMode, limitTimeoutSeconds, limitFallback := withExecutionDefaults(req.ExecutionMode, req.LimitTimeoutSeconds, req.LimitFallback)
+	splitRatio, experimentMode := withExperimentDefaults(req.SplitRatio, req.ExperimentMode)
+	guardrails, err := encodeGuardrails(req.Guardrails)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 创建交易员配置（数据库实体）
 	trader := &config.TraderRecord{
-		ID:                   traderID,
-		UserID:               userID,
-		Name:                 req.Name,
-		AIModelID:            req.AIModelID,
-		ExchangeID:           req.ExchangeID,
-		InitialBalance:       actualBalance, // 使用实际查询的余额
-		BTCETHLeverage:       btcEthLeverage,
-		AltcoinLeverage:      altcoinLeverage,
-		TradingSymbols:       req.TradingSymbols,
-		UseCoinPool:          req.UseCoinPool,
-		UseOITop:             req.UseOITop,
-		CustomPrompt:         req.CustomPrompt,
-		OverrideBasePrompt:   req.OverrideBasePrompt,
-		SystemPromptTemplate: systemPromptTemplate,
-		IsCrossMargin:        isCrossMargin,
-		ScanIntervalMinutes:  scanIntervalMinutes,
-		IsRunning:            false,
+		ID:                       traderID,
+		UserID:                   userID,
+		Name:                     req.Name,
+		AIModelID:                req.AIModelID,
+		ExchangeID:               req.ExchangeID,
+		ExchangeAccountID:        req.ExchangeAccountID,
+		InitialBalance:           actualBalance, // 使用实际查询的余额
+		BTCETHLeverage:           btcEthLeverage,
+		AltcoinLeverage:          altcoinLeverage,
+		TradingSymbols:           req.TradingSymbols,
+		ExcludedSymbols:          strings.Join(splitTradingSymbols(req.ExcludedSymbols), ","),
+		UseCoinPool:              req.UseCoinPool,
+		UseOITop:                 req.UseOITop,
+		CustomPrompt:             req.CustomPrompt,
+		OverrideBasePrompt:       req.OverrideBasePrompt,
+		SystemPromptTemplate:     systemPromptTemplate,
+		IsCrossMargin:            isCrossMargin,
+		ScanIntervalMinutes:      scanIntervalMinutes,
+		IsRunning:                false,
+		IsPublic:                 req.IsPublic,
+		MaxDailyLossPct:          req.MaxDailyLossPct,
+		DailyLossFlatten:         req.DailyLossFlatten,
+		MaxPositionValueUSDT:     req.MaxPositionValueUSDT,
+		MaxTotalExposurePct:      req.MaxTotalExposurePct,
+		DefaultStopLossPct:       req.DefaultStopLossPct,
+		DefaultTakeProfitPct:     req.DefaultTakeProfitPct,
+		CooldownMinutesAfterLoss: req.CooldownMinutesAfterLoss,
+		ExecutionMode:            executionMode,
+		LimitOffsetBps:           req.LimitOffsetBps,
+		LimitTimeoutSeconds:      limitTimeoutSeconds,
+		LimitFallback:            limitFallback,
+		ExperimentEnabled:        req.ExperimentEnabled,
+		TemplateA:                req.TemplateA,
+		TemplateB:                req.TemplateB,
+		SplitRatio:               splitRatio,
+		ExperimentMode:           experimentMode,
+		FallbackAIModelID:        req.FallbackAIModelID,
+		FallbackStickyMinutes:    req.FallbackStickyMinutes,
+		EnsembleModelIDs:         req.EnsembleModelIDs,
+		ConsensusRule:            req.ConsensusRule,
+		Guardrails:               guardrails,
 	}
 
 	// 保存到数据库
@@ -659,11 +1067,12 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 	// 立即将新交易员加载到TraderManager中
 	err = s.traderManager.LoadTraderByID(s.database, userID, traderID)
 	if err != nil {
-		log.Printf("⚠️ 加载交易员到内存失败: %v", err)
+		requestLogf(c, "⚠️ 加载交易员到内存失败: %v", err)
 		// 这里不返回错误，因为交易员已经成功创建到数据库
 	}
 
-	log.Printf("✓ 创建交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
+	requestLogf(c, "✓ 创建交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
+	s.audit(c, userID, auditTraderCreate, traderID, gin.H{"name": req.Name, "ai_model": req.AIModelID, "exchange": req.ExchangeID})
 
 	c.JSON(http.StatusCreated, gin.H{
 		"trader_id":   traderID,
@@ -675,18 +1084,42 @@ func (s *Server) handleCreateTrader(c *gin.Context) {
 
 // UpdateTraderRequest 更新交易员请求
 type UpdateTraderRequest struct {
-	Name                 string  `json:"name" binding:"required"`
-	AIModelID            string  `json:"ai_model_id" binding:"required"`
-	ExchangeID           string  `json:"exchange_id" binding:"required"`
-	InitialBalance       float64 `json:"initial_balance"`
-	ScanIntervalMinutes  int     `json:"scan_interval_minutes"`
-	BTCETHLeverage       int     `json:"btc_eth_leverage"`
-	AltcoinLeverage      int     `json:"altcoin_leverage"`
-	TradingSymbols       string  `json:"trading_symbols"`
-	CustomPrompt         string  `json:"custom_prompt"`
-	OverrideBasePrompt   bool    `json:"override_base_prompt"`
-	SystemPromptTemplate string  `json:"system_prompt_template"`
-	IsCrossMargin        *bool   `json:"is_cross_margin"`
+	Name                     string   `json:"name" binding:"required"`
+	AIModelID                string   `json:"ai_model_id" binding:"required"`
+	ExchangeID               string   `json:"exchange_id" binding:"required"`
+	ExchangeAccountID        string   `json:"exchange_account_id"` // 交易所账户ID（为空且交易所未变时保持原账户）
+	InitialBalance           float64  `json:"initial_balance"`
+	ScanIntervalMinutes      int      `json:"scan_interval_minutes"`
+	BTCETHLeverage           int      `json:"btc_eth_leverage"`
+	AltcoinLeverage          int      `json:"altcoin_leverage"`
+	TradingSymbols           string   `json:"trading_symbols"`
+	CustomPrompt             string   `json:"custom_prompt"`
+	OverrideBasePrompt       bool     `json:"override_base_prompt"`
+	SystemPromptTemplate     string   `json:"system_prompt_template"`
+	IsCrossMargin            *bool    `json:"is_cross_margin"`
+	IsPublic                 *bool    `json:"is_public"`                   // nil表示保持原值
+	MaxDailyLossPct          *float64 `json:"max_daily_loss_pct"`          // nil表示保持原值，0表示关闭日亏损熔断
+	DailyLossFlatten         *bool    `json:"daily_loss_flatten"`          // nil表示保持原值
+	MaxPositionValueUSDT     *float64 `json:"max_position_value_usdt"`     // nil表示保持原值，0表示不限制
+	MaxTotalExposurePct      *float64 `json:"max_total_exposure_pct"`      // nil表示保持原值，0表示不限制
+	DefaultStopLossPct       *float64 `json:"default_stop_loss_pct"`       // nil表示保持原值，0表示不使用默认止损
+	DefaultTakeProfitPct     *float64 `json:"default_take_profit_pct"`     // nil表示保持原值，0表示不使用默认止盈
+	CooldownMinutesAfterLoss *int     `json:"cooldown_minutes_after_loss"` // nil表示保持原值，0表示关闭亏损冷却
+	ExecutionMode            *string  `json:"execution_mode"`              // nil表示保持原值
+	LimitOffsetBps           *float64 `json:"limit_offset_bps"`            // nil表示保持原值
+	LimitTimeoutSeconds      *int     `json:"limit_timeout_seconds"`       // nil表示保持原值
+	LimitFallback            *string  `json:"limit_fallback"`              // nil表示保持原值
+	ExperimentEnabled        *bool    `json:"experiment_enabled"`          // nil表示保持原值，false表示关闭A/B实验（历史记录的分组不变）
+	TemplateA                *string  `json:"template_a"`                  // nil表示保持原值
+	TemplateB                *string  `json:"template_b"`                  // nil表示保持原值
+	SplitRatio               *float64 `json:"split_ratio"`                 // nil表示保持原值
+	ExperimentMode           *string  `json:"experiment_mode"`             // nil表示保持原值
+	FallbackAIModelID        *string  `json:"fallback_ai_model_id"`        // nil表示保持原值，空字符串表示不使用备用模型
+	FallbackStickyMinutes    *int     `json:"fallback_sticky_minutes"`     // nil表示保持原值
+	EnsembleModelIDs         *string  `json:"ensemble_model_ids"`          // nil表示保持原值，空字符串表示关闭多模型共识
+	ConsensusRule            *string  `json:"consensus_rule"`              // nil表示保持原值
+	ExcludedSymbols          *string  `json:"excluded_symbols"`            // nil表示保持原值，空字符串表示不排除任何币种
+	Restart                  bool     `json:"restart"`                     // 运行中修改模型/交易所时自动停止并重启（也可用 ?restart=true）
 }
 
 // handleUpdateTrader 更新交易员配置
@@ -720,6 +1153,22 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		return
 	}
 
+	// 未指定账户且交易所未变时保持原账户（兼容不传账户的旧客户端）
+	if req.ExchangeAccountID == "" && req.ExchangeID == existingTrader.ExchangeID {
+		req.ExchangeAccountID = existingTrader.ExchangeAccountID
+	}
+
+	// 校验引用的AI模型和交易所属于当前用户且可用
+	refErrs, err := s.validateTraderReferences(userID, req.AIModelID, req.ExchangeID, req.ExchangeAccountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(refErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": refErrs[0].Message, "field": refErrs[0].Field})
+		return
+	}
+
 	// 设置默认值
 	isCrossMargin := existingTrader.IsCrossMargin // 保持原值
 	if req.IsCrossMargin != nil {
@@ -744,6 +1193,163 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 		scanIntervalMinutes = 3
 	}
 
+	// 公开状态，未提供时保持原值
+	isPublic := existingTrader.IsPublic
+	if req.IsPublic != nil {
+		isPublic = *req.IsPublic
+	}
+
+	// 日亏损熔断设置，未提供时保持原值
+	maxDailyLossPct := existingTrader.MaxDailyLossPct
+	if req.MaxDailyLossPct != nil {
+		if fieldErr := validateMaxDailyLossPct(*req.MaxDailyLossPct); fieldErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fieldErr.Message, "field": fieldErr.Field})
+			return
+		}
+		maxDailyLossPct = *req.MaxDailyLossPct
+	}
+	dailyLossFlatten := existingTrader.DailyLossFlatten
+	if req.DailyLossFlatten != nil {
+		dailyLossFlatten = *req.DailyLossFlatten
+	}
+
+	// 仓位/敞口上限，未提供时保持原值
+	maxPositionValueUSDT := existingTrader.MaxPositionValueUSDT
+	if req.MaxPositionValueUSDT != nil {
+		maxPositionValueUSDT = *req.MaxPositionValueUSDT
+	}
+	maxTotalExposurePct := existingTrader.MaxTotalExposurePct
+	if req.MaxTotalExposurePct != nil {
+		maxTotalExposurePct = *req.MaxTotalExposurePct
+	}
+	if fieldErrs := validateExposureLimits(maxPositionValueUSDT, maxTotalExposurePct); len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErrs[0].Message, "field": fieldErrs[0].Field})
+		return
+	}
+
+	// 默认止盈止损百分比，未提供时保持原值
+	defaultStopLossPct := existingTrader.DefaultStopLossPct
+	if req.DefaultStopLossPct != nil {
+		defaultStopLossPct = *req.DefaultStopLossPct
+	}
+	defaultTakeProfitPct := existingTrader.DefaultTakeProfitPct
+	if req.DefaultTakeProfitPct != nil {
+		defaultTakeProfitPct = *req.DefaultTakeProfitPct
+	}
+	if fieldErrs := validateDefaultProtection(defaultStopLossPct, defaultTakeProfitPct); len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErrs[0].Message, "field": fieldErrs[0].Field})
+		return
+	}
+
+	// 亏损冷却，未提供时保持原值
+	cooldownMinutesAfterLoss := existingTrader.CooldownMinutesAfterLoss
+	if req.CooldownMinutesAfterLoss != nil {
+		cooldownMinutesAfterLoss = *req.CooldownMinutesAfterLoss
+	}
+	if fieldErr := validateLossCooldown(cooldownMinutesAfterLoss); fieldErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErr.Message, "field": fieldErr.Field})
+		return
+	}
+
+	// 开仓执行方式，未提供时保持原值
+	executionMode, limitOffsetBps := existingTrader.ExecutionMode, existingTrader.LimitOffsetBps
+	limitTimeoutSeconds, limitFallback := existingTrader.LimitTimeoutSeconds, existingTrader.LimitFallback
+	if req.ExecutionMode != nil {
+		executionMode = *req.ExecutionMode
+	}
+	if req.LimitOffsetBps != nil {
+		limitOffsetBps = *req.LimitOffsetBps
+	}
+	if req.LimitTimeoutSeconds != nil {
+		limitTimeoutSeconds = *req.LimitTimeoutSeconds
+	}
+	if req.LimitFallback != nil {
+		limitFallback = *req.LimitFallback
+	}
+	if fieldErrs := validateExecutionSettings(executionMode, limitOffsetBps, limitTimeoutSeconds, limitFallback); len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErrs[0].Message, "field": fieldErrs[0].Field})
+		return
+	}
+	executionMode, limitTimeoutSeconds, limitFallback = withExecutionDefaults(executionMode, limitTimeoutSeconds, limitFallback)
+
+	// A/B实验设置，未提供时保持原值
+	experimentEnabled, templateA, templateB := existingTrader.ExperimentEnabled, existingTrader.TemplateA, existingTrader.TemplateB
+	splitRatio, experimentMode := existingTrader.SplitRatio, existingTrader.ExperimentMode
+	if req.ExperimentEnabled != nil {
+		experimentEnabled = *req.ExperimentEnabled
+	}
+	if req.TemplateA != nil {
+		templateA = *req.TemplateA
+	}
+	if req.TemplateB != nil {
+		templateB = *req.TemplateB
+	}
+	if req.SplitRatio != nil {
+		splitRatio = *req.SplitRatio
+	}
+	if req.ExperimentMode != nil {
+		experimentMode = *req.ExperimentMode
+	}
+	if fieldErrs := validateExperimentSettings(userID, experimentEnabled, templateA, templateB, splitRatio, experimentMode); len(fieldErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErrs[0].Message, "field": fieldErrs[0].Field})
+		return
+	}
+	splitRatio, experimentMode = withExperimentDefaults(splitRatio, experimentMode)
+
+	// 备用AI模型，未提供时保持原值
+	fallbackModelID, fallbackStickyMinutes := existingTrader.FallbackAIModelID, existingTrader.FallbackStickyMinutes
+	if req.FallbackAIModelID != nil {
+		fallbackModelID = *req.FallbackAIModelID
+	}
+	if req.FallbackStickyMinutes != nil {
+		fallbackStickyMinutes = *req.FallbackStickyMinutes
+	}
+	fallbackModelID, fallbackErrs, err := s.validateFallbackModel(userID, req.AIModelID, fallbackModelID, fallbackStickyMinutes)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fallbackErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fallbackErrs[0].Message, "field": fallbackErrs[0].Field})
+		return
+	}
+
+	// 多模型共识，未提供时保持原值
+	ensembleModelIDs, consensusRule := existingTrader.EnsembleModelIDs, existingTrader.ConsensusRule
+	if req.EnsembleModelIDs != nil {
+		ensembleModelIDs = *req.EnsembleModelIDs
+	}
+	if req.ConsensusRule != nil {
+		consensusRule = *req.ConsensusRule
+	}
+	ensembleModelIDs, consensusRule, ensembleErrs, err := s.validateEnsembleModels(userID, ensembleModelIDs, consensusRule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(ensembleErrs) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": ensembleErrs[0].Message, "field": ensembleErrs[0].Field})
+		return
+	}
+
+	// 排除币种，未提供时保持原值
+	excludedSymbols := existingTrader.ExcludedSymbols
+	if req.ExcludedSymbols != nil {
+		if fieldErr := validateSymbolFormat("excluded_symbols", *req.ExcludedSymbols); fieldErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fieldErr.Message, "field": fieldErr.Field})
+			return
+		}
+		excludedSymbols = strings.Join(splitTradingSymbols(*req.ExcludedSymbols), ",")
+	}
+
+	// 交易币种或交易所变化时校验币种在交易所存在且可交易
+	if req.TradingSymbols != existingTrader.TradingSymbols || req.ExchangeID != existingTrader.ExchangeID || req.ExchangeAccountID != existingTrader.ExchangeAccountID {
+		if issues := s.checkUpdatedSymbols(c, userID, traderID, req.ExchangeID, req.ExchangeAccountID, req.TradingSymbols); len(issues) > 0 {
+			respondInvalidSymbols(c, issues)
+			return
+		}
+	}
+
 	// 设置提示词模板，允许更新
 	systemPromptTemplate := req.SystemPromptTemplate
 	if systemPromptTemplate == "" {
@@ -752,83 +1358,172 @@ func (s *Server) handleUpdateTrader(c *gin.Context) {
 
 	// 更新交易员配置
 	trader := &config.TraderRecord{
-		ID:                   traderID,
-		UserID:               userID,
-		Name:                 req.Name,
-		AIModelID:            req.AIModelID,
-		ExchangeID:           req.ExchangeID,
-		InitialBalance:       req.InitialBalance,
-		BTCETHLeverage:       btcEthLeverage,
-		AltcoinLeverage:      altcoinLeverage,
-		TradingSymbols:       req.TradingSymbols,
-		CustomPrompt:         req.CustomPrompt,
-		OverrideBasePrompt:   req.OverrideBasePrompt,
-		SystemPromptTemplate: systemPromptTemplate,
-		IsCrossMargin:        isCrossMargin,
-		ScanIntervalMinutes:  scanIntervalMinutes,
-		IsRunning:            existingTrader.IsRunning, // 保持原值
+		ID:                       traderID,
+		UserID:                   userID,
+		Name:                     req.Name,
+		AIModelID:                req.AIModelID,
+		ExchangeID:               req.ExchangeID,
+		ExchangeAccountID:        req.ExchangeAccountID,
+		InitialBalance:           req.InitialBalance,
+		BTCETHLeverage:           btcEthLeverage,
+		AltcoinLeverage:          altcoinLeverage,
+		TradingSymbols:           req.TradingSymbols,
+		CustomPrompt:             req.CustomPrompt,
+		OverrideBasePrompt:       req.OverrideBasePrompt,
+		SystemPromptTemplate:     systemPromptTemplate,
+		IsCrossMargin:            isCrossMargin,
+		ScanIntervalMinutes:      scanIntervalMinutes,
+		IsRunning:                existingTrader.IsRunning, // 保持原值
+		IsPublic:                 isPublic,
+		MaxDailyLossPct:          maxDailyLossPct,
+		DailyLossFlatten:         dailyLossFlatten,
+		MaxPositionValueUSDT:     maxPositionValueUSDT,
+		MaxTotalExposurePct:      maxTotalExposurePct,
+		DefaultStopLossPct:       defaultStopLossPct,
+		DefaultTakeProfitPct:     defaultTakeProfitPct,
+		CooldownMinutesAfterLoss: cooldownMinutesAfterLoss,
+		ExecutionMode:            executionMode,
+		LimitOffsetBps:           limitOffsetBps,
+		LimitTimeoutSeconds:      limitTimeoutSeconds,
+		LimitFallback:            limitFallback,
+		ExperimentEnabled:        experimentEnabled,
+		TemplateA:                templateA,
+		TemplateB:                templateB,
+		SplitRatio:               splitRatio,
+		ExperimentMode:           experimentMode,
+		FallbackAIModelID:        fallbackModelID,
+		FallbackStickyMinutes:    fallbackStickyMinutes,
+		EnsembleModelIDs:         ensembleModelIDs,
+		ConsensusRule:            consensusRule,
+		ExcludedSymbols:          excludedSymbols,
+	}
+
+	// 运行中的交易员修改模型/交易所需要新的客户端：未传 restart=true 时拒绝，避免旧实例继续在旧交易所上交易
+	restart := req.Restart || c.Query("restart") == "true"
+	credentialChanges := make([]string, 0)
+	if req.AIModelID != existingTrader.AIModelID {
+		credentialChanges = append(credentialChanges, "ai_model_id")
+	}
+	if req.ExchangeID != existingTrader.ExchangeID {
+		credentialChanges = append(credentialChanges, "exchange_id")
+	} else if trader.ExchangeAccountID != existingTrader.ExchangeAccountID {
+		credentialChanges = append(credentialChanges, "exchange_account_id")
+	}
+	liveTrader, liveErr := s.traderManager.GetTrader(traderID)
+	wasRunning := liveErr == nil && liveTrader.IsRunning()
+	if wasRunning && len(credentialChanges) > 0 && !restart {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":            "交易员运行中，不能修改AI模型或交易所；请先停止交易员，或传入 restart=true 自动停止并重启",
+			"restart_required": credentialChanges,
+		})
+		return
 	}
 
 	// 更新数据库
-	err = s.database.UpdateTrader(trader)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易员失败: %v", err)})
-		return
-	}
+	saveTrader := func() error {
+		if err := s.database.UpdateTrader(trader); err != nil {
+			return fmt.Errorf("更新交易员失败: %w", err)
+		}
 
-	// 如果请求中包含initial_balance且与现有值不同，单独更新它
-	// UpdateTrader不会更新initial_balance，需要使用专门的方法
-	if req.InitialBalance > 0 && math.Abs(req.InitialBalance-existingTrader.InitialBalance) > 0.1 {
-		err = s.database.UpdateTraderInitialBalance(userID, traderID, req.InitialBalance)
-		if err != nil {
-			log.Printf("⚠️ 更新初始余额失败: %v", err)
-			// 不返回错误，因为主要配置已更新成功
-		} else {
-			log.Printf("✓ 初始余额已更新: %.2f -> %.2f", existingTrader.InitialBalance, req.InitialBalance)
+		// 如果请求中包含initial_balance且与现有值不同，单独更新它
+		// UpdateTrader不会更新initial_balance，需要使用专门的方法
+		if req.InitialBalance > 0 && math.Abs(req.InitialBalance-existingTrader.InitialBalance) > 0.1 {
+			if err := s.database.UpdateTraderInitialBalance(userID, traderID, req.InitialBalance); err != nil {
+				requestLogf(c, "⚠️ 更新初始余额失败: %v", err)
+				// 不返回错误，因为主要配置已更新成功
+			} else {
+				requestLogf(c, "✓ 初始余额已更新: %.2f -> %.2f", existingTrader.InitialBalance, req.InitialBalance)
+			}
 		}
+		return nil
 	}
 
-	// 🔄 从内存中移除旧的trader实例，以便重新加载最新配置
-	s.traderManager.RemoveTrader(traderID)
+	appliedLive, restartRequired := []string{}, []string{}
+	reloaded := false
+	switch {
+	case wasRunning && len(credentialChanges) > 0:
+		// 停止 → 更新 → 按新配置重启，旧实例退出后才写入新配置
+		requestLogf(c, "🔁 模型/交易所已变化，重启交易员: %s", traderID)
+		if err := s.traderManager.RestartTraderWithUpdate(s.database, userID, traderID, saveTrader); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		reloaded = true
+	case wasRunning:
+		// 运行中且模型/交易所未变化时直接热更新，保留内存中的周期状态
+		if err := saveTrader(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		trader.InitialBalance = req.InitialBalance
+		appliedLive, restartRequired = applyTraderUpdateLive(liveTrader, existingTrader, trader)
+		requestLogf(c, "♻️  交易员 %s 已热更新: %v (需重启生效: %v)", traderID, appliedLive, restartRequired)
+	default:
+		if err := saveTrader(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
 
-	// 重新加载交易员到内存
-	err = s.traderManager.LoadTraderByID(s.database, userID, traderID)
-	if err != nil {
-		log.Printf("⚠️ 重新加载交易员到内存失败: %v", err)
+		// 🔄 从内存中移除旧的trader实例，以便重新加载最新配置
+		s.traderManager.RemoveTrader(traderID)
+
+		// 重新加载交易员到内存
+		if err := s.traderManager.LoadTraderByID(s.database, userID, traderID); err != nil {
+			requestLogf(c, "⚠️ 重新加载交易员到内存失败: %v", err)
+		}
+		reloaded = true
+	}
+	if isPublic != existingTrader.IsPublic {
+		// 公开状态变化立即反映到排行榜
+		s.traderManager.InvalidateCompetitionCache()
 	}
 
-	log.Printf("✓ 更新交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
+	requestLogf(c, "✓ 更新交易员成功: %s (模型: %s, 交易所: %s)", req.Name, req.AIModelID, req.ExchangeID)
+	s.audit(c, userID, auditTraderUpdate, traderID, gin.H{"name": req.Name, "ai_model": req.AIModelID, "exchange": req.ExchangeID})
 
 	c.JSON(http.StatusOK, gin.H{
-		"trader_id":   traderID,
-		"trader_name": req.Name,
-		"ai_model":    req.AIModelID,
-		"message":     "交易员更新成功",
+		"trader_id":        traderID,
+		"trader_name":      req.Name,
+		"ai_model":         req.AIModelID,
+		"message":          "交易员更新成功",
+		"reloaded":         reloaded,        // 是否重新加载了交易员实例
+		"applied_live":     appliedLive,     // 已在运行中实例上立即生效的字段
+		"restart_required": restartRequired, // 需要重启交易员才能生效的字段
 	})
 }
 
-// handleDeleteTrader 删除交易员
+// handleDeleteTrader 删除交易员（先停止并从内存移除，再删除数据库记录）
 func (s *Server) handleDeleteTrader(c *gin.Context) {
 	userID := c.GetString("user_id")
 	traderID := c.Param("id")
 
-	// 从数据库删除
-	err := s.database.DeleteTrader(userID, traderID)
+	// 检查交易员是否存在且属于当前用户
+	traders, err := s.database.GetTraders(userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除交易员失败: %v", err)})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
 		return
 	}
-
-	// 如果交易员正在运行，先停止它
-	if trader, err := s.traderManager.GetTrader(traderID); err == nil {
-		status := trader.GetStatus()
-		if isRunning, ok := status["is_running"].(bool); ok && isRunning {
-			trader.Stop()
-			log.Printf("⏹  已停止运行中的交易员: %s", traderID)
+	found := false
+	for _, trader := range traders {
+		if trader.ID == traderID {
+			found = true
+			break
 		}
 	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
 
-	log.Printf("✓ 交易员已删除: %s", traderID)
+	if err := s.traderManager.DeleteTrader(s.database, userID, traderID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("删除交易员失败: %v", err)})
+		return
+	}
+	// 公开接口立即不再返回已删除的交易员
+	s.traderManager.InvalidateCompetitionCache()
+
+	requestLogf(c, "✓ 交易员已删除: %s", traderID)
+	s.audit(c, userID, auditTraderDelete, traderID, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已删除"})
 }
 
@@ -848,10 +1543,10 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 	templateName := traderRecord.SystemPromptTemplate
 
 	// 🔥 启动前强制重新加载配置（热更新API Key）
-	log.Printf("🔄 重新加载交易员配置以应用最新API Key...")
+	requestLogf(c, "🔄 重新加载交易员配置以应用最新API Key...")
 	err = s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
-		log.Printf("❌ 重新加载配置失败: %v", err)
+		requestLogf(c, "❌ 重新加载配置失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "加载最新配置失败: " + err.Error()})
 		return
 	}
@@ -869,24 +1564,22 @@ func (s *Server) handleStartTrader(c *gin.Context) {
 		return
 	}
 
-	// 重新加载系统提示词模板（确保使用最新的硬盘文件）
+	// 重新加载系统提示词模板（确保使用最新的硬盘文件），并固定本次运行使用的模板版本
 	s.reloadPromptTemplatesWithLog(templateName)
+	trader.PinPromptTemplate()
 
-	// 启动交易员
-	go func() {
-		log.Printf("▶️  启动交易员 %s (%s)", traderID, trader.GetName())
-		if err := trader.Run(); err != nil {
-			log.Printf("❌ 交易员 %s 运行错误: %v", trader.GetName(), err)
-		}
-	}()
+	// 启动交易员（监督模式：异常退出时自动重启）
+	requestLogf(c, "▶️  启动交易员 %s (%s)", traderID, trader.GetName())
+	s.traderManager.StartTrader(trader, s.database)
 
 	// 更新数据库中的运行状态
 	err = s.database.UpdateTraderStatus(userID, traderID, true)
 	if err != nil {
-		log.Printf("⚠️  更新交易员状态失败: %v", err)
+		requestLogf(c, "⚠️  更新交易员状态失败: %v", err)
 	}
 
-	log.Printf("✓ 交易员 %s 已启动（使用最新API配置）", trader.GetName())
+	requestLogf(c, "✓ 交易员 %s 已启动（使用最新API配置）", trader.GetName())
+	s.audit(c, userID, auditTraderStart, traderID, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已启动"})
 }
 
@@ -908,23 +1601,24 @@ func (s *Server) handleStopTrader(c *gin.Context) {
 		return
 	}
 
-	// 检查交易员是否正在运行
+	// 检查交易员是否正在运行（等待自动重启的交易员也可以停止）
 	status := trader.GetStatus()
-	if isRunning, ok := status["is_running"].(bool); ok && !isRunning {
+	if isRunning, ok := status["is_running"].(bool); ok && !isRunning && !trader.RestartPending() {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员已停止"})
 		return
 	}
 
-	// 停止交易员
+	// 停止交易员（同时取消待执行的自动重启）
 	trader.Stop()
 
 	// 更新数据库中的运行状态
 	err = s.database.UpdateTraderStatus(userID, traderID, false)
 	if err != nil {
-		log.Printf("⚠️  更新交易员状态失败: %v", err)
+		requestLogf(c, "⚠️  更新交易员状态失败: %v", err)
 	}
 
-	log.Printf("⏹  交易员 %s 已停止", trader.GetName())
+	requestLogf(c, "⏹  交易员 %s 已停止", trader.GetName())
+	s.audit(c, userID, auditTraderStop, traderID, nil)
 	c.JSON(http.StatusOK, gin.H{"message": "交易员已停止"})
 }
 
@@ -955,7 +1649,7 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 	if err == nil {
 		trader.SetCustomPrompt(req.CustomPrompt)
 		trader.SetOverrideBasePrompt(req.OverrideBasePrompt)
-		log.Printf("✓ 已更新交易员 %s 的自定义prompt (覆盖基础=%v)", trader.GetName(), req.OverrideBasePrompt)
+		requestLogf(c, "✓ 已更新交易员 %s 的自定义prompt (覆盖基础=%v)", trader.GetName(), req.OverrideBasePrompt)
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "自定义prompt已更新"})
@@ -964,14 +1658,14 @@ func (s *Server) handleUpdateTraderPrompt(c *gin.Context) {
 // handleGetModelConfigs 获取AI模型配置
 func (s *Server) handleGetModelConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
-	log.Printf("🔍 查询用户 %s 的AI模型配置", userID)
+	requestLogf(c, "🔍 查询用户 %s 的AI模型配置", userID)
 	models, err := s.database.GetAIModels(userID)
 	if err != nil {
-		log.Printf("❌ 获取AI模型配置失败: %v", err)
+		requestLogf(c, "❌ 获取AI模型配置失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI模型配置失败: %v", err)})
 		return
 	}
-	log.Printf("✅ 找到 %d 个AI模型配置", len(models))
+	requestLogf(c, "✅ 找到 %d 个AI模型配置", len(models))
 
 	// 转换为安全的响应结构，移除敏感信息
 	safeModels := make([]SafeModelConfig, len(models))
@@ -983,56 +1677,59 @@ func (s *Server) handleGetModelConfigs(c *gin.Context) {
 			Enabled:         model.Enabled,
 			CustomAPIURL:    model.CustomAPIURL,
 			CustomModelName: model.CustomModelName,
+			Configured:      aiModelConfigured(model),
+			AIModelParams:   model.AIModelParams,
 		}
 	}
 
-	c.JSON(http.StatusOK, safeModels)
+	s.respondSensitiveJSON(c, http.StatusOK, safeModels)
 }
 
 // handleUpdateModelConfigs 更新AI模型配置（仅支持加密数据）
 func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	// 读取原始请求体
-	bodyBytes, err := c.GetRawData()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+	var req UpdateModelConfigRequest
+	if err := s.bindSensitiveJSON(c, "模型配置", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var req UpdateModelConfigRequest
-
-	// 尝试解析为加密payload
-	var encryptedPayload crypto.EncryptedPayload
-	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err == nil && encryptedPayload.WrappedKey != "" {
-		// 这是加密数据，进行解密
-		decrypted, err := s.cryptoHandler.cryptoService.DecryptSensitiveData(&encryptedPayload)
-		if err != nil {
-			log.Printf("❌ 解密模型配置失败 (UserID: %s): %v", userID, err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "解密数据失败"})
-			return
-		}
-
-		// 解析解密后的数据
-		if err := json.Unmarshal([]byte(decrypted), &req); err != nil {
-			log.Printf("❌ 解析解密数据失败: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
-			return
-		}
-		log.Printf("🔓 已解密模型配置数据 (UserID: %s)", userID)
-	} else {
-		// 尝试作为非加密数据解析（HTTP环境降级方案）
-		if err := json.Unmarshal(bodyBytes, &req); err != nil {
-			log.Printf("❌ 解析模型配置失败: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
+	// 更新每个模型的配置
+	for modelID, modelData := range req.Models {
+		if modelData.Enabled && isLocalModelID(modelID) && strings.TrimSpace(modelData.CustomAPIURL) == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("本地模型 %s 需要填写API地址（如 %s）", modelID, mcp.DefaultLocalBaseURL)})
 			return
 		}
-		log.Printf("⚠️  使用非加密传输更新模型配置 (UserID: %s) - 建议使用HTTPS", userID)
 	}
-
-	// 更新每个模型的配置
+	existing, err := s.database.GetAIModels(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取AI模型配置失败"})
+		return
+	}
+	warnings := make([]string, 0)
 	for modelID, modelData := range req.Models {
-		err := s.database.UpdateAIModel(userID, modelID, modelData.Enabled, modelData.APIKey, modelData.CustomAPIURL, modelData.CustomModelName)
+		var params *config.AIModelParams
+		if modelData.Temperature != nil || modelData.MaxTokens != nil || modelData.RequestTimeout != nil || modelData.ContextTokens != nil {
+			params = &config.AIModelParams{}
+			if saved := findUserAIModel(existing, modelID); saved != nil {
+				*params = saved.AIModelParams
+			}
+			if modelData.Temperature != nil {
+				params.Temperature = modelData.Temperature
+			}
+			if modelData.MaxTokens != nil {
+				params.MaxTokens = *modelData.MaxTokens
+			}
+			if modelData.RequestTimeout != nil {
+				params.RequestTimeoutSeconds = *modelData.RequestTimeout
+			}
+			if modelData.ContextTokens != nil {
+				params.ContextTokens = *modelData.ContextTokens
+			}
+			warnings = append(warnings, clampAIModelParams(modelID, params)...)
+		}
+		err := s.database.UpdateAIModel(userID, modelID, modelData.Enabled, modelData.APIKey, modelData.CustomAPIURL, modelData.CustomModelName, params)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新模型 %s 失败: %v", modelID, err)})
 			return
@@ -1042,31 +1739,38 @@ func (s *Server) handleUpdateModelConfigs(c *gin.Context) {
 	// 重新加载该用户的所有交易员，使新配置立即生效
 	err = s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
-		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
+		requestLogf(c, "⚠️ 重新加载用户交易员到内存失败: %v", err)
 		// 这里不返回错误，因为模型配置已经成功更新到数据库
 	}
 
-	log.Printf("✓ AI模型配置已更新: %+v", SanitizeModelConfigForLog(req.Models))
-	c.JSON(http.StatusOK, gin.H{"message": "模型配置已更新"})
+	requestLogf(c, "✓ AI模型配置已更新: %+v", SanitizeModelConfigForLog(req.Models))
+	s.audit(c, userID, auditModelUpdate, "", SanitizeModelConfigForLog(req.Models))
+	response := gin.H{"message": "模型配置已更新"}
+	if len(warnings) > 0 {
+		response["warnings"] = warnings
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 // handleGetExchangeConfigs 获取交易所配置
 func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
-	log.Printf("🔍 查询用户 %s 的交易所配置", userID)
+	requestLogf(c, "🔍 查询用户 %s 的交易所配置", userID)
 	exchanges, err := s.database.GetExchanges(userID)
 	if err != nil {
-		log.Printf("❌ 获取交易所配置失败: %v", err)
+		requestLogf(c, "❌ 获取交易所配置失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易所配置失败: %v", err)})
 		return
 	}
-	log.Printf("✅ 找到 %d 个交易所配置", len(exchanges))
+	requestLogf(c, "✅ 找到 %d 个交易所配置", len(exchanges))
 
 	// 转换为安全的响应结构，移除敏感信息
 	safeExchanges := make([]SafeExchangeConfig, len(exchanges))
 	for i, exchange := range exchanges {
 		safeExchanges[i] = SafeExchangeConfig{
 			ID:                    exchange.ID,
+			AccountID:             exchange.AccountID,
+			Label:                 exchange.Label,
 			Name:                  exchange.Name,
 			Type:                  exchange.Type,
 			Enabled:               exchange.Enabled,
@@ -1074,71 +1778,178 @@ func (s *Server) handleGetExchangeConfigs(c *gin.Context) {
 			HyperliquidWalletAddr: exchange.HyperliquidWalletAddr,
 			AsterUser:             exchange.AsterUser,
 			AsterSigner:           exchange.AsterSigner,
+			TradePermission:       exchange.TradePermission,
 		}
 	}
 
-	c.JSON(http.StatusOK, safeExchanges)
+	s.respondSensitiveJSON(c, http.StatusOK, safeExchanges)
 }
 
 // handleUpdateExchangeConfigs 更新交易所配置（支持加密和非加密数据）
 func (s *Server) handleUpdateExchangeConfigs(c *gin.Context) {
 	userID := c.GetString("user_id")
 
-	// 读取原始请求体
-	bodyBytes, err := c.GetRawData()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败"})
+	var req UpdateExchangeConfigRequest
+	if err := s.bindSensitiveJSON(c, "交易所配置", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	var req UpdateExchangeConfigRequest
+	// 提交了新密钥的账户，保存后检测API权限
+	type accountRef struct{ exchangeID, accountID string }
+	var keysChanged []accountRef
 
-	// 尝试解析为加密payload
-	var encryptedPayload crypto.EncryptedPayload
-	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err == nil && encryptedPayload.WrappedKey != "" {
-		// 这是加密数据，进行解密
-		decrypted, err := s.cryptoHandler.cryptoService.DecryptSensitiveData(&encryptedPayload)
+	// 更新每个交易所的配置
+	for exchangeID, exchangeData := range req.Exchanges {
+		err := s.database.UpdateExchange(userID, exchangeID, exchangeData.Enabled, exchangeData.APIKey, exchangeData.SecretKey, exchangeData.Testnet, exchangeData.HyperliquidWalletAddr, exchangeData.AsterUser, exchangeData.AsterSigner, exchangeData.AsterPrivateKey)
 		if err != nil {
-			log.Printf("❌ 解密交易所配置失败 (UserID: %s): %v", userID, err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "解密数据失败"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所 %s 失败: %v", exchangeID, err)})
 			return
 		}
+		if exchangeData.APIKey != "" || exchangeData.AsterPrivateKey != "" {
+			keysChanged = append(keysChanged, accountRef{exchangeID, config.DefaultExchangeAccountID})
+		}
+	}
 
-		// 解析解密后的数据
-		if err := json.Unmarshal([]byte(decrypted), &req); err != nil {
-			log.Printf("❌ 解析解密数据失败: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "解析解密数据失败"})
+	// 按账户更新：已有账户必须存在，account_id 为空时新建
+	var createdAccounts []string
+	if len(req.Accounts) > 0 {
+		exchanges, err := s.database.GetExchanges(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易所配置失败"})
 			return
 		}
-		log.Printf("🔓 已解密交易所配置数据 (UserID: %s)", userID)
-	} else {
-		// 尝试作为非加密数据解析（HTTP环境降级方案）
-		if err := json.Unmarshal(bodyBytes, &req); err != nil {
-			log.Printf("❌ 解析交易所配置失败: %v", err)
-			c.JSON(http.StatusBadRequest, gin.H{"error": "请求格式错误"})
-			return
+		for _, account := range req.Accounts {
+			if account.ExchangeID == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "交易所账户缺少 exchange_id"})
+				return
+			}
+			if account.AccountID == "" {
+				accountID, err := s.database.CreateExchangeAccount(userID, account.ExchangeID, account.Label, account.Enabled, account.APIKey, account.SecretKey, account.Testnet, account.HyperliquidWalletAddr, account.AsterUser, account.AsterSigner, account.AsterPrivateKey)
+				if err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("新建交易所 %s 账户失败: %v", account.ExchangeID, err)})
+					return
+				}
+				createdAccounts = append(createdAccounts, accountID)
+				keysChanged = append(keysChanged, accountRef{account.ExchangeID, accountID})
+				continue
+			}
+			if account.AccountID != config.DefaultExchangeAccountID && config.FindExchangeAccount(exchanges, account.ExchangeID, account.AccountID) == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("交易所账户不存在: %s/%s", account.ExchangeID, account.AccountID)})
+				return
+			}
+			err := s.database.UpdateExchangeAccount(userID, account.ExchangeID, account.AccountID, account.Label, account.Enabled, account.APIKey, account.SecretKey, account.Testnet, account.HyperliquidWalletAddr, account.AsterUser, account.AsterSigner, account.AsterPrivateKey)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所账户 %s/%s 失败: %v", account.ExchangeID, account.AccountID, err)})
+				return
+			}
+			if account.APIKey != "" || account.AsterPrivateKey != "" {
+				keysChanged = append(keysChanged, accountRef{account.ExchangeID, account.AccountID})
+			}
 		}
-		log.Printf("⚠️  使用非加密传输更新交易所配置 (UserID: %s) - 建议使用HTTPS", userID)
 	}
 
-	// 更新每个交易所的配置
-	for exchangeID, exchangeData := range req.Exchanges {
-		err := s.database.UpdateExchange(userID, exchangeID, exchangeData.Enabled, exchangeData.APIKey, exchangeData.SecretKey, exchangeData.Testnet, exchangeData.HyperliquidWalletAddr, exchangeData.AsterUser, exchangeData.AsterSigner, exchangeData.AsterPrivateKey)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("更新交易所 %s 失败: %v", exchangeID, err)})
-			return
+	var permissions []*ExchangePermissionResult
+	for _, ref := range keysChanged {
+		if result := s.detectExchangePermissions(c, userID, ref.exchangeID, ref.accountID); result != nil {
+			permissions = append(permissions, result)
 		}
 	}
 
 	// 重新加载该用户的所有交易员，使新配置立即生效
-	err = s.traderManager.LoadUserTraders(s.database, userID)
+	err := s.traderManager.LoadUserTraders(s.database, userID)
 	if err != nil {
-		log.Printf("⚠️ 重新加载用户交易员到内存失败: %v", err)
+		requestLogf(c, "⚠️ 重新加载用户交易员到内存失败: %v", err)
 		// 这里不返回错误，因为交易所配置已经成功更新到数据库
 	}
 
-	log.Printf("✓ 交易所配置已更新: %+v", SanitizeExchangeConfigForLog(req.Exchanges))
-	c.JSON(http.StatusOK, gin.H{"message": "交易所配置已更新"})
+	safeConfig := SanitizeExchangeConfigForLog(req.Exchanges)
+	if len(req.Accounts) > 0 {
+		safeConfig["accounts"] = SanitizeExchangeAccountsForLog(req.Accounts)
+	}
+	requestLogf(c, "✓ 交易所配置已更新: %+v", safeConfig)
+	s.audit(c, userID, auditExchangeUpdate, "", safeConfig)
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "交易所配置已更新",
+		"created_accounts": createdAccounts,
+		"permissions":      permissions,
+		"warnings":         permissionWarnings(permissions),
+	})
+}
+
+// handleDeleteExchangeAccount 删除交易所账户（默认账户和仍被交易员使用的账户不可删除）
+func (s *Server) handleDeleteExchangeAccount(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exchangeID := c.Param("exchange_id")
+	accountID := c.Param("account_id")
+
+	if accountID == config.DefaultExchangeAccountID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "默认账户不能删除"})
+		return
+	}
+
+	traders, err := s.database.GetTraders(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取交易员列表失败"})
+		return
+	}
+	var usedBy []string
+	for _, trader := range traders {
+		if trader.ExchangeID == exchangeID && trader.ExchangeAccountID == accountID {
+			usedBy = append(usedBy, trader.ID)
+		}
+	}
+	if len(usedBy) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "该账户仍被交易员使用，请先修改或删除这些交易员", "trader_ids": usedBy})
+		return
+	}
+
+	if err := s.database.DeleteExchangeAccount(userID, exchangeID, accountID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "🗑️ 用户 %s 删除交易所账户: %s/%s", userID, exchangeID, accountID)
+	s.audit(c, userID, auditExchangeDelete, exchangeID, gin.H{"account_id": accountID})
+	c.JSON(http.StatusOK, gin.H{"message": "交易所账户已删除"})
+}
+
+// bindSensitiveJSON 解析可能经过加密的敏感请求体：优先按 crypto.EncryptedPayload 解密，否则按明文JSON解析（HTTP环境降级方案）
+func (s *Server) bindSensitiveJSON(c *gin.Context, what string, out interface{}) error {
+	userID := c.GetString("user_id")
+
+	// 读取原始请求体
+	bodyBytes, err := c.GetRawData()
+	if err != nil {
+		return fmt.Errorf("读取请求体失败")
+	}
+
+	// 尝试解析为加密payload
+	var encryptedPayload crypto.EncryptedPayload
+	if err := json.Unmarshal(bodyBytes, &encryptedPayload); err == nil && encryptedPayload.WrappedKey != "" {
+		// 这是加密数据，进行解密
+		decrypted, err := s.cryptoHandler.cryptoService.DecryptSensitiveData(&encryptedPayload, userID)
+		if err != nil {
+			requestLogf(c, "❌ 解密%s失败 (UserID: %s): %v", what, userID, err)
+			return fmt.Errorf("解密数据失败")
+		}
+
+		// 解析解密后的数据
+		if err := json.Unmarshal([]byte(decrypted), out); err != nil {
+			requestLogf(c, "❌ 解析解密数据失败: %v", err)
+			return fmt.Errorf("解析解密数据失败")
+		}
+		requestLogf(c, "🔓 已解密%s数据 (UserID: %s)", what, userID)
+		return nil
+	}
+
+	// 尝试作为非加密数据解析（HTTP环境降级方案）
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		requestLogf(c, "❌ 解析%s失败: %v", what, err)
+		return fmt.Errorf("请求格式错误")
+	}
+	requestLogf(c, "⚠️  使用非加密传输%s (UserID: %s) - 建议使用HTTPS", what, userID)
+	return nil
 }
 
 // handleUpdateExchangeKeysOnly 仅更新数据库中的API密钥（不影响运行中的交易员）
@@ -1147,6 +1958,7 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 	exchangeID := c.Param("exchange_id")
 
 	var req struct {
+		AccountID string `json:"account_id"` // 为空时更新默认账户
 		APIKey    string `json:"api_key" binding:"required"`
 		SecretKey string `json:"secret_key" binding:"required"`
 	}
@@ -1156,7 +1968,7 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🔑 [密钥更新] 用户 %s 请求更新交易所 %s 的API密钥（仅数据库）", userID, exchangeID)
+	requestLogf(c, "🔑 [密钥更新] 用户 %s 请求更新交易所 %s 的API密钥（仅数据库）", userID, exchangeID)
 
 	// 1. 获取现有配置
 	exchanges, err := s.database.GetExchanges(userID)
@@ -1165,14 +1977,7 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 		return
 	}
 
-	var existingExchange *config.ExchangeConfig
-	for _, ex := range exchanges {
-		if ex.ID == exchangeID {
-			existingExchange = ex
-			break
-		}
-	}
-
+	existingExchange := config.FindExchangeAccount(exchanges, exchangeID, req.AccountID)
 	if existingExchange == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "交易所配置不存在"})
 		return
@@ -1188,7 +1993,7 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 	var affectedTraders []string
 	var runningTraders []string
 	for _, trader := range traders {
-		if trader.ExchangeID == exchangeID {
+		if trader.ExchangeID == exchangeID && trader.ExchangeAccountID == existingExchange.AccountID {
 			affectedTraders = append(affectedTraders, trader.ID)
 			if trader.IsRunning {
 				runningTraders = append(runningTraders, trader.ID)
@@ -1196,13 +2001,15 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 		}
 	}
 
-	log.Printf("📊 [密钥更新] 发现 %d 个使用 %s 的交易员，其中 %d 个正在运行", 
+	requestLogf(c, "📊 [密钥更新] 发现 %d 个使用 %s 的交易员，其中 %d 个正在运行", 
 		len(affectedTraders), exchangeID, len(runningTraders))
 
 	// 3. 仅更新数据库中的API密钥（保留其他配置）
-	err = s.database.UpdateExchange(
+	err = s.database.UpdateExchangeAccount(
 		userID,
 		exchangeID,
+		existingExchange.AccountID,
+		"",
 		existingExchange.Enabled,
 		req.APIKey,
 		req.SecretKey,
@@ -1218,8 +2025,14 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 		return
 	}
 
-	log.Printf("✅ [密钥更新] API密钥已更新到数据库")
-	log.Printf("ℹ️  [密钥更新] 运行中的交易员将继续使用旧密钥，直到下次重启")
+	requestLogf(c, "✅ [密钥更新] API密钥已更新到数据库")
+	permissions := s.detectExchangePermissions(c, userID, exchangeID, existingExchange.AccountID)
+	s.audit(c, userID, auditExchangeUpdateKeys, exchangeID, gin.H{
+		"account_id": existingExchange.AccountID,
+		"api_key":    MaskSensitiveString(req.APIKey),
+		"secret_key": MaskSensitiveString(req.SecretKey),
+	})
+	requestLogf(c, "ℹ️  [密钥更新] 运行中的交易员将继续使用旧密钥，直到下次重启")
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":          "API密钥已更新到数据库",
@@ -1227,6 +2040,8 @@ func (s *Server) handleUpdateExchangeKeysOnly(c *gin.Context) {
 		"running_traders":  len(runningTraders),
 		"trader_ids":       affectedTraders,
 		"note":             "运行中的交易员将在下次重启时使用新密钥",
+		"permissions":      permissions,
+		"warnings":         permissionWarnings([]*ExchangePermissionResult{permissions}),
 	})
 }
 
@@ -1236,7 +2051,7 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var req struct {
-		APIKey string `json:"api_key" binding:"required"`
+		APIKey string `json:"api_key"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -1244,7 +2059,7 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 		return
 	}
 
-	log.Printf("🔑 [AI密钥更新] 用户 %s 请求更新DeepSeek和Qwen的API密钥（仅数据库）", userID)
+	requestLogf(c, "🔑 [AI密钥更新] 用户 %s 请求更新DeepSeek和Qwen的API密钥（仅数据库）", userID)
 
 	// 1. 获取现有AI模型配置
 	aiModels, err := s.database.GetAIModels(userID)
@@ -1253,16 +2068,33 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 		return
 	}
 
-	// 2. 查找DeepSeek和Qwen模型
+	// 2. 查找DeepSeek和Qwen模型（本地模型无需密钥，视为已配置）
 	var deepseekModel *config.AIModelConfig
 	var qwenModel *config.AIModelConfig
+	localModels := []string{}
 
 	for _, model := range aiModels {
 		if model.Provider == "deepseek" {
 			deepseekModel = model
 		} else if model.Provider == "qwen" {
 			qwenModel = model
+		} else if mcp.IsLocalProvider(model.Provider) && aiModelConfigured(model) {
+			localModels = append(localModels, model.ID)
+		}
+	}
+
+	// 未提供密钥：仅本地模型可用时不算错误
+	if strings.TrimSpace(req.APIKey) == "" {
+		if len(localModels) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供API密钥"})
+			return
 		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "本地模型无需API密钥，未更新任何密钥",
+			"updated_models": []string{},
+			"local_models":   localModels,
+		})
+		return
 	}
 
 	updatedModels := []string{}
@@ -1278,16 +2110,17 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 			req.APIKey,
 			deepseekModel.CustomAPIURL,
 			deepseekModel.CustomModelName,
+			nil,
 		)
 		if err != nil {
-			log.Printf("❌ [AI密钥更新] 更新DeepSeek模型失败: %v", err)
+			requestLogf(c, "❌ [AI密钥更新] 更新DeepSeek模型失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新DeepSeek模型密钥失败: " + err.Error()})
 			return
 		}
 		updatedModels = append(updatedModels, "deepseek")
-		log.Printf("✅ [AI密钥更新] DeepSeek模型密钥已更新")
+		requestLogf(c, "✅ [AI密钥更新] DeepSeek模型密钥已更新")
 	} else {
-		log.Printf("⚠️  [AI密钥更新] 未找到DeepSeek模型配置")
+		requestLogf(c, "⚠️  [AI密钥更新] 未找到DeepSeek模型配置")
 	}
 
 	// 4. 更新Qwen模型
@@ -1299,16 +2132,17 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 			req.APIKey,
 			qwenModel.CustomAPIURL,
 			qwenModel.CustomModelName,
+			nil,
 		)
 		if err != nil {
-			log.Printf("❌ [AI密钥更新] 更新Qwen模型失败: %v", err)
+			requestLogf(c, "❌ [AI密钥更新] 更新Qwen模型失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "更新Qwen模型密钥失败: " + err.Error()})
 			return
 		}
 		updatedModels = append(updatedModels, "qwen")
-		log.Printf("✅ [AI密钥更新] Qwen模型密钥已更新")
+		requestLogf(c, "✅ [AI密钥更新] Qwen模型密钥已更新")
 	} else {
-		log.Printf("⚠️  [AI密钥更新] 未找到Qwen模型配置")
+		requestLogf(c, "⚠️  [AI密钥更新] 未找到Qwen模型配置")
 	}
 
 	if len(updatedModels) == 0 {
@@ -1319,7 +2153,7 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 	// 5. 获取使用这些模型的交易员信息
 	traders, err := s.database.GetTraders(userID)
 	if err != nil {
-		log.Printf("⚠️  [AI密钥更新] 获取交易员列表失败: %v", err)
+		requestLogf(c, "⚠️  [AI密钥更新] 获取交易员列表失败: %v", err)
 	} else {
 		for _, trader := range traders {
 			// 检查交易员使用的模型是否是deepseek或qwen
@@ -1338,9 +2172,10 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 		}
 	}
 
-	log.Printf("📊 [AI密钥更新] 已更新 %d 个模型（%v），影响 %d 个交易员，其中 %d 个正在运行",
+	requestLogf(c, "📊 [AI密钥更新] 已更新 %d 个模型（%v），影响 %d 个交易员，其中 %d 个正在运行",
 		len(updatedModels), updatedModels, len(affectedTraders), len(runningTraders))
-	log.Printf("ℹ️  [AI密钥更新] 运行中的交易员将继续使用旧密钥，直到下次重启")
+	s.audit(c, userID, auditModelUpdateKeys, "", gin.H{"models": updatedModels, "api_key": MaskSensitiveString(req.APIKey)})
+	requestLogf(c, "ℹ️  [AI密钥更新] 运行中的交易员将继续使用旧密钥，直到下次重启")
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":          "AI模型API密钥已更新到数据库",
@@ -1348,6 +2183,7 @@ func (s *Server) handleUpdateAIModelKeysOnly(c *gin.Context) {
 		"affected_traders": len(affectedTraders),
 		"running_traders":  len(runningTraders),
 		"trader_ids":       affectedTraders,
+		"local_models":     localModels,
 		"note":             "运行中的交易员将在下次重启时使用新密钥",
 	})
 }
@@ -1358,16 +2194,21 @@ func (s *Server) handleGetUserSignalSource(c *gin.Context) {
 	source, err := s.database.GetUserSignalSource(userID)
 	if err != nil {
 		// 如果配置不存在，返回空配置而不是404错误
-		c.JSON(http.StatusOK, gin.H{
-			"coin_pool_url": "",
-			"oi_top_url":    "",
+		s.respondSensitiveJSON(c, http.StatusOK, gin.H{
+			"coin_pool_url":   "",
+			"oi_top_url":      "",
+			"header_names":    []string{},
+			"timeout_seconds": 0,
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"coin_pool_url": source.CoinPoolURL,
-		"oi_top_url":    source.OITopURL,
+	// 请求头只返回名称，不返回值（可能包含认证凭证）
+	s.respondSensitiveJSON(c, http.StatusOK, gin.H{
+		"coin_pool_url":   source.CoinPoolURL,
+		"oi_top_url":      source.OITopURL,
+		"header_names":    signalSourceHeaderNames(source.Headers),
+		"timeout_seconds": source.TimeoutSeconds,
 	})
 }
 
@@ -1377,23 +2218,174 @@ func (s *Server) handleSaveUserSignalSource(c *gin.Context) {
 	var req struct {
 		CoinPoolURL string `json:"coin_pool_url"`
 		OITopURL    string `json:"oi_top_url"`
+		// Headers 请求头（不提供时保留已保存的请求头；值为空的名称保留原值，未列出的名称被删除）
+		Headers        map[string]string `json:"headers"`
+		TimeoutSeconds *int              `json:"timeout_seconds"` // 请求超时秒数（不提供时保留原值，0表示默认）
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	for _, u := range []string{req.CoinPoolURL, req.OITopURL} {
+		if u == "" {
+			continue
+		}
+		if err := signalsource.ValidateURL(u); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	// 合并请求头和超时设置（GET 接口不返回请求头的值，前端提交空值表示保留）
+	var saved *config.UserSignalSource
+	if existing, err := s.database.GetUserSignalSource(userID); err == nil {
+		saved = existing
+	}
+	headers, timeoutSeconds, fieldErr := mergeSignalSourceOptions(saved, req.Headers, req.TimeoutSeconds)
+	if fieldErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErr.Message, "fields": []traderFieldError{*fieldErr}})
+		return
+	}
 
 	err := s.database.CreateUserSignalSource(userID, req.CoinPoolURL, req.OITopURL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存用户信号源配置失败: %v", err)})
 		return
 	}
+	if err := s.database.SetUserSignalSourceOptions(userID, headers, timeoutSeconds); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存信号源请求头失败: %v", err)})
+		return
+	}
 
-	log.Printf("✓ 用户信号源配置已保存: user=%s, coin_pool=%s, oi_top=%s", userID, req.CoinPoolURL, req.OITopURL)
+	log.Printf("✓ 用户信号源配置已保存: user=%s, coin_pool=%s, oi_top=%s, headers=%v", userID, req.CoinPoolURL, req.OITopURL, signalSourceHeaderNames(headers))
 	c.JSON(http.StatusOK, gin.H{"message": "用户信号源配置已保存"})
 }
 
+// handleGetUserSpend 获取用户AI花费统计（月度汇总、每日序列、按交易员拆分）
+func (s *Server) handleGetUserSpend(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	// 可选参数 month=YYYY-MM，默认当前月份（UTC）
+	monthTime := time.Now()
+	if month := c.Query("month"); month != "" {
+		parsed, err := time.Parse("2006-01", month)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "month 参数格式错误，应为 YYYY-MM"})
+			return
+		}
+		monthTime = parsed
+	}
+	start, end := config.SpendMonthRange(monthTime)
+
+	settings, err := s.database.GetUserSpendSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取花费预算配置失败: %v", err)})
+		return
+	}
+
+	total, err := s.database.GetUserSpendTotal(userID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI花费失败: %v", err)})
+		return
+	}
+
+	daily, err := s.database.GetUserSpendDaily(userID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取每日AI花费失败: %v", err)})
+		return
+	}
+
+	byTrader, err := s.database.GetUserSpendByTrader(userID, start, end)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取交易员AI花费失败: %v", err)})
+		return
+	}
+
+	usedPct := 0.0
+	if settings.MonthlyBudgetUSD > 0 {
+		usedPct = total / settings.MonthlyBudgetUSD * 100
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"month":              start.Format("2006-01"),
+		"spent_usd":          total,
+		"monthly_budget_usd": settings.MonthlyBudgetUSD,
+		"used_pct":           usedPct,
+		"alert_thresholds":   settings.AlertThresholds,
+		"downgrade_enabled":  settings.DowngradeEnabled,
+		"fallback_model_id":  settings.FallbackModelID,
+		"daily":              daily,
+		"traders":            byTrader,
+	})
+}
+
+// handleGetUserSpendSettings 获取用户AI花费预算配置
+func (s *Server) handleGetUserSpendSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+	settings, err := s.database.GetUserSpendSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取花费预算配置失败: %v", err)})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// handleUpdateUserSpendSettings 更新用户AI花费预算配置
+func (s *Server) handleUpdateUserSpendSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		MonthlyBudgetUSD float64 `json:"monthly_budget_usd"`
+		AlertThresholds  []int   `json:"alert_thresholds"`
+		DowngradeEnabled bool    `json:"downgrade_enabled"`
+		FallbackModelID  string  `json:"fallback_model_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 备用模型必须属于当前用户
+	if req.FallbackModelID != "" {
+		models, err := s.database.GetAIModels(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("获取AI模型配置失败: %v", err)})
+			return
+		}
+		found := false
+		for _, m := range models {
+			if m.ID == req.FallbackModelID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "备用模型不存在"})
+			return
+		}
+	}
+	if req.DowngradeEnabled && req.FallbackModelID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "启用超预算降级时必须指定备用模型"})
+		return
+	}
+
+	settings := &config.UserSpendSettings{
+		UserID:           userID,
+		MonthlyBudgetUSD: req.MonthlyBudgetUSD,
+		AlertThresholds:  req.AlertThresholds,
+		DowngradeEnabled: req.DowngradeEnabled,
+		FallbackModelID:  req.FallbackModelID,
+	}
+	if err := s.database.UpdateUserSpendSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Printf("✓ 用户AI花费预算已更新: user=%s, budget=$%.2f, thresholds=%v, downgrade=%v, fallback=%s",
+		userID, req.MonthlyBudgetUSD, req.AlertThresholds, req.DowngradeEnabled, req.FallbackModelID)
+	c.JSON(http.StatusOK, gin.H{"message": "AI花费预算配置已保存"})
+}
+
 // handleTraderList trader列表
 func (s *Server) handleTraderList(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -1421,9 +2413,12 @@ func (s *Server) handleTraderList(c *gin.Context) {
 			"trader_name":            trader.Name,
 			"ai_model":               trader.AIModelID, // 使用完整 ID
 			"exchange_id":            trader.ExchangeID,
+			"exchange_account_id":    trader.ExchangeAccountID,
 			"is_running":             isRunning,
 			"initial_balance":        trader.InitialBalance,
 			"system_prompt_template": trader.SystemPromptTemplate,
+			"last_error":             trader.LastError,
+			"is_public":              trader.IsPublic,
 		})
 	}
 
@@ -1459,22 +2454,45 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 	aiModelID := traderConfig.AIModelID
 
 	result := map[string]interface{}{
-		"trader_id":              traderConfig.ID,
-		"trader_name":            traderConfig.Name,
-		"ai_model":               aiModelID,
-		"exchange_id":            traderConfig.ExchangeID,
-		"initial_balance":        traderConfig.InitialBalance,
-		"scan_interval_minutes":  traderConfig.ScanIntervalMinutes,
-		"btc_eth_leverage":       traderConfig.BTCETHLeverage,
-		"altcoin_leverage":       traderConfig.AltcoinLeverage,
-		"trading_symbols":        traderConfig.TradingSymbols,
-		"custom_prompt":          traderConfig.CustomPrompt,
-		"override_base_prompt":   traderConfig.OverrideBasePrompt,
-		"system_prompt_template": traderConfig.SystemPromptTemplate,
-		"is_cross_margin":        traderConfig.IsCrossMargin,
-		"use_coin_pool":          traderConfig.UseCoinPool,
-		"use_oi_top":             traderConfig.UseOITop,
-		"is_running":             isRunning,
+		"trader_id":                   traderConfig.ID,
+		"trader_name":                 traderConfig.Name,
+		"ai_model":                    aiModelID,
+		"exchange_id":                 traderConfig.ExchangeID,
+		"exchange_account_id":         traderConfig.ExchangeAccountID,
+		"initial_balance":             traderConfig.InitialBalance,
+		"scan_interval_minutes":       traderConfig.ScanIntervalMinutes,
+		"btc_eth_leverage":            traderConfig.BTCETHLeverage,
+		"altcoin_leverage":            traderConfig.AltcoinLeverage,
+		"trading_symbols":             traderConfig.TradingSymbols,
+		"excluded_symbols":            traderConfig.ExcludedSymbols,
+		"custom_prompt":               traderConfig.CustomPrompt,
+		"override_base_prompt":        traderConfig.OverrideBasePrompt,
+		"system_prompt_template":      traderConfig.SystemPromptTemplate,
+		"is_cross_margin":             traderConfig.IsCrossMargin,
+		"use_coin_pool":               traderConfig.UseCoinPool,
+		"use_oi_top":                  traderConfig.UseOITop,
+		"is_running":                  isRunning,
+		"is_public":                   traderConfig.IsPublic,
+		"max_daily_loss_pct":          traderConfig.MaxDailyLossPct,
+		"daily_loss_flatten":          traderConfig.DailyLossFlatten,
+		"max_position_value_usdt":     traderConfig.MaxPositionValueUSDT,
+		"max_total_exposure_pct":      traderConfig.MaxTotalExposurePct,
+		"default_stop_loss_pct":       traderConfig.DefaultStopLossPct,
+		"default_take_profit_pct":     traderConfig.DefaultTakeProfitPct,
+		"cooldown_minutes_after_loss": traderConfig.CooldownMinutesAfterLoss,
+		"execution_mode":              traderConfig.ExecutionMode,
+		"limit_offset_bps":            traderConfig.LimitOffsetBps,
+		"limit_timeout_seconds":       traderConfig.LimitTimeoutSeconds,
+		"limit_fallback":              traderConfig.LimitFallback,
+		"experiment_enabled":          traderConfig.ExperimentEnabled,
+		"template_a":                  traderConfig.TemplateA,
+		"template_b":                  traderConfig.TemplateB,
+		"split_ratio":                 traderConfig.SplitRatio,
+		"experiment_mode":             traderConfig.ExperimentMode,
+		"fallback_ai_model_id":        traderConfig.FallbackAIModelID,
+		"fallback_sticky_minutes":     traderConfig.FallbackStickyMinutes,
+		"ensemble_model_ids":          traderConfig.EnsembleModelIDs,
+		"consensus_rule":              traderConfig.ConsensusRule,
 	}
 
 	c.JSON(http.StatusOK, result)
@@ -1484,7 +2502,7 @@ func (s *Server) handleGetTraderConfig(c *gin.Context) {
 func (s *Server) handleStatus(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1502,7 +2520,7 @@ func (s *Server) handleStatus(c *gin.Context) {
 func (s *Server) handleAccount(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1513,7 +2531,7 @@ func (s *Server) handleAccount(c *gin.Context) {
 	}
 
 	log.Printf("📊 收到账户信息请求 [%s]", trader.GetName())
-	account, err := trader.GetAccountInfo()
+	account, err := trader.GetAccount()
 	if err != nil {
 		log.Printf("❌ 获取账户信息失败 [%s]: %v", trader.GetName(), err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -1524,10 +2542,10 @@ func (s *Server) handleAccount(c *gin.Context) {
 
 	log.Printf("✓ 返回账户信息 [%s]: 净值=%.2f, 可用=%.2f, 盈亏=%.2f (%.2f%%)",
 		trader.GetName(),
-		account["total_equity"],
-		account["available_balance"],
-		account["total_pnl"],
-		account["total_pnl_pct"])
+		account.TotalEquity,
+		account.AvailableBalance,
+		account.TotalPnL,
+		account.TotalPnLPct)
 	c.JSON(http.StatusOK, account)
 }
 
@@ -1535,7 +2553,7 @@ func (s *Server) handleAccount(c *gin.Context) {
 func (s *Server) handlePositions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1560,7 +2578,7 @@ func (s *Server) handlePositions(c *gin.Context) {
 func (s *Server) handleDecisions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1570,8 +2588,16 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
-	// 获取所有历史决策记录（无限制）
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	// 从 query 参数读取 limit，默认 50，最大 500
+	limit := 50
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 500 {
+			limit = l
+		}
+	}
+
+	// cursor 为上一页返回的 next_cursor，为空时从最新记录开始
+	page, err := trader.GetDecisionLogger().GetRecordsPage(c.Query("cursor"), limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取决策日志失败: %v", err),
@@ -1579,14 +2605,14 @@ func (s *Server) handleDecisions(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, records)
+	c.JSON(http.StatusOK, page)
 }
 
 // handleLatestDecisions 最新决策日志（最近5条，最新的在前）
 func (s *Server) handleLatestDecisions(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1625,7 +2651,7 @@ func (s *Server) handleLatestDecisions(c *gin.Context) {
 func (s *Server) handleStatistics(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -1663,45 +2689,58 @@ func (s *Server) handleCompetition(c *gin.Context) {
 		})
 		return
 	}
+	if excludePaperRequested(c) {
+		competition = manager.ExcludePaperTraders(competition)
+	}
 
 	c.JSON(http.StatusOK, competition)
 }
 
 // handleEquityHistory 收益率历史数据
 func (s *Server) handleEquityHistory(c *gin.Context) {
-	_, traderID, err := s.getTraderFromQuery(c)
+	viewerID := s.optionalUserID(c)
+	traderID, err := s.getPublicTraderFromQuery(c, viewerID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	trader, err := s.traderManager.GetTrader(traderID)
+	// 解析时间范围和降采样粒度（默认最近7天）
+	query, err := parseEquityQuery(c, time.Now())
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 获取尽可能多的历史数据（几天的数据）
-	// 每3分钟一个周期：10000条 = 约20天的数据
-	records, err := trader.GetDecisionLogger().GetLatestRecords(10000)
+	// 私有交易员只对所有者可见
+	trader, ok := s.getVisibleTrader(traderID, viewerID)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	// 优先使用定时采样的权益快照；没有快照（如启用快照前的历史）时回退到决策记录
+	snapshots, err := s.database.GetEquitySnapshots(traderID, query.From, query.To)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取历史数据失败: %v", err),
 		})
 		return
 	}
+	if len(snapshots) > 0 {
+		c.JSON(http.StatusOK, equityPointsFromSnapshots(downsampleEquitySnapshots(snapshots, query)))
+		return
+	}
 
-	// 构建收益率历史数据点
-	type EquityPoint struct {
-		Timestamp        string  `json:"timestamp"`
-		TotalEquity      float64 `json:"total_equity"`      // 账户净值（wallet + unrealized）
-		AvailableBalance float64 `json:"available_balance"` // 可用余额
-		TotalPnL         float64 `json:"total_pnl"`         // 总盈亏（相对初始余额）
-		TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
-		PositionCount    int     `json:"position_count"`    // 持仓数量
-		MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
-		CycleNumber      int     `json:"cycle_number"`
+	// 只读取请求时间范围内的决策记录（超出上限时均匀抽样）
+	records, err := trader.GetDecisionLogger().GetRecordsByTimeRange(query.From, query.To, equityHistoryMaxRecords)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("获取历史数据失败: %v", err),
+		})
+		return
 	}
+	records = downsampleEquityRecords(records, query)
 
 	// 从AutoTrader获取当前初始余额（用作旧数据的fallback）
 	base := 0.0
@@ -1719,7 +2758,7 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 		return
 	}
 
-	var history []EquityPoint
+	history := make([]equityPoint, 0, len(records))
 	for _, record := range records {
 		// TotalBalance字段实际存储的是TotalEquity
 		// totalEquity := record.AccountState.TotalBalance
@@ -1742,7 +2781,7 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 			totalPnLPct = (totalPnL / base) * 100
 		}
 
-		history = append(history, EquityPoint{
+		history = append(history, equityPoint{
 			Timestamp:        record.Timestamp.Format("2006-01-02 15:04:05"),
 			TotalEquity:      totalEquity,
 			AvailableBalance: record.AccountState.AvailableBalance,
@@ -1757,261 +2796,48 @@ func (s *Server) handleEquityHistory(c *gin.Context) {
 	c.JSON(http.StatusOK, history)
 }
 
-// analyzePerformanceFromBinance 从Binance API获取真实交易数据并分析
-func (s *Server) analyzePerformanceFromBinance(traderInstance trader.Trader, lookbackDays int) (*logger.PerformanceAnalysis, error) {
-	// ✅ 修复：直接类型断言到 *FuturesTrader
-	futuresTrader, ok := traderInstance.(*trader.FuturesTrader)
+// analyzePerformanceFromExchange 从交易所API获取真实成交记录并分析
+func (s *Server) analyzePerformanceFromExchange(traderInstance trader.Trader, lookbackDays int) (*logger.PerformanceAnalysis, error) {
+	provider, ok := traderInstance.(trader.TradeHistoryProvider)
 	if !ok {
-		return nil, fmt.Errorf("交易员不支持Binance API（不是 FuturesTrader 类型）")
+		return nil, fmt.Errorf("交易员不支持从交易所API获取交易历史")
 	}
 
-	tradeHistory, err := futuresTrader.GetAllTradeHistory(lookbackDays)
+	tradeHistory, err := provider.GetAllTradeHistory(lookbackDays)
 	if err != nil {
 		return nil, fmt.Errorf("获取交易历史失败: %w", err)
 	}
-	
-	// ✅ 添加日志：查看获取到的数据
+
 	totalTradesCount := 0
-	for symbol, trades := range tradeHistory {
+	for _, trades := range tradeHistory {
 		totalTradesCount += len(trades)
-		log.Printf("📊 %s: %d 笔交易", symbol, len(trades))
 	}
 	log.Printf("📊 总共获取到 %d 个币种，%d 笔交易记录", len(tradeHistory), totalTradesCount)
 
-	// 构建性能分析
-	analysis := &logger.PerformanceAnalysis{
-		RecentTrades: []logger.TradeOutcome{},
-		SymbolStats:  make(map[string]*logger.SymbolPerformance),
-	}
-
-	// 按币种分组分析交易
-	for symbol, trades := range tradeHistory {
-		if len(trades) == 0 {
-			continue
-		}
-
-		// 追踪每个方向的持仓
-		type Position struct {
-			openPrice      float64
-			openTime       int64
-			totalQty       float64
-			totalCost      float64
-			realizedPnl    float64
-			commission     float64
-			tradeCount     int
-		}
-
-		longPos := &Position{}
-		shortPos := &Position{}
-
-		for _, trade := range trades {
-			var pos *Position
-			if trade.PositionSide == "LONG" {
-				pos = longPos
-			} else if trade.PositionSide == "SHORT" {
-				pos = shortPos
-			} else {
-				continue
-			}
-
-			// 累积交易数据
-			if trade.Side == "BUY" && trade.PositionSide == "LONG" ||
-				trade.Side == "SELL" && trade.PositionSide == "SHORT" {
-				// 开仓
-				if pos.totalQty == 0 {
-					pos.openTime = trade.Time
-				}
-				pos.totalCost += trade.Price * trade.Qty
-				pos.totalQty += trade.Qty
-				pos.tradeCount++
-			} else {
-				// 平仓
-				pos.realizedPnl += trade.RealizedPnl
-				pos.commission += trade.Commission
-				pos.totalQty -= trade.Qty
-				pos.tradeCount++
-
-				// 如果完全平仓，记录交易结果
-				if pos.totalQty <= 0.0001 && pos.tradeCount > 0 {
-					avgOpenPrice := pos.totalCost / (pos.totalQty + trade.Qty)
-					duration := time.Duration((trade.Time - pos.openTime) * int64(time.Millisecond))
-					
-					// 计算仓位相关数据
-					quantity := pos.totalQty + trade.Qty
-					positionValue := avgOpenPrice * quantity
-					leverage := 5 // 默认杠杆，可以从配置中获取
-					marginUsed := positionValue / float64(leverage)
-
-					outcome := logger.TradeOutcome{
-						Symbol:        symbol,
-						Side:          strings.ToLower(trade.PositionSide),
-						Quantity:      quantity,
-						Leverage:      leverage,
-						OpenPrice:     avgOpenPrice,
-						ClosePrice:    trade.Price,
-						PositionValue: positionValue,
-						MarginUsed:    marginUsed,
-						PnL:           pos.realizedPnl - pos.commission,
-						PnLPct:        (pos.realizedPnl - pos.commission) / marginUsed * 100,
-						Duration:      duration.String(),
-						OpenTime:      time.UnixMilli(pos.openTime),
-						CloseTime:     time.UnixMilli(trade.Time),
-					}
-
-					analysis.RecentTrades = append(analysis.RecentTrades, outcome)
-					analysis.TotalTrades++
-
-					if outcome.PnL > 0 {
-						analysis.WinningTrades++
-						analysis.AvgWin += outcome.PnL
-					} else if outcome.PnL < 0 {
-						analysis.LosingTrades++
-						analysis.AvgLoss += outcome.PnL
-					}
-
-					// 更新币种统计
-					if _, exists := analysis.SymbolStats[symbol]; !exists {
-						analysis.SymbolStats[symbol] = &logger.SymbolPerformance{
-							Symbol: symbol,
-						}
-					}
-					stats := analysis.SymbolStats[symbol]
-					stats.TotalTrades++
-					stats.TotalPnL += outcome.PnL
-					if outcome.PnL > 0 {
-						stats.WinningTrades++
-					} else if outcome.PnL < 0 {
-						stats.LosingTrades++
-					}
-
-					// 重置持仓
-					*pos = Position{}
-				}
-			}
+	// 资金费计入交易盈亏（获取失败时不影响成交分析）
+	var fundingFees []trader.FundingFee
+	if fundingProvider, ok := traderInstance.(trader.FundingProvider); ok {
+		fundingFees, err = fundingProvider.GetFundingFees(time.Now().AddDate(0, 0, -lookbackDays))
+		if err != nil {
+			log.Printf("⚠️ 获取资金费流水失败，表现分析不含资金费: %v", err)
 		}
 	}
 
-	// 计算统计指标
-	if analysis.WinningTrades > 0 {
-		analysis.AvgWin /= float64(analysis.WinningTrades)
-	}
-	if analysis.LosingTrades > 0 {
-		analysis.AvgLoss /= float64(analysis.LosingTrades)
-	}
-	if analysis.TotalTrades > 0 {
-		analysis.WinRate = float64(analysis.WinningTrades) / float64(analysis.TotalTrades) * 100
-	}
-	
-	// ✅ 修复盈亏比计算：防止除以零和异常值
-	if analysis.AvgLoss != 0 && analysis.LosingTrades > 0 {
-		analysis.ProfitFactor = analysis.AvgWin / -analysis.AvgLoss
-		// 限制最大值，避免显示异常的 999.00
-		if analysis.ProfitFactor > 100 {
-			analysis.ProfitFactor = 100
-		}
-	} else if analysis.WinningTrades > 0 && analysis.LosingTrades == 0 {
-		// 如果只有盈利交易，没有亏损交易，设置为一个合理的上限
-		analysis.ProfitFactor = 100
-	} else {
-		analysis.ProfitFactor = 0
-	}
-	
-	// ✅ 计算夏普比率（风险调整后收益）
-	// 夏普比率 = (平均收益率 - 无风险利率) / 收益率标准差
-	if len(analysis.RecentTrades) >= 2 {
-		// 1. 计算每笔交易的收益率
-		returns := make([]float64, 0, len(analysis.RecentTrades))
-		
-		log.Printf("📊 开始计算夏普比率，交易数量: %d", len(analysis.RecentTrades))
-		
-		for i, trade := range analysis.RecentTrades {
-			var returnRate float64
-			var baseValue float64
-			
-			// 优先使用保证金，其次仓位价值，最后使用开仓价值估算
-			if trade.MarginUsed > 0 {
-				baseValue = trade.MarginUsed
-				returnRate = trade.PnL / trade.MarginUsed
-				log.Printf("  交易%d: 使用保证金 %.2f, 盈亏 %.2f, 收益率 %.4f", i+1, trade.MarginUsed, trade.PnL, returnRate)
-			} else if trade.PositionValue > 0 {
-				baseValue = trade.PositionValue
-				returnRate = trade.PnL / trade.PositionValue
-				log.Printf("  交易%d: 使用仓位价值 %.2f, 盈亏 %.2f, 收益率 %.4f", i+1, trade.PositionValue, trade.PnL, returnRate)
-			} else if trade.OpenPrice > 0 && trade.Quantity > 0 {
-				// 降级方案：使用开仓价值估算
-				baseValue = trade.OpenPrice * trade.Quantity
-				if trade.Leverage > 0 {
-					baseValue = baseValue / float64(trade.Leverage)
-				}
-				if baseValue > 0 {
-					returnRate = trade.PnL / baseValue
-					log.Printf("  交易%d: 使用估算保证金 %.2f (开仓价 %.2f × 数量 %.4f ÷ 杠杆 %d), 盈亏 %.2f, 收益率 %.4f", 
-						i+1, baseValue, trade.OpenPrice, trade.Quantity, trade.Leverage, trade.PnL, returnRate)
-				}
-			}
-			
-			if baseValue > 0 {
-				returns = append(returns, returnRate)
-			} else {
-				log.Printf("  ⚠️ 交易%d: 无法计算收益率，跳过", i+1)
-			}
-		}
-		
-		log.Printf("📊 有效收益率数量: %d", len(returns))
-		
-		if len(returns) >= 2 {
-			// 2. 计算平均收益率
-			var sumReturns float64
-			for _, r := range returns {
-				sumReturns += r
-			}
-			avgReturn := sumReturns / float64(len(returns))
-			
-			// 3. 计算标准差
-			var sumSquaredDiff float64
-			for _, r := range returns {
-				diff := r - avgReturn
-				sumSquaredDiff += diff * diff
-			}
-			stdDev := math.Sqrt(sumSquaredDiff / float64(len(returns)))
-			
-			// 4. 计算夏普比率（假设无风险利率为0）
-			if stdDev > 0 {
-				// 不年化，直接使用交易级别的夏普比率
-				analysis.SharpeRatio = avgReturn / stdDev
-				
-				// 限制范围 [-3, 3]，避免异常值
-				if analysis.SharpeRatio > 3 {
-					analysis.SharpeRatio = 3
-				} else if analysis.SharpeRatio < -3 {
-					analysis.SharpeRatio = -3
-				}
-				
-				log.Printf("📊 夏普比率计算完成: 平均收益率=%.4f, 标准差=%.4f, 夏普比率=%.2f", 
-					avgReturn, stdDev, analysis.SharpeRatio)
-			} else {
-				log.Printf("⚠️ 标准差为0，无法计算夏普比率")
-			}
-		} else {
-			log.Printf("⚠️ 有效交易数量不足(%d < 2)，无法计算夏普比率", len(returns))
-		}
-	} else {
-		log.Printf("⚠️ 交易数量不足(%d < 2)，无法计算夏普比率", len(analysis.RecentTrades))
-	}
-	
-	log.Printf("📊 统计结果: 总交易=%d, 盈利=%d, 亏损=%d, 胜率=%.2f%%, 盈亏比=%.2f, 夏普比率=%.2f",
-		analysis.TotalTrades, analysis.WinningTrades, analysis.LosingTrades, 
-		analysis.WinRate, analysis.ProfitFactor, analysis.SharpeRatio)
+	analysis := trader.AnalyzeTradeHistoryWithFunding(tradeHistory, fundingFees)
 
-	// 计算币种统计
-	for _, stats := range analysis.SymbolStats {
-		if stats.TotalTrades > 0 {
-			stats.WinRate = float64(stats.WinningTrades) / float64(stats.TotalTrades) * 100
-			stats.AvgPnL = stats.TotalPnL / float64(stats.TotalTrades)
+	// 起始净值 = 当前钱包余额 - 窗口内已平仓交易的净盈亏
+	now := time.Now()
+	startEquity := 0.0
+	if balance, err := traderInstance.GetBalance(); err == nil {
+		startEquity = trader.BalanceFromMap(balance).WalletBalance
+		for _, outcome := range analysis.RecentTrades {
+			startEquity -= outcome.PnL
 		}
+	} else {
+		log.Printf("⚠️ 获取账户余额失败，表现分析不含回撤: %v", err)
 	}
-
-	log.Printf("✅ 从Binance API分析了 %d 笔交易", analysis.TotalTrades)
+	trader.ApplyTradeRiskMetrics(analysis, startEquity, now.AddDate(0, 0, -lookbackDays), now)
+	log.Printf("✅ 从交易所API分析了 %d 笔交易", analysis.TotalTrades)
 	return analysis, nil
 }
 
@@ -2019,7 +2845,7 @@ func (s *Server) analyzePerformanceFromBinance(traderInstance trader.Trader, loo
 func (s *Server) handlePerformance(c *gin.Context) {
 	_, traderID, err := s.getTraderFromQuery(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(traderQueryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -2029,13 +2855,11 @@ func (s *Server) handlePerformance(c *gin.Context) {
 		return
 	}
 
-	// 🔥 优先使用Binance API获取真实交易数据
-	// 尝试从Binance获取最近7天的交易历史
-	// ✅ 修复：直接传递 AutoTrader，在函数内部获取底层 Trader
-	performance, err := s.analyzePerformanceFromBinance(trader.GetTrader(), 7)
+	// 🔥 优先使用交易所API获取最近7天的真实成交记录
+	performance, err := s.analyzePerformanceFromExchange(trader.GetTrader(), 7)
 	if err != nil {
-		// 如果Binance API失败，降级到本地日志分析
-		log.Printf("⚠️ 从Binance获取交易历史失败，使用本地日志: %v", err)
+		// 如果交易所API失败，降级到本地日志分析
+		log.Printf("⚠️ 从交易所获取交易历史失败，使用本地日志: %v", err)
 		performance, err = trader.GetDecisionLogger().AnalyzePerformance(100)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
@@ -2045,6 +2869,13 @@ func (s *Server) handlePerformance(c *gin.Context) {
 		}
 	}
 
+	// 成交滑点来自本地决策记录（交易所成交历史不含决策时的参考价）
+	if performance.Slippage == nil {
+		if records, err := trader.GetDecisionLogger().GetLatestRecords(100); err == nil {
+			performance.Slippage = logger.SummarizeSlippage(records)
+		}
+	}
+
 	c.JSON(http.StatusOK, performance)
 }
 
@@ -2066,26 +2897,23 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := tokenParts[1]
-
-		// 黑名单检查
-		if auth.IsTokenBlacklisted(tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token已失效，请重新登录"})
-			c.Abort()
-			return
-		}
-
-		// 验证JWT token
-		claims, err := auth.ValidateJWT(tokenString)
+		// 黑名单、签名、账户删除/会话撤销、用户禁用检查（与 WebSocket 握手相同）
+		claims, status, err := s.authenticateToken(tokenParts[1])
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token: " + err.Error()})
+			c.JSON(status, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
 		// 将用户信息存储到上下文中
+		role := claims.Role
+		if role == "" {
+			role = config.UserRoleUser
+		}
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
+		c.Set("role", role)
+		c.Set("session_id", claims.SessionID)
 		c.Next()
 	}
 }
@@ -2115,16 +2943,26 @@ func (s *Server) handleLogout(c *gin.Context) {
 		exp = time.Now().Add(24 * time.Hour)
 	}
 	auth.BlacklistToken(tokenString, exp)
+
+	// 同时撤销刷新token（可选，未提供时仅使访问token失效）
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if c.ShouldBindJSON(&req) == nil && req.RefreshToken != "" {
+		if err := auth.RevokeRefreshToken(s.database, req.RefreshToken, claims.UserID); err != nil {
+			log.Printf("⚠️ 登出时撤销刷新token失败: %v", err)
+		}
+	}
+
+	if n := s.wsHub.closeToken(tokenString); n > 0 {
+		log.Printf("🔌 登出时断开 %d 个WebSocket连接", n)
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "已登出"})
 }
 
 // handleRegister 处理用户注册请求
 func (s *Server) handleRegister(c *gin.Context) {
-	regEnabled := true
-	if regStr, err := s.database.GetSystemConfig("registration_enabled"); err == nil {
-		regEnabled = strings.ToLower(regStr) != "false"
-	}
-	if !regEnabled {
+	if !s.database.SystemConfig().Bool("registration_enabled", true) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "注册已关闭"})
 		return
 	}
@@ -2140,9 +2978,9 @@ func (s *Server) handleRegister(c *gin.Context) {
 		return
 	}
 
-	// 检查是否开启了内测模式
-	betaModeStr, _ := s.database.GetSystemConfig("beta_mode")
-	if betaModeStr == "true" {
+	// 检查是否开启了内测模式（同一请求内使用同一取值）
+	betaMode := s.database.SystemConfig().Bool("beta_mode", false)
+	if betaMode {
 		// 内测模式下必须提供有效的内测码
 		if req.BetaCode == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "内测期间，注册需要提供内测码"})
@@ -2168,11 +3006,12 @@ func (s *Server) handleRegister(c *gin.Context) {
 		if !existingUser.OTPVerified {
 			qrCodeURL := auth.GetOTPQRCodeURL(existingUser.OTPSecret, req.Email)
 			c.JSON(http.StatusOK, gin.H{
-				"user_id":     existingUser.ID,
-				"email":       req.Email,
-				"otp_secret":  existingUser.OTPSecret,
-				"qr_code_url": qrCodeURL,
-				"message":     "检测到未完成的注册，请继续完成OTP设置",
+				"user_id":                     existingUser.ID,
+				"email":                       req.Email,
+				"otp_secret":                  existingUser.OTPSecret,
+				"qr_code_url":                 qrCodeURL,
+				"requires_email_verification": s.emailUnverified(existingUser),
+				"message":                     "检测到未完成的注册，请继续完成OTP设置",
 			})
 			return
 		}
@@ -2195,14 +3034,16 @@ func (s *Server) handleRegister(c *gin.Context) {
 		return
 	}
 
-	// 创建用户（未验证OTP状态）
+	// 创建用户（未验证OTP状态；配置了邮件服务时还需验证邮箱）
 	userID := uuid.New().String()
+	requireEmail := s.emailVerificationRequired()
 	user := &config.User{
-		ID:           userID,
-		Email:        req.Email,
-		PasswordHash: passwordHash,
-		OTPSecret:    otpSecret,
-		OTPVerified:  false,
+		ID:            userID,
+		Email:         req.Email,
+		PasswordHash:  passwordHash,
+		OTPSecret:     otpSecret,
+		OTPVerified:   false,
+		EmailVerified: !requireEmail,
 	}
 
 	err = s.database.CreateUser(user)
@@ -2212,8 +3053,7 @@ func (s *Server) handleRegister(c *gin.Context) {
 	}
 
 	// 如果是内测模式，标记内测码为已使用
-	betaModeStr2, _ := s.database.GetSystemConfig("beta_mode")
-	if betaModeStr2 == "true" && req.BetaCode != "" {
+	if betaMode && req.BetaCode != "" {
 		err := s.database.UseBetaCode(req.BetaCode, req.Email)
 		if err != nil {
 			log.Printf("⚠️ 标记内测码为已使用失败: %v", err)
@@ -2223,15 +3063,20 @@ func (s *Server) handleRegister(c *gin.Context) {
 		}
 	}
 
-	// 返回OTP设置信息
-	qrCodeURL := auth.GetOTPQRCodeURL(otpSecret, req.Email)
-	c.JSON(http.StatusOK, gin.H{
-		"user_id":     userID,
-		"email":       req.Email,
-		"otp_secret":  otpSecret,
-		"qr_code_url": qrCodeURL,
-		"message":     "请使用Google Authenticator扫描二维码并验证OTP",
-	})
+	// 发送邮箱验证邮件
+	resp := gin.H{
+		"user_id":                     userID,
+		"email":                       req.Email,
+		"otp_secret":                  otpSecret,
+		"qr_code_url":                 auth.GetOTPQRCodeURL(otpSecret, req.Email),
+		"requires_email_verification": requireEmail,
+		"message":                     "请使用Google Authenticator扫描二维码并验证OTP",
+	}
+	if requireEmail {
+		resp["email_sent"] = s.sendVerificationEmail(user)
+		resp["message"] = "验证邮件已发送，请验证邮箱并使用Google Authenticator扫描二维码完成注册"
+	}
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleCompleteRegistration 完成注册（验证OTP）
@@ -2253,6 +3098,11 @@ func (s *Server) handleCompleteRegistration(c *gin.Context) {
 		return
 	}
 
+	if s.emailUnverified(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "请先验证邮箱", "requires_email_verification": true})
+		return
+	}
+
 	// 验证OTP
 	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "OTP验证码错误"})
@@ -2266,25 +3116,31 @@ func (s *Server) handleCompleteRegistration(c *gin.Context) {
 		return
 	}
 
-	// 生成JWT token
-	token, err := auth.GenerateJWT(user.ID, user.Email)
+	// 生成访问token和刷新token
+	resp, err := s.issueTokenPair(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
 	}
 
+	// 生成一次性恢复码（仅在此返回一次）
+	recoveryCodes, err := s.issueRecoveryCodes(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成恢复码失败"})
+		return
+	}
+
 	// 初始化用户的默认模型和交易所配置
 	err = s.initUserDefaultConfigs(user.ID)
 	if err != nil {
 		log.Printf("初始化用户默认配置失败: %v", err)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":   token,
-		"user_id": user.ID,
-		"email":   user.Email,
-		"message": "注册完成",
-	})
+	resp["recovery_codes"] = recoveryCodes
+	resp["user_id"] = user.ID
+	resp["email"] = user.Email
+	resp["message"] = "注册完成"
+	c.JSON(http.StatusOK, resp)
 }
 
 // handleLogin 处理用户登录请求
@@ -2299,19 +3155,37 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
+	// 锁定或渐进延迟中的尝试与密码错误返回相同的错误，不泄露锁定状态
+	if s.loginBlocked(c, req.Email) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
+		return
+	}
+
 	// 获取用户信息
 	user, err := s.database.GetUserByEmail(req.Email)
 	if err != nil {
+		s.recordLoginFailure(c, "", req.Email)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
 		return
 	}
 
 	// 验证密码
 	if !auth.CheckPassword(req.Password, user.PasswordHash) {
+		s.recordLoginFailure(c, user.ID, req.Email)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "邮箱或密码错误"})
 		return
 	}
 
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账户已被禁用"})
+		return
+	}
+
+	if s.emailUnverified(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "请先验证邮箱", "requires_email_verification": true})
+		return
+	}
+
 	// 检查OTP是否已验证
 	if !user.OTPVerified {
 		c.JSON(http.StatusUnauthorized, gin.H{
@@ -2346,73 +3220,120 @@ func (s *Server) handleVerifyOTP(c *gin.Context) {
 	// 获取用户信息
 	user, err := s.database.GetUserByID(req.UserID)
 	if err != nil {
+		s.recordLoginFailure(c, "", "")
 		c.JSON(http.StatusNotFound, gin.H{"error": "用户不存在"})
 		return
 	}
 
-	// 验证OTP
-	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
+	if user.Disabled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "账户已被禁用"})
+		return
+	}
+
+	if s.emailUnverified(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "请先验证邮箱", "requires_email_verification": true})
+		return
+	}
+
+	// 验证码错误和锁定中返回相同的错误
+	if s.loginBlocked(c, user.Email) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
+		return
+	}
+
+	// 验证OTP（也接受一次性恢复码）
+	if !s.verifySecondFactor(user, req.OTPCode) {
+		s.recordLoginFailure(c, user.ID, user.Email)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "验证码错误"})
 		return
 	}
+	s.resetLoginFailures(user.Email)
 
-	// 生成JWT token
-	token, err := auth.GenerateJWT(user.ID, user.Email)
+	// 生成访问token和刷新token
+	resp, err := s.issueTokenPair(c, user)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":   token,
-		"user_id": user.ID,
-		"email":   user.Email,
-		"message": "登录成功",
-	})
+	resp["user_id"] = user.ID
+	resp["email"] = user.Email
+	resp["message"] = "登录成功"
+	c.JSON(http.StatusOK, resp)
 }
 
-// handleResetPassword 重置密码（通过邮箱 + OTP 验证）
-func (s *Server) handleResetPassword(c *gin.Context) {
-	var req struct {
-		Email       string `json:"email" binding:"required,email"`
-		NewPassword string `json:"new_password" binding:"required,min=6"`
-		OTPCode     string `json:"otp_code" binding:"required"`
+// issueTokenPair 为登录用户签发访问token和新的刷新token链
+func (s *Server) issueTokenPair(c *gin.Context, user *config.User) (gin.H, error) {
+	refreshToken, rec, err := auth.IssueRefreshToken(s.database, user.ID, requestDeviceInfo(c))
+	if err != nil {
+		return nil, err
+	}
+	token, err := auth.GenerateSessionJWT(user.ID, user.Email, user.Role, rec.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+	return tokenPairResponse(token, refreshToken, rec), nil
+}
+
+// tokenPairResponse 构造token对响应
+func tokenPairResponse(token, refreshToken string, rec *auth.RefreshToken) gin.H {
+	return gin.H{
+		"token":              token,
+		"expires_in":         int(auth.AccessTokenTTL.Seconds()),
+		"refresh_token":      refreshToken,
+		"refresh_expires_at": rec.ExpiresAt.Unix(),
+	}
+}
+
+// requestDeviceInfo 从请求中提取设备信息
+func requestDeviceInfo(c *gin.Context) auth.DeviceInfo {
+	return auth.DeviceInfo{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
 	}
+}
 
+// handleRefreshToken 使用刷新token换取新的访问token和刷新token（旧刷新token立即失效）
+func (s *Server) handleRefreshToken(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refresh_token" binding:"required"`
+	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 查询用户
-	user, err := s.database.GetUserByEmail(req.Email)
+	refreshToken, rec, err := auth.RotateRefreshToken(s.database, req.RefreshToken, requestDeviceInfo(c))
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "邮箱不存在"})
+		if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenExpired) ||
+			errors.Is(err, auth.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 验证 OTP
-	if !auth.VerifyOTP(user.OTPSecret, req.OTPCode) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Google Authenticator 验证码错误"})
+	user, err := s.database.GetUserByID(rec.UserID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "用户不存在"})
 		return
 	}
-
-	// 生成新密码哈希
-	newPasswordHash, err := auth.HashPassword(req.NewPassword)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码处理失败"})
+	if user.Disabled {
+		if err := s.database.RevokeRefreshTokenFamily(rec.FamilyID); err != nil {
+			log.Printf("⚠️ 撤销被禁用用户的刷新token失败: %v", err)
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "账户已被禁用"})
 		return
 	}
 
-	// 更新密码
-	err = s.database.UpdateUserPassword(user.ID, newPasswordHash)
+	token, err := auth.GenerateSessionJWT(user.ID, user.Email, user.Role, rec.FamilyID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "密码更新失败"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "生成token失败"})
 		return
 	}
 
-	log.Printf("✓ 用户 %s 密码已重置", user.Email)
-	c.JSON(http.StatusOK, gin.H{"message": "密码重置成功，请使用新密码登录"})
+	c.JSON(http.StatusOK, tokenPairResponse(token, refreshToken, rec))
 }
 
 // initUserDefaultConfigs 为新用户初始化默认的模型和交易所配置
@@ -2470,29 +3391,64 @@ func (s *Server) Start() error {
 	log.Printf("🌐 API服务器启动在 http://localhost%s", addr)
 	log.Printf("📊 API文档:")
 	log.Printf("  • GET  /api/health           - 健康检查")
+	log.Printf("  • POST /api/refresh          - 使用刷新token换取新的访问token（旧刷新token失效）")
+	log.Printf("  • POST /api/forgot-password  - 发送重置密码邮件（需配置SMTP，重置时还需OTP）")
+	log.Printf("  • POST /api/user/recovery-codes/regenerate - 重新生成OTP恢复码（旧恢复码失效）")
+	log.Printf("  • POST /api/user/signal-sources/test - 测试信号源地址（返回解析出的币种列表或具体的格式错误）")
+	log.Printf("  • GET  /api/user/notifications - 通知通道（Telegram / Discord / Webhook，POST /:id/test 发送测试通知）")
+	log.Printf("  • GET  /api/webhooks           - 出站Webhook订阅（GET /:id/deliveries 查看投递失败记录）")
+	log.Printf("  • GET  /api/health/deep      - 深度健康检查（管理员，数据库/交易员/行情流/AI服务，异常返回503）")
 	log.Printf("  • GET  /api/traders          - 公开的AI交易员排行榜前50名（无需认证）")
 	log.Printf("  • GET  /api/competition      - 公开的竞赛数据（无需认证）")
 	log.Printf("  • GET  /api/top-traders      - 前5名交易员数据（无需认证，表现对比用）")
-	log.Printf("  • GET  /api/equity-history?trader_id=xxx - 公开的收益率历史数据（无需认证，竞赛用）")
+	log.Printf("  • GET  /api/equity-history?trader_id=xxx&from=&to=&resolution=1h - 公开的收益率历史数据（无需认证，竞赛用，默认最近7天）")
 	log.Printf("  • GET  /api/equity-history-batch?trader_ids=a,b,c - 批量获取历史数据（无需认证，表现对比优化）")
 	log.Printf("  • GET  /api/traders/:id/public-config - 公开的交易员配置（无需认证，不含敏感信息）")
+	log.Printf("  • GET  /api/market/tickers?limit=20 - 24小时成交额最高的交易对（无需认证，symbol=BTCUSDT 查询单个）")
 	log.Printf("  • POST /api/traders          - 创建新的AI交易员")
+	log.Printf("  • POST /api/traders/import   - 从导出文件导入AI交易员")
+	log.Printf("  • GET  /api/traders/:id/export - 导出AI交易员配置（不含密钥）")
 	log.Printf("  • DELETE /api/traders/:id    - 删除AI交易员")
 	log.Printf("  • POST /api/traders/:id/start - 启动AI交易员")
 	log.Printf("  • POST /api/traders/:id/stop  - 停止AI交易员")
+	log.Printf("  • POST /api/traders/:id/dry-run - 决策预演（调用AI但不执行交易）")
+	log.Printf("  • POST /api/traders/:id/trigger-cycle - 立即执行一个决策周期（运行中的交易员）")
+	log.Printf("  • GET  /api/traders/:id/guardrails - 获取交易员护栏规则")
+	log.Printf("  • PUT  /api/traders/:id/guardrails - 更新交易员护栏规则（拒绝或调整AI开仓决策）")
+	log.Printf("  • POST /api/emergency-stop   - 紧急停止当前用户的所有交易员（close_positions=true 时一键平仓）")
 	log.Printf("  • GET  /api/models           - 获取AI模型配置")
 	log.Printf("  • PUT  /api/models           - 更新AI模型配置")
+	log.Printf("  • POST /api/models/:model_id/test - 测试AI模型API密钥（发送一次最小对话请求，不保存）")
 	log.Printf("  • GET  /api/exchanges        - 获取交易所配置")
 	log.Printf("  • PUT  /api/exchanges        - 更新交易所配置")
+	log.Printf("  • POST /api/exchanges/:exchange_id/test - 测试交易所API密钥（查询余额，不保存）")
+	log.Printf("  • DELETE /api/exchanges/:exchange_id/accounts/:account_id - 删除交易所账户（默认账户和使用中的账户不可删除）")
 	log.Printf("  • GET  /api/status?trader_id=xxx     - 指定trader的系统状态")
 	log.Printf("  • GET  /api/account?trader_id=xxx    - 指定trader的账户信息")
 	log.Printf("  • GET  /api/positions?trader_id=xxx  - 指定trader的持仓列表")
-	log.Printf("  • GET  /api/decisions?trader_id=xxx  - 指定trader的决策日志")
+	log.Printf("  • GET  /api/decisions?trader_id=xxx&cursor=&limit=50  - 指定trader的决策日志（分页，最新在前）")
 	log.Printf("  • GET  /api/decisions/latest?trader_id=xxx - 指定trader的最新决策")
 	log.Printf("  • GET  /api/statistics?trader_id=xxx - 指定trader的统计信息")
 	log.Printf("  • GET  /api/performance?trader_id=xxx - 指定trader的AI学习表现分析")
+	log.Printf("  • GET  /api/pnl-daily?trader_id=xxx&month=YYYY-MM&tz=Asia/Shanghai - 指定trader的每日盈亏日历")
+	log.Printf("  • GET  /api/reports?period=daily|weekly - 已发送的定时日报/周报（在用户设置中开启）")
+	log.Printf("  • GET  /api/market/liquidations?symbol=BTCUSDT - 最近1m/5m/15m的强平量（不指定symbol时返回强平金额最大的交易对）")
+	log.Printf("  • GET  /api/market/status?limit=50 - 行情流健康状态（消息速率、丢弃消息数、重连次数及各流明细）")
+	log.Printf("  • GET  /api/experiments?trader_id=xxx - 指定trader的提示词A/B实验对比")
+	log.Printf("  • GET  /api/user/spend?month=YYYY-MM - 当前用户的AI花费统计")
+	log.Printf("  • GET  /api/audit-log?from=&to=&action=&limit=50&offset=0 - 当前用户的敏感配置变更审计日志")
+	log.Printf("  • GET  /api/ws?trader_id=xxx&token=xxx - 交易员实时事件推送（WebSocket）")
+	log.Printf("  • GET  /api/admin/users      - 用户管理（仅管理员）")
+	log.Printf("  • POST /api/admin/beta-codes - 生成内测码（仅管理员）")
+	log.Printf("  • POST /api/admin/emergency-stop - 紧急停止所有用户的交易员（仅管理员）")
+	log.Printf("  • PUT  /api/admin/system-config - 修改系统配置（仅管理员）")
+	log.Printf("  • DELETE /api/admin/login-lockouts - 解除登录锁定（仅管理员）")
+	log.Printf("  • POST /api/admin/crypto/rotate-key - 轮换RSA传输公钥（仅管理员）")
+	log.Printf("  • GET  /metrics              - Prometheus 指标（需配置 metrics_token 或 metrics_port）")
 	log.Println()
 
+	s.startMetricsServer()
+
 	// 创建 http.Server 以支持 graceful shutdown
 	s.httpServer = &http.Server{
 		Addr:    addr,
@@ -2504,14 +3460,30 @@ func (s *Server) Start() error {
 
 // Shutdown 优雅关闭 API 服务器
 func (s *Server) Shutdown() error {
+	if s.auditLog != nil {
+		defer s.auditLog.Close()
+	}
+	if s.notifier != nil {
+		defer s.notifier.Close()
+	}
+	if s.webhooks != nil {
+		defer s.webhooks.Close()
+	}
 	if s.httpServer == nil {
 		return nil
 	}
 
+	// http.Server.Shutdown 不会关闭已升级的 WebSocket 连接，需主动断开
+	s.wsHub.closeAll()
+
 	// 设置 5 秒超时
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	if s.metricsServer != nil {
+		s.metricsServer.Shutdown(ctx)
+	}
+
 	return s.httpServer.Shutdown(ctx)
 }
 
@@ -2524,7 +3496,8 @@ func (s *Server) handleGetPromptTemplates(c *gin.Context) {
 	response := make([]map[string]interface{}, 0, len(templates))
 	for _, tmpl := range templates {
 		response = append(response, map[string]interface{}{
-			"name": tmpl.Name,
+			"name":   tmpl.Name,
+			"source": tmpl.Source,
 		})
 	}
 
@@ -2559,6 +3532,9 @@ func (s *Server) handlePublicTraderList(c *gin.Context) {
 		})
 		return
 	}
+	if excludePaperRequested(c) {
+		competition = manager.ExcludePaperTraders(competition)
+	}
 
 	// 获取traders数组
 	tradersData, exists := competition["traders"]
@@ -2583,6 +3559,8 @@ func (s *Server) handlePublicTraderList(c *gin.Context) {
 			"trader_name":            trader["trader_name"],
 			"ai_model":               trader["ai_model"],
 			"exchange":               trader["exchange"],
+			"is_paper":               trader["is_paper"],
+			"testnet":                trader["testnet"],
 			"is_running":             trader["is_running"],
 			"total_equity":           trader["total_equity"],
 			"total_pnl":              trader["total_pnl"],
@@ -2605,13 +3583,20 @@ func (s *Server) handlePublicCompetition(c *gin.Context) {
 		})
 		return
 	}
+	if excludePaperRequested(c) {
+		competition = manager.ExcludePaperTraders(competition)
+	}
 
 	c.JSON(http.StatusOK, competition)
 }
 
 // handleTopTraders 获取前5名交易员数据（无需认证，用于表现对比）
 func (s *Server) handleTopTraders(c *gin.Context) {
-	topTraders, err := s.traderManager.GetTopTradersData()
+	getTopTraders := s.traderManager.GetTopTradersData
+	if excludePaperRequested(c) {
+		getTopTraders = s.traderManager.GetRealMoneyTopTradersData
+	}
+	topTraders, err := getTopTraders()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("获取前10名交易员数据失败: %v", err),
@@ -2622,12 +3607,24 @@ func (s *Server) handleTopTraders(c *gin.Context) {
 	c.JSON(http.StatusOK, topTraders)
 }
 
+// excludePaperRequested 请求是否要求排除模拟盘和测试网交易员（?exclude_paper=true，用于真实资金排行榜）
+func excludePaperRequested(c *gin.Context) bool {
+	return c.Query("exclude_paper") == "true"
+}
+
 // handleEquityHistoryBatch 批量获取多个交易员的收益率历史数据（无需认证，用于表现对比）
 func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 	var requestBody struct {
 		TraderIDs []string `json:"trader_ids"`
 	}
 
+	// 与单个交易员接口相同的 from/to/resolution 参数
+	query, err := parseEquityQuery(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// 尝试解析POST请求的JSON body
 	if err := c.ShouldBindJSON(&requestBody); err != nil {
 		// 如果JSON解析失败，尝试从query参数获取（兼容GET请求）
@@ -2656,7 +3653,7 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 				}
 			}
 
-			result := s.getEquityHistoryForTraders(traderIDs)
+			result := s.getEquityHistoryForTraders(traderIDs, query, s.optionalUserID(c))
 			c.JSON(http.StatusOK, result)
 			return
 		}
@@ -2673,12 +3670,12 @@ func (s *Server) handleEquityHistoryBatch(c *gin.Context) {
 		requestBody.TraderIDs = requestBody.TraderIDs[:20]
 	}
 
-	result := s.getEquityHistoryForTraders(requestBody.TraderIDs)
+	result := s.getEquityHistoryForTraders(requestBody.TraderIDs, query, s.optionalUserID(c))
 	c.JSON(http.StatusOK, result)
 }
 
-// getEquityHistoryForTraders 获取多个交易员的历史数据
-func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]interface{} {
+// getEquityHistoryForTraders 获取多个交易员的历史数据（跳过 viewerID 无权查看的私有交易员）
+func (s *Server) getEquityHistoryForTraders(traderIDs []string, query *equityQuery, viewerID string) map[string]interface{} {
 	result := make(map[string]interface{})
 	histories := make(map[string]interface{})
 	errors := make(map[string]string)
@@ -2688,18 +3685,41 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 			continue
 		}
 
-		trader, err := s.traderManager.GetTrader(traderID)
-		if err != nil {
+		trader, ok := s.getVisibleTrader(traderID, viewerID)
+		if !ok {
 			errors[traderID] = "交易员不存在"
 			continue
 		}
 
-		// 获取历史数据（用于对比展示，限制数据量）
-		records, err := trader.GetDecisionLogger().GetLatestRecords(500)
+		// 优先使用权益快照，没有快照时回退到决策记录
+		snapshots, err := s.database.GetEquitySnapshots(traderID, query.From, query.To)
+		if err != nil {
+			errors[traderID] = fmt.Sprintf("获取历史数据失败: %v", err)
+			continue
+		}
+		if len(snapshots) > 0 {
+			snapshots = downsampleEquitySnapshots(snapshots, query)
+			history := make([]map[string]interface{}, 0, len(snapshots))
+			for _, snapshot := range snapshots {
+				history = append(history, map[string]interface{}{
+					"timestamp":     snapshot.Timestamp,
+					"total_equity":  snapshot.TotalEquity,
+					"total_pnl":     snapshot.TotalPnL,
+					"total_pnl_pct": snapshot.TotalPnLPct,
+					"balance":       snapshot.WalletBalance,
+				})
+			}
+			histories[traderID] = history
+			continue
+		}
+
+		// 获取时间范围内的历史数据（用于对比展示，降采样以限制数据量）
+		records, err := trader.GetDecisionLogger().GetRecordsByTimeRange(query.From, query.To, equityHistoryMaxRecords)
 		if err != nil {
 			errors[traderID] = fmt.Sprintf("获取历史数据失败: %v", err)
 			continue
 		}
+		records = downsampleEquityRecords(records, query)
 
 		// 构建收益率历史数据
 		history := make([]map[string]interface{}, 0, len(records))
@@ -2720,6 +3740,9 @@ func (s *Server) getEquityHistoryForTraders(traderIDs []string) map[string]inter
 
 	result["histories"] = histories
 	result["count"] = len(histories)
+	result["from"] = query.From
+	result["to"] = query.To
+	result["resolution"] = query.Resolution
 	if len(errors) > 0 {
 		result["errors"] = errors
 	}
@@ -2735,24 +3758,29 @@ func (s *Server) handleGetPublicTraderConfig(c *gin.Context) {
 		return
 	}
 
-	trader, err := s.traderManager.GetTrader(traderID)
-	if err != nil {
+	trader, ok := s.getVisibleTrader(traderID, s.optionalUserID(c))
+	if !ok {
 		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
 		return
 	}
 
 	// 获取交易员的状态信息
 	status := trader.GetStatus()
+	maxPositionValueUSDT, maxTotalExposurePct := trader.GetExposureLimits()
 
-	// 只返回公开的配置信息，不包含API密钥等敏感数据
+	// 只返回公开的配置信息，不包含API密钥等敏感数据（风控上限用于让观众了解风险偏好）
 	result := map[string]interface{}{
-		"trader_id":   trader.GetID(),
-		"trader_name": trader.GetName(),
-		"ai_model":    trader.GetAIModel(),
-		"exchange":    trader.GetExchange(),
-		"is_running":  status["is_running"],
-		"ai_provider": status["ai_provider"],
-		"start_time":  status["start_time"],
+		"trader_id":               trader.GetID(),
+		"trader_name":             trader.GetName(),
+		"ai_model":                trader.GetAIModel(),
+		"exchange":                trader.GetExchange(),
+		"is_paper":                trader.GetExchange() == config.PaperExchangeID,
+		"testnet":                 trader.IsTestnet(),
+		"is_running":              status["is_running"],
+		"ai_provider":             status["ai_provider"],
+		"start_time":              status["start_time"],
+		"max_position_value_usdt": maxPositionValueUSDT,
+		"max_total_exposure_pct":  maxTotalExposurePct,
 	}
 
 	c.JSON(http.StatusOK, result)
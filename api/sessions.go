@@ -0,0 +1,73 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"nofx/auth"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleListSessions 列出当前用户的登录会话（设备、IP、登录和最近使用时间，不含token）
+func (s *Server) handleListSessions(c *gin.Context) {
+	sessions, err := s.database.ListUserSessions(c.GetString("user_id"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	current := c.GetString("session_id")
+	for _, session := range sessions {
+		session.Current = session.ID == current
+	}
+	c.JSON(http.StatusOK, gin.H{"sessions": sessions})
+}
+
+// handleRevokeSession 撤销一个会话（刷新token失效，该会话已签发的访问token同时失效）
+func (s *Server) handleRevokeSession(c *gin.Context) {
+	userID := c.GetString("user_id")
+	sessionID := c.Param("id")
+
+	found, err := s.database.RevokeUserSession(userID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "会话不存在或已失效"})
+		return
+	}
+	auth.RevokeSessionTokens(sessionID)
+
+	s.audit(c, userID, auditSessionRevoke, sessionID, nil)
+	requestLogf(c, "🔒 用户 %s 撤销会话 %s", userID, sessionID)
+	c.JSON(http.StatusOK, gin.H{"message": "会话已撤销"})
+}
+
+// handleRevokeOtherSessions 撤销当前会话以外的全部会话（旧token不属于任何会话时撤销全部会话）
+func (s *Server) handleRevokeOtherSessions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	revoked, err := s.revokeOtherSessions(userID, c.GetString("session_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.audit(c, userID, auditSessionRevoke, "others", gin.H{"revoked": revoked})
+	requestLogf(c, "🔒 用户 %s 撤销其他 %d 个会话", userID, revoked)
+	c.JSON(http.StatusOK, gin.H{"message": "其他会话已撤销", "revoked": revoked})
+}
+
+// revokeOtherSessions 撤销用户除 keepSessionID 之外的全部会话（为空时撤销全部），返回撤销的会话数
+// 修改密码、重置OTP 等操作后调用，使其他设备必须重新登录
+func (s *Server) revokeOtherSessions(userID, keepSessionID string) (int, error) {
+	revoked, err := s.database.RevokeOtherUserSessions(userID, keepSessionID)
+	if err != nil {
+		return 0, err
+	}
+	auth.RevokeSessionTokens(revoked...)
+	if len(revoked) > 0 {
+		log.Printf("🔒 已撤销用户 %s 的 %d 个会话", userID, len(revoked))
+	}
+	return len(revoked), nil
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSessions_ListAndRevoke 列出会话（不含token）、撤销其他会话和单个会话后对应的访问token立即失效
+func TestSessions_ListAndRevoke(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	login := func(userID, userAgent string) (string, string) {
+		refreshToken, rec, err := auth.IssueRefreshToken(s.database, userID, auth.DeviceInfo{UserAgent: userAgent, IPAddress: "10.0.0.1"})
+		if err != nil {
+			t.Fatalf("签发刷新token失败: %v", err)
+		}
+		token, _ := auth.GenerateSessionJWT(userID, userID+"@test.com", config.UserRoleUser, rec.FamilyID)
+		return token, refreshToken
+	}
+	laptop, laptopRefresh := login("user-a", "laptop")
+	phone, _ := login("user-a", "phone")
+	tablet, _ := login("user-a", "tablet")
+	other, _ := login("user-b", "other")
+
+	do := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(token string) []config.UserSession {
+		w := do(http.MethodGet, "/api/sessions", token)
+		if w.Code != http.StatusOK {
+			t.Fatalf("获取会话列表应返回200: %d %s", w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), laptopRefresh) || strings.Contains(w.Body.String(), auth.HashRefreshToken(laptopRefresh)) {
+			t.Errorf("会话列表不应包含token: %s", w.Body.String())
+		}
+		var resp struct {
+			Sessions []config.UserSession `json:"sessions"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.Sessions
+	}
+
+	sessions := list(laptop)
+	if len(sessions) != 3 {
+		t.Fatalf("应有3个会话，实际 %d", len(sessions))
+	}
+	var laptopID, tabletID string
+	for _, session := range sessions {
+		switch session.DeviceInfo {
+		case "laptop":
+			laptopID = session.ID
+			if !session.Current {
+				t.Error("发起请求的会话应标记为当前会话")
+			}
+		case "tablet":
+			tabletID = session.ID
+		}
+		if session.CreatedAt.IsZero() || session.LastUsedAt.IsZero() {
+			t.Errorf("会话应包含登录和最近使用时间: %+v", session)
+		}
+	}
+
+	// 不能撤销其他用户的会话
+	if w := do(http.MethodDelete, "/api/sessions/"+laptopID, other); w.Code != http.StatusNotFound {
+		t.Errorf("撤销其他用户的会话应返回404，实际 %d", w.Code)
+	}
+
+	if w := do(http.MethodDelete, "/api/sessions/"+tabletID, laptop); w.Code != http.StatusOK {
+		t.Fatalf("撤销会话应返回200: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/sessions", tablet); w.Code != http.StatusUnauthorized {
+		t.Errorf("被撤销会话的访问token应失效，实际 %d", w.Code)
+	}
+
+	w := do(http.MethodPost, "/api/sessions/revoke-others", laptop)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"revoked":1`) {
+		t.Fatalf("撤销其他会话应撤销1个: %d %s", w.Code, w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/sessions", phone); w.Code != http.StatusUnauthorized {
+		t.Errorf("其他会话的访问token应失效，实际 %d", w.Code)
+	}
+	if sessions := list(laptop); len(sessions) != 1 || sessions[0].ID != laptopID {
+		t.Errorf("应只剩当前会话: %+v", sessions)
+	}
+	if sessions := list(other); len(sessions) != 1 {
+		t.Errorf("不应影响其他用户的会话: %+v", sessions)
+	}
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/signalsource"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSignalSourceRequest 测试信号源请求（url 为空时使用已保存的对应信号源地址、请求头和超时）
+type TestSignalSourceRequest struct {
+	URL            string            `json:"url"`
+	Source         string            `json:"source"`          // coin_pool / oi_top，url 为空时必填
+	Headers        map[string]string `json:"headers"`         // 测试指定 url 时附加的请求头
+	TimeoutSeconds int               `json:"timeout_seconds"` // 测试指定 url 时的超时秒数（0表示默认）
+}
+
+// signalSourceHeaderNames 返回排序后的请求头名称（接口只返回名称，不返回值）
+func signalSourceHeaderNames(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validateSignalSourceTimeout 校验信号源超时秒数（0表示默认）
+func validateSignalSourceTimeout(seconds int) *traderFieldError {
+	if seconds < 0 || time.Duration(seconds)*time.Second > signalsource.MaxTimeout {
+		return &traderFieldError{"timeout_seconds", fmt.Sprintf("超时必须在0-%d秒之间", int(signalsource.MaxTimeout.Seconds()))}
+	}
+	return nil
+}
+
+// mergeSignalSourceOptions 合并请求中的请求头和超时与已保存的配置
+//
+// headers 为 nil 时保留已保存的请求头；值为空的名称保留已保存的值（名称不存在时报错），未列出的名称被删除。
+// timeoutSeconds 为 nil 时保留已保存的超时。
+func mergeSignalSourceOptions(saved *config.UserSignalSource, headers map[string]string, timeoutSeconds *int) (map[string]string, int, *traderFieldError) {
+	var savedHeaders map[string]string
+	savedTimeout := 0
+	if saved != nil {
+		savedHeaders, savedTimeout = saved.Headers, saved.TimeoutSeconds
+	}
+
+	timeout := savedTimeout
+	if timeoutSeconds != nil {
+		if fieldErr := validateSignalSourceTimeout(*timeoutSeconds); fieldErr != nil {
+			return nil, 0, fieldErr
+		}
+		timeout = *timeoutSeconds
+	}
+
+	if headers == nil {
+		return savedHeaders, timeout, nil
+	}
+	merged := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if value == "" {
+			existing, ok := savedHeaders[name]
+			if !ok {
+				return nil, 0, &traderFieldError{"headers", fmt.Sprintf("请求头 %s 的值不能为空", name)}
+			}
+			value = existing
+		}
+		merged[name] = value
+	}
+	if err := signalsource.ValidateHeaders(merged); err != nil {
+		return nil, 0, &traderFieldError{"headers", err.Error()}
+	}
+	return merged, timeout, nil
+}
+
+// handleTestSignalSource 请求信号源并返回解析出的币种列表（不使用缓存），解析失败时返回具体的错误位置
+func (s *Server) handleTestSignalSource(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req TestSignalSourceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := signalsource.ValidateHeaders(req.Headers); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if fieldErr := validateSignalSourceTimeout(req.TimeoutSeconds); fieldErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErr.Message})
+		return
+	}
+
+	src := signalsource.Source{URL: req.URL, Headers: req.Headers, Timeout: time.Duration(req.TimeoutSeconds) * time.Second}
+	if src.URL == "" {
+		if req.Source != "coin_pool" && req.Source != "oi_top" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请提供 url，或指定 source 为 coin_pool / oi_top 测试已保存的信号源"})
+			return
+		}
+		if saved, err := s.database.GetUserSignalSource(userID); err == nil {
+			src = signalsource.Source{URL: saved.CoinPoolURL, Headers: saved.Headers, Timeout: time.Duration(saved.TimeoutSeconds) * time.Second}
+			if req.Source == "oi_top" {
+				src.URL = saved.OITopURL
+			}
+		}
+		if src.URL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "未配置 " + req.Source + " 信号源地址"})
+			return
+		}
+	}
+	if err := signalsource.ValidateURL(src.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := signalsource.Default().FetchNoCache(c.Request.Context(), src)
+	if err != nil {
+		// 响应格式错误返回422（附带出错位置），网络/上游错误返回502
+		var parseErr *signalsource.ParseError
+		if errors.As(err, &parseErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error(), "path": parseErr.Path})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "📡 用户 %s 测试信号源 %s: %d 个币种 (%s)", userID, src.URL, len(result.Symbols), result.Format)
+	resp := gin.H{
+		"symbols":    result.Symbols,
+		"count":      len(result.Symbols),
+		"format":     result.Format,
+		"fetched_at": result.FetchedAt,
+	}
+	if !result.UpdatedAt.IsZero() {
+		resp["updated_at"] = result.UpdatedAt
+	}
+	c.JSON(http.StatusOK, resp)
+}
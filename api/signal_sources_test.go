@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postTestSignalSource 以指定用户调用信号源测试接口
+func postTestSignalSource(s *Server, userID, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/user/signal-sources/test", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", userID)
+	s.handleTestSignalSource(c)
+	return w
+}
+
+// TestHandleTestSignalSource 测试信号源解析结果、格式错误位置、上游错误和已保存地址
+func TestHandleTestSignalSource(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"symbols": ["btc", "ETH/USDT"], "updated_at": "2026-10-16T08:00:00Z"}`))
+		case "/bad":
+			w.Write([]byte(`["BTC", "ETH$"]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	w := postTestSignalSource(s, "user-a", `{"url": "`+upstream.URL+`/ok"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Symbols []string `json:"symbols"`
+		Count   int      `json:"count"`
+		Format  string   `json:"format"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"BTCUSDT", "ETHUSDT"}, resp.Symbols)
+	assert.Equal(t, 2, resp.Count)
+	assert.Equal(t, "symbols", resp.Format)
+
+	w = postTestSignalSource(s, "user-a", `{"url": "`+upstream.URL+`/bad"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "[1]: 无效的币种")
+
+	w = postTestSignalSource(s, "user-a", `{"url": "`+upstream.URL+`/missing"}`)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+
+	w = postTestSignalSource(s, "user-a", `{"url": "file:///etc/passwd"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// 未提供 url 时测试已保存的信号源
+	w = postTestSignalSource(s, "user-a", `{"source": "oi_top"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code, "未配置信号源")
+	require.NoError(t, s.database.CreateUserSignalSource("user-a", "", upstream.URL+"/ok"))
+	w = postTestSignalSource(s, "user-a", `{"source": "oi_top"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+// callSignalSourceHandler 以指定用户调用信号源配置接口
+func callSignalSourceHandler(handler gin.HandlerFunc, method, userID, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/api/user/signal-sources", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", userID)
+	handler(c)
+	return w
+}
+
+// TestSignalSourceHeaders 测试请求头只返回名称、空值保留原值，并在测试已保存的信号源时发送
+func TestSignalSourceHeaders(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	var gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`["BTC"]`))
+	}))
+	defer upstream.Close()
+
+	body := `{"coin_pool_url": "` + upstream.URL + `", "headers": {"Authorization": "Bearer secret-token"}, "timeout_seconds": 10}`
+	w := callSignalSourceHandler(s.handleSaveUserSignalSource, http.MethodPost, "user-a", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	w = callSignalSourceHandler(s.handleGetUserSignalSource, http.MethodGet, "user-a", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "secret-token")
+	var resp struct {
+		HeaderNames    []string `json:"header_names"`
+		TimeoutSeconds int      `json:"timeout_seconds"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []string{"Authorization"}, resp.HeaderNames)
+	assert.Equal(t, 10, resp.TimeoutSeconds)
+
+	// 回传空值保留已保存的请求头
+	body = `{"coin_pool_url": "` + upstream.URL + `", "headers": {"Authorization": ""}}`
+	w = callSignalSourceHandler(s.handleSaveUserSignalSource, http.MethodPost, "user-a", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = postTestSignalSource(s, "user-a", `{"source": "coin_pool"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Bearer secret-token", gotAuth)
+
+	for name, invalid := range map[string]string{
+		"新请求头空值": `{"headers": {"X-Api-Key": ""}}`,
+		"名称无效":   `{"headers": {"Bad Header": "v"}}`,
+		"值包含换行":  `{"headers": {"X-Api-Key": "a\r\nb"}}`,
+		"超时过长":   `{"timeout_seconds": 3600}`,
+	} {
+		w = callSignalSourceHandler(s.handleSaveUserSignalSource, http.MethodPost, "user-a", invalid)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
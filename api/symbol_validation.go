@@ -0,0 +1,109 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/trader"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// splitTradingSymbols 拆分逗号分隔的交易币种（转大写，忽略空项）
+func splitTradingSymbols(symbols string) []string {
+	var result []string
+	for _, symbol := range strings.Split(symbols, ",") {
+		if symbol = strings.ToUpper(strings.TrimSpace(symbol)); symbol != "" {
+			result = append(result, symbol)
+		}
+	}
+	return result
+}
+
+// validateSymbolFormat 校验逗号分隔的币种列表格式（必须以USDT结尾）
+func validateSymbolFormat(field, symbols string) *traderFieldError {
+	for _, symbol := range splitTradingSymbols(symbols) {
+		if !strings.HasSuffix(symbol, "USDT") {
+			return &traderFieldError{field, fmt.Sprintf("无效的币种格式: %s，必须以USDT结尾", symbol)}
+		}
+	}
+	return nil
+}
+
+// checkSymbolsOnExchange 用交易所的交易对信息校验币种；交易所不支持或查询失败/超时时跳过校验（返回 nil）
+func checkSymbolsOnExchange(c *gin.Context, t trader.Trader, symbols string) []trader.SymbolIssue {
+	list := splitTradingSymbols(symbols)
+	if t == nil || len(list) == 0 {
+		return nil
+	}
+
+	type checkResult struct {
+		issues []trader.SymbolIssue
+		err    error
+	}
+	done := make(chan checkResult, 1)
+	go func() {
+		issues, err := trader.ValidateSymbols(t, list)
+		done <- checkResult{issues: issues, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		if result.err != nil {
+			requestLogf(c, "⚠️ 校验交易币种失败，跳过校验: %v", result.err)
+			return nil
+		}
+		return result.issues
+	case <-time.After(exchangeTestTimeout):
+		requestLogf(c, "⚠️ 校验交易币种超时，跳过校验")
+		return nil
+	}
+}
+
+// checkUpdatedSymbols 更新交易员时校验交易币种：优先使用已加载的交易员实例，否则用交易员所选账户创建临时交易器
+func (s *Server) checkUpdatedSymbols(c *gin.Context, userID, traderID, exchangeID, accountID, symbols string) []trader.SymbolIssue {
+	list := splitTradingSymbols(symbols)
+	if len(list) == 0 || exchangeID == config.PaperExchangeID {
+		return nil
+	}
+
+	if at, err := s.traderManager.GetTrader(traderID); err == nil && at.GetExchange() == exchangeID {
+		issues, err := at.ValidateSymbols(list)
+		if err != nil {
+			requestLogf(c, "⚠️ 校验交易币种失败，跳过校验: %v", err)
+			return nil
+		}
+		return issues
+	}
+
+	exchanges, err := s.database.GetExchanges(userID)
+	if err != nil {
+		requestLogf(c, "⚠️ 获取交易所配置失败，跳过交易币种校验: %v", err)
+		return nil
+	}
+	ex := config.FindExchangeAccount(exchanges, exchangeID, accountID)
+	if ex == nil || !ex.Enabled {
+		return nil
+	}
+	t, err := s.newExchangeTrader(exchangeID, ex, userID)
+	if err != nil {
+		requestLogf(c, "⚠️ 创建临时交易器失败，跳过交易币种校验: %v", err)
+		return nil
+	}
+	return checkSymbolsOnExchange(c, t, symbols)
+}
+
+// respondInvalidSymbols 返回未通过交易所校验的币种列表
+func respondInvalidSymbols(c *gin.Context, issues []trader.SymbolIssue) {
+	names := make([]string, 0, len(issues))
+	for _, issue := range issues {
+		names = append(names, fmt.Sprintf("%s(%s)", issue.Symbol, issue.Reason))
+	}
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error":           "以下交易币种在交易所不可用: " + strings.Join(names, ", "),
+		"field":           "trading_symbols",
+		"invalid_symbols": issues,
+	})
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nofx/config"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// fakeSymbolTrader 提供交易对规则的测试 trader
+type fakeSymbolTrader struct {
+	trader.Trader
+	filters map[string]trader.SymbolFilter
+}
+
+func (f *fakeSymbolTrader) GetSymbolFilters() (map[string]trader.SymbolFilter, error) {
+	return f.filters, nil
+}
+
+// TestCheckUpdatedSymbols 测试更新交易员时返回交易所中不存在或不可交易的币种
+func TestCheckUpdatedSymbols(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	if err := s.database.UpdateExchange("user-a", "binance", true, "key", "secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("保存交易所配置失败: %v", err)
+	}
+
+	s.traderFactory = func(exchangeID string, cfg *config.ExchangeConfig, userID string) (trader.Trader, error) {
+		return &fakeSymbolTrader{filters: map[string]trader.SymbolFilter{
+			"BTCUSDT":  {Symbol: "BTCUSDT", Status: trader.SymbolStatusTrading},
+			"LUNAUSDT": {Symbol: "LUNAUSDT", Status: "SETTLING"},
+		}}, nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/traders/trader-a", nil)
+
+	issues := s.checkUpdatedSymbols(c, "user-a", "trader-a", "binance", "", "BTCUSDT, btcusdtt,LUNAUSDT")
+	if len(issues) != 2 || issues[0].Symbol != "BTCUSDTT" || issues[1].Symbol != "LUNAUSDT" {
+		t.Fatalf("校验结果不符合预期: %+v", issues)
+	}
+
+	respondInvalidSymbols(c, issues)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("状态码 = %d, want 400", w.Code)
+	}
+	var resp struct {
+		Field          string               `json:"field"`
+		InvalidSymbols []trader.SymbolIssue `json:"invalid_symbols"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	if resp.Field != "trading_symbols" || len(resp.InvalidSymbols) != 2 {
+		t.Errorf("响应不符合预期: %s", w.Body.String())
+	}
+
+	if issues := s.checkUpdatedSymbols(c, "user-a", "trader-a", config.PaperExchangeID, "", "BTCUSDTT"); issues != nil {
+		t.Errorf("模拟盘不校验交易币种，实际 %+v", issues)
+	}
+}
+
+// TestValidateCreateTraderRequest_ExcludedSymbols 测试排除币种与交易币种使用相同的USDT后缀校验
+func TestValidateCreateTraderRequest_ExcludedSymbols(t *testing.T) {
+	errs := validateCreateTraderRequest(&CreateTraderRequest{ExcludedSymbols: "pepeusdt, WIFUSDT"})
+	if len(errs) != 0 {
+		t.Fatalf("合法的排除币种不应报错: %+v", errs)
+	}
+
+	errs = validateCreateTraderRequest(&CreateTraderRequest{ExcludedSymbols: "PEPEUSDT,DOGE"})
+	if len(errs) != 1 || errs[0].Field != "excluded_symbols" {
+		t.Fatalf("缺少USDT后缀的排除币种应报错: %+v", errs)
+	}
+}
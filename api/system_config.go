@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"nofx/config"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultLeverage 系统配置未设置杠杆时的默认倍数
+const defaultLeverage = 5
+
+// systemConfigReadOnlyKeys 不允许通过接口修改、也不在接口中返回的配置
+var systemConfigReadOnlyKeys = map[string]bool{
+	"jwt_secret": true,
+}
+
+// systemConfigSecretKeys 接口和审计日志中脱敏显示的配置
+var systemConfigSecretKeys = map[string]bool{
+	"metrics_token": true,
+	"smtp_password": true,
+}
+
+// systemConfigRestartKeys 启动时读取、修改后需重启才生效的配置（其余配置读取时即生效）
+var systemConfigRestartKeys = map[string]bool{
+	"api_server_port":        true,
+	"use_default_coins":      true,
+	"coin_pool_api_url":      true,
+	"oi_top_api_url":         true,
+	"rate_limit_auth":        true,
+	"rate_limit_public":      true,
+	"rate_limit_lockout":     true,
+	"rate_limit_refresh":     true,
+	"trusted_proxies":        true,
+	"cors_allowed_origins":   true,
+	"cors_allow_credentials": true,
+	"metrics_token":          true,
+	"metrics_port":           true,
+}
+
+// displaySystemConfigValue 接口返回的配置值（敏感配置脱敏）
+func displaySystemConfigValue(key, value string) string {
+	if systemConfigSecretKeys[key] && value != "" {
+		return MaskSensitiveString(value)
+	}
+	return value
+}
+
+// handleAdminGetSystemConfig 列出全部系统配置（仅管理员）
+func (s *Server) handleAdminGetSystemConfig(c *gin.Context) {
+	configs, err := s.database.ListSystemConfig()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "读取系统配置失败: " + err.Error()})
+		return
+	}
+
+	result := make(map[string]string, len(configs))
+	for key, value := range configs {
+		if !systemConfigReadOnlyKeys[key] {
+			result[key] = displaySystemConfigValue(key, value)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"config": result})
+}
+
+// systemConfigValue 请求中的配置值：字符串原样使用，布尔值、数字和数组使用其 JSON 文本
+func systemConfigValue(raw json.RawMessage) (string, bool) {
+	var str string
+	if err := json.Unmarshal(raw, &str); err == nil {
+		return str, true
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil || compact.String() == "null" {
+		return "", false
+	}
+	return compact.String(), true
+}
+
+// handleAdminUpdateSystemConfig 批量修改系统配置（仅管理员）
+// 请求体为 {"key": value}，只能修改已存在的配置；全部校验通过后才写入，写入后立即对读取方和订阅者生效
+func (s *Server) handleAdminUpdateSystemConfig(c *gin.Context) {
+	var req map[string]json.RawMessage
+	if err := c.ShouldBindJSON(&req); err != nil || len(req) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "请求体应为非空的 {\"配置项\": 值} 对象"})
+		return
+	}
+
+	sysConfig := s.database.SystemConfig()
+	keys := make([]string, 0, len(req))
+	values := make(map[string]string, len(req))
+	fieldErrors := make(map[string]string)
+	for key, raw := range req {
+		keys = append(keys, key)
+		if systemConfigReadOnlyKeys[key] {
+			fieldErrors[key] = "不允许通过接口修改"
+			continue
+		}
+		if _, err := sysConfig.Get(key); errors.Is(err, sql.ErrNoRows) {
+			fieldErrors[key] = "未知的配置项"
+			continue
+		} else if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "读取系统配置失败: " + err.Error()})
+			return
+		}
+		value, ok := systemConfigValue(raw)
+		if !ok {
+			fieldErrors[key] = "配置值必须是字符串、数字、布尔值或数组"
+			continue
+		}
+		if err := config.ValidateSystemConfig(key, value); err != nil {
+			fieldErrors[key] = err.Error()
+			continue
+		}
+		values[key] = value
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "系统配置校验失败", "fields": fieldErrors})
+		return
+	}
+
+	sort.Strings(keys)
+	updated := make(map[string]string, len(keys))
+	var restartRequired []string
+	for _, key := range keys {
+		if err := sysConfig.Set(key, values[key]); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "保存配置 " + key + " 失败: " + err.Error(), "updated": updated})
+			return
+		}
+		updated[key] = displaySystemConfigValue(key, values[key])
+		if systemConfigRestartKeys[key] {
+			restartRequired = append(restartRequired, key)
+		}
+	}
+
+	userID := c.GetString("user_id")
+	s.audit(c, userID, auditSystemConfigUpdate, "", gin.H{"config": updated})
+	requestLogf(c, "⚙️ 管理员 %s 修改系统配置: %v", userID, keys)
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "系统配置已更新",
+		"updated":          updated,
+		"restart_required": restartRequired,
+	})
+}
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/auth"
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestAdminUpdateSystemConfig 管理员修改系统配置：全部校验通过才写入，修改后注册开关和默认杠杆立即生效
+func TestAdminUpdateSystemConfig(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	adminToken, _ := auth.GenerateJWT("user-a", "user-a@test.com", config.UserRoleAdmin)
+	userToken, _ := auth.GenerateJWT("user-b", "user-b@test.com", "")
+	if err := s.database.SetUserRole("user-a", config.UserRoleAdmin); err != nil {
+		t.Fatalf("设置管理员失败: %v", err)
+	}
+
+	do := func(method, path, token, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+		s.router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := do(http.MethodPut, "/api/admin/system-config", userToken, `{"beta_mode":true}`); w.Code != http.StatusForbidden {
+		t.Errorf("普通用户修改系统配置应返回403，实际 %d", w.Code)
+	}
+
+	// 任一配置项无效时不写入任何配置
+	w := do(http.MethodPut, "/api/admin/system-config", adminToken, `{"altcoin_leverage":10,"btc_eth_leverage":500,"jwt_secret":"x","no_such_key":"1"}`)
+	var failed struct {
+		Fields map[string]string `json:"fields"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &failed)
+	if w.Code != http.StatusBadRequest || len(failed.Fields) != 3 {
+		t.Fatalf("应返回3个字段错误: %d %s", w.Code, w.Body.String())
+	}
+	if got := s.database.SystemConfig().Int("altcoin_leverage", 0); got != 5 {
+		t.Errorf("校验失败时不应写入其他配置，altcoin_leverage=%d", got)
+	}
+
+	w = do(http.MethodPut, "/api/admin/system-config", adminToken,
+		`{"registration_enabled":false,"altcoin_leverage":10,"default_coins":["BTCUSDT","ETHUSDT"],"metrics_token":"token-1234567890"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("修改系统配置应返回200: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "token-1234567890") {
+		t.Errorf("响应中不应包含敏感配置原文: %s", w.Body.String())
+	}
+
+	w = do(http.MethodGet, "/api/config", "", "")
+	var cfg struct {
+		RegistrationEnabled bool     `json:"registration_enabled"`
+		AltcoinLeverage     int      `json:"altcoin_leverage"`
+		DefaultCoins        []string `json:"default_coins"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &cfg)
+	if cfg.RegistrationEnabled || cfg.AltcoinLeverage != 10 || len(cfg.DefaultCoins) != 2 {
+		t.Errorf("修改后 /api/config 应立即返回新值: %s", w.Body.String())
+	}
+	if w := do(http.MethodPost, "/api/register", "", `{"email":"new@test.com","password":"secret123"}`); w.Code != http.StatusForbidden {
+		t.Errorf("关闭注册后应返回403，实际 %d", w.Code)
+	}
+
+	w = do(http.MethodGet, "/api/admin/system-config", adminToken, "")
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), "jwt_secret") || strings.Contains(w.Body.String(), "token-1234567890") {
+		t.Errorf("配置列表不应包含 jwt_secret 和敏感配置原文: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/config"
+	"nofx/manager"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupTraderAccessServer 创建带两个用户及其交易员的测试服务器
+func setupTraderAccessServer(t *testing.T) *Server {
+	db, err := config.NewDatabase(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	for _, userID := range []string{"user-a", "user-b", "user-empty"} {
+		if err := db.CreateUser(&config.User{ID: userID, Email: userID + "@test.com", PasswordHash: "hash"}); err != nil {
+			t.Fatalf("创建用户失败: %v", err)
+		}
+	}
+	for _, tr := range []*config.TraderRecord{
+		{ID: "trader-a", UserID: "user-a", Name: "A", AIModelID: "deepseek", ExchangeID: "binance"},
+		{ID: "trader-b", UserID: "user-b", Name: "B", AIModelID: "deepseek", ExchangeID: "binance"},
+	} {
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+
+	return &Server{traderManager: manager.NewTraderManager(), database: db}
+}
+
+// newTraderQueryContext 构造带 user_id 和 query 的 gin 上下文
+func newTraderQueryContext(userID, query string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/account"+query, nil)
+	c.Set("user_id", userID)
+	return c
+}
+
+// TestGetTraderFromQuery_Ownership 测试 trader_id 归属校验
+func TestGetTraderFromQuery_Ownership(t *testing.T) {
+	s := setupTraderAccessServer(t)
+
+	tests := []struct {
+		name       string
+		userID     string
+		query      string
+		wantTrader string
+		wantStatus int // 0 表示不应返回错误
+	}{
+		{"访问自己的交易员", "user-a", "?trader_id=trader-a", "trader-a", 0},
+		{"访问他人的交易员返回403", "user-a", "?trader_id=trader-b", "", http.StatusForbidden},
+		{"访问不存在的交易员返回403", "user-a", "?trader_id=unknown", "", http.StatusForbidden},
+		{"未指定时回退到自己的第一个交易员", "user-b", "", "trader-b", 0},
+		{"未指定且没有交易员时不回退到他人的交易员", "user-empty", "", "", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, traderID, err := s.getTraderFromQuery(newTraderQueryContext(tt.userID, tt.query))
+			if tt.wantStatus == 0 {
+				if err != nil {
+					t.Fatalf("不应返回错误: %v", err)
+				}
+				if traderID != tt.wantTrader {
+					t.Errorf("traderID = %s, want %s", traderID, tt.wantTrader)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("应返回错误，实际得到 traderID=%s", traderID)
+			}
+			if status := traderQueryErrorStatus(err); status != tt.wantStatus {
+				t.Errorf("状态码 = %d, want %d", status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+// TestHandleAccount_ForbiddenForOtherUser 访问他人交易员的账户信息返回403
+func TestHandleAccount_ForbiddenForOtherUser(t *testing.T) {
+	s := setupTraderAccessServer(t)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/account?trader_id=trader-b", nil)
+	c.Set("user_id", "user-a")
+
+	s.handleAccount(c)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("状态码 = %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+// TestHandleDeleteTrader_Ownership 测试只能删除自己的交易员，删除后记录不再存在
+func TestHandleDeleteTrader_Ownership(t *testing.T) {
+	s := setupTraderAccessServer(t)
+
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/api/traders/trader-b", nil)
+	c.Params = gin.Params{{Key: "id", Value: "trader-b"}}
+	c.Set("user_id", "user-a")
+	s.handleDeleteTrader(c)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("删除他人交易员状态码 = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/api/traders/trader-a", nil)
+	c.Params = gin.Params{{Key: "id", Value: "trader-a"}}
+	c.Set("user_id", "user-a")
+	s.handleDeleteTrader(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("删除自己的交易员状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	if traders, _ := s.database.GetTraders("user-a"); len(traders) != 0 {
+		t.Errorf("删除后仍有 %d 个交易员", len(traders))
+	}
+	if traders, _ := s.database.GetTraders("user-b"); len(traders) != 1 {
+		t.Error("不应影响其他用户的交易员")
+	}
+}
+
+// TestValidateTraderReferences 测试创建/更新交易员时校验AI模型和交易所归属及可用状态
+func TestValidateTraderReferences(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	db := s.database
+	if err := db.CreateAIModel("user-a", "user-a_deepseek", "DeepSeek", "deepseek", true, "sk-test", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	if err := db.CreateAIModel("user-a", "user-a_qwen", "Qwen", "qwen", true, "", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	if err := db.CreateExchange("user-a", "binance", "Binance", "binance", true, "key", "secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("创建交易所失败: %v", err)
+	}
+	if err := db.CreateExchange("user-a", "aster", "Aster", "aster", false, "", "", false, "", "", "", ""); err != nil {
+		t.Fatalf("创建交易所失败: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		userID     string
+		aiModelID  string
+		exchangeID string
+		wantFields []string
+	}{
+		{"自己已启用的模型和交易所", "user-a", "user-a_deepseek", "binance", nil},
+		{"旧数据按 provider 匹配", "user-a", "deepseek", "binance", nil},
+		{"模型未配置API Key", "user-a", "user-a_qwen", "binance", []string{"ai_model_id"}},
+		{"交易所未启用", "user-a", "user-a_deepseek", "aster", []string{"exchange_id"}},
+		{"模拟盘无需交易所配置", "user-a", "user-a_deepseek", "paper", nil},
+		{"他人的模型和交易所", "user-b", "user-a_deepseek", "binance", []string{"ai_model_id", "exchange_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs, err := s.validateTraderReferences(tt.userID, tt.aiModelID, tt.exchangeID, "")
+			if err != nil {
+				t.Fatalf("校验失败: %v", err)
+			}
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("错误数量 = %d, want %d: %+v", len(errs), len(tt.wantFields), errs)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Errorf("errs[%d].Field = %s, want %s", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+
+	// 创建接口返回400和出错字段
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/traders", strings.NewReader(`{"name":"X","ai_model_id":"user-a_deepseek","exchange_id":"binance"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", "user-b")
+	s.handleCreateTrader(c)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), `"field":"ai_model_id"`) {
+		t.Errorf("引用他人模型创建交易员应返回400，实际 %d: %s", w.Code, w.Body.String())
+	}
+}
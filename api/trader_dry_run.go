@@ -0,0 +1,46 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"nofx/decision"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// dryRunPerMinute 每个用户每分钟允许的决策预演次数（每次预演都会消耗AI token）
+const dryRunPerMinute = 3
+
+// handleDryRunTrader 决策预演：按正常周期构建提示词并调用模型，返回渲染后的提示词、模型原始输出和解析后的决策，不执行交易
+func (s *Server) handleDryRunTrader(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 校验交易员是否属于当前用户
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	result, err := at.DryRun()
+	if err != nil {
+		switch {
+		case errors.Is(err, trader.ErrCycleInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		case errors.Is(err, decision.ErrAICall):
+			c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	requestLogf(c, "🧪 用户 %s 预演交易员 %s 的决策: %d 条", userID, traderID, len(result.Decisions))
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"fmt"
+	"nofx/trader"
+	"strings"
+)
+
+// maxEnsembleModels 多模型共识最多同时请求的模型数（每个模型都会产生一次调用费用）
+const maxEnsembleModels = 3
+
+// validateEnsembleModels 校验多模型共识配置（2-3个属于当前用户、已启用且已配置的不同模型），
+// 返回规范化后的模型ID列表（逗号分隔）和共识规则（默认 majority）
+func (s *Server) validateEnsembleModels(userID, ensembleModelIDs, rule string) (string, string, []traderFieldError, error) {
+	errs := make([]traderFieldError, 0)
+	if rule == "" {
+		rule = trader.ConsensusMajority
+	}
+	if rule != trader.ConsensusAll && rule != trader.ConsensusMajority {
+		errs = append(errs, traderFieldError{"consensus_rule", "共识规则必须是 all 或 majority"})
+	}
+
+	ids := trader.ParseEnsembleModelIDs(ensembleModelIDs)
+	if len(ids) == 0 {
+		return "", rule, errs, nil
+	}
+	if len(ids) < 2 || len(ids) > maxEnsembleModels {
+		errs = append(errs, traderFieldError{"ensemble_model_ids", fmt.Sprintf("多模型共识需要2-%d个模型", maxEnsembleModels)})
+		return ensembleModelIDs, rule, errs, nil
+	}
+
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return "", "", nil, fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	normalized := make([]string, 0, len(ids))
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		model := findUserAIModel(models, id)
+		switch {
+		case model == nil:
+			errs = append(errs, traderFieldError{"ensemble_model_ids", fmt.Sprintf("AI模型不存在: %s", id)})
+		case !model.Enabled:
+			errs = append(errs, traderFieldError{"ensemble_model_ids", fmt.Sprintf("AI模型未启用: %s", id)})
+		case !aiModelConfigured(model):
+			errs = append(errs, traderFieldError{"ensemble_model_ids", fmt.Sprintf("AI模型未配置API Key或地址: %s", id)})
+		case seen[model.ID]:
+			errs = append(errs, traderFieldError{"ensemble_model_ids", fmt.Sprintf("AI模型重复: %s", id)})
+		default:
+			seen[model.ID] = true
+			normalized = append(normalized, model.ID)
+		}
+	}
+	if len(errs) > 0 {
+		return ensembleModelIDs, rule, errs, nil
+	}
+	return strings.Join(normalized, ","), rule, errs, nil
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestValidateEnsembleModels 测试多模型共识配置校验（模型数量、归属、启用状态、重复和共识规则）
+func TestValidateEnsembleModels(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	require.NoError(t, s.database.UpdateAIModel("user-a", "deepseek", true, "sk-a", "", "", nil))
+	require.NoError(t, s.database.UpdateAIModel("user-a", "qwen", true, "sk-b", "", "", nil))
+	require.NoError(t, s.database.UpdateAIModel("user-a", "user-a_custom", false, "sk-c", "", "", nil))
+
+	ids, rule, errs, err := s.validateEnsembleModels("user-a", "deepseek, qwen", "")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Equal(t, "majority", rule)
+	assert.Equal(t, "user-a_deepseek,user-a_qwen", ids, "按provider引用的模型规范化为用户模型ID")
+
+	// 未配置多模型共识
+	ids, _, errs, err = s.validateEnsembleModels("user-a", "", "all")
+	require.NoError(t, err)
+	assert.Empty(t, errs)
+	assert.Empty(t, ids)
+
+	cases := []struct {
+		name, ids, rule, field string
+	}{
+		{"只有一个模型", "deepseek", "", "ensemble_model_ids"},
+		{"超过三个模型", "deepseek,qwen,a,b", "", "ensemble_model_ids"},
+		{"模型重复", "deepseek,deepseek", "", "ensemble_model_ids"},
+		{"模型未启用", "deepseek,user-a_custom", "", "ensemble_model_ids"},
+		{"其他用户的模型", "deepseek,user-b_qwen", "", "ensemble_model_ids"},
+		{"未知共识规则", "deepseek,qwen", "any", "consensus_rule"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, errs, err := s.validateEnsembleModels("user-a", tc.ids, tc.rule)
+			require.NoError(t, err)
+			require.NotEmpty(t, errs)
+			assert.Equal(t, tc.field, errs[0].Field)
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"nofx/trader"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// traderExportKind 导出文档类型标识
+	traderExportKind = "nofx.trader"
+	// traderExportVersion 当前导出格式版本，字段结构变化时递增并在 upgradeTraderExport 中迁移旧版本
+	traderExportVersion = 1
+)
+
+// traderExport 可移植的交易员配置导出文档（不包含交易所和AI模型的密钥）
+type traderExport struct {
+	Kind       string             `json:"kind"`
+	Version    int                `json:"version"`
+	ExportedAt string             `json:"exported_at,omitempty"`
+	Trader     traderExportConfig `json:"trader"`
+}
+
+// traderExportConfig 导出的交易员配置，ai_model_id / exchange_id 只引用导入方已配置的模型和交易所
+// 交易所账户ID因用户而异，不导出；导入时使用默认账户或 exchange_account_id 查询参数指定的账户
+type traderExportConfig struct {
+	Name                     string  `json:"name"`
+	AIModelID                string  `json:"ai_model_id"`
+	ExchangeID               string  `json:"exchange_id"`
+	InitialBalance           float64 `json:"initial_balance"`
+	ScanIntervalMinutes      int     `json:"scan_interval_minutes"`
+	BTCETHLeverage           int     `json:"btc_eth_leverage"`
+	AltcoinLeverage          int     `json:"altcoin_leverage"`
+	TradingSymbols           string  `json:"trading_symbols"`
+	ExcludedSymbols          string  `json:"excluded_symbols"`
+	CustomPrompt             string  `json:"custom_prompt"`
+	OverrideBasePrompt       bool    `json:"override_base_prompt"`
+	SystemPromptTemplate     string  `json:"system_prompt_template"`
+	IsCrossMargin            *bool   `json:"is_cross_margin"`
+	UseCoinPool              bool    `json:"use_coin_pool"`
+	UseOITop                 bool    `json:"use_oi_top"`
+	IsPublic                 bool    `json:"is_public"`
+	MaxDailyLossPct          float64 `json:"max_daily_loss_pct"`
+	DailyLossFlatten         bool    `json:"daily_loss_flatten"`
+	MaxPositionValueUSDT     float64 `json:"max_position_value_usdt"`
+	MaxTotalExposurePct      float64 `json:"max_total_exposure_pct"`
+	DefaultStopLossPct       float64 `json:"default_stop_loss_pct"`
+	DefaultTakeProfitPct     float64 `json:"default_take_profit_pct"`
+	CooldownMinutesAfterLoss int     `json:"cooldown_minutes_after_loss"`
+	ExecutionMode            string  `json:"execution_mode"`
+	LimitOffsetBps           float64 `json:"limit_offset_bps"`
+	LimitTimeoutSeconds      int     `json:"limit_timeout_seconds"`
+	LimitFallback            string  `json:"limit_fallback"`
+	ExperimentEnabled        bool    `json:"experiment_enabled"`
+	TemplateA                string  `json:"template_a"`
+	TemplateB                string  `json:"template_b"`
+	SplitRatio               float64 `json:"split_ratio"`
+	ExperimentMode           string  `json:"experiment_mode"`
+	FallbackAIModelID        string  `json:"fallback_ai_model_id"`
+	FallbackStickyMinutes    int     `json:"fallback_sticky_minutes"`
+	EnsembleModelIDs         string  `json:"ensemble_model_ids"`
+	ConsensusRule            string  `json:"consensus_rule"`
+
+	Guardrails []trader.GuardrailConfig `json:"guardrails,omitempty"`
+}
+
+// newTraderExport 由交易员记录生成导出文档
+func newTraderExport(record *config.TraderRecord, now time.Time) *traderExport {
+	isCrossMargin := record.IsCrossMargin
+	// 保存时已校验，无法解析的旧数据不导出护栏规则
+	guardrails, _ := trader.ParseGuardrails(record.Guardrails)
+	return &traderExport{
+		Kind:       traderExportKind,
+		Version:    traderExportVersion,
+		ExportedAt: now.UTC().Format(time.RFC3339),
+		Trader: traderExportConfig{
+			Name:                     record.Name,
+			AIModelID:                record.AIModelID,
+			ExchangeID:               record.ExchangeID,
+			InitialBalance:           record.InitialBalance,
+			ScanIntervalMinutes:      record.ScanIntervalMinutes,
+			BTCETHLeverage:           record.BTCETHLeverage,
+			AltcoinLeverage:          record.AltcoinLeverage,
+			TradingSymbols:           record.TradingSymbols,
+			ExcludedSymbols:          record.ExcludedSymbols,
+			CustomPrompt:             record.CustomPrompt,
+			OverrideBasePrompt:       record.OverrideBasePrompt,
+			SystemPromptTemplate:     record.SystemPromptTemplate,
+			IsCrossMargin:            &isCrossMargin,
+			UseCoinPool:              record.UseCoinPool,
+			UseOITop:                 record.UseOITop,
+			IsPublic:                 record.IsPublic,
+			MaxDailyLossPct:          record.MaxDailyLossPct,
+			DailyLossFlatten:         record.DailyLossFlatten,
+			MaxPositionValueUSDT:     record.MaxPositionValueUSDT,
+			MaxTotalExposurePct:      record.MaxTotalExposurePct,
+			DefaultStopLossPct:       record.DefaultStopLossPct,
+			DefaultTakeProfitPct:     record.DefaultTakeProfitPct,
+			CooldownMinutesAfterLoss: record.CooldownMinutesAfterLoss,
+			ExecutionMode:            record.ExecutionMode,
+			LimitOffsetBps:           record.LimitOffsetBps,
+			LimitTimeoutSeconds:      record.LimitTimeoutSeconds,
+			LimitFallback:            record.LimitFallback,
+			ExperimentEnabled:        record.ExperimentEnabled,
+			TemplateA:                record.TemplateA,
+			TemplateB:                record.TemplateB,
+			SplitRatio:               record.SplitRatio,
+			ExperimentMode:           record.ExperimentMode,
+			FallbackAIModelID:        record.FallbackAIModelID,
+			FallbackStickyMinutes:    record.FallbackStickyMinutes,
+			EnsembleModelIDs:         record.EnsembleModelIDs,
+			ConsensusRule:            record.ConsensusRule,
+			Guardrails:               guardrails,
+		},
+	}
+}
+
+// upgradeTraderExport 将旧版本导出文档迁移到当前版本
+func upgradeTraderExport(doc *traderExport) error {
+	if doc.Kind != traderExportKind {
+		return fmt.Errorf("不是交易员导出文件 (kind=%q)", doc.Kind)
+	}
+	switch doc.Version {
+	case traderExportVersion:
+		return nil
+	default:
+		return fmt.Errorf("不支持的导出格式版本: %d（当前支持 %d）", doc.Version, traderExportVersion)
+	}
+}
+
+// createRequest 转换为创建交易员请求
+func (cfg *traderExportConfig) createRequest() *CreateTraderRequest {
+	return &CreateTraderRequest{
+		Name:                     strings.TrimSpace(cfg.Name),
+		AIModelID:                cfg.AIModelID,
+		ExchangeID:               cfg.ExchangeID,
+		InitialBalance:           cfg.InitialBalance,
+		ScanIntervalMinutes:      cfg.ScanIntervalMinutes,
+		BTCETHLeverage:           cfg.BTCETHLeverage,
+		AltcoinLeverage:          cfg.AltcoinLeverage,
+		TradingSymbols:           cfg.TradingSymbols,
+		ExcludedSymbols:          cfg.ExcludedSymbols,
+		CustomPrompt:             cfg.CustomPrompt,
+		OverrideBasePrompt:       cfg.OverrideBasePrompt,
+		SystemPromptTemplate:     cfg.SystemPromptTemplate,
+		IsCrossMargin:            cfg.IsCrossMargin,
+		UseCoinPool:              cfg.UseCoinPool,
+		UseOITop:                 cfg.UseOITop,
+		IsPublic:                 cfg.IsPublic,
+		MaxDailyLossPct:          cfg.MaxDailyLossPct,
+		DailyLossFlatten:         cfg.DailyLossFlatten,
+		MaxPositionValueUSDT:     cfg.MaxPositionValueUSDT,
+		MaxTotalExposurePct:      cfg.MaxTotalExposurePct,
+		DefaultStopLossPct:       cfg.DefaultStopLossPct,
+		DefaultTakeProfitPct:     cfg.DefaultTakeProfitPct,
+		CooldownMinutesAfterLoss: cfg.CooldownMinutesAfterLoss,
+		ExecutionMode:            cfg.ExecutionMode,
+		LimitOffsetBps:           cfg.LimitOffsetBps,
+		LimitTimeoutSeconds:      cfg.LimitTimeoutSeconds,
+		LimitFallback:            cfg.LimitFallback,
+		ExperimentEnabled:        cfg.ExperimentEnabled,
+		TemplateA:                cfg.TemplateA,
+		TemplateB:                cfg.TemplateB,
+		SplitRatio:               cfg.SplitRatio,
+		ExperimentMode:           cfg.ExperimentMode,
+		FallbackAIModelID:        cfg.FallbackAIModelID,
+		FallbackStickyMinutes:    cfg.FallbackStickyMinutes,
+		EnsembleModelIDs:         cfg.EnsembleModelIDs,
+		ConsensusRule:            cfg.ConsensusRule,
+		Guardrails:               cfg.Guardrails,
+	}
+}
+
+// validateTraderImport 校验导入的交易员配置：必填字段、与创建接口相同的字段规则，以及引用的模型/交易所是否已配置
+func (s *Server) validateTraderImport(userID string, req *CreateTraderRequest) ([]traderFieldError, error) {
+	errs := make([]traderFieldError, 0)
+	if req.Name == "" {
+		errs = append(errs, traderFieldError{"name", "交易员名称不能为空"})
+	}
+	errs = append(errs, validateCreateTraderRequest(req)...)
+	errs = append(errs, validateExperimentSettings(userID, req.ExperimentEnabled, req.TemplateA, req.TemplateB, req.SplitRatio, req.ExperimentMode)...)
+
+	if req.AIModelID == "" {
+		errs = append(errs, traderFieldError{"ai_model_id", "AI模型不能为空"})
+	}
+	if req.ExchangeID == "" {
+		errs = append(errs, traderFieldError{"exchange_id", "交易所不能为空"})
+	}
+	if req.AIModelID == "" || req.ExchangeID == "" {
+		return errs, nil
+	}
+
+	refErrs, err := s.validateTraderReferences(userID, req.AIModelID, req.ExchangeID, req.ExchangeAccountID)
+	if err != nil {
+		return nil, err
+	}
+	fallbackModelID, fallbackErrs, err := s.validateFallbackModel(userID, req.AIModelID, req.FallbackAIModelID, req.FallbackStickyMinutes)
+	if err != nil {
+		return nil, err
+	}
+	req.FallbackAIModelID = fallbackModelID
+	ensembleModelIDs, consensusRule, ensembleErrs, err := s.validateEnsembleModels(userID, req.EnsembleModelIDs, req.ConsensusRule)
+	if err != nil {
+		return nil, err
+	}
+	req.EnsembleModelIDs, req.ConsensusRule = ensembleModelIDs, consensusRule
+	errs = append(errs, refErrs...)
+	errs = append(errs, fallbackErrs...)
+	return append(errs, ensembleErrs...), nil
+}
+
+// handleExportTrader 导出交易员配置为可移植的 JSON 文档（不含密钥）
+func (s *Server) handleExportTrader(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	record, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="trader-%s.json"`, record.ID))
+	c.JSON(http.StatusOK, newTraderExport(record, time.Now()))
+}
+
+// handleImportTrader 从导出文档为当前用户创建新的交易员
+func (s *Server) handleImportTrader(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var doc traderExport
+	if err := c.ShouldBindJSON(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := upgradeTraderExport(&doc); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := doc.Trader.createRequest()
+	req.ExchangeAccountID = c.Query("exchange_account_id")
+	fieldErrors, err := s.validateTraderImport(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(fieldErrors) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "导入的交易员配置校验失败", "fields": fieldErrors})
+		return
+	}
+
+	s.createTrader(c, userID, req)
+}
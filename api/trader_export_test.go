@@ -0,0 +1,126 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"nofx/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTraderExportContext 构造带 user_id 的 gin 上下文
+func newTraderExportContext(userID, method, path string, body []byte) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, path, bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("user_id", userID)
+	return c, w
+}
+
+// TestHandleExportTrader 测试导出只包含配置字段且只能导出自己的交易员
+func TestHandleExportTrader(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	if err := s.database.CreateAIModel("user-a", "user-a_deepseek", "DeepSeek", "deepseek", true, "sk-test", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	if err := s.database.CreateExchange("user-a", "binance", "Binance", "cex", true, "api-key", "secret-key", false, "", "", "", ""); err != nil {
+		t.Fatalf("创建交易所失败: %v", err)
+	}
+	record := &config.TraderRecord{ID: "trader-export", UserID: "user-a", Name: "A", AIModelID: "user-a_deepseek", ExchangeID: "binance", BTCETHLeverage: 10}
+	if err := s.database.CreateTrader(record); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	c, w := newTraderExportContext("user-a", http.MethodGet, "/api/traders/trader-export/export", nil)
+	c.Params = gin.Params{{Key: "id", Value: "trader-export"}}
+	s.handleExportTrader(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("状态码 = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(strings.ToLower(w.Body.String()), "api_key") || strings.Contains(w.Body.String(), "secret") {
+		t.Errorf("导出内容不应包含密钥字段: %s", w.Body.String())
+	}
+
+	var doc traderExport
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("解析导出文档失败: %v", err)
+	}
+	if doc.Kind != traderExportKind || doc.Version != traderExportVersion {
+		t.Errorf("导出文档版本信息不正确: %s v%d", doc.Kind, doc.Version)
+	}
+	if doc.Trader.Name != "A" || doc.Trader.AIModelID != "user-a_deepseek" || doc.Trader.ExchangeID != "binance" || doc.Trader.BTCETHLeverage != 10 {
+		t.Errorf("导出的交易员配置不正确: %+v", doc.Trader)
+	}
+
+	c, w = newTraderExportContext("user-b", http.MethodGet, "/api/traders/trader-export/export", nil)
+	c.Params = gin.Params{{Key: "id", Value: "trader-export"}}
+	s.handleExportTrader(c)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("导出他人交易员状态码 = %d, want 404", w.Code)
+	}
+}
+
+// TestHandleImportTrader_Validation 测试导入时的版本检查和逐字段错误列表
+func TestHandleImportTrader_Validation(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	if err := s.database.CreateAIModel("user-b", "user-b_deepseek", "DeepSeek", "deepseek", true, "sk-test", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+
+	doc := traderExport{
+		Kind:    traderExportKind,
+		Version: traderExportVersion,
+		Trader: traderExportConfig{
+			Name:           "Imported",
+			AIModelID:      "user-b_deepseek",
+			ExchangeID:     "binance",
+			BTCETHLeverage: 100,
+			TradingSymbols: "BTCUSDT,ETHBTC",
+		},
+	}
+	body, _ := json.Marshal(doc)
+	c, w := newTraderExportContext("user-b", http.MethodPost, "/api/traders/import", body)
+	s.handleImportTrader(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("状态码 = %d, want 400: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Fields []traderFieldError `json:"fields"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("解析响应失败: %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, f := range resp.Fields {
+		fields[f.Field] = true
+	}
+	for _, want := range []string{"btc_eth_leverage", "trading_symbols", "exchange_id"} {
+		if !fields[want] {
+			t.Errorf("错误列表缺少字段 %s: %+v", want, resp.Fields)
+		}
+	}
+	if fields["ai_model_id"] {
+		t.Errorf("已启用的AI模型不应报错: %+v", resp.Fields)
+	}
+
+	doc.Version = traderExportVersion + 1
+	body, _ = json.Marshal(doc)
+	c, w = newTraderExportContext("user-b", http.MethodPost, "/api/traders/import", body)
+	s.handleImportTrader(c)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "版本") {
+		t.Errorf("不支持的版本应返回400，实际 %d: %s", w.Code, w.Body.String())
+	}
+
+	traders, _ := s.database.GetTraders("user-b")
+	if len(traders) != 1 {
+		t.Errorf("校验失败时不应创建交易员，实际 %d 个", len(traders))
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"fmt"
+	"nofx/mcp"
+)
+
+// maxFallbackStickyMinutes 备用模型粘滞时长上限（1天）
+const maxFallbackStickyMinutes = 24 * 60
+
+// validateFallbackModel 校验备用AI模型（属于当前用户、已启用、已配置且不能与主模型相同），返回规范化后的模型ID
+func (s *Server) validateFallbackModel(userID, aiModelID, fallbackModelID string, stickyMinutes int) (string, []traderFieldError, error) {
+	errs := make([]traderFieldError, 0)
+	if stickyMinutes < 0 || stickyMinutes > maxFallbackStickyMinutes {
+		errs = append(errs, traderFieldError{"fallback_sticky_minutes", "备用模型粘滞时间必须在0-1440分钟之间（0表示每周期先尝试主模型）"})
+	}
+	if fallbackModelID == "" {
+		return "", errs, nil
+	}
+
+	models, err := s.database.GetAIModels(userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("获取AI模型配置失败: %w", err)
+	}
+	fallback := findUserAIModel(models, fallbackModelID)
+	switch {
+	case fallback == nil:
+		errs = append(errs, traderFieldError{"fallback_ai_model_id", fmt.Sprintf("备用AI模型不存在: %s", fallbackModelID)})
+	case !fallback.Enabled:
+		errs = append(errs, traderFieldError{"fallback_ai_model_id", fmt.Sprintf("备用AI模型未启用: %s", fallbackModelID)})
+	case mcp.IsLocalProvider(fallback.Provider) && fallback.CustomAPIURL == "":
+		errs = append(errs, traderFieldError{"fallback_ai_model_id", fmt.Sprintf("本地AI模型未配置API地址: %s", fallbackModelID)})
+	case !aiModelConfigured(fallback):
+		errs = append(errs, traderFieldError{"fallback_ai_model_id", fmt.Sprintf("备用AI模型未配置API Key: %s", fallbackModelID)})
+	default:
+		if primary := findUserAIModel(models, aiModelID); primary != nil && primary.ID == fallback.ID {
+			errs = append(errs, traderFieldError{"fallback_ai_model_id", "备用AI模型不能与主模型相同"})
+		}
+		return fallback.ID, errs, nil
+	}
+	return fallbackModelID, errs, nil
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateGuardrailsRequest 更新交易员护栏规则请求（空数组表示清除全部规则）
+type UpdateGuardrailsRequest struct {
+	Guardrails []trader.GuardrailConfig `json:"guardrails"`
+}
+
+// validateGuardrails 校验护栏规则配置（类型已注册、参数有效、数量不超过上限）
+func validateGuardrails(configs []trader.GuardrailConfig) *traderFieldError {
+	if _, err := trader.NewGuardrailPipeline(configs); err != nil {
+		return &traderFieldError{"guardrails", err.Error()}
+	}
+	return nil
+}
+
+// encodeGuardrails 序列化护栏规则用于保存（未配置时保存为空字符串）
+func encodeGuardrails(configs []trader.GuardrailConfig) (string, error) {
+	if len(configs) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(configs)
+	if err != nil {
+		return "", fmt.Errorf("序列化护栏规则失败: %w", err)
+	}
+	return string(data), nil
+}
+
+// handleGetTraderGuardrails 获取交易员的护栏规则和可用的规则类型
+func (s *Server) handleGetTraderGuardrails(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	record, _, _, err := s.database.GetTraderConfig(userID, traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+	guardrails, err := trader.ParseGuardrails(record.Guardrails)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if guardrails == nil {
+		guardrails = []trader.GuardrailConfig{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"guardrails":      guardrails,
+		"available_types": trader.GuardrailTypes(),
+	})
+}
+
+// handleUpdateTraderGuardrails 更新交易员的护栏规则（按列表顺序执行），运行中的交易员从下一个开仓决策开始生效
+func (s *Server) handleUpdateTraderGuardrails(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	var req UpdateGuardrailsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+	if fieldErr := validateGuardrails(req.Guardrails); fieldErr != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fieldErr.Message, "fields": []traderFieldError{*fieldErr}})
+		return
+	}
+
+	encoded, err := encodeGuardrails(req.Guardrails)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.database.UpdateTraderGuardrails(userID, traderID, encoded); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("保存护栏规则失败: %v", err)})
+		return
+	}
+
+	// 已加载的交易员立即应用（配置已校验，这里不会失败）
+	if at, err := s.traderManager.GetTrader(traderID); err == nil {
+		if err := at.SetGuardrails(req.Guardrails); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	requestLogf(c, "🚧 用户 %s 更新交易员 %s 的护栏规则（%d 条）", userID, traderID, len(req.Guardrails))
+	s.audit(c, userID, auditTraderGuardrails, traderID, gin.H{"guardrails": len(req.Guardrails)})
+
+	if req.Guardrails == nil {
+		req.Guardrails = []trader.GuardrailConfig{}
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "护栏规则已更新", "guardrails": req.Guardrails})
+}
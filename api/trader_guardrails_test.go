@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"nofx/config"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// putTraderGuardrails 以指定用户调用更新护栏规则接口
+func putTraderGuardrails(s *Server, userID, traderID, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/api/traders/"+traderID+"/guardrails", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: traderID}}
+	c.Set("user_id", userID)
+	s.handleUpdateTraderGuardrails(c)
+	return w
+}
+
+// TestUpdateTraderGuardrails 测试护栏规则的校验、保存、清除和归属校验
+func TestUpdateTraderGuardrails(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	require.NoError(t, s.database.UpdateAIModel("user-a", "deepseek", true, "sk-a", "", "", nil))
+	require.NoError(t, s.database.CreateTrader(&config.TraderRecord{ID: "trader-g", UserID: "user-a", Name: "G", AIModelID: "user-a_deepseek", ExchangeID: config.PaperExchangeID}))
+
+	body := `{"guardrails": [
+		{"name": "never_short_btc", "type": "symbol_blacklist", "params": {"symbols": ["btcusdt"], "sides": ["short"]}},
+		{"type": "max_positions", "params": {"max": 3}}
+	]}`
+	w := putTraderGuardrails(s, "user-a", "trader-g", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	record, _, _, err := s.database.GetTraderConfig("user-a", "trader-g")
+	require.NoError(t, err)
+	var saved []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(record.Guardrails), &saved))
+	require.Len(t, saved, 2)
+	assert.Equal(t, "never_short_btc", saved[0]["name"])
+	assert.Equal(t, "max_positions", saved[1]["type"])
+
+	// 无效配置返回400且不覆盖已保存的规则
+	for name, invalid := range map[string]string{
+		"未知类型":   `{"guardrails": [{"type": "no_such_rule", "params": {}}]}`,
+		"参数无效":   `{"guardrails": [{"type": "max_leverage", "params": {"max": 0}}]}`,
+		"未知参数":   `{"guardrails": [{"type": "max_positions", "params": {"maximum": 3}}]}`,
+		"时段格式错误": `{"guardrails": [{"type": "trading_hours", "params": {"start": "9am", "end": "17:00"}}]}`,
+	} {
+		w := putTraderGuardrails(s, "user-a", "trader-g", invalid)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+	record, _, _, err = s.database.GetTraderConfig("user-a", "trader-g")
+	require.NoError(t, err)
+	assert.Contains(t, record.Guardrails, "never_short_btc")
+
+	// 其他用户的交易员
+	w = putTraderGuardrails(s, "user-b", "trader-g", body)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// 空数组清除全部规则
+	w = putTraderGuardrails(s, "user-a", "trader-g", `{"guardrails": []}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	record, _, _, err = s.database.GetTraderConfig("user-a", "trader-g")
+	require.NoError(t, err)
+	assert.Empty(t, record.Guardrails)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"nofx/trader"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleTriggerCycle 通知运行中的交易员立即执行一个决策周期（如修改提示词后不必等待下一个扫描间隔）
+func (s *Server) handleTriggerCycle(c *gin.Context) {
+	userID := c.GetString("user_id")
+	traderID := c.Param("id")
+
+	// 校验交易员是否属于当前用户
+	if _, _, _, err := s.database.GetTraderConfig(userID, traderID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在或无访问权限"})
+		return
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "交易员不存在"})
+		return
+	}
+
+	retryAfter, err := at.TriggerCycle()
+	switch {
+	case errors.Is(err, trader.ErrTraderNotRunning):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "交易员未运行，请先启动"})
+		return
+	case errors.Is(err, trader.ErrCycleInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, trader.ErrTriggerCooldown):
+		seconds := max(int(math.Ceil(retryAfter.Seconds())), 1)
+		c.Header("Retry-After", strconv.Itoa(seconds))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error(), "retry_after": seconds})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "👆 用户 %s 手动触发交易员 %s 的决策周期", userID, traderID)
+	c.JSON(http.StatusAccepted, gin.H{"message": "已触发决策周期"})
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"math"
+	"nofx/config"
+	"nofx/trader"
+	"strings"
+	"time"
+)
+
+// liveTraderSettings 运行中交易员可热更新的参数
+type liveTraderSettings interface {
+	SetScanInterval(interval time.Duration)
+	SetLeverage(btcEthLeverage, altcoinLeverage int)
+	SetTradingSymbols(symbols []string)
+	SetExcludedSymbols(symbols []string)
+	SetCustomPrompt(prompt string)
+	SetOverrideBasePrompt(override bool)
+	SetSystemPromptTemplate(templateName string)
+	SetPublic(public bool)
+	SetDailyLossLimit(maxDailyLossPct float64, flatten bool)
+	SetExposureLimits(maxPositionValueUSDT, maxTotalExposurePct float64)
+	SetDefaultProtection(stopLossPct, takeProfitPct float64)
+	SetLossCooldown(minutes int)
+	SetExecutionSettings(mode string, offsetBps float64, timeoutSeconds int, fallback string)
+	SetPromptExperiment(exp trader.PromptExperiment)
+	SetAIFallback(modelID string, stickyMinutes int)
+	SetEnsemble(modelIDs []string, rule string)
+}
+
+// applyTraderUpdateLive 将配置变化直接应用到运行中的交易员实例，返回已热更新的字段和需要重启才能生效的字段
+func applyTraderUpdateLive(at liveTraderSettings, old, updated *config.TraderRecord) (applied, restartRequired []string) {
+	applied = make([]string, 0)
+	restartRequired = make([]string, 0)
+
+	if updated.ScanIntervalMinutes != old.ScanIntervalMinutes {
+		at.SetScanInterval(time.Duration(updated.ScanIntervalMinutes) * time.Minute)
+		applied = append(applied, "scan_interval_minutes")
+	}
+	if updated.BTCETHLeverage != old.BTCETHLeverage || updated.AltcoinLeverage != old.AltcoinLeverage {
+		at.SetLeverage(updated.BTCETHLeverage, updated.AltcoinLeverage)
+		if updated.BTCETHLeverage != old.BTCETHLeverage {
+			applied = append(applied, "btc_eth_leverage")
+		}
+		if updated.AltcoinLeverage != old.AltcoinLeverage {
+			applied = append(applied, "altcoin_leverage")
+		}
+	}
+	if updated.TradingSymbols != old.TradingSymbols {
+		at.SetTradingSymbols(strings.Split(updated.TradingSymbols, ","))
+		applied = append(applied, "trading_symbols")
+	}
+	if updated.ExcludedSymbols != old.ExcludedSymbols {
+		at.SetExcludedSymbols(strings.Split(updated.ExcludedSymbols, ","))
+		applied = append(applied, "excluded_symbols")
+	}
+	if updated.CustomPrompt != old.CustomPrompt {
+		at.SetCustomPrompt(updated.CustomPrompt)
+		applied = append(applied, "custom_prompt")
+	}
+	if updated.OverrideBasePrompt != old.OverrideBasePrompt {
+		at.SetOverrideBasePrompt(updated.OverrideBasePrompt)
+		applied = append(applied, "override_base_prompt")
+	}
+	if updated.SystemPromptTemplate != old.SystemPromptTemplate {
+		at.SetSystemPromptTemplate(updated.SystemPromptTemplate)
+		applied = append(applied, "system_prompt_template")
+	}
+	if updated.IsPublic != old.IsPublic {
+		at.SetPublic(updated.IsPublic)
+		applied = append(applied, "is_public")
+	}
+	if updated.MaxDailyLossPct != old.MaxDailyLossPct || updated.DailyLossFlatten != old.DailyLossFlatten {
+		at.SetDailyLossLimit(updated.MaxDailyLossPct, updated.DailyLossFlatten)
+		if updated.MaxDailyLossPct != old.MaxDailyLossPct {
+			applied = append(applied, "max_daily_loss_pct")
+		}
+		if updated.DailyLossFlatten != old.DailyLossFlatten {
+			applied = append(applied, "daily_loss_flatten")
+		}
+	}
+	if updated.MaxPositionValueUSDT != old.MaxPositionValueUSDT || updated.MaxTotalExposurePct != old.MaxTotalExposurePct {
+		at.SetExposureLimits(updated.MaxPositionValueUSDT, updated.MaxTotalExposurePct)
+		if updated.MaxPositionValueUSDT != old.MaxPositionValueUSDT {
+			applied = append(applied, "max_position_value_usdt")
+		}
+		if updated.MaxTotalExposurePct != old.MaxTotalExposurePct {
+			applied = append(applied, "max_total_exposure_pct")
+		}
+	}
+	if updated.DefaultStopLossPct != old.DefaultStopLossPct || updated.DefaultTakeProfitPct != old.DefaultTakeProfitPct {
+		at.SetDefaultProtection(updated.DefaultStopLossPct, updated.DefaultTakeProfitPct)
+		if updated.DefaultStopLossPct != old.DefaultStopLossPct {
+			applied = append(applied, "default_stop_loss_pct")
+		}
+		if updated.DefaultTakeProfitPct != old.DefaultTakeProfitPct {
+			applied = append(applied, "default_take_profit_pct")
+		}
+	}
+	if updated.CooldownMinutesAfterLoss != old.CooldownMinutesAfterLoss {
+		at.SetLossCooldown(updated.CooldownMinutesAfterLoss)
+		applied = append(applied, "cooldown_minutes_after_loss")
+	}
+	if updated.ExecutionMode != old.ExecutionMode || updated.LimitOffsetBps != old.LimitOffsetBps ||
+		updated.LimitTimeoutSeconds != old.LimitTimeoutSeconds || updated.LimitFallback != old.LimitFallback {
+		at.SetExecutionSettings(updated.ExecutionMode, updated.LimitOffsetBps, updated.LimitTimeoutSeconds, updated.LimitFallback)
+		applied = append(applied, "execution_mode")
+	}
+	// A/B实验：新配置从下一个周期起生效，已记录周期的分组标签保持不变
+	if updated.ExperimentEnabled != old.ExperimentEnabled || updated.TemplateA != old.TemplateA || updated.TemplateB != old.TemplateB ||
+		updated.SplitRatio != old.SplitRatio || updated.ExperimentMode != old.ExperimentMode {
+		at.SetPromptExperiment(trader.PromptExperiment{
+			Enabled:    updated.ExperimentEnabled,
+			TemplateA:  updated.TemplateA,
+			TemplateB:  updated.TemplateB,
+			SplitRatio: updated.SplitRatio,
+			Mode:       updated.ExperimentMode,
+		})
+		applied = append(applied, "experiment")
+	}
+	if updated.FallbackAIModelID != old.FallbackAIModelID || updated.FallbackStickyMinutes != old.FallbackStickyMinutes {
+		at.SetAIFallback(updated.FallbackAIModelID, updated.FallbackStickyMinutes)
+		applied = append(applied, "fallback_ai_model_id")
+	}
+	if updated.EnsembleModelIDs != old.EnsembleModelIDs || updated.ConsensusRule != old.ConsensusRule {
+		at.SetEnsemble(trader.ParseEnsembleModelIDs(updated.EnsembleModelIDs), updated.ConsensusRule)
+		applied = append(applied, "ensemble_model_ids")
+	}
+
+	// 以下字段在创建实例时固化（日志名称、保证金模式、盈亏基准），需重启后生效
+	if updated.Name != old.Name {
+		restartRequired = append(restartRequired, "name")
+	}
+	if updated.IsCrossMargin != old.IsCrossMargin {
+		restartRequired = append(restartRequired, "is_cross_margin")
+	}
+	if updated.InitialBalance > 0 && math.Abs(updated.InitialBalance-old.InitialBalance) > 0.1 {
+		restartRequired = append(restartRequired, "initial_balance")
+	}
+
+	return applied, restartRequired
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
+)
+
+// fakeLiveTrader 记录热更新调用
+type fakeLiveTrader struct {
+	scanInterval     time.Duration
+	btcEthLeverage   int
+	altcoinLeverage  int
+	symbols          []string
+	excluded         []string
+	public           bool
+	maxDailyLossPct  float64
+	flatten          bool
+	maxPositionValue float64
+	maxExposurePct   float64
+	experiment       trader.PromptExperiment
+	fallbackModel    string
+	ensembleModels   []string
+	calls            int
+}
+
+func (f *fakeLiveTrader) SetScanInterval(interval time.Duration) {
+	f.scanInterval = interval
+	f.calls++
+}
+func (f *fakeLiveTrader) SetLeverage(btcEthLeverage, altcoinLeverage int) {
+	f.btcEthLeverage, f.altcoinLeverage = btcEthLeverage, altcoinLeverage
+	f.calls++
+}
+func (f *fakeLiveTrader) SetTradingSymbols(symbols []string)  { f.symbols = symbols; f.calls++ }
+func (f *fakeLiveTrader) SetExcludedSymbols(symbols []string) { f.excluded = symbols; f.calls++ }
+func (f *fakeLiveTrader) SetCustomPrompt(prompt string)       { f.calls++ }
+func (f *fakeLiveTrader) SetOverrideBasePrompt(override bool) { f.calls++ }
+func (f *fakeLiveTrader) SetSystemPromptTemplate(name string) { f.calls++ }
+func (f *fakeLiveTrader) SetPublic(public bool)               { f.public = public; f.calls++ }
+func (f *fakeLiveTrader) SetDailyLossLimit(pct float64, flatten bool) {
+	f.maxDailyLossPct, f.flatten = pct, flatten
+	f.calls++
+}
+func (f *fakeLiveTrader) SetExposureLimits(maxPositionValue, maxExposurePct float64) {
+	f.maxPositionValue, f.maxExposurePct = maxPositionValue, maxExposurePct
+	f.calls++
+}
+func (f *fakeLiveTrader) SetDefaultProtection(stopLossPct, takeProfitPct float64) { f.calls++ }
+func (f *fakeLiveTrader) SetLossCooldown(minutes int)                             { f.calls++ }
+func (f *fakeLiveTrader) SetExecutionSettings(mode string, offsetBps float64, timeoutSeconds int, fallback string) {
+	f.calls++
+}
+func (f *fakeLiveTrader) SetAIFallback(modelID string, stickyMinutes int) {
+	f.fallbackModel = modelID
+	f.calls++
+}
+func (f *fakeLiveTrader) SetEnsemble(modelIDs []string, rule string) {
+	f.ensembleModels = modelIDs
+	f.calls++
+}
+func (f *fakeLiveTrader) SetPromptExperiment(exp trader.PromptExperiment) {
+	f.experiment = exp
+	f.calls++
+}
+
+// TestApplyTraderUpdateLive 测试只热更新变化的字段，并列出需要重启的字段
+func TestApplyTraderUpdateLive(t *testing.T) {
+	old := &config.TraderRecord{
+		Name: "A", ScanIntervalMinutes: 3, BTCETHLeverage: 5, AltcoinLeverage: 5,
+		TradingSymbols: "BTCUSDT", SystemPromptTemplate: "default", IsCrossMargin: true, InitialBalance: 1000,
+	}
+	updated := *old
+	updated.ScanIntervalMinutes = 15
+	updated.BTCETHLeverage = 10
+	updated.TradingSymbols = "BTCUSDT,ETHUSDT"
+	updated.ExcludedSymbols = "PEPEUSDT"
+	updated.IsPublic = true
+	updated.IsCrossMargin = false
+
+	at := &fakeLiveTrader{}
+	applied, restartRequired := applyTraderUpdateLive(at, old, &updated)
+
+	wantApplied := []string{"scan_interval_minutes", "btc_eth_leverage", "trading_symbols", "excluded_symbols", "is_public"}
+	if !reflect.DeepEqual(applied, wantApplied) {
+		t.Errorf("applied = %v, want %v", applied, wantApplied)
+	}
+	if !reflect.DeepEqual(restartRequired, []string{"is_cross_margin"}) {
+		t.Errorf("restartRequired = %v", restartRequired)
+	}
+	if at.scanInterval != 15*time.Minute || at.btcEthLeverage != 10 || at.altcoinLeverage != 5 || len(at.symbols) != 2 || len(at.excluded) != 1 || !at.public {
+		t.Errorf("热更新参数不正确: %+v", at)
+	}
+
+	at = &fakeLiveTrader{}
+	applied, restartRequired = applyTraderUpdateLive(at, old, old)
+	if len(applied) != 0 || len(restartRequired) != 0 || at.calls != 0 {
+		t.Errorf("配置未变化时不应调用热更新: applied=%v restart=%v calls=%d", applied, restartRequired, at.calls)
+	}
+}
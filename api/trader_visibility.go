@@ -0,0 +1,42 @@
+package api
+
+import (
+	"nofx/auth"
+	"nofx/trader"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// optionalUserID 公开接口中解析可选的 Bearer token，返回登录用户ID（未登录或token无效时返回空字符串）
+func (s *Server) optionalUserID(c *gin.Context) string {
+	tokenString, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || tokenString == "" || auth.IsTokenBlacklisted(tokenString) {
+		return ""
+	}
+	claims, err := auth.ValidateJWT(tokenString)
+	if err != nil {
+		return ""
+	}
+	if disabled, err := s.database.IsUserDisabled(claims.UserID); err == nil && disabled {
+		return ""
+	}
+	return claims.UserID
+}
+
+// canViewTrader 公开接口是否可以展示该交易员：所有者选择公开，或请求者就是所有者
+func canViewTrader(at *trader.AutoTrader, viewerID string) bool {
+	if at == nil {
+		return false
+	}
+	return at.IsPublic() || (viewerID != "" && at.GetUserID() == viewerID)
+}
+
+// getVisibleTrader 获取公开接口可访问的交易员，不可访问时与不存在返回相同错误，避免泄露私有交易员是否存在
+func (s *Server) getVisibleTrader(traderID, viewerID string) (*trader.AutoTrader, bool) {
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil || !canViewTrader(at, viewerID) {
+		return nil, false
+	}
+	return at, true
+}
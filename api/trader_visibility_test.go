@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"nofx/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestOptionalUserID 测试公开接口解析可选登录身份
+func TestOptionalUserID(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+
+	// 使用独立邮箱，避免与其他测试中登出拉黑的token完全相同
+	tokenA, err := auth.GenerateJWT("user-a", "visibility-a@test.com", "user")
+	if err != nil {
+		t.Fatalf("生成token失败: %v", err)
+	}
+	tokenB, _ := auth.GenerateJWT("user-b", "visibility-b@test.com", "user")
+	if err := s.database.SetUserDisabled("user-b", true); err != nil {
+		t.Fatalf("禁用用户失败: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"未登录", "", ""},
+		{"有效token", "Bearer " + tokenA, "user-a"},
+		{"无效token", "Bearer not-a-jwt", ""},
+		{"格式错误", tokenA, ""},
+		{"已禁用用户", "Bearer " + tokenB, ""},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			gin.SetMode(gin.TestMode)
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/equity-history", nil)
+			if tt.header != "" {
+				c.Request.Header.Set("Authorization", tt.header)
+			}
+			if got := s.optionalUserID(c); got != tt.want {
+				t.Errorf("optionalUserID = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPublicEndpoints_HideUnknownTraders 测试公开接口对不可见的交易员统一返回不存在
+func TestPublicEndpoints_HideUnknownTraders(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	s.router = gin.New()
+	s.setupRoutes()
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/traders/trader-a/public-config", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("public-config 状态码 = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/equity-history?trader_id=trader-a", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("equity-history 状态码 = %d, want 404", w.Code)
+	}
+
+	if canViewTrader(nil, "user-a") {
+		t.Error("不存在的交易员不应可见")
+	}
+}
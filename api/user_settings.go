@@ -0,0 +1,72 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"nofx/config"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetUserSettings 获取当前用户的个人设置（未保存过时返回默认值）
+func (s *Server) handleGetUserSettings(c *gin.Context) {
+	settings, err := s.database.GetUserSettings(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"settings":             settings,
+		"supported_currencies": config.SupportedDisplayCurrencies,
+	})
+}
+
+// handleUpdateUserSettings 更新当前用户的个人设置（只修改请求中出现的字段）
+func (s *Server) handleUpdateUserSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		Timezone        *string                         `json:"timezone"`
+		DisplayCurrency *string                         `json:"display_currency"`
+		Notifications   *config.NotificationPreferences `json:"notifications"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	settings, err := s.database.GetUserSettings(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.DisplayCurrency != nil {
+		settings.DisplayCurrency = *req.DisplayCurrency
+	}
+	if req.Notifications != nil {
+		settings.Notifications = *req.Notifications
+	}
+
+	if err := s.database.UpdateUserSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	requestLogf(c, "⚙️ 用户 %s 更新个人设置: 时区=%s 显示币种=%s", userID, settings.Timezone, settings.DisplayCurrency)
+	c.JSON(http.StatusOK, gin.H{"message": "设置已保存", "settings": settings})
+}
+
+// requestLocation 按日期统计使用的时区：优先使用 tz 查询参数，否则使用用户设置的时区
+func (s *Server) requestLocation(c *gin.Context) (*time.Location, error) {
+	if tz := c.Query("tz"); tz != "" {
+		loc, err := config.LoadUserTimezone(tz)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区: %s", tz)
+		}
+		return loc, nil
+	}
+	return s.database.GetUserLocation(c.GetString("user_id")), nil
+}
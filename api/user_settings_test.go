@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestUserSettings_UpdateAndLocation 部分更新个人设置，按日期统计的接口未指定 tz 时使用用户时区
+func TestUserSettings_UpdateAndLocation(t *testing.T) {
+	s := setupTraderAccessServer(t)
+	gin.SetMode(gin.TestMode)
+
+	call := func(method, body, query string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(method, "/api/user/settings"+query, strings.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Set("user_id", "user-a")
+		handler(c)
+		return w
+	}
+
+	if w := call(http.MethodPut, `{"timezone":"Not/AZone"}`, "", s.handleUpdateUserSettings); w.Code != http.StatusBadRequest {
+		t.Errorf("无效时区应返回400，实际 %d", w.Code)
+	}
+	if w := call(http.MethodPut, `{"timezone":"America/New_York"}`, "", s.handleUpdateUserSettings); w.Code != http.StatusOK {
+		t.Fatalf("更新时区应返回200: %d %s", w.Code, w.Body.String())
+	}
+
+	w := call(http.MethodGet, "", "", s.handleGetUserSettings)
+	var resp struct {
+		Settings struct {
+			Timezone        string `json:"timezone"`
+			DisplayCurrency string `json:"display_currency"`
+			Notifications   struct {
+				TradeEvents bool `json:"trade_events"`
+			} `json:"notifications"`
+		} `json:"settings"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if resp.Settings.Timezone != "America/New_York" || resp.Settings.DisplayCurrency != "USDT" || !resp.Settings.Notifications.TradeEvents {
+		t.Errorf("未修改的字段应保留默认值: %s", w.Body.String())
+	}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", "user-a")
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/pnl-daily", nil)
+	if loc, err := s.requestLocation(c); err != nil || loc.String() != "America/New_York" {
+		t.Errorf("未指定 tz 时应使用用户时区: %v %v", loc, err)
+	}
+	c, _ = gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user_id", "user-a")
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/pnl-daily?tz=Asia/Tokyo", nil)
+	if loc, err := s.requestLocation(c); err != nil || loc.String() != "Asia/Tokyo" {
+		t.Errorf("tz 参数应优先于用户设置: %v %v", loc, err)
+	}
+}
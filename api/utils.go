@@ -17,19 +17,36 @@ func MaskSensitiveString(s string) string {
 
 // SanitizeModelConfigForLog 脱敏模型配置用于日志输出
 func SanitizeModelConfigForLog(models map[string]struct {
-	Enabled         bool   `json:"enabled"`
-	APIKey          string `json:"api_key"`
-	CustomAPIURL    string `json:"custom_api_url"`
-	CustomModelName string `json:"custom_model_name"`
+	Enabled         bool     `json:"enabled"`
+	APIKey          string   `json:"api_key"`
+	CustomAPIURL    string   `json:"custom_api_url"`
+	CustomModelName string   `json:"custom_model_name"`
+	Temperature     *float64 `json:"temperature"`
+	MaxTokens       *int     `json:"max_tokens"`
+	RequestTimeout  *int     `json:"request_timeout_seconds"`
+	ContextTokens   *int     `json:"context_tokens"`
 }) map[string]interface{} {
 	safe := make(map[string]interface{})
 	for modelID, cfg := range models {
-		safe[modelID] = map[string]interface{}{
+		entry := map[string]interface{}{
 			"enabled":           cfg.Enabled,
 			"api_key":           MaskSensitiveString(cfg.APIKey),
 			"custom_api_url":    cfg.CustomAPIURL,
 			"custom_model_name": cfg.CustomModelName,
 		}
+		if cfg.Temperature != nil {
+			entry["temperature"] = *cfg.Temperature
+		}
+		if cfg.MaxTokens != nil {
+			entry["max_tokens"] = *cfg.MaxTokens
+		}
+		if cfg.RequestTimeout != nil {
+			entry["request_timeout_seconds"] = *cfg.RequestTimeout
+		}
+		if cfg.ContextTokens != nil {
+			entry["context_tokens"] = *cfg.ContextTokens
+		}
+		safe[modelID] = entry
 	}
 	return safe
 }
@@ -79,6 +96,40 @@ func SanitizeExchangeConfigForLog(exchanges map[string]struct {
 	return safe
 }
 
+// SanitizeExchangeAccountsForLog 脱敏按账户提交的交易所配置用于日志输出
+func SanitizeExchangeAccountsForLog(accounts []ExchangeAccountUpdate) []map[string]interface{} {
+	safe := make([]map[string]interface{}, 0, len(accounts))
+	for _, account := range accounts {
+		safeAccount := map[string]interface{}{
+			"exchange_id": account.ExchangeID,
+			"account_id":  account.AccountID,
+			"label":       account.Label,
+			"enabled":     account.Enabled,
+			"testnet":     account.Testnet,
+		}
+		if account.APIKey != "" {
+			safeAccount["api_key"] = MaskSensitiveString(account.APIKey)
+		}
+		if account.SecretKey != "" {
+			safeAccount["secret_key"] = MaskSensitiveString(account.SecretKey)
+		}
+		if account.AsterPrivateKey != "" {
+			safeAccount["aster_private_key"] = MaskSensitiveString(account.AsterPrivateKey)
+		}
+		if account.HyperliquidWalletAddr != "" {
+			safeAccount["hyperliquid_wallet_addr"] = account.HyperliquidWalletAddr
+		}
+		if account.AsterUser != "" {
+			safeAccount["aster_user"] = account.AsterUser
+		}
+		if account.AsterSigner != "" {
+			safeAccount["aster_signer"] = account.AsterSigner
+		}
+		safe = append(safe, safeAccount)
+	}
+	return safe
+}
+
 // MaskEmail 脱敏邮箱地址，保留前2位和@后部分
 func MaskEmail(email string) string {
 	if email == "" {
@@ -49,10 +49,14 @@ func TestMaskSensitiveString(t *testing.T) {
 
 func TestSanitizeModelConfigForLog(t *testing.T) {
 	models := map[string]struct {
-		Enabled         bool   `json:"enabled"`
-		APIKey          string `json:"api_key"`
-		CustomAPIURL    string `json:"custom_api_url"`
-		CustomModelName string `json:"custom_model_name"`
+		Enabled         bool     `json:"enabled"`
+		APIKey          string   `json:"api_key"`
+		CustomAPIURL    string   `json:"custom_api_url"`
+		CustomModelName string   `json:"custom_model_name"`
+		Temperature     *float64 `json:"temperature"`
+		MaxTokens       *int     `json:"max_tokens"`
+		RequestTimeout  *int     `json:"request_timeout_seconds"`
+		ContextTokens   *int     `json:"context_tokens"`
 	}{
 		"deepseek": {
 			Enabled:         true,
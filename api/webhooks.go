@@ -0,0 +1,245 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"nofx/config"
+	"nofx/notify"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// webhookTimeout 出站 Webhook 单次请求超时（较短，避免慢接口占满发送协程）
+const webhookTimeout = 5 * time.Second
+
+// newWebhookDispatcher 创建出站 Webhook 发送器（有界队列，失败退避重试）
+func newWebhookDispatcher() *notify.Dispatcher {
+	d := notify.NewDispatcher(0, 0)
+	d.SetTimeout(webhookTimeout)
+	return d
+}
+
+// webhookResponse 接口返回的 Webhook（不含密钥）
+func webhookResponse(w *config.Webhook) *config.Webhook {
+	masked := *w
+	masked.Secret = ""
+	return &masked
+}
+
+// generateWebhookSecret 未指定密钥时生成随机签名密钥
+func generateWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// handleListWebhooks 获取当前用户的 Webhook（不含密钥）和可订阅的事件类型
+func (s *Server) handleListWebhooks(c *gin.Context) {
+	webhooks, err := s.database.ListWebhooks(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	result := make([]*config.Webhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		result = append(result, webhookResponse(w))
+	}
+	c.JSON(http.StatusOK, gin.H{"webhooks": result, "events": trader.EventTypes})
+}
+
+// handleCreateWebhook 新建 Webhook（未填写 secret 时自动生成，仅在此次响应中返回）
+func (s *Server) handleCreateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req struct {
+		URL     string   `json:"url"`
+		Secret  string   `json:"secret"`
+		Events  []string `json:"events"`
+		Enabled *bool    `json:"enabled"`
+	}
+	if err := s.bindSensitiveJSON(c, "Webhook", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "生成签名密钥失败"})
+			return
+		}
+		req.Secret = secret
+	}
+
+	w := &config.Webhook{
+		UserID:  userID,
+		URL:     req.URL,
+		Secret:  req.Secret,
+		Events:  req.Events,
+		Enabled: req.Enabled == nil || *req.Enabled,
+	}
+	if err := w.Validate(trader.EventTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.database.CreateWebhook(w, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.subscriptions.invalidate(userID)
+	s.audit(c, userID, auditWebhookCreate, w.ID, gin.H{"url": w.URL, "events": w.Events})
+	requestLogf(c, "🪝 用户 %s 新建Webhook %s", userID, w.URL)
+	c.JSON(http.StatusOK, w)
+}
+
+// handleUpdateWebhook 更新 Webhook（未提供的字段保留原值）
+func (s *Server) handleUpdateWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	w, err := s.database.GetWebhook(userID, c.Param("id"))
+	if errors.Is(err, config.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	var req struct {
+		URL     *string  `json:"url"`
+		Secret  *string  `json:"secret"`
+		Events  []string `json:"events"`
+		Enabled *bool    `json:"enabled"`
+	}
+	if err := s.bindSensitiveJSON(c, "Webhook", &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.URL != nil {
+		w.URL = *req.URL
+	}
+	if req.Secret != nil && *req.Secret != "" {
+		w.Secret = *req.Secret
+	}
+	if req.Events != nil {
+		w.Events = req.Events
+	}
+	if req.Enabled != nil {
+		w.Enabled = *req.Enabled
+	}
+	if err := w.Validate(trader.EventTypes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := s.database.UpdateWebhook(w, time.Now()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.subscriptions.invalidate(userID)
+	s.audit(c, userID, auditWebhookUpdate, w.ID, gin.H{"url": w.URL, "events": w.Events, "enabled": w.Enabled})
+	requestLogf(c, "🪝 用户 %s 更新Webhook %s", userID, w.URL)
+	c.JSON(http.StatusOK, webhookResponse(w))
+}
+
+// handleDeleteWebhook 删除 Webhook 及其死信记录
+func (s *Server) handleDeleteWebhook(c *gin.Context) {
+	userID := c.GetString("user_id")
+	id := c.Param("id")
+	err := s.database.DeleteWebhook(userID, id)
+	if errors.Is(err, config.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.subscriptions.invalidate(userID)
+	s.audit(c, userID, auditWebhookDelete, id, nil)
+	requestLogf(c, "🪝 用户 %s 删除Webhook %s", userID, id)
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook已删除"})
+}
+
+// handleGetWebhookDeliveries 获取 Webhook 最终投递失败的请求（死信，最新的在前）
+func (s *Server) handleGetWebhookDeliveries(c *gin.Context) {
+	userID := c.GetString("user_id")
+	id := c.Param("id")
+	if _, err := s.database.GetWebhook(userID, id); errors.Is(err, config.ErrWebhookNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	deliveries, err := s.database.ListWebhookDeliveries(userID, id, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"deliveries": deliveries})
+}
+
+// dispatchWebhooks 把交易员事件加入用户订阅了该事件的 Webhook 的发送队列（不等待发送结果）
+func (s *Server) dispatchWebhooks(userID string, event trader.TraderEvent) {
+	if s.webhooks == nil || userID == "" {
+		return
+	}
+	subs, err := s.userSubscriptions(userID)
+	if err != nil {
+		log.Printf("⚠️ 读取用户 %s 的事件订阅失败: %v", userID, err)
+		return
+	}
+	for _, target := range subs.webhooks {
+		w := target.webhook
+		if !w.Subscribes(event.Type) {
+			continue
+		}
+		msg := &notify.Message{
+			ID:       uuid.New().String(),
+			Event:    event.Type,
+			TraderID: event.TraderID,
+			Time:     event.Timestamp,
+			Data:     event.Data,
+		}
+		if msg.Time.IsZero() {
+			msg.Time = time.Now()
+		}
+		s.webhooks.EnqueueWithFailure(target.channel, "webhook:"+w.ID, msg, s.recordWebhookFailure(w))
+	}
+}
+
+// recordWebhookFailure 最终投递失败时写入死信记录
+func (s *Server) recordWebhookFailure(w *config.Webhook) notify.FailureFunc {
+	return func(msg *notify.Message, attempts int, err error) {
+		payload, _ := json.Marshal(msg)
+		delivery := &config.WebhookDelivery{
+			WebhookID: w.ID,
+			UserID:    w.UserID,
+			Event:     msg.Event,
+			Payload:   string(payload),
+			Error:     err.Error(),
+			Attempts:  attempts,
+		}
+		var statusErr *notify.StatusError
+		if errors.As(err, &statusErr) {
+			delivery.StatusCode = statusErr.StatusCode
+		}
+		if err := s.database.RecordWebhookDelivery(delivery); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+}
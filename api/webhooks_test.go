@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/notify"
+	"nofx/trader"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestWebhooks_SignedDeliveryAndDeadLetter 只投递订阅的事件、请求体带 HMAC 签名，投递失败写入死信并可通过接口查看
+func TestWebhooks_SignedDeliveryAndDeadLetter(t *testing.T) {
+	type request struct {
+		body                 []byte
+		timestamp, signature string
+	}
+	received := make(chan request, 4)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{body, r.Header.Get("X-NOFX-Timestamp"), r.Header.Get("X-NOFX-Signature")}
+	}))
+	defer receiver.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer broken.Close()
+
+	s := setupTraderAccessServer(t)
+	s.webhooks = newWebhookDispatcher()
+	defer s.webhooks.Close()
+	// 测试服务器监听在回环地址
+	notify.SetAllowPrivateTargets(true)
+	defer notify.SetAllowPrivateTargets(false)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", "user-a") })
+	router.GET("/api/webhooks", s.handleListWebhooks)
+	router.POST("/api/webhooks", s.handleCreateWebhook)
+	router.GET("/api/webhooks/:id/deliveries", s.handleGetWebhookDeliveries)
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return w
+	}
+
+	if w := do(http.MethodPost, "/api/webhooks", `{"url":"`+receiver.URL+`","events":["unknown"]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("不支持的事件类型应返回400，实际 %d", w.Code)
+	}
+	w := do(http.MethodPost, "/api/webhooks", `{"url":"`+receiver.URL+`","events":["state_changed","position_opened"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("新建Webhook应返回200: %d %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		ID     string `json:"id"`
+		Secret string `json:"secret"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if len(created.Secret) != 64 {
+		t.Fatalf("未填写密钥时应生成并返回: %s", w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/webhooks", ""); strings.Contains(w.Body.String(), created.Secret) {
+		t.Errorf("Webhook列表不应包含密钥: %s", w.Body.String())
+	}
+	w = do(http.MethodPost, "/api/webhooks", `{"url":"`+broken.URL+`","secret":"s","events":["*"]}`)
+	var brokenHook struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &brokenHook)
+
+	s.dispatchWebhooks("user-a", trader.TraderEvent{Type: trader.EventCycleCompleted, TraderID: "trader-a"})
+	s.dispatchWebhooks("user-a", trader.TraderEvent{
+		Type: trader.EventStateChanged, TraderID: "trader-a", Timestamp: time.Now(),
+		Data: map[string]interface{}{"is_running": true},
+	})
+
+	select {
+	case req := <-received:
+		if notify.Sign(created.Secret, req.timestamp, req.body) != req.signature {
+			t.Errorf("签名校验失败: %s", req.signature)
+		}
+		var msg notify.Message
+		json.Unmarshal(req.body, &msg)
+		if msg.Event != trader.EventStateChanged || msg.TraderID != "trader-a" || msg.ID == "" {
+			t.Errorf("投递内容不正确: %s", req.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("未收到Webhook请求")
+	}
+	select {
+	case req := <-received:
+		t.Errorf("未订阅的事件不应投递: %s", req.body)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// 410 不可重试，两个事件都写入死信
+	deadline := time.Now().Add(5 * time.Second)
+	var resp struct {
+		Deliveries []struct {
+			Event      string `json:"event"`
+			StatusCode int    `json:"status_code"`
+			Attempts   int    `json:"attempts"`
+		} `json:"deliveries"`
+	}
+	for len(resp.Deliveries) < 2 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		json.Unmarshal(do(http.MethodGet, "/api/webhooks/"+brokenHook.ID+"/deliveries", "").Body.Bytes(), &resp)
+	}
+	if len(resp.Deliveries) != 2 || resp.Deliveries[0].StatusCode != http.StatusGone || resp.Deliveries[0].Attempts != 1 {
+		t.Fatalf("投递失败应写入死信: %+v", resp.Deliveries)
+	}
+	if w := do(http.MethodGet, "/api/webhooks/"+created.ID+"/deliveries", ""); !strings.Contains(w.Body.String(), `"deliveries":[]`) {
+		t.Errorf("投递成功不应有死信: %s", w.Body.String())
+	}
+	if w := do(http.MethodGet, "/api/webhooks/unknown/deliveries", ""); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的Webhook应返回404，实际 %d", w.Code)
+	}
+}
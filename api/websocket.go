@@ -0,0 +1,249 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"nofx/auth"
+	"nofx/trader"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsAuthTimeout  = 10 * time.Second // 首条消息认证超时
+	wsWriteTimeout = 10 * time.Second // 单条消息写超时（超时视为慢客户端）
+	wsPongTimeout  = 60 * time.Second // 未收到 pong 的超时
+	wsPingInterval = 30 * time.Second // ping 间隔（需小于 wsPongTimeout）
+	wsEventBuffer  = 64               // 每个连接的事件缓冲区大小
+)
+
+var wsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+	// 来源在 handleWebSocket 中按 CORS 配置校验；认证由 JWT 保证
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsAuthMessage 首条认证消息（未在 query 参数中携带 token 时使用）
+type wsAuthMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// wsHub 按 JWT 记录活跃的 WebSocket 连接，用于登出时断开
+type wsHub struct {
+	mu    sync.Mutex
+	conns map[string]map[*websocket.Conn]context.CancelFunc
+}
+
+// newWSHub 创建连接登记表
+func newWSHub() *wsHub {
+	return &wsHub{conns: make(map[string]map[*websocket.Conn]context.CancelFunc)}
+}
+
+// add 登记连接
+func (h *wsHub) add(token string, conn *websocket.Conn, cancel context.CancelFunc) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.conns[token] == nil {
+		h.conns[token] = make(map[*websocket.Conn]context.CancelFunc)
+	}
+	h.conns[token][conn] = cancel
+}
+
+// remove 注销连接
+func (h *wsHub) remove(token string, conn *websocket.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.conns[token], conn)
+	if len(h.conns[token]) == 0 {
+		delete(h.conns, token)
+	}
+}
+
+// closeToken 断开使用该 token 的所有连接（登出时调用）
+func (h *wsHub) closeToken(token string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := len(h.conns[token])
+	for _, cancel := range h.conns[token] {
+		cancel()
+	}
+	return n
+}
+
+// closeAll 断开所有连接（服务器关闭时调用）
+func (h *wsHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, conns := range h.conns {
+		for _, cancel := range conns {
+			cancel()
+		}
+	}
+}
+
+// authenticateToken 校验访问 token（黑名单、签名、账户删除/会话撤销、用户禁用），
+// 失败时返回应响应的 HTTP 状态码；authMiddleware 和 WebSocket 握手共用
+func (s *Server) authenticateToken(tokenString string) (*auth.Claims, int, error) {
+	if tokenString == "" {
+		return nil, http.StatusUnauthorized, fmt.Errorf("缺少token")
+	}
+	if auth.IsTokenBlacklisted(tokenString) {
+		return nil, http.StatusUnauthorized, fmt.Errorf("token已失效，请重新登录")
+	}
+	claims, err := auth.ValidateJWT(tokenString)
+	if err != nil {
+		return nil, http.StatusUnauthorized, fmt.Errorf("无效的token: %w", err)
+	}
+	// 已删除账户此前签发的token立即失效
+	if auth.IsUserTokenRevoked(claims) {
+		return nil, http.StatusUnauthorized, fmt.Errorf("token已失效，请重新登录")
+	}
+	// 已撤销会话签发的token立即失效
+	if auth.IsSessionTokenRevoked(claims) {
+		return nil, http.StatusUnauthorized, fmt.Errorf("会话已撤销，请重新登录")
+	}
+	// 被管理员禁用的用户立即失去访问权限（即使token未过期）
+	if disabled, err := s.database.IsUserDisabled(claims.UserID); err == nil && disabled {
+		return nil, http.StatusForbidden, fmt.Errorf("账户已被禁用")
+	}
+	return claims, 0, nil
+}
+
+// handleWebSocket 交易员实时事件推送
+// GET /api/ws?trader_id=xxx&token=xxx，未携带 token 时需在首条消息中发送 {"type":"auth","token":"..."}
+func (s *Server) handleWebSocket(c *gin.Context) {
+	if origin := c.GetHeader("Origin"); !s.cors.allowsOrigin(origin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "不允许的来源"})
+		return
+	}
+
+	tokenString := c.Query("token")
+	var userID string
+	if tokenString != "" {
+		claims, status, err := s.authenticateToken(tokenString)
+		if err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		userID = claims.UserID
+	}
+
+	conn, err := wsUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("⚠️ WebSocket升级失败: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// 首条消息认证
+	if tokenString == "" {
+		var msg wsAuthMessage
+		conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+		if err := conn.ReadJSON(&msg); err != nil || msg.Type != "auth" {
+			closeWebSocket(conn, websocket.ClosePolicyViolation, "缺少认证消息")
+			return
+		}
+		claims, _, err := s.authenticateToken(msg.Token)
+		if err != nil {
+			closeWebSocket(conn, websocket.ClosePolicyViolation, err.Error())
+			return
+		}
+		userID = claims.UserID
+		tokenString = msg.Token
+	}
+
+	// 与其他接口相同的归属校验
+	c.Set("user_id", userID)
+	_, traderID, err := s.getTraderFromQuery(c)
+	if err != nil {
+		closeWebSocket(conn, websocket.ClosePolicyViolation, err.Error())
+		return
+	}
+	at, err := s.traderManager.GetTrader(traderID)
+	if err != nil {
+		closeWebSocket(conn, websocket.ClosePolicyViolation, err.Error())
+		return
+	}
+
+	sub := at.Events().Subscribe(wsEventBuffer)
+	defer sub.Close()
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	s.wsHub.add(tokenString, conn, cancel)
+	defer s.wsHub.remove(tokenString, conn)
+
+	log.Printf("🔌 WebSocket已连接: 用户 %s, 交易员 %s", userID, at.GetID())
+	defer log.Printf("🔌 WebSocket已断开: 用户 %s, 交易员 %s", userID, at.GetID())
+
+	// 读循环：处理 pong 和客户端关闭
+	go func() {
+		defer cancel()
+		conn.SetReadLimit(4096)
+		conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(wsPongTimeout))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 连接建立后先推送当前状态
+	if err := writeWebSocketJSON(conn, trader.TraderEvent{
+		Type:      "connected",
+		TraderID:  at.GetID(),
+		Timestamp: time.Now(),
+		Data:      at.GetStatus(),
+	}); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(wsPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				// 交易员已从内存移除，或客户端消费过慢被断开
+				closeWebSocket(conn, websocket.CloseGoingAway, "交易员已关闭或连接过慢")
+				return
+			}
+			if err := writeWebSocketJSON(conn, event); err != nil {
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-ctx.Done():
+			// 登出、服务器关闭或客户端断开
+			closeWebSocket(conn, websocket.CloseNormalClosure, "连接已关闭")
+			return
+		}
+	}
+}
+
+// writeWebSocketJSON 带写超时地发送 JSON 消息
+func writeWebSocketJSON(conn *websocket.Conn, v interface{}) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+// closeWebSocket 发送关闭帧
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(code, reason),
+		time.Now().Add(time.Second))
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"nofx/auth"
+)
+
+// TestAuthenticateToken WebSocket 握手与 authMiddleware 使用相同的token和用户检查
+func TestAuthenticateToken(t *testing.T) {
+	auth.SetJWTSecret("test-secret")
+	s := setupTraderAccessServer(t)
+
+	token, _ := auth.GenerateJWT("user-a", "user-a@test.com", "")
+	claims, _, err := s.authenticateToken(token)
+	if err != nil || claims.UserID != "user-a" {
+		t.Fatalf("有效token应通过认证: %v", err)
+	}
+
+	if _, status, err := s.authenticateToken(""); err == nil || status != http.StatusUnauthorized {
+		t.Errorf("缺少token应返回401: %d %v", status, err)
+	}
+
+	sessionToken, _ := auth.GenerateSessionJWT("user-a", "user-a@test.com", "", "ws-session")
+	auth.RevokeSessionTokens("ws-session")
+	if _, status, err := s.authenticateToken(sessionToken); err == nil || status != http.StatusUnauthorized {
+		t.Errorf("已撤销会话的token应返回401: %d %v", status, err)
+	}
+
+	if err := s.database.SetUserDisabled("user-b", true); err != nil {
+		t.Fatalf("禁用用户失败: %v", err)
+	}
+	disabledToken, _ := auth.GenerateJWT("user-b", "user-b@test.com", "")
+	if _, status, err := s.authenticateToken(disabledToken); err == nil || status != http.StatusForbidden {
+		t.Errorf("被禁用用户应返回403: %d %v", status, err)
+	}
+
+	emptyToken, _ := auth.GenerateJWT("user-empty", "user-empty@test.com", "")
+	auth.RevokeUserTokens("user-empty")
+	if _, status, err := s.authenticateToken(emptyToken); err == nil || status != http.StatusUnauthorized {
+		t.Errorf("已删除账户的token应返回401: %d %v", status, err)
+	}
+}
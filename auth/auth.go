@@ -22,6 +22,18 @@ var tokenBlacklist = struct {
 	items map[string]time.Time
 }{items: make(map[string]time.Time)}
 
+// revokedUsers 用户级token撤销：撤销时间之前签发的访问token全部失效（仅内存，超过访问token有效期后清理）
+var revokedUsers = struct {
+	sync.Mutex
+	items map[string]time.Time
+}{items: make(map[string]time.Time)}
+
+// revokedSessions 会话级token撤销：被撤销会话（刷新token链）此前签发的访问token全部失效（仅内存，超过访问token有效期后清理）
+var revokedSessions = struct {
+	sync.Mutex
+	items map[string]time.Time
+}{items: make(map[string]time.Time)}
+
 // maxBlacklistEntries 黑名单最大容量阈值
 const maxBlacklistEntries = 100_000
 
@@ -54,6 +66,58 @@ func BlacklistToken(token string, exp time.Time) {
 	}
 }
 
+// RevokeUserTokens 使用户此前签发的所有访问token立即失效（删除账户时调用，刷新token需另外从数据库撤销）
+func RevokeUserTokens(userID string) {
+	now := time.Now()
+	revokedUsers.Lock()
+	defer revokedUsers.Unlock()
+	for id, revokedAt := range revokedUsers.items {
+		if now.Sub(revokedAt) > AccessTokenTTL {
+			delete(revokedUsers.items, id)
+		}
+	}
+	revokedUsers.items[userID] = now
+}
+
+// IsUserTokenRevoked 检查token是否在用户级撤销之前签发
+func IsUserTokenRevoked(claims *Claims) bool {
+	revokedUsers.Lock()
+	defer revokedUsers.Unlock()
+	revokedAt, ok := revokedUsers.items[claims.UserID]
+	if !ok {
+		return false
+	}
+	return claims.IssuedAt == nil || !claims.IssuedAt.Time.After(revokedAt)
+}
+
+// RevokeSessionTokens 使指定会话此前签发的访问token立即失效（刷新token需另外从数据库撤销）
+func RevokeSessionTokens(sessionIDs ...string) {
+	now := time.Now()
+	revokedSessions.Lock()
+	defer revokedSessions.Unlock()
+	for id, revokedAt := range revokedSessions.items {
+		if now.Sub(revokedAt) > AccessTokenTTL {
+			delete(revokedSessions.items, id)
+		}
+	}
+	for _, id := range sessionIDs {
+		if id != "" {
+			revokedSessions.items[id] = now
+		}
+	}
+}
+
+// IsSessionTokenRevoked 检查token所属的会话是否已被撤销
+func IsSessionTokenRevoked(claims *Claims) bool {
+	if claims.SessionID == "" {
+		return false
+	}
+	revokedSessions.Lock()
+	defer revokedSessions.Unlock()
+	_, ok := revokedSessions.items[claims.SessionID]
+	return ok
+}
+
 // IsTokenBlacklisted 检查token是否在黑名单中（过期自动清理）
 func IsTokenBlacklisted(token string) bool {
 	tokenBlacklist.Lock()
@@ -72,6 +136,9 @@ func IsTokenBlacklisted(token string) bool {
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	Role   string `json:"role,omitempty"` // 用户角色（旧token没有该字段，视为普通用户）
+	// SessionID 签发该token的会话（刷新token轮换链ID），旧token没有该字段
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -112,12 +179,19 @@ func VerifyOTP(secret, code string) bool {
 }
 
 // GenerateJWT 生成JWT token
-func GenerateJWT(userID, email string) (string, error) {
+func GenerateJWT(userID, email, role string) (string, error) {
+	return GenerateSessionJWT(userID, email, role, "")
+}
+
+// GenerateSessionJWT 生成属于某个会话的JWT token（撤销会话时该token同时失效）
+func GenerateSessionJWT(userID, email, role, sessionID string) (string, error) {
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)), // 24小时过期
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)), // 短期有效，过期后用刷新token续期
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "nofxAI",
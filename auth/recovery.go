@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount 每次生成的恢复码数量
+	RecoveryCodeCount = 10
+	// recoveryCodeLength 恢复码有效字符长度（不含分隔符）
+	recoveryCodeLength = 10
+	// recoveryCodeAlphabet 恢复码字符集（去掉易混淆的 0/O/1/I/L）
+	recoveryCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+// GenerateRecoveryCodes 生成一组一次性恢复码（格式 XXXXX-XXXXX）
+func GenerateRecoveryCodes() ([]string, error) {
+	max := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	codes := make([]string, 0, RecoveryCodeCount)
+	seen := make(map[string]bool, RecoveryCodeCount)
+	for len(codes) < RecoveryCodeCount {
+		var sb strings.Builder
+		for i := 0; i < recoveryCodeLength; i++ {
+			if i == recoveryCodeLength/2 {
+				sb.WriteByte('-')
+			}
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return nil, fmt.Errorf("生成恢复码失败: %w", err)
+			}
+			sb.WriteByte(recoveryCodeAlphabet[n.Int64()])
+		}
+		code := sb.String()
+		if !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 统一大小写并去掉分隔符和空白
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// IsRecoveryCodeFormat 判断输入是否为恢复码格式（用于与6位OTP码区分）
+func IsRecoveryCodeFormat(code string) bool {
+	code = normalizeRecoveryCode(code)
+	if len(code) != recoveryCodeLength {
+		return false
+	}
+	for _, r := range code {
+		if !strings.ContainsRune(recoveryCodeAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// HashRecoveryCode 计算恢复码哈希（数据库中只保存哈希）
+func HashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
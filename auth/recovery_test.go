@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestGenerateRecoveryCodes 测试恢复码生成、格式识别和哈希归一化
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	if err != nil {
+		t.Fatalf("生成恢复码失败: %v", err)
+	}
+	if len(codes) != RecoveryCodeCount {
+		t.Fatalf("恢复码数量应为 %d，实际 %d", RecoveryCodeCount, len(codes))
+	}
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		if seen[code] {
+			t.Errorf("恢复码重复: %s", code)
+		}
+		seen[code] = true
+		if !IsRecoveryCodeFormat(code) {
+			t.Errorf("生成的恢复码格式不正确: %s", code)
+		}
+	}
+
+	code := codes[0]
+	if HashRecoveryCode(code) != HashRecoveryCode(strings.ToLower(strings.ReplaceAll(code, "-", ""))) {
+		t.Error("大小写和分隔符不同的同一恢复码哈希应一致")
+	}
+	if IsRecoveryCodeFormat("123456") {
+		t.Error("6位OTP码不应被识别为恢复码")
+	}
+}
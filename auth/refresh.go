@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// AccessTokenTTL 访问token有效期（过期后使用刷新token换取新token）
+	AccessTokenTTL = 15 * time.Minute
+	// RefreshTokenTTL 刷新token有效期
+	RefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidRefreshToken 刷新token不存在或格式错误
+	ErrInvalidRefreshToken = errors.New("无效的刷新token")
+	// ErrRefreshTokenExpired 刷新token已过期
+	ErrRefreshTokenExpired = errors.New("刷新token已过期")
+	// ErrRefreshTokenReused 已轮换或已撤销的刷新token被再次使用（整条链已被撤销）
+	ErrRefreshTokenReused = errors.New("刷新token已失效，请重新登录")
+)
+
+// RefreshToken 持久化的刷新token记录（只保存哈希，不保存明文）
+type RefreshToken struct {
+	ID         string
+	UserID     string
+	TokenHash  string
+	FamilyID   string // 同一次登录产生的轮换链共享同一个 FamilyID
+	ParentID   string // 被本token替换的上一个token
+	DeviceInfo string
+	IPAddress  string
+	ExpiresAt  time.Time
+	Revoked    bool
+	CreatedAt  time.Time
+}
+
+// RefreshTokenStore 刷新token存储
+type RefreshTokenStore interface {
+	CreateRefreshToken(token *RefreshToken) error
+	GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error)
+	// ConsumeRefreshToken 原子地将未撤销的token标记为已撤销并记录替换者，返回是否成功（false 表示已被使用）
+	ConsumeRefreshToken(tokenHash, replacedByID string) (bool, error)
+	RevokeRefreshTokenFamily(familyID string) error
+}
+
+// DeviceInfo 签发刷新token时记录的设备信息
+type DeviceInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+// HashRefreshToken 计算刷新token的哈希（数据库中只保存哈希）
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateRefreshTokenString 生成随机刷新token
+func generateRefreshTokenString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// IssueRefreshToken 为新登录签发刷新token（开启新的轮换链）
+func IssueRefreshToken(store RefreshTokenStore, userID string, device DeviceInfo) (string, *RefreshToken, error) {
+	id := uuid.New().String()
+	return issueRefreshToken(store, &RefreshToken{
+		ID:       id,
+		UserID:   userID,
+		FamilyID: id,
+	}, device)
+}
+
+// issueRefreshToken 生成token并保存记录
+func issueRefreshToken(store RefreshTokenStore, rec *RefreshToken, device DeviceInfo) (string, *RefreshToken, error) {
+	token, err := generateRefreshTokenString()
+	if err != nil {
+		return "", nil, fmt.Errorf("生成刷新token失败: %w", err)
+	}
+
+	now := time.Now()
+	rec.TokenHash = HashRefreshToken(token)
+	rec.DeviceInfo = device.UserAgent
+	rec.IPAddress = device.IPAddress
+	rec.CreatedAt = now
+	rec.ExpiresAt = now.Add(RefreshTokenTTL)
+
+	if err := store.CreateRefreshToken(rec); err != nil {
+		return "", nil, fmt.Errorf("保存刷新token失败: %w", err)
+	}
+	return token, rec, nil
+}
+
+// RotateRefreshToken 使用刷新token换取新的刷新token，旧token立即失效。
+// 已失效的token被再次使用时视为泄露，撤销整条轮换链并返回 ErrRefreshTokenReused。
+func RotateRefreshToken(store RefreshTokenStore, token string, device DeviceInfo) (string, *RefreshToken, error) {
+	if token == "" {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	current, err := store.GetRefreshTokenByHash(HashRefreshToken(token))
+	if err != nil || current == nil {
+		return "", nil, ErrInvalidRefreshToken
+	}
+
+	if current.Revoked {
+		return "", nil, revokeReusedFamily(store, current)
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return "", nil, ErrRefreshTokenExpired
+	}
+
+	next := &RefreshToken{
+		ID:       uuid.New().String(),
+		UserID:   current.UserID,
+		FamilyID: current.FamilyID,
+		ParentID: current.ID,
+	}
+
+	// 并发刷新时只有一个请求能消费成功，另一个按重用处理
+	consumed, err := store.ConsumeRefreshToken(current.TokenHash, next.ID)
+	if err != nil {
+		return "", nil, fmt.Errorf("轮换刷新token失败: %w", err)
+	}
+	if !consumed {
+		return "", nil, revokeReusedFamily(store, current)
+	}
+
+	return issueRefreshToken(store, next, device)
+}
+
+// revokeReusedFamily 检测到刷新token重用时撤销整条轮换链
+func revokeReusedFamily(store RefreshTokenStore, rec *RefreshToken) error {
+	log.Printf("⚠️ 检测到刷新token重用: user=%s family=%s，撤销整条token链", rec.UserID, rec.FamilyID)
+	if err := store.RevokeRefreshTokenFamily(rec.FamilyID); err != nil {
+		return fmt.Errorf("撤销token链失败: %w", err)
+	}
+	return ErrRefreshTokenReused
+}
+
+// RevokeRefreshToken 撤销刷新token所在的整条轮换链（登出时使用），userID 不匹配时拒绝
+func RevokeRefreshToken(store RefreshTokenStore, token, userID string) error {
+	rec, err := store.GetRefreshTokenByHash(HashRefreshToken(token))
+	if err != nil || rec == nil || rec.UserID != userID {
+		return ErrInvalidRefreshToken
+	}
+	return store.RevokeRefreshTokenFamily(rec.FamilyID)
+}
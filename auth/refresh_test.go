@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryRefreshStore 内存实现的刷新token存储（测试用）
+type memoryRefreshStore struct {
+	mu     sync.Mutex
+	tokens map[string]*RefreshToken // token_hash -> record
+}
+
+func newMemoryRefreshStore() *memoryRefreshStore {
+	return &memoryRefreshStore{tokens: make(map[string]*RefreshToken)}
+}
+
+func (m *memoryRefreshStore) CreateRefreshToken(token *RefreshToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cp := *token
+	m.tokens[token.TokenHash] = &cp
+	return nil
+}
+
+func (m *memoryRefreshStore) GetRefreshTokenByHash(tokenHash string) (*RefreshToken, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.tokens[tokenHash]
+	if !ok {
+		return nil, fmt.Errorf("not found")
+	}
+	cp := *rec
+	return &cp, nil
+}
+
+func (m *memoryRefreshStore) ConsumeRefreshToken(tokenHash, replacedByID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rec, ok := m.tokens[tokenHash]
+	if !ok || rec.Revoked {
+		return false, nil
+	}
+	rec.Revoked = true
+	return true, nil
+}
+
+func (m *memoryRefreshStore) RevokeRefreshTokenFamily(familyID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, rec := range m.tokens {
+		if rec.FamilyID == familyID {
+			rec.Revoked = true
+		}
+	}
+	return nil
+}
+
+// TestRotateRefreshToken 测试刷新token轮换：旧token失效、新token可用
+func TestRotateRefreshToken(t *testing.T) {
+	store := newMemoryRefreshStore()
+	device := DeviceInfo{UserAgent: "test-agent", IPAddress: "127.0.0.1"}
+
+	first, firstRec, err := IssueRefreshToken(store, "user-1", device)
+	if err != nil {
+		t.Fatalf("签发刷新token失败: %v", err)
+	}
+	if firstRec.FamilyID != firstRec.ID || firstRec.DeviceInfo != "test-agent" {
+		t.Errorf("首个token记录不正确: %+v", firstRec)
+	}
+
+	second, secondRec, err := RotateRefreshToken(store, first, device)
+	if err != nil {
+		t.Fatalf("轮换刷新token失败: %v", err)
+	}
+	if second == first {
+		t.Error("轮换后应生成新的token")
+	}
+	if secondRec.FamilyID != firstRec.FamilyID || secondRec.ParentID != firstRec.ID || secondRec.UserID != "user-1" {
+		t.Errorf("新token应继承轮换链: %+v", secondRec)
+	}
+
+	if _, _, err := RotateRefreshToken(store, second, device); err != nil {
+		t.Errorf("新token应可继续轮换: %v", err)
+	}
+}
+
+// TestRotateRefreshToken_ReuseRevokesFamily 测试旧token被重用时撤销整条链
+func TestRotateRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	store := newMemoryRefreshStore()
+
+	first, _, _ := IssueRefreshToken(store, "user-1", DeviceInfo{})
+	second, _, err := RotateRefreshToken(store, first, DeviceInfo{})
+	if err != nil {
+		t.Fatalf("轮换刷新token失败: %v", err)
+	}
+
+	// 另一条登录链不受影响
+	other, _, _ := IssueRefreshToken(store, "user-1", DeviceInfo{})
+
+	if _, _, err := RotateRefreshToken(store, first, DeviceInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("重用旧token应返回 ErrRefreshTokenReused，实际 %v", err)
+	}
+	if _, _, err := RotateRefreshToken(store, second, DeviceInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("重用后同链的新token也应失效，实际 %v", err)
+	}
+	if _, _, err := RotateRefreshToken(store, other, DeviceInfo{}); err != nil {
+		t.Errorf("其他登录链不应受影响: %v", err)
+	}
+}
+
+// TestRotateRefreshToken_Concurrent 测试同一token并发刷新时只有一个成功
+func TestRotateRefreshToken_Concurrent(t *testing.T) {
+	store := newMemoryRefreshStore()
+	token, _, _ := IssueRefreshToken(store, "user-1", DeviceInfo{})
+
+	const n = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, _, err := RotateRefreshToken(store, token, DeviceInfo{}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("并发刷新应只有1个成功，实际 %d", succeeded)
+	}
+}
+
+// TestRotateRefreshToken_InvalidAndExpired 测试无效和过期token
+func TestRotateRefreshToken_InvalidAndExpired(t *testing.T) {
+	store := newMemoryRefreshStore()
+
+	if _, _, err := RotateRefreshToken(store, "", DeviceInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("空token应返回 ErrInvalidRefreshToken，实际 %v", err)
+	}
+	if _, _, err := RotateRefreshToken(store, "unknown", DeviceInfo{}); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("未知token应返回 ErrInvalidRefreshToken，实际 %v", err)
+	}
+
+	token, rec, _ := IssueRefreshToken(store, "user-1", DeviceInfo{})
+	store.tokens[rec.TokenHash].ExpiresAt = time.Now().Add(-time.Minute)
+	if _, _, err := RotateRefreshToken(store, token, DeviceInfo{}); !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Errorf("过期token应返回 ErrRefreshTokenExpired，实际 %v", err)
+	}
+}
+
+// TestRevokeRefreshToken 测试登出时撤销刷新token
+func TestRevokeRefreshToken(t *testing.T) {
+	store := newMemoryRefreshStore()
+	token, _, _ := IssueRefreshToken(store, "user-1", DeviceInfo{})
+
+	if err := RevokeRefreshToken(store, token, "user-2"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("撤销他人token应被拒绝，实际 %v", err)
+	}
+	if err := RevokeRefreshToken(store, token, "user-1"); err != nil {
+		t.Fatalf("撤销token失败: %v", err)
+	}
+	if _, _, err := RotateRefreshToken(store, token, DeviceInfo{}); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("撤销后的token应不可用，实际 %v", err)
+	}
+}
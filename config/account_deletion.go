@@ -0,0 +1,86 @@
+package config
+
+import (
+	"fmt"
+)
+
+// userOwnedTables 按 user_id 归属于用户的数据表（删除用户时级联清理；审计日志保留并匿名化，不在此列）
+var userOwnedTables = []string{
+	"traders",
+	"ai_models",
+	"exchanges",
+	"user_signal_sources",
+	"prompt_templates",
+	"prompt_template_versions",
+	"trade_history",
+	"sync_status",
+	"ai_spend_records",
+	"user_spend_settings",
+	"user_settings",
+	"refresh_tokens",
+	"otp_recovery_codes",
+	"email_tokens",
+	"equity_snapshots",
+	"paper_accounts",
+	"paper_positions",
+	"notification_channels",
+	"webhooks",
+	"webhook_deliveries",
+	"performance_reports",
+	"report_markers",
+}
+
+// DeletedUserAuditID 已删除用户的审计记录中替代 user_id 的占位值（保留操作记录，不再关联到具体用户）
+const DeletedUserAuditID = "deleted-user"
+
+// credentialScrubStatements 删除前先清空的凭证字段（纵深防御：即使删除被中断或数据库文件残留旧页，也不再保存明文/密文密钥）
+var credentialScrubStatements = []string{
+	`UPDATE exchanges SET api_key = '', secret_key = '', aster_private_key = '', aster_signer = '', aster_user = '', hyperliquid_wallet_addr = '' WHERE user_id = ?`,
+	`UPDATE ai_models SET api_key = '' WHERE user_id = ?`,
+	`UPDATE user_signal_sources SET headers = '' WHERE user_id = ?`,
+	`UPDATE notification_channels SET config = '' WHERE user_id = ?`, // Bot token、Webhook 地址和签名密钥
+	`UPDATE webhooks SET secret = '', url = '' WHERE user_id = ?`,    // 签名密钥；地址中也可能带有 token
+}
+
+// DeleteUserAccount 删除用户账户（用户自助注销和管理员删除用户共用）：先覆盖凭证字段，再删除用户的全部数据和用户记录、
+// 匿名化其审计日志，返回各表删除的行数
+// 所有操作在同一事务中执行，中断后重试是安全的（已删除的用户再次执行只会清理残留数据）
+func (d *Database) DeleteUserAccount(userID string) (map[string]int64, error) {
+	var deleted map[string]int64
+	err := d.writeTx(func(tx *sqlTx) error {
+		deleted = make(map[string]int64)
+		for _, statement := range credentialScrubStatements {
+			if _, err := tx.Exec(statement, userID); err != nil {
+				return fmt.Errorf("清除凭证失败: %w", err)
+			}
+		}
+
+		for _, table := range userOwnedTables {
+			result, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE user_id = ?`, table), userID)
+			if err != nil {
+				return fmt.Errorf("删除用户数据失败 [%s]: %w", table, err)
+			}
+			if n, _ := result.RowsAffected(); n > 0 {
+				deleted[table] = n
+			}
+		}
+
+		// 审计日志不随用户删除，只去掉与用户的关联
+		if _, err := tx.Exec(`UPDATE audit_log SET user_id = ? WHERE user_id = ?`, DeletedUserAuditID, userID); err != nil {
+			return fmt.Errorf("匿名化审计日志失败: %w", err)
+		}
+
+		result, err := tx.Exec(`DELETE FROM users WHERE id = ?`, userID)
+		if err != nil {
+			return fmt.Errorf("删除用户失败: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			deleted["users"] = n
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// 用户角色
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
+// UserSummary 管理员视角的用户概要（含交易员数量）
+type UserSummary struct {
+	ID                 string    `json:"id"`
+	Email              string    `json:"email"`
+	Role               string    `json:"role"`
+	Disabled           bool      `json:"disabled"`
+	OTPVerified        bool      `json:"otp_verified"`
+	EmailVerified      bool      `json:"email_verified"`
+	TraderCount        int       `json:"trader_count"`
+	RunningTraderCount int       `json:"running_trader_count"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+// ListUsersWithTraderCounts 获取所有用户及其交易员数量
+func (d *Database) ListUsersWithTraderCounts() ([]*UserSummary, error) {
+	rows, err := d.db.Query(`
+		SELECT u.id, u.email, COALESCE(u.role, 'user'), COALESCE(u.disabled, 0), u.otp_verified, COALESCE(u.email_verified, 0),
+		       COUNT(t.id), COALESCE(SUM(CASE WHEN t.is_running = 1 THEN 1 ELSE 0 END), 0), u.created_at
+		FROM users u
+		LEFT JOIN traders t ON t.user_id = u.id
+		GROUP BY u.id
+		ORDER BY u.created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询用户列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	users := make([]*UserSummary, 0)
+	for rows.Next() {
+		var u UserSummary
+		if err := rows.Scan(&u.ID, &u.Email, &u.Role, &u.Disabled, &u.OTPVerified, &u.EmailVerified,
+			&u.TraderCount, &u.RunningTraderCount, &u.CreatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, &u)
+	}
+	return users, rows.Err()
+}
+
+// IsUserDisabled 检查用户是否被禁用（用户不存在时返回 false）
+func (d *Database) IsUserDisabled(userID string) (bool, error) {
+	var disabled bool
+	err := d.db.QueryRow(`SELECT COALESCE(disabled, 0) FROM users WHERE id = ?`, userID).Scan(&disabled)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return disabled, nil
+}
+
+// SetUserDisabled 禁用/启用用户
+func (d *Database) SetUserDisabled(userID string, disabled bool) error {
+	result, err := d.db.Exec(`
+		UPDATE users SET disabled = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, disabled, userID)
+	if err != nil {
+		return fmt.Errorf("更新用户状态失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("用户不存在")
+	}
+	return nil
+}
+
+// SetUserRole 设置用户角色
+func (d *Database) SetUserRole(userID, role string) error {
+	if role != UserRoleUser && role != UserRoleAdmin {
+		return fmt.Errorf("无效的角色: %s", role)
+	}
+	result, err := d.db.Exec(`
+		UPDATE users SET role = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, role, userID)
+	if err != nil {
+		return fmt.Errorf("更新用户角色失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("用户不存在")
+	}
+	return nil
+}
+
+// PromoteAdminByEmail 将指定邮箱的用户设为管理员（用于创建第一个管理员）
+func (d *Database) PromoteAdminByEmail(email string) (*User, error) {
+	user, err := d.GetUserByEmail(strings.TrimSpace(email))
+	if err != nil {
+		return nil, fmt.Errorf("用户 %s 不存在（请先注册）", email)
+	}
+	if user.Role != UserRoleAdmin {
+		if err := d.SetUserRole(user.ID, UserRoleAdmin); err != nil {
+			return nil, err
+		}
+		user.Role = UserRoleAdmin
+	}
+	return user, nil
+}
+
+// PromoteInitialAdminByEmail 系统中还没有管理员时将指定邮箱的用户设为管理员（NOFX_ADMIN_EMAIL 每次启动时调用）；
+// 已有管理员时不做任何修改并返回 false，避免之后用该邮箱注册的用户自动获得管理员权限
+func (d *Database) PromoteInitialAdminByEmail(email string) (*User, bool, error) {
+	var admins int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM users WHERE role = ?`, UserRoleAdmin).Scan(&admins); err != nil {
+		return nil, false, fmt.Errorf("查询管理员失败: %w", err)
+	}
+	if admins > 0 {
+		return nil, false, nil
+	}
+	user, err := d.PromoteAdminByEmail(email)
+	if err != nil {
+		return nil, false, err
+	}
+	return user, true, nil
+}
+
+// ResetUserOTP 重置用户OTP密钥（用户需重新绑定Google Authenticator）
+func (d *Database) ResetUserOTP(userID, otpSecret string) error {
+	result, err := d.db.Exec(`
+		UPDATE users SET otp_secret = ?, otp_verified = 0, updated_at = CURRENT_TIMESTAMP WHERE id = ?
+	`, otpSecret, userID)
+	if err != nil {
+		return fmt.Errorf("重置OTP失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("用户不存在")
+	}
+	// 旧的恢复码随OTP一起失效，重新绑定时会生成新的恢复码
+	if err := d.DeleteRecoveryCodes(userID); err != nil {
+		return fmt.Errorf("删除恢复码失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAdminUsers_ListDisableAndRole 测试用户列表、禁用和角色
+func TestAdminUsers_ListDisableAndRole(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	for _, tr := range []*TraderRecord{
+		{ID: "t1", UserID: userID, Name: "T1", AIModelID: "deepseek", ExchangeID: "binance", IsRunning: true},
+		{ID: "t2", UserID: userID, Name: "T2", AIModelID: "deepseek", ExchangeID: "binance"},
+	} {
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+
+	users, err := db.ListUsersWithTraderCounts()
+	if err != nil {
+		t.Fatalf("获取用户列表失败: %v", err)
+	}
+	var found *UserSummary
+	for _, u := range users {
+		if u.ID == userID {
+			found = u
+		}
+	}
+	if found == nil || found.TraderCount != 2 || found.RunningTraderCount != 1 || found.Role != UserRoleUser {
+		t.Fatalf("用户概要不正确: %+v", found)
+	}
+
+	if err := db.SetUserDisabled(userID, true); err != nil {
+		t.Fatalf("禁用用户失败: %v", err)
+	}
+	if disabled, _ := db.IsUserDisabled(userID); !disabled {
+		t.Error("用户应被禁用")
+	}
+	if err := db.SetUserDisabled("no-such-user", true); err == nil {
+		t.Error("禁用不存在的用户应返回错误")
+	}
+
+	if err := db.SetUserRole(userID, UserRoleAdmin); err != nil {
+		t.Fatalf("设置角色失败: %v", err)
+	}
+	user, err := db.GetUserByID(userID)
+	if err != nil {
+		t.Fatalf("获取用户失败: %v", err)
+	}
+	if user.Role != UserRoleAdmin || !user.Disabled {
+		t.Errorf("用户角色/状态不正确: role=%s disabled=%v", user.Role, user.Disabled)
+	}
+	if err := db.SetUserRole(userID, "root"); err == nil {
+		t.Error("无效角色应返回错误")
+	}
+}
+
+// TestPromoteInitialAdminByEmail 只在还没有管理员时设置第一个管理员
+func TestPromoteInitialAdminByEmail(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, _, err := db.PromoteInitialAdminByEmail("nobody@test.com"); err == nil {
+		t.Error("不存在的邮箱应返回错误")
+	}
+	user, promoted, err := db.PromoteInitialAdminByEmail("test-user-001@test.com")
+	if err != nil || !promoted || user.Role != UserRoleAdmin {
+		t.Fatalf("没有管理员时应设置第一个管理员: %+v %v %v", user, promoted, err)
+	}
+
+	// 已有管理员后不再自动提升（NOFX_ADMIN_EMAIL 改为其他邮箱也不生效）
+	if _, promoted, err := db.PromoteInitialAdminByEmail("test-user-002@test.com"); err != nil || promoted {
+		t.Fatalf("已有管理员时不应再设置: %v %v", promoted, err)
+	}
+	if u, _ := db.GetUserByID("test-user-002"); u == nil || u.Role == UserRoleAdmin {
+		t.Errorf("已有管理员时用户角色不应改变: %+v", u)
+	}
+	if _, promoted, _ := db.PromoteInitialAdminByEmail("test-user-001@test.com"); promoted {
+		t.Error("已是管理员时不应重复设置")
+	}
+}
+
+// TestAdminUsers_DeleteCascade 测试删除用户时级联删除其数据
+func TestAdminUsers_DeleteCascade(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-002"
+	if err := db.CreateTrader(&TraderRecord{ID: "t3", UserID: userID, Name: "T3", AIModelID: "deepseek", ExchangeID: "binance"}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	if err := db.RecordAISpend(&AISpendRecord{UserID: userID, TraderID: "t3", CostUSD: 1, CreatedAt: time.Now()}); err != nil {
+		t.Fatalf("记录花费失败: %v", err)
+	}
+
+	if _, err := db.DeleteUserAccount(userID); err != nil {
+		t.Fatalf("删除用户失败: %v", err)
+	}
+
+	if _, err := db.GetUserByID(userID); err == nil {
+		t.Error("用户应已删除")
+	}
+	traders, _ := db.GetTraders(userID)
+	if len(traders) != 0 {
+		t.Errorf("交易员应被级联删除，剩余 %d 个", len(traders))
+	}
+	start, end := SpendMonthRange(time.Now())
+	if total, _ := db.GetUserSpendTotal(userID, start, end); total != 0 {
+		t.Errorf("花费记录应被级联删除，剩余 %.2f", total)
+	}
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSpendAlertThresholds 默认的花费告警阈值（预算百分比）
+var DefaultSpendAlertThresholds = []int{50, 80, 100}
+
+// AISpendRecord 单次 AI 调用花费记录
+type AISpendRecord struct {
+	ID               int64     `json:"id"`
+	UserID           string    `json:"user_id"`
+	TraderID         string    `json:"trader_id"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	CostUSD          float64   `json:"cost_usd"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UserSpendSettings 用户 AI 花费预算配置
+type UserSpendSettings struct {
+	UserID           string  `json:"user_id"`
+	MonthlyBudgetUSD float64 `json:"monthly_budget_usd"` // 月度预算（0 表示不限制）
+	AlertThresholds  []int   `json:"alert_thresholds"`   // 告警阈值（预算百分比，升序）
+	DowngradeEnabled bool    `json:"downgrade_enabled"`  // 达到100%时是否切换到备用模型（否则仅告警）
+	FallbackModelID  string  `json:"fallback_model_id"`  // 备用（更便宜的）AI模型ID
+	AlertMonth       string  `json:"alert_month"`        // 最近一次告警所属月份（YYYY-MM）
+	AlertLevel       int     `json:"alert_level"`        // 当月已发送的最高告警阈值
+}
+
+// DailySpend 每日花费汇总
+type DailySpend struct {
+	Date             string  `json:"date"` // YYYY-MM-DD (UTC)
+	CostUSD          float64 `json:"cost_usd"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Calls            int     `json:"calls"`
+}
+
+// TraderSpend 单个交易员的花费汇总
+type TraderSpend struct {
+	TraderID         string  `json:"trader_id"`
+	TraderName       string  `json:"trader_name"`
+	CostUSD          float64 `json:"cost_usd"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Calls            int     `json:"calls"`
+}
+
+// SpendMonthRange 返回指定时间所在月份的 UTC 起止时间 [start, end)
+func SpendMonthRange(t time.Time) (time.Time, time.Time) {
+	t = t.UTC()
+	start := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(0, 1, 0)
+}
+
+// formatDBTime 格式化为与 CURRENT_TIMESTAMP 一致的 UTC 字符串，便于范围比较
+func formatDBTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// parseSpendThresholds 解析逗号分隔的阈值列表
+func parseSpendThresholds(raw string) []int {
+	thresholds := make([]int, 0)
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if v, err := strconv.Atoi(part); err == nil && v > 0 {
+			thresholds = append(thresholds, v)
+		}
+	}
+	sort.Ints(thresholds)
+	return thresholds
+}
+
+// formatSpendThresholds 将阈值列表格式化为逗号分隔字符串
+func formatSpendThresholds(thresholds []int) string {
+	parts := make([]string, 0, len(thresholds))
+	for _, v := range thresholds {
+		parts = append(parts, strconv.Itoa(v))
+	}
+	return strings.Join(parts, ",")
+}
+
+// RecordAISpend 记录一次 AI 调用花费
+func (d *Database) RecordAISpend(record *AISpendRecord) error {
+	createdAt := record.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := d.db.Exec(`
+		INSERT INTO ai_spend_records (user_id, trader_id, model, prompt_tokens, completion_tokens, cost_usd, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, record.UserID, record.TraderID, record.Model, record.PromptTokens, record.CompletionTokens,
+		record.CostUSD, formatDBTime(createdAt))
+	if err != nil {
+		return fmt.Errorf("记录AI花费失败: %w", err)
+	}
+	return nil
+}
+
+// GetUserSpendSettings 获取用户花费预算配置（未配置时返回默认值）
+func (d *Database) GetUserSpendSettings(userID string) (*UserSpendSettings, error) {
+	settings := &UserSpendSettings{
+		UserID:          userID,
+		AlertThresholds: append([]int(nil), DefaultSpendAlertThresholds...),
+	}
+
+	var thresholds string
+	err := d.db.QueryRow(`
+		SELECT monthly_budget_usd, COALESCE(alert_thresholds, ''), downgrade_enabled,
+		       COALESCE(fallback_model_id, ''), COALESCE(alert_month, ''), COALESCE(alert_level, 0)
+		FROM user_spend_settings WHERE user_id = ?
+	`, userID).Scan(
+		&settings.MonthlyBudgetUSD, &thresholds, &settings.DowngradeEnabled,
+		&settings.FallbackModelID, &settings.AlertMonth, &settings.AlertLevel,
+	)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取花费预算配置失败: %w", err)
+	}
+
+	if parsed := parseSpendThresholds(thresholds); len(parsed) > 0 {
+		settings.AlertThresholds = parsed
+	}
+	return settings, nil
+}
+
+// UpdateUserSpendSettings 保存用户花费预算配置（不修改告警状态）
+func (d *Database) UpdateUserSpendSettings(settings *UserSpendSettings) error {
+	if settings.MonthlyBudgetUSD < 0 {
+		return fmt.Errorf("月度预算不能为负数")
+	}
+	for _, v := range settings.AlertThresholds {
+		if v <= 0 || v > 1000 {
+			return fmt.Errorf("告警阈值必须在1-1000之间: %d", v)
+		}
+	}
+
+	thresholds := append([]int(nil), settings.AlertThresholds...)
+	sort.Ints(thresholds)
+	if len(thresholds) == 0 {
+		thresholds = append(thresholds, DefaultSpendAlertThresholds...)
+	}
+
+	_, err := d.db.Exec(`
+		INSERT INTO user_spend_settings (user_id, monthly_budget_usd, alert_thresholds, downgrade_enabled, fallback_model_id, updated_at)
+		VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			monthly_budget_usd = excluded.monthly_budget_usd,
+			alert_thresholds = excluded.alert_thresholds,
+			downgrade_enabled = excluded.downgrade_enabled,
+			fallback_model_id = excluded.fallback_model_id,
+			updated_at = CURRENT_TIMESTAMP
+	`, settings.UserID, settings.MonthlyBudgetUSD, formatSpendThresholds(thresholds),
+		settings.DowngradeEnabled, settings.FallbackModelID)
+	if err != nil {
+		return fmt.Errorf("保存花费预算配置失败: %w", err)
+	}
+	return nil
+}
+
+// MarkSpendAlertSent 记录当月已发送的告警阈值，避免重复告警
+func (d *Database) MarkSpendAlertSent(userID, month string, level int) error {
+	_, err := d.db.Exec(`
+		INSERT INTO user_spend_settings (user_id, alert_month, alert_level, updated_at)
+		VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			alert_month = excluded.alert_month,
+			alert_level = excluded.alert_level,
+			updated_at = CURRENT_TIMESTAMP
+	`, userID, month, level)
+	if err != nil {
+		return fmt.Errorf("更新花费告警状态失败: %w", err)
+	}
+	return nil
+}
+
+// GetUserSpendTotal 获取用户在 [start, end) 时间范围内的 AI 花费总额
+func (d *Database) GetUserSpendTotal(userID string, start, end time.Time) (float64, error) {
+	var total float64
+	err := d.db.QueryRow(`
+		SELECT COALESCE(SUM(cost_usd), 0) FROM ai_spend_records
+		WHERE user_id = ? AND created_at >= ? AND created_at < ?
+	`, userID, formatDBTime(start), formatDBTime(end)).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("统计AI花费失败: %w", err)
+	}
+	return total, nil
+}
+
+// GetUserSpendDaily 获取用户在 [start, end) 时间范围内的每日花费序列
+func (d *Database) GetUserSpendDaily(userID string, start, end time.Time) ([]*DailySpend, error) {
+	rows, err := d.db.Query(`
+		SELECT substr(CAST(created_at AS TEXT), 1, 10) AS day, COALESCE(SUM(cost_usd), 0),
+		       COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(completion_tokens), 0), COUNT(*)
+		FROM ai_spend_records
+		WHERE user_id = ? AND created_at >= ? AND created_at < ?
+		GROUP BY day ORDER BY day
+	`, userID, formatDBTime(start), formatDBTime(end))
+	if err != nil {
+		return nil, fmt.Errorf("查询每日AI花费失败: %w", err)
+	}
+	defer rows.Close()
+
+	daily := make([]*DailySpend, 0)
+	for rows.Next() {
+		var item DailySpend
+		if err := rows.Scan(&item.Date, &item.CostUSD, &item.PromptTokens, &item.CompletionTokens, &item.Calls); err != nil {
+			return nil, err
+		}
+		daily = append(daily, &item)
+	}
+	return daily, rows.Err()
+}
+
+// GetUserSpendByTrader 获取用户在 [start, end) 时间范围内按交易员汇总的花费（降序）
+func (d *Database) GetUserSpendByTrader(userID string, start, end time.Time) ([]*TraderSpend, error) {
+	rows, err := d.db.Query(`
+		SELECT s.trader_id, COALESCE(t.name, ''), COALESCE(SUM(s.cost_usd), 0),
+		       COALESCE(SUM(s.prompt_tokens), 0), COALESCE(SUM(s.completion_tokens), 0), COUNT(*)
+		FROM ai_spend_records s
+		LEFT JOIN traders t ON t.id = s.trader_id
+		WHERE s.user_id = ? AND s.created_at >= ? AND s.created_at < ?
+		GROUP BY s.trader_id ORDER BY SUM(s.cost_usd) DESC
+	`, userID, formatDBTime(start), formatDBTime(end))
+	if err != nil {
+		return nil, fmt.Errorf("查询交易员AI花费失败: %w", err)
+	}
+	defer rows.Close()
+
+	traders := make([]*TraderSpend, 0)
+	for rows.Next() {
+		var item TraderSpend
+		if err := rows.Scan(&item.TraderID, &item.TraderName, &item.CostUSD,
+			&item.PromptTokens, &item.CompletionTokens, &item.Calls); err != nil {
+			return nil, err
+		}
+		traders = append(traders, &item)
+	}
+	return traders, rows.Err()
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestUserSpendSettings_DefaultsAndUpdate 测试花费预算配置的默认值与保存
+func TestUserSpendSettings_DefaultsAndUpdate(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+
+	// 未配置时返回默认阈值
+	settings, err := db.GetUserSpendSettings(userID)
+	if err != nil {
+		t.Fatalf("获取默认配置失败: %v", err)
+	}
+	if settings.MonthlyBudgetUSD != 0 || len(settings.AlertThresholds) != 3 {
+		t.Errorf("默认配置不正确: %+v", settings)
+	}
+
+	// 保存配置（阈值乱序，应被排序）
+	err = db.UpdateUserSpendSettings(&UserSpendSettings{
+		UserID:           userID,
+		MonthlyBudgetUSD: 20,
+		AlertThresholds:  []int{90, 60},
+		DowngradeEnabled: true,
+		FallbackModelID:  "qwen",
+	})
+	if err != nil {
+		t.Fatalf("保存配置失败: %v", err)
+	}
+
+	// 告警状态单独更新，不应覆盖预算配置
+	if err := db.MarkSpendAlertSent(userID, "2026-10", 60); err != nil {
+		t.Fatalf("更新告警状态失败: %v", err)
+	}
+
+	settings, err = db.GetUserSpendSettings(userID)
+	if err != nil {
+		t.Fatalf("获取配置失败: %v", err)
+	}
+	if settings.MonthlyBudgetUSD != 20 || !settings.DowngradeEnabled || settings.FallbackModelID != "qwen" {
+		t.Errorf("配置未正确保存: %+v", settings)
+	}
+	if len(settings.AlertThresholds) != 2 || settings.AlertThresholds[0] != 60 || settings.AlertThresholds[1] != 90 {
+		t.Errorf("阈值应按升序保存，实际 %v", settings.AlertThresholds)
+	}
+	if settings.AlertMonth != "2026-10" || settings.AlertLevel != 60 {
+		t.Errorf("告警状态不正确: month=%s level=%d", settings.AlertMonth, settings.AlertLevel)
+	}
+
+	// 负数预算应被拒绝
+	if err := db.UpdateUserSpendSettings(&UserSpendSettings{UserID: userID, MonthlyBudgetUSD: -1}); err == nil {
+		t.Error("负数预算应返回错误")
+	}
+}
+
+// TestAISpend_MonthlyAggregation 测试花费的月度汇总、每日序列和交易员拆分
+func TestAISpend_MonthlyAggregation(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-002"
+	records := []*AISpendRecord{
+		{UserID: userID, TraderID: "t1", Model: "deepseek-chat", PromptTokens: 1000, CompletionTokens: 200, CostUSD: 1.5, CreatedAt: time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)},
+		{UserID: userID, TraderID: "t1", Model: "deepseek-chat", PromptTokens: 1000, CompletionTokens: 200, CostUSD: 0.5, CreatedAt: time.Date(2026, 10, 1, 9, 0, 0, 0, time.UTC)},
+		{UserID: userID, TraderID: "t2", Model: "qwen3-max", PromptTokens: 500, CompletionTokens: 100, CostUSD: 3, CreatedAt: time.Date(2026, 10, 2, 9, 0, 0, 0, time.UTC)},
+		// 上个月的记录不应计入
+		{UserID: userID, TraderID: "t1", Model: "deepseek-chat", CostUSD: 100, CreatedAt: time.Date(2026, 9, 30, 23, 59, 0, 0, time.UTC)},
+		// 其他用户的记录不应计入
+		{UserID: "test-user-003", TraderID: "t3", Model: "deepseek-chat", CostUSD: 50, CreatedAt: time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)},
+	}
+	for _, r := range records {
+		if err := db.RecordAISpend(r); err != nil {
+			t.Fatalf("记录花费失败: %v", err)
+		}
+	}
+
+	start, end := SpendMonthRange(time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC))
+
+	total, err := db.GetUserSpendTotal(userID, start, end)
+	if err != nil {
+		t.Fatalf("统计花费失败: %v", err)
+	}
+	if total != 5 {
+		t.Errorf("月度花费应为 5，实际 %.2f", total)
+	}
+
+	daily, err := db.GetUserSpendDaily(userID, start, end)
+	if err != nil {
+		t.Fatalf("查询每日花费失败: %v", err)
+	}
+	if len(daily) != 2 || daily[0].Date != "2026-10-01" || daily[0].CostUSD != 2 || daily[0].Calls != 2 {
+		t.Errorf("每日花费不正确: %+v", daily)
+	}
+
+	byTrader, err := db.GetUserSpendByTrader(userID, start, end)
+	if err != nil {
+		t.Fatalf("查询交易员花费失败: %v", err)
+	}
+	if len(byTrader) != 2 || byTrader[0].TraderID != "t2" || byTrader[0].CostUSD != 3 {
+		t.Errorf("交易员花费应按金额降序: %+v", byTrader)
+	}
+}
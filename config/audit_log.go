@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AuditEntry 敏感配置变更的审计记录（Summary 中不包含任何密钥）
+type AuditEntry struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Action    string    `json:"action"`
+	TargetID  string    `json:"target_id"`
+	IPAddress string    `json:"ip_address"`
+	Summary   string    `json:"summary"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditLogFilter 审计日志查询条件（零值时间表示不限制）
+type AuditLogFilter struct {
+	From   time.Time
+	To     time.Time
+	Action string
+	Limit  int
+	Offset int
+}
+
+// InsertAuditEntries 批量写入审计记录（单个事务）
+func (d *Database) InsertAuditEntries(entries []*AuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO audit_log (user_id, action, target_id, ip_address, summary, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("准备审计日志语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, e := range entries {
+		createdAt := e.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if _, err := stmt.Exec(e.UserID, e.Action, e.TargetID, e.IPAddress, e.Summary, formatDBTime(createdAt)); err != nil {
+			return fmt.Errorf("写入审计日志失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries 分页查询用户的审计记录（最新在前），返回记录和符合条件的总数
+func (d *Database) ListAuditEntries(userID string, filter AuditLogFilter) ([]*AuditEntry, int, error) {
+	conditions := []string{"user_id = ?"}
+	args := []interface{}{userID}
+	if !filter.From.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, formatDBTime(filter.From))
+	}
+	if !filter.To.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, formatDBTime(filter.To))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, filter.Action)
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM audit_log WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("统计审计日志失败: %w", err)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT id, user_id, action, COALESCE(target_id, ''), COALESCE(ip_address, ''), COALESCE(summary, ''), created_at
+		FROM audit_log WHERE `+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT ? OFFSET ?
+	`, append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("查询审计日志失败: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*AuditEntry, 0)
+	for rows.Next() {
+		var e AuditEntry
+		if err := rows.Scan(&e.ID, &e.UserID, &e.Action, &e.TargetID, &e.IPAddress, &e.Summary, &e.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, &e)
+	}
+	return entries, total, rows.Err()
+}
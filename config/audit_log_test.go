@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAuditLog_InsertAndFilter 测试审计日志批量写入、时间/动作筛选和分页
+func TestAuditLog_InsertAndFilter(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	base := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	entries := []*AuditEntry{
+		{UserID: "test-user-001", Action: "trader.create", TargetID: "t1", IPAddress: "1.2.3.4", CreatedAt: base},
+		{UserID: "test-user-001", Action: "trader.start", TargetID: "t1", CreatedAt: base.Add(time.Hour)},
+		{UserID: "test-user-001", Action: "exchange.update", Summary: `{"binance":{"api_key":"abcd****wxyz"}}`, CreatedAt: base.Add(2 * time.Hour)},
+		{UserID: "test-user-002", Action: "trader.create", TargetID: "t2", CreatedAt: base},
+	}
+	if err := db.InsertAuditEntries(entries); err != nil {
+		t.Fatalf("写入审计日志失败: %v", err)
+	}
+
+	all, total, err := db.ListAuditEntries("test-user-001", AuditLogFilter{Limit: 10})
+	if err != nil {
+		t.Fatalf("查询审计日志失败: %v", err)
+	}
+	if total != 3 || len(all) != 3 {
+		t.Fatalf("应返回3条记录，实际 total=%d len=%d", total, len(all))
+	}
+	if all[0].Action != "exchange.update" || !all[0].CreatedAt.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("应按时间倒序返回，实际第一条为 %s @ %v", all[0].Action, all[0].CreatedAt)
+	}
+	if all[2].IPAddress != "1.2.3.4" {
+		t.Errorf("IP地址未保存: %q", all[2].IPAddress)
+	}
+
+	page, total, _ := db.ListAuditEntries("test-user-001", AuditLogFilter{Limit: 1, Offset: 1})
+	if total != 3 || len(page) != 1 || page[0].Action != "trader.start" {
+		t.Errorf("分页结果不正确: total=%d %v", total, page)
+	}
+
+	ranged, total, _ := db.ListAuditEntries("test-user-001", AuditLogFilter{From: base.Add(30 * time.Minute), To: base.Add(90 * time.Minute), Limit: 10})
+	if total != 1 || ranged[0].Action != "trader.start" {
+		t.Errorf("时间筛选结果不正确: total=%d", total)
+	}
+
+	byAction, total, _ := db.ListAuditEntries("test-user-001", AuditLogFilter{Action: "trader.create", Limit: 10})
+	if total != 1 || byAction[0].TargetID != "t1" {
+		t.Errorf("动作筛选结果不正确: total=%d", total)
+	}
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+const (
+	// betaCodeAlphabet 内测码字符集（去掉易混淆的 0/O/1/I/L）
+	betaCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+	// BetaCodeLength 生成的内测码长度
+	BetaCodeLength = 10
+	// MaxBetaCodesPerRequest 单次最多生成的内测码数量
+	MaxBetaCodesPerRequest = 500
+)
+
+// 内测码列表筛选条件
+const (
+	BetaCodeFilterAll    = ""
+	BetaCodeFilterUsed   = "used"
+	BetaCodeFilterUnused = "unused"
+)
+
+// BetaCode 内测码
+type BetaCode struct {
+	Code      string     `json:"code"`
+	Used      bool       `json:"used"`       // 已用满（use_count >= max_uses）
+	UseCount  int        `json:"use_count"`  // 已使用次数
+	MaxUses   int        `json:"max_uses"`   // 最大使用次数
+	UsedBy    string     `json:"used_by"`    // 最近一次使用者邮箱
+	UsedAt    *time.Time `json:"used_at"`    // 最近一次使用时间
+	ExpiresAt *time.Time `json:"expires_at"` // 过期时间（为空表示永不过期）
+	Revoked   bool       `json:"revoked"`    // 是否已被管理员作废
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// generateBetaCode 使用 crypto/rand 生成随机内测码
+func generateBetaCode() (string, error) {
+	var sb strings.Builder
+	max := big.NewInt(int64(len(betaCodeAlphabet)))
+	for i := 0; i < BetaCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(betaCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// GenerateBetaCodes 生成 count 个随机内测码（maxUses<=0 时为1次；expiresAt 为 nil 表示永不过期）
+func (d *Database) GenerateBetaCodes(count, maxUses int, expiresAt *time.Time) ([]string, error) {
+	if count <= 0 || count > MaxBetaCodesPerRequest {
+		return nil, fmt.Errorf("生成数量必须在1-%d之间", MaxBetaCodesPerRequest)
+	}
+	if maxUses <= 0 {
+		maxUses = 1
+	}
+	var expires interface{}
+	if expiresAt != nil {
+		if !expiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("过期时间必须晚于当前时间")
+		}
+		expires = formatDBTime(*expiresAt)
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	codes := make([]string, 0, count)
+	for len(codes) < count {
+		code, err := generateBetaCode()
+		if err != nil {
+			return nil, fmt.Errorf("生成内测码失败: %w", err)
+		}
+
+		// 主键保证唯一，冲突时重新生成
+		result, err := tx.Exec(`
+			INSERT INTO beta_codes (code, max_uses, expires_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING
+		`, code, maxUses, expires)
+		if err != nil {
+			return nil, fmt.Errorf("保存内测码失败: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n > 0 {
+			codes = append(codes, code)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return codes, nil
+}
+
+// ListBetaCodes 列出内测码，filter 为 used/unused 时按是否已用满筛选（unused 不含已作废和已过期）
+func (d *Database) ListBetaCodes(filter string) ([]*BetaCode, error) {
+	query := `
+		SELECT code, COALESCE(use_count, 0), COALESCE(max_uses, 1), COALESCE(used_by, ''),
+		       used_at, expires_at, COALESCE(revoked, 0), created_at
+		FROM beta_codes`
+	switch filter {
+	case BetaCodeFilterAll:
+	case BetaCodeFilterUsed:
+		query += ` WHERE use_count > 0`
+	case BetaCodeFilterUnused:
+		query += ` WHERE use_count < max_uses AND revoked = 0 AND (expires_at IS NULL OR expires_at > ?)`
+	default:
+		return nil, fmt.Errorf("无效的筛选条件: %s", filter)
+	}
+	query += ` ORDER BY created_at DESC, code`
+
+	var args []interface{}
+	if filter == BetaCodeFilterUnused {
+		args = append(args, formatDBTime(time.Now()))
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询内测码失败: %w", err)
+	}
+	defer rows.Close()
+
+	codes := make([]*BetaCode, 0)
+	for rows.Next() {
+		var bc BetaCode
+		var usedAt, expiresAt sql.NullTime
+		if err := rows.Scan(&bc.Code, &bc.UseCount, &bc.MaxUses, &bc.UsedBy,
+			&usedAt, &expiresAt, &bc.Revoked, &bc.CreatedAt); err != nil {
+			return nil, err
+		}
+		if usedAt.Valid {
+			bc.UsedAt = &usedAt.Time
+		}
+		if expiresAt.Valid {
+			bc.ExpiresAt = &expiresAt.Time
+		}
+		bc.Used = bc.UseCount >= bc.MaxUses
+		codes = append(codes, &bc)
+	}
+	return codes, rows.Err()
+}
+
+// RevokeBetaCode 作废内测码（保留记录用于审计）
+func (d *Database) RevokeBetaCode(code string) error {
+	result, err := d.db.Exec(`UPDATE beta_codes SET revoked = 1 WHERE code = ?`, code)
+	if err != nil {
+		return fmt.Errorf("作废内测码失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("内测码不存在")
+	}
+	return nil
+}
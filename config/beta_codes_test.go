@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBetaCodes_GenerateUseAndRevoke 测试内测码生成、多次使用、筛选和作废
+func TestBetaCodes_GenerateUseAndRevoke(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	codes, err := db.GenerateBetaCodes(3, 2, nil)
+	if err != nil {
+		t.Fatalf("生成内测码失败: %v", err)
+	}
+	if len(codes) != 3 || len(codes[0]) != BetaCodeLength {
+		t.Fatalf("生成结果不正确: %v", codes)
+	}
+
+	code := codes[0]
+	for i := 0; i < 2; i++ {
+		if valid, _ := db.ValidateBetaCode(code); !valid {
+			t.Fatalf("第%d次使用前内测码应有效", i+1)
+		}
+		if err := db.UseBetaCode(code, "user@test.com"); err != nil {
+			t.Fatalf("使用内测码失败: %v", err)
+		}
+	}
+	if valid, _ := db.ValidateBetaCode(code); valid {
+		t.Error("用满后内测码应无效")
+	}
+	if err := db.UseBetaCode(code, "other@test.com"); err == nil {
+		t.Error("用满后再次使用应返回错误")
+	}
+
+	// 作废
+	if err := db.RevokeBetaCode(codes[1]); err != nil {
+		t.Fatalf("作废内测码失败: %v", err)
+	}
+	if valid, _ := db.ValidateBetaCode(codes[1]); valid {
+		t.Error("作废后内测码应无效")
+	}
+
+	used, err := db.ListBetaCodes(BetaCodeFilterUsed)
+	if err != nil {
+		t.Fatalf("查询已使用内测码失败: %v", err)
+	}
+	if len(used) != 1 || used[0].Code != code || !used[0].Used || used[0].UseCount != 2 || used[0].UsedBy != "user@test.com" || used[0].UsedAt == nil {
+		t.Errorf("已使用列表不正确: %+v", used)
+	}
+
+	unused, err := db.ListBetaCodes(BetaCodeFilterUnused)
+	if err != nil {
+		t.Fatalf("查询未使用内测码失败: %v", err)
+	}
+	if len(unused) != 1 || unused[0].Code != codes[2] {
+		t.Errorf("未使用列表应只包含未作废的码: %+v", unused)
+	}
+
+	if _, err := db.ListBetaCodes("bogus"); err == nil {
+		t.Error("无效筛选条件应返回错误")
+	}
+}
+
+// TestBetaCodes_Expiry 测试内测码过期
+func TestBetaCodes_Expiry(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	past := time.Now().Add(-time.Hour)
+	if _, err := db.GenerateBetaCodes(1, 1, &past); err == nil {
+		t.Error("过期时间早于当前时间应返回错误")
+	}
+
+	future := time.Now().Add(time.Hour)
+	codes, err := db.GenerateBetaCodes(1, 1, &future)
+	if err != nil {
+		t.Fatalf("生成内测码失败: %v", err)
+	}
+
+	// 手动改为已过期
+	if _, err := db.db.Exec(`UPDATE beta_codes SET expires_at = ? WHERE code = ?`, formatDBTime(past), codes[0]); err != nil {
+		t.Fatalf("更新过期时间失败: %v", err)
+	}
+	if valid, _ := db.ValidateBetaCode(codes[0]); valid {
+		t.Error("过期的内测码应无效")
+	}
+
+	list, _ := db.ListBetaCodes(BetaCodeFilterAll)
+	if len(list) != 1 || list[0].ExpiresAt == nil {
+		t.Errorf("列表应包含过期时间: %+v", list)
+	}
+}
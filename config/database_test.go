@@ -797,3 +797,157 @@ func TestConcurrentWritesWithWAL(t *testing.T) {
 		t.Errorf("并发写入失败次数过多: %d", errorCount)
 	}
 }
+
+// TestTraderIsPublic 测试交易员公开状态默认关闭，并可通过更新开启
+func TestTraderIsPublic(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	trader := &TraderRecord{ID: "trader-public", UserID: "test-user-001", Name: "P", AIModelID: "deepseek", ExchangeID: "binance", SystemPromptTemplate: "default"}
+	if err := db.CreateTrader(trader); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	traders, err := db.GetTraders("test-user-001")
+	if err != nil || len(traders) != 1 {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	if traders[0].IsPublic {
+		t.Error("新交易员默认不应公开")
+	}
+
+	trader.IsPublic = true
+	if err := db.UpdateTrader(trader); err != nil {
+		t.Fatalf("更新交易员失败: %v", err)
+	}
+	traders, _ = db.GetTraders("test-user-001")
+	if !traders[0].IsPublic {
+		t.Error("更新后应为公开")
+	}
+}
+
+// TestTraderRiskLimits 测试交易员风控上限、默认止盈止损和亏损冷却字段的保存与更新
+func TestTraderRiskLimits(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	trader := &TraderRecord{ID: "trader-risk", UserID: "test-user-001", Name: "R", AIModelID: "deepseek", ExchangeID: "binance",
+		MaxPositionValueUSDT: 500, MaxTotalExposurePct: 200, DefaultStopLossPct: 2, DefaultTakeProfitPct: 6,
+		CooldownMinutesAfterLoss: 30}
+	if err := db.CreateTrader(trader); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	traders, err := db.GetTraders("test-user-001")
+	if err != nil || len(traders) != 1 {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	if traders[0].MaxPositionValueUSDT != 500 || traders[0].MaxTotalExposurePct != 200 {
+		t.Errorf("风控上限 = (%v, %v), want (500, 200)", traders[0].MaxPositionValueUSDT, traders[0].MaxTotalExposurePct)
+	}
+	if traders[0].DefaultStopLossPct != 2 || traders[0].DefaultTakeProfitPct != 6 {
+		t.Errorf("默认止盈止损 = (%v, %v), want (2, 6)", traders[0].DefaultStopLossPct, traders[0].DefaultTakeProfitPct)
+	}
+	if traders[0].CooldownMinutesAfterLoss != 30 {
+		t.Errorf("亏损冷却 = %d, want 30", traders[0].CooldownMinutesAfterLoss)
+	}
+
+	trader.MaxPositionValueUSDT = 0
+	trader.MaxTotalExposurePct = 150
+	trader.DefaultTakeProfitPct = 0
+	trader.CooldownMinutesAfterLoss = 0
+	if err := db.UpdateTrader(trader); err != nil {
+		t.Fatalf("更新交易员失败: %v", err)
+	}
+	traders, _ = db.GetTraders("test-user-001")
+	if traders[0].MaxPositionValueUSDT != 0 || traders[0].MaxTotalExposurePct != 150 {
+		t.Errorf("更新后风控上限 = (%v, %v), want (0, 150)", traders[0].MaxPositionValueUSDT, traders[0].MaxTotalExposurePct)
+	}
+	if traders[0].DefaultStopLossPct != 2 || traders[0].DefaultTakeProfitPct != 0 {
+		t.Errorf("更新后默认止盈止损 = (%v, %v), want (2, 0)", traders[0].DefaultStopLossPct, traders[0].DefaultTakeProfitPct)
+	}
+	if traders[0].CooldownMinutesAfterLoss != 0 {
+		t.Errorf("更新后亏损冷却 = %d, want 0", traders[0].CooldownMinutesAfterLoss)
+	}
+}
+
+// TestTraderExecutionSettings 测试开仓执行方式的保存、更新和旧数据默认值
+func TestTraderExecutionSettings(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	trader := &TraderRecord{ID: "trader-exec", UserID: "test-user-001", Name: "E", AIModelID: "deepseek", ExchangeID: "binance",
+		ExecutionMode: "limit", LimitOffsetBps: 2.5, LimitTimeoutSeconds: 45, LimitFallback: "cancel"}
+	if err := db.CreateTrader(trader); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+
+	traders, err := db.GetTraders("test-user-001")
+	if err != nil || len(traders) != 1 {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	got := traders[0]
+	if got.ExecutionMode != "limit" || got.LimitOffsetBps != 2.5 || got.LimitTimeoutSeconds != 45 || got.LimitFallback != "cancel" {
+		t.Errorf("执行方式 = (%s, %v, %d, %s), want (limit, 2.5, 45, cancel)",
+			got.ExecutionMode, got.LimitOffsetBps, got.LimitTimeoutSeconds, got.LimitFallback)
+	}
+
+	trader.ExecutionMode = "market"
+	trader.LimitFallback = "market"
+	if err := db.UpdateTrader(trader); err != nil {
+		t.Fatalf("更新交易员失败: %v", err)
+	}
+	traders, _ = db.GetTraders("test-user-001")
+	if traders[0].ExecutionMode != "market" || traders[0].LimitFallback != "market" || traders[0].LimitTimeoutSeconds != 45 {
+		t.Errorf("更新后执行方式 = (%s, %s, %d), want (market, market, 45)",
+			traders[0].ExecutionMode, traders[0].LimitFallback, traders[0].LimitTimeoutSeconds)
+	}
+
+	// 升级前的旧记录（列为 NULL）读取为市价默认值
+	if _, err := db.db.Exec(`UPDATE traders SET execution_mode = NULL, limit_timeout_seconds = NULL, limit_fallback = NULL WHERE id = ?`, "trader-exec"); err != nil {
+		t.Fatalf("清空列失败: %v", err)
+	}
+	traders, _ = db.GetTraders("test-user-001")
+	if traders[0].ExecutionMode != "market" || traders[0].LimitTimeoutSeconds != 30 || traders[0].LimitFallback != "market" {
+		t.Errorf("旧记录默认值 = (%s, %d, %s), want (market, 30, market)",
+			traders[0].ExecutionMode, traders[0].LimitTimeoutSeconds, traders[0].LimitFallback)
+	}
+}
+
+// TestDeleteTrader_RemovesTraderData 测试删除交易员时一并删除其权益快照，且不影响其他交易员
+func TestDeleteTrader_RemovesTraderData(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	for _, id := range []string{"trader-del", "trader-keep"} {
+		if err := db.CreateTrader(&TraderRecord{ID: id, UserID: "test-user-001", Name: id, AIModelID: "deepseek", ExchangeID: "binance"}); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+	now := time.Now().UTC()
+	if err := db.SaveEquitySnapshots([]*EquitySnapshot{
+		{TraderID: "trader-del", UserID: "test-user-001", Timestamp: now, TotalEquity: 100},
+		{TraderID: "trader-keep", UserID: "test-user-001", Timestamp: now, TotalEquity: 200},
+	}); err != nil {
+		t.Fatalf("写入权益快照失败: %v", err)
+	}
+
+	if err := db.DeleteTrader("test-user-002", "trader-del"); err == nil {
+		t.Error("删除他人的交易员应返回错误")
+	}
+	if err := db.DeleteTrader("test-user-001", "trader-del"); err != nil {
+		t.Fatalf("删除交易员失败: %v", err)
+	}
+
+	traders, _ := db.GetTraders("test-user-001")
+	if len(traders) != 1 || traders[0].ID != "trader-keep" {
+		t.Errorf("删除后应只剩 trader-keep: %d", len(traders))
+	}
+	from, to := now.Add(-time.Hour), now.Add(time.Hour)
+	if snapshots, _ := db.GetEquitySnapshots("trader-del", from, to); len(snapshots) != 0 {
+		t.Errorf("已删除交易员的权益快照应被删除，剩余 %d 条", len(snapshots))
+	}
+	if snapshots, _ := db.GetEquitySnapshots("trader-keep", from, to); len(snapshots) != 1 {
+		t.Errorf("其他交易员的权益快照不应被删除，剩余 %d 条", len(snapshots))
+	}
+}
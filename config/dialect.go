@@ -0,0 +1,176 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// postgresDriverName PostgreSQL 使用的 database/sql 驱动名（pgx stdlib，见 postgres_driver.go）
+const postgresDriverName = "pgx"
+
+// dialect 数据库方言
+type dialect int
+
+const (
+	dialectSQLite dialect = iota
+	dialectPostgres
+)
+
+// String 方言名称（用于日志）
+func (d dialect) String() string {
+	if d == dialectPostgres {
+		return "PostgreSQL"
+	}
+	return "SQLite"
+}
+
+// dialectForDSN 按连接串选择数据库：postgres:// 或 postgresql:// 使用 PostgreSQL，其余视为 SQLite 文件路径
+func dialectForDSN(dsn string) dialect {
+	lower := strings.ToLower(dsn)
+	if strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://") {
+		return dialectPostgres
+	}
+	return dialectSQLite
+}
+
+// postgresDSN 未指定时区时使用 UTC（与 SQLite 的 CURRENT_TIMESTAMP 和 formatDBTime 一致）
+func postgresDSN(dsn string) string {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return dsn
+	}
+	q := u.Query()
+	if q.Get("timezone") == "" {
+		q.Set("timezone", "UTC")
+		u.RawQuery = q.Encode()
+	}
+	return u.String()
+}
+
+// RedactDSN 隐藏连接串中的密码（用于日志），SQLite 文件路径原样返回
+func RedactDSN(dsn string) string {
+	if dialectForDSN(dsn) != dialectPostgres {
+		return dsn
+	}
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "postgres://***"
+	}
+	return u.Redacted()
+}
+
+// rebind 将 ? 占位符改写为方言的占位符（PostgreSQL 为 $1, $2...，跳过字符串字面量中的 ?）
+func (d dialect) rebind(query string) string {
+	if d != dialectPostgres || !strings.Contains(query, "?") {
+		return query
+	}
+	var b strings.Builder
+	b.Grow(len(query) + 16)
+	n := 0
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'':
+			inString = !inString
+		case c == '?' && !inString:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// args PostgreSQL 的布尔字段与 SQLite 一样按 0/1 存储（SMALLINT），bool 参数转换为整数
+func (d dialect) args(args []interface{}) []interface{} {
+	if d != dialectPostgres {
+		return args
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		converted[i] = arg
+		if b, ok := arg.(bool); ok {
+			converted[i] = 0
+			if b {
+				converted[i] = 1
+			}
+		}
+	}
+	return converted
+}
+
+// isUniqueViolation 是否为唯一约束冲突（SQLite: UNIQUE constraint failed，PostgreSQL: 23505 duplicate key）
+func isUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint") || strings.Contains(msg, "duplicate key") || strings.Contains(msg, "23505")
+}
+
+// sqlDB 按方言改写SQL的数据库连接（SQL 统一使用 ? 占位符和 0/1 布尔值书写）
+type sqlDB struct {
+	*sql.DB
+	dialect dialect
+}
+
+// Exec 执行SQL
+func (db *sqlDB) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return db.DB.Exec(db.dialect.rebind(query), db.dialect.args(args)...)
+}
+
+// Query 查询多行
+func (db *sqlDB) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return db.DB.Query(db.dialect.rebind(query), db.dialect.args(args)...)
+}
+
+// QueryRow 查询单行
+func (db *sqlDB) QueryRow(query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRow(db.dialect.rebind(query), db.dialect.args(args)...)
+}
+
+// QueryRowContext 带 context 查询单行
+func (db *sqlDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return db.DB.QueryRowContext(ctx, db.dialect.rebind(query), db.dialect.args(args)...)
+}
+
+// Begin 开始事务
+func (db *sqlDB) Begin() (*sqlTx, error) {
+	tx, err := db.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	return &sqlTx{Tx: tx, dialect: db.dialect}, nil
+}
+
+// sqlTx 按方言改写SQL的事务
+type sqlTx struct {
+	*sql.Tx
+	dialect dialect
+}
+
+// Exec 执行SQL
+func (tx *sqlTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.Tx.Exec(tx.dialect.rebind(query), tx.dialect.args(args)...)
+}
+
+// Query 查询多行
+func (tx *sqlTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.Tx.Query(tx.dialect.rebind(query), tx.dialect.args(args)...)
+}
+
+// QueryRow 查询单行
+func (tx *sqlTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.Tx.QueryRow(tx.dialect.rebind(query), tx.dialect.args(args)...)
+}
+
+// Prepare 预编译SQL（执行时的参数不做布尔转换）
+func (tx *sqlTx) Prepare(query string) (*sql.Stmt, error) {
+	return tx.Tx.Prepare(tx.dialect.rebind(query))
+}
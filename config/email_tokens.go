@@ -0,0 +1,105 @@
+package config
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// 邮件令牌用途
+const (
+	EmailTokenVerify        = "verify_email"
+	EmailTokenPasswordReset = "password_reset"
+)
+
+// ErrEmailTokenInvalid 令牌不存在、已使用、已过期或用途不符
+var ErrEmailTokenInvalid = errors.New("链接无效或已过期")
+
+// hashEmailToken 令牌的存储形式（数据库中不保存明文）
+func hashEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateEmailToken 为用户生成一次性邮件令牌（同一用途之前未使用的令牌同时作废），返回令牌明文
+func (d *Database) CreateEmailToken(userID, purpose string, ttl time.Duration, now time.Time) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成令牌失败: %w", err)
+	}
+	token := hex.EncodeToString(buf)
+
+	err := d.writeTx(func(tx *sqlTx) error {
+		if _, err := tx.Exec(`
+			UPDATE email_tokens SET used_at = ? WHERE user_id = ? AND purpose = ? AND used_at = 0
+		`, now.UnixMilli(), userID, purpose); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`
+			INSERT INTO email_tokens (token_hash, user_id, purpose, created_at, expires_at)
+			VALUES (?, ?, ?, ?, ?)
+		`, hashEmailToken(token), userID, purpose, now.UnixMilli(), now.Add(ttl).UnixMilli())
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("保存令牌失败: %w", err)
+	}
+	return token, nil
+}
+
+// LookupEmailToken 查找有效令牌对应的用户（不消耗令牌）
+func (d *Database) LookupEmailToken(token, purpose string, now time.Time) (string, error) {
+	var userID string
+	err := d.db.QueryRow(`
+		SELECT user_id FROM email_tokens
+		WHERE token_hash = ? AND purpose = ? AND used_at = 0 AND expires_at > ?
+	`, hashEmailToken(token), purpose, now.UnixMilli()).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return "", ErrEmailTokenInvalid
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询令牌失败: %w", err)
+	}
+	return userID, nil
+}
+
+// ConsumeEmailToken 消耗令牌并返回对应的用户（并发使用同一令牌时只有一个成功）
+func (d *Database) ConsumeEmailToken(token, purpose string, now time.Time) (string, error) {
+	userID, err := d.LookupEmailToken(token, purpose, now)
+	if err != nil {
+		return "", err
+	}
+	result, err := d.db.Exec(`
+		UPDATE email_tokens SET used_at = ?
+		WHERE token_hash = ? AND purpose = ? AND used_at = 0 AND expires_at > ?
+	`, now.UnixMilli(), hashEmailToken(token), purpose, now.UnixMilli())
+	if err != nil {
+		return "", fmt.Errorf("使用令牌失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return "", ErrEmailTokenInvalid
+	}
+	return userID, nil
+}
+
+// CountEmailTokensSince 用户在 since 之后生成的令牌数量（用于限制发信频率）
+func (d *Database) CountEmailTokensSince(userID, purpose string, since time.Time) (int, error) {
+	var count int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM email_tokens WHERE user_id = ? AND purpose = ? AND created_at > ?
+	`, userID, purpose, since.UnixMilli()).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("统计令牌失败: %w", err)
+	}
+	return count, nil
+}
+
+// SetUserEmailVerified 标记用户邮箱已验证
+func (d *Database) SetUserEmailVerified(userID string) error {
+	_, err := d.db.Exec(`UPDATE users SET email_verified = ?, updated_at = CURRENT_TIMESTAMP WHERE id = ?`, true, userID)
+	return err
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEmailTokens 令牌只保存哈希、过期和被新令牌替代后失效、只能使用一次
+func TestEmailTokens(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/tokens.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	first, err := db.CreateEmailToken("user-1", EmailTokenPasswordReset, 30*time.Minute, now)
+	if err != nil {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+	var plain int
+	db.db.QueryRow(`SELECT COUNT(*) FROM email_tokens WHERE token_hash = ?`, first).Scan(&plain)
+	if plain != 0 {
+		t.Error("数据库中不应保存令牌明文")
+	}
+	if _, err := db.LookupEmailToken(first, EmailTokenVerify, now); err != ErrEmailTokenInvalid {
+		t.Error("用途不符的令牌应无效")
+	}
+	if _, err := db.LookupEmailToken(first, EmailTokenPasswordReset, now.Add(30*time.Minute)); err != ErrEmailTokenInvalid {
+		t.Error("恰好到期的令牌应无效")
+	}
+
+	second, err := db.CreateEmailToken("user-1", EmailTokenPasswordReset, 30*time.Minute, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("生成令牌失败: %v", err)
+	}
+	if _, err := db.LookupEmailToken(first, EmailTokenPasswordReset, now.Add(time.Minute)); err != ErrEmailTokenInvalid {
+		t.Error("生成新令牌后旧令牌应失效")
+	}
+	if userID, err := db.ConsumeEmailToken(second, EmailTokenPasswordReset, now.Add(2*time.Minute)); err != nil || userID != "user-1" {
+		t.Fatalf("使用令牌失败: %v", err)
+	}
+	if _, err := db.ConsumeEmailToken(second, EmailTokenPasswordReset, now.Add(2*time.Minute)); err != ErrEmailTokenInvalid {
+		t.Error("令牌只能使用一次")
+	}
+	if n, _ := db.CountEmailTokensSince("user-1", EmailTokenPasswordReset, now.Add(-time.Hour)); n != 2 {
+		t.Errorf("应统计到2个令牌，实际 %d", n)
+	}
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// EquitySnapshotRaw 采样得到的原始快照
+	EquitySnapshotRaw = "raw"
+	// EquitySnapshotHourly 超过保留期后按小时聚合的快照（每小时保留最后一条）
+	EquitySnapshotHourly = "hourly"
+)
+
+// EquitySnapshot 交易员账户权益快照（按固定间隔采样，与决策周期无关）
+type EquitySnapshot struct {
+	ID               int64     `json:"id"`
+	TraderID         string    `json:"trader_id"`
+	UserID           string    `json:"user_id"`
+	Timestamp        time.Time `json:"timestamp"`
+	TotalEquity      float64   `json:"total_equity"`      // 账户净值（wallet + unrealized）
+	WalletBalance    float64   `json:"wallet_balance"`    // 钱包余额（不含未实现盈亏）
+	AvailableBalance float64   `json:"available_balance"` // 可用余额
+	InitialBalance   float64   `json:"initial_balance"`   // 采样时的初始余额（用于计算历史盈亏百分比）
+	TotalPnL         float64   `json:"total_pnl"`         // 总盈亏（相对初始余额）
+	TotalPnLPct      float64   `json:"total_pnl_pct"`     // 总盈亏百分比
+	PositionCount    int       `json:"position_count"`    // 持仓数量
+	MarginUsedPct    float64   `json:"margin_used_pct"`   // 保证金使用率
+	Resolution       string    `json:"resolution"`        // raw 或 hourly
+}
+
+// SaveEquitySnapshots 批量写入权益快照（单个事务）
+func (d *Database) SaveEquitySnapshots(snapshots []*EquitySnapshot) error {
+	if len(snapshots) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO equity_snapshots (trader_id, user_id, timestamp, total_equity, wallet_balance, available_balance, initial_balance,
+			total_pnl, total_pnl_pct, position_count, margin_used_pct, resolution)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("准备权益快照语句失败: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, s := range snapshots {
+		timestamp := s.Timestamp
+		if timestamp.IsZero() {
+			timestamp = time.Now()
+		}
+		resolution := s.Resolution
+		if resolution == "" {
+			resolution = EquitySnapshotRaw
+		}
+		if _, err := stmt.Exec(s.TraderID, s.UserID, formatDBTime(timestamp), s.TotalEquity, s.WalletBalance, s.AvailableBalance, s.InitialBalance,
+			s.TotalPnL, s.TotalPnLPct, s.PositionCount, s.MarginUsedPct, resolution); err != nil {
+			return fmt.Errorf("写入权益快照失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// GetEquitySnapshots 查询交易员在 [from, to) 内的权益快照（按时间正序）
+func (d *Database) GetEquitySnapshots(traderID string, from, to time.Time) ([]*EquitySnapshot, error) {
+	rows, err := d.db.Query(`
+		SELECT id, trader_id, user_id, timestamp, total_equity, wallet_balance, available_balance, initial_balance,
+			total_pnl, total_pnl_pct, position_count, margin_used_pct, resolution
+		FROM equity_snapshots
+		WHERE trader_id = ? AND timestamp >= ? AND timestamp < ?
+		ORDER BY timestamp ASC, id ASC
+	`, traderID, formatDBTime(from), formatDBTime(to))
+	if err != nil {
+		return nil, fmt.Errorf("查询权益快照失败: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := make([]*EquitySnapshot, 0)
+	for rows.Next() {
+		var s EquitySnapshot
+		if err := rows.Scan(&s.ID, &s.TraderID, &s.UserID, &s.Timestamp, &s.TotalEquity, &s.WalletBalance, &s.AvailableBalance, &s.InitialBalance,
+			&s.TotalPnL, &s.TotalPnLPct, &s.PositionCount, &s.MarginUsedPct, &s.Resolution); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, &s)
+	}
+	return snapshots, rows.Err()
+}
+
+// CompactEquitySnapshots 将 before 之前的原始快照聚合为每小时一条（保留每小时最后一条），返回删除的原始快照数。
+// before 会向下取整到整点，保证每个小时只被聚合一次
+func (d *Database) CompactEquitySnapshots(before time.Time) (int64, error) {
+	cutoff := formatDBTime(before.UTC().Truncate(time.Hour))
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO equity_snapshots (trader_id, user_id, timestamp, total_equity, wallet_balance, available_balance, initial_balance,
+			total_pnl, total_pnl_pct, position_count, margin_used_pct, resolution)
+		SELECT s.trader_id, s.user_id, s.timestamp, s.total_equity, s.wallet_balance, s.available_balance, s.initial_balance,
+			s.total_pnl, s.total_pnl_pct, s.position_count, s.margin_used_pct, ?
+		FROM equity_snapshots s
+		WHERE s.resolution = ? AND s.timestamp < ?
+		  AND s.id = (
+			SELECT s2.id FROM equity_snapshots s2
+			WHERE s2.trader_id = s.trader_id AND s2.resolution = s.resolution
+			  AND substr(CAST(s2.timestamp AS TEXT), 1, 13) = substr(CAST(s.timestamp AS TEXT), 1, 13)
+			ORDER BY s2.timestamp DESC, s2.id DESC LIMIT 1
+		  )
+	`, EquitySnapshotHourly, EquitySnapshotRaw, cutoff); err != nil {
+		return 0, fmt.Errorf("聚合权益快照失败: %w", err)
+	}
+
+	result, err := tx.Exec(`DELETE FROM equity_snapshots WHERE resolution = ? AND timestamp < ?`, EquitySnapshotRaw, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("删除过期权益快照失败: %w", err)
+	}
+	deleted, _ := result.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return deleted, nil
+}
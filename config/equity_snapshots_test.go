@@ -0,0 +1,70 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEquitySnapshots_SaveAndCompact 测试权益快照写入、查询以及过期原始快照按小时聚合
+func TestEquitySnapshots_SaveAndCompact(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	old := time.Date(2026, 10, 1, 10, 0, 0, 0, time.UTC)
+	recent := time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC)
+	snapshots := []*EquitySnapshot{
+		{TraderID: "t1", UserID: "test-user-001", Timestamp: old.Add(10 * time.Minute), TotalEquity: 100},
+		{TraderID: "t1", UserID: "test-user-001", Timestamp: old.Add(50 * time.Minute), TotalEquity: 110},
+		{TraderID: "t1", UserID: "test-user-001", Timestamp: old.Add(70 * time.Minute), TotalEquity: 120},
+		{TraderID: "t2", UserID: "test-user-002", Timestamp: old.Add(20 * time.Minute), TotalEquity: 500},
+		{TraderID: "t1", UserID: "test-user-001", Timestamp: recent, TotalEquity: 130, PositionCount: 2},
+	}
+	if err := db.SaveEquitySnapshots(snapshots); err != nil {
+		t.Fatalf("写入权益快照失败: %v", err)
+	}
+
+	all, err := db.GetEquitySnapshots("t1", old, recent.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("查询权益快照失败: %v", err)
+	}
+	if len(all) != 4 || all[0].TotalEquity != 100 || all[3].PositionCount != 2 {
+		t.Fatalf("查询结果不正确: len=%d", len(all))
+	}
+	if all[0].Resolution != EquitySnapshotRaw {
+		t.Errorf("默认粒度应为 raw，实际 %q", all[0].Resolution)
+	}
+
+	// 截止时间不在整点时向下取整，11:00 之后的原始快照保留
+	deleted, err := db.CompactEquitySnapshots(old.Add(90 * time.Minute))
+	if err != nil {
+		t.Fatalf("聚合权益快照失败: %v", err)
+	}
+	if deleted != 3 {
+		t.Errorf("应删除3条原始快照，实际 %d", deleted)
+	}
+
+	all, _ = db.GetEquitySnapshots("t1", old, recent.Add(time.Hour))
+	if len(all) != 3 {
+		t.Fatalf("聚合后 t1 应剩3条快照，实际 %d", len(all))
+	}
+	if all[0].Resolution != EquitySnapshotHourly || all[0].TotalEquity != 110 {
+		t.Errorf("10点应保留最后一条作为小时数据，实际 %s %.0f", all[0].Resolution, all[0].TotalEquity)
+	}
+	if all[1].Resolution != EquitySnapshotRaw || all[1].TotalEquity != 120 {
+		t.Errorf("截止时间之后的原始快照应保留，实际 %s %.0f", all[1].Resolution, all[1].TotalEquity)
+	}
+
+	others, _ := db.GetEquitySnapshots("t2", old, recent)
+	if len(others) != 1 || others[0].Resolution != EquitySnapshotHourly || others[0].UserID != "test-user-002" {
+		t.Errorf("t2 应聚合为1条小时数据: %+v", others)
+	}
+
+	// 重复聚合不会产生重复的小时数据
+	if deleted, _ := db.CompactEquitySnapshots(old.Add(90 * time.Minute)); deleted != 0 {
+		t.Errorf("重复聚合不应删除数据，实际 %d", deleted)
+	}
+	all, _ = db.GetEquitySnapshots("t1", old, recent.Add(time.Hour))
+	if len(all) != 3 {
+		t.Errorf("重复聚合后 t1 应仍为3条，实际 %d", len(all))
+	}
+}
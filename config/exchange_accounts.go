@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+)
+
+// DefaultExchangeAccountID 交易所默认账户ID（多账户之前的单一配置迁移为默认账户）
+const DefaultExchangeAccountID = "default"
+
+// exchangeAccountIDOrDefault 账户ID为空时使用默认账户
+func exchangeAccountIDOrDefault(accountID string) string {
+	if accountID == "" {
+		return DefaultExchangeAccountID
+	}
+	return accountID
+}
+
+// exchangeNameAndType 根据交易所ID确定显示名称和类型
+func exchangeNameAndType(id string) (name, typ string) {
+	switch id {
+	case "binance":
+		return "Binance Futures", "cex"
+	case "bybit":
+		return "Bybit", "cex"
+	case "hyperliquid":
+		return "Hyperliquid", "dex"
+	case "aster":
+		return "Aster DEX", "dex"
+	default:
+		return id + " Exchange", "cex"
+	}
+}
+
+// FindExchangeAccount 在交易所配置列表中查找指定交易所的账户（accountID 为空时查找默认账户）
+func FindExchangeAccount(exchanges []*ExchangeConfig, exchangeID, accountID string) *ExchangeConfig {
+	accountID = exchangeAccountIDOrDefault(accountID)
+	for _, exchange := range exchanges {
+		if exchange.ID == exchangeID && exchangeAccountIDOrDefault(exchange.AccountID) == accountID {
+			return exchange
+		}
+	}
+	return nil
+}
+
+// CreateExchangeAccount 为交易所新建一个账户，返回生成的账户ID
+func (d *Database) CreateExchangeAccount(userID, id, label string, enabled bool, apiKey, secretKey string, testnet bool, hyperliquidWalletAddr, asterUser, asterSigner, asterPrivateKey string) (string, error) {
+	accountID := fmt.Sprintf("%s_%s", id, uuid.New().String()[:8])
+	name, typ := exchangeNameAndType(id)
+
+	_, err := d.db.Exec(`
+		INSERT INTO exchanges (id, user_id, account_id, label, name, type, enabled, api_key, secret_key, testnet,
+		                       hyperliquid_wallet_addr, aster_user, aster_signer, aster_private_key)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, id, userID, accountID, label, name, typ, enabled,
+		d.encryptSensitiveData(apiKey), d.encryptSensitiveData(secretKey), testnet,
+		hyperliquidWalletAddr, asterUser, asterSigner, d.encryptSensitiveData(asterPrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("创建交易所账户失败: %w", err)
+	}
+
+	log.Printf("🆕 用户 %s 新建交易所账户: %s/%s (%s)", userID, id, accountID, label)
+	return accountID, nil
+}
+
+// DeleteExchangeAccount 删除交易所账户（调用方需先确认没有交易员使用该账户）
+func (d *Database) DeleteExchangeAccount(userID, id, accountID string) error {
+	result, err := d.db.Exec(`
+		DELETE FROM exchanges WHERE user_id = ? AND id = ? AND account_id = ?
+	`, userID, id, exchangeAccountIDOrDefault(accountID))
+	if err != nil {
+		return fmt.Errorf("删除交易所账户失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("交易所账户不存在: %s/%s", id, accountID)
+	}
+	return nil
+}
+
+// UpdateExchangeTradePermission 记录检测到的API密钥合约交易权限（enabled/disabled/unknown）
+func (d *Database) UpdateExchangeTradePermission(userID, id, accountID, permission string) error {
+	_, err := d.db.Exec(`
+		UPDATE exchanges SET trade_permission = ? WHERE user_id = ? AND id = ? AND account_id = ?
+	`, permission, userID, id, exchangeAccountIDOrDefault(accountID))
+	if err != nil {
+		return fmt.Errorf("更新交易所API权限失败: %w", err)
+	}
+	return nil
+}
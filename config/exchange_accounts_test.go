@@ -0,0 +1,165 @@
+package config
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// TestExchangeAccounts_MultipleAccountsPerExchange 测试同一交易所配置多个账户，交易员按账户ID引用各自的密钥
+func TestExchangeAccounts_MultipleAccountsPerExchange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	if err := db.UpdateExchange(userID, "binance", true, "main-key", "main-secret", false, "", "", "", ""); err != nil {
+		t.Fatalf("保存默认账户失败: %v", err)
+	}
+	subID, err := db.CreateExchangeAccount(userID, "binance", "子账户-网格", true, "sub-key", "sub-secret", false, "", "", "", "")
+	if err != nil {
+		t.Fatalf("新建账户失败: %v", err)
+	}
+	if subID == DefaultExchangeAccountID || subID == "" {
+		t.Fatalf("新建账户应生成独立的账户ID，实际: %q", subID)
+	}
+
+	exchanges, err := db.GetExchanges(userID)
+	if err != nil {
+		t.Fatalf("获取交易所配置失败: %v", err)
+	}
+	main := FindExchangeAccount(exchanges, "binance", "")
+	sub := FindExchangeAccount(exchanges, "binance", subID)
+	if main == nil || sub == nil {
+		t.Fatalf("应同时存在默认账户和子账户: %+v", exchanges)
+	}
+	if main.AccountID != DefaultExchangeAccountID || main.APIKey != "main-key" {
+		t.Errorf("默认账户 = (%s, %s), want (default, main-key)", main.AccountID, main.APIKey)
+	}
+	if sub.Label != "子账户-网格" || sub.APIKey != "sub-key" || sub.SecretKey != "sub-secret" {
+		t.Errorf("子账户 = (%s, %s, %s)", sub.Label, sub.APIKey, sub.SecretKey)
+	}
+
+	// 更新子账户不影响默认账户，备注名为空时保留原值
+	if err := db.UpdateExchangeAccount(userID, "binance", subID, "", true, "sub-key-2", "", true, "", "", "", ""); err != nil {
+		t.Fatalf("更新子账户失败: %v", err)
+	}
+	exchanges, _ = db.GetExchanges(userID)
+	main = FindExchangeAccount(exchanges, "binance", DefaultExchangeAccountID)
+	sub = FindExchangeAccount(exchanges, "binance", subID)
+	if main.APIKey != "main-key" || main.Testnet {
+		t.Errorf("默认账户被修改: (%s, %v)", main.APIKey, main.Testnet)
+	}
+	if sub.APIKey != "sub-key-2" || sub.SecretKey != "sub-secret" || !sub.Testnet || sub.Label != "子账户-网格" {
+		t.Errorf("子账户更新结果 = (%s, %s, %v, %s)", sub.APIKey, sub.SecretKey, sub.Testnet, sub.Label)
+	}
+
+	// 交易员引用子账户；未指定账户的交易员使用默认账户
+	if err := db.CreateAIModel(userID, "test-user-001_deepseek", "DeepSeek", "deepseek", true, "ai-key", ""); err != nil {
+		t.Fatalf("配置AI模型失败: %v", err)
+	}
+	traders := []*TraderRecord{
+		{ID: "trader-sub", UserID: userID, Name: "Sub", AIModelID: "test-user-001_deepseek", ExchangeID: "binance", ExchangeAccountID: subID},
+		{ID: "trader-main", UserID: userID, Name: "Main", AIModelID: "test-user-001_deepseek", ExchangeID: "binance"},
+	}
+	for _, trader := range traders {
+		if err := db.CreateTrader(trader); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+	record, _, exchange, err := db.GetTraderConfig(userID, "trader-sub")
+	if err != nil {
+		t.Fatalf("获取交易员配置失败: %v", err)
+	}
+	if record.ExchangeAccountID != subID || exchange.AccountID != subID || exchange.APIKey != "sub-key-2" {
+		t.Errorf("子账户交易员 = (%s, %s, %s)", record.ExchangeAccountID, exchange.AccountID, exchange.APIKey)
+	}
+	record, _, exchange, err = db.GetTraderConfig(userID, "trader-main")
+	if err != nil {
+		t.Fatalf("获取交易员配置失败: %v", err)
+	}
+	if record.ExchangeAccountID != DefaultExchangeAccountID || exchange.APIKey != "main-key" {
+		t.Errorf("默认账户交易员 = (%s, %s)", record.ExchangeAccountID, exchange.APIKey)
+	}
+
+	if err := db.DeleteExchangeAccount(userID, "binance", subID); err != nil {
+		t.Fatalf("删除子账户失败: %v", err)
+	}
+	if err := db.DeleteExchangeAccount(userID, "binance", subID); err == nil {
+		t.Error("重复删除应返回错误")
+	}
+	exchanges, _ = db.GetExchanges(userID)
+	if FindExchangeAccount(exchanges, "binance", subID) != nil || FindExchangeAccount(exchanges, "binance", "") == nil {
+		t.Errorf("删除子账户后配置 = %+v", exchanges)
+	}
+}
+
+// TestMigrateExchangesTable_LegacyConfigBecomesDefaultAccount 测试旧版单账户配置迁移为默认账户
+func TestMigrateExchangesTable_LegacyConfigBecomesDefaultAccount(t *testing.T) {
+	dbPath := t.TempDir() + "/legacy.db"
+
+	// 旧版结构：主键 (id, user_id)，Hyperliquid 字段由 ALTER TABLE 追加在末尾
+	legacy, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("打开旧数据库失败: %v", err)
+	}
+	for _, query := range []string{
+		`CREATE TABLE exchanges (
+			id TEXT NOT NULL,
+			user_id TEXT NOT NULL DEFAULT 'default',
+			name TEXT NOT NULL,
+			type TEXT NOT NULL,
+			enabled BOOLEAN DEFAULT 0,
+			api_key TEXT DEFAULT '',
+			secret_key TEXT DEFAULT '',
+			testnet BOOLEAN DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id, user_id)
+		)`,
+		`ALTER TABLE exchanges ADD COLUMN hyperliquid_wallet_addr TEXT DEFAULT ''`,
+		`INSERT INTO exchanges (id, user_id, name, type, enabled, api_key, secret_key, hyperliquid_wallet_addr)
+			VALUES ('hyperliquid', 'test-user-001', 'Hyperliquid', 'dex', 1, 'legacy-key', '', '0xLegacy')`,
+	} {
+		if _, err := legacy.Exec(query); err != nil {
+			t.Fatalf("构造旧数据库失败: %v", err)
+		}
+	}
+	legacy.Close()
+
+	db, err := NewDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	exchanges, err := db.GetExchanges("test-user-001")
+	if err != nil {
+		t.Fatalf("获取交易所配置失败: %v", err)
+	}
+	exchange := FindExchangeAccount(exchanges, "hyperliquid", DefaultExchangeAccountID)
+	if exchange == nil {
+		t.Fatalf("旧配置应迁移为默认账户: %+v", exchanges)
+	}
+	if exchange.APIKey != "legacy-key" || exchange.HyperliquidWalletAddr != "0xLegacy" || !exchange.Enabled {
+		t.Errorf("迁移后的配置 = (%s, %s, %v)", exchange.APIKey, exchange.HyperliquidWalletAddr, exchange.Enabled)
+	}
+
+	// 迁移后可以为同一交易所新建第二个账户
+	if _, err := db.CreateExchangeAccount("test-user-001", "hyperliquid", "第二钱包", true, "key-2", "", false, "0xSecond", "", "", ""); err != nil {
+		t.Fatalf("迁移后新建账户失败: %v", err)
+	}
+	exchanges, _ = db.GetExchanges("test-user-001")
+	if len(exchanges) != 2 || exchanges[0].AccountID != DefaultExchangeAccountID {
+		t.Errorf("应有2个账户且默认账户在前: %+v", exchanges)
+	}
+
+	// 再次打开不会重复迁移
+	db.Close()
+	db, err = NewDatabase(dbPath)
+	if err != nil {
+		t.Fatalf("重新打开数据库失败: %v", err)
+	}
+	exchanges, _ = db.GetExchanges("test-user-001")
+	if len(exchanges) != 2 {
+		t.Errorf("重新打开后账户数 = %d, want 2", len(exchanges))
+	}
+}
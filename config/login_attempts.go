@@ -0,0 +1,180 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	// LoginScopeAccount 按账号（小写邮箱）统计登录失败
+	LoginScopeAccount = "account"
+	// LoginScopeIP 按客户端IP统计登录失败
+	LoginScopeIP = "ip"
+
+	// loginRetryDelayMax 渐进延迟上限
+	loginRetryDelayMax = 30 * time.Second
+)
+
+// LoginLockoutPolicy 登录失败锁定策略
+type LoginLockoutPolicy struct {
+	MaxFailures int           // 窗口内失败次数达到该值后锁定
+	Window      time.Duration // 失败计数窗口（自窗口内第一次失败起算）
+	Lockout     time.Duration // 锁定时长
+}
+
+// LoginThrottle 某个账号或IP的登录失败状态
+type LoginThrottle struct {
+	Scope         string    `json:"scope"`
+	Subject       string    `json:"subject"`
+	Failures      int       `json:"failures"`
+	WindowStart   time.Time `json:"window_start"`
+	NextAttemptAt time.Time `json:"next_attempt_at"` // 渐进延迟：在此之前的尝试直接拒绝
+	LockedUntil   time.Time `json:"locked_until"`
+	JustLocked    bool      `json:"-"` // 本次失败触发了锁定
+}
+
+// Blocked 当前是否禁止尝试登录（锁定中或渐进延迟未结束），返回剩余等待时间
+func (t *LoginThrottle) Blocked(now time.Time) (bool, time.Duration) {
+	if t == nil {
+		return false, 0
+	}
+	until := t.NextAttemptAt
+	if t.LockedUntil.After(until) {
+		until = t.LockedUntil
+	}
+	if now.Before(until) {
+		return true, until.Sub(now)
+	}
+	return false, 0
+}
+
+// Locked 当前是否处于锁定状态
+func (t *LoginThrottle) Locked(now time.Time) bool {
+	return t != nil && now.Before(t.LockedUntil)
+}
+
+// loginRetryDelay 第 failures 次失败后到下次允许尝试的延迟：第一次失败不延迟，之后从1秒开始翻倍，最长30秒
+func loginRetryDelay(failures int) time.Duration {
+	if failures <= 1 {
+		return 0
+	}
+	if failures > 7 {
+		return loginRetryDelayMax
+	}
+	return min(time.Second<<(failures-2), loginRetryDelayMax)
+}
+
+// unixMilli 时间转 Unix 毫秒（零值为0）
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+// fromUnixMilli Unix 毫秒转时间（0为零值）
+func fromUnixMilli(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms)
+}
+
+// scanLoginThrottle 读取一行登录失败状态
+func scanLoginThrottle(row interface{ Scan(...interface{}) error }) (*LoginThrottle, error) {
+	t := &LoginThrottle{}
+	var windowStart, nextAttemptAt, lockedUntil int64
+	if err := row.Scan(&t.Scope, &t.Subject, &t.Failures, &windowStart, &nextAttemptAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+	t.WindowStart = fromUnixMilli(windowStart)
+	t.NextAttemptAt = fromUnixMilli(nextAttemptAt)
+	t.LockedUntil = fromUnixMilli(lockedUntil)
+	return t, nil
+}
+
+const loginThrottleColumns = `scope, subject, failures, window_start, next_attempt_at, locked_until`
+
+// GetLoginThrottle 获取账号或IP的登录失败状态，没有失败记录时返回 nil
+func (d *Database) GetLoginThrottle(scope, subject string) (*LoginThrottle, error) {
+	t, err := scanLoginThrottle(d.db.QueryRow(`SELECT `+loginThrottleColumns+` FROM login_attempts WHERE scope = ? AND subject = ?`, scope, subject))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取登录失败记录失败: %w", err)
+	}
+	return t, nil
+}
+
+// RecordLoginFailure 记录一次登录失败：窗口过期后重新计数，达到上限时锁定，返回更新后的状态
+func (d *Database) RecordLoginFailure(scope, subject string, now time.Time, policy LoginLockoutPolicy) (*LoginThrottle, error) {
+	var t *LoginThrottle
+	err := d.writeTx(func(tx *sqlTx) error {
+		existing, err := scanLoginThrottle(tx.QueryRow(`SELECT `+loginThrottleColumns+` FROM login_attempts WHERE scope = ? AND subject = ?`, scope, subject))
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		t = &LoginThrottle{Scope: scope, Subject: subject, WindowStart: now}
+		// 窗口未过期，或仍处于锁定中时累计计数
+		if existing != nil && (now.Sub(existing.WindowStart) < policy.Window || existing.Locked(now)) {
+			t.Failures = existing.Failures
+			t.WindowStart = existing.WindowStart
+			t.LockedUntil = existing.LockedUntil
+		}
+		wasLocked := t.Locked(now)
+
+		t.Failures++
+		t.NextAttemptAt = now.Add(loginRetryDelay(t.Failures))
+		if policy.MaxFailures > 0 && t.Failures >= policy.MaxFailures && !wasLocked {
+			t.LockedUntil = now.Add(policy.Lockout)
+			t.JustLocked = true
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO login_attempts (scope, subject, failures, window_start, next_attempt_at, locked_until)
+			VALUES (?, ?, ?, ?, ?, ?)
+			ON CONFLICT(scope, subject) DO UPDATE SET
+				failures = excluded.failures,
+				window_start = excluded.window_start,
+				next_attempt_at = excluded.next_attempt_at,
+				locked_until = excluded.locked_until
+		`, scope, subject, t.Failures, unixMilli(t.WindowStart), unixMilli(t.NextAttemptAt), unixMilli(t.LockedUntil))
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("记录登录失败失败: %w", err)
+	}
+	return t, nil
+}
+
+// ClearLoginThrottle 清除账号或IP的登录失败记录（登录成功或管理员解除锁定），返回是否存在记录
+func (d *Database) ClearLoginThrottle(scope, subject string) (bool, error) {
+	result, err := d.db.Exec(`DELETE FROM login_attempts WHERE scope = ? AND subject = ?`, scope, subject)
+	if err != nil {
+		return false, fmt.Errorf("清除登录失败记录失败: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// ListLoginLockouts 列出当前处于锁定状态的账号和IP
+func (d *Database) ListLoginLockouts(now time.Time) ([]*LoginThrottle, error) {
+	rows, err := d.db.Query(`SELECT `+loginThrottleColumns+` FROM login_attempts WHERE locked_until > ? ORDER BY locked_until DESC`, now.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("获取登录锁定列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	lockouts := []*LoginThrottle{}
+	for rows.Next() {
+		t, err := scanLoginThrottle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("读取登录锁定记录失败: %w", err)
+		}
+		lockouts = append(lockouts, t)
+	}
+	return lockouts, rows.Err()
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestLoginThrottle_WindowAndLockout 失败计数窗口和锁定时长的边界
+func TestLoginThrottle_WindowAndLockout(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/login.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	policy := LoginLockoutPolicy{MaxFailures: 3, Window: 10 * time.Minute, Lockout: 5 * time.Minute}
+	start := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	fail := func(at time.Time) *LoginThrottle {
+		t.Helper()
+		throttle, err := db.RecordLoginFailure(LoginScopeAccount, "a@test.com", at, policy)
+		if err != nil {
+			t.Fatalf("记录登录失败失败: %v", err)
+		}
+		return throttle
+	}
+
+	// 窗口到期前一刻的失败仍然累计，恰好到期时重新计数
+	fail(start)
+	if got := fail(start.Add(policy.Window - time.Millisecond)); got.Failures != 2 {
+		t.Errorf("窗口内应累计到2次，实际 %d", got.Failures)
+	}
+	if got := fail(start.Add(policy.Window)); got.Failures != 1 || !got.WindowStart.Equal(start.Add(policy.Window)) {
+		t.Errorf("窗口到期后应重新计数，实际 %d 次，窗口起点 %v", got.Failures, got.WindowStart)
+	}
+
+	// 第二次失败后需等待1秒
+	second := start.Add(policy.Window + time.Minute)
+	throttle := fail(second)
+	if blocked, _ := throttle.Blocked(second.Add(999 * time.Millisecond)); !blocked {
+		t.Error("渐进延迟结束前应拦截")
+	}
+	if blocked, _ := throttle.Blocked(second.Add(time.Second)); blocked {
+		t.Error("渐进延迟结束后应放行")
+	}
+
+	lockedAt := second.Add(time.Minute)
+	throttle = fail(lockedAt)
+	if !throttle.JustLocked || !throttle.LockedUntil.Equal(lockedAt.Add(policy.Lockout)) {
+		t.Fatalf("达到上限应锁定 %v，实际 %+v", policy.Lockout, throttle)
+	}
+	stored, err := db.GetLoginThrottle(LoginScopeAccount, "a@test.com")
+	if err != nil || stored == nil {
+		t.Fatalf("读取登录失败记录失败: %v", err)
+	}
+	if blocked, _ := stored.Blocked(throttle.LockedUntil.Add(-time.Millisecond)); !blocked {
+		t.Error("锁定结束前一刻应拦截")
+	}
+	if blocked, _ := stored.Blocked(throttle.LockedUntil); blocked {
+		t.Error("锁定恰好结束时应放行")
+	}
+	if lockouts, _ := db.ListLoginLockouts(throttle.LockedUntil); len(lockouts) != 0 {
+		t.Errorf("锁定结束后不应出现在锁定列表中: %v", lockouts)
+	}
+
+	// 锁定结束但窗口未过期时再失败一次立即重新锁定
+	if got := fail(throttle.LockedUntil); !got.JustLocked || got.Failures != 4 {
+		t.Errorf("窗口内锁定结束后再次失败应重新锁定，实际 %+v", got)
+	}
+	if lockouts, _ := db.ListLoginLockouts(throttle.LockedUntil); len(lockouts) != 1 {
+		t.Errorf("锁定列表应包含1条记录，实际 %d", len(lockouts))
+	}
+
+	if cleared, err := db.ClearLoginThrottle(LoginScopeAccount, "a@test.com"); err != nil || !cleared {
+		t.Fatalf("清除登录失败记录失败: %v", err)
+	}
+	if stored, _ := db.GetLoginThrottle(LoginScopeAccount, "a@test.com"); stored != nil {
+		t.Errorf("清除后不应存在记录: %+v", stored)
+	}
+}
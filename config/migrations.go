@@ -0,0 +1,242 @@
+package config
+
+import (
+	"fmt"
+	"log"
+)
+
+// schemaMigration 版本化的表结构变更，SQLite 和 PostgreSQL 各自提供SQL，在同一事务中执行并记录到 schema_migrations
+type schemaMigration struct {
+	version  int
+	name     string
+	sqlite   []string
+	postgres []string
+}
+
+// schemaMigrations 基线表结构（createTables / postgresSchema）之后的变更，按版本号递增追加，已发布的版本不要修改
+var schemaMigrations = []schemaMigration{
+	{
+		version:  1,
+		name:     "traders_user_index",
+		sqlite:   []string{`CREATE INDEX IF NOT EXISTS idx_traders_user ON traders(user_id)`},
+		postgres: []string{`CREATE INDEX IF NOT EXISTS idx_traders_user ON traders(user_id)`},
+	},
+	{
+		version: 2,
+		name:    "user_settings",
+		sqlite: []string{`CREATE TABLE IF NOT EXISTS user_settings (
+			user_id TEXT PRIMARY KEY,
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			display_currency TEXT NOT NULL DEFAULT 'USDT',
+			notifications TEXT NOT NULL DEFAULT '',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`},
+		postgres: []string{`CREATE TABLE IF NOT EXISTS user_settings (
+			user_id TEXT PRIMARY KEY,
+			timezone TEXT NOT NULL DEFAULT 'UTC',
+			display_currency TEXT NOT NULL DEFAULT 'USDT',
+			notifications TEXT NOT NULL DEFAULT '',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`},
+	},
+	{
+		version:  3,
+		name:     "login_attempts",
+		sqlite:   []string{loginAttemptsTable},
+		postgres: []string{loginAttemptsTable},
+	},
+	{
+		version: 4,
+		name:    "email_verification",
+		// 已有用户视为已验证邮箱
+		sqlite: []string{
+			`ALTER TABLE users ADD COLUMN email_verified BOOLEAN DEFAULT 0`,
+			`UPDATE users SET email_verified = 1`,
+			emailTokensTable,
+			`CREATE INDEX IF NOT EXISTS idx_email_tokens_user ON email_tokens(user_id, purpose)`,
+		},
+		postgres: []string{
+			`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified SMALLINT DEFAULT 0`,
+			`UPDATE users SET email_verified = 1`,
+			emailTokensTable,
+			`CREATE INDEX IF NOT EXISTS idx_email_tokens_user ON email_tokens(user_id, purpose)`,
+		},
+	},
+	{
+		version: 5,
+		name:    "notification_channels",
+		sqlite: []string{
+			fmt.Sprintf(notificationChannelsTable, "BOOLEAN"),
+			`CREATE INDEX IF NOT EXISTS idx_notification_channels_user ON notification_channels(user_id)`,
+		},
+		postgres: []string{
+			fmt.Sprintf(notificationChannelsTable, "SMALLINT"),
+			`CREATE INDEX IF NOT EXISTS idx_notification_channels_user ON notification_channels(user_id)`,
+		},
+	},
+	{
+		version: 6,
+		name:    "webhooks",
+		sqlite: []string{
+			fmt.Sprintf(webhooksTable, "BOOLEAN"),
+			`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id)`,
+			webhookDeliveriesTable,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at)`,
+		},
+		postgres: []string{
+			fmt.Sprintf(webhooksTable, "SMALLINT"),
+			`CREATE INDEX IF NOT EXISTS idx_webhooks_user ON webhooks(user_id)`,
+			webhookDeliveriesTable,
+			`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at)`,
+		},
+	},
+	{
+		version:  7,
+		name:     "performance_reports",
+		sqlite:   []string{performanceReportsTable, reportMarkersTable},
+		postgres: []string{performanceReportsTable, reportMarkersTable},
+	},
+}
+
+// loginAttemptsTable 登录失败计数（时间字段为 Unix 毫秒，两种数据库通用）
+const loginAttemptsTable = `CREATE TABLE IF NOT EXISTS login_attempts (
+	scope TEXT NOT NULL,
+	subject TEXT NOT NULL,
+	failures INTEGER NOT NULL DEFAULT 0,
+	window_start BIGINT NOT NULL DEFAULT 0,
+	next_attempt_at BIGINT NOT NULL DEFAULT 0,
+	locked_until BIGINT NOT NULL DEFAULT 0,
+	PRIMARY KEY (scope, subject)
+)`
+
+// emailTokensTable 邮件验证/重置密码令牌（只保存哈希，时间字段为 Unix 毫秒）
+const emailTokensTable = `CREATE TABLE IF NOT EXISTS email_tokens (
+	token_hash TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	purpose TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	expires_at BIGINT NOT NULL,
+	used_at BIGINT NOT NULL DEFAULT 0
+)`
+
+// notificationChannelsTable 用户通知通道（config 为加密后的通道参数，时间字段为 Unix 毫秒；%s 为布尔列类型）
+const notificationChannelsTable = `CREATE TABLE IF NOT EXISTS notification_channels (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	type TEXT NOT NULL,
+	name TEXT NOT NULL DEFAULT '',
+	enabled %s NOT NULL DEFAULT 1,
+	config TEXT NOT NULL,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`
+
+// webhooksTable 用户的出站 Webhook 订阅（secret 加密存储，events 为逗号分隔的事件类型，时间字段为 Unix 毫秒；%s 为布尔列类型）
+const webhooksTable = `CREATE TABLE IF NOT EXISTS webhooks (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL DEFAULT '',
+	enabled %s NOT NULL DEFAULT 1,
+	created_at BIGINT NOT NULL,
+	updated_at BIGINT NOT NULL
+)`
+
+// webhookDeliveriesTable 最终投递失败的 Webhook 请求（死信记录，时间字段为 Unix 毫秒）
+const webhookDeliveriesTable = `CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id TEXT PRIMARY KEY,
+	webhook_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	event TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	attempts INTEGER NOT NULL DEFAULT 0,
+	created_at BIGINT NOT NULL
+)`
+
+// performanceReportsTable 定时表现报告（日期为用户时区的 YYYY-MM-DD，时间字段为 Unix 毫秒）
+const performanceReportsTable = `CREATE TABLE IF NOT EXISTS performance_reports (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL,
+	period TEXT NOT NULL,
+	period_start TEXT NOT NULL,
+	period_end TEXT NOT NULL,
+	timezone TEXT NOT NULL,
+	data TEXT NOT NULL,
+	text TEXT NOT NULL DEFAULT '',
+	html TEXT NOT NULL DEFAULT '',
+	created_at BIGINT NOT NULL,
+	UNIQUE(user_id, period, period_start)
+)`
+
+// reportMarkersTable 每个用户每种周期最近一次处理过的报告（调度器重启后据此跳过已处理的周期）
+const reportMarkersTable = `CREATE TABLE IF NOT EXISTS report_markers (
+	user_id TEXT NOT NULL,
+	period TEXT NOT NULL,
+	period_start TEXT NOT NULL,
+	updated_at BIGINT NOT NULL,
+	PRIMARY KEY (user_id, period)
+)`
+
+// applyMigrations 按版本号顺序执行尚未执行的迁移（PostgreSQL 下多个实例同时启动时通过咨询锁串行执行）
+func (d *Database) applyMigrations() error {
+	if _, err := d.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`); err != nil {
+		return fmt.Errorf("创建schema_migrations表失败: %w", err)
+	}
+
+	for _, m := range schemaMigrations {
+		if err := d.applyMigration(m); err != nil {
+			return fmt.Errorf("执行迁移 %d_%s 失败: %w", m.version, m.name, err)
+		}
+	}
+	return nil
+}
+
+// applyMigration 在事务中执行单个迁移，已执行过的跳过
+func (d *Database) applyMigration(m schemaMigration) error {
+	statements := m.sqlite
+	if d.db.dialect == dialectPostgres {
+		statements = m.postgres
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if d.db.dialect == dialectPostgres {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(?)`, postgresSchemaLockKey); err != nil {
+			return fmt.Errorf("获取迁移锁失败: %w", err)
+		}
+	}
+	var applied int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`, m.version).Scan(&applied); err != nil {
+		return err
+	}
+	if applied > 0 {
+		return nil
+	}
+
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, m.version, m.name); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	log.Printf("🔄 已执行数据库迁移 %d_%s", m.version, m.name)
+	return nil
+}
@@ -0,0 +1,151 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nofx/notify"
+
+	"github.com/google/uuid"
+)
+
+// MaxNotificationChannels 每个用户最多配置的通知通道数
+const MaxNotificationChannels = 10
+
+// ErrNotificationChannelNotFound 通知通道不存在或不属于该用户
+var ErrNotificationChannelNotFound = errors.New("通知通道不存在")
+
+// NotificationChannel 用户配置的通知通道（Config 包含凭证，存储时加密）
+type NotificationChannel struct {
+	ID        string               `json:"id"`
+	UserID    string               `json:"-"`
+	Type      string               `json:"type"` // telegram / discord / webhook
+	Name      string               `json:"name"`
+	Enabled   bool                 `json:"enabled"`
+	Config    notify.ChannelConfig `json:"config"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// Validate 校验通道类型和参数
+func (ch *NotificationChannel) Validate() error {
+	ch.Name = strings.TrimSpace(ch.Name)
+	if len([]rune(ch.Name)) > 50 {
+		return fmt.Errorf("通道名称不能超过50个字符")
+	}
+	return ch.Config.Validate(ch.Type)
+}
+
+// encodeChannelConfig 序列化并加密通道参数
+func (d *Database) encodeChannelConfig(cfg notify.ChannelConfig) (string, error) {
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("序列化通知通道配置失败: %w", err)
+	}
+	return d.encryptSensitiveData(string(data)), nil
+}
+
+// ListNotificationChannels 获取用户的全部通知通道（按创建时间排序）
+func (d *Database) ListNotificationChannels(userID string) ([]*NotificationChannel, error) {
+	rows, err := d.db.Query(`
+		SELECT id, type, name, enabled, config, created_at, updated_at
+		FROM notification_channels WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取通知通道失败: %w", err)
+	}
+	defer rows.Close()
+
+	channels := make([]*NotificationChannel, 0)
+	for rows.Next() {
+		ch := &NotificationChannel{UserID: userID}
+		var encoded string
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&ch.ID, &ch.Type, &ch.Name, &ch.Enabled, &encoded, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("读取通知通道失败: %w", err)
+		}
+		if err := json.Unmarshal([]byte(d.decryptSensitiveData(encoded)), &ch.Config); err != nil {
+			return nil, fmt.Errorf("解析通知通道配置失败 [%s]: %w", ch.ID, err)
+		}
+		ch.CreatedAt = time.UnixMilli(createdAt).UTC()
+		ch.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+		channels = append(channels, ch)
+	}
+	return channels, rows.Err()
+}
+
+// GetNotificationChannel 获取用户的一个通知通道
+func (d *Database) GetNotificationChannel(userID, id string) (*NotificationChannel, error) {
+	channels, err := d.ListNotificationChannels(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range channels {
+		if ch.ID == id {
+			return ch, nil
+		}
+	}
+	return nil, ErrNotificationChannelNotFound
+}
+
+// CreateNotificationChannel 新建通知通道（生成ID和时间戳）
+func (d *Database) CreateNotificationChannel(ch *NotificationChannel, now time.Time) error {
+	encoded, err := d.encodeChannelConfig(ch.Config)
+	if err != nil {
+		return err
+	}
+	ch.ID = uuid.New().String()
+	ch.CreatedAt = now.UTC()
+	ch.UpdatedAt = now.UTC()
+
+	return d.writeTx(func(tx *sqlTx) error {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM notification_channels WHERE user_id = ?`, ch.UserID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= MaxNotificationChannels {
+			return fmt.Errorf("最多配置 %d 个通知通道", MaxNotificationChannels)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO notification_channels (id, user_id, type, name, enabled, config, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, ch.ID, ch.UserID, ch.Type, ch.Name, ch.Enabled, encoded, now.UnixMilli(), now.UnixMilli())
+		return err
+	})
+}
+
+// UpdateNotificationChannel 更新通知通道的名称、启用状态和参数（类型不可修改）
+func (d *Database) UpdateNotificationChannel(ch *NotificationChannel, now time.Time) error {
+	encoded, err := d.encodeChannelConfig(ch.Config)
+	if err != nil {
+		return err
+	}
+	result, err := d.db.Exec(`
+		UPDATE notification_channels SET name = ?, enabled = ?, config = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, ch.Name, ch.Enabled, encoded, now.UnixMilli(), ch.ID, ch.UserID)
+	if err != nil {
+		return fmt.Errorf("更新通知通道失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotificationChannelNotFound
+	}
+	ch.UpdatedAt = now.UTC()
+	return nil
+}
+
+// DeleteNotificationChannel 删除通知通道
+func (d *Database) DeleteNotificationChannel(userID, id string) error {
+	result, err := d.db.Exec(`DELETE FROM notification_channels WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("删除通知通道失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrNotificationChannelNotFound
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/crypto"
+	"nofx/notify"
+)
+
+// TestNotificationChannels 通道参数加密存储、按用户隔离、更新删除和数量上限
+func TestNotificationChannels(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "test-data-key")
+	db, err := NewDatabase(t.TempDir() + "/notify.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	cs, err := crypto.NewCryptoService(t.TempDir() + "/rsa_key")
+	if err != nil {
+		t.Fatalf("初始化加密服务失败: %v", err)
+	}
+	db.SetCryptoService(cs)
+
+	now := time.Now()
+	ch := &NotificationChannel{
+		UserID:  "user-1",
+		Type:    notify.ChannelTelegram,
+		Name:    "我的机器人",
+		Enabled: true,
+		Config:  notify.ChannelConfig{BotToken: "123456:SECRET-TOKEN", ChatID: "42"},
+	}
+	if err := db.CreateNotificationChannel(ch, now); err != nil {
+		t.Fatalf("创建通知通道失败: %v", err)
+	}
+
+	var stored string
+	db.db.QueryRow(`SELECT config FROM notification_channels WHERE id = ?`, ch.ID).Scan(&stored)
+	if strings.Contains(stored, "SECRET-TOKEN") {
+		t.Errorf("通道参数应加密存储: %s", stored)
+	}
+
+	got, err := db.GetNotificationChannel("user-1", ch.ID)
+	if err != nil || got.Config.BotToken != "123456:SECRET-TOKEN" || !got.Enabled || got.Name != "我的机器人" {
+		t.Fatalf("读取通知通道不正确: %+v %v", got, err)
+	}
+	if _, err := db.GetNotificationChannel("user-2", ch.ID); err != ErrNotificationChannelNotFound {
+		t.Error("不能读取其他用户的通知通道")
+	}
+
+	got.Enabled = false
+	got.Config.ChatID = "43"
+	if err := db.UpdateNotificationChannel(got, now.Add(time.Minute)); err != nil {
+		t.Fatalf("更新通知通道失败: %v", err)
+	}
+	if got, _ := db.GetNotificationChannel("user-1", ch.ID); got.Enabled || got.Config.ChatID != "43" {
+		t.Errorf("更新未生效: %+v", got)
+	}
+	other := *got
+	other.UserID = "user-2"
+	if err := db.UpdateNotificationChannel(&other, now); err != ErrNotificationChannelNotFound {
+		t.Error("不能修改其他用户的通知通道")
+	}
+
+	for i := 1; i < MaxNotificationChannels; i++ {
+		extra := &NotificationChannel{UserID: "user-1", Type: notify.ChannelDiscord, Config: notify.ChannelConfig{WebhookURL: "https://example.com/hook"}}
+		if err := db.CreateNotificationChannel(extra, now); err != nil {
+			t.Fatalf("创建第 %d 个通道失败: %v", i+1, err)
+		}
+	}
+	if err := db.CreateNotificationChannel(&NotificationChannel{UserID: "user-1", Type: notify.ChannelDiscord}, now); err == nil {
+		t.Error("超过数量上限应拒绝")
+	}
+
+	if err := db.DeleteNotificationChannel("user-1", ch.ID); err != nil {
+		t.Fatalf("删除通知通道失败: %v", err)
+	}
+	if channels, _ := db.ListNotificationChannels("user-1"); len(channels) != MaxNotificationChannels-1 {
+		t.Errorf("删除后应剩 %d 个通道，实际 %d", MaxNotificationChannels-1, len(channels))
+	}
+}
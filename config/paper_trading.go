@@ -0,0 +1,111 @@
+package config
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PaperExchangeID 模拟盘交易所ID（无需API密钥，不需要用户配置交易所记录）
+const PaperExchangeID = "paper"
+
+// PaperExchangeConfig 返回模拟盘的虚拟交易所配置（始终启用）
+func PaperExchangeConfig(userID string) *ExchangeConfig {
+	return &ExchangeConfig{
+		ID:        PaperExchangeID,
+		UserID:    userID,
+		AccountID: DefaultExchangeAccountID,
+		Name:      "Paper Trading",
+		Type:      PaperExchangeID,
+		Enabled:   true,
+	}
+}
+
+// PaperAccount 模拟盘账户（虚拟钱包余额 + 持仓）
+type PaperAccount struct {
+	TraderID  string           `json:"trader_id"`
+	UserID    string           `json:"user_id"`
+	Balance   float64          `json:"balance"` // 钱包余额（已实现盈亏和手续费已计入，不含未实现盈亏）
+	Positions []*PaperPosition `json:"positions"`
+}
+
+// PaperPosition 模拟盘持仓
+type PaperPosition struct {
+	Symbol     string    `json:"symbol"`
+	Side       string    `json:"side"` // long 或 short
+	Quantity   float64   `json:"quantity"`
+	EntryPrice float64   `json:"entry_price"`
+	Leverage   int       `json:"leverage"`
+	StopLoss   float64   `json:"stop_loss"`   // 止损触发价（0表示未设置）
+	TakeProfit float64   `json:"take_profit"` // 止盈触发价（0表示未设置）
+	OpenedAt   time.Time `json:"opened_at"`
+}
+
+// GetPaperAccount 获取模拟盘账户，不存在时返回 nil
+func (d *Database) GetPaperAccount(traderID string) (*PaperAccount, error) {
+	account := &PaperAccount{TraderID: traderID}
+	err := d.db.QueryRow(`SELECT user_id, balance FROM paper_accounts WHERE trader_id = ?`, traderID).
+		Scan(&account.UserID, &account.Balance)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询模拟盘账户失败: %w", err)
+	}
+
+	rows, err := d.db.Query(`
+		SELECT symbol, side, quantity, entry_price, leverage, stop_loss, take_profit, opened_at
+		FROM paper_positions WHERE trader_id = ? ORDER BY opened_at, symbol
+	`, traderID)
+	if err != nil {
+		return nil, fmt.Errorf("查询模拟盘持仓失败: %w", err)
+	}
+	defer rows.Close()
+
+	account.Positions = make([]*PaperPosition, 0)
+	for rows.Next() {
+		var p PaperPosition
+		if err := rows.Scan(&p.Symbol, &p.Side, &p.Quantity, &p.EntryPrice, &p.Leverage, &p.StopLoss, &p.TakeProfit, &p.OpenedAt); err != nil {
+			return nil, err
+		}
+		account.Positions = append(account.Positions, &p)
+	}
+	return account, rows.Err()
+}
+
+// SavePaperAccount 保存模拟盘账户余额和全部持仓（单个事务，持仓整体替换）
+func (d *Database) SavePaperAccount(account *PaperAccount) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		INSERT INTO paper_accounts (trader_id, user_id, balance, updated_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(trader_id) DO UPDATE SET balance = excluded.balance, updated_at = CURRENT_TIMESTAMP
+	`, account.TraderID, account.UserID, account.Balance); err != nil {
+		return fmt.Errorf("保存模拟盘账户失败: %w", err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM paper_positions WHERE trader_id = ?`, account.TraderID); err != nil {
+		return fmt.Errorf("清理模拟盘持仓失败: %w", err)
+	}
+	for _, p := range account.Positions {
+		openedAt := p.OpenedAt
+		if openedAt.IsZero() {
+			openedAt = time.Now()
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO paper_positions (trader_id, user_id, symbol, side, quantity, entry_price, leverage, stop_loss, take_profit, opened_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, account.TraderID, account.UserID, p.Symbol, p.Side, p.Quantity, p.EntryPrice, p.Leverage, p.StopLoss, p.TakeProfit, formatDBTime(openedAt)); err != nil {
+			return fmt.Errorf("保存模拟盘持仓失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
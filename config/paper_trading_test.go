@@ -0,0 +1,95 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPaperAccount_SaveAndLoad 测试模拟盘账户的保存、持仓整体替换以及随交易员删除
+func TestPaperAccount_SaveAndLoad(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	account, err := db.GetPaperAccount("paper-1")
+	if err != nil || account != nil {
+		t.Fatalf("不存在的模拟盘账户应返回 nil: %v, %v", account, err)
+	}
+
+	openedAt := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	account = &PaperAccount{
+		TraderID: "paper-1",
+		UserID:   "test-user-001",
+		Balance:  1000,
+		Positions: []*PaperPosition{
+			{Symbol: "BTCUSDT", Side: "long", Quantity: 0.01, EntryPrice: 60000, Leverage: 5, StopLoss: 58000, OpenedAt: openedAt},
+			{Symbol: "ETHUSDT", Side: "short", Quantity: 0.5, EntryPrice: 3000, Leverage: 3, OpenedAt: openedAt.Add(time.Minute)},
+		},
+	}
+	if err := db.SavePaperAccount(account); err != nil {
+		t.Fatalf("保存模拟盘账户失败: %v", err)
+	}
+
+	loaded, err := db.GetPaperAccount("paper-1")
+	if err != nil || loaded == nil {
+		t.Fatalf("读取模拟盘账户失败: %v", err)
+	}
+	if loaded.Balance != 1000 || len(loaded.Positions) != 2 {
+		t.Fatalf("账户内容不正确: balance=%f positions=%d", loaded.Balance, len(loaded.Positions))
+	}
+	btc := loaded.Positions[0]
+	if btc.Symbol != "BTCUSDT" || btc.Leverage != 5 || btc.StopLoss != 58000 || !btc.OpenedAt.Equal(openedAt) {
+		t.Errorf("持仓内容不正确: %+v", btc)
+	}
+
+	// 再次保存时持仓整体替换
+	loaded.Balance = 1200
+	loaded.Positions = loaded.Positions[1:]
+	if err := db.SavePaperAccount(loaded); err != nil {
+		t.Fatalf("更新模拟盘账户失败: %v", err)
+	}
+	updated, _ := db.GetPaperAccount("paper-1")
+	if updated.Balance != 1200 || len(updated.Positions) != 1 || updated.Positions[0].Symbol != "ETHUSDT" {
+		t.Errorf("更新后账户内容不正确: %+v", updated)
+	}
+
+	// 删除交易员时一并删除模拟盘数据
+	if err := db.CreateTrader(&TraderRecord{ID: "paper-1", UserID: "test-user-001", Name: "paper", AIModelID: "deepseek", ExchangeID: PaperExchangeID}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	if err := db.DeleteTrader("test-user-001", "paper-1"); err != nil {
+		t.Fatalf("删除交易员失败: %v", err)
+	}
+	if account, _ := db.GetPaperAccount("paper-1"); account != nil {
+		t.Error("删除交易员后模拟盘账户应被删除")
+	}
+}
+
+// TestGetTraderConfig_PaperExchange 测试模拟盘交易员无需交易所记录即可读取完整配置
+func TestGetTraderConfig_PaperExchange(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if err := db.CreateAIModel("test-user-001", "test-user-001_deepseek", "DeepSeek", "deepseek", true, "sk-test", ""); err != nil {
+		t.Fatalf("创建AI模型失败: %v", err)
+	}
+	for _, trader := range []*TraderRecord{
+		{ID: "paper-trader", UserID: "test-user-001", Name: "paper", AIModelID: "test-user-001_deepseek", ExchangeID: PaperExchangeID},
+		{ID: "real-trader", UserID: "test-user-001", Name: "real", AIModelID: "test-user-001_deepseek", ExchangeID: "binance"},
+	} {
+		if err := db.CreateTrader(trader); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+
+	_, _, exchange, err := db.GetTraderConfig("test-user-001", "paper-trader")
+	if err != nil {
+		t.Fatalf("读取模拟盘交易员配置失败: %v", err)
+	}
+	if exchange.ID != PaperExchangeID || exchange.Type != PaperExchangeID || !exchange.Enabled {
+		t.Errorf("模拟盘交易所配置不正确: %+v", exchange)
+	}
+
+	if _, _, _, err := db.GetTraderConfig("test-user-001", "real-trader"); err == nil {
+		t.Error("缺少交易所记录的真实交易员应返回错误")
+	}
+}
@@ -0,0 +1,4 @@
+package config
+
+// 注册 PostgreSQL 驱动（pgx），DATABASE_URL 为 postgres:// 连接串时使用
+import _ "github.com/jackc/pgx/v5/stdlib"
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPromptTemplateNotFound 用户提示词模板不存在
+var ErrPromptTemplateNotFound = errors.New("提示词模板不存在")
+
+// ErrPromptTemplateExists 同名用户提示词模板已存在
+var ErrPromptTemplateExists = errors.New("提示词模板已存在")
+
+// UserPromptTemplate 用户自定义提示词模板（Content 为最新版本的内容）
+type UserPromptTemplate struct {
+	ID        int64     `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Content   string    `json:"content"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PromptTemplateVersion 提示词模板的历史版本
+type PromptTemplateVersion struct {
+	TemplateID int64     `json:"template_id"`
+	Version    int       `json:"version"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// CreatePromptTemplate 创建用户提示词模板（版本1；同名已存在时返回 ErrPromptTemplateExists）
+func (d *Database) CreatePromptTemplate(userID, name, content string) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var templateID int64
+	err = tx.QueryRow(`
+		INSERT INTO prompt_templates (user_id, name, content, version) VALUES (?, ?, ?, 1)
+		RETURNING id
+	`, userID, name, content).Scan(&templateID)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return 0, ErrPromptTemplateExists
+		}
+		return 0, fmt.Errorf("创建提示词模板失败: %w", err)
+	}
+	if err := insertPromptTemplateVersion(tx, templateID, userID, 1, content); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return 1, nil
+}
+
+// UpdatePromptTemplate 保存用户提示词模板的新内容，生成并返回新的版本号
+func (d *Database) UpdatePromptTemplate(userID, name, content string) (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	var templateID int64
+	var version int
+	err = tx.QueryRow(`
+		UPDATE prompt_templates SET content = ?, version = version + 1, updated_at = ?
+		WHERE user_id = ? AND name = ?
+		RETURNING id, version
+	`, content, formatDBTime(time.Now()), userID, name).Scan(&templateID, &version)
+	if err == sql.ErrNoRows {
+		return 0, ErrPromptTemplateNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("更新提示词模板失败: %w", err)
+	}
+	if err := insertPromptTemplateVersion(tx, templateID, userID, version, content); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return version, nil
+}
+
+func insertPromptTemplateVersion(tx *sqlTx, templateID int64, userID string, version int, content string) error {
+	if _, err := tx.Exec(`
+		INSERT INTO prompt_template_versions (template_id, user_id, version, content) VALUES (?, ?, ?, ?)
+	`, templateID, userID, version, content); err != nil {
+		return fmt.Errorf("保存提示词模板版本失败: %w", err)
+	}
+	return nil
+}
+
+// DeletePromptTemplate 删除用户提示词模板及其全部历史版本
+func (d *Database) DeletePromptTemplate(userID, name string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`
+		DELETE FROM prompt_template_versions WHERE template_id IN
+			(SELECT id FROM prompt_templates WHERE user_id = ? AND name = ?)
+	`, userID, name); err != nil {
+		return fmt.Errorf("删除提示词模板版本失败: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM prompt_templates WHERE user_id = ? AND name = ?`, userID, name)
+	if err != nil {
+		return fmt.Errorf("删除提示词模板失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrPromptTemplateNotFound
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// GetPromptTemplateVersions 获取用户模板的全部历史版本（版本号从新到旧）
+func (d *Database) GetPromptTemplateVersions(userID, name string) ([]*PromptTemplateVersion, error) {
+	rows, err := d.db.Query(`
+		SELECT v.template_id, v.version, v.content, v.created_at
+		FROM prompt_template_versions v
+		JOIN prompt_templates t ON t.id = v.template_id
+		WHERE t.user_id = ? AND t.name = ?
+		ORDER BY v.version DESC
+	`, userID, name)
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词模板版本失败: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make([]*PromptTemplateVersion, 0)
+	for rows.Next() {
+		var v PromptTemplateVersion
+		if err := rows.Scan(&v.TemplateID, &v.Version, &v.Content, &v.CreatedAt); err != nil {
+			return nil, fmt.Errorf("读取提示词模板版本失败: %w", err)
+		}
+		versions = append(versions, &v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(versions) == 0 {
+		return nil, ErrPromptTemplateNotFound
+	}
+	return versions, nil
+}
+
+// GetPromptTemplateVersion 获取用户模板的指定版本
+func (d *Database) GetPromptTemplateVersion(userID, name string, version int) (*PromptTemplateVersion, error) {
+	var v PromptTemplateVersion
+	err := d.db.QueryRow(`
+		SELECT v.template_id, v.version, v.content, v.created_at
+		FROM prompt_template_versions v
+		JOIN prompt_templates t ON t.id = v.template_id
+		WHERE t.user_id = ? AND t.name = ? AND v.version = ?
+	`, userID, name, version).Scan(&v.TemplateID, &v.Version, &v.Content, &v.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPromptTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取提示词模板版本失败: %w", err)
+	}
+	return &v, nil
+}
+
+// GetPromptTemplate 获取用户的指定提示词模板
+func (d *Database) GetPromptTemplate(userID, name string) (*UserPromptTemplate, error) {
+	var t UserPromptTemplate
+	err := d.db.QueryRow(`
+		SELECT id, user_id, name, content, version, created_at, updated_at
+		FROM prompt_templates WHERE user_id = ? AND name = ?
+	`, userID, name).Scan(&t.ID, &t.UserID, &t.Name, &t.Content, &t.Version, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrPromptTemplateNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取提示词模板失败: %w", err)
+	}
+	return &t, nil
+}
+
+// GetUserPromptTemplates 获取用户的全部提示词模板（按名称排序）
+func (d *Database) GetUserPromptTemplates(userID string) ([]*UserPromptTemplate, error) {
+	return d.queryPromptTemplates(`WHERE user_id = ?`, userID)
+}
+
+// GetAllUserPromptTemplates 获取所有用户的提示词模板（启动时加载到内存）
+func (d *Database) GetAllUserPromptTemplates() ([]*UserPromptTemplate, error) {
+	return d.queryPromptTemplates(``)
+}
+
+func (d *Database) queryPromptTemplates(where string, args ...any) ([]*UserPromptTemplate, error) {
+	rows, err := d.db.Query(`
+		SELECT id, user_id, name, content, version, created_at, updated_at
+		FROM prompt_templates `+where+` ORDER BY user_id, name
+	`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询提示词模板失败: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]*UserPromptTemplate, 0)
+	for rows.Next() {
+		var t UserPromptTemplate
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Content, &t.Version, &t.CreatedAt, &t.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("读取提示词模板失败: %w", err)
+		}
+		templates = append(templates, &t)
+	}
+	return templates, rows.Err()
+}
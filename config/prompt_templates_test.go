@@ -0,0 +1,84 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestPromptTemplates_UserScoped 测试用户提示词模板按用户隔离及增删改
+func TestPromptTemplates_UserScoped(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.CreatePromptTemplate("user-a", "scalping", "v1"); err != nil {
+		t.Fatalf("创建模板失败: %v", err)
+	}
+	if _, err := db.CreatePromptTemplate("user-a", "scalping", "v1"); !errors.Is(err, ErrPromptTemplateExists) {
+		t.Errorf("重复创建应返回 ErrPromptTemplateExists, got %v", err)
+	}
+	if _, err := db.CreatePromptTemplate("user-b", "scalping", "other"); err != nil {
+		t.Fatalf("不同用户可以使用相同名称: %v", err)
+	}
+
+	if version, err := db.UpdatePromptTemplate("user-a", "scalping", "v2"); err != nil || version != 2 {
+		t.Fatalf("更新模板应生成版本2: %d, %v", version, err)
+	}
+	if tmpl, err := db.GetPromptTemplate("user-a", "scalping"); err != nil || tmpl.Content != "v2" || tmpl.Version != 2 {
+		t.Errorf("更新后内容应为 v2: %+v, %v", tmpl, err)
+	}
+	if tmpl, _ := db.GetPromptTemplate("user-b", "scalping"); tmpl == nil || tmpl.Content != "other" {
+		t.Errorf("其他用户的模板不应被修改: %+v", tmpl)
+	}
+
+	if all, _ := db.GetAllUserPromptTemplates(); len(all) != 2 {
+		t.Errorf("应有2个用户模板，实际 %d", len(all))
+	}
+	if err := db.DeletePromptTemplate("user-a", "scalping"); err != nil {
+		t.Fatalf("删除模板失败: %v", err)
+	}
+	if err := db.DeletePromptTemplate("user-a", "scalping"); !errors.Is(err, ErrPromptTemplateNotFound) {
+		t.Errorf("删除不存在的模板应返回 ErrPromptTemplateNotFound, got %v", err)
+	}
+	if list, _ := db.GetUserPromptTemplates("user-a"); len(list) != 0 {
+		t.Errorf("删除后 user-a 不应有模板: %d", len(list))
+	}
+}
+
+// TestPromptTemplates_Versions 测试每次保存生成单调递增的版本并保留历史内容
+func TestPromptTemplates_Versions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	if _, err := db.CreatePromptTemplate("user-a", "trend", "v1"); err != nil {
+		t.Fatalf("创建模板失败: %v", err)
+	}
+	for i, content := range []string{"v2", "v3"} {
+		if version, err := db.UpdatePromptTemplate("user-a", "trend", content); err != nil || version != i+2 {
+			t.Fatalf("第%d次更新应生成版本%d: %d, %v", i+1, i+2, version, err)
+		}
+	}
+	if _, err := db.UpdatePromptTemplate("user-b", "trend", "x"); !errors.Is(err, ErrPromptTemplateNotFound) {
+		t.Errorf("不能更新他人的模板: %v", err)
+	}
+
+	versions, err := db.GetPromptTemplateVersions("user-a", "trend")
+	if err != nil || len(versions) != 3 || versions[0].Version != 3 || versions[2].Content != "v1" {
+		t.Fatalf("版本列表应按从新到旧返回3个版本: %+v, %v", versions, err)
+	}
+	if v, err := db.GetPromptTemplateVersion("user-a", "trend", 2); err != nil || v.Content != "v2" {
+		t.Errorf("版本2内容应为 v2: %+v, %v", v, err)
+	}
+	if _, err := db.GetPromptTemplateVersions("user-b", "trend"); !errors.Is(err, ErrPromptTemplateNotFound) {
+		t.Errorf("其他用户不能查看版本: %v", err)
+	}
+
+	// 删除模板同时删除历史版本
+	if err := db.DeletePromptTemplate("user-a", "trend"); err != nil {
+		t.Fatalf("删除模板失败: %v", err)
+	}
+	var count int
+	db.db.QueryRow(`SELECT COUNT(*) FROM prompt_template_versions`).Scan(&count)
+	if count != 0 {
+		t.Errorf("删除模板后应清理历史版本，剩余 %d", count)
+	}
+}
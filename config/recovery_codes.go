@@ -0,0 +1,62 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ReplaceRecoveryCodes 用新的恢复码哈希替换用户的全部恢复码（旧码全部失效）
+func (d *Database) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM otp_recovery_codes WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("删除旧恢复码失败: %w", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.Exec(`
+			INSERT INTO otp_recovery_codes (user_id, code_hash) VALUES (?, ?)
+		`, userID, hash); err != nil {
+			return fmt.Errorf("保存恢复码失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交事务失败: %w", err)
+	}
+	return nil
+}
+
+// ConsumeRecoveryCode 原子地消费一个未使用的恢复码，返回是否成功（不存在或已使用返回 false）
+func (d *Database) ConsumeRecoveryCode(userID, codeHash string) (bool, error) {
+	result, err := d.db.Exec(`
+		UPDATE otp_recovery_codes SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL
+	`, formatDBTime(time.Now()), userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("消费恢复码失败: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// CountUnusedRecoveryCodes 获取用户剩余可用的恢复码数量
+func (d *Database) CountUnusedRecoveryCodes(userID string) (int, error) {
+	var count int
+	err := d.db.QueryRow(`
+		SELECT COUNT(*) FROM otp_recovery_codes WHERE user_id = ? AND used_at IS NULL
+	`, userID).Scan(&count)
+	return count, err
+}
+
+// DeleteRecoveryCodes 删除用户的全部恢复码
+func (d *Database) DeleteRecoveryCodes(userID string) error {
+	_, err := d.db.Exec(`DELETE FROM otp_recovery_codes WHERE user_id = ?`, userID)
+	return err
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"sync"
+	"testing"
+)
+
+// TestRecoveryCodes_DoubleSpend 测试恢复码只能使用一次，重新生成后旧码失效
+func TestRecoveryCodes_DoubleSpend(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	if err := db.ReplaceRecoveryCodes(userID, []string{"hash-a", "hash-b"}); err != nil {
+		t.Fatalf("保存恢复码失败: %v", err)
+	}
+
+	if ok, err := db.ConsumeRecoveryCode(userID, "hash-a"); err != nil || !ok {
+		t.Fatalf("首次使用恢复码应成功: ok=%v err=%v", ok, err)
+	}
+	if ok, _ := db.ConsumeRecoveryCode(userID, "hash-a"); ok {
+		t.Error("同一恢复码不应被使用两次")
+	}
+	if ok, _ := db.ConsumeRecoveryCode("other-user", "hash-b"); ok {
+		t.Error("其他用户不能使用该恢复码")
+	}
+	if n, _ := db.CountUnusedRecoveryCodes(userID); n != 1 {
+		t.Errorf("剩余恢复码应为1，实际 %d", n)
+	}
+
+	// 重新生成后旧码全部失效
+	if err := db.ReplaceRecoveryCodes(userID, []string{"hash-c"}); err != nil {
+		t.Fatalf("重新生成恢复码失败: %v", err)
+	}
+	if ok, _ := db.ConsumeRecoveryCode(userID, "hash-b"); ok {
+		t.Error("重新生成后旧恢复码应失效")
+	}
+	if ok, _ := db.ConsumeRecoveryCode(userID, "hash-c"); !ok {
+		t.Error("新恢复码应可用")
+	}
+
+	// 重置OTP时恢复码一并删除
+	if err := db.ReplaceRecoveryCodes(userID, []string{"hash-d"}); err != nil {
+		t.Fatalf("保存恢复码失败: %v", err)
+	}
+	if err := db.ResetUserOTP(userID, "NEWSECRET"); err != nil {
+		t.Fatalf("重置OTP失败: %v", err)
+	}
+	if n, _ := db.CountUnusedRecoveryCodes(userID); n != 0 {
+		t.Errorf("重置OTP后恢复码应被删除，剩余 %d", n)
+	}
+}
+
+// TestRecoveryCodes_ConcurrentConsume 测试并发使用同一恢复码时只有一个成功
+func TestRecoveryCodes_ConcurrentConsume(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+	// 单连接避免并发写入时的 SQLITE_BUSY，校验的是 UPDATE 条件本身的原子性
+	db.db.SetMaxOpenConns(1)
+
+	userID := "test-user-001"
+	if err := db.ReplaceRecoveryCodes(userID, []string{"hash-a"}); err != nil {
+		t.Fatalf("保存恢复码失败: %v", err)
+	}
+
+	const n = 10
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := db.ConsumeRecoveryCode(userID, "hash-a")
+			if err != nil {
+				t.Errorf("消费恢复码出错: %v", err)
+				return
+			}
+			if ok {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Errorf("并发使用同一恢复码应只有1次成功，实际 %d", succeeded)
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"nofx/auth"
+	"time"
+)
+
+// 确保 Database 实现刷新token存储接口
+var _ auth.RefreshTokenStore = (*Database)(nil)
+
+// CreateRefreshToken 保存刷新token记录
+func (d *Database) CreateRefreshToken(token *auth.RefreshToken) error {
+	_, err := d.db.Exec(`
+		INSERT INTO refresh_tokens (id, user_id, token_hash, family_id, parent_id, device_info, ip_address, expires_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, token.ID, token.UserID, token.TokenHash, token.FamilyID, token.ParentID,
+		token.DeviceInfo, token.IPAddress, formatDBTime(token.ExpiresAt), formatDBTime(token.CreatedAt))
+	if err != nil {
+		return fmt.Errorf("保存刷新token失败: %w", err)
+	}
+	return nil
+}
+
+// GetRefreshTokenByHash 根据token哈希获取刷新token记录
+func (d *Database) GetRefreshTokenByHash(tokenHash string) (*auth.RefreshToken, error) {
+	var token auth.RefreshToken
+	err := d.db.QueryRow(`
+		SELECT id, user_id, token_hash, family_id, COALESCE(parent_id, ''),
+		       COALESCE(device_info, ''), COALESCE(ip_address, ''), expires_at, COALESCE(revoked, 0), created_at
+		FROM refresh_tokens WHERE token_hash = ?
+	`, tokenHash).Scan(&token.ID, &token.UserID, &token.TokenHash, &token.FamilyID, &token.ParentID,
+		&token.DeviceInfo, &token.IPAddress, &token.ExpiresAt, &token.Revoked, &token.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("刷新token不存在")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询刷新token失败: %w", err)
+	}
+	return &token, nil
+}
+
+// ConsumeRefreshToken 将未撤销的刷新token标记为已轮换，返回是否成功（已被使用过则返回 false）
+func (d *Database) ConsumeRefreshToken(tokenHash, replacedByID string) (bool, error) {
+	result, err := d.db.Exec(`
+		UPDATE refresh_tokens SET revoked = 1, revoked_at = ?, replaced_by = ?
+		WHERE token_hash = ? AND revoked = 0
+	`, formatDBTime(time.Now()), replacedByID, tokenHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeRefreshTokenFamily 撤销同一轮换链上的所有刷新token
+func (d *Database) RevokeRefreshTokenFamily(familyID string) error {
+	_, err := d.db.Exec(`
+		UPDATE refresh_tokens SET revoked = 1, revoked_at = COALESCE(revoked_at, ?)
+		WHERE family_id = ?
+	`, formatDBTime(time.Now()), familyID)
+	if err != nil {
+		return fmt.Errorf("撤销刷新token失败: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserRefreshTokens 撤销用户的全部刷新token（如禁用账户时）
+func (d *Database) RevokeUserRefreshTokens(userID string) error {
+	_, err := d.db.Exec(`
+		UPDATE refresh_tokens SET revoked = 1, revoked_at = COALESCE(revoked_at, ?)
+		WHERE user_id = ? AND revoked = 0
+	`, formatDBTime(time.Now()), userID)
+	if err != nil {
+		return fmt.Errorf("撤销用户刷新token失败: %w", err)
+	}
+	return nil
+}
+
+// UserSession 一次登录产生的会话（同一条刷新token轮换链，ID 为轮换链ID），不包含任何token值
+type UserSession struct {
+	ID         string    `json:"id"`
+	DeviceInfo string    `json:"device_info"`  // 最近一次刷新时的 User-Agent
+	IPAddress  string    `json:"ip_address"`   // 最近一次刷新时的IP
+	CreatedAt  time.Time `json:"created_at"`   // 登录时间
+	LastUsedAt time.Time `json:"last_used_at"` // 最近一次刷新时间
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"` // 是否为发起请求的会话
+}
+
+// ListUserSessions 获取用户的有效会话（按最近使用时间倒序）
+func (d *Database) ListUserSessions(userID string, now time.Time) ([]*UserSession, error) {
+	// 每条轮换链最多只有一个未撤销的token（链头）；链上第一个token的ID即轮换链ID，其签发时间为登录时间
+	rows, err := d.db.Query(`
+		SELECT t.family_id, COALESCE(t.device_info, ''), COALESCE(t.ip_address, ''),
+		       f.created_at, t.created_at, t.expires_at
+		FROM refresh_tokens t
+		LEFT JOIN refresh_tokens f ON f.id = t.family_id
+		WHERE t.user_id = ? AND t.revoked = 0
+		ORDER BY t.created_at DESC
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("查询会话失败: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*UserSession{}
+	for rows.Next() {
+		var s UserSession
+		var loginAt sql.NullTime
+		if err := rows.Scan(&s.ID, &s.DeviceInfo, &s.IPAddress, &loginAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("读取会话失败: %w", err)
+		}
+		s.CreatedAt = s.LastUsedAt
+		if loginAt.Valid {
+			s.CreatedAt = loginAt.Time
+		}
+		if s.ExpiresAt.After(now) {
+			sessions = append(sessions, &s)
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// RevokeUserSession 撤销用户的一个会话，返回会话是否存在（只能撤销自己的会话）
+func (d *Database) RevokeUserSession(userID, sessionID string) (bool, error) {
+	result, err := d.db.Exec(`
+		UPDATE refresh_tokens SET revoked = 1, revoked_at = COALESCE(revoked_at, ?)
+		WHERE user_id = ? AND family_id = ? AND revoked = 0
+	`, formatDBTime(time.Now()), userID, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("撤销会话失败: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// RevokeOtherUserSessions 撤销用户除 keepSessionID 之外的全部会话（keepSessionID 为空时撤销全部），返回被撤销的会话ID
+func (d *Database) RevokeOtherUserSessions(userID, keepSessionID string) ([]string, error) {
+	var revoked []string
+	err := d.writeTx(func(tx *sqlTx) error {
+		revoked = nil
+		rows, err := tx.Query(`
+			SELECT DISTINCT family_id FROM refresh_tokens WHERE user_id = ? AND revoked = 0 AND family_id != ?
+		`, userID, keepSessionID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return err
+			}
+			revoked = append(revoked, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE refresh_tokens SET revoked = 1, revoked_at = COALESCE(revoked_at, ?)
+			WHERE user_id = ? AND revoked = 0 AND family_id != ?
+		`, formatDBTime(time.Now()), userID, keepSessionID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("撤销其他会话失败: %w", err)
+	}
+	return revoked, nil
+}
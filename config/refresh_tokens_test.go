@@ -0,0 +1,47 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"nofx/auth"
+)
+
+// TestListUserSessions 轮换后仍是同一个会话：登录时间取链上第一个token，设备信息和最近使用时间取链头
+func TestListUserSessions(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/sessions.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	token, first, err := auth.IssueRefreshToken(db, "user-1", auth.DeviceInfo{UserAgent: "old-agent"})
+	if err != nil {
+		t.Fatalf("签发刷新token失败: %v", err)
+	}
+	// 让轮换后的token签发时间晚于登录时间（数据库时间精度为秒）
+	db.db.Exec(`UPDATE refresh_tokens SET created_at = ? WHERE id = ?`, formatDBTime(first.CreatedAt.Add(-time.Hour)), first.ID)
+	if _, _, err := auth.RotateRefreshToken(db, token, auth.DeviceInfo{UserAgent: "new-agent"}); err != nil {
+		t.Fatalf("轮换刷新token失败: %v", err)
+	}
+
+	sessions, err := db.ListUserSessions("user-1", time.Now())
+	if err != nil {
+		t.Fatalf("获取会话失败: %v", err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("轮换后应仍为1个会话，实际 %d", len(sessions))
+	}
+	session := sessions[0]
+	if session.ID != first.FamilyID || session.DeviceInfo != "new-agent" || !session.CreatedAt.Before(session.LastUsedAt) {
+		t.Errorf("会话信息不正确: %+v", session)
+	}
+
+	revoked, err := db.RevokeOtherUserSessions("user-1", "")
+	if err != nil || len(revoked) != 1 || revoked[0] != first.FamilyID {
+		t.Fatalf("撤销全部会话失败: %v %v", revoked, err)
+	}
+	if sessions, _ := db.ListUserSessions("user-1", time.Now()); len(sessions) != 0 {
+		t.Errorf("撤销后不应有会话: %+v", sessions)
+	}
+}
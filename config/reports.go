@@ -0,0 +1,124 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ReportPeriodDaily / ReportPeriodWeekly 定时报告周期
+	ReportPeriodDaily  = "daily"
+	ReportPeriodWeekly = "weekly"
+	// maxReportsPerPeriod 每个用户每种周期最多保留的报告数
+	maxReportsPerPeriod = 100
+)
+
+// PerformanceReport 已生成的定时表现报告
+type PerformanceReport struct {
+	ID          string          `json:"id"`
+	UserID      string          `json:"-"`
+	Period      string          `json:"period"`       // daily / weekly
+	PeriodStart string          `json:"period_start"` // 统计区间开始日期（用户时区，YYYY-MM-DD，含）
+	PeriodEnd   string          `json:"period_end"`   // 统计区间结束日期（用户时区，YYYY-MM-DD，不含）
+	Timezone    string          `json:"timezone"`
+	Data        json.RawMessage `json:"data"`           // 报告内容（JSON）
+	Text        string          `json:"text"`           // 纯文本正文（Telegram / Discord）
+	HTML        string          `json:"html,omitempty"` // HTML 正文（邮件）
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// SavePerformanceReport 保存报告（同一用户、周期、开始日期的报告覆盖旧记录），只保留最近的 maxReportsPerPeriod 份
+func (d *Database) SavePerformanceReport(report *PerformanceReport) error {
+	report.ID = uuid.New().String()
+	if report.CreatedAt.IsZero() {
+		report.CreatedAt = time.Now().UTC()
+	}
+	return d.writeTx(func(tx *sqlTx) error {
+		if _, err := tx.Exec(`
+			INSERT INTO performance_reports (id, user_id, period, period_start, period_end, timezone, data, text, html, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(user_id, period, period_start) DO UPDATE SET
+				id = excluded.id,
+				period_end = excluded.period_end,
+				timezone = excluded.timezone,
+				data = excluded.data,
+				text = excluded.text,
+				html = excluded.html,
+				created_at = excluded.created_at
+		`, report.ID, report.UserID, report.Period, report.PeriodStart, report.PeriodEnd, report.Timezone,
+			string(report.Data), report.Text, report.HTML, report.CreatedAt.UnixMilli()); err != nil {
+			return fmt.Errorf("保存报告失败: %w", err)
+		}
+		_, err := tx.Exec(`
+			DELETE FROM performance_reports WHERE user_id = ? AND period = ? AND id NOT IN (
+				SELECT id FROM performance_reports WHERE user_id = ? AND period = ?
+				ORDER BY period_start DESC LIMIT ?
+			)
+		`, report.UserID, report.Period, report.UserID, report.Period, maxReportsPerPeriod)
+		return err
+	})
+}
+
+// ListPerformanceReports 获取用户的报告（最新的在前）
+func (d *Database) ListPerformanceReports(userID, period string, limit int) ([]*PerformanceReport, error) {
+	if limit <= 0 || limit > maxReportsPerPeriod {
+		limit = maxReportsPerPeriod
+	}
+	rows, err := d.db.Query(`
+		SELECT id, period_start, period_end, timezone, data, text, html, created_at
+		FROM performance_reports WHERE user_id = ? AND period = ?
+		ORDER BY period_start DESC LIMIT ?
+	`, userID, period, limit)
+	if err != nil {
+		return nil, fmt.Errorf("获取报告失败: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]*PerformanceReport, 0)
+	for rows.Next() {
+		report := &PerformanceReport{UserID: userID, Period: period}
+		var data string
+		var createdAt int64
+		if err := rows.Scan(&report.ID, &report.PeriodStart, &report.PeriodEnd, &report.Timezone,
+			&data, &report.Text, &report.HTML, &createdAt); err != nil {
+			return nil, fmt.Errorf("读取报告失败: %w", err)
+		}
+		report.Data = json.RawMessage(data)
+		report.CreatedAt = time.UnixMilli(createdAt).UTC()
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// GetReportMarker 用户该周期最近一次处理过的报告开始日期（没有时为空字符串）
+// 没有交易活动时不生成报告，但仍会记录标记，重启后不会重复统计同一周期
+func (d *Database) GetReportMarker(userID, period string) (string, error) {
+	var periodStart string
+	err := d.db.QueryRow(`SELECT period_start FROM report_markers WHERE user_id = ? AND period = ?`, userID, period).Scan(&periodStart)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("获取报告发送记录失败: %w", err)
+	}
+	return periodStart, nil
+}
+
+// SetReportMarker 记录用户该周期已处理到的报告开始日期
+func (d *Database) SetReportMarker(userID, period, periodStart string) error {
+	_, err := d.db.Exec(`
+		INSERT INTO report_markers (user_id, period, period_start, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, period) DO UPDATE SET
+			period_start = excluded.period_start,
+			updated_at = excluded.updated_at
+	`, userID, period, periodStart, time.Now().UnixMilli())
+	if err != nil {
+		return fmt.Errorf("保存报告发送记录失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// TestPerformanceReports 同一周期的报告覆盖旧记录、按周期和用户隔离、保留上限；发送标记持久化
+func TestPerformanceReports(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/reports.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	save := func(userID, period, start, text string) {
+		t.Helper()
+		report := &PerformanceReport{UserID: userID, Period: period, PeriodStart: start, PeriodEnd: start, Timezone: "UTC",
+			Data: json.RawMessage(`{"pnl":1}`), Text: text}
+		if err := db.SavePerformanceReport(report); err != nil {
+			t.Fatalf("保存报告失败: %v", err)
+		}
+	}
+	save("user-1", ReportPeriodDaily, "2025-03-01", "first")
+	save("user-1", ReportPeriodDaily, "2025-03-01", "regenerated")
+	save("user-1", ReportPeriodDaily, "2025-03-02", "second")
+	save("user-1", ReportPeriodWeekly, "2025-02-24", "weekly")
+	save("user-2", ReportPeriodDaily, "2025-03-02", "other user")
+
+	reports, err := db.ListPerformanceReports("user-1", ReportPeriodDaily, 10)
+	if err != nil {
+		t.Fatalf("获取报告失败: %v", err)
+	}
+	if len(reports) != 2 || reports[0].PeriodStart != "2025-03-02" || reports[1].Text != "regenerated" {
+		t.Fatalf("报告列表不正确: %+v", reports)
+	}
+	if string(reports[0].Data) != `{"pnl":1}` {
+		t.Errorf("报告内容不正确: %s", reports[0].Data)
+	}
+
+	for i := 0; i < maxReportsPerPeriod+5; i++ {
+		save("user-3", ReportPeriodDaily, fmt.Sprintf("2024-%02d-%02d", i/28+1, i%28+1), "")
+	}
+	if reports, _ := db.ListPerformanceReports("user-3", ReportPeriodDaily, 0); len(reports) != maxReportsPerPeriod || reports[len(reports)-1].PeriodStart != "2024-01-06" {
+		t.Errorf("应只保留最近 %d 份报告: %d", maxReportsPerPeriod, len(reports))
+	}
+
+	if marker, err := db.GetReportMarker("user-1", ReportPeriodDaily); err != nil || marker != "" {
+		t.Fatalf("没有发送记录时应为空: %q %v", marker, err)
+	}
+	if err := db.SetReportMarker("user-1", ReportPeriodDaily, "2025-03-01"); err != nil {
+		t.Fatalf("保存发送记录失败: %v", err)
+	}
+	if err := db.SetReportMarker("user-1", ReportPeriodDaily, "2025-03-02"); err != nil {
+		t.Fatalf("更新发送记录失败: %v", err)
+	}
+	if marker, _ := db.GetReportMarker("user-1", ReportPeriodDaily); marker != "2025-03-02" {
+		t.Errorf("发送记录不正确: %q", marker)
+	}
+	if marker, _ := db.GetReportMarker("user-1", ReportPeriodWeekly); marker != "" {
+		t.Errorf("不同周期的发送记录应独立: %q", marker)
+	}
+}
@@ -0,0 +1,344 @@
+package config
+
+import "fmt"
+
+// postgresSchema PostgreSQL 表结构（与 SQLite 的 createTables + 历史 ALTER TABLE 字段一致）
+// 布尔字段按 SMALLINT 0/1 存储，与 SQLite 的取值和查询写法保持一致；时间字段为 UTC 的 TIMESTAMP。
+// 不声明外键：SQLite 未开启 foreign_keys，默认数据使用 user_id='default' 且 traders.exchange_id 不是唯一键
+var postgresSchema = []string{
+	// AI模型配置表
+	`CREATE TABLE IF NOT EXISTS ai_models (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL DEFAULT 'default',
+		name TEXT NOT NULL,
+		provider TEXT NOT NULL,
+		enabled SMALLINT DEFAULT 0,
+		api_key TEXT DEFAULT '',
+		custom_api_url TEXT DEFAULT '',
+		custom_model_name TEXT DEFAULT '',
+		temperature DOUBLE PRECISION DEFAULT NULL,
+		max_tokens INTEGER DEFAULT 0,
+		request_timeout_seconds INTEGER DEFAULT 0,
+		context_tokens INTEGER DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 交易所配置表（每个交易所可有多个账户）
+	`CREATE TABLE IF NOT EXISTS exchanges (
+		id TEXT NOT NULL,
+		user_id TEXT NOT NULL DEFAULT 'default',
+		account_id TEXT NOT NULL DEFAULT 'default',
+		label TEXT DEFAULT '',
+		name TEXT NOT NULL,
+		type TEXT NOT NULL,
+		enabled SMALLINT DEFAULT 0,
+		api_key TEXT DEFAULT '',
+		secret_key TEXT DEFAULT '',
+		testnet SMALLINT DEFAULT 0,
+		hyperliquid_wallet_addr TEXT DEFAULT '',
+		aster_user TEXT DEFAULT '',
+		aster_signer TEXT DEFAULT '',
+		aster_private_key TEXT DEFAULT '',
+		trade_permission TEXT DEFAULT 'unknown',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (id, user_id, account_id)
+	)`,
+
+	// 用户信号源配置表
+	`CREATE TABLE IF NOT EXISTS user_signal_sources (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL UNIQUE,
+		coin_pool_url TEXT DEFAULT '',
+		oi_top_url TEXT DEFAULT '',
+		headers TEXT DEFAULT '',
+		timeout_seconds INTEGER DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 交易员配置表
+	`CREATE TABLE IF NOT EXISTS traders (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL DEFAULT 'default',
+		name TEXT NOT NULL,
+		ai_model_id TEXT NOT NULL,
+		exchange_id TEXT NOT NULL,
+		exchange_account_id TEXT DEFAULT 'default',
+		initial_balance DOUBLE PRECISION NOT NULL,
+		scan_interval_minutes INTEGER DEFAULT 3,
+		is_running SMALLINT DEFAULT 0,
+		btc_eth_leverage INTEGER DEFAULT 5,
+		altcoin_leverage INTEGER DEFAULT 5,
+		trading_symbols TEXT DEFAULT '',
+		use_coin_pool SMALLINT DEFAULT 0,
+		use_oi_top SMALLINT DEFAULT 0,
+		custom_prompt TEXT DEFAULT '',
+		override_base_prompt SMALLINT DEFAULT 0,
+		is_cross_margin SMALLINT DEFAULT 1,
+		use_default_coins SMALLINT DEFAULT 1,
+		custom_coins TEXT DEFAULT '',
+		system_prompt_template TEXT DEFAULT 'default',
+		last_error TEXT DEFAULT '',
+		is_public SMALLINT DEFAULT 0,
+		max_daily_loss_pct DOUBLE PRECISION DEFAULT 0,
+		daily_loss_flatten SMALLINT DEFAULT 0,
+		max_position_value_usdt DOUBLE PRECISION DEFAULT 0,
+		max_total_exposure_pct DOUBLE PRECISION DEFAULT 0,
+		default_stop_loss_pct DOUBLE PRECISION DEFAULT 0,
+		default_take_profit_pct DOUBLE PRECISION DEFAULT 0,
+		cooldown_minutes_after_loss INTEGER DEFAULT 0,
+		execution_mode TEXT DEFAULT 'market',
+		limit_offset_bps DOUBLE PRECISION DEFAULT 0,
+		limit_timeout_seconds INTEGER DEFAULT 30,
+		limit_fallback TEXT DEFAULT 'market',
+		experiment_enabled SMALLINT DEFAULT 0,
+		experiment_template_a TEXT DEFAULT '',
+		experiment_template_b TEXT DEFAULT '',
+		experiment_split_ratio DOUBLE PRECISION DEFAULT 0.5,
+		experiment_mode TEXT DEFAULT 'alternate',
+		fallback_ai_model_id TEXT DEFAULT '',
+		fallback_sticky_minutes INTEGER DEFAULT 0,
+		ensemble_model_ids TEXT DEFAULT '',
+		consensus_rule TEXT DEFAULT 'majority',
+		guardrails TEXT DEFAULT '',
+		excluded_symbols TEXT DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 用户表
+	`CREATE TABLE IF NOT EXISTS users (
+		id TEXT PRIMARY KEY,
+		email TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		otp_secret TEXT,
+		otp_verified SMALLINT DEFAULT 0,
+		role TEXT DEFAULT 'user',
+		disabled SMALLINT DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 系统配置表
+	`CREATE TABLE IF NOT EXISTS system_config (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 内测码表
+	`CREATE TABLE IF NOT EXISTS beta_codes (
+		code TEXT PRIMARY KEY,
+		used SMALLINT DEFAULT 0,
+		used_by TEXT DEFAULT '',
+		used_at TIMESTAMP DEFAULT NULL,
+		max_uses INTEGER DEFAULT 1,
+		use_count INTEGER DEFAULT 0,
+		expires_at TIMESTAMP DEFAULT NULL,
+		revoked SMALLINT DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 交易历史表
+	`CREATE TABLE IF NOT EXISTS trade_history (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		trader_id TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		side TEXT NOT NULL,
+		position_side TEXT NOT NULL,
+		price DOUBLE PRECISION NOT NULL,
+		quantity DOUBLE PRECISION NOT NULL,
+		realized_pnl DOUBLE PRECISION NOT NULL,
+		commission DOUBLE PRECISION NOT NULL,
+		commission_asset TEXT NOT NULL,
+		trade_time BIGINT NOT NULL,
+		buyer SMALLINT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, trader_id, symbol, trade_time, side, position_side)
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_trade_history_user_trader ON trade_history(user_id, trader_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_trade_history_time ON trade_history(trade_time DESC)`,
+	`CREATE INDEX IF NOT EXISTS idx_trade_history_symbol ON trade_history(symbol)`,
+
+	// 同步状态表
+	`CREATE TABLE IF NOT EXISTS sync_status (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		trader_id TEXT NOT NULL,
+		last_sync_time BIGINT NOT NULL,
+		last_sync_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, trader_id)
+	)`,
+
+	// AI 调用花费记录表
+	`CREATE TABLE IF NOT EXISTS ai_spend_records (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		trader_id TEXT NOT NULL,
+		model TEXT NOT NULL DEFAULT '',
+		prompt_tokens INTEGER NOT NULL DEFAULT 0,
+		completion_tokens INTEGER NOT NULL DEFAULT 0,
+		cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_ai_spend_user_time ON ai_spend_records(user_id, created_at)`,
+
+	// 审计日志表
+	`CREATE TABLE IF NOT EXISTS audit_log (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		action TEXT NOT NULL,
+		target_id TEXT DEFAULT '',
+		ip_address TEXT DEFAULT '',
+		summary TEXT DEFAULT '',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_audit_log_user_time ON audit_log(user_id, created_at)`,
+
+	// 权益快照表
+	`CREATE TABLE IF NOT EXISTS equity_snapshots (
+		id BIGSERIAL PRIMARY KEY,
+		trader_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		timestamp TIMESTAMP NOT NULL,
+		total_equity DOUBLE PRECISION NOT NULL DEFAULT 0,
+		wallet_balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+		available_balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+		initial_balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+		total_pnl DOUBLE PRECISION NOT NULL DEFAULT 0,
+		total_pnl_pct DOUBLE PRECISION NOT NULL DEFAULT 0,
+		position_count INTEGER NOT NULL DEFAULT 0,
+		margin_used_pct DOUBLE PRECISION NOT NULL DEFAULT 0,
+		resolution TEXT NOT NULL DEFAULT 'raw'
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_equity_snapshots_trader_time ON equity_snapshots(trader_id, timestamp)`,
+
+	// 模拟盘账户表
+	`CREATE TABLE IF NOT EXISTS paper_accounts (
+		trader_id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 模拟盘持仓表
+	`CREATE TABLE IF NOT EXISTS paper_positions (
+		trader_id TEXT NOT NULL,
+		user_id TEXT NOT NULL,
+		symbol TEXT NOT NULL,
+		side TEXT NOT NULL,
+		quantity DOUBLE PRECISION NOT NULL,
+		entry_price DOUBLE PRECISION NOT NULL,
+		leverage INTEGER NOT NULL DEFAULT 1,
+		stop_loss DOUBLE PRECISION NOT NULL DEFAULT 0,
+		take_profit DOUBLE PRECISION NOT NULL DEFAULT 0,
+		opened_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (trader_id, symbol, side)
+	)`,
+
+	// OTP恢复码表
+	`CREATE TABLE IF NOT EXISTS otp_recovery_codes (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		code_hash TEXT NOT NULL,
+		used_at TIMESTAMP DEFAULT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, code_hash)
+	)`,
+
+	// 刷新token表
+	`CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id TEXT PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		token_hash TEXT NOT NULL UNIQUE,
+		family_id TEXT NOT NULL,
+		parent_id TEXT DEFAULT '',
+		replaced_by TEXT DEFAULT '',
+		device_info TEXT DEFAULT '',
+		ip_address TEXT DEFAULT '',
+		expires_at TIMESTAMP NOT NULL,
+		revoked SMALLINT DEFAULT 0,
+		revoked_at TIMESTAMP DEFAULT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id)`,
+	`CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id)`,
+
+	// 用户 AI 花费预算配置表
+	`CREATE TABLE IF NOT EXISTS user_spend_settings (
+		user_id TEXT PRIMARY KEY,
+		monthly_budget_usd DOUBLE PRECISION DEFAULT 0,
+		alert_thresholds TEXT DEFAULT '50,80,100',
+		downgrade_enabled SMALLINT DEFAULT 0,
+		fallback_model_id TEXT DEFAULT '',
+		alert_month TEXT DEFAULT '',
+		alert_level INTEGER DEFAULT 0,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+
+	// 用户自定义提示词模板表
+	`CREATE TABLE IF NOT EXISTS prompt_templates (
+		id BIGSERIAL PRIMARY KEY,
+		user_id TEXT NOT NULL,
+		name TEXT NOT NULL,
+		content TEXT NOT NULL,
+		version INTEGER DEFAULT 1,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(user_id, name)
+	)`,
+
+	// 提示词模板版本表
+	`CREATE TABLE IF NOT EXISTS prompt_template_versions (
+		id BIGSERIAL PRIMARY KEY,
+		template_id BIGINT NOT NULL,
+		user_id TEXT NOT NULL,
+		version INTEGER NOT NULL,
+		content TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(template_id, version)
+	)`,
+
+	// 自动更新 updated_at（对应 SQLite 的 AFTER UPDATE 触发器）
+	`CREATE OR REPLACE FUNCTION set_updated_at() RETURNS TRIGGER AS $$
+	BEGIN
+		NEW.updated_at = CURRENT_TIMESTAMP;
+		RETURN NEW;
+	END;
+	$$ LANGUAGE plpgsql`,
+}
+
+// postgresUpdatedAtTables 需要自动更新 updated_at 的表
+var postgresUpdatedAtTables = []string{"users", "ai_models", "exchanges", "traders", "user_signal_sources", "system_config"}
+
+// postgresSchemaLockKey 建表和迁移时使用的事务级咨询锁（多个实例同时启动时串行执行）
+const postgresSchemaLockKey = 0x6e6f6678
+
+// createPostgresTables 在一个事务中创建 PostgreSQL 表结构（需要 PostgreSQL 14+，使用 CREATE OR REPLACE TRIGGER）
+func (d *Database) createPostgresTables() error {
+	queries := append([]string{}, postgresSchema...)
+	for _, table := range postgresUpdatedAtTables {
+		queries = append(queries, fmt.Sprintf(`CREATE OR REPLACE TRIGGER update_%s_updated_at
+		BEFORE UPDATE ON %s
+		FOR EACH ROW EXECUTE FUNCTION set_updated_at()`, table, table))
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock(?)`, postgresSchemaLockKey); err != nil {
+		return fmt.Errorf("获取建表锁失败: %w", err)
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(query); err != nil {
+			return fmt.Errorf("执行SQL失败 [%s]: %w", query, err)
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestUserSignalSourceOptions 测试信号源请求头加密存储、超时保存，以及更新地址时保留请求头
+func TestUserSignalSourceOptions(t *testing.T) {
+	db, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	userID := "test-user-001"
+	if err := db.CreateUserSignalSource(userID, "https://pool.example.com", ""); err != nil {
+		t.Fatalf("创建信号源配置失败: %v", err)
+	}
+	headers := map[string]string{"Authorization": "Bearer secret-token"}
+	if err := db.SetUserSignalSourceOptions(userID, headers, 15); err != nil {
+		t.Fatalf("保存请求头失败: %v", err)
+	}
+
+	var stored string
+	if err := db.db.QueryRow(`SELECT headers FROM user_signal_sources WHERE user_id = ?`, userID).Scan(&stored); err != nil {
+		t.Fatalf("查询请求头失败: %v", err)
+	}
+	if db.cryptoService != nil && strings.Contains(stored, "secret-token") {
+		t.Error("请求头应加密存储")
+	}
+
+	// 更新地址不影响请求头和超时
+	if err := db.CreateUserSignalSource(userID, "https://pool2.example.com", "https://oi.example.com"); err != nil {
+		t.Fatalf("更新信号源配置失败: %v", err)
+	}
+	source, err := db.GetUserSignalSource(userID)
+	if err != nil {
+		t.Fatalf("获取信号源配置失败: %v", err)
+	}
+	if source.CoinPoolURL != "https://pool2.example.com" || source.OITopURL != "https://oi.example.com" {
+		t.Errorf("信号源地址未更新: %+v", source)
+	}
+	if source.Headers["Authorization"] != "Bearer secret-token" || source.TimeoutSeconds != 15 {
+		t.Errorf("请求头和超时应保留: %v %d", source.Headers, source.TimeoutSeconds)
+	}
+
+	// 清除请求头
+	if err := db.SetUserSignalSourceOptions(userID, nil, 0); err != nil {
+		t.Fatalf("清除请求头失败: %v", err)
+	}
+	source, err = db.GetUserSignalSource(userID)
+	if err != nil {
+		t.Fatalf("获取信号源配置失败: %v", err)
+	}
+	if len(source.Headers) != 0 || source.TimeoutSeconds != 0 {
+		t.Errorf("请求头和超时应被清除: %v %d", source.Headers, source.TimeoutSeconds)
+	}
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	// sqliteBusyTimeoutMs 连接等待其他写入释放锁的时间（毫秒）
+	sqliteBusyTimeoutMs = 5000
+	// sqliteMaxOpenConns SQLite 连接池上限（WAL 模式下读可以并发，写入由 BEGIN IMMEDIATE 串行）
+	sqliteMaxOpenConns = 8
+	// busyRetryAttempts 写事务遇到 SQLITE_BUSY 时的最多尝试次数
+	busyRetryAttempts = 5
+	// busyRetryBaseDelay 重试的初始等待时间（每次翻倍）
+	busyRetryBaseDelay = 50 * time.Millisecond
+)
+
+// sqliteDSN 在数据库路径上附加连接参数：每个连接都启用 WAL、FULL 同步和 busy_timeout，
+// 事务使用 BEGIN IMMEDIATE 在开始时获取写锁（避免读事务升级为写事务时直接返回 SQLITE_BUSY）
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)&_pragma=synchronous(FULL)&_txlock=immediate",
+		path, sep, sqliteBusyTimeoutMs)
+}
+
+// isBusyError 是否为 SQLite 锁冲突（SQLITE_BUSY / SQLITE_LOCKED，含扩展错误码）
+func isBusyError(err error) bool {
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) {
+		code := sqliteErr.Code() & 0xff
+		return code == sqlite3.SQLITE_BUSY || code == sqlite3.SQLITE_LOCKED
+	}
+	return err != nil && strings.Contains(err.Error(), "database is locked")
+}
+
+// retryOnBusy 执行 fn，遇到 SQLite 锁冲突时按指数退避重试（fn 需可重复执行，通常是一个完整的写事务）
+func retryOnBusy(fn func() error) error {
+	delay := busyRetryBaseDelay
+	var err error
+	for attempt := 1; attempt <= busyRetryAttempts; attempt++ {
+		if err = fn(); !isBusyError(err) {
+			return err
+		}
+		if attempt < busyRetryAttempts {
+			log.Printf("⚠️ 数据库繁忙，%v 后重试（第%d次）: %v", delay, attempt, err)
+			time.Sleep(delay)
+			delay *= 2
+		}
+	}
+	return err
+}
+
+// writeTx 在短写事务中执行 fn 并提交，SQLite 锁冲突时整个事务重试
+func (d *Database) writeTx(fn func(tx *sqlTx) error) error {
+	return retryOnBusy(func() error {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return fmt.Errorf("开始事务失败: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
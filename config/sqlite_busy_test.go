@@ -0,0 +1,83 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// TestRetryOnBusy 锁冲突时重试直到成功，其他错误立即返回
+func TestRetryOnBusy(t *testing.T) {
+	calls := 0
+	err := retryOnBusy(func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("写入失败: %w", errors.New("database is locked (5) (SQLITE_BUSY)"))
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Fatalf("应重试到第3次成功: calls=%d err=%v", calls, err)
+	}
+
+	calls = 0
+	err = retryOnBusy(func() error {
+		calls++
+		return errors.New("交易员不存在")
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("非锁冲突错误不应重试: calls=%d err=%v", calls, err)
+	}
+}
+
+// TestConcurrentTraderCreateAndConfigUpdates 并发创建交易员、保存交易所密钥和AI模型、写系统配置时不出现 database is locked
+func TestConcurrentTraderCreateAndConfigUpdates(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/stress.db")
+	if err != nil {
+		t.Fatalf("创建数据库失败: %v", err)
+	}
+	defer db.Close()
+	if err := db.CreateUser(&User{ID: "user-s", Email: "s@test.com", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+
+	const workers, iterations = 16, 10
+	var wg sync.WaitGroup
+	errCh := make(chan error, workers*iterations*4)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < iterations; i++ {
+				traderID := fmt.Sprintf("trader-%d-%d", w, i)
+				if err := db.CreateTrader(&TraderRecord{ID: traderID, UserID: "user-s", Name: traderID, AIModelID: "user-s_deepseek", ExchangeID: "binance", InitialBalance: 100}); err != nil {
+					errCh <- fmt.Errorf("创建交易员: %w", err)
+				}
+				if err := db.UpdateExchange("user-s", "binance", true, fmt.Sprintf("api-%d", w), "secret", false, "", "", "", ""); err != nil {
+					errCh <- fmt.Errorf("保存交易所: %w", err)
+				}
+				if err := db.UpdateAIModel("user-s", "user-s_deepseek", true, fmt.Sprintf("key-%d", w), "", "", &AIModelParams{MaxTokens: i}); err != nil {
+					errCh <- fmt.Errorf("保存AI模型: %w", err)
+				}
+				if err := db.SetSystemConfig(fmt.Sprintf("stress_%d", w), fmt.Sprint(i)); err != nil {
+					errCh <- fmt.Errorf("写系统配置: %w", err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		t.Error(err)
+	}
+
+	traders, err := db.GetTraders("user-s")
+	if err != nil || len(traders) != workers*iterations {
+		t.Fatalf("应创建 %d 个交易员: %d %v", workers*iterations, len(traders), err)
+	}
+	exchanges, err := db.GetExchanges("user-s")
+	if err != nil || len(exchanges) != 1 {
+		t.Fatalf("并发保存同一交易所应只有一条记录: %d %v", len(exchanges), err)
+	}
+}
@@ -0,0 +1,434 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/mail"
+	"net/url"
+	"nofx/market"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// systemConfigCacheTTL 缓存有效期，过期后重新读取数据库（多实例共用 PostgreSQL 时可感知其他实例的修改）
+const systemConfigCacheTTL = 30 * time.Second
+
+// systemConfigEntry 缓存的配置值
+type systemConfigEntry struct {
+	value    string
+	found    bool
+	loadedAt time.Time
+}
+
+// systemConfigSubscriber 配置变更订阅者
+type systemConfigSubscriber struct {
+	fn func(value string)
+}
+
+// SystemConfig 带缓存、写入校验和变更通知的系统配置
+// 读取：String/Int/Float/Bool/StringSlice/Seconds，缺失、为空或不满足校验规则时返回调用方给出的默认值
+// 写入：Set 先按键的校验规则检查，写入数据库后立即更新缓存并通知订阅者
+type SystemConfig struct {
+	load func(key string) (string, error)
+	save func(key, value string) error
+	now  func() time.Time
+	ttl  time.Duration
+
+	mu      sync.RWMutex
+	entries map[string]systemConfigEntry
+	subs    map[string][]*systemConfigSubscriber
+
+	warned sync.Map // 已提示过的无效值（key=value），避免每次读取都打印日志
+}
+
+// newSystemConfig 创建系统配置服务（load 未找到配置时返回 sql.ErrNoRows）
+func newSystemConfig(load func(key string) (string, error), save func(key, value string) error) *SystemConfig {
+	return &SystemConfig{
+		load:    load,
+		save:    save,
+		now:     time.Now,
+		ttl:     systemConfigCacheTTL,
+		entries: make(map[string]systemConfigEntry),
+		subs:    make(map[string][]*systemConfigSubscriber),
+	}
+}
+
+// SystemConfig 系统配置服务（首次调用时创建）
+func (d *Database) SystemConfig() *SystemConfig {
+	d.systemConfigOnce.Do(func() {
+		d.systemConfig = newSystemConfig(d.loadSystemConfig, d.saveSystemConfig)
+	})
+	return d.systemConfig
+}
+
+// Get 读取原始配置值，配置不存在时返回 sql.ErrNoRows
+func (s *SystemConfig) Get(key string) (string, error) {
+	s.mu.RLock()
+	entry, ok := s.entries[key]
+	s.mu.RUnlock()
+	if ok && s.now().Sub(entry.loadedAt) < s.ttl {
+		return entry.get()
+	}
+
+	value, err := s.load(key)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", err
+	}
+	s.store(key, systemConfigEntry{value: value, found: err == nil, loadedAt: s.now()}, ok)
+	return value, err
+}
+
+// get 缓存项对应的返回值
+func (e systemConfigEntry) get() (string, error) {
+	if !e.found {
+		return "", sql.ErrNoRows
+	}
+	return e.value, nil
+}
+
+// Set 校验并写入配置，成功后通知订阅者
+func (s *SystemConfig) Set(key, value string) error {
+	if err := ValidateSystemConfig(key, value); err != nil {
+		return err
+	}
+	if err := s.save(key, value); err != nil {
+		return err
+	}
+	s.store(key, systemConfigEntry{value: value, found: true, loadedAt: s.now()}, true)
+	return nil
+}
+
+// store 更新缓存，值相对之前缓存的值发生变化时通知订阅者（notify=false 表示首次加载，不通知）
+func (s *SystemConfig) store(key string, entry systemConfigEntry, notify bool) {
+	s.mu.Lock()
+	previous, cached := s.entries[key]
+	s.entries[key] = entry
+	var subs []*systemConfigSubscriber
+	if notify && (!cached || previous.value != entry.value || previous.found != entry.found) {
+		subs = append(subs, s.subs[key]...)
+	}
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		sub.fn(entry.value)
+	}
+}
+
+// Subscribe 订阅配置变更（通过 Set 修改或缓存过期后读到新值时回调），返回取消订阅函数
+func (s *SystemConfig) Subscribe(key string, fn func(value string)) func() {
+	sub := &systemConfigSubscriber{fn: fn}
+	s.mu.Lock()
+	s.subs[key] = append(s.subs[key], sub)
+	s.mu.Unlock()
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		subs := s.subs[key]
+		for i, existing := range subs {
+			if existing == sub {
+				s.subs[key] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Invalidate 清空缓存，下次读取时重新查询数据库
+func (s *SystemConfig) Invalidate() {
+	s.mu.Lock()
+	s.entries = make(map[string]systemConfigEntry)
+	s.mu.Unlock()
+}
+
+// lookup 读取有效的配置值：缺失、为空或不满足该键的校验规则时返回 false
+func (s *SystemConfig) lookup(key string) (string, bool) {
+	value, err := s.Get(key)
+	value = strings.TrimSpace(value)
+	if err != nil || value == "" {
+		return "", false
+	}
+	if err := ValidateSystemConfig(key, value); err != nil {
+		s.warnInvalid(key, value, err)
+		return "", false
+	}
+	return value, true
+}
+
+// warnInvalid 每个无效值只提示一次
+func (s *SystemConfig) warnInvalid(key, value string, err error) {
+	if _, loaded := s.warned.LoadOrStore(key+"="+value, struct{}{}); !loaded {
+		log.Printf("⚠️ 系统配置 %s=%q 无效，使用默认值: %v", key, value, err)
+	}
+}
+
+// String 读取字符串配置
+func (s *SystemConfig) String(key, def string) string {
+	if value, ok := s.lookup(key); ok {
+		return value
+	}
+	return def
+}
+
+// Int 读取整数配置
+func (s *SystemConfig) Int(key string, def int) int {
+	value, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		s.warnInvalid(key, value, err)
+		return def
+	}
+	return n
+}
+
+// Float 读取浮点数配置
+func (s *SystemConfig) Float(key string, def float64) float64 {
+	value, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		s.warnInvalid(key, value, err)
+		return def
+	}
+	return f
+}
+
+// Bool 读取布尔配置（true/false/1/0）
+func (s *SystemConfig) Bool(key string, def bool) bool {
+	value, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		s.warnInvalid(key, value, err)
+		return def
+	}
+	return b
+}
+
+// Seconds 读取以秒为单位的时长配置（支持小数）
+func (s *SystemConfig) Seconds(key string, def time.Duration) time.Duration {
+	value, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		s.warnInvalid(key, value, err)
+		return def
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// StringSlice 读取列表配置（JSON 字符串数组，或逗号分隔），列表为空时返回默认值
+func (s *SystemConfig) StringSlice(key string, def []string) []string {
+	value, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	items, err := parseStringSlice(value)
+	if err != nil {
+		s.warnInvalid(key, value, err)
+		return def
+	}
+	if len(items) == 0 {
+		return def
+	}
+	return items
+}
+
+// parseStringSlice 解析 JSON 字符串数组或逗号分隔的列表（去除空白和空项）
+func parseStringSlice(value string) ([]string, error) {
+	var raw []string
+	if strings.HasPrefix(value, "[") {
+		if err := json.Unmarshal([]byte(value), &raw); err != nil {
+			return nil, err
+		}
+	} else {
+		raw = strings.Split(value, ",")
+	}
+	items := make([]string, 0, len(raw))
+	for _, item := range raw {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// systemConfigValidators 各配置项的写入校验规则（未列出的键不校验；空值表示使用默认值，不校验）
+var systemConfigValidators = map[string]func(string) error{
+	"beta_mode":                     validateBool,
+	"use_default_coins":             validateBool,
+	"registration_enabled":          validateBool,
+	"cors_allow_credentials":        validateBool,
+	"api_server_port":               validateIntRange(1, 65535),
+	"metrics_port":                  validateIntRange(0, 65535),
+	"btc_eth_leverage":              validateIntRange(1, 125),
+	"altcoin_leverage":              validateIntRange(1, 125),
+	"daily_loss_reset_hour":         validateIntRange(0, 23),
+	"stop_trading_minutes":          validateIntRange(0, math.MaxInt32),
+	"rate_limit_auth":               validateIntRange(1, math.MaxInt32),
+	"rate_limit_public":             validateIntRange(1, math.MaxInt32),
+	"rate_limit_lockout":            validateIntRange(1, math.MaxInt32),
+	"rate_limit_refresh":            validateIntRange(1, math.MaxInt32),
+	"trusted_proxies":               validateTrustedProxies,
+	"trader_max_restarts":           validateIntRange(0, math.MaxInt32),
+	"kline_cache_max_candles":       validateIntRange(1, math.MaxInt32),
+	"login_max_failures":            validateIntRange(1, 1000),
+	"login_ip_max_failures":         validateIntRange(1, 100000),
+	"login_failure_window_minutes":  validateIntRange(1, 7*24*60),
+	"login_lockout_minutes":         validateIntRange(1, 7*24*60),
+	"smtp_port":                     validateIntRange(1, 65535),
+	"smtp_from":                     validateEmailAddress,
+	"app_base_url":                  validateHTTPURL,
+	"email_verification_required":   validateBool,
+	"email_send_per_hour":           validateIntRange(1, 1000),
+	"rsa_key_grace_hours":           validateIntRange(1, 30*24),
+	"pre_order_hook_timeout_ms":     validateIntRange(10, 60000),
+	"pre_order_hook_fallback":       validateOneOf("reject", "allow"),
+	"max_daily_loss":                validateFloatRange(0, 100),
+	"max_drawdown":                  validateFloatRange(0, 100),
+	"paper_slippage_pct":            validateFloatRange(0, 100),
+	"paper_taker_fee_pct":           validateFloatRange(0, 100),
+	"trader_resume_stagger_seconds": validateFloatRange(0, math.MaxFloat64),
+	"competition_cache_ttl_seconds": validatePositiveFloat,
+	"snapshot_interval_seconds":     validatePositiveFloat,
+	"default_coins":                 validateCoinList,
+	"market_proxy_url":              market.ValidateProxyURL,
+	"coin_pool_api_url":             validateHTTPURL,
+	"oi_top_api_url":                validateHTTPURL,
+}
+
+// ValidateSystemConfig 按配置项的校验规则检查取值
+func ValidateSystemConfig(key, value string) error {
+	validate, ok := systemConfigValidators[key]
+	value = strings.TrimSpace(value)
+	if !ok || value == "" {
+		return nil
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+// validateOneOf 取值必须是给定选项之一
+func validateOneOf(options ...string) func(string) error {
+	return func(value string) error {
+		for _, option := range options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("必须是 %s 之一", strings.Join(options, " / "))
+	}
+}
+
+// validateBool 布尔值
+func validateBool(value string) error {
+	if _, err := strconv.ParseBool(value); err != nil {
+		return fmt.Errorf("必须是 true 或 false")
+	}
+	return nil
+}
+
+// validateIntRange 闭区间内的整数
+func validateIntRange(min, max int) func(string) error {
+	return func(value string) error {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < min || n > max {
+			if max == math.MaxInt32 {
+				return fmt.Errorf("必须是不小于 %d 的整数", min)
+			}
+			return fmt.Errorf("必须是 %d 到 %d 之间的整数", min, max)
+		}
+		return nil
+	}
+}
+
+// validateFloatRange 闭区间内的数值
+func validateFloatRange(min, max float64) func(string) error {
+	return func(value string) error {
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || f < min || f > max {
+			if max == math.MaxFloat64 {
+				return fmt.Errorf("必须是不小于 %g 的数值", min)
+			}
+			return fmt.Errorf("必须是 %g 到 %g 之间的数值", min, max)
+		}
+		return nil
+	}
+}
+
+// validatePositiveFloat 大于0的数值
+func validatePositiveFloat(value string) error {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f <= 0 {
+		return fmt.Errorf("必须是大于0的数值")
+	}
+	return nil
+}
+
+// validateCoinList 非空的币种 JSON 数组
+func validateCoinList(value string) error {
+	var coins []string
+	if err := json.Unmarshal([]byte(value), &coins); err != nil {
+		return fmt.Errorf(`必须是币种的 JSON 数组，例如 ["BTCUSDT","ETHUSDT"]`)
+	}
+	if len(coins) == 0 {
+		return fmt.Errorf("币种列表不能为空")
+	}
+	for _, coin := range coins {
+		if strings.TrimSpace(coin) == "" {
+			return fmt.Errorf("币种不能为空字符串")
+		}
+	}
+	return nil
+}
+
+// validateTrustedProxies 逗号分隔的IP或CIDR
+func validateTrustedProxies(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			if _, _, err := net.ParseCIDR(entry); err != nil {
+				return fmt.Errorf("%q 不是有效的IP或CIDR", entry)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEmailAddress 邮件地址（可带显示名）
+func validateEmailAddress(value string) error {
+	if _, err := mail.ParseAddress(value); err != nil {
+		return fmt.Errorf("必须是有效的邮件地址，例如 NOFX <noreply@example.com>")
+	}
+	return nil
+}
+
+// validateHTTPURL http(s) 地址
+func validateHTTPURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("必须是 http:// 或 https:// 开头的地址")
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestSystemConfig_TypedGetters 类型化读取：缺失、为空或不满足校验规则时返回默认值
+func TestSystemConfig_TypedGetters(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/sysconfig.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	sc := db.SystemConfig()
+
+	// 绕过校验写入旧数据中可能存在的非法值
+	for key, value := range map[string]string{
+		"btc_eth_leverage":          "0",
+		"altcoin_leverage":          "abc",
+		"registration_enabled":      "false",
+		"cors_allowed_origins":      " https://a.com, ,https://b.com ",
+		"snapshot_interval_seconds": "1.5",
+	} {
+		if err := db.saveSystemConfig(key, value); err != nil {
+			t.Fatalf("写入配置失败: %v", err)
+		}
+	}
+
+	if got := sc.Int("btc_eth_leverage", 5); got != 5 {
+		t.Errorf("超出范围的杠杆应返回默认值，实际 %d", got)
+	}
+	if got := sc.Int("altcoin_leverage", 5); got != 5 {
+		t.Errorf("非数字的杠杆应返回默认值，实际 %d", got)
+	}
+	if got := sc.Int("missing_key", 7); got != 7 {
+		t.Errorf("缺失的配置应返回默认值，实际 %d", got)
+	}
+	if sc.Bool("registration_enabled", true) {
+		t.Error("registration_enabled=false 应读取为 false")
+	}
+	if got := sc.Float("max_drawdown", 1); got != 20 {
+		t.Errorf("应读取默认数据中的 max_drawdown=20.0，实际 %v", got)
+	}
+	if got := sc.Seconds("snapshot_interval_seconds", time.Minute); got != 1500*time.Millisecond {
+		t.Errorf("秒数配置解析错误: %v", got)
+	}
+	if got := sc.StringSlice("cors_allowed_origins", nil); !reflect.DeepEqual(got, []string{"https://a.com", "https://b.com"}) {
+		t.Errorf("逗号分隔的列表解析错误: %v", got)
+	}
+	if got := sc.StringSlice("default_coins", nil); len(got) != 8 || got[0] != "BTCUSDT" {
+		t.Errorf("JSON数组解析错误: %v", got)
+	}
+}
+
+// TestSystemConfig_SetValidatesAndNotifies 写入前校验，写入后立即可读并通知订阅者
+func TestSystemConfig_SetValidatesAndNotifies(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/sysconfig.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	sc := db.SystemConfig()
+
+	for key, value := range map[string]string{
+		"beta_mode":        "yes",
+		"btc_eth_leverage": "200",
+		"default_coins":    "BTCUSDT",
+		"market_proxy_url": "ftp://proxy:21",
+		"max_daily_loss":   "-1",
+	} {
+		if err := db.SetSystemConfig(key, value); err == nil {
+			t.Errorf("%s=%q 应校验失败", key, value)
+		}
+	}
+	if sc.Bool("beta_mode", true) {
+		t.Error("校验失败的写入不应生效")
+	}
+
+	var notified []string
+	unsubscribe := sc.Subscribe("beta_mode", func(value string) { notified = append(notified, value) })
+	if err := db.SetSystemConfig("beta_mode", "true"); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if !sc.Bool("beta_mode", false) {
+		t.Error("写入后应立即读取到新值")
+	}
+	// 值未变化不通知
+	if err := db.SetSystemConfig("beta_mode", "true"); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	unsubscribe()
+	if err := db.SetSystemConfig("beta_mode", "false"); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if !reflect.DeepEqual(notified, []string{"true"}) {
+		t.Errorf("订阅者应只收到一次变更通知，实际 %v", notified)
+	}
+}
+
+// TestSystemConfig_CacheRefresh 读取走缓存，过期后重新读取数据库并通知其他实例写入的变更
+func TestSystemConfig_CacheRefresh(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/sysconfig.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	sc := db.SystemConfig()
+	now := time.Now()
+	sc.now = func() time.Time { return now }
+
+	var notified []string
+	sc.Subscribe("altcoin_leverage", func(value string) { notified = append(notified, value) })
+	if got := sc.Int("altcoin_leverage", 0); got != 5 {
+		t.Fatalf("应读取默认杠杆 5，实际 %d", got)
+	}
+
+	// 模拟其他实例直接修改数据库
+	if err := db.saveSystemConfig("altcoin_leverage", "10"); err != nil {
+		t.Fatalf("写入配置失败: %v", err)
+	}
+	if got := sc.Int("altcoin_leverage", 0); got != 5 {
+		t.Errorf("缓存有效期内应返回缓存值，实际 %d", got)
+	}
+	now = now.Add(systemConfigCacheTTL)
+	if got := sc.Int("altcoin_leverage", 0); got != 10 {
+		t.Errorf("缓存过期后应读取到新值，实际 %d", got)
+	}
+	if !reflect.DeepEqual(notified, []string{"10"}) {
+		t.Errorf("缓存刷新读到新值时应通知订阅者，实际 %v", notified)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultUserTimezone 未设置时区的用户按 UTC 划分日期
+	DefaultUserTimezone = "UTC"
+	// DefaultDisplayCurrency 默认计价显示币种
+	DefaultDisplayCurrency = "USDT"
+	// defaultReportHour 定时报告默认发送时间（用户时区的小时）
+	defaultReportHour = 8
+)
+
+// SupportedDisplayCurrencies 可选的计价显示币种（仅影响前端显示，账户数据仍以 USDT 计价）
+var SupportedDisplayCurrencies = []string{"USDT", "USDC", "USD", "EUR", "CNY", "BTC"}
+
+// NotificationPreferences 用户通知偏好
+type NotificationPreferences struct {
+	TradeEvents  bool `json:"trade_events"`  // 开仓/平仓通知
+	RiskAlerts   bool `json:"risk_alerts"`   // 熔断、交易员异常停止等风险告警
+	SpendAlerts  bool `json:"spend_alerts"`  // AI花费预算和余额告警
+	DailyReport  bool `json:"daily_report"`  // 每日报告
+	WeeklyReport bool `json:"weekly_report"` // 每周报告
+	ReportHour   int  `json:"report_hour"`   // 报告发送时间（用户时区的小时，0-23）
+}
+
+// DefaultNotificationPreferences 默认通知偏好：告警类全部开启，定时报告关闭
+func DefaultNotificationPreferences() NotificationPreferences {
+	return NotificationPreferences{
+		TradeEvents: true,
+		RiskAlerts:  true,
+		SpendAlerts: true,
+		ReportHour:  defaultReportHour,
+	}
+}
+
+// UserSettings 用户个人设置
+type UserSettings struct {
+	UserID          string                  `json:"user_id"`
+	Timezone        string                  `json:"timezone"`         // IANA 时区名（如 Asia/Shanghai），用于每日盈亏、报告时间等按日期划分的统计
+	DisplayCurrency string                  `json:"display_currency"` // 计价显示币种
+	Notifications   NotificationPreferences `json:"notifications"`
+	UpdatedAt       time.Time               `json:"updated_at,omitempty"`
+}
+
+// Location 用户时区（无效时使用 UTC）
+func (s *UserSettings) Location() *time.Location {
+	if loc, err := LoadUserTimezone(s.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// LoadUserTimezone 校验并加载 IANA 时区名（不接受空字符串和 Local，避免依赖服务器时区）
+func LoadUserTimezone(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("无效的时区: %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("无效的时区: %s", name)
+	}
+	return loc, nil
+}
+
+// defaultUserSettings 未保存过设置的用户使用的默认值
+func defaultUserSettings(userID string) *UserSettings {
+	return &UserSettings{
+		UserID:          userID,
+		Timezone:        DefaultUserTimezone,
+		DisplayCurrency: DefaultDisplayCurrency,
+		Notifications:   DefaultNotificationPreferences(),
+	}
+}
+
+// Validate 校验设置
+func (s *UserSettings) Validate() error {
+	if _, err := LoadUserTimezone(s.Timezone); err != nil {
+		return err
+	}
+	if !slices.Contains(SupportedDisplayCurrencies, s.DisplayCurrency) {
+		return fmt.Errorf("不支持的显示币种: %s（支持 %s）", s.DisplayCurrency, strings.Join(SupportedDisplayCurrencies, ", "))
+	}
+	if s.Notifications.ReportHour < 0 || s.Notifications.ReportHour > 23 {
+		return fmt.Errorf("报告发送时间必须在0-23之间: %d", s.Notifications.ReportHour)
+	}
+	return nil
+}
+
+// GetUserSettings 获取用户设置（未保存过时返回默认值）
+func (d *Database) GetUserSettings(userID string) (*UserSettings, error) {
+	settings := defaultUserSettings(userID)
+
+	var notifications string
+	var updatedAt sql.NullTime
+	err := d.db.QueryRow(`
+		SELECT timezone, display_currency, notifications, updated_at
+		FROM user_settings WHERE user_id = ?
+	`, userID).Scan(&settings.Timezone, &settings.DisplayCurrency, &notifications, &updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("获取用户设置失败: %w", err)
+	}
+
+	settings.UpdatedAt = updatedAt.Time
+	if notifications != "" {
+		if err := json.Unmarshal([]byte(notifications), &settings.Notifications); err != nil {
+			settings.Notifications = DefaultNotificationPreferences()
+		}
+	}
+	return settings, nil
+}
+
+// UpdateUserSettings 校验并保存用户设置
+func (d *Database) UpdateUserSettings(settings *UserSettings) error {
+	settings.Timezone = strings.TrimSpace(settings.Timezone)
+	settings.DisplayCurrency = strings.ToUpper(strings.TrimSpace(settings.DisplayCurrency))
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	notifications, err := json.Marshal(settings.Notifications)
+	if err != nil {
+		return fmt.Errorf("序列化通知偏好失败: %w", err)
+	}
+	_, err = d.db.Exec(`
+		INSERT INTO user_settings (user_id, timezone, display_currency, notifications, updated_at)
+		VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(user_id) DO UPDATE SET
+			timezone = excluded.timezone,
+			display_currency = excluded.display_currency,
+			notifications = excluded.notifications,
+			updated_at = CURRENT_TIMESTAMP
+	`, settings.UserID, settings.Timezone, settings.DisplayCurrency, string(notifications))
+	if err != nil {
+		return fmt.Errorf("保存用户设置失败: %w", err)
+	}
+	return nil
+}
+
+// GetUserLocation 用户时区（读取失败或未设置时为 UTC）
+func (d *Database) GetUserLocation(userID string) *time.Location {
+	settings, err := d.GetUserSettings(userID)
+	if err != nil {
+		return time.UTC
+	}
+	return settings.Location()
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"testing"
+)
+
+// TestUserSettings_DefaultsAndValidation 未保存过设置时返回默认值，保存时校验时区和显示币种
+func TestUserSettings_DefaultsAndValidation(t *testing.T) {
+	db, err := NewDatabase(t.TempDir() + "/settings.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+
+	settings, err := db.GetUserSettings("user-a")
+	if err != nil {
+		t.Fatalf("获取用户设置失败: %v", err)
+	}
+	if settings.Timezone != "UTC" || settings.DisplayCurrency != "USDT" || !settings.Notifications.RiskAlerts || settings.Notifications.DailyReport {
+		t.Fatalf("默认设置不正确: %+v", settings)
+	}
+
+	for _, invalid := range []*UserSettings{
+		{UserID: "user-a", Timezone: "Mars/Olympus", DisplayCurrency: "USDT"},
+		{UserID: "user-a", Timezone: "Local", DisplayCurrency: "USDT"},
+		{UserID: "user-a", Timezone: "UTC", DisplayCurrency: "DOGE"},
+		{UserID: "user-a", Timezone: "UTC", DisplayCurrency: "USDT", Notifications: NotificationPreferences{ReportHour: 24}},
+	} {
+		if err := db.UpdateUserSettings(invalid); err == nil {
+			t.Errorf("应拒绝无效设置: %+v", invalid)
+		}
+	}
+
+	settings.Timezone = "Asia/Shanghai"
+	settings.DisplayCurrency = "cny"
+	settings.Notifications.DailyReport = true
+	if err := db.UpdateUserSettings(settings); err != nil {
+		t.Fatalf("保存用户设置失败: %v", err)
+	}
+	saved, err := db.GetUserSettings("user-a")
+	if err != nil {
+		t.Fatalf("获取用户设置失败: %v", err)
+	}
+	if saved.DisplayCurrency != "CNY" || !saved.Notifications.DailyReport || saved.UpdatedAt.IsZero() {
+		t.Errorf("保存后的设置不正确: %+v", saved)
+	}
+	if loc := db.GetUserLocation("user-a"); loc.String() != "Asia/Shanghai" {
+		t.Errorf("用户时区应为 Asia/Shanghai，实际 %s", loc)
+	}
+	if loc := db.GetUserLocation("user-b"); loc.String() != "UTC" {
+		t.Errorf("未设置时区的用户应使用 UTC，实际 %s", loc)
+	}
+}
@@ -0,0 +1,248 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"nofx/notify"
+
+	"github.com/google/uuid"
+)
+
+// MaxWebhooks 每个用户最多配置的 Webhook 数
+const MaxWebhooks = 10
+
+// maxWebhookDeliveries 每个 Webhook 保留的死信记录数
+const maxWebhookDeliveries = 100
+
+// ErrWebhookNotFound Webhook 不存在或不属于该用户
+var ErrWebhookNotFound = errors.New("Webhook 不存在")
+
+// WebhookAllEvents 订阅全部事件类型
+const WebhookAllEvents = "*"
+
+// Webhook 用户的出站 Webhook 订阅（Secret 用于请求签名，存储时加密）
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"` // 订阅的事件类型，包含 "*" 时订阅全部
+	Enabled   bool      `json:"enabled"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate 校验地址和事件类型（allowedEvents 为可订阅的事件类型）
+func (w *Webhook) Validate(allowedEvents []string) error {
+	w.URL = strings.TrimSpace(w.URL)
+	if err := notify.ValidateTargetURL(w.URL); err != nil {
+		return fmt.Errorf("url %w", err)
+	}
+	if w.Secret == "" {
+		return fmt.Errorf("secret 不能为空")
+	}
+	if len(w.Events) == 0 {
+		return fmt.Errorf("至少订阅一个事件类型")
+	}
+	for _, event := range w.Events {
+		if event == WebhookAllEvents {
+			continue
+		}
+		found := false
+		for _, allowed := range allowedEvents {
+			if event == allowed {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("不支持的事件类型: %s（支持 %s 或 *）", event, strings.Join(allowedEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// Subscribes 是否订阅了该事件类型
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.Events {
+		if e == event || e == WebhookAllEvents {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 最终投递失败的 Webhook 请求（死信）
+type WebhookDelivery struct {
+	ID         string    `json:"id"`
+	WebhookID  string    `json:"webhook_id"`
+	UserID     string    `json:"-"`
+	Event      string    `json:"event"`
+	Payload    string    `json:"payload"`     // 请求体 JSON
+	StatusCode int       `json:"status_code"` // 最后一次响应状态码（连接失败、超时时为0）
+	Error      string    `json:"error"`
+	Attempts   int       `json:"attempts"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ListWebhooks 获取用户的全部 Webhook（按创建时间排序）
+func (d *Database) ListWebhooks(userID string) ([]*Webhook, error) {
+	rows, err := d.db.Query(`
+		SELECT id, url, secret, events, enabled, created_at, updated_at
+		FROM webhooks WHERE user_id = ?
+		ORDER BY created_at, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取Webhook失败: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := make([]*Webhook, 0)
+	for rows.Next() {
+		w := &Webhook{UserID: userID}
+		var secret, events string
+		var createdAt, updatedAt int64
+		if err := rows.Scan(&w.ID, &w.URL, &secret, &events, &w.Enabled, &createdAt, &updatedAt); err != nil {
+			return nil, fmt.Errorf("读取Webhook失败: %w", err)
+		}
+		w.Secret = d.decryptSensitiveData(secret)
+		w.Events = splitWebhookEvents(events)
+		w.CreatedAt = time.UnixMilli(createdAt).UTC()
+		w.UpdatedAt = time.UnixMilli(updatedAt).UTC()
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+func splitWebhookEvents(events string) []string {
+	result := make([]string, 0)
+	for _, event := range strings.Split(events, ",") {
+		if event = strings.TrimSpace(event); event != "" {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// GetWebhook 获取用户的一个 Webhook
+func (d *Database) GetWebhook(userID, id string) (*Webhook, error) {
+	webhooks, err := d.ListWebhooks(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range webhooks {
+		if w.ID == id {
+			return w, nil
+		}
+	}
+	return nil, ErrWebhookNotFound
+}
+
+// CreateWebhook 新建 Webhook（生成ID和时间戳）
+func (d *Database) CreateWebhook(w *Webhook, now time.Time) error {
+	w.ID = uuid.New().String()
+	w.CreatedAt = now.UTC()
+	w.UpdatedAt = now.UTC()
+
+	return d.writeTx(func(tx *sqlTx) error {
+		var count int
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE user_id = ?`, w.UserID).Scan(&count); err != nil {
+			return err
+		}
+		if count >= MaxWebhooks {
+			return fmt.Errorf("最多配置 %d 个Webhook", MaxWebhooks)
+		}
+		_, err := tx.Exec(`
+			INSERT INTO webhooks (id, user_id, url, secret, events, enabled, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, w.ID, w.UserID, w.URL, d.encryptSensitiveData(w.Secret), strings.Join(w.Events, ","), w.Enabled, now.UnixMilli(), now.UnixMilli())
+		return err
+	})
+}
+
+// UpdateWebhook 更新 Webhook 的地址、密钥、订阅事件和启用状态
+func (d *Database) UpdateWebhook(w *Webhook, now time.Time) error {
+	result, err := d.db.Exec(`
+		UPDATE webhooks SET url = ?, secret = ?, events = ?, enabled = ?, updated_at = ?
+		WHERE id = ? AND user_id = ?
+	`, w.URL, d.encryptSensitiveData(w.Secret), strings.Join(w.Events, ","), w.Enabled, now.UnixMilli(), w.ID, w.UserID)
+	if err != nil {
+		return fmt.Errorf("更新Webhook失败: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return ErrWebhookNotFound
+	}
+	w.UpdatedAt = now.UTC()
+	return nil
+}
+
+// DeleteWebhook 删除 Webhook 及其死信记录
+func (d *Database) DeleteWebhook(userID, id string) error {
+	return d.writeTx(func(tx *sqlTx) error {
+		result, err := tx.Exec(`DELETE FROM webhooks WHERE id = ? AND user_id = ?`, id, userID)
+		if err != nil {
+			return fmt.Errorf("删除Webhook失败: %w", err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return ErrWebhookNotFound
+		}
+		_, err = tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ? AND user_id = ?`, id, userID)
+		return err
+	})
+}
+
+// RecordWebhookDelivery 记录一次最终投递失败的请求，每个 Webhook 只保留最近 maxWebhookDeliveries 条
+func (d *Database) RecordWebhookDelivery(delivery *WebhookDelivery) error {
+	delivery.ID = uuid.New().String()
+	if delivery.CreatedAt.IsZero() {
+		delivery.CreatedAt = time.Now().UTC()
+	}
+	return d.writeTx(func(tx *sqlTx) error {
+		if _, err := tx.Exec(`
+			INSERT INTO webhook_deliveries (id, webhook_id, user_id, event, payload, status_code, error, attempts, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`, delivery.ID, delivery.WebhookID, delivery.UserID, delivery.Event, delivery.Payload,
+			delivery.StatusCode, delivery.Error, delivery.Attempts, delivery.CreatedAt.UnixMilli()); err != nil {
+			return fmt.Errorf("记录Webhook死信失败: %w", err)
+		}
+		_, err := tx.Exec(`
+			DELETE FROM webhook_deliveries WHERE webhook_id = ? AND id NOT IN (
+				SELECT id FROM webhook_deliveries WHERE webhook_id = ?
+				ORDER BY created_at DESC, id DESC LIMIT ?
+			)
+		`, delivery.WebhookID, delivery.WebhookID, maxWebhookDeliveries)
+		return err
+	})
+}
+
+// ListWebhookDeliveries 获取 Webhook 的死信记录（最新的在前）
+func (d *Database) ListWebhookDeliveries(userID, webhookID string, limit int) ([]*WebhookDelivery, error) {
+	if limit <= 0 || limit > maxWebhookDeliveries {
+		limit = maxWebhookDeliveries
+	}
+	rows, err := d.db.Query(`
+		SELECT id, event, payload, status_code, error, attempts, created_at
+		FROM webhook_deliveries WHERE webhook_id = ? AND user_id = ?
+		ORDER BY created_at DESC, id DESC LIMIT ?
+	`, webhookID, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("获取Webhook死信失败: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*WebhookDelivery, 0)
+	for rows.Next() {
+		delivery := &WebhookDelivery{WebhookID: webhookID, UserID: userID}
+		var createdAt int64
+		if err := rows.Scan(&delivery.ID, &delivery.Event, &delivery.Payload, &delivery.StatusCode,
+			&delivery.Error, &delivery.Attempts, &createdAt); err != nil {
+			return nil, fmt.Errorf("读取Webhook死信失败: %w", err)
+		}
+		delivery.CreatedAt = time.UnixMilli(createdAt).UTC()
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/crypto"
+)
+
+// TestWebhooks 密钥加密存储、按用户隔离、事件订阅、死信记录保留上限和删除时级联清理
+func TestWebhooks(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "test-data-key")
+	db, err := NewDatabase(t.TempDir() + "/webhooks.db")
+	if err != nil {
+		t.Fatalf("打开数据库失败: %v", err)
+	}
+	defer db.Close()
+	cs, err := crypto.NewCryptoService(t.TempDir() + "/rsa_key")
+	if err != nil {
+		t.Fatalf("初始化加密服务失败: %v", err)
+	}
+	db.SetCryptoService(cs)
+
+	allowed := []string{"state_changed", "position_opened"}
+	if err := (&Webhook{URL: "https://example.com", Secret: "s", Events: []string{"unknown"}}).Validate(allowed); err == nil {
+		t.Error("不支持的事件类型应拒绝")
+	}
+	if err := (&Webhook{URL: "http://169.254.169.254/latest/meta-data", Secret: "s", Events: []string{"*"}}).Validate(allowed); err == nil {
+		t.Error("链路本地地址应拒绝")
+	}
+
+	now := time.Now()
+	w := &Webhook{UserID: "user-1", URL: "https://example.com/hook", Secret: "SECRET-VALUE", Events: []string{"position_opened"}, Enabled: true}
+	if err := w.Validate(allowed); err != nil {
+		t.Fatalf("校验失败: %v", err)
+	}
+	if err := db.CreateWebhook(w, now); err != nil {
+		t.Fatalf("创建Webhook失败: %v", err)
+	}
+
+	var stored string
+	db.db.QueryRow(`SELECT secret FROM webhooks WHERE id = ?`, w.ID).Scan(&stored)
+	if strings.Contains(stored, "SECRET-VALUE") {
+		t.Errorf("密钥应加密存储: %s", stored)
+	}
+	got, err := db.GetWebhook("user-1", w.ID)
+	if err != nil || got.Secret != "SECRET-VALUE" || !got.Subscribes("position_opened") || got.Subscribes("state_changed") {
+		t.Fatalf("读取Webhook不正确: %+v %v", got, err)
+	}
+	if _, err := db.GetWebhook("user-2", w.ID); err != ErrWebhookNotFound {
+		t.Error("不能读取其他用户的Webhook")
+	}
+
+	got.Events = []string{WebhookAllEvents}
+	if err := db.UpdateWebhook(got, now); err != nil {
+		t.Fatalf("更新Webhook失败: %v", err)
+	}
+	if got, _ := db.GetWebhook("user-1", w.ID); !got.Subscribes("state_changed") {
+		t.Errorf("订阅 * 后应包含全部事件: %+v", got)
+	}
+
+	for i := 0; i < maxWebhookDeliveries+5; i++ {
+		if err := db.RecordWebhookDelivery(&WebhookDelivery{
+			WebhookID: w.ID, UserID: "user-1", Event: "position_opened",
+			Payload: fmt.Sprintf(`{"n":%d}`, i), Attempts: 3, CreatedAt: now.Add(time.Duration(i) * time.Millisecond),
+		}); err != nil {
+			t.Fatalf("记录死信失败: %v", err)
+		}
+	}
+	deliveries, err := db.ListWebhookDeliveries("user-1", w.ID, 0)
+	if err != nil || len(deliveries) != maxWebhookDeliveries {
+		t.Fatalf("死信应只保留 %d 条，实际 %d (%v)", maxWebhookDeliveries, len(deliveries), err)
+	}
+	if deliveries[0].Payload != fmt.Sprintf(`{"n":%d}`, maxWebhookDeliveries+4) {
+		t.Errorf("最新的死信应在前: %+v", deliveries[0])
+	}
+	if other, _ := db.ListWebhookDeliveries("user-2", w.ID, 0); len(other) != 0 {
+		t.Error("不能读取其他用户的死信")
+	}
+
+	if err := db.DeleteWebhook("user-2", w.ID); err != ErrWebhookNotFound {
+		t.Error("不能删除其他用户的Webhook")
+	}
+	if err := db.DeleteWebhook("user-1", w.ID); err != nil {
+		t.Fatalf("删除Webhook失败: %v", err)
+	}
+	if deliveries, _ := db.ListWebhookDeliveries("user-1", w.ID, 0); len(deliveries) != 0 {
+		t.Errorf("删除Webhook后应清理死信，剩余 %d 条", len(deliveries))
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -17,6 +16,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -39,13 +39,17 @@ type AADData struct {
 	UserID    string `json:"userId"`
 	SessionID string `json:"sessionId"`
 	TS        int64  `json:"ts"`
+	Nonce     string `json:"nonce"`
 	Purpose   string `json:"purpose"`
 }
 
 type CryptoService struct {
-	privateKey *rsa.PrivateKey
-	publicKey  *rsa.PublicKey
-	dataKey    []byte
+	mu      sync.RWMutex
+	keyPath string
+	current *rsaKey
+	retired []*rsaKey
+	dataKey []byte
+	nonces  nonceCache
 }
 
 func NewCryptoService(privateKeyPath string) (*CryptoService, error) {
@@ -68,15 +72,23 @@ func NewCryptoService(privateKeyPath string) (*CryptoService, error) {
 		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
 
+	// 读取轮换后仍在宽限期内的旧私钥
+	currentID := KeyID(&privateKey.PublicKey)
+	retired, err := loadRetiredKeys(privateKeyPath, currentID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	dataKey, err := loadDataKeyFromEnv()
 	if err != nil {
 		return nil, fmt.Errorf("failed to load data encryption key: %w", err)
 	}
 
 	return &CryptoService{
-		privateKey: privateKey,
-		publicKey:  &privateKey.PublicKey,
-		dataKey:    dataKey,
+		keyPath: privateKeyPath,
+		current: &rsaKey{id: currentID, privateKey: privateKey},
+		retired: retired,
+		dataKey: dataKey,
 	}, nil
 }
 
@@ -95,14 +107,19 @@ func GenerateRSAKeyPair(privateKeyPath string) error {
 		return err
 	}
 
-	// 编码私钥
-	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+	return writeRSAKeyPair(privateKeyPath, privateKey)
+}
+
+func encodePrivateKeyPEM(privateKey *rsa.PrivateKey) []byte {
+	return pem.EncodeToMemory(&pem.Block{
 		Type:  "RSA PRIVATE KEY",
 		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
 	})
+}
 
+func writeRSAKeyPair(privateKeyPath string, privateKey *rsa.PrivateKey) error {
 	// 保存私钥
-	if err := ioutil.WriteFile(privateKeyPath, privateKeyPEM, 0600); err != nil {
+	if err := writeFileAtomic(privateKeyPath, encodePrivateKeyPEM(privateKey), 0600); err != nil {
 		return err
 	}
 
@@ -119,7 +136,7 @@ func GenerateRSAKeyPair(privateKeyPath string) error {
 
 	// 保存公钥
 	publicKeyPath := privateKeyPath + ".pub"
-	if err := ioutil.WriteFile(publicKeyPath, publicKeyPEM, 0644); err != nil {
+	if err := writeFileAtomic(publicKeyPath, publicKeyPEM, 0644); err != nil {
 		return err
 	}
 
@@ -203,7 +220,11 @@ func (cs *CryptoService) HasDataKey() bool {
 }
 
 func (cs *CryptoService) GetPublicKeyPEM() string {
-	publicKeyDER, err := x509.MarshalPKIXPublicKey(cs.publicKey)
+	cs.mu.RLock()
+	publicKey := &cs.current.privateKey.PublicKey
+	cs.mu.RUnlock()
+
+	publicKeyDER, err := x509.MarshalPKIXPublicKey(publicKey)
 	if err != nil {
 		return ""
 	}
@@ -346,17 +367,19 @@ func (cs *CryptoService) DecryptPayload(payload *EncryptedPayload) ([]byte, erro
 		if err != nil {
 			return nil, fmt.Errorf("failed to decode AAD: %w", err)
 		}
+	}
 
-		// 验证 AAD
-		var aadData AADData
-		if err := json.Unmarshal(aad, &aadData); err == nil {
-			// 可以在这里添加额外的验证逻辑
-			// 例如：验证 sessionID、userID 等
+	// 3. 按 KID 选择私钥，使用 RSA-OAEP 解密 AES 密钥
+	privateKeys, err := cs.decryptionKeys(payload.KID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	var aesKey []byte
+	for _, privateKey := range privateKeys {
+		if aesKey, err = rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, wrappedKey, nil); err == nil {
+			break
 		}
 	}
-
-	// 3. 使用 RSA-OAEP 解密 AES 密钥
-	aesKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, cs.privateKey, wrappedKey, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to unwrap AES key: %w", err)
 	}
@@ -385,10 +408,15 @@ func (cs *CryptoService) DecryptPayload(payload *EncryptedPayload) ([]byte, erro
 	return plaintext, nil
 }
 
-func (cs *CryptoService) DecryptSensitiveData(payload *EncryptedPayload) (string, error) {
+// DecryptSensitiveData 解密前端加密的敏感数据，并要求 AAD 绑定到 userID、在有效期内且 nonce 未使用过
+// AAD 在解密成功（通过 GCM 认证）后才校验，伪造的 AAD 不会占用 nonce
+func (cs *CryptoService) DecryptSensitiveData(payload *EncryptedPayload, userID string) (string, error) {
 	plaintext, err := cs.DecryptPayload(payload)
 	if err != nil {
 		return "", err
 	}
+	if err := cs.verifyAAD(payload, userID, time.Now()); err != nil {
+		return "", err
+	}
 	return string(plaintext), nil
 }
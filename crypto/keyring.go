@@ -0,0 +1,224 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultKeyGracePeriod 輪換後舊公鑰加密的數據仍可解密的默認時長
+const DefaultKeyGracePeriod = 24 * time.Hour
+
+// rsaKey 密鑰環中的一把 RSA 私鑰（當前密鑰的 expiresAt 為零值）
+type rsaKey struct {
+	id         string
+	privateKey *rsa.PrivateKey
+	retiredAt  time.Time
+	expiresAt  time.Time
+}
+
+// KeyInfo 密鑰環狀態（不含私鑰）
+type KeyInfo struct {
+	ID        string     `json:"id"`
+	Current   bool       `json:"current"`
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// retiredKeyMeta 舊密鑰的元數據，保存在 <私鑰路徑>.keyring.json
+type retiredKeyMeta struct {
+	KID       string    `json:"kid"`
+	RetiredAt time.Time `json:"retired_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// KeyID 公鑰標識：DER 編碼公鑰 SHA-256 的前 8 字節（十六進制）
+func KeyID(publicKey *rsa.PublicKey) string {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8])
+}
+
+func keyringMetaPath(privateKeyPath string) string {
+	return privateKeyPath + ".keyring.json"
+}
+
+func retiredKeyPath(privateKeyPath, kid string) string {
+	return privateKeyPath + "." + kid + ".retired"
+}
+
+// loadRetiredKeys 讀取仍在寬限期內的舊私鑰，並刪除已過期的舊私鑰文件
+func loadRetiredKeys(privateKeyPath, currentID string, now time.Time) ([]*rsaKey, error) {
+	data, err := os.ReadFile(keyringMetaPath(privateKeyPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read keyring: %w", err)
+	}
+
+	var metas []retiredKeyMeta
+	if err := json.Unmarshal(data, &metas); err != nil {
+		return nil, fmt.Errorf("failed to parse keyring: %w", err)
+	}
+
+	var keys []*rsaKey
+	for _, meta := range metas {
+		path := retiredKeyPath(privateKeyPath, meta.KID)
+		// 輪換中途退出時當前密鑰可能已記為舊密鑰
+		if meta.KID == currentID {
+			continue
+		}
+		if !now.Before(meta.ExpiresAt) {
+			os.Remove(path)
+			continue
+		}
+		pemBytes, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read retired key %s: %w", meta.KID, err)
+		}
+		privateKey, err := ParseRSAPrivateKeyFromPEM(pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse retired key %s: %w", meta.KID, err)
+		}
+		keys = append(keys, &rsaKey{
+			id:         meta.KID,
+			privateKey: privateKey,
+			retiredAt:  meta.RetiredAt,
+			expiresAt:  meta.ExpiresAt,
+		})
+	}
+	return keys, nil
+}
+
+// saveRetiredKeys 寫入舊密鑰元數據
+func saveRetiredKeys(privateKeyPath string, keys []*rsaKey) error {
+	metas := make([]retiredKeyMeta, 0, len(keys))
+	for _, key := range keys {
+		metas = append(metas, retiredKeyMeta{KID: key.id, RetiredAt: key.retiredAt, ExpiresAt: key.expiresAt})
+	}
+	data, err := json.MarshalIndent(metas, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(keyringMetaPath(privateKeyPath), data, 0600)
+}
+
+// writeFileAtomic 先寫臨時文件再重命名，避免中途退出留下不完整的密鑰文件
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// CurrentKeyID 當前公鑰標識（客戶端加密時放入 EncryptedPayload.KID）
+func (cs *CryptoService) CurrentKeyID() string {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+	return cs.current.id
+}
+
+// Keys 當前密鑰和仍在寬限期內的舊密鑰
+func (cs *CryptoService) Keys() []KeyInfo {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	now := time.Now()
+	keys := []KeyInfo{{ID: cs.current.id, Current: true}}
+	for _, key := range cs.retired {
+		if !now.Before(key.expiresAt) {
+			continue
+		}
+		retiredAt, expiresAt := key.retiredAt, key.expiresAt
+		keys = append(keys, KeyInfo{ID: key.id, RetiredAt: &retiredAt, ExpiresAt: &expiresAt})
+	}
+	return keys
+}
+
+// RotateKey 生成新的 RSA 密鑰對並設為當前密鑰，舊密鑰在 grace 時長內仍可用於解密
+func (cs *CryptoService) RotateKey(grace time.Duration) (string, error) {
+	if cs.keyPath == "" {
+		return "", errors.New("key rotation requires a private key file")
+	}
+	if grace <= 0 {
+		grace = DefaultKeyGracePeriod
+	}
+
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	now := time.Now()
+	old := &rsaKey{
+		id:         cs.current.id,
+		privateKey: cs.current.privateKey,
+		retiredAt:  now,
+		expiresAt:  now.Add(grace),
+	}
+	retired := []*rsaKey{old}
+	for _, key := range cs.retired {
+		if now.Before(key.expiresAt) {
+			retired = append(retired, key)
+		} else {
+			os.Remove(retiredKeyPath(cs.keyPath, key.id))
+		}
+	}
+
+	// 先保存舊密鑰再替換當前密鑰，任一步失敗都不會丟失仍在使用的私鑰
+	if err := writeFileAtomic(retiredKeyPath(cs.keyPath, old.id), encodePrivateKeyPEM(old.privateKey), 0600); err != nil {
+		return "", fmt.Errorf("failed to save retired key: %w", err)
+	}
+	if err := saveRetiredKeys(cs.keyPath, retired); err != nil {
+		return "", fmt.Errorf("failed to save keyring: %w", err)
+	}
+	if err := writeRSAKeyPair(cs.keyPath, newKey); err != nil {
+		return "", fmt.Errorf("failed to save new key: %w", err)
+	}
+
+	cs.current = &rsaKey{id: KeyID(&newKey.PublicKey), privateKey: newKey}
+	cs.retired = retired
+	return cs.current.id, nil
+}
+
+// decryptionKeys 按 KID 選擇解密私鑰；未帶 KID 的舊客戶端依次嘗試當前密鑰和寬限期內的舊密鑰
+func (cs *CryptoService) decryptionKeys(kid string, now time.Time) ([]*rsa.PrivateKey, error) {
+	cs.mu.RLock()
+	defer cs.mu.RUnlock()
+
+	if kid == "" || kid == cs.current.id {
+		keys := []*rsa.PrivateKey{cs.current.privateKey}
+		if kid == "" {
+			for _, key := range cs.retired {
+				if now.Before(key.expiresAt) {
+					keys = append(keys, key.privateKey)
+				}
+			}
+		}
+		return keys, nil
+	}
+	for _, key := range cs.retired {
+		if key.id == kid {
+			if !now.Before(key.expiresAt) {
+				return nil, fmt.Errorf("key %s has expired", kid)
+			}
+			return []*rsa.PrivateKey{key.privateKey}, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key id: %s", kid)
+}
@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+// testAAD 當前時間、隨機 nonce 的 AAD
+func testAAD(userID string) AADData {
+	nonce := make([]byte, payloadNonceSize)
+	rand.Read(nonce)
+	return AADData{
+		UserID:  userID,
+		TS:      time.Now().Unix(),
+		Nonce:   base64.RawURLEncoding.EncodeToString(nonce),
+		Purpose: SensitiveDataPurpose,
+	}
+}
+
+// encryptForTest 按前端流程加密：AES-GCM 加密數據（AAD 參與認證），RSA-OAEP 包裝 AES 密鑰
+func encryptForTest(t *testing.T, publicKeyPEM, kid string, aad AADData, plaintext string) *EncryptedPayload {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("解析公鑰失敗: %v", err)
+	}
+
+	aesKey := make([]byte, 32)
+	iv := make([]byte, 12)
+	rand.Read(aesKey)
+	rand.Read(iv)
+	aadBytes, _ := json.Marshal(aad)
+	aesBlock, _ := aes.NewCipher(aesKey)
+	gcm, _ := cipher.NewGCM(aesBlock)
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, parsed.(*rsa.PublicKey), aesKey, nil)
+	if err != nil {
+		t.Fatalf("包裝密鑰失敗: %v", err)
+	}
+
+	return &EncryptedPayload{
+		WrappedKey: base64.RawURLEncoding.EncodeToString(wrappedKey),
+		IV:         base64.RawURLEncoding.EncodeToString(iv),
+		Ciphertext: base64.RawURLEncoding.EncodeToString(gcm.Seal(nil, iv, []byte(plaintext), aadBytes)),
+		AAD:        base64.RawURLEncoding.EncodeToString(aadBytes),
+		KID:        kid,
+		TS:         aad.TS,
+	}
+}
+
+// TestRotateKey 輪換後新舊公鑰加密的數據都能解密（帶或不帶 kid），重啟後舊密鑰仍在，過期後拒絕
+func TestRotateKey(t *testing.T) {
+	t.Setenv(dataKeyEnvName, "test-data-key")
+	keyPath := t.TempDir() + "/rsa_key"
+	cs, err := NewCryptoService(keyPath)
+	if err != nil {
+		t.Fatalf("初始化加密服務失敗: %v", err)
+	}
+
+	oldKID := cs.CurrentKeyID()
+	oldPEM := cs.GetPublicKeyPEM()
+	oldWithKID := encryptForTest(t, oldPEM, oldKID, testAAD("user-1"), "old-secret")
+	oldWithoutKID := encryptForTest(t, oldPEM, "", testAAD("user-1"), "old-secret")
+
+	newKID, err := cs.RotateKey(time.Hour)
+	if err != nil {
+		t.Fatalf("輪換密鑰失敗: %v", err)
+	}
+	if newKID == oldKID || cs.CurrentKeyID() != newKID {
+		t.Fatalf("輪換後應使用新密鑰: %s -> %s", oldKID, newKID)
+	}
+	current := encryptForTest(t, cs.GetPublicKeyPEM(), newKID, testAAD("user-1"), "new-secret")
+
+	// 重新載入，模擬服務重啟
+	cs, err = NewCryptoService(keyPath)
+	if err != nil {
+		t.Fatalf("重新載入加密服務失敗: %v", err)
+	}
+	if cs.CurrentKeyID() != newKID || len(cs.Keys()) != 2 {
+		t.Fatalf("重啟後應保留當前密鑰和舊密鑰: %+v", cs.Keys())
+	}
+
+	for name, tc := range map[string]struct {
+		payload *EncryptedPayload
+		want    string
+	}{
+		"當前密鑰":     {current, "new-secret"},
+		"舊密鑰帶kid":  {oldWithKID, "old-secret"},
+		"舊密鑰不帶kid": {oldWithoutKID, "old-secret"},
+	} {
+		got, err := cs.DecryptSensitiveData(tc.payload, "user-1")
+		if err != nil || got != tc.want {
+			t.Errorf("%s: 解密結果 %q, %v", name, got, err)
+		}
+	}
+
+	if _, err := cs.DecryptSensitiveData(encryptForTest(t, cs.GetPublicKeyPEM(), "unknown", testAAD("user-1"), "x"), "user-1"); err == nil {
+		t.Error("未知的 kid 應拒絕")
+	}
+
+	// 寬限期結束後舊密鑰失效
+	cs.retired[0].expiresAt = time.Now().Add(-time.Second)
+	if _, err := cs.DecryptSensitiveData(encryptForTest(t, oldPEM, oldKID, testAAD("user-1"), "x"), "user-1"); err == nil {
+		t.Error("過期的舊密鑰不應再能解密")
+	}
+	if _, err := cs.DecryptSensitiveData(encryptForTest(t, oldPEM, "", testAAD("user-1"), "x"), "user-1"); err == nil {
+		t.Error("不帶 kid 時也不應使用過期的舊密鑰")
+	}
+}
@@ -0,0 +1,81 @@
+package crypto
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// SensitiveDataPurpose 前端加密敏感數據時 AAD 中的 purpose
+	SensitiveDataPurpose = "sensitive_data_encryption"
+
+	payloadMaxAge    = 5 * time.Minute // 加密數據的有效期
+	payloadMaxSkew   = time.Minute     // 允許客戶端時鐘超前的時長
+	payloadNonceSize = 16              // nonce 最少字節數（base64url 解碼後）
+)
+
+// nonceCache 有效期內已使用過的 nonce，過期的條目在寫入時清理
+type nonceCache struct {
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// use 記錄 nonce，有效期內重複出現時返回 false
+func (nc *nonceCache) use(nonce string, expiresAt, now time.Time) bool {
+	nc.mu.Lock()
+	defer nc.mu.Unlock()
+
+	if nc.seen == nil {
+		nc.seen = make(map[string]time.Time)
+	}
+	for key, exp := range nc.seen {
+		if !now.Before(exp) {
+			delete(nc.seen, key)
+		}
+	}
+	if _, ok := nc.seen[nonce]; ok {
+		return false
+	}
+	nc.seen[nonce] = expiresAt
+	return true
+}
+
+// verifyAAD 校驗已通過 AES-GCM 認證的 AAD：用途、所屬用戶、時間戳和 nonce
+func (cs *CryptoService) verifyAAD(payload *EncryptedPayload, userID string, now time.Time) error {
+	if payload.AAD == "" {
+		return errors.New("missing AAD")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload.AAD)
+	if err != nil {
+		return fmt.Errorf("failed to decode AAD: %w", err)
+	}
+	var aad AADData
+	if err := json.Unmarshal(raw, &aad); err != nil {
+		return fmt.Errorf("invalid AAD: %w", err)
+	}
+
+	if aad.Purpose != SensitiveDataPurpose {
+		return fmt.Errorf("unexpected purpose: %s", aad.Purpose)
+	}
+	if userID == "" || aad.UserID != userID {
+		return errors.New("payload was encrypted for another user")
+	}
+
+	issuedAt := time.Unix(aad.TS, 0)
+	if aad.TS == 0 || now.Sub(issuedAt) > payloadMaxAge || issuedAt.Sub(now) > payloadMaxSkew {
+		return errors.New("timestamp invalid or expired")
+	}
+
+	if nonce, err := base64.RawURLEncoding.DecodeString(aad.Nonce); err != nil || len(nonce) < payloadNonceSize {
+		return errors.New("missing or invalid nonce")
+	}
+	// nonce 保留到 ts 過期為止，之後重放會因時間戳被拒絕
+	if !cs.nonces.use(aad.Nonce, issuedAt.Add(payloadMaxAge), now) {
+		return errors.New("payload replayed")
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package crypto
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDecryptSensitiveDataReplay 同一加密數據只能解密一次；過期、缺少 nonce 或屬於其他用戶的數據被拒絕
+func TestDecryptSensitiveDataReplay(t *testing.T) {
+	t.Setenv(dataKeyEnvName, "test-data-key")
+	cs, err := NewCryptoService(t.TempDir() + "/rsa_key")
+	if err != nil {
+		t.Fatalf("初始化加密服務失敗: %v", err)
+	}
+	publicKey, kid := cs.GetPublicKeyPEM(), cs.CurrentKeyID()
+
+	payload := encryptForTest(t, publicKey, kid, testAAD("user-1"), "secret")
+	if got, err := cs.DecryptSensitiveData(payload, "user-1"); err != nil || got != "secret" {
+		t.Fatalf("首次解密應成功: %q, %v", got, err)
+	}
+	if _, err := cs.DecryptSensitiveData(payload, "user-1"); err == nil {
+		t.Error("重放的數據應被拒絕")
+	}
+
+	if _, err := cs.DecryptSensitiveData(encryptForTest(t, publicKey, kid, testAAD("user-1"), "secret"), "user-2"); err == nil {
+		t.Error("其他用戶的數據應被拒絕")
+	}
+
+	stale := testAAD("user-1")
+	stale.TS = time.Now().Add(-payloadMaxAge - time.Minute).Unix()
+	if _, err := cs.DecryptSensitiveData(encryptForTest(t, publicKey, kid, stale, "secret"), "user-1"); err == nil {
+		t.Error("過期的數據應被拒絕")
+	}
+
+	noNonce := testAAD("user-1")
+	noNonce.Nonce = ""
+	if _, err := cs.DecryptSensitiveData(encryptForTest(t, publicKey, kid, noNonce, "secret"), "user-1"); err == nil {
+		t.Error("缺少 nonce 的數據應被拒絕")
+	}
+
+	// 篡改 AAD 的數據無法通過認證，也不會佔用 nonce
+	forged := encryptForTest(t, publicKey, kid, testAAD("user-1"), "secret")
+	original := forged.AAD
+	forged.AAD = encryptForTest(t, publicKey, kid, testAAD("user-2"), "x").AAD
+	if _, err := cs.DecryptSensitiveData(forged, "user-2"); err == nil {
+		t.Error("篡改 AAD 的數據應被拒絕")
+	}
+	forged.AAD = original
+	if _, err := cs.DecryptSensitiveData(forged, "user-1"); err != nil {
+		t.Errorf("原始數據應仍可解密: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ResponseEncryptionPurpose 服務端加密響應時 AAD 中的 purpose
+const ResponseEncryptionPurpose = "response_encryption"
+
+// minClientKeyBits 客戶端臨時公鑰的最小長度
+const minClientKeyBits = 2048
+
+// ParseClientPublicKey 解析客戶端提供的臨時 RSA 公鑰（base64/base64url 編碼的 SPKI DER，即 WebCrypto exportKey('spki')）
+func ParseClientPublicKey(encoded string) (*rsa.PublicKey, error) {
+	encoded = strings.TrimRight(strings.TrimSpace(encoded), "=")
+	der, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil {
+		if der, err = base64.RawURLEncoding.DecodeString(encoded); err != nil {
+			return nil, errors.New("invalid base64 public key")
+		}
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	if rsaKey.N.BitLen() < minClientKeyBits {
+		return nil, fmt.Errorf("RSA public key must be at least %d bits", minClientKeyBits)
+	}
+	return rsaKey, nil
+}
+
+// EncryptForClient 用與前端相同的混合方案加密響應：AES-256-GCM 加密數據，RSA-OAEP(SHA-256) 用客戶端公鑰包裝 AES 密鑰
+func EncryptForClient(publicKey *rsa.PublicKey, plaintext []byte) (*EncryptedPayload, error) {
+	aesKey := make([]byte, 32)
+	if _, err := rand.Read(aesKey); err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, payloadNonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	ts := time.Now().Unix()
+	aad, err := json.Marshal(AADData{
+		TS:      ts,
+		Nonce:   base64.RawURLEncoding.EncodeToString(nonce),
+		Purpose: ResponseEncryptionPurpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	iv := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, aesKey, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap AES key: %w", err)
+	}
+
+	return &EncryptedPayload{
+		WrappedKey: base64.RawURLEncoding.EncodeToString(wrappedKey),
+		IV:         base64.RawURLEncoding.EncodeToString(iv),
+		Ciphertext: base64.RawURLEncoding.EncodeToString(gcm.Seal(nil, iv, plaintext, aad)),
+		AAD:        base64.RawURLEncoding.EncodeToString(aad),
+		TS:         ts,
+	}, nil
+}
@@ -35,8 +35,8 @@ func NewSecureStorage(db *sql.DB) (*SecureStorage, error) {
 
 // ==================== 交易所配置加密存儲 ====================
 
-// SaveEncryptedExchangeConfig 保存加密的交易所配置
-func (ss *SecureStorage) SaveEncryptedExchangeConfig(userID, exchangeID, apiKey, secretKey, asterPrivateKey string) error {
+// SaveEncryptedExchangeConfig 保存加密的交易所账户配置
+func (ss *SecureStorage) SaveEncryptedExchangeConfig(userID, exchangeID, accountID, apiKey, secretKey, asterPrivateKey string) error {
 	// 加密敏感字段
 	encryptedAPIKey, err := ss.em.EncryptForDatabase(apiKey)
 	if err != nil {
@@ -60,29 +60,29 @@ func (ss *SecureStorage) SaveEncryptedExchangeConfig(userID, exchangeID, apiKey,
 	_, err = ss.db.Exec(`
 		UPDATE exchanges
 		SET api_key = ?, secret_key = ?, aster_private_key = ?, updated_at = datetime('now')
-		WHERE user_id = ? AND id = ?
-	`, encryptedAPIKey, encryptedSecretKey, encryptedPrivateKey, userID, exchangeID)
+		WHERE user_id = ? AND id = ? AND account_id = ?
+	`, encryptedAPIKey, encryptedSecretKey, encryptedPrivateKey, userID, exchangeID, accountID)
 
 	if err != nil {
 		return err
 	}
 
 	// 記錄審計日誌
-	ss.logAudit(userID, "exchange_config_update", exchangeID, "密鑰已更新")
+	ss.logAudit(userID, "exchange_config_update", exchangeID+"/"+accountID, "密鑰已更新")
 
-	log.Printf("🔐 [%s] 交易所 %s 的密鑰已加密保存", userID, exchangeID)
+	log.Printf("🔐 [%s] 交易所 %s/%s 的密鑰已加密保存", userID, exchangeID, accountID)
 	return nil
 }
 
-// LoadDecryptedExchangeConfig 加載並解密交易所配置
-func (ss *SecureStorage) LoadDecryptedExchangeConfig(userID, exchangeID string) (apiKey, secretKey, asterPrivateKey string, err error) {
+// LoadDecryptedExchangeConfig 加載並解密交易所賬戶配置
+func (ss *SecureStorage) LoadDecryptedExchangeConfig(userID, exchangeID, accountID string) (apiKey, secretKey, asterPrivateKey string, err error) {
 	var encryptedAPIKey, encryptedSecretKey, encryptedPrivateKey sql.NullString
 
 	err = ss.db.QueryRow(`
 		SELECT api_key, secret_key, aster_private_key
 		FROM exchanges
-		WHERE user_id = ? AND id = ?
-	`, userID, exchangeID).Scan(&encryptedAPIKey, &encryptedSecretKey, &encryptedPrivateKey)
+		WHERE user_id = ? AND id = ? AND account_id = ?
+	`, userID, exchangeID, accountID).Scan(&encryptedAPIKey, &encryptedSecretKey, &encryptedPrivateKey)
 
 	if err != nil {
 		return "", "", "", err
@@ -113,7 +113,7 @@ func (ss *SecureStorage) LoadDecryptedExchangeConfig(userID, exchangeID string)
 	}
 
 	// 記錄審計日誌
-	ss.logAudit(userID, "exchange_config_read", exchangeID, "密鑰已讀取")
+	ss.logAudit(userID, "exchange_config_read", exchangeID+"/"+accountID, "密鑰已讀取")
 
 	return apiKey, secretKey, asterPrivateKey, nil
 }
@@ -252,7 +252,7 @@ func (ss *SecureStorage) MigrateToEncrypted() error {
 
 	// 遷移交易所配置
 	rows, err := tx.Query(`
-		SELECT user_id, id, api_key, secret_key, aster_private_key
+		SELECT user_id, id, account_id, api_key, secret_key, aster_private_key
 		FROM exchanges
 		WHERE api_key != '' AND api_key NOT LIKE '%==%' -- 過濾已加密數據
 	`)
@@ -262,9 +262,9 @@ func (ss *SecureStorage) MigrateToEncrypted() error {
 
 	var count int
 	for rows.Next() {
-		var userID, exchangeID, apiKey, secretKey string
+		var userID, exchangeID, accountID, apiKey, secretKey string
 		var asterPrivateKey sql.NullString
-		if err := rows.Scan(&userID, &exchangeID, &apiKey, &secretKey, &asterPrivateKey); err != nil {
+		if err := rows.Scan(&userID, &exchangeID, &accountID, &apiKey, &secretKey, &asterPrivateKey); err != nil {
 			rows.Close()
 			return err
 		}
@@ -281,8 +281,8 @@ func (ss *SecureStorage) MigrateToEncrypted() error {
 		_, err = tx.Exec(`
 			UPDATE exchanges
 			SET api_key = ?, secret_key = ?, aster_private_key = ?
-			WHERE user_id = ? AND id = ?
-		`, encAPIKey, encSecretKey, encPrivateKey, userID, exchangeID)
+			WHERE user_id = ? AND id = ? AND account_id = ?
+		`, encAPIKey, encSecretKey, encPrivateKey, userID, exchangeID, accountID)
 
 		if err != nil {
 			rows.Close()
@@ -0,0 +1,183 @@
+package decision
+
+import (
+	"log"
+	"nofx/market"
+	"nofx/mcp"
+	"sort"
+	"unicode/utf8"
+)
+
+const (
+	// fallbackContextTokens 未知服务商（自定义API）的默认上下文窗口
+	fallbackContextTokens = 32000
+	// reservedOutputTokens 未配置 max_tokens 时为模型输出预留的token数
+	reservedOutputTokens = 4000
+)
+
+// defaultContextTokens 各服务商模型的默认上下文窗口（可在AI模型配置中通过 context_tokens 覆盖）
+var defaultContextTokens = map[string]int{
+	"deepseek":         64000,
+	"qwen":             128000,
+	mcp.ProviderLocal:  32768,
+	mcp.ProviderOllama: 32768,
+}
+
+// klineLookbackSteps 超出预算时每个币种K线序列依次缩短到的长度（保留最新的数据）
+var klineLookbackSteps = []int{6, 3, 1}
+
+// PromptTruncation 提示词超出token预算时的裁剪记录
+type PromptTruncation struct {
+	BudgetTokens   int      `json:"budget_tokens"`             // 输入提示词可用的token预算（已扣除系统提示词）
+	OriginalTokens int      `json:"original_tokens"`           // 裁剪前的估算token数
+	FinalTokens    int      `json:"final_tokens"`              // 裁剪后的估算token数
+	KlineLookback  int      `json:"kline_lookback"`            // 裁剪后每个币种保留的K线序列长度
+	DroppedSymbols []string `json:"dropped_symbols,omitempty"` // 被移除的候选币种（非持仓、成交额从低到高）
+	OverBudget     bool     `json:"over_budget,omitempty"`     // 移除全部候选币种后仍超出预算
+}
+
+// DefaultContextTokens 服务商模型的默认上下文窗口
+func DefaultContextTokens(provider string) int {
+	if tokens, ok := defaultContextTokens[provider]; ok {
+		return tokens
+	}
+	return fallbackContextTokens
+}
+
+// PromptTokenBudget 计算输入提示词（system+user）的token预算：上下文窗口扣除输出预留，再留10%余量抵消估算误差。
+// contextTokens/maxTokens 为 0 时使用服务商默认值
+func PromptTokenBudget(provider string, contextTokens, maxTokens int) int {
+	if contextTokens <= 0 {
+		contextTokens = DefaultContextTokens(provider)
+	}
+	if maxTokens <= 0 {
+		maxTokens = reservedOutputTokens
+	}
+	return max((contextTokens-maxTokens)*9/10, 1)
+}
+
+// EstimateTokens 粗略估算文本的token数（偏保守：ASCII约每3个字符1个token，中文等非ASCII字符每字1个token）
+func EstimateTokens(text string) int {
+	ascii, other := 0, 0
+	for _, r := range text {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return (ascii+2)/3 + other
+}
+
+// buildUserPromptWithinBudget 构建 User Prompt，超出token预算时逐步裁剪：
+// 先缩短每个币种的K线序列，仍超出时按成交额从低到高移除非持仓的候选币种（budget <= 0 表示不限制）
+func buildUserPromptWithinBudget(ctx *Context, budget int) (string, *PromptTruncation) {
+	prompt := buildUserPrompt(ctx)
+	tokens := EstimateTokens(prompt)
+	if budget <= 0 || tokens <= budget {
+		return prompt, nil
+	}
+
+	truncation := &PromptTruncation{BudgetTokens: budget, OriginalTokens: tokens}
+	trimmed := *ctx
+	for _, lookback := range klineLookbackSteps {
+		trimmed.MarketDataMap = trimMarketData(ctx.MarketDataMap, lookback)
+		truncation.KlineLookback = lookback
+		prompt = buildUserPrompt(&trimmed)
+		if tokens = EstimateTokens(prompt); tokens <= budget {
+			truncation.FinalTokens = tokens
+			log.Printf("✂️  提示词超出预算(%d > %d tokens)，K线序列缩短到 %d 条", truncation.OriginalTokens, budget, lookback)
+			return prompt, truncation
+		}
+	}
+
+	// 移除的币种越多提示词越短，二分查找最少需要移除的数量
+	droppable := droppableSymbols(&trimmed)
+	base := trimmed.MarketDataMap
+	build := func(drop int) string {
+		data := make(map[string]*market.Data, len(base))
+		for symbol, d := range base {
+			data[symbol] = d
+		}
+		for _, symbol := range droppable[:drop] {
+			delete(data, symbol)
+		}
+		trimmed.MarketDataMap = data
+		return buildUserPrompt(&trimmed)
+	}
+	drop := sort.Search(len(droppable), func(n int) bool {
+		return EstimateTokens(build(n)) <= budget
+	})
+	prompt = build(drop)
+	truncation.FinalTokens = EstimateTokens(prompt)
+	truncation.OverBudget = truncation.FinalTokens > budget
+	if drop > 0 {
+		truncation.DroppedSymbols = append([]string(nil), droppable[:drop]...)
+	}
+	log.Printf("✂️  提示词超出预算(%d > %d tokens)，K线序列缩短到 %d 条并移除 %d 个候选币种，裁剪后 %d tokens",
+		truncation.OriginalTokens, budget, truncation.KlineLookback, drop, truncation.FinalTokens)
+	return prompt, truncation
+}
+
+// trimMarketData 复制市场数据并把序列缩短到最近 lookback 条（不修改原数据）
+func trimMarketData(data map[string]*market.Data, lookback int) map[string]*market.Data {
+	trimmed := make(map[string]*market.Data, len(data))
+	for symbol, d := range data {
+		c := *d
+		if d.IntradaySeries != nil {
+			series := *d.IntradaySeries
+			series.MidPrices = lastN(series.MidPrices, lookback)
+			series.EMA20Values = lastN(series.EMA20Values, lookback)
+			series.MACDValues = lastN(series.MACDValues, lookback)
+			series.RSI7Values = lastN(series.RSI7Values, lookback)
+			series.RSI14Values = lastN(series.RSI14Values, lookback)
+			series.Volume = lastN(series.Volume, lookback)
+			c.IntradaySeries = &series
+		}
+		if d.LongerTermContext != nil {
+			longer := *d.LongerTermContext
+			longer.MACDValues = lastN(longer.MACDValues, lookback)
+			longer.RSI14Values = lastN(longer.RSI14Values, lookback)
+			c.LongerTermContext = &longer
+		}
+		trimmed[symbol] = &c
+	}
+	return trimmed
+}
+
+// lastN 返回切片最后 n 个元素
+func lastN(values []float64, n int) []float64 {
+	if len(values) <= n {
+		return values
+	}
+	return values[len(values)-n:]
+}
+
+// droppableSymbols 可移除的候选币种（不含持仓币种），按4小时平均成交额从低到高排序
+func droppableSymbols(ctx *Context) []string {
+	held := make(map[string]bool, len(ctx.Positions))
+	for _, pos := range ctx.Positions {
+		held[pos.Symbol] = true
+	}
+	var symbols []string
+	seen := make(map[string]bool)
+	for _, coin := range ctx.CandidateCoins {
+		if held[coin.Symbol] || seen[coin.Symbol] || ctx.MarketDataMap[coin.Symbol] == nil {
+			continue
+		}
+		seen[coin.Symbol] = true
+		symbols = append(symbols, coin.Symbol)
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		return quoteVolume(ctx.MarketDataMap[symbols[i]]) < quoteVolume(ctx.MarketDataMap[symbols[j]])
+	})
+	return symbols
+}
+
+// quoteVolume 4小时平均成交额（无长期数据时为0，最先被移除）
+func quoteVolume(data *market.Data) float64 {
+	if data.LongerTermContext == nil {
+		return 0
+	}
+	return data.LongerTermContext.AverageVolume * data.CurrentPrice
+}
@@ -0,0 +1,121 @@
+package decision
+
+import (
+	"fmt"
+	"nofx/market"
+	"testing"
+)
+
+// newSyntheticData 生成带完整10条序列的市场数据（volume 越大成交额越高）
+func newSyntheticData(symbol string, volume float64) *market.Data {
+	series := make([]float64, 10)
+	for i := range series {
+		series[i] = 100 + float64(i)*0.123456
+	}
+	return &market.Data{
+		Symbol:       symbol,
+		CurrentPrice: 100,
+		OpenInterest: &market.OIData{Latest: 1e6, Average: 1e6},
+		IntradaySeries: &market.IntradayData{
+			MidPrices: series, EMA20Values: series, MACDValues: series,
+			RSI7Values: series, RSI14Values: series, Volume: series,
+		},
+		LongerTermContext: &market.LongerTermData{
+			AverageVolume: volume,
+			MACDValues:    series,
+			RSI14Values:   series,
+		},
+	}
+}
+
+// newSyntheticContext 生成 n 个候选币种的上下文，第一个币种为持仓且成交额最低
+func newSyntheticContext(n int) *Context {
+	ctx := &Context{
+		Account:       AccountInfo{TotalEquity: 1000, AvailableBalance: 1000},
+		MarketDataMap: make(map[string]*market.Data),
+	}
+	for i := 0; i < n; i++ {
+		symbol := fmt.Sprintf("COIN%03dUSDT", i)
+		ctx.CandidateCoins = append(ctx.CandidateCoins, CandidateCoin{Symbol: symbol, Sources: []string{"ai500"}})
+		ctx.MarketDataMap[symbol] = newSyntheticData(symbol, float64(i+1)*1000)
+	}
+	held := ctx.CandidateCoins[0].Symbol
+	ctx.Positions = []PositionInfo{{Symbol: held, Side: "long", EntryPrice: 100, MarkPrice: 100, Quantity: 1, Leverage: 5}}
+	return ctx
+}
+
+// TestBuildUserPromptWithinBudget_NeverExceedsBudget 测试100个币种时各种预算下提示词都不超出预算，且持仓币种始终保留
+func TestBuildUserPromptWithinBudget_NeverExceedsBudget(t *testing.T) {
+	ctx := newSyntheticContext(100)
+	full := EstimateTokens(buildUserPrompt(ctx))
+
+	for _, budget := range []int{full / 2, full / 5, full / 20, 3000} {
+		prompt, truncation := buildUserPromptWithinBudget(ctx, budget)
+		if tokens := EstimateTokens(prompt); tokens > budget {
+			t.Errorf("预算 %d: 裁剪后 %d tokens 超出预算", budget, tokens)
+		}
+		if truncation == nil || truncation.OverBudget || truncation.FinalTokens > budget {
+			t.Fatalf("预算 %d: 裁剪记录错误: %+v", budget, truncation)
+		}
+		for _, dropped := range truncation.DroppedSymbols {
+			if dropped == "COIN000USDT" {
+				t.Errorf("预算 %d: 持仓币种不应被移除", budget)
+			}
+		}
+	}
+
+	// 原始市场数据不被修改
+	if got := len(ctx.MarketDataMap["COIN050USDT"].IntradaySeries.MidPrices); got != 10 {
+		t.Errorf("原始K线序列被修改: %d", got)
+	}
+}
+
+// TestBuildUserPromptWithinBudget_ReducesLookbackFirst 测试先缩短K线序列，仍超出时按成交额从低到高移除非持仓币种
+func TestBuildUserPromptWithinBudget_ReducesLookbackFirst(t *testing.T) {
+	ctx := newSyntheticContext(100)
+	full := EstimateTokens(buildUserPrompt(ctx))
+
+	prompt, truncation := buildUserPromptWithinBudget(ctx, full)
+	if truncation != nil || EstimateTokens(prompt) != full {
+		t.Fatalf("未超出预算时不应裁剪: %+v", truncation)
+	}
+
+	_, truncation = buildUserPromptWithinBudget(ctx, full*9/10)
+	if truncation == nil || truncation.KlineLookback != klineLookbackSteps[0] || len(truncation.DroppedSymbols) != 0 {
+		t.Fatalf("略超预算时应只缩短K线序列: %+v", truncation)
+	}
+
+	_, truncation = buildUserPromptWithinBudget(ctx, full/10)
+	if truncation == nil || len(truncation.DroppedSymbols) == 0 {
+		t.Fatalf("大幅超出预算时应移除候选币种: %+v", truncation)
+	}
+	// COIN000 为持仓，成交额最低的非持仓币种 COIN001 最先被移除
+	if truncation.DroppedSymbols[0] != "COIN001USDT" {
+		t.Errorf("应先移除成交额最低的非持仓币种，实际 %v", truncation.DroppedSymbols[:3])
+	}
+}
+
+// TestBuildUserPromptWithinBudget_OverBudget 测试只剩持仓币种仍超出预算时标记 over_budget
+func TestBuildUserPromptWithinBudget_OverBudget(t *testing.T) {
+	ctx := newSyntheticContext(10)
+	_, truncation := buildUserPromptWithinBudget(ctx, 10)
+	if truncation == nil || !truncation.OverBudget || len(truncation.DroppedSymbols) != 9 {
+		t.Fatalf("应移除全部非持仓币种并标记超出预算: %+v", truncation)
+	}
+}
+
+// TestPromptTokenBudget 测试按服务商默认上下文窗口和输出预留计算预算
+func TestPromptTokenBudget(t *testing.T) {
+	if got := PromptTokenBudget("deepseek", 0, 0); got != (64000-reservedOutputTokens)*9/10 {
+		t.Errorf("deepseek 默认预算错误: %d", got)
+	}
+	if got := PromptTokenBudget("custom", 0, 2000); got != (fallbackContextTokens-2000)*9/10 {
+		t.Errorf("自定义API默认预算错误: %d", got)
+	}
+	if got := PromptTokenBudget("qwen", 8192, 4096); got != (8192-4096)*9/10 {
+		t.Errorf("配置的上下文窗口应覆盖默认值: %d", got)
+	}
+	if got := EstimateTokens("abcdef中文"); got != 4 {
+		t.Errorf("EstimateTokens = %d, want 4", got)
+	}
+}
@@ -2,6 +2,7 @@ package decision
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -13,6 +14,9 @@ import (
 	"time"
 )
 
+// ErrAICall AI接口调用失败（区别于市场数据获取失败和响应解析失败）
+var ErrAICall = errors.New("调用AI API失败")
+
 // 预编译正则表达式（性能优化：避免每次调用时重新编译）
 var (
 	// ✅ 安全的正則：精確匹配 ```json 代碼塊
@@ -41,7 +45,8 @@ type PositionInfo struct {
 	PeakPnLPct       float64 `json:"peak_pnl_pct"` // 历史最高收益率（百分比）
 	LiquidationPrice float64 `json:"liquidation_price"`
 	MarginUsed       float64 `json:"margin_used"`
-	UpdateTime       int64   `json:"update_time"` // 持仓更新时间戳（毫秒）
+	UpdateTime       int64   `json:"update_time"`  // 持仓更新时间戳（毫秒）
+	FundingFees      float64 `json:"funding_fees"` // 持仓期间累计资金费（正数为收到，负数为支付）
 }
 
 // AccountInfo 账户信息
@@ -54,12 +59,21 @@ type AccountInfo struct {
 	MarginUsed       float64 `json:"margin_used"`       // 已用保证金
 	MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
 	PositionCount    int     `json:"position_count"`    // 持仓数量
+	FundingFees      float64 `json:"funding_fees"`      // 近7天资金费合计（正数为收到，负数为支付）
+}
+
+// FundingInfo 交易所资金费率（正数表示多头向空头支付）
+type FundingInfo struct {
+	Rate            float64 `json:"rate"`              // 当前周期费率
+	PredictedRate   float64 `json:"predicted_rate"`    // 预测的下一周期费率（0 表示交易所不提供）
+	IntervalHours   int     `json:"interval_hours"`    // 结算间隔（小时）
+	NextFundingTime int64   `json:"next_funding_time"` // 下次结算时间（毫秒，0 表示未知）
 }
 
 // CandidateCoin 候选币种（来自币种池）
 type CandidateCoin struct {
 	Symbol  string   `json:"symbol"`
-	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"
+	Sources []string `json:"sources"` // 来源: "ai500" 和/或 "oi_top"，或 "volume_top"（24小时成交额排名）
 }
 
 // OITopData 持仓量增长Top数据（用于AI决策参考）
@@ -85,6 +99,17 @@ type Context struct {
 	Performance     interface{}             `json:"-"` // 历史表现分析（logger.PerformanceAnalysis）
 	BTCETHLeverage  int                     `json:"-"` // BTC/ETH杠杆倍数（从配置读取）
 	AltcoinLeverage int                     `json:"-"` // 山寨币杠杆倍数（从配置读取）
+	RiskLimits      string                  `json:"-"` // 交易员配置的风控限制说明（仓位/敞口上限、亏损冷却，为空表示未配置）
+	RiskFeedback    []string                `json:"-"` // 上一周期开仓被风控缩减/拒绝的原因
+	FundingRates    map[string]*FundingInfo `json:"-"` // 当前交易所的资金费率（持仓和候选币种，交易所不支持时为空）
+	UserID          string                  `json:"-"` // 交易员所属用户（优先使用该用户的同名提示词模板）
+	PromptTemplate  *PromptTemplate         `json:"-"` // 交易员启动时固定的模板版本（为空时按名称解析最新版本）
+	// PromptTokenBudget 输入提示词（system+user）的token预算，超出时裁剪市场数据（0表示不限制）
+	PromptTokenBudget int `json:"-"`
+	// SignalSourceErrors 本周期信号源获取失败的原因（写入决策日志，不发送给AI）
+	SignalSourceErrors []string `json:"-"`
+	// MarketSource 交易员所在交易所的行情来源（为空时使用币安行情）
+	MarketSource market.Source `json:"-"`
 }
 
 // Decision AI的交易决策
@@ -118,6 +143,23 @@ type FullDecision struct {
 	Timestamp    time.Time  `json:"timestamp"`
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒）方便排查延迟问题
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+	// PromptTemplate/PromptTemplateVersion 本周期使用的提示词模板（系统模板版本为0，便于追溯决策对应的模板措辞）
+	PromptTemplate        string `json:"prompt_template,omitempty"`
+	PromptTemplateVersion int    `json:"prompt_template_version,omitempty"`
+	// Repaired 首次输出未通过校验、经过一次修复请求（无论修复是否成功）
+	Repaired bool           `json:"repaired,omitempty"`
+	Repair   *RepairAttempt `json:"repair,omitempty"`
+	// PromptTruncation 提示词超出token预算时的裁剪记录（未裁剪时为空）
+	PromptTruncation *PromptTruncation `json:"prompt_truncation,omitempty"`
+}
+
+// RepairAttempt 决策修复记录（保存两次输出，便于统计各模型需要修复的频率）
+type RepairAttempt struct {
+	OriginalOutput   string   `json:"original_output"`
+	ValidationErrors []string `json:"validation_errors"`
+	RepairedOutput   string   `json:"repaired_output,omitempty"`
+	Success          bool     `json:"success"`
+	Error            string   `json:"error,omitempty"`
 }
 
 // GetFullDecision 获取AI的完整交易决策（批量分析所有币种和持仓）
@@ -133,38 +175,127 @@ func GetFullDecisionWithCustomPrompt(ctx *Context, mcpClient mcp.AIClient, custo
 	}
 
 	// 2. 构建 System Prompt（固定规则）和 User Prompt（动态数据）
-	systemPrompt := buildSystemPromptWithCustom(ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase, templateName)
-	userPrompt := buildUserPrompt(ctx)
+	template := ctx.PromptTemplate
+	if template == nil {
+		template = ResolvePromptTemplate(ctx.UserID, templateName)
+	}
+	systemPrompt := buildSystemPromptWithCustom(template, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage, customPrompt, overrideBase)
+	userBudget := 0
+	if ctx.PromptTokenBudget > 0 {
+		userBudget = max(ctx.PromptTokenBudget-EstimateTokens(systemPrompt), 1)
+	}
+	userPrompt, truncation := buildUserPromptWithinBudget(ctx, userBudget)
 
 	// 3. 调用AI API（使用 system + user prompt）
 	aiCallStart := time.Now()
 	aiResponse, err := mcpClient.CallWithMessages(systemPrompt, userPrompt)
-	aiCallDuration := time.Since(aiCallStart)
 	if err != nil {
-		return nil, fmt.Errorf("调用AI API失败: %w", err)
+		return nil, fmt.Errorf("%w: %w", ErrAICall, err)
 	}
 
-	// 4. 解析AI响应
+	// 4. 解析并校验AI响应，未通过校验时发送一次修复请求
 	decision, err := parseFullDecisionResponse(aiResponse, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
-
-	// 无论是否有错误，都要保存 SystemPrompt 和 UserPrompt（用于调试和决策未执行后的问题定位）
-	if decision != nil {
-		decision.Timestamp = time.Now()
-		decision.SystemPrompt = systemPrompt // 保存系统prompt
-		decision.UserPrompt = userPrompt     // 保存输入prompt
-		decision.AIRequestDurationMs = aiCallDuration.Milliseconds()
-	}
-
 	if err != nil {
-		return decision, fmt.Errorf("解析AI响应失败: %w", err)
+		decision = repairFullDecision(mcpClient, systemPrompt, userPrompt, aiResponse, err, ctx.Account.TotalEquity, ctx.BTCETHLeverage, ctx.AltcoinLeverage)
 	}
 
 	decision.Timestamp = time.Now()
 	decision.SystemPrompt = systemPrompt // 保存系统prompt
 	decision.UserPrompt = userPrompt     // 保存输入prompt
+	decision.AIRequestDurationMs = time.Since(aiCallStart).Milliseconds()
+	decision.PromptTruncation = truncation
+	if template != nil && !(overrideBase && customPrompt != "") {
+		decision.PromptTemplate = template.Name
+		decision.PromptTemplateVersion = template.Version
+	}
+	return decision, nil
+}
+
+// repairFullDecision 把校验错误和原始输出发回模型，要求只返回修正后的JSON；
+// 修复仍失败时返回观望决策（绝不使用未通过校验的决策交易）
+func repairFullDecision(mcpClient mcp.AIClient, systemPrompt, userPrompt, originalOutput string, validationErr error, accountEquity float64, btcEthLeverage, altcoinLeverage int) *FullDecision {
+	repair := &RepairAttempt{
+		OriginalOutput:   originalOutput,
+		ValidationErrors: validationErrorMessages(validationErr),
+	}
+	log.Printf("🔧 AI决策未通过校验，发送修复请求: %v", validationErr)
+
+	decision, err := callRepair(mcpClient, systemPrompt, userPrompt, repair, accountEquity, btcEthLeverage, altcoinLeverage)
+	if err != nil {
+		repair.Error = err.Error()
+		log.Printf("⚠️  AI决策修复失败，本周期观望: %v", err)
+		decision = &FullDecision{
+			CoTTrace: extractCoTTrace(originalOutput),
+			Decisions: []Decision{{
+				Symbol:    "ALL",
+				Action:    ActionWait,
+				Reasoning: fmt.Sprintf("AI输出未通过校验且修复失败，本周期不操作: %v", err),
+			}},
+		}
+	} else {
+		repair.Success = true
+		log.Printf("✓ AI决策修复成功")
+	}
+
+	decision.Repaired = true
+	decision.Repair = repair
+	return decision
+}
+
+// callRepair 发送修复请求并重新解析校验
+func callRepair(mcpClient mcp.AIClient, systemPrompt, userPrompt string, repair *RepairAttempt, accountEquity float64, btcEthLeverage, altcoinLeverage int) (*FullDecision, error) {
+	request, err := mcp.NewRequestBuilder().
+		WithSystemPrompt(systemPrompt).
+		WithUserPrompt(userPrompt).
+		AddAssistantMessage(repair.OriginalOutput).
+		AddUserMessage(buildRepairPrompt(repair.ValidationErrors)).
+		Build()
+	if err != nil {
+		return nil, fmt.Errorf("构建修复请求失败: %w", err)
+	}
+
+	repairedOutput, err := mcpClient.CallWithRequest(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrAICall, err)
+	}
+	repair.RepairedOutput = repairedOutput
+
+	decision, err := parseFullDecisionResponse(repairedOutput, accountEquity, btcEthLeverage, altcoinLeverage)
+	if err != nil {
+		return nil, fmt.Errorf("修复后的输出仍未通过校验: %w", err)
+	}
+	// 修复响应只含JSON，沿用原始输出的思维链
+	decision.CoTTrace = extractCoTTrace(repair.OriginalOutput)
 	return decision, nil
 }
 
+// buildRepairPrompt 构建修复提示词
+func buildRepairPrompt(validationErrors []string) string {
+	var sb strings.Builder
+	sb.WriteString("你上一条回复中的决策JSON未通过校验，错误如下：\n")
+	for _, e := range validationErrors {
+		sb.WriteString("- ")
+		sb.WriteString(e)
+		sb.WriteString("\n")
+	}
+	sb.WriteString("\n请修正以上错误，只输出修正后的JSON决策数组（不要输出思维链或任何其他文字）。")
+	sb.WriteString("如果无法给出合规的交易决策，请输出 [{\"symbol\": \"ALL\", \"action\": \"wait\", \"reasoning\": \"...\"}]。")
+	return sb.String()
+}
+
+// validationErrorMessages 拆分校验错误（结构校验错误逐条列出）
+func validationErrorMessages(err error) []string {
+	var schemaErrs SchemaErrors
+	if errors.As(err, &schemaErrs) {
+		messages := make([]string, len(schemaErrs))
+		for i, e := range schemaErrs {
+			messages[i] = e.String()
+		}
+		return messages
+	}
+	return []string{err.Error()}
+}
+
 // fetchMarketDataForContext 为上下文中的所有币种获取市场数据和OI数据
 func fetchMarketDataForContext(ctx *Context) error {
 	ctx.MarketDataMap = make(map[string]*market.Data)
@@ -195,7 +326,7 @@ func fetchMarketDataForContext(ctx *Context) error {
 	}
 
 	for symbol := range symbolSet {
-		data, err := market.Get(symbol)
+		data, err := market.GetFrom(ctx.MarketSource, symbol)
 		if err != nil {
 			// 单个币种失败不影响整体，只记录错误
 			continue
@@ -272,14 +403,14 @@ func calculateMaxCandidates(ctx *Context) int {
 }
 
 // buildSystemPromptWithCustom 构建包含自定义内容的 System Prompt
-func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinLeverage int, customPrompt string, overrideBase bool, templateName string) string {
+func buildSystemPromptWithCustom(template *PromptTemplate, accountEquity float64, btcEthLeverage, altcoinLeverage int, customPrompt string, overrideBase bool) string {
 	// 如果覆盖基础prompt且有自定义prompt，只使用自定义prompt
 	if overrideBase && customPrompt != "" {
 		return customPrompt
 	}
 
 	// 获取基础prompt（使用指定的模板）
-	basePrompt := buildSystemPrompt(accountEquity, btcEthLeverage, altcoinLeverage, templateName)
+	basePrompt := buildSystemPrompt(template, accountEquity, btcEthLeverage, altcoinLeverage)
 
 	// 如果没有自定义prompt，直接返回基础prompt
 	if customPrompt == "" {
@@ -298,30 +429,35 @@ func buildSystemPromptWithCustom(accountEquity float64, btcEthLeverage, altcoinL
 	return sb.String()
 }
 
-// buildSystemPrompt 构建 System Prompt（使用模板+动态部分）
-func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage int, templateName string) string {
-	var sb strings.Builder
-
-	// 1. 加载提示词模板（核心交易策略部分）
+// ResolvePromptTemplate 按名称解析提示词模板（用户模板优先于同名系统模板，不存在时使用 default，都不存在时返回 nil）
+func ResolvePromptTemplate(userID, templateName string) *PromptTemplate {
 	if templateName == "" {
 		templateName = "default" // 默认使用 default 模板
 	}
 
-	template, err := GetPromptTemplate(templateName)
+	template, err := GetPromptTemplateForUser(userID, templateName)
+	if err == nil {
+		return template
+	}
+	// 如果模板不存在，记录错误并使用 default
+	log.Printf("⚠️  提示词模板 '%s' 不存在，使用 default: %v", templateName, err)
+	template, err = GetPromptTemplateForUser(userID, "default")
 	if err != nil {
-		// 如果模板不存在，记录错误并使用 default
-		log.Printf("⚠️  提示词模板 '%s' 不存在，使用 default: %v", templateName, err)
-		template, err = GetPromptTemplate("default")
-		if err != nil {
-			// 如果连 default 都不存在，使用内置的简化版本
-			log.Printf("❌ 无法加载任何提示词模板，使用内置简化版本")
-			sb.WriteString("你是专业的加密货币交易AI。请根据市场数据做出交易决策。\n\n")
-		} else {
-			sb.WriteString(template.Content)
-			sb.WriteString("\n\n")
-		}
+		log.Printf("❌ 无法加载任何提示词模板，使用内置简化版本")
+		return nil
+	}
+	return template
+}
+
+// buildSystemPrompt 构建 System Prompt（使用模板+动态部分，template 为空时使用内置简化版本）
+func buildSystemPrompt(template *PromptTemplate, accountEquity float64, btcEthLeverage, altcoinLeverage int) string {
+	var sb strings.Builder
+
+	// 1. 提示词模板（核心交易策略部分）
+	if template == nil {
+		sb.WriteString("你是专业的加密货币交易AI。请根据市场数据做出交易决策。\n\n")
 	} else {
-		sb.WriteString(template.Content)
+		sb.WriteString(renderPromptTemplate(template.Content, accountEquity, btcEthLeverage, altcoinLeverage))
 		sb.WriteString("\n\n")
 	}
 
@@ -361,6 +497,35 @@ func buildSystemPrompt(accountEquity float64, btcEthLeverage, altcoinLeverage in
 	return sb.String()
 }
 
+// formatFunding 格式化交易所资金费率；side 和 notional 非空时估算该持仓下次结算的资金费（正数为收到）
+func formatFunding(info *FundingInfo, side string, notional float64) string {
+	if info == nil {
+		return ""
+	}
+	interval := ""
+	if info.IntervalHours > 0 {
+		interval = fmt.Sprintf("/%dh", info.IntervalHours)
+	}
+	line := fmt.Sprintf("资金费率: 当前%+.4f%%%s", info.Rate*100, interval)
+	if info.PredictedRate != 0 {
+		line += fmt.Sprintf(" | 预测%+.4f%%", info.PredictedRate*100)
+	}
+	if info.NextFundingTime > 0 {
+		if wait := time.Until(time.UnixMilli(info.NextFundingTime)); wait > 0 {
+			line += fmt.Sprintf(" | 距下次结算%d分钟", int(wait.Minutes()))
+		}
+	}
+	if side != "" && notional > 0 {
+		// 费率为正时多头支付、空头收取
+		estimate := notional * info.Rate
+		if side == "long" {
+			estimate = -estimate
+		}
+		line += fmt.Sprintf(" | 下次结算预计%+.4f USDT", estimate)
+	}
+	return line
+}
+
 // buildUserPrompt 构建 User Prompt（动态数据）
 func buildUserPrompt(ctx *Context) string {
 	var sb strings.Builder
@@ -384,6 +549,9 @@ func buildUserPrompt(ctx *Context) string {
 		ctx.Account.TotalPnLPct,
 		ctx.Account.MarginUsedPct,
 		ctx.Account.PositionCount))
+	if ctx.Account.FundingFees != 0 {
+		sb.WriteString(fmt.Sprintf("近7天资金费: %+.4f USDT（正数为收到，负数为支付）\n\n", ctx.Account.FundingFees))
+	}
 
 	// 持仓（完整市场数据）
 	if len(ctx.Positions) > 0 {
@@ -410,6 +578,13 @@ func buildUserPrompt(ctx *Context) string {
 				i+1, pos.Symbol, strings.ToUpper(pos.Side),
 				pos.EntryPrice, pos.MarkPrice, pos.Quantity, positionValue, pos.UnrealizedPnLPct, pos.UnrealizedPnL, pos.PeakPnLPct,
 				pos.Leverage, pos.MarginUsed, pos.LiquidationPrice, holdingDuration))
+			fundingLine := formatFunding(ctx.FundingRates[pos.Symbol], pos.Side, positionValue)
+			if pos.FundingFees != 0 {
+				fundingLine = strings.TrimPrefix(fundingLine+" | ", " | ") + fmt.Sprintf("持仓期间资金费%+.4f USDT", pos.FundingFees)
+			}
+			if fundingLine != "" {
+				sb.WriteString(fundingLine + "\n\n")
+			}
 
 			// 使用FormatMarketData输出完整市场数据
 			if marketData, ok := ctx.MarketDataMap[pos.Symbol]; ok {
@@ -436,10 +611,15 @@ func buildUserPrompt(ctx *Context) string {
 			sourceTags = " (AI500+OI_Top双重信号)"
 		} else if len(coin.Sources) == 1 && coin.Sources[0] == "oi_top" {
 			sourceTags = " (OI_Top持仓增长)"
+		} else if len(coin.Sources) == 1 && coin.Sources[0] == VolumeTopSource {
+			sourceTags = " (24h成交额Top)"
 		}
 
 		// 使用FormatMarketData输出完整市场数据
 		sb.WriteString(fmt.Sprintf("### %d. %s%s\n\n", displayedCount, coin.Symbol, sourceTags))
+		if funding := formatFunding(ctx.FundingRates[coin.Symbol], "", 0); funding != "" {
+			sb.WriteString(funding + "\n\n")
+		}
 		sb.WriteString(market.Format(marketData))
 		sb.WriteString("\n")
 	}
@@ -459,6 +639,21 @@ func buildUserPrompt(ctx *Context) string {
 		}
 	}
 
+	// 风控限制与上一周期的风控反馈（超限的开仓会被强制缩减或拒绝）
+	if ctx.RiskLimits != "" || len(ctx.RiskFeedback) > 0 {
+		sb.WriteString("## 🛡️ 风控限制\n")
+		if ctx.RiskLimits != "" {
+			sb.WriteString(ctx.RiskLimits + "\n")
+		}
+		if len(ctx.RiskFeedback) > 0 {
+			sb.WriteString("上一周期被风控调整的开仓:\n")
+			for _, feedback := range ctx.RiskFeedback {
+				sb.WriteString("- " + feedback + "\n")
+			}
+		}
+		sb.WriteString("\n")
+	}
+
 	sb.WriteString("---\n\n")
 	sb.WriteString("现在请分析并输出决策（思维链 + JSON）\n")
 
@@ -479,7 +674,13 @@ func parseFullDecisionResponse(aiResponse string, accountEquity float64, btcEthL
 		}, fmt.Errorf("提取决策失败: %w", err)
 	}
 
-	// 3. 验证决策
+	// 3. 结构校验（一次列出所有错误），通过后再做业务规则验证
+	if schemaErrs := ValidateDecisionSchema(decisions); len(schemaErrs) > 0 {
+		return &FullDecision{
+			CoTTrace:  cotTrace,
+			Decisions: decisions,
+		}, fmt.Errorf("决策结构校验失败: %w", schemaErrs)
+	}
 	if err := validateDecisions(decisions, accountEquity, btcEthLeverage, altcoinLeverage); err != nil {
 		return &FullDecision{
 			CoTTrace:  cotTrace,
@@ -0,0 +1,72 @@
+package decision
+
+import "strings"
+
+// 差异行类型
+const (
+	DiffEqual  = " "
+	DiffAdd    = "+"
+	DiffRemove = "-"
+)
+
+// DiffLine 模板版本差异中的一行
+type DiffLine struct {
+	Op   string `json:"op"` // " " 未变 / "+" 新增 / "-" 删除
+	Text string `json:"text"`
+}
+
+// DiffPromptTemplates 按行比较两个模板版本（基于最长公共子序列，模板有长度上限，规模可控）
+func DiffPromptTemplates(from, to string) []DiffLine {
+	a := strings.Split(from, "\n")
+	b := strings.Split(to, "\n")
+
+	// lcs[i][j] = a[i:] 与 b[j:] 的最长公共子序列长度
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	lines := make([]DiffLine, 0, max(len(a), len(b)))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: DiffEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: DiffRemove, Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: DiffAdd, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: DiffRemove, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: DiffAdd, Text: b[j]})
+	}
+	return lines
+}
+
+// FormatDiff 把差异格式化为类似 unified diff 的文本（每行以 " "、"+"、"-" 开头）
+func FormatDiff(lines []DiffLine) string {
+	var sb strings.Builder
+	for _, line := range lines {
+		sb.WriteString(line.Op)
+		sb.WriteString(line.Text)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
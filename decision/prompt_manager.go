@@ -9,16 +9,26 @@ import (
 	"sync"
 )
 
+// 提示词模板来源
+const (
+	PromptSourceSystem = "system" // prompts 目录下的系统模板
+	PromptSourceUser   = "user"   // 用户通过API创建、保存在数据库中的模板
+)
+
 // PromptTemplate 系统提示词模板
 type PromptTemplate struct {
 	Name    string // 模板名称（文件名，不含扩展名）
 	Content string // 模板内容
+	Source  string // 模板来源（system / user）
+	Version int    // 用户模板的版本号（系统模板为 0）
 }
 
 // PromptManager 提示词管理器
 type PromptManager struct {
 	templates map[string]*PromptTemplate
-	mu        sync.RWMutex
+	// userTemplates 用户模板（userID → 名称 → 模板），不受 ReloadTemplates 影响
+	userTemplates map[string]map[string]*PromptTemplate
+	mu            sync.RWMutex
 }
 
 var (
@@ -41,7 +51,8 @@ func init() {
 // NewPromptManager 创建提示词管理器
 func NewPromptManager() *PromptManager {
 	return &PromptManager{
-		templates: make(map[string]*PromptTemplate),
+		templates:     make(map[string]*PromptTemplate),
+		userTemplates: make(map[string]map[string]*PromptTemplate),
 	}
 }
 
@@ -83,6 +94,7 @@ func (pm *PromptManager) LoadTemplates(dir string) error {
 		pm.templates[templateName] = &PromptTemplate{
 			Name:    templateName,
 			Content: string(content),
+			Source:  PromptSourceSystem,
 		}
 
 		log.Printf("  📄 加载提示词模板: %s (%s)", templateName, fileName)
@@ -130,7 +142,46 @@ func (pm *PromptManager) GetAllTemplates() []*PromptTemplate {
 	return templates
 }
 
-// ReloadTemplates 重新加载所有模板
+// GetTemplateForUser 获取用户可用的提示词模板：优先使用用户模板，其次是同名系统模板
+func (pm *PromptManager) GetTemplateForUser(userID, name string) (*PromptTemplate, error) {
+	pm.mu.RLock()
+	if template, exists := pm.userTemplates[userID][name]; exists && userID != "" {
+		pm.mu.RUnlock()
+		return template, nil
+	}
+	pm.mu.RUnlock()
+
+	return pm.GetTemplate(name)
+}
+
+// SetUserTemplate 新增或替换用户模板（version 为数据库中的最新版本号）
+func (pm *PromptManager) SetUserTemplate(userID, name, content string, version int) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.userTemplates[userID] == nil {
+		pm.userTemplates[userID] = make(map[string]*PromptTemplate)
+	}
+	pm.userTemplates[userID][name] = &PromptTemplate{
+		Name:    name,
+		Content: content,
+		Source:  PromptSourceUser,
+		Version: version,
+	}
+}
+
+// RemoveUserTemplate 删除用户模板
+func (pm *PromptManager) RemoveUserTemplate(userID, name string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	delete(pm.userTemplates[userID], name)
+	if len(pm.userTemplates[userID]) == 0 {
+		delete(pm.userTemplates, userID)
+	}
+}
+
+// ReloadTemplates 重新加载所有系统模板（用户模板保留，交易员引用的用户模板不会丢失）
 func (pm *PromptManager) ReloadTemplates(dir string) error {
 	pm.mu.Lock()
 	pm.templates = make(map[string]*PromptTemplate)
@@ -160,3 +211,18 @@ func GetAllPromptTemplates() []*PromptTemplate {
 func ReloadPromptTemplates() error {
 	return globalPromptManager.ReloadTemplates(promptsDir)
 }
+
+// GetPromptTemplateForUser 获取用户可用的提示词模板，用户模板优先（全局函数）
+func GetPromptTemplateForUser(userID, name string) (*PromptTemplate, error) {
+	return globalPromptManager.GetTemplateForUser(userID, name)
+}
+
+// SetUserPromptTemplate 新增或替换用户模板（全局函数）
+func SetUserPromptTemplate(userID, name, content string, version int) {
+	globalPromptManager.SetUserTemplate(userID, name, content, version)
+}
+
+// RemoveUserPromptTemplate 删除用户模板（全局函数）
+func RemoveUserPromptTemplate(userID, name string) {
+	globalPromptManager.RemoveUserTemplate(userID, name)
+}
@@ -3,6 +3,7 @@ package decision
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -283,3 +284,89 @@ func TestReloadPromptTemplates_GlobalFunction(t *testing.T) {
 		t.Errorf("模板内容不正确: got %s, want '测试内容'", template.Content)
 	}
 }
+
+func TestPromptManager_UserTemplates(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "default.txt"), []byte("系统默认策略"), 0644); err != nil {
+		t.Fatalf("创建模板文件失败: %v", err)
+	}
+
+	pm := NewPromptManager()
+	if err := pm.LoadTemplates(tempDir); err != nil {
+		t.Fatalf("加载模板失败: %v", err)
+	}
+	pm.SetUserTemplate("user-a", "default", "用户A的策略", 1)
+	pm.SetUserTemplate("user-a", "scalping", "用户A的短线策略", 1)
+
+	// 用户模板优先于同名系统模板，其他用户仍使用系统模板
+	if tmpl, err := pm.GetTemplateForUser("user-a", "default"); err != nil || tmpl.Content != "用户A的策略" || tmpl.Source != PromptSourceUser {
+		t.Errorf("user-a 应解析到用户模板: %+v, %v", tmpl, err)
+	}
+	if tmpl, err := pm.GetTemplateForUser("user-b", "default"); err != nil || tmpl.Source != PromptSourceSystem {
+		t.Errorf("user-b 应解析到系统模板: %+v, %v", tmpl, err)
+	}
+	if _, err := pm.GetTemplateForUser("user-b", "scalping"); err == nil {
+		t.Error("其他用户不能使用 user-a 的模板")
+	}
+
+	// 重新加载系统模板后用户模板仍然可用
+	if err := pm.ReloadTemplates(tempDir); err != nil {
+		t.Fatalf("重新加载失败: %v", err)
+	}
+	if tmpl, err := pm.GetTemplateForUser("user-a", "scalping"); err != nil || tmpl.Content != "用户A的短线策略" {
+		t.Errorf("重新加载后用户模板丢失: %+v, %v", tmpl, err)
+	}
+
+	pm.RemoveUserTemplate("user-a", "default")
+	if tmpl, _ := pm.GetTemplateForUser("user-a", "default"); tmpl == nil || tmpl.Source != PromptSourceSystem {
+		t.Errorf("删除用户模板后应回退到系统模板: %+v", tmpl)
+	}
+}
+
+func TestValidatePromptTemplate(t *testing.T) {
+	valid := "策略说明\n" + PlaceholderAccountState + "\n" + PlaceholderMarketData
+	if err := ValidatePromptTemplate(valid); err != nil {
+		t.Errorf("合法模板不应报错: %v", err)
+	}
+
+	err := ValidatePromptTemplate("只有账户状态 " + PlaceholderAccountState)
+	templateErr, ok := err.(*PromptTemplateError)
+	if !ok || len(templateErr.MissingPlaceholders) != 1 || templateErr.MissingPlaceholders[0] != PlaceholderMarketData {
+		t.Errorf("应返回缺少的占位符 %s: %v", PlaceholderMarketData, err)
+	}
+
+	if err := ValidatePromptTemplate(valid + strings.Repeat("x", MaxPromptTemplateSize)); err == nil {
+		t.Error("超过长度上限的模板应被拒绝")
+	}
+	if err := ValidatePromptTemplateName("../etc/passwd"); err == nil {
+		t.Error("非法模板名称应被拒绝")
+	}
+
+	rendered := renderPromptTemplate(valid, 1000, 10, 5)
+	if strings.Contains(rendered, "{{") || !strings.Contains(rendered, "1000.00 USDT") {
+		t.Errorf("占位符未被替换: %s", rendered)
+	}
+}
+
+func TestDiffPromptTemplates(t *testing.T) {
+	lines := DiffPromptTemplates("规则A\n规则B\n规则C", "规则A\n规则B2\n规则C\n规则D")
+
+	want := []DiffLine{
+		{DiffEqual, "规则A"},
+		{DiffRemove, "规则B"},
+		{DiffAdd, "规则B2"},
+		{DiffEqual, "规则C"},
+		{DiffAdd, "规则D"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d: %+v", len(want), len(lines), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d: expected %+v, got %+v", i, want[i], lines[i])
+		}
+	}
+	if got := FormatDiff(lines); !strings.Contains(got, "-规则B\n+规则B2\n") {
+		t.Errorf("unexpected formatted diff:\n%s", got)
+	}
+}
@@ -0,0 +1,79 @@
+package decision
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// MaxPromptTemplateSize 用户提示词模板的最大长度（字节）
+const MaxPromptTemplateSize = 32 * 1024
+
+// 模板占位符（构建 System Prompt 时替换为实时内容）
+const (
+	PlaceholderAccountState = "{{account_state}}" // 账户净值和杠杆上限
+	PlaceholderMarketData   = "{{market_data}}"   // 市场数据章节说明（具体数据在用户消息中）
+)
+
+// RequiredPromptPlaceholders 用户模板必须包含的占位符
+var RequiredPromptPlaceholders = []string{PlaceholderAccountState, PlaceholderMarketData}
+
+// rePromptTemplateName 模板名称只允许字母、数字、下划线和短横线
+var rePromptTemplateName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// PromptTemplateError 提示词模板校验错误
+type PromptTemplateError struct {
+	Message             string
+	MissingPlaceholders []string // 缺少的必需占位符
+}
+
+func (e *PromptTemplateError) Error() string {
+	if len(e.MissingPlaceholders) > 0 {
+		return fmt.Sprintf("%s: %s", e.Message, strings.Join(e.MissingPlaceholders, ", "))
+	}
+	return e.Message
+}
+
+// ValidatePromptTemplateName 校验模板名称
+func ValidatePromptTemplateName(name string) error {
+	if !rePromptTemplateName.MatchString(name) {
+		return &PromptTemplateError{Message: "模板名称只能包含字母、数字、下划线和短横线（1-64个字符）"}
+	}
+	return nil
+}
+
+// ValidatePromptTemplate 校验用户模板内容：不能为空、不能超过长度限制、必须包含必需占位符
+func ValidatePromptTemplate(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return &PromptTemplateError{Message: "模板内容不能为空"}
+	}
+	if len(content) > MaxPromptTemplateSize {
+		return &PromptTemplateError{Message: fmt.Sprintf("模板内容过长: %d 字节（上限 %d 字节）", len(content), MaxPromptTemplateSize)}
+	}
+
+	var missing []string
+	for _, placeholder := range RequiredPromptPlaceholders {
+		if !strings.Contains(content, placeholder) {
+			missing = append(missing, placeholder)
+		}
+	}
+	if len(missing) > 0 {
+		return &PromptTemplateError{Message: "模板缺少必需的占位符", MissingPlaceholders: missing}
+	}
+	return nil
+}
+
+// renderPromptTemplate 替换模板中的占位符（系统模板不含占位符时原样返回）
+func renderPromptTemplate(content string, accountEquity float64, btcEthLeverage, altcoinLeverage int) string {
+	if !strings.Contains(content, "{{") {
+		return content
+	}
+	accountState := fmt.Sprintf("账户净值: %.2f USDT | BTC/ETH最大杠杆: %dx | 山寨币最大杠杆: %dx",
+		accountEquity, btcEthLeverage, altcoinLeverage)
+	marketData := "市场数据（价格、技术指标序列、持仓量、资金费率）、当前持仓和候选币种在用户消息中按币种提供"
+
+	return strings.NewReplacer(
+		PlaceholderAccountState, accountState,
+		PlaceholderMarketData, marketData,
+	).Replace(content)
+}
@@ -21,7 +21,7 @@ func TestBuildSystemPrompt_ContainsAllValidActions(t *testing.T) {
 	}
 
 	// 构建 prompt
-	prompt := buildSystemPrompt(1000.0, 10, 5, "default")
+	prompt := buildSystemPrompt(ResolvePromptTemplate("", "default"), 1000.0, 10, 5)
 
 	// 验证每个有效 action 都在 prompt 中出现
 	for _, action := range validActions {
@@ -33,7 +33,7 @@ func TestBuildSystemPrompt_ContainsAllValidActions(t *testing.T) {
 
 // TestBuildSystemPrompt_ActionListCompleteness 测试 action 列表的完整性
 func TestBuildSystemPrompt_ActionListCompleteness(t *testing.T) {
-	prompt := buildSystemPrompt(1000.0, 10, 5, "default")
+	prompt := buildSystemPrompt(ResolvePromptTemplate("", "default"), 1000.0, 10, 5)
 
 	// 检查是否包含关键的缺失 action
 	missingActions := []string{
@@ -48,3 +48,29 @@ func TestBuildSystemPrompt_ActionListCompleteness(t *testing.T) {
 		}
 	}
 }
+
+// TestFormatFunding 测试资金费率格式化及持仓下次结算资金费估算
+func TestFormatFunding(t *testing.T) {
+	if got := formatFunding(nil, "long", 1000); got != "" {
+		t.Errorf("无资金费率时应返回空字符串，实际: %s", got)
+	}
+
+	info := &FundingInfo{Rate: 0.0001, PredictedRate: -0.0002, IntervalHours: 8}
+	line := formatFunding(info, "", 0)
+	for _, want := range []string{"当前+0.0100%/8h", "预测-0.0200%"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("资金费率行缺少 %q: %s", want, line)
+		}
+	}
+	if strings.Contains(line, "预计") {
+		t.Errorf("候选币种不应估算持仓资金费: %s", line)
+	}
+
+	// 费率为正时多头支付、空头收取
+	if line := formatFunding(info, "long", 10000); !strings.Contains(line, "预计-1.0000 USDT") {
+		t.Errorf("多仓应支付资金费: %s", line)
+	}
+	if line := formatFunding(info, "short", 10000); !strings.Contains(line, "预计+1.0000 USDT") {
+		t.Errorf("空仓应收取资金费: %s", line)
+	}
+}
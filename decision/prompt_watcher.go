@@ -0,0 +1,226 @@
+package decision
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+const (
+	// DefaultPromptWatchInterval 无法监听文件变化时轮询提示词目录的间隔
+	DefaultPromptWatchInterval = 2 * time.Second
+	// promptReloadDebounce 文件最后一次变化后等待的时间（编辑器保存时可能分多次写入）
+	promptReloadDebounce = time.Second
+	// maxSystemPromptTemplateSize 系统模板的最大长度（字节）
+	maxSystemPromptTemplateSize = 256 * 1024
+)
+
+// rePromptPlaceholder 模板中的占位符
+var rePromptPlaceholder = regexp.MustCompile(`\{\{[^{}]*\}\}`)
+
+// promptFileState 模板文件的修改时间和大小（用于检测变化）
+type promptFileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// validateSystemPromptTemplate 校验系统模板文件内容：不能为空、必须是UTF-8、不超过长度限制、占位符必须完整且可识别
+func validateSystemPromptTemplate(content string) error {
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("模板内容为空")
+	}
+	if !utf8.ValidString(content) {
+		return fmt.Errorf("模板内容不是有效的UTF-8文本")
+	}
+	if len(content) > maxSystemPromptTemplateSize {
+		return fmt.Errorf("模板内容过长: %d 字节（上限 %d 字节）", len(content), maxSystemPromptTemplateSize)
+	}
+	placeholders := rePromptPlaceholder.FindAllString(content, -1)
+	if len(placeholders) != strings.Count(content, "{{") || len(placeholders) != strings.Count(content, "}}") {
+		return fmt.Errorf("模板包含未闭合的占位符")
+	}
+	for _, placeholder := range placeholders {
+		if placeholder != PlaceholderAccountState && placeholder != PlaceholderMarketData {
+			return fmt.Errorf("模板包含未知的占位符: %s", placeholder)
+		}
+	}
+	return nil
+}
+
+// ReloadChangedTemplates 重新读取目录中的系统模板，校验通过后整体替换内存中的模板集合。
+// 校验失败或读取失败的模板保留上一个有效版本；返回新增、修改和删除的模板名称
+func (pm *PromptManager) ReloadChangedTemplates(dir string) ([]string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("扫描提示词目录失败: %w", err)
+	}
+
+	pm.mu.RLock()
+	previous := pm.templates
+	pm.mu.RUnlock()
+
+	next := make(map[string]*PromptTemplate, len(files))
+	var updated []string
+	for _, file := range files {
+		fileName := filepath.Base(file)
+		name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+		old := previous[name]
+
+		content, err := os.ReadFile(file)
+		if err == nil {
+			err = validateSystemPromptTemplate(string(content))
+		}
+		if err != nil {
+			if old != nil {
+				log.Printf("⚠️  提示词模板 %s 无效，继续使用上一个有效版本: %v", fileName, err)
+				next[name] = old
+			} else {
+				log.Printf("⚠️  提示词模板 %s 无效，已跳过: %v", fileName, err)
+			}
+			continue
+		}
+
+		if old != nil && old.Content == string(content) {
+			next[name] = old
+			continue
+		}
+		next[name] = &PromptTemplate{Name: name, Content: string(content), Source: PromptSourceSystem}
+		updated = append(updated, name)
+	}
+	for name := range previous {
+		if _, ok := next[name]; !ok {
+			updated = append(updated, name)
+		}
+	}
+	sort.Strings(updated)
+
+	pm.mu.Lock()
+	pm.templates = next
+	pm.mu.Unlock()
+	return updated, nil
+}
+
+// Watch 监听提示词目录的文件变化（fsnotify），变化稳定 debounce 后自动重新加载系统模板，直到 ctx 取消；
+// 无法创建文件监听时退回按 interval 轮询
+func (pm *PromptManager) Watch(ctx context.Context, dir string, interval, debounce time.Duration) {
+	watcher, err := fsnotify.NewWatcher()
+	if err == nil {
+		if err = watcher.Add(dir); err != nil {
+			watcher.Close()
+		}
+	}
+	if err != nil {
+		log.Printf("⚠️  无法监听提示词目录变化，改为每 %v 轮询: %v", interval, err)
+		pm.poll(ctx, dir, interval, debounce)
+		return
+	}
+	defer watcher.Close()
+
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			// 编辑器保存可能是写入、创建或重命名替换，只忽略权限变化
+			if filepath.Ext(event.Name) != ".txt" || event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(debounce)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("⚠️  监听提示词目录出错: %v", err)
+		case <-timer.C:
+			pm.reloadWatched(dir)
+		}
+	}
+}
+
+// poll 按 interval 扫描目录中模板文件的修改时间和大小，变化稳定 debounce 后重新加载
+func (pm *PromptManager) poll(ctx context.Context, dir string, interval, debounce time.Duration) {
+	last := scanPromptFiles(dir)
+	var changedAt time.Time
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			current := scanPromptFiles(dir)
+			if !samePromptFiles(last, current) {
+				last = current
+				changedAt = now
+				continue
+			}
+			if changedAt.IsZero() || now.Sub(changedAt) < debounce {
+				continue
+			}
+			changedAt = time.Time{}
+			pm.reloadWatched(dir)
+		}
+	}
+}
+
+// reloadWatched 目录变化后重新加载系统模板并记录变化的模板
+func (pm *PromptManager) reloadWatched(dir string) {
+	updated, err := pm.ReloadChangedTemplates(dir)
+	if err != nil {
+		log.Printf("⚠️  热加载提示词模板失败: %v", err)
+		return
+	}
+	if len(updated) > 0 {
+		log.Printf("🔄 提示词模板已热加载: %s（运行中的交易员下个周期生效）", strings.Join(updated, ", "))
+	}
+}
+
+// scanPromptFiles 获取目录中模板文件的修改时间和大小
+func scanPromptFiles(dir string) map[string]promptFileState {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+	states := make(map[string]promptFileState, len(files))
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			continue
+		}
+		states[file] = promptFileState{modTime: info.ModTime(), size: info.Size()}
+	}
+	return states
+}
+
+// samePromptFiles 两次扫描结果是否一致
+func samePromptFiles(a, b map[string]promptFileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for file, state := range a {
+		if other, ok := b[file]; !ok || !other.modTime.Equal(state.modTime) || other.size != state.size {
+			return false
+		}
+	}
+	return true
+}
+
+// WatchPromptTemplates 监视 prompts 目录并热加载系统模板（全局函数，阻塞直到 ctx 取消）
+func WatchPromptTemplates(ctx context.Context, interval time.Duration) {
+	log.Printf("👀 监视提示词目录: %s", promptsDir)
+	globalPromptManager.Watch(ctx, promptsDir, interval, promptReloadDebounce)
+}
@@ -0,0 +1,126 @@
+package decision
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestReloadChangedTemplates 测试热加载只替换校验通过的模板，无效模板保留上一个有效版本
+func TestReloadChangedTemplates(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name+".txt"), []byte(content), 0644); err != nil {
+			t.Fatalf("写入模板失败: %v", err)
+		}
+	}
+	write("alpha", "alpha v1")
+	write("beta", "beta v1")
+	write("gamma", "gamma v1")
+
+	pm := NewPromptManager()
+	if err := pm.LoadTemplates(dir); err != nil {
+		t.Fatalf("加载模板失败: %v", err)
+	}
+
+	write("alpha", "alpha v2 {{account_state}}")
+	write("beta", "   ")                       // 编辑器保存中途的空文件
+	write("gamma", "gamma v2 {{unknown}}")     // 未知占位符
+	write("delta", "delta v1")                 // 新增模板
+	write("epsilon", "epsilon {{market_data}") // 未闭合占位符（没有上一个版本，跳过）
+
+	updated, err := pm.ReloadChangedTemplates(dir)
+	if err != nil {
+		t.Fatalf("热加载失败: %v", err)
+	}
+	if want := []string{"alpha", "delta"}; !reflect.DeepEqual(updated, want) {
+		t.Errorf("更新的模板 = %v, want %v", updated, want)
+	}
+
+	expect := map[string]string{"alpha": "alpha v2 {{account_state}}", "beta": "beta v1", "gamma": "gamma v1", "delta": "delta v1"}
+	for name, content := range expect {
+		template, err := pm.GetTemplate(name)
+		if err != nil || template.Content != content {
+			t.Errorf("模板 %s = %+v (%v), want %q", name, template, err, content)
+		}
+	}
+	if _, err := pm.GetTemplate("epsilon"); err == nil {
+		t.Error("无效的新模板不应被加载")
+	}
+
+	// 删除的模板从集合中移除
+	os.Remove(filepath.Join(dir, "delta.txt"))
+	updated, _ = pm.ReloadChangedTemplates(dir)
+	if !reflect.DeepEqual(updated, []string{"delta"}) {
+		t.Errorf("删除后更新的模板 = %v", updated)
+	}
+}
+
+// TestPromptManagerWatch 测试文件修改后监视器自动热加载
+func TestPromptManagerWatch(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "watched.txt")
+	if err := os.WriteFile(file, []byte("watched v1"), 0644); err != nil {
+		t.Fatalf("写入模板失败: %v", err)
+	}
+	pm := NewPromptManager()
+	if err := pm.LoadTemplates(dir); err != nil {
+		t.Fatalf("加载模板失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pm.Watch(ctx, dir, 10*time.Millisecond, 20*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(file, []byte("watched v2 (更新后的内容)"), 0644); err != nil {
+		t.Fatalf("更新模板失败: %v", err)
+	}
+
+	waitForTemplate(t, pm, "watched", "watched v2 (更新后的内容)")
+
+	// 编辑器常见的保存方式：写入临时文件后重命名替换
+	tmp := filepath.Join(dir, ".watched.swp")
+	if err := os.WriteFile(tmp, []byte("watched v3"), 0644); err != nil {
+		t.Fatalf("写入临时文件失败: %v", err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		t.Fatalf("替换模板失败: %v", err)
+	}
+	waitForTemplate(t, pm, "watched", "watched v3")
+}
+
+// TestPromptManagerPoll 测试无法监听文件变化时的轮询热加载
+func TestPromptManagerPoll(t *testing.T) {
+	dir := t.TempDir()
+	pm := NewPromptManager()
+	if err := pm.LoadTemplates(dir); err != nil {
+		t.Fatalf("加载模板失败: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go pm.poll(ctx, dir, 10*time.Millisecond, 20*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "polled.txt"), []byte("polled v1"), 0644); err != nil {
+		t.Fatalf("写入模板失败: %v", err)
+	}
+	waitForTemplate(t, pm, "polled", "polled v1")
+}
+
+// waitForTemplate 等待模板热加载为指定内容
+func waitForTemplate(t *testing.T, pm *PromptManager, name, content string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if template, _ := pm.GetTemplate(name); template != nil && template.Content == content {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("监视器未热加载模板 %s", name)
+}
@@ -0,0 +1,137 @@
+package decision
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 决策动作（AI输出的 action 字段只能取这些值）
+const (
+	ActionOpenLong         = "open_long"
+	ActionOpenShort        = "open_short"
+	ActionCloseLong        = "close_long"
+	ActionCloseShort       = "close_short"
+	ActionUpdateStopLoss   = "update_stop_loss"
+	ActionUpdateTakeProfit = "update_take_profit"
+	ActionPartialClose     = "partial_close"
+	ActionHold             = "hold"
+	ActionWait             = "wait"
+)
+
+// MaxSchemaLeverage 结构校验允许的杠杆上限（交易所最大杠杆；超出交易员配置的部分由业务校验自动修正）
+const MaxSchemaLeverage = 125
+
+// reSymbol 交易对格式（如 BTCUSDT、1000PEPEUSDT）
+var reSymbol = regexp.MustCompile(`^[A-Z0-9]+USDT$`)
+
+// validSchemaActions 合法的 action 枚举
+var validSchemaActions = []string{
+	ActionOpenLong, ActionOpenShort, ActionCloseLong, ActionCloseShort,
+	ActionUpdateStopLoss, ActionUpdateTakeProfit, ActionPartialClose, ActionHold, ActionWait,
+}
+
+// SchemaError 决策结构校验错误
+type SchemaError struct {
+	Index   int    // 决策序号（从1开始，0 表示整个输出）
+	Field   string // 出错的字段
+	Message string
+}
+
+func (e SchemaError) String() string {
+	if e.Index == 0 {
+		return e.Message
+	}
+	return fmt.Sprintf("决策 #%d 字段 %s: %s", e.Index, e.Field, e.Message)
+}
+
+// SchemaErrors 结构校验错误列表（一次返回所有错误，便于修复请求一次性纠正）
+type SchemaErrors []SchemaError
+
+func (errs SchemaErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.String()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateDecisionSchema 按严格结构校验解析后的决策列表：
+// action 枚举、symbol 格式、开仓的方向/仓位/杠杆范围、可选止损止盈的取值以及各动作的必填字段
+func ValidateDecisionSchema(decisions []Decision) SchemaErrors {
+	var errs SchemaErrors
+	if len(decisions) == 0 {
+		return SchemaErrors{{Message: "决策列表为空，至少需要一条决策（无操作时输出 wait）"}}
+	}
+
+	for i := range decisions {
+		d := &decisions[i]
+		add := func(field, format string, args ...any) {
+			errs = append(errs, SchemaError{Index: i + 1, Field: field, Message: fmt.Sprintf(format, args...)})
+		}
+
+		if !containsString(validSchemaActions, d.Action) {
+			add("action", "无效的action %q，必须是 %s 之一", d.Action, strings.Join(validSchemaActions, "/"))
+		}
+		switch {
+		case d.Symbol == "":
+			add("symbol", "不能为空")
+		case d.Symbol == "ALL" && (d.Action == ActionHold || d.Action == ActionWait):
+			// 全局观望允许 ALL
+		case !reSymbol.MatchString(d.Symbol):
+			add("symbol", "格式无效 %q，必须是大写的USDT交易对（如 BTCUSDT）", d.Symbol)
+		}
+
+		switch d.Action {
+		case ActionOpenLong, ActionOpenShort:
+			if d.Leverage < 1 || d.Leverage > MaxSchemaLeverage {
+				add("leverage", "必须是 1-%d 之间的整数，实际: %d", MaxSchemaLeverage, d.Leverage)
+			}
+			if d.PositionSizeUSD <= 0 {
+				add("position_size_usd", "开仓必须大于0，实际: %.2f", d.PositionSizeUSD)
+			}
+			if d.StopLoss < 0 {
+				add("stop_loss", "不能为负数: %.4f", d.StopLoss)
+			}
+			if d.TakeProfit < 0 {
+				add("take_profit", "不能为负数: %.4f", d.TakeProfit)
+			}
+			// 止损止盈可选，同时提供时必须位于正确的方向
+			if d.StopLoss > 0 && d.TakeProfit > 0 {
+				if d.Action == ActionOpenLong && d.StopLoss >= d.TakeProfit {
+					add("stop_loss", "做多时止损价(%.4f)必须小于止盈价(%.4f)", d.StopLoss, d.TakeProfit)
+				}
+				if d.Action == ActionOpenShort && d.StopLoss <= d.TakeProfit {
+					add("stop_loss", "做空时止损价(%.4f)必须大于止盈价(%.4f)", d.StopLoss, d.TakeProfit)
+				}
+			}
+		case ActionUpdateStopLoss:
+			if d.NewStopLoss <= 0 {
+				add("new_stop_loss", "update_stop_loss 必须提供大于0的新止损价")
+			}
+		case ActionUpdateTakeProfit:
+			if d.NewTakeProfit <= 0 {
+				add("new_take_profit", "update_take_profit 必须提供大于0的新止盈价")
+			}
+		case ActionPartialClose:
+			if d.ClosePercentage <= 0 || d.ClosePercentage > 100 {
+				add("close_percentage", "必须在 0-100 之间，实际: %.1f", d.ClosePercentage)
+			}
+		}
+
+		if d.Confidence < 0 || d.Confidence > 100 {
+			add("confidence", "必须在 0-100 之间，实际: %d", d.Confidence)
+		}
+	}
+	return errs
+}
+
+// containsString 字符串切片是否包含指定值
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package decision
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"nofx/mcp"
+)
+
+// fakeRepairClient 按顺序返回预设响应的AI客户端，并记录收到的修复请求
+type fakeRepairClient struct {
+	responses []string
+	err       error
+	requests  []*mcp.Request
+}
+
+func (f *fakeRepairClient) SetAPIKey(apiKey string, customURL string, customModel string) {}
+func (f *fakeRepairClient) SetTimeout(timeout time.Duration)                              {}
+
+func (f *fakeRepairClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return f.next()
+}
+
+func (f *fakeRepairClient) CallWithRequest(req *mcp.Request) (string, error) {
+	f.requests = append(f.requests, req)
+	return f.next()
+}
+
+func (f *fakeRepairClient) next() (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	if len(f.responses) == 0 {
+		return "", errors.New("no more responses")
+	}
+	resp := f.responses[0]
+	f.responses = f.responses[1:]
+	return resp, nil
+}
+
+func TestValidateDecisionSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		decisions  []Decision
+		wantFields []string
+	}{
+		{"合法的观望", []Decision{{Symbol: "ALL", Action: "wait"}}, nil},
+		{"合法的开仓", []Decision{{Symbol: "BTCUSDT", Action: "open_long", Leverage: 5, PositionSizeUSD: 100, StopLoss: 90, TakeProfit: 130}}, nil},
+		{"空列表", []Decision{}, []string{""}},
+		{"无效action", []Decision{{Symbol: "BTCUSDT", Action: "buy"}}, []string{"action"}},
+		{"交易对格式", []Decision{{Symbol: "btc/usdt", Action: "close_long"}}, []string{"symbol"}},
+		{"ALL 只能用于观望", []Decision{{Symbol: "ALL", Action: "close_long"}}, []string{"symbol"}},
+		{"开仓字段一次列出所有错误", []Decision{{Symbol: "ETHUSDT", Action: "open_short", Leverage: 0, PositionSizeUSD: 0, StopLoss: 90, TakeProfit: 130, Confidence: 150}},
+			[]string{"leverage", "position_size_usd", "stop_loss", "confidence"}},
+		{"止损止盈可选", []Decision{{Symbol: "SOLUSDT", Action: "open_long", Leverage: 3, PositionSizeUSD: 50}}, nil},
+		{"部分平仓比例", []Decision{{Symbol: "SOLUSDT", Action: "partial_close", ClosePercentage: 120}}, []string{"close_percentage"}},
+		{"更新止损缺少新价格", []Decision{{Symbol: "SOLUSDT", Action: "update_stop_loss"}}, []string{"new_stop_loss"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := ValidateDecisionSchema(tt.decisions)
+			if len(errs) != len(tt.wantFields) {
+				t.Fatalf("expected %d errors, got %d: %v", len(tt.wantFields), len(errs), errs)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Errorf("error %d: expected field %q, got %q (%s)", i, field, errs[i].Field, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRepairFullDecision_Success(t *testing.T) {
+	original := "<reasoning>BTC 突破</reasoning><decision>[{\"symbol\": \"BTCUSDT\", \"action\": \"buy\"}]</decision>"
+	client := &fakeRepairClient{responses: []string{`[{"symbol": "BTCUSDT", "action": "close_long", "reasoning": "止盈"}]`}}
+
+	_, parseErr := parseFullDecisionResponse(original, 1000, 10, 5)
+	if parseErr == nil {
+		t.Fatal("original output should fail validation")
+	}
+
+	decision := repairFullDecision(client, "system", "user", original, parseErr, 1000, 10, 5)
+	if !decision.Repaired || decision.Repair == nil || !decision.Repair.Success {
+		t.Fatalf("expected successful repair, got %+v", decision.Repair)
+	}
+	if len(decision.Decisions) != 1 || decision.Decisions[0].Action != "close_long" {
+		t.Errorf("expected repaired close_long decision, got %+v", decision.Decisions)
+	}
+	if decision.CoTTrace != "BTC 突破" {
+		t.Errorf("repaired decision should keep original reasoning, got %q", decision.CoTTrace)
+	}
+	if decision.Repair.OriginalOutput != original || decision.Repair.RepairedOutput == "" {
+		t.Errorf("both outputs should be recorded: %+v", decision.Repair)
+	}
+
+	// 修复请求包含原始输出和校验错误
+	if len(client.requests) != 1 {
+		t.Fatalf("expected exactly one repair request, got %d", len(client.requests))
+	}
+	messages := client.requests[0].Messages
+	if len(messages) != 4 || messages[2].Role != "assistant" || messages[2].Content != original {
+		t.Fatalf("repair request should replay the original output, got %+v", messages)
+	}
+	if !strings.Contains(messages[3].Content, "action") || !strings.Contains(messages[3].Content, "只输出修正后的JSON") {
+		t.Errorf("repair prompt should list validation errors, got %q", messages[3].Content)
+	}
+}
+
+func TestRepairFullDecision_FallsBackToWait(t *testing.T) {
+	original := `[{"symbol": "BTCUSDT", "action": "open_long", "leverage": 0}]`
+	tests := []struct {
+		name   string
+		client *fakeRepairClient
+	}{
+		{"修复输出仍无效", &fakeRepairClient{responses: []string{`[{"symbol": "BTCUSDT", "action": "open_long", "leverage": 500}]`}}},
+		{"修复输出JSON格式错误", &fakeRepairClient{responses: []string{`[{"symbol": "BTCUSDT", "action": }]`}}},
+		{"修复请求调用失败", &fakeRepairClient{err: errors.New("timeout")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, parseErr := parseFullDecisionResponse(original, 1000, 10, 5)
+			decision := repairFullDecision(tt.client, "system", "user", original, parseErr, 1000, 10, 5)
+
+			if !decision.Repaired || decision.Repair.Success || decision.Repair.Error == "" {
+				t.Fatalf("expected failed repair to be recorded, got %+v", decision.Repair)
+			}
+			if len(decision.Decisions) != 1 || decision.Decisions[0].Action != "wait" || decision.Decisions[0].Symbol != "ALL" {
+				t.Errorf("failed repair must fall back to wait, got %+v", decision.Decisions)
+			}
+			if len(tt.client.requests) != 1 {
+				t.Errorf("expected a single repair attempt, got %d", len(tt.client.requests))
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package decision
+
+import "nofx/market"
+
+// VolumeTopSource 按24小时成交额选出的候选币种来源标记
+const VolumeTopSource = "volume_top"
+
+// VolumeTopCandidates 按24小时成交额选择最活跃的前 n 个交易对作为候选币种（跳过 skip 返回 true 的交易对）
+// 数据来自全市场24小时行情流，行情监控未启动或尚未收到数据时返回空列表
+func VolumeTopCandidates(n int, skip func(symbol string) bool) []CandidateCoin {
+	var coins []CandidateCoin
+	for _, ticker := range market.TopByQuoteVolume(0) {
+		if len(coins) >= n {
+			break
+		}
+		if skip != nil && skip(ticker.Symbol) {
+			continue
+		}
+		coins = append(coins, CandidateCoin{Symbol: ticker.Symbol, Sources: []string{VolumeTopSource}})
+	}
+	return coins
+}
@@ -33,8 +33,14 @@ func RegisterHook(key string, hook HookFunc) {
 }
 
 // hook list
+// GETIP 及以下的类型化扩展点见 points.go，通过 Point.Register 注册；其余仍使用 RegisterHook / HookExec
 const (
-	GETIP              = "GETIP"              // func (userID string) *IpResult
+	GETIP              = "GETIP"              // 类型化扩展点 GetIP
+	PRE_DECISION       = "PRE_DECISION"       // 类型化扩展点 PreDecision
+	POST_DECISION      = "POST_DECISION"      // 类型化扩展点 PostDecision
+	PRE_ORDER          = "PRE_ORDER"          // 类型化扩展点 PreOrder
+	ON_TRADER_START    = "ON_TRADER_START"    // 类型化扩展点 OnTraderStart
+	ON_TRADER_STOP     = "ON_TRADER_STOP"     // 类型化扩展点 OnTraderStop
 	NEW_BINANCE_TRADER = "NEW_BINANCE_TRADER" // func (userID string, client *futures.Client) *NewBinanceTraderResult
 	NEW_ASTER_TRADER   = "NEW_ASTER_TRADER"   // func (userID string, client *http.Client) *NewAsterTraderResult
 	SET_HTTP_CLIENT    = "SET_HTTP_CLIENT"    // func (client *http.Client) *SetHttpClientResult
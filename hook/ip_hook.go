@@ -2,6 +2,7 @@ package hook
 
 import "github.com/rs/zerolog/log"
 
+// IpResult 旧版 GETIP（RegisterHook 注册）的返回值，新插件请使用类型化扩展点 GetIP
 type IpResult struct {
 	Err error
 	IP  string
@@ -0,0 +1,121 @@
+package hook
+
+// 类型化扩展点。插件在 init 中注册处理函数，例如：
+//
+//	hook.PreOrder.Register("compliance", hook.PriorityHigh, func(ctx context.Context, in hook.PreOrderInput, out *hook.PreOrderOutput) error {
+//		if in.Notional > 10000 {
+//			out.Reject, out.Reason = true, "单笔超过合规上限"
+//		}
+//		return nil
+//	})
+var (
+	// GetIP 获取用户专用出口IP（如代理IP），用于交易所白名单提示
+	// 错误：Continue 时忽略；Abort 时 API 回退到服务器公网IP
+	GetIP = NewPoint[GetIPInput, GetIPOutput](GETIP)
+
+	// PreDecision 请求AI决策之前调用，可跳过本周期
+	// 错误：Abort 时跳过本周期（原因写入决策记录）
+	PreDecision = NewPoint[PreDecisionInput, PreDecisionOutput](PRE_DECISION)
+
+	// PostDecision 决策执行完成（或失败）后调用，只用于观察
+	// 错误：只记录日志，不影响交易
+	PostDecision = NewPoint[PostDecisionInput, PostDecisionOutput](POST_DECISION)
+
+	// PreOrder 每个下单动作（开仓、平仓、部分平仓）发送到交易所之前调用，可放行、缩减或拒绝该订单
+	// 超时（系统配置 pre_order_hook_timeout_ms）或 Abort 错误时按 pre_order_hook_fallback 拒绝或放行；
+	// 被拒绝的订单同一周期内不再重试
+	PreOrder = NewPoint[PreOrderInput, PreOrderOutput](PRE_ORDER)
+
+	// OnTraderStart 交易员启动时调用（主循环开始之前）
+	// 错误：Abort 时交易员不启动，错误返回给启动方
+	OnTraderStart = NewPoint[TraderLifecycleInput, TraderLifecycleOutput](ON_TRADER_START)
+
+	// OnTraderStop 交易员停止（主动停止或出错退出）后调用
+	// 错误：只记录日志
+	OnTraderStop = NewPoint[TraderLifecycleInput, TraderLifecycleOutput](ON_TRADER_STOP)
+)
+
+// GetIPInput GETIP 输入
+type GetIPInput struct {
+	UserID string
+}
+
+// GetIPOutput GETIP 输出（IP 为空时使用服务器公网IP）
+type GetIPOutput struct {
+	IP string
+}
+
+// PreDecisionInput PRE_DECISION 输入
+type PreDecisionInput struct {
+	TraderID         string
+	UserID           string
+	CycleNumber      int
+	Manual           bool     // 手动触发的周期
+	Equity           float64  // 账户净值
+	PositionCount    int      // 当前持仓数
+	CandidateSymbols []string // 本周期候选币种
+}
+
+// PreDecisionOutput PRE_DECISION 输出
+type PreDecisionOutput struct {
+	Skip   bool   // 跳过本周期（不请求AI）
+	Reason string // 跳过原因（写入决策记录）
+}
+
+// PostDecisionInput POST_DECISION 输入
+type PostDecisionInput struct {
+	TraderID    string
+	UserID      string
+	CycleNumber int
+	Success     bool
+	Error       string
+	Actions     []ActionResult // 本周期各决策的执行结果
+}
+
+// ActionResult 单个决策的执行结果
+type ActionResult struct {
+	Action   string
+	Symbol   string
+	Quantity float64
+	Price    float64
+	Success  bool
+	Error    string
+}
+
+// PostDecisionOutput POST_DECISION 输出（预留）
+type PostDecisionOutput struct{}
+
+// PreOrderInput PRE_ORDER 输入（即将发送的订单）
+type PreOrderInput struct {
+	TraderID string
+	UserID   string
+	Symbol   string
+	Action   string  // open_long / open_short / close_long / close_short / partial_close
+	Side     string  // BUY / SELL
+	Quantity float64 // 预估数量（开仓按当前价格估算，平仓按当前持仓计算）
+	Leverage int
+	Notional float64 // 预估名义价值（USDT）
+}
+
+// PreOrderOutput PRE_ORDER 输出
+// Quantity / Notional / Leverage 由调用方预先填入输入的值，处理函数只能调小（调大或设为0会被忽略）
+type PreOrderOutput struct {
+	Reject   bool    // 拒绝该订单
+	Reason   string  // 拒绝或调整的原因（写入决策记录）
+	Quantity float64 // 调整后的数量（平仓时按比例转为部分平仓）
+	Notional float64 // 调整后的名义价值（开仓）
+	Leverage int     // 调整后的杠杆（开仓）
+}
+
+// TraderLifecycleInput ON_TRADER_START / ON_TRADER_STOP 输入
+type TraderLifecycleInput struct {
+	TraderID string
+	UserID   string
+	Name     string
+	Exchange string
+	AIModel  string
+	Error    string // 出错退出时的错误（仅 ON_TRADER_STOP）
+}
+
+// TraderLifecycleOutput ON_TRADER_START / ON_TRADER_STOP 输出（预留）
+type TraderLifecycleOutput struct{}
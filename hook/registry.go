@@ -0,0 +1,209 @@
+package hook
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+)
+
+// Handler 类型化扩展点的处理函数：in 为只读输入，out 为输出（调用方预先填好默认值，处理函数按需修改）
+type Handler[In, Out any] func(ctx context.Context, in In, out *Out) error
+
+// ErrorPolicy 处理函数返回错误（或 panic）时的处理方式
+type ErrorPolicy int
+
+const (
+	// Continue 记录日志，继续执行后续处理函数（默认）
+	Continue ErrorPolicy = iota
+	// Abort 停止执行后续处理函数，Run 返回该错误（各扩展点对错误的含义见 points.go）
+	Abort
+)
+
+// Priority 处理函数优先级常量（越小越先执行，同优先级按注册顺序执行）
+const (
+	PriorityHigh    = 10
+	PriorityDefault = 100
+	PriorityLow     = 200
+)
+
+// HandlerError 处理函数返回的错误（Abort 策略时由 Run 返回）
+type HandlerError struct {
+	Point   string
+	Handler string
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("hook %s/%s: %v", e.Point, e.Handler, e.Err)
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// handlerOptions 处理函数的可选配置（注册后通过 Registration 链式修改）
+type handlerOptions struct {
+	policy ErrorPolicy
+}
+
+type entry[In, Out any] struct {
+	name     string
+	priority int
+	seq      int
+	fn       Handler[In, Out]
+	opts     *handlerOptions
+}
+
+// Registration 已注册的处理函数（用于链式调用）
+type Registration struct {
+	mu   *sync.RWMutex
+	opts *handlerOptions
+}
+
+// OnError 设置错误处理策略（链式调用）
+func (r *Registration) OnError(policy ErrorPolicy) *Registration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.opts.policy = policy
+	return r
+}
+
+// Point 类型化的扩展点：按优先级依次执行注册的处理函数
+type Point[In, Out any] struct {
+	name     string
+	mu       sync.RWMutex
+	handlers []*entry[In, Out]
+	seq      int
+}
+
+// pointInfo 扩展点的非泛型视图（用于列出全部扩展点）
+type pointInfo interface {
+	Name() string
+	Handlers() []string
+}
+
+var (
+	points   []pointInfo
+	pointsMu sync.Mutex
+)
+
+// NewPoint 创建扩展点并加入全局列表
+func NewPoint[In, Out any](name string) *Point[In, Out] {
+	p := &Point[In, Out]{name: name}
+	pointsMu.Lock()
+	points = append(points, p)
+	pointsMu.Unlock()
+	return p
+}
+
+// Name 扩展点名称
+func (p *Point[In, Out]) Name() string {
+	return p.name
+}
+
+// Register 注册处理函数（通常在插件包的 init 中调用）
+// name: 处理函数名称（用于日志）
+// priority: 优先级（建议使用常量：PriorityHigh、PriorityDefault等）
+func (p *Point[In, Out]) Register(name string, priority int, fn Handler[In, Out]) *Registration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	e := &entry[In, Out]{name: name, priority: priority, seq: p.seq, fn: fn, opts: &handlerOptions{policy: Continue}}
+	p.handlers = append(p.handlers, e)
+	sort.SliceStable(p.handlers, func(i, j int) bool {
+		if p.handlers[i].priority != p.handlers[j].priority {
+			return p.handlers[i].priority < p.handlers[j].priority
+		}
+		return p.handlers[i].seq < p.handlers[j].seq
+	})
+	return &Registration{mu: &p.mu, opts: e.opts}
+}
+
+// Handlers 已注册的处理函数名称（按执行顺序）
+func (p *Point[In, Out]) Handlers() []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	names := make([]string, 0, len(p.handlers))
+	for _, e := range p.handlers {
+		names = append(names, e.name)
+	}
+	return names
+}
+
+// Registered 是否注册了处理函数（Hook 全局关闭时视为未注册）
+func (p *Point[In, Out]) Registered() bool {
+	if !EnableHooks {
+		return false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.handlers) > 0
+}
+
+// Reset 清空处理函数（测试用）
+func (p *Point[In, Out]) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handlers = nil
+}
+
+// Run 按优先级依次执行处理函数：Continue 策略的错误只记录日志，
+// Abort 策略的错误停止执行并返回 *HandlerError；处理函数 panic 视为返回错误
+func (p *Point[In, Out]) Run(ctx context.Context, in In, out *Out) error {
+	if !EnableHooks {
+		return nil
+	}
+	p.mu.RLock()
+	type call struct {
+		e      *entry[In, Out]
+		policy ErrorPolicy
+	}
+	calls := make([]call, 0, len(p.handlers))
+	for _, e := range p.handlers {
+		calls = append(calls, call{e, e.opts.policy})
+	}
+	p.mu.RUnlock()
+
+	for _, c := range calls {
+		if err := ctx.Err(); err != nil {
+			return &HandlerError{Point: p.name, Handler: c.e.name, Err: err}
+		}
+		if err := invoke(ctx, c.e.fn, in, out); err != nil {
+			herr := &HandlerError{Point: p.name, Handler: c.e.name, Err: err}
+			if c.policy == Abort {
+				log.Printf("🔌 %v（中止）", herr)
+				return herr
+			}
+			log.Printf("🔌 %v（继续执行）", herr)
+		}
+	}
+	return nil
+}
+
+// invoke 执行单个处理函数，panic 转为错误
+func invoke[In, Out any](ctx context.Context, fn Handler[In, Out], in In, out *Out) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ Hook处理函数panic: %v\n%s", r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn(ctx, in, out)
+}
+
+// RegisteredHandlers 全部扩展点已注册的处理函数（启动时打印）
+func RegisteredHandlers() map[string][]string {
+	pointsMu.Lock()
+	defer pointsMu.Unlock()
+	result := make(map[string][]string, len(points))
+	for _, p := range points {
+		if handlers := p.Handlers(); len(handlers) > 0 {
+			result[p.Name()] = handlers
+		}
+	}
+	return result
+}
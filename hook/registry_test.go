@@ -0,0 +1,85 @@
+package hook
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type testInput struct {
+	Value int
+}
+
+type testOutput struct {
+	Calls []string
+	Total int
+}
+
+// TestPointRun 按优先级（同优先级按注册顺序）执行；Continue 策略的错误和 panic 不影响后续处理函数；Abort 策略的错误中止执行
+func TestPointRun(t *testing.T) {
+	p := NewPoint[testInput, testOutput]("TEST_POINT")
+	record := func(name string, err error) Handler[testInput, testOutput] {
+		return func(ctx context.Context, in testInput, out *testOutput) error {
+			out.Calls = append(out.Calls, name)
+			out.Total += in.Value
+			return err
+		}
+	}
+	p.Register("low", PriorityLow, record("low", nil))
+	p.Register("failing", PriorityDefault, record("failing", errors.New("boom")))
+	p.Register("default", PriorityDefault, record("default", nil))
+	p.Register("panicking", PriorityDefault, func(ctx context.Context, in testInput, out *testOutput) error {
+		panic("unexpected")
+	})
+	p.Register("high", PriorityHigh, record("high", nil))
+
+	if got := p.Handlers(); !reflect.DeepEqual(got, []string{"high", "failing", "default", "panicking", "low"}) {
+		t.Fatalf("执行顺序不正确: %v", got)
+	}
+
+	var out testOutput
+	if err := p.Run(context.Background(), testInput{Value: 2}, &out); err != nil {
+		t.Fatalf("Continue 策略的错误不应返回: %v", err)
+	}
+	if !reflect.DeepEqual(out.Calls, []string{"high", "failing", "default", "low"}) || out.Total != 8 {
+		t.Errorf("出错和panic后应继续执行后续处理函数: %+v", out)
+	}
+
+	// 改为 Abort 后出错即中止，返回带扩展点和处理函数名称的错误
+	p.Reset()
+	p.Register("first", PriorityHigh, record("first", nil))
+	abortErr := errors.New("rejected")
+	p.Register("guard", PriorityDefault, record("guard", abortErr)).OnError(Abort)
+	p.Register("last", PriorityLow, record("last", nil))
+
+	out = testOutput{}
+	err := p.Run(context.Background(), testInput{Value: 1}, &out)
+	var herr *HandlerError
+	if !errors.As(err, &herr) || herr.Point != "TEST_POINT" || herr.Handler != "guard" || !errors.Is(err, abortErr) {
+		t.Fatalf("Abort 策略应返回 HandlerError: %v", err)
+	}
+	if !reflect.DeepEqual(out.Calls, []string{"first", "guard"}) {
+		t.Errorf("Abort 后不应执行后续处理函数: %v", out.Calls)
+	}
+
+	// panic 在 Abort 策略下同样中止
+	p.Reset()
+	p.Register("panicking", PriorityDefault, func(ctx context.Context, in testInput, out *testOutput) error {
+		panic("unexpected")
+	}).OnError(Abort)
+	if err := p.Run(context.Background(), testInput{}, &testOutput{}); err == nil {
+		t.Error("Abort 策略下 panic 应返回错误")
+	}
+
+	// 全局关闭 Hook 时不执行
+	EnableHooks = false
+	defer func() { EnableHooks = true }()
+	if p.Registered() || p.Run(context.Background(), testInput{}, &testOutput{}) != nil {
+		t.Error("关闭 Hook 后不应执行处理函数")
+	}
+
+	if _, ok := RegisteredHandlers()["TEST_POINT"]; !ok {
+		t.Error("RegisteredHandlers 应包含已注册处理函数的扩展点")
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -27,6 +30,118 @@ type DecisionRecord struct {
 	ErrorMessage   string             `json:"error_message"`   // 错误信息（如果有）
 	// AIRequestDurationMs 记录 AI API 调用耗时（毫秒），方便评估调用性能
 	AIRequestDurationMs int64 `json:"ai_request_duration_ms,omitempty"`
+	// AIAttempts AI API调用尝试次数（大于1表示发生了重试，可用于观察服务商稳定性）
+	AIAttempts int `json:"ai_attempts,omitempty"`
+	// PromptTemplate/PromptTemplateVersion 本周期使用的提示词模板及版本（系统模板版本为0）
+	PromptTemplate        string `json:"prompt_template,omitempty"`
+	PromptTemplateVersion int    `json:"prompt_template_version,omitempty"`
+	// PromptVariant A/B实验分组（A/B，未参与实验的周期为空）；写入后不再修改，关闭实验不影响历史归属
+	PromptVariant string `json:"prompt_variant,omitempty"`
+	// Repaired AI首次输出未通过结构校验、发送过修复请求（用于统计各模型需要修复的频率）
+	Repaired bool            `json:"repaired,omitempty"`
+	Repair   *DecisionRepair `json:"repair,omitempty"`
+	// AIModel 实际产生本周期决策的模型；AIFallback 主模型调用失败、改用其他模型的记录
+	AIModel    string           `json:"ai_model,omitempty"`
+	AIFallback *AIFallbackEvent `json:"ai_fallback,omitempty"`
+	// Ensemble 多模型共识模式下每个模型的原始决策和合并结果
+	Ensemble *EnsembleRecord `json:"ensemble,omitempty"`
+	// ManualTrigger 由用户手动触发（而非定时）的决策周期
+	ManualTrigger bool `json:"manual_trigger,omitempty"`
+	// DryRun 决策预演记录（未执行交易，单独保存，不参与统计和历史表现分析）
+	DryRun bool `json:"dry_run,omitempty"`
+	// ModelSwitch 本周期发生的AI模型切换（如超出月度预算后降级到备用模型）
+	ModelSwitch *ModelSwitchEvent `json:"model_switch,omitempty"`
+	// CircuitBreaker 本周期发生的日亏损熔断触发/解除事件
+	CircuitBreaker *CircuitBreakerEvent `json:"circuit_breaker,omitempty"`
+	// PromptTruncation 输入提示词超出模型上下文预算时的裁剪记录
+	PromptTruncation *PromptTruncation `json:"prompt_truncation,omitempty"`
+	// Guardrails 护栏规则对开仓决策的干预（拒绝或调整仓位/杠杆）
+	Guardrails []GuardrailIntervention `json:"guardrails,omitempty"`
+	// SignalSourceErrors 本周期信号源获取/解析失败的原因（失败时使用缓存或回退到交易币种）
+	SignalSourceErrors []string `json:"signal_source_errors,omitempty"`
+
+	// 周期耗时分解（始终输出，便于前端绘制耗时曲线；旧记录没有这些字段，读取为0）
+	AIModelUsed         string `json:"ai_model_used"`         // 实际产生决策的模型
+	AILatencyMs         int64  `json:"ai_latency_ms"`         // 获取AI决策的总耗时（含重试和备用模型，毫秒）
+	PromptTokens        int    `json:"prompt_tokens"`         // 输入token
+	CompletionTokens    int    `json:"completion_tokens"`     // 输出token
+	ExchangeExecutionMs int64  `json:"exchange_execution_ms"` // 交易所下单执行耗时合计（毫秒）
+	TotalCycleMs        int64  `json:"total_cycle_ms"`        // 整个周期的耗时（毫秒）
+}
+
+// AIFallbackEvent 主模型调用失败后本周期改用备用模型的记录
+type AIFallbackEvent struct {
+	FailedModel string `json:"failed_model"`           // 调用失败的模型
+	UsedModel   string `json:"used_model"`             // 实际产生决策的模型
+	ErrorClass  string `json:"error_class"`            // 失败分类（insufficient_balance/auth/rate_limit/timeout/...）
+	Error       string `json:"error"`                  // 失败原因
+	StickyUntil string `json:"sticky_until,omitempty"` // 粘滞模式下持续使用备用模型的截止时间
+}
+
+// EnsembleRecord 多模型共识决策记录（保存每个模型的原始决策，只执行达成共识的合并结果）
+type EnsembleRecord struct {
+	Rule    string                `json:"rule"`              // 共识规则（all/majority）
+	Models  []EnsembleModelOutput `json:"models"`            // 各模型的输出（包括调用失败的模型）
+	Agreed  []string              `json:"agreed"`            // 达成共识的决策（币种: 动作）
+	Skipped []string              `json:"skipped,omitempty"` // 未达成共识、未执行的决策及各动作票数
+}
+
+// EnsembleModelOutput 共识模式下单个模型的输出和用量
+type EnsembleModelOutput struct {
+	Model            string  `json:"model"`                       // 模型ID
+	DecisionJSON     string  `json:"decision_json,omitempty"`     // 该模型的原始决策
+	CoTTrace         string  `json:"cot_trace,omitempty"`         // 该模型的思维链
+	Error            string  `json:"error,omitempty"`             // 调用失败或超时的原因
+	DurationMs       int64   `json:"duration_ms,omitempty"`       // 调用耗时（毫秒）
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`     // 输入token
+	CompletionTokens int     `json:"completion_tokens,omitempty"` // 输出token
+	CostUSD          float64 `json:"cost_usd,omitempty"`          // 估算费用
+}
+
+// PromptTruncation 提示词裁剪记录（先缩短K线序列，再移除低成交额的非持仓候选币种）
+type PromptTruncation struct {
+	BudgetTokens   int      `json:"budget_tokens"`             // 输入提示词可用的token预算
+	OriginalTokens int      `json:"original_tokens"`           // 裁剪前的估算token数
+	FinalTokens    int      `json:"final_tokens"`              // 裁剪后的估算token数
+	KlineLookback  int      `json:"kline_lookback"`            // 每个币种保留的K线序列长度
+	DroppedSymbols []string `json:"dropped_symbols,omitempty"` // 被移除的候选币种
+	OverBudget     bool     `json:"over_budget,omitempty"`     // 移除全部候选币种后仍超出预算
+}
+
+// GuardrailIntervention 护栏规则干预记录
+type GuardrailIntervention struct {
+	Rule    string `json:"rule"`    // 规则名称
+	Symbol  string `json:"symbol"`  // 币种
+	Action  string `json:"action"`  // 决策动作
+	Verdict string `json:"verdict"` // clamp/veto
+	Reason  string `json:"reason"`  // 干预原因
+}
+
+// ModelSwitchEvent AI模型切换事件
+type ModelSwitchEvent struct {
+	FromModel string `json:"from_model"` // 切换前的模型
+	ToModel   string `json:"to_model"`   // 切换后的模型
+	Reason    string `json:"reason"`     // 切换原因
+}
+
+// DecisionRepair AI决策修复记录（两次输出都保存）
+type DecisionRepair struct {
+	OriginalOutput   string   `json:"original_output"`           // 首次输出
+	ValidationErrors []string `json:"validation_errors"`         // 校验错误
+	RepairedOutput   string   `json:"repaired_output,omitempty"` // 修复请求的输出
+	Success          bool     `json:"success"`                   // 修复是否成功（失败时本周期观望）
+	Error            string   `json:"error,omitempty"`
+}
+
+// CircuitBreakerEvent 日亏损熔断事件
+type CircuitBreakerEvent struct {
+	Type           string    `json:"type"`             // tripped（触发）/ reset（新交易日自动解除）
+	LossPct        float64   `json:"loss_pct"`         // 当日亏损百分比（相对日起点净值）
+	LimitPct       float64   `json:"limit_pct"`        // 最大日亏损阈值
+	DayStartEquity float64   `json:"day_start_equity"` // 日起点净值
+	Equity         float64   `json:"equity"`           // 当前净值
+	Flattened      bool      `json:"flattened"`        // 触发时是否已平掉所有持仓
+	ResumeAt       time.Time `json:"resume_at"`        // 自动恢复时间（下一个日界）
 }
 
 // AccountSnapshot 账户状态快照
@@ -53,25 +168,38 @@ type PositionSnapshot struct {
 
 // DecisionAction 决策动作
 type DecisionAction struct {
-	Action    string    `json:"action"`    // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
-	Symbol    string    `json:"symbol"`    // 币种
-	Quantity  float64   `json:"quantity"`  // 数量（部分平仓时使用）
-	Leverage  int       `json:"leverage"`  // 杠杆（开仓时）
-	Price     float64   `json:"price"`     // 执行价格
-	OrderID   int64     `json:"order_id"`  // 订单ID
-	Timestamp time.Time `json:"timestamp"` // 执行时间
-	Success   bool      `json:"success"`   // 是否成功
-	Error     string    `json:"error"`     // 错误信息
+	Action    string    `json:"action"`              // open_long, open_short, close_long, close_short, update_stop_loss, update_take_profit, partial_close
+	Symbol    string    `json:"symbol"`              // 币种
+	Quantity  float64   `json:"quantity"`            // 数量（部分平仓时使用）
+	Leverage  int       `json:"leverage"`            // 杠杆（开仓时）
+	Price     float64   `json:"price"`               // 执行价格
+	OrderID   int64     `json:"order_id"`            // 订单ID
+	Timestamp time.Time `json:"timestamp"`           // 执行时间
+	Success   bool      `json:"success"`             // 是否成功
+	Error     string    `json:"error"`               // 错误信息
+	RiskNote  string    `json:"risk_note,omitempty"` // 风控调整说明（如开仓价值超过仓位/敞口上限被缩减）
+
+	// 成交质量（交易所返回成交均价时记录，用于衡量滑点）
+	ExecutionMode string  `json:"execution_mode,omitempty"` // 执行方式: market, limit, limit+market（限价超时后剩余部分转市价）
+	IntendedPrice float64 `json:"intended_price,omitempty"` // 决策时的参考价格
+	FillPrice     float64 `json:"fill_price,omitempty"`     // 实际成交均价
+	SlippageBps   float64 `json:"slippage_bps,omitempty"`   // 滑点（基点，正数表示比参考价更差）
 }
 
 // IDecisionLogger 决策日志记录器接口
 type IDecisionLogger interface {
 	// LogDecision 记录决策
 	LogDecision(record *DecisionRecord) error
+	// LogDryRun 记录决策预演（保存在 dry_run 子目录，不占用周期编号）
+	LogDryRun(record *DecisionRecord) error
 	// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
 	GetLatestRecords(n int) ([]*DecisionRecord, error)
+	// GetRecordsPage 分页获取记录（按时间倒序：从新到旧），cursor 为上一页返回的 NextCursor
+	GetRecordsPage(cursor string, limit int) (*DecisionPage, error)
 	// GetRecordByDate 获取指定日期的所有记录
 	GetRecordByDate(date time.Time) ([]*DecisionRecord, error)
+	// GetRecordsByTimeRange 获取 [from, to) 时间范围内的记录（按时间正序），超过 maxPoints 条时均匀抽样（maxPoints<=0 不限制）
+	GetRecordsByTimeRange(from, to time.Time, maxPoints int) ([]*DecisionRecord, error)
 	// CleanOldRecords 清理N天前的旧记录
 	CleanOldRecords(days int) error
 	// GetStatistics 获取统计信息
@@ -80,6 +208,14 @@ type IDecisionLogger interface {
 	AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error)
 }
 
+// DecisionPage 决策记录分页结果
+type DecisionPage struct {
+	Records    []*DecisionRecord `json:"records"`     // 本页记录（从新到旧）
+	Total      int               `json:"total"`       // 记录总数
+	NextCursor string            `json:"next_cursor"` // 下一页游标（为空表示没有更多）
+	HasMore    bool              `json:"has_more"`    // 是否还有更早的记录
+}
+
 // DecisionLogger 决策日志记录器
 type DecisionLogger struct {
 	logDir      string
@@ -136,6 +272,31 @@ func (l *DecisionLogger) LogDecision(record *DecisionRecord) error {
 	return nil
 }
 
+// dryRunDir 决策预演记录的子目录（读取正常记录时跳过目录，预演不影响统计）
+const dryRunDir = "dry_run"
+
+// LogDryRun 记录决策预演
+func (l *DecisionLogger) LogDryRun(record *DecisionRecord) error {
+	record.DryRun = true
+	record.Timestamp = time.Now()
+
+	dir := filepath.Join(l.logDir, dryRunDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("创建预演记录目录失败: %w", err)
+	}
+
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化预演记录失败: %w", err)
+	}
+
+	filename := fmt.Sprintf("dryrun_%s.json", record.Timestamp.Format("20060102_150405.000"))
+	if err := os.WriteFile(filepath.Join(dir, filename), data, 0600); err != nil {
+		return fmt.Errorf("写入预演记录失败: %w", err)
+	}
+	return nil
+}
+
 // GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
 func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	files, err := ioutil.ReadDir(l.logDir)
@@ -175,6 +336,99 @@ func (l *DecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
 	return records, nil
 }
 
+// GetRecordsPage 分页获取记录（按时间倒序：从新到旧）
+// 游标为上一页最后一条记录的文件名，新周期写入的记录总是排在游标之前，因此翻页结果稳定
+func (l *DecisionLogger) GetRecordsPage(cursor string, limit int) (*DecisionPage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	names, err := l.listRecordFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 定位起始位置：游标之前（更早）的第一个文件
+	start := len(names) - 1
+	if cursor != "" {
+		start = sort.Search(len(names), func(i int) bool {
+			return !recordFileLess(names[i], cursor)
+		}) - 1
+	}
+
+	page := &DecisionPage{
+		Records: make([]*DecisionRecord, 0, limit),
+		Total:   len(names),
+	}
+
+	// 只读取本页需要的文件
+	i := start
+	lastName := ""
+	for ; i >= 0 && len(page.Records) < limit; i-- {
+		data, err := ioutil.ReadFile(filepath.Join(l.logDir, names[i]))
+		if err != nil {
+			continue
+		}
+
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+
+		page.Records = append(page.Records, &record)
+		lastName = names[i]
+	}
+
+	if i >= 0 && lastName != "" {
+		page.HasMore = true
+		page.NextCursor = lastName
+	}
+
+	return page, nil
+}
+
+// listRecordFiles 列出所有决策记录文件名（按时间正序，不读取文件内容）
+func (l *DecisionLogger) listRecordFiles() ([]string, error) {
+	entries, err := os.ReadDir(l.logDir)
+	if err != nil {
+		return nil, fmt.Errorf("读取日志目录失败: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "decision_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		names = append(names, name)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return recordFileLess(names[i], names[j])
+	})
+	return names, nil
+}
+
+// recordFileLess 比较两个记录文件名的先后（decision_YYYYMMDD_HHMMSS_cycleN.json）
+// 时间相同时按周期编号数值比较，避免 cycle10 排在 cycle9 之前
+func recordFileLess(a, b string) bool {
+	const tsLen = len("decision_20060102_150405")
+	if len(a) < tsLen || len(b) < tsLen || a[:tsLen] != b[:tsLen] {
+		return a < b
+	}
+	return recordCycleNumber(a) < recordCycleNumber(b)
+}
+
+// recordCycleNumber 从文件名中解析周期编号
+func recordCycleNumber(name string) int {
+	idx := strings.LastIndex(name, "_cycle")
+	if idx < 0 {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSuffix(name[idx+len("_cycle"):], ".json"))
+	return n
+}
+
 // GetRecordByDate 获取指定日期的所有记录
 func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
 	dateStr := date.Format("20060102")
@@ -203,6 +457,67 @@ func (l *DecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, err
 	return records, nil
 }
 
+// GetRecordsByTimeRange 获取时间范围内的记录（按时间正序，超过 maxPoints 条时均匀抽样）
+// 先按文件名中的时间筛选和抽样，只读取需要返回的文件
+func (l *DecisionLogger) GetRecordsByTimeRange(from, to time.Time, maxPoints int) ([]*DecisionRecord, error) {
+	names, err := l.listRecordFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	// 文件名精确到秒（本地时间），按秒筛选后再用记录中的时间精确过滤
+	fromSec := from.Truncate(time.Second)
+	const tsLayout = "20060102_150405"
+	var matched []string
+	for _, name := range names {
+		stamp := strings.TrimPrefix(name, "decision_")
+		if len(stamp) < len(tsLayout) {
+			continue
+		}
+		ts, err := time.ParseInLocation(tsLayout, stamp[:len(tsLayout)], time.Local)
+		if err != nil || ts.Before(fromSec) || !ts.Before(to) {
+			continue
+		}
+		matched = append(matched, name)
+	}
+
+	records := make([]*DecisionRecord, 0, len(matched))
+	for _, i := range sampleEvenly(len(matched), maxPoints) {
+		data, err := os.ReadFile(filepath.Join(l.logDir, matched[i]))
+		if err != nil {
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			continue
+		}
+		if record.Timestamp.Before(from) || !record.Timestamp.Before(to) {
+			continue
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
+
+// sampleEvenly 从 n 个元素中均匀选取至多 maxPoints 个的下标（包含首尾），maxPoints<=0 时返回全部
+func sampleEvenly(n, maxPoints int) []int {
+	if maxPoints <= 0 || n <= maxPoints {
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes
+	}
+	if maxPoints == 1 {
+		return []int{n - 1}
+	}
+	indexes := make([]int, maxPoints)
+	for i := range indexes {
+		indexes[i] = i * (n - 1) / (maxPoints - 1)
+	}
+	return indexes
+}
+
 // CleanOldRecords 清理N天前的旧记录
 func (l *DecisionLogger) CleanOldRecords(days int) error {
 	cutoffTime := time.Now().AddDate(0, 0, -days)
@@ -243,6 +558,8 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 	}
 
 	stats := &Statistics{}
+	var risk riskAccumulator
+	var latency latencyCollector
 
 	for _, file := range files {
 		if file.IsDir() {
@@ -261,21 +578,19 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 		}
 
 		stats.TotalCycles++
-
-		for _, action := range record.Decisions {
-			if action.Success {
-				switch action.Action {
-				case "open_long", "open_short":
-					stats.TotalOpenPositions++
-				case "close_long", "close_short", "auto_close_long", "auto_close_short":
-					stats.TotalClosePositions++
-					// 🔧 BUG FIX：partial_close 不計入 TotalClosePositions，避免重複計數
-					// case "partial_close": // 不計數，因為只有完全平倉才算一次
-					// update_stop_loss 和 update_take_profit 不計入統計
-				}
+		if record.PromptVariant != "" {
+			if stats.VariantCycles == nil {
+				stats.VariantCycles = make(map[string]int)
 			}
+			stats.VariantCycles[record.PromptVariant]++
 		}
 
+		opens, closes := countPositionActions(&record)
+		stats.TotalOpenPositions += opens
+		stats.TotalClosePositions += closes
+		risk.add(equityPointFromRecord(&record))
+		latency.add(record.AILatencyMs, record.ExchangeExecutionMs, record.TotalCycleMs)
+
 		if record.Success {
 			stats.SuccessfulCycles++
 		} else {
@@ -283,9 +598,29 @@ func (l *DecisionLogger) GetStatistics() (*Statistics, error) {
 		}
 	}
 
+	stats.RiskMetrics = risk.result()
+	stats.Latency = latency.result()
 	return stats, nil
 }
 
+// countPositionActions 统计记录中成功的开仓和平仓次数
+func countPositionActions(record *DecisionRecord) (opens, closes int) {
+	for _, action := range record.Decisions {
+		if action.Success {
+			switch action.Action {
+			case "open_long", "open_short":
+				opens++
+			case "close_long", "close_short", "auto_close_long", "auto_close_short":
+				closes++
+				// 🔧 BUG FIX：partial_close 不計入 TotalClosePositions，避免重複計數
+				// case "partial_close": // 不計數，因為只有完全平倉才算一次
+				// update_stop_loss 和 update_take_profit 不計入統計
+			}
+		}
+	}
+	return opens, closes
+}
+
 // Statistics 统计信息
 type Statistics struct {
 	TotalCycles         int `json:"total_cycles"`
@@ -293,6 +628,82 @@ type Statistics struct {
 	FailedCycles        int `json:"failed_cycles"`
 	TotalOpenPositions  int `json:"total_open_positions"`
 	TotalClosePositions int `json:"total_close_positions"`
+
+	VariantCycles map[string]int `json:"variant_cycles,omitempty"` // A/B实验各分组的周期数
+
+	RiskMetrics // 基于每个周期账户净值的最大回撤、索提诺比率和持仓时间占比
+
+	Latency *LatencyStats `json:"latency,omitempty"` // 周期耗时分位数（只统计记录了耗时的周期）
+}
+
+// LatencyStats 周期耗时分位数
+type LatencyStats struct {
+	AI                LatencyPercentiles `json:"ai"`                 // 获取AI决策
+	ExchangeExecution LatencyPercentiles `json:"exchange_execution"` // 交易所下单执行
+	TotalCycle        LatencyPercentiles `json:"total_cycle"`        // 整个周期
+}
+
+// LatencyPercentiles 耗时分位数（毫秒）
+type LatencyPercentiles struct {
+	Samples int   `json:"samples"`
+	P50     int64 `json:"p50"`
+	P90     int64 `json:"p90"`
+	P99     int64 `json:"p99"`
+	Max     int64 `json:"max"`
+}
+
+// latencyCollector 收集各项耗时（0表示未记录，不计入）
+type latencyCollector struct {
+	ai, exchange, total []int64
+}
+
+// add 收集一条记录的耗时
+func (c *latencyCollector) add(aiMs, exchangeMs, totalMs int64) {
+	if aiMs > 0 {
+		c.ai = append(c.ai, aiMs)
+	}
+	if exchangeMs > 0 {
+		c.exchange = append(c.exchange, exchangeMs)
+	}
+	if totalMs > 0 {
+		c.total = append(c.total, totalMs)
+	}
+}
+
+// result 计算分位数，没有任何耗时记录时返回 nil
+func (c *latencyCollector) result() *LatencyStats {
+	if len(c.ai) == 0 && len(c.exchange) == 0 && len(c.total) == 0 {
+		return nil
+	}
+	return &LatencyStats{
+		AI:                latencyPercentiles(c.ai),
+		ExchangeExecution: latencyPercentiles(c.exchange),
+		TotalCycle:        latencyPercentiles(c.total),
+	}
+}
+
+// latencyPercentiles 计算耗时分位数（最近秩法）
+func latencyPercentiles(values []int64) LatencyPercentiles {
+	if len(values) == 0 {
+		return LatencyPercentiles{}
+	}
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p float64) int64 {
+		idx := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		return sorted[idx]
+	}
+	return LatencyPercentiles{
+		Samples: len(sorted),
+		P50:     rank(0.50),
+		P90:     rank(0.90),
+		P99:     rank(0.99),
+		Max:     sorted[len(sorted)-1],
+	}
 }
 
 // TradeOutcome 单笔交易结果
@@ -311,6 +722,10 @@ type TradeOutcome struct {
 	OpenTime      time.Time `json:"open_time"`      // 开仓时间
 	CloseTime     time.Time `json:"close_time"`     // 平仓时间
 	WasStopLoss   bool      `json:"was_stop_loss"`  // 是否止损
+	FundingFees   float64   `json:"funding_fees"`   // 持仓期间的资金费（正数为收到，已计入 PnL）
+
+	Variant        string `json:"variant,omitempty"`         // 开仓周期所属的A/B实验分组
+	PromptTemplate string `json:"prompt_template,omitempty"` // 开仓周期使用的提示词模板
 }
 
 // PerformanceAnalysis 交易表现分析
@@ -327,6 +742,121 @@ type PerformanceAnalysis struct {
 	SymbolStats   map[string]*SymbolPerformance `json:"symbol_stats"`   // 各币种表现
 	BestSymbol    string                        `json:"best_symbol"`    // 表现最好的币种
 	WorstSymbol   string                        `json:"worst_symbol"`   // 表现最差的币种
+
+	FundingFees float64 `json:"funding_fees"` // 统计窗口内的资金费合计（正数为收到，负数为支付）
+
+	Slippage *SlippageStats `json:"slippage,omitempty"` // 成交滑点统计（没有成交均价记录时为空）
+
+	VariantStats map[string]*VariantPerformance `json:"variant_stats,omitempty"` // A/B实验各分组表现（按开仓周期的分组归属）
+
+	RiskMetrics // 最大回撤、索提诺比率、平均持仓时长和持仓时间占比
+}
+
+// VariantPerformance A/B实验分组的交易表现
+type VariantPerformance struct {
+	Variant       string   `json:"variant"`        // 分组（A/B）
+	Templates     []string `json:"templates"`      // 该分组开仓时使用过的模板
+	TotalTrades   int      `json:"total_trades"`   // 交易次数
+	WinningTrades int      `json:"winning_trades"` // 盈利次数
+	LosingTrades  int      `json:"losing_trades"`  // 亏损次数
+	WinRate       float64  `json:"win_rate"`       // 胜率
+	TotalPnL      float64  `json:"total_pn_l"`     // 总盈亏
+	AvgPnL        float64  `json:"avg_pn_l"`       // 平均盈亏
+	ProfitFactor  float64  `json:"profit_factor"`  // 盈亏比（总盈利/总亏损）
+}
+
+// SummarizeVariants 按A/B实验分组汇总交易结果（没有分组归属的交易不计入，没有任何分组时返回 nil）
+func SummarizeVariants(trades []TradeOutcome) map[string]*VariantPerformance {
+	var stats map[string]*VariantPerformance
+	grossWin := make(map[string]float64)
+	grossLoss := make(map[string]float64)
+	for _, trade := range trades {
+		if trade.Variant == "" {
+			continue
+		}
+		if stats == nil {
+			stats = make(map[string]*VariantPerformance)
+		}
+		vs, exists := stats[trade.Variant]
+		if !exists {
+			vs = &VariantPerformance{Variant: trade.Variant, Templates: []string{}}
+			stats[trade.Variant] = vs
+		}
+		if trade.PromptTemplate != "" && !containsTemplate(vs.Templates, trade.PromptTemplate) {
+			vs.Templates = append(vs.Templates, trade.PromptTemplate)
+		}
+		vs.TotalTrades++
+		vs.TotalPnL += trade.PnL
+		if trade.PnL > 0 {
+			vs.WinningTrades++
+			grossWin[trade.Variant] += trade.PnL
+		} else if trade.PnL < 0 {
+			vs.LosingTrades++
+			grossLoss[trade.Variant] -= trade.PnL
+		}
+	}
+
+	for variant, vs := range stats {
+		vs.WinRate = float64(vs.WinningTrades) / float64(vs.TotalTrades) * 100
+		vs.AvgPnL = vs.TotalPnL / float64(vs.TotalTrades)
+		// 与整体统计一致：只有盈利没有亏损时记为 999
+		if grossLoss[variant] > 0 {
+			vs.ProfitFactor = grossWin[variant] / grossLoss[variant]
+		} else if grossWin[variant] > 0 {
+			vs.ProfitFactor = 999.0
+		}
+	}
+	return stats
+}
+
+func containsTemplate(templates []string, name string) bool {
+	for _, t := range templates {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SlippageStats 成交滑点统计（决策参考价 vs 实际成交均价）
+type SlippageStats struct {
+	Samples        int     `json:"samples"`         // 有成交均价的操作数
+	AvgBps         float64 `json:"avg_bps"`         // 平均滑点（基点，正数表示比参考价更差）
+	WorstBps       float64 `json:"worst_bps"`       // 最差滑点（基点）
+	CostUSDT       float64 `json:"cost_usdt"`       // 滑点造成的成本合计（USDT，负数表示获得价格改善）
+	LimitOrders    int     `json:"limit_orders"`    // 限价执行的开仓数
+	MarketFallback int     `json:"market_fallback"` // 限价超时后转市价的次数
+}
+
+// SummarizeSlippage 汇总决策记录中的成交滑点（没有任何成交均价记录时返回 nil）
+func SummarizeSlippage(records []*DecisionRecord) *SlippageStats {
+	stats := &SlippageStats{}
+	var totalBps float64
+	for _, record := range records {
+		for _, action := range record.Decisions {
+			if !action.Success || action.FillPrice <= 0 || action.IntendedPrice <= 0 {
+				continue
+			}
+			switch action.ExecutionMode {
+			case "limit":
+				stats.LimitOrders++
+			case "limit+market":
+				stats.LimitOrders++
+				stats.MarketFallback++
+			}
+			if stats.Samples == 0 || action.SlippageBps > stats.WorstBps {
+				stats.WorstBps = action.SlippageBps
+			}
+			stats.Samples++
+			totalBps += action.SlippageBps
+			stats.CostUSDT += action.SlippageBps / 10000 * action.IntendedPrice * action.Quantity
+		}
+	}
+	if stats.Samples == 0 {
+		return nil
+	}
+	stats.AvgBps = totalBps / float64(stats.Samples)
+	return stats
 }
 
 // SymbolPerformance 币种表现统计
@@ -342,7 +872,12 @@ type SymbolPerformance struct {
 
 // AnalyzePerformance 分析最近N个周期的交易表现
 func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error) {
-	records, err := l.GetLatestRecords(lookbackCycles)
+	return analyzePerformance(l.GetLatestRecords, lookbackCycles)
+}
+
+// analyzePerformance 基于最近的决策记录分析交易表现（文件和SQLite记录器共用）
+func analyzePerformance(latestRecords func(n int) ([]*DecisionRecord, error), lookbackCycles int) (*PerformanceAnalysis, error) {
+	records, err := latestRecords(lookbackCycles)
 	if err != nil {
 		return nil, fmt.Errorf("读取历史记录失败: %w", err)
 	}
@@ -359,15 +894,128 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		SymbolStats:  make(map[string]*SymbolPerformance),
 	}
 
+	// 为了避免开仓记录在窗口外导致匹配失败，获取更多历史记录来构建完整的持仓状态（使用更大的窗口）
+	var history []*DecisionRecord
+	if allRecords, err := latestRecords(lookbackCycles * 3); err == nil && len(allRecords) > len(records) { // 扩大3倍窗口
+		history = allRecords
+	}
+
+	for _, outcome := range PairRecordTrades(history, records) {
+		analysis.RecentTrades = append(analysis.RecentTrades, outcome)
+		analysis.TotalTrades++
+
+		// 分类交易
+		if outcome.PnL > 0 {
+			analysis.WinningTrades++
+			analysis.AvgWin += outcome.PnL
+		} else if outcome.PnL < 0 {
+			analysis.LosingTrades++
+			analysis.AvgLoss += outcome.PnL
+		}
+
+		// 更新币种统计
+		stats, exists := analysis.SymbolStats[outcome.Symbol]
+		if !exists {
+			stats = &SymbolPerformance{Symbol: outcome.Symbol}
+			analysis.SymbolStats[outcome.Symbol] = stats
+		}
+		stats.TotalTrades++
+		stats.TotalPnL += outcome.PnL
+		if outcome.PnL > 0 {
+			stats.WinningTrades++
+		} else if outcome.PnL < 0 {
+			stats.LosingTrades++
+		}
+	}
+
+	// 计算统计指标
+	if analysis.TotalTrades > 0 {
+		analysis.WinRate = (float64(analysis.WinningTrades) / float64(analysis.TotalTrades)) * 100
+
+		// 计算总盈利和总亏损
+		totalWinAmount := analysis.AvgWin   // 当前是累加的总和
+		totalLossAmount := analysis.AvgLoss // 当前是累加的总和（负数）
+
+		if analysis.WinningTrades > 0 {
+			analysis.AvgWin /= float64(analysis.WinningTrades)
+		}
+		if analysis.LosingTrades > 0 {
+			analysis.AvgLoss /= float64(analysis.LosingTrades)
+		}
+
+		// Profit Factor = 总盈利 / 总亏损（绝对值）
+		// 注意：totalLossAmount 是负数，所以取负号得到绝对值
+		if totalLossAmount != 0 {
+			analysis.ProfitFactor = totalWinAmount / (-totalLossAmount)
+		} else if totalWinAmount > 0 {
+			// 只有盈利没有亏损的情况，设置为一个很大的值表示完美策略
+			analysis.ProfitFactor = 999.0
+		}
+	}
+
+	// 计算各币种胜率和平均盈亏
+	bestPnL := -999999.0
+	worstPnL := 999999.0
+	for symbol, stats := range analysis.SymbolStats {
+		if stats.TotalTrades > 0 {
+			stats.WinRate = (float64(stats.WinningTrades) / float64(stats.TotalTrades)) * 100
+			stats.AvgPnL = stats.TotalPnL / float64(stats.TotalTrades)
+
+			if stats.TotalPnL > bestPnL {
+				bestPnL = stats.TotalPnL
+				analysis.BestSymbol = symbol
+			}
+			if stats.TotalPnL < worstPnL {
+				worstPnL = stats.TotalPnL
+				analysis.WorstSymbol = symbol
+			}
+		}
+	}
+
+	// A/B实验分组表现（在截取最近交易之前统计全部交易）
+	analysis.VariantStats = SummarizeVariants(analysis.RecentTrades)
+
+	// 风险指标：回撤、索提诺比率和持仓时间占比来自每个周期的账户净值，平均持仓时长来自配对的交易
+	var risk riskAccumulator
+	for _, record := range records {
+		risk.add(equityPointFromRecord(record))
+	}
+	analysis.RiskMetrics = risk.result()
+	if len(analysis.RecentTrades) > 0 {
+		analysis.AvgHoldingMinutes = averageHoldingMinutes(analysis.RecentTrades)
+	}
+
+	// 只保留最近的交易（倒序：最新的在前）
+	if len(analysis.RecentTrades) > 10 {
+		// 反转数组，让最新的在前
+		for i, j := 0, len(analysis.RecentTrades)-1; i < j; i, j = i+1, j-1 {
+			analysis.RecentTrades[i], analysis.RecentTrades[j] = analysis.RecentTrades[j], analysis.RecentTrades[i]
+		}
+		analysis.RecentTrades = analysis.RecentTrades[:10]
+	} else if len(analysis.RecentTrades) > 0 {
+		// 反转数组
+		for i, j := 0, len(analysis.RecentTrades)-1; i < j; i, j = i+1, j-1 {
+			analysis.RecentTrades[i], analysis.RecentTrades[j] = analysis.RecentTrades[j], analysis.RecentTrades[i]
+		}
+	}
+
+	// 计算夏普比率（需要至少2个数据点）
+	analysis.SharpeRatio = calculateSharpeRatio(records)
+	analysis.Slippage = SummarizeSlippage(records)
+
+	return analysis, nil
+}
+
+// PairRecordTrades 按币种和方向配对决策记录中的开平仓动作，按平仓顺序返回完全平仓的交易
+// history 只用于恢复 records 开始前仍在持仓的开仓信息（可包含 records 本身）；部分平仓累积到完全平仓时记为一笔
+func PairRecordTrades(history, records []*DecisionRecord) []TradeOutcome {
 	// 追踪持仓状态：symbol_side -> {side, openPrice, openTime, quantity, leverage}
 	openPositions := make(map[string]map[string]interface{})
 
-	// 为了避免开仓记录在窗口外导致匹配失败，需要先从所有历史记录中找出未平仓的持仓
-	// 获取更多历史记录来构建完整的持仓状态（使用更大的窗口）
-	allRecords, err := l.GetLatestRecords(lookbackCycles * 3) // 扩大3倍窗口
-	if err == nil && len(allRecords) > len(records) {
+	// 为了避免开仓记录在窗口外导致匹配失败，先从 history 中找出未平仓的持仓
+	if len(history) > 0 {
 		// 先从扩大的窗口中收集所有开仓记录
-		for _, record := range allRecords {
+		for _, record := range history {
 			for _, action := range record.Decisions {
 				if !action.Success {
 					continue
@@ -402,6 +1050,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						"openTime":  action.Timestamp,
 						"quantity":  action.Quantity,
 						"leverage":  action.Leverage,
+						"variant":   record.PromptVariant,
+						"template":  record.PromptTemplate,
 					}
 				case "close_long", "close_short", "auto_close_long", "auto_close_short":
 					// 移除已平仓记录
@@ -413,6 +1063,7 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 	}
 
 	// 遍历分析窗口内的记录，生成交易结果
+	var outcomes []TradeOutcome
 	for _, record := range records {
 		for _, action := range record.Decisions {
 			if !action.Success {
@@ -453,6 +1104,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					"accumulatedPnL":     0.0,             // 🔧 BUG FIX：累積部分平倉盈虧
 					"partialCloseCount":  0,               // 🔧 BUG FIX：部分平倉次數
 					"partialCloseVolume": 0.0,             // 🔧 BUG FIX：部分平倉總量
+					"variant":            record.PromptVariant,
+					"template":           record.PromptTemplate,
 				}
 
 			case "close_long", "close_short", "partial_close", "auto_close_long", "auto_close_short":
@@ -463,6 +1116,8 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 					side := openPos["side"].(string)
 					quantity := openPos["quantity"].(float64)
 					leverage := openPos["leverage"].(int)
+					variant, _ := openPos["variant"].(string)
+					promptTemplate, _ := openPos["template"].(string)
 
 					// 🔧 BUG FIX：取得追蹤字段（若不存在則初始化）
 					remainingQty, _ := openPos["remainingQuantity"].(float64)
@@ -512,47 +1167,24 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 							}
 
 							outcome := TradeOutcome{
-								Symbol:        symbol,
-								Side:          side,
-								Quantity:      quantity, // 使用原始總量
-								Leverage:      leverage,
-								OpenPrice:     openPrice,
-								ClosePrice:    action.Price, // 最後一次平倉價格
-								PositionValue: positionValue,
-								MarginUsed:    marginUsed,
-								PnL:           accumulatedPnL, // 🔧 使用累積盈虧
-								PnLPct:        pnlPct,
-								Duration:      action.Timestamp.Sub(openTime).String(),
-								OpenTime:      openTime,
-								CloseTime:     action.Timestamp,
+								Symbol:         symbol,
+								Side:           side,
+								Quantity:       quantity, // 使用原始總量
+								Leverage:       leverage,
+								OpenPrice:      openPrice,
+								ClosePrice:     action.Price, // 最後一次平倉價格
+								PositionValue:  positionValue,
+								MarginUsed:     marginUsed,
+								PnL:            accumulatedPnL, // 🔧 使用累積盈虧
+								PnLPct:         pnlPct,
+								Duration:       action.Timestamp.Sub(openTime).String(),
+								OpenTime:       openTime,
+								CloseTime:      action.Timestamp,
+								Variant:        variant,
+								PromptTemplate: promptTemplate,
 							}
 
-							analysis.RecentTrades = append(analysis.RecentTrades, outcome)
-							analysis.TotalTrades++ // 🔧 只在完全平倉時計數
-
-							// 分类交易
-							if accumulatedPnL > 0 {
-								analysis.WinningTrades++
-								analysis.AvgWin += accumulatedPnL
-							} else if accumulatedPnL < 0 {
-								analysis.LosingTrades++
-								analysis.AvgLoss += accumulatedPnL
-							}
-
-							// 更新币种统计
-							if _, exists := analysis.SymbolStats[symbol]; !exists {
-								analysis.SymbolStats[symbol] = &SymbolPerformance{
-									Symbol: symbol,
-								}
-							}
-							stats := analysis.SymbolStats[symbol]
-							stats.TotalTrades++
-							stats.TotalPnL += accumulatedPnL
-							if accumulatedPnL > 0 {
-								stats.WinningTrades++
-							} else if accumulatedPnL < 0 {
-								stats.LosingTrades++
-							}
+							outcomes = append(outcomes, outcome) // 🔧 只在完全平倉時計數
 
 							// 刪除持倉記錄
 							delete(openPositions, posKey)
@@ -572,47 +1204,24 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 						}
 
 						outcome := TradeOutcome{
-							Symbol:        symbol,
-							Side:          side,
-							Quantity:      quantity, // 使用原始總量
-							Leverage:      leverage,
-							OpenPrice:     openPrice,
-							ClosePrice:    action.Price,
-							PositionValue: positionValue,
-							MarginUsed:    marginUsed,
-							PnL:           totalPnL, // 🔧 包含之前部分平倉的 PnL
-							PnLPct:        pnlPct,
-							Duration:      action.Timestamp.Sub(openTime).String(),
-							OpenTime:      openTime,
-							CloseTime:     action.Timestamp,
-						}
-
-						analysis.RecentTrades = append(analysis.RecentTrades, outcome)
-						analysis.TotalTrades++
-
-						// 分类交易
-						if totalPnL > 0 {
-							analysis.WinningTrades++
-							analysis.AvgWin += totalPnL
-						} else if totalPnL < 0 {
-							analysis.LosingTrades++
-							analysis.AvgLoss += totalPnL
+							Symbol:         symbol,
+							Side:           side,
+							Quantity:       quantity, // 使用原始總量
+							Leverage:       leverage,
+							OpenPrice:      openPrice,
+							ClosePrice:     action.Price,
+							PositionValue:  positionValue,
+							MarginUsed:     marginUsed,
+							PnL:            totalPnL, // 🔧 包含之前部分平倉的 PnL
+							PnLPct:         pnlPct,
+							Duration:       action.Timestamp.Sub(openTime).String(),
+							OpenTime:       openTime,
+							CloseTime:      action.Timestamp,
+							Variant:        variant,
+							PromptTemplate: promptTemplate,
 						}
 
-						// 更新币种统计
-						if _, exists := analysis.SymbolStats[symbol]; !exists {
-							analysis.SymbolStats[symbol] = &SymbolPerformance{
-								Symbol: symbol,
-							}
-						}
-						stats := analysis.SymbolStats[symbol]
-						stats.TotalTrades++
-						stats.TotalPnL += totalPnL
-						if totalPnL > 0 {
-							stats.WinningTrades++
-						} else if totalPnL < 0 {
-							stats.LosingTrades++
-						}
+						outcomes = append(outcomes, outcome)
 
 						// 刪除持倉記錄
 						delete(openPositions, posKey)
@@ -622,73 +1231,12 @@ func (l *DecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAna
 		}
 	}
 
-	// 计算统计指标
-	if analysis.TotalTrades > 0 {
-		analysis.WinRate = (float64(analysis.WinningTrades) / float64(analysis.TotalTrades)) * 100
-
-		// 计算总盈利和总亏损
-		totalWinAmount := analysis.AvgWin   // 当前是累加的总和
-		totalLossAmount := analysis.AvgLoss // 当前是累加的总和（负数）
-
-		if analysis.WinningTrades > 0 {
-			analysis.AvgWin /= float64(analysis.WinningTrades)
-		}
-		if analysis.LosingTrades > 0 {
-			analysis.AvgLoss /= float64(analysis.LosingTrades)
-		}
-
-		// Profit Factor = 总盈利 / 总亏损（绝对值）
-		// 注意：totalLossAmount 是负数，所以取负号得到绝对值
-		if totalLossAmount != 0 {
-			analysis.ProfitFactor = totalWinAmount / (-totalLossAmount)
-		} else if totalWinAmount > 0 {
-			// 只有盈利没有亏损的情况，设置为一个很大的值表示完美策略
-			analysis.ProfitFactor = 999.0
-		}
-	}
-
-	// 计算各币种胜率和平均盈亏
-	bestPnL := -999999.0
-	worstPnL := 999999.0
-	for symbol, stats := range analysis.SymbolStats {
-		if stats.TotalTrades > 0 {
-			stats.WinRate = (float64(stats.WinningTrades) / float64(stats.TotalTrades)) * 100
-			stats.AvgPnL = stats.TotalPnL / float64(stats.TotalTrades)
-
-			if stats.TotalPnL > bestPnL {
-				bestPnL = stats.TotalPnL
-				analysis.BestSymbol = symbol
-			}
-			if stats.TotalPnL < worstPnL {
-				worstPnL = stats.TotalPnL
-				analysis.WorstSymbol = symbol
-			}
-		}
-	}
-
-	// 只保留最近的交易（倒序：最新的在前）
-	if len(analysis.RecentTrades) > 10 {
-		// 反转数组，让最新的在前
-		for i, j := 0, len(analysis.RecentTrades)-1; i < j; i, j = i+1, j-1 {
-			analysis.RecentTrades[i], analysis.RecentTrades[j] = analysis.RecentTrades[j], analysis.RecentTrades[i]
-		}
-		analysis.RecentTrades = analysis.RecentTrades[:10]
-	} else if len(analysis.RecentTrades) > 0 {
-		// 反转数组
-		for i, j := 0, len(analysis.RecentTrades)-1; i < j; i, j = i+1, j-1 {
-			analysis.RecentTrades[i], analysis.RecentTrades[j] = analysis.RecentTrades[j], analysis.RecentTrades[i]
-		}
-	}
-
-	// 计算夏普比率（需要至少2个数据点）
-	analysis.SharpeRatio = l.calculateSharpeRatio(records)
-
-	return analysis, nil
+	return outcomes
 }
 
 // calculateSharpeRatio 计算夏普比率
 // 基于账户净值的变化计算风险调整后收益
-func (l *DecisionLogger) calculateSharpeRatio(records []*DecisionRecord) float64 {
+func calculateSharpeRatio(records []*DecisionRecord) float64 {
 	if len(records) < 2 {
 		return 0.0
 	}
@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+)
+
+// TestGetRecordsPage_StableWhileWriting 分页按时间倒序，翻页期间写入新记录不影响后续页
+func TestGetRecordsPage_StableWhileWriting(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+
+	// 同一秒内写入多条记录，cycle10 应排在 cycle9 之后
+	for i := 0; i < 12; i++ {
+		if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+
+	page, err := l.GetRecordsPage("", 5)
+	if err != nil {
+		t.Fatalf("获取第一页失败: %v", err)
+	}
+	if page.Total != 12 || len(page.Records) != 5 || !page.HasMore {
+		t.Fatalf("第一页不正确: total=%d len=%d has_more=%v", page.Total, len(page.Records), page.HasMore)
+	}
+	if page.Records[0].CycleNumber != 12 || page.Records[4].CycleNumber != 8 {
+		t.Errorf("第一页应为 cycle12..8，实际 %d..%d", page.Records[0].CycleNumber, page.Records[4].CycleNumber)
+	}
+
+	// 翻页期间写入新周期
+	for i := 0; i < 3; i++ {
+		if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+
+	page, err = l.GetRecordsPage(page.NextCursor, 5)
+	if err != nil {
+		t.Fatalf("获取第二页失败: %v", err)
+	}
+	if page.Total != 15 || len(page.Records) != 5 {
+		t.Fatalf("第二页不正确: total=%d len=%d", page.Total, len(page.Records))
+	}
+	if page.Records[0].CycleNumber != 7 || page.Records[4].CycleNumber != 3 {
+		t.Errorf("第二页应为 cycle7..3，实际 %d..%d", page.Records[0].CycleNumber, page.Records[4].CycleNumber)
+	}
+
+	page, err = l.GetRecordsPage(page.NextCursor, 5)
+	if err != nil {
+		t.Fatalf("获取最后一页失败: %v", err)
+	}
+	if len(page.Records) != 2 || page.HasMore || page.NextCursor != "" {
+		t.Errorf("最后一页应只有2条且没有更多: len=%d has_more=%v cursor=%q", len(page.Records), page.HasMore, page.NextCursor)
+	}
+}
+
+// TestSummarizeSlippage 只统计成功且记录了成交均价的操作
+func TestSummarizeSlippage(t *testing.T) {
+	if SummarizeSlippage([]*DecisionRecord{{Decisions: []DecisionAction{{Success: true, Price: 100}}}}) != nil {
+		t.Error("没有成交均价记录时应返回 nil")
+	}
+
+	records := []*DecisionRecord{
+		{Decisions: []DecisionAction{
+			{Success: true, Quantity: 2, IntendedPrice: 100, FillPrice: 100.1, SlippageBps: 10, ExecutionMode: "market"},
+			{Success: true, Quantity: 1, IntendedPrice: 200, FillPrice: 199.9, SlippageBps: -5, ExecutionMode: "limit"},
+		}},
+		{Decisions: []DecisionAction{
+			{Success: true, Quantity: 1, IntendedPrice: 100, FillPrice: 100.3, SlippageBps: 30, ExecutionMode: "limit+market"},
+			{Success: false, Quantity: 1, IntendedPrice: 100, FillPrice: 110, SlippageBps: 1000},
+		}},
+	}
+	stats := SummarizeSlippage(records)
+	if stats == nil || stats.Samples != 3 {
+		t.Fatalf("样本数不正确: %+v", stats)
+	}
+	if stats.AvgBps < 11.66 || stats.AvgBps > 11.67 || stats.WorstBps != 30 {
+		t.Errorf("平均/最差滑点 = (%v, %v), want (11.67, 30)", stats.AvgBps, stats.WorstBps)
+	}
+	// 0.2 + (-0.1) + 0.3 = 0.4 USDT
+	if stats.CostUSDT < 0.3999 || stats.CostUSDT > 0.4001 {
+		t.Errorf("滑点成本 = %v, want 0.4", stats.CostUSDT)
+	}
+	if stats.LimitOrders != 2 || stats.MarketFallback != 1 {
+		t.Errorf("限价/转市价次数 = (%d, %d), want (2, 1)", stats.LimitOrders, stats.MarketFallback)
+	}
+}
+
+// TestVariantAttribution 交易按开仓周期的实验分组归属，关闭实验后平仓不影响历史归属
+func TestVariantAttribution(t *testing.T) {
+	l := NewDecisionLogger(t.TempDir())
+	open := func(symbol string, price, qty float64) DecisionAction {
+		return DecisionAction{Action: "open_long", Symbol: symbol, Price: price, Quantity: qty, Leverage: 5, Success: true}
+	}
+	closeLong := func(symbol string, price float64) DecisionAction {
+		return DecisionAction{Action: "close_long", Symbol: symbol, Price: price, Success: true}
+	}
+	records := []*DecisionRecord{
+		{PromptVariant: "A", PromptTemplate: "trend", Decisions: []DecisionAction{open("BTCUSDT", 100, 1)}},
+		{PromptVariant: "B", PromptTemplate: "revert", Decisions: []DecisionAction{open("ETHUSDT", 10, 10)}},
+		// 实验已关闭：平仓周期没有分组标签
+		{Decisions: []DecisionAction{closeLong("BTCUSDT", 110), closeLong("ETHUSDT", 9)}},
+		{Decisions: []DecisionAction{open("SOLUSDT", 20, 1)}},
+		{Decisions: []DecisionAction{closeLong("SOLUSDT", 25)}},
+	}
+	for _, record := range records {
+		record.Success = true
+		if err := l.LogDecision(record); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+
+	analysis, err := l.AnalyzePerformance(100)
+	if err != nil {
+		t.Fatalf("分析失败: %v", err)
+	}
+	if analysis.TotalTrades != 3 {
+		t.Fatalf("总交易数 = %d, want 3", analysis.TotalTrades)
+	}
+	if len(analysis.VariantStats) != 2 {
+		t.Fatalf("应只有A/B两组（未参与实验的交易不计入）: %+v", analysis.VariantStats)
+	}
+	a, b := analysis.VariantStats["A"], analysis.VariantStats["B"]
+	if a.TotalTrades != 1 || a.WinRate != 100 || a.TotalPnL != 10 || a.ProfitFactor != 999 {
+		t.Errorf("A组统计不正确: %+v", a)
+	}
+	if b.TotalTrades != 1 || b.LosingTrades != 1 || b.TotalPnL != -10 || b.ProfitFactor != 0 {
+		t.Errorf("B组统计不正确: %+v", b)
+	}
+	if len(a.Templates) != 1 || a.Templates[0] != "trend" {
+		t.Errorf("A组模板 = %v, want [trend]", a.Templates)
+	}
+
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if stats.VariantCycles["A"] != 1 || stats.VariantCycles["B"] != 1 || stats.TotalCycles != 5 {
+		t.Errorf("分组周期数不正确: %+v", stats)
+	}
+}
+
+// TestLogDryRun_ExcludedFromRecords 预演记录单独保存，不占用周期编号，也不出现在正常记录和统计中
+func TestLogDryRun_ExcludedFromRecords(t *testing.T) {
+	dir := t.TempDir()
+	l := NewDecisionLogger(dir)
+
+	if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("写入记录失败: %v", err)
+	}
+	dryRun := &DecisionRecord{Success: true, DecisionJSON: "[]"}
+	if err := l.LogDryRun(dryRun); err != nil {
+		t.Fatalf("写入预演记录失败: %v", err)
+	}
+	if !dryRun.DryRun || dryRun.CycleNumber != 0 {
+		t.Errorf("预演记录应标记 dry_run 且不占用周期编号: dry_run=%v cycle=%d", dryRun.DryRun, dryRun.CycleNumber)
+	}
+	if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("写入记录失败: %v", err)
+	}
+
+	records, err := l.GetLatestRecords(10)
+	if err != nil {
+		t.Fatalf("获取记录失败: %v", err)
+	}
+	if len(records) != 2 || records[1].CycleNumber != 2 {
+		t.Fatalf("正常记录应为2条且周期连续，实际 %d 条", len(records))
+	}
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("获取统计失败: %v", err)
+	}
+	if stats.TotalCycles != 2 {
+		t.Errorf("统计不应包含预演记录: total_cycles=%d", stats.TotalCycles)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, dryRunDir, "dryrun_*.json"))
+	if err != nil || len(files) != 1 {
+		t.Errorf("预演记录应保存在 %s 子目录: %v %v", dryRunDir, files, err)
+	}
+}
+
+// TestCycleTiming_OldRecordsAndPercentiles 旧记录的耗时字段读取为0且不计入分位数
+func TestCycleTiming_OldRecordsAndPercentiles(t *testing.T) {
+	var old DecisionRecord
+	if err := json.Unmarshal([]byte(`{"cycle_number": 1, "success": true, "ai_request_duration_ms": 800}`), &old); err != nil {
+		t.Fatalf("旧记录应能正常解析: %v", err)
+	}
+	if old.AILatencyMs != 0 || old.TotalCycleMs != 0 || old.AIModelUsed != "" || old.PromptTokens != 0 {
+		t.Errorf("旧记录的耗时字段应为0: %+v", old)
+	}
+
+	l := newTestSQLiteLogger(t, t.TempDir())
+	if err := insertDecision(l.db, &old); err != nil {
+		t.Fatalf("写入旧记录失败: %v", err)
+	}
+	for i := 1; i <= 100; i++ {
+		record := &DecisionRecord{CycleNumber: i + 1, Success: true, AILatencyMs: int64(i * 100), TotalCycleMs: int64(i * 200)}
+		if i%2 == 0 {
+			record.ExchangeExecutionMs = int64(i * 10)
+		}
+		if err := insertDecision(l.db, record); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+	stats, err := l.GetStatistics()
+	if err != nil || stats.Latency == nil {
+		t.Fatalf("统计应包含耗时分位数: %+v %v", stats, err)
+	}
+	want := LatencyPercentiles{Samples: 100, P50: 5000, P90: 9000, P99: 9900, Max: 10000}
+	if stats.Latency.AI != want {
+		t.Errorf("AI耗时分位数不正确: %+v", stats.Latency.AI)
+	}
+	if stats.Latency.ExchangeExecution.Samples != 50 || stats.Latency.ExchangeExecution.P50 != 500 || stats.Latency.TotalCycle.P90 != 18000 {
+		t.Errorf("下单/周期耗时分位数不正确: %+v", stats.Latency)
+	}
+
+	if stats, err := NewDecisionLogger(t.TempDir()).GetStatistics(); err != nil || stats.Latency != nil {
+		t.Errorf("没有耗时记录时不应输出分位数: %+v %v", stats, err)
+	}
+}
@@ -0,0 +1,216 @@
+package logger
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// maxSortinoRatio 索提诺比率的上限（没有下行波动或样本很少时避免出现异常大的值）
+const maxSortinoRatio = 10.0
+
+// RiskMetrics 基于权益曲线和持仓区间的风险指标
+type RiskMetrics struct {
+	MaxDrawdown       float64 `json:"max_drawdown"`        // 最大回撤（USDT，峰值到谷底的跌幅）
+	MaxDrawdownPct    float64 `json:"max_drawdown_pct"`    // 最大回撤百分比（相对回撤开始时的峰值）
+	SortinoRatio      float64 `json:"sortino_ratio"`       // 索提诺比率（平均周期收益 / 下行偏差，不年化，限制在 [-10, 10]）
+	AvgHoldingMinutes float64 `json:"avg_holding_minutes"` // 平均持仓时长（分钟）
+	TimeInMarketPct   float64 `json:"time_in_market_pct"`  // 有持仓的时间占统计区间的百分比
+}
+
+// EquityPoint 权益曲线上的一个点
+type EquityPoint struct {
+	Time     time.Time
+	Equity   float64
+	InMarket bool // 该点到下一个点之间是否有持仓
+}
+
+// riskAccumulator 逐点累积风险指标（一次遍历，不保存整条曲线）
+type riskAccumulator struct {
+	count     int
+	first     time.Time
+	last      EquityPoint
+	peak      float64
+	maxDD     float64
+	maxDDPct  float64
+	returns   int
+	sumReturn float64
+	sumDown2  float64 // 负收益的平方和（下行偏差）
+
+	inMarket     time.Duration
+	episodes     int           // 连续持仓区间的数量
+	episodeTotal time.Duration // 连续持仓区间的总时长
+	episodeStart time.Time
+}
+
+// add 追加一个权益点（需按时间正序，净值<=0的点视为无效数据跳过）
+func (a *riskAccumulator) add(p EquityPoint) {
+	if p.Equity <= 0 {
+		return
+	}
+	if a.count == 0 {
+		a.first = p.Time
+		a.peak = p.Equity
+	} else {
+		prev := a.last
+		if prev.InMarket {
+			a.inMarket += p.Time.Sub(prev.Time)
+		}
+		r := (p.Equity - prev.Equity) / prev.Equity
+		a.returns++
+		a.sumReturn += r
+		if r < 0 {
+			a.sumDown2 += r * r
+		}
+	}
+
+	// 持仓区间：从进入持仓的点到离开持仓的点
+	if p.InMarket && (a.count == 0 || !a.last.InMarket) {
+		a.episodeStart = p.Time
+	} else if !p.InMarket && a.count > 0 && a.last.InMarket {
+		a.episodes++
+		a.episodeTotal += p.Time.Sub(a.episodeStart)
+	}
+
+	if p.Equity > a.peak {
+		a.peak = p.Equity
+	}
+	if dd := a.peak - p.Equity; dd > a.maxDD {
+		a.maxDD = dd
+	}
+	if ddPct := (a.peak - p.Equity) / a.peak * 100; ddPct > a.maxDDPct {
+		a.maxDDPct = ddPct
+	}
+
+	a.count++
+	a.last = p
+}
+
+// result 计算累积的风险指标（最后一个点仍在持仓时，其未结束的持仓区间计入平均持仓时长）
+func (a *riskAccumulator) result() RiskMetrics {
+	m := RiskMetrics{MaxDrawdown: a.maxDD, MaxDrawdownPct: a.maxDDPct}
+	if a.count < 2 {
+		return m
+	}
+
+	if span := a.last.Time.Sub(a.first); span > 0 {
+		m.TimeInMarketPct = float64(a.inMarket) / float64(span) * 100
+	}
+	episodes, total := a.episodes, a.episodeTotal
+	if a.last.InMarket && a.last.Time.After(a.episodeStart) {
+		episodes++
+		total += a.last.Time.Sub(a.episodeStart)
+	}
+	if episodes > 0 {
+		m.AvgHoldingMinutes = total.Minutes() / float64(episodes)
+	}
+
+	m.SortinoRatio = sortinoRatio(a.sumReturn/float64(a.returns), math.Sqrt(a.sumDown2/float64(a.returns)))
+	return m
+}
+
+// sortinoRatio 平均收益 / 下行偏差（无风险利率为0），没有下行波动时正收益取上限
+func sortinoRatio(meanReturn, downsideDev float64) float64 {
+	if downsideDev == 0 {
+		if meanReturn > 0 {
+			return maxSortinoRatio
+		}
+		return 0
+	}
+	return math.Max(-maxSortinoRatio, math.Min(maxSortinoRatio, meanReturn/downsideDev))
+}
+
+// ComputeRiskMetrics 一次遍历权益曲线计算最大回撤、索提诺比率、持仓时间占比和平均持仓时长
+// points 需按时间正序；平均持仓时长按连续持仓区间计算（有逐笔交易时用 ApplyTradeExposure 覆盖）
+func ComputeRiskMetrics(points []EquityPoint) RiskMetrics {
+	var acc riskAccumulator
+	for _, p := range points {
+		acc.add(p)
+	}
+	return acc.result()
+}
+
+// equityPointFromRecord 决策记录中的账户净值和持仓状态
+func equityPointFromRecord(record *DecisionRecord) EquityPoint {
+	return EquityPoint{
+		Time:     record.Timestamp,
+		Equity:   record.AccountState.TotalBalance,
+		InMarket: record.AccountState.PositionCount > 0,
+	}
+}
+
+// ApplyTradeExposure 按逐笔交易的开平仓时间计算平均持仓时长和 [from, to] 内的持仓时间占比
+// 重叠的持仓区间合并后计算占比；from/to 为零值时使用交易的最早开仓和最晚平仓时间
+func ApplyTradeExposure(m *RiskMetrics, trades []TradeOutcome, from, to time.Time) {
+	if len(trades) == 0 {
+		return
+	}
+	sorted := make([]TradeOutcome, len(trades))
+	copy(sorted, trades)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].OpenTime.Before(sorted[j].OpenTime) })
+
+	if from.IsZero() {
+		from = sorted[0].OpenTime
+	}
+	var covered time.Duration
+	var curStart, curEnd, lastClose time.Time
+	for _, trade := range sorted {
+		if trade.CloseTime.After(lastClose) {
+			lastClose = trade.CloseTime
+		}
+
+		start, end := trade.OpenTime, trade.CloseTime
+		if start.Before(from) {
+			start = from
+		}
+		if !to.IsZero() && end.After(to) {
+			end = to
+		}
+		if !end.After(start) {
+			continue
+		}
+		if curEnd.IsZero() || start.After(curEnd) {
+			covered += curEnd.Sub(curStart)
+			curStart, curEnd = start, end
+		} else if end.After(curEnd) {
+			curEnd = end
+		}
+	}
+	covered += curEnd.Sub(curStart)
+
+	m.AvgHoldingMinutes = averageHoldingMinutes(sorted)
+	if to.IsZero() {
+		to = lastClose
+	}
+	if span := to.Sub(from); span > 0 {
+		m.TimeInMarketPct = float64(covered) / float64(span) * 100
+	}
+}
+
+// averageHoldingMinutes 逐笔交易的平均持仓时长（分钟）
+func averageHoldingMinutes(trades []TradeOutcome) float64 {
+	if len(trades) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, trade := range trades {
+		total += trade.CloseTime.Sub(trade.OpenTime)
+	}
+	return total.Minutes() / float64(len(trades))
+}
+
+// TradeEquityCurve 由起始净值和按平仓时间累计的交易盈亏构造权益曲线（用于没有净值快照的交易所成交分析）
+func TradeEquityCurve(trades []TradeOutcome, startEquity float64, from time.Time) []EquityPoint {
+	sorted := make([]TradeOutcome, len(trades))
+	copy(sorted, trades)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].CloseTime.Before(sorted[j].CloseTime) })
+
+	points := make([]EquityPoint, 0, len(sorted)+1)
+	points = append(points, EquityPoint{Time: from, Equity: startEquity})
+	equity := startEquity
+	for _, trade := range sorted {
+		equity += trade.PnL
+		points = append(points, EquityPoint{Time: trade.CloseTime, Equity: equity})
+	}
+	return points
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// approxEqual 浮点数近似相等
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// riskFixture 每10分钟一个点：净值 100 → 110 → 99 → 104.5 → 121，持仓 是/是/否/是/否
+func riskFixture(start time.Time) []EquityPoint {
+	equities := []float64{100, 110, 99, 104.5, 121}
+	inMarket := []bool{true, true, false, true, false}
+	points := make([]EquityPoint, len(equities))
+	for i := range equities {
+		points[i] = EquityPoint{Time: start.Add(time.Duration(i) * 10 * time.Minute), Equity: equities[i], InMarket: inMarket[i]}
+	}
+	return points
+}
+
+// TestComputeRiskMetrics 对照手算结果
+func TestComputeRiskMetrics(t *testing.T) {
+	m := ComputeRiskMetrics(riskFixture(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	// 峰值110 → 谷底99：回撤11 USDT，10%
+	if !approxEqual(m.MaxDrawdown, 11) || !approxEqual(m.MaxDrawdownPct, 10) {
+		t.Errorf("最大回撤应为 11 / 10%%，实际 %v / %v", m.MaxDrawdown, m.MaxDrawdownPct)
+	}
+	// 收益率 +10%, -10%, +5.5/99, +16.5/104.5；下行偏差 = sqrt(0.1²/4) = 0.05
+	mean := (0.1 - 0.1 + 5.5/99 + 16.5/104.5) / 4
+	if !approxEqual(m.SortinoRatio, mean/0.05) {
+		t.Errorf("索提诺比率应为 %v，实际 %v", mean/0.05, m.SortinoRatio)
+	}
+	// 40分钟中有30分钟持仓；持仓区间 20分钟 和 10分钟
+	if !approxEqual(m.TimeInMarketPct, 75) || !approxEqual(m.AvgHoldingMinutes, 15) {
+		t.Errorf("持仓占比应为75%%、平均持仓15分钟，实际 %v / %v", m.TimeInMarketPct, m.AvgHoldingMinutes)
+	}
+
+	// 不足两个点或没有下行波动
+	if m := ComputeRiskMetrics(nil); m != (RiskMetrics{}) {
+		t.Errorf("空曲线应返回零值: %+v", m)
+	}
+	rising := []EquityPoint{{Time: time.Unix(0, 0), Equity: 100}, {Time: time.Unix(60, 0), Equity: 101}}
+	if m := ComputeRiskMetrics(rising); m.SortinoRatio != maxSortinoRatio || m.MaxDrawdown != 0 {
+		t.Errorf("只涨不跌时索提诺比率应取上限: %+v", m)
+	}
+}
+
+// TestApplyTradeExposure 重叠的持仓区间合并计算占比，平均持仓按逐笔交易计算
+func TestApplyTradeExposure(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+	trades := []TradeOutcome{
+		{OpenTime: at(120), CloseTime: at(150), PnL: -20},
+		{OpenTime: at(0), CloseTime: at(60), PnL: 50},
+		{OpenTime: at(30), CloseTime: at(90), PnL: 30},
+	}
+
+	var m RiskMetrics
+	ApplyTradeExposure(&m, trades, start, at(200))
+	// 持仓区间 [0,90] ∪ [120,150] = 120分钟 / 200分钟；平均持仓 (60+60+30)/3
+	if !approxEqual(m.TimeInMarketPct, 60) || !approxEqual(m.AvgHoldingMinutes, 50) {
+		t.Errorf("持仓占比应为60%%、平均持仓50分钟，实际 %v / %v", m.TimeInMarketPct, m.AvgHoldingMinutes)
+	}
+
+	// 起始净值1000，按平仓时间：+50 → +30 → -20，峰值1080到1060
+	m = ComputeRiskMetrics(TradeEquityCurve(trades, 1000, start))
+	if !approxEqual(m.MaxDrawdown, 20) || !approxEqual(m.MaxDrawdownPct, 20.0/1080*100) {
+		t.Errorf("交易权益曲线回撤不正确: %+v", m)
+	}
+}
+
+// TestStatisticsRiskMetrics 两种记录器的统计信息都包含基于周期净值的风险指标
+func TestStatisticsRiskMetrics(t *testing.T) {
+	l := newTestSQLiteLogger(t, t.TempDir())
+	for i, p := range riskFixture(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		record := &DecisionRecord{Timestamp: p.Time, CycleNumber: i + 1, Success: true}
+		record.AccountState.TotalBalance = p.Equity
+		if p.InMarket {
+			record.AccountState.PositionCount = 1
+		}
+		if err := insertDecision(l.db, record); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if !approxEqual(stats.MaxDrawdownPct, 10) || !approxEqual(stats.TimeInMarketPct, 75) {
+		t.Errorf("SQLite统计的风险指标不正确: %+v", stats.RiskMetrics)
+	}
+
+	fileLogger := NewDecisionLogger(t.TempDir())
+	for _, equity := range []float64{100, 80, 90} {
+		record := &DecisionRecord{Success: true}
+		record.AccountState.TotalBalance = equity
+		if err := fileLogger.LogDecision(record); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+	stats, err = fileLogger.GetStatistics()
+	if err != nil || !approxEqual(stats.MaxDrawdown, 20) || !approxEqual(stats.MaxDrawdownPct, 20) {
+		t.Errorf("文件统计的风险指标不正确: %+v %v", stats, err)
+	}
+}
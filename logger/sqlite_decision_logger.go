@@ -0,0 +1,489 @@
+package logger
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// decisionDBFile 决策记录数据库文件名（位于交易员的日志目录下）
+const decisionDBFile = "decisions.db"
+
+// metaFilesImported 标记日志目录中的JSON记录文件已导入数据库
+const metaFilesImported = "files_imported"
+
+// SQLiteDecisionLogger 基于SQLite的决策日志记录器
+//
+// 每个交易员一个数据库文件，常用字段单独成列并建立索引，完整记录以JSON保存。
+// 写入在事务中完成，读取不会读到写了一半的记录。
+type SQLiteDecisionLogger struct {
+	db *sql.DB
+
+	mu          sync.Mutex // 保护 cycleNumber
+	cycleNumber int
+}
+
+var (
+	openLoggersMu sync.Mutex
+	openLoggers   = make(map[string]*SQLiteDecisionLogger) // 日志目录 -> 已打开的记录器
+)
+
+// OpenDecisionLogger 获取日志目录对应的决策日志记录器
+//
+// 使用SQLite记录器，同一目录复用同一个实例（交易员重新加载时不重复打开数据库）；
+// 数据库无法打开时回退到文件记录器。
+func OpenDecisionLogger(logDir string) IDecisionLogger {
+	openLoggersMu.Lock()
+	defer openLoggersMu.Unlock()
+
+	key := filepath.Clean(logDir)
+	if l, ok := openLoggers[key]; ok {
+		return l
+	}
+	l, err := NewSQLiteDecisionLogger(logDir)
+	if err != nil {
+		fmt.Printf("⚠ 打开决策记录数据库失败，使用文件记录: %v\n", err)
+		return NewDecisionLogger(logDir)
+	}
+	openLoggers[key] = l
+	return l
+}
+
+// RemoveDecisionLogs 关闭日志目录对应的记录器并删除整个目录（删除交易员/账户时调用，目录不存在时不报错）
+func RemoveDecisionLogs(logDir string) error {
+	openLoggersMu.Lock()
+	defer openLoggersMu.Unlock()
+
+	key := filepath.Clean(logDir)
+	if l, ok := openLoggers[key]; ok {
+		l.Close()
+		delete(openLoggers, key)
+	}
+	if err := os.RemoveAll(logDir); err != nil {
+		return fmt.Errorf("删除决策日志失败: %w", err)
+	}
+	return nil
+}
+
+// NewSQLiteDecisionLogger 打开（或创建）日志目录下的决策记录数据库，首次打开时导入目录中已有的JSON记录文件
+func NewSQLiteDecisionLogger(logDir string) (*SQLiteDecisionLogger, error) {
+	if logDir == "" {
+		logDir = "decision_logs"
+	}
+	if err := os.MkdirAll(logDir, 0700); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+
+	dsn := filepath.Join(logDir, decisionDBFile) + "?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开决策记录数据库失败: %w", err)
+	}
+
+	l := &SQLiteDecisionLogger{db: db}
+	if err := l.createTables(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := l.importRecordFiles(logDir); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := db.QueryRow(`SELECT COALESCE(MAX(cycle_number), 0) FROM decisions WHERE dry_run = 0`).Scan(&l.cycleNumber); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("读取周期编号失败: %w", err)
+	}
+	return l, nil
+}
+
+// Close 关闭数据库
+func (l *SQLiteDecisionLogger) Close() error {
+	return l.db.Close()
+}
+
+// createTables 创建决策记录表和索引
+func (l *SQLiteDecisionLogger) createTables() error {
+	queries := []string{
+		`CREATE TABLE IF NOT EXISTS decisions (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			timestamp_ms INTEGER NOT NULL,
+			cycle_number INTEGER NOT NULL DEFAULT 0,
+			dry_run BOOLEAN NOT NULL DEFAULT 0,
+			success BOOLEAN NOT NULL DEFAULT 0,
+			actions TEXT DEFAULT '',
+			open_count INTEGER DEFAULT 0,
+			close_count INTEGER DEFAULT 0,
+			total_balance REAL DEFAULT 0,
+			available_balance REAL DEFAULT 0,
+			unrealized_profit REAL DEFAULT 0,
+			position_count INTEGER DEFAULT 0,
+			prompt_variant TEXT DEFAULT '',
+			record TEXT NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS decision_log_meta (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_decisions_dry_run_id ON decisions(dry_run, id)`,
+		`CREATE INDEX IF NOT EXISTS idx_decisions_timestamp ON decisions(timestamp_ms)`,
+		`CREATE INDEX IF NOT EXISTS idx_decisions_dry_run_timestamp ON decisions(dry_run, timestamp_ms)`,
+	}
+	for _, query := range queries {
+		if _, err := l.db.Exec(query); err != nil {
+			return fmt.Errorf("创建决策记录表失败: %w", err)
+		}
+	}
+
+	// 后续新增的列（已存在时忽略错误）
+	alterQueries := []string{
+		`ALTER TABLE decisions ADD COLUMN ai_latency_ms INTEGER DEFAULT 0`,
+		`ALTER TABLE decisions ADD COLUMN exchange_execution_ms INTEGER DEFAULT 0`,
+		`ALTER TABLE decisions ADD COLUMN total_cycle_ms INTEGER DEFAULT 0`,
+	}
+	for _, query := range alterQueries {
+		l.db.Exec(query)
+	}
+	return nil
+}
+
+// importRecordFiles 一次性导入日志目录中的JSON记录文件（含预演记录），保留原周期编号和时间；原文件不删除
+func (l *SQLiteDecisionLogger) importRecordFiles(logDir string) error {
+	var imported string
+	err := l.db.QueryRow(`SELECT value FROM decision_log_meta WHERE key = ?`, metaFilesImported).Scan(&imported)
+	if err == nil {
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("读取导入状态失败: %w", err)
+	}
+
+	fileLogger := &DecisionLogger{logDir: logDir}
+	names, err := fileLogger.listRecordFiles()
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		paths = append(paths, filepath.Join(logDir, name))
+	}
+	dryRuns, _ := filepath.Glob(filepath.Join(logDir, dryRunDir, "dryrun_*.json"))
+	paths = append(paths, dryRuns...)
+
+	tx, err := l.db.Begin()
+	if err != nil {
+		return fmt.Errorf("开始导入事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	count, skipped := 0, 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			skipped++
+			continue
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			// 并发读写时可能留下截断的文件，跳过
+			skipped++
+			continue
+		}
+		if err := insertDecision(tx, &record); err != nil {
+			return fmt.Errorf("导入决策记录 %s 失败: %w", filepath.Base(path), err)
+		}
+		count++
+	}
+	if _, err := tx.Exec(`INSERT INTO decision_log_meta (key, value) VALUES (?, ?)`, metaFilesImported, time.Now().Format(time.RFC3339)); err != nil {
+		return fmt.Errorf("保存导入状态失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交导入事务失败: %w", err)
+	}
+
+	if count > 0 || skipped > 0 {
+		fmt.Printf("📦 已导入 %d 条决策记录文件到数据库（跳过 %d 个无法解析的文件）: %s\n", count, skipped, logDir)
+	}
+	return nil
+}
+
+// execer 插入记录使用的数据库/事务
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertDecision 写入一条决策记录
+func insertDecision(db execer, record *DecisionRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("序列化决策记录失败: %w", err)
+	}
+	opens, closes := countPositionActions(record)
+	_, err = db.Exec(`
+		INSERT INTO decisions (timestamp_ms, cycle_number, dry_run, success, actions, open_count, close_count,
+			total_balance, available_balance, unrealized_profit, position_count, prompt_variant, record,
+			ai_latency_ms, exchange_execution_ms, total_cycle_ms)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, record.Timestamp.UnixMilli(), record.CycleNumber, record.DryRun, record.Success, summarizeActions(record), opens, closes,
+		record.AccountState.TotalBalance, record.AccountState.AvailableBalance, record.AccountState.TotalUnrealizedProfit,
+		record.AccountState.PositionCount, record.PromptVariant, string(data),
+		record.AILatencyMs, record.ExchangeExecutionMs, record.TotalCycleMs)
+	return err
+}
+
+// summarizeActions 决策动作摘要（如 "open_long BTCUSDT, close_short ETHUSDT"）
+func summarizeActions(record *DecisionRecord) string {
+	parts := make([]string, 0, len(record.Decisions))
+	for _, action := range record.Decisions {
+		parts = append(parts, action.Action+" "+action.Symbol)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// LogDecision 记录决策
+func (l *SQLiteDecisionLogger) LogDecision(record *DecisionRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	record.CycleNumber = l.cycleNumber + 1
+	record.Timestamp = time.Now()
+	if err := insertDecision(l.db, record); err != nil {
+		return fmt.Errorf("写入决策记录失败: %w", err)
+	}
+	l.cycleNumber++
+
+	fmt.Printf("📝 决策记录已保存: cycle %d\n", record.CycleNumber)
+	return nil
+}
+
+// LogDryRun 记录决策预演（不占用周期编号）
+func (l *SQLiteDecisionLogger) LogDryRun(record *DecisionRecord) error {
+	record.DryRun = true
+	record.Timestamp = time.Now()
+	if err := insertDecision(l.db, record); err != nil {
+		return fmt.Errorf("写入预演记录失败: %w", err)
+	}
+	return nil
+}
+
+// queryRecords 查询并解析决策记录
+func (l *SQLiteDecisionLogger) queryRecords(query string, args ...interface{}) ([]*DecisionRecord, []int64, error) {
+	rows, err := l.db.Query(query, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	var records []*DecisionRecord
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		var data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		var record DecisionRecord
+		if err := json.Unmarshal([]byte(data), &record); err != nil {
+			continue
+		}
+		records = append(records, &record)
+		ids = append(ids, id)
+	}
+	return records, ids, rows.Err()
+}
+
+// GetLatestRecords 获取最近N条记录（按时间正序：从旧到新）
+func (l *SQLiteDecisionLogger) GetLatestRecords(n int) ([]*DecisionRecord, error) {
+	records, _, err := l.queryRecords(`SELECT id, record FROM decisions WHERE dry_run = 0 ORDER BY id DESC LIMIT ?`, n)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
+
+// GetRecordsPage 分页获取记录（按时间倒序：从新到旧），游标为上一页最后一条记录的ID
+func (l *SQLiteDecisionLogger) GetRecordsPage(cursor string, limit int) (*DecisionPage, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	before := int64(1<<63 - 1)
+	if cursor != "" {
+		id, err := strconv.ParseInt(cursor, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("无效的游标: %s", cursor)
+		}
+		before = id
+	}
+
+	page := &DecisionPage{}
+	if err := l.db.QueryRow(`SELECT COUNT(*) FROM decisions WHERE dry_run = 0`).Scan(&page.Total); err != nil {
+		return nil, fmt.Errorf("统计决策记录失败: %w", err)
+	}
+
+	// 多取一条判断是否还有更早的记录
+	records, ids, err := l.queryRecords(`SELECT id, record FROM decisions WHERE dry_run = 0 AND id < ? ORDER BY id DESC LIMIT ?`, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) > limit {
+		records, ids = records[:limit], ids[:limit]
+		page.HasMore = true
+		page.NextCursor = strconv.FormatInt(ids[limit-1], 10)
+	}
+	if records == nil {
+		records = []*DecisionRecord{}
+	}
+	page.Records = records
+	return page, nil
+}
+
+// GetRecordByDate 获取指定日期的所有记录（日期边界按 date 所在时区划分，调用方传入用户时区的时间）
+func (l *SQLiteDecisionLogger) GetRecordByDate(date time.Time) ([]*DecisionRecord, error) {
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	end := start.AddDate(0, 0, 1)
+	records, _, err := l.queryRecords(`
+		SELECT id, record FROM decisions
+		WHERE dry_run = 0 AND timestamp_ms >= ? AND timestamp_ms < ?
+		ORDER BY id
+	`, start.UnixMilli(), end.UnixMilli())
+	return records, err
+}
+
+// GetRecordsByTimeRange 获取时间范围内的记录（按时间正序，超过 maxPoints 条时均匀抽样）
+// 先通过索引只查询ID完成抽样，再读取被选中的完整记录
+func (l *SQLiteDecisionLogger) GetRecordsByTimeRange(from, to time.Time, maxPoints int) ([]*DecisionRecord, error) {
+	rows, err := l.db.Query(`
+		SELECT id FROM decisions
+		WHERE dry_run = 0 AND timestamp_ms >= ? AND timestamp_ms < ?
+		ORDER BY timestamp_ms, id
+	`, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, fmt.Errorf("查询决策记录失败: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("读取决策记录失败: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取决策记录失败: %w", err)
+	}
+
+	selected := make([]int64, 0, len(ids))
+	for _, i := range sampleEvenly(len(ids), maxPoints) {
+		selected = append(selected, ids[i])
+	}
+
+	// 分批按ID读取（避免超过SQLite参数数量限制）
+	const batchSize = 500
+	records := make([]*DecisionRecord, 0, len(selected))
+	for start := 0; start < len(selected); start += batchSize {
+		batch := selected[start:min(start+batchSize, len(selected))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(batch)), ",")
+		batchRecords, _, err := l.queryRecords(`SELECT id, record FROM decisions WHERE id IN (`+placeholders+`) ORDER BY timestamp_ms, id`, args...)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, batchRecords...)
+	}
+	return records, nil
+}
+
+// CleanOldRecords 清理N天前的旧记录
+func (l *SQLiteDecisionLogger) CleanOldRecords(days int) error {
+	cutoff := time.Now().AddDate(0, 0, -days)
+	result, err := l.db.Exec(`DELETE FROM decisions WHERE timestamp_ms < ?`, cutoff.UnixMilli())
+	if err != nil {
+		return fmt.Errorf("清理旧记录失败: %w", err)
+	}
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		fmt.Printf("🗑️ 已清理 %d 条旧记录（%d天前）\n", removed, days)
+	}
+	return nil
+}
+
+// GetStatistics 获取统计信息（按列聚合，不解析完整记录）
+func (l *SQLiteDecisionLogger) GetStatistics() (*Statistics, error) {
+	rows, err := l.db.Query(`
+		SELECT prompt_variant, COUNT(*), COALESCE(SUM(success), 0), COALESCE(SUM(open_count), 0), COALESCE(SUM(close_count), 0)
+		FROM decisions WHERE dry_run = 0
+		GROUP BY prompt_variant
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("统计决策记录失败: %w", err)
+	}
+	defer rows.Close()
+
+	stats := &Statistics{}
+	for rows.Next() {
+		var variant string
+		var cycles, successful, opens, closes int
+		if err := rows.Scan(&variant, &cycles, &successful, &opens, &closes); err != nil {
+			return nil, fmt.Errorf("读取统计结果失败: %w", err)
+		}
+		stats.TotalCycles += cycles
+		stats.SuccessfulCycles += successful
+		stats.FailedCycles += cycles - successful
+		stats.TotalOpenPositions += opens
+		stats.TotalClosePositions += closes
+		if variant != "" {
+			if stats.VariantCycles == nil {
+				stats.VariantCycles = make(map[string]int)
+			}
+			stats.VariantCycles[variant] = cycles
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取统计结果失败: %w", err)
+	}
+
+	// 风险指标和耗时分位数只需要净值、持仓数和耗时几列，逐行累积
+	equityRows, err := l.db.Query(`
+		SELECT timestamp_ms, total_balance, position_count, ai_latency_ms, exchange_execution_ms, total_cycle_ms
+		FROM decisions WHERE dry_run = 0 ORDER BY timestamp_ms, id
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("查询净值曲线失败: %w", err)
+	}
+	defer equityRows.Close()
+
+	var risk riskAccumulator
+	var latency latencyCollector
+	for equityRows.Next() {
+		var timestampMs, aiMs, exchangeMs, totalMs int64
+		var equity float64
+		var positions int
+		if err := equityRows.Scan(&timestampMs, &equity, &positions, &aiMs, &exchangeMs, &totalMs); err != nil {
+			return nil, fmt.Errorf("读取净值曲线失败: %w", err)
+		}
+		risk.add(EquityPoint{Time: time.UnixMilli(timestampMs), Equity: equity, InMarket: positions > 0})
+		latency.add(aiMs, exchangeMs, totalMs)
+	}
+	stats.RiskMetrics = risk.result()
+	stats.Latency = latency.result()
+	return stats, equityRows.Err()
+}
+
+// AnalyzePerformance 分析最近N个周期的交易表现
+func (l *SQLiteDecisionLogger) AnalyzePerformance(lookbackCycles int) (*PerformanceAnalysis, error) {
+	return analyzePerformance(l.GetLatestRecords, lookbackCycles)
+}
@@ -0,0 +1,224 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestSQLiteLogger 在临时目录创建SQLite决策日志记录器
+func newTestSQLiteLogger(t *testing.T, dir string) *SQLiteDecisionLogger {
+	t.Helper()
+	l, err := NewSQLiteDecisionLogger(dir)
+	if err != nil {
+		t.Fatalf("创建SQLite记录器失败: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// TestSQLiteDecisionLogger_PagingAndStatistics 分页稳定、预演记录不计入，统计按列聚合
+func TestSQLiteDecisionLogger_PagingAndStatistics(t *testing.T) {
+	l := newTestSQLiteLogger(t, t.TempDir())
+
+	for i := 0; i < 12; i++ {
+		record := &DecisionRecord{Success: i%4 != 0}
+		if i == 0 {
+			record.PromptVariant = "A"
+			record.Decisions = []DecisionAction{
+				{Action: "open_long", Symbol: "BTCUSDT", Success: true},
+				{Action: "close_short", Symbol: "ETHUSDT", Success: true},
+				{Action: "open_short", Symbol: "SOLUSDT", Success: false},
+			}
+		}
+		if err := l.LogDecision(record); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+	if err := l.LogDryRun(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("写入预演记录失败: %v", err)
+	}
+
+	page, err := l.GetRecordsPage("", 5)
+	if err != nil {
+		t.Fatalf("获取第一页失败: %v", err)
+	}
+	if page.Total != 12 || len(page.Records) != 5 || !page.HasMore || page.Records[0].CycleNumber != 12 {
+		t.Fatalf("第一页不正确: total=%d len=%d has_more=%v", page.Total, len(page.Records), page.HasMore)
+	}
+	if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("写入记录失败: %v", err)
+	}
+	page, err = l.GetRecordsPage(page.NextCursor, 5)
+	if err != nil {
+		t.Fatalf("获取第二页失败: %v", err)
+	}
+	if page.Records[0].CycleNumber != 7 || page.Records[4].CycleNumber != 3 {
+		t.Errorf("第二页应为 cycle7..3，实际 %d..%d", page.Records[0].CycleNumber, page.Records[4].CycleNumber)
+	}
+	page, err = l.GetRecordsPage(page.NextCursor, 5)
+	if err != nil || len(page.Records) != 2 || page.HasMore || page.NextCursor != "" {
+		t.Errorf("最后一页应只有2条且没有更多: %+v %v", page, err)
+	}
+
+	records, err := l.GetLatestRecords(3)
+	if err != nil || len(records) != 3 || records[0].CycleNumber != 11 || records[2].CycleNumber != 13 {
+		t.Fatalf("最近记录应按时间正序: %v", err)
+	}
+
+	stats, err := l.GetStatistics()
+	if err != nil {
+		t.Fatalf("统计失败: %v", err)
+	}
+	if stats.TotalCycles != 13 || stats.FailedCycles != 3 || stats.SuccessfulCycles != 10 {
+		t.Errorf("周期统计不正确: %+v", stats)
+	}
+	if stats.TotalOpenPositions != 1 || stats.TotalClosePositions != 1 || stats.VariantCycles["A"] != 1 {
+		t.Errorf("开平仓/分组统计不正确: %+v", stats)
+	}
+}
+
+// TestSQLiteDecisionLogger_ImportFiles 首次打开时导入已有的JSON记录文件（跳过截断的文件），周期编号接续，只导入一次
+func TestSQLiteDecisionLogger_ImportFiles(t *testing.T) {
+	dir := t.TempDir()
+	fileLogger := NewDecisionLogger(dir)
+	for _, record := range []*DecisionRecord{
+		{Success: true, PromptVariant: "A", Decisions: []DecisionAction{{Action: "open_long", Symbol: "BTCUSDT", Price: 100, Quantity: 1, Success: true}}},
+		{Success: true, Decisions: []DecisionAction{{Action: "close_long", Symbol: "BTCUSDT", Price: 110, Success: true}}},
+	} {
+		if err := fileLogger.LogDecision(record); err != nil {
+			t.Fatalf("写入文件记录失败: %v", err)
+		}
+	}
+	if err := fileLogger.LogDryRun(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("写入预演记录失败: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "decision_20991231_235959_cycle99.json"), []byte(`{"cycle_number": 99,`), 0600); err != nil {
+		t.Fatalf("写入截断文件失败: %v", err)
+	}
+
+	l, err := NewSQLiteDecisionLogger(dir)
+	if err != nil {
+		t.Fatalf("创建SQLite记录器失败: %v", err)
+	}
+	records, err := l.GetLatestRecords(10)
+	if err != nil || len(records) != 2 || records[1].CycleNumber != 2 {
+		t.Fatalf("应导入2条正常记录: %d %v", len(records), err)
+	}
+	analysis, err := l.AnalyzePerformance(10)
+	if err != nil || analysis.TotalTrades != 1 || analysis.VariantStats["A"] == nil {
+		t.Fatalf("导入的记录应参与表现分析: %+v %v", analysis, err)
+	}
+	if err := l.LogDecision(&DecisionRecord{Success: true}); err != nil {
+		t.Fatalf("写入记录失败: %v", err)
+	}
+	l.Close()
+
+	// 再次打开不重复导入
+	l = newTestSQLiteLogger(t, dir)
+	stats, err := l.GetStatistics()
+	if err != nil || stats.TotalCycles != 3 {
+		t.Fatalf("重新打开后不应重复导入: %+v %v", stats, err)
+	}
+	records, err = l.GetLatestRecords(1)
+	if err != nil || records[0].CycleNumber != 3 {
+		t.Errorf("周期编号应接续: %v", err)
+	}
+}
+
+// seedDecisions 直接写入 n 条间隔为 interval 的决策记录（用于构造长时间的历史）
+func seedDecisions(tb testing.TB, l *SQLiteDecisionLogger, n int, start time.Time, interval time.Duration) {
+	tb.Helper()
+	tx, err := l.db.Begin()
+	if err != nil {
+		tb.Fatalf("开始事务失败: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		record := &DecisionRecord{
+			Timestamp:    start.Add(time.Duration(i) * interval),
+			CycleNumber:  i + 1,
+			Success:      true,
+			AccountState: AccountSnapshot{TotalBalance: 1000 + float64(i)},
+		}
+		if err := insertDecision(tx, record); err != nil {
+			tb.Fatalf("写入记录失败: %v", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		tb.Fatalf("提交事务失败: %v", err)
+	}
+}
+
+// TestGetRecordsByTimeRange 只返回时间范围内的记录（正序），超出上限时均匀抽样并保留首尾
+func TestGetRecordsByTimeRange(t *testing.T) {
+	l := newTestSQLiteLogger(t, t.TempDir())
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedDecisions(t, l, 1000, start, time.Minute)
+
+	records, err := l.GetRecordsByTimeRange(start.Add(100*time.Minute), start.Add(200*time.Minute), 0)
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(records) != 100 || records[0].CycleNumber != 101 || records[99].CycleNumber != 200 {
+		t.Fatalf("应返回范围内的100条记录（不含结束时间）: %d", len(records))
+	}
+
+	records, err = l.GetRecordsByTimeRange(start, start.Add(24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("查询失败: %v", err)
+	}
+	if len(records) != 10 || records[0].CycleNumber != 1 || records[9].CycleNumber != 1000 {
+		t.Fatalf("抽样应覆盖整个范围（含首尾）: %d", len(records))
+	}
+	for i := 1; i < len(records); i++ {
+		if gap := records[i].CycleNumber - records[i-1].CycleNumber; gap < 110 || gap > 112 {
+			t.Errorf("抽样间隔应均匀: %d -> %d", records[i-1].CycleNumber, records[i].CycleNumber)
+		}
+	}
+
+	// 文件记录器行为一致
+	fileLogger := NewDecisionLogger(t.TempDir())
+	for i := 0; i < 5; i++ {
+		if err := fileLogger.LogDecision(&DecisionRecord{Success: true}); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+	now := time.Now()
+	records, err = fileLogger.GetRecordsByTimeRange(now.Add(-time.Hour), now.Add(time.Hour), 2)
+	if err != nil || len(records) != 2 || records[0].CycleNumber != 1 || records[1].CycleNumber != 5 {
+		t.Errorf("文件记录器抽样不正确: %d %v", len(records), err)
+	}
+	records, err = fileLogger.GetRecordsByTimeRange(now.Add(-48*time.Hour), now.Add(-24*time.Hour), 0)
+	if err != nil || len(records) != 0 {
+		t.Errorf("范围外不应返回记录: %d %v", len(records), err)
+	}
+}
+
+// BenchmarkGetRecordsByTimeRange 两年的3分钟周期历史（约35万条）中查询最近7天和全部范围（抽样500条）
+func BenchmarkGetRecordsByTimeRange(b *testing.B) {
+	l, err := NewSQLiteDecisionLogger(b.TempDir())
+	if err != nil {
+		b.Fatalf("创建SQLite记录器失败: %v", err)
+	}
+	defer l.Close()
+	const n = 2 * 365 * 24 * 20
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seedDecisions(b, l, n, start, 3*time.Minute)
+	end := start.Add(n * 3 * time.Minute)
+
+	b.Run("last_7_days", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := l.GetRecordsByTimeRange(end.Add(-7*24*time.Hour), end, 10000); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("all_sampled_500", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := l.GetRecordsByTimeRange(start, end, 500); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Message 一封邮件（纯文本正文，可附带 HTML 版本）
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	HTML    string // HTML 正文（可选，设置后以 multipart/alternative 发送，Body 作为纯文本备选）
+}
+
+// validate 校验收件人和主题（拒绝换行，防止邮件头注入）
+func (m *Message) validate() error {
+	if m.To == "" {
+		return fmt.Errorf("收件人不能为空")
+	}
+	if strings.ContainsAny(m.To, "\r\n") || strings.ContainsAny(m.Subject, "\r\n") {
+		return fmt.Errorf("收件人和主题不能包含换行")
+	}
+	return nil
+}
+
+// Sender 邮件发送接口
+type Sender interface {
+	Send(msg *Message) error
+}
+
+// MockSender 只记录不发送的邮件发送器（用于测试和本地开发）
+type MockSender struct {
+	mu       sync.Mutex
+	messages []*Message
+}
+
+// Send 记录邮件
+func (m *MockSender) Send(msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	copied := *msg
+	m.messages = append(m.messages, &copied)
+	return nil
+}
+
+// Messages 已发送的全部邮件
+func (m *MockSender) Messages() []*Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*Message(nil), m.messages...)
+}
+
+// Last 最近一封发给 to 的邮件，没有时返回 nil
+func (m *MockSender) Last(to string) *Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.messages) - 1; i >= 0; i-- {
+		if strings.EqualFold(m.messages[i].To, to) {
+			return m.messages[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// smtpTimeout 连接和发送的超时时间
+const smtpTimeout = 15 * time.Second
+
+// SMTPConfig SMTP 服务器配置
+type SMTPConfig struct {
+	Host     string
+	Port     int // 465 使用隐式 TLS，其他端口在服务器支持时使用 STARTTLS
+	Username string
+	Password string
+	From     string // 发件人地址（可带显示名，如 "NOFX <noreply@example.com>"）
+}
+
+// SMTPSender 通过 SMTP 发送邮件
+type SMTPSender struct {
+	cfg SMTPConfig
+}
+
+// NewSMTPSender 创建 SMTP 发送器
+func NewSMTPSender(cfg SMTPConfig) *SMTPSender {
+	if cfg.Port == 0 {
+		cfg.Port = 587
+	}
+	return &SMTPSender{cfg: cfg}
+}
+
+// Send 发送邮件
+func (s *SMTPSender) Send(msg *Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+	from, err := mail.ParseAddress(s.cfg.From)
+	if err != nil {
+		return fmt.Errorf("发件人地址无效: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("收件人地址无效: %w", err)
+	}
+
+	client, err := s.dial()
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %w", err)
+	}
+	defer client.Close()
+
+	if s.cfg.Username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+				return fmt.Errorf("SMTP认证失败: %w", err)
+			}
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM 失败: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("SMTP RCPT TO 失败: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA 失败: %w", err)
+	}
+	if _, err := w.Write(buildMessage(from, to, msg)); err != nil {
+		w.Close()
+		return fmt.Errorf("写入邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial 连接SMTP服务器（465 端口使用隐式 TLS，否则在支持时升级 STARTTLS）
+func (s *SMTPSender) dial() (*smtp.Client, error) {
+	addr := net.JoinHostPort(s.cfg.Host, strconv.Itoa(s.cfg.Port))
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	tlsConfig := &tls.Config{ServerName: s.cfg.Host}
+
+	var conn net.Conn
+	var err error
+	if s.cfg.Port == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if s.cfg.Port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				client.Close()
+				return nil, err
+			}
+		}
+	}
+	return client, nil
+}
+
+// buildMessage 生成 RFC 5322 格式的邮件（主题按 RFC 2047 编码；有 HTML 正文时为 multipart/alternative）
+func buildMessage(from, to *mail.Address, msg *Message) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+		buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+		buf.Write(crlf(msg.Body))
+		return buf.Bytes()
+	}
+
+	parts := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Body},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, _ := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		w.Write(crlf(part.body))
+	}
+	parts.Close()
+	return buf.Bytes()
+}
+
+// crlf 正文换行统一转换为 CRLF
+func crlf(body string) []byte {
+	return bytes.ReplaceAll(bytes.ReplaceAll([]byte(body), []byte("\r\n"), []byte("\n")), []byte("\n"), []byte("\r\n"))
+}
@@ -0,0 +1,75 @@
+package mailer
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+)
+
+// TestBuildMessage 邮件头编码和正文换行
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "NOFX", Address: "noreply@example.com"}
+	to := &mail.Address{Address: "user@example.com"}
+	data := string(buildMessage(from, to, &Message{To: to.Address, Subject: "验证邮箱", Body: "第一行\n第二行"}))
+
+	if !strings.Contains(data, "Subject: =?utf-8?q?") {
+		t.Errorf("中文主题应按 RFC 2047 编码: %s", data)
+	}
+	if !strings.Contains(data, "\r\n\r\n第一行\r\n第二行") {
+		t.Errorf("正文换行应转换为 CRLF: %q", data)
+	}
+}
+
+// TestMessageValidate 拒绝包含换行的收件人和主题
+func TestMessageValidate(t *testing.T) {
+	sender := &MockSender{}
+	if err := sender.Send(&Message{To: "a@example.com", Subject: "hi\r\nBcc: b@example.com"}); err == nil {
+		t.Error("主题包含换行应被拒绝")
+	}
+	if err := sender.Send(&Message{To: "a@example.com", Subject: "hi", Body: "body"}); err != nil {
+		t.Fatalf("发送失败: %v", err)
+	}
+	if last := sender.Last("A@example.com"); last == nil || last.Body != "body" {
+		t.Errorf("应记录已发送的邮件: %+v", last)
+	}
+}
+
+// TestBuildMessageHTML 有 HTML 正文时以 multipart/alternative 发送纯文本和 HTML 两个版本
+func TestBuildMessageHTML(t *testing.T) {
+	from := &mail.Address{Address: "noreply@example.com"}
+	to := &mail.Address{Address: "user@example.com"}
+	data := buildMessage(from, to, &Message{To: to.Address, Subject: "日报", Body: "纯文本\n正文", HTML: "<p>HTML 正文</p>"})
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("解析邮件失败: %v", err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("应为 multipart/alternative: %s %v", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("读取邮件分段失败: %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+	if len(types) != 2 || !strings.HasPrefix(types[0], "text/plain") || !strings.HasPrefix(types[1], "text/html") {
+		t.Fatalf("分段类型不正确: %v", types)
+	}
+	if bodies[0] != "纯文本\r\n正文" || bodies[1] != "<p>HTML 正文</p>" {
+		t.Errorf("分段内容不正确: %q", bodies)
+	}
+}
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"nofx/api"
 	"nofx/auth"
 	"nofx/config"
 	"nofx/crypto"
+	"nofx/decision"
+	"nofx/hook"
 	"nofx/manager"
 	"nofx/market"
 	"nofx/pool"
@@ -16,10 +20,18 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	// traderShutdownTimeout 退出时等待交易员结束当前决策周期的最长时间
+	traderShutdownTimeout = 30 * time.Second
+	// defaultResumeStagger 重启后恢复交易员的默认启动间隔（system_config: trader_resume_stagger_seconds）
+	defaultResumeStagger = 2 * time.Second
+)
+
 // ConfigFile 配置文件结构，只包含需要同步到数据库的字段
 // TODO 现在与config.Config相同，未来会被替换， 现在为了兼容性不得不保留当前文件
 type ConfigFile struct {
@@ -160,10 +172,18 @@ func main() {
 	// In Docker Compose, variables are injected by the runtime and this is harmless.
 	_ = godotenv.Load()
 
-	// 初始化数据库配置
+	// 初始化数据库配置（DATABASE_URL 为 postgres:// 连接串时使用 PostgreSQL，命令行参数优先）
+	// 用法: nofx [-promote-admin 邮箱] [-rotate-rsa-key] [数据库路径或DSN]
+	promoteAdmin := flag.String("promote-admin", "", "将指定邮箱的已注册用户设为管理员后退出（用于创建第一个管理员）")
+	rotateRSAKey := flag.Bool("rotate-rsa-key", false, "启动时轮换RSA传输公钥（旧公钥在 rsa_key_grace_hours 内仍可解密）")
+	flag.Parse()
+
 	dbPath := "config.db"
-	if len(os.Args) > 1 {
-		dbPath = os.Args[1]
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		dbPath = dsn
+	}
+	if flag.NArg() > 0 {
+		dbPath = flag.Arg(0)
 	}
 
 	// 读取配置文件
@@ -172,13 +192,32 @@ func main() {
 		log.Fatalf("❌ 读取config.json失败: %v", err)
 	}
 
-	log.Printf("📋 初始化配置数据库: %s", dbPath)
+	log.Printf("📋 初始化配置数据库: %s", config.RedactDSN(dbPath))
 	database, err := config.NewDatabase(dbPath)
 	if err != nil {
 		log.Fatalf("❌ 初始化数据库失败: %v", err)
 	}
 	defer database.Close()
 
+	// 创建管理员：命令行参数执行后退出；环境变量 NOFX_ADMIN_EMAIL 只在还没有管理员时设置第一个管理员（适合 Docker 部署）
+	if *promoteAdmin != "" {
+		user, err := database.PromoteAdminByEmail(*promoteAdmin)
+		if err != nil {
+			log.Fatalf("❌ 设置管理员失败: %v", err)
+		}
+		log.Printf("👑 用户 %s 已设为管理员，重新登录后生效", user.Email)
+		return
+	}
+	if email := strings.TrimSpace(os.Getenv("NOFX_ADMIN_EMAIL")); email != "" {
+		if user, promoted, err := database.PromoteInitialAdminByEmail(email); err != nil {
+			log.Printf("⚠️  NOFX_ADMIN_EMAIL: %v", err)
+		} else if promoted {
+			log.Printf("👑 用户 %s 已设为第一个管理员", user.Email)
+		} else {
+			log.Printf("ℹ️  NOFX_ADMIN_EMAIL: 已存在管理员，忽略（其他管理员请通过 /api/admin/users/:id/role 设置）")
+		}
+	}
+
 	// 初始化加密服务
 	log.Printf("🔐 初始化加密服务...")
 	cryptoService, err := crypto.NewCryptoService("secrets/rsa_key")
@@ -186,7 +225,16 @@ func main() {
 		log.Fatalf("❌ 初始化加密服务失败: %v", err)
 	}
 	database.SetCryptoService(cryptoService)
-	log.Printf("✅ 加密服务初始化成功")
+	if *rotateRSAKey {
+		grace := time.Duration(database.SystemConfig().Int("rsa_key_grace_hours", 24)) * time.Hour
+		oldKeyID := cryptoService.CurrentKeyID()
+		keyID, err := cryptoService.RotateKey(grace)
+		if err != nil {
+			log.Fatalf("❌ 轮换RSA公钥失败: %v", err)
+		}
+		log.Printf("🔑 RSA公钥已轮换: %s -> %s（旧公钥 %v 内仍可解密）", oldKeyID, keyID, grace)
+	}
+	log.Printf("✅ 加密服务初始化成功 (公钥 %s)", cryptoService.CurrentKeyID())
 
 	// 同步config.json到数据库
 	if err := syncConfigToDatabase(database, configFile); err != nil {
@@ -199,15 +247,14 @@ func main() {
 	}
 
 	// 获取系统配置
-	useDefaultCoinsStr, _ := database.GetSystemConfig("use_default_coins")
-	useDefaultCoins := useDefaultCoinsStr == "true"
-	apiPortStr, _ := database.GetSystemConfig("api_server_port")
+	sysConfig := database.SystemConfig()
+	useDefaultCoins := sysConfig.Bool("use_default_coins", false)
 
 	// 设置JWT密钥（优先使用环境变量）
 	jwtSecret := strings.TrimSpace(os.Getenv("JWT_SECRET"))
 	if jwtSecret == "" {
 		// 回退到数据库配置
-		jwtSecret, _ = database.GetSystemConfig("jwt_secret")
+		jwtSecret = sysConfig.String("jwt_secret", "")
 		if jwtSecret == "" {
 			jwtSecret = "your-jwt-secret-key-change-in-production-make-it-long-and-random"
 			log.Printf("⚠️  使用默认JWT密钥，建议使用加密设置脚本生成安全密钥")
@@ -219,28 +266,24 @@ func main() {
 	}
 	auth.SetJWTSecret(jwtSecret)
 
+	// 行情连接代理：系统配置优先于环境变量，定期重新读取（修改后新建立的连接使用新代理）
+	market.WatchProxyURL(func() (string, error) {
+		return database.GetSystemConfig("market_proxy_url")
+	}, 30*time.Second)
+	sysConfig.Subscribe("market_proxy_url", func(value string) {
+		if err := market.SetProxyURL(value); err != nil {
+			log.Printf("⚠️  系统配置中的代理无效，继续使用之前的代理: %v", err)
+		}
+	})
+
 	// 管理员模式下需要管理员密码，缺失则退出
 
 	log.Printf("✓ 配置数据库初始化成功")
 	fmt.Println()
 
-	// 从数据库读取默认主流币种列表
-	defaultCoinsJSON, _ := database.GetSystemConfig("default_coins")
-	var defaultCoins []string
-
-	if defaultCoinsJSON != "" {
-		// 尝试从JSON解析
-		if err := json.Unmarshal([]byte(defaultCoinsJSON), &defaultCoins); err != nil {
-			log.Printf("⚠️  解析default_coins配置失败: %v，使用硬编码默认值", err)
-			defaultCoins = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"}
-		} else {
-			log.Printf("✓ 从数据库加载默认币种列表（共%d个）: %v", len(defaultCoins), defaultCoins)
-		}
-	} else {
-		// 如果数据库中没有配置，使用硬编码默认值
-		defaultCoins = []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"}
-		log.Printf("⚠️  数据库中未配置default_coins，使用硬编码默认值")
-	}
+	// 从数据库读取默认主流币种列表（未配置或无效时使用硬编码默认值）
+	defaultCoins := sysConfig.StringSlice("default_coins", []string{"BTCUSDT", "ETHUSDT", "SOLUSDT", "BNBUSDT", "XRPUSDT", "DOGEUSDT", "ADAUSDT", "HYPEUSDT"})
+	log.Printf("✓ 默认币种列表（共%d个）: %v", len(defaultCoins), defaultCoins)
 
 	pool.SetDefaultCoins(defaultCoins)
 	// 设置是否使用默认主流币种
@@ -250,13 +293,13 @@ func main() {
 	}
 
 	// 设置币种池API URL
-	coinPoolAPIURL, _ := database.GetSystemConfig("coin_pool_api_url")
+	coinPoolAPIURL := sysConfig.String("coin_pool_api_url", "")
 	if coinPoolAPIURL != "" {
 		pool.SetCoinPoolAPI(coinPoolAPIURL)
 		log.Printf("✓ 已配置AI500币种池API")
 	}
 
-	oiTopAPIURL, _ := database.GetSystemConfig("oi_top_api_url")
+	oiTopAPIURL := sysConfig.String("oi_top_api_url", "")
 	if oiTopAPIURL != "" {
 		pool.SetOITopAPI(oiTopAPIURL)
 		log.Printf("✓ 已配置OI Top API")
@@ -303,6 +346,11 @@ func main() {
 	// 	log.Fatalf("初始化失败: %v", err)
 	// }
 
+	// 扩展点处理函数由插件包在 init 中注册（main 中空白导入插件包即可启用）
+	for point, handlers := range hook.RegisteredHandlers() {
+		log.Printf("🔌 扩展点 %s: %s", point, strings.Join(handlers, ", "))
+	}
+
 	fmt.Println()
 	fmt.Println("🤖 AI全权决策模式:")
 	fmt.Printf("  • AI将自主决定每笔交易的杠杆倍数（山寨币最高5倍，BTC/ETH最高5倍）\n")
@@ -327,12 +375,10 @@ func main() {
 		} else {
 			log.Printf("⚠️  环境变量 NOFX_BACKEND_PORT 无效: %s", envPort)
 		}
-	} else if apiPortStr != "" {
+	} else if port := sysConfig.Int("api_server_port", 0); port > 0 {
 		// 2. 从数据库配置读取（config.json 同步过来的）
-		if port, err := strconv.Atoi(apiPortStr); err == nil && port > 0 {
-			apiPort = port
-			log.Printf("🔌 使用数据库配置端口: %d (api_server_port)", apiPort)
-		}
+		apiPort = port
+		log.Printf("🔌 使用数据库配置端口: %d (api_server_port)", apiPort)
 	} else {
 		log.Printf("🔌 使用默认端口: %d", apiPort)
 	}
@@ -346,25 +392,62 @@ func main() {
 	}()
 
 	// 启动流行情数据 - 默认使用所有交易员设置的币种 如果没有设置币种 则优先使用系统默认
-	go market.NewWSMonitor(150).Start(database.GetCustomCoins())
+	wsMonitor := market.NewWSMonitor(150)
+	applyMaxCandles := func(string) { wsMonitor.SetMaxCandles(sysConfig.Int("kline_cache_max_candles", 0)) }
+	applyMaxCandles("")
+	sysConfig.Subscribe("kline_cache_max_candles", applyMaxCandles)
+	go wsMonitor.Start(database.GetCustomCoins())
 	//go market.NewWSMonitor(150).Start([]string{}) //这里是一个使用方式 传入空的话 则使用market市场的所有币种
 	// 设置优雅退出
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
-	// TODO: 启动数据库中配置为运行状态的交易员
-	// traderManager.StartAll()
+	// 恢复重启前运行中的交易员（错开启动，避免同时请求交易所）
+	resumeCtx, cancelResume := context.WithCancel(context.Background())
+	resumeStagger := sysConfig.Seconds("trader_resume_stagger_seconds", defaultResumeStagger)
+	// 缓存有效期和自动重启次数修改后立即生效
+	applyCompetitionTTL := func(string) {
+		traderManager.SetCompetitionCacheTTL(sysConfig.Seconds("competition_cache_ttl_seconds", 0))
+	}
+	applyMaxRestarts := func(string) { traderManager.SetMaxRestarts(sysConfig.Int("trader_max_restarts", -1)) }
+	applyCompetitionTTL("")
+	applyMaxRestarts("")
+	sysConfig.Subscribe("competition_cache_ttl_seconds", applyCompetitionTTL)
+	sysConfig.Subscribe("trader_max_restarts", applyMaxRestarts)
+	go traderManager.ResumeRunningTraders(resumeCtx, database, resumeStagger)
+
+	// 定时采样运行中交易员的账户权益（收益率曲线不再依赖决策周期）
+	snapshotCtx, cancelSnapshots := context.WithCancel(context.Background())
+	snapshotInterval := sysConfig.Seconds("snapshot_interval_seconds", manager.DefaultEquitySnapshotInterval)
+	go traderManager.RunEquitySnapshots(snapshotCtx, database, snapshotInterval)
+
+	// 按用户时区定时发送日报/周报（用户设置中开启）
+	reportCtx, cancelReports := context.WithCancel(context.Background())
+	go apiServer.RunReportScheduler(reportCtx)
+
+	// 监视 prompts 目录，模板文件修改后自动热加载（运行中的交易员下个周期生效）
+	promptWatchCtx, cancelPromptWatch := context.WithCancel(context.Background())
+	go decision.WatchPromptTemplates(promptWatchCtx, decision.DefaultPromptWatchInterval)
 
 	// 等待退出信号
 	<-sigChan
 	fmt.Println()
 	fmt.Println()
 	log.Println("📛 收到退出信号，正在优雅关闭...")
+	cancelResume()
+	cancelSnapshots()
+	cancelReports()
+	cancelPromptWatch()
 
-	// 步骤 1: 停止所有交易员
+	// 步骤 1: 停止所有交易员（等待进行中的决策周期结束，避免下单中途退出）
 	log.Println("⏸️  停止所有交易员...")
-	traderManager.StopAll()
-	log.Println("✅ 所有交易员已停止")
+	stopCtx, cancelStop := context.WithTimeout(context.Background(), traderShutdownTimeout)
+	if abandoned := traderManager.StopAll(stopCtx, database); len(abandoned) > 0 {
+		log.Printf("⚠️  %d 个交易员未能在 %v 内停止，已放弃等待: %v", len(abandoned), traderShutdownTimeout, abandoned)
+	} else {
+		log.Println("✅ 所有交易员已停止")
+	}
+	cancelStop()
 
 	// 步骤 2: 关闭 API 服务器
 	log.Println("🛑 停止 API 服务器...")
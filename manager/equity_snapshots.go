@@ -0,0 +1,146 @@
+package manager
+
+import (
+	"context"
+	"log"
+	"nofx/config"
+	"time"
+)
+
+const (
+	// DefaultEquitySnapshotInterval 默认权益采样间隔（system_config: snapshot_interval_seconds）
+	DefaultEquitySnapshotInterval = 2 * time.Minute
+	// equitySnapshotRawRetention 原始快照保留时长，超过后按小时聚合
+	equitySnapshotRawRetention = 7 * 24 * time.Hour
+	// equitySnapshotCompactEvery 聚合过期快照的间隔
+	equitySnapshotCompactEvery = time.Hour
+)
+
+// EquitySnapshotStore 权益快照持久化（*config.Database 实现）
+type EquitySnapshotStore interface {
+	SaveEquitySnapshots(snapshots []*config.EquitySnapshot) error
+	CompactEquitySnapshots(before time.Time) (int64, error)
+}
+
+// equitySampler 权益采样需要的交易员能力（便于测试替换）
+type equitySampler interface {
+	GetID() string
+	GetName() string
+	GetUserID() string
+	IsRunning() bool
+	GetAccountInfo() (map[string]interface{}, error)
+}
+
+// RunEquitySnapshots 按固定间隔采样所有运行中交易员的账户权益并写入 store，
+// 每小时将超过7天的原始快照聚合为每小时一条；阻塞直到 ctx 取消
+func (tm *TraderManager) RunEquitySnapshots(ctx context.Context, store EquitySnapshotStore, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultEquitySnapshotInterval
+	}
+	log.Printf("📸 权益快照已启动，采样间隔 %v", interval)
+
+	compactEquitySnapshots(store, time.Now())
+	lastCompact := time.Now()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			takeEquitySnapshots(tm.equitySamplers(), store, now)
+			if now.Sub(lastCompact) >= equitySnapshotCompactEvery {
+				compactEquitySnapshots(store, now)
+				lastCompact = now
+			}
+		}
+	}
+}
+
+// equitySamplers 返回当前已加载的交易员
+func (tm *TraderManager) equitySamplers() []equitySampler {
+	tm.mu.RLock()
+	defer tm.mu.RUnlock()
+	samplers := make([]equitySampler, 0, len(tm.traders))
+	for _, t := range tm.traders {
+		if t != nil {
+			samplers = append(samplers, t)
+		}
+	}
+	return samplers
+}
+
+// takeEquitySnapshots 并发采样运行中交易员的账户权益并批量写入，单个交易员失败只记录日志
+func takeEquitySnapshots(samplers []equitySampler, store EquitySnapshotStore, now time.Time) int {
+	running := make([]equitySampler, 0, len(samplers))
+	for _, s := range samplers {
+		if s.IsRunning() {
+			running = append(running, s)
+		}
+	}
+	if len(running) == 0 {
+		return 0
+	}
+
+	results := make([]*config.EquitySnapshot, len(running))
+	forEachBounded(len(running), competitionFetchWorkers, func(i int) {
+		account, err := running[i].GetAccountInfo()
+		if err != nil {
+			log.Printf("⚠️ 权益采样失败 [%s]: %v", running[i].GetName(), err)
+			return
+		}
+		results[i] = &config.EquitySnapshot{
+			TraderID:         running[i].GetID(),
+			UserID:           running[i].GetUserID(),
+			Timestamp:        now,
+			TotalEquity:      accountFloat(account, "total_equity"),
+			WalletBalance:    accountFloat(account, "wallet_balance"),
+			AvailableBalance: accountFloat(account, "available_balance"),
+			InitialBalance:   accountFloat(account, "initial_balance"),
+			TotalPnL:         accountFloat(account, "total_pnl"),
+			TotalPnLPct:      accountFloat(account, "total_pnl_pct"),
+			PositionCount:    int(accountFloat(account, "position_count")),
+			MarginUsedPct:    accountFloat(account, "margin_used_pct"),
+			Resolution:       config.EquitySnapshotRaw,
+		}
+	})
+
+	snapshots := make([]*config.EquitySnapshot, 0, len(results))
+	for _, s := range results {
+		if s != nil {
+			snapshots = append(snapshots, s)
+		}
+	}
+	if err := store.SaveEquitySnapshots(snapshots); err != nil {
+		log.Printf("⚠️ 保存权益快照失败: %v", err)
+		return 0
+	}
+	return len(snapshots)
+}
+
+// compactEquitySnapshots 聚合超过保留期的原始快照
+func compactEquitySnapshots(store EquitySnapshotStore, now time.Time) {
+	deleted, err := store.CompactEquitySnapshots(now.Add(-equitySnapshotRawRetention))
+	if err != nil {
+		log.Printf("⚠️ 聚合权益快照失败: %v", err)
+		return
+	}
+	if deleted > 0 {
+		log.Printf("🗜  已将 %d 条过期权益快照聚合为小时数据", deleted)
+	}
+}
+
+// accountFloat 读取账户信息中的数值字段（兼容 int/float64）
+func accountFloat(account map[string]interface{}, key string) float64 {
+	switch v := account[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		return 0
+	}
+}
@@ -2,30 +2,163 @@ package manager
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"nofx/config"
+	"nofx/decision"
+	"nofx/metrics"
 	"nofx/trader"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
-// CompetitionCache 竞赛数据缓存
+// CompetitionCache 竞赛数据缓存（过期后先返回旧数据，同时在后台刷新）
 type CompetitionCache struct {
-	data      map[string]interface{}
-	timestamp time.Time
-	mu        sync.RWMutex
+	data       map[string]interface{}
+	timestamp  time.Time
+	ttl        time.Duration
+	inflight   chan struct{} // 刷新进行中时非nil，刷新完成后关闭
+	generation uint64        // 每次清空缓存时递增，丢弃清空前发起的刷新结果
+	mu         sync.RWMutex
+}
+
+const (
+	// defaultCompetitionCacheTTL 竞赛数据缓存默认有效期（system_config: competition_cache_ttl_seconds）
+	defaultCompetitionCacheTTL = 30 * time.Second
+	// competitionFetchWorkers 获取竞赛数据时同时查询交易所账户的最大并发数
+	competitionFetchWorkers = 8
+	// competitionFetchTimeout 单个交易员账户查询超时时间
+	competitionFetchTimeout = 3 * time.Second
+)
+
+// get 返回缓存数据：有效期内直接返回；已过期时返回旧数据并在后台刷新；
+// 没有任何缓存时同步刷新（并发调用只会触发一次 fetch）
+func (cc *CompetitionCache) get(fetch func() map[string]interface{}) map[string]interface{} {
+	for attempt := 0; ; attempt++ {
+		cc.mu.Lock()
+		if cc.data != nil {
+			data := copyCompetitionData(cc.data)
+			if time.Since(cc.timestamp) >= cc.ttl && cc.inflight == nil {
+				cc.startRefresh(fetch)
+			}
+			cc.mu.Unlock()
+			return data
+		}
+		if cc.inflight == nil {
+			cc.startRefresh(fetch)
+		}
+		inflight := cc.inflight
+		cc.mu.Unlock()
+
+		<-inflight
+		// 等待的刷新在清空缓存前发起时结果会被丢弃，再等待一次新的刷新
+		if attempt > 0 {
+			cc.mu.RLock()
+			defer cc.mu.RUnlock()
+			return copyCompetitionData(cc.data)
+		}
+	}
+}
+
+// startRefresh 在后台执行 fetch（调用方需持有写锁）
+func (cc *CompetitionCache) startRefresh(fetch func() map[string]interface{}) {
+	done := make(chan struct{})
+	cc.inflight = done
+	go cc.refresh(fetch, cc.generation, done)
+}
+
+// refresh 执行 fetch 并更新缓存，完成后唤醒等待的调用方
+func (cc *CompetitionCache) refresh(fetch func() map[string]interface{}, generation uint64, done chan struct{}) {
+	var data map[string]interface{}
+	defer func() {
+		cc.mu.Lock()
+		if data != nil && cc.generation == generation {
+			cc.data = data
+			cc.timestamp = time.Now()
+		}
+		if cc.inflight == done {
+			cc.inflight = nil
+		}
+		close(done)
+		cc.mu.Unlock()
+	}()
+	data = fetch()
+}
+
+// invalidate 清空缓存，进行中的刷新结果将被丢弃
+func (cc *CompetitionCache) invalidate() {
+	cc.mu.Lock()
+	cc.data = nil
+	cc.timestamp = time.Time{}
+	cc.generation++
+	cc.inflight = nil
+	cc.mu.Unlock()
+}
+
+// copyCompetitionData 复制顶层map，避免调用方修改缓存
+func copyCompetitionData(data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		return nil
+	}
+	cached := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		cached[k] = v
+	}
+	return cached
 }
 
 // TraderManager 管理多个trader实例
 type TraderManager struct {
 	traders          map[string]*trader.AutoTrader // key: trader ID
 	competitionCache *CompetitionCache
+	restartPolicy    restartPolicy // 异常退出自动重启策略
+	reloadMu         sync.Mutex    // 串行化 停止 → 更新 → 启动 的重启序列
 	mu               sync.RWMutex
+	eventHandler     TraderEventHandler // 交易员事件处理（通知等），在独立协程中调用
+}
+
+// TraderEventHandler 处理交易员事件（在每个交易员的事件转发协程中调用，不阻塞决策周期）
+type TraderEventHandler func(at *trader.AutoTrader, event trader.TraderEvent)
+
+// SetEventHandler 设置交易员事件处理函数，之后加载的交易员的事件都会转发给它
+func (tm *TraderManager) SetEventHandler(handler TraderEventHandler) {
+	tm.mu.Lock()
+	defer tm.mu.Unlock()
+	tm.eventHandler = handler
+	for _, at := range tm.traders {
+		if at != nil {
+			tm.forwardEvents(at)
+		}
+	}
+}
+
+// forwardEvents 订阅交易员的事件总线并转发给 eventHandler，事件总线关闭（交易员移除）时退出，调用方需持有 tm.mu
+func (tm *TraderManager) forwardEvents(at *trader.AutoTrader) {
+	handler := tm.eventHandler
+	bus := at.Events()
+	if handler == nil || bus == nil {
+		return
+	}
+	sub := bus.Subscribe(0)
+	go consumeEvents(bus, sub, at.GetName(), func(event trader.TraderEvent) { handler(at, event) })
+}
+
+// consumeEvents 逐个处理订阅到的事件直到事件总线关闭；
+// 处理过慢（缓冲区满）会被事件总线断开，此时重新订阅，避免之后的事件全部丢失
+func consumeEvents(bus *trader.EventBus, sub *trader.EventSubscription, name string, handle func(trader.TraderEvent)) {
+	for {
+		for event := range sub.C {
+			handle(event)
+		}
+		if bus.Closed() {
+			return
+		}
+		log.Printf("⚠️ [%s] 事件处理过慢被断开，重新订阅交易员事件", name)
+		sub = bus.Subscribe(0)
+	}
 }
 
 // NewTraderManager 创建trader管理器
@@ -33,8 +166,9 @@ func NewTraderManager() *TraderManager {
 	return &TraderManager{
 		traders: make(map[string]*trader.AutoTrader),
 		competitionCache: &CompetitionCache{
-			data: make(map[string]interface{}),
+			ttl: defaultCompetitionCacheTTL,
 		},
+		restartPolicy: defaultRestartPolicy,
 	}
 }
 
@@ -66,35 +200,11 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	log.Printf("📋 总共加载 %d 个交易员配置", len(allTraders))
 
 	// 获取系统配置（不包含信号源，信号源现在为用户级别）
-	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
-	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
-	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
-	defaultCoinsStr, _ := database.GetSystemConfig("default_coins")
-
-	// 解析配置
-	maxDailyLoss := 10.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDailyLossStr, 64); err == nil {
-		maxDailyLoss = val
-	}
-
-	maxDrawdown := 20.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDrawdownStr, 64); err == nil {
-		maxDrawdown = val
-	}
-
-	stopTradingMinutes := 60 // 默认值
-	if val, err := strconv.Atoi(stopTradingMinutesStr); err == nil {
-		stopTradingMinutes = val
-	}
-
-	// 解析默认币种列表
-	var defaultCoins []string
-	if defaultCoinsStr != "" {
-		if err := json.Unmarshal([]byte(defaultCoinsStr), &defaultCoins); err != nil {
-			log.Printf("⚠️ 解析默认币种配置失败: %v，使用空列表", err)
-			defaultCoins = []string{}
-		}
-	}
+	sysConfig := database.SystemConfig()
+	maxDailyLoss := sysConfig.Float("max_daily_loss", 10.0)
+	maxDrawdown := sysConfig.Float("max_drawdown", 20.0)
+	stopTradingMinutes := sysConfig.Int("stop_trading_minutes", 60)
+	defaultCoins := sysConfig.StringSlice("default_coins", nil)
 
 	// 为每个交易员获取AI模型和交易所配置
 	for _, traderCfg := range allTraders {
@@ -141,16 +251,10 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 			continue
 		}
 
-		var exchangeCfg *config.ExchangeConfig
-		for _, exchange := range exchanges {
-			if exchange.ID == traderCfg.ExchangeID {
-				exchangeCfg = exchange
-				break
-			}
-		}
+		exchangeCfg := findExchangeConfig(exchanges, traderCfg.UserID, traderCfg.ExchangeID, traderCfg.ExchangeAccountID)
 
 		if exchangeCfg == nil {
-			log.Printf("⚠️  交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			log.Printf("⚠️  交易员 %s 的交易所 %s（账户 %s）不存在，跳过", traderCfg.Name, traderCfg.ExchangeID, traderCfg.ExchangeAccountID)
 			continue
 		}
 
@@ -178,23 +282,77 @@ func (tm *TraderManager) LoadTradersFromDatabase(database *config.Database) erro
 	}
 
 	log.Printf("✓ 成功加载 %d 个交易员到内存", len(tm.traders))
+	return nil
+}
 
-	// ✅ 自动启动之前运行中的交易员（重启后恢复状态）
-	autoStartCount := 0
-	for _, traderCfg := range allTraders {
-		if traderCfg.IsRunning {
-			if at, exists := tm.traders[traderCfg.ID]; exists {
-				log.Printf("🔄 自动启动交易员: %s (恢复运行状态)", traderCfg.Name)
-				go at.Run()
-				autoStartCount++
+// ResumeRunningTraders 重启后恢复数据库中标记为运行中的交易员，每次启动间隔 stagger 避免同时请求交易所。
+// 加载失败（如凭证失效、模型或交易所已禁用）的交易员清除运行标记，避免每次重启都重复失败；
+// ctx 取消时停止启动剩余的交易员。返回成功启动的数量
+func (tm *TraderManager) ResumeRunningTraders(ctx context.Context, database *config.Database, stagger time.Duration) int {
+	userIDs, err := database.GetAllUsers()
+	if err != nil {
+		log.Printf("⚠️ 恢复交易员失败，获取用户列表出错: %v", err)
+		return 0
+	}
+
+	var pending []*config.TraderRecord
+	for _, userID := range userIDs {
+		traders, err := database.GetTraders(userID)
+		if err != nil {
+			log.Printf("⚠️ 获取用户 %s 的交易员失败: %v", userID, err)
+			continue
+		}
+		for _, t := range traders {
+			if t.IsRunning {
+				pending = append(pending, t)
 			}
 		}
 	}
-	if autoStartCount > 0 {
-		log.Printf("✅ 已自动启动 %d 个交易员（恢复运行状态）", autoStartCount)
+	if len(pending) == 0 {
+		return 0
 	}
+	log.Printf("🔄 发现 %d 个重启前运行中的交易员，间隔 %v 依次恢复...", len(pending), stagger)
 
-	return nil
+	// 与手动启动一致：启动前重新加载系统提示词模板（确保使用最新的硬盘文件）
+	if err := decision.ReloadPromptTemplates(); err != nil {
+		log.Printf("⚠️  重新加载提示词模板失败: %v", err)
+	}
+
+	resumed := 0
+	for i, traderCfg := range pending {
+		if i > 0 && stagger > 0 {
+			select {
+			case <-time.After(stagger):
+			case <-ctx.Done():
+			}
+		}
+		if ctx.Err() != nil {
+			log.Printf("⏹  已取消恢复，剩余 %d 个交易员未启动", len(pending)-i)
+			break
+		}
+
+		if err := tm.LoadTraderByID(database, traderCfg.UserID, traderCfg.ID); err != nil {
+			log.Printf("❌ 恢复交易员 %s 失败，已清除运行标记: %v", traderCfg.Name, err)
+			if err := database.UpdateTraderStatus(traderCfg.UserID, traderCfg.ID, false); err != nil {
+				log.Printf("⚠️ 清除交易员 %s 运行标记失败: %v", traderCfg.Name, err)
+			}
+			continue
+		}
+		at, err := tm.GetTrader(traderCfg.ID)
+		if err != nil {
+			continue
+		}
+		if at.IsRunning() {
+			continue
+		}
+
+		log.Printf("▶️  恢复交易员 %s (%s) [模板: %s]", traderCfg.Name, traderCfg.ID, traderCfg.SystemPromptTemplate)
+		tm.StartTrader(at, database)
+		resumed++
+	}
+
+	log.Printf("✅ 已恢复 %d/%d 个交易员", resumed, len(pending))
+	return resumed
 }
 
 // addTraderFromConfig 内部方法：从配置添加交易员（不加锁，因为调用方已加锁）
@@ -227,40 +385,80 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveOITopURL string
+	if traderCfg.UseOITop && oiTopURL != "" {
+		effectiveOITopURL = oiTopURL
+		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+	}
+	signalHeaders, signalTimeout := signalSourceOptions(database, userID)
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                    traderCfg.ID,
-		Name:                  traderCfg.Name,
-		AIModel:               aiModelCfg.Provider, // 使用provider作为模型标识
-		Exchange:              exchangeCfg.ID,      // 使用exchange ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        effectiveCoinPoolURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
-		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		InitialBalance:        traderCfg.InitialBalance,
-		BTCETHLeverage:        traderCfg.BTCETHLeverage,
-		AltcoinLeverage:       traderCfg.AltcoinLeverage,
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:         traderCfg.IsCrossMargin,
-		DefaultCoins:          defaultCoins,
-		TradingCoins:          tradingCoins,
-		SystemPromptTemplate:  traderCfg.SystemPromptTemplate, // 系统提示词模板
+		ID:                       traderCfg.ID,
+		Name:                     traderCfg.Name,
+		AIModel:                  aiModelCfg.Provider, // 使用provider作为模型标识
+		Exchange:                 exchangeCfg.ID,      // 使用exchange ID
+		BinanceAPIKey:            "",
+		BinanceSecretKey:         "",
+		HyperliquidPrivateKey:    "",
+		HyperliquidTestnet:       exchangeCfg.Testnet,
+		CoinPoolAPIURL:           effectiveCoinPoolURL,
+		OITopAPIURL:              effectiveOITopURL,
+		SignalSourceHeaders:      signalHeaders,
+		SignalSourceTimeout:      signalTimeout,
+		UseQwen:                  aiModelCfg.Provider == "qwen",
+		DeepSeekKey:              "",
+		QwenKey:                  "",
+		CustomAPIURL:             aiModelCfg.CustomAPIURL,    // 自定义API URL
+		CustomModelName:          aiModelCfg.CustomModelName, // 自定义模型名称
+		AIModelParams:            aiModelCfg.AIModelParams,   // 模型请求参数
+		ScanInterval:             time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		InitialBalance:           traderCfg.InitialBalance,
+		BTCETHLeverage:           traderCfg.BTCETHLeverage,
+		AltcoinLeverage:          traderCfg.AltcoinLeverage,
+		MaxDailyLoss:             maxDailyLoss,
+		MaxDrawdown:              maxDrawdown,
+		StopTradingTime:          time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:            traderCfg.IsCrossMargin,
+		DefaultCoins:             defaultCoins,
+		TradingCoins:             tradingCoins,
+		SystemPromptTemplate:     traderCfg.SystemPromptTemplate, // 系统提示词模板
+		IsPublic:                 traderCfg.IsPublic,
+		MaxDailyLossPct:          traderCfg.MaxDailyLossPct,
+		DailyLossFlatten:         traderCfg.DailyLossFlatten,
+		MaxPositionValueUSDT:     traderCfg.MaxPositionValueUSDT,
+		MaxTotalExposurePct:      traderCfg.MaxTotalExposurePct,
+		DefaultStopLossPct:       traderCfg.DefaultStopLossPct,
+		DefaultTakeProfitPct:     traderCfg.DefaultTakeProfitPct,
+		CooldownMinutesAfterLoss: traderCfg.CooldownMinutesAfterLoss,
+		ExecutionMode:            traderCfg.ExecutionMode,
+		LimitOffsetBps:           traderCfg.LimitOffsetBps,
+		LimitTimeoutSeconds:      traderCfg.LimitTimeoutSeconds,
+		LimitFallback:            traderCfg.LimitFallback,
+		Experiment: trader.PromptExperiment{
+			Enabled:    traderCfg.ExperimentEnabled,
+			TemplateA:  traderCfg.TemplateA,
+			TemplateB:  traderCfg.TemplateB,
+			SplitRatio: traderCfg.SplitRatio,
+			Mode:       traderCfg.ExperimentMode,
+		},
+		FallbackAIModelID:     traderCfg.FallbackAIModelID,
+		FallbackStickyMinutes: traderCfg.FallbackStickyMinutes,
+		EnsembleModelIDs:      trader.ParseEnsembleModelIDs(traderCfg.EnsembleModelIDs),
+		ConsensusRule:         traderCfg.ConsensusRule,
+		Guardrails:            traderGuardrails(traderCfg),
+		ExcludedSymbols:       strings.Split(traderCfg.ExcludedSymbols, ","),
 	}
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
 	} else if exchangeCfg.ID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -275,6 +473,9 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else {
+		// 自定义/本地模型（本地模型的 API Key 可为空）
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
 	}
 
 	// 创建trader实例
@@ -294,7 +495,7 @@ func (tm *TraderManager) addTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		}
 	}
 
-	tm.traders[traderCfg.ID] = at
+	tm.putTrader(traderCfg.ID, at)
 	log.Printf("✓ Trader '%s' (%s + %s) 已加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
@@ -334,39 +535,79 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveOITopURL string
+	if traderCfg.UseOITop && oiTopURL != "" {
+		effectiveOITopURL = oiTopURL
+		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+	}
+	signalHeaders, signalTimeout := signalSourceOptions(database, userID)
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                    traderCfg.ID,
-		Name:                  traderCfg.Name,
-		AIModel:               aiModelCfg.Provider, // 使用provider作为模型标识
-		Exchange:              exchangeCfg.ID,      // 使用exchange ID
-		BinanceAPIKey:         "",
-		BinanceSecretKey:      "",
-		HyperliquidPrivateKey: "",
-		HyperliquidTestnet:    exchangeCfg.Testnet,
-		CoinPoolAPIURL:        effectiveCoinPoolURL,
-		UseQwen:               aiModelCfg.Provider == "qwen",
-		DeepSeekKey:           "",
-		QwenKey:               "",
-		CustomAPIURL:          aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:       aiModelCfg.CustomModelName, // 自定义模型名称
-		ScanInterval:          time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		InitialBalance:        traderCfg.InitialBalance,
-		BTCETHLeverage:        traderCfg.BTCETHLeverage,
-		AltcoinLeverage:       traderCfg.AltcoinLeverage,
-		MaxDailyLoss:          maxDailyLoss,
-		MaxDrawdown:           maxDrawdown,
-		StopTradingTime:       time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:         traderCfg.IsCrossMargin,
-		DefaultCoins:          defaultCoins,
-		TradingCoins:          tradingCoins,
+		ID:                       traderCfg.ID,
+		Name:                     traderCfg.Name,
+		AIModel:                  aiModelCfg.Provider, // 使用provider作为模型标识
+		Exchange:                 exchangeCfg.ID,      // 使用exchange ID
+		BinanceAPIKey:            "",
+		BinanceSecretKey:         "",
+		HyperliquidPrivateKey:    "",
+		HyperliquidTestnet:       exchangeCfg.Testnet,
+		CoinPoolAPIURL:           effectiveCoinPoolURL,
+		OITopAPIURL:              effectiveOITopURL,
+		SignalSourceHeaders:      signalHeaders,
+		SignalSourceTimeout:      signalTimeout,
+		UseQwen:                  aiModelCfg.Provider == "qwen",
+		DeepSeekKey:              "",
+		QwenKey:                  "",
+		CustomAPIURL:             aiModelCfg.CustomAPIURL,    // 自定义API URL
+		CustomModelName:          aiModelCfg.CustomModelName, // 自定义模型名称
+		AIModelParams:            aiModelCfg.AIModelParams,   // 模型请求参数
+		ScanInterval:             time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		InitialBalance:           traderCfg.InitialBalance,
+		BTCETHLeverage:           traderCfg.BTCETHLeverage,
+		AltcoinLeverage:          traderCfg.AltcoinLeverage,
+		MaxDailyLoss:             maxDailyLoss,
+		MaxDrawdown:              maxDrawdown,
+		StopTradingTime:          time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:            traderCfg.IsCrossMargin,
+		DefaultCoins:             defaultCoins,
+		TradingCoins:             tradingCoins,
+		IsPublic:                 traderCfg.IsPublic,
+		MaxDailyLossPct:          traderCfg.MaxDailyLossPct,
+		DailyLossFlatten:         traderCfg.DailyLossFlatten,
+		MaxPositionValueUSDT:     traderCfg.MaxPositionValueUSDT,
+		MaxTotalExposurePct:      traderCfg.MaxTotalExposurePct,
+		DefaultStopLossPct:       traderCfg.DefaultStopLossPct,
+		DefaultTakeProfitPct:     traderCfg.DefaultTakeProfitPct,
+		CooldownMinutesAfterLoss: traderCfg.CooldownMinutesAfterLoss,
+		ExecutionMode:            traderCfg.ExecutionMode,
+		LimitOffsetBps:           traderCfg.LimitOffsetBps,
+		LimitTimeoutSeconds:      traderCfg.LimitTimeoutSeconds,
+		LimitFallback:            traderCfg.LimitFallback,
+		Experiment: trader.PromptExperiment{
+			Enabled:    traderCfg.ExperimentEnabled,
+			TemplateA:  traderCfg.TemplateA,
+			TemplateB:  traderCfg.TemplateB,
+			SplitRatio: traderCfg.SplitRatio,
+			Mode:       traderCfg.ExperimentMode,
+		},
+		FallbackAIModelID:     traderCfg.FallbackAIModelID,
+		FallbackStickyMinutes: traderCfg.FallbackStickyMinutes,
+		EnsembleModelIDs:      trader.ParseEnsembleModelIDs(traderCfg.EnsembleModelIDs),
+		ConsensusRule:         traderCfg.ConsensusRule,
+		Guardrails:            traderGuardrails(traderCfg),
+		ExcludedSymbols:       strings.Split(traderCfg.ExcludedSymbols, ","),
 	}
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
 	} else if exchangeCfg.ID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -381,6 +622,9 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else {
+		// 自定义/本地模型（本地模型的 API Key 可为空）
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
 	}
 
 	// 创建trader实例
@@ -400,7 +644,7 @@ func (tm *TraderManager) AddTraderFromDB(traderCfg *config.TraderRecord, aiModel
 		}
 	}
 
-	tm.traders[traderCfg.ID] = at
+	tm.putTrader(traderCfg.ID, at)
 	log.Printf("✓ Trader '%s' (%s + %s) 已添加", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
@@ -457,15 +701,314 @@ func (tm *TraderManager) StartAll() {
 	}
 }
 
-// StopAll 停止所有trader
-func (tm *TraderManager) StopAll() {
+// TraderStatusStore 持久化交易员运行状态（*config.Database 实现）
+type TraderStatusStore interface {
+	UpdateTraderStatus(userID, id string, isRunning bool) error
+}
+
+// TraderRunStore 监督运行时持久化运行状态和异常原因（*config.Database 实现）
+type TraderRunStore interface {
+	TraderStatusStore
+	UpdateTraderLastError(userID, id, lastError string) error
+}
+
+// restartPolicy 交易员异常退出后的自动重启策略
+type restartPolicy struct {
+	BaseDelay   time.Duration // 首次重启等待时间，之后每次翻倍
+	MaxDelay    time.Duration // 重启等待上限
+	MaxFailures int           // 连续异常退出达到该次数后放弃（0=不限）
+	ResetAfter  time.Duration // 单次运行超过该时长视为恢复正常，连续失败计数清零
+}
+
+// defaultRestartPolicy 默认重启策略（最大失败次数可通过 system_config: trader_max_restarts 调整）
+var defaultRestartPolicy = restartPolicy{
+	BaseDelay:   10 * time.Second,
+	MaxDelay:    10 * time.Minute,
+	MaxFailures: 5,
+	ResetAfter:  30 * time.Minute,
+}
+
+// backoff 返回第 failures 次连续失败后的重启等待时间（指数退避，封顶 MaxDelay）
+func (p restartPolicy) backoff(failures int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// SetMaxRestarts 设置连续异常退出多少次后放弃自动重启（0=不限，负数忽略）
+func (tm *TraderManager) SetMaxRestarts(n int) {
+	if n < 0 {
+		return
+	}
+	tm.mu.Lock()
+	tm.restartPolicy.MaxFailures = n
+	tm.mu.Unlock()
+}
+
+// supervisedTrader 监督运行需要的交易员能力（便于测试替换）
+type supervisedTrader interface {
+	GetID() string
+	GetName() string
+	GetUserID() string
+	Run() error
+	BeginSupervision() <-chan struct{}
+	EndSupervision(stopRequested <-chan struct{})
+	RecordRunFailure(err error, restarts int)
+}
+
+// StartTrader 以监督模式在后台启动交易员：运行出错或 panic 时按指数退避自动重启，
+// 手动停止后不再重启；连续失败达到上限时放弃并清除运行标记。异常原因写入 last_error
+func (tm *TraderManager) StartTrader(at *trader.AutoTrader, store TraderRunStore) {
 	tm.mu.RLock()
-	defer tm.mu.RUnlock()
+	policy := tm.restartPolicy
+	tm.mu.RUnlock()
+
+	// 交易员被移除或重新加载（实例已替换）时不再重启旧实例
+	stillCurrent := func() bool {
+		current, err := tm.GetTrader(at.GetID())
+		return err == nil && current == at
+	}
+	stopRequested := at.BeginSupervision()
+	go superviseTrader(at, stopRequested, store, policy, stillCurrent)
+}
+
+// superviseTrader 循环运行交易员直到正常停止、收到停止请求或连续失败达到上限
+func superviseTrader(at supervisedTrader, stopRequested <-chan struct{}, store TraderRunStore, policy restartPolicy, stillCurrent func() bool) {
+	defer at.EndSupervision(stopRequested)
+	if store != nil {
+		if err := store.UpdateTraderLastError(at.GetUserID(), at.GetID(), ""); err != nil {
+			log.Printf("⚠️ 清除交易员 %s 异常记录失败: %v", at.GetName(), err)
+		}
+	}
+
+	failures := 0
+	for {
+		started := time.Now()
+		err := at.Run()
+		if err == nil {
+			return
+		}
+
+		if policy.ResetAfter > 0 && time.Since(started) >= policy.ResetAfter {
+			failures = 0
+		}
+		failures++
+		at.RecordRunFailure(err, failures)
+		if store != nil {
+			if dbErr := store.UpdateTraderLastError(at.GetUserID(), at.GetID(), err.Error()); dbErr != nil {
+				log.Printf("⚠️ 保存交易员 %s 异常记录失败: %v", at.GetName(), dbErr)
+			}
+		}
 
-	log.Println("⏹  停止所有Trader...")
+		// AI认证失败（API Key无效或被撤销）重启也无法恢复，直接停止并清除运行标记
+		if errors.Is(err, trader.ErrAIAuthFailed) {
+			log.Printf("❌ 交易员 %s AI认证失败，停止且不自动重启: %v", at.GetName(), err)
+			if store != nil {
+				if dbErr := store.UpdateTraderStatus(at.GetUserID(), at.GetID(), false); dbErr != nil {
+					log.Printf("⚠️ 清除交易员 %s 运行标记失败: %v", at.GetName(), dbErr)
+				}
+			}
+			return
+		}
+
+		if policy.MaxFailures > 0 && failures >= policy.MaxFailures {
+			log.Printf("❌ 交易员 %s 连续异常退出 %d 次，放弃自动重启: %v", at.GetName(), failures, err)
+			if store != nil {
+				if dbErr := store.UpdateTraderStatus(at.GetUserID(), at.GetID(), false); dbErr != nil {
+					log.Printf("⚠️ 清除交易员 %s 运行标记失败: %v", at.GetName(), dbErr)
+				}
+			}
+			return
+		}
+
+		delay := policy.backoff(failures)
+		log.Printf("⚠️ 交易员 %s 异常退出（第 %d 次）: %v，%v 后自动重启", at.GetName(), failures, err, delay)
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-stopRequested:
+			timer.Stop()
+			log.Printf("⏹  交易员 %s 已手动停止，取消自动重启", at.GetName())
+			return
+		}
+		select {
+		case <-stopRequested:
+			return
+		default:
+		}
+		if stillCurrent != nil && !stillCurrent() {
+			log.Printf("⏹  交易员 %s 已被移除或重新加载，取消自动重启", at.GetName())
+			return
+		}
+		log.Printf("🔄 自动重启交易员 %s（第 %d 次）", at.GetName(), failures)
+	}
+}
+
+// stoppableTrader StopAll 需要的交易员能力（便于测试替换）
+type stoppableTrader interface {
+	GetID() string
+	GetName() string
+	GetUserID() string
+	IsRunning() bool
+	Stop()
+}
+
+// StopAll 通知所有运行中的trader停止，并在 ctx 截止前等待进行中的决策周期结束。
+// 停止前处于运行状态的trader在 store 中保持 is_running=true，进程重启后会自动恢复；
+// 超时仍未停止的trader记录日志后放弃等待，返回其ID
+func (tm *TraderManager) StopAll(ctx context.Context, store TraderStatusStore) []string {
+	tm.mu.RLock()
+	traders := make([]stoppableTrader, 0, len(tm.traders))
 	for _, t := range tm.traders {
-		t.Stop()
+		if t != nil {
+			traders = append(traders, t)
+		}
 	}
+	tm.mu.RUnlock()
+
+	return stopTraders(ctx, traders, store)
+}
+
+// stopTraders 并发停止运行中的trader，ctx 截止时返回仍未停止的trader ID
+func stopTraders(ctx context.Context, traders []stoppableTrader, store TraderStatusStore) []string {
+	var running []stoppableTrader
+	for _, t := range traders {
+		if t.IsRunning() {
+			running = append(running, t)
+		}
+	}
+	if len(running) == 0 {
+		return nil
+	}
+	log.Printf("⏹  停止 %d 个运行中的Trader...", len(running))
+
+	done := make(chan string, len(running))
+	for _, t := range running {
+		if store != nil {
+			if err := store.UpdateTraderStatus(t.GetUserID(), t.GetID(), true); err != nil {
+				log.Printf("⚠️ 保存交易员 %s 运行状态失败: %v", t.GetName(), err)
+			}
+		}
+		go func(t stoppableTrader) {
+			t.Stop() // 等待进行中的决策周期结束
+			done <- t.GetID()
+		}(t)
+	}
+
+	stopped := make(map[string]bool, len(running))
+	for len(stopped) < len(running) {
+		select {
+		case id := <-done:
+			stopped[id] = true
+		case <-ctx.Done():
+			var abandoned []string
+			for _, t := range running {
+				if !stopped[t.GetID()] {
+					log.Printf("⚠️ 交易员 %s (%s) 未能在截止时间前停止，放弃等待", t.GetName(), t.GetID())
+					abandoned = append(abandoned, t.GetID())
+				}
+			}
+			return abandoned
+		}
+	}
+	log.Printf("✓ %d 个Trader已停止", len(running))
+	return nil
+}
+
+// RestartTraderWithUpdate 按 停止 → update → 重新加载并启动 的顺序替换交易员实例。
+// Stop 会等待进行中的决策周期结束，保证新配置生效后旧实例不再执行任何周期；update 失败时恢复运行旧实例
+func (tm *TraderManager) RestartTraderWithUpdate(database *config.Database, userID, traderID string, update func() error) error {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+
+	old, err := tm.GetTrader(traderID)
+	if err != nil {
+		return err
+	}
+
+	reload := func(start bool) error {
+		tm.RemoveTrader(traderID)
+		if err := tm.LoadTraderByID(database, userID, traderID); err != nil {
+			return fmt.Errorf("重新加载交易员失败: %w", err)
+		}
+		if start {
+			at, err := tm.GetTrader(traderID)
+			if err != nil {
+				return err
+			}
+			tm.StartTrader(at, database)
+		}
+		return nil
+	}
+	restore := func() { tm.StartTrader(old, database) }
+
+	return replaceTrader(old, update, reload, restore)
+}
+
+// replaceTrader 停止旧实例并等待其退出后执行 update；成功则 reload（start 表示旧实例原本在运行），失败则 restore 旧实例
+func replaceTrader(old stoppableTrader, update func() error, reload func(start bool) error, restore func()) error {
+	wasRunning := old.IsRunning()
+	if wasRunning {
+		old.Stop()
+		log.Printf("⏹  已停止交易员 %s，准备按新配置重启", old.GetName())
+	}
+
+	if err := update(); err != nil {
+		if wasRunning {
+			restore()
+		}
+		return err
+	}
+	return reload(wasRunning)
+}
+
+// DeleteTrader 按 停止并等待 → 从内存移除 → 删除数据库记录 的顺序删除交易员，
+// 保证删除后不会再有周期以已删除的交易员身份下单；数据库删除失败时重新加载（原本运行则重新启动）
+func (tm *TraderManager) DeleteTrader(database *config.Database, userID, traderID string) error {
+	tm.reloadMu.Lock()
+	defer tm.reloadMu.Unlock()
+
+	at, err := tm.GetTrader(traderID)
+	if err != nil {
+		// 未加载到内存，直接删除数据库记录
+		return database.DeleteTrader(userID, traderID)
+	}
+
+	restore := func(start bool) {
+		if err := tm.LoadTraderByID(database, userID, traderID); err != nil {
+			log.Printf("❌ 删除失败后恢复交易员 %s 失败: %v", traderID, err)
+			return
+		}
+		if restored, err := tm.GetTrader(traderID); err == nil && start {
+			tm.StartTrader(restored, database)
+		}
+	}
+	return deleteTrader(at,
+		func() { tm.RemoveTrader(traderID) },
+		func() error { return database.DeleteTrader(userID, traderID) },
+		restore,
+	)
+}
+
+// deleteTrader 停止并等待旧实例退出、注销后再删除记录；删除失败时 restore（start 表示原本在运行）
+func deleteTrader(at stoppableTrader, unregister func(), deleteRecord func() error, restore func(start bool)) error {
+	wasRunning := at.IsRunning()
+	if wasRunning {
+		at.Stop()
+		log.Printf("⏹  已停止待删除的交易员 %s", at.GetName())
+	}
+	unregister()
+
+	if err := deleteRecord(); err != nil {
+		restore(wasRunning)
+		return err
+	}
+	return nil
 }
 
 // GetComparisonData 获取对比数据
@@ -489,6 +1032,8 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 			"trader_name":     t.GetName(),
 			"ai_model":        t.GetAIModel(),
 			"exchange":        t.GetExchange(),
+			"is_paper":        t.GetExchange() == config.PaperExchangeID,
+			"testnet":         t.IsTestnet(),
 			"total_equity":    account["total_equity"],
 			"total_pnl":       account["total_pnl"],
 			"total_pnl_pct":   account["total_pnl_pct"],
@@ -505,32 +1050,42 @@ func (tm *TraderManager) GetComparisonData() (map[string]interface{}, error) {
 	return comparison, nil
 }
 
-// GetCompetitionData 获取竞赛数据（全平台所有交易员）
-func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
-	// 检查缓存是否有效（30秒内）
-	tm.competitionCache.mu.RLock()
-	if time.Since(tm.competitionCache.timestamp) < 30*time.Second && len(tm.competitionCache.data) > 0 {
-		// 返回缓存数据
-		cachedData := make(map[string]interface{})
-		for k, v := range tm.competitionCache.data {
-			cachedData[k] = v
-		}
-		tm.competitionCache.mu.RUnlock()
-		log.Printf("📋 返回竞赛数据缓存 (缓存时间: %.1fs)", time.Since(tm.competitionCache.timestamp).Seconds())
-		return cachedData, nil
+// SetCompetitionCacheTTL 设置竞赛数据缓存有效期（小于等于0时忽略）
+func (tm *TraderManager) SetCompetitionCacheTTL(ttl time.Duration) {
+	if ttl <= 0 {
+		return
 	}
-	tm.competitionCache.mu.RUnlock()
+	tm.competitionCache.mu.Lock()
+	tm.competitionCache.ttl = ttl
+	tm.competitionCache.mu.Unlock()
+}
 
+// InvalidateCompetitionCache 清空竞赛数据缓存（交易员公开状态变化或被删除时调用，下次请求同步刷新）
+func (tm *TraderManager) InvalidateCompetitionCache() {
+	tm.competitionCache.invalidate()
+}
+
+// GetCompetitionData 获取竞赛数据（全平台公开的交易员）。
+// 结果按TTL缓存，/api/competition、/api/traders 和 /api/top-traders 共用；缓存过期时先返回旧数据并在后台刷新
+func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
+	return tm.competitionCache.get(tm.collectCompetitionData), nil
+}
+
+// collectCompetitionData 并发获取所有公开交易员的账户数据，按收益率排序后取前50名
+func (tm *TraderManager) collectCompetitionData() map[string]interface{} {
 	tm.mu.RLock()
 
 	// 获取所有交易员列表
+	// 只统计所有者选择公开的交易员
 	allTraders := make([]*trader.AutoTrader, 0, len(tm.traders))
 	for _, t := range tm.traders {
-		allTraders = append(allTraders, t)
+		if t != nil && t.IsPublic() {
+			allTraders = append(allTraders, t)
+		}
 	}
 	tm.mu.RUnlock()
 
-	log.Printf("🔄 重新获取竞赛数据，交易员数量: %d", len(allTraders))
+	log.Printf("🔄 重新获取竞赛数据，公开交易员数量: %d", len(allTraders))
 
 	// 并发获取交易员数据
 	traders := tm.getConcurrentTraderData(allTraders)
@@ -555,126 +1110,153 @@ func (tm *TraderManager) GetCompetitionData() (map[string]interface{}, error) {
 		traders = traders[:limit]
 	}
 
+	paperCount := 0
+	for _, t := range allTraders {
+		if t.GetExchange() == config.PaperExchangeID || t.IsTestnet() {
+			paperCount++
+		}
+	}
+
 	comparison := make(map[string]interface{})
 	comparison["traders"] = traders
 	comparison["count"] = len(traders)
 	comparison["total_count"] = totalCount // 总交易员数量
-
-	// 更新缓存
-	tm.competitionCache.mu.Lock()
-	tm.competitionCache.data = comparison
-	tm.competitionCache.timestamp = time.Now()
-	tm.competitionCache.mu.Unlock()
-
-	return comparison, nil
+	comparison["paper_count"] = paperCount // 其中模拟资金（模拟盘/测试网）交易员数量
+	return comparison
 }
 
-// getConcurrentTraderData 并发获取多个交易员的数据
-func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) []map[string]interface{} {
-	type traderResult struct {
-		index int
-		data  map[string]interface{}
-	}
-
-	// 创建结果通道
-	resultChan := make(chan traderResult, len(traders))
-
-	// 并发获取每个交易员的数据
-	for i, t := range traders {
-		go func(index int, trader *trader.AutoTrader) {
-			// 设置单个交易员的超时时间为3秒
-			ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-			defer cancel()
-
-			// 使用通道来实现超时控制
-			accountChan := make(chan map[string]interface{}, 1)
-			errorChan := make(chan error, 1)
-
-			go func() {
-				account, err := trader.GetAccountInfo()
-				if err != nil {
-					errorChan <- err
-				} else {
-					accountChan <- account
-				}
-			}()
-
-			status := trader.GetStatus()
-			var traderData map[string]interface{}
+// ExcludePaperTraders 从竞赛数据中移除模拟盘和测试网交易员（用于真实资金排行榜），不修改原数据
+func ExcludePaperTraders(competition map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(competition))
+	for key, value := range competition {
+		result[key] = value
+	}
 
-			select {
-			case account := <-accountChan:
-				// 成功获取账户信息
-				traderData = map[string]interface{}{
-					"trader_id":              trader.GetID(),
-					"trader_name":            trader.GetName(),
-					"ai_model":               trader.GetAIModel(),
-					"exchange":               trader.GetExchange(),
-					"total_equity":           account["total_equity"],
-					"total_pnl":              account["total_pnl"],
-					"total_pnl_pct":          account["total_pnl_pct"],
-					"position_count":         account["position_count"],
-					"margin_used_pct":        account["margin_used_pct"],
-					"is_running":             status["is_running"],
-					"system_prompt_template": trader.GetSystemPromptTemplate(),
-				}
-			case err := <-errorChan:
-				// 获取账户信息失败
-				log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", trader.GetID(), err)
-				traderData = map[string]interface{}{
-					"trader_id":              trader.GetID(),
-					"trader_name":            trader.GetName(),
-					"ai_model":               trader.GetAIModel(),
-					"exchange":               trader.GetExchange(),
-					"total_equity":           0.0,
-					"total_pnl":              0.0,
-					"total_pnl_pct":          0.0,
-					"position_count":         0,
-					"margin_used_pct":        0.0,
-					"is_running":             status["is_running"],
-					"system_prompt_template": trader.GetSystemPromptTemplate(),
-					"error":                  "账户数据获取失败",
-				}
-			case <-ctx.Done():
-				// 超时
-				log.Printf("⏰ 获取交易员 %s 账户信息超时", trader.GetID())
-				traderData = map[string]interface{}{
-					"trader_id":              trader.GetID(),
-					"trader_name":            trader.GetName(),
-					"ai_model":               trader.GetAIModel(),
-					"exchange":               trader.GetExchange(),
-					"total_equity":           0.0,
-					"total_pnl":              0.0,
-					"total_pnl_pct":          0.0,
-					"position_count":         0,
-					"margin_used_pct":        0.0,
-					"is_running":             status["is_running"],
-					"system_prompt_template": trader.GetSystemPromptTemplate(),
-					"error":                  "获取超时",
-				}
-			}
+	traders, ok := competition["traders"].([]map[string]interface{})
+	if !ok {
+		return result
+	}
+	realTraders := make([]map[string]interface{}, 0, len(traders))
+	for _, t := range traders {
+		isPaper, _ := t["is_paper"].(bool)
+		testnet, _ := t["testnet"].(bool)
+		if !isPaper && !testnet {
+			realTraders = append(realTraders, t)
+		}
+	}
 
-			resultChan <- traderResult{index: index, data: traderData}
-		}(i, t)
+	result["traders"] = realTraders
+	result["count"] = len(realTraders)
+	if total, ok := competition["total_count"].(int); ok {
+		paperCount, _ := competition["paper_count"].(int)
+		result["total_count"] = total - paperCount
 	}
+	result["paper_count"] = 0
+	return result
+}
 
-	// 收集所有结果
-	results := make([]map[string]interface{}, len(traders))
-	for i := 0; i < len(traders); i++ {
-		result := <-resultChan
-		results[result.index] = result.data
+// forEachBounded 以最多 workers 个并发执行 fn(0..n-1)，全部完成后返回
+func forEachBounded(n, workers int, fn func(i int)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(i)
+		}(i)
 	}
+	wg.Wait()
+}
 
+// getConcurrentTraderData 并发获取多个交易员的数据（最多 competitionFetchWorkers 个并发，单个失败或超时不影响其他交易员）
+func (tm *TraderManager) getConcurrentTraderData(traders []*trader.AutoTrader) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(traders))
+	forEachBounded(len(traders), competitionFetchWorkers, func(i int) {
+		results[i] = fetchCompetitionEntry(traders[i])
+	})
 	return results
 }
 
+// fetchCompetitionEntry 获取单个交易员的竞赛数据，失败或超时时返回带 error 字段的零值数据
+func fetchCompetitionEntry(trader *trader.AutoTrader) map[string]interface{} {
+	ctx, cancel := context.WithTimeout(context.Background(), competitionFetchTimeout)
+	defer cancel()
+
+	// 使用通道来实现超时控制
+	accountChan := make(chan map[string]interface{}, 1)
+	errorChan := make(chan error, 1)
+
+	go func() {
+		account, err := trader.GetAccountInfo()
+		if err != nil {
+			errorChan <- err
+		} else {
+			accountChan <- account
+		}
+	}()
+
+	status := trader.GetStatus()
+	traderData := map[string]interface{}{
+		"trader_id":              trader.GetID(),
+		"trader_name":            trader.GetName(),
+		"ai_model":               trader.GetAIModel(),
+		"exchange":               trader.GetExchange(),
+		"is_paper":               trader.GetExchange() == config.PaperExchangeID,
+		"testnet":                trader.IsTestnet(),
+		"total_equity":           0.0,
+		"total_pnl":              0.0,
+		"total_pnl_pct":          0.0,
+		"position_count":         0,
+		"margin_used_pct":        0.0,
+		"is_running":             status["is_running"],
+		"system_prompt_template": trader.GetSystemPromptTemplate(),
+	}
+
+	select {
+	case account := <-accountChan:
+		// 成功获取账户信息
+		for _, key := range []string{"total_equity", "total_pnl", "total_pnl_pct", "position_count", "margin_used_pct"} {
+			traderData[key] = account[key]
+		}
+	case err := <-errorChan:
+		// 获取账户信息失败
+		log.Printf("⚠️ 获取交易员 %s 账户信息失败: %v", trader.GetID(), err)
+		traderData["error"] = "账户数据获取失败"
+	case <-ctx.Done():
+		// 超时
+		log.Printf("⏰ 获取交易员 %s 账户信息超时", trader.GetID())
+		traderData["error"] = "获取超时"
+	}
+	return traderData
+}
+
 // GetTopTradersData 获取前5名交易员数据（用于表现对比）
 func (tm *TraderManager) GetTopTradersData() (map[string]interface{}, error) {
+	return tm.getTopTradersData(false)
+}
+
+// GetRealMoneyTopTradersData 获取前5名真实资金交易员数据（排除模拟盘和测试网）
+func (tm *TraderManager) GetRealMoneyTopTradersData() (map[string]interface{}, error) {
+	return tm.getTopTradersData(true)
+}
+
+func (tm *TraderManager) getTopTradersData(excludePaper bool) (map[string]interface{}, error) {
 	// 复用竞赛数据缓存，因为前5名是从全部数据中筛选出来的
 	competitionData, err := tm.GetCompetitionData()
 	if err != nil {
 		return nil, err
 	}
+	if excludePaper {
+		competitionData = ExcludePaperTraders(competitionData)
+	}
 
 	// 从竞赛数据中提取前5名
 	allTraders, ok := competitionData["traders"].([]map[string]interface{})
@@ -741,10 +1323,11 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 	log.Printf("📋 为用户 %s 加载交易员配置: %d 个", userID, len(traders))
 
 	// 获取系统配置（不包含信号源，信号源现在为用户级别）
-	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
-	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
-	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
-	defaultCoinsStr, _ := database.GetSystemConfig("default_coins")
+	sysConfig := database.SystemConfig()
+	maxDailyLoss := sysConfig.Float("max_daily_loss", 10.0)
+	maxDrawdown := sysConfig.Float("max_drawdown", 20.0)
+	stopTradingMinutes := sysConfig.Int("stop_trading_minutes", 60)
+	defaultCoins := sysConfig.StringSlice("default_coins", nil)
 
 	// 获取用户信号源配置
 	var coinPoolURL, oiTopURL string
@@ -756,31 +1339,6 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		log.Printf("🔍 用户 %s 暂未配置信号源", userID)
 	}
 
-	// 解析配置
-	maxDailyLoss := 10.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDailyLossStr, 64); err == nil {
-		maxDailyLoss = val
-	}
-
-	maxDrawdown := 20.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDrawdownStr, 64); err == nil {
-		maxDrawdown = val
-	}
-
-	stopTradingMinutes := 60 // 默认值
-	if val, err := strconv.Atoi(stopTradingMinutesStr); err == nil {
-		stopTradingMinutes = val
-	}
-
-	// 解析默认币种列表
-	var defaultCoins []string
-	if defaultCoinsStr != "" {
-		if err := json.Unmarshal([]byte(defaultCoinsStr), &defaultCoins); err != nil {
-			log.Printf("⚠️ 解析默认币种配置失败: %v，使用空列表", err)
-			defaultCoins = []string{}
-		}
-	}
-
 	// 🔧 性能优化：在循环外只查询一次AI模型和交易所配置
 	// 避免在循环中重复查询相同的数据，减少数据库压力和锁持有时间
 	aiModels, err := database.GetAIModels(userID)
@@ -835,16 +1393,10 @@ func (tm *TraderManager) LoadUserTraders(database *config.Database, userID strin
 		}
 
 		// 从已查询的列表中查找交易所配置
-		var exchangeCfg *config.ExchangeConfig
-		for _, exchange := range exchanges {
-			if exchange.ID == traderCfg.ExchangeID {
-				exchangeCfg = exchange
-				break
-			}
-		}
+		exchangeCfg := findExchangeConfig(exchanges, traderCfg.UserID, traderCfg.ExchangeID, traderCfg.ExchangeAccountID)
 
 		if exchangeCfg == nil {
-			log.Printf("⚠️ 交易员 %s 的交易所 %s 不存在，跳过", traderCfg.Name, traderCfg.ExchangeID)
+			log.Printf("⚠️ 交易员 %s 的交易所 %s（账户 %s）不存在，跳过", traderCfg.Name, traderCfg.ExchangeID, traderCfg.ExchangeAccountID)
 			continue
 		}
 
@@ -939,16 +1491,10 @@ func (tm *TraderManager) LoadTraderByID(database *config.Database, userID, trade
 		return fmt.Errorf("获取交易所配置失败: %w", err)
 	}
 
-	var exchangeCfg *config.ExchangeConfig
-	for _, exchange := range exchanges {
-		if exchange.ID == traderCfg.ExchangeID {
-			exchangeCfg = exchange
-			break
-		}
-	}
+	exchangeCfg := findExchangeConfig(exchanges, traderCfg.UserID, traderCfg.ExchangeID, traderCfg.ExchangeAccountID)
 
 	if exchangeCfg == nil {
-		return fmt.Errorf("交易所 %s 不存在", traderCfg.ExchangeID)
+		return fmt.Errorf("交易所 %s（账户 %s）不存在", traderCfg.ExchangeID, traderCfg.ExchangeAccountID)
 	}
 
 	if !exchangeCfg.Enabled {
@@ -956,10 +1502,11 @@ func (tm *TraderManager) LoadTraderByID(database *config.Database, userID, trade
 	}
 
 	// 5. 查询系统配置
-	maxDailyLossStr, _ := database.GetSystemConfig("max_daily_loss")
-	maxDrawdownStr, _ := database.GetSystemConfig("max_drawdown")
-	stopTradingMinutesStr, _ := database.GetSystemConfig("stop_trading_minutes")
-	defaultCoinsStr, _ := database.GetSystemConfig("default_coins")
+	sysConfig := database.SystemConfig()
+	maxDailyLoss := sysConfig.Float("max_daily_loss", 10.0)
+	maxDrawdown := sysConfig.Float("max_drawdown", 20.0)
+	stopTradingMinutes := sysConfig.Int("stop_trading_minutes", 60)
+	defaultCoins := sysConfig.StringSlice("default_coins", nil)
 
 	// 6. 查询用户信号源配置
 	var coinPoolURL, oiTopURL string
@@ -971,31 +1518,6 @@ func (tm *TraderManager) LoadTraderByID(database *config.Database, userID, trade
 		log.Printf("🔍 用户 %s 暂未配置信号源", userID)
 	}
 
-	// 7. 解析系统配置
-	maxDailyLoss := 10.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDailyLossStr, 64); err == nil {
-		maxDailyLoss = val
-	}
-
-	maxDrawdown := 20.0 // 默认值
-	if val, err := strconv.ParseFloat(maxDrawdownStr, 64); err == nil {
-		maxDrawdown = val
-	}
-
-	stopTradingMinutes := 60 // 默认值
-	if val, err := strconv.Atoi(stopTradingMinutesStr); err == nil {
-		stopTradingMinutes = val
-	}
-
-	// 解析默认币种列表
-	var defaultCoins []string
-	if defaultCoinsStr != "" {
-		if err := json.Unmarshal([]byte(defaultCoinsStr), &defaultCoins); err != nil {
-			log.Printf("⚠️ 解析默认币种配置失败: %v，使用空列表", err)
-			defaultCoins = []string{}
-		}
-	}
-
 	// 8. 调用私有方法加载交易员
 	log.Printf("📋 加载单个交易员: %s (%s)", traderCfg.Name, traderID)
 	return tm.loadSingleTrader(
@@ -1039,35 +1561,75 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		effectiveCoinPoolURL = coinPoolURL
 		log.Printf("✓ 交易员 %s 启用 COIN POOL 信号源: %s", traderCfg.Name, coinPoolURL)
 	}
+	var effectiveOITopURL string
+	if traderCfg.UseOITop && oiTopURL != "" {
+		effectiveOITopURL = oiTopURL
+		log.Printf("✓ 交易员 %s 启用 OI TOP 信号源: %s", traderCfg.Name, oiTopURL)
+	}
+	signalHeaders, signalTimeout := signalSourceOptions(database, userID)
 
 	// 构建AutoTraderConfig
 	traderConfig := trader.AutoTraderConfig{
-		ID:                   traderCfg.ID,
-		Name:                 traderCfg.Name,
-		AIModel:              aiModelCfg.Provider, // 使用provider作为模型标识
-		Exchange:             exchangeCfg.ID,      // 使用exchange ID
-		InitialBalance:       traderCfg.InitialBalance,
-		BTCETHLeverage:       traderCfg.BTCETHLeverage,
-		AltcoinLeverage:      traderCfg.AltcoinLeverage,
-		ScanInterval:         time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
-		CoinPoolAPIURL:       effectiveCoinPoolURL,
-		CustomAPIURL:         aiModelCfg.CustomAPIURL,    // 自定义API URL
-		CustomModelName:      aiModelCfg.CustomModelName, // 自定义模型名称
-		UseQwen:              aiModelCfg.Provider == "qwen",
-		MaxDailyLoss:         maxDailyLoss,
-		MaxDrawdown:          maxDrawdown,
-		StopTradingTime:      time.Duration(stopTradingMinutes) * time.Minute,
-		IsCrossMargin:        traderCfg.IsCrossMargin,
-		DefaultCoins:         defaultCoins,
-		TradingCoins:         tradingCoins,
-		SystemPromptTemplate: traderCfg.SystemPromptTemplate, // 系统提示词模板
-		HyperliquidTestnet:   exchangeCfg.Testnet,            // Hyperliquid测试网
+		ID:                       traderCfg.ID,
+		Name:                     traderCfg.Name,
+		AIModel:                  aiModelCfg.Provider, // 使用provider作为模型标识
+		Exchange:                 exchangeCfg.ID,      // 使用exchange ID
+		InitialBalance:           traderCfg.InitialBalance,
+		BTCETHLeverage:           traderCfg.BTCETHLeverage,
+		AltcoinLeverage:          traderCfg.AltcoinLeverage,
+		ScanInterval:             time.Duration(traderCfg.ScanIntervalMinutes) * time.Minute,
+		CoinPoolAPIURL:           effectiveCoinPoolURL,
+		OITopAPIURL:              effectiveOITopURL,
+		SignalSourceHeaders:      signalHeaders,
+		SignalSourceTimeout:      signalTimeout,
+		CustomAPIURL:             aiModelCfg.CustomAPIURL,    // 自定义API URL
+		CustomModelName:          aiModelCfg.CustomModelName, // 自定义模型名称
+		AIModelParams:            aiModelCfg.AIModelParams,   // 模型请求参数
+		UseQwen:                  aiModelCfg.Provider == "qwen",
+		MaxDailyLoss:             maxDailyLoss,
+		MaxDrawdown:              maxDrawdown,
+		StopTradingTime:          time.Duration(stopTradingMinutes) * time.Minute,
+		IsCrossMargin:            traderCfg.IsCrossMargin,
+		DefaultCoins:             defaultCoins,
+		TradingCoins:             tradingCoins,
+		SystemPromptTemplate:     traderCfg.SystemPromptTemplate, // 系统提示词模板
+		HyperliquidTestnet:       exchangeCfg.Testnet,            // Hyperliquid测试网
+		IsPublic:                 traderCfg.IsPublic,
+		MaxDailyLossPct:          traderCfg.MaxDailyLossPct,
+		DailyLossFlatten:         traderCfg.DailyLossFlatten,
+		MaxPositionValueUSDT:     traderCfg.MaxPositionValueUSDT,
+		MaxTotalExposurePct:      traderCfg.MaxTotalExposurePct,
+		DefaultStopLossPct:       traderCfg.DefaultStopLossPct,
+		DefaultTakeProfitPct:     traderCfg.DefaultTakeProfitPct,
+		CooldownMinutesAfterLoss: traderCfg.CooldownMinutesAfterLoss,
+		ExecutionMode:            traderCfg.ExecutionMode,
+		LimitOffsetBps:           traderCfg.LimitOffsetBps,
+		LimitTimeoutSeconds:      traderCfg.LimitTimeoutSeconds,
+		LimitFallback:            traderCfg.LimitFallback,
+		Experiment: trader.PromptExperiment{
+			Enabled:    traderCfg.ExperimentEnabled,
+			TemplateA:  traderCfg.TemplateA,
+			TemplateB:  traderCfg.TemplateB,
+			SplitRatio: traderCfg.SplitRatio,
+			Mode:       traderCfg.ExperimentMode,
+		},
+		FallbackAIModelID:     traderCfg.FallbackAIModelID,
+		FallbackStickyMinutes: traderCfg.FallbackStickyMinutes,
+		EnsembleModelIDs:      trader.ParseEnsembleModelIDs(traderCfg.EnsembleModelIDs),
+		ConsensusRule:         traderCfg.ConsensusRule,
+		Guardrails:            traderGuardrails(traderCfg),
+		ExcludedSymbols:       strings.Split(traderCfg.ExcludedSymbols, ","),
 	}
 
 	// 根据交易所类型设置API密钥
 	if exchangeCfg.ID == "binance" {
 		traderConfig.BinanceAPIKey = exchangeCfg.APIKey
 		traderConfig.BinanceSecretKey = exchangeCfg.SecretKey
+		traderConfig.BinanceTestnet = exchangeCfg.Testnet
+	} else if exchangeCfg.ID == "bybit" {
+		traderConfig.BybitAPIKey = exchangeCfg.APIKey
+		traderConfig.BybitSecretKey = exchangeCfg.SecretKey
+		traderConfig.BybitTestnet = exchangeCfg.Testnet
 	} else if exchangeCfg.ID == "hyperliquid" {
 		traderConfig.HyperliquidPrivateKey = exchangeCfg.APIKey // hyperliquid用APIKey存储private key
 		traderConfig.HyperliquidWalletAddr = exchangeCfg.HyperliquidWalletAddr
@@ -1082,6 +1644,9 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		traderConfig.QwenKey = aiModelCfg.APIKey
 	} else if aiModelCfg.Provider == "deepseek" {
 		traderConfig.DeepSeekKey = aiModelCfg.APIKey
+	} else {
+		// 自定义/本地模型（本地模型的 API Key 可为空）
+		traderConfig.CustomAPIKey = aiModelCfg.APIKey
 	}
 
 	// 创建trader实例
@@ -1101,19 +1666,73 @@ func (tm *TraderManager) loadSingleTrader(traderCfg *config.TraderRecord, aiMode
 		}
 	}
 
-	tm.traders[traderCfg.ID] = at
+	tm.putTrader(traderCfg.ID, at)
 	log.Printf("✓ Trader '%s' (%s + %s) 已为用户加载到内存", traderCfg.Name, aiModelCfg.Provider, exchangeCfg.ID)
 	return nil
 }
 
+// putTrader 将trader放入内存并更新已加载数量指标，调用方需持有 tm.mu 写锁
+func (tm *TraderManager) putTrader(id string, at *trader.AutoTrader) {
+	old, exists := tm.traders[id]
+	if exists && old != nil {
+		metrics.TradersLoaded.Dec(old.GetUserID())
+	}
+	tm.traders[id] = at
+	metrics.TradersLoaded.Inc(at.GetUserID())
+	if old != at {
+		tm.forwardEvents(at)
+	}
+}
+
 // RemoveTrader 从内存中移除指定的trader（不影响数据库）
 // 用于更新trader配置时强制重新加载
 func (tm *TraderManager) RemoveTrader(traderID string) {
 	tm.mu.Lock()
 	defer tm.mu.Unlock()
 
-	if _, exists := tm.traders[traderID]; exists {
+	if at, exists := tm.traders[traderID]; exists {
+		// 断开该交易员的所有实时事件订阅（WebSocket）
+		if at != nil {
+			at.CloseEvents()
+		}
 		delete(tm.traders, traderID)
+		if at != nil {
+			metrics.TradersLoaded.Dec(at.GetUserID())
+		}
 		log.Printf("✓ Trader %s 已从内存中移除", traderID)
 	}
 }
+
+// findExchangeConfig 在用户交易所列表中查找交易员使用的交易所账户配置（accountID 为空时使用默认账户）
+// 模拟盘不需要用户配置交易所记录，未找到时返回虚拟配置
+func findExchangeConfig(exchanges []*config.ExchangeConfig, userID, exchangeID, accountID string) *config.ExchangeConfig {
+	if exchange := config.FindExchangeAccount(exchanges, exchangeID, accountID); exchange != nil {
+		return exchange
+	}
+	if exchangeID == config.PaperExchangeID {
+		return config.PaperExchangeConfig(userID)
+	}
+	return nil
+}
+
+// traderGuardrails 解析交易员保存的护栏规则（格式错误时记录日志并不启用护栏）
+func traderGuardrails(traderCfg *config.TraderRecord) []trader.GuardrailConfig {
+	guardrails, err := trader.ParseGuardrails(traderCfg.Guardrails)
+	if err != nil {
+		log.Printf("⚠️ 交易员 %s 的护栏规则无法解析，已忽略: %v", traderCfg.Name, err)
+		return nil
+	}
+	return guardrails
+}
+
+// signalSourceOptions 读取用户信号源的请求头和超时（未配置时返回空）
+func signalSourceOptions(database *config.Database, userID string) (map[string]string, time.Duration) {
+	if database == nil {
+		return nil, 0
+	}
+	source, err := database.GetUserSignalSource(userID)
+	if err != nil {
+		return nil, 0
+	}
+	return source.Headers, time.Duration(source.TimeoutSeconds) * time.Second
+}
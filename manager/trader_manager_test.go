@@ -1,7 +1,16 @@
 package manager
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"nofx/config"
+	"nofx/trader"
 )
 
 // TestRemoveTrader 测试从内存中移除trader
@@ -85,3 +94,726 @@ func TestGetTrader_AfterRemove(t *testing.T) {
 		t.Error("获取已移除的 trader 应该返回错误")
 	}
 }
+
+// fakeStoppableTrader 模拟交易员，Stop 会阻塞 stopDelay 模拟进行中的决策周期
+type fakeStoppableTrader struct {
+	id        string
+	running   bool
+	stopDelay time.Duration
+	stopped   chan struct{}
+}
+
+func newFakeStoppableTrader(id string, running bool, stopDelay time.Duration) *fakeStoppableTrader {
+	return &fakeStoppableTrader{id: id, running: running, stopDelay: stopDelay, stopped: make(chan struct{})}
+}
+
+func (f *fakeStoppableTrader) GetID() string     { return f.id }
+func (f *fakeStoppableTrader) GetName() string   { return "fake-" + f.id }
+func (f *fakeStoppableTrader) GetUserID() string { return "user-" + f.id }
+func (f *fakeStoppableTrader) IsRunning() bool   { return f.running }
+func (f *fakeStoppableTrader) Stop() {
+	time.Sleep(f.stopDelay)
+	close(f.stopped)
+}
+
+// fakeStatusStore 记录持久化的运行状态
+type fakeStatusStore struct {
+	mu     sync.Mutex
+	status map[string]bool
+}
+
+func (s *fakeStatusStore) UpdateTraderStatus(userID, id string, isRunning bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status[id] = isRunning
+	return nil
+}
+
+// TestStopTraders_WaitsForInFlightCycles 测试在截止时间内等待所有交易员停止，并保留运行状态
+func TestStopTraders_WaitsForInFlightCycles(t *testing.T) {
+	fast := newFakeStoppableTrader("fast", true, 10*time.Millisecond)
+	idle := newFakeStoppableTrader("idle", false, 0)
+	store := &fakeStatusStore{status: make(map[string]bool)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	abandoned := stopTraders(ctx, []stoppableTrader{fast, idle}, store)
+
+	if len(abandoned) != 0 {
+		t.Errorf("不应有放弃等待的交易员: %v", abandoned)
+	}
+	select {
+	case <-fast.stopped:
+	default:
+		t.Error("运行中的交易员应已停止")
+	}
+	if !store.status["fast"] {
+		t.Error("运行中的交易员应保留 is_running=true 以便重启后恢复")
+	}
+	if _, ok := store.status["idle"]; ok {
+		t.Error("未运行的交易员不应写入状态")
+	}
+}
+
+// TestStopTraders_AbandonsSlowTraderAtDeadline 测试超过截止时间的交易员被放弃等待
+func TestStopTraders_AbandonsSlowTraderAtDeadline(t *testing.T) {
+	fast := newFakeStoppableTrader("fast", true, 0)
+	slow := newFakeStoppableTrader("slow", true, 2*time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	abandoned := stopTraders(ctx, []stoppableTrader{fast, slow}, nil)
+	elapsed := time.Since(start)
+
+	if elapsed > time.Second {
+		t.Errorf("应在截止时间后立即返回，实际耗时 %v", elapsed)
+	}
+	if len(abandoned) != 1 || abandoned[0] != "slow" {
+		t.Errorf("abandoned = %v, want [slow]", abandoned)
+	}
+}
+
+// setupResumeTestDB 创建包含一个标记为运行中、但AI模型未启用（无法加载）的交易员的数据库
+func setupResumeTestDB(t *testing.T) *config.Database {
+	db, err := config.NewDatabase(t.TempDir() + "/test.db")
+	if err != nil {
+		t.Fatalf("创建测试数据库失败: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.CreateUser(&config.User{ID: "user-a", Email: "a@test.com", PasswordHash: "hash"}); err != nil {
+		t.Fatalf("创建用户失败: %v", err)
+	}
+	for _, id := range []string{"broken-1", "broken-2"} {
+		tr := &config.TraderRecord{ID: id, UserID: "user-a", Name: id, AIModelID: "deepseek", ExchangeID: "binance", IsRunning: true}
+		if err := db.CreateTrader(tr); err != nil {
+			t.Fatalf("创建交易员失败: %v", err)
+		}
+	}
+	if err := db.CreateTrader(&config.TraderRecord{ID: "stopped", UserID: "user-a", Name: "stopped", AIModelID: "deepseek", ExchangeID: "binance"}); err != nil {
+		t.Fatalf("创建交易员失败: %v", err)
+	}
+	return db
+}
+
+// TestResumeRunningTraders_ClearsFlagOnLoadFailure 测试无法加载的交易员被清除运行标记，不会每次重启都重复失败
+func TestResumeRunningTraders_ClearsFlagOnLoadFailure(t *testing.T) {
+	db := setupResumeTestDB(t)
+	tm := NewTraderManager()
+
+	if resumed := tm.ResumeRunningTraders(context.Background(), db, 0); resumed != 0 {
+		t.Errorf("resumed = %d, want 0", resumed)
+	}
+
+	traders, err := db.GetTraders("user-a")
+	if err != nil {
+		t.Fatalf("获取交易员失败: %v", err)
+	}
+	for _, tr := range traders {
+		if tr.IsRunning {
+			t.Errorf("交易员 %s 加载失败后应清除运行标记", tr.ID)
+		}
+	}
+}
+
+// TestResumeRunningTraders_StopsOnCancel 测试取消后不再启动剩余的交易员
+func TestResumeRunningTraders_StopsOnCancel(t *testing.T) {
+	db := setupResumeTestDB(t)
+	tm := NewTraderManager()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	tm.ResumeRunningTraders(ctx, db, time.Hour)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("取消后应立即返回，实际耗时 %v", elapsed)
+	}
+
+	traders, _ := db.GetTraders("user-a")
+	running := 0
+	for _, tr := range traders {
+		if tr.IsRunning {
+			running++
+		}
+	}
+	if running != 2 {
+		t.Errorf("取消后未处理的交易员应保留运行标记，running = %d, want 2", running)
+	}
+}
+
+// fakeRunStore 记录持久化的运行状态和异常原因
+type fakeRunStore struct {
+	fakeStatusStore
+	lastError map[string]string
+}
+
+func newFakeRunStore() *fakeRunStore {
+	return &fakeRunStore{fakeStatusStore: fakeStatusStore{status: map[string]bool{}}, lastError: map[string]string{}}
+}
+
+func (s *fakeRunStore) UpdateTraderLastError(userID, id, lastError string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastError[id] = lastError
+	return nil
+}
+
+// fakeSupervisedTrader 按顺序返回预设的运行结果
+type fakeSupervisedTrader struct {
+	id       string
+	results  []error
+	runs     int
+	restarts []int
+	ended    bool
+}
+
+func (f *fakeSupervisedTrader) GetID() string     { return f.id }
+func (f *fakeSupervisedTrader) GetName() string   { return "fake-" + f.id }
+func (f *fakeSupervisedTrader) GetUserID() string { return "user-" + f.id }
+func (f *fakeSupervisedTrader) Run() error {
+	f.runs++
+	if f.runs > len(f.results) {
+		return nil
+	}
+	return f.results[f.runs-1]
+}
+func (f *fakeSupervisedTrader) BeginSupervision() <-chan struct{}            { return make(chan struct{}) }
+func (f *fakeSupervisedTrader) EndSupervision(stopRequested <-chan struct{}) { f.ended = true }
+func (f *fakeSupervisedTrader) RecordRunFailure(err error, restarts int) {
+	f.restarts = append(f.restarts, restarts)
+}
+
+// TestRestartPolicy_Backoff 测试指数退避及上限
+func TestRestartPolicy_Backoff(t *testing.T) {
+	p := restartPolicy{BaseDelay: 10 * time.Second, MaxDelay: 10 * time.Minute}
+	for failures, want := range map[int]time.Duration{
+		1:  10 * time.Second,
+		2:  20 * time.Second,
+		3:  40 * time.Second,
+		6:  320 * time.Second,
+		7:  10 * time.Minute,
+		50: 10 * time.Minute,
+	} {
+		if got := p.backoff(failures); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", failures, got, want)
+		}
+	}
+}
+
+// TestSuperviseTrader_RestartsUntilNormalStop 测试异常退出后重启，正常停止后退出监督
+func TestSuperviseTrader_RestartsUntilNormalStop(t *testing.T) {
+	at := &fakeSupervisedTrader{id: "t1", results: []error{errors.New("panic: boom"), errors.New("panic: boom")}}
+	store := newFakeRunStore()
+	policy := restartPolicy{BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond, MaxFailures: 5}
+
+	superviseTrader(at, make(chan struct{}), store, policy, func() bool { return true })
+
+	if at.runs != 3 {
+		t.Errorf("Run 调用次数 = %d, want 3", at.runs)
+	}
+	if len(at.restarts) != 2 || at.restarts[1] != 2 {
+		t.Errorf("连续失败计数 = %v, want [1 2]", at.restarts)
+	}
+	if store.lastError["t1"] != "panic: boom" {
+		t.Errorf("last_error = %q", store.lastError["t1"])
+	}
+	if _, ok := store.status["t1"]; ok {
+		t.Error("未放弃时不应修改运行标记")
+	}
+	if !at.ended {
+		t.Error("退出时应结束监督")
+	}
+}
+
+// TestSuperviseTrader_AIAuthFailureStops 测试AI认证失败时记录原因、清除运行标记且不自动重启
+func TestSuperviseTrader_AIAuthFailureStops(t *testing.T) {
+	failure := fmt.Errorf("%w: API Key已被撤销", trader.ErrAIAuthFailed)
+	at := &fakeSupervisedTrader{id: "t1", results: []error{failure, nil}}
+	store := newFakeRunStore()
+	store.status["t1"] = true
+	policy := restartPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxFailures: 5}
+
+	superviseTrader(at, make(chan struct{}), store, policy, nil)
+
+	if at.runs != 1 {
+		t.Errorf("Run 调用次数 = %d, want 1", at.runs)
+	}
+	if store.status["t1"] {
+		t.Error("认证失败后应清除运行标记")
+	}
+	if store.lastError["t1"] != failure.Error() {
+		t.Errorf("last_error = %q, want %q", store.lastError["t1"], failure.Error())
+	}
+}
+
+// TestSuperviseTrader_GivesUpAfterMaxFailures 测试连续失败达到上限后放弃并清除运行标记
+func TestSuperviseTrader_GivesUpAfterMaxFailures(t *testing.T) {
+	failure := errors.New("交易员运行时panic: nil map")
+	at := &fakeSupervisedTrader{id: "t1", results: []error{failure, failure, failure, failure}}
+	store := newFakeRunStore()
+	store.status["t1"] = true
+	policy := restartPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, MaxFailures: 3}
+
+	superviseTrader(at, make(chan struct{}), store, policy, nil)
+
+	if at.runs != 3 {
+		t.Errorf("Run 调用次数 = %d, want 3", at.runs)
+	}
+	if store.status["t1"] {
+		t.Error("放弃重启后应清除运行标记")
+	}
+	if store.lastError["t1"] != failure.Error() {
+		t.Errorf("last_error = %q, want %q", store.lastError["t1"], failure.Error())
+	}
+}
+
+// TestSuperviseTrader_StopCancelsPendingRestart 测试退避等待期间手动停止会取消重启
+func TestSuperviseTrader_StopCancelsPendingRestart(t *testing.T) {
+	at := &fakeSupervisedTrader{id: "t1", results: []error{errors.New("boom")}}
+	stopRequested := make(chan struct{})
+	policy := restartPolicy{BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	done := make(chan struct{})
+	go func() {
+		superviseTrader(at, stopRequested, newFakeRunStore(), policy, nil)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(stopRequested)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("手动停止后监督者应退出")
+	}
+	if at.runs != 1 {
+		t.Errorf("Run 调用次数 = %d, want 1", at.runs)
+	}
+}
+
+// TestSuperviseTrader_SkipsReplacedTrader 测试交易员被移除或替换后不再重启旧实例
+func TestSuperviseTrader_SkipsReplacedTrader(t *testing.T) {
+	at := &fakeSupervisedTrader{id: "t1", results: []error{errors.New("boom")}}
+	policy := restartPolicy{BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+	superviseTrader(at, make(chan struct{}), newFakeRunStore(), policy, func() bool { return false })
+
+	if at.runs != 1 {
+		t.Errorf("Run 调用次数 = %d, want 1", at.runs)
+	}
+}
+
+// TestForEachBounded_LimitsConcurrency 测试并发数不超过上限且所有任务都执行
+func TestForEachBounded_LimitsConcurrency(t *testing.T) {
+	var active, peak, done int32
+	forEachBounded(20, 3, func(i int) {
+		n := atomic.AddInt32(&active, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&active, -1)
+		atomic.AddInt32(&done, 1)
+	})
+
+	if done != 20 {
+		t.Errorf("完成任务数 = %d, want 20", done)
+	}
+	if peak > 3 {
+		t.Errorf("最大并发 = %d, want <= 3", peak)
+	}
+}
+
+// TestCompetitionCache_ServesStaleWhileRefreshing 测试缓存过期后先返回旧数据并只触发一次后台刷新
+func TestCompetitionCache_ServesStaleWhileRefreshing(t *testing.T) {
+	cc := &CompetitionCache{ttl: time.Hour}
+	var calls int32
+	release := make(chan struct{})
+	fetch := func() map[string]interface{} {
+		n := atomic.AddInt32(&calls, 1)
+		if n > 1 {
+			<-release
+		}
+		return map[string]interface{}{"version": int(n)}
+	}
+
+	if got := cc.get(fetch)["version"]; got != 1 {
+		t.Fatalf("首次获取 version = %v, want 1", got)
+	}
+	if got := cc.get(fetch)["version"]; got != 1 || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("有效期内应命中缓存: version=%v calls=%d", got, calls)
+	}
+
+	cc.mu.Lock()
+	cc.timestamp = time.Now().Add(-2 * time.Hour)
+	cc.mu.Unlock()
+	for i := 0; i < 5; i++ {
+		if got := cc.get(fetch)["version"]; got != 1 {
+			t.Fatalf("刷新期间应返回旧数据，实际 version = %v", got)
+		}
+	}
+
+	close(release)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		cc.mu.RLock()
+		version := cc.data["version"]
+		cc.mu.RUnlock()
+		if version == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := cc.get(fetch)["version"]; got != 2 {
+		t.Errorf("后台刷新后 version = %v, want 2", got)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Errorf("fetch 调用次数 = %d, want 2", n)
+	}
+}
+
+// TestCompetitionCache_ColdCallersShareFetch 测试无缓存时并发请求只触发一次获取
+func TestCompetitionCache_ColdCallersShareFetch(t *testing.T) {
+	cc := &CompetitionCache{ttl: time.Hour}
+	var calls int32
+	fetch := func() map[string]interface{} {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return map[string]interface{}{"count": 0}
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if data := cc.get(fetch); data == nil {
+				t.Error("应返回数据")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("fetch 调用次数 = %d, want 1", n)
+	}
+}
+
+// TestCompetitionCache_InvalidateDiscardsInflightRefresh 测试清空缓存后丢弃清空前发起的刷新结果
+func TestCompetitionCache_InvalidateDiscardsInflightRefresh(t *testing.T) {
+	cc := &CompetitionCache{ttl: time.Hour}
+	var calls int32
+	release := make(chan struct{})
+	fetch := func() map[string]interface{} {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			<-release
+		}
+		return map[string]interface{}{"version": int(n)}
+	}
+
+	result := make(chan map[string]interface{}, 1)
+	go func() { result <- cc.get(fetch) }()
+	for atomic.LoadInt32(&calls) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cc.invalidate()
+	close(release)
+
+	if got := (<-result)["version"]; got != 2 {
+		t.Errorf("清空缓存后应返回新的刷新结果，实际 version = %v", got)
+	}
+}
+
+// fakeEquityStore 记录写入的权益快照
+type fakeEquityStore struct {
+	saved []*config.EquitySnapshot
+}
+
+func (f *fakeEquityStore) SaveEquitySnapshots(snapshots []*config.EquitySnapshot) error {
+	f.saved = append(f.saved, snapshots...)
+	return nil
+}
+
+func (f *fakeEquityStore) CompactEquitySnapshots(before time.Time) (int64, error) { return 0, nil }
+
+// fakeEquitySampler 返回固定账户信息的交易员
+type fakeEquitySampler struct {
+	fakeStoppableTrader
+	account map[string]interface{}
+	err     error
+}
+
+func (f *fakeEquitySampler) GetAccountInfo() (map[string]interface{}, error) {
+	return f.account, f.err
+}
+
+// TestTakeEquitySnapshots_OnlyRunningTraders 测试只采样运行中的交易员，单个失败不影响其他交易员
+func TestTakeEquitySnapshots_OnlyRunningTraders(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	samplers := []equitySampler{
+		&fakeEquitySampler{
+			fakeStoppableTrader: fakeStoppableTrader{id: "a", running: true},
+			account:             map[string]interface{}{"total_equity": 1050.5, "wallet_balance": 1000.0, "position_count": 2},
+		},
+		&fakeEquitySampler{
+			fakeStoppableTrader: fakeStoppableTrader{id: "b", running: false},
+			account:             map[string]interface{}{"total_equity": 999.0},
+		},
+		&fakeEquitySampler{
+			fakeStoppableTrader: fakeStoppableTrader{id: "c", running: true},
+			err:                 errors.New("交易所不可用"),
+		},
+	}
+
+	store := &fakeEquityStore{}
+	if n := takeEquitySnapshots(samplers, store, now); n != 1 {
+		t.Fatalf("应保存1条快照，实际 %d", n)
+	}
+	s := store.saved[0]
+	if s.TraderID != "a" || s.UserID != "user-a" || !s.Timestamp.Equal(now) {
+		t.Errorf("快照归属不正确: %+v", s)
+	}
+	if s.TotalEquity != 1050.5 || s.WalletBalance != 1000 || s.PositionCount != 2 || s.Resolution != config.EquitySnapshotRaw {
+		t.Errorf("快照字段不正确: %+v", s)
+	}
+}
+
+// fakeCyclingTrader 模拟运行中的交易员：后台goroutine持续执行周期，Stop 等待goroutine退出
+type fakeCyclingTrader struct {
+	fakeStoppableTrader
+	cycles atomic.Int64
+	stopCh chan struct{}
+	done   chan struct{}
+}
+
+func newFakeCyclingTrader(id string) *fakeCyclingTrader {
+	f := &fakeCyclingTrader{
+		fakeStoppableTrader: fakeStoppableTrader{id: id, running: true},
+		stopCh:              make(chan struct{}),
+		done:                make(chan struct{}),
+	}
+	go func() {
+		defer close(f.done)
+		for {
+			select {
+			case <-f.stopCh:
+				return
+			case <-time.After(time.Millisecond):
+				f.cycles.Add(1)
+			}
+		}
+	}()
+	return f
+}
+
+func (f *fakeCyclingTrader) Stop() {
+	f.running = false
+	close(f.stopCh)
+	<-f.done
+}
+
+// TestReplaceTrader_NoOrphanedCycles 测试重启序列：旧实例退出后才写入新配置，之后不再执行任何周期
+func TestReplaceTrader_NoOrphanedCycles(t *testing.T) {
+	old := newFakeCyclingTrader("a")
+	time.Sleep(5 * time.Millisecond)
+
+	var cyclesAtUpdate int64
+	var started bool
+	err := replaceTrader(old,
+		func() error {
+			select {
+			case <-old.done:
+			default:
+				t.Error("写入新配置时旧实例仍在运行")
+			}
+			cyclesAtUpdate = old.cycles.Load()
+			return nil
+		},
+		func(start bool) error {
+			started = start
+			return nil
+		},
+		func() { t.Error("更新成功时不应恢复旧实例") },
+	)
+	if err != nil {
+		t.Fatalf("重启失败: %v", err)
+	}
+	if !started {
+		t.Error("原本运行中的交易员应在重新加载后启动")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	if n := old.cycles.Load(); n != cyclesAtUpdate {
+		t.Errorf("旧实例在更新后仍执行了 %d 个周期", n-cyclesAtUpdate)
+	}
+}
+
+// TestReplaceTrader_RestoreOnUpdateFailure 测试写入失败时不重新加载并恢复运行旧实例
+func TestReplaceTrader_RestoreOnUpdateFailure(t *testing.T) {
+	old := newFakeCyclingTrader("a")
+	restored, reloaded := false, false
+	err := replaceTrader(old,
+		func() error { return errors.New("数据库不可用") },
+		func(bool) error { reloaded = true; return nil },
+		func() { restored = true },
+	)
+	if err == nil {
+		t.Fatal("更新失败时应返回错误")
+	}
+	if !restored || reloaded {
+		t.Errorf("restored=%v reloaded=%v, want true/false", restored, reloaded)
+	}
+
+	// 未运行的交易员直接更新并重新加载，不需要启动
+	idle := newFakeStoppableTrader("b", false, 0)
+	var startArg = true
+	if err := replaceTrader(idle, func() error { return nil }, func(start bool) error { startArg = start; return nil }, func() {}); err != nil {
+		t.Fatalf("更新未运行的交易员失败: %v", err)
+	}
+	if startArg {
+		t.Error("未运行的交易员重新加载后不应启动")
+	}
+}
+
+// TestDeleteTrader_StopsBeforeDelete 测试删除顺序：停止并等待退出 → 从内存移除 → 删除记录
+func TestDeleteTrader_StopsBeforeDelete(t *testing.T) {
+	at := newFakeCyclingTrader("a")
+	var steps []string
+	err := deleteTrader(at,
+		func() {
+			select {
+			case <-at.done:
+			default:
+				t.Error("移除时旧实例仍在运行")
+			}
+			steps = append(steps, "unregister")
+		},
+		func() error {
+			steps = append(steps, "delete")
+			return nil
+		},
+		func(bool) { t.Error("删除成功时不应恢复") },
+	)
+	if err != nil {
+		t.Fatalf("删除失败: %v", err)
+	}
+	if len(steps) != 2 || steps[0] != "unregister" || steps[1] != "delete" {
+		t.Errorf("删除步骤顺序不正确: %v", steps)
+	}
+
+	// 数据库删除失败时恢复并重新启动原本运行的交易员
+	at = newFakeCyclingTrader("b")
+	restartOnRestore := false
+	err = deleteTrader(at, func() {}, func() error { return errors.New("数据库不可用") }, func(start bool) { restartOnRestore = start })
+	if err == nil || !restartOnRestore {
+		t.Errorf("删除失败时应返回错误并恢复运行: err=%v restart=%v", err, restartOnRestore)
+	}
+}
+
+// TestExcludePaperTraders 测试真实资金排行榜排除模拟盘和测试网交易员且不修改缓存数据
+func TestExcludePaperTraders(t *testing.T) {
+	competition := map[string]interface{}{
+		"traders": []map[string]interface{}{
+			{"trader_id": "paper-1", "is_paper": true},
+			{"trader_id": "real-1", "is_paper": false},
+			{"trader_id": "real-2"},
+			{"trader_id": "testnet-1", "testnet": true},
+		},
+		"count":       4,
+		"total_count": 60,
+		"paper_count": 12,
+	}
+
+	result := ExcludePaperTraders(competition)
+	traders := result["traders"].([]map[string]interface{})
+	if len(traders) != 2 || traders[0]["trader_id"] != "real-1" {
+		t.Fatalf("应只保留真实资金交易员: %+v", traders)
+	}
+	if result["count"] != 2 || result["total_count"] != 48 || result["paper_count"] != 0 {
+		t.Errorf("统计数量不正确: count=%v total=%v paper=%v", result["count"], result["total_count"], result["paper_count"])
+	}
+	if len(competition["traders"].([]map[string]interface{})) != 4 || competition["count"] != 4 {
+		t.Error("不应修改原竞赛数据")
+	}
+}
+
+// TestFindExchangeConfig_PaperFallback 测试按账户查找交易所配置，模拟盘交易员无需交易所记录
+func TestFindExchangeConfig_PaperFallback(t *testing.T) {
+	exchanges := []*config.ExchangeConfig{
+		{ID: "binance", AccountID: config.DefaultExchangeAccountID, Enabled: true},
+		{ID: "binance", AccountID: "binance_sub", Enabled: true},
+	}
+
+	if cfg := findExchangeConfig(exchanges, "user-1", "binance", ""); cfg != exchanges[0] {
+		t.Errorf("未指定账户时应返回默认账户")
+	}
+	if cfg := findExchangeConfig(exchanges, "user-1", "binance", "binance_sub"); cfg != exchanges[1] {
+		t.Errorf("应返回交易员指定的账户")
+	}
+	if cfg := findExchangeConfig(exchanges, "user-1", "binance", "binance_deleted"); cfg != nil {
+		t.Errorf("不存在的账户应返回 nil")
+	}
+	if cfg := findExchangeConfig(exchanges, "user-1", "aster", ""); cfg != nil {
+		t.Errorf("未配置的真实交易所应返回 nil")
+	}
+	cfg := findExchangeConfig(nil, "user-1", config.PaperExchangeID, "")
+	if cfg == nil || cfg.ID != config.PaperExchangeID || !cfg.Enabled || cfg.UserID != "user-1" {
+		t.Errorf("模拟盘应返回虚拟交易所配置: %+v", cfg)
+	}
+}
+
+// TestConsumeEvents_ResubscribesAfterSlowDisconnect 处理过慢被事件总线断开后重新订阅，之后的事件继续处理；总线关闭时退出
+func TestConsumeEvents_ResubscribesAfterSlowDisconnect(t *testing.T) {
+	bus := trader.NewEventBus()
+	sub := bus.Subscribe(1)
+	release := make(chan struct{})
+	handled := make(chan string, 8)
+	done := make(chan struct{})
+	go func() {
+		consumeEvents(bus, sub, "test", func(event trader.TraderEvent) {
+			if event.Type == "slow" {
+				<-release
+			}
+			handled <- event.Type
+		})
+		close(done)
+	}()
+
+	// 第一个事件阻塞处理，后续事件填满缓冲区，订阅被断开
+	bus.Publish(trader.TraderEvent{Type: "slow"})
+	deadline := time.Now().Add(2 * time.Second)
+	for bus.SubscriberCount() == 1 && time.Now().Before(deadline) {
+		bus.Publish(trader.TraderEvent{Type: "dropped"})
+	}
+	close(release)
+
+	deadline = time.Now().Add(2 * time.Second)
+	for bus.SubscriberCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	bus.Publish(trader.TraderEvent{Type: "after"})
+	for {
+		select {
+		case eventType := <-handled:
+			if eventType != "after" {
+				continue
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("断开后重新订阅的事件应继续处理")
+		}
+		break
+	}
+
+	bus.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("事件总线关闭后应退出")
+	}
+}
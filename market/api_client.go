@@ -11,9 +11,8 @@ import (
 	"time"
 )
 
-const (
-	baseURL = "https://fapi.binance.com"
-)
+// baseURL 币安合约REST接口地址
+var baseURL = "https://fapi.binance.com"
 
 type APIClient struct {
 	client *http.Client
@@ -21,7 +20,8 @@ type APIClient struct {
 
 func NewAPIClient() *APIClient {
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: restTransport(),
 	}
 
 	hookRes := hook.HookExec[hook.SetHttpClientResult](hook.SET_HTTP_CLIENT, client)
@@ -69,16 +69,23 @@ func (c *APIClient) GetKlines(symbol, interval string, limit int) ([]Kline, erro
 	q.Add("limit", strconv.Itoa(limit))
 	req.URL.RawQuery = q.Encode()
 
+	if err := restLimiter.wait(klineRequestWeight(limit)); err != nil {
+		return nil, err
+	}
 	resp, err := c.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
+	restLimiter.observe(resp)
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取K线失败 (HTTP %d): %s", resp.StatusCode, string(body))
+	}
 
 	var klineResponses []KlineResponse
 	err = json.Unmarshal(body, &klineResponses)
@@ -158,3 +165,32 @@ func (c *APIClient) GetCurrentPrice(symbol string) (float64, error) {
 
 	return price, nil
 }
+
+// GetMarkPrice 获取合约标记价格（模拟盘按标记价格成交和计算未实现盈亏）
+func (c *APIClient) GetMarkPrice(symbol string) (float64, error) {
+	url := fmt.Sprintf("%s/fapi/v1/premiumIndex?symbol=%s", baseURL, symbol)
+	resp, err := c.client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Symbol    string `json:"symbol"`
+		MarkPrice string `json:"markPrice"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, err
+	}
+
+	price, err := strconv.ParseFloat(result.MarkPrice, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析标记价格失败: %w", err)
+	}
+	return price, nil
+}
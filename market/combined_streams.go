@@ -1,82 +1,199 @@
 package market
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+)
 
-	"github.com/gorilla/websocket"
+const (
+	// combinedStreamEndpoint 币安合约组合流端点
+	combinedStreamEndpoint = "wss://fstream.binance.com/stream"
+	// wsReadTimeout 超过该时间没有收到任何消息（包括pong）视为连接失效
+	wsReadTimeout = 30 * time.Second
+	// wsPingInterval 主动发送ping的间隔（需明显小于读取超时）
+	wsPingInterval = 10 * time.Second
+	// wsWriteTimeout 单次写入（订阅、ping、pong、关闭帧）的超时时间
+	wsWriteTimeout = 10 * time.Second
+	// reconnectBaseDelay 首次重连前的等待时间，之后每次失败翻倍
+	reconnectBaseDelay = time.Second
+	// reconnectMaxDelay 重连等待时间上限
+	reconnectMaxDelay = time.Minute
+	// defaultMaxStreamsPerConnection 单个连接订阅的流数量上限（币安上限为1024，留出余量并分摊消息量）
+	defaultMaxStreamsPerConnection = 200
+	// subscribeAckTimeout 等待订阅确认（{"result":null,"id":...}）的超时时间，超时后重发一次
+	subscribeAckTimeout = 10 * time.Second
 )
 
+// CombinedStreamsClient 币安组合流客户端，订阅的流超过单连接上限时自动分布到多个连接（分片）
+// 分片对调用方透明：消息按流名称路由到订阅者，每个分片独立保活和重连
 type CombinedStreamsClient struct {
-	conn              *websocket.Conn
-	mu                sync.RWMutex
+	mu                sync.RWMutex // 保护 subscribers、shards、shardOf 以及各分片的流列表
 	subscribers       map[string]chan []byte
-	reconnect         bool
-	done              chan struct{}
-	batchSize         int      // 每批订阅的流数量
-	subscribedStreams []string // 记录已订阅的流，用于重连后恢复
+	shards            []*streamShard
+	shardOf           map[string]*streamShard // 流 -> 所在分片
+	nextShardID       int
+	started           bool            // Connect 成功后才允许订阅（新建的分片立即连接）
+	ctx               context.Context // Close 时取消，停止读取、保活和重连
+	cancel            context.CancelFunc
+	closeOnce         sync.Once
+	endpoint          string
+	readTimeout       time.Duration
+	pingInterval      time.Duration
+	reconnectBase     time.Duration
+	reconnectMax      time.Duration
+	batchSize         int                     // 每批订阅的流数量
+	maxStreamsPerConn int                     // 单个连接的流数量上限
+	onReconnect       func(streams []string)  // 重连成功后的回调
+	lastMessageAt     atomic.Int64            // 最近一次收到消息的时间（UnixNano），用于健康检查
+	stats             map[string]*streamStats // 各流的消息统计（随订阅者添加和移除）
+	messages          atomic.Uint64
+	dropped           atomic.Uint64
+	reconnects        atomic.Uint64
+	ackTimeout        time.Duration            // 等待订阅确认的超时时间
+	requestID         atomic.Int64             // SUBSCRIBE/UNSUBSCRIBE 请求编号
+	pendingMu         sync.Mutex               // 保护 pending
+	pending           map[int64]*streamRequest // 等待响应的请求
 }
 
 func NewCombinedStreamsClient(batchSize int) *CombinedStreamsClient {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &CombinedStreamsClient{
 		subscribers:       make(map[string]chan []byte),
-		reconnect:         true,
-		done:              make(chan struct{}),
+		stats:             make(map[string]*streamStats),
+		shardOf:           make(map[string]*streamShard),
+		ctx:               ctx,
+		cancel:            cancel,
+		endpoint:          combinedStreamEndpoint,
+		readTimeout:       wsReadTimeout,
+		pingInterval:      wsPingInterval,
+		reconnectBase:     reconnectBaseDelay,
+		reconnectMax:      reconnectMaxDelay,
 		batchSize:         batchSize,
-		subscribedStreams: make([]string, 0),
+		maxStreamsPerConn: defaultMaxStreamsPerConnection,
+		ackTimeout:        subscribeAckTimeout,
+		pending:           make(map[int64]*streamRequest),
+	}
+}
+
+// SetMaxStreamsPerConnection 设置单个连接的流数量上限（只影响之后新订阅的流）
+func (c *CombinedStreamsClient) SetMaxStreamsPerConnection(n int) {
+	if n <= 0 {
+		return
 	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxStreamsPerConn = n
+}
+
+// SubscribeStreams 订阅流（分配到未满的连接，需先 Connect）
+func (c *CombinedStreamsClient) SubscribeStreams(streams []string) error {
+	return c.subscribeStreams(streams)
+}
+
+// SetReconnectHandler 设置重连成功后的回调（参数为重连的连接负责的流，用于补齐断线期间的数据）
+func (c *CombinedStreamsClient) SetReconnectHandler(handler func(streams []string)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onReconnect = handler
+}
+
+// reconnectHandler 当前的重连回调
+func (c *CombinedStreamsClient) reconnectHandler() func(streams []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.onReconnect
 }
 
+// Connect 建立第一个连接，之后订阅的流超过单连接上限时按需新建连接
 func (c *CombinedStreamsClient) Connect() error {
-	dialer := websocket.Dialer{
-		HandshakeTimeout: 45 * time.Second, // 增加超时时间以适应代理
-		Proxy:            getProxyFunc(),    // ✅ 添加代理支持
+	c.mu.Lock()
+	if len(c.shards) == 0 {
+		c.shards = append(c.shards, c.newShardLocked())
 	}
+	shards := append([]*streamShard(nil), c.shards...)
+	c.mu.Unlock()
 
-	// 组合流使用不同的端点
-	conn, _, err := dialer.Dial("wss://fstream.binance.com/stream", nil)
-	if err != nil {
-		return fmt.Errorf("组合流WebSocket连接失败: %v", err)
+	var pending []*streamShard
+	var reqs [][]*streamRequest
+	for _, shard := range shards {
+		if shard.currentConn() != nil {
+			continue
+		}
+		shardReqs, err := shard.connect()
+		if err != nil {
+			return err
+		}
+		pending = append(pending, shard)
+		reqs = append(reqs, shardReqs)
 	}
 
 	c.mu.Lock()
-	c.conn = conn
+	c.started = true
 	c.mu.Unlock()
 
-	log.Println("组合流WebSocket连接成功")
-	go c.readMessages()
+	var err error
+	for i, shard := range pending {
+		err = errors.Join(err, shard.confirm(reqs[i]))
+	}
+	return err
+}
 
-	return nil
+// newShardLocked 创建新分片（调用方持有 c.mu）
+func (c *CombinedStreamsClient) newShardLocked() *streamShard {
+	c.nextShardID++
+	return newStreamShard(c, c.nextShardID)
 }
 
-// BatchSubscribeKlines 批量订阅K线
+// errNotConnected Connect 之前或 Close 之后订阅
+var errNotConnected = errors.New("WebSocket未连接")
+
+// subscribeBatchDelay 批次间的订阅间隔，避免触发交易所的订阅频率限制
+const subscribeBatchDelay = 100 * time.Millisecond
+
+// BatchSubscribeKlines 批量订阅K线（等待订阅确认，被拒绝或未确认的流在返回的错误中列出）
 func (c *CombinedStreamsClient) BatchSubscribeKlines(symbols []string, interval string) error {
+	return c.batchSubscribe(symbols, func(symbol string) string {
+		return fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval)
+	})
+}
+
+// batchSubscribe 按 batchSize 分批订阅每个交易对的流（stream 生成流名称）
+// 某一批失败时继续订阅其余批次，最后汇总返回各批的错误
+func (c *CombinedStreamsClient) batchSubscribe(symbols []string, stream func(symbol string) string) error {
 	// 将symbols分批处理
 	batches := c.splitIntoBatches(symbols, c.batchSize)
 
+	var errs error
 	for i, batch := range batches {
 		log.Printf("订阅第 %d 批, 数量: %d", i+1, len(batch))
 
 		streams := make([]string, len(batch))
 		for j, symbol := range batch {
-			streams[j] = fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval)
+			streams[j] = stream(symbol)
 		}
 
 		if err := c.subscribeStreams(streams); err != nil {
-			return fmt.Errorf("第 %d 批订阅失败: %v", i+1, err)
+			if errors.Is(err, errNotConnected) {
+				return fmt.Errorf("第 %d 批订阅失败: %w", i+1, err)
+			}
+			errs = errors.Join(errs, fmt.Errorf("第 %d 批订阅失败: %w", i+1, err))
 		}
 
 		// 批次间延迟，避免被限制
 		if i < len(batches)-1 {
-			time.Sleep(100 * time.Millisecond)
+			time.Sleep(subscribeBatchDelay)
 		}
 	}
 
-	return nil
+	return errs
 }
 
 // splitIntoBatches 将切片分成指定大小的批次
@@ -94,176 +211,284 @@ func (c *CombinedStreamsClient) splitIntoBatches(symbols []string, batchSize int
 	return batches
 }
 
-// subscribeStreams 订阅多个流
+// subscribeStreams 订阅多个流（已订阅的流跳过），新流分配到流数量最少且未满的连接，都已满时新建连接
+// 连接重连期间订阅的流在重连成功后恢复；等待订阅确认，被拒绝的流从订阅列表移除并在错误中列出
 func (c *CombinedStreamsClient) subscribeStreams(streams []string) error {
-	subscribeMsg := map[string]interface{}{
-		"method": "SUBSCRIBE",
-		"params": streams,
-		"id":     time.Now().UnixNano(),
-	}
-
 	c.mu.Lock()
-	if c.conn == nil {
+	if !c.started || c.ctx.Err() != nil {
 		c.mu.Unlock()
-		return fmt.Errorf("WebSocket未连接")
+		return errNotConnected
 	}
 
-	// 记录已订阅的流（用于重连后恢复）
-	c.subscribedStreams = append(c.subscribedStreams, streams...)
-	conn := c.conn
+	groups := make(map[*streamShard][]string)
+	var created []*streamShard
+	for _, stream := range streams {
+		if _, exists := c.shardOf[stream]; exists {
+			continue
+		}
+		shard := c.leastLoadedShardLocked()
+		if shard == nil {
+			shard = c.newShardLocked()
+			c.shards = append(c.shards, shard)
+			created = append(created, shard)
+		}
+		// 记录已订阅的流（用于重连后恢复）
+		shard.streams[stream] = true
+		c.shardOf[stream] = shard
+		groups[shard] = append(groups[shard], stream)
+	}
 	c.mu.Unlock()
 
-	log.Printf("订阅流: %v", streams)
-	return conn.WriteJSON(subscribeMsg)
+	// 新建的分片连接时订阅全部流，连接失败时进入重连循环
+	reqs := make(map[*streamShard][]*streamRequest)
+	for _, shard := range created {
+		log.Printf("🔀 订阅流超过单连接上限，新建组合流连接#%d（%d 个流）", shard.id, len(groups[shard]))
+		shardReqs, err := shard.connect()
+		if err != nil {
+			log.Printf("⚠️  组合流连接#%d 建立失败: %v", shard.id, err)
+			go shard.handleReconnect()
+		}
+		reqs[shard] = shardReqs
+		delete(groups, shard)
+	}
+
+	for shard, group := range groups {
+		conn := shard.currentConn()
+		if conn == nil {
+			continue // 重连成功后恢复
+		}
+		log.Printf("订阅流: %v", group)
+		shardReqs, err := shard.send(conn, "SUBSCRIBE", group)
+		reqs[shard] = shardReqs
+		if err != nil {
+			return err
+		}
+	}
+
+	var err error
+	for shard, shardReqs := range reqs {
+		err = errors.Join(err, shard.confirm(shardReqs))
+	}
+	return err
 }
 
-func (c *CombinedStreamsClient) readMessages() {
-	for {
-		select {
-		case <-c.done:
-			return
-		default:
-			c.mu.RLock()
-			conn := c.conn
-			c.mu.RUnlock()
+// leastLoadedShardLocked 流数量最少且未达上限的分片，都已满时返回 nil（调用方持有 c.mu）
+func (c *CombinedStreamsClient) leastLoadedShardLocked() *streamShard {
+	var best *streamShard
+	for _, shard := range c.shards {
+		if len(shard.streams) >= c.maxStreamsPerConn {
+			continue
+		}
+		if best == nil || len(shard.streams) < len(best.streams) {
+			best = shard
+		}
+	}
+	return best
+}
 
-			if conn == nil {
-				time.Sleep(1 * time.Second)
-				continue
-			}
+// UnsubscribeStreams 取消订阅多个流（按 batchSize 分批发送），重连后不再恢复
+// 未连接时只从已订阅列表中移除；除最后一个连接外，流全部取消的连接被关闭回收
+func (c *CombinedStreamsClient) UnsubscribeStreams(streams []string) error {
+	c.mu.Lock()
+	groups := make(map[*streamShard][]string)
+	for _, stream := range streams {
+		shard, exists := c.shardOf[stream]
+		if !exists {
+			continue
+		}
+		delete(c.shardOf, stream)
+		delete(shard.streams, stream)
+		delete(shard.confirmed, stream)
+		groups[shard] = append(groups[shard], stream)
+	}
+	var emptied []*streamShard
+	for shard := range groups {
+		if len(shard.streams) == 0 && len(c.shards) > 1 {
+			c.removeShardLocked(shard)
+			emptied = append(emptied, shard)
+		}
+	}
+	c.mu.Unlock()
 
-			// ✅ 设置读取超时（60秒），防止静默失败
-			conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-
-			_, message, err := conn.ReadMessage()
-			if err != nil {
-				// 检查是否是超时错误
-				if netErr, ok := err.(interface{ Timeout() bool }); ok && netErr.Timeout() {
-					log.Printf("⚠️  WebSocket 读取超时（60秒无数据），触发重连...")
-				} else {
-					log.Printf("读取组合流消息失败: %v", err)
-				}
-				c.handleReconnect()
-				return
-			}
+	for _, shard := range emptied {
+		log.Printf("🔀 组合流连接#%d 已无订阅，关闭连接", shard.id)
+		shard.close()
+		delete(groups, shard)
+	}
+	for shard, group := range groups {
+		conn := shard.currentConn()
+		if conn == nil {
+			continue
+		}
+		log.Printf("取消订阅流: %v", group)
+		// 不等待取消订阅的确认（流已从列表移除，被拒绝时只记录日志）
+		if _, err := shard.send(conn, "UNSUBSCRIBE", group); err != nil {
+			return fmt.Errorf("取消订阅失败: %w", err)
+		}
+	}
+	return nil
+}
 
-			c.handleCombinedMessage(message)
+// removeShardLocked 从分片列表中移除分片（调用方持有 c.mu）
+func (c *CombinedStreamsClient) removeShardLocked(target *streamShard) {
+	for i, shard := range c.shards {
+		if shard == target {
+			c.shards = append(c.shards[:i], c.shards[i+1:]...)
+			return
 		}
 	}
 }
 
+// ConnectionStreamCounts 各连接订阅的流数量（按连接创建顺序）
+func (c *CombinedStreamsClient) ConnectionStreamCounts() []int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	counts := make([]int, len(c.shards))
+	for i, shard := range c.shards {
+		counts[i] = len(shard.streams)
+	}
+	return counts
+}
+
+// SubscribedStreams 所有已订阅的流（排序后返回）
+func (c *CombinedStreamsClient) SubscribedStreams() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	streams := make([]string, 0, len(c.shardOf))
+	for stream := range c.shardOf {
+		streams = append(streams, stream)
+	}
+	sort.Strings(streams)
+	return streams
+}
+
 func (c *CombinedStreamsClient) handleCombinedMessage(message []byte) {
+	now := time.Now()
+	c.lastMessageAt.Store(now.UnixNano())
+
 	var combinedMsg struct {
 		Stream string          `json:"stream"`
 		Data   json.RawMessage `json:"data"`
+		ID     *int64          `json:"id"`    // 订阅请求的响应
+		Error  *streamError    `json:"error"` // 订阅请求被拒绝
 	}
 
 	if err := json.Unmarshal(message, &combinedMsg); err != nil {
 		log.Printf("解析组合消息失败: %v", err)
 		return
 	}
+	if combinedMsg.Stream == "" {
+		c.handleResponse(combinedMsg.ID, combinedMsg.Error)
+		return
+	}
 
+	// 持有读锁完成非阻塞发送，避免 RemoveSubscriber 同时关闭通道
 	c.mu.RLock()
-	ch, exists := c.subscribers[combinedMsg.Stream]
-	c.mu.RUnlock()
-
-	if exists {
+	defer c.mu.RUnlock()
+	if ch, exists := c.subscribers[combinedMsg.Stream]; exists {
 		select {
 		case ch <- combinedMsg.Data:
+			c.recordMessage(combinedMsg.Stream, now, false)
 		default:
-			log.Printf("订阅者通道已满: %s", combinedMsg.Stream)
+			c.recordMessage(combinedMsg.Stream, now, true)
 		}
 	}
 }
 
-func (c *CombinedStreamsClient) AddSubscriber(stream string, bufferSize int) <-chan []byte {
-	ch := make(chan []byte, bufferSize)
-	c.mu.Lock()
-	c.subscribers[stream] = ch
-	c.mu.Unlock()
-	return ch
+// LastMessageTime 返回最近一次收到消息的时间（从未收到时为零值）
+func (c *CombinedStreamsClient) LastMessageTime() time.Time {
+	ns := c.lastMessageAt.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
 }
 
-func (c *CombinedStreamsClient) handleReconnect() {
-	if !c.reconnect {
-		return
+// LastConnectedTime 返回各连接中最近一次连接成功的时间（从未连接时为零值）
+func (c *CombinedStreamsClient) LastConnectedTime() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var latest int64
+	for _, shard := range c.shards {
+		latest = max(latest, shard.lastConnectedAt.Load())
 	}
+	if latest == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, latest)
+}
 
-	log.Println("组合流尝试重新连接...")
-	time.Sleep(3 * time.Second)
+// IsReconnecting 是否有连接正在重连
+func (c *CombinedStreamsClient) IsReconnecting() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, shard := range c.shards {
+		if shard.reconnecting.Load() {
+			return true
+		}
+	}
+	return false
+}
 
-	if err := c.Connect(); err != nil {
-		log.Printf("组合流重新连接失败: %v", err)
-		go c.handleReconnect()
-		return
+// IsConnected 所有连接是否都处于连接状态
+func (c *CombinedStreamsClient) IsConnected() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.shards) == 0 {
+		return false
+	}
+	for _, shard := range c.shards {
+		if shard.currentConn() == nil {
+			return false
+		}
 	}
+	return true
+}
 
-	// ✅ 重连成功后，重新订阅所有流
+func (c *CombinedStreamsClient) AddSubscriber(stream string, bufferSize int) <-chan []byte {
+	ch := make(chan []byte, bufferSize)
 	c.mu.Lock()
-	// 去重订阅流列表
-	streamSet := make(map[string]bool)
-	for _, stream := range c.subscribedStreams {
-		streamSet[stream] = true
-	}
-	uniqueStreams := make([]string, 0, len(streamSet))
-	for stream := range streamSet {
-		uniqueStreams = append(uniqueStreams, stream)
+	c.subscribers[stream] = ch
+	if _, ok := c.stats[stream]; !ok {
+		c.stats[stream] = &streamStats{}
 	}
 	c.mu.Unlock()
+	return ch
+}
 
-	if len(uniqueStreams) > 0 {
-		log.Printf("🔄 重新订阅 %d 个数据流...", len(uniqueStreams))
-		// 分批重新订阅
-		for i := 0; i < len(uniqueStreams); i += c.batchSize {
-			end := i + c.batchSize
-			if end > len(uniqueStreams) {
-				end = len(uniqueStreams)
-			}
-			batch := uniqueStreams[i:end]
-
-			subscribeMsg := map[string]interface{}{
-				"method": "SUBSCRIBE",
-				"params": batch,
-				"id":     time.Now().UnixNano(),
-			}
-
-			c.mu.RLock()
-			conn := c.conn
-			c.mu.RUnlock()
-
-			if conn != nil {
-				if err := conn.WriteJSON(subscribeMsg); err != nil {
-					log.Printf("⚠️  重新订阅失败: %v", err)
-				} else {
-					log.Printf("✅ 已重新订阅批次 %d/%d", (i/c.batchSize)+1, (len(uniqueStreams)+c.batchSize-1)/c.batchSize)
-				}
-			}
-
-			if i+c.batchSize < len(uniqueStreams) {
-				time.Sleep(100 * time.Millisecond)
-			}
-		}
-		log.Printf("✅ 所有数据流重新订阅完成")
+// RemoveSubscriber 移除并关闭流的订阅者通道（不取消订阅，需配合 UnsubscribeStreams）
+func (c *CombinedStreamsClient) RemoveSubscriber(stream string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch, exists := c.subscribers[stream]; exists {
+		close(ch)
+		delete(c.subscribers, stream)
+		delete(c.stats, stream)
 	}
+}
 
-	// 重新启动读取循环
-	go c.readMessages()
+// SubscriberCount 当前订阅者通道数量
+func (c *CombinedStreamsClient) SubscriberCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.subscribers)
 }
 
+// Close 关闭客户端（各连接发送关闭帧、停止重连并关闭所有订阅者通道），可重复调用
 func (c *CombinedStreamsClient) Close() {
-	c.reconnect = false
-	close(c.done)
+	c.closeOnce.Do(func() {
+		c.cancel()
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+		c.mu.Lock()
+		defer c.mu.Unlock()
 
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
+		for _, shard := range c.shards {
+			shard.close()
+		}
 
-	for stream, ch := range c.subscribers {
-		close(ch)
-		delete(c.subscribers, stream)
-	}
+		for stream, ch := range c.subscribers {
+			close(ch)
+			delete(c.subscribers, stream)
+		}
+	})
 }
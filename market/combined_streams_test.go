@@ -0,0 +1,474 @@
+package market
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestRemoveSubscriber 移除订阅者后通道关闭、订阅者数量减少，与并发消息分发互不影响
+func TestRemoveSubscriber(t *testing.T) {
+	c := NewCombinedStreamsClient(10)
+	ch := c.AddSubscriber("btcusdt@kline_3m", 1)
+	c.AddSubscriber("ethusdt@kline_3m", 1)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			c.handleCombinedMessage([]byte(`{"stream":"btcusdt@kline_3m","data":{}}`))
+		}
+	}()
+	c.RemoveSubscriber("btcusdt@kline_3m")
+	wg.Wait()
+
+	if c.SubscriberCount() != 1 {
+		t.Errorf("订阅者数量应减少为1，实际 %d", c.SubscriberCount())
+	}
+	for range ch {
+	}
+	c.RemoveSubscriber("btcusdt@kline_3m") // 重复移除不应panic
+
+}
+
+// TestReleaseSymbols 交易员停止或币种变更后，没有交易员使用的动态订阅被释放
+func TestReleaseSymbols(t *testing.T) {
+	m := NewWSMonitor(10)
+	defer func() { WSMonitorCli = nil }()
+
+	for _, symbol := range []string{"SOLUSDT", "DOGEUSDT"} {
+		m.klines.Merge(symbol, "3m", []Kline{{Close: 1}})
+		m.trackDynamicStreams(symbol, m.subscribeSymbol(symbol, "3m"))
+	}
+	RetainSymbols("trader-a", []string{"SOLUSDT", "DOGEUSDT"})
+	RetainSymbols("trader-b", []string{"solusdt"})
+	if m.combinedClient.SubscriberCount() != 2 {
+		t.Fatalf("仍被使用的订阅不应释放: %d", m.combinedClient.SubscriberCount())
+	}
+
+	// trader-a 不再交易 DOGE
+	RetainSymbols("trader-a", []string{"SOLUSDT"})
+	if m.combinedClient.SubscriberCount() != 1 {
+		t.Errorf("DOGE 的订阅应释放: %d", m.combinedClient.SubscriberCount())
+	}
+	if _, ok := m.klines.Klines("DOGEUSDT", "3m", 0); ok {
+		t.Error("释放后应清除K线缓存")
+	}
+
+	ReleaseSymbols("trader-a")
+	if m.combinedClient.SubscriberCount() != 1 {
+		t.Error("trader-b 仍在使用 SOL")
+	}
+	ReleaseSymbols("trader-b")
+	if m.combinedClient.SubscriberCount() != 0 {
+		t.Errorf("所有交易员停止后应释放全部动态订阅: %d", m.combinedClient.SubscriberCount())
+	}
+}
+
+// TestCombinedStreamsKeepAlive 假WebSocket服务端：客户端定期ping、回复服务端ping，pong持续延长读取超时，重复关闭不panic
+func TestCombinedStreamsKeepAlive(t *testing.T) {
+	var connections, clientPings, serverPongs atomic.Int32
+	closeFrame := make(chan int, 1)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		connections.Add(1)
+		conn.SetPingHandler(func(data string) error {
+			clientPings.Add(1)
+			return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		conn.SetPongHandler(func(data string) error {
+			if data == "server-ping" {
+				serverPongs.Add(1)
+			}
+			return nil
+		})
+		conn.WriteControl(websocket.PingMessage, []byte("server-ping"), time.Now().Add(time.Second))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if ce, ok := err.(*websocket.CloseError); ok {
+					closeFrame <- ce.Code
+				}
+				return
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c := NewCombinedStreamsClient(10)
+	c.endpoint = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.pingInterval = 20 * time.Millisecond
+	c.readTimeout = 100 * time.Millisecond
+	if err := c.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+
+	// 服务端不推送任何数据，pong 应持续延长读取超时而不触发重连
+	time.Sleep(400 * time.Millisecond)
+	if !c.IsConnected() || connections.Load() != 1 {
+		t.Errorf("收到pong时不应超时重连: connected=%v connections=%d", c.IsConnected(), connections.Load())
+	}
+	if clientPings.Load() < 3 {
+		t.Errorf("客户端应定期发送ping: %d", clientPings.Load())
+	}
+	if serverPongs.Load() != 1 {
+		t.Errorf("客户端应回复服务端的ping: %d", serverPongs.Load())
+	}
+
+	c.Close()
+	c.Close()
+	select {
+	case code := <-closeFrame:
+		if code != websocket.CloseNormalClosure {
+			t.Errorf("关闭帧状态码应为1000: %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Error("服务端应收到关闭帧")
+	}
+	if c.IsConnected() {
+		t.Error("关闭后不应处于连接状态")
+	}
+}
+
+// TestCombinedStreamsReconnect 连接断开后按退避重试（服务端暂时拒绝连接），只有一个重连循环，重连后恢复的订阅不重复
+func TestCombinedStreamsReconnect(t *testing.T) {
+	var attempts, connections atomic.Int32
+	subscribed := make(chan []string, 10)
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := attempts.Add(1)
+		// 第一次连接成功后，接下来两次连接被拒绝
+		if n == 2 || n == 3 {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		id := connections.Add(1)
+		for {
+			var msg struct {
+				Method string   `json:"method"`
+				Params []string `json:"params"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			subscribed <- msg.Params
+			if id == 1 {
+				return // 第一次连接收到订阅后断开
+			}
+		}
+	}))
+	defer srv.Close()
+
+	c := NewCombinedStreamsClient(10)
+	c.endpoint = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.reconnectBase = 10 * time.Millisecond
+	c.reconnectMax = 40 * time.Millisecond
+	defer c.Close()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	firstConnected := c.LastConnectedTime()
+	if err := c.subscribeStreams([]string{"btcusdt@kline_3m", "btcusdt@kline_3m", "ethusdt@kline_3m"}); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	<-subscribed
+
+	select {
+	case streams := <-subscribed:
+		if len(streams) != 2 {
+			t.Errorf("重连后应恢复去重后的2个流: %v", streams)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("未在重连后恢复订阅")
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if attempts.Load() != 4 || connections.Load() != 2 {
+		t.Errorf("应只有一个重连循环: attempts=%d connections=%d", attempts.Load(), connections.Load())
+	}
+	if !c.IsConnected() || c.IsReconnecting() || !c.LastConnectedTime().After(firstConnected) {
+		t.Errorf("重连后状态不正确: connected=%v reconnecting=%v", c.IsConnected(), c.IsReconnecting())
+	}
+	if streams := c.SubscribedStreams(); len(streams) != 2 {
+		t.Errorf("恢复订阅不应改变已订阅列表: %v", streams)
+	}
+}
+
+// TestReconnectDelay 重连等待时间翻倍且不超过上限，抖动在 [0.5, 1.0] 倍之间
+func TestReconnectDelay(t *testing.T) {
+	delay := time.Second
+	for i := 0; i < 10; i++ {
+		delay = nextReconnectDelay(delay, time.Minute)
+	}
+	if delay != time.Minute {
+		t.Errorf("等待时间应封顶为1分钟: %v", delay)
+	}
+	for i := 0; i < 100; i++ {
+		if d := jitterDelay(time.Second); d < 500*time.Millisecond || d > time.Second {
+			t.Fatalf("抖动超出范围: %v", d)
+		}
+	}
+}
+
+// fakeStreamServer 模拟币安组合流：记录每个连接订阅的流并回复确认，能向订阅了某个流的连接推送消息
+type fakeStreamServer struct {
+	*httptest.Server
+	mu     sync.Mutex
+	conns  []*fakeStreamConn
+	reject map[string]bool // 包含这些流的请求返回错误
+	silent map[string]int  // 包含这些流的请求不回复的剩余次数
+}
+
+// fakeStreamConn 假服务端上的一个连接
+type fakeStreamConn struct {
+	conn    *websocket.Conn
+	streams map[string]bool
+	closed  bool
+}
+
+func newFakeStreamServer(t *testing.T) *fakeStreamServer {
+	s := &fakeStreamServer{reject: make(map[string]bool), silent: make(map[string]int)}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		fc := &fakeStreamConn{conn: conn, streams: make(map[string]bool)}
+		s.mu.Lock()
+		s.conns = append(s.conns, fc)
+		s.mu.Unlock()
+		for {
+			var msg struct {
+				Method string   `json:"method"`
+				Params []string `json:"params"`
+				ID     int64    `json:"id"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				s.mu.Lock()
+				fc.closed = true
+				s.mu.Unlock()
+				return
+			}
+			s.mu.Lock()
+			s.respond(fc, msg.Method, msg.Params, msg.ID)
+			s.mu.Unlock()
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// respond 记录订阅并回复确认，包含被拒绝的流时返回错误，需要静默时不回复（调用方持有 s.mu）
+func (s *fakeStreamServer) respond(fc *fakeStreamConn, method string, params []string, id int64) {
+	for _, stream := range params {
+		if s.silent[stream] > 0 {
+			s.silent[stream]--
+			return
+		}
+	}
+	for _, stream := range params {
+		if s.reject[stream] {
+			fc.conn.WriteJSON(map[string]interface{}{"error": map[string]interface{}{"code": 2, "msg": "Invalid request: " + stream}, "id": id})
+			return
+		}
+	}
+	for _, stream := range params {
+		fc.streams[stream] = method == "SUBSCRIBE"
+	}
+	fc.conn.WriteJSON(map[string]interface{}{"result": nil, "id": id})
+}
+
+// streamCounts 各个未关闭连接上订阅的流数量
+func (s *fakeStreamServer) streamCounts() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var counts []int
+	for _, fc := range s.conns {
+		if fc.closed {
+			continue
+		}
+		n := 0
+		for _, subscribed := range fc.streams {
+			if subscribed {
+				n++
+			}
+		}
+		counts = append(counts, n)
+	}
+	return counts
+}
+
+// push 向订阅了该流的连接推送消息
+func (s *fakeStreamServer) push(stream, data string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, fc := range s.conns {
+		if !fc.closed && fc.streams[stream] {
+			return fc.conn.WriteMessage(websocket.TextMessage, []byte(`{"stream":"`+stream+`","data":`+data+`}`)) == nil
+		}
+	}
+	return false
+}
+
+// TestCombinedStreamsSharding 超过单连接上限的流分布到多个连接，消息按流路由，取消订阅后空连接被回收、新流填补空位
+func TestCombinedStreamsSharding(t *testing.T) {
+	srv := newFakeStreamServer(t)
+	c := NewCombinedStreamsClient(2)
+	c.endpoint = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.SetMaxStreamsPerConnection(3)
+	defer c.Close()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+
+	symbols := []string{"AUSDT", "BUSDT", "CUSDT", "DUSDT", "EUSDT", "FUSDT", "GUSDT"}
+	last := c.AddSubscriber("gusdt@kline_3m", 1)
+	if err := c.BatchSubscribeKlines(symbols, "3m"); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if counts := c.ConnectionStreamCounts(); len(counts) != 3 || counts[0] != 3 || counts[1] != 3 || counts[2] != 1 {
+		t.Fatalf("应分布到3个连接: %v", counts)
+	}
+	waitFor(t, func() bool { return len(srv.streamCounts()) == 3 && sum(srv.streamCounts()) == 7 }, "服务端应在3个连接上收到7个订阅")
+
+	// 第3个连接上的消息路由到订阅者
+	if !srv.push("gusdt@kline_3m", `{"e":"kline"}`) {
+		t.Fatal("推送失败")
+	}
+	select {
+	case data := <-last:
+		if string(data) != `{"e":"kline"}` {
+			t.Errorf("消息内容不正确: %s", data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("分片上的消息未路由到订阅者")
+	}
+	if !c.IsConnected() {
+		t.Error("所有连接都应处于连接状态")
+	}
+
+	// 第3个连接的流全部取消后关闭该连接
+	if err := c.UnsubscribeStreams([]string{"gusdt@kline_3m"}); err != nil {
+		t.Fatalf("取消订阅失败: %v", err)
+	}
+	if counts := c.ConnectionStreamCounts(); len(counts) != 2 {
+		t.Errorf("空连接应被回收: %v", counts)
+	}
+	waitFor(t, func() bool { return len(srv.streamCounts()) == 2 }, "服务端应看到空连接关闭")
+
+	// 第1个连接取消2个流后，新流填补到该连接
+	if err := c.UnsubscribeStreams([]string{"ausdt@kline_3m", "busdt@kline_3m"}); err != nil {
+		t.Fatalf("取消订阅失败: %v", err)
+	}
+	if err := c.BatchSubscribeKlines([]string{"HUSDT", "IUSDT"}, "3m"); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	if counts := c.ConnectionStreamCounts(); len(counts) != 2 || counts[0] != 3 || counts[1] != 3 {
+		t.Errorf("新流应填补未满的连接: %v", counts)
+	}
+	waitFor(t, func() bool { c := srv.streamCounts(); return len(c) == 2 && c[0] == 3 && c[1] == 3 }, "服务端订阅数应与客户端一致")
+}
+
+// waitFor 等待条件成立（最多1秒）
+func waitFor(t *testing.T, cond func() bool, msg string) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal(msg)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// sum 求和
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// TestSubscriptionAck 收到确认的流记为已确认；被拒绝的批次从订阅列表移除并在错误中列出流名称；超时未确认的请求重发一次
+func TestSubscriptionAck(t *testing.T) {
+	srv := newFakeStreamServer(t)
+	srv.reject["badusdt@kline_3m"] = true
+	srv.silent["cusdt@kline_3m"] = 1
+	c := NewCombinedStreamsClient(2)
+	c.endpoint = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.ackTimeout = 200 * time.Millisecond
+	defer c.Close()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+
+	err := c.BatchSubscribeKlines([]string{"AUSDT", "BADUSDT", "CUSDT", "DUSDT"}, "3m")
+	if err == nil || !strings.Contains(err.Error(), "ausdt@kline_3m,badusdt@kline_3m") || !strings.Contains(err.Error(), "code=2") {
+		t.Fatalf("被拒绝的批次应返回带流名称和错误码的错误: %v", err)
+	}
+	if strings.Contains(err.Error(), "cusdt") {
+		t.Errorf("重发后确认的流不应出现在错误中: %v", err)
+	}
+	want := []string{"cusdt@kline_3m", "dusdt@kline_3m"}
+	if streams := c.SubscribedStreams(); strings.Join(streams, ",") != strings.Join(want, ",") {
+		t.Errorf("被拒绝的流应从订阅列表移除: %v", streams)
+	}
+	if streams := c.ConfirmedStreams(); strings.Join(streams, ",") != strings.Join(want, ",") {
+		t.Errorf("已确认的流不正确: %v", streams)
+	}
+	health := c.GetHealth()
+	if health.Subscribed != 2 || len(health.Unconfirmed) != 0 {
+		t.Errorf("健康状态中的订阅确认不正确: %+v", health)
+	}
+
+	// 一直没有确认时重发一次后返回错误，流保留在订阅列表中（重连后再次订阅）
+	srv.mu.Lock()
+	srv.silent["eusdt@kline_3m"] = 2
+	srv.mu.Unlock()
+	err = c.subscribeStreams([]string{"eusdt@kline_3m"})
+	if err == nil || !strings.Contains(err.Error(), "eusdt@kline_3m") {
+		t.Fatalf("重发后仍未确认应返回错误: %v", err)
+	}
+	if streams := c.UnconfirmedStreams(); len(streams) != 1 || streams[0] != "eusdt@kline_3m" {
+		t.Errorf("未确认的流应保留在订阅列表中: %v", streams)
+	}
+}
+
+// TestSubscriptionAckResetOnReconnect 断线后已确认状态清空，重连恢复订阅并收到确认后重新确认
+func TestSubscriptionAckResetOnReconnect(t *testing.T) {
+	srv := newFakeStreamServer(t)
+	c := NewCombinedStreamsClient(10)
+	c.endpoint = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.reconnectBase = 300 * time.Millisecond // 留出观察断线状态的时间
+	defer c.Close()
+	if err := c.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	if err := c.subscribeStreams([]string{"btcusdt@kline_3m"}); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+
+	srv.mu.Lock()
+	srv.conns[0].conn.Close()
+	srv.mu.Unlock()
+	waitFor(t, func() bool { return len(c.ConfirmedStreams()) == 0 }, "断线后应清空已确认的流")
+	waitFor(t, func() bool { return len(c.ConfirmedStreams()) == 1 && c.IsConnected() }, "重连后应重新确认")
+}
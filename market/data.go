@@ -24,41 +24,24 @@ var (
 	frCacheTTL     = 1 * time.Hour
 )
 
-// Get 获取指定代币的市场数据
+// Get 获取指定代币的市场数据（币安行情）
 func Get(symbol string) (*Data, error) {
+	return GetFrom(Binance, symbol)
+}
+
+// GetFrom 从指定行情来源获取代币的市场数据（source 为 nil 时使用币安行情）
+func GetFrom(source Source, symbol string) (*Data, error) {
+	if source == nil {
+		source = Binance
+	}
 	var klines3m, klines4h []Kline
 	var err error
 	// 标准化symbol
 	symbol = Normalize(symbol)
-	// 获取3分钟K线数据 (最近10个)
-	klines3m, err = WSMonitorCli.GetCurrentKlines(symbol, "3m") // 多获取一些用于计算
+	// 获取3分钟K线数据：实时流缓存 + REST补齐（数据流卡住或重连期间错过的K线从API刷新）
+	klines3m, err = source.GetKlines(symbol, "3m", maxCachedKlines)
 	if err != nil {
-		return nil, fmt.Errorf("获取3分钟K线失败: %v", err)
-	}
-
-	// ✅ 数据新鲜度检测：防止 WebSocket 数据流卡住
-	if len(klines3m) > 0 {
-		lastKlineTime := time.UnixMilli(klines3m[len(klines3m)-1].CloseTime)
-		dataAge := time.Since(lastKlineTime)
-		
-		// 3分钟K线超过5分钟未更新 = 数据流卡住
-		if dataAge > 5*time.Minute {
-			log.Printf("⚠️  %s 数据过期 (最后更新: %.1f分钟前)，强制从API刷新...", 
-				symbol, dataAge.Minutes())
-			
-			// 强制从API获取最新数据
-			apiClient := NewAPIClient()
-			freshKlines, apiErr := apiClient.GetKlines(symbol, "3m", 100)
-			if apiErr == nil && len(freshKlines) > 0 {
-				// 更新缓存
-				WSMonitorCli.klineDataMap3m.Store(symbol, freshKlines)
-				klines3m = freshKlines
-				log.Printf("✓ %s 3分钟K线数据已刷新 (最新时间: %s)", 
-					symbol, time.UnixMilli(freshKlines[len(freshKlines)-1].CloseTime).Format("15:04:05"))
-			} else {
-				log.Printf("❌ %s API刷新失败: %v，使用缓存数据", symbol, apiErr)
-			}
-		}
+		return nil, err
 	}
 
 	// Data staleness detection: Prevent DOGEUSDT-style price freeze issues
@@ -67,32 +50,10 @@ func Get(symbol string) (*Data, error) {
 		return nil, fmt.Errorf("%s data is stale, possible cache failure", symbol)
 	}
 
-	// 获取4小时K线数据 (最近10个)
-	klines4h, err = WSMonitorCli.GetCurrentKlines(symbol, "4h") // 多获取用于计算指标
+	// 获取4小时K线数据（同样由REST补齐）
+	klines4h, err = source.GetKlines(symbol, "4h", maxCachedKlines)
 	if err != nil {
-		return nil, fmt.Errorf("获取4小时K线失败: %v", err)
-	}
-
-	// ✅ 4小时K线新鲜度检测
-	if len(klines4h) > 0 {
-		lastKlineTime := time.UnixMilli(klines4h[len(klines4h)-1].CloseTime)
-		dataAge := time.Since(lastKlineTime)
-		
-		// 4小时K线超过5小时未更新 = 数据流卡住
-		if dataAge > 5*time.Hour {
-			log.Printf("⚠️  %s 4小时K线数据过期 (最后更新: %.1f小时前)，强制从API刷新...", 
-				symbol, dataAge.Hours())
-			
-			apiClient := NewAPIClient()
-			freshKlines, apiErr := apiClient.GetKlines(symbol, "4h", 100)
-			if apiErr == nil && len(freshKlines) > 0 {
-				WSMonitorCli.klineDataMap4h.Store(symbol, freshKlines)
-				klines4h = freshKlines
-				log.Printf("✓ %s 4小时K线数据已刷新", symbol)
-			} else {
-				log.Printf("❌ %s 4小时K线API刷新失败: %v，使用缓存数据", symbol, apiErr)
-			}
-		}
+		return nil, err
 	}
 
 	// 检查数据是否为空
@@ -105,6 +66,11 @@ func Get(symbol string) (*Data, error) {
 
 	// 计算当前指标 (基于3分钟最新数据)
 	currentPrice := klines3m[len(klines3m)-1].Close
+	if pricer, ok := source.(midPricer); ok {
+		if mid, ok := pricer.MidPrice(symbol); ok && mid > 0 {
+			currentPrice = mid
+		}
+	}
 	currentEMA20 := calculateEMA(klines3m, 20)
 	currentMACD := calculateMACD(klines3m)
 	currentRSI7 := calculateRSI(klines3m, 7)
@@ -129,14 +95,14 @@ func Get(symbol string) (*Data, error) {
 	}
 
 	// 获取OI数据
-	oiData, err := getOpenInterestData(symbol)
+	oiData, err := source.GetOpenInterest(symbol)
 	if err != nil {
 		// OI失败不影响整体,使用默认值
 		oiData = &OIData{Latest: 0, Average: 0}
 	}
 
 	// 获取Funding Rate
-	fundingRate, _ := getFundingRate(symbol)
+	fundingRate, _ := source.GetFundingRate(symbol)
 
 	// 计算日内系列数据
 	intradayData := calculateIntradaySeries(klines3m)
@@ -144,6 +110,12 @@ func Get(symbol string) (*Data, error) {
 	// 计算长期数据
 	longerTermData := calculateLongerTermData(klines4h)
 
+	// 强平统计来自币安强平订单流，其他交易所没有对应数据
+	var liquidations *LiquidationStats
+	if source == Binance {
+		liquidations = GetLiquidationStats(symbol)
+	}
+
 	return &Data{
 		Symbol:            symbol,
 		CurrentPrice:      currentPrice,
@@ -154,8 +126,10 @@ func Get(symbol string) (*Data, error) {
 		CurrentRSI7:       currentRSI7,
 		OpenInterest:      oiData,
 		FundingRate:       fundingRate,
+		Liquidations:      liquidations,
 		IntradaySeries:    intradayData,
 		LongerTermContext: longerTermData,
+		Timeframes:        source.Snapshot(symbol, snapshotIntervals, snapshotCandles),
 	}, nil
 }
 
@@ -477,6 +451,10 @@ func Format(data *Data) string {
 
 	sb.WriteString(fmt.Sprintf("Funding Rate: %.2e\n\n", data.FundingRate))
 
+	if data.Liquidations != nil {
+		sb.WriteString(formatLiquidations(data.Liquidations))
+	}
+
 	if data.IntradaySeries != nil {
 		sb.WriteString("Intraday series (3‑minute intervals, oldest → latest):\n\n")
 
@@ -507,6 +485,21 @@ func Format(data *Data) string {
 		sb.WriteString(fmt.Sprintf("3m ATR (14‑period): %.3f\n\n", data.IntradaySeries.ATR14))
 	}
 
+	for _, interval := range snapshotIntervals {
+		klines := data.Timeframes[interval]
+		if len(klines) == 0 {
+			continue
+		}
+		closes := make([]float64, len(klines))
+		volumes := make([]float64, len(klines))
+		for i, k := range klines {
+			closes[i], volumes[i] = k.Close, k.Volume
+		}
+		sb.WriteString(fmt.Sprintf("%s candles (oldest → latest, last candle still open):\n\n", interval))
+		sb.WriteString(fmt.Sprintf("Close prices: %s\n\n", formatFloatSlice(closes)))
+		sb.WriteString(fmt.Sprintf("Volume: %s\n\n", formatFloatSlice(volumes)))
+	}
+
 	if data.LongerTermContext != nil {
 		sb.WriteString("Longer‑term context (4‑hour timeframe):\n\n")
 
@@ -0,0 +1,506 @@
+package market
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// hyperliquidMainnetWS Hyperliquid 主网行情WebSocket端点
+	hyperliquidMainnetWS = "wss://api.hyperliquid.xyz/ws"
+	// hyperliquidTestnetWS Hyperliquid 测试网行情WebSocket端点
+	hyperliquidTestnetWS = "wss://api.hyperliquid-testnet.xyz/ws"
+	// hyperliquidPingInterval 主动发送ping的间隔（服务端60秒没有消息会断开连接）
+	hyperliquidPingInterval = 20 * time.Second
+	// hyperliquidReadTimeout 超过该时间没有收到任何消息（包括pong）视为连接失效
+	hyperliquidReadTimeout = 60 * time.Second
+)
+
+// StreamSubscriber 行情流订阅客户端：币安组合流和Hyperliquid使用相同的流名称和订阅方式
+// （<symbol>@kline_<interval> 推送币安格式的K线消息），上层不需要关心行情来自哪个交易所
+type StreamSubscriber interface {
+	Connect() error
+	AddSubscriber(stream string, bufferSize int) <-chan []byte
+	RemoveSubscriber(stream string)
+	SubscribeStreams(streams []string) error
+	UnsubscribeStreams(streams []string) error
+	IsConnected() bool
+	Close()
+}
+
+var (
+	_ StreamSubscriber = (*CombinedStreamsClient)(nil)
+	_ StreamSubscriber = (*HyperliquidStreamsClient)(nil)
+)
+
+// MidPrice 中间价（<symbol>@mid 流推送的消息）
+type MidPrice struct {
+	Symbol string  `json:"symbol"`
+	Price  float64 `json:"price"`
+	Time   int64   `json:"time"` // 毫秒
+}
+
+// MidPriceStream 交易对的中间价流名称（Hyperliquid 由 allMids 订阅分发）
+func MidPriceStream(symbol string) string {
+	return strings.ToLower(Normalize(symbol)) + "@mid"
+}
+
+// HyperliquidCoin 将USDT交易对转换为Hyperliquid币种名称（BTCUSDT -> BTC，1000PEPEUSDT -> kPEPE）
+func HyperliquidCoin(symbol string) string {
+	coin := strings.TrimSuffix(strings.ToUpper(symbol), "USDT")
+	if rest, ok := strings.CutPrefix(coin, "1000"); ok && rest != "" {
+		return "k" + rest
+	}
+	return coin
+}
+
+// HyperliquidSymbol 将Hyperliquid币种名称转换为USDT交易对（HyperliquidCoin 的逆转换）
+func HyperliquidSymbol(coin string) string {
+	if rest, ok := strings.CutPrefix(coin, "k"); ok && rest != "" && strings.ToUpper(rest) == rest {
+		return "1000" + rest + "USDT"
+	}
+	return strings.ToUpper(coin) + "USDT"
+}
+
+// hlSubscription Hyperliquid 订阅（多个流可以共用一个订阅，如所有中间价流共用 allMids）
+type hlSubscription struct {
+	Type     string `json:"type"`
+	Coin     string `json:"coin,omitempty"`
+	Interval string `json:"interval,omitempty"`
+}
+
+// parseHyperliquidStream 将流名称映射为Hyperliquid订阅
+func parseHyperliquidStream(stream string) (hlSubscription, error) {
+	if symbol, interval, ok := strings.Cut(stream, "@kline_"); ok && symbol != "" && interval != "" {
+		return hlSubscription{Type: "candle", Coin: HyperliquidCoin(symbol), Interval: interval}, nil
+	}
+	if symbol, ok := strings.CutSuffix(stream, "@mid"); ok && symbol != "" {
+		return hlSubscription{Type: "allMids"}, nil
+	}
+	return hlSubscription{}, fmt.Errorf("Hyperliquid不支持的行情流: %s", stream)
+}
+
+// hlCandle Hyperliquid K线推送
+type hlCandle struct {
+	OpenTime  int64  `json:"t"`
+	CloseTime int64  `json:"T"`
+	Coin      string `json:"s"`
+	Interval  string `json:"i"`
+	Open      string `json:"o"`
+	Close     string `json:"c"`
+	High      string `json:"h"`
+	Low       string `json:"l"`
+	Volume    string `json:"v"`
+	Trades    int    `json:"n"`
+}
+
+// toWSData 转换为币安K线流的消息格式（Hyperliquid 不提供成交额和主动买入量）
+func (hc hlCandle) toWSData() KlineWSData {
+	var data KlineWSData
+	data.EventType = "kline"
+	data.EventTime = time.Now().UnixMilli()
+	data.Symbol = HyperliquidSymbol(hc.Coin)
+	data.Kline.StartTime = hc.OpenTime
+	data.Kline.CloseTime = hc.CloseTime
+	data.Kline.Symbol = data.Symbol
+	data.Kline.Interval = hc.Interval
+	data.Kline.OpenPrice = hc.Open
+	data.Kline.ClosePrice = hc.Close
+	data.Kline.HighPrice = hc.High
+	data.Kline.LowPrice = hc.Low
+	data.Kline.Volume = hc.Volume
+	data.Kline.NumberOfTrades = hc.Trades
+	data.Kline.QuoteVolume = "0"
+	data.Kline.TakerBuyBaseVolume = "0"
+	data.Kline.TakerBuyQuoteVolume = "0"
+	return data
+}
+
+// HyperliquidStreamsClient Hyperliquid 行情WebSocket客户端（单连接，断线后指数退避重连并恢复订阅）
+type HyperliquidStreamsClient struct {
+	mu            sync.RWMutex
+	subscribers   map[string]chan []byte
+	streams       map[string]hlSubscription // 已订阅的流
+	midStreams    map[string]string         // Hyperliquid币种 -> 中间价流
+	conn          *websocket.Conn
+	writeMu       sync.Mutex
+	ctx           context.Context
+	cancel        context.CancelFunc
+	closeOnce     sync.Once
+	endpoint      string
+	readTimeout   time.Duration
+	pingInterval  time.Duration
+	reconnectBase time.Duration
+	reconnectMax  time.Duration
+	reconnecting  atomic.Bool
+	lastMessageAt atomic.Int64
+}
+
+// NewHyperliquidStreamsClient 创建Hyperliquid行情客户端
+func NewHyperliquidStreamsClient(testnet bool) *HyperliquidStreamsClient {
+	endpoint := hyperliquidMainnetWS
+	if testnet {
+		endpoint = hyperliquidTestnetWS
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &HyperliquidStreamsClient{
+		subscribers:   make(map[string]chan []byte),
+		streams:       make(map[string]hlSubscription),
+		midStreams:    make(map[string]string),
+		ctx:           ctx,
+		cancel:        cancel,
+		endpoint:      endpoint,
+		readTimeout:   hyperliquidReadTimeout,
+		pingInterval:  hyperliquidPingInterval,
+		reconnectBase: reconnectBaseDelay,
+		reconnectMax:  reconnectMaxDelay,
+	}
+}
+
+// Connect 建立连接（代理配置与币安行情一致）
+func (c *HyperliquidStreamsClient) Connect() error {
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	c.start(conn)
+	log.Printf("✅ Hyperliquid行情WebSocket连接成功")
+	return nil
+}
+
+func (c *HyperliquidStreamsClient) dial() (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 45 * time.Second,
+		Proxy:            getProxyFunc(),
+		NetDialContext:   proxyDialContext,
+	}
+	conn, _, err := dialer.DialContext(c.ctx, c.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("Hyperliquid行情WebSocket连接失败: %w", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	return conn, nil
+}
+
+// start 启用连接并启动读取和保活goroutine
+func (c *HyperliquidStreamsClient) start(conn *websocket.Conn) {
+	c.mu.Lock()
+	if c.ctx.Err() != nil {
+		c.mu.Unlock()
+		conn.Close() // 连接过程中客户端已关闭
+		return
+	}
+	c.conn = conn
+	c.mu.Unlock()
+	go c.readMessages(conn)
+	go c.keepAlive(conn)
+}
+
+// currentConn 当前连接（未连接时为 nil）
+func (c *HyperliquidStreamsClient) currentConn() *websocket.Conn {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.conn
+}
+
+// IsConnected 是否处于连接状态
+func (c *HyperliquidStreamsClient) IsConnected() bool {
+	return c.currentConn() != nil
+}
+
+// LastMessageTime 返回最近一次收到消息的时间（从未收到时为零值）
+func (c *HyperliquidStreamsClient) LastMessageTime() time.Time {
+	ns := c.lastMessageAt.Load()
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ns)
+}
+
+func (c *HyperliquidStreamsClient) writeJSON(conn *websocket.Conn, v interface{}) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	return conn.WriteJSON(v)
+}
+
+// send 发送订阅或取消订阅请求
+func (c *HyperliquidStreamsClient) send(conn *websocket.Conn, method string, subs []hlSubscription) error {
+	for _, sub := range subs {
+		if err := c.writeJSON(conn, map[string]interface{}{"method": method, "subscription": sub}); err != nil {
+			return fmt.Errorf("发送Hyperliquid %s请求失败: %w", method, err)
+		}
+	}
+	return nil
+}
+
+// keepAlive 定期发送ping（Hyperliquid 使用 JSON 消息 {"method":"ping"}，回复 pong 频道消息）
+func (c *HyperliquidStreamsClient) keepAlive(conn *websocket.Conn) {
+	ticker := time.NewTicker(c.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+			if c.currentConn() != conn {
+				return
+			}
+			if err := c.writeJSON(conn, map[string]string{"method": "ping"}); err != nil {
+				log.Printf("⚠️  Hyperliquid行情发送ping失败: %v", err)
+				conn.Close() // 触发读取失败并进入重连
+				return
+			}
+		}
+	}
+}
+
+func (c *HyperliquidStreamsClient) readMessages(conn *websocket.Conn) {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if c.ctx.Err() != nil {
+				return
+			}
+			log.Printf("⚠️  Hyperliquid行情读取失败: %v", err)
+			c.dropConnection(conn)
+			go c.handleReconnect()
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		c.lastMessageAt.Store(time.Now().UnixNano())
+		c.handleMessage(message)
+	}
+}
+
+// dropConnection 丢弃失效的连接
+func (c *HyperliquidStreamsClient) dropConnection(conn *websocket.Conn) {
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	c.mu.Unlock()
+	conn.Close()
+}
+
+// handleReconnect 指数退避重连并恢复所有订阅（同一时间只有一个重连循环）
+func (c *HyperliquidStreamsClient) handleReconnect() {
+	if !c.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+	defer c.reconnecting.Store(false)
+
+	delay := c.reconnectBase
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-time.After(jitterDelay(delay)):
+		}
+
+		conn, err := c.dial()
+		if err == nil {
+			if err = c.send(conn, "subscribe", c.subscriptions()); err != nil {
+				conn.Close()
+			}
+		}
+		if err != nil {
+			log.Printf("Hyperliquid行情第 %d 次重连失败: %v", attempt, err)
+			delay = nextReconnectDelay(delay, c.reconnectMax)
+			continue
+		}
+		c.start(conn)
+		log.Printf("✅ Hyperliquid行情第 %d 次尝试重连成功", attempt)
+		return
+	}
+}
+
+// subscriptions 当前所有流需要的订阅（去重）
+func (c *HyperliquidStreamsClient) subscriptions() []hlSubscription {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[hlSubscription]bool)
+	var subs []hlSubscription
+	for _, sub := range c.streams {
+		if !seen[sub] {
+			seen[sub] = true
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// usedLocked 订阅是否仍被某个流使用（调用方需持有锁）
+func (c *HyperliquidStreamsClient) usedLocked(sub hlSubscription) bool {
+	for _, s := range c.streams {
+		if s == sub {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscribeStreams 订阅流（已被其他流使用的订阅不重复发送，如多个中间价流共用 allMids）
+func (c *HyperliquidStreamsClient) SubscribeStreams(streams []string) error {
+	var newSubs []hlSubscription
+	c.mu.Lock()
+	for _, stream := range streams {
+		if _, exists := c.streams[stream]; exists {
+			continue
+		}
+		sub, err := parseHyperliquidStream(stream)
+		if err != nil {
+			c.mu.Unlock()
+			return err
+		}
+		if !c.usedLocked(sub) {
+			newSubs = append(newSubs, sub)
+		}
+		c.streams[stream] = sub
+		if sub.Type == "allMids" {
+			symbol, _ := strings.CutSuffix(stream, "@mid")
+			c.midStreams[HyperliquidCoin(symbol)] = stream
+		}
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil || len(newSubs) == 0 {
+		return nil // 未连接时在连接（重连）后统一订阅
+	}
+	return c.send(conn, "subscribe", newSubs)
+}
+
+// UnsubscribeStreams 取消订阅流（订阅不再被任何流使用时才向服务端取消）
+func (c *HyperliquidStreamsClient) UnsubscribeStreams(streams []string) error {
+	var unused []hlSubscription
+	c.mu.Lock()
+	for _, stream := range streams {
+		sub, exists := c.streams[stream]
+		if !exists {
+			continue
+		}
+		delete(c.streams, stream)
+		if sub.Type == "allMids" {
+			symbol, _ := strings.CutSuffix(stream, "@mid")
+			delete(c.midStreams, HyperliquidCoin(symbol))
+		}
+		if !c.usedLocked(sub) {
+			unused = append(unused, sub)
+		}
+	}
+	conn := c.conn
+	c.mu.Unlock()
+
+	if conn == nil || len(unused) == 0 {
+		return nil
+	}
+	return c.send(conn, "unsubscribe", unused)
+}
+
+// handleMessage 将Hyperliquid消息转换后分发给对应流的订阅者
+func (c *HyperliquidStreamsClient) handleMessage(message []byte) {
+	var envelope struct {
+		Channel string          `json:"channel"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil {
+		log.Printf("解析Hyperliquid消息失败: %v", err)
+		return
+	}
+
+	switch envelope.Channel {
+	case "candle":
+		var candle hlCandle
+		if err := json.Unmarshal(envelope.Data, &candle); err != nil {
+			log.Printf("解析Hyperliquid K线失败: %v", err)
+			return
+		}
+		stream := strings.ToLower(HyperliquidSymbol(candle.Coin)) + "@kline_" + candle.Interval
+		if data, err := json.Marshal(candle.toWSData()); err == nil {
+			c.deliver(stream, data)
+		}
+	case "allMids":
+		var mids struct {
+			Mids map[string]string `json:"mids"`
+		}
+		if err := json.Unmarshal(envelope.Data, &mids); err != nil {
+			log.Printf("解析Hyperliquid中间价失败: %v", err)
+			return
+		}
+		now := time.Now().UnixMilli()
+		c.mu.RLock()
+		targets := make(map[string]string, len(c.midStreams))
+		for coin, stream := range c.midStreams {
+			targets[coin] = stream
+		}
+		c.mu.RUnlock()
+		for coin, stream := range targets {
+			price, err := strconv.ParseFloat(mids.Mids[coin], 64)
+			if err != nil {
+				continue
+			}
+			if data, err := json.Marshal(MidPrice{Symbol: HyperliquidSymbol(coin), Price: price, Time: now}); err == nil {
+				c.deliver(stream, data)
+			}
+		}
+	}
+}
+
+// deliver 非阻塞地发送给流的订阅者（持有读锁，避免 RemoveSubscriber 同时关闭通道）
+func (c *HyperliquidStreamsClient) deliver(stream string, data []byte) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if ch, ok := c.subscribers[stream]; ok {
+		select {
+		case ch <- data:
+		default:
+			log.Printf("Hyperliquid订阅者通道已满: %s", stream)
+		}
+	}
+}
+
+// AddSubscriber 添加流的订阅者通道
+func (c *HyperliquidStreamsClient) AddSubscriber(stream string, bufferSize int) <-chan []byte {
+	ch := make(chan []byte, bufferSize)
+	c.mu.Lock()
+	c.subscribers[stream] = ch
+	c.mu.Unlock()
+	return ch
+}
+
+// RemoveSubscriber 移除并关闭流的订阅者通道（不取消订阅，需配合 UnsubscribeStreams）
+func (c *HyperliquidStreamsClient) RemoveSubscriber(stream string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if ch, exists := c.subscribers[stream]; exists {
+		close(ch)
+		delete(c.subscribers, stream)
+	}
+}
+
+// Close 关闭连接、停止重连并关闭所有订阅者通道，可重复调用
+func (c *HyperliquidStreamsClient) Close() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.conn != nil {
+			c.conn.Close()
+			c.conn = nil
+		}
+		for stream, ch := range c.subscribers {
+			close(ch)
+			delete(c.subscribers, stream)
+		}
+	})
+}
@@ -0,0 +1,270 @@
+package market
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// hyperliquidMainnetInfo Hyperliquid 主网 Info 接口
+	hyperliquidMainnetInfo = "https://api.hyperliquid.xyz/info"
+	// hyperliquidTestnetInfo Hyperliquid 测试网 Info 接口
+	hyperliquidTestnetInfo = "https://api.hyperliquid-testnet.xyz/info"
+	// hyperliquidAssetCtxTTL 资金费率和持仓量缓存时间（一次请求返回所有币种）
+	hyperliquidAssetCtxTTL = time.Minute
+	// hyperliquidRedialInterval 行情流连接失败后再次尝试的间隔（期间只使用REST数据）
+	hyperliquidRedialInterval = time.Minute
+)
+
+// hyperliquidSources 按网络共享的Hyperliquid行情来源（所有Hyperliquid交易员共用连接和缓存）
+var (
+	hyperliquidSourcesMu sync.Mutex
+	hyperliquidSources   = make(map[bool]*HyperliquidSource)
+)
+
+// HyperliquidMarket 获取主网或测试网的共享Hyperliquid行情来源
+func HyperliquidMarket(testnet bool) *HyperliquidSource {
+	hyperliquidSourcesMu.Lock()
+	defer hyperliquidSourcesMu.Unlock()
+	if source, ok := hyperliquidSources[testnet]; ok {
+		return source
+	}
+	infoURL := hyperliquidMainnetInfo
+	if testnet {
+		infoURL = hyperliquidTestnetInfo
+	}
+	source := NewHyperliquidSource(NewHyperliquidStreamsClient(testnet), infoURL)
+	hyperliquidSources[testnet] = source
+	return source
+}
+
+// hlAssetCtx Hyperliquid 币种的资金费率和持仓量
+type hlAssetCtx struct {
+	Funding      string `json:"funding"`
+	OpenInterest string `json:"openInterest"`
+}
+
+// HyperliquidSource Hyperliquid 行情来源：K线和中间价来自WebSocket（按需订阅），缺失的K线用 Info 接口补齐
+type HyperliquidSource struct {
+	client  *HyperliquidStreamsClient
+	infoURL string
+	http    *http.Client
+	klines  *KlineCache
+
+	mu         sync.Mutex
+	connected  bool
+	dialFailed time.Time          // 最近一次连接失败的时间
+	subscribed map[string]bool    // 已订阅的流
+	mids       map[string]float64 // 交易对 -> 中间价
+
+	ctxMu     sync.Mutex
+	assetCtxs map[string]hlAssetCtx // 交易对 -> 资金费率和持仓量
+	ctxAt     time.Time
+}
+
+// NewHyperliquidSource 创建Hyperliquid行情来源（首次获取数据时才连接WebSocket）
+func NewHyperliquidSource(client *HyperliquidStreamsClient, infoURL string) *HyperliquidSource {
+	return &HyperliquidSource{
+		client:     client,
+		infoURL:    infoURL,
+		http:       &http.Client{Timeout: 30 * time.Second, Transport: restTransport()},
+		klines:     NewKlineCache(defaultMaxCandles, defaultKlineIdleTTL),
+		subscribed: make(map[string]bool),
+		mids:       make(map[string]float64),
+	}
+}
+
+func (s *HyperliquidSource) Name() string { return "hyperliquid" }
+
+// ensureStream 按需连接并订阅流，首次订阅时启动 consume 消费推送（失败时只记录日志，数据由REST补齐）
+func (s *HyperliquidSource) ensureStream(stream string, consume func(ch <-chan []byte)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.subscribed[stream] {
+		return
+	}
+	if !s.connected {
+		if time.Since(s.dialFailed) < hyperliquidRedialInterval {
+			return
+		}
+		if err := s.client.Connect(); err != nil {
+			log.Printf("⚠️  %v（%v 内使用REST数据）", err, hyperliquidRedialInterval)
+			s.dialFailed = time.Now()
+			return
+		}
+		s.connected = true // 之后断线由客户端自动重连
+	}
+	go consume(s.client.AddSubscriber(stream, 100))
+	if err := s.client.SubscribeStreams([]string{stream}); err != nil {
+		log.Printf("⚠️  订阅Hyperliquid行情流 %s 失败: %v", stream, err)
+		s.client.RemoveSubscriber(stream)
+		return
+	}
+	s.subscribed[stream] = true
+}
+
+// GetKlines 获取最近 n 根K线：优先使用实时流缓存，缓存不足或过期时从 candleSnapshot 补齐
+func (s *HyperliquidSource) GetKlines(symbol, interval string, n int) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	s.ensureStream(fmt.Sprintf("%s@kline_%s", strings.ToLower(symbol), interval), func(ch <-chan []byte) {
+		s.klines.Consume(symbol, interval, ch)
+	})
+
+	cached, _ := s.klines.Klines(symbol, interval, 0)
+	if len(cached) >= n && !klinesOutdated(cached, interval, time.Now()) {
+		return lastKlines(cached, n), nil
+	}
+	fresh, err := s.candleSnapshot(symbol, interval, max(n, maxCachedKlines))
+	if err != nil {
+		if len(cached) > 0 {
+			log.Printf("⚠️  %s %s Hyperliquid K线补齐失败: %v，使用缓存数据", symbol, interval, err)
+			return lastKlines(cached, n), nil
+		}
+		return nil, fmt.Errorf("获取Hyperliquid %s K线失败: %w", interval, err)
+	}
+	s.klines.Merge(symbol, interval, fresh)
+	return lastKlines(mergeKlines(cached, fresh, 0), n), nil
+}
+
+// MidPrice 获取实时中间价（首次调用时订阅，尚未收到推送时 ok=false）
+func (s *HyperliquidSource) MidPrice(symbol string) (float64, bool) {
+	symbol = Normalize(symbol)
+	s.ensureStream(MidPriceStream(symbol), func(ch <-chan []byte) {
+		for data := range ch {
+			var mid MidPrice
+			if err := json.Unmarshal(data, &mid); err != nil {
+				continue
+			}
+			s.mu.Lock()
+			s.mids[symbol] = mid.Price
+			s.mu.Unlock()
+		}
+	})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	price, ok := s.mids[symbol]
+	return price, ok
+}
+
+// GetOpenInterest 获取持仓量（Hyperliquid 只提供当前值）
+func (s *HyperliquidSource) GetOpenInterest(symbol string) (*OIData, error) {
+	ctx, err := s.assetCtx(symbol)
+	if err != nil {
+		return nil, err
+	}
+	oi, _ := strconv.ParseFloat(ctx.OpenInterest, 64)
+	return &OIData{Latest: oi, Average: oi}, nil
+}
+
+// GetFundingRate 获取资金费率（Hyperliquid 每小时结算一次）
+func (s *HyperliquidSource) GetFundingRate(symbol string) (float64, error) {
+	ctx, err := s.assetCtx(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(ctx.Funding, 64)
+}
+
+// Snapshot 获取多个周期的最近K线（由缓存中的较低周期聚合）
+func (s *HyperliquidSource) Snapshot(symbol string, intervals []string, n int) map[string][]Kline {
+	return s.klines.Snapshot(Normalize(symbol), intervals, n)
+}
+
+// assetCtx 获取币种的资金费率和持仓量（metaAndAssetCtxs 一次返回所有币种，缓存 hyperliquidAssetCtxTTL）
+func (s *HyperliquidSource) assetCtx(symbol string) (hlAssetCtx, error) {
+	s.ctxMu.Lock()
+	defer s.ctxMu.Unlock()
+
+	if s.assetCtxs == nil || time.Since(s.ctxAt) > hyperliquidAssetCtxTTL {
+		var result []json.RawMessage
+		if err := s.post(map[string]string{"type": "metaAndAssetCtxs"}, &result); err != nil {
+			return hlAssetCtx{}, err
+		}
+		if len(result) != 2 {
+			return hlAssetCtx{}, fmt.Errorf("Hyperliquid metaAndAssetCtxs 返回格式错误")
+		}
+		var meta struct {
+			Universe []struct {
+				Name string `json:"name"`
+			} `json:"universe"`
+		}
+		var ctxs []hlAssetCtx
+		if err := json.Unmarshal(result[0], &meta); err != nil {
+			return hlAssetCtx{}, fmt.Errorf("解析Hyperliquid元数据失败: %w", err)
+		}
+		if err := json.Unmarshal(result[1], &ctxs); err != nil {
+			return hlAssetCtx{}, fmt.Errorf("解析Hyperliquid资产数据失败: %w", err)
+		}
+		s.assetCtxs = make(map[string]hlAssetCtx, len(ctxs))
+		for i, asset := range meta.Universe {
+			if i < len(ctxs) {
+				s.assetCtxs[HyperliquidSymbol(asset.Name)] = ctxs[i]
+			}
+		}
+		s.ctxAt = time.Now()
+	}
+
+	ctx, ok := s.assetCtxs[Normalize(symbol)]
+	if !ok {
+		return hlAssetCtx{}, fmt.Errorf("Hyperliquid 没有 %s 的行情", symbol)
+	}
+	return ctx, nil
+}
+
+// candleSnapshot 通过 Info 接口获取最近 n 根K线
+func (s *HyperliquidSource) candleSnapshot(symbol, interval string, n int) ([]Kline, error) {
+	duration, ok := klineIntervals[interval]
+	if !ok {
+		return nil, fmt.Errorf("不支持的K线周期: %s", interval)
+	}
+	end := time.Now()
+	start := end.Add(-duration * time.Duration(n))
+	var candles []hlCandle
+	err := s.post(map[string]interface{}{
+		"type": "candleSnapshot",
+		"req": map[string]interface{}{
+			"coin":      HyperliquidCoin(symbol),
+			"interval":  interval,
+			"startTime": start.UnixMilli(),
+			"endTime":   end.UnixMilli(),
+		},
+	}, &candles)
+	if err != nil {
+		return nil, err
+	}
+
+	klines := make([]Kline, 0, len(candles))
+	for _, candle := range candles {
+		klines = append(klines, klineFromWS(candle.toWSData()))
+	}
+	return klines, nil
+}
+
+// post 请求 Info 接口
+func (s *HyperliquidSource) post(body interface{}, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	resp, err := s.http.Post(s.infoURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("请求Hyperliquid Info接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Hyperliquid Info接口返回 HTTP %d: %s", resp.StatusCode, string(data))
+	}
+	return json.Unmarshal(data, result)
+}
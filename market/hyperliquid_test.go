@@ -0,0 +1,267 @@
+package market
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestHyperliquidSymbolMapping 交易对与Hyperliquid币种名称互相转换
+func TestHyperliquidSymbolMapping(t *testing.T) {
+	cases := map[string]string{
+		"BTCUSDT":      "BTC",
+		"ethusdt":      "ETH",
+		"1000PEPEUSDT": "kPEPE",
+	}
+	for symbol, coin := range cases {
+		if got := HyperliquidCoin(symbol); got != coin {
+			t.Errorf("HyperliquidCoin(%s) = %s，期望 %s", symbol, got, coin)
+		}
+	}
+	for _, symbol := range []string{"BTCUSDT", "1000PEPEUSDT", "SOLUSDT"} {
+		if got := HyperliquidSymbol(HyperliquidCoin(symbol)); got != symbol {
+			t.Errorf("往返转换 %s 得到 %s", symbol, got)
+		}
+	}
+
+	sub, err := parseHyperliquidStream("1000pepeusdt@kline_15m")
+	if err != nil || sub != (hlSubscription{Type: "candle", Coin: "kPEPE", Interval: "15m"}) {
+		t.Errorf("K线流映射不正确: %+v %v", sub, err)
+	}
+	if sub, err := parseHyperliquidStream("btcusdt@mid"); err != nil || sub.Type != "allMids" {
+		t.Errorf("中间价流应映射为 allMids: %+v %v", sub, err)
+	}
+	if _, err := parseHyperliquidStream("btcusdt@forceOrder"); err == nil {
+		t.Error("不支持的流应返回错误")
+	}
+}
+
+// fakeHyperliquidServer 模拟Hyperliquid行情WebSocket：记录收到的订阅请求，并向当前连接推送消息
+type fakeHyperliquidServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	conn     *websocket.Conn
+	requests []string // method:type:coin
+}
+
+func newFakeHyperliquidServer(t *testing.T) *fakeHyperliquidServer {
+	s := &fakeHyperliquidServer{}
+	upgrader := websocket.Upgrader{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conn = conn
+		s.mu.Unlock()
+		for {
+			var msg struct {
+				Method       string         `json:"method"`
+				Subscription hlSubscription `json:"subscription"`
+			}
+			if err := conn.ReadJSON(&msg); err != nil {
+				return
+			}
+			if msg.Method == "ping" {
+				continue
+			}
+			s.mu.Lock()
+			s.requests = append(s.requests, msg.Method+":"+msg.Subscription.Type+":"+msg.Subscription.Coin)
+			s.mu.Unlock()
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// waitRequests 等待收到 n 个订阅请求
+func (s *fakeHyperliquidServer) waitRequests(t *testing.T, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		requests := append([]string(nil), s.requests...)
+		s.mu.Unlock()
+		if len(requests) >= n {
+			return requests
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("等待 %d 个订阅请求超时", n)
+	return nil
+}
+
+// push 向当前连接推送消息
+func (s *fakeHyperliquidServer) push(t *testing.T, message string) {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.conn.WriteMessage(websocket.TextMessage, []byte(message)); err != nil {
+		t.Fatalf("推送消息失败: %v", err)
+	}
+}
+
+// receive 在超时前读取一条订阅消息
+func receive(t *testing.T, ch <-chan []byte) []byte {
+	t.Helper()
+	select {
+	case data := <-ch:
+		return data
+	case <-time.After(2 * time.Second):
+		t.Fatal("等待推送超时")
+		return nil
+	}
+}
+
+// TestHyperliquidStreamsClient K线转换为币安格式推送，多个中间价流共用一个 allMids 订阅，最后一个取消时才退订
+func TestHyperliquidStreamsClient(t *testing.T) {
+	server := newFakeHyperliquidServer(t)
+	c := NewHyperliquidStreamsClient(false)
+	c.endpoint = "ws" + strings.TrimPrefix(server.URL, "http")
+	defer c.Close()
+
+	if err := c.Connect(); err != nil {
+		t.Fatalf("连接失败: %v", err)
+	}
+	klineCh := c.AddSubscriber("btcusdt@kline_3m", 10)
+	btcMid := c.AddSubscriber("btcusdt@mid", 10)
+	pepeMid := c.AddSubscriber("1000pepeusdt@mid", 10)
+	if err := c.SubscribeStreams([]string{"btcusdt@kline_3m", "btcusdt@mid", "1000pepeusdt@mid"}); err != nil {
+		t.Fatalf("订阅失败: %v", err)
+	}
+	requests := server.waitRequests(t, 2)
+	if len(requests) != 2 || requests[0] != "subscribe:candle:BTC" || requests[1] != "subscribe:allMids:" {
+		t.Fatalf("订阅请求不正确: %v", requests)
+	}
+
+	server.push(t, `{"channel":"candle","data":{"t":1700000000000,"T":1700000179999,"s":"BTC","i":"3m","o":"100","c":"101.5","h":"102","l":"99","v":"12.5","n":42}}`)
+	var wsData KlineWSData
+	if err := json.Unmarshal(receive(t, klineCh), &wsData); err != nil {
+		t.Fatalf("K线消息应为币安格式: %v", err)
+	}
+	kline := klineFromWS(wsData)
+	if wsData.Symbol != "BTCUSDT" || kline.OpenTime != 1700000000000 || kline.Close != 101.5 || kline.Volume != 12.5 || kline.Trades != 42 {
+		t.Errorf("K线转换不正确: %+v", kline)
+	}
+
+	server.push(t, `{"channel":"allMids","data":{"mids":{"BTC":"65000.5","kPEPE":"0.0123","ETH":"3000"}}}`)
+	var mid MidPrice
+	if err := json.Unmarshal(receive(t, btcMid), &mid); err != nil || mid.Symbol != "BTCUSDT" || mid.Price != 65000.5 {
+		t.Errorf("BTC中间价不正确: %+v %v", mid, err)
+	}
+	if err := json.Unmarshal(receive(t, pepeMid), &mid); err != nil || mid.Symbol != "1000PEPEUSDT" || mid.Price != 0.0123 {
+		t.Errorf("PEPE中间价不正确: %+v %v", mid, err)
+	}
+
+	if err := c.UnsubscribeStreams([]string{"btcusdt@mid"}); err != nil {
+		t.Fatalf("取消订阅失败: %v", err)
+	}
+	if err := c.UnsubscribeStreams([]string{"1000pepeusdt@mid"}); err != nil {
+		t.Fatalf("取消订阅失败: %v", err)
+	}
+	requests = server.waitRequests(t, 3)
+	if len(requests) != 3 || requests[2] != "unsubscribe:allMids:" {
+		t.Errorf("最后一个中间价流取消后才应退订 allMids: %v", requests)
+	}
+}
+
+// TestHyperliquidSourceGetKlines 实时流没有数据时从 candleSnapshot 补齐，资金费率和持仓量来自 metaAndAssetCtxs
+func TestHyperliquidSourceGetKlines(t *testing.T) {
+	interval := 3 * time.Minute
+	last := time.Now().Truncate(interval)
+	var requests []string
+	var mu sync.Mutex
+	info := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Type string `json:"type"`
+			Req  struct {
+				Coin     string `json:"coin"`
+				Interval string `json:"interval"`
+			} `json:"req"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		requests = append(requests, body.Type)
+		mu.Unlock()
+		switch body.Type {
+		case "candleSnapshot":
+			if body.Req.Coin != "BTC" || body.Req.Interval != "3m" {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			var candles []hlCandle
+			for i := 4; i >= 0; i-- {
+				open := last.Add(-time.Duration(i) * interval)
+				candles = append(candles, hlCandle{
+					OpenTime: open.UnixMilli(), CloseTime: open.Add(interval).UnixMilli() - 1,
+					Coin: "BTC", Interval: "3m", Open: "100", Close: "101", High: "102", Low: "99", Volume: "1", Trades: 1,
+				})
+			}
+			json.NewEncoder(w).Encode(candles)
+		case "metaAndAssetCtxs":
+			w.Write([]byte(`[{"universe":[{"name":"BTC"},{"name":"kPEPE"}]},[{"funding":"0.0000125","openInterest":"1500.5"},{"funding":"-0.0001","openInterest":"10"}]]`))
+		}
+	}))
+	defer info.Close()
+
+	// 行情流连接失败时只使用REST数据
+	client := NewHyperliquidStreamsClient(false)
+	client.endpoint = "ws://127.0.0.1:1/ws"
+	defer client.Close()
+	source := NewHyperliquidSource(client, info.URL)
+
+	klines, err := source.GetKlines("BTCUSDT", "3m", 3)
+	if err != nil {
+		t.Fatalf("获取K线失败: %v", err)
+	}
+	if len(klines) != 3 || klines[2].OpenTime != last.UnixMilli() || klines[2].Close != 101 {
+		t.Fatalf("应返回最近3根K线: %+v", klines)
+	}
+	if _, err := source.GetKlines("BTCUSDT", "3m", 3); err != nil {
+		t.Fatalf("再次获取K线失败: %v", err)
+	}
+	mu.Lock()
+	snapshots := len(requests)
+	mu.Unlock()
+	if snapshots != 1 {
+		t.Errorf("缓存足够且未过期时不应重复请求REST: %d", snapshots)
+	}
+
+	funding, err := source.GetFundingRate("BTCUSDT")
+	if err != nil || funding != 0.0000125 {
+		t.Errorf("资金费率不正确: %v %v", funding, err)
+	}
+	oi, err := source.GetOpenInterest("1000PEPEUSDT")
+	if err != nil || oi.Latest != 10 {
+		t.Errorf("持仓量不正确: %+v %v", oi, err)
+	}
+	if _, err := source.GetFundingRate("DOGEUSDT"); err == nil {
+		t.Error("Hyperliquid没有的币种应返回错误")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Errorf("metaAndAssetCtxs 应在有效期内复用: %v", requests)
+	}
+}
+
+// TestSourceForExchange 按交易所选择行情来源
+func TestSourceForExchange(t *testing.T) {
+	if SourceForExchange("binance", false) != Binance || SourceForExchange("aster", false) != Binance {
+		t.Error("非Hyperliquid交易所应使用币安行情")
+	}
+	mainnet := SourceForExchange("hyperliquid", false)
+	if mainnet.Name() != "hyperliquid" || mainnet != Source(HyperliquidMarket(false)) {
+		t.Error("Hyperliquid交易员应共用Hyperliquid行情来源")
+	}
+	if SourceForExchange("hyperliquid", true) == mainnet {
+		t.Error("测试网和主网应使用不同的行情来源")
+	}
+}
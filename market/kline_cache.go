@@ -0,0 +1,329 @@
+package market
+
+import (
+	"encoding/json"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxCandles 每个序列默认保留的K线数量（3m K线约25小时，足够聚合出最近的1h K线）
+	defaultMaxCandles = 500
+	// defaultKlineIdleTTL 交易对超过该时间没有更新也没有读取时从缓存中淘汰
+	defaultKlineIdleTTL = 30 * time.Minute
+	// maxAggregateInterval 支持聚合的最大周期（更大的周期如1w不按UTC零点对齐）
+	maxAggregateInterval = 24 * time.Hour
+	// snapshotCandles 决策提示词中每个中间周期展示的K线数量
+	snapshotCandles = 10
+)
+
+// snapshotIntervals 决策提示词中展示的中间周期（3m与4h之间，由3m聚合）
+var snapshotIntervals = []string{"15m", "1h"}
+
+// klineRing 固定容量的K线环形缓冲区（按开盘时间递增，写满后覆盖最旧的K线）
+type klineRing struct {
+	buf   []Kline
+	start int // 最旧K线的位置
+	size  int
+}
+
+func newKlineRing(capacity int) *klineRing {
+	return &klineRing{buf: make([]Kline, capacity)}
+}
+
+// at 第 i 旧的K线
+func (r *klineRing) at(i int) *Kline {
+	return &r.buf[(r.start+i)%len(r.buf)]
+}
+
+// upsert 追加新K线或更新当前K线，早于最新K线的数据返回 false（由 merge 处理）
+func (r *klineRing) upsert(k Kline) bool {
+	if r.size > 0 {
+		last := r.at(r.size - 1)
+		if k.OpenTime == last.OpenTime {
+			*last = k
+			return true
+		}
+		if k.OpenTime < last.OpenTime {
+			return false
+		}
+	}
+	if r.size < len(r.buf) {
+		*r.at(r.size) = k
+		r.size++
+	} else {
+		r.buf[r.start] = k
+		r.start = (r.start + 1) % len(r.buf)
+	}
+	return true
+}
+
+// slice 最近 n 根K线的副本（n<=0 返回全部）
+func (r *klineRing) slice(n int) []Kline {
+	if n <= 0 || n > r.size {
+		n = r.size
+	}
+	result := make([]Kline, n)
+	for i := range result {
+		result[i] = *r.at(r.size - n + i)
+	}
+	return result
+}
+
+// reset 用按开盘时间排序的K线重建缓冲区（超出容量时保留最近的）
+func (r *klineRing) reset(klines []Kline) {
+	if len(klines) > len(r.buf) {
+		klines = klines[len(klines)-len(r.buf):]
+	}
+	r.start, r.size = 0, len(klines)
+	copy(r.buf, klines)
+}
+
+// KlineCache 所有交易员共享的K线缓存：按交易对和周期保存最近的K线，
+// 由组合流订阅通道实时更新，更高周期（如15m/1h）没有直接订阅时由较低周期按UTC对齐聚合
+type KlineCache struct {
+	mu         sync.RWMutex
+	maxCandles int
+	idleTTL    time.Duration
+	series     map[string]map[string]*klineRing // 交易对 -> 周期 -> K线
+	lastActive map[string]time.Time             // 交易对最近一次更新或读取的时间
+}
+
+// NewKlineCache 创建K线缓存（maxCandles<=0 或 idleTTL<=0 时使用默认值）
+func NewKlineCache(maxCandles int, idleTTL time.Duration) *KlineCache {
+	if maxCandles <= 0 {
+		maxCandles = defaultMaxCandles
+	}
+	if idleTTL <= 0 {
+		idleTTL = defaultKlineIdleTTL
+	}
+	return &KlineCache{
+		maxCandles: maxCandles,
+		idleTTL:    idleTTL,
+		series:     make(map[string]map[string]*klineRing),
+		lastActive: make(map[string]time.Time),
+	}
+}
+
+// SetMaxCandles 设置每个序列保留的K线数量（已有序列按新容量重建，保留最近的K线）
+func (kc *KlineCache) SetMaxCandles(n int) {
+	if n <= 0 {
+		return
+	}
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	kc.maxCandles = n
+	for _, intervals := range kc.series {
+		for interval, ring := range intervals {
+			resized := newKlineRing(n)
+			resized.reset(ring.slice(0))
+			intervals[interval] = resized
+		}
+	}
+}
+
+// ring 获取或创建序列（调用方需持有写锁）
+func (kc *KlineCache) ring(symbol, interval string) *klineRing {
+	intervals, ok := kc.series[symbol]
+	if !ok {
+		intervals = make(map[string]*klineRing)
+		kc.series[symbol] = intervals
+	}
+	ring, ok := intervals[interval]
+	if !ok {
+		ring = newKlineRing(kc.maxCandles)
+		intervals[interval] = ring
+	}
+	return ring
+}
+
+// Update 写入实时流推送的K线（同一开盘时间覆盖，迟到的旧K线忽略）
+func (kc *KlineCache) Update(symbol, interval string, k Kline) {
+	symbol = strings.ToUpper(symbol)
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	kc.ring(symbol, interval).upsert(k)
+	kc.lastActive[symbol] = time.Now()
+}
+
+// Merge 合并REST获取的K线，按开盘时间去重（同一根K线保留成交笔数更多的版本）
+func (kc *KlineCache) Merge(symbol, interval string, klines []Kline) {
+	if len(klines) == 0 {
+		return
+	}
+	symbol = strings.ToUpper(symbol)
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	ring := kc.ring(symbol, interval)
+	ring.reset(mergeKlines(ring.slice(0), klines, kc.maxCandles))
+	kc.lastActive[symbol] = time.Now()
+}
+
+// Consume 从组合流订阅通道读取K线消息并写入缓存（通道关闭时返回）
+func (kc *KlineCache) Consume(symbol, interval string, ch <-chan []byte) {
+	for data := range ch {
+		var wsData KlineWSData
+		if err := json.Unmarshal(data, &wsData); err != nil {
+			log.Printf("解析Kline数据失败: %v", err)
+			continue
+		}
+		kc.Update(symbol, interval, klineFromWS(wsData))
+	}
+}
+
+// Klines 直接缓存的最近 n 根K线（n<=0 返回全部），没有该序列时 ok=false
+func (kc *KlineCache) Klines(symbol, interval string, n int) (klines []Kline, ok bool) {
+	symbol = strings.ToUpper(symbol)
+	kc.mu.RLock()
+	ring, ok := kc.series[symbol][interval]
+	if ok && ring.size > 0 {
+		klines = ring.slice(n)
+	}
+	kc.mu.RUnlock()
+	if ok {
+		kc.touch(symbol)
+	}
+	return klines, ok && len(klines) > 0
+}
+
+// Get 最近 n 根K线：有直接缓存的序列时返回缓存，否则由能整除目标周期的最大较低周期聚合
+func (kc *KlineCache) Get(symbol, interval string, n int) ([]Kline, bool) {
+	if klines, ok := kc.Klines(symbol, interval, n); ok {
+		return klines, true
+	}
+	target, ok := klineIntervals[interval]
+	if !ok || target > maxAggregateInterval {
+		return nil, false
+	}
+
+	symbol = strings.ToUpper(symbol)
+	kc.mu.RLock()
+	var source []Kline
+	var sourceDur time.Duration
+	for iv, ring := range kc.series[symbol] {
+		d, ok := klineIntervals[iv]
+		if !ok || d >= target || target%d != 0 || d <= sourceDur || ring.size == 0 {
+			continue
+		}
+		source, sourceDur = ring.slice(0), d
+	}
+	kc.mu.RUnlock()
+	if len(source) == 0 {
+		return nil, false
+	}
+	kc.touch(symbol)
+
+	aggregated := aggregateKlines(source, target)
+	if n > 0 && len(aggregated) > n {
+		aggregated = aggregated[len(aggregated)-n:]
+	}
+	return aggregated, len(aggregated) > 0
+}
+
+// Snapshot 交易对多个周期的最近 n 根K线（没有数据的周期不包含在结果中）
+func (kc *KlineCache) Snapshot(symbol string, intervals []string, n int) map[string][]Kline {
+	snapshot := make(map[string][]Kline, len(intervals))
+	for _, interval := range intervals {
+		if klines, ok := kc.Get(symbol, interval, n); ok {
+			snapshot[interval] = klines
+		}
+	}
+	return snapshot
+}
+
+// GetKlineSnapshot 获取交易对多个周期的最近K线（行情监控未启动时返回空结果）
+func GetKlineSnapshot(symbol string, intervals []string, n int) map[string][]Kline {
+	m := WSMonitorCli
+	if m == nil || m.klines == nil {
+		return map[string][]Kline{}
+	}
+	return m.klines.Snapshot(Normalize(symbol), intervals, n)
+}
+
+// touch 记录交易对的读取时间（被读取的交易对不会因为实时流中断而被淘汰）
+func (kc *KlineCache) touch(symbol string) {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	if _, ok := kc.series[symbol]; ok {
+		kc.lastActive[symbol] = time.Now()
+	}
+}
+
+// DeleteSymbol 删除交易对的所有序列
+func (kc *KlineCache) DeleteSymbol(symbol string) {
+	symbol = strings.ToUpper(symbol)
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	delete(kc.series, symbol)
+	delete(kc.lastActive, symbol)
+}
+
+// EvictIdle 淘汰超过 idleTTL 没有更新也没有读取的交易对，返回被淘汰的交易对
+func (kc *KlineCache) EvictIdle(now time.Time) []string {
+	kc.mu.Lock()
+	defer kc.mu.Unlock()
+	var evicted []string
+	for symbol, last := range kc.lastActive {
+		if now.Sub(last) > kc.idleTTL {
+			delete(kc.series, symbol)
+			delete(kc.lastActive, symbol)
+			evicted = append(evicted, symbol)
+		}
+	}
+	sort.Strings(evicted)
+	return evicted
+}
+
+// aggregateKlines 将较低周期的K线按UTC对齐聚合为目标周期（source 需按开盘时间排序）
+// 开头缺少K线的不完整区间被丢弃；最后一个区间包含仍未收盘的K线，收盘时间为该区间的结束时间
+func aggregateKlines(source []Kline, target time.Duration) []Kline {
+	targetMs := target.Milliseconds()
+	var result []Kline
+	for _, k := range source {
+		bucketStart := k.OpenTime - k.OpenTime%targetMs
+		if len(result) == 0 || result[len(result)-1].OpenTime != bucketStart {
+			result = append(result, Kline{
+				OpenTime:  bucketStart,
+				CloseTime: bucketStart + targetMs - 1,
+				Open:      k.Open,
+				High:      k.High,
+				Low:       k.Low,
+			})
+		}
+		agg := &result[len(result)-1]
+		agg.High = max(agg.High, k.High)
+		agg.Low = min(agg.Low, k.Low)
+		agg.Close = k.Close
+		agg.Volume += k.Volume
+		agg.QuoteVolume += k.QuoteVolume
+		agg.Trades += k.Trades
+		agg.TakerBuyBaseVolume += k.TakerBuyBaseVolume
+		agg.TakerBuyQuoteVolume += k.TakerBuyQuoteVolume
+	}
+	// 第一个区间缺少开头的K线时开盘价和高低点不完整
+	if len(result) > 1 && result[0].OpenTime != source[0].OpenTime {
+		result = result[1:]
+	}
+	return result
+}
+
+// klineFromWS 将实时流K线消息转换为Kline
+func klineFromWS(wsData KlineWSData) Kline {
+	kline := Kline{
+		OpenTime:  wsData.Kline.StartTime,
+		CloseTime: wsData.Kline.CloseTime,
+		Trades:    wsData.Kline.NumberOfTrades,
+	}
+	kline.Open, _ = parseFloat(wsData.Kline.OpenPrice)
+	kline.High, _ = parseFloat(wsData.Kline.HighPrice)
+	kline.Low, _ = parseFloat(wsData.Kline.LowPrice)
+	kline.Close, _ = parseFloat(wsData.Kline.ClosePrice)
+	kline.Volume, _ = parseFloat(wsData.Kline.Volume)
+	kline.QuoteVolume, _ = parseFloat(wsData.Kline.QuoteVolume)
+	kline.TakerBuyBaseVolume, _ = parseFloat(wsData.Kline.TakerBuyBaseVolume)
+	kline.TakerBuyQuoteVolume, _ = parseFloat(wsData.Kline.TakerBuyQuoteVolume)
+	return kline
+}
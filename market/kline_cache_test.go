@@ -0,0 +1,139 @@
+package market
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// threeMinuteKlines 从 start 开始构造 n 根连续的3m K线（第 i 根开盘价为 i，收盘价为 i+1）
+func threeMinuteKlines(start time.Time, n int) []Kline {
+	klines := make([]Kline, n)
+	for i := range klines {
+		open := start.Add(time.Duration(i) * 3 * time.Minute).UnixMilli()
+		klines[i] = Kline{
+			OpenTime:  open,
+			CloseTime: open + 3*60*1000 - 1,
+			Open:      float64(i),
+			High:      float64(i) + 2,
+			Low:       float64(i) - 1,
+			Close:     float64(i) + 1,
+			Volume:    1,
+			Trades:    1,
+		}
+	}
+	return klines
+}
+
+// TestKlineRing 写满后覆盖最旧的K线，同一开盘时间更新当前K线，迟到的旧K线被忽略
+func TestKlineRing(t *testing.T) {
+	kc := NewKlineCache(3, time.Hour)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, k := range threeMinuteKlines(start, 5) {
+		kc.Update("btcusdt", "3m", k)
+	}
+	klines, ok := kc.Klines("BTCUSDT", "3m", 0)
+	if !ok || len(klines) != 3 || klines[0].Open != 2 || klines[2].Open != 4 {
+		t.Fatalf("应只保留最近3根K线: %+v", klines)
+	}
+
+	current := klines[2]
+	current.Close = 99
+	kc.Update("BTCUSDT", "3m", current)
+	kc.Update("BTCUSDT", "3m", threeMinuteKlines(start, 1)[0])
+	klines, _ = kc.Klines("BTCUSDT", "3m", 2)
+	if len(klines) != 2 || klines[1].Close != 99 || klines[0].Open != 3 {
+		t.Errorf("当前K线应被更新且旧K线被忽略: %+v", klines)
+	}
+
+	kc.SetMaxCandles(2)
+	klines, _ = kc.Klines("BTCUSDT", "3m", 0)
+	if len(klines) != 2 || klines[1].Close != 99 {
+		t.Errorf("缩小容量后应保留最近的K线: %+v", klines)
+	}
+}
+
+// TestKlineCacheAggregation 聚合区间按UTC对齐，丢弃开头不完整的区间，最后一个区间包含未收盘的K线
+func TestKlineCacheAggregation(t *testing.T) {
+	kc := NewKlineCache(100, time.Hour)
+	// 从 00:09 开始：00:00 的15m区间和1h区间开头缺少K线
+	start := time.Date(2026, 1, 1, 0, 9, 0, 0, time.UTC)
+	kc.Merge("BTCUSDT", "3m", threeMinuteKlines(start, 37)) // 00:09 ~ 01:57（最后一根未收盘）
+
+	fifteen, ok := kc.Get("BTCUSDT", "15m", 0)
+	if !ok || len(fifteen) != 7 {
+		t.Fatalf("应聚合出7根15m K线: %d", len(fifteen))
+	}
+	first := fifteen[0]
+	if got := time.UnixMilli(first.OpenTime).UTC(); got != time.Date(2026, 1, 1, 0, 15, 0, 0, time.UTC) {
+		t.Errorf("第一根15m K线应从 00:15 开始: %v", got)
+	}
+	// 00:15 区间包含第 2~6 根3m K线
+	if first.Open != 2 || first.Close != 7 || first.High != 8 || first.Low != 1 || first.Volume != 5 || first.Trades != 5 {
+		t.Errorf("15m K线的开高低收或成交量不正确: %+v", first)
+	}
+	if first.CloseTime != first.OpenTime+15*60*1000-1 {
+		t.Errorf("收盘时间应为区间结束时间: %+v", first)
+	}
+	last := fifteen[len(fifteen)-1]
+	if got := time.UnixMilli(last.OpenTime).UTC(); got != time.Date(2026, 1, 1, 1, 45, 0, 0, time.UTC) || last.Close != 37 || last.Volume != 5 {
+		t.Errorf("最后一根15m K线应包含未收盘的K线: %v %+v", got, last)
+	}
+
+	hourly, ok := kc.Get("BTCUSDT", "1h", 0)
+	if !ok || len(hourly) != 1 {
+		t.Fatalf("开头不完整的1h区间应丢弃，只剩 01:00 区间: %d", len(hourly))
+	}
+	if got := time.UnixMilli(hourly[0].OpenTime).UTC(); got != time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC) || hourly[0].Open != 17 || hourly[0].Volume != 20 {
+		t.Errorf("1h K线不正确: %v %+v", got, hourly[0])
+	}
+
+	// 直接订阅的周期优先于聚合；不能整除的周期不聚合
+	kc.Merge("BTCUSDT", "1h", []Kline{{OpenTime: hourly[0].OpenTime, Close: 1}})
+	if direct, _ := kc.Get("BTCUSDT", "1h", 0); len(direct) != 1 || direct[0].Close != 1 {
+		t.Errorf("应使用直接缓存的1h K线: %+v", direct)
+	}
+	if _, ok := kc.Get("BTCUSDT", "5m", 0); ok {
+		t.Error("3m 不能聚合为 5m")
+	}
+
+	snapshot := kc.Snapshot("BTCUSDT", []string{"15m", "1d", "5m"}, 3)
+	if len(snapshot["15m"]) != 3 || len(snapshot["1d"]) != 1 || snapshot["5m"] != nil {
+		t.Errorf("快照结果不正确: %v", snapshot)
+	}
+}
+
+// TestKlineCacheEvictIdle 超过空闲时间没有更新也没有读取的交易对被淘汰
+func TestKlineCacheEvictIdle(t *testing.T) {
+	kc := NewKlineCache(10, time.Minute)
+	kc.Update("BTCUSDT", "3m", Kline{OpenTime: 1})
+	kc.Update("ETHUSDT", "3m", Kline{OpenTime: 1})
+	if evicted := kc.EvictIdle(time.Now()); len(evicted) != 0 {
+		t.Fatalf("活跃的交易对不应淘汰: %v", evicted)
+	}
+	kc.Klines("ETHUSDT", "3m", 0)
+	kc.mu.Lock()
+	kc.lastActive["BTCUSDT"] = time.Now().Add(-2 * time.Minute)
+	kc.mu.Unlock()
+	if evicted := kc.EvictIdle(time.Now()); fmt.Sprint(evicted) != "[BTCUSDT]" {
+		t.Errorf("应淘汰不活跃的 BTCUSDT: %v", evicted)
+	}
+	if _, ok := kc.Klines("BTCUSDT", "3m", 0); ok {
+		t.Error("淘汰后不应再有缓存")
+	}
+}
+
+// TestKlineCacheConsume 从订阅通道读取实时流消息写入缓存
+func TestKlineCacheConsume(t *testing.T) {
+	kc := NewKlineCache(10, time.Hour)
+	ch := make(chan []byte, 2)
+	ch <- []byte(`{"e":"kline","s":"BTCUSDT","k":{"t":60000,"T":239999,"o":"1","h":"3","l":"0.5","c":"2","v":"10","n":7}}`)
+	ch <- []byte(`not json`)
+	close(ch)
+	kc.Consume("BTCUSDT", "3m", ch)
+
+	klines, ok := kc.Klines("BTCUSDT", "3m", 0)
+	if !ok || len(klines) != 1 || klines[0].OpenTime != 60000 || klines[0].Close != 2 || klines[0].Trades != 7 {
+		t.Errorf("实时流K线写入不正确: %+v", klines)
+	}
+}
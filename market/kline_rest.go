@@ -0,0 +1,260 @@
+package market
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// restWeightBudget 行情REST请求每分钟可用的权重（币安上限2400/分钟/IP，留出余量给交易器的请求）
+	restWeightBudget = 1200
+	// restMaxWait 等待权重恢复的最长时间，超过时直接返回错误（避免拖慢决策周期）
+	restMaxWait = 10 * time.Second
+	// maxCachedKlines 启动和重连时补齐的K线数量（也是行情指标使用的K线数量）
+	maxCachedKlines = 100
+)
+
+// klineIntervals 币安K线周期对应的时长
+var klineIntervals = map[string]time.Duration{
+	"1m": time.Minute, "3m": 3 * time.Minute, "5m": 5 * time.Minute, "15m": 15 * time.Minute, "30m": 30 * time.Minute,
+	"1h": time.Hour, "2h": 2 * time.Hour, "4h": 4 * time.Hour, "6h": 6 * time.Hour, "8h": 8 * time.Hour,
+	"12h": 12 * time.Hour, "1d": 24 * time.Hour,
+}
+
+// restLimiter 所有行情REST请求共享的限速器
+var restLimiter = newRESTRateLimiter(restWeightBudget, restMaxWait)
+
+// restTransport 行情REST请求共享的连接池，代理配置与WebSocket一致
+var restTransport = sync.OnceValue(func() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = getProxyFunc()
+	transport.DialContext = proxyDialContext
+	return transport
+})
+
+// restRateLimiter 按币安每分钟请求权重限速，收到 429/418 时暂停到 Retry-After 之后
+type restRateLimiter struct {
+	mu          sync.Mutex
+	budget      int
+	maxWait     time.Duration
+	windowStart time.Time
+	used        int
+	pausedUntil time.Time
+}
+
+func newRESTRateLimiter(budget int, maxWait time.Duration) *restRateLimiter {
+	return &restRateLimiter{budget: budget, maxWait: maxWait}
+}
+
+// wait 占用请求权重，本分钟权重用尽或被交易所限流时等待（超过 maxWait 返回错误）
+func (l *restRateLimiter) wait(weight int) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		var wait time.Duration
+		switch {
+		case now.Before(l.pausedUntil):
+			wait = l.pausedUntil.Sub(now)
+		default:
+			l.rollWindow(now)
+			if l.used+weight <= l.budget {
+				l.used += weight
+				l.mu.Unlock()
+				return nil
+			}
+			wait = l.windowStart.Add(time.Minute).Sub(now)
+		}
+		l.mu.Unlock()
+
+		if wait > l.maxWait {
+			return fmt.Errorf("行情REST请求已达频率限制，需等待 %v", wait.Truncate(time.Second))
+		}
+		time.Sleep(wait)
+	}
+}
+
+// rollWindow 进入新的一分钟时重置已用权重（与交易所按自然分钟统计一致）
+func (l *restRateLimiter) rollWindow(now time.Time) {
+	if now.Sub(l.windowStart) >= time.Minute {
+		l.windowStart = now.Truncate(time.Minute)
+		l.used = 0
+	}
+}
+
+// observe 根据响应头同步交易所统计的已用权重（包含同一IP上其他请求），限流响应时暂停
+func (l *restRateLimiter) observe(resp *http.Response) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.rollWindow(time.Now())
+	if used, err := strconv.Atoi(resp.Header.Get("X-MBX-USED-WEIGHT-1M")); err == nil && used > l.used {
+		l.used = used
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusTeapot {
+		retryAfter := time.Minute
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		l.pausedUntil = time.Now().Add(retryAfter)
+		log.Printf("⚠️  行情REST请求被限流（HTTP %d），暂停 %v", resp.StatusCode, retryAfter)
+	}
+}
+
+// klineRequestWeight /fapi/v1/klines 的请求权重（随 limit 增加）
+func klineRequestWeight(limit int) int {
+	switch {
+	case limit < 100:
+		return 1
+	case limit < 500:
+		return 2
+	case limit <= 1000:
+		return 5
+	default:
+		return 10
+	}
+}
+
+// mergeKlines 合并两组K线，按开盘时间去重排序（同一根K线保留成交笔数更多的版本，即更新的快照），只保留最近 limit 根
+func mergeKlines(base, updates []Kline, limit int) []Kline {
+	byOpenTime := make(map[int64]Kline, len(base)+len(updates))
+	for _, list := range [][]Kline{base, updates} {
+		for _, k := range list {
+			if existing, ok := byOpenTime[k.OpenTime]; !ok || k.Trades >= existing.Trades {
+				byOpenTime[k.OpenTime] = k
+			}
+		}
+	}
+	merged := make([]Kline, 0, len(byOpenTime))
+	for _, k := range byOpenTime {
+		merged = append(merged, k)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].OpenTime < merged[j].OpenTime })
+	if limit > 0 && len(merged) > limit {
+		merged = merged[len(merged)-limit:]
+	}
+	return merged
+}
+
+// klinesOutdated 最后一根K线收盘后又过了一个完整周期仍未更新，说明实时流错过了数据
+func klinesOutdated(klines []Kline, interval string, now time.Time) bool {
+	duration, ok := klineIntervals[interval]
+	if !ok || len(klines) == 0 {
+		return len(klines) == 0
+	}
+	lastClose := time.UnixMilli(klines[len(klines)-1].CloseTime)
+	return now.Sub(lastClose) > duration
+}
+
+// lastKlines 返回最近 n 根K线的副本
+func lastKlines(klines []Kline, n int) []Kline {
+	if n > 0 && len(klines) > n {
+		klines = klines[len(klines)-n:]
+	}
+	result := make([]Kline, len(klines))
+	copy(result, klines)
+	return result
+}
+
+// GetKlines 获取最近 n 根K线：优先使用实时流缓存（未订阅的周期由较低周期聚合），缓存不足或实时流错过了K线
+// （如重连期间）时用REST补齐，与缓存按开盘时间去重合并；REST失败时退回缓存数据
+func GetKlines(symbol, interval string, n int) ([]Kline, error) {
+	symbol = Normalize(symbol)
+	m := WSMonitorCli
+
+	var cached []Kline
+	if m != nil && isCachedInterval(interval) {
+		klines, err := m.GetCurrentKlines(symbol, interval) // 缓存未命中时从REST加载并动态订阅
+		if err != nil {
+			return nil, err
+		}
+		cached = klines
+		if len(cached) >= n && !klinesOutdated(cached, interval, time.Now()) {
+			return lastKlines(cached, n), nil
+		}
+	} else if m != nil {
+		// 聚合K线的收盘时间是区间结束时间，新鲜度看较低周期的实时流
+		base, ok := m.klines.Klines(symbol, subKlineTime[0], 1)
+		if ok && !klinesOutdated(base, subKlineTime[0], time.Now()) {
+			if klines, ok := m.klines.Get(symbol, interval, n); ok && len(klines) >= n {
+				return klines, nil
+			}
+		}
+	}
+
+	fresh, err := NewAPIClient().GetKlines(symbol, interval, max(n, maxCachedKlines))
+	if err != nil {
+		if len(cached) > 0 {
+			log.Printf("⚠️  %s %s K线REST补齐失败: %v，使用缓存数据", symbol, interval, err)
+			return lastKlines(cached, n), nil
+		}
+		return nil, fmt.Errorf("获取%s K线失败: %w", interval, err)
+	}
+	if m != nil {
+		m.mergeKlineCache(symbol, interval, fresh)
+	}
+	return lastKlines(mergeKlines(cached, fresh, 0), n), nil
+}
+
+// isCachedInterval 实时流缓存的K线周期
+func isCachedInterval(interval string) bool {
+	for _, st := range subKlineTime {
+		if st == interval {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeKlineCache 将REST数据合并到实时流缓存（只处理缓存的周期）
+func (m *WSMonitor) mergeKlineCache(symbol, interval string, klines []Kline) {
+	if !isCachedInterval(interval) || len(klines) == 0 {
+		return
+	}
+	m.klines.Merge(symbol, interval, klines)
+}
+
+// backfillStreams 重连后用REST补齐断线期间的K线（只处理K线流，限制并发）
+func (m *WSMonitor) backfillStreams(streams []string) {
+	type target struct{ symbol, interval string }
+	var targets []target
+	for _, stream := range streams {
+		name, interval, ok := strings.Cut(stream, "@kline_")
+		if ok && isCachedInterval(interval) {
+			targets = append(targets, target{strings.ToUpper(name), interval})
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	log.Printf("🔄 重连后补齐 %d 个K线流的数据...", len(targets))
+	apiClient := NewAPIClient()
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, 5) // 限制并发数
+	failed := 0
+	var failedMu sync.Mutex
+	for _, tg := range targets {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(tg target) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			klines, err := apiClient.GetKlines(tg.symbol, tg.interval, maxCachedKlines)
+			if err != nil {
+				failedMu.Lock()
+				failed++
+				failedMu.Unlock()
+				return
+			}
+			m.mergeKlineCache(tg.symbol, tg.interval, klines)
+		}(tg)
+	}
+	wg.Wait()
+	log.Printf("✅ K线补齐完成（失败 %d 个，下次获取时再从REST补齐）", failed)
+}
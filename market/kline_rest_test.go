@@ -0,0 +1,116 @@
+package market
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestMergeKlines 按开盘时间去重排序，同一根K线保留成交笔数更多的版本，只保留最近 limit 根
+func TestMergeKlines(t *testing.T) {
+	base := []Kline{{OpenTime: 1, Trades: 10}, {OpenTime: 2, Trades: 5, Close: 1}, {OpenTime: 3, Trades: 1}}
+	updates := []Kline{{OpenTime: 2, Trades: 8, Close: 2}, {OpenTime: 4, Trades: 3}, {OpenTime: 3, Trades: 0}}
+
+	merged := mergeKlines(base, updates, 3)
+	if len(merged) != 3 || merged[0].OpenTime != 2 || merged[2].OpenTime != 4 {
+		t.Fatalf("合并结果不正确: %+v", merged)
+	}
+	if merged[0].Close != 2 || merged[1].Trades != 1 {
+		t.Errorf("重复K线应保留成交笔数更多的版本: %+v", merged)
+	}
+}
+
+// TestRESTRateLimiter 权重用尽时等待（超过最长等待返回错误），限流响应按 Retry-After 暂停
+func TestRESTRateLimiter(t *testing.T) {
+	l := newRESTRateLimiter(10, 0)
+	for i := 0; i < 2; i++ {
+		if err := l.wait(5); err != nil {
+			t.Fatalf("预算内的请求不应等待: %v", err)
+		}
+	}
+	if err := l.wait(1); err == nil {
+		t.Error("权重用尽时应返回错误")
+	}
+
+	l = newRESTRateLimiter(1000, 0)
+	resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{"X-Mbx-Used-Weight-1m": {"999"}}}
+	l.observe(resp)
+	if err := l.wait(2); err == nil {
+		t.Error("应按响应头同步交易所统计的已用权重")
+	}
+
+	l = newRESTRateLimiter(1000, 0)
+	l.observe(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"30"}}})
+	if err := l.wait(1); err == nil || !strings.Contains(err.Error(), "频率限制") {
+		t.Errorf("限流期间应拒绝请求: %v", err)
+	}
+}
+
+// klineJSON 构造一根REST格式的K线
+func klineJSON(openTime int64, close float64, trades int) string {
+	return fmt.Sprintf(`[%d,"1","2","0.5","%g","10",%d,"100",%d,"5","50",""]`, openTime, close, openTime+59999, trades)
+}
+
+// TestGetKlinesRESTBackfill 缓存不足或过期时从REST补齐并与实时流缓存去重合并，REST失败时退回缓存
+func TestGetKlinesRESTBackfill(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	var requests atomic.Int32
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path != "/fapi/v1/klines" || r.URL.Query().Get("symbol") != "BTCUSDT" {
+			t.Errorf("请求不正确: %s", r.URL)
+		}
+		var rows []string
+		for i := 3; i >= 0; i-- {
+			rows = append(rows, klineJSON(now.Add(-time.Duration(i)*time.Minute).UnixMilli(), float64(100-i), 1))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(rows, ","))
+	}))
+	defer server.Close()
+
+	oldBaseURL, oldMonitor := baseURL, WSMonitorCli
+	baseURL = server.URL
+	defer func() { baseURL, WSMonitorCli = oldBaseURL, oldMonitor }()
+
+	// 没有监控器时直接使用REST
+	WSMonitorCli = nil
+	klines, err := GetKlines("btc", "1m", 2)
+	if err != nil || len(klines) != 2 || klines[1].Close != 100 {
+		t.Fatalf("应返回REST的最近2根K线: %+v %v", klines, err)
+	}
+
+	// 缓存中的当前K线更新（成交笔数更多）优先于REST快照，过期的缓存被补齐
+	m := &WSMonitor{klines: NewKlineCache(0, 0)}
+	WSMonitorCli = m
+	stale := []Kline{
+		{OpenTime: now.Add(-3 * time.Hour).UnixMilli(), CloseTime: now.Add(-3*time.Hour).UnixMilli() + 179999},
+		{OpenTime: now.Add(-time.Minute).UnixMilli(), CloseTime: now.UnixMilli() - 1, Close: 42, Trades: 9},
+	}
+	m.klines.Merge("BTCUSDT", "3m", stale[:1])
+	klines, err = GetKlines("BTCUSDT", "3m", 10)
+	if err != nil || len(klines) != 5 {
+		t.Fatalf("过期缓存应从REST补齐: %d %v", len(klines), err)
+	}
+	m.klines.Merge("BTCUSDT", "3m", stale[1:])
+	cached, _ := m.GetCurrentKlines("BTCUSDT", "3m")
+	if len(cached) != 5 || cached[3].Close != 42 {
+		t.Errorf("重复K线应按开盘时间去重并保留更新的版本: %+v", cached)
+	}
+
+	// REST失败时退回缓存
+	fail.Store(true)
+	before := requests.Load()
+	klines, err = GetKlines("BTCUSDT", "3m", 100)
+	if err != nil || len(klines) != 5 || requests.Load() != before+1 {
+		t.Errorf("REST失败时应返回缓存数据: %d %v", len(klines), err)
+	}
+}
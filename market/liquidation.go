@@ -0,0 +1,282 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// allMarketLiquidationStream 全市场强平订单流（每个交易对每秒最多推送一条最新的强平订单）
+const allMarketLiquidationStream = "!forceOrder@arr"
+
+const (
+	// liquidationRetention 强平数据保留时长（最长统计窗口），同时也是去重记录的保留时长
+	liquidationRetention = 15 * time.Minute
+	// liquidationBucketSize 环形缓冲区每个桶的时长（统计窗口的精度）
+	liquidationBucketSize = 5 * time.Second
+)
+
+// liquidationWindows 强平量统计窗口
+var liquidationWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"1m", time.Minute},
+	{"5m", 5 * time.Minute},
+	{"15m", 15 * time.Minute},
+}
+
+// ForceOrderWSData 强平订单流的原始消息（<symbol>@forceOrder 和 !forceOrder@arr）
+type ForceOrderWSData struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Order     struct {
+		Symbol       string `json:"s"`
+		Side         string `json:"S"`
+		OrderType    string `json:"o"`
+		TimeInForce  string `json:"f"`
+		Quantity     string `json:"q"`
+		Price        string `json:"p"`
+		AveragePrice string `json:"ap"`
+		Status       string `json:"X"`
+		LastFilled   string `json:"l"`
+		FilledQty    string `json:"z"`
+		TradeTime    int64  `json:"T"`
+	} `json:"o"`
+}
+
+// LiquidationEvent 一次强平订单
+type LiquidationEvent struct {
+	Symbol   string
+	Side     string // 强平订单方向：SELL 为多单被强平，BUY 为空单被强平
+	Price    float64
+	Quantity float64
+	Notional float64 // 强平金额（USDT）
+	Time     time.Time
+	key      string // 去重键（重连后交易所可能重复推送同一订单）
+}
+
+// LiquidationStream 强平订单流名称，symbol 为空时为全市场流
+func LiquidationStream(symbol string) string {
+	if symbol == "" {
+		return allMarketLiquidationStream
+	}
+	return fmt.Sprintf("%s@forceOrder", strings.ToLower(symbol))
+}
+
+// ParseForceOrder 解析强平订单消息（成交均价为空时使用委托价格）
+func ParseForceOrder(data []byte) (LiquidationEvent, error) {
+	var raw ForceOrderWSData
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return LiquidationEvent{}, fmt.Errorf("解析强平订单数据失败: %w", err)
+	}
+	if raw.EventType != "forceOrder" {
+		return LiquidationEvent{}, fmt.Errorf("不是强平订单消息: %s", raw.EventType)
+	}
+
+	o := raw.Order
+	qty, err := parseFloat(o.FilledQty)
+	if err != nil || qty == 0 {
+		if qty, err = parseFloat(o.Quantity); err != nil {
+			return LiquidationEvent{}, fmt.Errorf("解析强平数量失败: %w", err)
+		}
+	}
+	price, err := parseFloat(o.AveragePrice)
+	if err != nil || price == 0 {
+		if price, err = parseFloat(o.Price); err != nil {
+			return LiquidationEvent{}, fmt.Errorf("解析强平价格失败: %w", err)
+		}
+	}
+
+	return LiquidationEvent{
+		Symbol:   o.Symbol,
+		Side:     o.Side,
+		Price:    price,
+		Quantity: qty,
+		Notional: price * qty,
+		Time:     time.UnixMilli(o.TradeTime),
+		key:      fmt.Sprintf("%s|%s|%d|%s|%s", o.Symbol, o.Side, o.TradeTime, o.Quantity, o.Price),
+	}, nil
+}
+
+// SubscribeLiquidations 订阅强平订单流：symbols 为空时订阅全市场流，否则按批订阅各交易对的流
+func (c *CombinedStreamsClient) SubscribeLiquidations(symbols []string) error {
+	if len(symbols) == 0 {
+		return c.subscribeStreams([]string{allMarketLiquidationStream})
+	}
+	return c.batchSubscribe(symbols, LiquidationStream)
+}
+
+// LiquidationWindow 一个统计窗口内的强平量
+type LiquidationWindow struct {
+	Window   string  `json:"window"`    // 1m/5m/15m
+	LongUSD  float64 `json:"long_usd"`  // 多单被强平金额
+	ShortUSD float64 `json:"short_usd"` // 空单被强平金额
+	Count    int     `json:"count"`     // 强平订单数
+}
+
+// LiquidationStats 单个交易对在各统计窗口内的强平量
+type LiquidationStats struct {
+	Symbol  string              `json:"symbol"`
+	Windows []LiquidationWindow `json:"windows"`
+}
+
+// total 最长窗口内的强平总金额
+func (s *LiquidationStats) total() float64 {
+	last := s.Windows[len(s.Windows)-1]
+	return last.LongUSD + last.ShortUSD
+}
+
+// liquidationBucket 一个时间桶内的强平量
+type liquidationBucket struct {
+	slot     int64 // 时间桶编号（Unix秒 / 桶时长）
+	longUSD  float64
+	shortUSD float64
+	count    int
+}
+
+// liquidationRing 按时间分桶的环形缓冲区，覆盖最近 liquidationRetention
+type liquidationRing [int(liquidationRetention / liquidationBucketSize)]liquidationBucket
+
+// seenLiquidation 去重记录（按时间先后排队，过期后移除）
+type seenLiquidation struct {
+	key  string
+	time time.Time
+}
+
+// LiquidationTracker 按交易对累积滚动窗口内的强平量（并发安全）
+type LiquidationTracker struct {
+	mu        sync.Mutex
+	rings     map[string]*liquidationRing
+	seen      map[string]bool
+	seenQueue []seenLiquidation
+}
+
+// NewLiquidationTracker 创建强平量累积器
+func NewLiquidationTracker() *LiquidationTracker {
+	return &LiquidationTracker{
+		rings: make(map[string]*liquidationRing),
+		seen:  make(map[string]bool),
+	}
+}
+
+// Add 记录一次强平，超出保留时长或重复推送的订单返回 false
+func (t *LiquidationTracker) Add(event LiquidationEvent, now time.Time) bool {
+	if now.Sub(event.Time) >= liquidationRetention || event.Notional <= 0 {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 移除过期的去重记录
+	expired := 0
+	for expired < len(t.seenQueue) && now.Sub(t.seenQueue[expired].time) >= liquidationRetention {
+		delete(t.seen, t.seenQueue[expired].key)
+		expired++
+	}
+	t.seenQueue = t.seenQueue[expired:]
+
+	if event.key != "" {
+		if t.seen[event.key] {
+			return false
+		}
+		t.seen[event.key] = true
+		t.seenQueue = append(t.seenQueue, seenLiquidation{key: event.key, time: event.Time})
+	}
+
+	ring, ok := t.rings[event.Symbol]
+	if !ok {
+		ring = &liquidationRing{}
+		t.rings[event.Symbol] = ring
+	}
+	slot := liquidationSlot(event.Time)
+	bucket := &ring[slot%int64(len(ring))]
+	if bucket.slot != slot {
+		*bucket = liquidationBucket{slot: slot}
+	}
+	if event.Side == "SELL" {
+		bucket.longUSD += event.Notional
+	} else {
+		bucket.shortUSD += event.Notional
+	}
+	bucket.count++
+	return true
+}
+
+// liquidationSlot 时间所在的时间桶编号
+func liquidationSlot(t time.Time) int64 {
+	return t.Unix() / int64(liquidationBucketSize/time.Second)
+}
+
+// Stats 交易对在各统计窗口内的强平量，最近 liquidationRetention 内没有强平时返回 nil
+func (t *LiquidationTracker) Stats(symbol string, now time.Time) *LiquidationStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ring, ok := t.rings[strings.ToUpper(symbol)]
+	if !ok {
+		return nil
+	}
+	stats := &LiquidationStats{Symbol: strings.ToUpper(symbol), Windows: make([]LiquidationWindow, len(liquidationWindows))}
+	for i, w := range liquidationWindows {
+		stats.Windows[i].Window = w.name
+	}
+	nowSlot := liquidationSlot(now)
+	for _, bucket := range ring {
+		age := time.Duration(nowSlot-bucket.slot) * liquidationBucketSize
+		if bucket.count == 0 || age >= liquidationRetention {
+			continue
+		}
+		if age < 0 { // 交易所时间略快于本地时间
+			age = 0
+		}
+		for i, w := range liquidationWindows {
+			if age < w.duration {
+				stats.Windows[i].LongUSD += bucket.longUSD
+				stats.Windows[i].ShortUSD += bucket.shortUSD
+				stats.Windows[i].Count += bucket.count
+			}
+		}
+	}
+	if stats.Windows[len(stats.Windows)-1].Count == 0 {
+		return nil
+	}
+	return stats
+}
+
+// All 所有交易对的强平量，按最长窗口内的强平总金额从大到小排序
+func (t *LiquidationTracker) All(now time.Time) []*LiquidationStats {
+	t.mu.Lock()
+	symbols := make([]string, 0, len(t.rings))
+	for symbol := range t.rings {
+		symbols = append(symbols, symbol)
+	}
+	t.mu.Unlock()
+
+	result := make([]*LiquidationStats, 0, len(symbols))
+	for _, symbol := range symbols {
+		if stats := t.Stats(symbol, now); stats != nil {
+			result = append(result, stats)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].total() != result[j].total() {
+			return result[i].total() > result[j].total()
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+	return result
+}
+
+// formatLiquidations 强平量的提示词文本
+func formatLiquidations(stats *LiquidationStats) string {
+	parts := make([]string, 0, len(stats.Windows))
+	for _, w := range stats.Windows {
+		parts = append(parts, fmt.Sprintf("%s: longs %.0f / shorts %.0f (%d orders)", w.Window, w.LongUSD, w.ShortUSD, w.Count))
+	}
+	return fmt.Sprintf("Liquidations (USDT liquidated): %s\n\n", strings.Join(parts, ", "))
+}
@@ -0,0 +1,99 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// forceOrderMessage 构造强平订单消息
+func forceOrderMessage(symbol, side string, qty, price string, tradeTime int64) []byte {
+	return []byte(fmt.Sprintf(`{"e":"forceOrder","E":%d,"o":{"s":"%s","S":"%s","o":"LIMIT","f":"IOC","q":"%s","p":"%s","ap":"%s","X":"FILLED","l":"%s","z":"%s","T":%d}}`,
+		tradeTime, symbol, side, qty, price, price, qty, qty, tradeTime))
+}
+
+// TestParseForceOrder 测试强平订单消息解析
+func TestParseForceOrder(t *testing.T) {
+	event, err := ParseForceOrder(forceOrderMessage("BTCUSDT", "SELL", "0.5", "60000", 1700000000000))
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if event.Symbol != "BTCUSDT" || event.Side != "SELL" || event.Notional != 30000 || event.Time.UnixMilli() != 1700000000000 {
+		t.Errorf("解析结果不正确: %+v", event)
+	}
+	if _, err := ParseForceOrder([]byte(`{"e":"markPriceUpdate"}`)); err == nil {
+		t.Error("非强平消息应返回错误")
+	}
+	if LiquidationStream("") != "!forceOrder@arr" || LiquidationStream("ETHUSDT") != "ethusdt@forceOrder" {
+		t.Error("流名称不正确")
+	}
+}
+
+// TestLiquidationTracker 按滚动窗口累积多空强平量，重复推送的订单不重复计数，过期数据不计入
+func TestLiquidationTracker(t *testing.T) {
+	tracker := NewLiquidationTracker()
+	now := time.Unix(1700000000, 0)
+	add := func(symbol, side string, qty string, ago time.Duration) bool {
+		event, err := ParseForceOrder(forceOrderMessage(symbol, side, qty, "100", now.Add(-ago).UnixMilli()))
+		if err != nil {
+			t.Fatalf("解析失败: %v", err)
+		}
+		return tracker.Add(event, now)
+	}
+
+	add("BTCUSDT", "SELL", "1", 10*time.Second)
+	add("BTCUSDT", "BUY", "2", 3*time.Minute)
+	add("BTCUSDT", "SELL", "3", 10*time.Minute)
+	if add("BTCUSDT", "SELL", "1", 10*time.Second) {
+		t.Error("重连后重复推送的订单不应重复计数")
+	}
+	if add("BTCUSDT", "SELL", "9", 20*time.Minute) {
+		t.Error("超出保留时长的订单不应计入")
+	}
+	add("ETHUSDT", "BUY", "50", time.Minute+30*time.Second)
+
+	stats := tracker.Stats("btcusdt", now)
+	if stats == nil || len(stats.Windows) != 3 {
+		t.Fatalf("应返回3个窗口: %+v", stats)
+	}
+	want := []LiquidationWindow{
+		{Window: "1m", LongUSD: 100, Count: 1},
+		{Window: "5m", LongUSD: 100, ShortUSD: 200, Count: 2},
+		{Window: "15m", LongUSD: 400, ShortUSD: 200, Count: 3},
+	}
+	for i, w := range want {
+		if stats.Windows[i] != w {
+			t.Errorf("窗口 %s 不正确: %+v", w.Window, stats.Windows[i])
+		}
+	}
+
+	all := tracker.All(now)
+	if len(all) != 2 || all[0].Symbol != "ETHUSDT" {
+		t.Errorf("应按强平金额排序: %+v", all)
+	}
+	if tracker.Stats("BTCUSDT", now.Add(20*time.Minute)) != nil {
+		t.Error("窗口外没有强平时应返回 nil")
+	}
+	if text := formatLiquidations(stats); !strings.Contains(text, "15m: longs 400 / shorts 200 (3 orders)") {
+		t.Errorf("提示词格式不正确: %s", text)
+	}
+
+	// 并发写入和读取
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				add("SOLUSDT", "SELL", fmt.Sprintf("%d.%d", i+1, j), time.Second)
+				tracker.Stats("SOLUSDT", now)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if stats := tracker.Stats("SOLUSDT", now); stats == nil || stats.Windows[0].Count != 800 {
+		t.Errorf("并发写入应全部计入: %+v", stats)
+	}
+}
@@ -0,0 +1,99 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// MarkPriceWSData 标记价格流的原始消息（<symbol>@markPrice 和 <symbol>@markPrice@1s）
+type MarkPriceWSData struct {
+	EventType            string `json:"e"`
+	EventTime            int64  `json:"E"`
+	Symbol               string `json:"s"`
+	MarkPrice            string `json:"p"`
+	IndexPrice           string `json:"i"`
+	EstimatedSettlePrice string `json:"P"`
+	FundingRate          string `json:"r"`
+	NextFundingTime      int64  `json:"T"`
+}
+
+// MarkPriceUpdate 解析后的标记价格和资金费率
+type MarkPriceUpdate struct {
+	Symbol               string
+	MarkPrice            float64
+	IndexPrice           float64
+	EstimatedSettlePrice float64
+	FundingRate          float64 // 当前资金费率（如 0.0001 表示 0.01%）
+	NextFundingTime      time.Time
+	EventTime            time.Time
+}
+
+// MarkPriceStream 标记价格流名称，interval 为 "1s" 时每秒推送，为空或 "3s" 时每3秒推送
+func MarkPriceStream(symbol, interval string) string {
+	stream := fmt.Sprintf("%s@markPrice", strings.ToLower(symbol))
+	if interval == "1s" {
+		stream += "@1s"
+	}
+	return stream
+}
+
+// ParseMarkPriceUpdate 解析标记价格流消息
+func ParseMarkPriceUpdate(data []byte) (MarkPriceUpdate, error) {
+	var raw MarkPriceWSData
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return MarkPriceUpdate{}, fmt.Errorf("解析标记价格数据失败: %w", err)
+	}
+	if raw.EventType != "markPriceUpdate" {
+		return MarkPriceUpdate{}, fmt.Errorf("不是标记价格消息: %s", raw.EventType)
+	}
+
+	update := MarkPriceUpdate{
+		Symbol:          raw.Symbol,
+		NextFundingTime: time.UnixMilli(raw.NextFundingTime),
+		EventTime:       time.UnixMilli(raw.EventTime),
+	}
+	var err error
+	if update.MarkPrice, err = parseFloat(raw.MarkPrice); err != nil {
+		return MarkPriceUpdate{}, fmt.Errorf("解析标记价格失败: %w", err)
+	}
+	if update.IndexPrice, err = parseFloat(raw.IndexPrice); err != nil {
+		return MarkPriceUpdate{}, fmt.Errorf("解析指数价格失败: %w", err)
+	}
+	// 交割合约没有资金费率，预估结算价和资金费率可能为空
+	update.EstimatedSettlePrice, _ = parseFloat(raw.EstimatedSettlePrice)
+	update.FundingRate, _ = parseFloat(raw.FundingRate)
+	return update, nil
+}
+
+// BatchSubscribeMarkPrice 批量订阅标记价格和资金费率（与K线共用分批和限速，重连后自动恢复）
+func (c *CombinedStreamsClient) BatchSubscribeMarkPrice(symbols []string, interval string) error {
+	return c.batchSubscribe(symbols, func(symbol string) string {
+		return MarkPriceStream(symbol, interval)
+	})
+}
+
+// AddTypedSubscriber 同 AddSubscriber，但用 parse 解码消息后投递（解析失败的消息记录日志后丢弃）
+// 客户端关闭时返回的通道随之关闭
+func AddTypedSubscriber[T any](c *CombinedStreamsClient, stream string, bufferSize int, parse func([]byte) (T, error)) <-chan T {
+	raw := c.AddSubscriber(stream, bufferSize)
+	out := make(chan T, bufferSize)
+	go func() {
+		defer close(out)
+		for data := range raw {
+			value, err := parse(data)
+			if err != nil {
+				log.Printf("⚠️  解析 %s 消息失败: %v", stream, err)
+				continue
+			}
+			select {
+			case out <- value:
+			default:
+				log.Printf("订阅者通道已满: %s", stream)
+			}
+		}
+	}()
+	return out
+}
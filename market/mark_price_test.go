@@ -0,0 +1,55 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+// TestParseMarkPriceUpdate 测试标记价格消息解析
+func TestParseMarkPriceUpdate(t *testing.T) {
+	data := []byte(`{"e":"markPriceUpdate","E":1562305380000,"s":"BTCUSDT","p":"11794.15000000","i":"11784.62659091","P":"11784.25641265","r":"0.00038167","T":1562306400000}`)
+	update, err := ParseMarkPriceUpdate(data)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if update.Symbol != "BTCUSDT" || update.MarkPrice != 11794.15 || update.IndexPrice != 11784.62659091 {
+		t.Errorf("价格解析不正确: %+v", update)
+	}
+	if update.FundingRate != 0.00038167 || update.NextFundingTime.UnixMilli() != 1562306400000 {
+		t.Errorf("资金费率解析不正确: %+v", update)
+	}
+
+	if _, err := ParseMarkPriceUpdate([]byte(`{"e":"kline","s":"BTCUSDT"}`)); err == nil {
+		t.Error("非标记价格消息应返回错误")
+	}
+
+	if got := MarkPriceStream("BTCUSDT", "1s"); got != "btcusdt@markPrice@1s" {
+		t.Errorf("流名称不正确: %s", got)
+	}
+	if got := MarkPriceStream("ETHUSDT", ""); got != "ethusdt@markPrice" {
+		t.Errorf("流名称不正确: %s", got)
+	}
+}
+
+// TestAddTypedSubscriber 组合流消息解码后投递，解析失败的消息被丢弃
+func TestAddTypedSubscriber(t *testing.T) {
+	c := NewCombinedStreamsClient(10)
+	ch := AddTypedSubscriber(c, "btcusdt@markPrice@1s", 10, ParseMarkPriceUpdate)
+
+	c.handleCombinedMessage([]byte(`{"stream":"btcusdt@markPrice@1s","data":{"e":"markPriceUpdate"}}`))
+	c.handleCombinedMessage([]byte(`{"stream":"btcusdt@markPrice@1s","data":{"e":"markPriceUpdate","E":1,"s":"BTCUSDT","p":"100.5","i":"100.4","P":"","r":"0.0001","T":2}}`))
+
+	select {
+	case update := <-ch:
+		if update.MarkPrice != 100.5 || update.FundingRate != 0.0001 {
+			t.Errorf("投递的数据不正确: %+v", update)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("未收到解码后的消息")
+	}
+
+	c.Close()
+	if _, ok := <-ch; ok {
+		t.Error("客户端关闭后通道应关闭")
+	}
+}
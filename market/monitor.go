@@ -1,7 +1,6 @@
 package market
 
 import (
-	"encoding/json"
 	"fmt"
 	"log"
 	"strings"
@@ -15,13 +14,18 @@ type WSMonitor struct {
 	symbols        []string
 	featuresMap    sync.Map
 	alertsChan     chan Alert
-	klineDataMap3m sync.Map // 存储每个交易对的K线历史数据
-	klineDataMap4h sync.Map // 存储每个交易对的K线历史数据
-	tickerDataMap  sync.Map // 存储每个交易对的ticker数据
+	klines         *KlineCache // 所有交易员共享的K线缓存
+	stop           chan struct{}
+	tickers        *TickerTracker // 全市场24小时统计
 	batchSize      int
 	filterSymbols  sync.Map // 使用sync.Map来存储需要监控的币种和其状态
 	symbolStats    sync.Map // 存储币种统计信息
 	FilterSymbol   []string //经过筛选的币种
+	liquidations   *LiquidationTracker
+
+	retainMu       sync.Mutex
+	dynamicStreams map[string][]string        // 按需动态订阅的交易对（不在启动时的列表中）及其流
+	retainedBy     map[string]map[string]bool // 交易员ID -> 交易员使用的交易对
 }
 type SymbolStats struct {
 	LastActiveTime   time.Time
@@ -40,7 +44,14 @@ func NewWSMonitor(batchSize int) *WSMonitor {
 		combinedClient: NewCombinedStreamsClient(batchSize),
 		alertsChan:     make(chan Alert, 1000),
 		batchSize:      batchSize,
+		liquidations:   NewLiquidationTracker(),
+		klines:         NewKlineCache(defaultMaxCandles, defaultKlineIdleTTL),
+		tickers:        NewTickerTracker(nil),
+		stop:           make(chan struct{}),
+		dynamicStreams: make(map[string][]string),
+		retainedBy:     make(map[string]map[string]bool),
 	}
+	WSMonitorCli.combinedClient.SetReconnectHandler(WSMonitorCli.backfillStreams)
 	return WSMonitorCli
 }
 
@@ -89,25 +100,17 @@ func (m *WSMonitor) initializeHistoricalData() error {
 			defer wg.Done()
 			defer func() { <-semaphore }()
 
-			// 获取历史K线数据
-			klines, err := apiClient.GetKlines(s, "3m", 100)
-			if err != nil {
-				log.Printf("获取 %s 历史数据失败: %v", s, err)
-				return
-			}
-			if len(klines) > 0 {
-				m.klineDataMap3m.Store(s, klines)
-				log.Printf("已加载 %s 的历史K线数据-3m: %d 条", s, len(klines))
-			}
-			// 获取历史K线数据
-			klines4h, err := apiClient.GetKlines(s, "4h", 100)
-			if err != nil {
-				log.Printf("获取 %s 历史数据失败: %v", s, err)
-				return
-			}
-			if len(klines4h) > 0 {
-				m.klineDataMap4h.Store(s, klines4h)
-				log.Printf("已加载 %s 的历史K线数据-4h: %d 条", s, len(klines4h))
+			// 获取历史K线数据（与实时流已收到的K线按开盘时间合并）
+			for _, st := range subKlineTime {
+				klines, err := apiClient.GetKlines(s, st, maxCachedKlines)
+				if err != nil {
+					log.Printf("获取 %s 历史数据失败: %v", s, err)
+					return
+				}
+				if len(klines) > 0 {
+					m.mergeKlineCache(s, st, klines)
+					log.Printf("已加载 %s 的历史K线数据-%s: %d 条", s, st, len(klines))
+				}
 			}
 		}(symbol)
 	}
@@ -136,6 +139,131 @@ func (m *WSMonitor) Start(coins []string) {
 		log.Printf("❌ 订阅币种交易对失败: %v", err)
 		return
 	}
+	if err := m.subscribeLiquidations(); err != nil {
+		log.Printf("⚠️  订阅强平订单流失败: %v", err)
+	}
+	if err := m.subscribeTickers(); err != nil {
+		log.Printf("⚠️  订阅全市场24小时行情流失败: %v", err)
+	}
+	go m.evictIdleKlines()
+}
+
+// evictIdleKlines 定期淘汰长时间没有更新也没有读取的交易对K线（如已取消订阅的动态交易对）
+func (m *WSMonitor) evictIdleKlines() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.stop:
+			return
+		case now := <-ticker.C:
+			if evicted := m.klines.EvictIdle(now); len(evicted) > 0 {
+				log.Printf("🧹 淘汰 %d 个不活跃交易对的K线缓存: %v", len(evicted), evicted)
+			}
+		}
+	}
+}
+
+// SetMaxCandles 设置K线缓存每个序列保留的K线数量
+func (m *WSMonitor) SetMaxCandles(n int) {
+	m.klines.SetMaxCandles(n)
+}
+
+// subscribeLiquidations 订阅全市场强平订单流并累积到强平量统计
+func (m *WSMonitor) subscribeLiquidations() error {
+	ch := AddTypedSubscriber(m.combinedClient, LiquidationStream(""), 1000, ParseForceOrder)
+	go func() {
+		for event := range ch {
+			m.liquidations.Add(event, time.Now())
+		}
+	}()
+	return m.combinedClient.SubscribeLiquidations(nil)
+}
+
+// subscribeTickers 订阅全市场24小时行情流（用于按成交额选择最活跃的交易对）
+func (m *WSMonitor) subscribeTickers() error {
+	ch := m.combinedClient.AddSubscriber(TickerArrStream, 10)
+	go func() {
+		for data := range ch {
+			if err := m.tickers.Update(data); err != nil {
+				log.Printf("解析24小时行情失败: %v", err)
+			}
+		}
+	}()
+	return m.combinedClient.subscribeStreams([]string{TickerArrStream})
+}
+
+// GetLiquidationStats 获取交易对最近的强平量（监控器未启动或没有强平时返回 nil）
+func GetLiquidationStats(symbol string) *LiquidationStats {
+	m := WSMonitorCli
+	if m == nil || m.liquidations == nil {
+		return nil
+	}
+	return m.liquidations.Stats(symbol, time.Now())
+}
+
+// GetAllLiquidationStats 获取所有交易对最近的强平量，按强平金额从大到小排序
+func GetAllLiquidationStats() []*LiquidationStats {
+	m := WSMonitorCli
+	if m == nil || m.liquidations == nil {
+		return []*LiquidationStats{}
+	}
+	return m.liquidations.All(time.Now())
+}
+
+// StreamHealth 行情流健康状态
+type StreamHealth struct {
+	Started         bool      `json:"started"`           // 监控器是否已创建
+	Connected       bool      `json:"connected"`         // 组合流是否已连接
+	Reconnecting    bool      `json:"reconnecting"`      // 是否正在重连
+	Symbols         int       `json:"symbols"`           // 监控的交易对数量
+	Connections     int       `json:"connections"`       // 组合流连接数量
+	LastMessageAt   time.Time `json:"last_message_at"`   // 最近一次收到行情消息的时间
+	LastConnectedAt time.Time `json:"last_connected_at"` // 最近一次连接（或重连）成功的时间
+	MessagesPerSec  float64   `json:"messages_per_sec"`  // 最近的每秒消息数
+	Dropped         uint64    `json:"dropped"`           // 订阅者处理不过来丢弃的消息数
+	Reconnects      uint64    `json:"reconnects"`        // 重连成功次数
+	Subscribed      int       `json:"subscribed"`        // 已订阅的流数量
+	Unconfirmed     []string  `json:"unconfirmed"`       // 已订阅但未收到订阅确认的流
+}
+
+// MarketStatus 行情流健康状态及各流的明细
+type MarketStatus struct {
+	StreamHealth
+	StreamCount int          `json:"stream_count"` // 订阅的流数量
+	Streams     []StreamStat `json:"streams"`      // 各流的状态（丢弃数多的在前）
+}
+
+// GetStreamHealth 获取全局行情监控器的健康状态（未启动时 Started=false）
+func GetStreamHealth() StreamHealth {
+	return GetMarketStatus().StreamHealth
+}
+
+// GetMarketStatus 获取全局行情监控器的健康状态和各流的消息统计（未启动时 Started=false）
+func GetMarketStatus() MarketStatus {
+	m := WSMonitorCli
+	if m == nil || m.combinedClient == nil {
+		return MarketStatus{Streams: []StreamStat{}}
+	}
+	h := m.combinedClient.GetHealth()
+	return MarketStatus{
+		StreamHealth: StreamHealth{
+			Started:         true,
+			Connected:       h.Connected,
+			Reconnecting:    h.Reconnecting,
+			Symbols:         len(m.symbols),
+			Connections:     h.Connections,
+			LastMessageAt:   h.LastMessageAt,
+			LastConnectedAt: h.LastConnectedAt,
+			MessagesPerSec:  h.MessagesPerSec,
+			Dropped:         h.Dropped,
+			Reconnects:      h.Reconnects,
+			Subscribed:      h.Subscribed,
+			Unconfirmed:     h.Unconfirmed,
+		},
+		StreamCount: len(h.Streams),
+		Streams:     h.Streams,
+	}
 }
 
 // subscribeSymbol 注册监听
@@ -168,73 +296,12 @@ func (m *WSMonitor) subscribeAll() error {
 }
 
 func (m *WSMonitor) handleKlineData(symbol string, ch <-chan []byte, _time string) {
-	for data := range ch {
-		var klineData KlineWSData
-		if err := json.Unmarshal(data, &klineData); err != nil {
-			log.Printf("解析Kline数据失败: %v", err)
-			continue
-		}
-		m.processKlineUpdate(symbol, klineData, _time)
-	}
-}
-
-func (m *WSMonitor) getKlineDataMap(_time string) *sync.Map {
-	var klineDataMap *sync.Map
-	if _time == "3m" {
-		klineDataMap = &m.klineDataMap3m
-	} else if _time == "4h" {
-		klineDataMap = &m.klineDataMap4h
-	} else {
-		klineDataMap = &sync.Map{}
-	}
-	return klineDataMap
-}
-func (m *WSMonitor) processKlineUpdate(symbol string, wsData KlineWSData, _time string) {
-	// 转换WebSocket数据为Kline结构
-	kline := Kline{
-		OpenTime:  wsData.Kline.StartTime,
-		CloseTime: wsData.Kline.CloseTime,
-		Trades:    wsData.Kline.NumberOfTrades,
-	}
-	kline.Open, _ = parseFloat(wsData.Kline.OpenPrice)
-	kline.High, _ = parseFloat(wsData.Kline.HighPrice)
-	kline.Low, _ = parseFloat(wsData.Kline.LowPrice)
-	kline.Close, _ = parseFloat(wsData.Kline.ClosePrice)
-	kline.Volume, _ = parseFloat(wsData.Kline.Volume)
-	kline.High, _ = parseFloat(wsData.Kline.HighPrice)
-	kline.QuoteVolume, _ = parseFloat(wsData.Kline.QuoteVolume)
-	kline.TakerBuyBaseVolume, _ = parseFloat(wsData.Kline.TakerBuyBaseVolume)
-	kline.TakerBuyQuoteVolume, _ = parseFloat(wsData.Kline.TakerBuyQuoteVolume)
-	// 更新K线数据
-	var klineDataMap = m.getKlineDataMap(_time)
-	value, exists := klineDataMap.Load(symbol)
-	var klines []Kline
-	if exists {
-		klines = value.([]Kline)
-
-		// 检查是否是新的K线
-		if len(klines) > 0 && klines[len(klines)-1].OpenTime == kline.OpenTime {
-			// 更新当前K线
-			klines[len(klines)-1] = kline
-		} else {
-			// 添加新K线
-			klines = append(klines, kline)
-
-			// 保持数据长度
-			if len(klines) > 100 {
-				klines = klines[1:]
-			}
-		}
-	} else {
-		klines = []Kline{kline}
-	}
-
-	klineDataMap.Store(symbol, klines)
+	m.klines.Consume(symbol, _time, ch)
 }
 
 func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, error) {
 	// 对每一个进来的symbol检测是否存在内类 是否的话就订阅它
-	value, exists := m.getKlineDataMap(duration).Load(symbol)
+	klines, exists := m.klines.Klines(symbol, duration, 0)
 	if !exists {
 		// 如果Ws数据未初始化完成时,单独使用api获取 - 兼容性代码 (防止在未初始化完成是,已经有交易员运行)
 		apiClient := NewAPIClient()
@@ -244,10 +311,11 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 		}
 
 		// 动态缓存进缓存
-		m.getKlineDataMap(duration).Store(strings.ToUpper(symbol), klines)
+		m.klines.Merge(symbol, duration, klines)
 
 		// 订阅 WebSocket 流
 		subStr := m.subscribeSymbol(symbol, duration)
+		m.trackDynamicStreams(symbol, subStr)
 		subErr := m.combinedClient.subscribeStreams(subStr)
 		log.Printf("动态订阅流: %v", subStr)
 		if subErr != nil {
@@ -260,14 +328,91 @@ func (m *WSMonitor) GetCurrentKlines(symbol string, duration string) ([]Kline, e
 		return result, nil
 	}
 
-	// ✅ FIX: 返回深拷贝而非引用，避免并发竞态条件
-	klines := value.([]Kline)
-	result := make([]Kline, len(klines))
-	copy(result, klines)
-	return result, nil
+	// 缓存返回的已是副本，避免并发竞态条件
+	return klines, nil
+}
+
+// trackDynamicStreams 记录按需动态订阅的流（交易员停止或不再使用该交易对时释放）
+func (m *WSMonitor) trackDynamicStreams(symbol string, streams []string) {
+	m.retainMu.Lock()
+	defer m.retainMu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	m.dynamicStreams[symbol] = append(m.dynamicStreams[symbol], streams...)
+}
+
+// retainSymbols 登记交易员使用的交易对（替换之前的登记）并释放不再被使用的动态订阅
+func (m *WSMonitor) retainSymbols(traderID string, symbols []string) {
+	m.retainMu.Lock()
+	set := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		set[strings.ToUpper(symbol)] = true
+	}
+	m.retainedBy[traderID] = set
+	m.retainMu.Unlock()
+	m.releaseUnusedStreams()
+}
+
+// releaseSymbols 取消交易员的登记并释放不再被使用的动态订阅
+func (m *WSMonitor) releaseSymbols(traderID string) {
+	m.retainMu.Lock()
+	delete(m.retainedBy, traderID)
+	m.retainMu.Unlock()
+	m.releaseUnusedStreams()
+}
+
+// releaseUnusedStreams 取消没有交易员登记的动态订阅，关闭订阅者通道并清除K线缓存
+// 仍需要数据的交易对（如候选币种）下次获取时会重新订阅
+func (m *WSMonitor) releaseUnusedStreams() {
+	m.retainMu.Lock()
+	var streams []string
+	var symbols []string
+	for symbol, symbolStreams := range m.dynamicStreams {
+		retained := false
+		for _, set := range m.retainedBy {
+			if set[symbol] {
+				retained = true
+				break
+			}
+		}
+		if !retained {
+			symbols = append(symbols, symbol)
+			streams = append(streams, symbolStreams...)
+			delete(m.dynamicStreams, symbol)
+		}
+	}
+	m.retainMu.Unlock()
+
+	if len(streams) == 0 {
+		return
+	}
+	for _, stream := range streams {
+		m.combinedClient.RemoveSubscriber(stream)
+	}
+	for _, symbol := range symbols {
+		m.klines.DeleteSymbol(symbol)
+	}
+	log.Printf("🧹 释放 %d 个不再使用的交易对的行情订阅: %v", len(symbols), symbols)
+	if err := m.combinedClient.UnsubscribeStreams(streams); err != nil {
+		log.Printf("⚠️  取消订阅失败: %v", err)
+	}
+}
+
+// RetainSymbols 登记交易员正在使用的交易对（交易币种变更时重新登记），释放其他不再被使用的动态订阅
+func RetainSymbols(traderID string, symbols []string) {
+	if m := WSMonitorCli; m != nil {
+		m.retainSymbols(traderID, symbols)
+	}
+}
+
+// ReleaseSymbols 交易员停止时取消登记，释放只有该交易员使用的动态订阅
+func ReleaseSymbols(traderID string) {
+	if m := WSMonitorCli; m != nil {
+		m.releaseSymbols(traderID)
+	}
 }
 
 func (m *WSMonitor) Close() {
+	close(m.stop)
 	m.wsClient.Close()
 	close(m.alertsChan)
 }
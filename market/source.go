@@ -0,0 +1,56 @@
+package market
+
+import "strings"
+
+// Source 行情数据来源：交易员使用所在交易所的行情，避免依赖无法访问的交易所和价格基差
+type Source interface {
+	// Name 来源名称（如 binance、hyperliquid）
+	Name() string
+	// GetKlines 获取最近 n 根K线（实时流缓存 + REST补齐）
+	GetKlines(symbol, interval string, n int) ([]Kline, error)
+	// GetOpenInterest 获取持仓量
+	GetOpenInterest(symbol string) (*OIData, error)
+	// GetFundingRate 获取最近的资金费率
+	GetFundingRate(symbol string) (float64, error)
+	// Snapshot 获取多个周期的最近K线（用于决策提示词，没有数据的周期不包含在结果中）
+	Snapshot(symbol string, intervals []string, n int) map[string][]Kline
+}
+
+// midPricer 能提供实时中间价的行情来源（用于当前价格，比K线收盘价更及时）
+type midPricer interface {
+	MidPrice(symbol string) (float64, bool)
+}
+
+// binanceSource 币安行情（全局行情监控器的实时流缓存 + REST）
+type binanceSource struct{}
+
+// Binance 默认的币安行情来源
+var Binance Source = binanceSource{}
+
+func (binanceSource) Name() string { return "binance" }
+
+func (binanceSource) GetKlines(symbol, interval string, n int) ([]Kline, error) {
+	return GetKlines(symbol, interval, n)
+}
+
+func (binanceSource) GetOpenInterest(symbol string) (*OIData, error) {
+	return getOpenInterestData(symbol)
+}
+
+func (binanceSource) GetFundingRate(symbol string) (float64, error) {
+	return getFundingRate(symbol)
+}
+
+func (binanceSource) Snapshot(symbol string, intervals []string, n int) map[string][]Kline {
+	return GetKlineSnapshot(symbol, intervals, n)
+}
+
+// SourceForExchange 根据交易员的交易所选择行情来源（Hyperliquid 使用自己的行情，其他交易所使用币安行情）
+func SourceForExchange(exchange string, testnet bool) Source {
+	switch strings.ToLower(exchange) {
+	case "hyperliquid":
+		return HyperliquidMarket(testnet)
+	default:
+		return Binance
+	}
+}
@@ -0,0 +1,206 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// errConnectionLost 等待响应期间连接断开（流仍在订阅列表中，重连后重新订阅并确认）
+var errConnectionLost = errors.New("连接已断开")
+
+// streamRequest 等待响应的 SUBSCRIBE/UNSUBSCRIBE 请求
+type streamRequest struct {
+	id      int64
+	method  string
+	streams []string
+	shard   *streamShard
+	conn    *websocket.Conn
+	done    chan error // 收到响应（nil 或拒绝原因）或连接断开时写入，缓冲为1
+}
+
+// streamError 币安对订阅请求返回的错误（{"error":{"code":2,"msg":"..."},"id":1}）
+type streamError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// newRequest 分配请求编号并登记为等待响应
+func (c *CombinedStreamsClient) newRequest(shard *streamShard, conn *websocket.Conn, method string, streams []string) *streamRequest {
+	req := &streamRequest{
+		id:      c.requestID.Add(1),
+		method:  method,
+		streams: streams,
+		shard:   shard,
+		conn:    conn,
+		done:    make(chan error, 1),
+	}
+	c.pendingMu.Lock()
+	c.pending[req.id] = req
+	c.pendingMu.Unlock()
+	return req
+}
+
+// forgetRequest 取消登记（请求发送失败时）
+func (c *CombinedStreamsClient) forgetRequest(id int64) {
+	c.pendingMu.Lock()
+	delete(c.pending, id)
+	c.pendingMu.Unlock()
+}
+
+// failPending 连接断开时结束该连接上所有等待响应的请求
+func (c *CombinedStreamsClient) failPending(conn *websocket.Conn) {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for id, req := range c.pending {
+		if req.conn == conn {
+			delete(c.pending, id)
+			req.done <- errConnectionLost
+		}
+	}
+}
+
+// handleResponse 处理订阅请求的响应：确认后记录为已确认的流；被拒绝的 SUBSCRIBE 中的流从订阅列表移除（重连后也不再恢复）
+func (c *CombinedStreamsClient) handleResponse(id *int64, respErr *streamError) {
+	if id == nil {
+		if respErr != nil {
+			log.Printf("❌ 组合流返回错误（无请求编号）: code=%d %s", respErr.Code, respErr.Msg)
+		}
+		return
+	}
+	c.pendingMu.Lock()
+	req, ok := c.pending[*id]
+	delete(c.pending, *id)
+	c.pendingMu.Unlock()
+	if !ok {
+		return // 连接已断开，或不是本客户端发出的请求
+	}
+
+	if respErr != nil {
+		err := fmt.Errorf("币安拒绝%s请求（code=%d: %s），流: %s", req.method, respErr.Code, respErr.Msg, strings.Join(req.streams, ","))
+		log.Printf("❌ %v", err)
+		if req.method == "SUBSCRIBE" {
+			c.dropStreams(req.shard, req.streams)
+		}
+		req.done <- err
+		return
+	}
+
+	if req.method == "SUBSCRIBE" {
+		c.mu.Lock()
+		for _, stream := range req.streams {
+			if req.shard.streams[stream] {
+				req.shard.confirmed[stream] = true
+			}
+		}
+		c.mu.Unlock()
+	}
+	req.done <- nil
+}
+
+// dropStreams 从分片的订阅列表中移除被拒绝的流
+func (c *CombinedStreamsClient) dropStreams(shard *streamShard, streams []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, stream := range streams {
+		if c.shardOf[stream] == shard {
+			delete(c.shardOf, stream)
+		}
+		delete(shard.streams, stream)
+		delete(shard.confirmed, stream)
+	}
+}
+
+// awaitResponses 在 ackTimeout 内等待请求的响应，返回超时未响应的流和被拒绝的原因（连接断开不算错误）
+func (c *CombinedStreamsClient) awaitResponses(reqs []*streamRequest) (timedOut []string, err error) {
+	ctx, cancel := context.WithTimeout(c.ctx, c.ackTimeout)
+	defer cancel()
+	for _, req := range reqs {
+		var reqErr error
+		select {
+		case reqErr = <-req.done:
+		case <-ctx.Done():
+			select {
+			case reqErr = <-req.done:
+			default:
+				timedOut = append(timedOut, req.streams...)
+				continue
+			}
+		}
+		if reqErr != nil && !errors.Is(reqErr, errConnectionLost) {
+			err = errors.Join(err, reqErr)
+		}
+	}
+	return timedOut, err
+}
+
+// confirm 等待订阅确认：超时未确认的流在当前连接上重发一次，被拒绝或重发后仍未确认时返回带流名称的错误
+func (s *streamShard) confirm(reqs []*streamRequest) error {
+	timedOut, err := s.client.awaitResponses(reqs)
+	if len(timedOut) == 0 {
+		return err
+	}
+	conn := s.currentConn()
+	timedOut = s.stillSubscribed(timedOut)
+	if conn == nil || len(timedOut) == 0 {
+		return err // 连接断开的流在重连后重新订阅
+	}
+
+	log.Printf("⚠️  组合流连接#%d %d 个流在 %v 内未收到订阅确认，重新发送", s.id, len(timedOut), s.client.ackTimeout)
+	retry, sendErr := s.send(conn, "SUBSCRIBE", timedOut)
+	if sendErr != nil {
+		return errors.Join(err, sendErr)
+	}
+	timedOut, retryErr := s.client.awaitResponses(retry)
+	err = errors.Join(err, retryErr)
+	if timedOut = s.stillSubscribed(timedOut); len(timedOut) > 0 {
+		err = errors.Join(err, fmt.Errorf("订阅未确认（重发后 %v 内仍无响应），流: %s", s.client.ackTimeout, strings.Join(timedOut, ",")))
+	}
+	return err
+}
+
+// stillSubscribed 过滤出仍由该分片负责的流（等待期间可能已取消订阅）
+func (s *streamShard) stillSubscribed(streams []string) []string {
+	s.client.mu.RLock()
+	defer s.client.mu.RUnlock()
+	var result []string
+	for _, stream := range streams {
+		if s.streams[stream] {
+			result = append(result, stream)
+		}
+	}
+	return result
+}
+
+// ConfirmedStreams 已收到订阅确认的流（排序后返回；连接断开后该连接的流需重新确认）
+func (c *CombinedStreamsClient) ConfirmedStreams() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var streams []string
+	for _, shard := range c.shards {
+		for stream := range shard.confirmed {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+	return streams
+}
+
+// UnconfirmedStreams 已订阅但尚未收到确认的流（排序后返回）
+func (c *CombinedStreamsClient) UnconfirmedStreams() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var streams []string
+	for stream, shard := range c.shardOf {
+		if !shard.confirmed[stream] {
+			streams = append(streams, stream)
+		}
+	}
+	sort.Strings(streams)
+	return streams
+}
@@ -0,0 +1,148 @@
+package market
+
+import (
+	"log"
+	"nofx/metrics"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// streamRateWindow 计算每秒消息数的统计窗口
+const streamRateWindow = 10 * time.Second
+
+// streamStats 单个流的消息统计
+type streamStats struct {
+	lastMessageAt atomic.Int64 // UnixNano
+	messages      atomic.Uint64
+	dropped       atomic.Uint64 // 订阅者通道已满时丢弃的消息数
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+	rate        float64 // 上一个完整统计窗口的每秒消息数
+}
+
+// record 记录一条消息（dropped 表示订阅者处理不过来被丢弃）
+func (s *streamStats) record(now time.Time, dropped bool) {
+	s.lastMessageAt.Store(now.UnixNano())
+	s.messages.Add(1)
+	if dropped {
+		s.dropped.Add(1)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+	}
+	if elapsed := now.Sub(s.windowStart); elapsed >= streamRateWindow {
+		s.rate = float64(s.windowCount) / elapsed.Seconds()
+		s.windowStart, s.windowCount = now, 0
+	}
+	s.windowCount++
+}
+
+// ratePerSec 每秒消息数（超过两个统计窗口没有消息时为0）
+func (s *streamStats) ratePerSec(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.windowStart.IsZero() || now.Sub(s.windowStart) > 2*streamRateWindow {
+		return 0
+	}
+	return s.rate
+}
+
+// StreamStat 单个流的健康状态
+type StreamStat struct {
+	Stream         string    `json:"stream"`
+	Connection     int       `json:"connection"`       // 所在连接编号（0 表示尚未分配连接）
+	LastMessageAt  time.Time `json:"last_message_at"`  // 最近一次收到消息的时间
+	Messages       uint64    `json:"messages"`         // 累计消息数
+	MessagesPerSec float64   `json:"messages_per_sec"` // 最近的每秒消息数
+	Dropped        uint64    `json:"dropped"`          // 订阅者通道已满时丢弃的消息数（持续增长说明消费者太慢）
+	Confirmed      bool      `json:"confirmed"`        // 是否已收到订阅确认
+}
+
+// CombinedStreamsHealth 组合流客户端的健康状态
+type CombinedStreamsHealth struct {
+	Connected       bool         `json:"connected"`         // 所有连接是否都已连接
+	Reconnecting    bool         `json:"reconnecting"`      // 是否有连接正在重连
+	Connections     int          `json:"connections"`       // 连接数量
+	LastMessageAt   time.Time    `json:"last_message_at"`   // 最近一次收到消息的时间
+	LastConnectedAt time.Time    `json:"last_connected_at"` // 最近一次连接（或重连）成功的时间
+	Messages        uint64       `json:"messages"`          // 累计消息数
+	MessagesPerSec  float64      `json:"messages_per_sec"`  // 所有流最近的每秒消息数之和
+	Dropped         uint64       `json:"dropped"`           // 累计丢弃的消息数
+	Reconnects      uint64       `json:"reconnects"`        // 累计重连成功次数
+	Subscribed      int          `json:"subscribed"`        // 已订阅的流数量
+	Unconfirmed     []string     `json:"unconfirmed"`       // 已订阅但尚未收到确认的流
+	Streams         []StreamStat `json:"streams"`           // 各流的状态（丢弃数多的在前）
+}
+
+// recordMessage 记录流收到的消息，订阅者通道已满时累计丢弃数（调用方需持有读锁）
+func (c *CombinedStreamsClient) recordMessage(stream string, now time.Time, dropped bool) {
+	c.messages.Add(1)
+	stats, ok := c.stats[stream]
+	if !ok {
+		return
+	}
+	stats.record(now, dropped)
+	if !dropped {
+		return
+	}
+	c.dropped.Add(1)
+	metrics.WSDroppedMessages.Inc()
+	// 只在第一次和每1000次时记录日志，避免慢消费者刷屏
+	if n := stats.dropped.Load(); n == 1 || n%1000 == 0 {
+		log.Printf("⚠️  订阅者通道已满: %s（累计丢弃 %d 条）", stream, n)
+	}
+}
+
+// GetHealth 获取连接状态、消息速率、丢弃消息数和重连次数（含各流的明细）
+func (c *CombinedStreamsClient) GetHealth() CombinedStreamsHealth {
+	now := time.Now()
+	health := CombinedStreamsHealth{
+		Connected:       c.IsConnected(),
+		Reconnecting:    c.IsReconnecting(),
+		LastMessageAt:   c.LastMessageTime(),
+		LastConnectedAt: c.LastConnectedTime(),
+		Messages:        c.messages.Load(),
+		Dropped:         c.dropped.Load(),
+		Reconnects:      c.reconnects.Load(),
+		Unconfirmed:     c.UnconfirmedStreams(),
+	}
+
+	c.mu.RLock()
+	health.Connections = len(c.shards)
+	health.Subscribed = len(c.shardOf)
+	health.Streams = make([]StreamStat, 0, len(c.stats))
+	for stream, stats := range c.stats {
+		stat := StreamStat{
+			Stream:         stream,
+			Messages:       stats.messages.Load(),
+			MessagesPerSec: stats.ratePerSec(now),
+			Dropped:        stats.dropped.Load(),
+		}
+		if ns := stats.lastMessageAt.Load(); ns != 0 {
+			stat.LastMessageAt = time.Unix(0, ns)
+		}
+		if shard, ok := c.shardOf[stream]; ok {
+			stat.Connection = shard.id
+			stat.Confirmed = shard.confirmed[stream]
+		}
+		health.MessagesPerSec += stat.MessagesPerSec
+		health.Streams = append(health.Streams, stat)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(health.Streams, func(i, j int) bool {
+		a, b := health.Streams[i], health.Streams[j]
+		if a.Dropped != b.Dropped {
+			return a.Dropped > b.Dropped
+		}
+		return a.Stream < b.Stream
+	})
+	return health
+}
@@ -0,0 +1,55 @@
+package market
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestStreamStatsRate 每秒消息数按完整统计窗口计算，长时间没有消息时归零
+func TestStreamStatsRate(t *testing.T) {
+	var s streamStats
+	start := time.Now()
+	for i := 0; i <= 50; i++ {
+		s.record(start.Add(time.Duration(i)*streamRateWindow/50), false)
+	}
+	now := start.Add(streamRateWindow)
+	if rate := s.ratePerSec(now); rate != 5 {
+		t.Errorf("10秒50条消息应为 5/s: %v", rate)
+	}
+	if rate := s.ratePerSec(now.Add(3 * streamRateWindow)); rate != 0 {
+		t.Errorf("长时间没有消息时速率应为0: %v", rate)
+	}
+	if s.messages.Load() != 51 || s.dropped.Load() != 0 {
+		t.Errorf("消息计数不正确: %d %d", s.messages.Load(), s.dropped.Load())
+	}
+}
+
+// TestCombinedStreamsHealthDropped 订阅者通道已满时按流累计丢弃数，慢消费者排在最前
+func TestCombinedStreamsHealthDropped(t *testing.T) {
+	c := NewCombinedStreamsClient(10)
+	slow := c.AddSubscriber("btcusdt@kline_3m", 1)
+	fast := c.AddSubscriber("ethusdt@kline_3m", 10)
+	for i := 0; i < 3; i++ {
+		for _, stream := range []string{"btcusdt@kline_3m", "ethusdt@kline_3m"} {
+			c.handleCombinedMessage([]byte(fmt.Sprintf(`{"stream":"%s","data":{}}`, stream)))
+		}
+	}
+	c.handleCombinedMessage([]byte(`{"stream":"unknown@kline_3m","data":{}}`))
+
+	h := c.GetHealth()
+	if h.Messages != 6 || h.Dropped != 2 || h.Connected || h.LastMessageAt.IsZero() {
+		t.Fatalf("汇总状态不正确: %+v", h)
+	}
+	if len(h.Streams) != 2 || h.Streams[0].Stream != "btcusdt@kline_3m" || h.Streams[0].Dropped != 2 || h.Streams[1].Dropped != 0 {
+		t.Errorf("各流丢弃数不正确: %+v", h.Streams)
+	}
+	if len(slow) != 1 || len(fast) != 3 {
+		t.Errorf("未满的通道应收到消息: %d %d", len(slow), len(fast))
+	}
+
+	c.RemoveSubscriber("btcusdt@kline_3m")
+	if h := c.GetHealth(); len(h.Streams) != 1 || h.Dropped != 2 {
+		t.Errorf("移除订阅者后应删除该流明细并保留累计值: %+v", h)
+	}
+}
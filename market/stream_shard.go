@@ -0,0 +1,321 @@
+package market
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"nofx/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// streamShard 组合流的一个WebSocket连接，负责其中一部分流（独立保活和重连）
+// 分片负责的流记录在 streams 中，由 CombinedStreamsClient.mu 保护
+type streamShard struct {
+	client          *CombinedStreamsClient
+	id              int
+	ctx             context.Context // 客户端关闭或分片被回收时取消
+	cancel          context.CancelFunc
+	mu              sync.RWMutex
+	conn            *websocket.Conn
+	writeMu         sync.Mutex      // 同一连接不支持并发写数据帧（控制帧除外）
+	streams         map[string]bool // 该连接上订阅的流，用于重连后恢复
+	confirmed       map[string]bool // 当前连接上已收到订阅确认的流
+	reconnecting    atomic.Bool     // 保证同一时间只有一个重连循环
+	lastConnectedAt atomic.Int64    // 最近一次连接成功的时间（UnixNano）
+}
+
+// newStreamShard 创建分片（尚未连接）
+func newStreamShard(c *CombinedStreamsClient, id int) *streamShard {
+	ctx, cancel := context.WithCancel(c.ctx)
+	return &streamShard{
+		client:    c,
+		id:        id,
+		ctx:       ctx,
+		cancel:    cancel,
+		streams:   make(map[string]bool),
+		confirmed: make(map[string]bool),
+	}
+}
+
+// connect 建立连接、恢复该分片的订阅并启动读取和保活goroutine，返回等待确认的订阅请求
+func (s *streamShard) connect() ([]*streamRequest, error) {
+	conn, err := s.dial()
+	if err != nil {
+		return nil, err
+	}
+	reqs, err := s.resubscribe(conn)
+	if err != nil {
+		s.client.failPending(conn)
+		conn.Close()
+		return nil, err
+	}
+	if err := s.start(conn); err != nil {
+		s.client.failPending(conn)
+		return nil, err
+	}
+	return reqs, nil
+}
+
+// dial 建立连接并设置保活（不启动读取循环）
+func (s *streamShard) dial() (*websocket.Conn, error) {
+	dialer := websocket.Dialer{
+		HandshakeTimeout: 45 * time.Second, // 增加超时时间以适应代理
+		Proxy:            getProxyFunc(),   // ✅ 添加代理支持
+		NetDialContext:   proxyDialContext, // SOCKS5 代理
+	}
+
+	// 组合流使用不同的端点
+	conn, _, err := dialer.DialContext(s.ctx, s.client.endpoint, nil)
+	if err != nil {
+		return nil, fmt.Errorf("组合流WebSocket连接失败: %v", err)
+	}
+	s.setupKeepAlive(conn)
+	return conn, nil
+}
+
+// start 启用连接并启动该连接的读取和保活goroutine
+func (s *streamShard) start(conn *websocket.Conn) error {
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		// 连接过程中客户端已关闭或分片已回收
+		s.mu.Unlock()
+		conn.Close()
+		return fmt.Errorf("组合流连接#%d已关闭", s.id)
+	}
+	s.conn = conn
+	s.mu.Unlock()
+	s.lastConnectedAt.Store(time.Now().UnixNano())
+
+	log.Printf("组合流WebSocket连接#%d成功", s.id)
+	go s.readMessages(conn)
+	go s.keepAlive(conn)
+
+	return nil
+}
+
+// currentConn 当前连接（重连期间为 nil）
+func (s *streamShard) currentConn() *websocket.Conn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.conn
+}
+
+// setupKeepAlive 收到pong或服务端ping时延长读取超时，并及时回复服务端的ping（币安长时间未收到pong会断开连接）
+func (s *streamShard) setupKeepAlive(conn *websocket.Conn) {
+	readTimeout := s.client.readTimeout
+	conn.SetReadDeadline(time.Now().Add(readTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(readTimeout))
+	})
+	conn.SetPingHandler(func(data string) error {
+		conn.SetReadDeadline(time.Now().Add(readTimeout))
+		err := conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(wsWriteTimeout))
+		if err == websocket.ErrCloseSent {
+			return nil
+		}
+		return err
+	})
+}
+
+// keepAlive 定期发送ping，连接失效时由读取超时触发重连
+func (s *streamShard) keepAlive(conn *websocket.Conn) {
+	ticker := time.NewTicker(s.client.pingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteTimeout)); err != nil {
+				return // 连接已关闭，读取循环负责重连
+			}
+		}
+	}
+}
+
+// writeJSON 串行写入JSON消息
+func (s *streamShard) writeJSON(conn *websocket.Conn, v interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	return conn.WriteJSON(v)
+}
+
+// send 在连接上按 batchSize 分批发送 SUBSCRIBE/UNSUBSCRIBE（批次间限速），返回等待响应的请求
+func (s *streamShard) send(conn *websocket.Conn, method string, streams []string) ([]*streamRequest, error) {
+	batches := s.client.splitIntoBatches(streams, s.client.batchSize)
+	reqs := make([]*streamRequest, 0, len(batches))
+	for i, batch := range batches {
+		req := s.client.newRequest(s, conn, method, batch)
+		msg := map[string]interface{}{
+			"method": method,
+			"params": batch,
+			"id":     req.id,
+		}
+		if err := s.writeJSON(conn, msg); err != nil {
+			s.client.forgetRequest(req.id)
+			return reqs, fmt.Errorf("%s 失败: %w", method, err)
+		}
+		reqs = append(reqs, req)
+		if i < len(batches)-1 {
+			time.Sleep(subscribeBatchDelay)
+		}
+	}
+	return reqs, nil
+}
+
+func (s *streamShard) readMessages(conn *websocket.Conn) {
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return // 主动关闭
+			}
+			var netErr interface{ Timeout() bool }
+			switch {
+			case websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway):
+				log.Printf("⚠️  服务端关闭组合流连接#%d: %v，触发重连...", s.id, err)
+			case errors.As(err, &netErr) && netErr.Timeout():
+				log.Printf("⚠️  WebSocket连接#%d 读取超时（%v无数据），触发重连...", s.id, s.client.readTimeout)
+			default:
+				log.Printf("读取组合流连接#%d消息失败: %v", s.id, err)
+			}
+			s.dropConnection(conn)
+			s.handleReconnect()
+			return
+		}
+
+		conn.SetReadDeadline(time.Now().Add(s.client.readTimeout))
+		s.client.handleCombinedMessage(message)
+	}
+}
+
+// dropConnection 关闭失效的连接（重连期间 IsConnected 返回 false），该连接上的订阅需在重连后重新确认
+func (s *streamShard) dropConnection(conn *websocket.Conn) {
+	s.mu.Lock()
+	if s.conn == conn {
+		s.conn = nil
+	}
+	s.mu.Unlock()
+	conn.Close()
+
+	s.client.mu.Lock()
+	clear(s.confirmed)
+	s.client.mu.Unlock()
+	s.client.failPending(conn)
+}
+
+// handleReconnect 重连循环：按指数退避（带随机抖动）重试直到成功、客户端关闭或分片被回收
+// 同一时间只运行一个重连循环；恢复订阅完成后才启动新连接的读取goroutine
+func (s *streamShard) handleReconnect() {
+	if !s.reconnecting.CompareAndSwap(false, true) {
+		return
+	}
+
+	delay := s.client.reconnectBase
+	for attempt := 1; ; attempt++ {
+		wait := jitterDelay(delay)
+		log.Printf("组合流连接#%d 第 %d 次尝试重新连接（等待 %v）...", s.id, attempt, wait.Truncate(time.Millisecond))
+		select {
+		case <-s.ctx.Done():
+			s.reconnecting.Store(false)
+			return
+		case <-time.After(wait):
+		}
+
+		conn, err := s.dial()
+		var reqs []*streamRequest
+		if err == nil {
+			if reqs, err = s.resubscribe(conn); err != nil {
+				s.client.failPending(conn)
+				conn.Close()
+			}
+		}
+		if err != nil {
+			log.Printf("组合流连接#%d重新连接失败: %v", s.id, err)
+			metrics.WSReconnects.Inc("failure")
+			delay = nextReconnectDelay(delay, s.client.reconnectMax)
+			continue
+		}
+
+		// 先结束重连状态再启动读取，新连接立即失效时可以再次进入重连
+		s.reconnecting.Store(false)
+		if err := s.start(conn); err != nil {
+			s.client.failPending(conn)
+			return // 客户端已关闭
+		}
+		go func() {
+			if err := s.confirm(reqs); err != nil {
+				log.Printf("⚠️  组合流连接#%d 恢复订阅未全部确认: %v", s.id, err)
+			}
+		}()
+		metrics.WSReconnects.Inc("success")
+		s.client.reconnects.Add(1)
+		log.Printf("✅ 组合流连接#%d 第 %d 次尝试重连成功", s.id, attempt)
+		if handler := s.client.reconnectHandler(); handler != nil {
+			go handler(s.streamList()) // 补齐断线期间错过的数据
+		}
+		return
+	}
+}
+
+// nextReconnectDelay 重连等待时间翻倍，不超过上限
+func nextReconnectDelay(delay, max time.Duration) time.Duration {
+	delay *= 2
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// jitterDelay 在 [0.5, 1.0] 倍之间随机抖动，避免大量客户端同时重连
+func jitterDelay(delay time.Duration) time.Duration {
+	return delay/2 + time.Duration(rand.Int63n(int64(delay)/2+1))
+}
+
+// resubscribe 在新连接上恢复该分片的所有流（已订阅列表本身不变），返回等待确认的请求
+// 读取循环启动后才能收到确认，由调用方在 start 之后等待
+func (s *streamShard) resubscribe(conn *websocket.Conn) ([]*streamRequest, error) {
+	streams := s.streamList()
+	if len(streams) == 0 {
+		return nil, nil
+	}
+	log.Printf("🔄 组合流连接#%d 重新订阅 %d 个数据流...", s.id, len(streams))
+	reqs, err := s.send(conn, "SUBSCRIBE", streams)
+	if err != nil {
+		return nil, fmt.Errorf("重新订阅失败: %w", err)
+	}
+	log.Printf("✅ 组合流连接#%d 数据流重新订阅请求已发送", s.id)
+	return reqs, nil
+}
+
+// streamList 分片当前负责的流
+func (s *streamShard) streamList() []string {
+	s.client.mu.RLock()
+	defer s.client.mu.RUnlock()
+	streams := make([]string, 0, len(s.streams))
+	for stream := range s.streams {
+		streams = append(streams, stream)
+	}
+	return streams
+}
+
+// close 发送关闭帧并断开连接，停止保活和重连
+func (s *streamShard) close() {
+	s.cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn != nil {
+		closeMsg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+		s.conn.WriteControl(websocket.CloseMessage, closeMsg, time.Now().Add(time.Second))
+		s.conn.Close()
+		s.conn = nil
+	}
+}
@@ -0,0 +1,271 @@
+package market
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TickerArrStream 全市场24小时行情流（每秒推送一次有变化的交易对）
+	TickerArrStream = "!ticker@arr"
+	// tickerStaleAfter 超过该时间没有更新的交易对（如已下架）不参与排名
+	tickerStaleAfter = 5 * time.Minute
+)
+
+// Ticker24h 交易对的24小时滚动统计
+type Ticker24h struct {
+	Symbol             string    `json:"symbol"`
+	LastPrice          float64   `json:"last_price"`
+	PriceChange        float64   `json:"price_change"`
+	PriceChangePercent float64   `json:"price_change_percent"`
+	HighPrice          float64   `json:"high_price"`
+	LowPrice           float64   `json:"low_price"`
+	Volume             float64   `json:"volume"`       // 24小时成交量（币）
+	QuoteVolume        float64   `json:"quote_volume"` // 24小时成交额（USDT）
+	Trades             int64     `json:"trades"`       // 24小时成交笔数
+	UpdatedAt          time.Time `json:"updated_at"`   // 最近一次推送的事件时间
+}
+
+// TickerTracker 维护全市场交易对的24小时统计（并发安全）
+type TickerTracker struct {
+	mu      sync.RWMutex
+	tickers map[string]*Ticker24h
+	accept  func(symbol []byte) bool // 需要跟踪的交易对（在解析其他字段前判断，跳过无关交易对）
+}
+
+// NewTickerTracker 创建24小时统计跟踪器，accept 为 nil 时只跟踪 USDT 交易对
+func NewTickerTracker(accept func(symbol []byte) bool) *TickerTracker {
+	if accept == nil {
+		accept = func(symbol []byte) bool { return bytes.HasSuffix(symbol, []byte("USDT")) }
+	}
+	return &TickerTracker{tickers: make(map[string]*Ticker24h), accept: accept}
+}
+
+// Update 解析一条 !ticker@arr 消息并更新统计
+// 消息每秒包含全市场交易对，手动扫描字段并原地更新已有交易对，避免逐条反序列化的内存分配
+func (t *TickerTracker) Update(message []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	p := tickerParser{data: message}
+	p.skipSpace()
+	if !p.consume('[') {
+		return fmt.Errorf("24小时行情消息不是数组")
+	}
+	for {
+		p.skipSpace()
+		if p.consume(']') {
+			return nil
+		}
+		if err := t.updateObject(&p); err != nil {
+			return err
+		}
+		p.skipSpace()
+		if !p.consume(',') && !p.peek(']') {
+			return fmt.Errorf("24小时行情消息格式错误（位置 %d）", p.pos)
+		}
+	}
+}
+
+// updateObject 解析数组中的一个交易对对象（字段均为字符串或数字）
+func (t *TickerTracker) updateObject(p *tickerParser) error {
+	if !p.consume('{') {
+		return fmt.Errorf("24小时行情消息格式错误（位置 %d）", p.pos)
+	}
+	var ticker *Ticker24h
+	skip := false
+	var fields Ticker24h
+	for {
+		p.skipSpace()
+		if p.consume('}') {
+			break
+		}
+		key, ok := p.str()
+		if !ok {
+			return fmt.Errorf("24小时行情字段名格式错误（位置 %d）", p.pos)
+		}
+		p.skipSpace()
+		if !p.consume(':') {
+			return fmt.Errorf("24小时行情消息格式错误（位置 %d）", p.pos)
+		}
+		p.skipSpace()
+		value, ok := p.value()
+		if !ok {
+			return fmt.Errorf("24小时行情字段值格式错误（位置 %d）", p.pos)
+		}
+		p.skipSpace()
+		p.consume(',')
+
+		if skip || len(key) != 1 {
+			continue
+		}
+		switch key[0] {
+		case 's':
+			if !t.accept(value) {
+				skip = true // 不关心的交易对只扫描不解析
+				continue
+			}
+			ticker = t.tickers[string(value)]
+			if ticker == nil {
+				ticker = &Ticker24h{Symbol: string(value)}
+				t.tickers[ticker.Symbol] = ticker
+			}
+		case 'E':
+			if ms, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+				fields.UpdatedAt = time.UnixMilli(ms)
+			}
+		case 'c':
+			fields.LastPrice = parseTickerFloat(value)
+		case 'p':
+			fields.PriceChange = parseTickerFloat(value)
+		case 'P':
+			fields.PriceChangePercent = parseTickerFloat(value)
+		case 'h':
+			fields.HighPrice = parseTickerFloat(value)
+		case 'l':
+			fields.LowPrice = parseTickerFloat(value)
+		case 'v':
+			fields.Volume = parseTickerFloat(value)
+		case 'q':
+			fields.QuoteVolume = parseTickerFloat(value)
+		case 'n':
+			fields.Trades, _ = strconv.ParseInt(string(value), 10, 64)
+		}
+	}
+	if ticker != nil && !skip {
+		fields.Symbol = ticker.Symbol
+		*ticker = fields
+	}
+	return nil
+}
+
+// parseTickerFloat 解析数字字符串（格式错误时为0）
+func parseTickerFloat(value []byte) float64 {
+	f, _ := strconv.ParseFloat(string(value), 64)
+	return f
+}
+
+// Get 获取交易对的24小时统计
+func (t *TickerTracker) Get(symbol string) (Ticker24h, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	ticker, ok := t.tickers[strings.ToUpper(symbol)]
+	if !ok {
+		return Ticker24h{}, false
+	}
+	return *ticker, true
+}
+
+// TopByQuoteVolume 按24小时成交额从大到小返回前 n 个交易对（不含长时间没有更新的交易对）
+func (t *TickerTracker) TopByQuoteVolume(n int, now time.Time) []Ticker24h {
+	t.mu.RLock()
+	result := make([]Ticker24h, 0, len(t.tickers))
+	for _, ticker := range t.tickers {
+		if now.Sub(ticker.UpdatedAt) <= tickerStaleAfter {
+			result = append(result, *ticker)
+		}
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].QuoteVolume != result[j].QuoteVolume {
+			return result[i].QuoteVolume > result[j].QuoteVolume
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+	if n > 0 && len(result) > n {
+		result = result[:n]
+	}
+	return result
+}
+
+// tickerParser 扁平JSON对象数组的扫描器（返回的值直接引用原始消息，不分配内存）
+type tickerParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *tickerParser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+func (p *tickerParser) peek(c byte) bool {
+	return p.pos < len(p.data) && p.data[p.pos] == c
+}
+
+func (p *tickerParser) consume(c byte) bool {
+	if p.peek(c) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// str 读取字符串（币安行情字段不含转义字符，遇到转义时按原样返回）
+func (p *tickerParser) str() ([]byte, bool) {
+	if !p.consume('"') {
+		return nil, false
+	}
+	start := p.pos
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos += 2
+			continue
+		case '"':
+			value := p.data[start:p.pos]
+			p.pos++
+			return value, true
+		}
+		p.pos++
+	}
+	return nil, false
+}
+
+// value 读取字符串、数字、布尔或 null 值
+func (p *tickerParser) value() ([]byte, bool) {
+	if p.peek('"') {
+		return p.str()
+	}
+	start := p.pos
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ',', '}', ']', ' ', '\t', '\n', '\r':
+			return p.data[start:p.pos], p.pos > start
+		case '{', '[', '"':
+			return nil, false // 行情对象中没有嵌套结构
+		}
+		p.pos++
+	}
+	return nil, false
+}
+
+// GetTicker 获取交易对的24小时统计（行情监控未启动或尚未收到时 ok=false）
+func GetTicker(symbol string) (Ticker24h, bool) {
+	m := WSMonitorCli
+	if m == nil || m.tickers == nil {
+		return Ticker24h{}, false
+	}
+	return m.tickers.Get(Normalize(symbol))
+}
+
+// TopByQuoteVolume 按24小时成交额返回最活跃的前 n 个交易对（行情监控未启动时返回空列表）
+func TopByQuoteVolume(n int) []Ticker24h {
+	m := WSMonitorCli
+	if m == nil || m.tickers == nil {
+		return []Ticker24h{}
+	}
+	return m.tickers.TopByQuoteVolume(n, time.Now())
+}
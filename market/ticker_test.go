@@ -0,0 +1,89 @@
+package market
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tickerArrMessage 构造 !ticker@arr 消息（每项为 交易对、成交额、事件时间）
+func tickerArrMessage(items ...any) []byte {
+	var objects []string
+	for i := 0; i+2 < len(items); i += 3 {
+		objects = append(objects, fmt.Sprintf(`{"e":"24hrTicker","E":%d,"s":"%s","p":"-1.5","P":"-2.10","w":"70","c":"70.5","Q":"1","o":"72","h":"73","l":"69","v":"1000","q":"%v","O":0,"C":0,"F":1,"L":2,"n":345}`,
+			items[i+2], items[i], items[i+1]))
+	}
+	return []byte("[" + strings.Join(objects, ",") + "]")
+}
+
+// TestTickerTracker 解析全市场24小时行情，跳过非USDT交易对，按成交额排序并排除长时间未更新的交易对
+func TestTickerTracker(t *testing.T) {
+	now := time.Now()
+	ms := now.UnixMilli()
+	tracker := NewTickerTracker(nil)
+	if err := tracker.Update(tickerArrMessage("BTCUSDT", 500, ms, "ETHBTC", 9999, ms, "SOLUSDT", 800, ms, "OLDUSDT", 900, ms-int64(time.Hour/time.Millisecond))); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	btc, ok := tracker.Get("btcusdt")
+	if !ok || btc.LastPrice != 70.5 || btc.PriceChangePercent != -2.1 || btc.QuoteVolume != 500 || btc.Trades != 345 || btc.UpdatedAt.UnixMilli() != ms {
+		t.Fatalf("BTC 统计不正确: %+v", btc)
+	}
+	if _, ok := tracker.Get("ETHBTC"); ok {
+		t.Error("非USDT交易对应跳过")
+	}
+
+	top := tracker.TopByQuoteVolume(5, now)
+	if len(top) != 2 || top[0].Symbol != "SOLUSDT" || top[1].Symbol != "BTCUSDT" {
+		t.Errorf("排名不正确（应排除过期交易对）: %+v", top)
+	}
+
+	// 只推送有变化的交易对，其余保留上次的值
+	if err := tracker.Update(tickerArrMessage("BTCUSDT", 1000, ms+1000)); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	if top := tracker.TopByQuoteVolume(1, now); top[0].Symbol != "BTCUSDT" || top[0].QuoteVolume != 1000 {
+		t.Errorf("更新后排名不正确: %+v", top)
+	}
+
+	for _, bad := range []string{`{"s":"BTCUSDT"}`, `[{"s":"BTCUSDT"`, `[{"s":{"x":1}}]`, `[{"s":"BTCUSDT"} {"s":"ETHUSDT"}]`} {
+		if err := tracker.Update([]byte(bad)); err == nil {
+			t.Errorf("格式错误的消息应返回错误: %s", bad)
+		}
+	}
+}
+
+// TestTickerTrackerAllocs 已跟踪的交易对和跳过的交易对更新时不分配内存
+func TestTickerTrackerAllocs(t *testing.T) {
+	tracker := NewTickerTracker(nil)
+	message := tickerArrMessage("BTCUSDT", 500, 1, "ETHUSDT", 400, 1, "ETHBTC", 300, 1)
+	if err := tracker.Update(message); err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		if err := tracker.Update(message); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("重复更新不应分配内存: %v 次/消息", allocs)
+	}
+}
+
+// BenchmarkTickerTrackerUpdate 约300个交易对的全市场24小时行情消息
+func BenchmarkTickerTrackerUpdate(b *testing.B) {
+	var items []any
+	for i := 0; i < 300; i++ {
+		items = append(items, fmt.Sprintf("COIN%dUSDT", i), i*1000, 1700000000000)
+	}
+	message := tickerArrMessage(items...)
+	tracker := NewTickerTracker(nil)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(message)))
+	for i := 0; i < b.N; i++ {
+		if err := tracker.Update(message); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -13,8 +13,10 @@ type Data struct {
 	CurrentRSI7       float64
 	OpenInterest      *OIData
 	FundingRate       float64
+	Liquidations      *LiquidationStats // 最近的强平量（行情监控未启动或没有强平时为 nil）
 	IntradaySeries    *IntradayData
 	LongerTermContext *LongerTermData
+	Timeframes        map[string][]Kline // 共享K线缓存中的中间周期K线（如15m/1h，由3m聚合），只包含有数据的周期
 }
 
 // OIData Open Interest数据
@@ -79,7 +79,8 @@ func NewWSClient() *WSClient {
 func (w *WSClient) Connect() error {
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 45 * time.Second, // 增加超时时间以适应代理
-		Proxy:            getProxyFunc(),    // ✅ 添加代理支持
+		Proxy:            getProxyFunc(),   // ✅ 添加代理支持
+		NetDialContext:   proxyDialContext, // SOCKS5 代理
 	}
 
 	conn, _, err := dialer.Dial("wss://ws-fapi.binance.com/ws-fapi/v1", nil)
@@ -3,10 +3,12 @@ package mcp
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -46,6 +48,10 @@ type Client struct {
 	logger     Logger // 日志器（可替换）
 	config     *Config // 配置对象（保存所有配置）
 
+	usageMu      sync.RWMutex
+	lastUsage    Usage // 最近一次成功调用的 token 用量
+	lastAttempts int   // 最近一次调用的尝试次数
+
 	// hooks 用于实现动态分派（多态）
 	// 当 DeepSeekClient 嵌入 Client 时，hooks 指向 DeepSeekClient
 	// 这样 call() 中调用的方法会自动分派到子类重写的版本
@@ -133,45 +139,26 @@ func (client *Client) SetTimeout(timeout time.Duration) {
 	client.httpClient.Timeout = timeout
 }
 
+// SetGenerationParams 设置请求的 temperature 和 max_tokens（temperature 为 nil、maxTokens <= 0 时保持默认值）
+func (client *Client) SetGenerationParams(temperature *float64, maxTokens int) {
+	if temperature != nil {
+		client.config.Temperature = *temperature
+	}
+	if maxTokens > 0 {
+		client.MaxTokens = maxTokens
+	}
+}
+
 // CallWithMessages 模板方法 - 固定的重试流程（不可重写）
 func (client *Client) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
-	if client.APIKey == "" {
+	if client.APIKey == "" && client.hooks.requiresAPIKey() {
 		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
 	}
 
-	// 固定的重试流程
-	var lastErr error
-	maxRetries := client.config.MaxRetries
-
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			client.logger.Warnf("⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
-		}
-
-		// 调用固定的单次调用流程
-		result, err := client.hooks.call(systemPrompt, userPrompt)
-		if err == nil {
-			if attempt > 1 {
-				client.logger.Infof("✓ AI API重试成功")
-			}
-			return result, nil
-		}
-
-		lastErr = err
-		// 通过 hooks 判断是否可重试（支持子类自定义重试策略）
-		if !client.hooks.isRetryableError(err) {
-			return "", err
-		}
-
-		// 重试前等待
-		if attempt < maxRetries {
-			waitTime := client.config.RetryWaitBase * time.Duration(attempt)
-			client.logger.Infof("⏳ 等待%v后重试...", waitTime)
-			time.Sleep(waitTime)
-		}
-	}
-
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	// 固定的重试流程（调用固定的单次调用流程）
+	return client.withRetry(func() (string, error) {
+		return client.hooks.call(systemPrompt, userPrompt)
+	})
 }
 
 func (client *Client) setAuthHeader(reqHeader http.Header) {
@@ -200,11 +187,19 @@ func (client *Client) buildMCPRequestBody(systemPrompt, userPrompt string) map[s
 		"model":       client.Model,
 		"messages":    messages,
 		"temperature": client.config.Temperature, // 使用配置的 temperature
-		"max_tokens":  client.MaxTokens,
+	}
+	// MaxTokens <= 0 表示不限制（由服务端决定，本地模型通常不需要）
+	if client.MaxTokens > 0 {
+		requestBody["max_tokens"] = client.MaxTokens
 	}
 	return requestBody
 }
 
+// requiresAPIKey 调用前是否必须设置 API Key（本地模型服务可重写为 false）
+func (client *Client) requiresAPIKey() bool {
+	return true
+}
+
 // can be used to marshal the request body and can be overridden
 func (client *Client) marshalRequestBody(requestBody map[string]any) ([]byte, error) {
 	jsonData, err := json.Marshal(requestBody)
@@ -299,7 +294,7 @@ func (client *Client) call(systemPrompt, userPrompt string) (string, error) {
 
 	// Step 7: 检查 HTTP 状态码（固定逻辑）
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", parseAPIErrorResponse(client.Provider, resp, body)
 	}
 
 	// Step 8: 解析响应（通过 hooks 实现动态分派）
@@ -308,6 +303,7 @@ func (client *Client) call(systemPrompt, userPrompt string) (string, error) {
 		return "", fmt.Errorf("fail to parse AI server response: %w", err)
 	}
 
+	client.recordUsage(body)
 	return result, nil
 }
 
@@ -316,8 +312,39 @@ func (client *Client) String() string {
 		client.Provider, client.Model)
 }
 
-// isRetryableError 判断错误是否可重试（网络错误、超时等）
+// recordUsage 记录最近一次调用的 token 用量
+func (client *Client) recordUsage(body []byte) {
+	usage := parseUsage(body)
+	if usage.Model == "" {
+		usage.Model = client.Model
+	}
+
+	client.usageMu.Lock()
+	client.lastUsage = usage
+	client.usageMu.Unlock()
+}
+
+// LastUsage 返回最近一次成功调用的 token 用量（实现 UsageReporter）
+func (client *Client) LastUsage() Usage {
+	client.usageMu.RLock()
+	defer client.usageMu.RUnlock()
+	return client.lastUsage
+}
+
+// isRetryableError 判断错误是否可重试（限流、服务端临时错误、网络错误、超时等）
+// 余额不足和鉴权等其他 4xx 错误重试也不会成功，不重试
 func (client *Client) isRetryableError(err error) bool {
+	if IsInsufficientBalanceError(err) || IsAuthError(err) {
+		return false
+	}
+	if IsRateLimitError(err) {
+		return true
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatusCodes[apiErr.StatusCode] && !apiErr.IsInsufficientBalance()
+	}
+
 	errStr := err.Error()
 	// 网络错误、超时、EOF等可以重试
 	for _, retryable := range client.config.RetryableErrors {
@@ -348,7 +375,7 @@ func (client *Client) isRetryableError(err error) bool {
 //       Build()
 //   result, err := client.CallWithRequest(request)
 func (client *Client) CallWithRequest(req *Request) (string, error) {
-	if client.APIKey == "" {
+	if client.APIKey == "" && client.hooks.requiresAPIKey() {
 		return "", fmt.Errorf("AI API密钥未设置，请先调用 SetAPIKey")
 	}
 
@@ -357,39 +384,10 @@ func (client *Client) CallWithRequest(req *Request) (string, error) {
 		req.Model = client.Model
 	}
 
-	// 固定的重试流程
-	var lastErr error
-	maxRetries := client.config.MaxRetries
-
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt > 1 {
-			client.logger.Warnf("⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
-		}
-
-		// 调用单次请求
-		result, err := client.callWithRequest(req)
-		if err == nil {
-			if attempt > 1 {
-				client.logger.Infof("✓ AI API重试成功")
-			}
-			return result, nil
-		}
-
-		lastErr = err
-		// 判断是否可重试
-		if !client.hooks.isRetryableError(err) {
-			return "", err
-		}
-
-		// 重试前等待
-		if attempt < maxRetries {
-			waitTime := client.config.RetryWaitBase * time.Duration(attempt)
-			client.logger.Infof("⏳ 等待%v后重试...", waitTime)
-			time.Sleep(waitTime)
-		}
-	}
-
-	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+	// 固定的重试流程（调用单次请求）
+	return client.withRetry(func() (string, error) {
+		return client.callWithRequest(req)
+	})
 }
 
 // callWithRequest 单次调用 AI API（使用 Request 对象）
@@ -432,7 +430,7 @@ func (client *Client) callWithRequest(req *Request) (string, error) {
 
 	// 检查 HTTP 状态码
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("API返回错误 (status %d): %s", resp.StatusCode, string(body))
+		return "", parseAPIErrorResponse(client.Provider, resp, body)
 	}
 
 	// 解析响应
@@ -441,6 +439,7 @@ func (client *Client) callWithRequest(req *Request) (string, error) {
 		return "", fmt.Errorf("fail to parse AI server response: %w", err)
 	}
 
+	client.recordUsage(body)
 	return result, nil
 }
 
@@ -471,7 +470,7 @@ func (client *Client) buildRequestBodyFromRequest(req *Request) map[string]any {
 
 	if req.MaxTokens != nil {
 		requestBody["max_tokens"] = *req.MaxTokens
-	} else {
+	} else if client.MaxTokens > 0 {
 		// 如果 Request 中没有设置，使用 Client 的 MaxTokens
 		requestBody["max_tokens"] = client.MaxTokens
 	}
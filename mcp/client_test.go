@@ -146,6 +146,49 @@ func TestClient_CallWithMessages_HTTPError(t *testing.T) {
 	}
 }
 
+func TestClient_CallWithMessages_ErrorClass(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		err    error
+		want   string
+	}{
+		{"余额不足", 402, `{"error":{"message":"Insufficient Balance"}}`, nil, ErrorClassInsufficientBalance},
+		{"限流", 429, `{"error":{"message":"Rate limit reached"}}`, nil, ErrorClassRateLimit},
+		{"认证失败", 401, `{"error":{"message":"invalid api key"}}`, nil, ErrorClassAuth},
+		{"服务端错误", 500, "Internal Server Error", nil, ErrorClassServerError},
+		{"网络错误", 0, "", errors.New("connection refused"), ErrorClassNetwork},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHTTP := NewMockHTTPClient()
+			if tt.err != nil {
+				mockHTTP.SetNetworkError(tt.err)
+			} else {
+				mockHTTP.SetErrorResponse(tt.status, tt.body)
+			}
+
+			client := NewClient(
+				WithHTTPClient(mockHTTP.ToHTTPClient()),
+				WithLogger(NewMockLogger()),
+				WithAPIKey("test-key"),
+				WithMaxRetries(1),
+			)
+
+			_, err := client.CallWithMessages("system", "user")
+			if got := ClassifyError(err); got != tt.want {
+				t.Errorf("ClassifyError(%v) = %q, want %q", err, got, tt.want)
+			}
+		})
+	}
+
+	if got := ClassifyError(errors.New("解析AI响应失败")); got != "" {
+		t.Errorf("非AI接口错误应返回空分类，实际 %q", got)
+	}
+}
+
 // ============================================================
 // 测试重试逻辑
 // ============================================================
@@ -383,6 +426,30 @@ func TestClient_SetTimeout(t *testing.T) {
 	}
 }
 
+func TestClient_SetGenerationParams(t *testing.T) {
+	c := NewClient(WithMaxTokens(2000)).(*Client)
+
+	// 未设置时保持默认值
+	c.SetGenerationParams(nil, 0)
+	body := c.buildMCPRequestBody("system prompt", "user prompt")
+	if body["temperature"] != MCPClientTemperature {
+		t.Errorf("expected default temperature %v, got %v", MCPClientTemperature, body["temperature"])
+	}
+	if body["max_tokens"] != 2000 {
+		t.Errorf("expected default max_tokens 2000, got %v", body["max_tokens"])
+	}
+
+	temperature := 0.0
+	c.SetGenerationParams(&temperature, 4096)
+	body = c.buildMCPRequestBody("system prompt", "user prompt")
+	if body["temperature"] != 0.0 {
+		t.Errorf("expected temperature 0, got %v", body["temperature"])
+	}
+	if body["max_tokens"] != 4096 {
+		t.Errorf("expected max_tokens 4096, got %v", body["max_tokens"])
+	}
+}
+
 // ============================================================
 // 测试 String 方法
 // ============================================================
@@ -24,6 +24,9 @@ type Config struct {
 	MaxRetries     int
 	RetryWaitBase  time.Duration
 	RetryableErrors []string
+	RetryBudget    time.Duration // 单次调用含重试的总时间预算（0 表示不限制）
+	MaxRetryWait   time.Duration // 单次重试等待上限
+	RetryJitter    float64       // 退避等待的随机抖动比例
 
 	// 超时配置
 	Timeout time.Duration
@@ -39,10 +42,13 @@ func DefaultConfig() *Config {
 		// 默认值
 		MaxTokens:      getEnvInt("AI_MAX_TOKENS", 2000),
 		Temperature:    MCPClientTemperature,
-		MaxRetries:     MaxRetryTimes,
+		MaxRetries:     getEnvInt("AI_MAX_RETRIES", MaxRetryTimes),
 		RetryWaitBase:  2 * time.Second,
 		Timeout:        DefaultTimeout,
 		RetryableErrors: retryableErrors,
+		RetryBudget:    time.Duration(getEnvInt("AI_RETRY_BUDGET_SECONDS", int(DefaultRetryBudget/time.Second))) * time.Second,
+		MaxRetryWait:   DefaultMaxRetryWait,
+		RetryJitter:    DefaultRetryJitter,
 
 		// 默认依赖
 		Logger:     &defaultLogger{},
@@ -31,6 +31,7 @@ func TestConfig_MaxRetries_IsUsed(t *testing.T) {
 		WithLogger(mockLogger),
 		WithAPIKey("sk-test-key"),
 		WithMaxRetries(5), // ✅ 设置重试5次
+		WithRetryWaitBase(10*time.Millisecond),
 	)
 
 	// 调用 API（应该失败）
@@ -152,6 +153,7 @@ func TestConfig_RetryWaitBase_IsUsed(t *testing.T) {
 		WithLogger(mockLogger),
 		WithAPIKey("sk-test-key"),
 		WithRetryWaitBase(customWaitBase), // ✅ 设置自定义等待时间
+		WithRetryJitter(0),                // 关闭随机抖动，便于验证等待时长
 		WithMaxRetries(3),
 	)
 
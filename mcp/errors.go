@@ -2,8 +2,12 @@ package mcp
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"strings"
+	"time"
 )
 
 // APIError AI API错误
@@ -12,19 +16,24 @@ type APIError struct {
 	Code       int    `json:"code"`
 	Message    string `json:"message"`
 	RawBody    string `json:"raw_body"`
+	ErrorCode  string `json:"error_code"` // 字符串错误码（OpenAI/Qwen 格式，如 invalid_api_key、Throttling）
+	ErrorType  string `json:"error_type"` // 错误类型（OpenAI/DeepSeek 格式，如 authentication_error）
+
+	// ParsedRetryAfter 服务端通过 Retry-After 响应头建议的重试等待时长
+	ParsedRetryAfter time.Duration `json:"-"`
 }
 
 func (e *APIError) Error() string {
-	return fmt.Sprintf("API返回错误 (status %d, code %d): %s", e.StatusCode, e.Code, e.Message)
+	return fmt.Sprintf("API返回错误 (status %d): %s", e.StatusCode, e.RawBody)
 }
 
 // IsInsufficientBalance 检查是否是余额不足错误
 func (e *APIError) IsInsufficientBalance() bool {
-	// DeepSeek/Qwen 余额不足错误码
-	if e.Code == 30001 {
+	// DeepSeek/Qwen 余额不足错误码，OpenAI 额度用尽（insufficient_quota，状态码为429），Qwen 欠费（Arrearage）
+	if e.Code == 30001 || e.ErrorCode == "insufficient_quota" || e.ErrorCode == "Arrearage" {
 		return true
 	}
-	
+
 	// 检查消息内容
 	msg := strings.ToLower(e.Message)
 	return strings.Contains(msg, "balance") && strings.Contains(msg, "insufficient") ||
@@ -32,6 +41,39 @@ func (e *APIError) IsInsufficientBalance() bool {
 		strings.Contains(msg, "账户余额不足")
 }
 
+// IsRateLimit 检查是否是限流错误（429、OpenAI rate_limit_exceeded、Qwen Throttling.*）
+func (e *APIError) IsRateLimit() bool {
+	if e.IsInsufficientBalance() {
+		return false
+	}
+	if e.StatusCode == http.StatusTooManyRequests ||
+		e.ErrorCode == "rate_limit_exceeded" || strings.HasPrefix(e.ErrorCode, "Throttling") ||
+		e.ErrorType == "rate_limit_error" {
+		return true
+	}
+	msg := strings.ToLower(e.Message)
+	return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
+
+// IsAuthFailure 检查是否是认证失败（401/403、OpenAI/Qwen invalid_api_key、DeepSeek authentication_error）
+func (e *APIError) IsAuthFailure() bool {
+	if e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden {
+		return true
+	}
+	switch e.ErrorCode {
+	case "invalid_api_key", "InvalidApiKey", "authentication_error", "AccessDenied":
+		return true
+	}
+	if e.ErrorType == "authentication_error" {
+		return true
+	}
+	msg := strings.ToLower(e.Message)
+	return strings.Contains(msg, "authentication fails") ||
+		strings.Contains(msg, "incorrect api key") ||
+		strings.Contains(msg, "invalid api key") ||
+		strings.Contains(msg, "invalid api-key")
+}
+
 // ParseAPIError 从响应体解析API错误
 func ParseAPIError(statusCode int, body []byte) *APIError {
 	apiErr := &APIError{
@@ -40,22 +82,28 @@ func ParseAPIError(statusCode int, body []byte) *APIError {
 	}
 
 	// 尝试解析JSON错误响应
+	// code 可能是数字（DeepSeek）也可能是字符串（Qwen 原生接口 / OpenAI）
 	var errResp struct {
-		Code    int    `json:"code"`
-		Message string `json:"message"`
+		Code    json.RawMessage `json:"code"`
+		Message string          `json:"message"`
 		Error   struct {
-			Code    string `json:"code"`
-			Message string `json:"message"`
+			Code    json.RawMessage `json:"code"`
+			Type    string          `json:"type"`
+			Message string          `json:"message"`
 		} `json:"error"`
 	}
 
 	if err := json.Unmarshal(body, &errResp); err == nil {
-		// DeepSeek/Qwen 格式
-		if errResp.Code != 0 {
-			apiErr.Code = errResp.Code
+		code, errorCode := parseErrorCode(errResp.Code)
+		if code != 0 || errorCode != "" {
+			// DeepSeek/Qwen 格式
+			apiErr.Code = code
+			apiErr.ErrorCode = errorCode
 			apiErr.Message = errResp.Message
 		} else if errResp.Error.Message != "" {
 			// OpenAI 格式
+			apiErr.Code, apiErr.ErrorCode = parseErrorCode(errResp.Error.Code)
+			apiErr.ErrorType = errResp.Error.Type
 			apiErr.Message = errResp.Error.Message
 		}
 	}
@@ -68,6 +116,39 @@ func ParseAPIError(statusCode int, body []byte) *APIError {
 	return apiErr
 }
 
+// parseErrorCode 解析数字或字符串形式的错误码
+func parseErrorCode(raw json.RawMessage) (int, string) {
+	if len(raw) == 0 {
+		return 0, ""
+	}
+	var code int
+	if err := json.Unmarshal(raw, &code); err == nil {
+		return code, ""
+	}
+	var errorCode string
+	if err := json.Unmarshal(raw, &errorCode); err == nil {
+		return 0, errorCode
+	}
+	return 0, ""
+}
+
+// parseAPIErrorResponse 解析非200响应：读取 Retry-After，并将余额不足、认证失败、限流转换为对应的错误类型
+func parseAPIErrorResponse(provider string, resp *http.Response, body []byte) error {
+	apiErr := ParseAPIError(resp.StatusCode, body)
+	apiErr.ParsedRetryAfter = parseRetryAfter(resp.Header)
+
+	switch {
+	case apiErr.IsInsufficientBalance():
+		return &InsufficientBalanceError{Provider: provider, APIError: apiErr}
+	case apiErr.IsAuthFailure():
+		return &AuthenticationError{Provider: provider, APIError: apiErr}
+	case apiErr.IsRateLimit():
+		return &RateLimitError{Provider: provider, APIError: apiErr, ParsedRetryAfter: apiErr.ParsedRetryAfter}
+	default:
+		return apiErr
+	}
+}
+
 // InsufficientBalanceError 余额不足错误（特殊标记）
 type InsufficientBalanceError struct {
 	Provider string
@@ -100,3 +181,118 @@ func IsInsufficientBalanceError(err error) bool {
 		strings.Contains(errMsg, "账户余额不足") ||
 		strings.Contains(errMsg, "code=30001")
 }
+
+// RateLimitError 限流错误：应等待后重试，不需要停止交易员
+type RateLimitError struct {
+	Provider         string
+	APIError         *APIError
+	ParsedRetryAfter time.Duration // 服务端建议的等待时长（未提供时为0）
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("[%s] AI API请求被限流: %s", e.Provider, e.APIError.Message)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.APIError
+}
+
+// IsRateLimitError 检查是否是限流错误
+func IsRateLimitError(err error) bool {
+	return AsRateLimitError(err) != nil
+}
+
+// AsRateLimitError 从错误链中取出限流错误（不是限流错误时返回 nil）
+func AsRateLimitError(err error) *RateLimitError {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr
+	}
+	if IsInsufficientBalanceError(err) {
+		return nil
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.IsRateLimit() {
+		return &RateLimitError{APIError: apiErr, ParsedRetryAfter: apiErr.ParsedRetryAfter}
+	}
+	return nil
+}
+
+// AuthenticationError 认证失败错误：API Key 无效、被撤销或无权限，重试不会成功
+type AuthenticationError struct {
+	Provider string
+	APIError *APIError
+}
+
+func (e *AuthenticationError) Error() string {
+	return fmt.Sprintf("[%s] AI API认证失败，请检查API Key是否有效: %s", e.Provider, e.APIError.Message)
+}
+
+func (e *AuthenticationError) Unwrap() error {
+	return e.APIError
+}
+
+// IsAuthError 检查是否是认证失败错误
+func IsAuthError(err error) bool {
+	var authErr *AuthenticationError
+	if errors.As(err, &authErr) {
+		return true
+	}
+	if IsInsufficientBalanceError(err) {
+		return false
+	}
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.IsAuthFailure()
+}
+
+// AI错误分类（用于监控指标）
+const (
+	ErrorClassInsufficientBalance = "insufficient_balance"
+	ErrorClassRateLimit           = "rate_limit"
+	ErrorClassAuth                = "auth"
+	ErrorClassServerError         = "server_error"
+	ErrorClassClientError         = "client_error"
+	ErrorClassTimeout             = "timeout"
+	ErrorClassNetwork             = "network"
+)
+
+// ClassifyError 对AI接口调用错误分类，非AI接口错误（如响应解析失败）返回空字符串
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if IsInsufficientBalanceError(err) {
+		return ErrorClassInsufficientBalance
+	}
+	if IsAuthError(err) {
+		return ErrorClassAuth
+	}
+	if IsRateLimitError(err) {
+		return ErrorClassRateLimit
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.IsInsufficientBalance(), apiErr.StatusCode == http.StatusPaymentRequired:
+			return ErrorClassInsufficientBalance
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return ErrorClassRateLimit
+		case apiErr.StatusCode == http.StatusUnauthorized, apiErr.StatusCode == http.StatusForbidden:
+			return ErrorClassAuth
+		case apiErr.StatusCode >= http.StatusInternalServerError:
+			return ErrorClassServerError
+		default:
+			return ErrorClassClientError
+		}
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package mcp
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseAPIErrorResponse_ProviderFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{"DeepSeek 认证失败", 401, `{"error":{"message":"Authentication Fails (no such user)","type":"authentication_error","code":"invalid_request_error"}}`, "auth"},
+		{"DeepSeek 余额不足", 402, `{"error":{"message":"Insufficient Balance","type":"unknown_error"}}`, "balance"},
+		{"DeepSeek 限流", 429, `{"error":{"message":"Rate limit reached for requests"}}`, "rate_limit"},
+		{"Qwen 兼容模式密钥无效", 401, `{"error":{"code":"invalid_api_key","message":"Incorrect API key provided."}}`, "auth"},
+		{"Qwen 原生密钥无效", 400, `{"code":"InvalidApiKey","message":"Invalid API-key provided.","request_id":"x"}`, "auth"},
+		{"Qwen 原生限流", 400, `{"code":"Throttling.RateQuota","message":"Requests rate limit exceeded"}`, "rate_limit"},
+		{"Qwen 欠费", 400, `{"code":"Arrearage","message":"Access denied, please make sure your account is in good standing."}`, "balance"},
+		{"OpenAI 限流", 429, `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`, "rate_limit"},
+		{"OpenAI 额度用尽", 429, `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`, "balance"},
+		{"服务端错误", 503, `Service Unavailable`, "api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: make(http.Header)}
+			err := parseAPIErrorResponse("test", resp, []byte(tt.body))
+
+			var got string
+			switch err.(type) {
+			case *AuthenticationError:
+				got = "auth"
+			case *RateLimitError:
+				got = "rate_limit"
+			case *InsufficientBalanceError:
+				got = "balance"
+			case *APIError:
+				got = "api"
+			}
+			if got != tt.want {
+				t.Errorf("expected %s, got %T: %v", tt.want, err, err)
+			}
+
+			// 经过多层包装后仍能识别
+			wrapped := fmt.Errorf("调用AI API失败: %w", err)
+			if IsAuthError(wrapped) != (tt.want == "auth") {
+				t.Errorf("IsAuthError mismatch for %s", tt.name)
+			}
+			if IsRateLimitError(wrapped) != (tt.want == "rate_limit") {
+				t.Errorf("IsRateLimitError mismatch for %s", tt.name)
+			}
+		})
+	}
+}
+
+func TestParseAPIErrorResponse_RetryAfter(t *testing.T) {
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: make(http.Header)}
+	resp.Header.Set("Retry-After", "20")
+
+	err := parseAPIErrorResponse("deepseek", resp, []byte(`{"error":{"message":"Rate limit reached"}}`))
+	rateLimitErr := AsRateLimitError(fmt.Errorf("wrapped: %w", err))
+	if rateLimitErr == nil {
+		t.Fatalf("expected RateLimitError, got %T", err)
+	}
+	if rateLimitErr.ParsedRetryAfter != 20*time.Second || rateLimitErr.APIError.ParsedRetryAfter != 20*time.Second {
+		t.Errorf("expected retry after 20s, got %v", rateLimitErr.ParsedRetryAfter)
+	}
+	if rateLimitErr.Provider != "deepseek" {
+		t.Errorf("expected provider deepseek, got %s", rateLimitErr.Provider)
+	}
+}
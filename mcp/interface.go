@@ -27,4 +27,5 @@ type clientHooks interface {
 	marshalRequestBody(requestBody map[string]any) ([]byte, error)
 	parseMCPResponse(body []byte) (string, error)
 	isRetryableError(err error) bool
+	requiresAPIKey() bool
 }
@@ -0,0 +1,131 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ProviderLocal       = "local"
+	ProviderOllama      = "ollama" // local 的别名
+	DefaultLocalBaseURL = "http://localhost:11434/v1"
+
+	// DefaultLocalTimeout 本地推理较慢，默认超时比云端模型更长（可通过 AI_LOCAL_TIMEOUT_SECONDS 调整）
+	DefaultLocalTimeout = 300 * time.Second
+)
+
+// IsLocalProvider 是否为本地模型服务（Ollama / vLLM / LM Studio 等 OpenAI 兼容接口，无需 API Key）
+func IsLocalProvider(provider string) bool {
+	return provider == ProviderLocal || provider == ProviderOllama
+}
+
+// LocalTimeout 本地模型请求超时
+func LocalTimeout() time.Duration {
+	return time.Duration(getEnvInt("AI_LOCAL_TIMEOUT_SECONDS", int(DefaultLocalTimeout/time.Second))) * time.Second
+}
+
+// LocalClient 本地模型客户端：API Key 可为空、不限制 max_tokens、兼容缺少 usage 等字段的响应
+type LocalClient struct {
+	*Client
+}
+
+// NewLocalClient 创建本地模型客户端
+func NewLocalClient() AIClient {
+	return NewLocalClientWithOptions()
+}
+
+// NewLocalClientWithOptions 创建本地模型客户端（支持选项模式）
+//
+// 使用示例：
+//
+//	client := mcp.NewLocalClientWithOptions(
+//	    mcp.WithBaseURL("http://192.168.1.10:11434/v1"),
+//	    mcp.WithModel("qwen2.5:14b"),
+//	)
+func NewLocalClientWithOptions(opts ...ClientOption) AIClient {
+	// 1. 本地模型预设选项（不设置 max_tokens，超时更长）
+	localOpts := []ClientOption{
+		WithProvider(ProviderLocal),
+		WithBaseURL(DefaultLocalBaseURL),
+		WithTimeout(LocalTimeout()),
+		WithMaxTokens(0),
+	}
+
+	// 2. 合并用户选项（用户选项优先级更高）
+	allOpts := append(localOpts, opts...)
+
+	baseClient := NewClient(allOpts...).(*Client)
+	localClient := &LocalClient{
+		Client: baseClient,
+	}
+	baseClient.hooks = localClient
+
+	return localClient
+}
+
+// SetAPIKey 设置本地模型服务地址和模型名（API Key 可为空，URL 以 # 结尾时使用完整URL）
+func (localClient *LocalClient) SetAPIKey(apiKey string, customURL string, customModel string) {
+	localClient.APIKey = apiKey
+
+	if customURL != "" {
+		if strings.HasSuffix(customURL, "#") {
+			localClient.BaseURL = strings.TrimSuffix(customURL, "#")
+			localClient.UseFullURL = true
+		} else {
+			localClient.BaseURL = strings.TrimSuffix(customURL, "/")
+			localClient.UseFullURL = false
+		}
+	}
+	if customModel != "" {
+		localClient.Model = customModel
+	}
+	localClient.logger.Infof("🔧 [MCP] 本地模型: BaseURL=%s, Model=%s, 超时=%v", localClient.BaseURL, localClient.Model, localClient.httpClient.Timeout)
+}
+
+// setAuthHeader 仅在配置了 API Key 时发送认证头
+func (localClient *LocalClient) setAuthHeader(reqHeaders http.Header) {
+	if localClient.APIKey != "" {
+		localClient.Client.setAuthHeader(reqHeaders)
+	}
+}
+
+func (localClient *LocalClient) requiresAPIKey() bool {
+	return false
+}
+
+// parseMCPResponse 兼容多种本地服务的响应格式：
+// OpenAI chat（choices[].message.content）、completions（choices[].text）以及 Ollama 原生接口（message.content）
+func (localClient *LocalClient) parseMCPResponse(body []byte) (string, error) {
+	var result struct {
+		Choices []struct {
+			Message *struct {
+				Content string `json:"content"`
+			} `json:"message"`
+			Text string `json:"text"`
+		} `json:"choices"`
+		Message *struct {
+			Content string `json:"content"`
+		} `json:"message"`
+		Response string `json:"response"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	if len(result.Choices) > 0 {
+		if result.Choices[0].Message != nil {
+			return result.Choices[0].Message.Content, nil
+		}
+		return result.Choices[0].Text, nil
+	}
+	if result.Message != nil {
+		return result.Message.Content, nil
+	}
+	if result.Response != "" {
+		return result.Response, nil
+	}
+	return "", fmt.Errorf("API返回空响应")
+}
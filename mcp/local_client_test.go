@@ -0,0 +1,107 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+)
+
+// 本地模型：无 API Key 时不发送认证头、不限制 max_tokens，响应缺少 usage 也能解析
+func TestLocalClient_CallWithoutAPIKey(t *testing.T) {
+	var body map[string]any
+	var authHeader string
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.ResponseFunc = func(req *http.Request) (*http.Response, error) {
+		authHeader = req.Header.Get("Authorization")
+		data, _ := io.ReadAll(req.Body)
+		json.Unmarshal(data, &body)
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"choices":[{"message":{"content":"local ok"}}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	}
+
+	client := NewLocalClientWithOptions(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewNoopLogger()),
+	)
+	client.SetAPIKey("", "http://127.0.0.1:11434/v1/", "llama3.1:8b")
+
+	result, err := client.CallWithMessages("system", "user")
+	if err != nil {
+		t.Fatalf("should not error without API key: %v", err)
+	}
+	if result != "local ok" {
+		t.Errorf("expected 'local ok', got '%s'", result)
+	}
+	if authHeader != "" {
+		t.Errorf("expected no Authorization header, got '%s'", authHeader)
+	}
+	if _, ok := body["max_tokens"]; ok {
+		t.Errorf("expected no max_tokens in request, got %v", body["max_tokens"])
+	}
+	if body["model"] != "llama3.1:8b" {
+		t.Errorf("expected model 'llama3.1:8b', got %v", body["model"])
+	}
+	if url := mockHTTP.GetLastRequest().URL.String(); url != "http://127.0.0.1:11434/v1/chat/completions" {
+		t.Errorf("unexpected request url: %s", url)
+	}
+	if usage := client.(UsageReporter).LastUsage(); usage.Model != "llama3.1:8b" || usage.TotalTokens != 0 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestLocalClient_ParseResponseFormats(t *testing.T) {
+	client := NewLocalClientWithOptions(WithLogger(NewNoopLogger())).(*LocalClient)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"chat", `{"choices":[{"message":{"content":"a"}}]}`, "a"},
+		{"completions", `{"choices":[{"text":"b"}]}`, "b"},
+		{"ollama native chat", `{"model":"llama3","message":{"role":"assistant","content":"c"},"done":true}`, "c"},
+		{"ollama generate", `{"model":"llama3","response":"d","done":true}`, "d"},
+	}
+	for _, tt := range tests {
+		got, err := client.parseMCPResponse([]byte(tt.body))
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected '%s', got '%s'", tt.name, tt.want, got)
+		}
+	}
+
+	if _, err := client.parseMCPResponse([]byte(`{}`)); err == nil {
+		t.Error("empty response should error")
+	}
+}
+
+func TestLocalClient_Defaults(t *testing.T) {
+	t.Setenv("AI_LOCAL_TIMEOUT_SECONDS", "600")
+
+	client := NewLocalClient().(*LocalClient)
+	if client.BaseURL != DefaultLocalBaseURL {
+		t.Errorf("expected default base url, got '%s'", client.BaseURL)
+	}
+	if client.httpClient.Timeout.Seconds() != 600 {
+		t.Errorf("expected timeout from env, got %v", client.httpClient.Timeout)
+	}
+	if client.MaxTokens != 0 {
+		t.Errorf("expected no max_tokens limit, got %d", client.MaxTokens)
+	}
+	if !IsLocalProvider(ProviderOllama) || IsLocalProvider(ProviderDeepSeek) {
+		t.Error("IsLocalProvider mismatch")
+	}
+
+	// 用户选项优先
+	if c := NewLocalClientWithOptions(WithMaxTokens(4000)).(*LocalClient); c.MaxTokens != 4000 {
+		t.Errorf("expected user max tokens, got %d", c.MaxTokens)
+	}
+}
@@ -68,6 +68,26 @@ func WithRetryWaitBase(waitTime time.Duration) ClientOption {
 	}
 }
 
+// WithRetryBudget 设置单次调用（含所有重试）的总时间预算，应小于交易员扫描间隔
+//
+// 使用示例：
+//   client := mcp.NewClient(mcp.WithRetryBudget(60 * time.Second))
+func WithRetryBudget(budget time.Duration) ClientOption {
+	return func(c *Config) {
+		c.RetryBudget = budget
+	}
+}
+
+// WithRetryJitter 设置退避等待的随机抖动比例（0 表示不抖动）
+//
+// 使用示例：
+//   client := mcp.NewClient(mcp.WithRetryJitter(0.5))
+func WithRetryJitter(jitter float64) ClientOption {
+	return func(c *Config) {
+		c.RetryJitter = jitter
+	}
+}
+
 // ============================================================
 // AI 参数选项
 // ============================================================
@@ -0,0 +1,134 @@
+package mcp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	// DefaultRetryBudget 单次调用（含所有重试）的默认总时间预算，应小于交易员扫描间隔
+	DefaultRetryBudget = 90 * time.Second
+
+	// DefaultMaxRetryWait 单次重试等待的上限（包括服务端 Retry-After）
+	DefaultMaxRetryWait = 30 * time.Second
+
+	// DefaultRetryJitter 退避等待的随机抖动比例（等待时长额外增加 0~25%）
+	DefaultRetryJitter = 0.25
+
+	// retryableStatusCodes 可重试的HTTP状态码（限流和网关/服务端临时错误）
+	retryableStatusCodes = map[int]bool{
+		http.StatusTooManyRequests:     true,
+		http.StatusInternalServerError: true,
+		http.StatusBadGateway:          true,
+		http.StatusServiceUnavailable:  true,
+		http.StatusGatewayTimeout:      true,
+	}
+)
+
+// RetryReporter 可选接口：支持上报最近一次调用尝试次数的客户端（1 表示未重试）
+type RetryReporter interface {
+	LastAttempts() int
+}
+
+// withRetry 固定的重试流程：指数退避 + 随机抖动，优先遵循 Retry-After，受最大次数和总时间预算限制
+func (client *Client) withRetry(callOnce func() (string, error)) (string, error) {
+	var lastErr error
+	maxRetries := client.config.MaxRetries
+	start := time.Now()
+	attempt := 1
+
+	defer func() {
+		client.usageMu.Lock()
+		client.lastAttempts = attempt
+		client.usageMu.Unlock()
+	}()
+
+	for ; attempt <= maxRetries; attempt++ {
+		if attempt > 1 {
+			client.logger.Warnf("⚠️  AI API调用失败，正在重试 (%d/%d)...", attempt, maxRetries)
+		}
+
+		result, err := callOnce()
+		if err == nil {
+			if attempt > 1 {
+				client.logger.Infof("✓ AI API重试成功")
+			}
+			return result, nil
+		}
+
+		lastErr = err
+		// 通过 hooks 判断是否可重试（支持子类自定义重试策略）
+		if !client.hooks.isRetryableError(err) {
+			return "", err
+		}
+		if attempt == maxRetries {
+			break
+		}
+
+		waitTime := client.retryWait(attempt, err)
+		if budget := client.config.RetryBudget; budget > 0 && time.Since(start)+waitTime > budget {
+			client.logger.Warnf("⚠️  AI API重试超出时间预算 %v，放弃重试", budget)
+			return "", fmt.Errorf("重试%d次后超出时间预算%v: %w", attempt, budget, lastErr)
+		}
+		client.logger.Infof("⏳ 等待%v后重试...", waitTime)
+		time.Sleep(waitTime)
+	}
+
+	return "", fmt.Errorf("重试%d次后仍然失败: %w", maxRetries, lastErr)
+}
+
+// retryWait 计算第 attempt 次失败后的等待时长
+func (client *Client) retryWait(attempt int, err error) time.Duration {
+	maxWait := client.config.MaxRetryWait
+	if maxWait <= 0 {
+		maxWait = DefaultMaxRetryWait
+	}
+
+	// 服务端指定了 Retry-After 时按其等待
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.ParsedRetryAfter > 0 {
+		return min(apiErr.ParsedRetryAfter, maxWait)
+	}
+
+	wait := client.config.RetryWaitBase << (attempt - 1)
+	if wait <= 0 || wait > maxWait {
+		wait = maxWait
+	}
+	if jitter := client.config.RetryJitter; jitter > 0 {
+		wait += time.Duration(rand.Float64() * jitter * float64(wait))
+	}
+	return wait
+}
+
+// LastAttempts 返回最近一次调用的尝试次数（实现 RetryReporter）
+func (client *Client) LastAttempts() int {
+	client.usageMu.RLock()
+	defer client.usageMu.RUnlock()
+	return client.lastAttempts
+}
+
+// parseRetryAfter 解析 Retry-After 响应头（秒数或HTTP日期），无效时返回 0
+func parseRetryAfter(header http.Header) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
+
+// SetRetryBudget 调整单次调用含重试的总时间预算（如交易员扫描间隔变化时）
+func (client *Client) SetRetryBudget(budget time.Duration) {
+	client.config.RetryBudget = budget
+}
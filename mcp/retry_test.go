@@ -0,0 +1,151 @@
+package mcp
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newStatusSequenceClient 按顺序返回给定状态码的客户端（最后一个状态码之后返回成功）
+func newStatusSequenceClient(t *testing.T, header http.Header, statuses []int, opts ...ClientOption) (AIClient, *int) {
+	t.Helper()
+	callCount := 0
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.ResponseFunc = func(req *http.Request) (*http.Response, error) {
+		callCount++
+		if callCount <= len(statuses) {
+			return &http.Response{
+				StatusCode: statuses[callCount-1],
+				Body:       io.NopCloser(bytes.NewBufferString(`{"error":{"message":"temporary"}}`)),
+				Header:     header,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(bytes.NewBufferString(`{"choices":[{"message":{"content":"ok"}}]}`)),
+			Header:     make(http.Header),
+		}, nil
+	}
+
+	opts = append([]ClientOption{
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewNoopLogger()),
+		WithAPIKey("sk-test-key"),
+		WithRetryWaitBase(10 * time.Millisecond),
+		WithMaxRetries(3),
+	}, opts...)
+	return NewClient(opts...), &callCount
+}
+
+func TestRetry_TransientStatusCodes(t *testing.T) {
+	client, callCount := newStatusSequenceClient(t, make(http.Header), []int{http.StatusBadGateway, http.StatusTooManyRequests})
+
+	result, err := client.CallWithMessages("system", "user")
+	if err != nil {
+		t.Fatalf("should succeed after retries: %v", err)
+	}
+	if result != "ok" || *callCount != 3 {
+		t.Errorf("expected 'ok' after 3 calls, got '%s' after %d", result, *callCount)
+	}
+	if attempts := client.(RetryReporter).LastAttempts(); attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+
+	// 成功调用后尝试次数重置
+	client.CallWithMessages("system", "user")
+	if attempts := client.(RetryReporter).LastAttempts(); attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetry_NonRetryableErrors(t *testing.T) {
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden, http.StatusPaymentRequired, http.StatusBadRequest} {
+		client, callCount := newStatusSequenceClient(t, make(http.Header), []int{status})
+		if _, err := client.CallWithMessages("system", "user"); err == nil {
+			t.Errorf("status %d: should error", status)
+		}
+		if *callCount != 1 {
+			t.Errorf("status %d: should not retry, got %d calls", status, *callCount)
+		}
+	}
+
+	c := NewClient().(*Client)
+	if c.isRetryableError(&InsufficientBalanceError{Provider: "deepseek", APIError: &APIError{StatusCode: 429}}) {
+		t.Error("insufficient balance should not be retryable")
+	}
+	if c.isRetryableError(&APIError{StatusCode: 503, Message: "Insufficient Balance"}) {
+		t.Error("insufficient balance api error should not be retryable")
+	}
+}
+
+func TestRetry_HonorsRetryAfter(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Retry-After", "1")
+	client, _ := newStatusSequenceClient(t, header, []int{http.StatusTooManyRequests})
+
+	start := time.Now()
+	if _, err := client.CallWithMessages("system", "user"); err != nil {
+		t.Fatalf("should succeed after retry: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("should wait for Retry-After (1s), waited %v", elapsed)
+	}
+}
+
+func TestRetry_BudgetExceeded(t *testing.T) {
+	header := make(http.Header)
+	header.Set("Retry-After", "5")
+	client, callCount := newStatusSequenceClient(t, header, []int{http.StatusServiceUnavailable}, WithRetryBudget(time.Second))
+
+	start := time.Now()
+	_, err := client.CallWithMessages("system", "user")
+	if err == nil || !strings.Contains(err.Error(), "时间预算") {
+		t.Fatalf("should stop retrying when budget exceeded, got %v", err)
+	}
+	if *callCount != 1 || time.Since(start) > time.Second {
+		t.Errorf("should give up without waiting, got %d calls in %v", *callCount, time.Since(start))
+	}
+	if attempts := client.(RetryReporter).LastAttempts(); attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryWait_ExponentialWithJitter(t *testing.T) {
+	c := NewClient(WithRetryWaitBase(time.Second), WithRetryJitter(0)).(*Client)
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 10: DefaultMaxRetryWait} {
+		if got := c.retryWait(attempt, errTest); got != want {
+			t.Errorf("attempt %d: expected %v, got %v", attempt, want, got)
+		}
+	}
+
+	c = NewClient(WithRetryWaitBase(time.Second), WithRetryJitter(0.5)).(*Client)
+	for i := 0; i < 20; i++ {
+		if got := c.retryWait(2, errTest); got < 2*time.Second || got > 3*time.Second {
+			t.Fatalf("jittered wait out of range: %v", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	header := make(http.Header)
+	if got := parseRetryAfter(header); got != 0 {
+		t.Errorf("missing header: expected 0, got %v", got)
+	}
+	header.Set("Retry-After", "3")
+	if got := parseRetryAfter(header); got != 3*time.Second {
+		t.Errorf("seconds: expected 3s, got %v", got)
+	}
+	header.Set("Retry-After", time.Now().Add(10*time.Second).UTC().Format(http.TimeFormat))
+	if got := parseRetryAfter(header); got <= 8*time.Second || got > 10*time.Second {
+		t.Errorf("http date: expected ~10s, got %v", got)
+	}
+	header.Set("Retry-After", "soon")
+	if got := parseRetryAfter(header); got != 0 {
+		t.Errorf("invalid: expected 0, got %v", got)
+	}
+}
+
+var errTest = &APIError{StatusCode: http.StatusBadGateway}
@@ -0,0 +1,103 @@
+package mcp
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Usage 单次 AI 调用的 token 用量（来自 OpenAI 兼容响应中的 usage 字段）
+type Usage struct {
+	Model            string `json:"model"`
+	PromptTokens     int    `json:"prompt_tokens"`
+	CompletionTokens int    `json:"completion_tokens"`
+	TotalTokens      int    `json:"total_tokens"`
+}
+
+// UsageReporter 可选接口：支持上报最近一次调用 token 用量的客户端
+//
+// 使用示例：
+//
+//	if reporter, ok := client.(mcp.UsageReporter); ok {
+//	    usage := reporter.LastUsage()
+//	}
+type UsageReporter interface {
+	LastUsage() Usage
+}
+
+// ModelPrice 模型单价（美元 / 百万 token）
+type ModelPrice struct {
+	InputPerMillion  float64
+	OutputPerMillion float64
+}
+
+var (
+	// modelPriceTable 常用模型价格表（按模型名前缀匹配，越长越优先）
+	modelPriceTable = map[string]ModelPrice{
+		"deepseek-chat":     {InputPerMillion: 0.28, OutputPerMillion: 0.42},
+		"deepseek-reasoner": {InputPerMillion: 0.28, OutputPerMillion: 0.42},
+		"qwen3-max":         {InputPerMillion: 1.2, OutputPerMillion: 6.0},
+		"qwen-max":          {InputPerMillion: 1.6, OutputPerMillion: 6.4},
+		"qwen-plus":         {InputPerMillion: 0.4, OutputPerMillion: 1.2},
+		"qwen-turbo":        {InputPerMillion: 0.05, OutputPerMillion: 0.2},
+		"gpt-4o-mini":       {InputPerMillion: 0.15, OutputPerMillion: 0.6},
+		"gpt-4o":            {InputPerMillion: 2.5, OutputPerMillion: 10.0},
+		"gpt-4.1-mini":      {InputPerMillion: 0.4, OutputPerMillion: 1.6},
+		"gpt-4.1":           {InputPerMillion: 2.0, OutputPerMillion: 8.0},
+		"claude-3-5-haiku":  {InputPerMillion: 0.8, OutputPerMillion: 4.0},
+		"claude-sonnet":     {InputPerMillion: 3.0, OutputPerMillion: 15.0},
+	}
+
+	// defaultModelPrice 未知模型的保守估价
+	defaultModelPrice = ModelPrice{InputPerMillion: 1.0, OutputPerMillion: 4.0}
+)
+
+// GetModelPrice 获取模型单价（按最长前缀匹配，未知模型返回默认估价）
+func GetModelPrice(model string) ModelPrice {
+	model = strings.ToLower(model)
+	if price, ok := modelPriceTable[model]; ok {
+		return price
+	}
+
+	bestLen := 0
+	price := defaultModelPrice
+	for prefix, p := range modelPriceTable {
+		if strings.HasPrefix(model, prefix) && len(prefix) > bestLen {
+			bestLen = len(prefix)
+			price = p
+		}
+	}
+	return price
+}
+
+// EstimateCost 根据 token 用量和价格表估算费用（美元）
+func EstimateCost(usage Usage) float64 {
+	price := GetModelPrice(usage.Model)
+	return float64(usage.PromptTokens)/1e6*price.InputPerMillion +
+		float64(usage.CompletionTokens)/1e6*price.OutputPerMillion
+}
+
+// parseUsage 从响应体中解析 usage 字段（解析失败返回零值）
+func parseUsage(body []byte) Usage {
+	var result struct {
+		Model string `json:"model"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+			TotalTokens      int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return Usage{}
+	}
+
+	usage := Usage{
+		Model:            result.Model,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		TotalTokens:      result.Usage.TotalTokens,
+	}
+	if usage.TotalTokens == 0 {
+		usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+	}
+	return usage
+}
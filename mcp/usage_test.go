@@ -0,0 +1,91 @@
+package mcp
+
+import (
+	"math"
+	"testing"
+)
+
+func TestClient_LastUsage(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"model":"deepseek-chat","choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":1200,"completion_tokens":300,"total_tokens":1500}}`
+
+	client := NewClient(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewNoopLogger()),
+		WithAPIKey("test-key"),
+		WithBaseURL("https://api.test.com"),
+	)
+
+	if _, err := client.CallWithMessages("system", "user"); err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+
+	reporter, ok := client.(UsageReporter)
+	if !ok {
+		t.Fatal("client should implement UsageReporter")
+	}
+
+	usage := reporter.LastUsage()
+	if usage.Model != "deepseek-chat" || usage.PromptTokens != 1200 || usage.CompletionTokens != 300 || usage.TotalTokens != 1500 {
+		t.Errorf("unexpected usage: %+v", usage)
+	}
+}
+
+func TestClient_LastUsage_FallbackModel(t *testing.T) {
+	mockHTTP := NewMockHTTPClient()
+	mockHTTP.Response = `{"choices":[{"message":{"content":"ok"}}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`
+
+	client := NewClient(
+		WithHTTPClient(mockHTTP.ToHTTPClient()),
+		WithLogger(NewNoopLogger()),
+		WithAPIKey("test-key"),
+		WithProvider(ProviderQwen),
+		WithModel("qwen3-max"),
+		WithBaseURL("https://api.test.com"),
+	)
+
+	if _, err := client.CallWithMessages("system", "user"); err != nil {
+		t.Fatalf("should not error: %v", err)
+	}
+
+	usage := client.(UsageReporter).LastUsage()
+	if usage.Model != "qwen3-max" {
+		t.Errorf("expected client model when response omits it, got '%s'", usage.Model)
+	}
+	if usage.TotalTokens != 15 {
+		t.Errorf("expected total tokens to be derived, got %d", usage.TotalTokens)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage Usage
+		want  float64
+	}{
+		{
+			name:  "exact model",
+			usage: Usage{Model: "deepseek-chat", PromptTokens: 1_000_000, CompletionTokens: 1_000_000},
+			want:  0.28 + 0.42,
+		},
+		{
+			name:  "prefix match",
+			usage: Usage{Model: "gpt-4o-mini-2024-07-18", PromptTokens: 1_000_000},
+			want:  0.15,
+		},
+		{
+			name:  "unknown model uses default price",
+			usage: Usage{Model: "some-local-model", CompletionTokens: 1_000_000},
+			want:  defaultModelPrice.OutputPerMillion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EstimateCost(tt.usage)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("EstimateCost() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
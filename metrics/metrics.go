@@ -0,0 +1,48 @@
+package metrics
+
+// 交易员
+var (
+	// TradersLoaded 已加载到内存的交易员数量（按用户）
+	TradersLoaded = defaultRegistry.NewGaugeVec("nofx_traders_loaded",
+		"Number of traders loaded in memory.", "user_id")
+	// TradersRunning 运行中的交易员数量（按用户）
+	TradersRunning = defaultRegistry.NewGaugeVec("nofx_traders_running",
+		"Number of running traders.", "user_id")
+)
+
+// 决策周期
+var (
+	// DecisionCycles 已执行的决策周期数
+	DecisionCycles = defaultRegistry.NewCounterVec("nofx_decision_cycles_total",
+		"Total number of decision cycles.", "trader_id")
+	// DecisionCyclesFailed 失败的决策周期数
+	DecisionCyclesFailed = defaultRegistry.NewCounterVec("nofx_decision_cycles_failed_total",
+		"Total number of failed decision cycles.", "trader_id")
+	// DecisionCycleDuration 决策周期耗时（包含AI调用和下单）
+	DecisionCycleDuration = defaultRegistry.NewHistogramVec("nofx_decision_cycle_duration_seconds",
+		"Duration of decision cycles in seconds.", []float64{1, 5, 10, 20, 30, 60, 90, 120, 180, 300}, "trader_id")
+)
+
+// AIErrors AI接口调用错误数（class 见 mcp.ClassifyError）
+var AIErrors = defaultRegistry.NewCounterVec("nofx_ai_errors_total",
+	"Total number of AI API errors by provider and class.", "provider", "class")
+
+// OrderErrors 执行交易决策（下单/平仓/修改止盈止损）失败数
+var OrderErrors = defaultRegistry.NewCounterVec("nofx_exchange_order_errors_total",
+	"Total number of failed exchange order actions.", "exchange", "action")
+
+// ExchangeRetries 交易所请求重试次数（reason: timestamp/rate_limit/server_error/network/gave_up）
+var ExchangeRetries = defaultRegistry.NewCounterVec("nofx_exchange_request_retries_total",
+	"Total number of exchange request retries by reason.", "exchange", "reason")
+
+// WSReconnects 行情组合流重连次数（result: success/failure）
+var WSReconnects = defaultRegistry.NewCounterVec("nofx_market_ws_reconnects_total",
+	"Total number of market WebSocket reconnect attempts.", "result")
+
+// WSDroppedMessages 行情订阅者通道已满时丢弃的消息数
+var WSDroppedMessages = defaultRegistry.NewCounterVec("nofx_market_ws_dropped_messages_total",
+	"Total number of market WebSocket messages dropped because a subscriber channel was full.")
+
+// HTTPRequestDuration API请求耗时
+var HTTPRequestDuration = defaultRegistry.NewHistogramVec("nofx_http_request_duration_seconds",
+	"HTTP request duration in seconds.", nil, "method", "route", "status")
@@ -0,0 +1,308 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// contentType Prometheus 文本格式
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// 指标类型
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// labelSeparator 拼接标签值作为 series key 的分隔符（不会出现在正常标签值中）
+const labelSeparator = "\xff"
+
+// DefaultBuckets 默认直方图分桶（秒）
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry 指标注册表，按注册顺序输出 Prometheus 文本格式
+type Registry struct {
+	mu      sync.RWMutex
+	metrics []*metricVec
+	names   map[string]bool
+}
+
+// NewRegistry 创建指标注册表
+func NewRegistry() *Registry {
+	return &Registry{names: make(map[string]bool)}
+}
+
+// defaultRegistry 全局注册表（/metrics 输出的内容）
+var defaultRegistry = NewRegistry()
+
+// series 一组标签值对应的指标数据
+type series struct {
+	labelValues []string
+	value       float64  // counter / gauge 的值
+	counts      []uint64 // histogram 各分桶计数（非累计）
+	count       uint64
+	sum         float64
+}
+
+// metricVec 带标签的指标（标签为空时只有一个 series）
+type metricVec struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// register 注册指标，名称重复时 panic（属于编程错误）
+func (r *Registry) register(m *metricVec) *metricVec {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[m.name] {
+		panic(fmt.Sprintf("metrics: 重复注册指标 %s", m.name))
+	}
+	r.names[m.name] = true
+	r.metrics = append(r.metrics, m)
+	return m
+}
+
+func newMetricVec(name, help, typ string, labels []string, buckets []float64) *metricVec {
+	return &metricVec{
+		name:    name,
+		help:    help,
+		typ:     typ,
+		labels:  labels,
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+}
+
+// lookup 查找标签值对应的 series（不存在时返回 nil），调用方需持有 m.mu
+func (m *metricVec) lookup(labelValues []string) *series {
+	return m.series[strings.Join(labelValues, labelSeparator)]
+}
+
+// getSeries 获取（或创建）标签值对应的 series，调用方需持有 m.mu
+func (m *metricVec) getSeries(labelValues []string) *series {
+	if len(labelValues) != len(m.labels) {
+		panic(fmt.Sprintf("metrics: %s 需要 %d 个标签值，实际 %d 个", m.name, len(m.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, labelSeparator)
+	s, ok := m.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if m.typ == typeHistogram {
+			s.counts = make([]uint64, len(m.buckets))
+		}
+		m.series[key] = s
+	}
+	return s
+}
+
+// CounterVec 只增不减的计数器
+type CounterVec struct{ m *metricVec }
+
+// NewCounterVec 在指定注册表创建计数器
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{m: r.register(newMetricVec(name, help, typeCounter, labels, nil))}
+}
+
+// Inc 计数加一
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add 计数增加 delta（负数会被忽略）
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		return
+	}
+	c.m.mu.Lock()
+	c.m.getSeries(labelValues).value += delta
+	c.m.mu.Unlock()
+}
+
+// Value 返回当前计数（主要用于测试）
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.m.mu.Lock()
+	defer c.m.mu.Unlock()
+	if s := c.m.lookup(labelValues); s != nil {
+		return s.value
+	}
+	return 0
+}
+
+// GaugeVec 可增可减的仪表
+type GaugeVec struct{ m *metricVec }
+
+// NewGaugeVec 在指定注册表创建仪表
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	return &GaugeVec{m: r.register(newMetricVec(name, help, typeGauge, labels, nil))}
+}
+
+// Set 设置当前值
+func (g *GaugeVec) Set(value float64, labelValues ...string) {
+	g.m.mu.Lock()
+	g.m.getSeries(labelValues).value = value
+	g.m.mu.Unlock()
+}
+
+// Add 当前值增加 delta（可为负数）
+func (g *GaugeVec) Add(delta float64, labelValues ...string) {
+	g.m.mu.Lock()
+	g.m.getSeries(labelValues).value += delta
+	g.m.mu.Unlock()
+}
+
+// Inc 当前值加一
+func (g *GaugeVec) Inc(labelValues ...string) { g.Add(1, labelValues...) }
+
+// Dec 当前值减一
+func (g *GaugeVec) Dec(labelValues ...string) { g.Add(-1, labelValues...) }
+
+// Value 返回当前值（主要用于测试）
+func (g *GaugeVec) Value(labelValues ...string) float64 {
+	g.m.mu.Lock()
+	defer g.m.mu.Unlock()
+	if s := g.m.lookup(labelValues); s != nil {
+		return s.value
+	}
+	return 0
+}
+
+// HistogramVec 直方图
+type HistogramVec struct{ m *metricVec }
+
+// NewHistogramVec 在指定注册表创建直方图，buckets 为空时使用 DefaultBuckets
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &HistogramVec{m: r.register(newMetricVec(name, help, typeHistogram, labels, sorted))}
+}
+
+// Observe 记录一次观测值
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	s := h.m.getSeries(labelValues)
+	if i := sort.SearchFloat64s(h.m.buckets, value); i < len(s.counts) {
+		s.counts[i]++
+	}
+	s.count++
+	s.sum += value
+}
+
+// Count 返回观测次数（主要用于测试）
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.m.mu.Lock()
+	defer h.m.mu.Unlock()
+	if s := h.m.lookup(labelValues); s != nil {
+		return s.count
+	}
+	return 0
+}
+
+// WriteText 以 Prometheus 文本格式输出所有指标
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.RLock()
+	metrics := append([]*metricVec(nil), r.metrics...)
+	r.mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	for _, m := range metrics {
+		m.writeText(bw)
+	}
+	return bw.Flush()
+}
+
+// writeText 输出单个指标的 HELP/TYPE 和所有 series（按标签值排序，输出稳定）
+func (m *metricVec) writeText(w *bufio.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, escapeHelp(m.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.typ)
+
+	keys := make([]string, 0, len(m.series))
+	for key := range m.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.series[key]
+		if m.typ != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, m.formatLabels(s.labelValues, ""), formatFloat(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, upper := range m.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.formatLabels(s.labelValues, formatFloat(upper)), cumulative)
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", m.name, m.formatLabels(s.labelValues, "+Inf"), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", m.name, m.formatLabels(s.labelValues, ""), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", m.name, m.formatLabels(s.labelValues, ""), s.count)
+	}
+}
+
+// formatLabels 格式化标签，le 非空时追加直方图分桶标签
+func (m *metricVec) formatLabels(values []string, le string) string {
+	if len(values) == 0 && le == "" {
+		return ""
+	}
+	pairs := make([]string, 0, len(values)+1)
+	for i, v := range values {
+		pairs = append(pairs, m.labels[i]+`="`+escapeLabelValue(v)+`"`)
+	}
+	if le != "" {
+		pairs = append(pairs, `le="`+le+`"`)
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
+func escapeLabelValue(s string) string { return labelEscaper.Replace(s) }
+
+// Handler 返回输出全局注册表的 HTTP 处理器
+func Handler() http.Handler {
+	return defaultRegistry.Handler()
+}
+
+// Handler 返回输出该注册表的 HTTP 处理器
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		r.WriteText(w)
+	})
+}
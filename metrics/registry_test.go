@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRegistry_WriteText 测试计数器和仪表的文本输出
+func TestRegistry_WriteText(t *testing.T) {
+	r := NewRegistry()
+	cycles := r.NewCounterVec("test_cycles_total", "Total cycles.", "trader_id")
+	running := r.NewGaugeVec("test_running", "Running traders.")
+
+	cycles.Inc("b")
+	cycles.Add(2, "a")
+	cycles.Add(-1, "a") // 计数器不能减少
+	running.Inc()
+	running.Inc()
+	running.Dec()
+
+	var sb strings.Builder
+	if err := r.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText 失败: %v", err)
+	}
+	want := `# HELP test_cycles_total Total cycles.
+# TYPE test_cycles_total counter
+test_cycles_total{trader_id="a"} 2
+test_cycles_total{trader_id="b"} 1
+# HELP test_running Running traders.
+# TYPE test_running gauge
+test_running 1
+`
+	if sb.String() != want {
+		t.Errorf("输出不符:\n%s\nwant:\n%s", sb.String(), want)
+	}
+}
+
+// TestHistogramVec_Buckets 测试直方图分桶为累计计数
+func TestHistogramVec_Buckets(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_duration_seconds", "Duration.", []float64{1, 0.1}, "route")
+
+	h.Observe(0.05, "/x")
+	h.Observe(0.1, "/x")
+	h.Observe(0.5, "/x")
+	h.Observe(3, "/x")
+
+	var sb strings.Builder
+	r.WriteText(&sb)
+	out := sb.String()
+	for _, line := range []string{
+		`test_duration_seconds_bucket{route="/x",le="0.1"} 2`,
+		`test_duration_seconds_bucket{route="/x",le="1"} 3`,
+		`test_duration_seconds_bucket{route="/x",le="+Inf"} 4`,
+		`test_duration_seconds_sum{route="/x"} 3.65`,
+		`test_duration_seconds_count{route="/x"} 4`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("输出缺少 %q:\n%s", line, out)
+		}
+	}
+	if got := h.Count("/x"); got != 4 {
+		t.Errorf("Count = %d, want 4", got)
+	}
+}
+
+// TestRegistry_EscapeAndHandler 测试标签转义和HTTP输出
+func TestRegistry_EscapeAndHandler(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounterVec("test_errors_total", "Errors.", "msg")
+	c.Inc("say \"hi\"\n")
+
+	w := httptest.NewRecorder()
+	r.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(w.Body.String(), `test_errors_total{msg="say \"hi\"\n"} 1`) {
+		t.Errorf("标签未正确转义:\n%s", w.Body.String())
+	}
+}
+
+// TestRegistry_DuplicateName 测试重复注册指标会 panic
+func TestRegistry_DuplicateName(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_dup", "Dup.")
+	defer func() {
+		if recover() == nil {
+			t.Error("重复注册应 panic")
+		}
+	}()
+	r.NewGaugeVec("test_dup", "Dup.")
+}
@@ -0,0 +1,143 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	telegramAPIBase = "https://api.telegram.org"
+	// telegramMaxLength / discordMaxLength 单条消息长度上限（字符）
+	telegramMaxLength = 4096
+	discordMaxLength  = 2000
+	// signatureHeader 通用 Webhook 的签名请求头：HMAC-SHA256(secret, 时间戳 + "." + 请求体)
+	signatureHeader = "X-NOFX-Signature"
+	timestampHeader = "X-NOFX-Timestamp"
+)
+
+// StatusError 通知服务返回的非2xx响应
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("通知服务返回 %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable 限流和服务端错误可以重试，其他4xx（凭证错误、地址不存在等）重试也不会成功
+func (e *StatusError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// postJSON 发送 JSON 请求，非2xx响应返回 *StatusError
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &StatusError{StatusCode: resp.StatusCode, Body: string(snippet)}
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+// truncate 按字符截断过长的消息
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
+
+// telegramChannel 通过 Bot API sendMessage 发送纯文本消息
+type telegramChannel struct {
+	client   *http.Client
+	apiBase  string
+	botToken string
+	chatID   string
+}
+
+func (t *telegramChannel) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"chat_id":                  t.chatID,
+		"text":                     truncate(msg.plainText(), telegramMaxLength),
+		"disable_web_page_preview": true,
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, t.client, t.apiBase+"/bot"+t.botToken+"/sendMessage", body, nil)
+}
+
+// discordChannel 通过 Discord Webhook 发送消息
+type discordChannel struct {
+	client     *http.Client
+	webhookURL string
+}
+
+func (d *discordChannel) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"content": truncate(msg.plainText(), discordMaxLength),
+		// 不解析 @everyone 等提及，避免消息内容触发通知
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	})
+	if err != nil {
+		return err
+	}
+	return postJSON(ctx, d.client, d.webhookURL, body, nil)
+}
+
+// webhookChannel 通用 Webhook：POST 完整的 Message JSON，配置了密钥时附带签名
+type webhookChannel struct {
+	client *http.Client
+	url    string
+	secret string
+}
+
+func (w *webhookChannel) Send(ctx context.Context, msg *Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	var headers map[string]string
+	if w.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		headers = map[string]string{
+			timestampHeader: timestamp,
+			signatureHeader: Sign(w.secret, timestamp, body),
+		}
+	}
+	return postJSON(ctx, w.client, w.url, body, headers)
+}
+
+// Sign 通用 Webhook 签名（接收方用相同算法校验请求来源和时间戳）
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
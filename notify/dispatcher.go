@@ -0,0 +1,165 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWorkers      = 2
+	defaultQueueSize    = 256
+	defaultMaxAttempts  = 3
+	defaultRetryBackoff = 2 * time.Second
+)
+
+// FailureFunc 通知最终发送失败（重试用尽、不可重试或队列已满）时的回调，attempts 为已尝试次数
+type FailureFunc func(msg *Message, attempts int, err error)
+
+// ErrQueueFull 发送队列已满，通知被丢弃
+var ErrQueueFull = errors.New("通知队列已满")
+
+// delivery 一次待发送的通知
+type delivery struct {
+	channel   Channel
+	name      string // 通道名称（用于日志）
+	msg       *Message
+	onFailure FailureFunc
+}
+
+// Dispatcher 异步发送通知：入队不阻塞（队列满时丢弃），失败时按指数退避重试
+type Dispatcher struct {
+	queue       chan delivery
+	maxAttempts int
+	backoff     time.Duration
+	timeout     time.Duration
+	stopCh      chan struct{}
+	wg          sync.WaitGroup
+	once        sync.Once
+}
+
+// NewDispatcher 创建并启动通知发送器（workers / queueSize <= 0 时使用默认值）
+func NewDispatcher(workers, queueSize int) *Dispatcher {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	d := &Dispatcher{
+		queue:       make(chan delivery, queueSize),
+		maxAttempts: defaultMaxAttempts,
+		backoff:     defaultRetryBackoff,
+		timeout:     defaultSendTimeout,
+		stopCh:      make(chan struct{}),
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.run()
+	}
+	return d
+}
+
+// SetTimeout 设置单次发送的超时时间（需在入队前调用）
+func (d *Dispatcher) SetTimeout(timeout time.Duration) {
+	if timeout > 0 {
+		d.timeout = timeout
+	}
+}
+
+// Enqueue 加入发送队列（不阻塞），队列已满或已关闭时丢弃并返回 false
+func (d *Dispatcher) Enqueue(channel Channel, name string, msg *Message) bool {
+	return d.EnqueueWithFailure(channel, name, msg, nil)
+}
+
+// EnqueueWithFailure 同 Enqueue，最终发送失败或队列已满时调用 onFailure（在发送协程中调用）
+func (d *Dispatcher) EnqueueWithFailure(channel Channel, name string, msg *Message, onFailure FailureFunc) bool {
+	select {
+	case <-d.stopCh:
+		return false
+	default:
+	}
+	select {
+	case d.queue <- delivery{channel: channel, name: name, msg: msg, onFailure: onFailure}:
+		return true
+	default:
+		log.Printf("⚠️ 通知队列已满，丢弃通知 [%s] %s", name, msg.Event)
+		if onFailure != nil {
+			onFailure(msg, 0, ErrQueueFull)
+		}
+		return false
+	}
+}
+
+// Close 停止发送器：等待正在发送的通知结束，队列中剩余的通知各尝试发送一次
+func (d *Dispatcher) Close() {
+	d.once.Do(func() {
+		close(d.stopCh)
+		d.wg.Wait()
+	})
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+	for {
+		select {
+		case job := <-d.queue:
+			d.deliver(job)
+		case <-d.stopCh:
+			for {
+				select {
+				case job := <-d.queue:
+					if err := d.send(job); err != nil {
+						d.fail(job, 1, err)
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver 发送并在可重试的失败后退避重试，关闭时放弃剩余的重试
+func (d *Dispatcher) deliver(job delivery) {
+	var err error
+	attempt := 1
+	for ; attempt <= d.maxAttempts; attempt++ {
+		if err = d.send(job); err == nil {
+			return
+		}
+		var statusErr *StatusError
+		if errors.As(err, &statusErr) && !statusErr.Retryable() {
+			break
+		}
+		if attempt == d.maxAttempts {
+			break
+		}
+		timer := time.NewTimer(d.backoff << (attempt - 1))
+		select {
+		case <-timer.C:
+		case <-d.stopCh:
+			timer.Stop()
+			log.Printf("⚠️ 通知发送失败 [%s] %s（服务关闭，放弃重试）: %v", job.name, job.msg.Event, err)
+			d.fail(job, attempt, err)
+			return
+		}
+	}
+	log.Printf("⚠️ 通知发送失败 [%s] %s: %v", job.name, job.msg.Event, err)
+	d.fail(job, attempt, err)
+}
+
+// fail 调用发送失败回调
+func (d *Dispatcher) fail(job delivery, attempts int, err error) {
+	if job.onFailure != nil {
+		job.onFailure(job.msg, attempts, err)
+	}
+}
+
+func (d *Dispatcher) send(job delivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d.timeout)
+	defer cancel()
+	return job.channel.Send(ctx, job.msg)
+}
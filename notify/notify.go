@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 通知通道类型
+const (
+	ChannelTelegram = "telegram" // Telegram Bot API
+	ChannelDiscord  = "discord"  // Discord Webhook
+	ChannelWebhook  = "webhook"  // 通用 Webhook（POST JSON）
+)
+
+// ChannelTypes 支持的通知通道类型
+var ChannelTypes = []string{ChannelTelegram, ChannelDiscord, ChannelWebhook}
+
+// defaultSendTimeout 单次发送的超时时间
+const defaultSendTimeout = 10 * time.Second
+
+// Message 一条通知
+type Message struct {
+	ID       string      `json:"id,omitempty"`        // 投递ID（接收方可用于去重）
+	Event    string      `json:"event"`               // 事件类型（如 position_opened）
+	Title    string      `json:"title,omitempty"`     // 标题
+	Text     string      `json:"text,omitempty"`      // 正文（纯文本）
+	TraderID string      `json:"trader_id,omitempty"` // 关联的交易员
+	Time     time.Time   `json:"time"`                // 事件时间
+	Data     interface{} `json:"data,omitempty"`      // 原始事件数据（仅通用 Webhook 发送）
+}
+
+// plainText 标题和正文合并为一段纯文本（Telegram / Discord 使用）
+func (m *Message) plainText() string {
+	if m.Title == "" {
+		return m.Text
+	}
+	if m.Text == "" {
+		return m.Title
+	}
+	return m.Title + "\n" + m.Text
+}
+
+// Channel 通知通道
+type Channel interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// ChannelConfig 通知通道参数（包含凭证，加密存储）
+type ChannelConfig struct {
+	BotToken   string `json:"bot_token,omitempty"`   // Telegram 机器人 token
+	ChatID     string `json:"chat_id,omitempty"`     // Telegram 会话ID（用户、群组或频道）
+	WebhookURL string `json:"webhook_url,omitempty"` // Discord / 通用 Webhook 地址
+	Secret     string `json:"secret,omitempty"`      // 通用 Webhook 签名密钥（为空时不签名）
+}
+
+// Validate 按通道类型校验必填参数
+func (c ChannelConfig) Validate(channelType string) error {
+	switch channelType {
+	case ChannelTelegram:
+		if strings.TrimSpace(c.BotToken) == "" || strings.TrimSpace(c.ChatID) == "" {
+			return fmt.Errorf("Telegram 通道需要填写 bot_token 和 chat_id")
+		}
+		if strings.ContainsAny(c.BotToken, "/?#") {
+			return fmt.Errorf("bot_token 格式无效")
+		}
+	case ChannelDiscord, ChannelWebhook:
+		if err := ValidateTargetURL(c.WebhookURL); err != nil {
+			return fmt.Errorf("webhook_url %w", err)
+		}
+	default:
+		return fmt.Errorf("不支持的通知通道类型: %s（支持 %s）", channelType, strings.Join(ChannelTypes, ", "))
+	}
+	return nil
+}
+
+// Masked 用于接口返回的脱敏配置（凭证只保留末4位）
+func (c ChannelConfig) Masked() ChannelConfig {
+	return ChannelConfig{
+		BotToken:   maskSecret(c.BotToken),
+		ChatID:     c.ChatID,
+		WebhookURL: maskURL(c.WebhookURL),
+		Secret:     maskSecret(c.Secret),
+	}
+}
+
+func maskSecret(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) <= 8 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
+
+// maskURL Webhook 地址的路径和查询参数通常包含凭证，只保留协议和主机
+func maskURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return maskSecret(value)
+	}
+	return u.Scheme + "://" + u.Host + "/****"
+}
+
+// NewChannel 按类型和参数创建通知通道（client 为 nil 时使用默认超时、只能连接公网地址的 HTTP 客户端）
+func NewChannel(channelType string, cfg ChannelConfig, client *http.Client) (Channel, error) {
+	if err := cfg.Validate(channelType); err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = newGuardedClient(defaultSendTimeout)
+	}
+	switch channelType {
+	case ChannelTelegram:
+		return &telegramChannel{client: client, apiBase: telegramAPIBase, botToken: cfg.BotToken, chatID: cfg.ChatID}, nil
+	case ChannelDiscord:
+		return &discordChannel{client: client, webhookURL: cfg.WebhookURL}, nil
+	default:
+		return &webhookChannel{client: client, url: cfg.WebhookURL, secret: cfg.Secret}, nil
+	}
+}
@@ -0,0 +1,178 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingServer 记录请求体，前 failures 次返回 status
+func recordingServer(t *testing.T, failures, status int) (*httptest.Server, func() []*http.Request, func() [][]byte) {
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		n := len(requests)
+		mu.Unlock()
+		if n <= failures {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	t.Cleanup(server.Close)
+	SetAllowPrivateTargets(true)
+	t.Cleanup(func() { SetAllowPrivateTargets(false) })
+	return server,
+		func() []*http.Request { mu.Lock(); defer mu.Unlock(); return append([]*http.Request(nil), requests...) },
+		func() [][]byte { mu.Lock(); defer mu.Unlock(); return append([][]byte(nil), bodies...) }
+}
+
+// TestChannels 各通道的请求格式：Telegram sendMessage、Discord content、通用 Webhook 完整消息和签名
+func TestChannels(t *testing.T) {
+	msg := &Message{Event: "position_opened", Title: "📈 开仓", Text: "BTCUSDT 开多", TraderID: "t1", Time: time.Now()}
+
+	server, requests, bodies := recordingServer(t, 0, 0)
+	telegram, _ := NewChannel(ChannelTelegram, ChannelConfig{BotToken: "123:abc", ChatID: "-100"}, nil)
+	telegram.(*telegramChannel).apiBase = server.URL
+	if err := telegram.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Telegram 发送失败: %v", err)
+	}
+	var tg map[string]interface{}
+	json.Unmarshal(bodies()[0], &tg)
+	if requests()[0].URL.Path != "/bot123:abc/sendMessage" || tg["chat_id"] != "-100" || tg["text"] != "📈 开仓\nBTCUSDT 开多" {
+		t.Errorf("Telegram 请求不正确: %s %v", requests()[0].URL.Path, tg)
+	}
+
+	discord, _ := NewChannel(ChannelDiscord, ChannelConfig{WebhookURL: server.URL + "/discord"}, nil)
+	if err := discord.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Discord 发送失败: %v", err)
+	}
+	if !strings.Contains(string(bodies()[1]), `"content":"📈 开仓\nBTCUSDT 开多"`) {
+		t.Errorf("Discord 请求不正确: %s", bodies()[1])
+	}
+
+	webhook, _ := NewChannel(ChannelWebhook, ChannelConfig{WebhookURL: server.URL + "/hook", Secret: "s3cret"}, nil)
+	if err := webhook.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Webhook 发送失败: %v", err)
+	}
+	req, body := requests()[2], bodies()[2]
+	if want := Sign("s3cret", req.Header.Get(timestampHeader), body); req.Header.Get(signatureHeader) != want {
+		t.Errorf("Webhook 签名不正确: %s", req.Header.Get(signatureHeader))
+	}
+	var got Message
+	if json.Unmarshal(body, &got); got.Event != msg.Event || got.TraderID != "t1" {
+		t.Errorf("Webhook 应发送完整消息: %s", body)
+	}
+}
+
+// TestChannelConfigValidate 必填参数和地址协议
+func TestChannelConfigValidate(t *testing.T) {
+	for _, tc := range []struct {
+		channelType string
+		cfg         ChannelConfig
+		ok          bool
+	}{
+		{ChannelTelegram, ChannelConfig{BotToken: "123:abc", ChatID: "1"}, true},
+		{ChannelTelegram, ChannelConfig{BotToken: "123:abc"}, false},
+		{ChannelTelegram, ChannelConfig{BotToken: "../x", ChatID: "1"}, false},
+		{ChannelDiscord, ChannelConfig{WebhookURL: "https://discord.com/api/webhooks/1/x"}, true},
+		{ChannelWebhook, ChannelConfig{WebhookURL: "file:///etc/passwd"}, false},
+		{ChannelWebhook, ChannelConfig{WebhookURL: "https://203.0.113.10/hook"}, true},
+		{ChannelWebhook, ChannelConfig{WebhookURL: "http://127.0.0.1:8080/hook"}, false},
+		{ChannelWebhook, ChannelConfig{WebhookURL: "http://10.0.0.5/hook"}, false},
+		{ChannelWebhook, ChannelConfig{WebhookURL: "http://169.254.169.254/latest/meta-data"}, false},
+		{ChannelDiscord, ChannelConfig{WebhookURL: "http://[::1]/hook"}, false},
+		{"sms", ChannelConfig{}, false},
+	} {
+		if err := tc.cfg.Validate(tc.channelType); (err == nil) != tc.ok {
+			t.Errorf("%s %+v: %v", tc.channelType, tc.cfg, err)
+		}
+	}
+
+	masked := ChannelConfig{BotToken: "123456:ABCDEFGH", WebhookURL: "https://discord.com/api/webhooks/1/token"}.Masked()
+	if masked.BotToken != "****EFGH" || masked.WebhookURL != "https://discord.com/****" {
+		t.Errorf("脱敏结果不正确: %+v", masked)
+	}
+}
+
+// TestGuardedClient 域名解析到内网地址时保存校验拒绝；发送时连接前重新解析，DNS 重绑定到回环地址的请求被拦截
+func TestGuardedClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	resolved := "203.0.113.10"
+	lookupIPAddr = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP(resolved)}}, nil
+	}
+	defer func() { lookupIPAddr = net.DefaultResolver.LookupIPAddr }()
+
+	if err := ValidateTargetURL("https://hooks.example.com/x"); err != nil {
+		t.Fatalf("解析到公网地址应允许: %v", err)
+	}
+	resolved = "192.168.1.1"
+	if err := ValidateTargetURL("https://hooks.example.com/x"); !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("解析到内网地址应拒绝: %v", err)
+	}
+
+	resolved = "127.0.0.1"
+	_, port, _ := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	hook, _ := NewChannel(ChannelWebhook, ChannelConfig{WebhookURL: "https://203.0.113.10/hook"}, nil)
+	hook.(*webhookChannel).url = "http://rebind.example.com:" + port + "/hook"
+	if err := hook.Send(context.Background(), &Message{Event: "test"}); !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("发送时解析到回环地址应拒绝: %v", err)
+	}
+}
+
+// TestDispatcherRetry 服务端错误退避重试直到成功；凭证错误（4xx）不重试并调用失败回调；Enqueue 不阻塞
+func TestDispatcherRetry(t *testing.T) {
+	d := NewDispatcher(1, 4)
+	d.backoff = time.Millisecond
+	defer d.Close()
+
+	flaky, flakyRequests, _ := recordingServer(t, 2, http.StatusBadGateway)
+	rejected, rejectedRequests, _ := recordingServer(t, 10, http.StatusUnauthorized)
+	flakyChannel, _ := NewChannel(ChannelWebhook, ChannelConfig{WebhookURL: flaky.URL}, nil)
+	rejectedChannel, _ := NewChannel(ChannelWebhook, ChannelConfig{WebhookURL: rejected.URL}, nil)
+
+	d.Enqueue(flakyChannel, "flaky", &Message{Event: "test"})
+	failed := make(chan int, 1)
+	d.EnqueueWithFailure(rejectedChannel, "rejected", &Message{Event: "test"}, func(msg *Message, attempts int, err error) {
+		failed <- attempts
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(flakyRequests()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	d.Close()
+	if n := len(flakyRequests()); n != 3 {
+		t.Errorf("502 应重试直到成功（共3次），实际 %d 次", n)
+	}
+	if n := len(rejectedRequests()); n != 1 {
+		t.Errorf("401 不应重试，实际 %d 次", n)
+	}
+	select {
+	case attempts := <-failed:
+		if attempts != 1 {
+			t.Errorf("失败回调的尝试次数应为1，实际 %d", attempts)
+		}
+	default:
+		t.Error("发送失败应调用失败回调")
+	}
+	if d.Enqueue(flakyChannel, "flaky", &Message{Event: "test"}) {
+		t.Error("关闭后不应再入队")
+	}
+}
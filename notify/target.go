@@ -0,0 +1,113 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPrivateTarget 投递地址指向内网、回环或链路本地地址（防止通过 Webhook 访问服务器内网，即 SSRF）
+var ErrPrivateTarget = errors.New("不允许发送到内网、回环或链路本地地址")
+
+// targetResolveTimeout 保存配置时解析主机的超时时间
+const targetResolveTimeout = 3 * time.Second
+
+// lookupIPAddr 解析主机地址（测试时替换）
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// allowPrivateTargets 是否允许发送到内网地址（默认禁止）
+var allowPrivateTargets atomic.Bool
+
+// SetAllowPrivateTargets 允许或禁止发送到内网、回环地址（仅用于测试：测试服务器监听在回环地址）
+func SetAllowPrivateTargets(allow bool) {
+	allowPrivateTargets.Store(allow)
+}
+
+// isPrivateTarget 是否为非公网地址（私有、回环、链路本地、未指定和组播地址）
+func isPrivateTarget(ip net.IP) bool {
+	return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified()
+}
+
+// checkTargetIPs 所有解析结果都必须是公网地址
+func checkTargetIPs(host string, ips []net.IPAddr) error {
+	if allowPrivateTargets.Load() {
+		return nil
+	}
+	for _, ip := range ips {
+		if !isPrivateTarget(ip.IP) {
+			continue
+		}
+		if ip.IP.String() == host {
+			return fmt.Errorf("%w: %s", ErrPrivateTarget, host)
+		}
+		return fmt.Errorf("%w: %s (%s)", ErrPrivateTarget, host, ip.IP)
+	}
+	return nil
+}
+
+// ValidateTargetURL 校验出站地址：必须是 http(s) 地址，主机解析出的地址不能是内网、回环或链路本地地址。
+// 保存时域名暂时无法解析不视为错误，发送时的连接校验（guardedDialContext）会再次检查
+func ValidateTargetURL(raw string) error {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("必须是 http:// 或 https:// 开头的地址")
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); ip != nil {
+		return checkTargetIPs(host, []net.IPAddr{{IP: ip}})
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), targetResolveTimeout)
+	defer cancel()
+	ips, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil
+	}
+	return checkTargetIPs(host, ips)
+}
+
+// guardedDialContext 建立连接前解析主机并校验地址，直接连接校验过的IP，
+// 避免 DNS 重绑定（保存时解析到公网、发送时解析到内网）绕过检查；重定向后的新连接同样经过校验
+func guardedDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips := []net.IPAddr{{IP: net.ParseIP(host)}}
+	if ips[0].IP == nil {
+		if ips, err = lookupIPAddr(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+	if err := checkTargetIPs(host, ips); err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	var lastErr error
+	for _, ip := range ips {
+		conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip.IP.String(), port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("无法解析主机: %s", host)
+	}
+	return nil, lastErr
+}
+
+// newGuardedClient 创建只能连接公网地址的 HTTP 客户端（不使用环境变量中的代理，否则连接校验的是代理地址）
+func newGuardedClient(timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = guardedDialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -71,7 +71,7 @@ func migrateExchanges(db *sql.DB, em *crypto.EncryptionManager) error {
 
 	// 查詢所有未加密的記錄（假設加密數據都包含 '==' Base64 特徵）
 	rows, err := db.Query(`
-		SELECT user_id, id, api_key, secret_key,
+		SELECT user_id, id, account_id, api_key, secret_key,
 		       COALESCE(hyperliquid_private_key, ''),
 		       COALESCE(aster_private_key, '')
 		FROM exchanges
@@ -91,8 +91,8 @@ func migrateExchanges(db *sql.DB, em *crypto.EncryptionManager) error {
 
 	count := 0
 	for rows.Next() {
-		var userID, exchangeID, apiKey, secretKey, hlPrivateKey, asterPrivateKey string
-		if err := rows.Scan(&userID, &exchangeID, &apiKey, &secretKey, &hlPrivateKey, &asterPrivateKey); err != nil {
+		var userID, exchangeID, accountID, apiKey, secretKey, hlPrivateKey, asterPrivateKey string
+		if err := rows.Scan(&userID, &exchangeID, &accountID, &apiKey, &secretKey, &hlPrivateKey, &asterPrivateKey); err != nil {
 			return err
 		}
 
@@ -128,14 +128,14 @@ func migrateExchanges(db *sql.DB, em *crypto.EncryptionManager) error {
 			UPDATE exchanges
 			SET api_key = ?, secret_key = ?,
 			    hyperliquid_private_key = ?, aster_private_key = ?
-			WHERE user_id = ? AND id = ?
-		`, encAPIKey, encSecretKey, encHLPrivateKey, encAsterPrivateKey, userID, exchangeID)
+			WHERE user_id = ? AND id = ? AND account_id = ?
+		`, encAPIKey, encSecretKey, encHLPrivateKey, encAsterPrivateKey, userID, exchangeID, accountID)
 
 		if err != nil {
 			return fmt.Errorf("更新數據庫失敗: %w", err)
 		}
 
-		log.Printf("  ✓ 已加密: [%s] %s", userID, exchangeID)
+		log.Printf("  ✓ 已加密: [%s] %s/%s", userID, exchangeID, accountID)
 		count++
 	}
 
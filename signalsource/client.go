@@ -0,0 +1,201 @@
+package signalsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultTTL     = 5 * time.Minute  // 默认缓存有效期
+	DefaultTimeout = 30 * time.Second // 默认请求超时
+	MaxTimeout     = 2 * time.Minute  // 允许配置的最大请求超时
+	maxHeaders     = 10               // 最多附加的请求头数量
+)
+
+// reHeaderName 请求头名称格式
+var reHeaderName = regexp.MustCompile(`^[A-Za-z0-9-]{1,64}$`)
+
+// Source 信号源配置
+type Source struct {
+	URL     string
+	Headers map[string]string // 附加的请求头（如 Authorization）
+	Timeout time.Duration     // 请求超时（0表示使用默认超时）
+}
+
+// cacheKey 缓存键（包含请求头摘要，不同凭证的请求不共用缓存）
+func (s Source) cacheKey() string {
+	if len(s.Headers) == 0 {
+		return s.URL
+	}
+	names := make([]string, 0, len(s.Headers))
+	for name := range s.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s\x00%s\x00", http.CanonicalHeaderKey(name), s.Headers[name])
+	}
+	return s.URL + "#" + hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheEntry 缓存的解析结果
+type cacheEntry struct {
+	result    *Result
+	expiresAt time.Time
+}
+
+// Client 信号源客户端（按URL缓存结果，上游不可用时返回过期缓存）
+type Client struct {
+	httpClient *http.Client
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+// NewClient 创建信号源客户端，ttl<=0 时使用默认缓存有效期
+func NewClient(ttl time.Duration) *Client {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Client{
+		httpClient: &http.Client{},
+		ttl:        ttl,
+		now:        time.Now,
+		cache:      make(map[string]*cacheEntry),
+	}
+}
+
+// defaultClient 交易员共用的客户端（同一个信号源只请求一次）
+var defaultClient = NewClient(DefaultTTL)
+
+// Default 返回共用的信号源客户端
+func Default() *Client {
+	return defaultClient
+}
+
+// ValidateURL 校验信号源地址（只允许 http/https）
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("信号源地址无效: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("信号源地址必须以 http:// 或 https:// 开头")
+	}
+	if u.Host == "" {
+		return fmt.Errorf("信号源地址缺少主机名")
+	}
+	return nil
+}
+
+// ValidateHeaders 校验附加的请求头（名称只能包含字母、数字和连字符，值不能包含换行）
+func ValidateHeaders(headers map[string]string) error {
+	if len(headers) > maxHeaders {
+		return fmt.Errorf("请求头最多 %d 个", maxHeaders)
+	}
+	for name, value := range headers {
+		if !reHeaderName.MatchString(name) {
+			return fmt.Errorf("请求头名称无效: %q", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("请求头 %s 的值不能包含换行", name)
+		}
+	}
+	return nil
+}
+
+// Fetch 获取信号源币种列表
+//
+// 缓存未过期时直接返回缓存；请求或解析失败时如果有过期缓存，返回过期缓存和错误（调用方可继续使用旧数据），
+// 没有缓存时只返回错误。
+func (c *Client) Fetch(ctx context.Context, src Source) (*Result, error) {
+	now := c.now()
+	key := src.cacheKey()
+	c.mu.Lock()
+	entry := c.cache[key]
+	c.mu.Unlock()
+	if entry != nil && now.Before(entry.expiresAt) {
+		return entry.result, nil
+	}
+
+	result, err := c.FetchNoCache(ctx, src)
+	if err != nil {
+		if entry != nil {
+			log.Printf("⚠️  信号源 %s 获取失败，使用 %s 前的缓存数据: %v", src.URL, now.Sub(entry.result.FetchedAt).Round(time.Second), err)
+			return entry.result, err
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.cache[key] = &cacheEntry{result: result, expiresAt: now.Add(c.ttl)}
+	c.mu.Unlock()
+	return result, nil
+}
+
+// FetchNoCache 直接请求信号源并解析（不读写缓存）
+func (c *Client) FetchNoCache(ctx context.Context, src Source) (*Result, error) {
+	if err := ValidateURL(src.URL); err != nil {
+		return nil, err
+	}
+	timeout := src.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for name, value := range src.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求信号源失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("信号源返回状态码 %d: %s", resp.StatusCode, truncate(string(body), 200))
+	}
+	if len(body) > maxBodySize {
+		return nil, fmt.Errorf("响应超过 %d 字节上限", maxBodySize)
+	}
+
+	result, err := Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	result.FetchedAt = c.now()
+	return result, nil
+}
+
+// truncate 截断过长的文本
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
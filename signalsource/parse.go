@@ -0,0 +1,235 @@
+// Package signalsource 获取用户配置的币种信号源（币种池 / OI Top）并解析为候选币种列表。
+//
+// 支持的响应格式：
+//
+//  1. 币种数组:            ["BTCUSDT", "ETH", "sol/usdt"]
+//  2. 带更新时间的对象:     {"symbols": ["BTCUSDT", "ETHUSDT"], "updated_at": "2025-01-01T00:00:00Z"}
+//     updated_at 可选，支持 RFC3339 字符串或 Unix 时间戳（秒/毫秒）
+//  3. AI500 币种池格式:    {"success": true, "data": {"coins": [{"pair": "BTCUSDT", ...}]}}
+//  4. OI Top 格式:         {"success": true, "data": {"positions": [{"symbol": "BTCUSDT", ...}]}}
+//
+// 币种统一转为大写 USDT 交易对（"eth"、"ETH/USDT"、"ETH-USDT" 都转为 ETHUSDT），重复项只保留一个。
+package signalsource
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 响应格式名称
+const (
+	FormatArray   = "array"   // 币种数组
+	FormatSymbols = "symbols" // {"symbols": [], "updated_at": ...}
+	FormatCoins   = "ai500"   // AI500 币种池
+	FormatOITop   = "oi_top"  // OI Top 持仓量排行
+)
+
+const (
+	maxSymbols   = 500     // 单个信号源最多返回的币种数
+	maxBodySize  = 1 << 20 // 响应体大小上限（1MB）
+	symbolSuffix = "USDT"  // 币种统一使用的计价货币
+	formatHint   = "支持的格式: [\"BTCUSDT\", ...] 或 {\"symbols\": [...], \"updated_at\": ...}"
+)
+
+// reSymbol 标准化后的交易对格式
+var reSymbol = regexp.MustCompile(`^[A-Z0-9]{1,20}USDT$`)
+
+// Result 信号源解析结果
+type Result struct {
+	Symbols   []string  `json:"symbols"`              // 标准化后的币种（保持信号源中的顺序）
+	Format    string    `json:"format"`               // 识别出的响应格式
+	UpdatedAt time.Time `json:"updated_at,omitempty"` // 信号源声明的更新时间（未提供时为零值）
+	FetchedAt time.Time `json:"fetched_at"`           // 获取时间
+}
+
+// ParseError 响应格式错误（包含出错的位置，便于用户修正信号源）
+type ParseError struct {
+	Path    string // 出错的字段路径（如 symbols[3]）
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Parse 解析信号源响应体
+func Parse(body []byte) (*Result, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, &ParseError{Message: "响应为空，" + formatHint}
+	}
+
+	var raw interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, &ParseError{Message: describeJSONError(body, err)}
+	}
+
+	switch v := raw.(type) {
+	case []interface{}:
+		symbols, err := normalizeList(v, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Symbols: symbols, Format: FormatArray}, nil
+	case map[string]interface{}:
+		return parseObject(v)
+	default:
+		return nil, &ParseError{Message: "响应必须是JSON数组或对象，" + formatHint}
+	}
+}
+
+// parseObject 解析对象格式的响应
+func parseObject(obj map[string]interface{}) (*Result, error) {
+	if list, ok := obj["symbols"]; ok {
+		items, ok := list.([]interface{})
+		if !ok {
+			return nil, &ParseError{Path: "symbols", Message: "必须是数组"}
+		}
+		symbols, err := normalizeList(items, "symbols", nil)
+		if err != nil {
+			return nil, err
+		}
+		result := &Result{Symbols: symbols, Format: FormatSymbols}
+		if updatedAt, ok := obj["updated_at"]; ok && updatedAt != nil {
+			t, err := parseTimestamp(updatedAt)
+			if err != nil {
+				return nil, &ParseError{Path: "updated_at", Message: err.Error()}
+			}
+			result.UpdatedAt = t
+		}
+		return result, nil
+	}
+
+	data, ok := obj["data"].(map[string]interface{})
+	if !ok {
+		return nil, &ParseError{Message: "对象中缺少 symbols 数组，" + formatHint}
+	}
+	if success, ok := obj["success"].(bool); ok && !success {
+		return nil, &ParseError{Path: "success", Message: "信号源返回失败状态"}
+	}
+	if coins, ok := data["coins"].([]interface{}); ok {
+		symbols, err := normalizeList(coins, "data.coins", objectField("pair"))
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Symbols: symbols, Format: FormatCoins}, nil
+	}
+	if positions, ok := data["positions"].([]interface{}); ok {
+		symbols, err := normalizeList(positions, "data.positions", objectField("symbol"))
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Symbols: symbols, Format: FormatOITop}, nil
+	}
+	return nil, &ParseError{Path: "data", Message: "缺少 coins 或 positions 数组，" + formatHint}
+}
+
+// objectField 从数组元素对象中取出币种字段
+func objectField(field string) func(item interface{}) (string, bool) {
+	return func(item interface{}) (string, bool) {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			return "", false
+		}
+		symbol, ok := obj[field].(string)
+		return symbol, ok
+	}
+}
+
+// normalizeList 标准化币种数组，extract 为空时数组元素必须是字符串
+func normalizeList(items []interface{}, path string, extract func(item interface{}) (string, bool)) ([]string, error) {
+	if len(items) == 0 {
+		return nil, &ParseError{Path: path, Message: "币种列表为空"}
+	}
+	if len(items) > maxSymbols {
+		return nil, &ParseError{Path: path, Message: fmt.Sprintf("币种数量 %d 超过上限 %d", len(items), maxSymbols)}
+	}
+
+	seen := make(map[string]bool, len(items))
+	symbols := make([]string, 0, len(items))
+	for i, item := range items {
+		itemPath := fmt.Sprintf("%s[%d]", path, i)
+		var raw string
+		var ok bool
+		if extract != nil {
+			raw, ok = extract(item)
+		} else {
+			raw, ok = item.(string)
+		}
+		if !ok {
+			if extract != nil {
+				return nil, &ParseError{Path: itemPath, Message: "缺少币种字段"}
+			}
+			return nil, &ParseError{Path: itemPath, Message: fmt.Sprintf("必须是字符串，实际为 %v", item)}
+		}
+		symbol, err := NormalizeSymbol(raw)
+		if err != nil {
+			return nil, &ParseError{Path: itemPath, Message: err.Error()}
+		}
+		if !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	return symbols, nil
+}
+
+// NormalizeSymbol 将币种转为大写 USDT 交易对（ETH、eth/usdt、ETH-USDT -> ETHUSDT），格式无效时返回错误
+func NormalizeSymbol(raw string) (string, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(raw))
+	symbol = strings.NewReplacer("/", "", "-", "", "_", "", ":", "").Replace(symbol)
+	if symbol == "" {
+		return "", errors.New("币种为空")
+	}
+	if !strings.HasSuffix(symbol, symbolSuffix) {
+		symbol += symbolSuffix
+	}
+	if !reSymbol.MatchString(symbol) {
+		return "", fmt.Errorf("无效的币种 %q（只能包含字母和数字）", raw)
+	}
+	return symbol, nil
+}
+
+// parseTimestamp 解析 RFC3339 字符串或 Unix 时间戳（秒/毫秒）
+func parseTimestamp(v interface{}) (time.Time, error) {
+	switch t := v.(type) {
+	case string:
+		parsed, err := time.Parse(time.RFC3339, t)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("时间格式无效 %q（应为RFC3339或Unix时间戳）", t)
+		}
+		return parsed, nil
+	case json.Number:
+		n, err := t.Int64()
+		if err != nil || n <= 0 {
+			return time.Time{}, fmt.Errorf("时间戳无效 %s", t)
+		}
+		if n > 1e12 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	default:
+		return time.Time{}, fmt.Errorf("时间格式无效（应为RFC3339或Unix时间戳）")
+	}
+}
+
+// describeJSONError 描述JSON语法错误的位置
+func describeJSONError(body []byte, err error) string {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset := int(syntaxErr.Offset)
+		line := bytes.Count(body[:min(offset, len(body))], []byte("\n")) + 1
+		return fmt.Sprintf("不是有效的JSON（第%d行，偏移%d）: %v", line, offset, syntaxErr)
+	}
+	return fmt.Sprintf("不是有效的JSON: %v", err)
+}
@@ -0,0 +1,131 @@
+package signalsource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParse_Formats 测试支持的响应格式和币种标准化
+func TestParse_Formats(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		format  string
+		symbols []string
+	}{
+		{"币种数组", `["btc", "ETH/USDT", "sol-usdt", "BTCUSDT"]`, FormatArray, []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"}},
+		{"symbols对象", `{"symbols": ["DOGE", "1000PEPEUSDT"], "updated_at": "2026-10-16T08:00:00Z"}`, FormatSymbols, []string{"DOGEUSDT", "1000PEPEUSDT"}},
+		{"AI500格式", `{"success": true, "data": {"coins": [{"pair": "BTCUSDT", "score": 80}, {"pair": "ETHUSDT"}]}}`, FormatCoins, []string{"BTCUSDT", "ETHUSDT"}},
+		{"OI Top格式", `{"success": true, "data": {"positions": [{"symbol": "XRPUSDT", "rank": 1}]}}`, FormatOITop, []string{"XRPUSDT"}},
+	}
+	for _, tc := range cases {
+		result, err := Parse([]byte(tc.body))
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.format, result.Format, tc.name)
+		assert.Equal(t, tc.symbols, result.Symbols, tc.name)
+	}
+
+	result, err := Parse([]byte(`{"symbols": ["BTC"], "updated_at": 1792137600000}`))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1792137600), result.UpdatedAt.Unix())
+}
+
+// TestParse_Errors 测试解析错误包含出错位置
+func TestParse_Errors(t *testing.T) {
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"空响应":       {``, "响应为空"},
+		"无效JSON":    {`["BTC",`, "不是有效的JSON"},
+		"非字符串元素":    {`["BTC", 42]`, "[1]: 必须是字符串"},
+		"无效币种":      {`{"symbols": ["BTC", "ETH$"]}`, "symbols[1]: 无效的币种"},
+		"symbols类型": {`{"symbols": "BTC"}`, "symbols: 必须是数组"},
+		"空列表":       {`{"symbols": []}`, "symbols: 币种列表为空"},
+		"时间格式":      {`{"symbols": ["BTC"], "updated_at": "yesterday"}`, "updated_at: 时间格式无效"},
+		"缺少字段":      {`{"success": true, "data": {"coins": [{"score": 1}]}}`, "data.coins[0]: 缺少币种字段"},
+		"失败状态":      {`{"success": false, "data": {"coins": []}}`, "success: 信号源返回失败状态"},
+		"未知对象":      {`{"coins": ["BTC"]}`, "缺少 symbols 数组"},
+		"标量":        {`"BTC"`, "必须是JSON数组或对象"},
+	}
+	for name, tc := range cases {
+		_, err := Parse([]byte(tc.body))
+		require.Error(t, err, name)
+		assert.Contains(t, err.Error(), tc.want, name)
+	}
+}
+
+// TestClient_CacheAndStale 测试缓存有效期内不重复请求，上游失败时返回过期缓存
+func TestClient_CacheAndStale(t *testing.T) {
+	var requests, failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, "upstream down", http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`["BTC", "ETH"]`))
+	}))
+	defer server.Close()
+
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	client := NewClient(time.Minute)
+	client.now = func() time.Time { return now }
+	src := Source{URL: server.URL}
+
+	result, err := client.Fetch(context.Background(), src)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BTCUSDT", "ETHUSDT"}, result.Symbols)
+	_, err = client.Fetch(context.Background(), src)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests), "缓存有效期内不重复请求")
+
+	// 缓存过期且上游失败：返回旧数据和错误
+	atomic.StoreInt32(&failing, 1)
+	now = now.Add(2 * time.Minute)
+	result, err = client.Fetch(context.Background(), src)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "502")
+	require.NotNil(t, result)
+	assert.Equal(t, []string{"BTCUSDT", "ETHUSDT"}, result.Symbols)
+
+	// 没有缓存时只返回错误
+	result, err = client.Fetch(context.Background(), Source{URL: server.URL + "/other"})
+	assert.Error(t, err)
+	assert.Nil(t, result)
+
+	_, err = client.Fetch(context.Background(), Source{URL: "ftp://example.com/list"})
+	assert.Error(t, err)
+}
+
+// TestClient_Headers 测试请求头随请求发送，不同凭证的请求不共用缓存
+func TestClient_Headers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer ok" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`["BTC"]`))
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Minute)
+	result, err := client.Fetch(context.Background(), Source{URL: server.URL, Headers: map[string]string{"Authorization": "Bearer ok"}, Timeout: time.Second})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"BTCUSDT"}, result.Symbols)
+
+	_, err = client.Fetch(context.Background(), Source{URL: server.URL})
+	require.Error(t, err, "未认证的请求不应命中带凭证请求的缓存")
+	assert.Contains(t, err.Error(), "401")
+
+	assert.Error(t, ValidateHeaders(map[string]string{"X-Key": "a\nb"}))
+	assert.Error(t, ValidateHeaders(map[string]string{"X Key": "v"}))
+	assert.NoError(t, ValidateHeaders(map[string]string{"X-Api-Key": "v"}))
+}
@@ -0,0 +1,179 @@
+package trader
+
+import "math"
+
+// Balance 账户余额（统一各交易所的字段含义）
+type Balance struct {
+	WalletBalance    float64 `json:"wallet_balance"`         // 钱包余额（不含未实现盈亏）
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`         // 未实现盈亏
+	AvailableBalance float64 `json:"available_balance"`      // 可用于开仓的余额
+	SpotBalance      float64 `json:"spot_balance,omitempty"` // 现货余额（仅 Hyperliquid，已计入钱包余额）
+}
+
+// Equity 账户净值 = 钱包余额 + 未实现盈亏
+func (b *Balance) Equity() float64 {
+	return b.WalletBalance + b.UnrealizedPnL
+}
+
+// ToMap 转换为旧版 GetBalance 返回的 map（过渡期兼容 Trader 接口）
+func (b *Balance) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"totalWalletBalance":    b.WalletBalance,
+		"availableBalance":      b.AvailableBalance,
+		"totalUnrealizedProfit": b.UnrealizedPnL,
+	}
+	if b.SpotBalance != 0 {
+		result["spotBalance"] = b.SpotBalance
+	}
+	return result
+}
+
+// BalanceFromMap 从旧版 GetBalance 的 map 结果解析余额（兼容尚未迁移的交易器的字段名）
+func BalanceFromMap(m map[string]interface{}) *Balance {
+	return &Balance{
+		WalletBalance:    mapFloat(m, "totalWalletBalance", "wallet_balance", "balance"),
+		UnrealizedPnL:    mapFloat(m, "totalUnrealizedProfit", "unrealized_profit"),
+		AvailableBalance: mapFloat(m, "availableBalance", "available_balance"),
+		SpotBalance:      mapFloat(m, "spotBalance"),
+	}
+}
+
+// Position 持仓（数量为正数，方向由 Side 表示）
+type Position struct {
+	Symbol           string  `json:"symbol"`
+	Side             string  `json:"side"` // long/short
+	Quantity         float64 `json:"quantity"`
+	EntryPrice       float64 `json:"entry_price"`
+	MarkPrice        float64 `json:"mark_price"`
+	UnrealizedPnL    float64 `json:"unrealized_pnl"`
+	Leverage         float64 `json:"leverage"`
+	LiquidationPrice float64 `json:"liquidation_price"`
+}
+
+// Notional 持仓名义价值（数量 × 标记价格）
+func (p *Position) Notional() float64 {
+	return p.Quantity * p.MarkPrice
+}
+
+// ToMap 转换为旧版 GetPositions 返回的 map（positionAmt 为正数）
+func (p *Position) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"symbol":           p.Symbol,
+		"side":             p.Side,
+		"positionAmt":      p.Quantity,
+		"entryPrice":       p.EntryPrice,
+		"markPrice":        p.MarkPrice,
+		"unRealizedProfit": p.UnrealizedPnL,
+		"leverage":         p.Leverage,
+		"liquidationPrice": p.LiquidationPrice,
+	}
+}
+
+// PositionFromMap 从旧版 GetPositions 的 map 结果解析持仓（positionAmt 可能为负数）
+func PositionFromMap(m map[string]interface{}) Position {
+	symbol, _ := m["symbol"].(string)
+	side, _ := m["side"].(string)
+	return Position{
+		Symbol:           symbol,
+		Side:             side,
+		Quantity:         math.Abs(mapFloat(m, "positionAmt")),
+		EntryPrice:       mapFloat(m, "entryPrice"),
+		MarkPrice:        mapFloat(m, "markPrice"),
+		UnrealizedPnL:    mapFloat(m, "unRealizedProfit"),
+		Leverage:         mapFloat(m, "leverage"),
+		LiquidationPrice: mapFloat(m, "liquidationPrice"),
+	}
+}
+
+// positionsToMaps 批量转换为旧版 map 结果
+func positionsToMaps(positions []Position) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(positions))
+	for i := range positions {
+		result = append(result, positions[i].ToMap())
+	}
+	return result
+}
+
+// mapFloat 按顺序读取第一个存在的数值字段（兼容 float64/int 两种类型）
+func mapFloat(m map[string]interface{}, keys ...string) float64 {
+	for _, key := range keys {
+		switch v := m[key].(type) {
+		case float64:
+			return v
+		case int:
+			return float64(v)
+		}
+	}
+	return 0
+}
+
+// AccountInfo 账户汇总信息（/api/account 的响应）
+type AccountInfo struct {
+	TotalEquity      float64 `json:"total_equity"`      // 账户净值 = 钱包余额 + 未实现盈亏
+	WalletBalance    float64 `json:"wallet_balance"`    // 钱包余额（不含未实现盈亏）
+	UnrealizedProfit float64 `json:"unrealized_profit"` // 未实现盈亏（交易所API官方值）
+	AvailableBalance float64 `json:"available_balance"` // 可用余额
+	TotalPnL         float64 `json:"total_pnl"`         // 总盈亏 = 净值 - 初始余额
+	TotalPnLPct      float64 `json:"total_pnl_pct"`     // 总盈亏百分比
+	InitialBalance   float64 `json:"initial_balance"`   // 初始余额
+	DailyPnL         float64 `json:"daily_pnl"`         // 日盈亏
+	PositionCount    int     `json:"position_count"`    // 持仓数量
+	MarginUsed       float64 `json:"margin_used"`       // 保证金占用
+	MarginUsedPct    float64 `json:"margin_used_pct"`   // 保证金使用率
+	FundingFees      float64 `json:"funding_fees"`      // 近7天资金费合计（正数为收到，负数为支付）
+}
+
+// ToMap 转换为旧版 GetAccountInfo 返回的 map
+func (a *AccountInfo) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"total_equity":      a.TotalEquity,
+		"wallet_balance":    a.WalletBalance,
+		"unrealized_profit": a.UnrealizedProfit,
+		"available_balance": a.AvailableBalance,
+		"total_pnl":         a.TotalPnL,
+		"total_pnl_pct":     a.TotalPnLPct,
+		"initial_balance":   a.InitialBalance,
+		"daily_pnl":         a.DailyPnL,
+		"position_count":    a.PositionCount,
+		"margin_used":       a.MarginUsed,
+		"margin_used_pct":   a.MarginUsedPct,
+		"funding_fees":      a.FundingFees,
+	}
+}
+
+// TypedAccountTrader 返回强类型余额和持仓的交易器（币安、Hyperliquid、Aster 已迁移）
+// 未实现此接口的交易器通过 FetchBalance/FetchPositions 从旧版 map 结果转换
+type TypedAccountTrader interface {
+	// GetBalanceInfo 获取账户余额
+	GetBalanceInfo() (*Balance, error)
+	// GetPositionList 获取所有持仓
+	GetPositionList() ([]Position, error)
+}
+
+// FetchBalance 获取强类型账户余额
+func FetchBalance(t Trader) (*Balance, error) {
+	if typed, ok := t.(TypedAccountTrader); ok {
+		return typed.GetBalanceInfo()
+	}
+	balance, err := t.GetBalance()
+	if err != nil {
+		return nil, err
+	}
+	return BalanceFromMap(balance), nil
+}
+
+// FetchPositions 获取强类型持仓列表
+func FetchPositions(t Trader) ([]Position, error) {
+	if typed, ok := t.(TypedAccountTrader); ok {
+		return typed.GetPositionList()
+	}
+	positions, err := t.GetPositions()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Position, 0, len(positions))
+	for _, pos := range positions {
+		result = append(result, PositionFromMap(pos))
+	}
+	return result, nil
+}
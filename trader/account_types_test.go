@@ -0,0 +1,54 @@
+package trader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	_ TypedAccountTrader = (*AsterTrader)(nil)
+	_ TypedAccountTrader = (*HyperliquidTrader)(nil)
+)
+
+// TestBalanceFromMap 测试兼容不同交易器的余额字段名
+func TestBalanceFromMap(t *testing.T) {
+	b := BalanceFromMap(map[string]interface{}{
+		"totalWalletBalance":    1000.0,
+		"totalUnrealizedProfit": -20.0,
+		"availableBalance":      700.0,
+	})
+	assert.Equal(t, 980.0, b.Equity())
+	assert.Equal(t, 700.0, b.AvailableBalance)
+	assert.Equal(t, b.ToMap(), map[string]interface{}{
+		"totalWalletBalance":    1000.0,
+		"totalUnrealizedProfit": -20.0,
+		"availableBalance":      700.0,
+	}, "往返转换不丢字段")
+
+	b = BalanceFromMap(map[string]interface{}{"balance": 500.0, "unrealized_profit": 5.0, "available_balance": 300})
+	assert.Equal(t, 505.0, b.Equity(), "兼容旧字段名")
+	assert.Equal(t, 300.0, b.AvailableBalance, "兼容整数类型")
+
+	assert.Zero(t, BalanceFromMap(map[string]interface{}{"totalWalletBalance": "1000"}).WalletBalance, "非数值字段忽略")
+}
+
+// TestFetchPositionsFromMap 测试未迁移的交易器通过 map 转换为强类型持仓
+func TestFetchPositionsFromMap(t *testing.T) {
+	mock := &MockTrader{positions: []map[string]interface{}{
+		{"symbol": "ETHUSDT", "side": "short", "positionAmt": -2.0, "markPrice": 3000.0, "unRealizedProfit": 15.0, "leverage": 5.0},
+	}}
+
+	positions, err := FetchPositions(mock)
+	assert.NoError(t, err)
+	if assert.Len(t, positions, 1) {
+		assert.Equal(t, "ETHUSDT", positions[0].Symbol)
+		assert.Equal(t, 2.0, positions[0].Quantity, "数量统一为正数")
+		assert.Equal(t, 6000.0, positions[0].Notional())
+		assert.Equal(t, 2.0, positions[0].ToMap()["positionAmt"])
+	}
+
+	balance, err := FetchBalance(mock)
+	assert.NoError(t, err)
+	assert.Equal(t, 10100.0, balance.Equity())
+}
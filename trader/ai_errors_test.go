@@ -0,0 +1,43 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/mcp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestHandleAIError 测试限流时提前重试、认证失败时停止，其他错误按原间隔继续
+func TestHandleAIError(t *testing.T) {
+	at := &AutoTrader{name: "test", aiModel: "deepseek", config: AutoTraderConfig{ScanInterval: 3 * time.Minute}}
+	aiErr := func(err error) error { return fmt.Errorf("%w: %w", decision.ErrAICall, err) }
+
+	// 限流：遵循 Retry-After，未提供时使用默认等待，且不超过扫描间隔的一半
+	retryAfter, fatal := at.handleAIError(aiErr(&mcp.RateLimitError{APIError: &mcp.APIError{StatusCode: 429}, ParsedRetryAfter: 20 * time.Second}))
+	assert.NoError(t, fatal)
+	assert.Equal(t, 20*time.Second, retryAfter)
+
+	retryAfter, _ = at.handleAIError(aiErr(&mcp.APIError{StatusCode: 429, Message: "Rate limit reached"}))
+	assert.Equal(t, aiRateLimitPause, retryAfter)
+
+	retryAfter, _ = at.handleAIError(aiErr(&mcp.RateLimitError{APIError: &mcp.APIError{StatusCode: 429}, ParsedRetryAfter: 10 * time.Minute}))
+	assert.Equal(t, 90*time.Second, retryAfter)
+
+	// 认证失败：返回 ErrAIAuthFailed，交易员停止
+	retryAfter, fatal = at.handleAIError(aiErr(&mcp.AuthenticationError{Provider: "deepseek", APIError: &mcp.APIError{StatusCode: 401, Message: "Authentication Fails"}}))
+	assert.Zero(t, retryAfter)
+	assert.ErrorIs(t, fatal, ErrAIAuthFailed)
+
+	// 其他错误（服务端错误、非AI接口错误）按原间隔继续
+	retryAfter, fatal = at.handleAIError(aiErr(&mcp.APIError{StatusCode: 500}))
+	assert.Zero(t, retryAfter)
+	assert.NoError(t, fatal)
+	retryAfter, fatal = at.handleAIError(errors.New("获取市场数据失败: 401 Unauthorized"))
+	assert.Zero(t, retryAfter)
+	assert.NoError(t, fatal)
+}
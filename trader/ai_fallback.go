@@ -0,0 +1,220 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"sync"
+	"time"
+)
+
+// getFullDecision 获取AI决策（测试中替换）
+var getFullDecision = decision.GetFullDecisionWithCustomPrompt
+
+// fallbackErrorClasses 主模型出现这些错误时本周期改用备用模型
+// 限流在客户端内已按退避重试过，到这里仍失败说明是持续限流
+var fallbackErrorClasses = map[string]bool{
+	mcp.ErrorClassInsufficientBalance: true,
+	mcp.ErrorClassAuth:                true,
+	mcp.ErrorClassRateLimit:           true,
+	mcp.ErrorClassTimeout:             true,
+	mcp.ErrorClassServerError:         true,
+	mcp.ErrorClassNetwork:             true,
+}
+
+// aiFallbackState 备用AI模型状态（主模型不可用时按周期切换）
+type aiFallbackState struct {
+	mu            sync.RWMutex
+	modelID       string       // 备用模型ID（为空表示未配置）
+	stickyMinutes int          // 主模型失败后持续使用备用模型的分钟数（0=每周期都先尝试主模型）
+	client        mcp.AIClient // 备用模型客户端（首次使用时创建）
+	stickyUntil   time.Time    // 粘滞截止时间（之前直接使用备用模型）
+	lastReason    string       // 最近一次切换到备用模型的原因
+}
+
+// aiCandidate 本周期可用于决策的模型
+type aiCandidate struct {
+	client   mcp.AIClient
+	model    string
+	fallback bool
+}
+
+// SetAIFallback 设置备用AI模型和粘滞时长（清除已创建的客户端和粘滞状态）
+func (at *AutoTrader) SetAIFallback(modelID string, stickyMinutes int) {
+	at.fallback.mu.Lock()
+	defer at.fallback.mu.Unlock()
+	at.fallback.modelID = modelID
+	at.fallback.stickyMinutes = stickyMinutes
+	at.fallback.client = nil
+	at.fallback.stickyUntil = time.Time{}
+	at.fallback.lastReason = ""
+}
+
+// getFallbackCandidate 获取备用模型（未配置、不存在或未启用时返回 nil）
+func (at *AutoTrader) getFallbackCandidate() *aiCandidate {
+	at.fallback.mu.Lock()
+	defer at.fallback.mu.Unlock()
+
+	if at.fallback.modelID == "" {
+		return nil
+	}
+	if at.fallback.client == nil {
+		store := at.getSpendStore()
+		if store == nil {
+			return nil
+		}
+		models, err := store.GetAIModels(at.userID)
+		if err != nil {
+			log.Printf("⚠️ [%s] 获取备用模型失败: %v", at.name, err)
+			return nil
+		}
+		for _, m := range models {
+			if m.ID != at.fallback.modelID {
+				continue
+			}
+			if !m.Enabled {
+				log.Printf("⚠️ [%s] 备用模型 %s 未启用", at.name, m.ID)
+				return nil
+			}
+			at.fallback.client = newAIClientForModel(m)
+			limitAIRetryBudget(at.fallback.client, at.getScanInterval())
+			break
+		}
+		if at.fallback.client == nil {
+			log.Printf("⚠️ [%s] 备用模型 %s 不存在", at.name, at.fallback.modelID)
+			return nil
+		}
+	}
+	return &aiCandidate{client: at.fallback.client, model: at.fallback.modelID, fallback: true}
+}
+
+// isFallbackSticky 是否处于粘滞期（直接使用备用模型，不重试故障的主模型）
+func (at *AutoTrader) isFallbackSticky(now time.Time) bool {
+	at.fallback.mu.RLock()
+	defer at.fallback.mu.RUnlock()
+	return now.Before(at.fallback.stickyUntil)
+}
+
+// markFallbackUsed 主模型失败、备用模型成功后记录原因，并按配置进入粘滞期
+func (at *AutoTrader) markFallbackUsed(now time.Time, reason string) time.Time {
+	at.fallback.mu.Lock()
+	defer at.fallback.mu.Unlock()
+	at.fallback.lastReason = reason
+	if at.fallback.stickyMinutes > 0 {
+		at.fallback.stickyUntil = now.Add(time.Duration(at.fallback.stickyMinutes) * time.Minute)
+	}
+	return at.fallback.stickyUntil
+}
+
+// clearFallbackSticky 主模型恢复后结束粘滞期
+func (at *AutoTrader) clearFallbackSticky() {
+	at.fallback.mu.Lock()
+	defer at.fallback.mu.Unlock()
+	at.fallback.stickyUntil = time.Time{}
+}
+
+// fallbackErrorClass 返回可触发备用模型的错误分类（不可触发时返回空字符串）
+func fallbackErrorClass(err error) string {
+	class := mcp.ClassifyError(err)
+	if fallbackErrorClasses[class] {
+		return class
+	}
+	return ""
+}
+
+// requestDecision 请求AI决策：优先使用主模型（粘滞期内优先备用模型），出现服务商故障时本周期改用另一个模型，
+// 并在决策记录中写明实际产生决策的模型。返回实际使用的客户端（用于统计用量和重试次数）。
+// 启用多模型共识时改为并发请求各成员模型，用量已按模型记录，返回的客户端为 nil
+func (at *AutoTrader) requestDecision(ctx *decision.Context, record *logger.DecisionRecord) (*decision.FullDecision, mcp.AIClient, error) {
+	if members, rule := at.getEnsembleMembers(); members != nil {
+		fullDecision, err := at.requestEnsembleDecision(ctx, record, members, rule)
+		return fullDecision, nil, err
+	}
+
+	now := time.Now()
+	primary := &aiCandidate{client: at.mcpClient, model: at.aiModel}
+	order := []*aiCandidate{primary}
+	if fb := at.getFallbackCandidate(); fb != nil {
+		if at.isFallbackSticky(now) {
+			order = []*aiCandidate{fb, primary}
+		} else {
+			order = append(order, fb)
+		}
+	}
+
+	first := order[0]
+	fullDecision, err := getFullDecision(ctx, first.client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	record.AIModel = first.model
+	if first.fallback && err == nil {
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔀 备用模型粘滞期内，使用备用模型 %s", first.model))
+	}
+	if err == nil || len(order) == 1 {
+		return fullDecision, first.client, err
+	}
+	class := fallbackErrorClass(err)
+	if class == "" {
+		return fullDecision, first.client, err
+	}
+
+	second := order[1]
+	log.Printf("🔀 [%s] 模型 %s 调用失败 (%s)，本周期改用 %s: %v", at.name, first.model, class, second.model, err)
+	secondDecision, secondErr := getFullDecision(ctx, second.client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	if secondErr != nil {
+		log.Printf("❌ [%s] 模型 %s 也调用失败: %v", at.name, second.model, secondErr)
+		return fullDecision, first.client, fmt.Errorf("%w（%s 也调用失败: %v）", err, second.model, secondErr)
+	}
+
+	// 另一个模型成功：记录主模型失败，并写明实际产生决策的模型
+	at.recordAIError(err)
+	record.AIModel = second.model
+	event := &logger.AIFallbackEvent{
+		FailedModel: first.model,
+		UsedModel:   second.model,
+		ErrorClass:  class,
+		Error:       err.Error(),
+	}
+	if second.fallback {
+		if until := at.markFallbackUsed(now, fmt.Sprintf("%s: %s", first.model, class)); until.After(now) {
+			event.StickyUntil = until.Format(time.RFC3339)
+		}
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔀 主模型 %s 调用失败 (%s)，本周期使用备用模型 %s", first.model, class, second.model))
+	} else {
+		at.clearFallbackSticky()
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔀 备用模型 %s 调用失败 (%s)，主模型 %s 已恢复", first.model, class, second.model))
+	}
+	record.AIFallback = event
+	return secondDecision, second.client, nil
+}
+
+// aiFallbackStatus 备用模型状态（未配置时返回 nil）
+func (at *AutoTrader) aiFallbackStatus(now time.Time) map[string]interface{} {
+	at.fallback.mu.RLock()
+	defer at.fallback.mu.RUnlock()
+	if at.fallback.modelID == "" {
+		return nil
+	}
+	status := map[string]interface{}{
+		"model":          at.fallback.modelID,
+		"sticky_minutes": at.fallback.stickyMinutes,
+		"sticky":         now.Before(at.fallback.stickyUntil),
+	}
+	if now.Before(at.fallback.stickyUntil) {
+		status["sticky_until"] = at.fallback.stickyUntil.Format(time.RFC3339)
+	}
+	if at.fallback.lastReason != "" {
+		status["last_reason"] = at.fallback.lastReason
+	}
+	return status
+}
+
+// activeAIModel 当前优先使用的模型（粘滞期内为备用模型）
+func (at *AutoTrader) activeAIModel(now time.Time) string {
+	at.fallback.mu.RLock()
+	defer at.fallback.mu.RUnlock()
+	if at.fallback.modelID != "" && now.Before(at.fallback.stickyUntil) {
+		return at.fallback.modelID
+	}
+	return at.aiModel
+}
@@ -0,0 +1,134 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedAIClient 只用于区分模型的AI客户端（决策由替换后的 getFullDecision 返回）
+type namedAIClient struct{ name string }
+
+func (c *namedAIClient) SetAPIKey(apiKey string, customURL string, customModel string) {}
+func (c *namedAIClient) SetTimeout(timeout time.Duration)                              {}
+func (c *namedAIClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return "", nil
+}
+func (c *namedAIClient) CallWithRequest(req *mcp.Request) (string, error) { return "", nil }
+
+// stubDecisions 按客户端返回预设结果，并记录调用顺序（共识模式下并发调用）
+func stubDecisions(t *testing.T, errs map[string]error) *[]string {
+	calls := &[]string{}
+	var mu sync.Mutex
+	original := getFullDecision
+	getFullDecision = func(ctx *decision.Context, client mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*decision.FullDecision, error) {
+		name := client.(*namedAIClient).name
+		mu.Lock()
+		defer mu.Unlock()
+		*calls = append(*calls, name)
+		if err := errs[name]; err != nil {
+			return nil, fmt.Errorf("%w: %w", decision.ErrAICall, err)
+		}
+		return &decision.FullDecision{Decisions: []decision.Decision{{Symbol: "ALL", Action: "wait"}}}, nil
+	}
+	t.Cleanup(func() { getFullDecision = original })
+	return calls
+}
+
+func newFallbackTestTrader(stickyMinutes int) *AutoTrader {
+	return &AutoTrader{
+		name:      "fallback",
+		aiModel:   "deepseek",
+		mcpClient: &namedAIClient{name: "deepseek"},
+		fallback: aiFallbackState{
+			modelID:       "user_qwen",
+			stickyMinutes: stickyMinutes,
+			client:        &namedAIClient{name: "user_qwen"},
+		},
+	}
+}
+
+func TestRequestDecision_FallsBackOnProviderFailure(t *testing.T) {
+	balanceErr := &mcp.InsufficientBalanceError{Provider: "deepseek", APIError: &mcp.APIError{Code: 30001, Message: "Insufficient Balance"}}
+	calls := stubDecisions(t, map[string]error{"deepseek": balanceErr})
+	at := newFallbackTestTrader(0)
+
+	record := &logger.DecisionRecord{}
+	result, client, err := at.requestDecision(&decision.Context{}, record)
+	require.NoError(t, err)
+	require.NotNil(t, result)
+	assert.Equal(t, "user_qwen", client.(*namedAIClient).name)
+	assert.Equal(t, []string{"deepseek", "user_qwen"}, *calls)
+
+	// 决策记录写明实际产生决策的模型和切换原因
+	assert.Equal(t, "user_qwen", record.AIModel)
+	require.NotNil(t, record.AIFallback)
+	assert.Equal(t, "deepseek", record.AIFallback.FailedModel)
+	assert.Equal(t, mcp.ErrorClassInsufficientBalance, record.AIFallback.ErrorClass)
+	assert.Empty(t, record.AIFallback.StickyUntil)
+
+	// 非粘滞模式：下一周期仍先尝试主模型
+	*calls = nil
+	_, _, err = at.requestDecision(&decision.Context{}, &logger.DecisionRecord{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"deepseek", "user_qwen"}, *calls)
+}
+
+func TestRequestDecision_StickyFallback(t *testing.T) {
+	errs := map[string]error{"deepseek": &mcp.APIError{StatusCode: 503}}
+	calls := stubDecisions(t, errs)
+	at := newFallbackTestTrader(30)
+
+	record := &logger.DecisionRecord{}
+	_, _, err := at.requestDecision(&decision.Context{}, record)
+	require.NoError(t, err)
+	assert.NotEmpty(t, record.AIFallback.StickyUntil)
+	assert.Equal(t, "user_qwen", at.activeAIModel(time.Now()))
+	assert.Equal(t, true, at.aiFallbackStatus(time.Now())["sticky"])
+
+	// 粘滞期内直接使用备用模型，不重试故障的主模型
+	*calls = nil
+	record = &logger.DecisionRecord{}
+	_, _, err = at.requestDecision(&decision.Context{}, record)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user_qwen"}, *calls)
+	assert.Equal(t, "user_qwen", record.AIModel)
+	assert.Nil(t, record.AIFallback)
+
+	// 粘滞期内备用模型故障、主模型已恢复：切回主模型并结束粘滞
+	errs["deepseek"] = nil
+	errs["user_qwen"] = &mcp.RateLimitError{APIError: &mcp.APIError{StatusCode: 429}}
+	*calls = nil
+	record = &logger.DecisionRecord{}
+	_, _, err = at.requestDecision(&decision.Context{}, record)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user_qwen", "deepseek"}, *calls)
+	assert.Equal(t, "deepseek", record.AIModel)
+	assert.Equal(t, "deepseek", at.activeAIModel(time.Now()))
+}
+
+func TestRequestDecision_NoFallbackForOtherErrors(t *testing.T) {
+	// 请求参数错误（4xx）不是服务商故障，不切换模型
+	calls := stubDecisions(t, map[string]error{"deepseek": &mcp.APIError{StatusCode: 400}})
+	at := newFallbackTestTrader(0)
+	_, _, err := at.requestDecision(&decision.Context{}, &logger.DecisionRecord{})
+	assert.ErrorIs(t, err, decision.ErrAICall)
+	assert.Equal(t, []string{"deepseek"}, *calls)
+
+	// 两个模型都失败：返回主模型错误（保留认证失败等分类），附带备用模型的失败原因
+	authErr := &mcp.AuthenticationError{Provider: "deepseek", APIError: &mcp.APIError{StatusCode: 401}}
+	calls = stubDecisions(t, map[string]error{"deepseek": authErr, "user_qwen": errors.New("connection refused")})
+	_, _, err = at.requestDecision(&decision.Context{}, &logger.DecisionRecord{})
+	assert.True(t, mcp.IsAuthError(err))
+	assert.Contains(t, err.Error(), "user_qwen 也调用失败")
+	assert.Equal(t, []string{"deepseek", "user_qwen"}, *calls)
+}
@@ -0,0 +1,248 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/logger"
+	"nofx/mcp"
+	"time"
+)
+
+// spendStore AI花费存储接口（由 config.Database 实现）
+type spendStore interface {
+	RecordAISpend(record *config.AISpendRecord) error
+	GetUserSpendSettings(userID string) (*config.UserSpendSettings, error)
+	GetUserSpendTotal(userID string, start, end time.Time) (float64, error)
+	MarkSpendAlertSent(userID, month string, level int) error
+	GetAIModels(userID string) ([]*config.AIModelConfig, error)
+}
+
+// spendState 交易员的预算降级状态
+type spendState struct {
+	primaryClient mcp.AIClient // 降级前的主模型客户端（用于恢复）
+	primaryModel  string       // 降级前的模型名称
+	downgraded    bool         // 当前是否已切换到备用模型
+}
+
+// getSpendStore 获取花费存储（数据库未注入时返回nil）
+func (at *AutoTrader) getSpendStore() spendStore {
+	if at.database == nil {
+		return nil
+	}
+	store, ok := at.database.(spendStore)
+	if !ok {
+		return nil
+	}
+	return store
+}
+
+// recordAISpend 记录本周期AI调用的token用量和估算费用（client 为实际产生决策的模型客户端）
+func (at *AutoTrader) recordAISpend(record *logger.DecisionRecord, client mcp.AIClient) {
+	store := at.getSpendStore()
+	if store == nil || at.userID == "" {
+		return
+	}
+
+	reporter, ok := client.(mcp.UsageReporter)
+	if !ok {
+		return
+	}
+	usage := reporter.LastUsage()
+	if usage.TotalTokens == 0 {
+		return
+	}
+
+	cost := mcp.EstimateCost(usage)
+	err := store.RecordAISpend(&config.AISpendRecord{
+		UserID:           at.userID,
+		TraderID:         at.id,
+		Model:            usage.Model,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CostUSD:          cost,
+	})
+	if err != nil {
+		log.Printf("⚠️ [%s] %v", at.name, err)
+		return
+	}
+
+	record.ExecutionLog = append(record.ExecutionLog,
+		fmt.Sprintf("AI用量: %s 输入%d/输出%d tokens, 约 $%.4f",
+			usage.Model, usage.PromptTokens, usage.CompletionTokens, cost))
+}
+
+// enforceSpendBudget 检查用户月度AI预算：触发阈值告警，并在超出预算时切换/恢复备用模型
+func (at *AutoTrader) enforceSpendBudget(record *logger.DecisionRecord) {
+	store := at.getSpendStore()
+	if store == nil || at.userID == "" {
+		return
+	}
+
+	settings, err := store.GetUserSpendSettings(at.userID)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v", at.name, err)
+		return
+	}
+
+	if settings.MonthlyBudgetUSD <= 0 {
+		// 未设置预算：如果之前降级过，则恢复主模型
+		at.restorePrimaryModel(record, "未设置月度预算")
+		return
+	}
+
+	now := time.Now()
+	start, end := config.SpendMonthRange(now)
+	spent, err := store.GetUserSpendTotal(at.userID, start, end)
+	if err != nil {
+		log.Printf("⚠️ [%s] %v", at.name, err)
+		return
+	}
+	usedPct := spent / settings.MonthlyBudgetUSD * 100
+
+	// 1. 阈值告警（每个阈值每月只告警一次，跨交易员共享状态）
+	month := start.Format("2006-01")
+	alertedLevel := 0
+	if settings.AlertMonth == month {
+		alertedLevel = settings.AlertLevel
+	}
+	crossed := 0
+	for _, threshold := range settings.AlertThresholds {
+		if usedPct >= float64(threshold) {
+			crossed = threshold
+		}
+	}
+	if crossed > alertedLevel {
+		if err := store.MarkSpendAlertSent(at.userID, month, crossed); err != nil {
+			log.Printf("⚠️ [%s] %v", at.name, err)
+		} else {
+			notifySpendAlert(fmt.Sprintf("💸 AI花费告警 [用户 %s]: 本月已花费 $%.2f / 预算 $%.2f (%.1f%%)，已超过 %d%% 阈值",
+				at.userID, spent, settings.MonthlyBudgetUSD, usedPct, crossed))
+			at.publishEvent(EventSpendAlert, map[string]interface{}{
+				"spent":     spent,
+				"budget":    settings.MonthlyBudgetUSD,
+				"used_pct":  usedPct,
+				"threshold": crossed,
+			})
+		}
+	}
+
+	// 2. 超出预算：切换到备用模型（仅告警模式下不做处理）
+	if usedPct >= 100 {
+		if settings.DowngradeEnabled && settings.FallbackModelID != "" {
+			at.switchToFallbackModel(store, settings, record, spent, usedPct)
+		} else {
+			at.restorePrimaryModel(record, "已关闭超预算自动降级")
+		}
+		return
+	}
+
+	// 3. 预算恢复（新的月份或调高预算）：切回主模型
+	at.restorePrimaryModel(record, fmt.Sprintf("预算使用率已回落至 %.1f%%", usedPct))
+}
+
+// switchToFallbackModel 切换到用户配置的备用模型，并在决策记录中留痕
+func (at *AutoTrader) switchToFallbackModel(store spendStore, settings *config.UserSpendSettings, record *logger.DecisionRecord, spent, usedPct float64) {
+	if at.spend.downgraded {
+		return
+	}
+
+	models, err := store.GetAIModels(at.userID)
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取备用模型失败: %v", at.name, err)
+		return
+	}
+
+	var fallback *config.AIModelConfig
+	for _, m := range models {
+		if m.ID == settings.FallbackModelID {
+			fallback = m
+			break
+		}
+	}
+	if fallback == nil || !fallback.Enabled || fallback.APIKey == "" {
+		log.Printf("⚠️ [%s] 备用模型 %s 不存在、未启用或未配置API Key，无法降级", at.name, settings.FallbackModelID)
+		return
+	}
+
+	fromModel := at.aiModel
+	at.spend.primaryClient = at.mcpClient
+	at.spend.primaryModel = at.aiModel
+	at.spend.downgraded = true
+	at.mcpClient = newAIClientForModel(fallback)
+	limitAIRetryBudget(at.mcpClient, at.getScanInterval())
+	at.aiModel = fallback.Provider
+
+	reason := fmt.Sprintf("本月AI花费 $%.2f 已达预算 $%.2f 的 %.1f%%", spent, settings.MonthlyBudgetUSD, usedPct)
+	record.ModelSwitch = &logger.ModelSwitchEvent{
+		FromModel: fromModel,
+		ToModel:   fallback.ID,
+		Reason:    reason,
+	}
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔀 已切换到备用模型 %s: %s", fallback.ID, reason))
+	notifySpendAlert(fmt.Sprintf("🔀 [%s] %s，已从 %s 切换到备用模型 %s", at.name, reason, fromModel, fallback.ID))
+}
+
+// restorePrimaryModel 预算恢复后切回主模型
+func (at *AutoTrader) restorePrimaryModel(record *logger.DecisionRecord, reason string) {
+	if !at.spend.downgraded {
+		return
+	}
+
+	fromModel := at.aiModel
+	at.mcpClient = at.spend.primaryClient
+	at.aiModel = at.spend.primaryModel
+	at.spend = spendState{}
+
+	record.ModelSwitch = &logger.ModelSwitchEvent{
+		FromModel: fromModel,
+		ToModel:   at.aiModel,
+		Reason:    reason,
+	}
+	record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔀 已恢复主模型 %s: %s", at.aiModel, reason))
+	log.Printf("🔀 [%s] %s，已恢复主模型 %s", at.name, reason, at.aiModel)
+}
+
+// newAIClientForProvider 根据AI模型配置创建客户端
+func newAIClientForProvider(provider, apiKey, customURL, customModel string) mcp.AIClient {
+	var client mcp.AIClient
+	switch provider {
+	case "custom":
+		client = mcp.New()
+	case mcp.ProviderLocal, mcp.ProviderOllama:
+		client = mcp.NewLocalClient()
+	case "qwen":
+		client = mcp.NewQwenClient()
+	default:
+		client = mcp.NewDeepSeekClient()
+	}
+	client.SetAPIKey(apiKey, customURL, customModel)
+	return client
+}
+
+// newAIClientForModel 根据已保存的AI模型配置创建客户端（含请求参数）
+func newAIClientForModel(m *config.AIModelConfig) mcp.AIClient {
+	client := newAIClientForProvider(m.Provider, m.APIKey, m.CustomAPIURL, m.CustomModelName)
+	applyAIModelParams(client, m.AIModelParams)
+	return client
+}
+
+// applyAIModelParams 应用模型配置中的请求参数（未设置的参数保持客户端默认值）
+func applyAIModelParams(client mcp.AIClient, params config.AIModelParams) {
+	if params.RequestTimeoutSeconds > 0 {
+		client.SetTimeout(time.Duration(params.RequestTimeoutSeconds) * time.Second)
+	}
+	setter, ok := client.(interface{ SetGenerationParams(*float64, int) })
+	if !ok {
+		return
+	}
+	setter.SetGenerationParams(params.Temperature, params.MaxTokens)
+}
+
+// notifySpendAlert 发送花费告警（标准日志 + logrus，已启用Telegram时会被推送）
+func notifySpendAlert(message string) {
+	log.Println(message)
+	if logger.Log != nil {
+		logger.Log.Warn(message)
+	}
+}
@@ -0,0 +1,97 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/adshao/go-binance/v2"
+)
+
+// TradePermission API密钥的合约交易权限
+type TradePermission string
+
+const (
+	TradePermissionEnabled  TradePermission = "enabled"  // 已开启合约交易
+	TradePermissionDisabled TradePermission = "disabled" // 只读或未开启合约交易
+	TradePermissionUnknown  TradePermission = "unknown"  // 交易所不提供权限查询或查询失败
+)
+
+// binanceSpotBaseURL 币安现货接口地址（API权限查询在 /sapi 下，测试中可替换）
+var binanceSpotBaseURL = binance.BaseAPIMainURL
+
+// APIPermissions 检测到的API密钥权限
+type APIPermissions struct {
+	FuturesTrading TradePermission `json:"futures_trading"`
+	Withdrawals    bool            `json:"withdrawals"`      // 是否开启了提现权限（建议关闭）
+	IPRestricted   bool            `json:"ip_restricted"`    // 是否限制了IP白名单
+	Detail         string          `json:"detail,omitempty"` // 无法检测时的原因
+}
+
+// APIPermissionChecker 可选接口：能查询API密钥权限的交易器
+type APIPermissionChecker interface {
+	GetAPIPermissions() (*APIPermissions, error)
+}
+
+// DetectAPIPermissions 检测交易器的API密钥权限（交易器不支持或查询失败时合约交易权限为 unknown）
+func DetectAPIPermissions(t Trader) *APIPermissions {
+	checker, ok := t.(APIPermissionChecker)
+	if !ok {
+		return &APIPermissions{FuturesTrading: TradePermissionUnknown, Detail: "该交易所不提供API权限查询"}
+	}
+	permissions, err := checker.GetAPIPermissions()
+	if err != nil {
+		return &APIPermissions{FuturesTrading: TradePermissionUnknown, Detail: err.Error()}
+	}
+	return permissions
+}
+
+// GetAPIPermissions 查询币安API密钥权限（GET /sapi/v1/account/apiRestrictions，测试网不支持）
+func (t *FuturesTrader) GetAPIPermissions() (*APIPermissions, error) {
+	if t.testnet {
+		return &APIPermissions{FuturesTrading: TradePermissionUnknown, Detail: "币安测试网不提供API权限查询"}, nil
+	}
+
+	client := binance.NewClient(t.client.APIKey, t.client.SecretKey)
+	client.BaseURL = binanceSpotBaseURL
+	client.HTTPClient = t.client.HTTPClient
+	client.TimeOffset = t.client.TimeOffset
+
+	restrictions, err := client.NewGetAPIKeyPermission().Do(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("查询币安API权限失败: %w", err)
+	}
+
+	permissions := &APIPermissions{
+		FuturesTrading: TradePermissionDisabled,
+		Withdrawals:    restrictions.EnableWithdrawals,
+		IPRestricted:   restrictions.IPRestrict,
+	}
+	if restrictions.EnableFutures {
+		permissions.FuturesTrading = TradePermissionEnabled
+	}
+	return permissions, nil
+}
+
+// GetAPIPermissions 查询Bybit API密钥权限（GET /v5/user/query-api）
+func (t *BybitTrader) GetAPIPermissions() (*APIPermissions, error) {
+	var result struct {
+		ReadOnly    int                 `json:"readOnly"`
+		IPs         []string            `json:"ips"`
+		Permissions map[string][]string `json:"permissions"`
+	}
+	if err := t.request(http.MethodGet, "/v5/user/query-api", nil, true, &result); err != nil {
+		return nil, fmt.Errorf("查询Bybit API权限失败: %w", err)
+	}
+
+	permissions := &APIPermissions{
+		FuturesTrading: TradePermissionDisabled,
+		Withdrawals:    slices.Contains(result.Permissions["Wallet"], "Withdraw"),
+		IPRestricted:   len(result.IPs) > 0 && !(len(result.IPs) == 1 && result.IPs[0] == "*"),
+	}
+	if result.ReadOnly == 0 && slices.Contains(result.Permissions["ContractTrade"], "Order") {
+		permissions.FuturesTrading = TradePermissionEnabled
+	}
+	return permissions, nil
+}
@@ -0,0 +1,83 @@
+package trader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFuturesTrader_GetAPIPermissions 币安API权限（apiRestrictions）：未开启合约交易的只读密钥
+func TestFuturesTrader_GetAPIPermissions(t *testing.T) {
+	enableFutures := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/sapi/v1/account/apiRestrictions", r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ipRestrict":        true,
+			"enableReading":     true,
+			"enableFutures":     enableFutures,
+			"enableWithdrawals": false,
+		})
+	}))
+	defer server.Close()
+
+	orig := binanceSpotBaseURL
+	binanceSpotBaseURL = server.URL
+	defer func() { binanceSpotBaseURL = orig }()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.HTTPClient = server.Client()
+	trader := &FuturesTrader{client: client}
+
+	permissions, err := trader.GetAPIPermissions()
+	require.NoError(t, err)
+	assert.Equal(t, TradePermissionDisabled, permissions.FuturesTrading)
+	assert.True(t, permissions.IPRestricted)
+	assert.False(t, permissions.Withdrawals)
+
+	enableFutures = true
+	assert.Equal(t, TradePermissionEnabled, DetectAPIPermissions(trader).FuturesTrading)
+
+	// 测试网不提供权限查询
+	trader.testnet = true
+	assert.Equal(t, TradePermissionUnknown, DetectAPIPermissions(trader).FuturesTrading)
+}
+
+// TestBybitTrader_GetAPIPermissions Bybit API权限（query-api）：只读密钥和可交易密钥
+func TestBybitTrader_GetAPIPermissions(t *testing.T) {
+	readOnly := 1
+	trader := newTestBybitTrader(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v5/user/query-api", r.URL.Path)
+		writeBybitResult(w, map[string]interface{}{
+			"readOnly": readOnly,
+			"ips":      []string{"*"},
+			"permissions": map[string][]string{
+				"ContractTrade": {"Order", "Position"},
+				"Wallet":        {"AccountTransfer", "Withdraw"},
+			},
+		})
+	})
+
+	permissions, err := trader.GetAPIPermissions()
+	require.NoError(t, err)
+	assert.Equal(t, TradePermissionDisabled, permissions.FuturesTrading)
+	assert.True(t, permissions.Withdrawals)
+	assert.False(t, permissions.IPRestricted)
+
+	readOnly = 0
+	permissions, err = trader.GetAPIPermissions()
+	require.NoError(t, err)
+	assert.Equal(t, TradePermissionEnabled, permissions.FuturesTrading)
+}
+
+// TestDetectAPIPermissions_Unsupported 不支持权限查询的交易所标记为 unknown
+func TestDetectAPIPermissions_Unsupported(t *testing.T) {
+	permissions := DetectAPIPermissions(&MockTrader{})
+	assert.Equal(t, TradePermissionUnknown, permissions.FuturesTrading)
+	assert.NotEmpty(t, permissions.Detail)
+}
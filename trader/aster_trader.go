@@ -37,6 +37,12 @@ type AsterTrader struct {
 	// 缓存交易对精度信息
 	symbolPrecision map[string]SymbolPrecision
 	mu              sync.RWMutex
+
+	// 交易对规则缓存（上架状态、最小名义价值等）
+	symbolFilters symbolFilterCache
+
+	// 服务器时钟（自动校正签名时间戳）
+	clock *serverClock
 }
 
 // SymbolPrecision 交易对精度信息
@@ -70,7 +76,7 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		client = res.GetResult()
 	}
 
-	return &AsterTrader{
+	trader := &AsterTrader{
 		ctx:             context.Background(),
 		user:            user,
 		signer:          signer,
@@ -78,7 +84,51 @@ func NewAsterTrader(user, signer, privateKeyHex string) (*AsterTrader, error) {
 		symbolPrecision: make(map[string]SymbolPrecision),
 		client:          client,
 		baseURL:         "https://fapi.asterdex.com",
-	}, nil
+	}
+	trader.clock = newServerClock("Aster", trader.fetchServerTime)
+
+	return trader, nil
+}
+
+// fetchServerTime 获取Aster服务器时间（毫秒）
+func (t *AsterTrader) fetchServerTime() (int64, error) {
+	resp, err := t.client.Get(t.baseURL + "/fapi/v3/time")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		ServerTime int64 `json:"serverTime"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	if result.ServerTime <= 0 {
+		return 0, fmt.Errorf("服务器时间无效: %s", string(body))
+	}
+	return result.ServerTime, nil
+}
+
+// ClockSkew 获取与Aster服务器的时钟偏移状态
+func (t *AsterTrader) ClockSkew() ClockSkewStatus {
+	if t.clock == nil {
+		return ClockSkewStatus{}
+	}
+	return t.clock.status()
+}
+
+// signTimestamp 生成签名用时间戳（毫秒），已按服务器时钟偏移校正
+func (t *AsterTrader) signTimestamp() int64 {
+	if t.clock == nil {
+		return time.Now().UnixMilli()
+	}
+	return t.clock.serverNowMillis()
 }
 
 // genNonce 生成微秒时间戳
@@ -150,6 +200,46 @@ func (t *AsterTrader) getPrecision(symbol string) (SymbolPrecision, error) {
 	return SymbolPrecision{}, fmt.Errorf("未找到交易对 %s 的精度信息", symbol)
 }
 
+// HedgeMode Aster 使用单向持仓模式（positionSide=BOTH）
+func (t *AsterTrader) HedgeMode() bool {
+	return false
+}
+
+// GetSymbolFilters 获取全部交易对的下单规则（缓存1小时）
+func (t *AsterTrader) GetSymbolFilters() (map[string]SymbolFilter, error) {
+	return t.symbolFilters.get(t.fetchSymbolFilters)
+}
+
+// fetchSymbolFilters 从 exchangeInfo 拉取交易对规则
+func (t *AsterTrader) fetchSymbolFilters() (map[string]SymbolFilter, error) {
+	resp, err := t.client.Get(t.baseURL + "/fapi/v3/exchangeInfo")
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取交易规则失败: %w", err)
+	}
+	var info struct {
+		Symbols []struct {
+			Symbol  string                   `json:"symbol"`
+			Status  string                   `json:"status"`
+			Filters []map[string]interface{} `json:"filters"`
+		} `json:"symbols"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("解析交易规则失败: %w", err)
+	}
+
+	filters := make(map[string]SymbolFilter, len(info.Symbols))
+	for _, s := range info.Symbols {
+		filters[s.Symbol] = parseExchangeFilters(s.Symbol, s.Status, s.Filters)
+	}
+	return filters, nil
+}
+
 // roundToTickSize 将价格/数量四舍五入到tick size/step size的整数倍
 func roundToTickSize(value float64, tickSize float64) float64 {
 	if tickSize <= 0 {
@@ -270,7 +360,7 @@ func (t *AsterTrader) normalize(v interface{}) (interface{}, error) {
 func (t *AsterTrader) sign(params map[string]interface{}, nonce uint64) error {
 	// 添加时间戳和接收窗口
 	params["recvWindow"] = "50000"
-	params["timestamp"] = strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+	params["timestamp"] = strconv.FormatInt(t.signTimestamp(), 10)
 
 	// 规范化参数为JSON字符串
 	jsonStr, err := t.normalizeAndStringify(params)
@@ -332,6 +422,12 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 	const maxRetries = 3
 	var lastErr error
 
+	// 首次签名请求前同步服务器时间（失败时使用本地时间继续）
+	if t.clock != nil && !t.clock.synced() {
+		t.clock.sync()
+	}
+
+	resyncCount := 0
 	for attempt := 1; attempt <= maxRetries; attempt++ {
 		// 每次重试都生成新的nonce和签名
 		nonce := t.genNonce()
@@ -352,6 +448,16 @@ func (t *AsterTrader) request(method, endpoint string, params map[string]interfa
 
 		lastErr = err
 
+		// 时间戳超出 recvWindow：重新同步服务器时间后重试（不占用网络重试次数）
+		if isTimestampError(err) && t.clock != nil && resyncCount < maxTimestampResyncRetries {
+			resyncCount++
+			log.Printf("⏱ Aster时间戳错误，重新同步服务器时间后重试 (%d/%d): %v", resyncCount, maxTimestampResyncRetries, err)
+			if syncErr := t.clock.sync(); syncErr == nil {
+				attempt--
+				continue
+			}
+		}
+
 		// 如果是网络超时或临时错误，重试
 		if strings.Contains(err.Error(), "timeout") ||
 			strings.Contains(err.Error(), "connection reset") ||
@@ -431,8 +537,17 @@ func (t *AsterTrader) doRequest(method, endpoint string, params map[string]inter
 	}
 }
 
-// GetBalance 获取账户余额
+// GetBalance 获取账户余额（旧版 map 结果，由 GetBalanceInfo 转换）
 func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
+	balance, err := t.GetBalanceInfo()
+	if err != nil {
+		return nil, err
+	}
+	return balance.ToMap(), nil
+}
+
+// GetBalanceInfo 获取账户余额
+func (t *AsterTrader) GetBalanceInfo() (*Balance, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/balance", params)
 	if err != nil {
@@ -473,14 +588,14 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	}
 
 	// 获取持仓计算保证金占用和真实未实现盈亏
-	positions, err := t.GetPositions()
+	positions, err := t.GetPositionList()
 	if err != nil {
 		log.Printf("⚠️  获取持仓信息失败: %v", err)
 		// fallback: 无法获取持仓时使用简单计算
-		return map[string]interface{}{
-			"totalWalletBalance":    crossWalletBalance,
-			"availableBalance":      availableBalance,
-			"totalUnrealizedProfit": crossUnPnl,
+		return &Balance{
+			WalletBalance:    crossWalletBalance,
+			AvailableBalance: availableBalance,
+			UnrealizedPnL:    crossUnPnl,
 		}, nil
 	}
 
@@ -489,19 +604,13 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	realUnrealizedPnl := 0.0
 	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		realUnrealizedPnl += unrealizedPnl
+		realUnrealizedPnl += pos.UnrealizedPnL
 
 		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if int(pos.Leverage) > 0 {
+			leverage = int(pos.Leverage)
 		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.Notional() / float64(leverage)
 		totalMarginUsed += marginUsed
 	}
 
@@ -512,15 +621,24 @@ func (t *AsterTrader) GetBalance() (map[string]interface{}, error) {
 	totalEquity := availableBalance + totalMarginUsed
 	totalWalletBalance := totalEquity - realUnrealizedPnl
 
-	return map[string]interface{}{
-		"totalWalletBalance":    totalWalletBalance, // 钱包余额（不含未实现盈亏）
-		"availableBalance":      availableBalance,   // 可用余额
-		"totalUnrealizedProfit": realUnrealizedPnl,  // 未实现盈亏（从持仓累加）
+	return &Balance{
+		WalletBalance:    totalWalletBalance, // 钱包余额（不含未实现盈亏）
+		AvailableBalance: availableBalance,   // 可用余额
+		UnrealizedPnL:    realUnrealizedPnl,  // 未实现盈亏（从持仓累加）
 	}, nil
 }
 
-// GetPositions 获取持仓信息
+// GetPositions 获取持仓信息（旧版 map 结果，由 GetPositionList 转换）
 func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
+	positions, err := t.GetPositionList()
+	if err != nil {
+		return nil, err
+	}
+	return positionsToMaps(positions), nil
+}
+
+// GetPositionList 获取持仓信息
+func (t *AsterTrader) GetPositionList() ([]Position, error) {
 	params := make(map[string]interface{})
 	body, err := t.request("GET", "/fapi/v3/positionRisk", params)
 	if err != nil {
@@ -532,7 +650,7 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 		return nil, err
 	}
 
-	result := []map[string]interface{}{}
+	result := []Position{}
 	for _, pos := range positions {
 		posAmtStr, ok := pos["positionAmt"].(string)
 		if !ok {
@@ -557,16 +675,16 @@ func (t *AsterTrader) GetPositions() ([]map[string]interface{}, error) {
 			posAmt = -posAmt
 		}
 
-		// 返回与Binance相同的字段名
-		result = append(result, map[string]interface{}{
-			"symbol":           pos["symbol"],
-			"side":             side,
-			"positionAmt":      posAmt,
-			"entryPrice":       entryPrice,
-			"markPrice":        markPrice,
-			"unRealizedProfit": unRealizedProfit,
-			"leverage":         leverageVal,
-			"liquidationPrice": liquidationPrice,
+		symbol, _ := pos["symbol"].(string)
+		result = append(result, Position{
+			Symbol:           symbol,
+			Side:             side,
+			Quantity:         posAmt,
+			EntryPrice:       entryPrice,
+			MarkPrice:        markPrice,
+			UnrealizedPnL:    unRealizedProfit,
+			Leverage:         leverageVal,
+			LiquidationPrice: liquidationPrice,
 		})
 	}
 
@@ -767,6 +885,7 @@ func (t *AsterTrader) CloseLong(symbol string, quantity float64) (map[string]int
 		"timeInForce":  "GTC",
 		"quantity":     qtyStr,
 		"price":        priceStr,
+		"reduceOnly":   "true", // 单向持仓模式下只减仓，数量超出持仓时不会反向开仓
 	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
@@ -850,6 +969,7 @@ func (t *AsterTrader) CloseShort(symbol string, quantity float64) (map[string]in
 		"timeInForce":  "GTC",
 		"quantity":     qtyStr,
 		"price":        priceStr,
+		"reduceOnly":   "true", // 单向持仓模式下只减仓，数量超出持仓时不会反向开仓
 	}
 
 	body, err := t.request("POST", "/fapi/v3/order", params)
@@ -959,66 +1079,63 @@ func (t *AsterTrader) GetMarketPrice(symbol string) (float64, error) {
 
 // SetStopLoss 设置止损
 func (t *AsterTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
-	side := "SELL"
-	if positionSide == "SHORT" {
-		side = "BUY"
-	}
+	_, err := t.placeTriggerOrder(symbol, positionSide, "STOP_MARKET", quantity, stopPrice)
+	return err
+}
 
-	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, stopPrice)
-	if err != nil {
-		return err
+// SetTakeProfit 设置止盈
+func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	_, err := t.placeTriggerOrder(symbol, positionSide, "TAKE_PROFIT_MARKET", quantity, takeProfitPrice)
+	return err
+}
+
+// PlaceProtectiveOrder 下止损/止盈触发市价单（只减仓），返回订单ID
+func (t *AsterTrader) PlaceProtectiveOrder(symbol, positionSide, kind string, quantity, triggerPrice float64) (string, error) {
+	orderType := "STOP_MARKET"
+	if kind == ProtectiveTakeProfit {
+		orderType = "TAKE_PROFIT_MARKET"
 	}
-	formattedQty, err := t.formatQuantity(symbol, quantity)
+	orderID, err := t.placeTriggerOrder(symbol, positionSide, orderType, quantity, triggerPrice)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("设置%s失败: %w", protectiveKindName(kind), err)
 	}
+	return strconv.FormatInt(orderID, 10), nil
+}
 
-	// 获取精度信息
-	prec, err := t.getPrecision(symbol)
+// CancelOrder 按订单ID撤单
+func (t *AsterTrader) CancelOrder(symbol, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
 	if err != nil {
-		return err
-	}
-
-	// 转换为字符串，使用正确的精度格式
-	priceStr := t.formatFloatWithPrecision(formattedPrice, prec.PricePrecision)
-	qtyStr := t.formatFloatWithPrecision(formattedQty, prec.QuantityPrecision)
-
-	params := map[string]interface{}{
-		"symbol":       symbol,
-		"positionSide": "BOTH",
-		"type":         "STOP_MARKET",
-		"side":         side,
-		"stopPrice":    priceStr,
-		"quantity":     qtyStr,
-		"timeInForce":  "GTC",
+		return fmt.Errorf("无效的订单ID: %s", orderID)
 	}
-
-	_, err = t.request("POST", "/fapi/v3/order", params)
+	_, err = t.request("DELETE", "/fapi/v3/order", map[string]interface{}{
+		"symbol":  symbol,
+		"orderId": id,
+	})
 	return err
 }
 
-// SetTakeProfit 设置止盈
-func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+// placeTriggerOrder 下触发市价单（STOP_MARKET/TAKE_PROFIT_MARKET，reduceOnly），返回订单ID
+func (t *AsterTrader) placeTriggerOrder(symbol, positionSide, orderType string, quantity, triggerPrice float64) (int64, error) {
 	side := "SELL"
 	if positionSide == "SHORT" {
 		side = "BUY"
 	}
 
 	// 格式化价格和数量到正确精度
-	formattedPrice, err := t.formatPrice(symbol, takeProfitPrice)
+	formattedPrice, err := t.formatPrice(symbol, triggerPrice)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	formattedQty, err := t.formatQuantity(symbol, quantity)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 获取精度信息
 	prec, err := t.getPrecision(symbol)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	// 转换为字符串，使用正确的精度格式
@@ -1028,15 +1145,26 @@ func (t *AsterTrader) SetTakeProfit(symbol string, positionSide string, quantity
 	params := map[string]interface{}{
 		"symbol":       symbol,
 		"positionSide": "BOTH",
-		"type":         "TAKE_PROFIT_MARKET",
+		"type":         orderType,
 		"side":         side,
 		"stopPrice":    priceStr,
 		"quantity":     qtyStr,
+		"reduceOnly":   "true", // 只减仓，避免持仓已平后触发反向开仓
 		"timeInForce":  "GTC",
 	}
 
-	_, err = t.request("POST", "/fapi/v3/order", params)
-	return err
+	body, err := t.request("POST", "/fapi/v3/order", params)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		OrderID int64 `json:"orderId"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析订单响应失败: %w", err)
+	}
+	return result.OrderID, nil
 }
 
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
@@ -1230,3 +1358,197 @@ func (t *AsterTrader) FormatQuantity(symbol string, quantity float64) (string, e
 	}
 	return fmt.Sprintf("%v", formatted), nil
 }
+
+// asterTradeHistoryWindow 成交记录接口单次查询的最大时间跨度
+const asterTradeHistoryWindow = 7 * 24 * time.Hour
+
+// asterUserTrade Aster成交记录（字段与币安合约一致）
+type asterUserTrade struct {
+	Symbol          string `json:"symbol"`
+	Side            string `json:"side"`
+	PositionSide    string `json:"positionSide"`
+	Price           string `json:"price"`
+	Qty             string `json:"qty"`
+	RealizedPnl     string `json:"realizedPnl"`
+	Commission      string `json:"commission"`
+	CommissionAsset string `json:"commissionAsset"`
+	Time            int64  `json:"time"`
+	Buyer           bool   `json:"buyer"`
+}
+
+// GetAllTradeHistory 获取所有币种的成交历史
+// 成交接口必须指定币种：从已实现盈亏流水和当前持仓中收集交易过的币种，再逐个查询
+func (t *AsterTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*Trade, error) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -lookbackDays)
+
+	symbols, err := t.tradedSymbols(start)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string][]*Trade)
+	for _, symbol := range symbols {
+		var raw []asterUserTrade
+		for windowStart := start; windowStart.Before(end); windowStart = windowStart.Add(asterTradeHistoryWindow) {
+			windowEnd := windowStart.Add(asterTradeHistoryWindow)
+			if windowEnd.After(end) {
+				windowEnd = end
+			}
+			body, err := t.request("GET", "/fapi/v3/userTrades", map[string]interface{}{
+				"symbol":    symbol,
+				"startTime": windowStart.UnixMilli(),
+				"endTime":   windowEnd.UnixMilli(),
+				"limit":     1000,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("获取 %s 成交历史失败: %w", symbol, err)
+			}
+			var page []asterUserTrade
+			if err := json.Unmarshal(body, &page); err != nil {
+				return nil, fmt.Errorf("解析 %s 成交历史失败: %w", symbol, err)
+			}
+			raw = append(raw, page...)
+		}
+		if trades := buildAsterTradeHistory(raw); len(trades) > 0 {
+			result[symbol] = trades
+		}
+	}
+	return result, nil
+}
+
+// tradedSymbols 收集指定时间以来有已实现盈亏或当前有持仓的币种
+func (t *AsterTrader) tradedSymbols(since time.Time) ([]string, error) {
+	body, err := t.request("GET", "/fapi/v3/income", map[string]interface{}{
+		"incomeType": "REALIZED_PNL",
+		"startTime":  since.UnixMilli(),
+		"limit":      1000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取盈亏流水失败: %w", err)
+	}
+	var incomes []struct {
+		Symbol string `json:"symbol"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, fmt.Errorf("解析盈亏流水失败: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var symbols []string
+	add := func(symbol string) {
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			symbols = append(symbols, symbol)
+		}
+	}
+	for _, income := range incomes {
+		add(income.Symbol)
+	}
+
+	positions, err := t.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		add(symbol)
+	}
+	sort.Strings(symbols)
+	return symbols, nil
+}
+
+// buildAsterTradeHistory 转换单个币种的成交记录并按时间升序排列
+// Aster使用单向持仓模式（positionSide=BOTH），需按净仓位推断开平方向
+func buildAsterTradeHistory(raw []asterUserTrade) []*Trade {
+	trades := make([]*Trade, 0, len(raw))
+	for _, r := range raw {
+		price, _ := strconv.ParseFloat(r.Price, 64)
+		qty, _ := strconv.ParseFloat(r.Qty, 64)
+		realizedPnl, _ := strconv.ParseFloat(r.RealizedPnl, 64)
+		commission, _ := strconv.ParseFloat(r.Commission, 64)
+		trades = append(trades, &Trade{
+			Symbol:          r.Symbol,
+			Side:            r.Side,
+			PositionSide:    r.PositionSide,
+			Price:           price,
+			Qty:             qty,
+			RealizedPnl:     realizedPnl,
+			Commission:      commission,
+			CommissionAsset: r.CommissionAsset,
+			Time:            r.Time,
+			Buyer:           r.Buyer,
+		})
+	}
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time < trades[j].Time })
+	return assignOneWayPositionSides(trades)
+}
+
+// GetFundingRates 获取资金费率（premiumIndex，字段与币安一致）
+func (t *AsterTrader) GetFundingRates(symbols []string) (map[string]FundingRate, error) {
+	resp, err := t.client.Get(t.baseURL + "/fapi/v1/premiumIndex")
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费率失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取资金费率失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取资金费率失败: HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	var indexes []struct {
+		Symbol          string `json:"symbol"`
+		LastFundingRate string `json:"lastFundingRate"`
+		NextFundingTime int64  `json:"nextFundingTime"`
+	}
+	if err := json.Unmarshal(body, &indexes); err != nil {
+		return nil, fmt.Errorf("解析资金费率失败: %w", err)
+	}
+
+	rates := make(map[string]FundingRate, len(indexes))
+	for _, index := range indexes {
+		rate, err := strconv.ParseFloat(index.LastFundingRate, 64)
+		if err != nil {
+			continue
+		}
+		fundingRate := FundingRate{Symbol: index.Symbol, Rate: rate}
+		if index.NextFundingTime > 0 {
+			fundingRate.NextFundingTime = time.UnixMilli(index.NextFundingTime)
+		}
+		rates[index.Symbol] = fundingRate
+	}
+	return filterFundingRates(rates, symbols), nil
+}
+
+// GetFundingFees 获取资金费收支（收入流水 FUNDING_FEE，单次最多1000条）
+func (t *AsterTrader) GetFundingFees(startTime time.Time) ([]FundingFee, error) {
+	body, err := t.request("GET", "/fapi/v3/income", map[string]interface{}{
+		"incomeType": "FUNDING_FEE",
+		"startTime":  startTime.UnixMilli(),
+		"limit":      1000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+	}
+	var incomes []struct {
+		Symbol string `json:"symbol"`
+		Income string `json:"income"`
+		Time   int64  `json:"time"`
+	}
+	if err := json.Unmarshal(body, &incomes); err != nil {
+		return nil, fmt.Errorf("解析资金费流水失败: %w", err)
+	}
+
+	fees := make([]FundingFee, 0, len(incomes))
+	for _, income := range incomes {
+		amount, err := strconv.ParseFloat(income.Income, 64)
+		if err != nil {
+			continue
+		}
+		fees = append(fees, FundingFee{Symbol: income.Symbol, Amount: amount, Time: income.Time})
+	}
+	return sortFundingFees(fees), nil
+}
@@ -2,19 +2,29 @@ package trader
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math"
+	"nofx/config"
 	"nofx/decision"
 	"nofx/logger"
 	"nofx/market"
 	"nofx/mcp"
+	"nofx/metrics"
 	"nofx/pool"
+	"runtime/debug"
 	"strings"
 	"sync"
 	"time"
 )
 
+// ErrAIAuthFailed AI API认证失败（API Key无效或被撤销），交易员停止且不自动重启
+var ErrAIAuthFailed = errors.New("AI API认证失败")
+
+// aiRateLimitPause AI接口限流且未返回 Retry-After 时，提前重试本周期前的默认等待时长
+var aiRateLimitPause = 30 * time.Second
+
 // AutoTraderConfig 自动交易配置（简化版 - AI全权决策）
 type AutoTraderConfig struct {
 	// Trader标识
@@ -23,11 +33,17 @@ type AutoTraderConfig struct {
 	AIModel string // AI模型: "qwen" 或 "deepseek"
 
 	// 交易平台选择
-	Exchange string // "binance", "hyperliquid" 或 "aster"
+	Exchange string // "binance", "bybit", "hyperliquid", "aster" 或 "paper"（模拟盘）
 
 	// 币安API配置
 	BinanceAPIKey    string
 	BinanceSecretKey string
+	BinanceTestnet   bool // 使用币安合约测试网
+
+	// Bybit API配置
+	BybitAPIKey    string
+	BybitSecretKey string
+	BybitTestnet   bool // 使用Bybit测试网
 
 	// Hyperliquid配置
 	HyperliquidPrivateKey string
@@ -39,7 +55,11 @@ type AutoTraderConfig struct {
 	AsterSigner     string // Aster API钱包地址
 	AsterPrivateKey string // Aster API钱包私钥
 
-	CoinPoolAPIURL string
+	// 信号源（交易员启用时配置，优先于交易币种作为候选币种）
+	CoinPoolAPIURL      string
+	OITopAPIURL         string
+	SignalSourceHeaders map[string]string // 请求信号源时附加的请求头（如 Authorization）
+	SignalSourceTimeout time.Duration     // 请求信号源的超时（0表示默认）
 
 	// AI配置
 	UseQwen     bool
@@ -50,6 +70,7 @@ type AutoTraderConfig struct {
 	CustomAPIURL    string
 	CustomAPIKey    string
 	CustomModelName string
+	AIModelParams   config.AIModelParams // 模型请求参数（temperature/max_tokens/超时，未设置时使用默认值）
 
 	// 扫描配置
 	ScanInterval time.Duration // 扫描间隔（建议3分钟）
@@ -66,15 +87,54 @@ type AutoTraderConfig struct {
 	MaxDrawdown     float64       // 最大回撤百分比（提示）
 	StopTradingTime time.Duration // 触发风控后暂停时长
 
+	// 日亏损熔断（按交易员配置，强制执行）
+	MaxDailyLossPct  float64 // 当日亏损（已实现+未实现）超过日起点净值的该百分比时停止开新仓，<=0 表示关闭
+	DailyLossFlatten bool    // 熔断时是否平掉所有持仓
+
+	// 仓位与敞口上限（按交易员配置，开仓时强制执行，计入已有持仓）
+	MaxPositionValueUSDT float64 // 单币种仓位价值上限（USDT），<=0 表示不限制
+	MaxTotalExposurePct  float64 // 所有持仓价值合计占净值的百分比上限，<=0 表示不限制
+
+	// 默认止盈止损（AI 未给出或价格无效时使用，相对开仓价的百分比，<=0 表示不使用）
+	DefaultStopLossPct   float64
+	DefaultTakeProfitPct float64
+
+	// 亏损冷却：同币种亏损平仓后的禁止开仓时间（分钟），<=0 表示关闭
+	CooldownMinutesAfterLoss int
+
+	// 开仓执行方式：market（默认）或 limit（在买一/卖一价 ± 偏移挂限价单，超时后按 LimitFallback 处理剩余数量）
+	ExecutionMode       string
+	LimitOffsetBps      float64 // 限价偏移（基点，正数向对手价方向让价以提高成交率，负数更被动）
+	LimitTimeoutSeconds int     // 限价单等待成交的时间（秒）
+	LimitFallback       string  // 超时未成交部分的处理: market（转市价）或 cancel（撤单放弃）
+
 	// 仓位模式
 	IsCrossMargin bool // true=全仓模式, false=逐仓模式
 
 	// 币种配置
-	DefaultCoins []string // 默认币种列表（从数据库获取）
-	TradingCoins []string // 实际交易币种列表
+	DefaultCoins    []string // 默认币种列表（从数据库获取）
+	TradingCoins    []string // 实际交易币种列表
+	ExcludedSymbols []string // 排除的币种（不出现在候选币种和提示词中，AI仍返回时拒绝开仓）
 
 	// 系统提示词模板
 	SystemPromptTemplate string // 系统提示词模板名称（如 "default", "aggressive"）
+
+	// 公开展示
+	IsPublic bool // 是否在公开排行榜/竞赛接口中展示
+
+	// 提示词模板A/B实验
+	Experiment PromptExperiment
+
+	// 备用AI模型：主模型余额不足、认证失败、持续限流、超时或服务不可用时本周期改用备用模型
+	FallbackAIModelID     string // 备用模型ID（为空表示不使用）
+	FallbackStickyMinutes int    // 切换后持续使用备用模型的分钟数（0=每周期先尝试主模型）
+
+	// 多模型共识：列出的模型并发给出决策，只执行按共识规则一致的动作（少于2个模型时不启用）
+	EnsembleModelIDs []string
+	ConsensusRule    string // all 或 majority
+
+	// 护栏规则：在AI决策和交易所之间按顺序执行，可拒绝或调整开仓决策
+	Guardrails []GuardrailConfig
 }
 
 // AutoTrader 自动交易器
@@ -87,13 +147,17 @@ type AutoTrader struct {
 	trader                Trader // 使用Trader接口（支持多平台）
 	mcpClient             mcp.AIClient
 	decisionLogger        logger.IDecisionLogger // 决策日志记录器
+	marketSource          market.Source          // 行情来源（Hyperliquid 使用自己的行情，其他交易所使用币安行情）
 	initialBalance        float64
 	dailyPnL              float64
-	customPrompt          string   // 自定义交易策略prompt
-	overrideBasePrompt    bool     // 是否覆盖基础prompt
-	systemPromptTemplate  string   // 系统提示词模板名称
-	defaultCoins          []string // 默认币种列表（从数据库获取）
-	tradingCoins          []string // 实际交易币种列表
+	customPrompt          string            // 自定义交易策略prompt
+	overrideBasePrompt    bool              // 是否覆盖基础prompt
+	systemPromptTemplate  string            // 系统提示词模板名称
+	defaultCoins          []string          // 默认币种列表（从数据库获取）
+	tradingCoins          []string          // 实际交易币种列表
+	invalidSymbols        map[string]string // 启动时校验不可交易的自定义币种（symbol -> 原因），不作为候选币种
+	excludedSymbols       map[string]bool   // 排除的币种（settingsMu 保护）
+	signalSourceErrors    []string          // 本周期信号源获取失败的原因（写入决策日志）
 	lastResetTime         time.Time
 	stopUntil             time.Time
 	isRunning             bool
@@ -108,6 +172,55 @@ type AutoTrader struct {
 	lastBalanceSyncTime   time.Time          // 上次余额同步时间
 	database              interface{}        // 数据库引用（用于自动更新余额）
 	userID                string             // 用户ID
+	spend                 spendState         // AI预算降级状态
+	events                *EventBus          // 实时事件总线（WebSocket推送）
+	supervision           supervisionState   // 监督运行状态（自动重启）
+	settingsMu            sync.RWMutex       // 保护可热更新的运行参数（扫描间隔、杠杆、交易币种、公开状态）
+	scanIntervalCh        chan time.Duration // 运行中修改扫描间隔时通知主循环重置定时器
+	dailyLoss             dailyLossState     // 日亏损熔断状态
+	dailyLossMu           sync.RWMutex       // 保护 dailyLoss（状态接口并发读取）
+	riskFeedback          []string           // 上一周期开仓被风控调整/拒绝的原因（反馈给下一周期的AI）
+
+	// 备用AI模型（主模型故障时按周期切换）
+	fallback aiFallbackState
+	// 多模型共识（启用时替代主模型/备用模型的单模型决策）
+	ensemble ensembleState
+	// 串行化决策周期和决策预演（两者都会读写持仓跟踪状态）
+	cycleMu sync.Mutex
+	// 手动触发决策周期（主循环监听）
+	trigger manualTriggerState
+
+	// 启动时固定的提示词模板版本（settingsMu 保护）
+	pinnedPrompt *decision.PromptTemplate
+	// 护栏规则流水线（settingsMu 保护，未配置时为 nil）
+	guardrails *GuardrailPipeline
+	// 提示词模板A/B实验状态（settingsMu 保护）
+	experiment experimentState
+
+	// 止盈止损单跟踪（symbol_side -> 订单）
+	protection   map[string]*ProtectiveOrders
+	protectionMu sync.Mutex
+
+	// 亏损冷却（symbol -> 最近一次平仓结果，symbol_side -> 上一周期观察到的未实现盈亏）
+	lastCloses      map[string]closeResult
+	lastPositionPnL map[string]float64
+	cooldownMu      sync.Mutex
+
+	// 资金费流水缓存（账户信息和AI上下文共用）
+	funding fundingFeesCache
+}
+
+// supervisionState 监督运行状态：停止请求信号、重启次数和最近一次错误
+type supervisionState struct {
+	stopRequestCh chan struct{} // 手动停止时关闭，通知监督者不再重启
+	restartCount  int           // 连续异常退出次数
+	lastError     string        // 最近一次异常退出原因
+	lastErrorAt   time.Time     // 最近一次异常退出时间
+}
+
+// DecisionLogDir 交易员的决策日志目录（每个交易员独立目录）
+func DecisionLogDir(traderID string) string {
+	return fmt.Sprintf("decision_logs/%s", traderID)
 }
 
 // NewAutoTrader 创建自动交易器
@@ -134,6 +247,11 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		// 使用自定义API
 		mcpClient.SetAPIKey(config.CustomAPIKey, config.CustomAPIURL, config.CustomModelName)
 		log.Printf("🤖 [%s] 使用自定义AI API: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
+	} else if mcp.IsLocalProvider(config.AIModel) {
+		// 本地模型（Ollama / OpenAI 兼容服务，无需 API Key）
+		mcpClient = mcp.NewLocalClient()
+		mcpClient.SetAPIKey(config.CustomAPIKey, config.CustomAPIURL, config.CustomModelName)
+		log.Printf("🤖 [%s] 使用本地AI模型: %s (模型: %s)", config.Name, config.CustomAPIURL, config.CustomModelName)
 	} else if config.UseQwen || config.AIModel == "qwen" {
 		// 使用Qwen (支持自定义URL和Model)
 		mcpClient = mcp.NewQwenClient()
@@ -154,6 +272,9 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		}
 	}
 
+	applyAIModelParams(mcpClient, config.AIModelParams)
+	limitAIRetryBudget(mcpClient, config.ScanInterval)
+
 	// 初始化币种池API
 	if config.CoinPoolAPIURL != "" {
 		pool.SetCoinPoolAPI(config.CoinPoolAPIURL)
@@ -178,7 +299,20 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	switch config.Exchange {
 	case "binance":
 		log.Printf("🏦 [%s] 使用币安合约交易", config.Name)
-		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey, userID)
+		if config.BinanceTestnet {
+			log.Printf("🧪 [%s] 币安合约测试网模式（模拟资金，盈亏不代表实盘结果）", config.Name)
+		}
+		trader = NewFuturesTrader(config.BinanceAPIKey, config.BinanceSecretKey, userID, config.BinanceTestnet)
+	case "bybit":
+		log.Printf("🏦 [%s] 使用Bybit合约交易", config.Name)
+		if config.BybitTestnet {
+			log.Printf("🧪 [%s] Bybit测试网模式（模拟资金，盈亏不代表实盘结果）", config.Name)
+		}
+		bybitTrader := NewBybitTrader(config.BybitAPIKey, config.BybitSecretKey, config.BybitTestnet)
+		if err := bybitTrader.EnsureHedgeMode(); err != nil {
+			log.Printf("⚠️ [%s] 设置Bybit双向持仓模式失败: %v (如果已是双向模式则忽略此警告)", config.Name, err)
+		}
+		trader = bybitTrader
 	case "hyperliquid":
 		log.Printf("🏦 [%s] 使用Hyperliquid交易", config.Name)
 		trader, err = NewHyperliquidTrader(config.HyperliquidPrivateKey, config.HyperliquidWalletAddr, config.HyperliquidTestnet)
@@ -191,6 +325,16 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		if err != nil {
 			return nil, fmt.Errorf("初始化Aster交易器失败: %w", err)
 		}
+	case "paper":
+		log.Printf("🏦 [%s] 使用模拟盘交易（虚拟资金）", config.Name)
+		store, ok := database.(paperStore)
+		if !ok {
+			return nil, fmt.Errorf("初始化模拟盘交易器失败: 数据库不支持模拟盘")
+		}
+		trader, err = NewPaperTrader(config.ID, userID, store, config.InitialBalance, nil)
+		if err != nil {
+			return nil, fmt.Errorf("初始化模拟盘交易器失败: %w", err)
+		}
 	default:
 		return nil, fmt.Errorf("不支持的交易平台: %s", config.Exchange)
 	}
@@ -201,8 +345,7 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 	}
 
 	// 初始化决策日志记录器（使用trader ID创建独立目录）
-	logDir := fmt.Sprintf("decision_logs/%s", config.ID)
-	decisionLogger := logger.NewDecisionLogger(logDir)
+	decisionLogger := logger.OpenDecisionLogger(DecisionLogDir(config.ID))
 
 	// 设置默认系统提示词模板
 	systemPromptTemplate := config.SystemPromptTemplate
@@ -211,6 +354,13 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		systemPromptTemplate = "adaptive"
 	}
 
+	// 护栏配置在保存时已校验，这里无效时只记录日志（不阻止交易员启动）
+	guardrails, err := NewGuardrailPipeline(config.Guardrails)
+	if err != nil {
+		log.Printf("⚠️ [%s] 护栏规则配置无效，已忽略: %v", config.Name, err)
+		guardrails = nil
+	}
+
 	return &AutoTrader{
 		id:                    config.ID,
 		name:                  config.Name,
@@ -218,12 +368,15 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		exchange:              config.Exchange,
 		config:                config,
 		trader:                trader,
+		marketSource:          market.SourceForExchange(config.Exchange, config.HyperliquidTestnet),
 		mcpClient:             mcpClient,
 		decisionLogger:        decisionLogger,
 		initialBalance:        config.InitialBalance,
 		systemPromptTemplate:  systemPromptTemplate,
 		defaultCoins:          config.DefaultCoins,
 		tradingCoins:          config.TradingCoins,
+		excludedSymbols:       newExcludedSymbolSet(config.ExcludedSymbols),
+		scanIntervalCh:        make(chan time.Duration, 1),
 		lastResetTime:         time.Now(),
 		startTime:             time.Now(),
 		callCount:             0,
@@ -236,11 +389,17 @@ func NewAutoTrader(config AutoTraderConfig, database interface{}, userID string)
 		lastBalanceSyncTime:   time.Now(), // 初始化为当前时间
 		database:              database,
 		userID:                userID,
+		events:                NewEventBus(),
+		experiment:            experimentState{config: config.Experiment},
+		fallback:              aiFallbackState{modelID: config.FallbackAIModelID, stickyMinutes: config.FallbackStickyMinutes},
+		ensemble:              ensembleState{modelIDs: config.EnsembleModelIDs, rule: config.ConsensusRule},
+		trigger:               manualTriggerState{ch: make(chan struct{}, 1)},
+		guardrails:            guardrails,
 	}, nil
 }
 
-// Run 运行自动交易主循环
-func (at *AutoTrader) Run() error {
+// Run 运行自动交易主循环（主循环 panic 时恢复并以错误返回，便于监督者重启）
+func (at *AutoTrader) Run() (err error) {
 	// 防止重复启动
 	at.mu.Lock()
 	if at.isRunning {
@@ -249,27 +408,83 @@ func (at *AutoTrader) Run() error {
 		return nil
 	}
 	at.isRunning = true
+	// 在锁内重建停止信号并登记主循环，确保并发调用的 Stop 一定会等待本轮循环退出
+	at.stopMonitorCh = make(chan struct{})
+	at.monitorWg.Add(1)
 	at.mu.Unlock()
+	defer at.monitorWg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("❌ [%s] 交易员运行时panic: %v\n%s", at.name, r, debug.Stack())
+			at.abortRun()
+			err = fmt.Errorf("交易员运行时panic: %v", r)
+		}
+	}()
+
+	// 启动扩展点（可拒绝启动），主循环退出时执行停止扩展点
+	if err := at.runStartHook(); err != nil {
+		log.Printf("🔌 [%s] %v", at.name, err)
+		at.abortRun()
+		return err
+	}
+	defer func() { at.runStopHook(err) }()
+
+	metrics.TradersRunning.Inc(at.userID)
+	defer metrics.TradersRunning.Dec(at.userID)
+
+	// 登记交易币种的行情订阅，停止时释放只有本交易员使用的动态订阅
+	market.RetainSymbols(at.id, at.getTradingCoins())
+	defer market.ReleaseSymbols(at.id)
+
+	// 未通过启动接口固定模板版本时（如服务重启后自动恢复运行），固定当前最新版本
+	if at.getPinnedPromptTemplate() == nil {
+		at.PinPromptTemplate()
+	}
 
-	at.stopMonitorCh = make(chan struct{})
 	at.startTime = time.Now()
+	at.publishStateChanged(true)
 
 	log.Println("🚀 AI驱动自动交易系统启动")
 	log.Printf("💰 初始余额: %.2f USDT", at.initialBalance)
-	log.Printf("⚙️  扫描间隔: %v", at.config.ScanInterval)
+	log.Printf("⚙️  扫描间隔: %v", at.getScanInterval())
 	log.Println("🤖 AI将全权决定杠杆、仓位大小、止损止盈等参数")
-	at.monitorWg.Add(1)
-	defer at.monitorWg.Done()
+
+	// 校验自定义交易币种在交易所存在且可交易
+	at.checkTradingSymbols()
 
 	// 启动回撤监控
 	at.startDrawdownMonitor()
+	// 启动软件止盈止损监控（交易所触发单下单失败时兜底）
+	at.startProtectionMonitor()
 
-	ticker := time.NewTicker(at.config.ScanInterval)
+	ticker := time.NewTicker(at.getScanInterval())
 	defer ticker.Stop()
 
-	// 首次立即执行
-	if err := at.runCycle(); err != nil {
+	// 首次立即执行（启动后立刻被停止则跳过）
+	select {
+	case <-at.stopMonitorCh:
+		return nil
+	default:
+	}
+	// AI接口限流时提前重试本周期（nil 表示没有待执行的重试）
+	var rateLimitRetry <-chan time.Time
+	runAndHandle := func(manual bool) error {
+		rateLimitRetry = nil
+		err := at.runCycleWithMetrics(manual)
+		if err == nil {
+			return nil
+		}
 		log.Printf("❌ 执行失败: %v", err)
+		retryAfter, fatal := at.handleAIError(err)
+		if retryAfter > 0 {
+			rateLimitRetry = time.After(retryAfter)
+		}
+		return fatal
+	}
+
+	if err := runAndHandle(false); err != nil {
+		at.abortRun()
+		return err
 	}
 
 	for {
@@ -283,9 +498,27 @@ func (at *AutoTrader) Run() error {
 
 		select {
 		case <-ticker.C:
-			if err := at.runCycle(); err != nil {
-				log.Printf("❌ 执行失败: %v", err)
+			if err := runAndHandle(false); err != nil {
+				at.abortRun()
+				return err
+			}
+		case <-rateLimitRetry:
+			log.Printf("[%s] 🔄 AI接口限流等待结束，重试本周期", at.name)
+			if err := runAndHandle(false); err != nil {
+				at.abortRun()
+				return err
 			}
+		case <-at.trigger.ch:
+			log.Printf("[%s] 👆 手动触发决策周期", at.name)
+			if err := runAndHandle(true); err != nil {
+				at.abortRun()
+				return err
+			}
+			// 下一个定时周期从手动周期结束后重新计时
+			ticker.Reset(at.getScanInterval())
+		case interval := <-at.scanIntervalCh:
+			ticker.Reset(interval)
+			log.Printf("[%s] ⚙️  扫描间隔已更新为 %v", at.name, interval)
 		case <-at.stopMonitorCh:
 			log.Printf("[%s] ⏹ 收到停止信号，退出自动交易主循环", at.name)
 			return nil
@@ -295,23 +528,153 @@ func (at *AutoTrader) Run() error {
 	return nil
 }
 
-// Stop 停止自动交易
+// Stop 停止自动交易（同时通知监督者不再自动重启）
 func (at *AutoTrader) Stop() {
 	at.mu.Lock()
+	if at.supervision.stopRequestCh != nil {
+		close(at.supervision.stopRequestCh)
+		at.supervision.stopRequestCh = nil
+	}
 	if !at.isRunning {
 		at.mu.Unlock()
 		return
 	}
 	at.isRunning = false
+	stopCh := at.stopMonitorCh
 	at.mu.Unlock()
 
-	close(at.stopMonitorCh) // 通知监控goroutine停止
-	at.monitorWg.Wait()     // 等待监控goroutine结束
+	close(stopCh)       // 通知主循环和监控goroutine停止
+	at.monitorWg.Wait() // 等待进行中的交易周期和监控goroutine结束
+	at.publishStateChanged(false)
 	log.Println("⏹ 自动交易系统停止")
 }
 
-// runCycle 运行一个交易周期（使用AI全权决策）
-func (at *AutoTrader) runCycle() error {
+// abortRun 主循环异常退出时清理运行状态（不等待 monitorWg，调用方仍持有计数）
+func (at *AutoTrader) abortRun() {
+	at.mu.Lock()
+	if !at.isRunning {
+		at.mu.Unlock()
+		return
+	}
+	at.isRunning = false
+	close(at.stopMonitorCh) // 通知监控goroutine停止
+	at.mu.Unlock()
+	at.publishStateChanged(false)
+}
+
+// BeginSupervision 开始一次监督运行，返回停止请求信号（Stop 时关闭）；
+// 同一交易员重复开始时关闭上一次的信号，让旧的监督者退出
+func (at *AutoTrader) BeginSupervision() <-chan struct{} {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if at.supervision.stopRequestCh != nil {
+		close(at.supervision.stopRequestCh)
+	}
+	at.supervision.stopRequestCh = make(chan struct{})
+	at.supervision.restartCount = 0
+	at.supervision.lastError = ""
+	at.supervision.lastErrorAt = time.Time{}
+	return at.supervision.stopRequestCh
+}
+
+// EndSupervision 结束监督运行（仅当 stopRequested 仍是当前信号时清除，避免影响新的监督者）
+func (at *AutoTrader) EndSupervision(stopRequested <-chan struct{}) {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	if at.supervision.stopRequestCh != nil && (<-chan struct{})(at.supervision.stopRequestCh) == stopRequested {
+		at.supervision.stopRequestCh = nil
+	}
+}
+
+// RestartPending 是否正处于异常退出后等待自动重启的状态
+func (at *AutoTrader) RestartPending() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return !at.isRunning && at.supervision.stopRequestCh != nil && at.supervision.restartCount > 0
+}
+
+// RecordRunFailure 记录一次异常退出（restarts 为连续异常退出次数）
+func (at *AutoTrader) RecordRunFailure(err error, restarts int) {
+	at.mu.Lock()
+	at.supervision.restartCount = restarts
+	at.supervision.lastError = err.Error()
+	at.supervision.lastErrorAt = time.Now()
+	at.mu.Unlock()
+
+	at.publishEvent(EventTraderError, map[string]interface{}{
+		"error":    err.Error(),
+		"restarts": restarts,
+	})
+}
+
+// runCycleWithMetrics 运行一个交易周期并记录周期数、失败数和耗时
+func (at *AutoTrader) runCycleWithMetrics(manual bool) error {
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	start := time.Now()
+	err := at.runCycle(manual)
+	metrics.DecisionCycles.Inc(at.id)
+	metrics.DecisionCycleDuration.Observe(time.Since(start).Seconds(), at.id)
+	if err != nil {
+		metrics.DecisionCyclesFailed.Inc(at.id)
+	}
+	return err
+}
+
+// recordAIError 按错误类型统计AI接口调用失败（市场数据和解析失败不计入）
+func (at *AutoTrader) recordAIError(err error) {
+	if !errors.Is(err, decision.ErrAICall) {
+		return
+	}
+	class := mcp.ClassifyError(err)
+	if class == "" {
+		class = "unknown"
+	}
+	metrics.AIErrors.Inc(at.aiModel, class)
+
+	if mcp.IsInsufficientBalanceError(err) {
+		at.publishEvent(EventAIBalanceAlert, map[string]interface{}{
+			"model": at.aiModel,
+			"error": err.Error(),
+		})
+	}
+}
+
+// handleAIError 根据AI错误类型决定后续处理：
+// 限流时返回提前重试的等待时长（不超过扫描间隔的一半）；认证失败（API Key无效或被撤销）时告警并返回
+// ErrAIAuthFailed，交易员停止且不自动重启；其他错误按原扫描间隔继续
+func (at *AutoTrader) handleAIError(err error) (retryAfter time.Duration, fatal error) {
+	if !errors.Is(err, decision.ErrAICall) {
+		return 0, nil
+	}
+
+	if mcp.IsAuthError(err) {
+		message := fmt.Sprintf("❌ [%s] AI模型 %s 认证失败（API Key无效或已被撤销），交易员已停止，请更新API Key后重新启动: %v", at.name, at.aiModel, err)
+		log.Println(message)
+		if logger.Log != nil {
+			logger.Log.Error(message)
+		}
+		return 0, fmt.Errorf("%w: %w", ErrAIAuthFailed, err)
+	}
+
+	if rateLimitErr := mcp.AsRateLimitError(err); rateLimitErr != nil {
+		retryAfter = rateLimitErr.ParsedRetryAfter
+		if retryAfter <= 0 {
+			retryAfter = aiRateLimitPause
+		}
+		if limit := at.getScanInterval() / 2; limit > 0 && retryAfter > limit {
+			retryAfter = limit
+		}
+		log.Printf("⏸ [%s] AI接口限流，暂停 %v 后重试本周期", at.name, retryAfter)
+		return retryAfter, nil
+	}
+	return 0, nil
+}
+
+// runCycle 运行一个交易周期（使用AI全权决策），manual 表示由用户手动触发
+func (at *AutoTrader) runCycle(manual bool) error {
+	cycleStart := time.Now()
 	at.callCount++
 
 	log.Print("\n" + strings.Repeat("=", 70) + "\n")
@@ -320,9 +683,15 @@ func (at *AutoTrader) runCycle() error {
 
 	// 创建决策记录
 	record := &logger.DecisionRecord{
-		ExecutionLog: []string{},
-		Success:      true,
+		ExecutionLog:  []string{},
+		Success:       true,
+		ManualTrigger: manual,
+	}
+	if manual {
+		record.ExecutionLog = append(record.ExecutionLog, "👆 手动触发的决策周期")
 	}
+	// 周期结束（包括提前返回）时推送事件
+	defer at.publishCycleCompleted(record)
 
 	// 1. 检查是否需要停止交易
 	if time.Now().Before(at.stopUntil) {
@@ -330,7 +699,7 @@ func (at *AutoTrader) runCycle() error {
 		log.Printf("⏸ 风险控制：暂停交易中，剩余 %.0f 分钟", remaining.Minutes())
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("风险控制暂停中，剩余 %.0f 分钟", remaining.Minutes())
-		at.decisionLogger.LogDecision(record)
+		at.saveCycleRecord(record, cycleStart)
 		return nil
 	}
 
@@ -353,7 +722,7 @@ func (at *AutoTrader) runCycle() error {
 	if err != nil {
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("构建交易上下文失败: %v", err)
-		at.decisionLogger.LogDecision(record)
+		at.saveCycleRecord(record, cycleStart)
 		return fmt.Errorf("构建交易上下文失败: %w", err)
 	}
 
@@ -385,13 +754,59 @@ func (at *AutoTrader) runCycle() error {
 	for _, coin := range ctx.CandidateCoins {
 		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
 	}
+	recordSignalSourceErrors(record, ctx)
 
 	log.Printf("📊 账户净值: %.2f USDT | 可用: %.2f USDT | 持仓: %d",
 		ctx.Account.TotalEquity, ctx.Account.AvailableBalance, ctx.Account.PositionCount)
 
-	// 5. 调用AI获取完整决策
+	// 4.5 日亏损熔断检查（熔断且无持仓时无需请求AI）
+	at.checkDailyLossBreaker(time.Now(), ctx.Account.TotalEquity, record)
+	if at.IsCircuitBroken() && ctx.Account.PositionCount == 0 {
+		log.Printf("⛔ [%s] 日亏损熔断中且无持仓，跳过本周期AI决策", at.name)
+		record.ExecutionLog = append(record.ExecutionLog, "⛔ 日亏损熔断中且无持仓，跳过本周期AI决策")
+		at.saveCycleRecord(record, cycleStart)
+		return nil
+	}
+
+	// 5. 检查AI月度预算（阈值告警 / 超预算切换备用模型）
+	at.enforceSpendBudget(record)
+
+	// 5.5 A/B实验：为本周期分配模板分组（分组标签写入决策记录，之后关闭实验不影响历史归属）
+	if variant, template := at.chooseExperimentVariant(); template != nil {
+		ctx.PromptTemplate = template
+		record.PromptVariant = variant
+		record.ExecutionLog = append(record.ExecutionLog,
+			fmt.Sprintf("🧪 A/B实验: %s组 (模板 %s v%d)", variant, template.Name, template.Version))
+	}
+
+	// 5.8 决策前扩展点（可跳过本周期）
+	if skip, reason := at.runPreDecisionHook(ctx, manual); skip {
+		log.Printf("🔌 [%s] 决策前扩展点跳过本周期: %s", at.name, reason)
+		record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔌 决策前扩展点跳过本周期: %s", reason))
+		at.saveCycleRecord(record, cycleStart)
+		return nil
+	}
+
+	// 6. 调用AI获取完整决策
 	log.Printf("🤖 正在请求AI分析并决策... [模板: %s]", at.systemPromptTemplate)
-	decision, err := decision.GetFullDecisionWithCustomPrompt(ctx, at.mcpClient, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	// 主模型出现服务商故障时本周期改用备用模型，usedClient 为实际产生决策的模型
+	aiStart := time.Now()
+	decision, usedClient, err := at.requestDecision(ctx, record)
+	recordAIUsage(record, usedClient, decision != nil, time.Since(aiStart))
+
+	// AI调用成功返回（即使解析失败）时记录token用量和花费
+	if decision != nil {
+		at.recordAISpend(record, usedClient)
+	}
+
+	// 记录AI调用尝试次数（>1 表示发生了重试，用于观察服务商稳定性）
+	if reporter, ok := usedClient.(mcp.RetryReporter); ok {
+		record.AIAttempts = reporter.LastAttempts()
+		if record.AIAttempts > 1 {
+			record.ExecutionLog = append(record.ExecutionLog,
+				fmt.Sprintf("AI调用重试: 共尝试 %d 次", record.AIAttempts))
+		}
+	}
 
 	if decision != nil && decision.AIRequestDurationMs > 0 {
 		record.AIRequestDurationMs = decision.AIRequestDurationMs
@@ -409,9 +824,28 @@ func (at *AutoTrader) runCycle() error {
 			decisionJSON, _ := json.MarshalIndent(decision.Decisions, "", "  ")
 			record.DecisionJSON = string(decisionJSON)
 		}
+		record.PromptTemplate = decision.PromptTemplate
+		record.PromptTemplateVersion = decision.PromptTemplateVersion
+		recordPromptTruncation(record, decision.PromptTruncation)
+		if decision.Repaired && decision.Repair != nil {
+			record.Repaired = true
+			record.Repair = &logger.DecisionRepair{
+				OriginalOutput:   decision.Repair.OriginalOutput,
+				ValidationErrors: decision.Repair.ValidationErrors,
+				RepairedOutput:   decision.Repair.RepairedOutput,
+				Success:          decision.Repair.Success,
+				Error:            decision.Repair.Error,
+			}
+			if decision.Repair.Success {
+				record.ExecutionLog = append(record.ExecutionLog, "🔧 AI输出未通过校验，修复请求成功")
+			} else {
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔧 AI输出修复失败，本周期观望: %s", decision.Repair.Error))
+			}
+		}
 	}
 
 	if err != nil {
+		at.recordAIError(err)
 		record.Success = false
 		record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
 
@@ -432,7 +866,8 @@ func (at *AutoTrader) runCycle() error {
 			}
 		}
 
-		at.decisionLogger.LogDecision(record)
+		at.runPostDecisionHook(record)
+		at.saveCycleRecord(record, cycleStart)
 		return fmt.Errorf("获取AI决策失败: %w", err)
 	}
 
@@ -473,7 +908,11 @@ func (at *AutoTrader) runCycle() error {
 	}
 	log.Println()
 
-	// 执行决策并记录结果
+	// 执行决策并记录结果（开仓被风控缩减/拒绝的原因反馈给下一周期的AI）
+	var riskFeedback []string
+	guardrails := at.getGuardrails()
+	guardrailState := newGuardrailState(ctx, time.Now())
+	preOrder := at.newPreOrderGate(ctx)
 	for _, d := range sortedDecisions {
 		actionRecord := logger.DecisionAction{
 			Action:    d.Action,
@@ -485,24 +924,108 @@ func (at *AutoTrader) runCycle() error {
 			Success:   false,
 		}
 
-		if err := at.executeDecisionWithRecord(&d, &actionRecord); err != nil {
+		// 熔断期间只允许平仓/持有，拒绝开新仓
+		if (d.Action == "open_long" || d.Action == "open_short") && at.IsCircuitBroken() {
+			log.Printf("⛔ 日亏损熔断中，跳过开仓 (%s %s)", d.Symbol, d.Action)
+			actionRecord.Error = "日亏损熔断中，禁止开新仓"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⛔ %s %s 已跳过: 日亏损熔断中", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		// 同币种上次亏损平仓后的冷却期内拒绝开仓（原因反馈给下一周期的AI）
+		if d.Action == "open_long" || d.Action == "open_short" {
+			if remaining := at.lossCooldownRemaining(d.Symbol, time.Now()); remaining > 0 {
+				reason := fmt.Sprintf("上次平仓亏损，冷却中（剩余 %d 分钟）", cooldownMinutes(remaining))
+				log.Printf("🧊 %s %s 已跳过: %s", d.Symbol, d.Action, reason)
+				actionRecord.Error = reason
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🧊 %s %s 已跳过: %s", d.Symbol, d.Action, reason))
+				riskFeedback = append(riskFeedback, fmt.Sprintf("%s %s 被拒绝: %s", d.Symbol, d.Action, reason))
+				record.Decisions = append(record.Decisions, actionRecord)
+				continue
+			}
+		}
+
+		// 排除的币种不应出现在决策中（提示词未提及），AI仍返回时拒绝执行（允许平掉排除前已有的持仓）
+		if d.Action != "close_long" && d.Action != "close_short" && at.isExcludedSymbol(d.Symbol) {
+			log.Printf("🚫 AI返回了排除的币种，已拒绝: %s %s", d.Symbol, d.Action)
+			actionRecord.Error = "币种在排除列表中"
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚫 %s %s 已拒绝: 币种在排除列表中", d.Symbol, d.Action))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		// 护栏规则：按顺序拒绝或调整开仓决策，每次干预都写入决策记录
+		interventions, vetoed := guardrails.Evaluate(&d, guardrailState)
+		record.Guardrails = append(record.Guardrails, interventions...)
+		for _, iv := range interventions {
+			if iv.Verdict == GuardrailClamp {
+				actionRecord.Leverage = d.Leverage
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚧 %s %s [%s] %s", d.Symbol, d.Action, iv.Rule, iv.Reason))
+				continue
+			}
+			log.Printf("🚧 %s %s 被护栏规则 %s 拒绝: %s", d.Symbol, d.Action, iv.Rule, iv.Reason)
+			actionRecord.Error = fmt.Sprintf("护栏规则 %s: %s", iv.Rule, iv.Reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🚧 %s %s 已跳过: [%s] %s", d.Symbol, d.Action, iv.Rule, iv.Reason))
+			riskFeedback = append(riskFeedback, fmt.Sprintf("%s %s 被拒绝: %s", d.Symbol, d.Action, iv.Reason))
+		}
+		if vetoed {
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		// 下单扩展点（部署方的自定义风控 / 合规审批），可缩减或拒绝订单
+		reason, interventions := preOrder.check(&d)
+		record.Guardrails = append(record.Guardrails, interventions...)
+		for _, iv := range interventions {
+			switch iv.Verdict {
+			case GuardrailClamp:
+				actionRecord.Action = d.Action
+				actionRecord.Leverage = d.Leverage
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔌 %s %s [%s] %s", d.Symbol, d.Action, iv.Rule, iv.Reason))
+			case GuardrailAllowOnFailure:
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("⚠️ %s %s [%s] 扩展点不可用，未经检查放行: %s", d.Symbol, d.Action, iv.Rule, iv.Reason))
+			}
+		}
+		if reason != "" {
+			actionRecord.Error = fmt.Sprintf("下单扩展点拒绝: %s", reason)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🔌 %s %s 已拒绝: %s", d.Symbol, d.Action, reason))
+			riskFeedback = append(riskFeedback, fmt.Sprintf("%s %s 被拒绝: %s", d.Symbol, d.Action, reason))
+			record.Decisions = append(record.Decisions, actionRecord)
+			continue
+		}
+
+		execStart := time.Now()
+		err := at.executeDecisionWithRecord(&d, &actionRecord)
+		record.ExchangeExecutionMs += time.Since(execStart).Milliseconds()
+		if err != nil {
 			log.Printf("❌ 执行决策失败 (%s %s): %v", d.Symbol, d.Action, err)
+			metrics.OrderErrors.Inc(at.exchange, d.Action)
 			actionRecord.Error = err.Error()
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ %s %s 失败: %v", d.Symbol, d.Action, err))
+			if isExposureLimitError(err) {
+				riskFeedback = append(riskFeedback, fmt.Sprintf("%s %s 被拒绝: %v", d.Symbol, d.Action, err))
+			}
 		} else {
 			actionRecord.Success = true
+			updateGuardrailState(guardrailState, &d)
 			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ %s %s 成功", d.Symbol, d.Action))
+			if actionRecord.RiskNote != "" {
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🛡️ %s", actionRecord.RiskNote))
+				riskFeedback = append(riskFeedback, fmt.Sprintf("%s %s: %s", d.Symbol, d.Action, actionRecord.RiskNote))
+			}
+			at.publishPositionEvent(&actionRecord)
 			// 成功执行后短暂延迟
 			time.Sleep(1 * time.Second)
 		}
 
 		record.Decisions = append(record.Decisions, actionRecord)
 	}
+	at.riskFeedback = riskFeedback
+	at.runPostDecisionHook(record)
 
 	// 9. 保存决策记录
-	if err := at.decisionLogger.LogDecision(record); err != nil {
-		log.Printf("⚠ 保存决策记录失败: %v", err)
-	}
+	at.saveCycleRecord(record, cycleStart)
 
 	return nil
 }
@@ -541,9 +1064,11 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 
 	var positionInfos []decision.PositionInfo
 	totalMarginUsed := 0.0
+	fundingFees, _ := at.fundingFees()
 
 	// 当前持仓的key集合（用于清理已平仓的记录）
 	currentPositionKeys := make(map[string]bool)
+	observedPnL := make(map[string]float64) // symbol_side -> 未实现盈亏（亏损冷却用）
 
 	for _, pos := range positions {
 		symbol := pos["symbol"].(string)
@@ -577,6 +1102,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		// 跟踪持仓首次出现时间
 		posKey := symbol + "_" + side
 		currentPositionKeys[posKey] = true
+		observedPnL[posKey] = unrealizedPnl
 		if _, exists := at.positionFirstSeenTime[posKey]; !exists {
 			// 新持仓，记录当前时间
 			at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
@@ -601,6 +1127,7 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			LiquidationPrice: liquidationPrice,
 			MarginUsed:       marginUsed,
 			UpdateTime:       updateTime,
+			FundingFees:      sumFundingFees(fundingFees, symbol, updateTime, 0),
 		})
 	}
 
@@ -610,6 +1137,8 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			delete(at.positionFirstSeenTime, key)
 		}
 	}
+	// 记录消失的持仓的平仓结果（交易所止盈止损触发等，用于亏损冷却）
+	at.observePositions(observedPnL, time.Now())
 
 	// 3. 获取交易员的候选币种池
 	candidateCoins, err := at.getCandidateCoins()
@@ -638,13 +1167,25 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 		performance = nil
 	}
 
-	// 6. 构建上下文
+	// 6. 持仓和候选币种的资金费率（当前交易所）
+	fundingSymbols := make([]string, 0, len(positionInfos)+len(candidateCoins))
+	for _, pos := range positionInfos {
+		fundingSymbols = append(fundingSymbols, pos.Symbol)
+	}
+	for _, coin := range candidateCoins {
+		fundingSymbols = append(fundingSymbols, coin.Symbol)
+	}
+
+	// 7. 构建上下文
+	btcEthLeverage, altcoinLeverage := at.getLeverage()
 	ctx := &decision.Context{
 		CurrentTime:     time.Now().Format("2006-01-02 15:04:05"),
 		RuntimeMinutes:  int(time.Since(at.startTime).Minutes()),
 		CallCount:       at.callCount,
-		BTCETHLeverage:  at.config.BTCETHLeverage,  // 使用配置的杠杆倍数
-		AltcoinLeverage: at.config.AltcoinLeverage, // 使用配置的杠杆倍数
+		BTCETHLeverage:  btcEthLeverage,  // 使用配置的杠杆倍数
+		AltcoinLeverage: altcoinLeverage, // 使用配置的杠杆倍数
+		UserID:          at.userID,
+		PromptTemplate:  at.refreshSystemPromptPin(),
 		Account: decision.AccountInfo{
 			TotalEquity:      totalEquity,
 			AvailableBalance: availableBalance,
@@ -654,15 +1195,27 @@ func (at *AutoTrader) buildTradingContext() (*decision.Context, error) {
 			MarginUsed:       totalMarginUsed,
 			MarginUsedPct:    marginUsedPct,
 			PositionCount:    len(positionInfos),
+			FundingFees:      sumFundingFees(fundingFees, "", 0, 0),
 		},
-		Positions:      positionInfos,
-		CandidateCoins: candidateCoins,
-		Performance:    performance, // 添加历史表现分析
+		Positions:         positionInfos,
+		CandidateCoins:    candidateCoins,
+		Performance:       performance, // 添加历史表现分析
+		RiskLimits:        at.riskLimitsForPrompt(),
+		RiskFeedback:      at.riskFeedback,
+		FundingRates:      at.fundingRatesForContext(fundingSymbols),
+		PromptTokenBudget: decision.PromptTokenBudget(at.config.AIModel, at.config.AIModelParams.ContextTokens, at.config.AIModelParams.MaxTokens),
+		MarketSource:      at.marketSource,
 	}
+	ctx.SignalSourceErrors = at.signalSourceErrors
 
 	return ctx, nil
 }
 
+// getMarketData 从交易员所在交易所的行情来源获取市场数据
+func (at *AutoTrader) getMarketData(symbol string) (*market.Data, error) {
+	return market.GetFrom(at.marketSource, symbol)
+}
+
 // executeDecisionWithRecord 执行AI决策并记录详细信息
 func (at *AutoTrader) executeDecisionWithRecord(decision *decision.Decision, actionRecord *logger.DecisionAction) error {
 	switch decision.Action {
@@ -693,17 +1246,20 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	log.Printf("  📈 开多仓: %s", decision.Symbol)
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
-	if err == nil {
+	positions, positionsErr := at.trader.GetPositions()
+	if positionsErr == nil {
 		for _, pos := range positions {
 			if pos["symbol"] == decision.Symbol && pos["side"] == "long" {
 				return fmt.Errorf("❌ %s 已有多仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_long 决策", decision.Symbol)
 			}
 		}
+		if err := at.checkOppositePosition(decision.Symbol, "long", positions); err != nil {
+			return err
+		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -723,6 +1279,12 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 		availableBalance = avail
 	}
 
+	// 🛡️ 风控：单币种仓位价值和总敞口上限（计入已有持仓，超限时缩减或拒绝）
+	positionSizeUSD, err := at.applyExposureLimits(decision.Symbol, decision.PositionSizeUSD, positions, positionsErr, balance, actionRecord)
+	if err != nil {
+		return err
+	}
+
 	// 手续费率（Taker费率 0.04% + 安全余量 0.01% = 0.05%）
 	feeRate := 0.0005
 	
@@ -734,11 +1296,11 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	maxPositionSize := availableBalance / (1.0/float64(decision.Leverage) + feeRate)
 	
 	// 如果 AI 要求的仓位超过可用余额，自动调整到最大可用仓位的 98%（留 2% 安全余量）
-	adjustedPositionSize := decision.PositionSizeUSD
-	if decision.PositionSizeUSD > maxPositionSize {
+	adjustedPositionSize := positionSizeUSD
+	if positionSizeUSD > maxPositionSize {
 		adjustedPositionSize = maxPositionSize * 0.98
 		log.Printf("  ⚠️  AI要求仓位 %.2f USDT 超过可用余额，自动调整为 %.2f USDT（%.1f%%）",
-			decision.PositionSizeUSD, adjustedPositionSize, (adjustedPositionSize/decision.PositionSizeUSD)*100)
+			positionSizeUSD, adjustedPositionSize, (adjustedPositionSize/positionSizeUSD)*100)
 	}
 	
 	// 重新计算数量和保证金
@@ -759,17 +1321,25 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 			totalRequired, requiredMargin, estimatedFee, availableBalance)
 	}
 
+	// 按交易所规则取整数量，低于最小名义价值时拒绝开仓（写入决策日志，避免交易所返回难懂的错误）
+	quantity, err = at.applySymbolFilter(decision.Symbol, quantity, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+
 	// 设置仓位模式
 	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
 		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
 		// 继续执行，不影响交易
 	}
 
-	// 开仓
-	order, err := at.trader.OpenLong(decision.Symbol, quantity, decision.Leverage)
+	// 开仓（按交易员配置使用市价或限价，记录成交均价和滑点）
+	order, filledQty, err := at.openPosition(decision.Symbol, "LONG", quantity, decision.Leverage, actionRecord)
 	if err != nil {
 		return err
 	}
+	quantity = filledQty
+	actionRecord.Quantity = quantity
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -782,13 +1352,8 @@ func (at *AutoTrader) executeOpenLongWithRecord(decision *decision.Decision, act
 	posKey := decision.Symbol + "_long"
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "LONG", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "LONG", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	}
+	// 设置止损止盈（只减仓触发单，记录订单ID以便后续调整/撤销）
+	at.attachProtectiveOrders(decision.Symbol, "LONG", quantity, actionRecord.Price, decision.StopLoss, decision.TakeProfit)
 
 	return nil
 }
@@ -798,17 +1363,20 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	log.Printf("  📉 开空仓: %s", decision.Symbol)
 
 	// ⚠️ 关键：检查是否已有同币种同方向持仓，如果有则拒绝开仓（防止仓位叠加超限）
-	positions, err := at.trader.GetPositions()
-	if err == nil {
+	positions, positionsErr := at.trader.GetPositions()
+	if positionsErr == nil {
 		for _, pos := range positions {
 			if pos["symbol"] == decision.Symbol && pos["side"] == "short" {
 				return fmt.Errorf("❌ %s 已有空仓，拒绝开仓以防止仓位叠加超限。如需换仓，请先给出 close_short 决策", decision.Symbol)
 			}
 		}
+		if err := at.checkOppositePosition(decision.Symbol, "short", positions); err != nil {
+			return err
+		}
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -828,6 +1396,12 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 		availableBalance = avail
 	}
 
+	// 🛡️ 风控：单币种仓位价值和总敞口上限（计入已有持仓，超限时缩减或拒绝）
+	positionSizeUSD, err := at.applyExposureLimits(decision.Symbol, decision.PositionSizeUSD, positions, positionsErr, balance, actionRecord)
+	if err != nil {
+		return err
+	}
+
 	// 手续费率（Taker费率 0.04% + 安全余量 0.01% = 0.05%）
 	feeRate := 0.0005
 	
@@ -835,11 +1409,11 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	maxPositionSize := availableBalance / (1.0/float64(decision.Leverage) + feeRate)
 	
 	// 如果 AI 要求的仓位超过可用余额，自动调整到最大可用仓位的 98%（留 2% 安全余量）
-	adjustedPositionSize := decision.PositionSizeUSD
-	if decision.PositionSizeUSD > maxPositionSize {
+	adjustedPositionSize := positionSizeUSD
+	if positionSizeUSD > maxPositionSize {
 		adjustedPositionSize = maxPositionSize * 0.98
 		log.Printf("  ⚠️  AI要求仓位 %.2f USDT 超过可用余额，自动调整为 %.2f USDT（%.1f%%）",
-			decision.PositionSizeUSD, adjustedPositionSize, (adjustedPositionSize/decision.PositionSizeUSD)*100)
+			positionSizeUSD, adjustedPositionSize, (adjustedPositionSize/positionSizeUSD)*100)
 	}
 	
 	// 重新计算数量和保证金
@@ -860,17 +1434,25 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 			totalRequired, requiredMargin, estimatedFee, availableBalance)
 	}
 
+	// 按交易所规则取整数量，低于最小名义价值时拒绝开仓（写入决策日志，避免交易所返回难懂的错误）
+	quantity, err = at.applySymbolFilter(decision.Symbol, quantity, marketData.CurrentPrice)
+	if err != nil {
+		return err
+	}
+
 	// 设置仓位模式
 	if err := at.trader.SetMarginMode(decision.Symbol, at.config.IsCrossMargin); err != nil {
 		log.Printf("  ⚠️ 设置仓位模式失败: %v", err)
 		// 继续执行，不影响交易
 	}
 
-	// 开仓
-	order, err := at.trader.OpenShort(decision.Symbol, quantity, decision.Leverage)
+	// 开仓（按交易员配置使用市价或限价，记录成交均价和滑点）
+	order, filledQty, err := at.openPosition(decision.Symbol, "SHORT", quantity, decision.Leverage, actionRecord)
 	if err != nil {
 		return err
 	}
+	quantity = filledQty
+	actionRecord.Quantity = quantity
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -883,13 +1465,8 @@ func (at *AutoTrader) executeOpenShortWithRecord(decision *decision.Decision, ac
 	posKey := decision.Symbol + "_short"
 	at.positionFirstSeenTime[posKey] = time.Now().UnixMilli()
 
-	// 设置止损止盈
-	if err := at.trader.SetStopLoss(decision.Symbol, "SHORT", quantity, decision.StopLoss); err != nil {
-		log.Printf("  ⚠ 设置止损失败: %v", err)
-	}
-	if err := at.trader.SetTakeProfit(decision.Symbol, "SHORT", quantity, decision.TakeProfit); err != nil {
-		log.Printf("  ⚠ 设置止盈失败: %v", err)
-	}
+	// 设置止损止盈（只减仓触发单，记录订单ID以便后续调整/撤销）
+	at.attachProtectiveOrders(decision.Symbol, "SHORT", quantity, actionRecord.Price, decision.StopLoss, decision.TakeProfit)
 
 	return nil
 }
@@ -899,7 +1476,7 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	log.Printf("  🔄 平多仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -910,6 +1487,9 @@ func (at *AutoTrader) executeCloseLongWithRecord(decision *decision.Decision, ac
 	if err != nil {
 		return err
 	}
+	recordFill(actionRecord, false, orderAvgPrice(order))
+	at.clearProtectiveOrders(decision.Symbol, "long")
+	at.recordPositionClose(decision.Symbol, "long", nil, time.Now())
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -925,7 +1505,7 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	log.Printf("  🔄 平空仓: %s", decision.Symbol)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -936,6 +1516,9 @@ func (at *AutoTrader) executeCloseShortWithRecord(decision *decision.Decision, a
 	if err != nil {
 		return err
 	}
+	recordFill(actionRecord, true, orderAvgPrice(order))
+	at.clearProtectiveOrders(decision.Symbol, "short")
+	at.recordPositionClose(decision.Symbol, "short", nil, time.Now())
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -951,7 +1534,7 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 	log.Printf("  🎯 调整止损: %s → %.2f", decision.Symbol, decision.NewStopLoss)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -1012,17 +1595,10 @@ func (at *AutoTrader) executeUpdateStopLossWithRecord(decision *decision.Decisio
 		log.Printf("  🚨 建议：手动平掉其中一个方向的持仓，或检查系统是否有BUG")
 	}
 
-	// 取消旧的止损单（只删除止损单，不影响止盈单）
-	// 注意：如果存在双向持仓，这会删除两个方向的止损单
-	if err := at.trader.CancelStopLossOrders(decision.Symbol); err != nil {
-		log.Printf("  ⚠ 取消旧止损单失败: %v", err)
-		// 不中断执行，继续设置新止损
-	}
-
-	// 调用交易所 API 修改止损
+	// 撤掉旧的止损单并挂出新价格（只影响止损单，不影响止盈单）
+	// 注意：未跟踪到订单ID时按类型撤单，如果存在双向持仓，这会删除两个方向的止损单
 	quantity := math.Abs(positionAmt)
-	err = at.trader.SetStopLoss(decision.Symbol, positionSide, quantity, decision.NewStopLoss)
-	if err != nil {
+	if err := at.replaceProtectiveLeg(decision.Symbol, positionSide, ProtectiveStopLoss, quantity, decision.NewStopLoss); err != nil {
 		return fmt.Errorf("修改止损失败: %w", err)
 	}
 
@@ -1035,7 +1611,7 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 	log.Printf("  🎯 调整止盈: %s → %.2f", decision.Symbol, decision.NewTakeProfit)
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -1096,17 +1672,10 @@ func (at *AutoTrader) executeUpdateTakeProfitWithRecord(decision *decision.Decis
 		log.Printf("  🚨 建议：手动平掉其中一个方向的持仓，或检查系统是否有BUG")
 	}
 
-	// 取消旧的止盈单（只删除止盈单，不影响止损单）
-	// 注意：如果存在双向持仓，这会删除两个方向的止盈单
-	if err := at.trader.CancelTakeProfitOrders(decision.Symbol); err != nil {
-		log.Printf("  ⚠ 取消旧止盈单失败: %v", err)
-		// 不中断执行，继续设置新止盈
-	}
-
-	// 调用交易所 API 修改止盈
+	// 撤掉旧的止盈单并挂出新价格（只影响止盈单，不影响止损单）
+	// 注意：未跟踪到订单ID时按类型撤单，如果存在双向持仓，这会删除两个方向的止盈单
 	quantity := math.Abs(positionAmt)
-	err = at.trader.SetTakeProfit(decision.Symbol, positionSide, quantity, decision.NewTakeProfit)
-	if err != nil {
+	if err := at.replaceProtectiveLeg(decision.Symbol, positionSide, ProtectiveTakeProfit, quantity, decision.NewTakeProfit); err != nil {
 		return fmt.Errorf("修改止盈失败: %w", err)
 	}
 
@@ -1124,7 +1693,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	}
 
 	// 获取当前价格
-	marketData, err := market.Get(decision.Symbol)
+	marketData, err := at.getMarketData(decision.Symbol)
 	if err != nil {
 		return err
 	}
@@ -1203,6 +1772,7 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 	if err != nil {
 		return fmt.Errorf("部分平仓失败: %w", err)
 	}
+	recordFill(actionRecord, positionSide == "SHORT", orderAvgPrice(order))
 
 	// 记录订单ID
 	if orderID, ok := order["orderId"].(int64); ok {
@@ -1214,25 +1784,32 @@ func (at *AutoTrader) executePartialCloseWithRecord(decision *decision.Decision,
 
 	// ✅ Step 4: 恢复止盈止损（防止剩余仓位裸奔）
 	// 重要：币安等交易所在部分平仓后会自动取消原有的 TP/SL 订单（因为数量不匹配）
-	// 如果 AI 提供了新的止损止盈价格，则为剩余仓位重新设置保护
-	if decision.NewStopLoss > 0 {
-		log.Printf("  → 为剩余仓位 %.4f 恢复止损单: %.2f", remainingQuantity, decision.NewStopLoss)
-		err = at.trader.SetStopLoss(decision.Symbol, positionSide, remainingQuantity, decision.NewStopLoss)
-		if err != nil {
+	// 如果 AI 提供了新的止损止盈价格则使用新价格，否则沿用当前跟踪的价格
+	trackedStopLoss, trackedTakeProfit := at.protectionLevels(decision.Symbol, positionSide)
+	newStopLoss, newTakeProfit := decision.NewStopLoss, decision.NewTakeProfit
+	if newStopLoss <= 0 {
+		newStopLoss = trackedStopLoss
+	}
+	if newTakeProfit <= 0 {
+		newTakeProfit = trackedTakeProfit
+	}
+
+	if newStopLoss > 0 {
+		log.Printf("  → 为剩余仓位 %.4f 恢复止损单: %.2f", remainingQuantity, newStopLoss)
+		if err := at.replaceProtectiveLeg(decision.Symbol, positionSide, ProtectiveStopLoss, remainingQuantity, newStopLoss); err != nil {
 			log.Printf("  ⚠️ 恢复止损失败: %v（不影响平仓结果）", err)
 		}
 	}
 
-	if decision.NewTakeProfit > 0 {
-		log.Printf("  → 为剩余仓位 %.4f 恢复止盈单: %.2f", remainingQuantity, decision.NewTakeProfit)
-		err = at.trader.SetTakeProfit(decision.Symbol, positionSide, remainingQuantity, decision.NewTakeProfit)
-		if err != nil {
+	if newTakeProfit > 0 {
+		log.Printf("  → 为剩余仓位 %.4f 恢复止盈单: %.2f", remainingQuantity, newTakeProfit)
+		if err := at.replaceProtectiveLeg(decision.Symbol, positionSide, ProtectiveTakeProfit, remainingQuantity, newTakeProfit); err != nil {
 			log.Printf("  ⚠️ 恢复止盈失败: %v（不影响平仓结果）", err)
 		}
 	}
 
-	// 如果 AI 没有提供新的止盈止损，记录警告
-	if decision.NewStopLoss <= 0 && decision.NewTakeProfit <= 0 {
+	// 既没有新价格也没有跟踪到原有止盈止损，记录警告
+	if newStopLoss <= 0 && newTakeProfit <= 0 {
 		log.Printf("  ⚠️⚠️⚠️ 警告: 部分平仓后AI未提供新的止盈止损价格")
 		log.Printf("  → 剩余仓位 %.4f (价值 %.2f USDT) 目前没有止盈止损保护", remainingQuantity, remainingValue)
 		log.Printf("  → 建议: 在 partial_close 决策中包含 new_stop_loss 和 new_take_profit 字段")
@@ -1251,6 +1828,18 @@ func (at *AutoTrader) GetName() string {
 	return at.name
 }
 
+// GetUserID 获取trader所属用户ID
+func (at *AutoTrader) GetUserID() string {
+	return at.userID
+}
+
+// IsRunning 是否正在运行
+func (at *AutoTrader) IsRunning() bool {
+	at.mu.RLock()
+	defer at.mu.RUnlock()
+	return at.isRunning
+}
+
 // GetAIModel 获取AI模型
 func (at *AutoTrader) GetAIModel() string {
 	return at.aiModel
@@ -1274,6 +1863,8 @@ func (at *AutoTrader) SetOverrideBasePrompt(override bool) {
 // SetSystemPromptTemplate 设置系统提示词模板
 func (at *AutoTrader) SetSystemPromptTemplate(templateName string) {
 	at.systemPromptTemplate = templateName
+	// 模板名称变化后重新固定新模板的最新版本
+	at.PinPromptTemplate()
 }
 
 // GetSystemPromptTemplate 获取当前系统提示词模板名称
@@ -1281,6 +1872,105 @@ func (at *AutoTrader) GetSystemPromptTemplate() string {
 	return at.systemPromptTemplate
 }
 
+// IsPublic 是否在公开排行榜/竞赛接口中展示
+func (at *AutoTrader) IsPublic() bool {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.config.IsPublic
+}
+
+// SetPublic 设置是否在公开排行榜中展示
+func (at *AutoTrader) SetPublic(public bool) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.config.IsPublic = public
+}
+
+// SetScanInterval 修改扫描间隔，运行中立即重置主循环定时器（下一轮在新间隔后执行）
+func (at *AutoTrader) SetScanInterval(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	at.settingsMu.Lock()
+	changed := at.config.ScanInterval != interval
+	at.config.ScanInterval = interval
+	at.settingsMu.Unlock()
+	if !changed {
+		return
+	}
+	limitAIRetryBudget(at.mcpClient, interval)
+
+	// 只保留最新一次修改，主循环未及时读取时覆盖旧值
+	select {
+	case <-at.scanIntervalCh:
+	default:
+	}
+	select {
+	case at.scanIntervalCh <- interval:
+	default:
+	}
+}
+
+// SetLeverage 修改BTC/ETH和山寨币杠杆，从下一个决策周期开始生效
+func (at *AutoTrader) SetLeverage(btcEthLeverage, altcoinLeverage int) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	if btcEthLeverage > 0 {
+		at.config.BTCETHLeverage = btcEthLeverage
+	}
+	if altcoinLeverage > 0 {
+		at.config.AltcoinLeverage = altcoinLeverage
+	}
+}
+
+// SetTradingSymbols 修改交易币种列表，为空时回退到默认币种，从下一个决策周期开始生效
+func (at *AutoTrader) SetTradingSymbols(symbols []string) {
+	coins := make([]string, 0, len(symbols))
+	for _, symbol := range symbols {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			coins = append(coins, symbol)
+		}
+	}
+	at.settingsMu.Lock()
+	at.tradingCoins = coins
+	at.invalidSymbols = nil // 新币种列表已在保存前校验
+	at.settingsMu.Unlock()
+
+	if at.IsRunning() {
+		market.RetainSymbols(at.id, coins)
+	}
+}
+
+// limitAIRetryBudget AI调用重试的总时间预算不超过扫描间隔的一半，避免重试拖到下一个周期
+func limitAIRetryBudget(client mcp.AIClient, interval time.Duration) {
+	setter, ok := client.(interface{ SetRetryBudget(time.Duration) })
+	if !ok || interval <= 0 {
+		return
+	}
+	setter.SetRetryBudget(min(mcp.DefaultRetryBudget, interval/2))
+}
+
+// getScanInterval 读取当前扫描间隔
+func (at *AutoTrader) getScanInterval() time.Duration {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.config.ScanInterval
+}
+
+// getLeverage 读取当前BTC/ETH和山寨币杠杆
+func (at *AutoTrader) getLeverage() (int, int) {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.config.BTCETHLeverage, at.config.AltcoinLeverage
+}
+
+// getTradingCoins 读取当前交易币种列表
+func (at *AutoTrader) getTradingCoins() []string {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.tradingCoins
+}
+
 // GetDecisionLogger 获取决策日志记录器
 func (at *AutoTrader) GetDecisionLogger() logger.IDecisionLogger {
 	return at.decisionLogger
@@ -1301,9 +1991,10 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 	at.mu.RLock()
 	isRunning := at.isRunning
 	startTime := at.startTime
+	supervision := at.supervision
 	at.mu.RUnlock()
 
-	return map[string]interface{}{
+	status := map[string]interface{}{
 		"trader_id":       at.id,
 		"trader_name":     at.name,
 		"ai_model":        at.aiModel,
@@ -1313,40 +2004,114 @@ func (at *AutoTrader) GetStatus() map[string]interface{} {
 		"runtime_minutes": int(time.Since(startTime).Minutes()),
 		"call_count":      at.callCount,
 		"initial_balance": at.initialBalance,
-		"scan_interval":   at.config.ScanInterval.String(),
+		"scan_interval":   at.getScanInterval().String(),
 		"stop_until":      at.stopUntil.Format(time.RFC3339),
 		"last_reset_time": at.lastResetTime.Format(time.RFC3339),
 		"ai_provider":     aiProvider,
+		"testnet":         at.IsTestnet(),
+		"restart_count":   supervision.restartCount,
+		"restart_pending": !isRunning && supervision.stopRequestCh != nil && supervision.restartCount > 0,
+		"last_error":      supervision.lastError,
+	}
+	if pinned := at.getPinnedPromptTemplate(); pinned != nil {
+		status["prompt_template"] = pinned.Name
+		status["prompt_template_version"] = pinned.Version
+		status["prompt_template_source"] = pinned.Source
 	}
+	if experiment := at.experimentStatus(); experiment != nil {
+		status["experiment"] = experiment
+	}
+	status["active_ai_model"] = at.activeAIModel(time.Now())
+	if fallback := at.aiFallbackStatus(time.Now()); fallback != nil {
+		status["ai_fallback"] = fallback
+	}
+	if ensemble := at.ensembleStatus(); ensemble != nil {
+		status["ensemble"] = ensemble
+	}
+	if !supervision.lastErrorAt.IsZero() {
+		status["last_error_at"] = supervision.lastErrorAt.Format(time.RFC3339)
+	}
+
+	// 日亏损熔断状态和剩余亏损额度
+	for key, value := range at.dailyLossStatus() {
+		status[key] = value
+	}
+
+	// 跟踪中的止盈止损单（含软件兜底状态）
+	status["protective_orders"] = at.GetProtectiveOrders()
+
+	// 交易所时钟偏移（支持的交易所才返回）
+	if skew, ok := at.ClockSkew(); ok {
+		status["clock_skew_ms"] = skew.OffsetMs
+		status["clock_skew_warning"] = skew.Warning
+		if skew.SyncError != "" {
+			status["clock_sync_error"] = skew.SyncError
+		}
+	}
+
+	// 交易所请求重试计数（支持的交易所才返回）
+	if stats, ok := at.RetryStats(); ok {
+		status["exchange_retries"] = stats
+	}
+
+	// 持仓模式（双向持仓时同一币种可同时持有多空两条腿）
+	status["hedge_mode"] = at.hedgeMode()
+
+	return status
 }
 
-// GetAccountInfo 获取账户信息（用于API）
-func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
-	balance, err := at.trader.GetBalance()
-	if err != nil {
-		return nil, fmt.Errorf("获取余额失败: %w", err)
+// IsTestnet 是否运行在交易所测试网（模拟资金，盈亏不代表实盘结果）
+func (at *AutoTrader) IsTestnet() bool {
+	switch at.config.Exchange {
+	case "binance":
+		return at.config.BinanceTestnet
+	case "bybit":
+		return at.config.BybitTestnet
+	case "hyperliquid":
+		return at.config.HyperliquidTestnet
 	}
+	return false
+}
 
-	// 获取账户字段
-	totalWalletBalance := 0.0
-	totalUnrealizedProfit := 0.0
-	availableBalance := 0.0
+// RetryStats 获取底层交易所的请求重试计数（交易所不支持时返回 false）
+func (at *AutoTrader) RetryStats() (RetryStats, bool) {
+	reporter, ok := at.trader.(RetryStatsReporter)
+	if !ok {
+		return RetryStats{}, false
+	}
+	return reporter.RetryStats(), true
+}
 
-	if wallet, ok := balance["totalWalletBalance"].(float64); ok {
-		totalWalletBalance = wallet
+// ClockSkew 获取底层交易所的时钟偏移状态（交易所不支持时返回 false）
+func (at *AutoTrader) ClockSkew() (ClockSkewStatus, bool) {
+	reporter, ok := at.trader.(ClockSkewReporter)
+	if !ok {
+		return ClockSkewStatus{}, false
 	}
-	if unrealized, ok := balance["totalUnrealizedProfit"].(float64); ok {
-		totalUnrealizedProfit = unrealized
+	return reporter.ClockSkew(), true
+}
+
+// GetAccountInfo 获取账户信息（旧版 map 结果，由 GetAccount 转换）
+func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
+	account, err := at.GetAccount()
+	if err != nil {
+		return nil, err
 	}
-	if avail, ok := balance["availableBalance"].(float64); ok {
-		availableBalance = avail
+	return account.ToMap(), nil
+}
+
+// GetAccount 获取账户信息（用于API）
+func (at *AutoTrader) GetAccount() (*AccountInfo, error) {
+	balance, err := FetchBalance(at.trader)
+	if err != nil {
+		return nil, fmt.Errorf("获取余额失败: %w", err)
 	}
 
 	// Total Equity = 钱包余额 + 未实现盈亏
-	totalEquity := totalWalletBalance + totalUnrealizedProfit
+	totalEquity := balance.Equity()
 
 	// 获取持仓计算总保证金
-	positions, err := at.trader.GetPositions()
+	positions, err := FetchPositions(at.trader)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
@@ -1354,27 +2119,21 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 	totalMarginUsed := 0.0
 	totalUnrealizedPnLCalculated := 0.0
 	for _, pos := range positions {
-		markPrice := pos["markPrice"].(float64)
-		quantity := pos["positionAmt"].(float64)
-		if quantity < 0 {
-			quantity = -quantity
-		}
-		unrealizedPnl := pos["unRealizedProfit"].(float64)
-		totalUnrealizedPnLCalculated += unrealizedPnl
+		totalUnrealizedPnLCalculated += pos.UnrealizedPnL
 
 		leverage := 10
-		if lev, ok := pos["leverage"].(float64); ok {
-			leverage = int(lev)
+		if int(pos.Leverage) > 0 {
+			leverage = int(pos.Leverage)
 		}
-		marginUsed := (quantity * markPrice) / float64(leverage)
+		marginUsed := pos.Notional() / float64(leverage)
 		totalMarginUsed += marginUsed
 	}
 
 	// 验证未实现盈亏的一致性（API值 vs 从持仓计算）
-	diff := math.Abs(totalUnrealizedProfit - totalUnrealizedPnLCalculated)
+	diff := math.Abs(balance.UnrealizedPnL - totalUnrealizedPnLCalculated)
 	if diff > 0.1 { // 允许0.01 USDT的误差
 		log.Printf("⚠️ 未实现盈亏不一致: API=%.4f, 计算=%.4f, 差异=%.4f",
-			totalUnrealizedProfit, totalUnrealizedPnLCalculated, diff)
+			balance.UnrealizedPnL, totalUnrealizedPnLCalculated, diff)
 	}
 
 	totalPnL := totalEquity - at.initialBalance
@@ -1390,23 +2149,28 @@ func (at *AutoTrader) GetAccountInfo() (map[string]interface{}, error) {
 		marginUsedPct = (totalMarginUsed / totalEquity) * 100
 	}
 
-	return map[string]interface{}{
+	fundingFees, _ := at.fundingFees()
+
+	return &AccountInfo{
 		// 核心字段
-		"total_equity":      totalEquity,           // 账户净值 = wallet + unrealized
-		"wallet_balance":    totalWalletBalance,    // 钱包余额（不含未实现盈亏）
-		"unrealized_profit": totalUnrealizedProfit, // 未实现盈亏（交易所API官方值）
-		"available_balance": availableBalance,      // 可用余额
+		TotalEquity:      totalEquity,
+		WalletBalance:    balance.WalletBalance,
+		UnrealizedProfit: balance.UnrealizedPnL,
+		AvailableBalance: balance.AvailableBalance,
 
 		// 盈亏统计
-		"total_pnl":       totalPnL,          // 总盈亏 = equity - initial
-		"total_pnl_pct":   totalPnLPct,       // 总盈亏百分比
-		"initial_balance": at.initialBalance, // 初始余额
-		"daily_pnl":       at.dailyPnL,       // 日盈亏
+		TotalPnL:       totalPnL,
+		TotalPnLPct:    totalPnLPct,
+		InitialBalance: at.initialBalance,
+		DailyPnL:       at.dailyPnL,
 
 		// 持仓信息
-		"position_count":  len(positions),  // 持仓数量
-		"margin_used":     totalMarginUsed, // 保证金占用
-		"margin_used_pct": marginUsedPct,   // 保证金使用率
+		PositionCount: len(positions),
+		MarginUsed:    totalMarginUsed,
+		MarginUsedPct: marginUsedPct,
+
+		// 资金费
+		FundingFees: sumFundingFees(fundingFees, "", 0, 0),
 	}, nil
 }
 
@@ -1506,9 +2270,26 @@ func sortDecisionsByPriority(decisions []decision.Decision) []decision.Decision
 	return sorted
 }
 
-// getCandidateCoins 获取交易员的候选币种列表
+// getCandidateCoins 获取交易员的候选币种列表（已移除排除的币种）
 func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
-	if len(at.tradingCoins) == 0 {
+	coins, err := at.loadCandidateCoins()
+	if err != nil {
+		return nil, err
+	}
+	return at.filterExcludedCandidates(coins), nil
+}
+
+// loadCandidateCoins 按交易币种/默认币种/币种池获取候选币种
+func (at *AutoTrader) loadCandidateCoins() ([]decision.CandidateCoin, error) {
+	// 启用了信号源时优先使用信号源（全部失败且无缓存时回退到交易币种）
+	var signalCoins []decision.CandidateCoin
+	signalCoins, at.signalSourceErrors = at.signalSourceCandidates()
+	if len(signalCoins) > 0 {
+		return signalCoins, nil
+	}
+
+	tradingCoins := at.getTradingCoins()
+	if len(tradingCoins) == 0 {
 		// 使用数据库配置的默认币种列表
 		var candidateCoins []decision.CandidateCoin
 
@@ -1530,6 +2311,14 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 
 			mergedPool, err := pool.GetMergedCoinPool(ai500Limit)
 			if err != nil {
+				// 币种池不可用时使用24小时成交额最高的币种
+				if volumeTop := decision.VolumeTopCandidates(ai500Limit, func(symbol string) bool {
+					_, invalid := at.invalidSymbol(symbol)
+					return invalid
+				}); len(volumeTop) > 0 {
+					log.Printf("📋 [%s] 币种池不可用(%v)，使用24小时成交额前%d的币种", at.name, err, len(volumeTop))
+					return volumeTop, nil
+				}
 				return nil, fmt.Errorf("获取合并币种池失败: %w", err)
 			}
 
@@ -1549,9 +2338,13 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 	} else {
 		// 使用自定义币种列表
 		var candidateCoins []decision.CandidateCoin
-		for _, coin := range at.tradingCoins {
+		for _, coin := range tradingCoins {
 			// 确保币种格式正确（转为大写USDT交易对）
 			symbol := normalizeSymbol(coin)
+			if reason, invalid := at.invalidSymbol(symbol); invalid {
+				log.Printf("⚠️ [%s] 跳过不可交易的币种 %s: %s", at.name, symbol, reason)
+				continue
+			}
 			candidateCoins = append(candidateCoins, decision.CandidateCoin{
 				Symbol:  symbol,
 				Sources: []string{"custom"}, // 标记为自定义来源
@@ -1559,7 +2352,7 @@ func (at *AutoTrader) getCandidateCoins() ([]decision.CandidateCoin, error) {
 		}
 
 		log.Printf("📋 [%s] 使用自定义币种: %d个币种 %v",
-			at.name, len(candidateCoins), at.tradingCoins)
+			at.name, len(candidateCoins), tradingCoins)
 		return candidateCoins, nil
 	}
 }
@@ -1695,9 +2488,49 @@ func (at *AutoTrader) emergencyClosePosition(symbol, side string) error {
 		return fmt.Errorf("未知的持仓方向: %s", side)
 	}
 
+	at.clearProtectiveOrders(symbol, side)
 	return nil
 }
 
+// PositionCloseResult 一键平仓时单个持仓的处理结果
+type PositionCloseResult struct {
+	Symbol string `json:"symbol"`
+	Side   string `json:"side"`
+	Closed bool   `json:"closed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// CloseAllPositions 市价平掉所有持仓并撤销相关挂单（用于紧急停止），返回每个持仓的处理结果
+func (at *AutoTrader) CloseAllPositions() ([]PositionCloseResult, error) {
+	positions, err := at.trader.GetPositions()
+	if err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	results := make([]PositionCloseResult, 0, len(positions))
+	for _, pos := range positions {
+		symbol, _ := pos["symbol"].(string)
+		side, _ := pos["side"].(string)
+		result := PositionCloseResult{Symbol: symbol, Side: side}
+
+		if err := at.emergencyClosePosition(symbol, side); err != nil {
+			log.Printf("❌ [%s] 紧急平仓失败 (%s %s): %v", at.name, symbol, side, err)
+			result.Error = err.Error()
+		} else {
+			result.Closed = true
+			at.ClearPeakPnLCache(symbol, side)
+			// 手动平仓同样开始亏损冷却计时
+			pnl, _ := pos["unRealizedProfit"].(float64)
+			at.recordPositionClose(symbol, side, &pnl, time.Now())
+			if err := at.trader.CancelAllOrders(symbol); err != nil {
+				log.Printf("⚠️ [%s] 平仓后撤销 %s 挂单失败: %v", at.name, symbol, err)
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
 // GetPeakPnLCache 获取最高收益缓存
 func (at *AutoTrader) GetPeakPnLCache() map[string]float64 {
 	at.peakPnLCacheMutex.RLock()
@@ -383,8 +383,8 @@ func (s *AutoTraderTestSuite) TestGetCandidateCoins() {
 // ============================================================
 
 func (s *AutoTraderTestSuite) TestBuildTradingContext() {
-	// Mock market.Get
-	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+	// Mock market.GetFrom（交易员通过自己的行情来源获取价格）
+	s.patches.ApplyFunc(market.GetFrom, func(_ market.Source, symbol string) (*market.Data, error) {
 		return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
 	})
 
@@ -476,7 +476,7 @@ func (s *AutoTraderTestSuite) TestExecuteOpenPosition() {
 	for _, tt := range tests {
 		time.Sleep(time.Millisecond)
 		s.Run(tt.name, func() {
-			s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			s.patches.ApplyFunc(market.GetFrom, func(_ market.Source, symbol string) (*market.Data, error) {
 				return &market.Data{Symbol: symbol, CurrentPrice: 50000.0}, nil
 			})
 
@@ -541,7 +541,7 @@ func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
 	for _, tt := range tests {
 		time.Sleep(time.Millisecond)
 		s.Run(tt.name, func() {
-			s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+			s.patches.ApplyFunc(market.GetFrom, func(_ market.Source, symbol string) (*market.Data, error) {
 				return &market.Data{Symbol: symbol, CurrentPrice: tt.currentPrice}, nil
 			})
 
@@ -559,9 +559,9 @@ func (s *AutoTraderTestSuite) TestExecuteClosePosition() {
 
 // TestExecuteUpdateStopOrTakeProfit 测试更新止损/止盈（多空通用）
 func (s *AutoTraderTestSuite) TestExecuteUpdateStopOrTakeProfit() {
-	// 使用指针变量来控制 market.Get 的返回值
+	// 使用指针变量来控制 market.GetFrom 的返回值
 	var testPrice *float64
-	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+	s.patches.ApplyFunc(market.GetFrom, func(_ market.Source, symbol string) (*market.Data, error) {
 		price := 50000.0
 		if testPrice != nil {
 			price = *testPrice
@@ -731,8 +731,8 @@ func (s *AutoTraderTestSuite) TestExecutePartialCloseWithRecord() {
 			},
 		}
 
-		// Mock market.Get
-		s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+		// Mock market.GetFrom（交易员通过自己的行情来源获取价格）
+		s.patches.ApplyFunc(market.GetFrom, func(_ market.Source, symbol string) (*market.Data, error) {
 			return &market.Data{
 				Symbol:       symbol,
 				CurrentPrice: 52000.0,
@@ -777,8 +777,8 @@ func (s *AutoTraderTestSuite) TestExecutePartialCloseWithRecord() {
 // ============================================================
 
 func (s *AutoTraderTestSuite) TestExecuteDecisionWithRecord() {
-	// Mock market.Get
-	s.patches.ApplyFunc(market.Get, func(symbol string) (*market.Data, error) {
+	// Mock market.GetFrom（交易员通过自己的行情来源获取价格）
+	s.patches.ApplyFunc(market.GetFrom, func(_ market.Source, symbol string) (*market.Data, error) {
 		return &market.Data{
 			Symbol:       symbol,
 			CurrentPrice: 50000.0,
@@ -958,6 +958,32 @@ func (s *AutoTraderTestSuite) TestCheckPositionDrawdown() {
 	}
 }
 
+// TestCloseAllPositions 测试紧急平仓逐个平掉持仓并报告失败的持仓
+func (s *AutoTraderTestSuite) TestCloseAllPositions() {
+	s.mockTrader.positions = []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long"},
+		{"symbol": "ETHUSDT", "side": "short"},
+	}
+	s.mockTrader.shouldFailCloseShort = true
+	defer func() {
+		s.mockTrader.shouldFailCloseShort = false
+		s.mockTrader.positions = []map[string]interface{}{}
+	}()
+
+	results, err := s.autoTrader.CloseAllPositions()
+	s.NoError(err)
+	s.Require().Len(results, 2)
+	s.True(results[0].Closed)
+	s.Equal("BTCUSDT", results[0].Symbol)
+	s.False(results[1].Closed)
+	s.NotEmpty(results[1].Error)
+
+	s.mockTrader.shouldFailPositions = true
+	defer func() { s.mockTrader.shouldFailPositions = false }()
+	_, err = s.autoTrader.CloseAllPositions()
+	s.Error(err)
+}
+
 // ============================================================
 // Mock 实现
 // ============================================================
@@ -1210,3 +1236,43 @@ func TestCalculatePnLPercentage_RealWorldScenarios(t *testing.T) {
 		}
 	})
 }
+
+// TestAutoTrader_HotReloadSettings 测试运行参数热更新：扫描间隔通知主循环、杠杆忽略非正值、币种列表去除空白
+func TestAutoTrader_HotReloadSettings(t *testing.T) {
+	at := &AutoTrader{
+		config:         AutoTraderConfig{ScanInterval: 3 * time.Minute, BTCETHLeverage: 5, AltcoinLeverage: 5},
+		scanIntervalCh: make(chan time.Duration, 1),
+	}
+
+	at.SetScanInterval(10 * time.Minute)
+	at.SetScanInterval(15 * time.Minute)
+	select {
+	case interval := <-at.scanIntervalCh:
+		if interval != 15*time.Minute {
+			t.Errorf("主循环应收到最新的扫描间隔，实际 %v", interval)
+		}
+	default:
+		t.Fatal("修改扫描间隔后应通知主循环")
+	}
+	at.SetScanInterval(15 * time.Minute)
+	if len(at.scanIntervalCh) != 0 {
+		t.Error("扫描间隔未变化时不应通知主循环")
+	}
+	if at.getScanInterval() != 15*time.Minute {
+		t.Errorf("扫描间隔 = %v, want 15m", at.getScanInterval())
+	}
+
+	at.SetLeverage(20, 0)
+	if btcEth, altcoin := at.getLeverage(); btcEth != 20 || altcoin != 5 {
+		t.Errorf("杠杆 = %d/%d, want 20/5", btcEth, altcoin)
+	}
+
+	at.SetTradingSymbols([]string{" btcusdt", "", "ETHUSDT "})
+	coins, err := at.getCandidateCoins()
+	if err != nil {
+		t.Fatalf("获取候选币种失败: %v", err)
+	}
+	if len(coins) != 2 || coins[0].Symbol != "BTCUSDT" || coins[1].Symbol != "ETHUSDT" {
+		t.Errorf("候选币种不正确: %+v", coins)
+	}
+}
@@ -8,8 +8,8 @@ import (
 	"log"
 	"nofx/hook"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/adshao/go-binance/v2/futures"
@@ -48,21 +48,36 @@ type FuturesTrader struct {
 	client *futures.Client
 
 	// 余额缓存
-	cachedBalance     map[string]interface{}
+	cachedBalance     *Balance
 	balanceCacheTime  time.Time
 	balanceCacheMutex sync.RWMutex
 
 	// 持仓缓存
-	cachedPositions     []map[string]interface{}
+	cachedPositions     []Position
 	positionsCacheTime  time.Time
 	positionsCacheMutex sync.RWMutex
 
 	// 缓存有效期（15秒）
 	cacheDuration time.Duration
+
+	// 服务器时钟（自动校正签名时间戳）
+	clock *serverClock
+
+	// 请求重试器（时间戳错误重新同步，限频/5xx/网络错误退避重试）
+	retrier *requestRetrier
+
+	// 交易对规则缓存（exchangeInfo）
+	symbolFilters symbolFilterCache
+
+	// 账户是否为单向持仓模式（零值按双向持仓处理，启动时检测账户实际模式）
+	oneWayMode atomic.Bool
+
+	// 是否连接币安合约测试网（模拟资金）
+	testnet bool
 }
 
-// NewFuturesTrader 创建合约交易器
-func NewFuturesTrader(apiKey, secretKey string, userId string) *FuturesTrader {
+// NewFuturesTrader 创建合约交易器（testnet=true 时连接币安合约测试网）
+func NewFuturesTrader(apiKey, secretKey string, userId string, testnet bool) *FuturesTrader {
 	client := futures.NewClient(apiKey, secretKey)
 
 	hookRes := hook.HookExec[hook.NewBinanceTraderResult](hook.NEW_BINANCE_TRADER, userId, client)
@@ -70,82 +85,195 @@ func NewFuturesTrader(apiKey, secretKey string, userId string) *FuturesTrader {
 		client = hookRes.GetResult()
 	}
 
+	// 只修改当前客户端的地址，不使用 futures.UseTestnet 全局开关，避免影响同进程内的实盘交易员
+	if testnet {
+		client.BaseURL = futures.BaseApiTestnetUrl
+		log.Printf("🧪 币安合约使用测试网: %s", client.BaseURL)
+	}
+
+	// 记录限频响应的 Retry-After，供重试时等待
+	retrier := newRequestRetrier("binance")
+	client.HTTPClient = withRetryAfter(client.HTTPClient, retrier)
+
 	// 同步时间，避免 Timestamp ahead 错误
-	syncBinanceServerTime(client)
+	clock := newBinanceServerClock(client)
+	clock.sync()
 	trader := &FuturesTrader{
 		client:        client,
 		cacheDuration: 15 * time.Second, // 15秒缓存
+		clock:         clock,
+		retrier:       retrier,
+		testnet:       testnet,
 	}
 
-	// 设置双向持仓模式（Hedge Mode）
-	// 这是必需的，因为代码中使用了 PositionSide (LONG/SHORT)
-	if err := trader.setDualSidePosition(); err != nil {
-		log.Printf("⚠️ 设置双向持仓模式失败: %v (如果已是双向模式则忽略此警告)", err)
+	// 只检测账户当前持仓模式并据此下单，不修改用户的账户设置
+	if _, err := trader.refreshPositionMode(); err != nil {
+		log.Printf("⚠️ 查询持仓模式失败，按双向持仓模式下单: %v", err)
 	}
 
 	return trader
 }
 
-// setDualSidePosition 设置双向持仓模式（初始化时调用）
-func (t *FuturesTrader) setDualSidePosition() error {
-	// 尝试设置双向持仓模式
-	err := t.client.NewChangePositionModeService().
-		DualSide(true). // true = 双向持仓（Hedge Mode）
-		Do(context.Background())
+// HedgeMode 账户是否为双向持仓模式
+func (t *FuturesTrader) HedgeMode() bool {
+	return !t.oneWayMode.Load()
+}
 
+// refreshPositionMode 查询账户持仓模式，返回模式是否发生变化
+func (t *FuturesTrader) refreshPositionMode() (bool, error) {
+	mode, err := withRetry(t.retrier, t.clock, func() (*futures.PositionMode, error) {
+		return t.client.NewGetPositionModeService().Do(context.Background())
+	})
 	if err != nil {
-		// 如果错误信息包含"No need to change"，说明已经是双向持仓模式
-		if strings.Contains(err.Error(), "No need to change position side") {
-			log.Printf("  ✓ 账户已是双向持仓模式（Hedge Mode）")
-			return nil
+		return false, fmt.Errorf("查询持仓模式失败: %w", err)
+	}
+
+	changed := t.oneWayMode.Swap(!mode.DualSidePosition) == mode.DualSidePosition
+	if mode.DualSidePosition {
+		log.Printf("  ✓ 账户持仓模式: 双向持仓（Hedge Mode）")
+	} else {
+		log.Printf("  ℹ️  账户持仓模式: 单向持仓（One-way Mode），订单 positionSide 使用 BOTH，平仓单附加 reduceOnly")
+	}
+	return changed, nil
+}
+
+// createOrder 按账户持仓模式下单：双向持仓时指定 positionSide（LONG/SHORT），
+// 单向持仓时 positionSide 为 BOTH，平仓单（reduceOnly=true）附加 reduceOnly 防止数量超出持仓时反向开仓。
+// 遇到 -4061（positionSide 与账户持仓模式不符，如用户在交易所手动切换了模式）时重新查询持仓模式后重试一次
+func (t *FuturesTrader) createOrder(positionSide futures.PositionSideType, reduceOnly bool, build func() *futures.CreateOrderService) (*futures.CreateOrderResponse, error) {
+	submit := func() (*futures.CreateOrderResponse, error) {
+		return withOrderRetry(t.retrier, t.clock, func() (*futures.CreateOrderResponse, error) {
+			service := build()
+			if t.HedgeMode() {
+				service.PositionSide(positionSide)
+			} else {
+				service.PositionSide(futures.PositionSideTypeBoth)
+				if reduceOnly {
+					service.ReduceOnly(true)
+				}
+			}
+			return service.Do(context.Background())
+		})
+	}
+
+	order, err := submit()
+	if isPositionSideMismatch(err) {
+		if changed, refreshErr := t.refreshPositionMode(); refreshErr == nil && changed {
+			log.Printf("  🔄 账户持仓模式已变化，按新模式重新下单")
+			order, err = submit()
+		}
+	}
+	return order, err
+}
+
+// cancelSideOrders 取消某一方向持仓的挂单：双向持仓时只取消该方向的委托，不影响另一方向持仓的止盈止损；
+// 单向持仓时取消该币种的所有挂单
+func (t *FuturesTrader) cancelSideOrders(symbol string, positionSide futures.PositionSideType) error {
+	if !t.HedgeMode() {
+		return t.CancelAllOrders(symbol)
+	}
+
+	orders, err := withRetry(t.retrier, t.clock, func() ([]*futures.Order, error) {
+		return t.client.NewListOpenOrdersService().
+			Symbol(symbol).
+			Do(context.Background())
+	})
+	if err != nil {
+		return fmt.Errorf("获取未完成订单失败: %w", err)
+	}
+
+	canceledCount := 0
+	for _, order := range orders {
+		if order.PositionSide != positionSide {
+			continue
+		}
+		orderID := order.OrderID
+		if err := withRetryErr(t.retrier, t.clock, func() error {
+			_, err := t.client.NewCancelOrderService().
+				Symbol(symbol).
+				OrderID(orderID).
+				Do(context.Background())
+			return err
+		}); err != nil {
+			return fmt.Errorf("取消订单 %d 失败: %w", orderID, err)
 		}
-		// 其他错误则返回（但在调用方不会中断初始化）
-		return err
+		canceledCount++
 	}
 
-	log.Printf("  ✓ 账户已切换为双向持仓模式（Hedge Mode）")
-	log.Printf("  ℹ️  双向持仓模式允许同时持有多单和空单")
+	if canceledCount > 0 {
+		log.Printf("  ✓ 已取消 %s %s方向的 %d 个挂单", symbol, positionSide, canceledCount)
+	}
 	return nil
 }
 
-// syncBinanceServerTime 同步币安服务器时间，确保请求时间戳合法
-func syncBinanceServerTime(client *futures.Client) {
-	serverTime, err := client.NewServerTimeService().Do(context.Background())
-	if err != nil {
-		log.Printf("⚠️ 同步币安服务器时间失败: %v", err)
-		return
+// IsTestnet 是否连接币安合约测试网
+func (t *FuturesTrader) IsTestnet() bool {
+	return t.testnet
+}
+
+// newBinanceServerClock 创建币安服务器时钟，同步结果写入 client.TimeOffset 以校正签名时间戳
+func newBinanceServerClock(client *futures.Client) *serverClock {
+	clock := newServerClock("币安", func() (int64, error) {
+		return client.NewServerTimeService().Do(context.Background())
+	})
+	clock.onSync = func(offsetMs int64) {
+		client.TimeOffset = offsetMs
 	}
+	return clock
+}
+
+// ClockSkew 获取与币安服务器的时钟偏移状态
+func (t *FuturesTrader) ClockSkew() ClockSkewStatus {
+	if t.clock == nil {
+		return ClockSkewStatus{}
+	}
+	return t.clock.status()
+}
 
-	now := time.Now().UnixMilli()
-	offset := now - serverTime
-	client.TimeOffset = offset
-	log.Printf("⏱ 已同步币安服务器时间，偏移 %dms", offset)
+// RetryStats 获取请求重试计数
+func (t *FuturesTrader) RetryStats() RetryStats {
+	if t.retrier == nil {
+		return RetryStats{}
+	}
+	return t.retrier.stats()
 }
 
-// GetBalance 获取账户余额（带缓存）
+// GetBalance 获取账户余额（旧版 map 结果，由 GetBalanceInfo 转换）
 func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
+	balance, err := t.GetBalanceInfo()
+	if err != nil {
+		return nil, err
+	}
+	return balance.ToMap(), nil
+}
+
+// GetBalanceInfo 获取账户余额（带缓存）
+func (t *FuturesTrader) GetBalanceInfo() (*Balance, error) {
 	// 先检查缓存是否有效
 	t.balanceCacheMutex.RLock()
 	if t.cachedBalance != nil && time.Since(t.balanceCacheTime) < t.cacheDuration {
 		cacheAge := time.Since(t.balanceCacheTime)
+		balance := *t.cachedBalance
 		t.balanceCacheMutex.RUnlock()
 		log.Printf("✓ 使用缓存的账户余额（缓存时间: %.1f秒前）", cacheAge.Seconds())
-		return t.cachedBalance, nil
+		return &balance, nil
 	}
 	t.balanceCacheMutex.RUnlock()
 
 	// 缓存过期或不存在，调用API
 	log.Printf("🔄 缓存过期，正在调用币安API获取账户余额...")
-	account, err := t.client.NewGetAccountService().Do(context.Background())
+	account, err := withRetry(t.retrier, t.clock, func() (*futures.Account, error) {
+		return t.client.NewGetAccountService().Do(context.Background())
+	})
 	if err != nil {
 		log.Printf("❌ 币安API调用失败: %v", err)
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	result := make(map[string]interface{})
-	result["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
-	result["availableBalance"], _ = strconv.ParseFloat(account.AvailableBalance, 64)
-	result["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
+	result := &Balance{}
+	result.WalletBalance, _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	result.AvailableBalance, _ = strconv.ParseFloat(account.AvailableBalance, 64)
+	result.UnrealizedPnL, _ = strconv.ParseFloat(account.TotalUnrealizedProfit, 64)
 
 	log.Printf("✓ 币安API返回: 总余额=%s, 可用=%s, 未实现盈亏=%s",
 		account.TotalWalletBalance,
@@ -154,61 +282,79 @@ func (t *FuturesTrader) GetBalance() (map[string]interface{}, error) {
 
 	// 更新缓存
 	t.balanceCacheMutex.Lock()
-	t.cachedBalance = result
+	cached := *result
+	t.cachedBalance = &cached
 	t.balanceCacheTime = time.Now()
 	t.balanceCacheMutex.Unlock()
 
 	return result, nil
 }
 
-// GetPositions 获取所有持仓（带缓存）
+// GetPositions 获取所有持仓（旧版 map 结果，空仓 positionAmt 保持币安原有的负数约定）
 func (t *FuturesTrader) GetPositions() ([]map[string]interface{}, error) {
+	positions, err := t.GetPositionList()
+	if err != nil {
+		return nil, err
+	}
+	result := positionsToMaps(positions)
+	for i := range positions {
+		if positions[i].Side == "short" {
+			result[i]["positionAmt"] = -positions[i].Quantity
+		}
+	}
+	return result, nil
+}
+
+// GetPositionList 获取所有持仓（带缓存）
+func (t *FuturesTrader) GetPositionList() ([]Position, error) {
 	// 先检查缓存是否有效
 	t.positionsCacheMutex.RLock()
 	if t.cachedPositions != nil && time.Since(t.positionsCacheTime) < t.cacheDuration {
 		cacheAge := time.Since(t.positionsCacheTime)
+		positions := append([]Position(nil), t.cachedPositions...)
 		t.positionsCacheMutex.RUnlock()
 		log.Printf("✓ 使用缓存的持仓信息（缓存时间: %.1f秒前）", cacheAge.Seconds())
-		return t.cachedPositions, nil
+		return positions, nil
 	}
 	t.positionsCacheMutex.RUnlock()
 
 	// 缓存过期或不存在，调用API
 	log.Printf("🔄 缓存过期，正在调用币安API获取持仓信息...")
-	positions, err := t.client.NewGetPositionRiskService().Do(context.Background())
+	positions, err := withRetry(t.retrier, t.clock, func() ([]*futures.PositionRisk, error) {
+		return t.client.NewGetPositionRiskService().Do(context.Background())
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	result := []Position{}
 	for _, pos := range positions {
 		posAmt, _ := strconv.ParseFloat(pos.PositionAmt, 64)
 		if posAmt == 0 {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-		posMap["symbol"] = pos.Symbol
-		posMap["positionAmt"], _ = strconv.ParseFloat(pos.PositionAmt, 64)
-		posMap["entryPrice"], _ = strconv.ParseFloat(pos.EntryPrice, 64)
-		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
-		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
-		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
-		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
-
-		// 判断方向
-		if posAmt > 0 {
-			posMap["side"] = "long"
-		} else {
-			posMap["side"] = "short"
+		position := Position{Symbol: pos.Symbol, Side: "long", Quantity: posAmt}
+		// 判断方向：双向持仓模式下多空两条腿按 positionSide 分别返回，单向持仓模式（BOTH）下空仓数量为负数
+		if pos.PositionSide == string(futures.PositionSideTypeShort) ||
+			(pos.PositionSide != string(futures.PositionSideTypeLong) && posAmt < 0) {
+			position.Side = "short"
 		}
+		if posAmt < 0 {
+			position.Quantity = -posAmt
+		}
+		position.EntryPrice, _ = strconv.ParseFloat(pos.EntryPrice, 64)
+		position.MarkPrice, _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		position.UnrealizedPnL, _ = strconv.ParseFloat(pos.UnRealizedProfit, 64)
+		position.Leverage, _ = strconv.ParseFloat(pos.Leverage, 64)
+		position.LiquidationPrice, _ = strconv.ParseFloat(pos.LiquidationPrice, 64)
 
-		result = append(result, posMap)
+		result = append(result, position)
 	}
 
 	// 更新缓存
 	t.positionsCacheMutex.Lock()
-	t.cachedPositions = result
+	t.cachedPositions = append([]Position(nil), result...)
 	t.positionsCacheTime = time.Now()
 	t.positionsCacheMutex.Unlock()
 
@@ -225,10 +371,12 @@ func (t *FuturesTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
 	}
 
 	// 尝试设置仓位模式
-	err := t.client.NewChangeMarginTypeService().
-		Symbol(symbol).
-		MarginType(marginType).
-		Do(context.Background())
+	err := withRetryErr(t.retrier, t.clock, func() error {
+		return t.client.NewChangeMarginTypeService().
+			Symbol(symbol).
+			MarginType(marginType).
+			Do(context.Background())
+	})
 
 	marginModeStr := "全仓"
 	if !isCrossMargin {
@@ -289,10 +437,12 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	}
 
 	// 切换杠杆
-	_, err = t.client.NewChangeLeverageService().
-		Symbol(symbol).
-		Leverage(leverage).
-		Do(context.Background())
+	_, err = withRetry(t.retrier, t.clock, func() (*futures.SymbolLeverage, error) {
+		return t.client.NewChangeLeverageService().
+			Symbol(symbol).
+			Leverage(leverage).
+			Do(context.Background())
+	})
 
 	if err != nil {
 		// 如果错误信息包含"No need to change"，说明杠杆已经是目标值
@@ -312,16 +462,16 @@ func (t *FuturesTrader) SetLeverage(symbol string, leverage int) error {
 	return nil
 }
 
-// OpenLong 开多仓
-func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
-	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+// prepareOpen 开仓前的准备：取消旧委托单、设置杠杆、格式化数量并检查最小名义价值，返回格式化后的数量
+func (t *FuturesTrader) prepareOpen(symbol string, positionSide futures.PositionSideType, quantity float64, leverage int) (string, error) {
+	// 先取消该方向的委托单（清理旧的止损止盈单，双向持仓时不影响另一方向持仓）
+	if err := t.cancelSideOrders(symbol, positionSide); err != nil {
 		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
 	}
 
 	// 设置杠杆
 	if err := t.SetLeverage(symbol, leverage); err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// 注意：仓位模式应该由调用方（AutoTrader）在开仓前通过 SetMarginMode 设置
@@ -329,29 +479,51 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	// 格式化数量到正确精度
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
 	// ✅ 检查格式化后的数量是否为 0（防止四舍五入导致的错误）
 	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
 	if parseErr != nil || quantityFloat <= 0 {
-		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)。建议增加开仓金额或选择价格更低的币种", quantity, quantityStr)
+		return "", fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)。建议增加开仓金额或选择价格更低的币种", quantity, quantityStr)
 	}
 
 	// ✅ 检查最小名义价值（Binance 要求至少 10 USDT）
 	if err := t.CheckMinNotional(symbol, quantityFloat); err != nil {
+		return "", err
+	}
+	return quantityStr, nil
+}
+
+// orderResult 转换下单结果（RESULT 响应包含成交均价，市价单可据此计算滑点）
+func orderResult(order *futures.CreateOrderResponse) map[string]interface{} {
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = order.Symbol
+	result["status"] = order.Status
+	if avgPrice, err := strconv.ParseFloat(order.AvgPrice, 64); err == nil && avgPrice > 0 {
+		result["avgPrice"] = avgPrice
+	}
+	return result
+}
+
+// OpenLong 开多仓
+func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	quantityStr, err := t.prepareOpen(symbol, futures.PositionSideTypeLong, quantity, leverage)
+	if err != nil {
 		return nil, err
 	}
 
 	// 创建市价买入订单（使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeBuy).
-		PositionSide(futures.PositionSideTypeLong).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+	order, err := t.createOrder(futures.PositionSideTypeLong, false, func() *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(futures.SideTypeBuy).
+			Type(futures.OrderTypeMarket).
+			Quantity(quantityStr).
+			NewClientOrderID(getBrOrderID()).
+			NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("开多仓失败: %w", err)
@@ -360,53 +532,26 @@ func (t *FuturesTrader) OpenLong(symbol string, quantity float64, leverage int)
 	log.Printf("✓ 开多仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return orderResult(order), nil
 }
 
 // OpenShort 开空仓
 func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
-	// 先取消该币种的所有委托单（清理旧的止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
-		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
-	}
-
-	// 设置杠杆
-	if err := t.SetLeverage(symbol, leverage); err != nil {
-		return nil, err
-	}
-
-	// 注意：仓位模式应该由调用方（AutoTrader）在开仓前通过 SetMarginMode 设置
-
-	// 格式化数量到正确精度
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	quantityStr, err := t.prepareOpen(symbol, futures.PositionSideTypeShort, quantity, leverage)
 	if err != nil {
 		return nil, err
 	}
 
-	// ✅ 检查格式化后的数量是否为 0（防止四舍五入导致的错误）
-	quantityFloat, parseErr := strconv.ParseFloat(quantityStr, 64)
-	if parseErr != nil || quantityFloat <= 0 {
-		return nil, fmt.Errorf("开仓数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)。建议增加开仓金额或选择价格更低的币种", quantity, quantityStr)
-	}
-
-	// ✅ 检查最小名义价值（Binance 要求至少 10 USDT）
-	if err := t.CheckMinNotional(symbol, quantityFloat); err != nil {
-		return nil, err
-	}
-
 	// 创建市价卖出订单（使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeSell).
-		PositionSide(futures.PositionSideTypeShort).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+	order, err := t.createOrder(futures.PositionSideTypeShort, false, func() *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(futures.SideTypeSell).
+			Type(futures.OrderTypeMarket).
+			Quantity(quantityStr).
+			NewClientOrderID(getBrOrderID()).
+			NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("开空仓失败: %w", err)
@@ -415,11 +560,7 @@ func (t *FuturesTrader) OpenShort(symbol string, quantity float64, leverage int)
 	log.Printf("✓ 开空仓成功: %s 数量: %s", symbol, quantityStr)
 	log.Printf("  订单ID: %d", order.OrderID)
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return orderResult(order), nil
 }
 
 // CloseLong 平多仓
@@ -450,14 +591,17 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 	}
 
 	// 创建市价卖出订单（平多，使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeSell).
-		PositionSide(futures.PositionSideTypeLong).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+	// 双向持仓模式下指定 positionSide 的平仓单只会减少该方向持仓（币安不接受 reduceOnly 参数），数量超出持仓时被拒绝而不会反向开仓；
+	// 单向持仓模式下附加 reduceOnly
+	order, err := t.createOrder(futures.PositionSideTypeLong, true, func() *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(futures.SideTypeSell).
+			Type(futures.OrderTypeMarket).
+			Quantity(quantityStr).
+			NewClientOrderID(getBrOrderID()).
+			NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("平多仓失败: %w", err)
@@ -465,16 +609,12 @@ func (t *FuturesTrader) CloseLong(symbol string, quantity float64) (map[string]i
 
 	log.Printf("✓ 平多仓成功: %s 数量: %s", symbol, quantityStr)
 
-	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	// 平仓后取消该方向的挂单（止损止盈单）
+	if err := t.cancelSideOrders(symbol, futures.PositionSideTypeLong); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return orderResult(order), nil
 }
 
 // CloseShort 平空仓
@@ -505,14 +645,17 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 	}
 
 	// 创建市价买入订单（平空，使用br ID）
-	order, err := t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(futures.SideTypeBuy).
-		PositionSide(futures.PositionSideTypeShort).
-		Type(futures.OrderTypeMarket).
-		Quantity(quantityStr).
-		NewClientOrderID(getBrOrderID()).
-		Do(context.Background())
+	// 双向持仓模式下指定 positionSide 的平仓单只会减少该方向持仓（币安不接受 reduceOnly 参数），数量超出持仓时被拒绝而不会反向开仓；
+	// 单向持仓模式下附加 reduceOnly
+	order, err := t.createOrder(futures.PositionSideTypeShort, true, func() *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(futures.SideTypeBuy).
+			Type(futures.OrderTypeMarket).
+			Quantity(quantityStr).
+			NewClientOrderID(getBrOrderID()).
+			NewOrderResponseType(futures.NewOrderRespTypeRESULT)
+	})
 
 	if err != nil {
 		return nil, fmt.Errorf("平空仓失败: %w", err)
@@ -520,24 +663,22 @@ func (t *FuturesTrader) CloseShort(symbol string, quantity float64) (map[string]
 
 	log.Printf("✓ 平空仓成功: %s 数量: %s", symbol, quantityStr)
 
-	// 平仓后取消该币种的所有挂单（止损止盈单）
-	if err := t.CancelAllOrders(symbol); err != nil {
+	// 平仓后取消该方向的挂单（止损止盈单）
+	if err := t.cancelSideOrders(symbol, futures.PositionSideTypeShort); err != nil {
 		log.Printf("  ⚠ 取消挂单失败: %v", err)
 	}
 
-	result := make(map[string]interface{})
-	result["orderId"] = order.OrderID
-	result["symbol"] = order.Symbol
-	result["status"] = order.Status
-	return result, nil
+	return orderResult(order), nil
 }
 
 // CancelStopLossOrders 仅取消止损单（不影响止盈单）
 func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
 	// 获取该币种的所有未完成订单
-	orders, err := t.client.NewListOpenOrdersService().
-		Symbol(symbol).
-		Do(context.Background())
+	orders, err := withRetry(t.retrier, t.clock, func() ([]*futures.Order, error) {
+		return t.client.NewListOpenOrdersService().
+			Symbol(symbol).
+			Do(context.Background())
+	})
 
 	if err != nil {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
@@ -585,9 +726,11 @@ func (t *FuturesTrader) CancelStopLossOrders(symbol string) error {
 // CancelTakeProfitOrders 仅取消止盈单（不影响止损单）
 func (t *FuturesTrader) CancelTakeProfitOrders(symbol string) error {
 	// 获取该币种的所有未完成订单
-	orders, err := t.client.NewListOpenOrdersService().
-		Symbol(symbol).
-		Do(context.Background())
+	orders, err := withRetry(t.retrier, t.clock, func() ([]*futures.Order, error) {
+		return t.client.NewListOpenOrdersService().
+			Symbol(symbol).
+			Do(context.Background())
+	})
 
 	if err != nil {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
@@ -634,9 +777,11 @@ func (t *FuturesTrader) CancelTakeProfitOrders(symbol string) error {
 
 // CancelAllOrders 取消该币种的所有挂单
 func (t *FuturesTrader) CancelAllOrders(symbol string) error {
-	err := t.client.NewCancelAllOpenOrdersService().
-		Symbol(symbol).
-		Do(context.Background())
+	err := withRetryErr(t.retrier, t.clock, func() error {
+		return t.client.NewCancelAllOpenOrdersService().
+			Symbol(symbol).
+			Do(context.Background())
+	})
 
 	if err != nil {
 		return fmt.Errorf("取消挂单失败: %w", err)
@@ -649,9 +794,11 @@ func (t *FuturesTrader) CancelAllOrders(symbol string) error {
 // CancelStopOrders 取消该币种的止盈/止损单（用于调整止盈止损位置）
 func (t *FuturesTrader) CancelStopOrders(symbol string) error {
 	// 获取该币种的所有未完成订单
-	orders, err := t.client.NewListOpenOrdersService().
-		Symbol(symbol).
-		Do(context.Background())
+	orders, err := withRetry(t.retrier, t.clock, func() ([]*futures.Order, error) {
+		return t.client.NewListOpenOrdersService().
+			Symbol(symbol).
+			Do(context.Background())
+	})
 
 	if err != nil {
 		return fmt.Errorf("获取未完成订单失败: %w", err)
@@ -695,7 +842,9 @@ func (t *FuturesTrader) CancelStopOrders(symbol string) error {
 
 // GetMarketPrice 获取市场价格
 func (t *FuturesTrader) GetMarketPrice(symbol string) (float64, error) {
-	prices, err := t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	prices, err := withRetry(t.retrier, t.clock, func() ([]*futures.SymbolPrice, error) {
+		return t.client.NewListPricesService().Symbol(symbol).Do(context.Background())
+	})
 	if err != nil {
 		return 0, fmt.Errorf("获取价格失败: %w", err)
 	}
@@ -722,44 +871,134 @@ func (t *FuturesTrader) CalculatePositionSize(balance, riskPercent, price float6
 
 // SetStopLoss 设置止损单
 func (t *FuturesTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
-	var side futures.SideType
-	var posSide futures.PositionSideType
+	if _, err := t.placeTriggerOrder(symbol, positionSide, futures.OrderTypeStopMarket, quantity, stopPrice); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
 
-	if positionSide == "LONG" {
-		side = futures.SideTypeSell
-		posSide = futures.PositionSideTypeLong
-	} else {
-		side = futures.SideTypeBuy
-		posSide = futures.PositionSideTypeShort
+	log.Printf("  止损价设置: %.4f", stopPrice)
+	return nil
+}
+
+// SetTakeProfit 设置止盈单
+func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if _, err := t.placeTriggerOrder(symbol, positionSide, futures.OrderTypeTakeProfitMarket, quantity, takeProfitPrice); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
 	}
 
-	// 格式化数量
-	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
+	return nil
+}
+
+// PlaceProtectiveOrder 下止损/止盈触发市价单（closePosition，只减仓），返回订单ID
+func (t *FuturesTrader) PlaceProtectiveOrder(symbol, positionSide, kind string, quantity, triggerPrice float64) (string, error) {
+	orderType := futures.OrderTypeStopMarket
+	if kind == ProtectiveTakeProfit {
+		orderType = futures.OrderTypeTakeProfitMarket
+	}
+	orderID, err := t.placeTriggerOrder(symbol, positionSide, orderType, quantity, triggerPrice)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("设置%s失败: %w", protectiveKindName(kind), err)
 	}
+	log.Printf("  %s价设置: %.4f (订单ID: %d)", protectiveKindName(kind), triggerPrice, orderID)
+	return strconv.FormatInt(orderID, 10), nil
+}
 
-	_, err = t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		PositionSide(posSide).
-		Type(futures.OrderTypeStopMarket).
-		StopPrice(fmt.Sprintf("%.8f", stopPrice)).
-		Quantity(quantityStr).
-		WorkingType(futures.WorkingTypeContractPrice).
-		ClosePosition(true).
-		Do(context.Background())
+// CancelOrder 按订单ID撤单
+func (t *FuturesTrader) CancelOrder(symbol, orderID string) error {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	_, err = withRetry(t.retrier, t.clock, func() (*futures.CancelOrderResponse, error) {
+		return t.client.NewCancelOrderService().
+			Symbol(symbol).
+			OrderID(id).
+			Do(context.Background())
+	})
+	if err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
+	return nil
+}
 
+// GetBestBidAsk 获取买一/卖一价
+func (t *FuturesTrader) GetBestBidAsk(symbol string) (float64, float64, error) {
+	tickers, err := t.client.NewListBookTickersService().Symbol(symbol).Do(context.Background())
 	if err != nil {
-		return fmt.Errorf("设置止损失败: %w", err)
+		return 0, 0, fmt.Errorf("获取盘口失败: %w", err)
+	}
+	if len(tickers) == 0 {
+		return 0, 0, fmt.Errorf("未找到 %s 的盘口数据", symbol)
+	}
+	bid, err := strconv.ParseFloat(tickers[0].BidPrice, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("解析买一价失败: %w", err)
 	}
+	ask, err := strconv.ParseFloat(tickers[0].AskPrice, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("解析卖一价失败: %w", err)
+	}
+	return bid, ask, nil
+}
 
-	log.Printf("  止损价设置: %.4f", stopPrice)
-	return nil
+// PlaceLimitOrder 下限价开仓单（GTC），返回订单ID
+func (t *FuturesTrader) PlaceLimitOrder(symbol, positionSide string, quantity, price float64, leverage int) (string, error) {
+	side, posSide := futures.SideTypeBuy, futures.PositionSideTypeLong
+	if positionSide == "SHORT" {
+		side, posSide = futures.SideTypeSell, futures.PositionSideTypeShort
+	}
+
+	quantityStr, err := t.prepareOpen(symbol, posSide, quantity, leverage)
+	if err != nil {
+		return "", err
+	}
+	priceStr, err := t.FormatPrice(symbol, price)
+	if err != nil {
+		return "", err
+	}
+
+	order, err := t.createOrder(posSide, false, func() *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(side).
+			Type(futures.OrderTypeLimit).
+			TimeInForce(futures.TimeInForceTypeGTC).
+			Quantity(quantityStr).
+			Price(priceStr).
+			NewClientOrderID(getBrOrderID())
+	})
+	if err != nil {
+		return "", fmt.Errorf("限价开仓失败: %w", err)
+	}
+
+	log.Printf("✓ 限价开仓单已挂出: %s %s 数量: %s 价格: %s (订单ID: %d)", symbol, positionSide, quantityStr, priceStr, order.OrderID)
+	return strconv.FormatInt(order.OrderID, 10), nil
 }
 
-// SetTakeProfit 设置止盈单
-func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+// GetOrder 查询订单成交状态
+func (t *FuturesTrader) GetOrder(symbol, orderID string) (*OrderFill, error) {
+	id, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	order, err := withRetry(t.retrier, t.clock, func() (*futures.Order, error) {
+		return t.client.NewGetOrderService().
+			Symbol(symbol).
+			OrderID(id).
+			Do(context.Background())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("查询订单失败: %w", err)
+	}
+
+	fill := &OrderFill{Status: string(order.Status)}
+	fill.ExecutedQty, _ = strconv.ParseFloat(order.ExecutedQuantity, 64)
+	fill.AvgPrice, _ = strconv.ParseFloat(order.AvgPrice, 64)
+	return fill, nil
+}
+
+// placeTriggerOrder 下触发市价单（STOP_MARKET/TAKE_PROFIT_MARKET），触发后平掉整个持仓
+func (t *FuturesTrader) placeTriggerOrder(symbol, positionSide string, orderType futures.OrderType, quantity, triggerPrice float64) (int64, error) {
 	var side futures.SideType
 	var posSide futures.PositionSideType
 
@@ -774,34 +1013,56 @@ func (t *FuturesTrader) SetTakeProfit(symbol string, positionSide string, quanti
 	// 格式化数量
 	quantityStr, err := t.FormatQuantity(symbol, quantity)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	_, err = t.client.NewCreateOrderService().
-		Symbol(symbol).
-		Side(side).
-		PositionSide(posSide).
-		Type(futures.OrderTypeTakeProfitMarket).
-		StopPrice(fmt.Sprintf("%.8f", takeProfitPrice)).
-		Quantity(quantityStr).
-		WorkingType(futures.WorkingTypeContractPrice).
-		ClosePosition(true).
-		Do(context.Background())
-
+	// closePosition 触发单本身只减仓，单向持仓模式下也不能再附加 reduceOnly
+	order, err := t.createOrder(posSide, false, func() *futures.CreateOrderService {
+		return t.client.NewCreateOrderService().
+			Symbol(symbol).
+			Side(side).
+			Type(orderType).
+			StopPrice(fmt.Sprintf("%.8f", triggerPrice)).
+			Quantity(quantityStr).
+			WorkingType(futures.WorkingTypeContractPrice).
+			ClosePosition(true)
+	})
 	if err != nil {
-		return fmt.Errorf("设置止盈失败: %w", err)
+		return 0, err
 	}
-
-	log.Printf("  止盈价设置: %.4f", takeProfitPrice)
-	return nil
+	return order.OrderID, nil
 }
 
 // GetMinNotional 获取最小名义价值（Binance要求）
 func (t *FuturesTrader) GetMinNotional(symbol string) float64 {
-	// 使用保守的默认值 10 USDT，确保订单能够通过交易所验证
+	if filter, ok := t.symbolFilters.lookup(symbol, t.fetchSymbolFilters); ok && filter.MinNotional > 0 {
+		return filter.MinNotional
+	}
+	// 未获取到交易规则时使用保守的默认值 10 USDT
 	return 10.0
 }
 
+// GetSymbolFilters 获取全部交易对的下单规则（缓存1小时）
+func (t *FuturesTrader) GetSymbolFilters() (map[string]SymbolFilter, error) {
+	return t.symbolFilters.get(t.fetchSymbolFilters)
+}
+
+// fetchSymbolFilters 从 exchangeInfo 拉取交易对规则
+func (t *FuturesTrader) fetchSymbolFilters() (map[string]SymbolFilter, error) {
+	exchangeInfo, err := withRetry(t.retrier, t.clock, func() (*futures.ExchangeInfo, error) {
+		return t.client.NewExchangeInfoService().Do(context.Background())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取交易规则失败: %w", err)
+	}
+
+	filters := make(map[string]SymbolFilter, len(exchangeInfo.Symbols))
+	for _, s := range exchangeInfo.Symbols {
+		filters[s.Symbol] = parseExchangeFilters(s.Symbol, s.Status, s.Filters)
+	}
+	return filters, nil
+}
+
 // CheckMinNotional 检查订单是否满足最小名义价值要求
 func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error {
 	price, err := t.GetMarketPrice(symbol)
@@ -824,29 +1085,37 @@ func (t *FuturesTrader) CheckMinNotional(symbol string, quantity float64) error
 
 // GetSymbolPrecision 获取交易对的数量精度
 func (t *FuturesTrader) GetSymbolPrecision(symbol string) (int, error) {
-	exchangeInfo, err := t.client.NewExchangeInfoService().Do(context.Background())
+	filters, err := t.GetSymbolFilters()
 	if err != nil {
-		return 0, fmt.Errorf("获取交易规则失败: %w", err)
+		return 0, err
 	}
 
-	for _, s := range exchangeInfo.Symbols {
-		if s.Symbol == symbol {
-			// 从LOT_SIZE filter获取精度
-			for _, filter := range s.Filters {
-				if filter["filterType"] == "LOT_SIZE" {
-					stepSize := filter["stepSize"].(string)
-					precision := calculatePrecision(stepSize)
-					log.Printf("  %s 数量精度: %d (stepSize: %s)", symbol, precision, stepSize)
-					return precision, nil
-				}
-			}
-		}
+	// 从LOT_SIZE filter获取精度
+	if filter, ok := filters[symbol]; ok && filter.StepSize > 0 {
+		precision := stepDecimals(filter.StepSize)
+		log.Printf("  %s 数量精度: %d (stepSize: %g)", symbol, precision, filter.StepSize)
+		return precision, nil
 	}
 
 	log.Printf("  ⚠ %s 未找到精度信息，使用默认精度3", symbol)
 	return 3, nil // 默认精度为3
 }
 
+// FormatPrice 按交易对的价格精度（PRICE_FILTER tickSize）格式化价格
+func (t *FuturesTrader) FormatPrice(symbol string, price float64) (string, error) {
+	filters, err := t.GetSymbolFilters()
+	if err != nil {
+		return "", err
+	}
+
+	precision := 2 // 默认精度为2
+	if filter, ok := filters[symbol]; ok && filter.TickSize > 0 {
+		price = filter.RoundPrice(price)
+		precision = stepDecimals(filter.TickSize)
+	}
+	return strconv.FormatFloat(price, 'f', precision, 64), nil
+}
+
 // calculatePrecision 从stepSize计算精度
 func calculatePrecision(stepSize string) int {
 	// 去除尾部的0
@@ -916,22 +1185,8 @@ func stringContains(s, substr string) bool {
 	return false
 }
 
-// BinanceTradeHistory Binance交易历史记录
-type BinanceTradeHistory struct {
-	Symbol          string
-	Side            string  // BUY/SELL
-	PositionSide    string  // LONG/SHORT
-	Price           float64
-	Qty             float64
-	RealizedPnl     float64
-	Commission      float64
-	CommissionAsset string
-	Time            int64
-	Buyer           bool
-}
-
 // GetTradeHistory 获取交易历史（最近N天）
-func (t *FuturesTrader) GetTradeHistory(symbol string, lookbackDays int) ([]*BinanceTradeHistory, error) {
+func (t *FuturesTrader) GetTradeHistory(symbol string, lookbackDays int) ([]*Trade, error) {
 	startTime := time.Now().AddDate(0, 0, -lookbackDays).UnixMilli()
 	
 	service := t.client.NewListAccountTradeService().
@@ -944,14 +1199,14 @@ func (t *FuturesTrader) GetTradeHistory(symbol string, lookbackDays int) ([]*Bin
 		return nil, fmt.Errorf("获取交易历史失败: %w", err)
 	}
 	
-	var history []*BinanceTradeHistory
+	var history []*Trade
 	for _, trade := range trades {
 		price, _ := strconv.ParseFloat(trade.Price, 64)
 		qty, _ := strconv.ParseFloat(trade.Quantity, 64) // 修复：使用Quantity而不是Qty
 		realizedPnl, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
 		commission, _ := strconv.ParseFloat(trade.Commission, 64)
 		
-		history = append(history, &BinanceTradeHistory{
+		history = append(history, &Trade{
 			Symbol:          trade.Symbol,
 			Side:            string(trade.Side),            // 修复：转换为string
 			PositionSide:    string(trade.PositionSide),    // 修复：转换为string
@@ -969,7 +1224,7 @@ func (t *FuturesTrader) GetTradeHistory(symbol string, lookbackDays int) ([]*Bin
 }
 
 // GetAllTradeHistory 获取所有币种的交易历史
-func (t *FuturesTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*BinanceTradeHistory, error) {
+func (t *FuturesTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*Trade, error) {
 	// ✅ 修复：直接从币安 API 获取所有交易历史，不限制币种
 	// 这样可以获取已平仓币种的历史交易
 	startTime := time.Now().AddDate(0, 0, -lookbackDays).UnixMilli()
@@ -985,14 +1240,14 @@ func (t *FuturesTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*Bina
 	}
 	
 	// 按币种分组
-	result := make(map[string][]*BinanceTradeHistory)
+	result := make(map[string][]*Trade)
 	for _, trade := range trades {
 		price, _ := strconv.ParseFloat(trade.Price, 64)
 		qty, _ := strconv.ParseFloat(trade.Quantity, 64)
 		realizedPnl, _ := strconv.ParseFloat(trade.RealizedPnl, 64)
 		commission, _ := strconv.ParseFloat(trade.Commission, 64)
 		
-		history := &BinanceTradeHistory{
+		history := &Trade{
 			Symbol:          trade.Symbol,
 			Side:            string(trade.Side),
 			PositionSide:    string(trade.PositionSide),
@@ -1010,3 +1265,66 @@ func (t *FuturesTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*Bina
 	
 	return result, nil
 }
+
+// binanceDefaultFundingInterval 币安资金费默认结算间隔（小时），调整过间隔的交易对在 fundingInfo 中单独列出
+const binanceDefaultFundingInterval = 8
+
+// GetFundingRates 获取资金费率（premiumIndex 一次返回全部交易对，lastFundingRate 为当前周期实时费率）
+func (t *FuturesTrader) GetFundingRates(symbols []string) (map[string]FundingRate, error) {
+	indexes, err := withRetry(t.retrier, t.clock, func() ([]*futures.PremiumIndex, error) {
+		return t.client.NewPremiumIndexService().Do(context.Background())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费率失败: %w", err)
+	}
+
+	// 结算间隔被调整过的交易对（获取失败时按默认间隔处理）
+	intervals := make(map[string]int)
+	if infos, err := t.client.NewFundingRateInfoService().Do(context.Background()); err == nil {
+		for _, info := range infos {
+			intervals[info.Symbol] = int(info.FundingIntervalHours)
+		}
+	}
+
+	rates := make(map[string]FundingRate, len(indexes))
+	for _, index := range indexes {
+		rate, err := strconv.ParseFloat(index.LastFundingRate, 64)
+		if err != nil {
+			continue
+		}
+		interval := binanceDefaultFundingInterval
+		if hours, ok := intervals[index.Symbol]; ok && hours > 0 {
+			interval = hours
+		}
+		fundingRate := FundingRate{Symbol: index.Symbol, Rate: rate, IntervalHours: interval}
+		if index.NextFundingTime > 0 {
+			fundingRate.NextFundingTime = time.UnixMilli(index.NextFundingTime)
+		}
+		rates[index.Symbol] = fundingRate
+	}
+	return filterFundingRates(rates, symbols), nil
+}
+
+// GetFundingFees 获取资金费收支（收入流水 FUNDING_FEE，单次最多1000条）
+func (t *FuturesTrader) GetFundingFees(startTime time.Time) ([]FundingFee, error) {
+	incomes, err := withRetry(t.retrier, t.clock, func() ([]*futures.IncomeHistory, error) {
+		return t.client.NewGetIncomeHistoryService().
+			IncomeType("FUNDING_FEE").
+			StartTime(startTime.UnixMilli()).
+			Limit(1000).
+			Do(context.Background())
+	})
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+	}
+
+	fees := make([]FundingFee, 0, len(incomes))
+	for _, income := range incomes {
+		amount, err := strconv.ParseFloat(income.Income, 64)
+		if err != nil {
+			continue
+		}
+		fees = append(fees, FundingFee{Symbol: income.Symbol, Amount: amount, Time: income.Time})
+	}
+	return sortFundingFees(fees), nil
+}
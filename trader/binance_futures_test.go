@@ -200,6 +200,18 @@ func NewBinanceFuturesTestSuite(t *testing.T) *BinanceFuturesTestSuite {
 				"workingType":   r.FormValue("workingType"),
 			}
 
+		// Mock GetOrder - /fapi/v1/order (GET)
+		case path == "/fapi/v1/order" && r.Method == "GET":
+			respBody = map[string]interface{}{
+				"orderId":     123456,
+				"symbol":      r.URL.Query().Get("symbol"),
+				"status":      "PARTIALLY_FILLED",
+				"price":       "49990.00",
+				"avgPrice":    "49990.00",
+				"origQty":     "0.010",
+				"executedQty": "0.004",
+			}
+
 		// Mock CancelOrder - /fapi/v1/order (DELETE)
 		case path == "/fapi/v1/order" && r.Method == "DELETE":
 			respBody = map[string]interface{}{
@@ -208,6 +220,16 @@ func NewBinanceFuturesTestSuite(t *testing.T) *BinanceFuturesTestSuite {
 				"status":  "CANCELED",
 			}
 
+		// Mock BookTicker - /fapi/v1/ticker/bookTicker
+		case path == "/fapi/v1/ticker/bookTicker":
+			respBody = map[string]interface{}{
+				"symbol":   r.URL.Query().Get("symbol"),
+				"bidPrice": "49990.00",
+				"bidQty":   "3.5",
+				"askPrice": "50000.10",
+				"askQty":   "1.2",
+			}
+
 		// Mock ListOpenOrders - /fapi/v1/openOrders
 		case path == "/fapi/v1/openOrders":
 			respBody = []map[string]interface{}{}
@@ -315,6 +337,65 @@ func TestFuturesTrader_CommonInterface(t *testing.T) {
 // 三、币安合约特定功能的单元测试
 // ============================================================
 
+// TestFuturesTrader_LimitOrders 测试限价开仓所需的盘口查询、价格精度、挂单和订单查询
+func TestFuturesTrader_LimitOrders(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+	trader := suite.Trader.(*FuturesTrader)
+
+	bid, ask, err := trader.GetBestBidAsk("BTCUSDT")
+	assert.NoError(t, err)
+	assert.Equal(t, 49990.0, bid)
+	assert.Equal(t, 50000.1, ask)
+
+	price, err := trader.FormatPrice("BTCUSDT", 49990.123456)
+	assert.NoError(t, err)
+	assert.Equal(t, "49990.12", price, "按 tickSize 0.01 保留两位小数")
+
+	orderID, err := trader.PlaceLimitOrder("BTCUSDT", "LONG", 0.01, 49990, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "123456", orderID)
+
+	fill, err := trader.GetOrder("BTCUSDT", orderID)
+	assert.NoError(t, err)
+	assert.Equal(t, "PARTIALLY_FILLED", fill.Status)
+	assert.Equal(t, 0.004, fill.ExecutedQty)
+	assert.Equal(t, 49990.0, fill.AvgPrice)
+
+	_, err = trader.GetOrder("BTCUSDT", "not-a-number")
+	assert.Error(t, err)
+}
+
+var _ LimitOrderTrader = (*FuturesTrader)(nil)
+
+// TestFuturesTrader_TypedAccount 测试强类型余额/持仓，以及旧版 map 结果保持原字段
+func TestFuturesTrader_TypedAccount(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+	trader := suite.Trader.(*FuturesTrader)
+
+	balance, err := trader.GetBalanceInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, 10000.0, balance.WalletBalance)
+	assert.Equal(t, 8000.0, balance.AvailableBalance)
+	assert.InDelta(t, 10100.5, balance.Equity(), 1e-9)
+
+	positions, err := trader.GetPositionList()
+	assert.NoError(t, err)
+	if assert.Len(t, positions, 1) {
+		assert.Equal(t, "long", positions[0].Side)
+		assert.Equal(t, 0.5, positions[0].Quantity)
+		assert.Equal(t, 10.0, positions[0].Leverage)
+	}
+
+	legacy, err := trader.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, 10000.0, legacy["totalWalletBalance"])
+	assert.Equal(t, 100.5, legacy["totalUnrealizedProfit"])
+}
+
+var _ TypedAccountTrader = (*FuturesTrader)(nil)
+
 // TestNewFuturesTrader 测试创建币安合约交易器
 func TestNewFuturesTrader(t *testing.T) {
 	// 创建 mock HTTP 服务器
@@ -343,7 +424,7 @@ func TestNewFuturesTrader(t *testing.T) {
 	defer mockServer.Close()
 
 	// 测试成功创建
-	trader := NewFuturesTrader("test_api_key", "test_secret_key", "test_user")
+	trader := NewFuturesTrader("test_api_key", "test_secret_key", "test_user", false)
 
 	// 修改 client 使用 mock server
 	trader.client.BaseURL = mockServer.URL
@@ -354,6 +435,17 @@ func TestNewFuturesTrader(t *testing.T) {
 	assert.Equal(t, 15*time.Second, trader.cacheDuration)
 }
 
+// TestNewFuturesTrader_Testnet 测试测试网模式切换REST地址，且不影响其他客户端
+func TestNewFuturesTrader_Testnet(t *testing.T) {
+	testnetTrader := NewFuturesTrader("test_api_key", "test_secret_key", "test_user", true)
+	assert.True(t, testnetTrader.IsTestnet())
+	assert.Equal(t, futures.BaseApiTestnetUrl, testnetTrader.client.BaseURL)
+
+	mainnetTrader := NewFuturesTrader("test_api_key", "test_secret_key", "test_user", false)
+	assert.False(t, mainnetTrader.IsTestnet())
+	assert.Equal(t, futures.BaseApiMainUrl, mainnetTrader.client.BaseURL)
+}
+
 // TestCalculatePositionSize 测试仓位计算
 func TestCalculatePositionSize(t *testing.T) {
 	trader := &FuturesTrader{}
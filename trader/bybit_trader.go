@@ -0,0 +1,763 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	bybitMainnetURL  = "https://api.bybit.com"
+	bybitTestnetURL  = "https://api-testnet.bybit.com"
+	bybitRecvWindow  = "5000"
+	bybitCategory    = "linear" // USDT永续合约
+	bybitSettleCoin  = "USDT"
+	bybitHistoryDays = 7 // 成交记录接口单次查询的最大时间跨度（天）
+
+	// 双向持仓模式下的仓位索引
+	bybitPositionIdxLong  = 1
+	bybitPositionIdxShort = 2
+
+	bybitRetLeverageNotModified = 110043 // 杠杆未修改
+	bybitRetPositionModeSame    = 110025 // 持仓模式未修改
+)
+
+// bybitAPIError Bybit接口返回的业务错误（retCode != 0）
+type bybitAPIError struct {
+	Code int
+	Msg  string
+}
+
+func (e *bybitAPIError) Error() string {
+	return fmt.Sprintf("Bybit API错误 retCode=%d: %s", e.Code, e.Msg)
+}
+
+// isBybitRetCode 判断错误是否为指定的Bybit业务错误码
+func isBybitRetCode(err error, code int) bool {
+	var apiErr *bybitAPIError
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// bybitInstrument 交易对精度信息
+type bybitInstrument struct {
+	QtyStep     float64
+	QtyDecimals int
+	MinOrderQty float64
+}
+
+// BybitTrader Bybit USDT永续合约交易器（V5 API，双向持仓模式）
+type BybitTrader struct {
+	apiKey    string
+	secretKey string
+	baseURL   string
+	testnet   bool
+	client    *http.Client
+
+	// 交易对精度缓存
+	instruments   map[string]bybitInstrument
+	instrumentsMu sync.RWMutex
+
+	// 服务器时钟（自动校正签名时间戳）
+	clock *serverClock
+}
+
+// NewBybitTrader 创建Bybit交易器（testnet=true 时连接Bybit测试网）。
+// 构造时不修改账户设置，可安全用于密钥校验、权限检测和余额查询；
+// 实盘运行前需调用 EnsureHedgeMode 切换为双向持仓模式
+func NewBybitTrader(apiKey, secretKey string, testnet bool) *BybitTrader {
+	baseURL := bybitMainnetURL
+	if testnet {
+		baseURL = bybitTestnetURL
+		log.Printf("🧪 Bybit使用测试网: %s", baseURL)
+	}
+
+	trader := &BybitTrader{
+		apiKey:      apiKey,
+		secretKey:   secretKey,
+		baseURL:     baseURL,
+		testnet:     testnet,
+		client:      &http.Client{Timeout: 30 * time.Second},
+		instruments: make(map[string]bybitInstrument),
+	}
+	trader.clock = newServerClock("Bybit", trader.fetchServerTime)
+	trader.clock.sync()
+
+	return trader
+}
+
+// IsTestnet 是否连接Bybit测试网
+func (t *BybitTrader) IsTestnet() bool {
+	return t.testnet
+}
+
+// ClockSkew 获取与Bybit服务器的时钟偏移状态
+func (t *BybitTrader) ClockSkew() ClockSkewStatus {
+	if t.clock == nil {
+		return ClockSkewStatus{}
+	}
+	return t.clock.status()
+}
+
+// fetchServerTime 获取Bybit服务器时间（毫秒）
+func (t *BybitTrader) fetchServerTime() (int64, error) {
+	resp, err := t.client.Get(t.baseURL + "/v5/market/time")
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		Time int64 `json:"time"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return 0, fmt.Errorf("解析服务器时间失败: %w", err)
+	}
+	if result.Time <= 0 {
+		return 0, fmt.Errorf("服务器时间无效: %s", string(body))
+	}
+	return result.Time, nil
+}
+
+// sign 计算请求签名：HMAC_SHA256(timestamp + apiKey + recvWindow + 参数)
+func (t *BybitTrader) sign(timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(t.secretKey))
+	mac.Write([]byte(timestamp + t.apiKey + bybitRecvWindow + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// request 发送请求并解析 result 字段，签名请求遇到时间戳错误时自动重新同步时钟
+func (t *BybitTrader) request(method, path string, params map[string]interface{}, signed bool, out interface{}) error {
+	return withTimestampResyncErr(t.clock, func() error {
+		return t.doRequest(method, path, params, signed, out)
+	})
+}
+
+func (t *BybitTrader) doRequest(method, path string, params map[string]interface{}, signed bool, out interface{}) error {
+	var query, body string
+	if method == http.MethodGet {
+		values := url.Values{}
+		for key, value := range params {
+			values.Set(key, fmt.Sprint(value))
+		}
+		query = values.Encode()
+	} else {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("序列化请求参数失败: %w", err)
+		}
+		body = string(data)
+	}
+
+	fullURL := t.baseURL + path
+	if query != "" {
+		fullURL += "?" + query
+	}
+	req, err := http.NewRequest(method, fullURL, strings.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if method != http.MethodGet {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if signed {
+		timestamp := strconv.FormatInt(t.clock.serverNowMillis(), 10)
+		payload := query
+		if method != http.MethodGet {
+			payload = body
+		}
+		req.Header.Set("X-BAPI-API-KEY", t.apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", timestamp)
+		req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+		req.Header.Set("X-BAPI-SIGN", t.sign(timestamp, payload))
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result struct {
+		RetCode int             `json:"retCode"`
+		RetMsg  string          `json:"retMsg"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.RetCode != 0 {
+		return &bybitAPIError{Code: result.RetCode, Msg: result.RetMsg}
+	}
+	if out != nil && len(result.Result) > 0 {
+		if err := json.Unmarshal(result.Result, out); err != nil {
+			return fmt.Errorf("解析响应数据失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// EnsureHedgeMode 设置USDT永续为双向持仓模式（代码中按多/空分别开平仓，交易员启动时调用）
+func (t *BybitTrader) EnsureHedgeMode() error {
+	err := t.request(http.MethodPost, "/v5/position/switch-mode", map[string]interface{}{
+		"category": bybitCategory,
+		"coin":     bybitSettleCoin,
+		"mode":     3, // 3 = 双向持仓
+	}, true, nil)
+	if isBybitRetCode(err, bybitRetPositionModeSame) {
+		log.Printf("  ✓ Bybit账户已是双向持仓模式")
+		return nil
+	}
+	return err
+}
+
+// GetBalance 获取账户余额（统一账户，返回字段与币安合约账户一致）
+func (t *BybitTrader) GetBalance() (map[string]interface{}, error) {
+	var result struct {
+		List []struct {
+			TotalWalletBalance    string `json:"totalWalletBalance"`
+			TotalAvailableBalance string `json:"totalAvailableBalance"`
+			TotalPerpUPL          string `json:"totalPerpUPL"`
+		} `json:"list"`
+	}
+	if err := t.request(http.MethodGet, "/v5/account/wallet-balance", map[string]interface{}{
+		"accountType": "UNIFIED",
+	}, true, &result); err != nil {
+		return nil, fmt.Errorf("获取账户余额失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return nil, fmt.Errorf("获取账户余额失败: 未返回统一账户信息")
+	}
+
+	account := result.List[0]
+	balance := make(map[string]interface{})
+	balance["totalWalletBalance"], _ = strconv.ParseFloat(account.TotalWalletBalance, 64)
+	balance["availableBalance"], _ = strconv.ParseFloat(account.TotalAvailableBalance, 64)
+	balance["totalUnrealizedProfit"], _ = strconv.ParseFloat(account.TotalPerpUPL, 64)
+	return balance, nil
+}
+
+// GetPositions 获取所有持仓（空仓 positionAmt 为负数，与币安一致）
+func (t *BybitTrader) GetPositions() ([]map[string]interface{}, error) {
+	var result struct {
+		List []struct {
+			Symbol        string `json:"symbol"`
+			Side          string `json:"side"`
+			Size          string `json:"size"`
+			AvgPrice      string `json:"avgPrice"`
+			MarkPrice     string `json:"markPrice"`
+			UnrealisedPnl string `json:"unrealisedPnl"`
+			Leverage      string `json:"leverage"`
+			LiqPrice      string `json:"liqPrice"`
+			PositionIdx   int    `json:"positionIdx"`
+		} `json:"list"`
+	}
+	if err := t.request(http.MethodGet, "/v5/position/list", map[string]interface{}{
+		"category":   bybitCategory,
+		"settleCoin": bybitSettleCoin,
+		"limit":      200,
+	}, true, &result); err != nil {
+		return nil, fmt.Errorf("获取持仓失败: %w", err)
+	}
+
+	positions := make([]map[string]interface{}, 0, len(result.List))
+	for _, pos := range result.List {
+		size, _ := strconv.ParseFloat(pos.Size, 64)
+		if size == 0 {
+			continue // 跳过无持仓的
+		}
+
+		side := "long"
+		if pos.PositionIdx == bybitPositionIdxShort || (pos.PositionIdx == 0 && pos.Side == "Sell") {
+			side = "short"
+			size = -size
+		}
+
+		posMap := make(map[string]interface{})
+		posMap["symbol"] = pos.Symbol
+		posMap["side"] = side
+		posMap["positionAmt"] = size
+		posMap["entryPrice"], _ = strconv.ParseFloat(pos.AvgPrice, 64)
+		posMap["markPrice"], _ = strconv.ParseFloat(pos.MarkPrice, 64)
+		posMap["unRealizedProfit"], _ = strconv.ParseFloat(pos.UnrealisedPnl, 64)
+		posMap["leverage"], _ = strconv.ParseFloat(pos.Leverage, 64)
+		posMap["liquidationPrice"], _ = strconv.ParseFloat(pos.LiqPrice, 64)
+		positions = append(positions, posMap)
+	}
+	return positions, nil
+}
+
+// OpenLong 开多仓
+func (t *BybitTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, "Buy", bybitPositionIdxLong, quantity, leverage)
+}
+
+// OpenShort 开空仓
+func (t *BybitTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, "Sell", bybitPositionIdxShort, quantity, leverage)
+}
+
+// CloseLong 平多仓（quantity=0表示全部平仓）
+func (t *BybitTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "long", "Sell", bybitPositionIdxLong, quantity)
+}
+
+// CloseShort 平空仓（quantity=0表示全部平仓）
+func (t *BybitTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "short", "Buy", bybitPositionIdxShort, quantity)
+}
+
+// openPosition 先清理旧委托、设置杠杆，再按市价开仓
+func (t *BybitTrader) openPosition(symbol, side string, positionIdx int, quantity float64, leverage int) (map[string]interface{}, error) {
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消旧委托单失败（可能没有委托单）: %v", err)
+	}
+	if err := t.SetLeverage(symbol, leverage); err != nil {
+		return nil, err
+	}
+
+	action := "开多仓"
+	if positionIdx == bybitPositionIdxShort {
+		action = "开空仓"
+	}
+	return t.placeMarketOrder(action, symbol, side, positionIdx, quantity, false)
+}
+
+// closePosition 按市价只减仓平仓，平仓后清理该币种的委托单
+func (t *BybitTrader) closePosition(symbol, positionSide, side string, positionIdx int, quantity float64) (map[string]interface{}, error) {
+	if quantity == 0 {
+		positions, err := t.GetPositions()
+		if err != nil {
+			return nil, err
+		}
+		for _, pos := range positions {
+			if pos["symbol"] == symbol && pos["side"] == positionSide {
+				quantity = math.Abs(pos["positionAmt"].(float64))
+				break
+			}
+		}
+		if quantity == 0 {
+			return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, map[string]string{"long": "多", "short": "空"}[positionSide])
+		}
+	}
+
+	action := "平多仓"
+	if positionIdx == bybitPositionIdxShort {
+		action = "平空仓"
+	}
+	result, err := t.placeMarketOrder(action, symbol, side, positionIdx, quantity, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.CancelAllOrders(symbol); err != nil {
+		log.Printf("  ⚠ 取消挂单失败: %v", err)
+	}
+	return result, nil
+}
+
+// placeMarketOrder 下市价单（reduceOnly=true 时只减仓，不会反向开仓）
+func (t *BybitTrader) placeMarketOrder(action, symbol, side string, positionIdx int, quantity float64, reduceOnly bool) (map[string]interface{}, error) {
+	quantityStr, err := t.FormatQuantity(symbol, quantity)
+	if err != nil {
+		return nil, err
+	}
+	if qty, _ := strconv.ParseFloat(quantityStr, 64); qty <= 0 {
+		return nil, fmt.Errorf("%s数量过小，格式化后为 0 (原始: %.8f → 格式化: %s)", action, quantity, quantityStr)
+	}
+
+	var order struct {
+		OrderID     string `json:"orderId"`
+		OrderLinkID string `json:"orderLinkId"`
+	}
+	if err := t.request(http.MethodPost, "/v5/order/create", map[string]interface{}{
+		"category":    bybitCategory,
+		"symbol":      symbol,
+		"side":        side,
+		"orderType":   "Market",
+		"qty":         quantityStr,
+		"positionIdx": positionIdx,
+		"reduceOnly":  reduceOnly,
+	}, true, &order); err != nil {
+		return nil, fmt.Errorf("%s失败: %w", action, err)
+	}
+
+	log.Printf("✓ %s成功: %s 数量: %s", action, symbol, quantityStr)
+
+	result := make(map[string]interface{})
+	result["orderId"] = order.OrderID
+	result["symbol"] = symbol
+	result["status"] = "NEW"
+	return result, nil
+}
+
+// SetLeverage 设置杠杆（多空两侧使用相同杠杆）
+func (t *BybitTrader) SetLeverage(symbol string, leverage int) error {
+	err := t.request(http.MethodPost, "/v5/position/set-leverage", map[string]interface{}{
+		"category":     bybitCategory,
+		"symbol":       symbol,
+		"buyLeverage":  strconv.Itoa(leverage),
+		"sellLeverage": strconv.Itoa(leverage),
+	}, true, nil)
+	if isBybitRetCode(err, bybitRetLeverageNotModified) {
+		log.Printf("  ✓ %s 杠杆已是 %dx", symbol, leverage)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("设置杠杆失败: %w", err)
+	}
+	log.Printf("  ✓ %s 杠杆已切换为 %dx", symbol, leverage)
+	return nil
+}
+
+// SetMarginMode 设置仓位模式（Bybit统一账户的全仓/逐仓为账户级别设置）
+func (t *BybitTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	mode, modeStr := "REGULAR_MARGIN", "全仓"
+	if !isCrossMargin {
+		mode, modeStr = "ISOLATED_MARGIN", "逐仓"
+	}
+
+	err := t.request(http.MethodPost, "/v5/account/set-margin-mode", map[string]interface{}{
+		"setMarginMode": mode,
+	}, true, nil)
+	if err != nil {
+		// 有持仓或挂单时无法切换，不影响交易
+		log.Printf("  ⚠️ %s 设置Bybit仓位模式为%s失败，继续使用当前模式: %v", symbol, modeStr, err)
+		return nil
+	}
+	log.Printf("  ✓ Bybit账户仓位模式已设置为%s", modeStr)
+	return nil
+}
+
+// GetMarketPrice 获取最新成交价
+func (t *BybitTrader) GetMarketPrice(symbol string) (float64, error) {
+	var result struct {
+		List []struct {
+			LastPrice string `json:"lastPrice"`
+		} `json:"list"`
+	}
+	if err := t.request(http.MethodGet, "/v5/market/tickers", map[string]interface{}{
+		"category": bybitCategory,
+		"symbol":   symbol,
+	}, false, &result); err != nil {
+		return 0, fmt.Errorf("获取价格失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return 0, fmt.Errorf("获取价格失败: 未找到 %s 的行情", symbol)
+	}
+	return strconv.ParseFloat(result.List[0].LastPrice, 64)
+}
+
+// SetStopLoss 设置止损（挂在仓位上，按标记价格触发）
+func (t *BybitTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.setTradingStop(symbol, bybitPositionIdx(positionSide), map[string]interface{}{
+		"stopLoss":    strconv.FormatFloat(stopPrice, 'f', -1, 64),
+		"slTriggerBy": "MarkPrice",
+	})
+}
+
+// SetTakeProfit 设置止盈（挂在仓位上，按标记价格触发）
+func (t *BybitTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.setTradingStop(symbol, bybitPositionIdx(positionSide), map[string]interface{}{
+		"takeProfit":  strconv.FormatFloat(takeProfitPrice, 'f', -1, 64),
+		"tpTriggerBy": "MarkPrice",
+	})
+}
+
+// CancelStopLossOrders 仅取消止损（保留止盈）
+func (t *BybitTrader) CancelStopLossOrders(symbol string) error {
+	return t.clearTradingStops(symbol, map[string]interface{}{"stopLoss": "0"})
+}
+
+// CancelTakeProfitOrders 仅取消止盈（保留止损）
+func (t *BybitTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.clearTradingStops(symbol, map[string]interface{}{"takeProfit": "0"})
+}
+
+// CancelAllOrders 取消该币种的所有挂单
+func (t *BybitTrader) CancelAllOrders(symbol string) error {
+	if err := t.request(http.MethodPost, "/v5/order/cancel-all", map[string]interface{}{
+		"category": bybitCategory,
+		"symbol":   symbol,
+	}, true, nil); err != nil {
+		return fmt.Errorf("取消挂单失败: %w", err)
+	}
+	return nil
+}
+
+// CancelStopOrders 取消该币种的止盈止损
+func (t *BybitTrader) CancelStopOrders(symbol string) error {
+	return t.clearTradingStops(symbol, map[string]interface{}{"stopLoss": "0", "takeProfit": "0"})
+}
+
+// setTradingStop 修改仓位的止盈止损设置
+func (t *BybitTrader) setTradingStop(symbol string, positionIdx int, fields map[string]interface{}) error {
+	params := map[string]interface{}{
+		"category":    bybitCategory,
+		"symbol":      symbol,
+		"positionIdx": positionIdx,
+		"tpslMode":    "Full",
+	}
+	for key, value := range fields {
+		params[key] = value
+	}
+	if err := t.request(http.MethodPost, "/v5/position/trading-stop", params, true, nil); err != nil {
+		return fmt.Errorf("设置止盈止损失败: %w", err)
+	}
+	return nil
+}
+
+// clearTradingStops 清除该币种所有持仓方向上的止盈/止损（无持仓时无需处理）
+func (t *BybitTrader) clearTradingStops(symbol string, fields map[string]interface{}) error {
+	positions, err := t.GetPositions()
+	if err != nil {
+		return err
+	}
+	for _, pos := range positions {
+		if pos["symbol"] != symbol {
+			continue
+		}
+		side, _ := pos["side"].(string)
+		if err := t.setTradingStop(symbol, bybitPositionIdx(side), fields); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bybitPositionIdx 将持仓方向（LONG/SHORT，大小写不敏感）转换为双向持仓模式的仓位索引
+func bybitPositionIdx(positionSide string) int {
+	if strings.EqualFold(positionSide, "short") {
+		return bybitPositionIdxShort
+	}
+	return bybitPositionIdxLong
+}
+
+// FormatQuantity 按交易对的数量步进值向下取整并格式化
+func (t *BybitTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	instrument, err := t.getInstrument(symbol)
+	if err != nil {
+		// 如果获取失败，使用默认格式
+		return fmt.Sprintf("%.3f", quantity), nil
+	}
+	if instrument.QtyStep > 0 {
+		// 加上极小值避免浮点误差导致少取一个步进
+		quantity = math.Floor(quantity/instrument.QtyStep+1e-9) * instrument.QtyStep
+	}
+	return strconv.FormatFloat(quantity, 'f', instrument.QtyDecimals, 64), nil
+}
+
+// getInstrument 获取交易对精度信息（带缓存）
+func (t *BybitTrader) getInstrument(symbol string) (bybitInstrument, error) {
+	t.instrumentsMu.RLock()
+	instrument, ok := t.instruments[symbol]
+	t.instrumentsMu.RUnlock()
+	if ok {
+		return instrument, nil
+	}
+
+	var result struct {
+		List []struct {
+			LotSizeFilter struct {
+				QtyStep     string `json:"qtyStep"`
+				MinOrderQty string `json:"minOrderQty"`
+			} `json:"lotSizeFilter"`
+		} `json:"list"`
+	}
+	if err := t.request(http.MethodGet, "/v5/market/instruments-info", map[string]interface{}{
+		"category": bybitCategory,
+		"symbol":   symbol,
+	}, false, &result); err != nil {
+		return bybitInstrument{}, fmt.Errorf("获取交易对信息失败: %w", err)
+	}
+	if len(result.List) == 0 {
+		return bybitInstrument{}, fmt.Errorf("未找到交易对 %s", symbol)
+	}
+
+	lot := result.List[0].LotSizeFilter
+	instrument.QtyStep, _ = strconv.ParseFloat(lot.QtyStep, 64)
+	instrument.MinOrderQty, _ = strconv.ParseFloat(lot.MinOrderQty, 64)
+	if dot := strings.IndexByte(lot.QtyStep, '.'); dot >= 0 {
+		instrument.QtyDecimals = len(strings.TrimRight(lot.QtyStep[dot+1:], "0"))
+	}
+
+	t.instrumentsMu.Lock()
+	t.instruments[symbol] = instrument
+	t.instrumentsMu.Unlock()
+	return instrument, nil
+}
+
+// bybitExecution 成交记录
+type bybitExecution struct {
+	Symbol     string `json:"symbol"`
+	Side       string `json:"side"`
+	OrderID    string `json:"orderId"`
+	ExecPrice  string `json:"execPrice"`
+	ExecQty    string `json:"execQty"`
+	ExecFee    string `json:"execFee"`
+	ExecTime   string `json:"execTime"`
+	ExecType   string `json:"execType"`
+	ClosedSize string `json:"closedSize"`
+}
+
+// bybitClosedPnl 平仓盈亏记录（按订单汇总，已扣除开平仓手续费）
+type bybitClosedPnl struct {
+	OrderID   string `json:"orderId"`
+	Qty       string `json:"qty"`
+	ClosedPnl string `json:"closedPnl"`
+}
+
+// GetAllTradeHistory 获取所有币种的交易历史（转换为与币安一致的结构，供表现分析使用）
+func (t *BybitTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*Trade, error) {
+	end := time.Now()
+	start := end.AddDate(0, 0, -lookbackDays)
+
+	var executions []bybitExecution
+	var closedPnls []bybitClosedPnl
+	// 接口单次查询最多跨越7天，按窗口分段并翻页
+	for windowStart := start; windowStart.Before(end); windowStart = windowStart.AddDate(0, 0, bybitHistoryDays) {
+		windowEnd := windowStart.AddDate(0, 0, bybitHistoryDays)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		if err := t.listPaged("/v5/execution/list", windowStart, windowEnd, func(raw json.RawMessage) error {
+			var page []bybitExecution
+			err := json.Unmarshal(raw, &page)
+			executions = append(executions, page...)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("获取成交记录失败: %w", err)
+		}
+		if err := t.listPaged("/v5/position/closed-pnl", windowStart, windowEnd, func(raw json.RawMessage) error {
+			var page []bybitClosedPnl
+			err := json.Unmarshal(raw, &page)
+			closedPnls = append(closedPnls, page...)
+			return err
+		}); err != nil {
+			return nil, fmt.Errorf("获取平仓盈亏失败: %w", err)
+		}
+	}
+
+	return buildBybitTradeHistory(executions, closedPnls), nil
+}
+
+// listPaged 按游标翻页查询列表接口
+func (t *BybitTrader) listPaged(path string, start, end time.Time, handle func(list json.RawMessage) error) error {
+	cursor := ""
+	for {
+		params := map[string]interface{}{
+			"category":  bybitCategory,
+			"startTime": start.UnixMilli(),
+			"endTime":   end.UnixMilli(),
+			"limit":     100,
+		}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		var page struct {
+			List           json.RawMessage `json:"list"`
+			NextPageCursor string          `json:"nextPageCursor"`
+		}
+		if err := t.request(http.MethodGet, path, params, true, &page); err != nil {
+			return err
+		}
+		if len(page.List) > 0 {
+			if err := handle(page.List); err != nil {
+				return err
+			}
+		}
+		if page.NextPageCursor == "" || page.NextPageCursor == cursor {
+			return nil
+		}
+		cursor = page.NextPageCursor
+	}
+}
+
+// buildBybitTradeHistory 将成交记录转换为按币种分组、按时间升序的交易历史
+// 双向持仓下：closedSize>0 的成交为平仓（买入平空、卖出平多），否则为开仓（买入开多、卖出开空）
+// Bybit的平仓盈亏已扣除开平仓手续费，因此按成交数量分摊到平仓成交的 RealizedPnl，且平仓成交不再单独计手续费
+func buildBybitTradeHistory(executions []bybitExecution, closedPnls []bybitClosedPnl) map[string][]*Trade {
+	type orderPnl struct{ qty, pnl float64 }
+	pnlByOrder := make(map[string]orderPnl)
+	for _, record := range closedPnls {
+		qty, _ := strconv.ParseFloat(record.Qty, 64)
+		pnl, _ := strconv.ParseFloat(record.ClosedPnl, 64)
+		current := pnlByOrder[record.OrderID]
+		pnlByOrder[record.OrderID] = orderPnl{qty: current.qty + qty, pnl: current.pnl + pnl}
+	}
+
+	result := make(map[string][]*Trade)
+	for _, exec := range executions {
+		if exec.ExecType != "" && exec.ExecType != "Trade" {
+			continue // 跳过资金费等非交易记录
+		}
+		price, _ := strconv.ParseFloat(exec.ExecPrice, 64)
+		qty, _ := strconv.ParseFloat(exec.ExecQty, 64)
+		fee, _ := strconv.ParseFloat(exec.ExecFee, 64)
+		closedSize, _ := strconv.ParseFloat(exec.ClosedSize, 64)
+		execTime, _ := strconv.ParseInt(exec.ExecTime, 10, 64)
+
+		side := strings.ToUpper(exec.Side)
+		trade := &Trade{
+			Symbol:          exec.Symbol,
+			Side:            side,
+			Price:           price,
+			Qty:             qty,
+			CommissionAsset: bybitSettleCoin,
+			Time:            execTime,
+			Buyer:           side == "BUY",
+		}
+
+		closing := closedSize > 0
+		switch {
+		case closing && side == "BUY":
+			trade.PositionSide = "SHORT"
+		case closing:
+			trade.PositionSide = "LONG"
+		case side == "BUY":
+			trade.PositionSide = "LONG"
+		default:
+			trade.PositionSide = "SHORT"
+		}
+
+		if closing {
+			if order, ok := pnlByOrder[exec.OrderID]; ok && order.qty > 0 {
+				trade.RealizedPnl = order.pnl * qty / order.qty
+			}
+		} else {
+			trade.Commission = fee
+		}
+
+		result[exec.Symbol] = append(result[exec.Symbol], trade)
+	}
+
+	for _, trades := range result {
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time < trades[j].Time })
+	}
+	return result
+}
@@ -0,0 +1,188 @@
+package trader
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBybitTrader 创建指向 mock 服务器的Bybit交易器（不调用构造函数，避免访问真实网络）
+func newTestBybitTrader(t *testing.T, handler http.HandlerFunc) *BybitTrader {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	trader := &BybitTrader{
+		apiKey:      "test_api_key",
+		secretKey:   "test_secret_key",
+		baseURL:     server.URL,
+		client:      server.Client(),
+		instruments: make(map[string]bybitInstrument),
+	}
+	trader.clock = newServerClock("Bybit", trader.fetchServerTime)
+	return trader
+}
+
+func writeBybitResult(w http.ResponseWriter, result interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"retCode": 0, "retMsg": "OK", "result": result})
+}
+
+// TestBybitTrader_SignedRequest 测试签名请求头与签名内容
+func TestBybitTrader_SignedRequest(t *testing.T) {
+	var body string
+	var header http.Header
+	trader := newTestBybitTrader(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, header = string(data), r.Header.Clone()
+		writeBybitResult(w, map[string]interface{}{})
+	})
+
+	require.NoError(t, trader.SetLeverage("BTCUSDT", 10))
+
+	assert.Equal(t, "test_api_key", header.Get("X-BAPI-API-KEY"))
+	assert.Equal(t, bybitRecvWindow, header.Get("X-BAPI-RECV-WINDOW"))
+	mac := hmac.New(sha256.New, []byte("test_secret_key"))
+	mac.Write([]byte(header.Get("X-BAPI-TIMESTAMP") + "test_api_key" + bybitRecvWindow + body))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), header.Get("X-BAPI-SIGN"))
+
+	var params map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(body), &params))
+	assert.Equal(t, "10", params["buyLeverage"])
+	assert.Equal(t, "10", params["sellLeverage"])
+}
+
+// TestBybitTrader_BalanceAndPositions 测试余额与持仓映射为与币安一致的字段
+func TestBybitTrader_BalanceAndPositions(t *testing.T) {
+	trader := newTestBybitTrader(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v5/account/wallet-balance":
+			assert.Equal(t, "UNIFIED", r.URL.Query().Get("accountType"))
+			writeBybitResult(w, map[string]interface{}{"list": []map[string]interface{}{{
+				"totalWalletBalance":    "1000.5",
+				"totalAvailableBalance": "800.25",
+				"totalPerpUPL":          "-12.5",
+			}}})
+		case "/v5/position/list":
+			writeBybitResult(w, map[string]interface{}{"list": []map[string]interface{}{
+				{"symbol": "BTCUSDT", "side": "Buy", "size": "0.01", "avgPrice": "60000", "markPrice": "61000", "unrealisedPnl": "10", "leverage": "5", "liqPrice": "50000", "positionIdx": 1},
+				{"symbol": "ETHUSDT", "side": "Sell", "size": "2", "avgPrice": "3000", "markPrice": "3100", "unrealisedPnl": "-200", "leverage": "3", "liqPrice": "4000", "positionIdx": 2},
+				{"symbol": "SOLUSDT", "side": "", "size": "0", "positionIdx": 1},
+			}})
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	})
+
+	balance, err := trader.GetBalance()
+	require.NoError(t, err)
+	assert.Equal(t, 1000.5, balance["totalWalletBalance"])
+	assert.Equal(t, 800.25, balance["availableBalance"])
+	assert.Equal(t, -12.5, balance["totalUnrealizedProfit"])
+
+	positions, err := trader.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 2, "空仓位应被跳过")
+	assert.Equal(t, "long", positions[0]["side"])
+	assert.Equal(t, 0.01, positions[0]["positionAmt"])
+	assert.Equal(t, 5.0, positions[0]["leverage"])
+	assert.Equal(t, "short", positions[1]["side"])
+	assert.Equal(t, -2.0, positions[1]["positionAmt"], "空仓数量应为负数")
+	assert.Equal(t, 4000.0, positions[1]["liquidationPrice"])
+}
+
+// TestBybitTrader_APIError 测试业务错误码转换为错误，且"杠杆未修改"视为成功
+func TestBybitTrader_APIError(t *testing.T) {
+	retCode := bybitRetLeverageNotModified
+	trader := newTestBybitTrader(t, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"retCode": retCode, "retMsg": "leverage not modified"})
+	})
+
+	assert.NoError(t, trader.SetLeverage("BTCUSDT", 5))
+
+	retCode = 10003
+	err := trader.SetLeverage("BTCUSDT", 5)
+	require.Error(t, err)
+	assert.True(t, isBybitRetCode(err, 10003))
+	assert.Contains(t, err.Error(), "retCode=10003")
+}
+
+// TestBybitTrader_EnsureHedgeMode 测试切换双向持仓模式，且"持仓模式未修改"视为成功
+func TestBybitTrader_EnsureHedgeMode(t *testing.T) {
+	var path string
+	var params map[string]interface{}
+	trader := newTestBybitTrader(t, func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&params)
+		json.NewEncoder(w).Encode(map[string]interface{}{"retCode": bybitRetPositionModeSame, "retMsg": "position mode not modified"})
+	})
+
+	require.NoError(t, trader.EnsureHedgeMode())
+	assert.Equal(t, "/v5/position/switch-mode", path)
+	assert.Equal(t, float64(3), params["mode"])
+}
+
+// TestBybitTrader_FormatQuantity 测试按数量步进值向下取整
+func TestBybitTrader_FormatQuantity(t *testing.T) {
+	trader := newTestBybitTrader(t, func(w http.ResponseWriter, r *http.Request) {
+		writeBybitResult(w, map[string]interface{}{"list": []map[string]interface{}{{
+			"lotSizeFilter": map[string]string{"qtyStep": "0.001", "minOrderQty": "0.001"},
+		}}})
+	})
+
+	qty, err := trader.FormatQuantity("BTCUSDT", 0.12345)
+	require.NoError(t, err)
+	assert.Equal(t, "0.123", qty)
+
+	qty, err = trader.FormatQuantity("BTCUSDT", 0.3)
+	require.NoError(t, err)
+	assert.Equal(t, "0.300", qty, "浮点误差不应导致少取一个步进")
+}
+
+// TestBuildBybitTradeHistory 测试成交记录转换：开平方向识别、平仓盈亏按数量分摊、按时间排序
+func TestBuildBybitTradeHistory(t *testing.T) {
+	executions := []bybitExecution{
+		// 平多（卖出），订单分两笔成交
+		{Symbol: "BTCUSDT", Side: "Sell", OrderID: "close-1", ExecPrice: "61000", ExecQty: "0.006", ExecFee: "0.2", ExecTime: "3000", ExecType: "Trade", ClosedSize: "0.006"},
+		{Symbol: "BTCUSDT", Side: "Sell", OrderID: "close-1", ExecPrice: "61000", ExecQty: "0.004", ExecFee: "0.1", ExecTime: "3001", ExecType: "Trade", ClosedSize: "0.004"},
+		// 开多（买入）
+		{Symbol: "BTCUSDT", Side: "Buy", OrderID: "open-1", ExecPrice: "60000", ExecQty: "0.01", ExecFee: "0.3", ExecTime: "1000", ExecType: "Trade", ClosedSize: "0"},
+		// 平空（买入）
+		{Symbol: "ETHUSDT", Side: "Buy", OrderID: "close-2", ExecPrice: "2900", ExecQty: "1", ExecFee: "0.5", ExecTime: "2000", ExecType: "Trade", ClosedSize: "1"},
+		// 资金费记录应被忽略
+		{Symbol: "ETHUSDT", Side: "Sell", OrderID: "", ExecPrice: "2950", ExecQty: "1", ExecFee: "0.1", ExecTime: "2500", ExecType: "Funding"},
+	}
+	closed := []bybitClosedPnl{
+		{OrderID: "close-1", Qty: "0.01", ClosedPnl: "9.4"},
+		{OrderID: "close-2", Qty: "1", ClosedPnl: "98.5"},
+	}
+
+	history := buildBybitTradeHistory(executions, closed)
+
+	btc := history["BTCUSDT"]
+	require.Len(t, btc, 3)
+	assert.Equal(t, int64(1000), btc[0].Time, "应按时间升序")
+	assert.Equal(t, "BUY", btc[0].Side)
+	assert.Equal(t, "LONG", btc[0].PositionSide)
+	assert.Equal(t, 0.3, btc[0].Commission)
+	assert.Zero(t, btc[0].RealizedPnl)
+
+	assert.Equal(t, "SELL", btc[1].Side)
+	assert.Equal(t, "LONG", btc[1].PositionSide)
+	assert.InDelta(t, 9.4*0.6, btc[1].RealizedPnl, 1e-9)
+	assert.InDelta(t, 9.4*0.4, btc[2].RealizedPnl, 1e-9)
+	assert.Zero(t, btc[1].Commission, "平仓盈亏已含手续费，不应重复扣除")
+
+	eth := history["ETHUSDT"]
+	require.Len(t, eth, 1)
+	assert.Equal(t, "SHORT", eth[0].PositionSide)
+	assert.True(t, eth[0].Buyer)
+	assert.Equal(t, 98.5, eth[0].RealizedPnl)
+}
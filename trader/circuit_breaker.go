@@ -0,0 +1,178 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/logger"
+	"strconv"
+	"time"
+)
+
+// systemConfigStore 系统配置读取接口（由 config.Database 实现）
+type systemConfigStore interface {
+	GetSystemConfig(key string) (string, error)
+}
+
+// dailyLossState 日亏损熔断状态（当前交易日起点净值、是否已熔断）
+type dailyLossState struct {
+	dayStart       time.Time // 当前交易日起点（UTC日界）
+	dayStartEquity float64   // 交易日起点净值（本交易日首次观测到的净值）
+	lastEquity     float64   // 最近一次观测到的净值
+	tripped        bool      // 是否已熔断
+	trippedAt      time.Time // 熔断时间
+}
+
+// dailyLossDayStart 计算当前时刻所属交易日的起点（每天 UTC resetHour 点为日界）
+func dailyLossDayStart(now time.Time, resetHour int) time.Time {
+	now = now.UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), resetHour, 0, 0, 0, time.UTC)
+	if now.Before(start) {
+		start = start.AddDate(0, 0, -1)
+	}
+	return start
+}
+
+// dailyLossResetHour 读取日界小时（系统配置 daily_loss_reset_hour，默认 0 即 00:00 UTC）
+func (at *AutoTrader) dailyLossResetHour() int {
+	store, ok := at.database.(systemConfigStore)
+	if !ok {
+		return 0
+	}
+	value, _ := store.GetSystemConfig("daily_loss_reset_hour")
+	hour, err := strconv.Atoi(value)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0
+	}
+	return hour
+}
+
+// SetDailyLossLimit 修改最大日亏损百分比（<=0 关闭熔断）和熔断时是否平仓，从下一个决策周期开始生效
+func (at *AutoTrader) SetDailyLossLimit(maxDailyLossPct float64, flatten bool) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.config.MaxDailyLossPct = maxDailyLossPct
+	at.config.DailyLossFlatten = flatten
+}
+
+// getDailyLossLimit 读取最大日亏损百分比和熔断时是否平仓
+func (at *AutoTrader) getDailyLossLimit() (float64, bool) {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.config.MaxDailyLossPct, at.config.DailyLossFlatten
+}
+
+// IsCircuitBroken 是否因当日亏损超限而熔断（熔断期间禁止开新仓）
+func (at *AutoTrader) IsCircuitBroken() bool {
+	at.dailyLossMu.RLock()
+	defer at.dailyLossMu.RUnlock()
+	return at.dailyLoss.tripped
+}
+
+// checkDailyLossBreaker 按当前净值更新当日盈亏（已实现+未实现），超过阈值时熔断，进入新交易日时自动解除
+// 触发和解除都会写入决策记录，便于在净值曲线上解释停止交易的区间
+func (at *AutoTrader) checkDailyLossBreaker(now time.Time, equity float64, record *logger.DecisionRecord) {
+	limit, flatten := at.getDailyLossLimit()
+	dayStart := dailyLossDayStart(now, at.dailyLossResetHour())
+
+	at.dailyLossMu.Lock()
+	state := &at.dailyLoss
+	if !dayStart.Equal(state.dayStart) {
+		if state.tripped {
+			record.CircuitBreaker = &logger.CircuitBreakerEvent{
+				Type:           "reset",
+				LimitPct:       limit,
+				DayStartEquity: equity,
+				Equity:         equity,
+			}
+			record.ExecutionLog = append(record.ExecutionLog, "✅ 新交易日开始，日亏损熔断已解除")
+			log.Printf("✅ [%s] 新交易日开始，日亏损熔断已解除", at.name)
+		}
+		*state = dailyLossState{dayStart: dayStart, dayStartEquity: equity}
+	}
+	state.lastEquity = equity
+
+	if limit <= 0 || state.tripped || state.dayStartEquity <= 0 {
+		at.dailyLossMu.Unlock()
+		return
+	}
+	lossPct := (state.dayStartEquity - equity) / state.dayStartEquity * 100
+	if lossPct < limit {
+		at.dailyLossMu.Unlock()
+		return
+	}
+
+	state.tripped = true
+	state.trippedAt = now
+	event := &logger.CircuitBreakerEvent{
+		Type:           "tripped",
+		LossPct:        lossPct,
+		LimitPct:       limit,
+		DayStartEquity: state.dayStartEquity,
+		Equity:         equity,
+		ResumeAt:       dayStart.AddDate(0, 0, 1),
+	}
+	at.dailyLossMu.Unlock()
+
+	message := fmt.Sprintf("⛔ 日亏损熔断: 当日亏损 %.2f%% (净值 %.2f → %.2f) 已超过上限 %.2f%%，停止开新仓直到 %s",
+		lossPct, event.DayStartEquity, equity, limit, event.ResumeAt.Format("2006-01-02 15:04 MST"))
+	log.Printf("[%s] %s", at.name, message)
+	record.ExecutionLog = append(record.ExecutionLog, message)
+
+	if flatten {
+		results, err := at.CloseAllPositions()
+		if err != nil {
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 熔断平仓失败: %v", err))
+		} else {
+			event.Flattened = true
+			for _, result := range results {
+				if !result.Closed {
+					event.Flattened = false
+					record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("❌ 熔断平仓 %s %s 失败: %s", result.Symbol, result.Side, result.Error))
+				}
+			}
+			if event.Flattened {
+				record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("✓ 熔断已平掉全部 %d 个持仓", len(results)))
+			}
+		}
+	}
+	record.CircuitBreaker = event
+	at.publishEvent(EventCircuitBreaker, event)
+}
+
+// dailyLossStatus 日亏损熔断状态（供 /api/status 展示剩余亏损额度）
+func (at *AutoTrader) dailyLossStatus() map[string]interface{} {
+	limit, _ := at.getDailyLossLimit()
+
+	at.dailyLossMu.RLock()
+	state := at.dailyLoss
+	at.dailyLossMu.RUnlock()
+
+	status := map[string]interface{}{
+		"circuit_broken":     state.tripped,
+		"max_daily_loss_pct": limit,
+	}
+	if state.dayStart.IsZero() {
+		return status // 尚未运行过决策周期
+	}
+
+	status["daily_loss_day_start"] = state.dayStart.Format(time.RFC3339)
+	status["daily_start_equity"] = state.dayStartEquity
+	dailyPnL := state.lastEquity - state.dayStartEquity
+	status["daily_pnl"] = dailyPnL
+	if state.dayStartEquity > 0 {
+		status["daily_pnl_pct"] = dailyPnL / state.dayStartEquity * 100
+	}
+	if limit > 0 {
+		// 剩余可亏损额度（USDT），熔断后为 0
+		budget := state.dayStartEquity*limit/100 + dailyPnL
+		if budget < 0 || state.tripped {
+			budget = 0
+		}
+		status["daily_loss_budget"] = budget
+	}
+	if state.tripped {
+		status["circuit_broken_at"] = state.trippedAt.Format(time.RFC3339)
+		status["circuit_breaker_resume_at"] = state.dayStart.AddDate(0, 0, 1).Format(time.RFC3339)
+	}
+	return status
+}
@@ -0,0 +1,105 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"nofx/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSystemConfigStore map[string]string
+
+func (f fakeSystemConfigStore) GetSystemConfig(key string) (string, error) {
+	return f[key], nil
+}
+
+// TestDailyLossDayStart 测试交易日起点按 UTC 日界小时计算
+func TestDailyLossDayStart(t *testing.T) {
+	now := time.Date(2025, 3, 10, 5, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), dailyLossDayStart(now, 0))
+	assert.Equal(t, time.Date(2025, 3, 9, 8, 0, 0, 0, time.UTC), dailyLossDayStart(now, 8), "日界之前属于前一交易日")
+
+	local := now.In(time.FixedZone("UTC+8", 8*3600))
+	assert.Equal(t, time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), dailyLossDayStart(local, 0))
+}
+
+// TestDailyLossBreaker 测试超过日亏损上限后熔断、记录事件，并在下一交易日自动解除
+func TestDailyLossBreaker(t *testing.T) {
+	at := &AutoTrader{name: "test", config: AutoTraderConfig{MaxDailyLossPct: 5}}
+	day := time.Date(2025, 3, 10, 1, 0, 0, 0, time.UTC)
+
+	record := &logger.DecisionRecord{}
+	at.checkDailyLossBreaker(day, 1000, record)
+	assert.False(t, at.IsCircuitBroken())
+	assert.Nil(t, record.CircuitBreaker)
+
+	record = &logger.DecisionRecord{}
+	at.checkDailyLossBreaker(day.Add(time.Hour), 970, record)
+	assert.False(t, at.IsCircuitBroken())
+	status := at.dailyLossStatus()
+	assert.InDelta(t, 20, status["daily_loss_budget"], 1e-9)
+	assert.InDelta(t, -3, status["daily_pnl_pct"], 1e-9)
+
+	record = &logger.DecisionRecord{}
+	at.checkDailyLossBreaker(day.Add(2*time.Hour), 949, record)
+	require.True(t, at.IsCircuitBroken())
+	require.NotNil(t, record.CircuitBreaker)
+	assert.Equal(t, "tripped", record.CircuitBreaker.Type)
+	assert.InDelta(t, 5.1, record.CircuitBreaker.LossPct, 1e-9)
+	assert.Equal(t, time.Date(2025, 3, 11, 0, 0, 0, 0, time.UTC), record.CircuitBreaker.ResumeAt)
+	assert.False(t, record.CircuitBreaker.Flattened)
+
+	status = at.dailyLossStatus()
+	assert.Equal(t, true, status["circuit_broken"])
+	assert.Equal(t, 0.0, status["daily_loss_budget"])
+	assert.Equal(t, "2025-03-11T00:00:00Z", status["circuit_breaker_resume_at"])
+
+	// 同一交易日内净值回升不解除熔断，也不重复记录事件
+	record = &logger.DecisionRecord{}
+	at.checkDailyLossBreaker(day.Add(3*time.Hour), 1000, record)
+	assert.True(t, at.IsCircuitBroken())
+	assert.Nil(t, record.CircuitBreaker)
+
+	// 新交易日自动解除，并以当前净值作为新的起点
+	record = &logger.DecisionRecord{}
+	at.checkDailyLossBreaker(day.Add(24*time.Hour), 940, record)
+	assert.False(t, at.IsCircuitBroken())
+	require.NotNil(t, record.CircuitBreaker)
+	assert.Equal(t, "reset", record.CircuitBreaker.Type)
+	assert.Equal(t, 940.0, at.dailyLossStatus()["daily_start_equity"])
+}
+
+// TestDailyLossBreakerFlatten 测试熔断时按配置平掉所有持仓，并使用系统配置的日界
+func TestDailyLossBreakerFlatten(t *testing.T) {
+	mockTrader := &MockTrader{positions: []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "long"},
+		{"symbol": "ETHUSDT", "side": "short"},
+	}}
+	at := &AutoTrader{
+		name:     "test",
+		config:   AutoTraderConfig{MaxDailyLossPct: 10, DailyLossFlatten: true},
+		trader:   mockTrader,
+		database: fakeSystemConfigStore{"daily_loss_reset_hour": "8"},
+	}
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	at.checkDailyLossBreaker(now, 1000, &logger.DecisionRecord{})
+	record := &logger.DecisionRecord{}
+	at.checkDailyLossBreaker(now.Add(time.Hour), 850, record)
+
+	require.NotNil(t, record.CircuitBreaker)
+	assert.True(t, record.CircuitBreaker.Flattened)
+	assert.Equal(t, time.Date(2025, 3, 11, 8, 0, 0, 0, time.UTC), record.CircuitBreaker.ResumeAt)
+
+	// 关闭熔断后不再触发
+	at.SetDailyLossLimit(0, false)
+	at.dailyLoss = dailyLossState{}
+	at.checkDailyLossBreaker(now, 1000, &logger.DecisionRecord{})
+	at.checkDailyLossBreaker(now.Add(time.Hour), 100, &logger.DecisionRecord{})
+	assert.False(t, at.IsCircuitBroken())
+	assert.NotContains(t, at.dailyLossStatus(), "daily_loss_budget")
+}
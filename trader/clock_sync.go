@@ -0,0 +1,166 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ClockSkewWarnThreshold 本地时钟与交易所时钟偏移超过该值时告警
+	ClockSkewWarnThreshold = 1000 * time.Millisecond
+
+	// maxTimestampResyncRetries 遇到时间戳错误(-1021)时重新同步并重试的最大次数
+	maxTimestampResyncRetries = 2
+)
+
+// ClockSkewStatus 与交易所服务器的时钟偏移状态
+type ClockSkewStatus struct {
+	OffsetMs   int64     `json:"offset_ms"`            // 本地时间 - 交易所时间（毫秒），正数表示本地时钟偏快
+	LastSyncAt time.Time `json:"last_sync_at"`         // 最近一次成功同步时间（零值表示从未同步）
+	Warning    bool      `json:"warning"`              // 偏移是否超过告警阈值
+	SyncError  string    `json:"sync_error,omitempty"` // 最近一次同步失败的错误
+}
+
+// ClockSkewReporter 可选接口：支持上报与交易所时钟偏移的交易器
+type ClockSkewReporter interface {
+	ClockSkew() ClockSkewStatus
+}
+
+// serverClock 维护本地时钟与交易所服务器时钟的偏移
+type serverClock struct {
+	name   string
+	now    func() time.Time      // 本地时钟（测试时可注入）
+	fetch  func() (int64, error) // 获取交易所服务器时间（毫秒）
+	onSync func(offsetMs int64)  // 同步成功后的回调（如写入SDK客户端）
+
+	mu         sync.RWMutex
+	offsetMs   int64
+	lastSyncAt time.Time
+	lastErr    error
+}
+
+// newServerClock 创建服务器时钟
+func newServerClock(name string, fetch func() (int64, error)) *serverClock {
+	return &serverClock{
+		name:  name,
+		now:   time.Now,
+		fetch: fetch,
+	}
+}
+
+// sync 同步交易所服务器时间并更新偏移
+func (c *serverClock) sync() error {
+	before := c.now()
+	serverMs, err := c.fetch()
+	after := c.now()
+	if err != nil {
+		c.mu.Lock()
+		c.lastErr = err
+		c.mu.Unlock()
+		log.Printf("⚠️ 同步%s服务器时间失败: %v", c.name, err)
+		return fmt.Errorf("同步%s服务器时间失败: %w", c.name, err)
+	}
+
+	// 取请求往返的中点作为本地时间，抵消网络延迟
+	localMs := before.UnixMilli() + after.Sub(before).Milliseconds()/2
+	offset := localMs - serverMs
+
+	c.mu.Lock()
+	c.offsetMs = offset
+	c.lastSyncAt = after
+	c.lastErr = nil
+	c.mu.Unlock()
+
+	if c.onSync != nil {
+		c.onSync(offset)
+	}
+
+	if time.Duration(absInt64(offset))*time.Millisecond > ClockSkewWarnThreshold {
+		log.Printf("⚠️ 本地时钟与%s服务器偏移 %dms，超过告警阈值 %v，请检查服务器NTP配置", c.name, offset, ClockSkewWarnThreshold)
+	} else {
+		log.Printf("⏱ 已同步%s服务器时间，偏移 %dms", c.name, offset)
+	}
+	return nil
+}
+
+// offset 获取当前缓存的偏移（毫秒）
+func (c *serverClock) offset() int64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.offsetMs
+}
+
+// synced 是否已成功同步过
+func (c *serverClock) synced() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !c.lastSyncAt.IsZero()
+}
+
+// serverNowMillis 按偏移校正后的交易所当前时间（毫秒），用于签名时间戳
+func (c *serverClock) serverNowMillis() int64 {
+	return c.now().UnixMilli() - c.offset()
+}
+
+// status 获取时钟偏移状态
+func (c *serverClock) status() ClockSkewStatus {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	status := ClockSkewStatus{
+		OffsetMs:   c.offsetMs,
+		LastSyncAt: c.lastSyncAt,
+		Warning:    time.Duration(absInt64(c.offsetMs))*time.Millisecond > ClockSkewWarnThreshold,
+	}
+	if c.lastErr != nil {
+		status.SyncError = c.lastErr.Error()
+	}
+	return status
+}
+
+// isTimestampError 判断是否为签名时间戳超出 recvWindow 的错误（Binance/Aster 错误码 -1021，Bybit retCode 10002）
+func isTimestampError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "-1021") ||
+		strings.Contains(msg, "retCode=10002") ||
+		strings.Contains(msg, "outside of the recvWindow") ||
+		strings.Contains(msg, "Timestamp for this request")
+}
+
+// withTimestampResync 执行签名请求，遇到时间戳错误时重新同步时钟并有限次重试
+func withTimestampResync[T any](clock *serverClock, fn func() (T, error)) (T, error) {
+	result, err := fn()
+	if clock == nil {
+		return result, err
+	}
+	for attempt := 1; attempt <= maxTimestampResyncRetries && isTimestampError(err); attempt++ {
+		log.Printf("⏱ 检测到时间戳错误，重新同步服务器时间后重试 (%d/%d): %v", attempt, maxTimestampResyncRetries, err)
+		if syncErr := clock.sync(); syncErr != nil {
+			return result, err
+		}
+		result, err = fn()
+	}
+	return result, err
+}
+
+// withTimestampResyncErr 同 withTimestampResync，用于只返回 error 的请求
+func withTimestampResyncErr(clock *serverClock, fn func() error) error {
+	_, err := withTimestampResync(clock, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+// absInt64 取绝对值
+func absInt64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,204 @@
+package trader
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+// newSkewedExchangeServer 创建模拟交易所：服务器时钟 = 本地时钟 - serverBehind，
+// 签名时间戳与服务器时间相差超过1秒时返回 -1021 错误
+func newSkewedExchangeServer(t *testing.T, serverBehind time.Duration, timePath string, signedPaths map[string]interface{}, rejected *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverNow := time.Now().Add(-serverBehind).UnixMilli()
+		w.Header().Set("Content-Type", "application/json")
+
+		if r.URL.Path == timePath {
+			json.NewEncoder(w).Encode(map[string]interface{}{"serverTime": serverNow})
+			return
+		}
+
+		resp, ok := signedPaths[r.URL.Path]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{})
+			return
+		}
+
+		r.ParseForm()
+		ts, _ := strconv.ParseInt(r.Form.Get("timestamp"), 10, 64)
+		if absInt64(ts-serverNow) > 1000 {
+			atomic.AddInt32(rejected, 1)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": -1021,
+				"msg":  "Timestamp for this request is outside of the recvWindow.",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+}
+
+// TestServerClock_InjectedSkew 注入偏快的本地时钟，同步后校正时间应与服务器一致
+func TestServerClock_InjectedSkew(t *testing.T) {
+	skew := 5 * time.Second
+	serverTime := time.Now()
+
+	clock := newServerClock("test", func() (int64, error) {
+		return serverTime.UnixMilli(), nil
+	})
+	clock.now = func() time.Time { return serverTime.Add(skew) }
+
+	assert.False(t, clock.synced())
+	assert.NoError(t, clock.sync())
+	assert.True(t, clock.synced())
+
+	assert.Equal(t, skew.Milliseconds(), clock.offset())
+	assert.Equal(t, serverTime.UnixMilli(), clock.serverNowMillis())
+
+	status := clock.status()
+	assert.True(t, status.Warning, "偏移超过阈值时应告警")
+	assert.Equal(t, skew.Milliseconds(), status.OffsetMs)
+}
+
+// TestIsTimestampError 测试时间戳错误识别
+func TestIsTimestampError(t *testing.T) {
+	assert.True(t, isTimestampError(assertError("<APIError> code=-1021, msg=Timestamp for this request is outside of the recvWindow.")))
+	assert.True(t, isTimestampError(assertError(`HTTP 400: {"code":-1021,"msg":"Timestamp for this request was 1000ms ahead of the server's time."}`)))
+	assert.False(t, isTimestampError(assertError("<APIError> code=-2019, msg=Margin is insufficient.")))
+	assert.False(t, isTimestampError(nil))
+}
+
+// TestFuturesTrader_ResyncOnTimestampError 本地时钟偏快时，首次请求被拒绝后自动同步并重试成功
+func TestFuturesTrader_ResyncOnTimestampError(t *testing.T) {
+	var rejected int32
+	server := newSkewedExchangeServer(t, 5*time.Second, "/fapi/v1/time", map[string]interface{}{
+		"/fapi/v2/account": map[string]interface{}{
+			"totalWalletBalance":    "1000.00",
+			"availableBalance":      "800.00",
+			"totalUnrealizedProfit": "10.00",
+		},
+	}, &rejected)
+	defer server.Close()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+
+	trader := &FuturesTrader{
+		client: client,
+		clock:  newBinanceServerClock(client), // 未同步，TimeOffset=0
+	}
+
+	balance, err := trader.GetBalance()
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, balance["totalWalletBalance"])
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rejected), "首次请求应因时间戳被拒绝")
+
+	// 偏移应写入SDK客户端
+	assert.InDelta(t, 5000, client.TimeOffset, 500)
+	assert.InDelta(t, 5000, trader.ClockSkew().OffsetMs, 500)
+	assert.True(t, trader.ClockSkew().Warning)
+}
+
+// TestFuturesTrader_ResyncBounded 时钟无法校正时，重试次数有限并返回原始错误
+func TestFuturesTrader_ResyncBounded(t *testing.T) {
+	var rejected int32
+	server := newSkewedExchangeServer(t, 0, "/fapi/v1/time", map[string]interface{}{
+		"/fapi/v2/account": map[string]interface{}{},
+	}, &rejected)
+	defer server.Close()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+
+	// 同步结果被故意写错，模拟时钟持续漂移
+	clock := newBinanceServerClock(client)
+	clock.onSync = func(int64) { client.TimeOffset = -60000 }
+	client.TimeOffset = -60000
+
+	_, err := withTimestampResync(clock, func() (*futures.Account, error) {
+		return client.NewGetAccountService().Do(context.Background())
+	})
+	assert.Error(t, err)
+	assert.True(t, isTimestampError(err))
+	assert.Equal(t, int32(1+maxTimestampResyncRetries), atomic.LoadInt32(&rejected))
+}
+
+// TestAsterTrader_SignWithInjectedSkew Aster 使用注入的偏快时钟签名，首次请求前自动同步
+func TestAsterTrader_SignWithInjectedSkew(t *testing.T) {
+	var rejected int32
+	server := newSkewedExchangeServer(t, 0, "/fapi/v3/time", map[string]interface{}{
+		"/fapi/v3/balance": []map[string]interface{}{
+			{"asset": "USDT", "crossWalletBalance": "500.00", "availableBalance": "400.00", "crossUnPnl": "0"},
+		},
+	}, &rejected)
+	defer server.Close()
+
+	privateKey, _ := crypto.GenerateKey()
+	trader := &AsterTrader{
+		ctx:             context.Background(),
+		user:            "0x1234567890123456789012345678901234567890",
+		signer:          "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
+		privateKey:      privateKey,
+		client:          server.Client(),
+		baseURL:         server.URL,
+		symbolPrecision: make(map[string]SymbolPrecision),
+	}
+	trader.clock = newServerClock("Aster", trader.fetchServerTime)
+	trader.clock.now = func() time.Time { return time.Now().Add(10 * time.Second) }
+
+	_, err := trader.request("GET", "/fapi/v3/balance", map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&rejected), "首次请求前已同步，不应被拒绝")
+	assert.InDelta(t, 10000, trader.ClockSkew().OffsetMs, 500)
+}
+
+// TestAsterTrader_ResyncOnTimestampError Aster 时钟在同步后发生漂移，遇到 -1021 时重新同步并重试
+func TestAsterTrader_ResyncOnTimestampError(t *testing.T) {
+	var rejected int32
+	server := newSkewedExchangeServer(t, 0, "/fapi/v3/time", map[string]interface{}{
+		"/fapi/v3/balance": []map[string]interface{}{},
+	}, &rejected)
+	defer server.Close()
+
+	privateKey, _ := crypto.GenerateKey()
+	trader := &AsterTrader{
+		ctx:             context.Background(),
+		user:            "0x1234567890123456789012345678901234567890",
+		signer:          "0xabcdefabcdefabcdefabcdefabcdefabcdefabcd",
+		privateKey:      privateKey,
+		client:          server.Client(),
+		baseURL:         server.URL,
+		symbolPrecision: make(map[string]SymbolPrecision),
+	}
+	trader.clock = newServerClock("Aster", trader.fetchServerTime)
+	assert.NoError(t, trader.clock.sync())
+
+	// 同步后本地时钟漂移 8 秒
+	trader.clock.now = func() time.Time { return time.Now().Add(8 * time.Second) }
+
+	_, err := trader.request("GET", "/fapi/v3/balance", map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rejected))
+	assert.InDelta(t, 8000, trader.ClockSkew().OffsetMs, 500)
+}
+
+// assertError 构造测试用错误
+func assertError(msg string) error {
+	return &testError{msg: msg}
+}
+
+type testError struct{ msg string }
+
+func (e *testError) Error() string { return e.msg }
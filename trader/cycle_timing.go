@@ -0,0 +1,44 @@
+package trader
+
+import (
+	"log"
+	"nofx/logger"
+	"nofx/mcp"
+	"time"
+)
+
+// recordAIUsage 记录实际产生决策的模型、获取决策的耗时和token用量
+//
+// responded 表示AI返回了响应（即使解析失败）；调用失败时只记录耗时，避免沿用上一次调用的用量。
+// 多模型共识模式（client 为 nil）按各模型的用量合计，模型记为参与共识的模型列表。
+func recordAIUsage(record *logger.DecisionRecord, client mcp.AIClient, responded bool, latency time.Duration) {
+	record.AILatencyMs = latency.Milliseconds()
+	record.AIModelUsed = record.AIModel
+	if !responded {
+		return
+	}
+
+	if record.Ensemble != nil {
+		for _, output := range record.Ensemble.Models {
+			record.PromptTokens += output.PromptTokens
+			record.CompletionTokens += output.CompletionTokens
+		}
+		return
+	}
+	if reporter, ok := client.(mcp.UsageReporter); ok {
+		usage := reporter.LastUsage()
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+		if usage.Model != "" {
+			record.AIModelUsed = usage.Model
+		}
+	}
+}
+
+// saveCycleRecord 写入整个周期的耗时并保存决策记录
+func (at *AutoTrader) saveCycleRecord(record *logger.DecisionRecord, cycleStart time.Time) {
+	record.TotalCycleMs = time.Since(cycleStart).Milliseconds()
+	if err := at.decisionLogger.LogDecision(record); err != nil {
+		log.Printf("⚠ 保存决策记录失败: %v", err)
+	}
+}
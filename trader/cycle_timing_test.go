@@ -0,0 +1,47 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"nofx/logger"
+	"nofx/mcp"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// usageAIClient 上报固定token用量的AI客户端
+type usageAIClient struct {
+	namedAIClient
+	usage mcp.Usage
+}
+
+func (c *usageAIClient) LastUsage() mcp.Usage { return c.usage }
+
+func TestRecordAIUsage(t *testing.T) {
+	client := &usageAIClient{usage: mcp.Usage{Model: "deepseek-chat", PromptTokens: 1200, CompletionTokens: 300}}
+
+	record := &logger.DecisionRecord{AIModel: "deepseek"}
+	recordAIUsage(record, client, true, 1500*time.Millisecond)
+	assert.Equal(t, int64(1500), record.AILatencyMs)
+	assert.Equal(t, "deepseek-chat", record.AIModelUsed)
+	assert.Equal(t, 1200, record.PromptTokens)
+	assert.Equal(t, 300, record.CompletionTokens)
+
+	// 调用失败时不沿用上一次调用的用量
+	failed := &logger.DecisionRecord{AIModel: "deepseek"}
+	recordAIUsage(failed, client, false, 30*time.Second)
+	assert.Equal(t, int64(30000), failed.AILatencyMs)
+	assert.Equal(t, "deepseek", failed.AIModelUsed)
+	assert.Zero(t, failed.PromptTokens)
+
+	// 多模型共识按各模型合计
+	ensemble := &logger.DecisionRecord{AIModel: "deepseek+qwen", Ensemble: &logger.EnsembleRecord{Models: []logger.EnsembleModelOutput{
+		{Model: "deepseek", PromptTokens: 1000, CompletionTokens: 200},
+		{Model: "qwen", PromptTokens: 900, CompletionTokens: 100},
+	}}}
+	recordAIUsage(ensemble, nil, true, time.Second)
+	assert.Equal(t, "deepseek+qwen", ensemble.AIModelUsed)
+	assert.Equal(t, 1900, ensemble.PromptTokens)
+	assert.Equal(t, 300, ensemble.CompletionTokens)
+}
@@ -0,0 +1,137 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"time"
+)
+
+// ErrCycleInProgress 交易周期正在执行（决策预演需等待周期结束）
+var ErrCycleInProgress = errors.New("交易周期正在执行中，请稍后再试")
+
+// DryRunResult 决策预演结果：与正常周期相同的提示词和模型调用，但不执行任何交易
+type DryRunResult struct {
+	Model                 string                  `json:"model"`                     // 调用的模型
+	SystemPrompt          string                  `json:"system_prompt"`             // 渲染后的系统提示词
+	UserPrompt            string                  `json:"user_prompt"`               // 渲染后的输入提示词（账户、持仓、行情、历史表现）
+	RawOutputs            []string                `json:"raw_outputs"`               // 模型原始输出（发生修复请求时包含修复后的输出）
+	CoTTrace              string                  `json:"cot_trace"`                 // 解析出的思维链
+	Decisions             []decision.Decision     `json:"decisions"`                 // 解析并校验后的决策
+	PromptTemplate        string                  `json:"prompt_template,omitempty"` // 使用的提示词模板
+	PromptTemplateVersion int                     `json:"prompt_template_version,omitempty"`
+	Repair                *decision.RepairAttempt `json:"repair,omitempty"` // 首次输出未通过校验时的修复记录
+	DurationMs            int64                   `json:"duration_ms"`      // AI调用耗时（毫秒）
+	// PromptTruncation 提示词超出模型上下文预算时的裁剪记录
+	PromptTruncation *decision.PromptTruncation `json:"prompt_truncation,omitempty"`
+}
+
+// recordingAIClient 记录模型原始输出的客户端包装（决策预演用）
+type recordingAIClient struct {
+	mcp.AIClient
+	outputs []string
+}
+
+func (c *recordingAIClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	output, err := c.AIClient.CallWithMessages(systemPrompt, userPrompt)
+	if err == nil {
+		c.outputs = append(c.outputs, output)
+	}
+	return output, err
+}
+
+func (c *recordingAIClient) CallWithRequest(req *mcp.Request) (string, error) {
+	output, err := c.AIClient.CallWithRequest(req)
+	if err == nil {
+		c.outputs = append(c.outputs, output)
+	}
+	return output, err
+}
+
+// DryRun 决策预演：按正常周期构建上下文和提示词并调用当前模型，返回各阶段结果。
+// 不执行任何交易，不计入周期编号，预演记录单独保存并标记为 dry_run；AI用量照常计入花费
+func (at *AutoTrader) DryRun() (*DryRunResult, error) {
+	if !at.cycleMu.TryLock() {
+		return nil, ErrCycleInProgress
+	}
+	defer at.cycleMu.Unlock()
+	start := time.Now()
+
+	ctx, err := at.buildTradingContext()
+	if err != nil {
+		return nil, fmt.Errorf("构建交易上下文失败: %w", err)
+	}
+
+	record := &logger.DecisionRecord{
+		DryRun:       true,
+		ExecutionLog: []string{"🧪 决策预演（不执行交易）"},
+		AIModel:      at.aiModel,
+	}
+	for _, coin := range ctx.CandidateCoins {
+		record.CandidateCoins = append(record.CandidateCoins, coin.Symbol)
+	}
+	recordSignalSourceErrors(record, ctx)
+
+	client := &recordingAIClient{AIClient: at.mcpClient}
+	aiStart := time.Now()
+	fullDecision, err := getFullDecision(ctx, client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+	recordAIUsage(record, at.mcpClient, fullDecision != nil || len(client.outputs) > 0, time.Since(aiStart))
+	if fullDecision != nil || len(client.outputs) > 0 {
+		at.recordAISpend(record, at.mcpClient)
+	}
+	if err != nil {
+		at.recordAIError(err)
+		record.Success = false
+		record.ErrorMessage = fmt.Sprintf("获取AI决策失败: %v", err)
+		at.logDryRun(record, start)
+		return nil, fmt.Errorf("获取AI决策失败: %w", err)
+	}
+
+	result := &DryRunResult{
+		Model:                 at.aiModel,
+		SystemPrompt:          fullDecision.SystemPrompt,
+		UserPrompt:            fullDecision.UserPrompt,
+		RawOutputs:            client.outputs,
+		CoTTrace:              fullDecision.CoTTrace,
+		Decisions:             fullDecision.Decisions,
+		PromptTemplate:        fullDecision.PromptTemplate,
+		PromptTemplateVersion: fullDecision.PromptTemplateVersion,
+		Repair:                fullDecision.Repair,
+		DurationMs:            fullDecision.AIRequestDurationMs,
+		PromptTruncation:      fullDecision.PromptTruncation,
+	}
+	if result.RawOutputs == nil {
+		result.RawOutputs = []string{}
+	}
+
+	record.Success = true
+	record.SystemPrompt = fullDecision.SystemPrompt
+	record.InputPrompt = fullDecision.UserPrompt
+	record.CoTTrace = fullDecision.CoTTrace
+	record.AIRequestDurationMs = fullDecision.AIRequestDurationMs
+	record.PromptTemplate = fullDecision.PromptTemplate
+	record.PromptTemplateVersion = fullDecision.PromptTemplateVersion
+	recordPromptTruncation(record, fullDecision.PromptTruncation)
+	if decisionJSON, err := json.MarshalIndent(fullDecision.Decisions, "", "  "); err == nil {
+		record.DecisionJSON = string(decisionJSON)
+	}
+	at.logDryRun(record, start)
+
+	log.Printf("🧪 [%s] 决策预演完成: %d 条决策", at.name, len(fullDecision.Decisions))
+	return result, nil
+}
+
+// logDryRun 写入预演耗时并保存预演记录（失败只记录日志，不影响预演结果）
+func (at *AutoTrader) logDryRun(record *logger.DecisionRecord, start time.Time) {
+	if at.decisionLogger == nil {
+		return
+	}
+	record.TotalCycleMs = time.Since(start).Milliseconds()
+	if err := at.decisionLogger.LogDryRun(record); err != nil {
+		log.Printf("⚠ [%s] 保存预演记录失败: %v", at.name, err)
+	}
+}
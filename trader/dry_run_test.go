@@ -0,0 +1,56 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+
+	"nofx/mcp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// scriptedAIClient 依次返回预设输出的AI客户端
+type scriptedAIClient struct {
+	namedAIClient
+	outputs []string
+	err     error
+}
+
+func (c *scriptedAIClient) next() (string, error) {
+	if c.err != nil {
+		return "", c.err
+	}
+	output := c.outputs[0]
+	c.outputs = c.outputs[1:]
+	return output, nil
+}
+
+func (c *scriptedAIClient) CallWithMessages(systemPrompt, userPrompt string) (string, error) {
+	return c.next()
+}
+func (c *scriptedAIClient) CallWithRequest(req *mcp.Request) (string, error) { return c.next() }
+
+func TestRecordingAIClient_CapturesRawOutputs(t *testing.T) {
+	client := &recordingAIClient{AIClient: &scriptedAIClient{outputs: []string{"first", "repaired"}}}
+
+	_, err := client.CallWithMessages("system", "user")
+	require.NoError(t, err)
+	_, err = client.CallWithRequest(&mcp.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first", "repaired"}, client.outputs)
+
+	failing := &recordingAIClient{AIClient: &scriptedAIClient{err: errors.New("down")}}
+	_, err = failing.CallWithMessages("system", "user")
+	assert.Error(t, err)
+	assert.Empty(t, failing.outputs)
+}
+
+func TestDryRun_RejectedWhileCycleRunning(t *testing.T) {
+	at := &AutoTrader{name: "dry-run"}
+	at.cycleMu.Lock()
+	defer at.cycleMu.Unlock()
+
+	_, err := at.DryRun()
+	assert.ErrorIs(t, err, ErrCycleInProgress)
+}
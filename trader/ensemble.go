@@ -0,0 +1,333 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"nofx/config"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 多模型共识规则
+const (
+	ConsensusAll      = "all"      // 所有返回决策的模型动作一致才执行
+	ConsensusMajority = "majority" // 超过半数返回决策的模型动作一致即执行
+)
+
+// ensembleCallTimeout 共识模式下单个模型的调用超时（超时的模型视为本周期未返回）
+const ensembleCallTimeout = 2 * time.Minute
+
+// ensembleState 多模型共识配置（成员客户端首次使用时创建）
+type ensembleState struct {
+	mu       sync.Mutex
+	modelIDs []string       // 参与共识的模型ID（少于2个表示不启用）
+	rule     string         // all 或 majority
+	members  []*aiCandidate // 已创建的成员客户端
+}
+
+// ensembleResult 单个模型在本周期的输出
+type ensembleResult struct {
+	member   *aiCandidate
+	decision *decision.FullDecision
+	err      error
+	duration time.Duration
+}
+
+// ParseEnsembleModelIDs 解析逗号分隔的模型ID列表（去除空白和空项）
+func ParseEnsembleModelIDs(raw string) []string {
+	var ids []string
+	for _, id := range strings.Split(raw, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// SetEnsemble 设置多模型共识的模型列表和共识规则（清除已创建的成员客户端）
+func (at *AutoTrader) SetEnsemble(modelIDs []string, rule string) {
+	at.ensemble.mu.Lock()
+	defer at.ensemble.mu.Unlock()
+	at.ensemble.modelIDs = modelIDs
+	at.ensemble.rule = rule
+	at.ensemble.members = nil
+}
+
+// getEnsembleMembers 获取共识模式的成员模型（未启用或可用模型不足2个时返回 nil，本周期按单模型决策）
+func (at *AutoTrader) getEnsembleMembers() ([]*aiCandidate, string) {
+	at.ensemble.mu.Lock()
+	defer at.ensemble.mu.Unlock()
+
+	if len(at.ensemble.modelIDs) < 2 {
+		return nil, ""
+	}
+	if at.ensemble.members == nil {
+		store := at.getSpendStore()
+		if store == nil {
+			return nil, ""
+		}
+		models, err := store.GetAIModels(at.userID)
+		if err != nil {
+			log.Printf("⚠️ [%s] 获取共识模型失败: %v", at.name, err)
+			return nil, ""
+		}
+		members := make([]*aiCandidate, 0, len(at.ensemble.modelIDs))
+		for _, id := range at.ensemble.modelIDs {
+			var cfg *config.AIModelConfig
+			for _, m := range models {
+				if m.ID == id {
+					cfg = m
+					break
+				}
+			}
+			if cfg == nil || !cfg.Enabled {
+				log.Printf("⚠️ [%s] 共识模型 %s 不存在或未启用，已跳过", at.name, id)
+				continue
+			}
+			client := newAIClientForModel(cfg)
+			if cfg.RequestTimeoutSeconds == 0 {
+				client.SetTimeout(ensembleCallTimeout)
+			}
+			limitAIRetryBudget(client, at.getScanInterval())
+			members = append(members, &aiCandidate{client: client, model: id})
+		}
+		if len(members) < 2 {
+			log.Printf("⚠️ [%s] 可用的共识模型不足2个，按单模型决策", at.name)
+			return nil, ""
+		}
+		at.ensemble.members = members
+	}
+	return at.ensemble.members, at.ensemble.rule
+}
+
+// requestEnsembleDecision 并发请求所有成员模型的决策，按共识规则合并后返回。
+// 单个模型失败或超时不影响其他模型，只有全部失败时才返回错误
+func (at *AutoTrader) requestEnsembleDecision(ctx *decision.Context, record *logger.DecisionRecord, members []*aiCandidate, rule string) (*decision.FullDecision, error) {
+	results := make(chan *ensembleResult, len(members))
+	for _, member := range members {
+		// 每个模型使用独立的上下文副本（获取市场数据时会重建上下文中的数据表）
+		memberCtx := *ctx
+		go func(member *aiCandidate) {
+			start := time.Now()
+			fullDecision, err := getFullDecision(&memberCtx, member.client, at.customPrompt, at.overrideBasePrompt, at.systemPromptTemplate)
+			results <- &ensembleResult{member: member, decision: fullDecision, err: err, duration: time.Since(start)}
+		}(member)
+	}
+
+	byModel := make(map[*aiCandidate]*ensembleResult, len(members))
+	timeout := time.NewTimer(ensembleCallTimeout)
+	defer timeout.Stop()
+collect:
+	for len(byModel) < len(members) {
+		select {
+		case result := <-results:
+			byModel[result.member] = result
+		case <-timeout.C:
+			break collect
+		}
+	}
+
+	ensemble := &logger.EnsembleRecord{Rule: rule}
+	var succeeded []*ensembleResult
+	var firstErr error
+	for _, member := range members {
+		output := logger.EnsembleModelOutput{Model: member.model}
+		result, ok := byModel[member]
+		switch {
+		case !ok:
+			output.Error = fmt.Sprintf("调用超时（%s）", ensembleCallTimeout)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%w: 模型 %s 调用超时", decision.ErrAICall, member.model)
+			}
+		case result.err != nil:
+			output.Error = result.err.Error()
+			output.DurationMs = result.duration.Milliseconds()
+			if firstErr == nil {
+				firstErr = result.err
+			}
+		default:
+			output.DurationMs = result.duration.Milliseconds()
+			output.CoTTrace = result.decision.CoTTrace
+			if decisionJSON, err := json.Marshal(result.decision.Decisions); err == nil {
+				output.DecisionJSON = string(decisionJSON)
+			}
+			// 按模型记录token用量和花费
+			if reporter, ok := member.client.(mcp.UsageReporter); ok {
+				usage := reporter.LastUsage()
+				output.PromptTokens = usage.PromptTokens
+				output.CompletionTokens = usage.CompletionTokens
+				output.CostUSD = mcp.EstimateCost(usage)
+			}
+			at.recordAISpend(record, member.client)
+			succeeded = append(succeeded, result)
+		}
+		if output.Error != "" {
+			log.Printf("⚠️ [%s] 共识模型 %s 未返回决策: %s", at.name, member.model, output.Error)
+			record.ExecutionLog = append(record.ExecutionLog, fmt.Sprintf("🗳️ 共识模型 %s 未返回决策: %s", member.model, output.Error))
+		}
+		ensemble.Models = append(ensemble.Models, output)
+	}
+	record.Ensemble = ensemble
+
+	if len(succeeded) == 0 {
+		return nil, fmt.Errorf("%w（共识模式下 %d 个模型全部调用失败）", firstErr, len(members))
+	}
+
+	responded := make([]string, 0, len(succeeded))
+	modelDecisions := make([][]decision.Decision, 0, len(succeeded))
+	for _, result := range succeeded {
+		responded = append(responded, result.member.model)
+		modelDecisions = append(modelDecisions, result.decision.Decisions)
+	}
+	record.AIModel = strings.Join(responded, "+")
+
+	merged, agreed, skipped := mergeEnsembleDecisions(responded, modelDecisions, rule)
+	ensemble.Agreed = agreed
+	ensemble.Skipped = skipped
+	record.ExecutionLog = append(record.ExecutionLog,
+		fmt.Sprintf("🗳️ 多模型共识 (%s): %d/%d 个模型返回决策，达成共识 %d 项，未达成 %d 项",
+			rule, len(succeeded), len(members), len(agreed), len(skipped)))
+
+	// 合并结果沿用第一个返回模型的提示词，思维链按模型拼接
+	base := succeeded[0].decision
+	var cot strings.Builder
+	var maxDuration int64
+	for _, result := range succeeded {
+		fmt.Fprintf(&cot, "===== %s =====\n%s\n\n", result.member.model, result.decision.CoTTrace)
+		maxDuration = max(maxDuration, result.decision.AIRequestDurationMs)
+	}
+	return &decision.FullDecision{
+		SystemPrompt:          base.SystemPrompt,
+		UserPrompt:            base.UserPrompt,
+		CoTTrace:              strings.TrimSpace(cot.String()),
+		Decisions:             merged,
+		Timestamp:             time.Now(),
+		AIRequestDurationMs:   maxDuration,
+		PromptTemplate:        base.PromptTemplate,
+		PromptTemplateVersion: base.PromptTemplateVersion,
+		PromptTruncation:      base.PromptTruncation,
+	}, nil
+}
+
+// mergeEnsembleDecisions 按币种合并各模型的决策：同一动作达到共识才执行（未给出该币种决策的模型视为不同意），
+// 开仓取最保守的仓位、杠杆和风险金额。返回合并后的决策以及达成/未达成共识的决策说明
+func mergeEnsembleDecisions(models []string, modelDecisions [][]decision.Decision, rule string) ([]decision.Decision, []string, []string) {
+	// 每个模型每个币种取第一条决策，币种按首次出现的顺序处理
+	var symbols []string
+	bySymbol := make(map[string]map[int]decision.Decision)
+	for i, decisions := range modelDecisions {
+		for _, d := range decisions {
+			if d.Action == decision.ActionHold || d.Action == decision.ActionWait {
+				continue
+			}
+			if bySymbol[d.Symbol] == nil {
+				bySymbol[d.Symbol] = make(map[int]decision.Decision)
+				symbols = append(symbols, d.Symbol)
+			}
+			if _, exists := bySymbol[d.Symbol][i]; !exists {
+				bySymbol[d.Symbol][i] = d
+			}
+		}
+	}
+
+	total := len(modelDecisions)
+	merged := make([]decision.Decision, 0)
+	agreed := make([]string, 0)
+	skipped := make([]string, 0)
+	for _, symbol := range symbols {
+		votes := make(map[string][]int)
+		var actions []string
+		for i := 0; i < total; i++ {
+			d, ok := bySymbol[symbol][i]
+			if !ok {
+				continue
+			}
+			if votes[d.Action] == nil {
+				actions = append(actions, d.Action)
+			}
+			votes[d.Action] = append(votes[d.Action], i)
+		}
+
+		var winner string
+		for _, action := range actions {
+			if consensusReached(len(votes[action]), total, rule) {
+				winner = action
+				break
+			}
+		}
+		if winner == "" {
+			var summary []string
+			for _, action := range actions {
+				summary = append(summary, fmt.Sprintf("%s×%d", action, len(votes[action])))
+			}
+			skipped = append(skipped, fmt.Sprintf("%s: %s（共 %d 个模型）", symbol, strings.Join(summary, ", "), total))
+			continue
+		}
+
+		voters := votes[winner]
+		result := bySymbol[symbol][voters[0]]
+		names := make([]string, 0, len(voters))
+		for _, i := range voters {
+			names = append(names, models[i])
+		}
+		if winner == decision.ActionOpenLong || winner == decision.ActionOpenShort {
+			result = conservativeOpen(bySymbol[symbol], voters)
+		}
+		result.Reasoning = fmt.Sprintf("[共识 %d/%d: %s] %s", len(voters), total, strings.Join(names, ", "), result.Reasoning)
+		merged = append(merged, result)
+		agreed = append(agreed, fmt.Sprintf("%s: %s（%d/%d）", symbol, winner, len(voters), total))
+	}
+
+	if len(merged) == 0 {
+		merged = append(merged, decision.Decision{
+			Symbol:    "ALL",
+			Action:    decision.ActionWait,
+			Reasoning: "多模型未就任何开平仓动作达成共识，本周期观望",
+		})
+	}
+	return merged, agreed, skipped
+}
+
+// consensusReached 判断票数是否满足共识规则（按实际返回决策的模型数计算）
+func consensusReached(votes, total int, rule string) bool {
+	if rule == ConsensusAll {
+		return votes == total
+	}
+	return votes*2 > total
+}
+
+// conservativeOpen 从同意开仓的模型中取最保守的参数：止损止盈沿用仓位最小的模型，杠杆、风险金额和信心度取最小值
+func conservativeOpen(decisions map[int]decision.Decision, voters []int) decision.Decision {
+	smallest := voters[0]
+	for _, i := range voters[1:] {
+		if decisions[i].PositionSizeUSD < decisions[smallest].PositionSizeUSD {
+			smallest = i
+		}
+	}
+	result := decisions[smallest]
+	for _, i := range voters {
+		d := decisions[i]
+		result.Leverage = min(result.Leverage, d.Leverage)
+		result.RiskUSD = min(result.RiskUSD, d.RiskUSD)
+		result.Confidence = min(result.Confidence, d.Confidence)
+	}
+	return result
+}
+
+// ensembleStatus 多模型共识状态（未启用时返回 nil）
+func (at *AutoTrader) ensembleStatus() map[string]interface{} {
+	at.ensemble.mu.Lock()
+	defer at.ensemble.mu.Unlock()
+	if len(at.ensemble.modelIDs) < 2 {
+		return nil
+	}
+	return map[string]interface{}{
+		"models": at.ensemble.modelIDs,
+		"rule":   at.ensemble.rule,
+	}
+}
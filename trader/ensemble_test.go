@@ -0,0 +1,112 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/mcp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openLong(symbol string, size float64, leverage int, stopLoss float64) decision.Decision {
+	return decision.Decision{
+		Symbol: symbol, Action: decision.ActionOpenLong, PositionSizeUSD: size, Leverage: leverage,
+		StopLoss: stopLoss, TakeProfit: stopLoss * 2, RiskUSD: size / 10, Confidence: 80, Reasoning: "趋势向上",
+	}
+}
+
+func TestMergeEnsembleDecisions_Majority(t *testing.T) {
+	models := []string{"deepseek", "qwen", "custom"}
+	merged, agreed, skipped := mergeEnsembleDecisions(models, [][]decision.Decision{
+		{openLong("BTCUSDT", 300, 10, 90), {Symbol: "ETHUSDT", Action: decision.ActionOpenShort, PositionSizeUSD: 100, Leverage: 5}},
+		{openLong("BTCUSDT", 200, 5, 95), {Symbol: "ETHUSDT", Action: decision.ActionWait}},
+		{{Symbol: "BTCUSDT", Action: decision.ActionOpenShort, PositionSizeUSD: 500, Leverage: 20}},
+	}, ConsensusMajority)
+
+	// BTC 2/3 同意开多，按最保守的参数执行；ETH 只有1票开空，不执行
+	require.Len(t, merged, 1)
+	btc := merged[0]
+	assert.Equal(t, decision.ActionOpenLong, btc.Action)
+	assert.Equal(t, 200.0, btc.PositionSizeUSD)
+	assert.Equal(t, 5, btc.Leverage)
+	assert.Equal(t, 95.0, btc.StopLoss, "止损止盈沿用仓位最小的模型")
+	assert.Equal(t, 20.0, btc.RiskUSD)
+	assert.Contains(t, btc.Reasoning, "共识 2/3: deepseek, qwen")
+	assert.Equal(t, []string{"BTCUSDT: open_long（2/3）"}, agreed)
+	assert.Len(t, skipped, 1)
+	assert.Contains(t, skipped[0], "ETHUSDT")
+}
+
+func TestMergeEnsembleDecisions_AllRule(t *testing.T) {
+	models := []string{"deepseek", "qwen"}
+	decisions := [][]decision.Decision{
+		{openLong("BTCUSDT", 300, 10, 90), {Symbol: "SOLUSDT", Action: decision.ActionCloseLong}},
+		{{Symbol: "SOLUSDT", Action: decision.ActionCloseLong}},
+	}
+
+	merged, _, skipped := mergeEnsembleDecisions(models, decisions, ConsensusAll)
+	require.Len(t, merged, 1)
+	assert.Equal(t, "SOLUSDT", merged[0].Symbol)
+	assert.Equal(t, decision.ActionCloseLong, merged[0].Action)
+	assert.Len(t, skipped, 1, "只有一个模型开多，all规则下不执行")
+
+	// 没有任何共识时观望
+	merged, agreed, _ := mergeEnsembleDecisions(models, [][]decision.Decision{
+		{openLong("BTCUSDT", 300, 10, 90)},
+		{{Symbol: "BTCUSDT", Action: decision.ActionOpenShort}},
+	}, ConsensusAll)
+	require.Len(t, merged, 1)
+	assert.Equal(t, decision.ActionWait, merged[0].Action)
+	assert.Empty(t, agreed)
+}
+
+func TestRequestDecision_EnsembleDegradesOnModelError(t *testing.T) {
+	calls := stubDecisions(t, map[string]error{"custom": errors.New("connection refused")})
+	original := getFullDecision
+	getFullDecision = func(ctx *decision.Context, client mcp.AIClient, customPrompt string, overrideBase bool, templateName string) (*decision.FullDecision, error) {
+		result, err := original(ctx, client, customPrompt, overrideBase, templateName)
+		if err == nil {
+			result.Decisions = []decision.Decision{openLong("BTCUSDT", 100, 3, 90)}
+			result.CoTTrace = "分析"
+		}
+		return result, err
+	}
+
+	at := newFallbackTestTrader(0)
+	at.ensemble = ensembleState{
+		modelIDs: []string{"deepseek", "user_qwen", "custom"},
+		rule:     ConsensusAll,
+		members: []*aiCandidate{
+			{client: &namedAIClient{name: "deepseek"}, model: "deepseek"},
+			{client: &namedAIClient{name: "user_qwen"}, model: "user_qwen"},
+			{client: &namedAIClient{name: "custom"}, model: "custom"},
+		},
+	}
+
+	record := &logger.DecisionRecord{}
+	result, client, err := at.requestDecision(&decision.Context{}, record)
+	require.NoError(t, err)
+	assert.Nil(t, client)
+	assert.Len(t, *calls, 3)
+
+	// 失败的模型被排除，剩余两个模型一致（all规则按返回决策的模型计算）
+	require.Len(t, result.Decisions, 1)
+	assert.Equal(t, decision.ActionOpenLong, result.Decisions[0].Action)
+	assert.Equal(t, "deepseek+user_qwen", record.AIModel)
+	require.NotNil(t, record.Ensemble)
+	require.Len(t, record.Ensemble.Models, 3)
+	assert.NotEmpty(t, record.Ensemble.Models[0].DecisionJSON)
+	assert.Contains(t, record.Ensemble.Models[2].Error, "connection refused")
+
+	// 全部失败时返回AI调用错误
+	calls = stubDecisions(t, map[string]error{
+		"deepseek": errors.New("down"), "user_qwen": errors.New("down"), "custom": errors.New("down"),
+	})
+	_, _, err = at.requestDecision(&decision.Context{}, &logger.DecisionRecord{})
+	assert.ErrorIs(t, err, decision.ErrAICall)
+	assert.Len(t, *calls, 3)
+}
@@ -0,0 +1,198 @@
+package trader
+
+import (
+	"log"
+	"nofx/logger"
+	"sync"
+	"time"
+)
+
+// 交易员事件类型
+const (
+	EventCycleCompleted = "cycle_completed" // 决策周期完成
+	EventPositionOpened = "position_opened" // 开仓成功
+	EventPositionClosed = "position_closed" // 平仓成功
+	EventStateChanged   = "state_changed"   // 运行状态变化（启动/停止）
+	EventTraderError    = "trader_error"    // 运行出错退出（监督者按重启策略处理）
+	EventCircuitBreaker = "circuit_breaker" // 日亏损熔断触发
+	EventAIBalanceAlert = "ai_balance"      // AI接口余额不足
+	EventSpendAlert     = "spend_alert"     // AI花费超过月度预算告警阈值
+)
+
+// EventTypes 全部事件类型（Webhook 订阅时校验）
+var EventTypes = []string{
+	EventCycleCompleted, EventPositionOpened, EventPositionClosed, EventStateChanged,
+	EventTraderError, EventCircuitBreaker, EventAIBalanceAlert, EventSpendAlert,
+}
+
+// defaultEventBufferSize 每个订阅者的默认缓冲区大小
+const defaultEventBufferSize = 64
+
+// TraderEvent 交易员实时事件
+type TraderEvent struct {
+	Type      string      `json:"type"`
+	TraderID  string      `json:"trader_id"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// EventSubscription 事件订阅
+// 订阅者消费过慢（缓冲区满）或事件总线关闭时，C 会被关闭
+type EventSubscription struct {
+	C <-chan TraderEvent
+
+	ch   chan TraderEvent
+	bus  *EventBus
+	once sync.Once
+}
+
+// Close 取消订阅
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+// EventBus 交易员事件总线，将事件分发给多个订阅者
+type EventBus struct {
+	mu     sync.Mutex
+	subs   map[*EventSubscription]struct{}
+	closed bool
+}
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[*EventSubscription]struct{}),
+	}
+}
+
+// Subscribe 订阅事件，bufferSize <= 0 时使用默认缓冲区大小
+// 事件总线已关闭时返回的订阅通道立即关闭
+func (b *EventBus) Subscribe(bufferSize int) *EventSubscription {
+	if bufferSize <= 0 {
+		bufferSize = defaultEventBufferSize
+	}
+
+	ch := make(chan TraderEvent, bufferSize)
+	sub := &EventSubscription{C: ch, ch: ch, bus: b}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		sub.once.Do(func() { close(ch) })
+		return sub
+	}
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish 向所有订阅者发布事件（不阻塞），缓冲区已满的慢订阅者会被断开
+func (b *EventBus) Publish(event TraderEvent) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("⚠️ 事件订阅者消费过慢，已断开 [%s]", event.TraderID)
+			delete(b.subs, sub)
+			sub.once.Do(func() { close(sub.ch) })
+		}
+	}
+}
+
+// Closed 事件总线是否已关闭
+func (b *EventBus) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// SubscriberCount 当前订阅者数量
+func (b *EventBus) SubscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// Close 关闭事件总线并断开所有订阅者
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		sub.once.Do(func() { close(sub.ch) })
+	}
+}
+
+// unsubscribe 移除订阅者
+func (b *EventBus) unsubscribe(sub *EventSubscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subs, sub)
+	sub.once.Do(func() { close(sub.ch) })
+}
+
+// Events 获取交易员的事件总线
+func (at *AutoTrader) Events() *EventBus {
+	return at.events
+}
+
+// CloseEvents 关闭事件总线，断开所有订阅者（交易员从内存移除时调用）
+func (at *AutoTrader) CloseEvents() {
+	if at.events != nil {
+		at.events.Close()
+	}
+}
+
+// publishEvent 发布交易员事件
+func (at *AutoTrader) publishEvent(eventType string, data interface{}) {
+	if at.events == nil {
+		return
+	}
+	at.events.Publish(TraderEvent{
+		Type:     eventType,
+		TraderID: at.id,
+		Data:     data,
+	})
+}
+
+// publishStateChanged 发布运行状态变化事件
+func (at *AutoTrader) publishStateChanged(running bool) {
+	at.publishEvent(EventStateChanged, map[string]interface{}{
+		"is_running": running,
+	})
+}
+
+// publishCycleCompleted 发布决策周期完成事件（不含提示词等大字段）
+func (at *AutoTrader) publishCycleCompleted(record *logger.DecisionRecord) {
+	at.publishEvent(EventCycleCompleted, map[string]interface{}{
+		"cycle_number":  record.CycleNumber,
+		"success":       record.Success,
+		"error_message": record.ErrorMessage,
+		"account_state": record.AccountState,
+		"decisions":     record.Decisions,
+		"model_switch":  record.ModelSwitch,
+	})
+}
+
+// publishPositionEvent 根据成功执行的动作发布开仓/平仓事件
+func (at *AutoTrader) publishPositionEvent(action *logger.DecisionAction) {
+	switch action.Action {
+	case "open_long", "open_short":
+		at.publishEvent(EventPositionOpened, action)
+	case "close_long", "close_short", "partial_close":
+		at.publishEvent(EventPositionClosed, action)
+	}
+}
@@ -0,0 +1,79 @@
+package trader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEventBus_FanOut 事件分发给所有订阅者
+func TestEventBus_FanOut(t *testing.T) {
+	bus := NewEventBus()
+	sub1 := bus.Subscribe(4)
+	sub2 := bus.Subscribe(4)
+
+	bus.Publish(TraderEvent{Type: EventCycleCompleted, TraderID: "t1"})
+
+	for _, sub := range []*EventSubscription{sub1, sub2} {
+		event := <-sub.C
+		assert.Equal(t, EventCycleCompleted, event.Type)
+		assert.False(t, event.Timestamp.IsZero(), "应自动填充时间戳")
+	}
+
+	sub1.Close()
+	sub1.Close() // 重复关闭不应 panic
+	assert.Equal(t, 1, bus.SubscriberCount())
+}
+
+// TestEventBus_SlowSubscriberDisconnected 慢订阅者缓冲区满后被断开，不影响其他订阅者
+func TestEventBus_SlowSubscriberDisconnected(t *testing.T) {
+	bus := NewEventBus()
+	slow := bus.Subscribe(1)
+	fast := bus.Subscribe(8)
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(TraderEvent{Type: EventStateChanged})
+	}
+
+	// 慢订阅者：收到缓冲的1条后通道关闭
+	<-slow.C
+	_, ok := <-slow.C
+	assert.False(t, ok, "慢订阅者应被断开")
+
+	assert.Len(t, fast.C, 3)
+	assert.Equal(t, 1, bus.SubscriberCount())
+}
+
+// TestEventBus_Close 关闭总线断开所有订阅者，之后的订阅立即结束
+func TestEventBus_Close(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.Subscribe(0)
+
+	bus.Close()
+	_, ok := <-sub.C
+	assert.False(t, ok)
+
+	bus.Publish(TraderEvent{Type: EventStateChanged}) // 关闭后发布不应 panic
+
+	late := bus.Subscribe(0)
+	_, ok = <-late.C
+	assert.False(t, ok)
+	late.Close()
+}
+
+// TestAutoTrader_StateChangedEvent Run/Stop 推送运行状态事件
+func TestAutoTrader_StateChangedEvent(t *testing.T) {
+	at := &AutoTrader{id: "t1", events: NewEventBus()}
+	sub := at.Events().Subscribe(4)
+
+	at.publishStateChanged(true)
+	at.CloseEvents()
+
+	event := <-sub.C
+	assert.Equal(t, EventStateChanged, event.Type)
+	assert.Equal(t, "t1", event.TraderID)
+	assert.Equal(t, true, event.Data.(map[string]interface{})["is_running"])
+
+	_, ok := <-sub.C
+	assert.False(t, ok, "CloseEvents 后订阅应结束")
+}
@@ -0,0 +1,51 @@
+package trader
+
+import (
+	"log"
+	"nofx/decision"
+	"strings"
+)
+
+// newExcludedSymbolSet 标准化排除币种列表（转大写USDT交易对，忽略空项）
+func newExcludedSymbolSet(symbols []string) map[string]bool {
+	set := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		if strings.TrimSpace(symbol) == "" {
+			continue
+		}
+		set[normalizeSymbol(symbol)] = true
+	}
+	return set
+}
+
+// SetExcludedSymbols 修改排除币种列表，从下一个决策周期开始生效
+func (at *AutoTrader) SetExcludedSymbols(symbols []string) {
+	set := newExcludedSymbolSet(symbols)
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.excludedSymbols = set
+}
+
+// isExcludedSymbol 币种是否在排除列表中
+func (at *AutoTrader) isExcludedSymbol(symbol string) bool {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.excludedSymbols[symbol]
+}
+
+// filterExcludedCandidates 从候选币种中移除排除的币种（不论来自自定义列表、默认币种还是信号源币种池）
+func (at *AutoTrader) filterExcludedCandidates(coins []decision.CandidateCoin) []decision.CandidateCoin {
+	filtered := coins[:0:0]
+	var removed []string
+	for _, coin := range coins {
+		if at.isExcludedSymbol(coin.Symbol) {
+			removed = append(removed, coin.Symbol)
+			continue
+		}
+		filtered = append(filtered, coin)
+	}
+	if len(removed) > 0 {
+		log.Printf("🚫 [%s] 候选币种中排除: %v", at.name, removed)
+	}
+	return filtered
+}
@@ -0,0 +1,35 @@
+package trader
+
+import (
+	"testing"
+	"time"
+)
+
+// TestExcludedSymbols_FilterCandidates 测试排除的币种不进入候选币种（自定义列表中的排除项同样移除），热更新后下一周期生效
+func TestExcludedSymbols_FilterCandidates(t *testing.T) {
+	at := &AutoTrader{
+		config:          AutoTraderConfig{ScanInterval: 3 * time.Minute},
+		tradingCoins:    []string{"BTCUSDT", "PEPEUSDT", "ETHUSDT", "WIFUSDT"},
+		excludedSymbols: newExcludedSymbolSet([]string{" pepeusdt", "", "wif"}),
+	}
+
+	coins, err := at.getCandidateCoins()
+	if err != nil {
+		t.Fatalf("获取候选币种失败: %v", err)
+	}
+	if len(coins) != 2 || coins[0].Symbol != "BTCUSDT" || coins[1].Symbol != "ETHUSDT" {
+		t.Errorf("候选币种不应包含排除的币种: %+v", coins)
+	}
+	if !at.isExcludedSymbol("WIFUSDT") || at.isExcludedSymbol("BTCUSDT") {
+		t.Error("排除币种应标准化为USDT交易对")
+	}
+
+	at.SetExcludedSymbols(nil)
+	coins, err = at.getCandidateCoins()
+	if err != nil {
+		t.Fatalf("获取候选币种失败: %v", err)
+	}
+	if len(coins) != 4 {
+		t.Errorf("清空排除列表后应恢复全部候选币种: %+v", coins)
+	}
+}
@@ -0,0 +1,128 @@
+package trader
+
+import (
+	"log"
+	"math/rand"
+	"nofx/decision"
+)
+
+// 提示词A/B实验分组方式
+const (
+	ExperimentModeAlternate = "alternate" // 按比例轮流分配周期（可复现）
+	ExperimentModeRandom    = "random"    // 每个周期按比例随机分配
+)
+
+// 实验分组标签（写入决策记录，历史记录的归属不随实验配置变化）
+const (
+	VariantA = "A"
+	VariantB = "B"
+)
+
+// PromptExperiment 提示词模板A/B实验配置
+type PromptExperiment struct {
+	Enabled    bool
+	TemplateA  string
+	TemplateB  string
+	SplitRatio float64 // A组周期占比（0-1），其余周期使用B组
+	Mode       string  // alternate 或 random
+}
+
+// experimentState 运行中的A/B实验状态（settingsMu 保护）
+type experimentState struct {
+	config  PromptExperiment
+	pinnedA *decision.PromptTemplate // 启动时固定的A组模板版本
+	pinnedB *decision.PromptTemplate // 启动时固定的B组模板版本
+	cyclesA int                      // 本次实验已分配给A组的周期数
+	cyclesB int                      // 本次实验已分配给B组的周期数
+}
+
+// SetPromptExperiment 设置A/B实验配置并固定两组模板的当前版本（关闭实验不影响已记录周期的分组归属）
+func (at *AutoTrader) SetPromptExperiment(exp PromptExperiment) {
+	var pinnedA, pinnedB *decision.PromptTemplate
+	if exp.Enabled {
+		pinnedA = pinExperimentTemplate(at.userID, exp.TemplateA)
+		pinnedB = pinExperimentTemplate(at.userID, exp.TemplateB)
+		if pinnedA == nil || pinnedB == nil {
+			log.Printf("⚠️ [%s] A/B实验模板不存在，实验暂不生效 (A=%s, B=%s)", at.name, exp.TemplateA, exp.TemplateB)
+		} else {
+			log.Printf("🧪 [%s] A/B实验: A=%s v%d, B=%s v%d, A组占比 %.0f%% (%s)",
+				at.name, pinnedA.Name, pinnedA.Version, pinnedB.Name, pinnedB.Version, exp.SplitRatio*100, exp.Mode)
+		}
+	}
+
+	at.settingsMu.Lock()
+	at.experiment = experimentState{config: exp, pinnedA: pinnedA, pinnedB: pinnedB}
+	at.settingsMu.Unlock()
+}
+
+// GetPromptExperiment 获取当前A/B实验配置
+func (at *AutoTrader) GetPromptExperiment() PromptExperiment {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.experiment.config
+}
+
+// pinExperimentTemplate 按名称解析模板并复制一份（不回退到 default，模板不存在时返回 nil）
+func pinExperimentTemplate(userID, name string) *decision.PromptTemplate {
+	template, err := decision.GetPromptTemplateForUser(userID, name)
+	if err != nil {
+		return nil
+	}
+	pinned := *template
+	return &pinned
+}
+
+// chooseExperimentVariant 为本周期选择实验分组，返回分组标签和对应模板（未开启实验或模板缺失时返回空）
+func (at *AutoTrader) chooseExperimentVariant() (string, *decision.PromptTemplate) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+
+	exp := &at.experiment
+	if !exp.config.Enabled || exp.pinnedA == nil || exp.pinnedB == nil {
+		return "", nil
+	}
+
+	ratio := exp.config.SplitRatio
+	var useA bool
+	if exp.config.Mode == ExperimentModeRandom {
+		useA = rand.Float64() < ratio
+	} else {
+		// 轮流模式：A组已分配周期数低于目标比例时分给A组，否则分给B组
+		total := exp.cyclesA + exp.cyclesB + 1
+		useA = float64(exp.cyclesA) < ratio*float64(total)
+	}
+
+	if useA {
+		exp.cyclesA++
+		return VariantA, exp.pinnedA
+	}
+	exp.cyclesB++
+	return VariantB, exp.pinnedB
+}
+
+// experimentStatus A/B实验的运行状态（未开启时返回 nil）
+func (at *AutoTrader) experimentStatus() map[string]interface{} {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+
+	exp := at.experiment
+	if !exp.config.Enabled {
+		return nil
+	}
+	status := map[string]interface{}{
+		"template_a":  exp.config.TemplateA,
+		"template_b":  exp.config.TemplateB,
+		"split_ratio": exp.config.SplitRatio,
+		"mode":        exp.config.Mode,
+		"cycles_a":    exp.cyclesA,
+		"cycles_b":    exp.cyclesB,
+		"active":      exp.pinnedA != nil && exp.pinnedB != nil,
+	}
+	if exp.pinnedA != nil {
+		status["template_a_version"] = exp.pinnedA.Version
+	}
+	if exp.pinnedB != nil {
+		status["template_b_version"] = exp.pinnedB.Version
+	}
+	return status
+}
@@ -0,0 +1,81 @@
+package trader
+
+import (
+	"strings"
+	"testing"
+
+	"nofx/decision"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func setupExperimentTemplates(t *testing.T, userID string) {
+	decision.SetUserPromptTemplate(userID, "exp_a", "A {{account_state}} {{market_data}}", 1)
+	decision.SetUserPromptTemplate(userID, "exp_b", "B {{account_state}} {{market_data}}", 3)
+	t.Cleanup(func() {
+		decision.RemoveUserPromptTemplate(userID, "exp_a")
+		decision.RemoveUserPromptTemplate(userID, "exp_b")
+	})
+}
+
+func TestChooseExperimentVariant_AlternatesBySplitRatio(t *testing.T) {
+	setupExperimentTemplates(t, "exp-user")
+	at := &AutoTrader{name: "exp", userID: "exp-user"}
+
+	at.SetPromptExperiment(PromptExperiment{Enabled: true, TemplateA: "exp_a", TemplateB: "exp_b", SplitRatio: 0.5, Mode: ExperimentModeAlternate})
+	var variants []string
+	for i := 0; i < 4; i++ {
+		variant, template := at.chooseExperimentVariant()
+		variants = append(variants, variant)
+		assert.Equal(t, "exp_"+strings.ToLower(variant), template.Name)
+	}
+	assert.Equal(t, []string{"A", "B", "A", "B"}, variants)
+
+	// 75% 分给A组：每4个周期中3个A
+	at.SetPromptExperiment(PromptExperiment{Enabled: true, TemplateA: "exp_a", TemplateB: "exp_b", SplitRatio: 0.75, Mode: ExperimentModeAlternate})
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		variant, _ := at.chooseExperimentVariant()
+		counts[variant]++
+	}
+	assert.Equal(t, map[string]int{"A": 6, "B": 2}, counts)
+}
+
+func TestChooseExperimentVariant_DisabledOrMissingTemplate(t *testing.T) {
+	setupExperimentTemplates(t, "exp-user2")
+	at := &AutoTrader{name: "exp", userID: "exp-user2"}
+
+	variant, template := at.chooseExperimentVariant()
+	assert.Empty(t, variant)
+	assert.Nil(t, template)
+
+	// B组模板不存在时实验不生效，周期不打分组标签
+	at.SetPromptExperiment(PromptExperiment{Enabled: true, TemplateA: "exp_a", TemplateB: "missing", SplitRatio: 0.5})
+	variant, template = at.chooseExperimentVariant()
+	assert.Empty(t, variant)
+	assert.Nil(t, template)
+
+	// 运行中关闭实验
+	at.SetPromptExperiment(PromptExperiment{Enabled: true, TemplateA: "exp_a", TemplateB: "exp_b", SplitRatio: 0.5})
+	variant, _ = at.chooseExperimentVariant()
+	assert.Equal(t, VariantA, variant)
+	at.SetPromptExperiment(PromptExperiment{})
+	variant, _ = at.chooseExperimentVariant()
+	assert.Empty(t, variant)
+}
+
+func TestSetPromptExperiment_PinsTemplateVersions(t *testing.T) {
+	setupExperimentTemplates(t, "exp-user3")
+	at := &AutoTrader{name: "exp", userID: "exp-user3"}
+	at.SetPromptExperiment(PromptExperiment{Enabled: true, TemplateA: "exp_a", TemplateB: "exp_b", SplitRatio: 0.5})
+
+	// 运行期间保存新版本不影响已固定的实验模板
+	decision.SetUserPromptTemplate("exp-user3", "exp_a", "A2 {{account_state}} {{market_data}}", 2)
+	_, template := at.chooseExperimentVariant()
+	assert.Equal(t, 1, template.Version)
+
+	status := at.experimentStatus()
+	assert.Equal(t, 1, status["template_a_version"])
+	assert.Equal(t, 3, status["template_b_version"])
+	assert.Equal(t, true, status["active"])
+}
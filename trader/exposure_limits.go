@@ -0,0 +1,155 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"nofx/logger"
+	"strings"
+	"time"
+)
+
+// minExposureRoomUSDT 风控剩余额度低于该值时直接拒绝开仓（缩减后的仓位会低于交易所最小下单金额）
+const minExposureRoomUSDT = 10.0
+
+// ExposureLimitError 开仓因仓位/敞口上限被拒绝（原因会反馈给下一周期的AI）
+type ExposureLimitError struct {
+	Reason string
+}
+
+func (e *ExposureLimitError) Error() string {
+	return e.Reason
+}
+
+// isExposureLimitError 判断错误是否为风控上限拒绝
+func isExposureLimitError(err error) bool {
+	var limitErr *ExposureLimitError
+	return errors.As(err, &limitErr)
+}
+
+// SetExposureLimits 修改单币种仓位价值上限和总敞口上限（<=0 表示不限制），从下一次开仓开始生效
+func (at *AutoTrader) SetExposureLimits(maxPositionValueUSDT, maxTotalExposurePct float64) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.config.MaxPositionValueUSDT = maxPositionValueUSDT
+	at.config.MaxTotalExposurePct = maxTotalExposurePct
+}
+
+// GetExposureLimits 读取单币种仓位价值上限（USDT）和总敞口上限（净值百分比）
+func (at *AutoTrader) GetExposureLimits() (float64, float64) {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.config.MaxPositionValueUSDT, at.config.MaxTotalExposurePct
+}
+
+// riskLimitsForPrompt 生成写入AI提示词的风控限制说明：仓位/敞口上限和亏损冷却（未配置时为空）
+func (at *AutoTrader) riskLimitsForPrompt() string {
+	maxPositionValue, maxExposurePct := at.GetExposureLimits()
+	var limits []string
+	if maxPositionValue > 0 {
+		limits = append(limits, fmt.Sprintf("单币种仓位价值≤%.0f USDT（含已有持仓）", maxPositionValue))
+	}
+	if maxExposurePct > 0 {
+		limits = append(limits, fmt.Sprintf("所有持仓价值合计≤净值的%.0f%%", maxExposurePct))
+	}
+
+	var lines []string
+	if len(limits) > 0 {
+		lines = append(lines, strings.Join(limits, " | ")+"，超出部分会被自动缩减或拒绝")
+	}
+	if cooldown := at.getLossCooldown(); cooldown > 0 {
+		lines = append(lines, fmt.Sprintf("同币种亏损平仓后%d分钟内禁止再次开仓", int(cooldown.Minutes())))
+		if cooling := at.lossCooldownsForPrompt(time.Now()); cooling != "" {
+			lines = append(lines, cooling)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// positionNotional 持仓名义价值（数量绝对值 × 标记价格）
+func positionNotional(pos map[string]interface{}) float64 {
+	quantity, _ := pos["positionAmt"].(float64)
+	markPrice, _ := pos["markPrice"].(float64)
+	return math.Abs(quantity) * markPrice
+}
+
+// clampPositionSize 按单币种仓位价值上限和总敞口上限计算允许的开仓价值
+// 已有持仓计入额度：同币种（不分方向）的持仓价值占用单币种额度，所有持仓价值合计占用总敞口额度
+// 返回允许的开仓价值和缩减说明（未缩减时为空），额度不足时返回 ExposureLimitError
+func clampPositionSize(symbol string, requested float64, positions []map[string]interface{}, equity, maxPositionValue, maxExposurePct float64) (float64, string, error) {
+	allowed := requested
+	var notes []string
+
+	if maxPositionValue > 0 {
+		existing := 0.0
+		for _, pos := range positions {
+			if pos["symbol"] == symbol {
+				existing += positionNotional(pos)
+			}
+		}
+		room := maxPositionValue - existing
+		if room < minExposureRoomUSDT {
+			return 0, "", &ExposureLimitError{Reason: fmt.Sprintf("%s 已有持仓价值 %.2f USDT，已达单币种仓位上限 %.2f USDT，拒绝开仓",
+				symbol, existing, maxPositionValue)}
+		}
+		if allowed > room {
+			notes = append(notes, fmt.Sprintf("单币种仓位上限 %.2f USDT（已有 %.2f）", maxPositionValue, existing))
+			allowed = room
+		}
+	}
+
+	if maxExposurePct > 0 {
+		if equity <= 0 {
+			return 0, "", &ExposureLimitError{Reason: fmt.Sprintf("账户净值 %.2f 无效，无法校验总敞口上限，拒绝开仓", equity)}
+		}
+		total := 0.0
+		for _, pos := range positions {
+			total += positionNotional(pos)
+		}
+		maxExposure := equity * maxExposurePct / 100
+		room := maxExposure - total
+		if room < minExposureRoomUSDT {
+			return 0, "", &ExposureLimitError{Reason: fmt.Sprintf("当前总持仓价值 %.2f USDT，已达总敞口上限 %.2f USDT（净值的 %.0f%%），拒绝开仓 %s",
+				total, maxExposure, maxExposurePct, symbol)}
+		}
+		if allowed > room {
+			notes = append(notes, fmt.Sprintf("总敞口上限 %.2f USDT（净值的 %.0f%%，已有 %.2f）", maxExposure, maxExposurePct, total))
+			allowed = room
+		}
+	}
+
+	if len(notes) == 0 {
+		return requested, "", nil
+	}
+	note := fmt.Sprintf("%s 开仓价值 %.2f USDT 超过%s，已缩减为 %.2f USDT",
+		symbol, requested, strings.Join(notes, "、"), allowed)
+	return allowed, note, nil
+}
+
+// applyExposureLimits 对AI给出的开仓价值执行仓位/敞口上限（无论AI如何决策都强制生效）
+// 缩减时写入 actionRecord.RiskNote；未配置上限时原样返回
+func (at *AutoTrader) applyExposureLimits(symbol string, requested float64, positions []map[string]interface{}, positionsErr error, balance map[string]interface{}, actionRecord *logger.DecisionAction) (float64, error) {
+	maxPositionValue, maxExposurePct := at.GetExposureLimits()
+	if maxPositionValue <= 0 && maxExposurePct <= 0 {
+		return requested, nil
+	}
+	if positionsErr != nil {
+		// 无法确认已有持仓时不冒险开仓
+		return 0, &ExposureLimitError{Reason: fmt.Sprintf("获取持仓失败，无法校验仓位上限，拒绝开仓: %v", positionsErr)}
+	}
+
+	wallet, _ := balance["totalWalletBalance"].(float64)
+	unrealized, _ := balance["totalUnrealizedProfit"].(float64)
+
+	allowed, note, err := clampPositionSize(symbol, requested, positions, wallet+unrealized, maxPositionValue, maxExposurePct)
+	if err != nil {
+		log.Printf("  🛡️ 风控拒绝: %v", err)
+		return 0, err
+	}
+	if note != "" {
+		log.Printf("  🛡️ 风控缩减: %s", note)
+		actionRecord.RiskNote = note
+	}
+	return allowed, nil
+}
@@ -0,0 +1,68 @@
+package trader
+
+import (
+	"errors"
+	"testing"
+
+	"nofx/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClampPositionSize 测试单币种仓位上限和总敞口上限计入已有持仓，超限时缩减或拒绝
+func TestClampPositionSize(t *testing.T) {
+	positions := []map[string]interface{}{
+		{"symbol": "BTCUSDT", "side": "short", "positionAmt": -0.01, "markPrice": 20000.0}, // 200 USDT
+		{"symbol": "ETHUSDT", "side": "long", "positionAmt": 0.5, "markPrice": 2000.0},     // 1000 USDT
+	}
+
+	// 未配置上限时原样返回
+	size, note, err := clampPositionSize("BTCUSDT", 5000, positions, 1000, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 5000.0, size)
+	assert.Empty(t, note)
+
+	// 单币种上限 500，已有 BTC 空仓 200 → 最多再开 300
+	size, note, err = clampPositionSize("BTCUSDT", 1000, positions, 1000, 500, 0)
+	require.NoError(t, err)
+	assert.InDelta(t, 300, size, 1e-9)
+	assert.Contains(t, note, "单币种仓位上限")
+
+	// 总敞口上限 150% 净值 = 1500，已有 1200 → 最多再开 300，取两者中更严格的
+	size, note, err = clampPositionSize("SOLUSDT", 400, positions, 1000, 350, 150)
+	require.NoError(t, err)
+	assert.InDelta(t, 300, size, 1e-9)
+	assert.Contains(t, note, "单币种仓位上限 350.00 USDT")
+	assert.Contains(t, note, "总敞口上限 1500.00 USDT")
+
+	// 额度用尽时拒绝
+	_, _, err = clampPositionSize("ETHUSDT", 200, positions, 1000, 1000, 0)
+	require.Error(t, err)
+	assert.True(t, isExposureLimitError(err))
+
+	_, _, err = clampPositionSize("SOLUSDT", 200, positions, 1000, 0, 120)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "总敞口上限")
+}
+
+// TestApplyExposureLimits 测试缩减记录到决策动作，且持仓未知时拒绝开仓
+func TestApplyExposureLimits(t *testing.T) {
+	at := &AutoTrader{config: AutoTraderConfig{MaxTotalExposurePct: 100}}
+	balance := map[string]interface{}{"totalWalletBalance": 900.0, "totalUnrealizedProfit": 100.0}
+
+	record := &logger.DecisionAction{}
+	size, err := at.applyExposureLimits("BTCUSDT", 2000, nil, nil, balance, record)
+	require.NoError(t, err)
+	assert.InDelta(t, 1000, size, 1e-9)
+	assert.Contains(t, record.RiskNote, "已缩减为 1000.00 USDT")
+
+	_, err = at.applyExposureLimits("BTCUSDT", 100, nil, errors.New("timeout"), balance, &logger.DecisionAction{})
+	assert.True(t, isExposureLimitError(err))
+
+	at.SetExposureLimits(0, 0)
+	size, err = at.applyExposureLimits("BTCUSDT", 2000, nil, errors.New("timeout"), balance, &logger.DecisionAction{})
+	require.NoError(t, err, "未配置上限时不依赖持仓信息")
+	assert.Equal(t, 2000.0, size)
+	assert.Empty(t, at.riskLimitsForPrompt())
+}
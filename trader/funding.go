@@ -0,0 +1,152 @@
+package trader
+
+import (
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+	"sync"
+	"time"
+)
+
+// fundingLookbackDays 资金费统计窗口（与表现分析的交易历史窗口一致）
+const fundingLookbackDays = 7
+
+// fundingFeesTTL 资金费流水缓存有效期（结算间隔至少1小时，收入流水接口权重较高）
+const fundingFeesTTL = 5 * time.Minute
+
+// FundingRate 永续合约资金费率（正数表示多头向空头支付）
+type FundingRate struct {
+	Symbol          string    `json:"symbol"`
+	Rate            float64   `json:"rate"`              // 当前周期费率（下次结算时按此费率收取）
+	PredictedRate   float64   `json:"predicted_rate"`    // 预测的下一周期费率（0 表示交易所不提供）
+	IntervalHours   int       `json:"interval_hours"`    // 结算间隔（小时）
+	NextFundingTime time.Time `json:"next_funding_time"` // 下次结算时间（零值表示未知）
+}
+
+// FundingFee 一笔资金费结算（正数为收到，负数为支付）
+type FundingFee struct {
+	Symbol string  `json:"symbol"`
+	Amount float64 `json:"amount"`
+	Time   int64   `json:"time"` // 毫秒时间戳
+}
+
+// FundingProvider 可选接口：支持查询资金费率和资金费收支的交易器
+type FundingProvider interface {
+	// GetFundingRates 获取指定币种的资金费率（symbols 为空时返回全部）
+	GetFundingRates(symbols []string) (map[string]FundingRate, error)
+	// GetFundingFees 获取指定时间以来的资金费收支（按时间升序）
+	GetFundingFees(startTime time.Time) ([]FundingFee, error)
+}
+
+// filterFundingRates 只保留需要的币种（symbols 为空时全部保留）
+func filterFundingRates(rates map[string]FundingRate, symbols []string) map[string]FundingRate {
+	if len(symbols) == 0 {
+		return rates
+	}
+	result := make(map[string]FundingRate, len(symbols))
+	for _, symbol := range symbols {
+		if rate, ok := rates[symbol]; ok {
+			result[symbol] = rate
+		}
+	}
+	return result
+}
+
+// sortFundingFees 按结算时间升序排列
+func sortFundingFees(fees []FundingFee) []FundingFee {
+	sort.SliceStable(fees, func(i, j int) bool {
+		return fees[i].Time < fees[j].Time
+	})
+	return fees
+}
+
+// sumFundingFees 汇总资金费（symbol 为空时汇总全部币种，只统计 [from, to] 时间范围内的结算，to 为 0 表示不限）
+func sumFundingFees(fees []FundingFee, symbol string, from, to int64) float64 {
+	total := 0.0
+	for _, fee := range fees {
+		if symbol != "" && fee.Symbol != symbol {
+			continue
+		}
+		if fee.Time < from || (to > 0 && fee.Time > to) {
+			continue
+		}
+		total += fee.Amount
+	}
+	return total
+}
+
+// applyTradeFunding 将持仓期间内该币种的资金费计入交易盈亏
+// 资金费流水不区分持仓方向，双向持仓时同一币种两条腿持仓期间重叠部分的资金费会分别计入
+func applyTradeFunding(outcome *logger.TradeOutcome, fees []FundingFee) {
+	funding := sumFundingFees(fees, outcome.Symbol, outcome.OpenTime.UnixMilli(), outcome.CloseTime.UnixMilli())
+	if funding == 0 {
+		return
+	}
+	outcome.FundingFees = funding
+	outcome.PnL += funding
+	if outcome.MarginUsed > 0 {
+		outcome.PnLPct = outcome.PnL / outcome.MarginUsed * 100
+	}
+}
+
+// fundingFeesCache 资金费流水缓存
+type fundingFeesCache struct {
+	mu        sync.Mutex
+	fees      []FundingFee
+	fetchedAt time.Time
+}
+
+// fundingFees 获取统计窗口内的资金费流水（带缓存，交易器不支持时返回 false）
+func (at *AutoTrader) fundingFees() ([]FundingFee, bool) {
+	provider, ok := at.trader.(FundingProvider)
+	if !ok {
+		return nil, false
+	}
+
+	at.funding.mu.Lock()
+	defer at.funding.mu.Unlock()
+	if at.funding.fees != nil && time.Since(at.funding.fetchedAt) < fundingFeesTTL {
+		return at.funding.fees, true
+	}
+
+	fees, err := provider.GetFundingFees(time.Now().AddDate(0, 0, -fundingLookbackDays))
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取资金费流水失败: %v", at.name, err)
+		// 刷新失败时沿用旧缓存
+		return at.funding.fees, at.funding.fees != nil
+	}
+	if fees == nil {
+		fees = []FundingFee{}
+	}
+	at.funding.fees = fees
+	at.funding.fetchedAt = time.Now()
+	return fees, true
+}
+
+// fundingRatesForContext 获取持仓和候选币种的资金费率（供AI决策参考，获取失败时返回 nil）
+func (at *AutoTrader) fundingRatesForContext(symbols []string) map[string]*decision.FundingInfo {
+	provider, ok := at.trader.(FundingProvider)
+	if !ok || len(symbols) == 0 {
+		return nil
+	}
+	rates, err := provider.GetFundingRates(symbols)
+	if err != nil {
+		log.Printf("⚠️ [%s] 获取资金费率失败: %v", at.name, err)
+		return nil
+	}
+
+	result := make(map[string]*decision.FundingInfo, len(rates))
+	for symbol, rate := range rates {
+		info := &decision.FundingInfo{
+			Rate:          rate.Rate,
+			PredictedRate: rate.PredictedRate,
+			IntervalHours: rate.IntervalHours,
+		}
+		if !rate.NextFundingTime.IsZero() {
+			info.NextFundingTime = rate.NextFundingTime.UnixMilli()
+		}
+		result[symbol] = info
+	}
+	return result
+}
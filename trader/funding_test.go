@@ -0,0 +1,119 @@
+package trader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFundingProviders 确认各交易所交易器实现了资金费接口
+func TestFundingProviders(t *testing.T) {
+	var _ FundingProvider = (*FuturesTrader)(nil)
+	var _ FundingProvider = (*AsterTrader)(nil)
+	var _ FundingProvider = (*HyperliquidTrader)(nil)
+}
+
+// TestAnalyzeTradeHistoryWithFunding 持仓期间的资金费计入交易净盈亏，窗口内全部资金费计入合计
+func TestAnalyzeTradeHistoryWithFunding(t *testing.T) {
+	history := map[string][]*Trade{
+		"BTCUSDT": {
+			// 多仓盈亏 (110-100)*1 = 10，持仓期间支付资金费 2
+			{Side: "BUY", PositionSide: "LONG", Price: 100, Qty: 1, Time: 1000},
+			{Side: "SELL", PositionSide: "LONG", Price: 110, Qty: 1, RealizedPnl: 10, Time: 5000},
+		},
+	}
+	fees := []FundingFee{
+		{Symbol: "BTCUSDT", Amount: -1.5, Time: 2000},
+		{Symbol: "BTCUSDT", Amount: -0.5, Time: 4000},
+		{Symbol: "BTCUSDT", Amount: -3, Time: 9000},  // 平仓后，不计入该笔交易
+		{Symbol: "ETHUSDT", Amount: 0.7, Time: 3000}, // 其他币种
+	}
+
+	analysis := AnalyzeTradeHistoryWithFunding(history, fees)
+	require.Len(t, analysis.RecentTrades, 1)
+	trade := analysis.RecentTrades[0]
+	assert.InDelta(t, -2.0, trade.FundingFees, 1e-9)
+	assert.InDelta(t, 8.0, trade.PnL, 1e-9)
+	assert.InDelta(t, 8.0, analysis.SymbolStats["BTCUSDT"].TotalPnL, 1e-9)
+	assert.InDelta(t, -4.3, analysis.FundingFees, 1e-9)
+
+	// 不传资金费时与 AnalyzeTradeHistory 一致
+	plain := AnalyzeTradeHistory(history)
+	assert.InDelta(t, 10.0, plain.RecentTrades[0].PnL, 1e-9)
+	assert.Zero(t, plain.FundingFees)
+}
+
+// TestSumFundingFees 按币种和时间范围汇总资金费
+func TestSumFundingFees(t *testing.T) {
+	fees := []FundingFee{
+		{Symbol: "BTCUSDT", Amount: -1, Time: 1000},
+		{Symbol: "BTCUSDT", Amount: 2, Time: 2000},
+		{Symbol: "ETHUSDT", Amount: 0.5, Time: 3000},
+	}
+	assert.InDelta(t, 1.5, sumFundingFees(fees, "", 0, 0), 1e-9)
+	assert.InDelta(t, 1.0, sumFundingFees(fees, "BTCUSDT", 0, 0), 1e-9)
+	assert.InDelta(t, 2.0, sumFundingFees(fees, "BTCUSDT", 1500, 0), 1e-9)
+	assert.InDelta(t, -1.0, sumFundingFees(fees, "BTCUSDT", 0, 1500), 1e-9)
+	assert.Zero(t, sumFundingFees(nil, "", 0, 0))
+}
+
+// TestFuturesTrader_FundingRatesAndFees 币安资金费率（premiumIndex + fundingInfo）和资金费流水（income FUNDING_FEE）
+func TestFuturesTrader_FundingRatesAndFees(t *testing.T) {
+	var incomeType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var respBody interface{}
+		switch r.URL.Path {
+		case "/fapi/v1/premiumIndex":
+			respBody = []map[string]interface{}{
+				{"symbol": "BTCUSDT", "markPrice": "50000", "lastFundingRate": "0.00010000", "nextFundingTime": 1700000000000},
+				{"symbol": "ETHUSDT", "markPrice": "3000", "lastFundingRate": "-0.00025000", "nextFundingTime": 1700000000000},
+			}
+		case "/fapi/v1/fundingInfo":
+			respBody = []map[string]interface{}{
+				{"symbol": "ETHUSDT", "fundingIntervalHours": 4},
+			}
+		case "/fapi/v1/income":
+			incomeType = r.URL.Query().Get("incomeType")
+			respBody = []map[string]interface{}{
+				{"symbol": "ETHUSDT", "incomeType": "FUNDING_FEE", "income": "0.12", "asset": "USDT", "time": 3000},
+				{"symbol": "BTCUSDT", "incomeType": "FUNDING_FEE", "income": "-0.50", "asset": "USDT", "time": 1000},
+			}
+		default:
+			respBody = map[string]interface{}{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(respBody)
+	}))
+	defer server.Close()
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	trader := &FuturesTrader{client: client}
+
+	rates, err := trader.GetFundingRates([]string{"ETHUSDT", "SOLUSDT"})
+	require.NoError(t, err)
+	require.Len(t, rates, 1)
+	eth := rates["ETHUSDT"]
+	assert.InDelta(t, -0.00025, eth.Rate, 1e-12)
+	assert.Equal(t, 4, eth.IntervalHours)
+	assert.Equal(t, int64(1700000000000), eth.NextFundingTime.UnixMilli())
+
+	all, err := trader.GetFundingRates(nil)
+	require.NoError(t, err)
+	assert.Equal(t, binanceDefaultFundingInterval, all["BTCUSDT"].IntervalHours)
+
+	fees, err := trader.GetFundingFees(time.UnixMilli(0))
+	require.NoError(t, err)
+	assert.Equal(t, "FUNDING_FEE", incomeType)
+	if assert.Len(t, fees, 2) {
+		assert.Equal(t, FundingFee{Symbol: "BTCUSDT", Amount: -0.5, Time: 1000}, fees[0])
+		assert.Equal(t, "ETHUSDT", fees[1].Symbol)
+	}
+}
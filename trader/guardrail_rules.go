@@ -0,0 +1,264 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/decision"
+	"strings"
+	"time"
+)
+
+// 内置护栏规则类型
+const (
+	GuardrailMaxPositions    = "max_positions"     // 同时持仓数上限
+	GuardrailSymbolBlacklist = "symbol_blacklist"  // 禁止开仓的币种（可限定方向）
+	GuardrailMaxLeverage     = "max_leverage"      // 杠杆上限（超出时调整）
+	GuardrailMaxPositionSize = "max_position_size" // 单笔开仓价值上限（超出时调整）
+	GuardrailTradingHours    = "trading_hours"     // 只在指定时段内开仓
+	GuardrailFundingWindow   = "funding_window"    // 资金费结算前后一段时间内禁止开仓
+)
+
+func init() {
+	RegisterGuardrail(GuardrailMaxPositions, newMaxPositionsRule)
+	RegisterGuardrail(GuardrailSymbolBlacklist, newSymbolBlacklistRule)
+	RegisterGuardrail(GuardrailMaxLeverage, newMaxLeverageRule)
+	RegisterGuardrail(GuardrailMaxPositionSize, newMaxPositionSizeRule)
+	RegisterGuardrail(GuardrailTradingHours, newTradingHoursRule)
+	RegisterGuardrail(GuardrailFundingWindow, newFundingWindowRule)
+}
+
+// decodeGuardrailParams 解析规则参数（不允许未知字段，避免拼写错误的参数被静默忽略）
+func decodeGuardrailParams(params json.RawMessage, out interface{}) error {
+	if len(params) == 0 {
+		return fmt.Errorf("缺少参数")
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(params)))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(out)
+}
+
+// maxPositionsRule 同时持仓数上限（加仓已有方向不计为新持仓）
+type maxPositionsRule struct {
+	Max int `json:"max"`
+}
+
+func newMaxPositionsRule(params json.RawMessage) (GuardrailRule, error) {
+	rule := &maxPositionsRule{}
+	if err := decodeGuardrailParams(params, rule); err != nil {
+		return nil, err
+	}
+	if rule.Max < 1 {
+		return nil, fmt.Errorf("max 必须大于0")
+	}
+	return rule, nil
+}
+
+func (r *maxPositionsRule) Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult {
+	if state.Positions[d.Symbol+"_"+positionSide(d.Action)] || len(state.Positions) < r.Max {
+		return GuardrailResult{Verdict: GuardrailPass}
+	}
+	return GuardrailResult{Verdict: GuardrailVeto, Reason: fmt.Sprintf("已有 %d 个持仓，达到上限 %d", len(state.Positions), r.Max)}
+}
+
+// symbolBlacklistRule 禁止开仓的币种；sides 为空表示禁止两个方向
+type symbolBlacklistRule struct {
+	Symbols []string `json:"symbols"`
+	Sides   []string `json:"sides,omitempty"` // long/short
+}
+
+func newSymbolBlacklistRule(params json.RawMessage) (GuardrailRule, error) {
+	rule := &symbolBlacklistRule{}
+	if err := decodeGuardrailParams(params, rule); err != nil {
+		return nil, err
+	}
+	if len(rule.Symbols) == 0 {
+		return nil, fmt.Errorf("symbols 不能为空")
+	}
+	for i, symbol := range rule.Symbols {
+		rule.Symbols[i] = strings.ToUpper(strings.TrimSpace(symbol))
+	}
+	for _, side := range rule.Sides {
+		if side != "long" && side != "short" {
+			return nil, fmt.Errorf("sides 只能是 long 或 short: %q", side)
+		}
+	}
+	return rule, nil
+}
+
+func (r *symbolBlacklistRule) Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult {
+	side := positionSide(d.Action)
+	sideBlocked := len(r.Sides) == 0
+	for _, s := range r.Sides {
+		if s == side {
+			sideBlocked = true
+		}
+	}
+	if !sideBlocked {
+		return GuardrailResult{Verdict: GuardrailPass}
+	}
+	for _, symbol := range r.Symbols {
+		if symbol == d.Symbol {
+			return GuardrailResult{Verdict: GuardrailVeto, Reason: fmt.Sprintf("%s 禁止开%s仓", d.Symbol, map[string]string{"long": "多", "short": "空"}[side])}
+		}
+	}
+	return GuardrailResult{Verdict: GuardrailPass}
+}
+
+// maxLeverageRule 杠杆上限，超出时调整为上限
+type maxLeverageRule struct {
+	Max int `json:"max"`
+}
+
+func newMaxLeverageRule(params json.RawMessage) (GuardrailRule, error) {
+	rule := &maxLeverageRule{}
+	if err := decodeGuardrailParams(params, rule); err != nil {
+		return nil, err
+	}
+	if rule.Max < 1 || rule.Max > 125 {
+		return nil, fmt.Errorf("max 必须在1-125之间")
+	}
+	return rule, nil
+}
+
+func (r *maxLeverageRule) Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult {
+	if d.Leverage <= r.Max {
+		return GuardrailResult{Verdict: GuardrailPass}
+	}
+	reason := fmt.Sprintf("杠杆 %dx 超过上限，调整为 %dx", d.Leverage, r.Max)
+	d.Leverage = r.Max
+	return GuardrailResult{Verdict: GuardrailClamp, Reason: reason}
+}
+
+// maxPositionSizeRule 单笔开仓价值上限（USDT），超出时调整为上限
+type maxPositionSizeRule struct {
+	MaxUSD float64 `json:"max_usd"`
+}
+
+func newMaxPositionSizeRule(params json.RawMessage) (GuardrailRule, error) {
+	rule := &maxPositionSizeRule{}
+	if err := decodeGuardrailParams(params, rule); err != nil {
+		return nil, err
+	}
+	if rule.MaxUSD <= 0 {
+		return nil, fmt.Errorf("max_usd 必须大于0")
+	}
+	return rule, nil
+}
+
+func (r *maxPositionSizeRule) Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult {
+	if d.PositionSizeUSD <= r.MaxUSD {
+		return GuardrailResult{Verdict: GuardrailPass}
+	}
+	reason := fmt.Sprintf("开仓价值 %.2f USDT 超过上限，调整为 %.2f USDT", d.PositionSizeUSD, r.MaxUSD)
+	d.PositionSizeUSD = r.MaxUSD
+	return GuardrailResult{Verdict: GuardrailClamp, Reason: reason}
+}
+
+// tradingHoursRule 只在每天 start-end 时段内开仓（end 早于 start 表示跨零点），weekdays 为空表示每天
+type tradingHoursRule struct {
+	Start    string `json:"start"`              // HH:MM
+	End      string `json:"end"`                // HH:MM
+	Timezone string `json:"timezone,omitempty"` // IANA 时区，默认 UTC
+	Weekdays []int  `json:"weekdays,omitempty"` // 0=周日 … 6=周六
+
+	location   *time.Location
+	start, end int // 一天中的分钟数
+}
+
+func newTradingHoursRule(params json.RawMessage) (GuardrailRule, error) {
+	rule := &tradingHoursRule{}
+	if err := decodeGuardrailParams(params, rule); err != nil {
+		return nil, err
+	}
+	var err error
+	if rule.start, err = parseClockMinutes(rule.Start); err != nil {
+		return nil, fmt.Errorf("start 格式错误: %w", err)
+	}
+	if rule.end, err = parseClockMinutes(rule.End); err != nil {
+		return nil, fmt.Errorf("end 格式错误: %w", err)
+	}
+	if rule.start == rule.end {
+		return nil, fmt.Errorf("start 和 end 不能相同")
+	}
+	rule.location = time.UTC
+	if rule.Timezone != "" {
+		if rule.location, err = time.LoadLocation(rule.Timezone); err != nil {
+			return nil, fmt.Errorf("时区无效: %s", rule.Timezone)
+		}
+	}
+	for _, day := range rule.Weekdays {
+		if day < 0 || day > 6 {
+			return nil, fmt.Errorf("weekdays 只能是0-6")
+		}
+	}
+	return rule, nil
+}
+
+// parseClockMinutes 解析 HH:MM 为一天中的分钟数
+func parseClockMinutes(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("应为 HH:MM: %q", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func (r *tradingHoursRule) Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult {
+	now := state.Now.In(r.location)
+	minutes := now.Hour()*60 + now.Minute()
+	var inWindow bool
+	if r.start < r.end {
+		inWindow = minutes >= r.start && minutes < r.end
+	} else {
+		inWindow = minutes >= r.start || minutes < r.end
+	}
+	if inWindow && len(r.Weekdays) > 0 {
+		// 跨零点时段凌晨部分属于前一天的交易时段
+		day := now.Weekday()
+		if r.start > r.end && minutes < r.end {
+			day = (day + 6) % 7
+		}
+		inWindow = false
+		for _, w := range r.Weekdays {
+			if time.Weekday(w) == day {
+				inWindow = true
+			}
+		}
+	}
+	if inWindow {
+		return GuardrailResult{Verdict: GuardrailPass}
+	}
+	return GuardrailResult{Verdict: GuardrailVeto, Reason: fmt.Sprintf("当前时间 %s 不在允许开仓的时段 %s-%s (%s)", now.Format("15:04"), r.Start, r.End, r.location)}
+}
+
+// fundingWindowRule 资金费结算前后 minutes 分钟内禁止开仓（交易所未提供结算时间时放行）
+type fundingWindowRule struct {
+	Minutes int `json:"minutes"`
+}
+
+func newFundingWindowRule(params json.RawMessage) (GuardrailRule, error) {
+	rule := &fundingWindowRule{}
+	if err := decodeGuardrailParams(params, rule); err != nil {
+		return nil, err
+	}
+	if rule.Minutes < 1 || rule.Minutes > 240 {
+		return nil, fmt.Errorf("minutes 必须在1-240之间")
+	}
+	return rule, nil
+}
+
+func (r *fundingWindowRule) Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult {
+	next, ok := state.NextFundings[d.Symbol]
+	if !ok {
+		return GuardrailResult{Verdict: GuardrailPass}
+	}
+	window := time.Duration(r.Minutes) * time.Minute
+	untilNext := next.Sub(state.Now)
+	if untilNext >= 0 && untilNext <= window {
+		return GuardrailResult{Verdict: GuardrailVeto, Reason: fmt.Sprintf("距离资金费结算还有 %d 分钟（%d 分钟内禁止开仓）", int(untilNext.Minutes()), r.Minutes)}
+	}
+	if untilNext < 0 && -untilNext <= window {
+		return GuardrailResult{Verdict: GuardrailVeto, Reason: fmt.Sprintf("资金费刚结算 %d 分钟（%d 分钟内禁止开仓）", int(-untilNext.Minutes()), r.Minutes)}
+	}
+	return GuardrailResult{Verdict: GuardrailPass}
+}
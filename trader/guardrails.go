@@ -0,0 +1,226 @@
+package trader
+
+import (
+	"encoding/json"
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 护栏规则的处理结果
+const (
+	GuardrailPass  = "pass"  // 放行
+	GuardrailClamp = "clamp" // 调整仓位/杠杆后放行
+	GuardrailVeto  = "veto"  // 拒绝执行
+
+	// GuardrailAllowOnFailure 检查本身超时或出错，按配置原样放行（订单未被调整）
+	GuardrailAllowOnFailure = "allow_on_failure"
+)
+
+// maxGuardrails 每个交易员最多配置的护栏规则数
+const maxGuardrails = 20
+
+// GuardrailConfig 护栏规则配置（按列表顺序依次执行，JSON 保存在交易员配置中）
+type GuardrailConfig struct {
+	Name   string          `json:"name,omitempty"`   // 规则名称（记录干预时使用，默认为规则类型）
+	Type   string          `json:"type"`             // 规则类型（max_positions/symbol_blacklist/max_leverage/...）
+	Params json.RawMessage `json:"params,omitempty"` // 规则参数
+}
+
+// GuardrailState 评估护栏时的账户状态（本周期内随开平仓更新）
+type GuardrailState struct {
+	Now          time.Time
+	Positions    map[string]bool      // 当前持仓（symbol_side）
+	NextFundings map[string]time.Time // 各币种下次资金费结算时间（未知时不存在）
+}
+
+// GuardrailResult 单条规则的评估结果
+type GuardrailResult struct {
+	Verdict string
+	Reason  string
+}
+
+// GuardrailRule 护栏规则：放行、调整（直接修改决策的仓位/杠杆）或拒绝一个开仓决策
+type GuardrailRule interface {
+	Evaluate(d *decision.Decision, state *GuardrailState) GuardrailResult
+}
+
+// GuardrailFactory 根据参数创建规则（参数无效时返回错误）
+type GuardrailFactory func(params json.RawMessage) (GuardrailRule, error)
+
+var (
+	guardrailFactoriesMu sync.RWMutex
+	guardrailFactories   = map[string]GuardrailFactory{}
+)
+
+// RegisterGuardrail 注册护栏规则类型（内置规则在包初始化时注册，扩展规则可在启动时注册）
+func RegisterGuardrail(ruleType string, factory GuardrailFactory) {
+	guardrailFactoriesMu.Lock()
+	defer guardrailFactoriesMu.Unlock()
+	guardrailFactories[ruleType] = factory
+}
+
+// GuardrailTypes 已注册的护栏规则类型
+func GuardrailTypes() []string {
+	guardrailFactoriesMu.RLock()
+	defer guardrailFactoriesMu.RUnlock()
+	types := make([]string, 0, len(guardrailFactories))
+	for t := range guardrailFactories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// namedGuardrail 带名称的规则
+type namedGuardrail struct {
+	name string
+	rule GuardrailRule
+}
+
+// GuardrailPipeline 按顺序执行的护栏规则列表
+type GuardrailPipeline struct {
+	rules []namedGuardrail
+}
+
+// ParseGuardrails 解析交易员保存的护栏配置 JSON（为空表示未配置）
+func ParseGuardrails(raw string) ([]GuardrailConfig, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var configs []GuardrailConfig
+	if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+		return nil, fmt.Errorf("护栏配置格式错误: %w", err)
+	}
+	return configs, nil
+}
+
+// NewGuardrailPipeline 根据配置创建护栏流水线（未知类型或参数无效时返回错误，错误信息包含规则序号）
+func NewGuardrailPipeline(configs []GuardrailConfig) (*GuardrailPipeline, error) {
+	if len(configs) > maxGuardrails {
+		return nil, fmt.Errorf("护栏规则最多 %d 条", maxGuardrails)
+	}
+	guardrailFactoriesMu.RLock()
+	defer guardrailFactoriesMu.RUnlock()
+
+	pipeline := &GuardrailPipeline{}
+	for i, cfg := range configs {
+		factory, ok := guardrailFactories[cfg.Type]
+		if !ok {
+			return nil, fmt.Errorf("第%d条护栏规则类型未知: %q", i+1, cfg.Type)
+		}
+		rule, err := factory(cfg.Params)
+		if err != nil {
+			return nil, fmt.Errorf("第%d条护栏规则 %s 参数无效: %w", i+1, cfg.Type, err)
+		}
+		name := cfg.Name
+		if name == "" {
+			name = cfg.Type
+		}
+		pipeline.rules = append(pipeline.rules, namedGuardrail{name: name, rule: rule})
+	}
+	return pipeline, nil
+}
+
+// Len 规则数量
+func (p *GuardrailPipeline) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.rules)
+}
+
+// Evaluate 依次执行规则，返回所有干预记录；某条规则拒绝时停止并返回 vetoed=true。
+// 只约束开仓决策，平仓和止盈止损调整总是放行（不阻止降低风险的操作）
+func (p *GuardrailPipeline) Evaluate(d *decision.Decision, state *GuardrailState) (interventions []logger.GuardrailIntervention, vetoed bool) {
+	if p == nil || !isOpenAction(d.Action) {
+		return nil, false
+	}
+	for _, r := range p.rules {
+		result := r.rule.Evaluate(d, state)
+		if result.Verdict == GuardrailPass || result.Verdict == "" {
+			continue
+		}
+		interventions = append(interventions, logger.GuardrailIntervention{
+			Rule:    r.name,
+			Symbol:  d.Symbol,
+			Action:  d.Action,
+			Verdict: result.Verdict,
+			Reason:  result.Reason,
+		})
+		if result.Verdict == GuardrailVeto {
+			return interventions, true
+		}
+	}
+	return interventions, false
+}
+
+// isOpenAction 是否为开仓决策
+func isOpenAction(action string) bool {
+	return action == "open_long" || action == "open_short"
+}
+
+// positionSide 开仓决策对应的持仓方向
+func positionSide(action string) string {
+	if action == "open_short" {
+		return "short"
+	}
+	return "long"
+}
+
+// SetGuardrails 设置护栏规则（下一个开仓决策开始生效），配置无效时保持原规则并返回错误
+func (at *AutoTrader) SetGuardrails(configs []GuardrailConfig) error {
+	pipeline, err := NewGuardrailPipeline(configs)
+	if err != nil {
+		return err
+	}
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.guardrails = pipeline
+	at.config.Guardrails = configs
+	return nil
+}
+
+// getGuardrails 当前的护栏流水线（未配置时为 nil）
+func (at *AutoTrader) getGuardrails() *GuardrailPipeline {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.guardrails
+}
+
+// newGuardrailState 根据本周期上下文构建护栏状态
+func newGuardrailState(ctx *decision.Context, now time.Time) *GuardrailState {
+	state := &GuardrailState{
+		Now:          now,
+		Positions:    make(map[string]bool),
+		NextFundings: make(map[string]time.Time),
+	}
+	if ctx == nil {
+		return state
+	}
+	for _, pos := range ctx.Positions {
+		state.Positions[pos.Symbol+"_"+pos.Side] = true
+	}
+	for symbol, info := range ctx.FundingRates {
+		if info != nil && info.NextFundingTime > 0 {
+			state.NextFundings[symbol] = time.UnixMilli(info.NextFundingTime)
+		}
+	}
+	return state
+}
+
+// updateGuardrailState 决策执行成功后更新持仓状态（供本周期后续决策的规则使用）
+func updateGuardrailState(state *GuardrailState, d *decision.Decision) {
+	switch d.Action {
+	case "open_long", "open_short":
+		state.Positions[d.Symbol+"_"+positionSide(d.Action)] = true
+	case "close_long":
+		delete(state.Positions, d.Symbol+"_long")
+	case "close_short":
+		delete(state.Positions, d.Symbol+"_short")
+	}
+}
@@ -0,0 +1,177 @@
+package trader
+
+import (
+	"encoding/json"
+	"nofx/decision"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mustGuardrailPipeline 由 JSON 配置创建护栏流水线
+func mustGuardrailPipeline(t *testing.T, raw string) *GuardrailPipeline {
+	t.Helper()
+	configs, err := ParseGuardrails(raw)
+	require.NoError(t, err)
+	pipeline, err := NewGuardrailPipeline(configs)
+	require.NoError(t, err)
+	return pipeline
+}
+
+// newTestGuardrailState 构造护栏状态（持仓为 symbol_side）
+func newTestGuardrailState(now time.Time, positions ...string) *GuardrailState {
+	state := &GuardrailState{Now: now, Positions: map[string]bool{}, NextFundings: map[string]time.Time{}}
+	for _, p := range positions {
+		state.Positions[p] = true
+	}
+	return state
+}
+
+// TestGuardrailPipeline_OrderAndVeto 测试规则按顺序执行：调整会累积，拒绝后停止执行后续规则
+func TestGuardrailPipeline_OrderAndVeto(t *testing.T) {
+	pipeline := mustGuardrailPipeline(t, `[
+		{"type": "max_leverage", "params": {"max": 5}},
+		{"name": "never_short_btc", "type": "symbol_blacklist", "params": {"symbols": ["BTCUSDT"], "sides": ["short"]}},
+		{"type": "max_position_size", "params": {"max_usd": 100}}
+	]`)
+	require.Equal(t, 3, pipeline.Len())
+	state := newTestGuardrailState(time.Now())
+
+	// 做多BTC：杠杆和仓位都被调整
+	d := decision.Decision{Symbol: "BTCUSDT", Action: "open_long", Leverage: 20, PositionSizeUSD: 500}
+	interventions, vetoed := pipeline.Evaluate(&d, state)
+	assert.False(t, vetoed)
+	require.Len(t, interventions, 2)
+	assert.Equal(t, "max_leverage", interventions[0].Rule)
+	assert.Equal(t, GuardrailClamp, interventions[0].Verdict)
+	assert.Equal(t, "max_position_size", interventions[1].Rule)
+	assert.Equal(t, 5, d.Leverage)
+	assert.Equal(t, 100.0, d.PositionSizeUSD)
+
+	// 做空BTC：被拒绝，之后的仓位规则不再执行
+	d = decision.Decision{Symbol: "BTCUSDT", Action: "open_short", Leverage: 3, PositionSizeUSD: 500}
+	interventions, vetoed = pipeline.Evaluate(&d, state)
+	assert.True(t, vetoed)
+	require.Len(t, interventions, 1)
+	assert.Equal(t, "never_short_btc", interventions[0].Rule)
+	assert.Equal(t, GuardrailVeto, interventions[0].Verdict)
+	assert.Equal(t, 500.0, d.PositionSizeUSD)
+
+	// 平仓不受护栏约束
+	d = decision.Decision{Symbol: "BTCUSDT", Action: "close_short"}
+	interventions, vetoed = pipeline.Evaluate(&d, state)
+	assert.False(t, vetoed)
+	assert.Empty(t, interventions)
+
+	// 未配置护栏时全部放行
+	var empty *GuardrailPipeline
+	interventions, vetoed = empty.Evaluate(&decision.Decision{Symbol: "BTCUSDT", Action: "open_short"}, state)
+	assert.False(t, vetoed)
+	assert.Empty(t, interventions)
+}
+
+// TestNewGuardrailPipeline_InvalidConfig 测试未知类型、无效参数和未知参数被拒绝
+func TestNewGuardrailPipeline_InvalidConfig(t *testing.T) {
+	cases := map[string]GuardrailConfig{
+		"未知类型":   {Type: "no_such_rule"},
+		"缺少参数":   {Type: GuardrailMaxPositions},
+		"未知参数":   {Type: GuardrailMaxPositions, Params: json.RawMessage(`{"maximum": 3}`)},
+		"杠杆超出范围": {Type: GuardrailMaxLeverage, Params: json.RawMessage(`{"max": 200}`)},
+		"无效方向":   {Type: GuardrailSymbolBlacklist, Params: json.RawMessage(`{"symbols": ["BTCUSDT"], "sides": ["up"]}`)},
+		"无效时区":   {Type: GuardrailTradingHours, Params: json.RawMessage(`{"start": "09:00", "end": "17:00", "timezone": "Mars/Base"}`)},
+		"资金费窗口":  {Type: GuardrailFundingWindow, Params: json.RawMessage(`{"minutes": 0}`)},
+	}
+	for name, cfg := range cases {
+		_, err := NewGuardrailPipeline([]GuardrailConfig{cfg})
+		assert.Error(t, err, name)
+	}
+
+	_, err := ParseGuardrails(`{"type": "max_positions"}`)
+	assert.Error(t, err, "配置必须是数组")
+	configs, err := ParseGuardrails("")
+	assert.NoError(t, err)
+	assert.Nil(t, configs)
+}
+
+// TestMaxPositionsRule 测试同时持仓数上限（已有方向加仓不计为新持仓，本周期开仓后计入）
+func TestMaxPositionsRule(t *testing.T) {
+	pipeline := mustGuardrailPipeline(t, `[{"type": "max_positions", "params": {"max": 2}}]`)
+	state := newTestGuardrailState(time.Now(), "BTCUSDT_long")
+
+	open := decision.Decision{Symbol: "ETHUSDT", Action: "open_long"}
+	_, vetoed := pipeline.Evaluate(&open, state)
+	assert.False(t, vetoed)
+	updateGuardrailState(state, &open)
+
+	_, vetoed = pipeline.Evaluate(&decision.Decision{Symbol: "SOLUSDT", Action: "open_short"}, state)
+	assert.True(t, vetoed, "已有2个持仓")
+	_, vetoed = pipeline.Evaluate(&decision.Decision{Symbol: "BTCUSDT", Action: "open_long"}, state)
+	assert.False(t, vetoed, "已有方向加仓")
+
+	updateGuardrailState(state, &decision.Decision{Symbol: "BTCUSDT", Action: "close_long"})
+	_, vetoed = pipeline.Evaluate(&decision.Decision{Symbol: "SOLUSDT", Action: "open_short"}, state)
+	assert.False(t, vetoed, "平仓后有空位")
+}
+
+// TestTradingHoursRule 测试交易时段（含跨零点时段、时区和星期限制）
+func TestTradingHoursRule(t *testing.T) {
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long"}
+	at := func(s string) *GuardrailState {
+		now, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return newTestGuardrailState(now)
+	}
+
+	daytime := mustGuardrailPipeline(t, `[{"type": "trading_hours", "params": {"start": "09:00", "end": "17:00", "timezone": "Asia/Shanghai", "weekdays": [1,2,3,4,5]}}]`)
+	_, vetoed := daytime.Evaluate(d, at("2026-10-14T02:00:00Z")) // 周三 10:00 北京时间
+	assert.False(t, vetoed)
+	_, vetoed = daytime.Evaluate(d, at("2026-10-14T10:00:00Z")) // 周三 18:00 北京时间
+	assert.True(t, vetoed)
+	_, vetoed = daytime.Evaluate(d, at("2026-10-17T02:00:00Z")) // 周六 10:00 北京时间
+	assert.True(t, vetoed)
+
+	overnight := mustGuardrailPipeline(t, `[{"type": "trading_hours", "params": {"start": "22:00", "end": "02:00", "weekdays": [5]}}]`)
+	_, vetoed = overnight.Evaluate(d, at("2026-10-16T23:00:00Z")) // 周五 23:00
+	assert.False(t, vetoed)
+	_, vetoed = overnight.Evaluate(d, at("2026-10-17T01:00:00Z")) // 周六 01:00，属于周五的时段
+	assert.False(t, vetoed)
+	_, vetoed = overnight.Evaluate(d, at("2026-10-17T03:00:00Z"))
+	assert.True(t, vetoed)
+}
+
+// TestFundingWindowRule 测试资金费结算前后禁止开仓（未知结算时间时放行）
+func TestFundingWindowRule(t *testing.T) {
+	pipeline := mustGuardrailPipeline(t, `[{"type": "funding_window", "params": {"minutes": 10}}]`)
+	now := time.Date(2026, 10, 16, 7, 55, 0, 0, time.UTC)
+	state := newTestGuardrailState(now)
+	state.NextFundings["BTCUSDT"] = time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	state.NextFundings["ETHUSDT"] = time.Date(2026, 10, 16, 7, 50, 0, 0, time.UTC)
+	state.NextFundings["SOLUSDT"] = time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	for symbol, want := range map[string]bool{"BTCUSDT": true, "ETHUSDT": true, "SOLUSDT": false, "XRPUSDT": false} {
+		interventions, vetoed := pipeline.Evaluate(&decision.Decision{Symbol: symbol, Action: "open_long"}, state)
+		assert.Equal(t, want, vetoed, symbol)
+		if want {
+			assert.Equal(t, GuardrailFundingWindow, interventions[0].Rule)
+		}
+	}
+}
+
+// TestNewGuardrailState 测试由决策上下文构建持仓和资金费结算时间
+func TestNewGuardrailState(t *testing.T) {
+	next := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	ctx := &decision.Context{
+		Positions: []decision.PositionInfo{{Symbol: "BTCUSDT", Side: "short"}},
+		FundingRates: map[string]*decision.FundingInfo{
+			"BTCUSDT": {NextFundingTime: next.UnixMilli()},
+			"ETHUSDT": {},
+		},
+	}
+	state := newGuardrailState(ctx, time.Now())
+	assert.True(t, state.Positions["BTCUSDT_short"])
+	assert.True(t, state.NextFundings["BTCUSDT"].Equal(next))
+	_, ok := state.NextFundings["ETHUSDT"]
+	assert.False(t, ok)
+}
@@ -0,0 +1,98 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+
+	"nofx/decision"
+	"nofx/hook"
+	"nofx/logger"
+)
+
+// lifecycleHookInput 交易员启动/停止扩展点的输入
+func (at *AutoTrader) lifecycleHookInput() hook.TraderLifecycleInput {
+	return hook.TraderLifecycleInput{
+		TraderID: at.id,
+		UserID:   at.userID,
+		Name:     at.name,
+		Exchange: at.exchange,
+		AIModel:  at.aiModel,
+	}
+}
+
+// runStartHook 执行 ON_TRADER_START 扩展点，Abort 策略的处理函数出错时不启动
+func (at *AutoTrader) runStartHook() error {
+	if !hook.OnTraderStart.Registered() {
+		return nil
+	}
+	var out hook.TraderLifecycleOutput
+	if err := hook.OnTraderStart.Run(context.Background(), at.lifecycleHookInput(), &out); err != nil {
+		return fmt.Errorf("启动扩展点拒绝启动: %w", err)
+	}
+	return nil
+}
+
+// runStopHook 执行 ON_TRADER_STOP 扩展点（出错退出时带上错误信息）
+func (at *AutoTrader) runStopHook(runErr error) {
+	if !hook.OnTraderStop.Registered() {
+		return
+	}
+	in := at.lifecycleHookInput()
+	if runErr != nil {
+		in.Error = runErr.Error()
+	}
+	var out hook.TraderLifecycleOutput
+	hook.OnTraderStop.Run(context.Background(), in, &out)
+}
+
+// runPreDecisionHook 执行 PRE_DECISION 扩展点，返回是否跳过本周期及原因
+func (at *AutoTrader) runPreDecisionHook(ctx *decision.Context, manual bool) (bool, string) {
+	if !hook.PreDecision.Registered() {
+		return false, ""
+	}
+	in := hook.PreDecisionInput{
+		TraderID:      at.id,
+		UserID:        at.userID,
+		CycleNumber:   at.callCount,
+		Manual:        manual,
+		Equity:        ctx.Account.TotalEquity,
+		PositionCount: ctx.Account.PositionCount,
+	}
+	for _, coin := range ctx.CandidateCoins {
+		in.CandidateSymbols = append(in.CandidateSymbols, coin.Symbol)
+	}
+	var out hook.PreDecisionOutput
+	if err := hook.PreDecision.Run(context.Background(), in, &out); err != nil {
+		return true, err.Error()
+	}
+	if out.Skip && out.Reason == "" {
+		out.Reason = "扩展点要求跳过"
+	}
+	return out.Skip, out.Reason
+}
+
+// runPostDecisionHook 执行 POST_DECISION 扩展点（只用于观察，错误不影响交易）
+func (at *AutoTrader) runPostDecisionHook(record *logger.DecisionRecord) {
+	if !hook.PostDecision.Registered() {
+		return
+	}
+	in := hook.PostDecisionInput{
+		TraderID:    at.id,
+		UserID:      at.userID,
+		CycleNumber: at.callCount,
+		Success:     record.Success,
+		Error:       record.ErrorMessage,
+	}
+	for _, action := range record.Decisions {
+		in.Actions = append(in.Actions, hook.ActionResult{
+			Action:   action.Action,
+			Symbol:   action.Symbol,
+			Quantity: action.Quantity,
+			Price:    action.Price,
+			Success:  action.Success,
+			Error:    action.Error,
+		})
+	}
+	var out hook.PostDecisionOutput
+	hook.PostDecision.Run(context.Background(), in, &out)
+}
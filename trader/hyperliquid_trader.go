@@ -1,14 +1,20 @@
 package trader
 
 import (
+	"bytes"
 	"context"
 	"crypto/ecdsa"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"math"
+	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/sonirico/go-hyperliquid"
@@ -22,6 +28,7 @@ type HyperliquidTrader struct {
 	meta          *hyperliquid.Meta // 缓存meta信息（包含精度等）
 	metaMutex     sync.RWMutex      // 保护meta字段的并发访问
 	isCrossMargin bool              // 是否为全仓模式
+	infoURL       string            // Info API 地址（SDK 未封装的查询直接请求）
 }
 
 // NewHyperliquidTrader 创建Hyperliquid交易器
@@ -126,11 +133,21 @@ func NewHyperliquidTrader(privateKeyHex string, walletAddr string, testnet bool)
 		walletAddr:    walletAddr,
 		meta:          meta,
 		isCrossMargin: true, // 默认使用全仓模式
+		infoURL:       apiURL + "/info",
 	}, nil
 }
 
-// GetBalance 获取账户余额
+// GetBalance 获取账户余额（旧版 map 结果，由 GetBalanceInfo 转换）
 func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
+	balance, err := t.GetBalanceInfo()
+	if err != nil {
+		return nil, err
+	}
+	return balance.ToMap(), nil
+}
+
+// GetBalanceInfo 获取账户余额
+func (t *HyperliquidTrader) GetBalanceInfo() (*Balance, error) {
 	log.Printf("🔄 正在调用Hyperliquid API获取账户余额...")
 
 	// ✅ Step 1: 查询 Spot 现货账户余额
@@ -155,9 +172,6 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 		return nil, fmt.Errorf("获取账户信息失败: %w", err)
 	}
 
-	// 解析余额信息（MarginSummary字段都是string）
-	result := make(map[string]interface{})
-
 	// ✅ Step 3: 根据保证金模式动态选择正确的摘要（CrossMarginSummary 或 MarginSummary）
 	var accountValue, totalMarginUsed float64
 	var summaryType string
@@ -222,10 +236,12 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	//      原因：Spot 和 Perpetuals 是独立帐户，需手动 ClassTransfer 才能转账
 	totalWalletBalance := walletBalanceWithoutUnrealized + spotUSDCBalance
 
-	result["totalWalletBalance"] = totalWalletBalance    // 总资产（Perp + Spot）
-	result["availableBalance"] = availableBalance        // 可用余额（仅 Perpetuals，不含 Spot）
-	result["totalUnrealizedProfit"] = totalUnrealizedPnl // 未实现盈亏（仅来自 Perpetuals）
-	result["spotBalance"] = spotUSDCBalance              // Spot 现货余额（单独返回）
+	result := &Balance{
+		WalletBalance:    totalWalletBalance, // 总资产（Perp + Spot）
+		AvailableBalance: availableBalance,   // 可用余额（仅 Perpetuals，不含 Spot）
+		UnrealizedPnL:    totalUnrealizedPnl, // 未实现盈亏（仅来自 Perpetuals）
+		SpotBalance:      spotUSDCBalance,    // Spot 现货余额（单独返回）
+	}
 
 	log.Printf("✓ Hyperliquid 完整账户:")
 	log.Printf("  • Spot 现货余额: %.2f USDC （需手动转账到 Perpetuals 才能开仓）", spotUSDCBalance)
@@ -242,15 +258,24 @@ func (t *HyperliquidTrader) GetBalance() (map[string]interface{}, error) {
 	return result, nil
 }
 
-// GetPositions 获取所有持仓
+// GetPositions 获取所有持仓（旧版 map 结果，由 GetPositionList 转换）
 func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
+	positions, err := t.GetPositionList()
+	if err != nil {
+		return nil, err
+	}
+	return positionsToMaps(positions), nil
+}
+
+// GetPositionList 获取所有持仓
+func (t *HyperliquidTrader) GetPositionList() ([]Position, error) {
 	// 获取账户状态
 	accountState, err := t.exchange.Info().UserState(t.ctx, t.walletAddr)
 	if err != nil {
 		return nil, fmt.Errorf("获取持仓失败: %w", err)
 	}
 
-	var result []map[string]interface{}
+	var result []Position
 
 	// 遍历所有持仓
 	for _, assetPos := range accountState.AssetPositions {
@@ -263,19 +288,16 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			continue // 跳过无持仓的
 		}
 
-		posMap := make(map[string]interface{})
-
 		// 标准化symbol格式（Hyperliquid使用如"BTC"，我们转换为"BTCUSDT"）
-		symbol := position.Coin + "USDT"
-		posMap["symbol"] = symbol
+		pos := Position{Symbol: position.Coin + "USDT"}
 
 		// 持仓数量和方向
 		if posAmt > 0 {
-			posMap["side"] = "long"
-			posMap["positionAmt"] = posAmt
+			pos.Side = "long"
+			pos.Quantity = posAmt
 		} else {
-			posMap["side"] = "short"
-			posMap["positionAmt"] = -posAmt // 转为正数
+			pos.Side = "short"
+			pos.Quantity = -posAmt // 转为正数
 		}
 
 		// 价格信息（EntryPx和LiquidationPx是指针类型）
@@ -296,13 +318,13 @@ func (t *HyperliquidTrader) GetPositions() ([]map[string]interface{}, error) {
 			markPrice = positionValue / absFloat(posAmt)
 		}
 
-		posMap["entryPrice"] = entryPrice
-		posMap["markPrice"] = markPrice
-		posMap["unRealizedProfit"] = unrealizedPnl
-		posMap["leverage"] = float64(position.Leverage.Value)
-		posMap["liquidationPrice"] = liquidationPx
+		pos.EntryPrice = entryPrice
+		pos.MarkPrice = markPrice
+		pos.UnrealizedPnL = unrealizedPnl
+		pos.Leverage = float64(position.Leverage.Value)
+		pos.LiquidationPrice = liquidationPx
 
-		result = append(result, posMap)
+		result = append(result, pos)
 	}
 
 	return result, nil
@@ -336,6 +358,39 @@ func (t *HyperliquidTrader) SetLeverage(symbol string, leverage int) error {
 	return nil
 }
 
+// hyperliquidMinNotional Hyperliquid 最小下单金额（USDC）
+const hyperliquidMinNotional = 10.0
+
+// HedgeMode Hyperliquid 每个币种只有一个净持仓，反向开仓会抵消现有持仓
+func (t *HyperliquidTrader) HedgeMode() bool {
+	return false
+}
+
+// GetSymbolFilters 根据缓存的 Meta 信息生成交易对规则（币种统一为 XXXUSDT 格式）
+func (t *HyperliquidTrader) GetSymbolFilters() (map[string]SymbolFilter, error) {
+	t.metaMutex.RLock()
+	defer t.metaMutex.RUnlock()
+	if t.meta == nil {
+		return nil, fmt.Errorf("Meta 信息尚未加载")
+	}
+
+	filters := make(map[string]SymbolFilter, len(t.meta.Universe))
+	for _, asset := range t.meta.Universe {
+		symbol := asset.Name + "USDT"
+		status := SymbolStatusTrading
+		if asset.IsDelisted {
+			status = "DELISTED"
+		}
+		filters[symbol] = SymbolFilter{
+			Symbol:      symbol,
+			Status:      status,
+			StepSize:    math.Pow10(-asset.SzDecimals),
+			MinNotional: hyperliquidMinNotional,
+		}
+	}
+	return filters, nil
+}
+
 // refreshMetaIfNeeded 当 Meta 信息失效时刷新（Asset ID 为 0 时触发）
 func (t *HyperliquidTrader) refreshMetaIfNeeded(coin string) error {
 	assetID := t.exchange.Info().NameToAsset(coin)
@@ -731,76 +786,87 @@ func (t *HyperliquidTrader) GetMarketPrice(symbol string) (float64, error) {
 
 // SetStopLoss 设置止损单
 func (t *HyperliquidTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
-	coin := convertSymbolToHyperliquid(symbol)
-
-	isBuy := positionSide == "SHORT" // 空仓止损=买入，多仓止损=卖出
-
-	// ⚠️ 关键：根据币种精度要求，四舍五入数量
-	roundedQuantity := t.roundToSzDecimals(coin, quantity)
-
-	// ⚠️ 关键：价格也需要处理为5位有效数字
-	roundedStopPrice := t.roundPriceToSigfigs(stopPrice)
+	if _, err := t.placeTriggerOrder(symbol, positionSide, "sl", quantity, stopPrice); err != nil {
+		return fmt.Errorf("设置止损失败: %w", err)
+	}
+	return nil
+}
 
-	// 创建止损单（Trigger Order）
-	order := hyperliquid.CreateOrderRequest{
-		Coin:  coin,
-		IsBuy: isBuy,
-		Size:  roundedQuantity,  // 使用四舍五入后的数量
-		Price: roundedStopPrice, // 使用处理后的价格
-		OrderType: hyperliquid.OrderType{
-			Trigger: &hyperliquid.TriggerOrderType{
-				TriggerPx: roundedStopPrice,
-				IsMarket:  true,
-				Tpsl:      "sl", // stop loss
-			},
-		},
-		ReduceOnly: true,
+// SetTakeProfit 设置止盈单
+func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	if _, err := t.placeTriggerOrder(symbol, positionSide, "tp", quantity, takeProfitPrice); err != nil {
+		return fmt.Errorf("设置止盈失败: %w", err)
 	}
+	return nil
+}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+// PlaceProtectiveOrder 下止损/止盈触发单（Trigger Order，只减仓），返回订单ID
+func (t *HyperliquidTrader) PlaceProtectiveOrder(symbol, positionSide, kind string, quantity, triggerPrice float64) (string, error) {
+	tpsl := "sl"
+	if kind == ProtectiveTakeProfit {
+		tpsl = "tp"
+	}
+	oid, err := t.placeTriggerOrder(symbol, positionSide, tpsl, quantity, triggerPrice)
 	if err != nil {
-		return fmt.Errorf("设置止损失败: %w", err)
+		return "", fmt.Errorf("设置%s失败: %w", protectiveKindName(kind), err)
 	}
+	return strconv.FormatInt(oid, 10), nil
+}
 
-	log.Printf("  止损价设置: %.4f", roundedStopPrice)
+// CancelOrder 按订单ID撤单
+func (t *HyperliquidTrader) CancelOrder(symbol, orderID string) error {
+	oid, err := strconv.ParseInt(orderID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("无效的订单ID: %s", orderID)
+	}
+	if _, err := t.exchange.Cancel(t.ctx, convertSymbolToHyperliquid(symbol), oid); err != nil {
+		return fmt.Errorf("取消订单失败: %w", err)
+	}
 	return nil
 }
 
-// SetTakeProfit 设置止盈单
-func (t *HyperliquidTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+// placeTriggerOrder 下触发市价单（tpsl: "sl" 止损 / "tp" 止盈），返回订单ID（未挂单时为0）
+func (t *HyperliquidTrader) placeTriggerOrder(symbol, positionSide, tpsl string, quantity, triggerPrice float64) (int64, error) {
 	coin := convertSymbolToHyperliquid(symbol)
 
-	isBuy := positionSide == "SHORT" // 空仓止盈=买入，多仓止盈=卖出
+	isBuy := positionSide == "SHORT" // 空仓止盈止损=买入，多仓止盈止损=卖出
 
 	// ⚠️ 关键：根据币种精度要求，四舍五入数量
 	roundedQuantity := t.roundToSzDecimals(coin, quantity)
 
 	// ⚠️ 关键：价格也需要处理为5位有效数字
-	roundedTakeProfitPrice := t.roundPriceToSigfigs(takeProfitPrice)
+	roundedTriggerPrice := t.roundPriceToSigfigs(triggerPrice)
 
-	// 创建止盈单（Trigger Order）
+	// 创建触发单（Trigger Order）
 	order := hyperliquid.CreateOrderRequest{
 		Coin:  coin,
 		IsBuy: isBuy,
-		Size:  roundedQuantity,        // 使用四舍五入后的数量
-		Price: roundedTakeProfitPrice, // 使用处理后的价格
+		Size:  roundedQuantity,     // 使用四舍五入后的数量
+		Price: roundedTriggerPrice, // 使用处理后的价格
 		OrderType: hyperliquid.OrderType{
 			Trigger: &hyperliquid.TriggerOrderType{
-				TriggerPx: roundedTakeProfitPrice,
+				TriggerPx: roundedTriggerPrice,
 				IsMarket:  true,
-				Tpsl:      "tp", // take profit
+				Tpsl:      hyperliquid.Tpsl(tpsl),
 			},
 		},
 		ReduceOnly: true,
 	}
 
-	_, err := t.exchange.Order(t.ctx, order, nil)
+	status, err := t.exchange.Order(t.ctx, order, nil)
 	if err != nil {
-		return fmt.Errorf("设置止盈失败: %w", err)
+		return 0, err
 	}
 
-	log.Printf("  止盈价设置: %.4f", roundedTakeProfitPrice)
-	return nil
+	if tpsl == "tp" {
+		log.Printf("  止盈价设置: %.4f", roundedTriggerPrice)
+	} else {
+		log.Printf("  止损价设置: %.4f", roundedTriggerPrice)
+	}
+	if status.Resting != nil {
+		return status.Resting.Oid, nil
+	}
+	return 0, nil
 }
 
 // FormatQuantity 格式化数量到正确的精度
@@ -887,6 +953,81 @@ func (t *HyperliquidTrader) roundPriceToSigfigs(price float64) float64 {
 	return rounded
 }
 
+// GetAllTradeHistory 获取所有币种的成交历史（userFillsByTime，单次最多返回2000条）
+func (t *HyperliquidTrader) GetAllTradeHistory(lookbackDays int) (map[string][]*Trade, error) {
+	startTime := time.Now().AddDate(0, 0, -lookbackDays).UnixMilli()
+	fills, err := t.exchange.Info().UserFillsByTime(t.ctx, t.walletAddr, startTime, nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取成交历史失败: %w", err)
+	}
+	return buildHyperliquidTradeHistory(fills), nil
+}
+
+// buildHyperliquidTradeHistory 将Hyperliquid成交记录转换为按币种分组、按时间升序的交易历史
+// dir 字段标明开平方向（Open Long / Close Short 等），反手成交（Long > Short）拆分为平仓+开仓两笔
+// closedPnl 不含手续费，与币安 realizedPnl 语义一致，手续费单独记入 Commission
+func buildHyperliquidTradeHistory(fills []hyperliquid.Fill) map[string][]*Trade {
+	result := make(map[string][]*Trade)
+	for _, fill := range fills {
+		price, _ := strconv.ParseFloat(fill.Price, 64)
+		qty, _ := strconv.ParseFloat(fill.Size, 64)
+		closedPnl, _ := strconv.ParseFloat(fill.ClosedPnl, 64)
+		fee, _ := strconv.ParseFloat(fill.Fee, 64)
+		startPosition, _ := strconv.ParseFloat(fill.StartPosition, 64)
+		if qty <= 0 {
+			continue
+		}
+
+		side := "SELL"
+		if fill.Side == "B" {
+			side = "BUY"
+		}
+		symbol := fill.Coin + "USDT"
+		newTrade := func(positionSide string, tradeQty, pnl float64) *Trade {
+			return &Trade{
+				Symbol:          symbol,
+				Side:            side,
+				PositionSide:    positionSide,
+				Price:           price,
+				Qty:             tradeQty,
+				RealizedPnl:     pnl,
+				Commission:      fee * tradeQty / qty,
+				CommissionAsset: fill.FeeToken,
+				Time:            fill.Time,
+				Buyer:           side == "BUY",
+			}
+		}
+
+		var trades []*Trade
+		switch {
+		case fill.Dir == "Long > Short", fill.Dir == "Short > Long":
+			closeSide, openSide := "LONG", "SHORT"
+			if fill.Dir == "Short > Long" {
+				closeSide, openSide = "SHORT", "LONG"
+			}
+			closeQty := math.Min(math.Abs(startPosition), qty)
+			if closeQty > 0 {
+				trades = append(trades, newTrade(closeSide, closeQty, closedPnl))
+			}
+			if qty-closeQty > 0 {
+				trades = append(trades, newTrade(openSide, qty-closeQty, 0))
+			}
+		case strings.Contains(fill.Dir, "Long"):
+			trades = append(trades, newTrade("LONG", qty, closedPnl))
+		case strings.Contains(fill.Dir, "Short"):
+			trades = append(trades, newTrade("SHORT", qty, closedPnl))
+		default:
+			continue // 现货等非永续成交
+		}
+		result[symbol] = append(result[symbol], trades...)
+	}
+
+	for _, trades := range result {
+		sort.SliceStable(trades, func(i, j int) bool { return trades[i].Time < trades[j].Time })
+	}
+	return result
+}
+
 // convertSymbolToHyperliquid 将标准symbol转换为Hyperliquid格式
 // 例如: "BTCUSDT" -> "BTC"
 func convertSymbolToHyperliquid(symbol string) string {
@@ -904,3 +1045,83 @@ func absFloat(x float64) float64 {
 	}
 	return x
 }
+
+// hyperliquidFundingInterval Hyperliquid 资金费每小时结算
+const hyperliquidFundingInterval = 1
+
+// GetFundingRates 获取资金费率（metaAndAssetCtxs 中的 funding 为当前小时费率）
+func (t *HyperliquidTrader) GetFundingRates(symbols []string) (map[string]FundingRate, error) {
+	metaAndCtxs, err := t.exchange.Info().MetaAndAssetCtxs(t.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费率失败: %w", err)
+	}
+
+	nextFundingTime := time.Now().Truncate(time.Hour).Add(time.Hour)
+	rates := make(map[string]FundingRate, len(metaAndCtxs.Universe))
+	for i, asset := range metaAndCtxs.Universe {
+		if i >= len(metaAndCtxs.Ctxs) {
+			break
+		}
+		rate, err := strconv.ParseFloat(metaAndCtxs.Ctxs[i].Funding, 64)
+		if err != nil {
+			continue
+		}
+		symbol := asset.Name + "USDT"
+		rates[symbol] = FundingRate{
+			Symbol:          symbol,
+			Rate:            rate,
+			IntervalHours:   hyperliquidFundingInterval,
+			NextFundingTime: nextFundingTime,
+		}
+	}
+	return filterFundingRates(rates, symbols), nil
+}
+
+// GetFundingFees 获取资金费收支（userFunding，SDK 的返回结构缺少金额字段，直接请求 Info API）
+func (t *HyperliquidTrader) GetFundingFees(startTime time.Time) ([]FundingFee, error) {
+	if t.infoURL == "" {
+		return nil, fmt.Errorf("未配置 Hyperliquid Info API 地址")
+	}
+	payload, err := json.Marshal(map[string]interface{}{
+		"type":      "userFunding",
+		"user":      t.walletAddr,
+		"startTime": startTime.UnixMilli(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Post(t.infoURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("获取资金费流水失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取资金费流水失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取资金费流水失败: HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	var records []struct {
+		Time  int64 `json:"time"`
+		Delta struct {
+			Coin string `json:"coin"`
+			USDC string `json:"usdc"`
+		} `json:"delta"`
+	}
+	if err := json.Unmarshal(body, &records); err != nil {
+		return nil, fmt.Errorf("解析资金费流水失败: %w", err)
+	}
+
+	fees := make([]FundingFee, 0, len(records))
+	for _, record := range records {
+		amount, err := strconv.ParseFloat(record.Delta.USDC, 64)
+		if err != nil {
+			continue
+		}
+		fees = append(fees, FundingFee{Symbol: record.Delta.Coin + "USDT", Amount: amount, Time: record.Time})
+	}
+	return sortFundingFees(fees), nil
+}
@@ -0,0 +1,136 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// closeResult 单个币种最近一次平仓的结果
+type closeResult struct {
+	pnl      float64
+	closedAt time.Time
+}
+
+// SetLossCooldown 修改亏损平仓后同币种的冷却时间（分钟，<=0 表示关闭），立即生效
+func (at *AutoTrader) SetLossCooldown(minutes int) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.config.CooldownMinutesAfterLoss = minutes
+}
+
+// getLossCooldown 读取亏损冷却时间
+func (at *AutoTrader) getLossCooldown() time.Duration {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	if at.config.CooldownMinutesAfterLoss <= 0 {
+		return 0
+	}
+	return time.Duration(at.config.CooldownMinutesAfterLoss) * time.Minute
+}
+
+// recordCloseResult 记录币种的平仓结果（只保留最近一次，盈利平仓会结束之前的冷却）
+func (at *AutoTrader) recordCloseResult(symbol string, pnl float64, closedAt time.Time) {
+	at.cooldownMu.Lock()
+	if at.lastCloses == nil {
+		at.lastCloses = make(map[string]closeResult)
+	}
+	if last, ok := at.lastCloses[symbol]; ok && closedAt.Before(last.closedAt) {
+		at.cooldownMu.Unlock()
+		return
+	}
+	at.lastCloses[symbol] = closeResult{pnl: pnl, closedAt: closedAt}
+	at.cooldownMu.Unlock()
+
+	if cooldown := at.getLossCooldown(); pnl < 0 && cooldown > 0 {
+		log.Printf("🧊 [%s] %s 亏损平仓 (%.2f USDT)，%d 分钟内不再开仓", at.name, symbol, pnl, int(cooldown.Minutes()))
+	}
+}
+
+// observePositions 记录本周期各持仓的未实现盈亏（symbol_side -> 盈亏）
+// 上一周期存在、本周期消失的持仓视为已平仓（交易所止盈止损触发、强平等），按最后观察到的盈亏记录结果
+func (at *AutoTrader) observePositions(current map[string]float64, now time.Time) {
+	at.cooldownMu.Lock()
+	previous := at.lastPositionPnL
+	at.lastPositionPnL = current
+	at.cooldownMu.Unlock()
+
+	for key, pnl := range previous {
+		if _, ok := current[key]; ok {
+			continue
+		}
+		if idx := strings.LastIndex(key, "_"); idx > 0 {
+			at.recordCloseResult(key[:idx], pnl, now)
+		}
+	}
+}
+
+// recordPositionClose 主动平仓（AI 平仓、一键平仓）后记录平仓结果，并从持仓观察中移除避免重复记录
+// pnl 为 nil 时使用最后一次观察到的未实现盈亏（没有观察记录则不记录）
+func (at *AutoTrader) recordPositionClose(symbol, side string, pnl *float64, closedAt time.Time) {
+	key := symbol + "_" + strings.ToLower(side)
+	at.cooldownMu.Lock()
+	observed, ok := at.lastPositionPnL[key]
+	delete(at.lastPositionPnL, key)
+	at.cooldownMu.Unlock()
+
+	if pnl != nil {
+		at.recordCloseResult(symbol, *pnl, closedAt)
+	} else if ok {
+		at.recordCloseResult(symbol, observed, closedAt)
+	}
+}
+
+// lossCooldownRemaining 返回币种剩余的亏损冷却时间（未在冷却中返回0）
+func (at *AutoTrader) lossCooldownRemaining(symbol string, now time.Time) time.Duration {
+	cooldown := at.getLossCooldown()
+	if cooldown <= 0 {
+		return 0
+	}
+
+	at.cooldownMu.Lock()
+	last, ok := at.lastCloses[symbol]
+	at.cooldownMu.Unlock()
+	if !ok || last.pnl >= 0 {
+		return 0
+	}
+	remaining := last.closedAt.Add(cooldown).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// cooldownMinutes 剩余冷却时间向上取整为分钟
+func cooldownMinutes(remaining time.Duration) int {
+	return int(math.Ceil(remaining.Minutes()))
+}
+
+// lossCooldownsForPrompt 生成写入AI提示词的冷却中币种列表（未开启或没有冷却中的币种时为空）
+func (at *AutoTrader) lossCooldownsForPrompt(now time.Time) string {
+	if at.getLossCooldown() <= 0 {
+		return ""
+	}
+
+	at.cooldownMu.Lock()
+	symbols := make([]string, 0, len(at.lastCloses))
+	for symbol := range at.lastCloses {
+		symbols = append(symbols, symbol)
+	}
+	at.cooldownMu.Unlock()
+	sort.Strings(symbols)
+
+	var cooling []string
+	for _, symbol := range symbols {
+		if remaining := at.lossCooldownRemaining(symbol, now); remaining > 0 {
+			cooling = append(cooling, fmt.Sprintf("%s(剩余%d分钟)", symbol, cooldownMinutes(remaining)))
+		}
+	}
+	if len(cooling) == 0 {
+		return ""
+	}
+	return "亏损冷却中禁止开仓: " + strings.Join(cooling, ", ")
+}
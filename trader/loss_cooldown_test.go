@@ -0,0 +1,59 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLossCooldown 测试亏损平仓后的冷却计时、盈利平仓解除冷却和关闭开关
+func TestLossCooldown(t *testing.T) {
+	at := &AutoTrader{config: AutoTraderConfig{CooldownMinutesAfterLoss: 30}}
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	at.recordCloseResult("BTCUSDT", -12.5, now)
+	assert.Equal(t, 30*time.Minute, at.lossCooldownRemaining("BTCUSDT", now))
+	assert.Equal(t, 20*time.Minute, at.lossCooldownRemaining("BTCUSDT", now.Add(10*time.Minute)))
+	assert.Zero(t, at.lossCooldownRemaining("BTCUSDT", now.Add(31*time.Minute)), "冷却结束")
+	assert.Zero(t, at.lossCooldownRemaining("ETHUSDT", now), "其他币种不受影响")
+
+	assert.Equal(t, "亏损冷却中禁止开仓: BTCUSDT(剩余21分钟)", at.lossCooldownsForPrompt(now.Add(9*time.Minute+30*time.Second)))
+	assert.Contains(t, at.riskLimitsForPrompt(), "同币种亏损平仓后30分钟内禁止再次开仓")
+
+	// 较早的结果不覆盖最近一次平仓
+	at.recordCloseResult("BTCUSDT", 5, now.Add(-time.Hour))
+	assert.Equal(t, 30*time.Minute, at.lossCooldownRemaining("BTCUSDT", now))
+
+	// 之后盈利平仓结束冷却
+	at.recordCloseResult("BTCUSDT", 5, now.Add(time.Minute))
+	assert.Zero(t, at.lossCooldownRemaining("BTCUSDT", now.Add(2*time.Minute)))
+
+	at.recordCloseResult("SOLUSDT", -1, now)
+	at.SetLossCooldown(0)
+	assert.Zero(t, at.lossCooldownRemaining("SOLUSDT", now), "关闭后不再冷却")
+	assert.Empty(t, at.lossCooldownsForPrompt(now))
+}
+
+// TestLossCooldownObservePositions 测试持仓消失（交易所止损触发）和主动平仓都会记录平仓结果
+func TestLossCooldownObservePositions(t *testing.T) {
+	at := &AutoTrader{config: AutoTraderConfig{CooldownMinutesAfterLoss: 15}}
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	at.observePositions(map[string]float64{"BTCUSDT_long": -8, "ETHUSDT_short": 3, "SOLUSDT_long": -2}, now)
+
+	// 主动平仓使用最后观察到的盈亏，之后不会因持仓消失重复记录
+	at.recordPositionClose("SOLUSDT", "long", nil, now.Add(time.Minute))
+	assert.Equal(t, 15*time.Minute, at.lossCooldownRemaining("SOLUSDT", now.Add(time.Minute)))
+
+	at.observePositions(map[string]float64{"ETHUSDT_short": 1}, now.Add(3*time.Minute))
+	assert.Equal(t, 15*time.Minute, at.lossCooldownRemaining("BTCUSDT", now.Add(3*time.Minute)), "消失的亏损持仓开始冷却")
+	assert.Zero(t, at.lossCooldownRemaining("ETHUSDT", now.Add(3*time.Minute)), "仍在持仓")
+	assert.Equal(t, 13*time.Minute, at.lossCooldownRemaining("SOLUSDT", now.Add(3*time.Minute)), "不重复记录")
+
+	// 手动平仓传入实际盈亏
+	profit := 4.0
+	at.recordPositionClose("ETHUSDT", "short", &profit, now.Add(4*time.Minute))
+	at.observePositions(map[string]float64{}, now.Add(6*time.Minute))
+	assert.Zero(t, at.lossCooldownRemaining("ETHUSDT", now.Add(6*time.Minute)))
+}
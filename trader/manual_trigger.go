@@ -0,0 +1,57 @@
+package trader
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// manualTriggerCooldown 两次手动触发决策周期的最短间隔（保护AI和交易所配额）
+const manualTriggerCooldown = 30 * time.Second
+
+var (
+	// ErrTraderNotRunning 交易员未运行（无法手动触发决策周期）
+	ErrTraderNotRunning = errors.New("交易员未运行")
+	// ErrTriggerCooldown 距上次手动触发不足冷却时间
+	ErrTriggerCooldown = errors.New("手动触发过于频繁，请稍后再试")
+)
+
+// manualTriggerState 手动触发决策周期的状态
+type manualTriggerState struct {
+	mu   sync.Mutex
+	ch   chan struct{} // 主循环与定时器一起监听，收到信号立即执行一个周期
+	last time.Time     // 上次成功触发的时间
+}
+
+// TriggerCycle 通知运行中的交易员立即执行一个决策周期（不等待周期结束）。
+// 返回 ErrTraderNotRunning、ErrCycleInProgress，或 ErrTriggerCooldown 及剩余等待时间
+func (at *AutoTrader) TriggerCycle() (time.Duration, error) {
+	at.mu.RLock()
+	running := at.isRunning
+	at.mu.RUnlock()
+	if !running {
+		return 0, ErrTraderNotRunning
+	}
+
+	at.trigger.mu.Lock()
+	defer at.trigger.mu.Unlock()
+
+	if remaining := manualTriggerCooldown - time.Since(at.trigger.last); remaining > 0 {
+		return remaining, ErrTriggerCooldown
+	}
+	// 周期正在执行（定时周期或决策预演）时拒绝，避免触发信号在周期结束后又立即执行一轮
+	if !at.cycleMu.TryLock() {
+		return 0, ErrCycleInProgress
+	}
+	at.cycleMu.Unlock()
+
+	select {
+	case at.trigger.ch <- struct{}{}:
+	default:
+		return 0, ErrCycleInProgress
+	}
+	at.trigger.last = time.Now()
+	log.Printf("👆 [%s] 已手动触发决策周期", at.name)
+	return 0, nil
+}
@@ -0,0 +1,39 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerCycle(t *testing.T) {
+	at := &AutoTrader{name: "trigger", trigger: manualTriggerState{ch: make(chan struct{}, 1)}}
+
+	_, err := at.TriggerCycle()
+	assert.ErrorIs(t, err, ErrTraderNotRunning)
+
+	at.isRunning = true
+
+	// 周期执行中拒绝
+	at.cycleMu.Lock()
+	_, err = at.TriggerCycle()
+	assert.ErrorIs(t, err, ErrCycleInProgress)
+	at.cycleMu.Unlock()
+
+	_, err = at.TriggerCycle()
+	require.NoError(t, err)
+	assert.Len(t, at.trigger.ch, 1, "主循环应收到触发信号")
+
+	// 冷却期内重复触发被拒绝，并返回剩余等待时间
+	retryAfter, err := at.TriggerCycle()
+	assert.ErrorIs(t, err, ErrTriggerCooldown)
+	assert.Greater(t, retryAfter, manualTriggerCooldown-time.Second)
+
+	// 冷却结束后可以再次触发
+	<-at.trigger.ch
+	at.trigger.last = time.Now().Add(-manualTriggerCooldown)
+	_, err = at.TriggerCycle()
+	assert.NoError(t, err)
+}
@@ -0,0 +1,236 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"nofx/logger"
+	"strconv"
+	"time"
+)
+
+// 开仓执行方式
+const (
+	ExecutionModeMarket = "market"
+	ExecutionModeLimit  = "limit"
+)
+
+// 限价单超时后剩余数量的处理方式
+const (
+	LimitFallbackMarket = "market"
+	LimitFallbackCancel = "cancel"
+)
+
+// 默认限价单等待时间
+const defaultLimitTimeoutSeconds = 30
+
+// limitOrderPollInterval 轮询限价单成交状态的间隔（测试中可缩短）
+var limitOrderPollInterval = 2 * time.Second
+
+// OrderFill 订单成交状态
+type OrderFill struct {
+	Status      string  // 交易所订单状态（NEW/PARTIALLY_FILLED/FILLED/CANCELED/EXPIRED/REJECTED）
+	ExecutedQty float64 // 已成交数量
+	AvgPrice    float64 // 成交均价（未成交时为0）
+}
+
+// done 订单是否已进入终态（不会再有新的成交）
+func (f *OrderFill) done() bool {
+	switch f.Status {
+	case "FILLED", "CANCELED", "EXPIRED", "REJECTED":
+		return true
+	}
+	return false
+}
+
+// LimitOrderTrader 支持限价开仓的交易器
+// 未实现此接口的交易器在限价模式下回退为市价开仓
+type LimitOrderTrader interface {
+	// GetBestBidAsk 获取买一/卖一价
+	GetBestBidAsk(symbol string) (bid, ask float64, err error)
+	// PlaceLimitOrder 下限价开仓单（positionSide 为 LONG/SHORT），返回交易所订单ID
+	PlaceLimitOrder(symbol, positionSide string, quantity, price float64, leverage int) (string, error)
+	// GetOrder 查询订单成交状态
+	GetOrder(symbol, orderID string) (*OrderFill, error)
+	// CancelOrder 按订单ID撤单
+	CancelOrder(symbol, orderID string) error
+}
+
+// SetExecutionSettings 修改开仓执行方式和限价参数，从下一次开仓开始生效
+func (at *AutoTrader) SetExecutionSettings(mode string, offsetBps float64, timeoutSeconds int, fallback string) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.config.ExecutionMode = mode
+	at.config.LimitOffsetBps = offsetBps
+	at.config.LimitTimeoutSeconds = timeoutSeconds
+	at.config.LimitFallback = fallback
+}
+
+// executionSettings 读取开仓执行方式和限价参数（未配置的项使用默认值）
+func (at *AutoTrader) executionSettings() (string, float64, time.Duration, string) {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	mode := at.config.ExecutionMode
+	if mode != ExecutionModeLimit {
+		mode = ExecutionModeMarket
+	}
+	timeout := at.config.LimitTimeoutSeconds
+	if timeout <= 0 {
+		timeout = defaultLimitTimeoutSeconds
+	}
+	fallback := at.config.LimitFallback
+	if fallback != LimitFallbackCancel {
+		fallback = LimitFallbackMarket
+	}
+	return mode, at.config.LimitOffsetBps, time.Duration(timeout) * time.Second, fallback
+}
+
+// limitEntryPrice 计算限价开仓价格：多单挂买一价、空单挂卖一价，偏移为正时向对手价方向让价
+func limitEntryPrice(positionSide string, bid, ask, offsetBps float64) float64 {
+	if positionSide == "SHORT" {
+		return ask * (1 - offsetBps/10000)
+	}
+	return bid * (1 + offsetBps/10000)
+}
+
+// orderAvgPrice 从下单结果中读取成交均价（交易所未返回时为0）
+func orderAvgPrice(order map[string]interface{}) float64 {
+	avgPrice, _ := order["avgPrice"].(float64)
+	return avgPrice
+}
+
+// recordFill 记录成交均价和相对决策参考价（actionRecord.Price）的滑点，并把执行价格更新为实际成交均价
+func recordFill(actionRecord *logger.DecisionAction, buy bool, fillPrice float64) {
+	if fillPrice <= 0 || actionRecord.Price <= 0 {
+		return
+	}
+	actionRecord.IntendedPrice = actionRecord.Price
+	actionRecord.FillPrice = fillPrice
+	slippage := (fillPrice - actionRecord.Price) / actionRecord.Price * 10000
+	if !buy {
+		slippage = -slippage
+	}
+	actionRecord.SlippageBps = slippage
+	actionRecord.Price = fillPrice
+}
+
+// marketOpen 市价开仓
+func (at *AutoTrader) marketOpen(symbol, positionSide string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if positionSide == "SHORT" {
+		return at.trader.OpenShort(symbol, quantity, leverage)
+	}
+	return at.trader.OpenLong(symbol, quantity, leverage)
+}
+
+// openPosition 按交易员配置的执行方式开仓，返回下单结果和实际成交数量，并在 actionRecord 中记录成交均价和滑点
+//
+// 限价模式的状态机：挂单 → 轮询直到完全成交或超时 → 撤单 → 再次查询最终成交量（撤单期间可能有新成交）
+// → 剩余数量按 LimitFallback 转市价或放弃（完全没有成交时返回错误）
+func (at *AutoTrader) openPosition(symbol, positionSide string, quantity float64, leverage int, actionRecord *logger.DecisionAction) (map[string]interface{}, float64, error) {
+	mode, offsetBps, timeout, fallback := at.executionSettings()
+	buy := positionSide != "SHORT"
+
+	lt, ok := at.trader.(LimitOrderTrader)
+	if mode == ExecutionModeLimit && !ok {
+		log.Printf("  ⚠️ 当前交易所不支持限价开仓，改用市价单")
+	}
+	if mode != ExecutionModeLimit || !ok {
+		order, err := at.marketOpen(symbol, positionSide, quantity, leverage)
+		if err != nil {
+			return nil, 0, err
+		}
+		actionRecord.ExecutionMode = ExecutionModeMarket
+		recordFill(actionRecord, buy, orderAvgPrice(order))
+		return order, quantity, nil
+	}
+
+	bid, ask, err := lt.GetBestBidAsk(symbol)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取盘口失败，无法限价开仓: %w", err)
+	}
+	price := limitEntryPrice(positionSide, bid, ask, offsetBps)
+	orderID, err := lt.PlaceLimitOrder(symbol, positionSide, quantity, price, leverage)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	fill := at.waitForLimitFill(lt, symbol, orderID, timeout)
+	filledQty, filledValue := fill.ExecutedQty, fill.ExecutedQty*fill.AvgPrice
+	actionRecord.ExecutionMode = ExecutionModeLimit
+
+	if remaining := quantity - filledQty; remaining > quantity*1e-6 {
+		if !fill.done() {
+			// 撤单未确认，剩余部分仍可能成交，不能再补市价单
+			log.Printf("  ⚠️ 限价单 %s 撤单未确认，剩余 %.4f 不转市价", orderID, remaining)
+			if filledQty <= 0 {
+				return nil, 0, fmt.Errorf("限价单 %s 超时且撤单未确认，请检查交易所挂单", orderID)
+			}
+		} else if fallback == LimitFallbackMarket {
+			log.Printf("  ⏱ 限价单 %s 超时，已成交 %.4f，剩余 %.4f 转市价", orderID, filledQty, remaining)
+			order, err := at.marketOpen(symbol, positionSide, remaining, leverage)
+			switch {
+			case err == nil:
+				actionRecord.ExecutionMode = "limit+market"
+				filledQty += remaining
+				filledValue += remaining * orderAvgPrice(order)
+				if orderAvgPrice(order) <= 0 {
+					filledValue = 0 // 市价部分没有成交均价，无法计算整体均价
+				}
+			case filledQty > 0:
+				log.Printf("  ⚠️ 剩余数量转市价失败，保留限价部分成交: %v", err)
+			default:
+				return nil, 0, fmt.Errorf("限价单超时未成交，转市价失败: %w", err)
+			}
+		} else if filledQty <= 0 {
+			return nil, 0, fmt.Errorf("限价单（价格 %.6f）在 %v 内未成交，已撤单", price, timeout)
+		} else {
+			log.Printf("  ⏱ 限价单 %s 超时，已成交 %.4f，剩余 %.4f 放弃", orderID, filledQty, remaining)
+		}
+	}
+
+	avgPrice := 0.0
+	if filledQty > 0 {
+		avgPrice = filledValue / filledQty
+	}
+	recordFill(actionRecord, buy, avgPrice)
+
+	order := map[string]interface{}{
+		"orderId": orderID,
+		"symbol":  symbol,
+		"status":  fill.Status,
+	}
+	if id, err := strconv.ParseInt(orderID, 10, 64); err == nil {
+		order["orderId"] = id
+	}
+	if avgPrice > 0 {
+		order["avgPrice"] = avgPrice
+	}
+	return order, filledQty, nil
+}
+
+// waitForLimitFill 轮询限价单直到进入终态或超时；超时后撤单并返回撤单后的最终成交状态
+func (at *AutoTrader) waitForLimitFill(lt LimitOrderTrader, symbol, orderID string, timeout time.Duration) *OrderFill {
+	fill := &OrderFill{}
+	deadline := time.Now().Add(timeout)
+	for {
+		if f, err := lt.GetOrder(symbol, orderID); err != nil {
+			log.Printf("  ⚠️ 查询限价单 %s 失败: %v", orderID, err)
+		} else {
+			fill = f
+			if fill.done() {
+				return fill
+			}
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		time.Sleep(limitOrderPollInterval)
+	}
+
+	if err := lt.CancelOrder(symbol, orderID); err != nil {
+		log.Printf("  ⚠️ 撤销限价单 %s 失败: %v", orderID, err)
+	}
+	if f, err := lt.GetOrder(symbol, orderID); err == nil {
+		fill = f
+	}
+	return fill
+}
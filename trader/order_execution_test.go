@@ -0,0 +1,133 @@
+package trader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"nofx/logger"
+)
+
+// fakeLimitTrader 模拟限价单成交过程的 MockTrader：撤单前返回 pending，撤单后返回 final
+type fakeLimitTrader struct {
+	MockTrader
+	bid, ask    float64
+	pending     OrderFill
+	final       OrderFill
+	canceled    bool
+	limitPrice  float64
+	marketQty   []float64
+	marketPrice float64
+}
+
+func (f *fakeLimitTrader) GetBestBidAsk(symbol string) (float64, float64, error) {
+	return f.bid, f.ask, nil
+}
+
+func (f *fakeLimitTrader) PlaceLimitOrder(symbol, positionSide string, quantity, price float64, leverage int) (string, error) {
+	f.limitPrice = price
+	return "42", nil
+}
+
+func (f *fakeLimitTrader) GetOrder(symbol, orderID string) (*OrderFill, error) {
+	if f.canceled {
+		fill := f.final
+		return &fill, nil
+	}
+	fill := f.pending
+	return &fill, nil
+}
+
+func (f *fakeLimitTrader) CancelOrder(symbol, orderID string) error {
+	f.canceled = true
+	return nil
+}
+
+func (f *fakeLimitTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	f.marketQty = append(f.marketQty, quantity)
+	return map[string]interface{}{"orderId": int64(7), "avgPrice": f.marketPrice}, nil
+}
+
+func (f *fakeLimitTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return f.OpenLong(symbol, quantity, leverage)
+}
+
+// TestOpenPositionLimitFilled 测试限价单在超时前完全成交
+func TestOpenPositionLimitFilled(t *testing.T) {
+	fake := &fakeLimitTrader{bid: 99.8, ask: 100.2, pending: OrderFill{Status: "FILLED", ExecutedQty: 0.01, AvgPrice: 99.9}}
+	at := &AutoTrader{trader: fake, config: AutoTraderConfig{ExecutionMode: ExecutionModeLimit, LimitOffsetBps: 2}}
+	record := &logger.DecisionAction{Price: 100}
+
+	order, filled, err := at.openPosition("BTCUSDT", "LONG", 0.01, 5, record)
+	require.NoError(t, err)
+	assert.InDelta(t, 99.8*1.0002, fake.limitPrice, 1e-9, "多单挂买一价并向上偏移")
+	assert.Equal(t, 0.01, filled)
+	assert.Equal(t, int64(42), order["orderId"])
+	assert.False(t, fake.canceled)
+	assert.Empty(t, fake.marketQty)
+
+	assert.Equal(t, ExecutionModeLimit, record.ExecutionMode)
+	assert.Equal(t, 100.0, record.IntendedPrice)
+	assert.Equal(t, 99.9, record.FillPrice)
+	assert.InDelta(t, -10, record.SlippageBps, 1e-6, "买入价格低于参考价为负滑点")
+}
+
+// TestOpenPositionLimitTimeout 测试限价单超时后撤单，剩余数量转市价或放弃
+func TestOpenPositionLimitTimeout(t *testing.T) {
+	original := limitOrderPollInterval
+	limitOrderPollInterval = 50 * time.Millisecond
+	defer func() { limitOrderPollInterval = original }()
+
+	t.Run("转市价", func(t *testing.T) {
+		fake := &fakeLimitTrader{
+			bid: 99.8, ask: 100.2, marketPrice: 100.2,
+			pending: OrderFill{Status: "NEW"},
+			final:   OrderFill{Status: "CANCELED", ExecutedQty: 0.004, AvgPrice: 99.8},
+		}
+		at := &AutoTrader{trader: fake, config: AutoTraderConfig{ExecutionMode: ExecutionModeLimit, LimitTimeoutSeconds: 1}}
+		record := &logger.DecisionAction{Price: 100}
+
+		order, filled, err := at.openPosition("BTCUSDT", "LONG", 0.01, 5, record)
+		require.NoError(t, err)
+		assert.True(t, fake.canceled)
+		require.Len(t, fake.marketQty, 1)
+		assert.InDelta(t, 0.006, fake.marketQty[0], 1e-9, "只补剩余数量")
+		assert.InDelta(t, 0.01, filled, 1e-9)
+		assert.InDelta(t, 100.04, order["avgPrice"].(float64), 1e-9, "按成交量加权的均价")
+		assert.Equal(t, "limit+market", record.ExecutionMode)
+		assert.InDelta(t, 4, record.SlippageBps, 1e-6)
+	})
+
+	t.Run("撤单放弃", func(t *testing.T) {
+		fake := &fakeLimitTrader{
+			bid: 99.8, ask: 100.2,
+			pending: OrderFill{Status: "NEW"},
+			final:   OrderFill{Status: "CANCELED"},
+		}
+		at := &AutoTrader{trader: fake, config: AutoTraderConfig{
+			ExecutionMode: ExecutionModeLimit, LimitOffsetBps: 1, LimitTimeoutSeconds: 1, LimitFallback: LimitFallbackCancel}}
+		record := &logger.DecisionAction{Price: 100}
+
+		_, _, err := at.openPosition("BTCUSDT", "SHORT", 0.01, 5, record)
+		assert.Error(t, err, "完全未成交时返回错误")
+		assert.InDelta(t, 100.2*0.9999, fake.limitPrice, 1e-9, "空单挂卖一价并向下偏移")
+		assert.True(t, fake.canceled)
+		assert.Empty(t, fake.marketQty)
+	})
+}
+
+// TestOpenPositionMarketFallback 测试交易器不支持限价单时回退为市价开仓
+func TestOpenPositionMarketFallback(t *testing.T) {
+	at := &AutoTrader{trader: &MockTrader{}, config: AutoTraderConfig{ExecutionMode: ExecutionModeLimit}}
+	record := &logger.DecisionAction{Price: 100}
+
+	order, filled, err := at.openPosition("BTCUSDT", "LONG", 0.5, 5, record)
+	require.NoError(t, err)
+	assert.Equal(t, int64(123456), order["orderId"])
+	assert.Equal(t, 0.5, filled)
+	assert.Equal(t, ExecutionModeMarket, record.ExecutionMode)
+	assert.Zero(t, record.FillPrice, "没有成交均价时不记录滑点")
+	assert.Equal(t, 100.0, record.Price)
+}
@@ -0,0 +1,458 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"nofx/config"
+	"nofx/market"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPaperSlippagePct = 0.05 // 默认滑点（百分比）
+	defaultPaperTakerFeePct = 0.04 // 默认吃单手续费（百分比）
+	paperQuantityEpsilon    = 1e-9 // 剩余数量小于该值视为已全部平仓
+)
+
+// paperStore 模拟盘存储接口（由 config.Database 实现）
+type paperStore interface {
+	GetPaperAccount(traderID string) (*config.PaperAccount, error)
+	SavePaperAccount(account *config.PaperAccount) error
+	GetSystemConfig(key string) (string, error)
+}
+
+// PaperTrader 模拟盘交易器
+// 按实时标记价格（含滑点）成交并扣除手续费，虚拟余额和持仓保存在数据库中
+type PaperTrader struct {
+	traderID string
+	userID   string
+	store    paperStore
+
+	// priceFunc 获取标记价格（测试时可替换）
+	priceFunc func(symbol string) (float64, error)
+
+	slippagePct float64
+	takerFeePct float64
+
+	mu        sync.Mutex
+	account   *config.PaperAccount
+	leverages map[string]int // 每个币种的当前杠杆设置（用于下一次开仓）
+	lastOrder int64
+}
+
+// NewPaperTrader 创建模拟盘交易器，首次使用时以初始金额创建虚拟账户
+func NewPaperTrader(traderID, userID string, store paperStore, initialBalance float64, priceFunc func(symbol string) (float64, error)) (*PaperTrader, error) {
+	if store == nil {
+		return nil, fmt.Errorf("模拟盘需要数据库支持")
+	}
+	if priceFunc == nil {
+		priceFunc = market.NewAPIClient().GetMarkPrice
+	}
+
+	account, err := store.GetPaperAccount(traderID)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		account = &config.PaperAccount{
+			TraderID:  traderID,
+			UserID:    userID,
+			Balance:   initialBalance,
+			Positions: make([]*config.PaperPosition, 0),
+		}
+		if err := store.SavePaperAccount(account); err != nil {
+			return nil, fmt.Errorf("创建模拟盘账户失败: %w", err)
+		}
+		log.Printf("🧪 创建模拟盘账户: %s 初始余额 %.2f USDT", traderID, initialBalance)
+	}
+	for _, pos := range account.Positions {
+		if pos.Leverage < 1 {
+			pos.Leverage = 1
+		}
+	}
+
+	return &PaperTrader{
+		traderID:    traderID,
+		userID:      userID,
+		store:       store,
+		priceFunc:   priceFunc,
+		slippagePct: readPaperPct(store, "paper_slippage_pct", defaultPaperSlippagePct),
+		takerFeePct: readPaperPct(store, "paper_taker_fee_pct", defaultPaperTakerFeePct),
+		account:     account,
+		leverages:   make(map[string]int),
+	}, nil
+}
+
+// readPaperPct 读取百分比系统配置，缺失或非法时使用默认值
+func readPaperPct(store paperStore, key string, fallback float64) float64 {
+	raw, err := store.GetSystemConfig(key)
+	if err != nil || raw == "" {
+		return fallback
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || value < 0 {
+		log.Printf("⚠️ 模拟盘配置 %s=%q 无效，使用默认值 %.4f", key, raw, fallback)
+		return fallback
+	}
+	return value
+}
+
+// GetBalance 获取模拟账户余额（返回字段与币安合约账户一致）
+func (t *PaperTrader) GetBalance() (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prices, err := t.refreshLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	unrealized, usedMargin := 0.0, 0.0
+	for _, pos := range t.account.Positions {
+		unrealized += paperUnrealizedPnL(pos, prices[pos.Symbol])
+		usedMargin += pos.Quantity * pos.EntryPrice / float64(pos.Leverage)
+	}
+
+	return map[string]interface{}{
+		"totalWalletBalance":    t.account.Balance,
+		"availableBalance":      math.Max(0, t.account.Balance+unrealized-usedMargin),
+		"totalUnrealizedProfit": unrealized,
+	}, nil
+}
+
+// GetPositions 获取模拟持仓（空仓 positionAmt 为负数，与币安一致）
+func (t *PaperTrader) GetPositions() ([]map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prices, err := t.refreshLocked()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(t.account.Positions))
+	for _, pos := range t.account.Positions {
+		amount := pos.Quantity
+		liquidation := pos.EntryPrice * (1 - 1/float64(pos.Leverage))
+		if pos.Side == "short" {
+			amount = -amount
+			liquidation = pos.EntryPrice * (1 + 1/float64(pos.Leverage))
+		}
+		result = append(result, map[string]interface{}{
+			"symbol":           pos.Symbol,
+			"side":             pos.Side,
+			"positionAmt":      amount,
+			"entryPrice":       pos.EntryPrice,
+			"markPrice":        prices[pos.Symbol],
+			"unRealizedProfit": paperUnrealizedPnL(pos, prices[pos.Symbol]),
+			"leverage":         float64(pos.Leverage),
+			"liquidationPrice": liquidation,
+		})
+	}
+	return result, nil
+}
+
+// OpenLong 模拟开多仓
+func (t *PaperTrader) OpenLong(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, "long", quantity, leverage)
+}
+
+// OpenShort 模拟开空仓
+func (t *PaperTrader) OpenShort(symbol string, quantity float64, leverage int) (map[string]interface{}, error) {
+	return t.openPosition(symbol, "short", quantity, leverage)
+}
+
+// CloseLong 模拟平多仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseLong(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "long", quantity)
+}
+
+// CloseShort 模拟平空仓（quantity=0表示全部平仓）
+func (t *PaperTrader) CloseShort(symbol string, quantity float64) (map[string]interface{}, error) {
+	return t.closePosition(symbol, "short", quantity)
+}
+
+// SetLeverage 记录币种杠杆（用于下一次开仓）
+func (t *PaperTrader) SetLeverage(symbol string, leverage int) error {
+	if leverage <= 0 {
+		return fmt.Errorf("杠杆倍数必须大于0: %d", leverage)
+	}
+	t.mu.Lock()
+	t.leverages[symbol] = leverage
+	t.mu.Unlock()
+	return nil
+}
+
+// SetMarginMode 模拟盘按全仓计算，仓位模式设置无需处理
+func (t *PaperTrader) SetMarginMode(symbol string, isCrossMargin bool) error {
+	return nil
+}
+
+// GetMarketPrice 获取标记价格
+func (t *PaperTrader) GetMarketPrice(symbol string) (float64, error) {
+	return t.priceFunc(symbol)
+}
+
+// SetStopLoss 设置止损触发价（每次查询余额或持仓时检查是否触发）
+func (t *PaperTrader) SetStopLoss(symbol string, positionSide string, quantity, stopPrice float64) error {
+	return t.updatePosition(symbol, positionSide, func(pos *config.PaperPosition) { pos.StopLoss = stopPrice })
+}
+
+// SetTakeProfit 设置止盈触发价（每次查询余额或持仓时检查是否触发）
+func (t *PaperTrader) SetTakeProfit(symbol string, positionSide string, quantity, takeProfitPrice float64) error {
+	return t.updatePosition(symbol, positionSide, func(pos *config.PaperPosition) { pos.TakeProfit = takeProfitPrice })
+}
+
+// CancelStopLossOrders 清除该币种的止损
+func (t *PaperTrader) CancelStopLossOrders(symbol string) error {
+	return t.updatePosition(symbol, "", func(pos *config.PaperPosition) { pos.StopLoss = 0 })
+}
+
+// CancelTakeProfitOrders 清除该币种的止盈
+func (t *PaperTrader) CancelTakeProfitOrders(symbol string) error {
+	return t.updatePosition(symbol, "", func(pos *config.PaperPosition) { pos.TakeProfit = 0 })
+}
+
+// CancelAllOrders 清除该币种的止盈止损（模拟盘没有其他挂单）
+func (t *PaperTrader) CancelAllOrders(symbol string) error {
+	return t.CancelStopOrders(symbol)
+}
+
+// CancelStopOrders 清除该币种的止盈止损
+func (t *PaperTrader) CancelStopOrders(symbol string) error {
+	return t.updatePosition(symbol, "", func(pos *config.PaperPosition) {
+		pos.StopLoss = 0
+		pos.TakeProfit = 0
+	})
+}
+
+// FormatQuantity 格式化数量（模拟盘统一保留4位小数）
+func (t *PaperTrader) FormatQuantity(symbol string, quantity float64) (string, error) {
+	return fmt.Sprintf("%.4f", quantity), nil
+}
+
+// openPosition 按标记价格加滑点开仓，同方向已有持仓时按加权均价合并
+func (t *PaperTrader) openPosition(symbol, side string, quantity float64, leverage int) (map[string]interface{}, error) {
+	if quantity <= 0 {
+		return nil, fmt.Errorf("开仓数量必须大于0")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if leverage <= 0 {
+		leverage = t.leverages[symbol]
+	}
+	if leverage <= 0 {
+		leverage = 1
+	}
+
+	prices, err := t.refreshLocked()
+	if err != nil {
+		return nil, err
+	}
+	markPrice, ok := prices[symbol]
+	if !ok {
+		if markPrice, err = t.priceFunc(symbol); err != nil {
+			return nil, fmt.Errorf("获取 %s 标记价格失败: %w", symbol, err)
+		}
+	}
+
+	fillPrice := t.fillPrice(markPrice, side == "long")
+	notional := quantity * fillPrice
+	fee := notional * t.takerFeePct / 100
+	margin := notional / float64(leverage)
+
+	available := t.account.Balance
+	for _, pos := range t.account.Positions {
+		available += paperUnrealizedPnL(pos, prices[pos.Symbol]) - pos.Quantity*pos.EntryPrice/float64(pos.Leverage)
+	}
+	if margin+fee > available {
+		return nil, fmt.Errorf("保证金不足: 需要 %.2f USDT（含手续费 %.2f），可用 %.2f USDT", margin+fee, fee, available)
+	}
+
+	pos := t.findPosition(symbol, side)
+	if pos == nil {
+		pos = &config.PaperPosition{Symbol: symbol, Side: side, OpenedAt: time.Now().UTC()}
+		t.account.Positions = append(t.account.Positions, pos)
+	}
+	pos.EntryPrice = (pos.EntryPrice*pos.Quantity + fillPrice*quantity) / (pos.Quantity + quantity)
+	pos.Quantity += quantity
+	pos.Leverage = leverage
+	t.account.Balance -= fee
+
+	if err := t.store.SavePaperAccount(t.account); err != nil {
+		return nil, err
+	}
+	log.Printf("🧪 模拟开%s仓: %s 数量 %.4f 成交价 %.4f 手续费 %.4f", paperSideName(side), symbol, quantity, fillPrice, fee)
+	return t.orderResult(symbol, fillPrice), nil
+}
+
+// closePosition 按当前标记价格平仓
+func (t *PaperTrader) closePosition(symbol, side string, quantity float64) (map[string]interface{}, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.findPosition(symbol, side) == nil {
+		return nil, fmt.Errorf("没有找到 %s 的%s仓", symbol, paperSideName(side))
+	}
+	markPrice, err := t.priceFunc(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("获取 %s 标记价格失败: %w", symbol, err)
+	}
+
+	fillPrice, err := t.closeLocked(symbol, side, quantity, markPrice)
+	if err != nil {
+		return nil, err
+	}
+	return t.orderResult(symbol, fillPrice), nil
+}
+
+// closeLocked 按标记价格减滑点平仓并结算盈亏（调用方需持有锁）
+func (t *PaperTrader) closeLocked(symbol, side string, quantity, markPrice float64) (float64, error) {
+	pos := t.findPosition(symbol, side)
+	if pos == nil {
+		return 0, fmt.Errorf("没有找到 %s 的%s仓", symbol, paperSideName(side))
+	}
+	if quantity <= 0 || quantity > pos.Quantity {
+		quantity = pos.Quantity
+	}
+
+	fillPrice := t.fillPrice(markPrice, side == "short")
+	pnl := (fillPrice - pos.EntryPrice) * quantity
+	if side == "short" {
+		pnl = -pnl
+	}
+	fee := quantity * fillPrice * t.takerFeePct / 100
+	t.account.Balance += pnl - fee
+
+	pos.Quantity -= quantity
+	if pos.Quantity < paperQuantityEpsilon {
+		t.removePosition(symbol, side)
+	}
+
+	if err := t.store.SavePaperAccount(t.account); err != nil {
+		return 0, err
+	}
+	log.Printf("🧪 模拟平%s仓: %s 数量 %.4f 成交价 %.4f 盈亏 %.4f 手续费 %.4f", paperSideName(side), symbol, quantity, fillPrice, pnl, fee)
+	return fillPrice, nil
+}
+
+// refreshLocked 获取所有持仓币种的标记价格，并执行已触发的止盈止损（调用方需持有锁）
+func (t *PaperTrader) refreshLocked() (map[string]float64, error) {
+	prices := make(map[string]float64)
+	for _, pos := range t.account.Positions {
+		if _, ok := prices[pos.Symbol]; ok {
+			continue
+		}
+		price, err := t.priceFunc(pos.Symbol)
+		if err != nil {
+			return nil, fmt.Errorf("获取 %s 标记价格失败: %w", pos.Symbol, err)
+		}
+		prices[pos.Symbol] = price
+	}
+
+	// 先收集再平仓，避免遍历时修改持仓列表
+	type trigger struct{ symbol, side, reason string }
+	var triggered []trigger
+	for _, pos := range t.account.Positions {
+		price := prices[pos.Symbol]
+		switch {
+		case pos.StopLoss > 0 && ((pos.Side == "long" && price <= pos.StopLoss) || (pos.Side == "short" && price >= pos.StopLoss)):
+			triggered = append(triggered, trigger{pos.Symbol, pos.Side, "止损"})
+		case pos.TakeProfit > 0 && ((pos.Side == "long" && price >= pos.TakeProfit) || (pos.Side == "short" && price <= pos.TakeProfit)):
+			triggered = append(triggered, trigger{pos.Symbol, pos.Side, "止盈"})
+		}
+	}
+	for _, tr := range triggered {
+		log.Printf("🧪 模拟%s触发: %s %s仓 标记价格 %.4f", tr.reason, tr.symbol, paperSideName(tr.side), prices[tr.symbol])
+		if _, err := t.closeLocked(tr.symbol, tr.side, 0, prices[tr.symbol]); err != nil {
+			return nil, err
+		}
+	}
+	return prices, nil
+}
+
+// updatePosition 修改持仓的止盈止损设置（side为空表示该币种所有方向）
+func (t *PaperTrader) updatePosition(symbol, side string, update func(pos *config.PaperPosition)) error {
+	side = strings.ToLower(side)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changed := false
+	for _, pos := range t.account.Positions {
+		if pos.Symbol == symbol && (side == "" || pos.Side == side) {
+			update(pos)
+			changed = true
+		}
+	}
+	if !changed {
+		if side == "" {
+			return nil
+		}
+		return fmt.Errorf("没有找到 %s 的%s仓", symbol, paperSideName(side))
+	}
+	return t.store.SavePaperAccount(t.account)
+}
+
+// fillPrice 计算含滑点的成交价（买入向上滑，卖出向下滑）
+func (t *PaperTrader) fillPrice(markPrice float64, buy bool) float64 {
+	if buy {
+		return markPrice * (1 + t.slippagePct/100)
+	}
+	return markPrice * (1 - t.slippagePct/100)
+}
+
+func (t *PaperTrader) findPosition(symbol, side string) *config.PaperPosition {
+	for _, pos := range t.account.Positions {
+		if pos.Symbol == symbol && pos.Side == side {
+			return pos
+		}
+	}
+	return nil
+}
+
+func (t *PaperTrader) removePosition(symbol, side string) {
+	positions := t.account.Positions[:0]
+	for _, pos := range t.account.Positions {
+		if pos.Symbol != symbol || pos.Side != side {
+			positions = append(positions, pos)
+		}
+	}
+	t.account.Positions = positions
+}
+
+// orderResult 构造与币安下单返回一致的结果（订单ID单调递增）
+func (t *PaperTrader) orderResult(symbol string, avgPrice float64) map[string]interface{} {
+	orderID := time.Now().UnixNano()
+	if orderID <= t.lastOrder {
+		orderID = t.lastOrder + 1
+	}
+	t.lastOrder = orderID
+	return map[string]interface{}{
+		"orderId":  orderID,
+		"symbol":   symbol,
+		"status":   "FILLED",
+		"avgPrice": avgPrice,
+	}
+}
+
+// paperUnrealizedPnL 按标记价格计算持仓未实现盈亏
+func paperUnrealizedPnL(pos *config.PaperPosition, markPrice float64) float64 {
+	if pos.Side == "short" {
+		return (pos.EntryPrice - markPrice) * pos.Quantity
+	}
+	return (markPrice - pos.EntryPrice) * pos.Quantity
+}
+
+func paperSideName(side string) string {
+	if side == "short" {
+		return "空"
+	}
+	return "多"
+}
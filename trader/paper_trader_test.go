@@ -0,0 +1,132 @@
+package trader
+
+import (
+	"fmt"
+	"testing"
+
+	"nofx/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePaperStore 内存模拟盘存储
+type fakePaperStore struct {
+	account *config.PaperAccount
+	configs map[string]string
+	saves   int
+}
+
+func (s *fakePaperStore) GetPaperAccount(traderID string) (*config.PaperAccount, error) {
+	return s.account, nil
+}
+
+func (s *fakePaperStore) SavePaperAccount(account *config.PaperAccount) error {
+	s.account = account
+	s.saves++
+	return nil
+}
+
+func (s *fakePaperStore) GetSystemConfig(key string) (string, error) {
+	return s.configs[key], nil
+}
+
+func newTestPaperTrader(t *testing.T, prices map[string]float64) (*PaperTrader, *fakePaperStore) {
+	store := &fakePaperStore{configs: map[string]string{"paper_slippage_pct": "0.1", "paper_taker_fee_pct": "0.05"}}
+	pt, err := NewPaperTrader("paper-1", "user-1", store, 1000, func(symbol string) (float64, error) {
+		price, ok := prices[symbol]
+		if !ok {
+			return 0, fmt.Errorf("未知币种 %s", symbol)
+		}
+		return price, nil
+	})
+	require.NoError(t, err)
+	return pt, store
+}
+
+// TestPaperTrader_OpenAndClose 测试按滑点成交、扣除手续费以及平仓结算盈亏
+func TestPaperTrader_OpenAndClose(t *testing.T) {
+	prices := map[string]float64{"BTCUSDT": 100}
+	pt, store := newTestPaperTrader(t, prices)
+	require.NotNil(t, store.account, "首次创建应持久化账户")
+
+	order, err := pt.OpenLong("BTCUSDT", 2, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "FILLED", order["status"])
+	assert.InDelta(t, 100.1, order["avgPrice"].(float64), 1e-9)
+	_, isInt64 := order["orderId"].(int64)
+	assert.True(t, isInt64, "订单ID应为int64，与币安一致")
+
+	// 开仓手续费 2 * 100.1 * 0.05% = 0.1001
+	balance, err := pt.GetBalance()
+	require.NoError(t, err)
+	assert.InDelta(t, 999.8999, balance["totalWalletBalance"].(float64), 1e-6)
+
+	prices["BTCUSDT"] = 110
+	positions, err := pt.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, "long", positions[0]["side"])
+	assert.InDelta(t, 2*(110-100.1), positions[0]["unRealizedProfit"].(float64), 1e-9)
+	assert.Equal(t, 5.0, positions[0]["leverage"])
+
+	// 平仓成交价 109.89，盈亏 2*(109.89-100.1)=19.58，手续费 0.10989
+	_, err = pt.CloseLong("BTCUSDT", 0)
+	require.NoError(t, err)
+	balance, err = pt.GetBalance()
+	require.NoError(t, err)
+	assert.InDelta(t, 999.8999+19.58-0.10989, balance["totalWalletBalance"].(float64), 1e-6)
+	assert.Empty(t, store.account.Positions)
+
+	_, err = pt.CloseLong("BTCUSDT", 0)
+	assert.Error(t, err, "无持仓时平仓应返回错误")
+}
+
+// TestPaperTrader_ShortAndMargin 测试空仓盈亏、加仓均价以及保证金不足拒绝开仓
+func TestPaperTrader_ShortAndMargin(t *testing.T) {
+	prices := map[string]float64{"ETHUSDT": 1000}
+	pt, _ := newTestPaperTrader(t, prices)
+	pt.slippagePct, pt.takerFeePct = 0, 0
+
+	_, err := pt.OpenShort("ETHUSDT", 1, 4)
+	require.NoError(t, err)
+	prices["ETHUSDT"] = 1200
+	_, err = pt.OpenShort("ETHUSDT", 1, 4)
+	require.NoError(t, err)
+
+	positions, err := pt.GetPositions()
+	require.NoError(t, err)
+	require.Len(t, positions, 1)
+	assert.Equal(t, -2.0, positions[0]["positionAmt"])
+	assert.Equal(t, 1100.0, positions[0]["entryPrice"])
+	assert.Equal(t, -200.0, positions[0]["unRealizedProfit"])
+
+	// 已用保证金 550，未实现亏损 200，可用资金 250
+	balance, err := pt.GetBalance()
+	require.NoError(t, err)
+	assert.Equal(t, 250.0, balance["availableBalance"])
+
+	_, err = pt.OpenLong("ETHUSDT", 1, 2)
+	assert.ErrorContains(t, err, "保证金不足")
+}
+
+// TestPaperTrader_StopLossTriggered 测试查询持仓时触发止损并自动平仓
+func TestPaperTrader_StopLossTriggered(t *testing.T) {
+	prices := map[string]float64{"SOLUSDT": 100}
+	pt, store := newTestPaperTrader(t, prices)
+	pt.slippagePct, pt.takerFeePct = 0, 0
+
+	_, err := pt.OpenLong("SOLUSDT", 1, 1)
+	require.NoError(t, err)
+	require.NoError(t, pt.SetStopLoss("SOLUSDT", "LONG", 1, 95))
+	require.NoError(t, pt.SetTakeProfit("SOLUSDT", "LONG", 1, 120))
+	assert.Equal(t, 95.0, store.account.Positions[0].StopLoss)
+
+	prices["SOLUSDT"] = 94
+	positions, err := pt.GetPositions()
+	require.NoError(t, err)
+	assert.Empty(t, positions, "跌破止损价后应自动平仓")
+	assert.Equal(t, 994.0, store.account.Balance)
+
+	assert.Error(t, pt.SetStopLoss("SOLUSDT", "LONG", 1, 90), "无持仓时设置止损应返回错误")
+}
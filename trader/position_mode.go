@@ -0,0 +1,53 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// PositionModeReporter 可选接口：能报告账户持仓模式的交易器
+// 双向持仓（Hedge Mode）下同一币种可同时持有多仓和空仓；单向持仓下反向开仓会抵消已有持仓
+type PositionModeReporter interface {
+	HedgeMode() bool
+}
+
+// isPositionSideMismatch 是否为订单 positionSide 与账户持仓模式不符的错误（币安 -4061）
+func isPositionSideMismatch(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == -4061
+	}
+	return strings.Contains(err.Error(), "code=-4061")
+}
+
+// hedgeMode 当前交易器是否为双向持仓模式（未实现 PositionModeReporter 的交易器按双向持仓处理）
+func (at *AutoTrader) hedgeMode() bool {
+	if reporter, ok := at.trader.(PositionModeReporter); ok {
+		return reporter.HedgeMode()
+	}
+	return true
+}
+
+// checkOppositePosition 单向持仓模式下，同币种已有反向持仓时拒绝开仓（否则开仓单会抵消反向持仓而不是新开一条腿）
+func (at *AutoTrader) checkOppositePosition(symbol, side string, positions []map[string]interface{}) error {
+	if at.hedgeMode() {
+		return nil
+	}
+	opposite, closeAction := "short", "close_short"
+	if side == "short" {
+		opposite, closeAction = "long", "close_long"
+	}
+	for _, pos := range positions {
+		if pos["symbol"] == symbol && pos["side"] == opposite {
+			return fmt.Errorf("❌ 账户为单向持仓模式，%s 已有%s仓，开%s仓会抵消现有持仓，拒绝开仓。如需反手，请先给出 %s 决策",
+				symbol, paperSideName(opposite), paperSideName(side), closeAction)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package trader
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+)
+
+// positionModeServer 模拟币安账户持仓模式、持仓、挂单和下单接口
+type positionModeServer struct {
+	mu         sync.Mutex
+	dualSide   bool
+	positions  []map[string]interface{}
+	openOrders []map[string]interface{}
+	orders     []url.Values // 收到的下单参数
+	canceled   []string     // 被取消的订单ID
+	cancelAll  int          // 撤销全部挂单的次数
+}
+
+func (s *positionModeServer) handler(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r.ParseForm()
+
+	var respBody interface{} = map[string]interface{}{}
+	switch {
+	case r.URL.Path == "/fapi/v1/positionSide/dual" && r.Method == http.MethodGet:
+		respBody = map[string]interface{}{"dualSidePosition": s.dualSide}
+	case r.URL.Path == "/fapi/v2/positionRisk":
+		respBody = s.positions
+	case r.URL.Path == "/fapi/v1/openOrders":
+		respBody = s.openOrders
+	case r.URL.Path == "/fapi/v1/order" && r.Method == http.MethodDelete:
+		s.canceled = append(s.canceled, r.Form.Get("orderId"))
+	case r.URL.Path == "/fapi/v1/allOpenOrders":
+		s.cancelAll++
+	case r.URL.Path == "/fapi/v1/ticker/price" || r.URL.Path == "/fapi/v2/ticker/price":
+		respBody = []map[string]interface{}{{"symbol": "BTCUSDT", "price": "50000.00"}}
+	case r.URL.Path == "/fapi/v1/order" && r.Method == http.MethodPost:
+		s.orders = append(s.orders, r.Form)
+		positionSide := r.Form.Get("positionSide")
+		if (positionSide == "BOTH") == s.dualSide {
+			w.WriteHeader(http.StatusBadRequest)
+			respBody = map[string]interface{}{"code": -4061, "msg": "Order's position side does not match user's setting."}
+			break
+		}
+		respBody = map[string]interface{}{"orderId": 1000 + len(s.orders), "symbol": "BTCUSDT", "status": "FILLED", "avgPrice": "50000.00"}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(respBody)
+}
+
+// newPositionModeTestTrader 创建连接模拟服务器的币安交易器，并按服务器设置检测持仓模式
+func newPositionModeTestTrader(t *testing.T, mock *positionModeServer) *FuturesTrader {
+	server := httptest.NewServer(http.HandlerFunc(mock.handler))
+	t.Cleanup(server.Close)
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = server.URL
+	client.HTTPClient = server.Client()
+	trader := &FuturesTrader{client: client, cacheDuration: 0}
+	_, err := trader.refreshPositionMode()
+	assert.NoError(t, err)
+	return trader
+}
+
+// TestFuturesTrader_HedgeModeOpenLongWithShort 双向持仓模式下已有空仓时开多仓：
+// 订单带 positionSide=LONG，不撤空仓的止损单，持仓列表分别返回多空两条腿
+func TestFuturesTrader_HedgeModeOpenLongWithShort(t *testing.T) {
+	mock := &positionModeServer{
+		dualSide: true,
+		positions: []map[string]interface{}{
+			{"symbol": "BTCUSDT", "positionAmt": "-0.2", "entryPrice": "51000", "markPrice": "50000", "leverage": "10", "positionSide": "SHORT"},
+			{"symbol": "BTCUSDT", "positionAmt": "0", "entryPrice": "0", "markPrice": "50000", "leverage": "10", "positionSide": "LONG"},
+		},
+		openOrders: []map[string]interface{}{
+			{"orderId": 7, "symbol": "BTCUSDT", "type": "STOP_MARKET", "positionSide": "SHORT"},
+		},
+	}
+	trader := newPositionModeTestTrader(t, mock)
+	assert.True(t, trader.HedgeMode())
+
+	_, err := trader.OpenLong("BTCUSDT", 0.1, 10)
+	assert.NoError(t, err)
+	if assert.Len(t, mock.orders, 1) {
+		assert.Equal(t, "LONG", mock.orders[0].Get("positionSide"))
+		assert.Empty(t, mock.orders[0].Get("reduceOnly"))
+	}
+	assert.Empty(t, mock.canceled, "不应撤销空仓的止损单")
+	assert.Zero(t, mock.cancelAll)
+
+	mock.positions[1]["positionAmt"] = "0.1"
+	mock.positions[1]["entryPrice"] = "50000"
+	positions, err := trader.GetPositionList()
+	assert.NoError(t, err)
+	if assert.Len(t, positions, 2) {
+		assert.Equal(t, "short", positions[0].Side)
+		assert.Equal(t, 0.2, positions[0].Quantity)
+		assert.Equal(t, "long", positions[1].Side)
+		assert.Equal(t, 0.1, positions[1].Quantity)
+	}
+}
+
+// TestFuturesTrader_OneWayMode 单向持仓模式下订单 positionSide 为 BOTH，平仓单附加 reduceOnly
+func TestFuturesTrader_OneWayMode(t *testing.T) {
+	mock := &positionModeServer{
+		positions: []map[string]interface{}{
+			{"symbol": "BTCUSDT", "positionAmt": "-0.2", "entryPrice": "51000", "markPrice": "50000", "leverage": "10", "positionSide": "BOTH"},
+		},
+	}
+	trader := newPositionModeTestTrader(t, mock)
+	assert.False(t, trader.HedgeMode())
+
+	positions, err := trader.GetPositionList()
+	assert.NoError(t, err)
+	if assert.Len(t, positions, 1) {
+		assert.Equal(t, "short", positions[0].Side)
+		assert.Equal(t, 0.2, positions[0].Quantity)
+	}
+
+	_, err = trader.CloseShort("BTCUSDT", 0)
+	assert.NoError(t, err)
+	if assert.Len(t, mock.orders, 1) {
+		assert.Equal(t, "BOTH", mock.orders[0].Get("positionSide"))
+		assert.Equal(t, "true", mock.orders[0].Get("reduceOnly"))
+		assert.Equal(t, "0.200", mock.orders[0].Get("quantity"))
+	}
+	assert.Equal(t, 1, mock.cancelAll)
+}
+
+// TestFuturesTrader_PositionSideMismatchRetry 账户模式被切换后（-4061）重新检测持仓模式并重新下单
+func TestFuturesTrader_PositionSideMismatchRetry(t *testing.T) {
+	mock := &positionModeServer{dualSide: true}
+	trader := newPositionModeTestTrader(t, mock)
+	mock.dualSide = false // 用户在交易所切换为单向持仓
+
+	_, err := trader.placeTriggerOrder("BTCUSDT", "LONG", futures.OrderTypeStopMarket, 0.1, 45000)
+	assert.NoError(t, err)
+	assert.False(t, trader.HedgeMode())
+	if assert.Len(t, mock.orders, 2) {
+		assert.Equal(t, "LONG", mock.orders[0].Get("positionSide"))
+		assert.Equal(t, "BOTH", mock.orders[1].Get("positionSide"))
+		assert.Empty(t, mock.orders[1].Get("reduceOnly"), "closePosition 触发单不能附加 reduceOnly")
+	}
+}
+
+// oneWayMockTrader 单向持仓模式的模拟交易器
+type oneWayMockTrader struct {
+	*MockTrader
+	hedge bool
+}
+
+func (m *oneWayMockTrader) HedgeMode() bool { return m.hedge }
+
+// TestCheckOppositePosition 单向持仓模式下已有空仓时拒绝开多仓，双向持仓模式下允许
+func TestCheckOppositePosition(t *testing.T) {
+	positions := []map[string]interface{}{{"symbol": "BTCUSDT", "side": "short", "positionAmt": 0.2}}
+	mockTrader := &oneWayMockTrader{MockTrader: &MockTrader{}}
+	at := &AutoTrader{trader: mockTrader, lastResetTime: time.Now()}
+
+	err := at.checkOppositePosition("BTCUSDT", "long", positions)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "单向持仓模式")
+		assert.Contains(t, err.Error(), "close_short")
+	}
+	assert.NoError(t, at.checkOppositePosition("ETHUSDT", "long", positions))
+	assert.NoError(t, at.checkOppositePosition("BTCUSDT", "short", positions))
+
+	mockTrader.hedge = true
+	assert.NoError(t, at.checkOppositePosition("BTCUSDT", "long", positions))
+	assert.True(t, at.GetStatus()["hedge_mode"].(bool))
+}
+
+var (
+	_ PositionModeReporter = (*FuturesTrader)(nil)
+	_ PositionModeReporter = (*AsterTrader)(nil)
+	_ PositionModeReporter = (*HyperliquidTrader)(nil)
+)
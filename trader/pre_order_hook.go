@@ -0,0 +1,200 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"nofx/decision"
+	"nofx/hook"
+	"nofx/logger"
+)
+
+const (
+	// preOrderHookRule 下单扩展点写入决策记录 guardrails 时的规则名
+	preOrderHookRule = "pre_order_hook"
+	// defaultPreOrderHookTimeout 下单扩展点默认超时
+	defaultPreOrderHookTimeout = 3 * time.Second
+)
+
+// preOrderGate 一个决策周期内的下单扩展点检查（记录本周期已拒绝的订单，不再重复请求）
+type preOrderGate struct {
+	at          *AutoTrader
+	ctx         *decision.Context
+	timeout     time.Duration
+	allowOnFail bool              // 扩展点超时或出错时放行
+	rejected    map[string]string // symbol|action -> 拒绝原因
+}
+
+// newPreOrderGate 读取超时和降级策略（系统配置 pre_order_hook_timeout_ms / pre_order_hook_fallback）
+func (at *AutoTrader) newPreOrderGate(ctx *decision.Context) *preOrderGate {
+	gate := &preOrderGate{at: at, ctx: ctx, timeout: defaultPreOrderHookTimeout, rejected: make(map[string]string)}
+	store, ok := at.database.(systemConfigStore)
+	if !ok {
+		return gate
+	}
+	if value, _ := store.GetSystemConfig("pre_order_hook_timeout_ms"); value != "" {
+		if ms, err := strconv.Atoi(value); err == nil && ms > 0 {
+			gate.timeout = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if value, _ := store.GetSystemConfig("pre_order_hook_fallback"); value == "allow" {
+		gate.allowOnFail = true
+	}
+	return gate
+}
+
+// isOrderAction 是否会向交易所下单（调整止盈止损只修改触发单，不经过 PRE_ORDER）
+func isOrderAction(action string) bool {
+	switch action {
+	case "open_long", "open_short", "close_long", "close_short", "partial_close":
+		return true
+	}
+	return false
+}
+
+// preOrderInput 按决策和当前行情/持仓估算即将发送的订单
+func (at *AutoTrader) preOrderInput(d *decision.Decision, ctx *decision.Context) hook.PreOrderInput {
+	in := hook.PreOrderInput{
+		TraderID: at.id,
+		UserID:   at.userID,
+		Symbol:   d.Symbol,
+		Action:   d.Action,
+		Leverage: d.Leverage,
+	}
+
+	switch d.Action {
+	case "open_long", "open_short":
+		in.Side = "BUY"
+		if d.Action == "open_short" {
+			in.Side = "SELL"
+		}
+		in.Notional = d.PositionSizeUSD
+		if data := ctx.MarketDataMap[d.Symbol]; data != nil && data.CurrentPrice > 0 {
+			in.Quantity = d.PositionSizeUSD / data.CurrentPrice
+		}
+	default:
+		side := "long"
+		if d.Action == "close_short" {
+			side = "short"
+		}
+		for _, pos := range ctx.Positions {
+			if pos.Symbol != d.Symbol || (d.Action != "partial_close" && pos.Side != side) {
+				continue
+			}
+			in.Side = "SELL"
+			if pos.Side == "short" {
+				in.Side = "BUY"
+			}
+			in.Leverage = pos.Leverage
+			in.Quantity = math.Abs(pos.Quantity)
+			if d.Action == "partial_close" && d.ClosePercentage > 0 {
+				in.Quantity *= d.ClosePercentage / 100
+			}
+			in.Notional = in.Quantity * pos.MarkPrice
+			break
+		}
+	}
+	return in
+}
+
+// runWithTimeout 执行扩展点，超过 timeout 未返回时放弃等待（处理函数在后台继续执行，结果丢弃）
+func (g *preOrderGate) runWithTimeout(in hook.PreOrderInput) (hook.PreOrderOutput, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), g.timeout)
+	defer cancel()
+
+	type result struct {
+		out hook.PreOrderOutput
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		out := hook.PreOrderOutput{Quantity: in.Quantity, Notional: in.Notional, Leverage: in.Leverage}
+		err := hook.PreOrder.Run(ctx, in, &out)
+		done <- result{out, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.out, r.err
+	case <-ctx.Done():
+		return hook.PreOrderOutput{}, fmt.Errorf("下单扩展点超时（%v）", g.timeout)
+	}
+}
+
+// check 对一个决策执行下单扩展点：返回拒绝原因（为空表示放行）和写入决策记录的干预；
+// 扩展点缩减订单时直接修改决策（开仓缩减仓位/杠杆，平仓转为部分平仓）
+func (g *preOrderGate) check(d *decision.Decision) (string, []logger.GuardrailIntervention) {
+	if !isOrderAction(d.Action) || !hook.PreOrder.Registered() {
+		return "", nil
+	}
+	key := d.Symbol + "|" + d.Action
+	if reason, ok := g.rejected[key]; ok {
+		return fmt.Sprintf("本周期已被拒绝: %s", reason), nil
+	}
+	intervention := func(verdict, reason string) logger.GuardrailIntervention {
+		return logger.GuardrailIntervention{Rule: preOrderHookRule, Symbol: d.Symbol, Action: d.Action, Verdict: verdict, Reason: reason}
+	}
+	reject := func(reason string) (string, []logger.GuardrailIntervention) {
+		g.rejected[key] = reason
+		log.Printf("🔌 [%s] %s %s 被下单扩展点拒绝: %s", g.at.name, d.Symbol, d.Action, reason)
+		return reason, []logger.GuardrailIntervention{intervention(GuardrailVeto, reason)}
+	}
+
+	in := g.at.preOrderInput(d, g.ctx)
+	out, err := g.runWithTimeout(in)
+	if err != nil {
+		if !g.allowOnFail {
+			return reject(fmt.Sprintf("%v（默认拒绝）", err))
+		}
+		log.Printf("⚠️ [%s] %s %s 下单扩展点不可用，按配置放行: %v", g.at.name, d.Symbol, d.Action, err)
+		return "", []logger.GuardrailIntervention{intervention(GuardrailAllowOnFailure, fmt.Sprintf("%v（默认放行）", err))}
+	}
+	if out.Reject {
+		if out.Reason == "" {
+			out.Reason = "下单扩展点拒绝"
+		}
+		return reject(out.Reason)
+	}
+
+	// 缩减订单（只能调小）
+	ratio := 1.0
+	if in.Notional > 0 && out.Notional > 0 && out.Notional < in.Notional {
+		ratio = out.Notional / in.Notional
+	}
+	if in.Quantity > 0 && out.Quantity > 0 && out.Quantity < in.Quantity {
+		ratio = math.Min(ratio, out.Quantity/in.Quantity)
+	}
+	var changes []string
+	if ratio < 1 {
+		switch d.Action {
+		case "open_long", "open_short":
+			d.PositionSizeUSD *= ratio
+			changes = append(changes, fmt.Sprintf("仓位缩减为 %.2f USDT", d.PositionSizeUSD))
+		default:
+			percentage := d.ClosePercentage
+			if d.Action != "partial_close" || percentage <= 0 {
+				percentage = 100
+			}
+			d.Action = "partial_close"
+			d.ClosePercentage = percentage * ratio
+			changes = append(changes, fmt.Sprintf("改为部分平仓 %.1f%%", d.ClosePercentage))
+		}
+	}
+	if (d.Action == "open_long" || d.Action == "open_short") && out.Leverage > 0 && out.Leverage < d.Leverage {
+		d.Leverage = out.Leverage
+		changes = append(changes, fmt.Sprintf("杠杆降为 %dx", d.Leverage))
+	}
+	if len(changes) == 0 {
+		return "", nil
+	}
+	reason := strings.Join(changes, "，")
+	if out.Reason != "" {
+		reason = out.Reason + " " + reason
+	}
+	return "", []logger.GuardrailIntervention{intervention(GuardrailClamp, reason)}
+}
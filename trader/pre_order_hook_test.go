@@ -0,0 +1,148 @@
+package trader
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"nofx/decision"
+	"nofx/hook"
+	"nofx/market"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPreOrderTestGate(t *testing.T, config fakeSystemConfigStore) *preOrderGate {
+	t.Helper()
+	hook.PreOrder.Reset()
+	t.Cleanup(hook.PreOrder.Reset)
+	at := &AutoTrader{id: "trader-1", userID: "user-1", name: "test", database: config}
+	return at.newPreOrderGate(&decision.Context{
+		MarketDataMap: map[string]*market.Data{"BTCUSDT": {Symbol: "BTCUSDT", CurrentPrice: 50000}},
+		Positions:     []decision.PositionInfo{{Symbol: "ETHUSDT", Side: "long", Quantity: 2, MarkPrice: 3000, Leverage: 5}},
+	})
+}
+
+// TestPreOrderGateApproveAndReduce 放行时不修改决策；缩减开仓仓位/杠杆；缩减平仓转为部分平仓
+func TestPreOrderGateApproveAndReduce(t *testing.T) {
+	gate := newPreOrderTestGate(t, nil)
+
+	// 未注册处理函数时直接放行
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 1000, Leverage: 10}
+	reason, interventions := gate.check(d)
+	assert.Empty(t, reason)
+	assert.Empty(t, interventions)
+
+	var seen hook.PreOrderInput
+	hook.PreOrder.Register("cap", hook.PriorityDefault, func(ctx context.Context, in hook.PreOrderInput, out *hook.PreOrderOutput) error {
+		seen = in
+		if in.Notional > 500 {
+			out.Notional, out.Leverage, out.Reason = 500, 3, "单笔上限"
+		}
+		return nil
+	})
+
+	d = &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 1000, Leverage: 10}
+	reason, interventions = gate.check(d)
+	assert.Empty(t, reason)
+	assert.Equal(t, "BUY", seen.Side)
+	assert.InDelta(t, 0.02, seen.Quantity, 1e-9)
+	assert.Equal(t, "trader-1", seen.TraderID)
+	assert.InDelta(t, 500, d.PositionSizeUSD, 1e-9)
+	assert.Equal(t, 3, d.Leverage)
+	require.Len(t, interventions, 1)
+	assert.Equal(t, GuardrailClamp, interventions[0].Verdict)
+	assert.Equal(t, preOrderHookRule, interventions[0].Rule)
+
+	// 平仓 6000 USDT 的多仓被缩减为 500 → 部分平仓
+	d = &decision.Decision{Symbol: "ETHUSDT", Action: "close_long"}
+	_, interventions = gate.check(d)
+	assert.Equal(t, "SELL", seen.Side)
+	assert.InDelta(t, 6000, seen.Notional, 1e-9)
+	assert.Equal(t, "partial_close", d.Action)
+	assert.InDelta(t, 100*500.0/6000, d.ClosePercentage, 1e-9)
+	require.Len(t, interventions, 1)
+
+	// 未超过上限的订单原样放行
+	d = &decision.Decision{Symbol: "BTCUSDT", Action: "open_short", PositionSizeUSD: 200, Leverage: 5}
+	reason, interventions = gate.check(d)
+	assert.Empty(t, reason)
+	assert.Empty(t, interventions)
+	assert.Equal(t, "SELL", seen.Side)
+	assert.InDelta(t, 200, d.PositionSizeUSD, 1e-9)
+}
+
+// TestPreOrderGateReject 拒绝的原因写入决策记录，同一周期内不再重复请求扩展点
+func TestPreOrderGateReject(t *testing.T) {
+	gate := newPreOrderTestGate(t, nil)
+	calls := 0
+	hook.PreOrder.Register("compliance", hook.PriorityDefault, func(ctx context.Context, in hook.PreOrderInput, out *hook.PreOrderOutput) error {
+		calls++
+		out.Reject, out.Reason = true, "未通过合规审批"
+		return nil
+	})
+
+	d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 1000, Leverage: 10}
+	reason, interventions := gate.check(d)
+	assert.Equal(t, "未通过合规审批", reason)
+	require.Len(t, interventions, 1)
+	assert.Equal(t, GuardrailVeto, interventions[0].Verdict)
+
+	reason, interventions = gate.check(d)
+	assert.Contains(t, reason, "未通过合规审批")
+	assert.Empty(t, interventions)
+	assert.Equal(t, 1, calls)
+
+	// 调整止盈止损不经过扩展点
+	reason, _ = gate.check(&decision.Decision{Symbol: "BTCUSDT", Action: "update_stop_loss"})
+	assert.Empty(t, reason)
+	assert.Equal(t, 1, calls)
+}
+
+// TestPreOrderGateFallback 扩展点超时或 Abort 错误时按 pre_order_hook_fallback 拒绝（默认）或放行
+func TestPreOrderGateFallback(t *testing.T) {
+	slow := func(ctx context.Context, in hook.PreOrderInput, out *hook.PreOrderOutput) error {
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+		out.Reject = true
+		return nil
+	}
+	failing := func(ctx context.Context, in hook.PreOrderInput, out *hook.PreOrderOutput) error {
+		return errors.New("风控服务不可用")
+	}
+
+	tests := []struct {
+		name     string
+		config   fakeSystemConfigStore
+		handler  hook.Handler[hook.PreOrderInput, hook.PreOrderOutput]
+		rejected bool
+	}{
+		{"超时默认拒绝", fakeSystemConfigStore{"pre_order_hook_timeout_ms": "20"}, slow, true},
+		{"超时按配置放行", fakeSystemConfigStore{"pre_order_hook_timeout_ms": "20", "pre_order_hook_fallback": "allow"}, slow, false},
+		{"错误默认拒绝", nil, failing, true},
+		{"错误按配置放行", fakeSystemConfigStore{"pre_order_hook_fallback": "allow"}, failing, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gate := newPreOrderTestGate(t, tt.config)
+			hook.PreOrder.Register("risk", hook.PriorityDefault, tt.handler).OnError(hook.Abort)
+
+			d := &decision.Decision{Symbol: "BTCUSDT", Action: "open_long", PositionSizeUSD: 1000, Leverage: 10}
+			start := time.Now()
+			reason, interventions := gate.check(d)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			assert.Equal(t, tt.rejected, reason != "")
+			require.Len(t, interventions, 1)
+			if tt.rejected {
+				assert.Equal(t, GuardrailVeto, interventions[0].Verdict)
+			} else {
+				assert.Equal(t, GuardrailAllowOnFailure, interventions[0].Verdict)
+				assert.InDelta(t, 1000, d.PositionSizeUSD, 1e-9)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package trader
+
+import (
+	"fmt"
+	"nofx/decision"
+	"nofx/logger"
+)
+
+// recordPromptTruncation 把提示词裁剪情况写入决策记录（未裁剪时不记录）
+func recordPromptTruncation(record *logger.DecisionRecord, truncation *decision.PromptTruncation) {
+	if truncation == nil {
+		return
+	}
+	record.PromptTruncation = &logger.PromptTruncation{
+		BudgetTokens:   truncation.BudgetTokens,
+		OriginalTokens: truncation.OriginalTokens,
+		FinalTokens:    truncation.FinalTokens,
+		KlineLookback:  truncation.KlineLookback,
+		DroppedSymbols: truncation.DroppedSymbols,
+		OverBudget:     truncation.OverBudget,
+	}
+	msg := fmt.Sprintf("✂️ 提示词超出上下文预算（约%d/%d tokens），K线序列缩短到%d条", truncation.OriginalTokens, truncation.BudgetTokens, truncation.KlineLookback)
+	if len(truncation.DroppedSymbols) > 0 {
+		msg += fmt.Sprintf("，移除%d个候选币种: %v", len(truncation.DroppedSymbols), truncation.DroppedSymbols)
+	}
+	if truncation.OverBudget {
+		msg += "（仅保留持仓币种仍超出预算）"
+	}
+	record.ExecutionLog = append(record.ExecutionLog, msg)
+}
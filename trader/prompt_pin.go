@@ -0,0 +1,53 @@
+package trader
+
+import (
+	"log"
+	"nofx/decision"
+)
+
+// PinPromptTemplate 固定当前模板名称对应的最新版本（启动时调用，运行期间用户模板被修改不影响本次运行，保证实验可复现）
+// A/B实验开启时同时固定两组模板的版本；prompts 目录中的系统模板热加载后由 refreshSystemPromptPin 更新
+func (at *AutoTrader) PinPromptTemplate() *decision.PromptTemplate {
+	template := decision.ResolvePromptTemplate(at.userID, at.systemPromptTemplate)
+	if template != nil {
+		pinned := *template
+		template = &pinned
+		log.Printf("📌 [%s] 固定提示词模板: %s (%s v%d)", at.name, template.Name, template.Source, template.Version)
+	}
+
+	at.settingsMu.Lock()
+	at.pinnedPrompt = template
+	at.settingsMu.Unlock()
+
+	if exp := at.GetPromptExperiment(); exp.Enabled {
+		at.SetPromptExperiment(exp)
+	}
+	return template
+}
+
+// refreshSystemPromptPin 固定的是系统模板且该模板已被热加载为新内容时改用新内容（在下个周期生效），
+// 用户模板按版本固定，不受影响
+func (at *AutoTrader) refreshSystemPromptPin() *decision.PromptTemplate {
+	pinned := at.getPinnedPromptTemplate()
+	if pinned == nil || pinned.Source != decision.PromptSourceSystem {
+		return pinned
+	}
+	latest, err := decision.GetPromptTemplate(pinned.Name)
+	if err != nil || latest.Content == pinned.Content {
+		return pinned
+	}
+
+	refreshed := *latest
+	at.settingsMu.Lock()
+	at.pinnedPrompt = &refreshed
+	at.settingsMu.Unlock()
+	log.Printf("🔄 [%s] 系统提示词模板 %s 已更新，本周期起使用新内容", at.name, refreshed.Name)
+	return &refreshed
+}
+
+// getPinnedPromptTemplate 获取固定的提示词模板（未固定时返回 nil，按名称解析最新版本）
+func (at *AutoTrader) getPinnedPromptTemplate() *decision.PromptTemplate {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	return at.pinnedPrompt
+}
@@ -0,0 +1,30 @@
+package trader
+
+import (
+	"testing"
+
+	"nofx/decision"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinPromptTemplate_KeepsVersionUntilRestart(t *testing.T) {
+	decision.SetUserPromptTemplate("pin-user", "pin_strategy", "v1 {{account_state}} {{market_data}}", 1)
+	t.Cleanup(func() { decision.RemoveUserPromptTemplate("pin-user", "pin_strategy") })
+
+	at := &AutoTrader{name: "pin", userID: "pin-user", systemPromptTemplate: "pin_strategy"}
+	pinned := at.PinPromptTemplate()
+	require.NotNil(t, pinned)
+	assert.Equal(t, 1, pinned.Version)
+
+	// 运行期间保存新版本不影响已固定的版本
+	decision.SetUserPromptTemplate("pin-user", "pin_strategy", "v2 {{account_state}} {{market_data}}", 2)
+	assert.Equal(t, 1, at.getPinnedPromptTemplate().Version)
+	assert.Contains(t, at.getPinnedPromptTemplate().Content, "v1")
+	// 系统模板热加载只更新系统模板的固定，用户模板仍按版本固定
+	assert.Equal(t, 1, at.refreshSystemPromptPin().Version)
+
+	// 重新启动时固定最新版本
+	assert.Equal(t, 2, at.PinPromptTemplate().Version)
+}
@@ -0,0 +1,323 @@
+package trader
+
+import (
+	"log"
+	"nofx/market"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 止盈止损触发单类型
+const (
+	ProtectiveStopLoss   = "stop_loss"
+	ProtectiveTakeProfit = "take_profit"
+)
+
+// protectionCheckInterval 软件止盈止损（交易所下单失败时的兜底）检查标记价格的间隔
+const protectionCheckInterval = 10 * time.Second
+
+// protectionMarkPrice 软件止盈止损读取的最新价格（来自交易员行情来源的实时缓存，测试中可替换）
+var protectionMarkPrice = func(source market.Source, symbol string) (float64, error) {
+	data, err := market.GetFrom(source, symbol)
+	if err != nil {
+		return 0, err
+	}
+	return data.CurrentPrice, nil
+}
+
+// ProtectiveOrderTrader 支持按订单ID管理止盈止损触发单的交易器
+// 未实现此接口的交易器（Bybit 持仓级止盈止损、模拟盘）回退到 SetStopLoss/SetTakeProfit，调整时按类型撤单
+type ProtectiveOrderTrader interface {
+	// PlaceProtectiveOrder 下只减仓的触发市价单（kind 为 ProtectiveStopLoss/ProtectiveTakeProfit），返回交易所订单ID
+	PlaceProtectiveOrder(symbol, positionSide, kind string, quantity, triggerPrice float64) (string, error)
+	// CancelOrder 按订单ID撤单
+	CancelOrder(symbol, orderID string) error
+}
+
+// protectiveKindName 触发单类型的中文名称（用于日志和错误信息）
+func protectiveKindName(kind string) string {
+	if kind == ProtectiveTakeProfit {
+		return "止盈"
+	}
+	return "止损"
+}
+
+// ProtectiveLeg 单个止损或止盈的跟踪状态
+type ProtectiveLeg struct {
+	Price    float64 `json:"price"`
+	OrderID  string  `json:"order_id,omitempty"` // 交易所订单ID（交易器不支持时为空）
+	Software bool    `json:"software"`           // 交易所下单失败，由软件监控价格触发
+}
+
+// ProtectiveOrders 单个持仓的止盈止损跟踪状态
+type ProtectiveOrders struct {
+	Symbol     string         `json:"symbol"`
+	Side       string         `json:"side"` // long/short
+	Quantity   float64        `json:"quantity"`
+	StopLoss   *ProtectiveLeg `json:"stop_loss,omitempty"`
+	TakeProfit *ProtectiveLeg `json:"take_profit,omitempty"`
+}
+
+// leg 按类型取出止损或止盈的跟踪状态
+func (p *ProtectiveOrders) leg(kind string) **ProtectiveLeg {
+	if kind == ProtectiveTakeProfit {
+		return &p.TakeProfit
+	}
+	return &p.StopLoss
+}
+
+// SetDefaultProtection 修改默认止损/止盈百分比（相对开仓价，<=0 表示不使用默认值），从下一次开仓开始生效
+func (at *AutoTrader) SetDefaultProtection(stopLossPct, takeProfitPct float64) {
+	at.settingsMu.Lock()
+	defer at.settingsMu.Unlock()
+	at.config.DefaultStopLossPct = stopLossPct
+	at.config.DefaultTakeProfitPct = takeProfitPct
+}
+
+// resolveProtectionLevels 确定开仓后的止损/止盈价格
+// AI 未给出或给出的价格已在成交价错误一侧（触发即成交）时，使用交易员配置的默认百分比
+func (at *AutoTrader) resolveProtectionLevels(positionSide string, entryPrice, stopLoss, takeProfit float64) (float64, float64) {
+	at.settingsMu.RLock()
+	stopLossPct, takeProfitPct := at.config.DefaultStopLossPct, at.config.DefaultTakeProfitPct
+	at.settingsMu.RUnlock()
+
+	long := positionSide == "LONG"
+	validStopLoss := stopLoss > 0 && (long && stopLoss < entryPrice || !long && stopLoss > entryPrice)
+	validTakeProfit := takeProfit > 0 && (long && takeProfit > entryPrice || !long && takeProfit < entryPrice)
+
+	if !validStopLoss && stopLossPct > 0 && entryPrice > 0 {
+		if long {
+			stopLoss = entryPrice * (1 - stopLossPct/100)
+		} else {
+			stopLoss = entryPrice * (1 + stopLossPct/100)
+		}
+		log.Printf("  🛡️ 使用默认止损 %.2f%% → %.4f", stopLossPct, stopLoss)
+	}
+	if !validTakeProfit && takeProfitPct > 0 && entryPrice > 0 {
+		if long {
+			takeProfit = entryPrice * (1 + takeProfitPct/100)
+		} else {
+			takeProfit = entryPrice * (1 - takeProfitPct/100)
+		}
+		log.Printf("  🛡️ 使用默认止盈 %.2f%% → %.4f", takeProfitPct, takeProfit)
+	}
+	return stopLoss, takeProfit
+}
+
+// attachProtectiveOrders 开仓成交后立即挂出止损/止盈触发单并记录订单ID
+func (at *AutoTrader) attachProtectiveOrders(symbol, positionSide string, quantity, entryPrice, stopLoss, takeProfit float64) {
+	stopLoss, takeProfit = at.resolveProtectionLevels(positionSide, entryPrice, stopLoss, takeProfit)
+
+	side := strings.ToLower(positionSide)
+	at.protectionMu.Lock()
+	if at.protection == nil {
+		at.protection = make(map[string]*ProtectiveOrders)
+	}
+	at.protection[symbol+"_"+side] = &ProtectiveOrders{Symbol: symbol, Side: side, Quantity: quantity}
+	at.protectionMu.Unlock()
+
+	if stopLoss > 0 {
+		if err := at.placeProtectiveLeg(symbol, positionSide, ProtectiveStopLoss, quantity, stopLoss); err != nil {
+			log.Printf("  ⚠ 设置止损失败: %v", err)
+		}
+	}
+	if takeProfit > 0 {
+		if err := at.placeProtectiveLeg(symbol, positionSide, ProtectiveTakeProfit, quantity, takeProfit); err != nil {
+			log.Printf("  ⚠ 设置止盈失败: %v", err)
+		}
+	}
+}
+
+// placeProtectiveLeg 在交易所挂出止损或止盈单并更新跟踪状态；下单失败时改由软件监控价格触发（返回原始错误）
+func (at *AutoTrader) placeProtectiveLeg(symbol, positionSide, kind string, quantity, price float64) error {
+	var orderID string
+	var err error
+	if placer, ok := at.trader.(ProtectiveOrderTrader); ok {
+		orderID, err = placer.PlaceProtectiveOrder(symbol, positionSide, kind, quantity, price)
+	} else if kind == ProtectiveTakeProfit {
+		err = at.trader.SetTakeProfit(symbol, positionSide, quantity, price)
+	} else {
+		err = at.trader.SetStopLoss(symbol, positionSide, quantity, price)
+	}
+
+	leg := &ProtectiveLeg{Price: price, OrderID: orderID, Software: err != nil}
+	side := strings.ToLower(positionSide)
+	at.protectionMu.Lock()
+	if at.protection == nil {
+		at.protection = make(map[string]*ProtectiveOrders)
+	}
+	orders, ok := at.protection[symbol+"_"+side]
+	if !ok {
+		orders = &ProtectiveOrders{Symbol: symbol, Side: side}
+		at.protection[symbol+"_"+side] = orders
+	}
+	orders.Quantity = quantity
+	*orders.leg(kind) = leg
+	at.protectionMu.Unlock()
+
+	if err != nil {
+		log.Printf("  🛡️ %s %s %s单下单失败，改由软件监控价格 %.4f 触发平仓", symbol, side, protectiveKindName(kind), price)
+	}
+	return err
+}
+
+// replaceProtectiveLeg 撤掉旧的止损或止盈单并挂出新价格（AI 调整止盈止损、部分平仓后恢复保护时使用）
+func (at *AutoTrader) replaceProtectiveLeg(symbol, positionSide, kind string, quantity, price float64) error {
+	side := strings.ToLower(positionSide)
+	oldOrderID := ""
+	at.protectionMu.Lock()
+	if orders, ok := at.protection[symbol+"_"+side]; ok {
+		if leg := *orders.leg(kind); leg != nil {
+			oldOrderID = leg.OrderID
+		}
+	}
+	at.protectionMu.Unlock()
+
+	// 有订单ID时只撤这一单；否则按类型撤单（双向持仓时会影响两个方向）
+	canceled := false
+	if canceler, ok := at.trader.(ProtectiveOrderTrader); ok && oldOrderID != "" {
+		if err := canceler.CancelOrder(symbol, oldOrderID); err != nil {
+			log.Printf("  ⚠ 按订单ID取消旧%s单失败（可能已触发或被交易所撤销）: %v", protectiveKindName(kind), err)
+		} else {
+			canceled = true
+		}
+	}
+	if !canceled {
+		var err error
+		if kind == ProtectiveTakeProfit {
+			err = at.trader.CancelTakeProfitOrders(symbol)
+		} else {
+			err = at.trader.CancelStopLossOrders(symbol)
+		}
+		if err != nil {
+			log.Printf("  ⚠ 取消旧%s单失败: %v", protectiveKindName(kind), err)
+		}
+	}
+
+	return at.placeProtectiveLeg(symbol, positionSide, kind, quantity, price)
+}
+
+// protectionLevels 返回持仓当前跟踪的止损和止盈价格（未跟踪时为0）
+func (at *AutoTrader) protectionLevels(symbol, side string) (float64, float64) {
+	at.protectionMu.Lock()
+	defer at.protectionMu.Unlock()
+
+	orders, ok := at.protection[symbol+"_"+strings.ToLower(side)]
+	if !ok {
+		return 0, 0
+	}
+	var stopLoss, takeProfit float64
+	if orders.StopLoss != nil {
+		stopLoss = orders.StopLoss.Price
+	}
+	if orders.TakeProfit != nil {
+		takeProfit = orders.TakeProfit.Price
+	}
+	return stopLoss, takeProfit
+}
+
+// clearProtectiveOrders 持仓平掉后撤销跟踪的止盈止损单并清除状态
+func (at *AutoTrader) clearProtectiveOrders(symbol, side string) {
+	key := symbol + "_" + strings.ToLower(side)
+	at.protectionMu.Lock()
+	orders, ok := at.protection[key]
+	delete(at.protection, key)
+	at.protectionMu.Unlock()
+	if !ok {
+		return
+	}
+
+	canceler, ok := at.trader.(ProtectiveOrderTrader)
+	if !ok {
+		return
+	}
+	for _, leg := range []*ProtectiveLeg{orders.StopLoss, orders.TakeProfit} {
+		if leg == nil || leg.OrderID == "" {
+			continue
+		}
+		// 平仓后触发单通常已被交易所撤销，失败只记录
+		if err := canceler.CancelOrder(symbol, leg.OrderID); err != nil {
+			log.Printf("  ℹ 撤销 %s 止盈止损单 %s: %v", symbol, leg.OrderID, err)
+		}
+	}
+}
+
+// GetProtectiveOrders 当前跟踪的止盈止损单（按 symbol_side 排序）
+func (at *AutoTrader) GetProtectiveOrders() []ProtectiveOrders {
+	at.protectionMu.Lock()
+	defer at.protectionMu.Unlock()
+
+	result := make([]ProtectiveOrders, 0, len(at.protection))
+	for _, orders := range at.protection {
+		result = append(result, *orders)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Symbol+result[i].Side < result[j].Symbol+result[j].Side
+	})
+	return result
+}
+
+// startProtectionMonitor 启动软件止盈止损监控（仅在交易所触发单下单失败的持仓上生效）
+func (at *AutoTrader) startProtectionMonitor() {
+	at.monitorWg.Add(1)
+	go func() {
+		defer at.monitorWg.Done()
+
+		ticker := time.NewTicker(protectionCheckInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				at.checkSoftwareProtection()
+			case <-at.stopMonitorCh:
+				return
+			}
+		}
+	}()
+}
+
+// checkSoftwareProtection 检查软件止盈止损是否触发，触发时市价平仓
+func (at *AutoTrader) checkSoftwareProtection() {
+	type trigger struct {
+		symbol, side, kind string
+		price              float64
+	}
+
+	at.protectionMu.Lock()
+	var pending []ProtectiveOrders
+	for _, orders := range at.protection {
+		if (orders.StopLoss != nil && orders.StopLoss.Software) || (orders.TakeProfit != nil && orders.TakeProfit.Software) {
+			pending = append(pending, *orders)
+		}
+	}
+	at.protectionMu.Unlock()
+
+	for _, orders := range pending {
+		price, err := protectionMarkPrice(at.marketSource, orders.Symbol)
+		if err != nil || price <= 0 {
+			log.Printf("⚠️ 软件止盈止损：获取 %s 价格失败: %v", orders.Symbol, err)
+			continue
+		}
+
+		var hit *trigger
+		long := orders.Side == "long"
+		if leg := orders.StopLoss; leg != nil && leg.Software && (long && price <= leg.Price || !long && price >= leg.Price) {
+			hit = &trigger{orders.Symbol, orders.Side, ProtectiveStopLoss, leg.Price}
+		} else if leg := orders.TakeProfit; leg != nil && leg.Software && (long && price >= leg.Price || !long && price <= leg.Price) {
+			hit = &trigger{orders.Symbol, orders.Side, ProtectiveTakeProfit, leg.Price}
+		}
+		if hit == nil {
+			continue
+		}
+
+		log.Printf("🛡️ [%s] 软件%s触发: %s %s 当前价 %.4f 触发价 %.4f，市价平仓",
+			at.name, protectiveKindName(hit.kind), hit.symbol, hit.side, price, hit.price)
+		if err := at.emergencyClosePosition(hit.symbol, hit.side); err != nil {
+			log.Printf("❌ 软件%s平仓失败 (%s %s): %v", protectiveKindName(hit.kind), hit.symbol, hit.side, err)
+			continue
+		}
+		at.ClearPeakPnLCache(hit.symbol, hit.side)
+	}
+}
@@ -0,0 +1,115 @@
+package trader
+
+import (
+	"errors"
+	"fmt"
+	"nofx/market"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProtectiveTrader 记录触发单下单/撤单的 MockTrader
+type fakeProtectiveTrader struct {
+	MockTrader
+	nextID    int
+	placed    map[string]string // orderID -> kind@price
+	canceled  []string
+	failPlace bool
+}
+
+func (f *fakeProtectiveTrader) PlaceProtectiveOrder(symbol, positionSide, kind string, quantity, triggerPrice float64) (string, error) {
+	if f.failPlace {
+		return "", errors.New("trigger orders not supported")
+	}
+	if f.placed == nil {
+		f.placed = make(map[string]string)
+	}
+	f.nextID++
+	id := fmt.Sprintf("%d", f.nextID)
+	f.placed[id] = fmt.Sprintf("%s@%g", kind, triggerPrice)
+	return id, nil
+}
+
+func (f *fakeProtectiveTrader) CancelOrder(symbol, orderID string) error {
+	f.canceled = append(f.canceled, orderID)
+	return nil
+}
+
+var (
+	_ ProtectiveOrderTrader = (*FuturesTrader)(nil)
+	_ ProtectiveOrderTrader = (*AsterTrader)(nil)
+	_ ProtectiveOrderTrader = (*HyperliquidTrader)(nil)
+)
+
+// TestResolveProtectionLevels 测试AI未给出或给出无效止盈止损时使用默认百分比
+func TestResolveProtectionLevels(t *testing.T) {
+	at := &AutoTrader{config: AutoTraderConfig{DefaultStopLossPct: 2, DefaultTakeProfitPct: 5}}
+
+	sl, tp := at.resolveProtectionLevels("LONG", 100, 95, 110)
+	assert.Equal(t, 95.0, sl, "AI给出的有效价格优先")
+	assert.Equal(t, 110.0, tp)
+
+	sl, tp = at.resolveProtectionLevels("LONG", 100, 0, 99)
+	assert.InDelta(t, 98, sl, 1e-9)
+	assert.InDelta(t, 105, tp, 1e-9, "止盈低于成交价时改用默认值")
+
+	sl, tp = at.resolveProtectionLevels("SHORT", 100, 99, 0)
+	assert.InDelta(t, 102, sl, 1e-9, "空单止损低于成交价时改用默认值")
+	assert.InDelta(t, 95, tp, 1e-9)
+
+	at.SetDefaultProtection(0, 0)
+	sl, tp = at.resolveProtectionLevels("LONG", 100, 0, 0)
+	assert.Zero(t, sl, "未配置默认值时不设置")
+	assert.Zero(t, tp)
+}
+
+// TestProtectiveOrdersLifecycle 测试开仓挂单、调整时按订单ID撤换、平仓后撤销
+func TestProtectiveOrdersLifecycle(t *testing.T) {
+	fake := &fakeProtectiveTrader{}
+	at := &AutoTrader{trader: fake}
+
+	at.attachProtectiveOrders("BTCUSDT", "LONG", 0.1, 100, 95, 110)
+	orders := at.GetProtectiveOrders()
+	require.Len(t, orders, 1)
+	assert.Equal(t, "long", orders[0].Side)
+	assert.Equal(t, "1", orders[0].StopLoss.OrderID)
+	assert.Equal(t, "2", orders[0].TakeProfit.OrderID)
+	assert.Equal(t, "stop_loss@95", fake.placed["1"])
+
+	require.NoError(t, at.replaceProtectiveLeg("BTCUSDT", "LONG", ProtectiveStopLoss, 0.05, 97))
+	assert.Equal(t, []string{"1"}, fake.canceled, "只撤掉旧止损单")
+	sl, tp := at.protectionLevels("BTCUSDT", "LONG")
+	assert.Equal(t, 97.0, sl)
+	assert.Equal(t, 110.0, tp)
+	assert.Equal(t, 0.05, at.GetProtectiveOrders()[0].Quantity)
+
+	at.clearProtectiveOrders("BTCUSDT", "long")
+	assert.Equal(t, []string{"1", "3", "2"}, fake.canceled)
+	assert.Empty(t, at.GetProtectiveOrders())
+}
+
+// TestSoftwareProtectionFallback 测试交易所下单失败时由软件监控价格触发平仓
+func TestSoftwareProtectionFallback(t *testing.T) {
+	fake := &fakeProtectiveTrader{failPlace: true}
+	at := &AutoTrader{trader: fake, peakPnLCache: make(map[string]float64)}
+
+	at.attachProtectiveOrders("ETHUSDT", "SHORT", 1, 2000, 2100, 1800)
+	orders := at.GetProtectiveOrders()
+	require.Len(t, orders, 1)
+	assert.True(t, orders[0].StopLoss.Software)
+	assert.True(t, orders[0].TakeProfit.Software)
+
+	price := 2050.0
+	original := protectionMarkPrice
+	protectionMarkPrice = func(source market.Source, symbol string) (float64, error) { return price, nil }
+	defer func() { protectionMarkPrice = original }()
+
+	at.checkSoftwareProtection()
+	assert.Len(t, at.GetProtectiveOrders(), 1, "未到触发价不平仓")
+
+	price = 2101
+	at.checkSoftwareProtection()
+	assert.Empty(t, at.GetProtectiveOrders(), "空单价格涨破止损后平仓并清除跟踪")
+}
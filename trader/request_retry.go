@@ -0,0 +1,306 @@
+package trader
+
+import (
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"nofx/metrics"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+)
+
+// retryReason 可重试的交易所错误类型
+type retryReason string
+
+const (
+	retryNone        retryReason = ""
+	retryTimestamp   retryReason = "timestamp"    // 签名时间戳超出 recvWindow（-1021）
+	retryRateLimit   retryReason = "rate_limit"   // 请求频率超限（-1003，HTTP 429/418）
+	retryServerError retryReason = "server_error" // 交易所内部错误或网关 5xx
+	retryNetwork     retryReason = "network"      // 连接重置、超时等网络错误
+)
+
+// RetryStats 交易所请求的重试计数（用于观察交易所连接的稳定性）
+type RetryStats struct {
+	Retries          int64 `json:"retries"`           // 总重试次数
+	TimestampResyncs int64 `json:"timestamp_resyncs"` // 时间戳错误后重新同步时钟的次数
+	RateLimited      int64 `json:"rate_limited"`      // 遇到限频的次数
+	ServerErrors     int64 `json:"server_errors"`     // 遇到交易所 5xx/内部错误的次数
+	NetworkErrors    int64 `json:"network_errors"`    // 遇到网络错误的次数
+	GaveUp           int64 `json:"gave_up"`           // 重试后仍失败（或不可安全重试）的次数
+}
+
+// RetryStatsReporter 可选接口：支持上报请求重试计数的交易器
+type RetryStatsReporter interface {
+	RetryStats() RetryStats
+}
+
+// requestRetrier 交易所请求重试器：时间戳错误重新同步时钟，其他临时错误按上限退避重试
+type requestRetrier struct {
+	exchange      string
+	maxRetries    int           // 临时错误的最大重试次数
+	baseDelay     time.Duration // 首次退避时间，之后每次翻倍
+	maxDelay      time.Duration // 单次退避上限
+	maxRetryAfter time.Duration // 交易所要求等待超过该时间时直接放弃，避免阻塞交易周期
+	sleep         func(time.Duration)
+	now           func() time.Time
+
+	mu              sync.Mutex
+	retryAfterUntil time.Time // 交易所 Retry-After 要求的最早重试时间
+
+	retries          atomic.Int64
+	timestampResyncs atomic.Int64
+	rateLimited      atomic.Int64
+	serverErrors     atomic.Int64
+	networkErrors    atomic.Int64
+	gaveUp           atomic.Int64
+}
+
+// newRequestRetrier 创建请求重试器（默认最多重试3次，退避 0.5s/1s/2s，单次不超过8秒）
+func newRequestRetrier(exchange string) *requestRetrier {
+	return &requestRetrier{
+		exchange:      exchange,
+		maxRetries:    3,
+		baseDelay:     500 * time.Millisecond,
+		maxDelay:      8 * time.Second,
+		maxRetryAfter: 30 * time.Second,
+		sleep:         time.Sleep,
+		now:           time.Now,
+	}
+}
+
+// stats 获取重试计数快照
+func (r *requestRetrier) stats() RetryStats {
+	return RetryStats{
+		Retries:          r.retries.Load(),
+		TimestampResyncs: r.timestampResyncs.Load(),
+		RateLimited:      r.rateLimited.Load(),
+		ServerErrors:     r.serverErrors.Load(),
+		NetworkErrors:    r.networkErrors.Load(),
+		GaveUp:           r.gaveUp.Load(),
+	}
+}
+
+// record 记录一次遇到的临时错误
+func (r *requestRetrier) record(reason retryReason) {
+	switch reason {
+	case retryTimestamp:
+		r.timestampResyncs.Add(1)
+	case retryRateLimit:
+		r.rateLimited.Add(1)
+	case retryServerError:
+		r.serverErrors.Add(1)
+	case retryNetwork:
+		r.networkErrors.Add(1)
+	}
+}
+
+// giveUp 记录放弃重试
+func (r *requestRetrier) giveUp(reason retryReason, err error) {
+	r.gaveUp.Add(1)
+	metrics.ExchangeRetries.Inc(r.exchange, "gave_up")
+	log.Printf("⚠️ %s请求失败，放弃重试 (%s): %v", r.exchange, reason, err)
+}
+
+// noteRetryAfter 记录交易所 Retry-After 要求的等待时间
+func (r *requestRetrier) noteRetryAfter(wait time.Duration) {
+	until := r.now().Add(wait)
+	r.mu.Lock()
+	if until.After(r.retryAfterUntil) {
+		r.retryAfterUntil = until
+	}
+	r.mu.Unlock()
+}
+
+// backoff 计算第 attempt 次重试的等待时间（限频时不少于 Retry-After 要求）
+func (r *requestRetrier) backoff(attempt int, reason retryReason) time.Duration {
+	delay := time.Duration(float64(r.baseDelay) * math.Pow(2, float64(attempt-1)))
+	if delay > r.maxDelay {
+		delay = r.maxDelay
+	}
+	if reason == retryRateLimit {
+		r.mu.Lock()
+		wait := r.retryAfterUntil.Sub(r.now())
+		r.mu.Unlock()
+		if wait > delay {
+			delay = wait
+		}
+	}
+	return delay
+}
+
+// withRetry 执行幂等请求（查询、撤单、设置杠杆等），所有临时错误都可重试
+func withRetry[T any](r *requestRetrier, clock *serverClock, fn func() (T, error)) (T, error) {
+	return retryRequest(r, clock, true, fn)
+}
+
+// withRetryErr 同 withRetry，用于只返回 error 的请求
+func withRetryErr(r *requestRetrier, clock *serverClock, fn func() error) error {
+	_, err := withRetry(r, clock, func() (struct{}, error) {
+		return struct{}{}, fn()
+	})
+	return err
+}
+
+// withOrderRetry 执行下单请求，只重试确定未被交易所执行的错误（时间戳、限频、连接未建立），
+// 交易所内部错误或连接中断时订单状态未知，重试可能重复下单
+func withOrderRetry[T any](r *requestRetrier, clock *serverClock, fn func() (T, error)) (T, error) {
+	return retryRequest(r, clock, false, fn)
+}
+
+// retryRequest 按错误类型重新同步时钟或退避重试（r 为 nil 时只处理时间戳错误）
+func retryRequest[T any](r *requestRetrier, clock *serverClock, idempotent bool, fn func() (T, error)) (T, error) {
+	if r == nil {
+		return withTimestampResync(clock, fn)
+	}
+
+	result, err := fn()
+	timestampRetries := 0
+	for attempt := 1; ; attempt++ {
+		reason, rejected := classifyRetry(err)
+		if reason == retryNone {
+			return result, err
+		}
+		r.record(reason)
+
+		if !idempotent && !rejected {
+			r.giveUp(reason, err)
+			return result, err
+		}
+		if attempt > r.maxRetries {
+			r.giveUp(reason, err)
+			return result, err
+		}
+
+		if reason == retryTimestamp {
+			timestampRetries++
+			if clock == nil || timestampRetries > maxTimestampResyncRetries {
+				r.giveUp(reason, err)
+				return result, err
+			}
+			log.Printf("⏱ 检测到时间戳错误，重新同步服务器时间后重试: %v", err)
+			if syncErr := clock.sync(); syncErr != nil {
+				r.giveUp(reason, err)
+				return result, err
+			}
+		} else {
+			delay := r.backoff(attempt, reason)
+			if delay > r.maxRetryAfter {
+				r.giveUp(reason, err)
+				return result, err
+			}
+			log.Printf("🔁 %s请求遇到临时错误 (%s)，%v 后重试 (%d/%d): %v", r.exchange, reason, delay, attempt, r.maxRetries, err)
+			r.sleep(delay)
+		}
+
+		r.retries.Add(1)
+		metrics.ExchangeRetries.Inc(r.exchange, string(reason))
+		result, err = fn()
+	}
+}
+
+// classifyRetry 判断错误是否可重试；rejected 表示交易所确定未处理该请求（下单也可安全重试）
+func classifyRetry(err error) (reason retryReason, rejected bool) {
+	if err == nil {
+		return retryNone, false
+	}
+	if isTimestampError(err) {
+		return retryTimestamp, true
+	}
+
+	var apiErr *common.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case -1003, -1015: // 请求过多 / 新订单过多
+			return retryRateLimit, true
+		case -1000, -1001, -1006, -1007, -1008: // 未知错误 / 内部错误 / 响应异常 / 超时 / 服务器繁忙
+			return retryServerError, false
+		}
+		if !apiErr.IsValid() {
+			// 非 JSON 响应体，一般来自网关（502/503/504）
+			return retryServerError, false
+		}
+		return retryNone, false
+	}
+
+	msg := err.Error()
+	if strings.Contains(msg, "code=-1003") || strings.Contains(msg, "Too many requests") {
+		return retryRateLimit, true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return retryNetwork, true // 连接未建立，请求未发出
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return retryNetwork, true
+	}
+	var netErr net.Error
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) ||
+		strings.Contains(msg, "connection reset by peer") {
+		return retryNetwork, false
+	}
+	return retryNone, false
+}
+
+// retryAfterTransport 记录限频响应中的 Retry-After 头，供重试器等待
+type retryAfterTransport struct {
+	base    http.RoundTripper
+	retrier *requestRetrier
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *retryAfterTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusTeapot, http.StatusServiceUnavailable:
+			if wait, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.retrier.now()); ok {
+				t.retrier.noteRetryAfter(wait)
+			}
+		}
+	}
+	return resp, err
+}
+
+// withRetryAfter 返回记录 Retry-After 的 HTTP 客户端（复制原客户端，不修改共享的 http.DefaultClient）
+func withRetryAfter(client *http.Client, retrier *requestRetrier) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	wrapped := *client
+	wrapped.Transport = &retryAfterTransport{base: base, retrier: retrier}
+	return &wrapped
+}
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期）
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
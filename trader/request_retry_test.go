@@ -0,0 +1,173 @@
+package trader
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/adshao/go-binance/v2/common"
+	"github.com/adshao/go-binance/v2/futures"
+	"github.com/stretchr/testify/assert"
+)
+
+// newRetryTestTrader 创建连接模拟服务器的币安交易器，重试等待只记录不真正休眠
+func newRetryTestTrader(server *httptest.Server, delays *[]time.Duration) *FuturesTrader {
+	retrier := newRequestRetrier("binance")
+	retrier.sleep = func(d time.Duration) { *delays = append(*delays, d) }
+
+	client := futures.NewClient("test_api_key", "test_secret_key")
+	client.BaseURL = server.URL
+	client.HTTPClient = withRetryAfter(server.Client(), retrier)
+	return &FuturesTrader{
+		client:        client,
+		cacheDuration: 15 * time.Second,
+		clock:         newBinanceServerClock(client),
+		retrier:       retrier,
+	}
+}
+
+// TestClassifyRetry 测试临时错误分类，以及下单是否可安全重试
+func TestClassifyRetry(t *testing.T) {
+	tests := []struct {
+		err      error
+		reason   retryReason
+		rejected bool
+	}{
+		{nil, retryNone, false},
+		{&common.APIError{Code: -1021, Message: "Timestamp for this request is outside of the recvWindow."}, retryTimestamp, true},
+		{&common.APIError{Code: -1003, Message: "Too many requests."}, retryRateLimit, true},
+		{fmt.Errorf("开多仓失败: %w", &common.APIError{Code: -1007, Message: "Timeout waiting for response from backend server."}), retryServerError, false},
+		{&common.APIError{Response: []byte("<html>502 Bad Gateway</html>")}, retryServerError, false},
+		{&common.APIError{Code: -2019, Message: "Margin is insufficient."}, retryNone, false},
+		{fmt.Errorf("Get \"https://fapi.binance.com\": %w", syscall.ECONNRESET), retryNetwork, false},
+		{fmt.Errorf("Get \"https://fapi.binance.com\": %w", syscall.ECONNREFUSED), retryNetwork, true},
+		{io.ErrUnexpectedEOF, retryNetwork, false},
+		{errors.New("精度错误"), retryNone, false},
+	}
+	for _, tt := range tests {
+		reason, rejected := classifyRetry(tt.err)
+		assert.Equal(t, tt.reason, reason, "%v", tt.err)
+		assert.Equal(t, tt.rejected, rejected, "%v", tt.err)
+	}
+}
+
+// TestParseRetryAfter 测试解析秒数和 HTTP 日期两种格式
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	wait, ok := parseRetryAfter("3", now)
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, wait)
+
+	wait, ok = parseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Second, wait)
+
+	_, ok = parseRetryAfter("", now)
+	assert.False(t, ok)
+}
+
+// TestFuturesTrader_RetryServerError 网关 5xx 时查询请求退避重试，并记录计数
+func TestFuturesTrader_RetryServerError(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte("<html>502 Bad Gateway</html>"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"totalWalletBalance":    "1000.00",
+			"availableBalance":      "800.00",
+			"totalUnrealizedProfit": "0",
+		})
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	trader := newRetryTestTrader(server, &delays)
+
+	balance, err := trader.GetBalanceInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, 1000.0, balance.WalletBalance)
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, delays, "指数退避")
+
+	stats := trader.RetryStats()
+	assert.Equal(t, int64(2), stats.Retries)
+	assert.Equal(t, int64(2), stats.ServerErrors)
+	assert.Zero(t, stats.GaveUp)
+}
+
+// TestFuturesTrader_RetryAfterRateLimit 限频时按 Retry-After 等待；要求等待过久时放弃
+func TestFuturesTrader_RetryAfterRateLimit(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// 第1次要求等待3秒，第2次成功，之后要求等待2分钟
+		if n := atomic.AddInt32(&calls, 1); n != 2 {
+			retryAfter := "3"
+			if n > 2 {
+				retryAfter = "120"
+			}
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(map[string]interface{}{"code": -1003, "msg": "Too many requests."})
+			return
+		}
+		json.NewEncoder(w).Encode([]map[string]interface{}{})
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	trader := newRetryTestTrader(server, &delays)
+
+	_, err := trader.GetPositionList()
+	assert.NoError(t, err)
+	if assert.Len(t, delays, 1) {
+		assert.InDelta(t, 3*time.Second, delays[0], float64(100*time.Millisecond), "等待时间不少于 Retry-After")
+	}
+
+	_, err = trader.GetPositionList()
+	assert.Error(t, err)
+	assert.Len(t, delays, 1, "超过等待上限时直接放弃")
+	assert.Equal(t, int64(2), trader.RetryStats().RateLimited)
+	assert.Equal(t, int64(1), trader.RetryStats().GaveUp)
+}
+
+// TestFuturesTrader_OrderNotRetriedOnUnknownStatus 下单遇到交易所超时（状态未知）时不重试，避免重复下单
+func TestFuturesTrader_OrderNotRetriedOnUnknownStatus(t *testing.T) {
+	var orders int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/fapi/v1/order" {
+			atomic.AddInt32(&orders, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"code": -1007,
+				"msg":  "Timeout waiting for response from backend server. Send status unknown; execution status unknown.",
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{})
+	}))
+	defer server.Close()
+
+	var delays []time.Duration
+	trader := newRetryTestTrader(server, &delays)
+
+	_, err := trader.placeTriggerOrder("BTCUSDT", "LONG", futures.OrderTypeStopMarket, 0.01, 45000)
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&orders))
+	assert.Empty(t, delays)
+	assert.Equal(t, int64(1), trader.RetryStats().GaveUp)
+}
+
+var _ RetryStatsReporter = (*FuturesTrader)(nil)
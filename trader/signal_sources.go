@@ -0,0 +1,73 @@
+package trader
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"nofx/decision"
+	"nofx/logger"
+	"nofx/signalsource"
+)
+
+// signalSourceCandidates 从交易员启用的信号源（币种池/OI Top）获取候选币种，返回合并后的币种和获取失败的原因
+//
+// 信号源失败但有缓存时继续使用缓存数据（同时记录错误）；未启用信号源时返回空。
+func (at *AutoTrader) signalSourceCandidates() ([]decision.CandidateCoin, []string) {
+	sources := []struct {
+		name string // 候选币种来源标记（与提示词中的来源说明一致）
+		url  string
+	}{
+		{"ai500", at.config.CoinPoolAPIURL},
+		{"oi_top", at.config.OITopAPIURL},
+	}
+
+	var coins []decision.CandidateCoin
+	var errs []string
+	index := make(map[string]int)
+	for _, src := range sources {
+		if src.url == "" {
+			continue
+		}
+		result, err := signalsource.Default().Fetch(context.Background(), signalsource.Source{
+			URL:     src.url,
+			Headers: at.config.SignalSourceHeaders,
+			Timeout: at.config.SignalSourceTimeout,
+		})
+		if err != nil {
+			msg := fmt.Sprintf("%s 信号源获取失败: %v", src.name, err)
+			if result != nil {
+				msg += "（使用缓存数据）"
+			}
+			log.Printf("⚠️ [%s] %s", at.name, msg)
+			errs = append(errs, msg)
+		}
+		if result == nil {
+			continue
+		}
+		for _, symbol := range result.Symbols {
+			if reason, invalid := at.invalidSymbol(symbol); invalid {
+				log.Printf("⚠️ [%s] 跳过不可交易的币种 %s: %s", at.name, symbol, reason)
+				continue
+			}
+			if i, ok := index[symbol]; ok {
+				coins[i].Sources = append(coins[i].Sources, src.name)
+				continue
+			}
+			index[symbol] = len(coins)
+			coins = append(coins, decision.CandidateCoin{Symbol: symbol, Sources: []string{src.name}})
+		}
+	}
+
+	if len(coins) > 0 {
+		log.Printf("📋 [%s] 使用信号源币种: %d个候选币种", at.name, len(coins))
+	}
+	return coins, errs
+}
+
+// recordSignalSourceErrors 将信号源获取失败的原因写入决策日志
+func recordSignalSourceErrors(record *logger.DecisionRecord, ctx *decision.Context) {
+	record.SignalSourceErrors = ctx.SignalSourceErrors
+	for _, msg := range ctx.SignalSourceErrors {
+		record.ExecutionLog = append(record.ExecutionLog, "📡 "+msg)
+	}
+}
@@ -0,0 +1,54 @@
+package trader
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestSignalSourceCandidates 测试信号源合并（来源标记）、失败时记录错误并回退到交易币种
+func TestSignalSourceCandidates(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/pool":
+			w.Write([]byte(`["BTC", "SOL"]`))
+		case "/oi":
+			w.Write([]byte(`{"symbols": ["SOLUSDT", "XRPUSDT"]}`))
+		default:
+			http.Error(w, "down", http.StatusServiceUnavailable)
+		}
+	}))
+	defer upstream.Close()
+
+	at := &AutoTrader{
+		config:       AutoTraderConfig{CoinPoolAPIURL: upstream.URL + "/pool", OITopAPIURL: upstream.URL + "/oi"},
+		tradingCoins: []string{"ETHUSDT"},
+	}
+	coins, err := at.getCandidateCoins()
+	if err != nil {
+		t.Fatalf("获取候选币种失败: %v", err)
+	}
+	if len(coins) != 3 || coins[0].Symbol != "BTCUSDT" || coins[1].Symbol != "SOLUSDT" || coins[2].Symbol != "XRPUSDT" {
+		t.Fatalf("候选币种应为两个信号源的合并: %+v", coins)
+	}
+	if len(coins[1].Sources) != 2 || coins[1].Sources[1] != "oi_top" {
+		t.Errorf("同时出现在两个信号源的币种应保留两个来源: %+v", coins[1].Sources)
+	}
+	if len(at.signalSourceErrors) != 0 {
+		t.Errorf("不应有信号源错误: %v", at.signalSourceErrors)
+	}
+
+	// 信号源不可用且没有缓存：记录错误并回退到交易币种
+	at.config = AutoTraderConfig{CoinPoolAPIURL: upstream.URL + "/down"}
+	coins, err = at.getCandidateCoins()
+	if err != nil {
+		t.Fatalf("获取候选币种失败: %v", err)
+	}
+	if len(coins) != 1 || coins[0].Symbol != "ETHUSDT" {
+		t.Errorf("信号源失败时应回退到交易币种: %+v", coins)
+	}
+	if len(at.signalSourceErrors) != 1 || !strings.Contains(at.signalSourceErrors[0], "503") {
+		t.Errorf("应记录信号源错误: %v", at.signalSourceErrors)
+	}
+}
@@ -0,0 +1,247 @@
+package trader
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SymbolStatusTrading 交易对可交易状态
+const SymbolStatusTrading = "TRADING"
+
+// symbolFiltersTTL 交易对规则缓存有效期（上下架、规则调整不频繁）
+const symbolFiltersTTL = time.Hour
+
+// SymbolFilter 交易对的下单规则（0 表示交易所未提供该限制）
+type SymbolFilter struct {
+	Symbol      string  `json:"symbol"`
+	Status      string  `json:"status"`       // 交易状态（TRADING 表示可交易）
+	TickSize    float64 `json:"tick_size"`    // 价格步长
+	StepSize    float64 `json:"step_size"`    // 数量步长
+	MinQty      float64 `json:"min_qty"`      // 最小下单数量
+	MinNotional float64 `json:"min_notional"` // 最小名义价值（USDT）
+}
+
+// Tradable 是否可交易
+func (f *SymbolFilter) Tradable() bool {
+	return f.Status == SymbolStatusTrading
+}
+
+// RoundQuantity 数量向下取整到步长（避免超出预算和可用保证金）
+func (f *SymbolFilter) RoundQuantity(quantity float64) float64 {
+	if f.StepSize <= 0 {
+		return quantity
+	}
+	steps := math.Floor(quantity/f.StepSize + 1e-9)
+	return roundToDecimals(steps*f.StepSize, stepDecimals(f.StepSize))
+}
+
+// RoundPrice 价格四舍五入到价格步长
+func (f *SymbolFilter) RoundPrice(price float64) float64 {
+	if f.TickSize <= 0 {
+		return price
+	}
+	return roundToDecimals(math.Round(price/f.TickSize)*f.TickSize, stepDecimals(f.TickSize))
+}
+
+// CheckOrder 检查下单数量和名义价值是否满足交易所最低要求
+func (f *SymbolFilter) CheckOrder(quantity, price float64) error {
+	if notional := quantity * price; f.MinNotional > 0 && notional < f.MinNotional {
+		return fmt.Errorf("%s 下单名义价值 %.2f USDT 低于交易所最小名义价值 %.2f USDT（数量 %g，价格 %g）",
+			f.Symbol, notional, f.MinNotional, quantity, price)
+	}
+	if quantity <= 0 || quantity < f.MinQty {
+		return fmt.Errorf("%s 下单数量 %g 低于交易所最小数量 %g", f.Symbol, quantity, math.Max(f.MinQty, f.StepSize))
+	}
+	return nil
+}
+
+// stepDecimals 步长的小数位数（如 0.001 -> 3）
+func stepDecimals(step float64) int {
+	return calculatePrecision(strconv.FormatFloat(step, 'f', -1, 64))
+}
+
+// roundToDecimals 按小数位数四舍五入，消除浮点误差
+func roundToDecimals(value float64, decimals int) float64 {
+	multiplier := math.Pow10(decimals)
+	return math.Round(value*multiplier) / multiplier
+}
+
+// SymbolFilterProvider 可选接口：能提供交易所全部交易对规则的交易器
+type SymbolFilterProvider interface {
+	GetSymbolFilters() (map[string]SymbolFilter, error)
+}
+
+// SymbolIssue 校验未通过的交易币种
+type SymbolIssue struct {
+	Symbol string `json:"symbol"`
+	Reason string `json:"reason"`
+}
+
+// ValidateSymbols 校验交易币种在交易所存在且可交易（交易器不支持查询交易对规则时不校验）
+func ValidateSymbols(t Trader, symbols []string) ([]SymbolIssue, error) {
+	provider, ok := t.(SymbolFilterProvider)
+	if !ok {
+		return nil, nil
+	}
+	filters, err := provider.GetSymbolFilters()
+	if err != nil {
+		return nil, fmt.Errorf("获取交易对信息失败: %w", err)
+	}
+	return checkSymbols(filters, symbols), nil
+}
+
+// checkSymbols 对照交易对规则检查币种
+func checkSymbols(filters map[string]SymbolFilter, symbols []string) []SymbolIssue {
+	var issues []SymbolIssue
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" {
+			continue
+		}
+		filter, ok := filters[symbol]
+		switch {
+		case !ok:
+			issues = append(issues, SymbolIssue{Symbol: symbol, Reason: "交易所不存在该交易对"})
+		case !filter.Tradable():
+			issues = append(issues, SymbolIssue{Symbol: symbol, Reason: fmt.Sprintf("交易对当前不可交易（状态: %s）", filter.Status)})
+		}
+	}
+	return issues
+}
+
+// symbolFilterCache 交易对规则缓存（过期后重新拉取）
+type symbolFilterCache struct {
+	mu        sync.Mutex
+	filters   map[string]SymbolFilter
+	fetchedAt time.Time
+}
+
+// get 获取缓存的交易对规则，过期或不存在时调用 fetch 刷新（刷新失败时沿用旧缓存）
+func (c *symbolFilterCache) get(fetch func() (map[string]SymbolFilter, error)) (map[string]SymbolFilter, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.filters != nil && time.Since(c.fetchedAt) < symbolFiltersTTL {
+		return c.filters, nil
+	}
+	filters, err := fetch()
+	if err != nil {
+		if c.filters != nil {
+			return c.filters, nil
+		}
+		return nil, err
+	}
+	c.filters = filters
+	c.fetchedAt = time.Now()
+	return filters, nil
+}
+
+// lookup 获取单个交易对的规则
+func (c *symbolFilterCache) lookup(symbol string, fetch func() (map[string]SymbolFilter, error)) (SymbolFilter, bool) {
+	filters, err := c.get(fetch)
+	if err != nil {
+		return SymbolFilter{}, false
+	}
+	filter, ok := filters[symbol]
+	return filter, ok
+}
+
+// parseExchangeFilters 解析币安/Aster exchangeInfo 中的 filters
+func parseExchangeFilters(symbol, status string, rawFilters []map[string]interface{}) SymbolFilter {
+	filter := SymbolFilter{Symbol: symbol, Status: status}
+	parse := func(raw map[string]interface{}, key string) float64 {
+		value, _ := raw[key].(string)
+		f, _ := strconv.ParseFloat(value, 64)
+		return f
+	}
+	for _, raw := range rawFilters {
+		switch raw["filterType"] {
+		case "PRICE_FILTER":
+			filter.TickSize = parse(raw, "tickSize")
+		case "LOT_SIZE":
+			filter.StepSize = parse(raw, "stepSize")
+			filter.MinQty = parse(raw, "minQty")
+		case "MIN_NOTIONAL":
+			filter.MinNotional = parse(raw, "notional")
+			if filter.MinNotional == 0 {
+				filter.MinNotional = parse(raw, "minNotional")
+			}
+		}
+	}
+	return filter
+}
+
+// ValidateSymbols 校验币种在当前交易所存在且可交易
+func (at *AutoTrader) ValidateSymbols(symbols []string) ([]SymbolIssue, error) {
+	return ValidateSymbols(at.trader, symbols)
+}
+
+// checkTradingSymbols 启动时校验自定义交易币种，不存在或已下架的币种不作为候选币种（查询失败时不排除）
+func (at *AutoTrader) checkTradingSymbols() {
+	coins := at.getTradingCoins()
+	if len(coins) == 0 {
+		return
+	}
+	symbols := make([]string, 0, len(coins))
+	for _, coin := range coins {
+		symbols = append(symbols, normalizeSymbol(coin))
+	}
+
+	issues, err := at.ValidateSymbols(symbols)
+	if err != nil {
+		log.Printf("⚠️ [%s] 校验交易币种失败，暂不排除任何币种: %v", at.name, err)
+		return
+	}
+
+	invalid := make(map[string]string, len(issues))
+	for _, issue := range issues {
+		invalid[issue.Symbol] = issue.Reason
+		log.Printf("⚠️ [%s] 交易币种 %s 不可用，将不参与交易: %s", at.name, issue.Symbol, issue.Reason)
+	}
+	at.settingsMu.Lock()
+	at.invalidSymbols = invalid
+	at.settingsMu.Unlock()
+}
+
+// invalidSymbol 币种是否在启动校验中被判定为不可交易
+func (at *AutoTrader) invalidSymbol(symbol string) (string, bool) {
+	at.settingsMu.RLock()
+	defer at.settingsMu.RUnlock()
+	reason, ok := at.invalidSymbols[symbol]
+	return reason, ok
+}
+
+// applySymbolFilter 按交易所规则将开仓数量向下取整到数量步长，并检查最小数量和最小名义价值
+// 交易器不支持或查询失败时原样返回，由交易所做最终校验
+func (at *AutoTrader) applySymbolFilter(symbol string, quantity, price float64) (float64, error) {
+	provider, ok := at.trader.(SymbolFilterProvider)
+	if !ok {
+		return quantity, nil
+	}
+	filters, err := provider.GetSymbolFilters()
+	if err != nil {
+		log.Printf("  ⚠️ 获取 %s 交易规则失败，跳过本地校验: %v", symbol, err)
+		return quantity, nil
+	}
+
+	filter, ok := filters[symbol]
+	if !ok {
+		return 0, fmt.Errorf("❌ %s 在交易所不存在，拒绝开仓", symbol)
+	}
+	if !filter.Tradable() {
+		return 0, fmt.Errorf("❌ %s 当前不可交易（状态: %s），拒绝开仓", symbol, filter.Status)
+	}
+
+	rounded := filter.RoundQuantity(quantity)
+	if err := filter.CheckOrder(rounded, price); err != nil {
+		return 0, fmt.Errorf("❌ 拒绝开仓: %w", err)
+	}
+	if rounded != quantity {
+		log.Printf("  📏 按交易所数量步长 %g 取整: %.8f -> %g", filter.StepSize, quantity, rounded)
+	}
+	return rounded, nil
+}
@@ -0,0 +1,98 @@
+package trader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSymbolFilterTrader 提供交易对规则的 MockTrader
+type fakeSymbolFilterTrader struct {
+	MockTrader
+	filters map[string]SymbolFilter
+}
+
+func (f *fakeSymbolFilterTrader) GetSymbolFilters() (map[string]SymbolFilter, error) {
+	return f.filters, nil
+}
+
+var (
+	_ SymbolFilterProvider = (*FuturesTrader)(nil)
+	_ SymbolFilterProvider = (*AsterTrader)(nil)
+	_ SymbolFilterProvider = (*HyperliquidTrader)(nil)
+)
+
+// TestSymbolFilterRounding 测试数量向下取整到步长、价格取整到价格步长
+func TestSymbolFilterRounding(t *testing.T) {
+	f := SymbolFilter{Symbol: "BTCUSDT", TickSize: 0.1, StepSize: 0.001, MinQty: 0.001, MinNotional: 100}
+
+	assert.Equal(t, 0.012, f.RoundQuantity(0.0129))
+	assert.Equal(t, 0.3, f.RoundQuantity(0.3), "恰好是步长整数倍时不因浮点误差少一步")
+	assert.Equal(t, 50000.2, f.RoundPrice(50000.16))
+
+	assert.NoError(t, f.CheckOrder(0.002, 50000))
+	assert.ErrorContains(t, f.CheckOrder(0.001, 50000), "最小名义价值 100.00 USDT")
+	assert.ErrorContains(t, (&SymbolFilter{StepSize: 0.001}).CheckOrder(0, 50000), "最小数量 0.001")
+}
+
+// TestValidateSymbols 测试不存在和不可交易的币种都会被返回
+func TestValidateSymbols(t *testing.T) {
+	fake := &fakeSymbolFilterTrader{filters: map[string]SymbolFilter{
+		"BTCUSDT":  {Symbol: "BTCUSDT", Status: SymbolStatusTrading},
+		"LUNAUSDT": {Symbol: "LUNAUSDT", Status: "SETTLING"},
+	}}
+
+	issues, err := ValidateSymbols(fake, []string{"btcusdt", "BTCUSDTT", "LUNAUSDT"})
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, "BTCUSDTT", issues[0].Symbol)
+	assert.Contains(t, issues[1].Reason, "SETTLING")
+
+	issues, err = ValidateSymbols(&MockTrader{}, []string{"BTCUSDTT"})
+	assert.NoError(t, err)
+	assert.Empty(t, issues, "不支持查询交易对规则的交易器不校验")
+}
+
+// TestApplySymbolFilter 测试开仓前按规则取整数量，低于最小名义价值时拒绝并给出原因
+func TestApplySymbolFilter(t *testing.T) {
+	fake := &fakeSymbolFilterTrader{filters: map[string]SymbolFilter{
+		"ETHUSDT": {Symbol: "ETHUSDT", Status: SymbolStatusTrading, StepSize: 0.01, MinNotional: 20},
+	}}
+	at := &AutoTrader{trader: fake, tradingCoins: []string{"ETHUSDT", "XYZUSDT"}}
+
+	qty, err := at.applySymbolFilter("ETHUSDT", 0.0567, 3000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.05, qty)
+
+	_, err = at.applySymbolFilter("ETHUSDT", 0.0067, 3000)
+	assert.ErrorContains(t, err, "低于交易所最小名义价值 20.00 USDT")
+
+	_, err = at.applySymbolFilter("XYZUSDT", 1, 100)
+	assert.ErrorContains(t, err, "在交易所不存在")
+
+	at.checkTradingSymbols()
+	_, invalid := at.invalidSymbol("XYZUSDT")
+	assert.True(t, invalid, "启动时校验出不存在的币种")
+	_, invalid = at.invalidSymbol("ETHUSDT")
+	assert.False(t, invalid)
+}
+
+// TestFuturesTrader_SymbolFilters 测试从 exchangeInfo 解析并缓存交易对规则
+func TestFuturesTrader_SymbolFilters(t *testing.T) {
+	suite := NewBinanceFuturesTestSuite(t)
+	defer suite.Cleanup()
+	trader := suite.Trader.(*FuturesTrader)
+
+	filters, err := trader.GetSymbolFilters()
+	require.NoError(t, err)
+	btc := filters["BTCUSDT"]
+	assert.True(t, btc.Tradable())
+	assert.Equal(t, 0.01, btc.TickSize)
+	assert.Equal(t, 0.001, btc.StepSize)
+	assert.Equal(t, 0.001, btc.MinQty)
+
+	issues, err := ValidateSymbols(trader, []string{"BTCUSDT", "BTCUSDTT"})
+	require.NoError(t, err)
+	assert.Equal(t, []SymbolIssue{{Symbol: "BTCUSDTT", Reason: "交易所不存在该交易对"}}, issues)
+}
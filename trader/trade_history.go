@@ -0,0 +1,342 @@
+package trader
+
+import (
+	"log"
+	"math"
+	"nofx/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Trade 标准化的成交记录（各交易所的成交历史统一转换为此结构，字段语义与币安一致）
+type Trade struct {
+	Symbol          string
+	Side            string // BUY/SELL
+	PositionSide    string // LONG/SHORT（单向持仓模式的 BOTH 需先经 assignOneWayPositionSides 转换）
+	Price           float64
+	Qty             float64
+	RealizedPnl     float64 // 平仓成交的已实现盈亏（未扣手续费）
+	Commission      float64
+	CommissionAsset string
+	Time            int64 // 毫秒时间戳
+	Buyer           bool
+}
+
+// TradeHistoryProvider 支持从交易所查询成交历史的交易器
+type TradeHistoryProvider interface {
+	// GetAllTradeHistory 获取最近N天所有币种的成交记录（按币种分组，组内按时间升序）
+	GetAllTradeHistory(lookbackDays int) (map[string][]*Trade, error)
+}
+
+// tradeQtyEpsilon 判断仓位已完全平仓的数量容差
+const tradeQtyEpsilon = 0.0001
+
+// analysisDefaultLeverage 成交记录中没有杠杆信息，按默认杠杆估算保证金
+const analysisDefaultLeverage = 5
+
+// isOpeningTrade 买入开多、卖出开空为开仓，其余为平仓
+func isOpeningTrade(trade *Trade) bool {
+	return trade.Side == "BUY" && trade.PositionSide == "LONG" ||
+		trade.Side == "SELL" && trade.PositionSide == "SHORT"
+}
+
+// AnalyzeTradeHistory 按币种、按方向配对开平仓成交，计算胜率、盈亏比、夏普比率等交易表现
+// 仓位从开仓累积到完全平仓记为一笔交易；窗口开始前已持有的仓位，其平仓成交因无法配对而被忽略
+func AnalyzeTradeHistory(history map[string][]*Trade) *logger.PerformanceAnalysis {
+	return AnalyzeTradeHistoryWithFunding(history, nil)
+}
+
+// AnalyzeTradeHistoryWithFunding 同 AnalyzeTradeHistory，并将资金费计入交易盈亏（净盈亏）
+// 窗口内全部资金费（含仍在持仓和无法配对的仓位）计入 FundingFees 合计
+func AnalyzeTradeHistoryWithFunding(history map[string][]*Trade, fees []FundingFee) *logger.PerformanceAnalysis {
+	analysis := &logger.PerformanceAnalysis{
+		RecentTrades: []logger.TradeOutcome{},
+		SymbolStats:  make(map[string]*logger.SymbolPerformance),
+		FundingFees:  sumFundingFees(fees, "", 0, 0),
+	}
+
+	symbols := make([]string, 0, len(history))
+	for symbol := range history {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		for _, outcome := range MatchTrades(symbol, history[symbol]) {
+			applyTradeFunding(&outcome, fees)
+			analysis.RecentTrades = append(analysis.RecentTrades, outcome)
+			analysis.TotalTrades++
+
+			if outcome.PnL > 0 {
+				analysis.WinningTrades++
+				analysis.AvgWin += outcome.PnL
+			} else if outcome.PnL < 0 {
+				analysis.LosingTrades++
+				analysis.AvgLoss += outcome.PnL
+			}
+
+			// 更新币种统计
+			stats, exists := analysis.SymbolStats[symbol]
+			if !exists {
+				stats = &logger.SymbolPerformance{Symbol: symbol}
+				analysis.SymbolStats[symbol] = stats
+			}
+			stats.TotalTrades++
+			stats.TotalPnL += outcome.PnL
+			if outcome.PnL > 0 {
+				stats.WinningTrades++
+			} else if outcome.PnL < 0 {
+				stats.LosingTrades++
+			}
+		}
+	}
+
+	// 按平仓时间排序，便于前端展示
+	sort.SliceStable(analysis.RecentTrades, func(i, j int) bool {
+		return analysis.RecentTrades[i].CloseTime.Before(analysis.RecentTrades[j].CloseTime)
+	})
+
+	// 计算统计指标
+	if analysis.WinningTrades > 0 {
+		analysis.AvgWin /= float64(analysis.WinningTrades)
+	}
+	if analysis.LosingTrades > 0 {
+		analysis.AvgLoss /= float64(analysis.LosingTrades)
+	}
+	if analysis.TotalTrades > 0 {
+		analysis.WinRate = float64(analysis.WinningTrades) / float64(analysis.TotalTrades) * 100
+	}
+
+	// 盈亏比：防止除以零和异常值
+	if analysis.AvgLoss != 0 && analysis.LosingTrades > 0 {
+		analysis.ProfitFactor = analysis.AvgWin / -analysis.AvgLoss
+		// 限制最大值，避免显示异常的 999.00
+		if analysis.ProfitFactor > 100 {
+			analysis.ProfitFactor = 100
+		}
+	} else if analysis.WinningTrades > 0 && analysis.LosingTrades == 0 {
+		// 如果只有盈利交易，没有亏损交易，设置为一个合理的上限
+		analysis.ProfitFactor = 100
+	}
+
+	analysis.SharpeRatio = tradeSharpeRatio(analysis.RecentTrades)
+
+	// 计算币种统计
+	for _, stats := range analysis.SymbolStats {
+		if stats.TotalTrades > 0 {
+			stats.WinRate = float64(stats.WinningTrades) / float64(stats.TotalTrades) * 100
+			stats.AvgPnL = stats.TotalPnL / float64(stats.TotalTrades)
+		}
+	}
+
+	log.Printf("📊 统计结果: 总交易=%d, 盈利=%d, 亏损=%d, 胜率=%.2f%%, 盈亏比=%.2f, 夏普比率=%.2f, 资金费=%+.4f",
+		analysis.TotalTrades, analysis.WinningTrades, analysis.LosingTrades,
+		analysis.WinRate, analysis.ProfitFactor, analysis.SharpeRatio, analysis.FundingFees)
+
+	return analysis
+}
+
+// ApplyTradeRiskMetrics 计算成交分析的风险指标：权益曲线由起始净值加上按平仓时间累计的交易盈亏构成（不含持仓期间的浮动盈亏），
+// 持仓时间占比按 [from, to] 窗口计算；startEquity<=0（无法获取余额）时只计算持仓时长和占比
+func ApplyTradeRiskMetrics(analysis *logger.PerformanceAnalysis, startEquity float64, from, to time.Time) {
+	if startEquity > 0 {
+		analysis.RiskMetrics = logger.ComputeRiskMetrics(logger.TradeEquityCurve(analysis.RecentTrades, startEquity, from))
+	}
+	logger.ApplyTradeExposure(&analysis.RiskMetrics, analysis.RecentTrades, from, to)
+}
+
+// positionTracker 按平均成本法跟踪单个币种单个方向的一轮持仓（从开仓到完全平仓）
+type positionTracker struct {
+	openTime    int64
+	qty         float64 // 当前持仓数量
+	cost        float64 // 当前持仓的成本（平均开仓价 × 数量），部分平仓时按比例减少
+	openedQty   float64 // 本轮累计开仓数量
+	openedValue float64 // 本轮累计开仓金额（用于开仓均价）
+	closedQty   float64 // 本轮累计平仓数量
+	closedValue float64 // 本轮累计平仓金额（用于平仓均价）
+	realizedPnl float64 // 本轮平仓成交的已实现盈亏合计
+	commission  float64 // 本轮开仓和平仓的手续费合计
+}
+
+// open 开仓或加仓
+func (p *positionTracker) open(trade *Trade) {
+	if p.qty <= tradeQtyEpsilon {
+		*p = positionTracker{openTime: trade.Time}
+	}
+	p.qty += trade.Qty
+	p.cost += trade.Price * trade.Qty
+	p.openedQty += trade.Qty
+	p.openedValue += trade.Price * trade.Qty
+	p.commission += trade.Commission
+}
+
+// close 部分或全部平仓，返回是否已完全平仓
+// 平仓数量超过跟踪到的持仓时（窗口开始前已持有的部分），只按跟踪到的比例计入盈亏和手续费
+func (p *positionTracker) close(trade *Trade) bool {
+	qty := math.Min(trade.Qty, p.qty)
+	ratio := qty / trade.Qty
+	p.cost -= p.cost * qty / p.qty
+	p.qty -= qty
+	p.closedQty += qty
+	p.closedValue += trade.Price * qty
+	p.realizedPnl += trade.RealizedPnl * ratio
+	p.commission += trade.Commission * ratio
+	return p.qty <= tradeQtyEpsilon
+}
+
+// outcome 完全平仓后生成交易结果（开平仓价格均为成交量加权均价）
+func (p *positionTracker) outcome(symbol, side string, closeTime int64) logger.TradeOutcome {
+	avgOpen := p.openedValue / p.openedQty
+	positionValue := avgOpen * p.closedQty
+	marginUsed := positionValue / float64(analysisDefaultLeverage)
+	pnl := p.realizedPnl - p.commission
+
+	outcome := logger.TradeOutcome{
+		Symbol:        symbol,
+		Side:          side,
+		Quantity:      p.closedQty,
+		Leverage:      analysisDefaultLeverage,
+		OpenPrice:     avgOpen,
+		ClosePrice:    p.closedValue / p.closedQty,
+		PositionValue: positionValue,
+		MarginUsed:    marginUsed,
+		PnL:           pnl,
+		Duration:      time.Duration((closeTime - p.openTime) * int64(time.Millisecond)).String(),
+		OpenTime:      time.UnixMilli(p.openTime),
+		CloseTime:     time.UnixMilli(closeTime),
+	}
+	if marginUsed > 0 {
+		outcome.PnLPct = pnl / marginUsed * 100
+	}
+	return outcome
+}
+
+// MatchTrades 配对单个币种的开平仓成交（成交需按时间升序），每个完全平仓的持仓生成一笔交易
+//
+// 按方向分别跟踪持仓：加仓累积数量和成本，部分平仓按平均成本减少持仓，平到零时输出一笔交易
+// （开仓价/平仓价为成交量加权均价，盈亏为已实现盈亏扣除开平仓手续费）。单向持仓模式（BOTH）的成交
+// 先推断方向并拆分反手成交。窗口开始前开的仓被平掉时无法配对而忽略，窗口结束时仍持有的仓位不输出。
+func MatchTrades(symbol string, trades []*Trade) []logger.TradeOutcome {
+	for _, trade := range trades {
+		if trade.PositionSide != "LONG" && trade.PositionSide != "SHORT" {
+			trades = assignOneWayPositionSides(trades)
+			break
+		}
+	}
+
+	var outcomes []logger.TradeOutcome
+	positions := map[string]*positionTracker{"LONG": {}, "SHORT": {}}
+	for _, trade := range trades {
+		pos, ok := positions[trade.PositionSide]
+		if !ok || trade.Qty <= 0 {
+			continue
+		}
+
+		if isOpeningTrade(trade) {
+			pos.open(trade)
+			continue
+		}
+
+		// 没有对应的开仓记录（窗口前开的仓）时无法计算开仓均价，忽略
+		if pos.qty <= tradeQtyEpsilon {
+			continue
+		}
+		if pos.close(trade) {
+			outcomes = append(outcomes, pos.outcome(symbol, strings.ToLower(trade.PositionSide), trade.Time))
+			*pos = positionTracker{}
+		}
+	}
+
+	return outcomes
+}
+
+// tradeSharpeRatio 计算交易级别的夏普比率（收益率相对保证金，无风险利率为0，不年化，限制在 [-3, 3]）
+func tradeSharpeRatio(trades []logger.TradeOutcome) float64 {
+	returns := make([]float64, 0, len(trades))
+	for _, trade := range trades {
+		// 优先使用保证金，其次仓位价值，最后使用开仓价值估算
+		baseValue := trade.MarginUsed
+		if baseValue <= 0 {
+			baseValue = trade.PositionValue
+		}
+		if baseValue <= 0 && trade.Leverage > 0 {
+			baseValue = trade.OpenPrice * trade.Quantity / float64(trade.Leverage)
+		}
+		if baseValue > 0 {
+			returns = append(returns, trade.PnL/baseValue)
+		}
+	}
+	if len(returns) < 2 {
+		return 0
+	}
+
+	var sumReturns float64
+	for _, r := range returns {
+		sumReturns += r
+	}
+	avgReturn := sumReturns / float64(len(returns))
+
+	var sumSquaredDiff float64
+	for _, r := range returns {
+		diff := r - avgReturn
+		sumSquaredDiff += diff * diff
+	}
+	stdDev := math.Sqrt(sumSquaredDiff / float64(len(returns)))
+	if stdDev == 0 {
+		return 0
+	}
+
+	return math.Max(-3, math.Min(3, avgReturn/stdDev))
+}
+
+// assignOneWayPositionSides 为单向持仓模式（positionSide=BOTH）的成交推断持仓方向
+// 从零仓位开始按时间累计净仓位：与净仓位同向为开仓，反向为平仓，反手成交拆分为平仓+开仓两笔（手续费按数量分摊）
+func assignOneWayPositionSides(trades []*Trade) []*Trade {
+	result := make([]*Trade, 0, len(trades))
+	net := 0.0 // 多仓为正，空仓为负
+
+	for _, trade := range trades {
+		if trade.PositionSide == "LONG" || trade.PositionSide == "SHORT" {
+			result = append(result, trade)
+			continue
+		}
+
+		buy := trade.Side == "BUY"
+		openSide, closeSide, direction := "LONG", "SHORT", 1.0
+		if !buy {
+			openSide, closeSide, direction = "SHORT", "LONG", -1.0
+		}
+
+		// 反向持有的数量（本笔成交可平掉的部分）
+		opposite := math.Max(0, -net*direction)
+		if opposite <= tradeQtyEpsilon && trade.RealizedPnl != 0 {
+			// 窗口开始前已持有的仓位被平掉：保留为平仓成交，净仓位不变
+			closing := *trade
+			closing.PositionSide = closeSide
+			result = append(result, &closing)
+			continue
+		}
+
+		closeQty := math.Min(trade.Qty, opposite)
+		openQty := trade.Qty - closeQty
+		if closeQty > tradeQtyEpsilon {
+			closing := *trade
+			closing.PositionSide = closeSide
+			closing.Qty = closeQty
+			closing.Commission = trade.Commission * closeQty / trade.Qty
+			result = append(result, &closing)
+		}
+		if openQty > tradeQtyEpsilon {
+			opening := *trade
+			opening.PositionSide = openSide
+			opening.Qty = openQty
+			opening.RealizedPnl = 0
+			opening.Commission = trade.Commission * openQty / trade.Qty
+			result = append(result, &opening)
+		}
+		net += trade.Qty * direction
+	}
+
+	return result
+}
@@ -0,0 +1,183 @@
+package trader
+
+import (
+	"testing"
+
+	"github.com/sonirico/go-hyperliquid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTradeHistoryProviders 确认各交易所交易器实现了成交历史接口
+func TestTradeHistoryProviders(t *testing.T) {
+	var _ TradeHistoryProvider = (*FuturesTrader)(nil)
+	var _ TradeHistoryProvider = (*BybitTrader)(nil)
+	var _ TradeHistoryProvider = (*HyperliquidTrader)(nil)
+	var _ TradeHistoryProvider = (*AsterTrader)(nil)
+}
+
+// TestAnalyzeTradeHistory 测试分批开仓配对、盈亏扣除平仓手续费以及统计指标
+func TestAnalyzeTradeHistory(t *testing.T) {
+	history := map[string][]*Trade{
+		"BTCUSDT": {
+			// 多仓分两次开、一次平：开仓均价 100，盈亏 (110-100)*2 - 手续费 1 = 19
+			{Side: "BUY", PositionSide: "LONG", Price: 90, Qty: 1, Time: 1000},
+			{Side: "BUY", PositionSide: "LONG", Price: 110, Qty: 1, Time: 2000},
+			{Side: "SELL", PositionSide: "LONG", Price: 110, Qty: 2, RealizedPnl: 20, Commission: 1, Time: 4000},
+		},
+		"ETHUSDT": {
+			// 窗口前开的空仓被平掉，无法配对，应忽略
+			{Side: "BUY", PositionSide: "SHORT", Price: 50, Qty: 3, RealizedPnl: 100, Time: 500},
+			// 空仓亏损 10
+			{Side: "SELL", PositionSide: "SHORT", Price: 100, Qty: 1, Time: 5000},
+			{Side: "BUY", PositionSide: "SHORT", Price: 110, Qty: 1, RealizedPnl: -10, Time: 6000},
+			// 未平仓的多仓不计入
+			{Side: "BUY", PositionSide: "LONG", Price: 100, Qty: 1, Time: 7000},
+		},
+	}
+
+	analysis := AnalyzeTradeHistory(history)
+
+	require.Equal(t, 2, analysis.TotalTrades)
+	assert.Equal(t, 1, analysis.WinningTrades)
+	assert.Equal(t, 1, analysis.LosingTrades)
+	assert.Equal(t, 50.0, analysis.WinRate)
+	assert.InDelta(t, 1.9, analysis.ProfitFactor, 1e-9)
+
+	long := analysis.RecentTrades[0]
+	assert.Equal(t, "BTCUSDT", long.Symbol)
+	assert.Equal(t, "long", long.Side)
+	assert.InDelta(t, 2, long.Quantity, 1e-9)
+	assert.InDelta(t, 100, long.OpenPrice, 1e-9)
+	assert.InDelta(t, 19, long.PnL, 1e-9)
+	assert.Equal(t, int64(1000), long.OpenTime.UnixMilli())
+	assert.Equal(t, int64(4000), long.CloseTime.UnixMilli())
+
+	short := analysis.RecentTrades[1]
+	assert.Equal(t, "ETHUSDT", short.Symbol)
+	assert.Equal(t, "short", short.Side)
+	assert.InDelta(t, -10, short.PnL, 1e-9)
+
+	assert.Equal(t, 1, analysis.SymbolStats["BTCUSDT"].TotalTrades)
+	assert.Equal(t, 100.0, analysis.SymbolStats["BTCUSDT"].WinRate)
+	assert.InDelta(t, -10, analysis.SymbolStats["ETHUSDT"].TotalPnL, 1e-9)
+}
+
+// TestMatchTrades 测试加仓、部分平仓、窗口前持仓的超额平仓、反手以及未平仓持仓的配对
+func TestMatchTrades(t *testing.T) {
+	t.Run("分批开仓分批平仓", func(t *testing.T) {
+		outcomes := MatchTrades("BTCUSDT", []*Trade{
+			{Side: "BUY", PositionSide: "LONG", Price: 100, Qty: 1, Commission: 0.1, Time: 1000},
+			{Side: "BUY", PositionSide: "LONG", Price: 120, Qty: 1, Commission: 0.1, Time: 2000},
+			{Side: "BUY", PositionSide: "LONG", Price: 110, Qty: 2, Commission: 0.2, Time: 3000},
+			{Side: "SELL", PositionSide: "LONG", Price: 120, Qty: 1, RealizedPnl: 10, Commission: 0.1, Time: 4000},
+			{Side: "SELL", PositionSide: "LONG", Price: 130, Qty: 3, RealizedPnl: 60, Commission: 0.3, Time: 5000},
+		})
+		require.Len(t, outcomes, 1)
+		assert.InDelta(t, 4, outcomes[0].Quantity, 1e-9)
+		assert.InDelta(t, 110, outcomes[0].OpenPrice, 1e-9)
+		assert.InDelta(t, 127.5, outcomes[0].ClosePrice, 1e-9)
+		assert.InDelta(t, 69.2, outcomes[0].PnL, 1e-9, "已实现盈亏扣除开平仓手续费")
+		assert.Equal(t, int64(1000), outcomes[0].OpenTime.UnixMilli())
+		assert.Equal(t, int64(5000), outcomes[0].CloseTime.UnixMilli())
+	})
+
+	t.Run("部分平仓后加仓", func(t *testing.T) {
+		outcomes := MatchTrades("ETHUSDT", []*Trade{
+			{Side: "SELL", PositionSide: "SHORT", Price: 100, Qty: 2, Time: 1000},
+			{Side: "BUY", PositionSide: "SHORT", Price: 90, Qty: 1, RealizedPnl: 10, Time: 2000},
+			{Side: "SELL", PositionSide: "SHORT", Price: 94, Qty: 2, Time: 3000},
+			{Side: "BUY", PositionSide: "SHORT", Price: 80, Qty: 3, RealizedPnl: 52, Time: 4000},
+		})
+		require.Len(t, outcomes, 1)
+		assert.Equal(t, "short", outcomes[0].Side)
+		assert.InDelta(t, 4, outcomes[0].Quantity, 1e-9)
+		assert.InDelta(t, 97, outcomes[0].OpenPrice, 1e-9)
+		assert.InDelta(t, 82.5, outcomes[0].ClosePrice, 1e-9)
+		assert.InDelta(t, 62, outcomes[0].PnL, 1e-9)
+	})
+
+	t.Run("平仓数量超过窗口内开仓", func(t *testing.T) {
+		// 窗口前已持有1个，窗口内开1个后平掉2个：只计入窗口内开仓对应的一半盈亏和手续费
+		outcomes := MatchTrades("BTCUSDT", []*Trade{
+			{Side: "BUY", PositionSide: "LONG", Price: 100, Qty: 1, Time: 1000},
+			{Side: "SELL", PositionSide: "LONG", Price: 110, Qty: 2, RealizedPnl: 30, Commission: 2, Time: 2000},
+			{Side: "BUY", PositionSide: "LONG", Price: 105, Qty: 1, Time: 3000},
+		})
+		require.Len(t, outcomes, 1, "窗口结束时仍持有的仓位不输出")
+		assert.InDelta(t, 1, outcomes[0].Quantity, 1e-9)
+		assert.InDelta(t, 14, outcomes[0].PnL, 1e-9)
+	})
+
+	t.Run("单向持仓反手", func(t *testing.T) {
+		outcomes := MatchTrades("BTCUSDT", []*Trade{
+			{Side: "BUY", PositionSide: "BOTH", Price: 100, Qty: 2, Time: 1000},
+			{Side: "SELL", PositionSide: "BOTH", Price: 110, Qty: 3, RealizedPnl: 20, Time: 2000},
+			{Side: "BUY", PositionSide: "BOTH", Price: 105, Qty: 1, RealizedPnl: 5, Time: 3000},
+		})
+		require.Len(t, outcomes, 2)
+		assert.Equal(t, "long", outcomes[0].Side)
+		assert.InDelta(t, 2, outcomes[0].Quantity, 1e-9)
+		assert.InDelta(t, 20, outcomes[0].PnL, 1e-9)
+		assert.Equal(t, "short", outcomes[1].Side)
+		assert.InDelta(t, 110, outcomes[1].OpenPrice, 1e-9)
+		assert.InDelta(t, 105, outcomes[1].ClosePrice, 1e-9)
+		assert.Equal(t, int64(2000), outcomes[1].OpenTime.UnixMilli())
+	})
+}
+
+// TestAssignOneWayPositionSides 测试单向持仓成交的开平方向推断与反手拆分
+func TestAssignOneWayPositionSides(t *testing.T) {
+	trades := assignOneWayPositionSides([]*Trade{
+		{Side: "BUY", PositionSide: "BOTH", Price: 100, Qty: 2, Commission: 0.2, Time: 1},
+		// 卖出3：平多2 + 开空1
+		{Side: "SELL", PositionSide: "BOTH", Price: 110, Qty: 3, RealizedPnl: 20, Commission: 0.3, Time: 2},
+		{Side: "BUY", PositionSide: "BOTH", Price: 105, Qty: 1, RealizedPnl: 5, Commission: 0.1, Time: 3},
+	})
+
+	require.Len(t, trades, 4)
+	assert.Equal(t, "LONG", trades[0].PositionSide)
+
+	assert.Equal(t, "LONG", trades[1].PositionSide)
+	assert.InDelta(t, 2, trades[1].Qty, 1e-9)
+	assert.InDelta(t, 20, trades[1].RealizedPnl, 1e-9)
+	assert.InDelta(t, 0.2, trades[1].Commission, 1e-9)
+
+	assert.Equal(t, "SHORT", trades[2].PositionSide)
+	assert.InDelta(t, 1, trades[2].Qty, 1e-9)
+	assert.Zero(t, trades[2].RealizedPnl)
+	assert.InDelta(t, 0.1, trades[2].Commission, 1e-9)
+
+	assert.Equal(t, "SHORT", trades[3].PositionSide)
+	assert.False(t, isOpeningTrade(trades[3]), "买入应平掉空仓")
+
+	analysis := AnalyzeTradeHistory(map[string][]*Trade{"BTCUSDT": trades})
+	assert.Equal(t, 2, analysis.TotalTrades)
+}
+
+// TestBuildHyperliquidTradeHistory 测试Hyperliquid成交的方向识别与反手拆分
+func TestBuildHyperliquidTradeHistory(t *testing.T) {
+	history := buildHyperliquidTradeHistory([]hyperliquid.Fill{
+		{Coin: "BTC", Side: "A", Dir: "Long > Short", Price: "110", Size: "3", StartPosition: "2", ClosedPnl: "20", Fee: "0.3", Time: 2000},
+		{Coin: "BTC", Side: "B", Dir: "Open Long", Price: "100", Size: "2", StartPosition: "0", ClosedPnl: "0", Fee: "0.2", Time: 1000},
+		{Coin: "ETH", Side: "B", Dir: "Close Short", Price: "90", Size: "1", StartPosition: "-1", ClosedPnl: "10", Fee: "0.1", Time: 1500},
+		{Coin: "PURR/USDC", Side: "B", Dir: "Buy", Price: "1", Size: "10", Time: 1600},
+	})
+
+	require.Len(t, history, 2, "现货成交应被忽略")
+	btc := history["BTCUSDT"]
+	require.Len(t, btc, 3)
+	assert.Equal(t, "BUY", btc[0].Side)
+	assert.Equal(t, "LONG", btc[0].PositionSide)
+	assert.Equal(t, "LONG", btc[1].PositionSide)
+	assert.InDelta(t, 2, btc[1].Qty, 1e-9)
+	assert.InDelta(t, 20, btc[1].RealizedPnl, 1e-9)
+	assert.InDelta(t, 0.2, btc[1].Commission, 1e-9)
+	assert.Equal(t, "SHORT", btc[2].PositionSide)
+	assert.InDelta(t, 1, btc[2].Qty, 1e-9)
+
+	eth := history["ETHUSDT"]
+	require.Len(t, eth, 1)
+	assert.Equal(t, "SHORT", eth[0].PositionSide)
+	assert.False(t, isOpeningTrade(eth[0]))
+}
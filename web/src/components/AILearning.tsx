@@ -30,6 +30,7 @@ interface TradeOutcome {
   open_time: string
   close_time: string
   was_stop_loss: boolean
+  funding_fees?: number
 }
 
 interface SymbolPerformance {
@@ -55,6 +56,17 @@ interface PerformanceAnalysis {
   symbol_stats: { [key: string]: SymbolPerformance }
   best_symbol: string
   worst_symbol: string
+  slippage?: SlippageStats
+  funding_fees?: number
+}
+
+interface SlippageStats {
+  samples: number
+  avg_bps: number // 正数表示成交价比决策参考价更差
+  worst_bps: number
+  cost_usdt: number
+  limit_orders: number
+  market_fallback: number
 }
 
 interface AILearningProps {
@@ -672,6 +684,100 @@ export default function AILearning({ traderId }: AILearningProps) {
         </div>
       )}
 
+      {/* 成交滑点（决策参考价 vs 实际成交均价） */}
+      {performance.slippage && (
+        <div
+          className="rounded-2xl p-6 backdrop-blur-sm"
+          style={{
+            background: 'rgba(30, 35, 41, 0.6)',
+            border: '1px solid #2B3139',
+          }}
+        >
+          <div className="flex items-center justify-between mb-4">
+            <span className="text-sm font-semibold" style={{ color: '#EAECEF' }}>
+              {t('executionSlippage', language)}
+            </span>
+            <span className="text-xs" style={{ color: '#848E9C' }}>
+              {t('slippageSamples', language, {
+                count: performance.slippage.samples,
+              })}
+            </span>
+          </div>
+          <div className="grid grid-cols-2 md:grid-cols-5 gap-4 text-sm">
+            {[
+              {
+                label: t('avgSlippage', language),
+                value: `${performance.slippage.avg_bps.toFixed(1)} bps`,
+                bad: performance.slippage.avg_bps > 0,
+              },
+              {
+                label: t('worstSlippage', language),
+                value: `${performance.slippage.worst_bps.toFixed(1)} bps`,
+                bad: performance.slippage.worst_bps > 0,
+              },
+              {
+                label: t('slippageCost', language),
+                value: `${performance.slippage.cost_usdt.toFixed(2)} USDT`,
+                bad: performance.slippage.cost_usdt > 0,
+              },
+              {
+                label: t('limitFills', language),
+                value: String(performance.slippage.limit_orders),
+                bad: false,
+              },
+              {
+                label: t('marketFallbacks', language),
+                value: String(performance.slippage.market_fallback),
+                bad: false,
+              },
+            ].map((item) => (
+              <div key={item.label}>
+                <div className="text-xs mb-1" style={{ color: '#848E9C' }}>
+                  {item.label}
+                </div>
+                <div
+                  className="font-bold mono"
+                  style={{ color: item.bad ? '#F6465D' : '#EAECEF' }}
+                >
+                  {item.value}
+                </div>
+              </div>
+            ))}
+          </div>
+        </div>
+      )}
+
+      {/* 资金费（已计入净盈亏） */}
+      {performance.funding_fees !== undefined && performance.funding_fees !== 0 && (
+        <div
+          className="rounded-2xl p-6 backdrop-blur-sm"
+          style={{
+            background: 'rgba(30, 35, 41, 0.6)',
+            border: '1px solid #2B3139',
+          }}
+        >
+          <div className="flex items-center justify-between">
+            <div>
+              <div className="text-sm font-semibold" style={{ color: '#EAECEF' }}>
+                {t('fundingFees', language)}
+              </div>
+              <div className="text-xs mt-1" style={{ color: '#848E9C' }}>
+                {t('fundingFeesHint', language)}
+              </div>
+            </div>
+            <div
+              className="text-xl font-bold mono"
+              style={{
+                color: performance.funding_fees >= 0 ? '#0ECB81' : '#F6465D',
+              }}
+            >
+              {performance.funding_fees >= 0 ? '+' : ''}
+              {performance.funding_fees.toFixed(2)} USDT
+            </div>
+          </div>
+        </div>
+      )}
+
       {/* 币种表现 & 历史成交 - 左右分屏 2列布局 */}
       <div className="grid grid-cols-1 lg:grid-cols-2 gap-6">
         {/* 左侧：币种表现统计表格 */}
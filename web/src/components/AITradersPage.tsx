@@ -8,6 +8,7 @@ import type {
   AIModel,
   Exchange,
 } from '../types'
+import { DEFAULT_EXCHANGE_ACCOUNT_ID, isExchangeAccount } from '../types'
 import { useLanguage } from '../contexts/LanguageContext'
 import { t, type Language } from '../i18n/translations'
 import { useAuth } from '../contexts/AuthContext'
@@ -48,6 +49,9 @@ function getModelDisplayName(modelId: string): string {
       return 'Qwen'
     case 'claude':
       return 'Claude'
+    case 'local':
+    case 'ollama':
+      return 'Local LLM'
     default:
       return modelId.toUpperCase()
   }
@@ -226,7 +230,9 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
   const handleCreateTrader = async (data: CreateTraderRequest) => {
     try {
       const model = allModels?.find((m) => m.id === data.ai_model_id)
-      const exchange = allExchanges?.find((e) => e.id === data.exchange_id)
+      const exchange = allExchanges?.find((e) =>
+        isExchangeAccount(e, data.exchange_id, data.exchange_account_id)
+      )
 
       if (!model?.enabled) {
         toast.error(t('modelNotConfigured', language))
@@ -268,7 +274,9 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
 
     try {
       const model = enabledModels?.find((m) => m.id === data.ai_model_id)
-      const exchange = enabledExchanges?.find((e) => e.id === data.exchange_id)
+      const exchange = enabledExchanges?.find((e) =>
+        isExchangeAccount(e, data.exchange_id, data.exchange_account_id)
+      )
 
       if (!model) {
         toast.error(t('modelConfigNotExist', language))
@@ -284,6 +292,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
         name: data.name,
         ai_model_id: data.ai_model_id,
         exchange_id: data.exchange_id,
+        exchange_account_id: data.exchange_account_id,
         initial_balance: data.initial_balance,
         scan_interval_minutes: data.scan_interval_minutes,
         btc_eth_leverage: data.btc_eth_leverage,
@@ -295,6 +304,18 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
         is_cross_margin: data.is_cross_margin,
         use_coin_pool: data.use_coin_pool,
         use_oi_top: data.use_oi_top,
+        is_public: data.is_public,
+        max_daily_loss_pct: data.max_daily_loss_pct,
+        daily_loss_flatten: data.daily_loss_flatten,
+        max_position_value_usdt: data.max_position_value_usdt,
+        max_total_exposure_pct: data.max_total_exposure_pct,
+        default_stop_loss_pct: data.default_stop_loss_pct,
+        default_take_profit_pct: data.default_take_profit_pct,
+        cooldown_minutes_after_loss: data.cooldown_minutes_after_loss,
+        execution_mode: data.execution_mode,
+        limit_offset_bps: data.limit_offset_bps,
+        limit_timeout_seconds: data.limit_timeout_seconds,
+        limit_fallback: data.limit_fallback,
       }
 
       await toast.promise(api.updateTrader(editingTrader.trader_id, request), {
@@ -357,6 +378,34 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
     }
   }
 
+  const handleEmergencyStop = async () => {
+    const ok = await confirmToast(t('confirmEmergencyStop', language), {
+      title: t('emergencyStop', language),
+    })
+    if (!ok) return
+    const closePositions = await confirmToast(
+      t('confirmEmergencyClosePositions', language),
+      {
+        title: t('emergencyStop', language),
+        okText: t('emergencyClosePositions', language),
+        cancelText: t('emergencyStopOnly', language),
+      }
+    )
+
+    try {
+      const result = await api.emergencyStop(closePositions)
+      if (result.failed_positions > 0 || result.failed_traders > 0) {
+        toast.warning(t('emergencyStopPartial', language))
+      } else {
+        toast.success(t('emergencyStopDone', language))
+      }
+      await mutateTraders()
+    } catch (error) {
+      console.error('Failed to emergency stop:', error)
+      toast.error(t('operationFailed', language))
+    }
+  }
+
   const handleModelClick = (modelId: string) => {
     if (!isModelInUse(modelId)) {
       setEditingModel(modelId)
@@ -382,7 +431,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
     allItems: T[] | undefined
     clearFields: (item: T) => T
     buildRequest: (items: T[]) => any
-    updateApi: (request: any) => Promise<void>
+    updateApi: (request: any) => Promise<unknown>
     refreshApi: () => Promise<T[]>
     setItems: (items: T[]) => void
     closeModal: () => void
@@ -568,19 +617,25 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
       }),
       buildRequest: (exchanges) => ({
         exchanges: Object.fromEntries(
-          exchanges.map((exchange) => [
-            exchange.id,
-            {
-              enabled: exchange.enabled,
-              api_key: exchange.apiKey || '',
-              secret_key: exchange.secretKey || '',
-              testnet: exchange.testnet || false,
-              hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
-              aster_user: exchange.asterUser || '',
-              aster_signer: exchange.asterSigner || '',
-              aster_private_key: exchange.asterPrivateKey || '',
-            },
-          ])
+          exchanges
+            .filter(
+              (exchange) =>
+                (exchange.account_id || DEFAULT_EXCHANGE_ACCOUNT_ID) ===
+                DEFAULT_EXCHANGE_ACCOUNT_ID
+            )
+            .map((exchange) => [
+              exchange.id,
+              {
+                enabled: exchange.enabled,
+                api_key: exchange.apiKey || '',
+                secret_key: exchange.secretKey || '',
+                testnet: exchange.testnet || false,
+                hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
+                aster_user: exchange.asterUser || '',
+                aster_signer: exchange.asterSigner || '',
+                aster_private_key: exchange.asterPrivateKey || '',
+              },
+            ])
         ),
       }),
       updateApi: api.updateExchangeConfigsEncrypted,
@@ -656,28 +711,41 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
       }
 
       const request = {
+        // exchanges 按交易所ID更新默认账户，其他账户不在此提交
         exchanges: Object.fromEntries(
-          updatedExchanges.map((exchange) => [
-            exchange.id,
-            {
-              enabled: exchange.enabled,
-              api_key: exchange.apiKey || '',
-              secret_key: exchange.secretKey || '',
-              testnet: exchange.testnet || false,
-              hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
-              aster_user: exchange.asterUser || '',
-              aster_signer: exchange.asterSigner || '',
-              aster_private_key: exchange.asterPrivateKey || '',
-            },
-          ])
+          updatedExchanges
+            .filter(
+              (exchange) =>
+                (exchange.account_id || DEFAULT_EXCHANGE_ACCOUNT_ID) ===
+                DEFAULT_EXCHANGE_ACCOUNT_ID
+            )
+            .map((exchange) => [
+              exchange.id,
+              {
+                enabled: exchange.enabled,
+                api_key: exchange.apiKey || '',
+                secret_key: exchange.secretKey || '',
+                testnet: exchange.testnet || false,
+                hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
+                aster_user: exchange.asterUser || '',
+                aster_signer: exchange.asterSigner || '',
+                aster_private_key: exchange.asterPrivateKey || '',
+              },
+            ])
         ),
       }
 
-      await toast.promise(api.updateExchangeConfigsEncrypted(request), {
+      const savePromise = api.updateExchangeConfigsEncrypted(request)
+      toast.promise(savePromise, {
         loading: '正在更新交易所配置…',
         success: '交易所配置已更新',
         error: '更新交易所配置失败',
       })
+      const result = await savePromise
+      // 例如密钥未开启合约交易权限：可以查看余额，但交易员下单会失败
+      result.warnings?.forEach((warning) =>
+        toast.warning(warning, { duration: 10000 })
+      )
 
       // 重新获取用户配置以确保数据同步
       const refreshedExchanges = await api.getExchangeConfigs()
@@ -795,6 +863,21 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
             {t('signalSource', language)}
           </button>
 
+          {traders && traders.some((trader) => trader.is_running) && (
+            <button
+              onClick={handleEmergencyStop}
+              className="px-3 md:px-4 py-2 rounded text-xs md:text-sm font-semibold transition-all hover:scale-105 flex items-center gap-1 md:gap-2 whitespace-nowrap"
+              style={{
+                background: 'rgba(246, 70, 93, 0.1)',
+                color: '#F6465D',
+                border: '1px solid rgba(246, 70, 93, 0.4)',
+              }}
+            >
+              <AlertTriangle className="w-3 h-3 md:w-4 md:h-4" />
+              {t('emergencyStop', language)}
+            </button>
+          )}
+
           <button
             onClick={() => setShowCreateModal(true)}
             disabled={
@@ -926,6 +1009,11 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
                             ? t('enabled', language)
                             : t('configured', language)}
                       </div>
+                      {exchange.trade_permission === 'disabled' && (
+                        <div className="text-xs" style={{ color: '#F6465D' }}>
+                          ⚠️ {t('tradePermissionDisabled', language)}
+                        </div>
+                      )}
                     </div>
                   </div>
                   <div
@@ -965,7 +1053,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
               const inUse = isExchangeInUse(exchange.id)
               return (
                 <div
-                  key={exchange.id}
+                  key={`${exchange.id}:${exchange.account_id || 'default'}`}
                   className={`flex items-center justify-between p-2 md:p-3 rounded transition-all ${
                     inUse
                       ? 'cursor-not-allowed'
@@ -984,6 +1072,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
                         style={{ color: '#EAECEF' }}
                       >
                         {getShortName(exchange.name)}
+                        {exchange.label ? ` · ${exchange.label}` : ''}
                       </div>
                       <div className="text-xs" style={{ color: '#848E9C' }}>
                         {exchange.type.toUpperCase()} •{' '}
@@ -1094,6 +1183,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
                           ? 'bg-green-100 text-green-800'
                           : 'bg-red-100 text-red-800'
                       }`}
+                      title={trader.last_error || undefined}
                       style={
                         trader.is_running
                           ? {
@@ -1509,9 +1599,14 @@ function ModelConfigModal({
     }
   }, [editingModelId, selectedModel])
 
+  // 本地模型（Ollama / OpenAI 兼容服务）无需API Key，但必须填写API地址
+  const isLocalModel =
+    selectedModel?.provider === 'local' || selectedModel?.provider === 'ollama'
+  const canSubmit = isLocalModel ? !!baseUrl.trim() : !!apiKey.trim()
+
   const handleSubmit = (e: React.FormEvent) => {
     e.preventDefault()
-    if (!selectedModelId || !apiKey.trim()) return
+    if (!selectedModelId || !canSubmit) return
 
     onSave(
       selectedModelId,
@@ -1639,14 +1734,18 @@ function ModelConfigModal({
                     type="password"
                     value={apiKey}
                     onChange={(e) => setApiKey(e.target.value)}
-                    placeholder={t('enterAPIKey', language)}
+                    placeholder={
+                      isLocalModel
+                        ? t('localAPIKeyOptional', language)
+                        : t('enterAPIKey', language)
+                    }
                     className="w-full px-3 py-2 rounded"
                     style={{
                       background: '#0B0E11',
                       border: '1px solid #2B3139',
                       color: '#EAECEF',
                     }}
-                    required
+                    required={!isLocalModel}
                   />
                 </div>
 
@@ -1661,16 +1760,23 @@ function ModelConfigModal({
                     type="url"
                     value={baseUrl}
                     onChange={(e) => setBaseUrl(e.target.value)}
-                    placeholder={t('customBaseURLPlaceholder', language)}
+                    placeholder={
+                      isLocalModel
+                        ? 'http://localhost:11434/v1'
+                        : t('customBaseURLPlaceholder', language)
+                    }
                     className="w-full px-3 py-2 rounded"
                     style={{
                       background: '#0B0E11',
                       border: '1px solid #2B3139',
                       color: '#EAECEF',
                     }}
+                    required={isLocalModel}
                   />
                   <div className="text-xs mt-1" style={{ color: '#848E9C' }}>
-                    {t('leaveBlankForDefault', language)}
+                    {isLocalModel
+                      ? t('localBaseURLRequired', language)
+                      : t('leaveBlankForDefault', language)}
                   </div>
                 </div>
 
@@ -1738,7 +1844,7 @@ function ModelConfigModal({
             </button>
             <button
               type="submit"
-              disabled={!selectedModel || !apiKey.trim()}
+              disabled={!selectedModel || !canSubmit}
               className="flex-1 px-4 py-2 rounded text-sm font-semibold disabled:opacity-50"
               style={{ background: '#F0B90B', color: '#000' }}
             >
@@ -13,6 +13,7 @@ export function CompetitionPage() {
   const { language } = useLanguage()
   const [selectedTrader, setSelectedTrader] = useState<any>(null)
   const [isModalOpen, setIsModalOpen] = useState(false)
+  const [hidePaper, setHidePaper] = useState(false)
 
   const { data: competition } = useSWR<CompetitionData>(
     'competition',
@@ -123,9 +124,12 @@ export function CompetitionPage() {
     )
   }
 
-  // 按收益率排序
-  const sortedTraders = [...competition.traders].sort(
-    (a, b) => b.total_pnl_pct - a.total_pnl_pct
+  // 按收益率排序（可选排除模拟盘交易员，只看真实资金排行）
+  const sortedTraders = competition.traders
+    .filter((trader) => !hidePaper || (!trader.is_paper && !trader.testnet))
+    .sort((a, b) => b.total_pnl_pct - a.total_pnl_pct)
+  const hasPaperTraders = competition.traders.some(
+    (trader) => trader.is_paper || trader.testnet
   )
 
   // 找出领先者
@@ -224,15 +228,30 @@ export function CompetitionPage() {
             >
               {t('leaderboard', language)}
             </h2>
-            <div
-              className="text-xs px-2 py-1 rounded"
-              style={{
-                background: 'rgba(240, 185, 11, 0.1)',
-                color: '#F0B90B',
-                border: '1px solid rgba(240, 185, 11, 0.2)',
-              }}
-            >
-              {t('live', language)}
+            <div className="flex items-center gap-2">
+              {hasPaperTraders && (
+                <label
+                  className="flex items-center gap-1 text-xs cursor-pointer"
+                  style={{ color: '#848E9C' }}
+                >
+                  <input
+                    type="checkbox"
+                    checked={hidePaper}
+                    onChange={(e) => setHidePaper(e.target.checked)}
+                  />
+                  {t('realMoneyOnly', language)}
+                </label>
+              )}
+              <div
+                className="text-xs px-2 py-1 rounded"
+                style={{
+                  background: 'rgba(240, 185, 11, 0.1)',
+                  color: '#F0B90B',
+                  border: '1px solid rgba(240, 185, 11, 0.2)',
+                }}
+              >
+                {t('live', language)}
+              </div>
             </div>
           </div>
           <div className="space-y-2">
@@ -280,6 +299,28 @@ export function CompetitionPage() {
                           style={{ color: '#EAECEF' }}
                         >
                           {trader.trader_name}
+                          {trader.is_paper && (
+                            <span
+                              className="ml-2 text-xs font-normal px-1.5 py-0.5 rounded"
+                              style={{
+                                background: 'rgba(132, 142, 156, 0.15)',
+                                color: '#848E9C',
+                              }}
+                            >
+                              {t('paperTrading', language)}
+                            </span>
+                          )}
+                          {trader.testnet && (
+                            <span
+                              className="ml-2 text-xs font-normal px-1.5 py-0.5 rounded"
+                              style={{
+                                background: 'rgba(240, 185, 11, 0.15)',
+                                color: '#F0B90B',
+                              }}
+                            >
+                              {t('testnet', language)}
+                            </span>
+                          )}
                         </div>
                         <div
                           className="text-xs mono font-semibold"
@@ -377,7 +418,7 @@ export function CompetitionPage() {
       </div>
 
       {/* Head-to-Head Stats */}
-      {competition.traders.length === 2 && (
+      {sortedTraders.length === 2 && (
         <div
           className="binance-card p-5 animate-slide-in"
           style={{ animationDelay: '0.3s' }}
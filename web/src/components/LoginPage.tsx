@@ -7,6 +7,7 @@ import { Eye, EyeOff } from 'lucide-react'
 import { Input } from './ui/input'
 import { toast } from 'sonner'
 import { useSystemConfig } from '../hooks/useSystemConfig'
+import { isOTPInputComplete, sanitizeOTPInput } from '../lib/otp'
 
 export function LoginPage() {
   const { language } = useLanguage()
@@ -291,7 +292,7 @@ export function LoginPage() {
                   type="text"
                   value={otpCode}
                   onChange={(e) =>
-                    setOtpCode(e.target.value.replace(/\D/g, '').slice(0, 6))
+                    setOtpCode(sanitizeOTPInput(e.target.value))
                   }
                   className="w-full px-3 py-2 rounded text-center text-2xl font-mono"
                   style={{
@@ -300,7 +301,7 @@ export function LoginPage() {
                     color: 'var(--brand-light-gray)',
                   }}
                   placeholder={t('otpPlaceholder', language)}
-                  maxLength={6}
+                  maxLength={11}
                   required
                 />
               </div>
@@ -331,7 +332,7 @@ export function LoginPage() {
                 </button>
                 <button
                   type="submit"
-                  disabled={loading || otpCode.length !== 6}
+                  disabled={loading || !isOTPInputComplete(otpCode)}
                   className="flex-1 px-4 py-2 rounded text-sm font-semibold transition-all hover:scale-105 disabled:opacity-50"
                   style={{ background: '#F0B90B', color: '#000' }}
                 >
@@ -15,9 +15,9 @@ export function RegisterPage() {
   const { language } = useLanguage()
   const { register, completeRegistration } = useAuth()
   const navigate = useNavigate()
-  const [step, setStep] = useState<'register' | 'setup-otp' | 'verify-otp'>(
-    'register'
-  )
+  const [step, setStep] = useState<
+    'register' | 'setup-otp' | 'verify-otp' | 'recovery-codes'
+  >('register')
   const [email, setEmail] = useState('')
   const [password, setPassword] = useState('')
   const [confirmPassword, setConfirmPassword] = useState('')
@@ -28,6 +28,7 @@ export function RegisterPage() {
   const [userID, setUserID] = useState('')
   const [otpSecret, setOtpSecret] = useState('')
   const [qrCodeURL, setQrCodeURL] = useState('')
+  const [recoveryCodes, setRecoveryCodes] = useState<string[]>([])
   const [error, setError] = useState('')
   const [loading, setLoading] = useState(false)
   const [passwordValid, setPasswordValid] = useState(false)
@@ -99,6 +100,10 @@ export function RegisterPage() {
       const msg = result.message || t('registrationFailed', language)
       setError(msg)
       toast.error(msg)
+    } else if (result.recoveryCodes?.length) {
+      // 恢复码只展示这一次，确认保存后再进入系统
+      setRecoveryCodes(result.recoveryCodes)
+      setStep('recovery-codes')
     }
     // 成功的话AuthContext会自动处理登录状态
 
@@ -109,6 +114,16 @@ export function RegisterPage() {
     copyWithToast(text)
   }
 
+  const handleRecoveryCodesSaved = () => {
+    const returnUrl = sessionStorage.getItem('returnUrl')
+    if (returnUrl) {
+      sessionStorage.removeItem('returnUrl')
+      navigate(returnUrl)
+    } else {
+      navigate('/traders')
+    }
+  }
+
   return (
     <div
       className="flex items-center justify-center py-12"
@@ -551,6 +566,58 @@ export function RegisterPage() {
               </div>
             </form>
           )}
+
+          {step === 'recovery-codes' && (
+            <div className="space-y-4">
+              <div className="text-center mb-2">
+                <div className="text-4xl mb-2">🗝️</div>
+                <h3
+                  className="text-lg font-semibold"
+                  style={{ color: 'var(--brand-light-gray)' }}
+                >
+                  {t('recoveryCodesTitle', language)}
+                </h3>
+                <p className="text-sm mt-1" style={{ color: '#848E9C' }}>
+                  {t('recoveryCodesHint', language)}
+                </p>
+              </div>
+
+              <div
+                className="grid grid-cols-2 gap-2 p-3 rounded font-mono text-sm text-center"
+                style={{
+                  background: 'var(--brand-black)',
+                  border: '1px solid var(--panel-border)',
+                  color: 'var(--brand-light-gray)',
+                }}
+              >
+                {recoveryCodes.map((code) => (
+                  <span key={code}>{code}</span>
+                ))}
+              </div>
+
+              <div className="flex gap-3">
+                <button
+                  type="button"
+                  onClick={() => copyToClipboard(recoveryCodes.join('\n'))}
+                  className="flex-1 px-4 py-2 rounded text-sm font-semibold"
+                  style={{
+                    background: 'var(--panel-bg-hover)',
+                    color: 'var(--text-secondary)',
+                  }}
+                >
+                  {t('recoveryCodesCopy', language)}
+                </button>
+                <button
+                  type="button"
+                  onClick={handleRecoveryCodesSaved}
+                  className="flex-1 px-4 py-2 rounded text-sm font-semibold transition-all hover:scale-105"
+                  style={{ background: '#F0B90B', color: '#000' }}
+                >
+                  {t('recoveryCodesContinue', language)}
+                </button>
+              </div>
+            </div>
+          )}
         </div>
 
         {/* Login Link */}
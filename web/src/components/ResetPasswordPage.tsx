@@ -7,6 +7,7 @@ import { ArrowLeft, KeyRound, Eye, EyeOff } from 'lucide-react'
 import PasswordChecklist from 'react-password-checklist'
 import { Input } from './ui/input'
 import { toast } from 'sonner'
+import { isOTPInputComplete, sanitizeOTPInput } from '../lib/otp'
 
 export function ResetPasswordPage() {
   const { language } = useLanguage()
@@ -247,7 +248,7 @@ export function ResetPasswordPage() {
                     type="text"
                     value={otpCode}
                     onChange={(e) =>
-                      setOtpCode(e.target.value.replace(/\D/g, '').slice(0, 6))
+                      setOtpCode(sanitizeOTPInput(e.target.value))
                     }
                     className="w-full px-3 py-2 rounded text-center text-2xl font-mono"
                     style={{
@@ -256,7 +257,7 @@ export function ResetPasswordPage() {
                       color: '#EAECEF',
                     }}
                     placeholder={t('otpPlaceholder', language)}
-                    maxLength={6}
+                    maxLength={11}
                     required
                   />
                 </div>
@@ -275,7 +276,7 @@ export function ResetPasswordPage() {
 
                 <button
                   type="submit"
-                  disabled={loading || otpCode.length !== 6 || !passwordValid}
+                  disabled={loading || !isOTPInputComplete(otpCode) || !passwordValid}
                   className="w-full px-4 py-2 rounded text-sm font-semibold transition-all hover:scale-105 disabled:opacity-50"
                   style={{ background: '#F0B90B', color: '#000' }}
                 >
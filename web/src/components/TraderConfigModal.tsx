@@ -1,5 +1,6 @@
 import { useState, useEffect } from 'react'
 import type { AIModel, Exchange, CreateTraderRequest } from '../types'
+import { PAPER_EXCHANGE_ID } from '../types'
 import { useLanguage } from '../contexts/LanguageContext'
 import { t } from '../i18n/translations'
 import { toast } from 'sonner'
@@ -12,11 +13,20 @@ function getShortName(fullName: string): string {
   return parts.length > 1 ? parts[parts.length - 1] : fullName
 }
 
+// 交易所下拉框的选项值：默认账户只用交易所ID，其他账户附加账户ID
+const EXCHANGE_ACCOUNT_SEPARATOR = ':'
+function exchangeOptionValue(exchangeId: string, accountId?: string): string {
+  return accountId && accountId !== 'default'
+    ? `${exchangeId}${EXCHANGE_ACCOUNT_SEPARATOR}${accountId}`
+    : exchangeId
+}
+
 interface TraderConfigData {
   trader_id?: string
   trader_name: string
   ai_model: string
   exchange_id: string
+  exchange_account_id?: string // 交易所账户ID（为空时使用默认账户）
   btc_eth_leverage: number
   altcoin_leverage: number
   trading_symbols: string
@@ -26,6 +36,18 @@ interface TraderConfigData {
   is_cross_margin: boolean
   use_coin_pool: boolean
   use_oi_top: boolean
+  is_public?: boolean // 是否在公开排行榜中展示
+  max_daily_loss_pct?: number // 最大日亏损百分比（0=关闭熔断）
+  daily_loss_flatten?: boolean // 熔断时是否平仓
+  max_position_value_usdt?: number // 单币种仓位价值上限（USDT，0=不限制）
+  max_total_exposure_pct?: number // 总持仓价值占净值百分比上限（0=不限制）
+  default_stop_loss_pct?: number // 默认止损百分比（0=不使用）
+  default_take_profit_pct?: number // 默认止盈百分比（0=不使用）
+  cooldown_minutes_after_loss?: number // 亏损平仓后同币种冷却分钟数（0=关闭）
+  execution_mode?: 'market' | 'limit' // 开仓执行方式
+  limit_offset_bps?: number // 限价偏移（基点）
+  limit_timeout_seconds?: number // 限价单等待成交时间（秒）
+  limit_fallback?: 'market' | 'cancel' // 限价单超时后的处理
   initial_balance?: number // 可选：创建时不需要，编辑时使用
   scan_interval_minutes: number
 }
@@ -63,6 +85,18 @@ export function TraderConfigModal({
     is_cross_margin: true,
     use_coin_pool: false,
     use_oi_top: false,
+    is_public: false,
+    max_daily_loss_pct: 0,
+    daily_loss_flatten: false,
+    max_position_value_usdt: 0,
+    max_total_exposure_pct: 0,
+    default_stop_loss_pct: 0,
+    default_take_profit_pct: 0,
+    cooldown_minutes_after_loss: 0,
+    execution_mode: 'market',
+    limit_offset_bps: 0,
+    limit_timeout_seconds: 30,
+    limit_fallback: 'market',
     scan_interval_minutes: 3,
   })
   const [isSaving, setIsSaving] = useState(false)
@@ -89,6 +123,7 @@ export function TraderConfigModal({
         trader_name: '',
         ai_model: availableModels[0]?.id || '',
         exchange_id: availableExchanges[0]?.id || '',
+        exchange_account_id: availableExchanges[0]?.account_id || '',
         btc_eth_leverage: 5,
         altcoin_leverage: 3,
         trading_symbols: '',
@@ -98,6 +133,18 @@ export function TraderConfigModal({
         is_cross_margin: true,
         use_coin_pool: false,
         use_oi_top: false,
+        is_public: false,
+        max_daily_loss_pct: 0,
+        daily_loss_flatten: false,
+        max_position_value_usdt: 0,
+        max_total_exposure_pct: 0,
+        default_stop_loss_pct: 0,
+        default_take_profit_pct: 0,
+        cooldown_minutes_after_loss: 0,
+        execution_mode: 'market',
+        limit_offset_bps: 0,
+        limit_timeout_seconds: 30,
+        limit_fallback: 'market',
         initial_balance: 1000,
         scan_interval_minutes: 3,
       })
@@ -223,6 +270,8 @@ export function TraderConfigModal({
     }
   }
 
+  const isPaper = formData.exchange_id === PAPER_EXCHANGE_ID
+
   const handleSave = async () => {
     if (!onSave) return
 
@@ -232,6 +281,7 @@ export function TraderConfigModal({
         name: formData.trader_name,
         ai_model_id: formData.ai_model,
         exchange_id: formData.exchange_id,
+        exchange_account_id: formData.exchange_account_id || '',
         btc_eth_leverage: formData.btc_eth_leverage,
         altcoin_leverage: formData.altcoin_leverage,
         trading_symbols: formData.trading_symbols,
@@ -241,11 +291,26 @@ export function TraderConfigModal({
         is_cross_margin: formData.is_cross_margin,
         use_coin_pool: formData.use_coin_pool,
         use_oi_top: formData.use_oi_top,
+        is_public: formData.is_public ?? false,
+        max_daily_loss_pct: formData.max_daily_loss_pct ?? 0,
+        daily_loss_flatten: formData.daily_loss_flatten ?? false,
+        max_position_value_usdt: formData.max_position_value_usdt ?? 0,
+        max_total_exposure_pct: formData.max_total_exposure_pct ?? 0,
+        default_stop_loss_pct: formData.default_stop_loss_pct ?? 0,
+        default_take_profit_pct: formData.default_take_profit_pct ?? 0,
+        cooldown_minutes_after_loss: formData.cooldown_minutes_after_loss ?? 0,
+        execution_mode: formData.execution_mode ?? 'market',
+        limit_offset_bps: formData.limit_offset_bps ?? 0,
+        limit_timeout_seconds: formData.limit_timeout_seconds ?? 30,
+        limit_fallback: formData.limit_fallback ?? 'market',
         scan_interval_minutes: formData.scan_interval_minutes,
       }
 
-      // 只在编辑模式时包含initial_balance（用于手动更新）
-      if (isEditMode && formData.initial_balance !== undefined) {
+      // 编辑模式时包含initial_balance（用于手动更新）；模拟盘创建时作为虚拟初始资金
+      if (
+        (isEditMode || isPaper) &&
+        formData.initial_balance !== undefined
+      ) {
         saveData.initial_balance = formData.initial_balance
       }
 
@@ -345,19 +410,35 @@ export function TraderConfigModal({
                     交易所
                   </label>
                   <select
-                    value={formData.exchange_id}
-                    onChange={(e) =>
-                      handleInputChange('exchange_id', e.target.value)
-                    }
+                    value={exchangeOptionValue(
+                      formData.exchange_id,
+                      formData.exchange_account_id
+                    )}
+                    onChange={(e) => {
+                      const [exchangeId, accountId = ''] =
+                        e.target.value.split(EXCHANGE_ACCOUNT_SEPARATOR)
+                      setFormData((prev) => ({
+                        ...prev,
+                        exchange_id: exchangeId,
+                        exchange_account_id: accountId,
+                      }))
+                    }}
                     className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
                   >
-                    {availableExchanges.map((exchange) => (
-                      <option key={exchange.id} value={exchange.id}>
-                        {getShortName(
-                          exchange.name || exchange.id
-                        ).toUpperCase()}
-                      </option>
-                    ))}
+                    {availableExchanges.map((exchange) => {
+                      const value = exchangeOptionValue(
+                        exchange.id,
+                        exchange.account_id
+                      )
+                      return (
+                        <option key={value} value={value}>
+                          {getShortName(
+                            exchange.name || exchange.id
+                          ).toUpperCase()}
+                          {exchange.label ? ` · ${exchange.label}` : ''}
+                        </option>
+                      )
+                    })}
                   </select>
                 </div>
               </div>
@@ -448,7 +529,30 @@ export function TraderConfigModal({
                     )}
                   </div>
                 )}
-                {!isEditMode && (
+                {!isEditMode && isPaper && (
+                  <div>
+                    <label className="text-sm text-[#EAECEF] mb-2 block">
+                      虚拟初始资金 ($)
+                    </label>
+                    <input
+                      type="number"
+                      value={formData.initial_balance || 0}
+                      onChange={(e) =>
+                        handleInputChange(
+                          'initial_balance',
+                          Number(e.target.value)
+                        )
+                      }
+                      className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                      min="100"
+                      step="0.01"
+                    />
+                    <p className="text-xs text-[#848E9C] mt-1">
+                      模拟盘按实时标记价格撮合，不会动用真实资金
+                    </p>
+                  </div>
+                )}
+                {!isEditMode && !isPaper && (
                   <div>
                     <label className="text-sm text-[#EAECEF] mb-2 block">
                       初始余额
@@ -631,6 +735,281 @@ export function TraderConfigModal({
             </div>
           </div>
 
+          {/* Visibility */}
+          <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
+            <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
+              🏆 公开展示
+            </h3>
+            <div className="flex items-center gap-3">
+              <input
+                type="checkbox"
+                checked={formData.is_public ?? false}
+                onChange={(e) =>
+                  handleInputChange('is_public', e.target.checked)
+                }
+                className="w-4 h-4"
+              />
+              <label className="text-sm text-[#EAECEF]">
+                在公开排行榜和竞赛页面展示该交易员（名称、收益和权益曲线）
+              </label>
+            </div>
+          </div>
+
+          {/* Position & Exposure Limits */}
+          <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
+            <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
+              🛡️ 仓位上限
+            </h3>
+            <div className="grid grid-cols-2 gap-4">
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  单币种仓位上限 (USDT)
+                </label>
+                <input
+                  type="number"
+                  value={formData.max_position_value_usdt ?? 0}
+                  onChange={(e) =>
+                    handleInputChange(
+                      'max_position_value_usdt',
+                      Number(e.target.value)
+                    )
+                  }
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                  min="0"
+                  step="100"
+                />
+              </div>
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  总敞口上限 (% 净值)
+                </label>
+                <input
+                  type="number"
+                  value={formData.max_total_exposure_pct ?? 0}
+                  onChange={(e) =>
+                    handleInputChange(
+                      'max_total_exposure_pct',
+                      Number(e.target.value)
+                    )
+                  }
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                  min="0"
+                  max="5000"
+                  step="10"
+                />
+              </div>
+            </div>
+            <p className="text-xs text-[#848E9C] mt-2">
+              计入已有持仓；AI 开仓超出上限时自动缩减，额度用尽时拒绝开仓。0
+              表示不限制
+            </p>
+          </div>
+
+          {/* Default Stop-Loss / Take-Profit */}
+          <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
+            <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
+              🎯 默认止盈止损
+            </h3>
+            <div className="grid grid-cols-2 gap-4">
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  默认止损 (% 开仓价)
+                </label>
+                <input
+                  type="number"
+                  value={formData.default_stop_loss_pct ?? 0}
+                  onChange={(e) =>
+                    handleInputChange(
+                      'default_stop_loss_pct',
+                      Number(e.target.value)
+                    )
+                  }
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                  min="0"
+                  max="99"
+                  step="0.5"
+                />
+              </div>
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  默认止盈 (% 开仓价)
+                </label>
+                <input
+                  type="number"
+                  value={formData.default_take_profit_pct ?? 0}
+                  onChange={(e) =>
+                    handleInputChange(
+                      'default_take_profit_pct',
+                      Number(e.target.value)
+                    )
+                  }
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                  min="0"
+                  max="99"
+                  step="0.5"
+                />
+              </div>
+            </div>
+            <p className="text-xs text-[#848E9C] mt-2">
+              AI 未给出止损/止盈或价格已越过成交价时使用；开仓后立即在交易所挂只减仓触发单。0
+              表示不使用
+            </p>
+          </div>
+
+          {/* Daily Loss Circuit Breaker */}
+          <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
+            <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
+              ⛔ 日亏损熔断
+            </h3>
+            <div className="space-y-4">
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  最大日亏损 (%)
+                </label>
+                <input
+                  type="number"
+                  value={formData.max_daily_loss_pct ?? 0}
+                  onChange={(e) =>
+                    handleInputChange(
+                      'max_daily_loss_pct',
+                      Number(e.target.value)
+                    )
+                  }
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                  min="0"
+                  max="99"
+                  step="0.5"
+                />
+                <p className="text-xs text-[#848E9C] mt-1">
+                  当日净值较日初回撤超过该比例时停止开新仓，次日 00:00 UTC
+                  自动恢复；0 表示关闭
+                </p>
+              </div>
+              <div className="flex items-center gap-3">
+                <input
+                  type="checkbox"
+                  checked={formData.daily_loss_flatten ?? false}
+                  onChange={(e) =>
+                    handleInputChange('daily_loss_flatten', e.target.checked)
+                  }
+                  className="w-4 h-4"
+                />
+                <label className="text-sm text-[#EAECEF]">
+                  熔断时平掉所有持仓
+                </label>
+              </div>
+            </div>
+          </div>
+
+          {/* Loss Cooldown */}
+          <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
+            <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
+              🧊 亏损冷却
+            </h3>
+            <div>
+              <label className="text-sm text-[#EAECEF] block mb-2">
+                亏损平仓后冷却时间 (分钟)
+              </label>
+              <input
+                type="number"
+                value={formData.cooldown_minutes_after_loss ?? 0}
+                onChange={(e) =>
+                  handleInputChange(
+                    'cooldown_minutes_after_loss',
+                    Number(e.target.value)
+                  )
+                }
+                className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                min="0"
+                max="10080"
+                step="5"
+              />
+              <p className="text-xs text-[#848E9C] mt-1">
+                某币种亏损平仓（包括止损触发和手动平仓）后，在冷却时间内拒绝 AI
+                再次开仓该币种；0 表示关闭
+              </p>
+            </div>
+          </div>
+
+          {/* Order Execution */}
+          <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
+            <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
+              ⚡ 开仓执行方式
+            </h3>
+            <div className="grid grid-cols-2 gap-4">
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  执行方式
+                </label>
+                <select
+                  value={formData.execution_mode ?? 'market'}
+                  onChange={(e) =>
+                    handleInputChange('execution_mode', e.target.value)
+                  }
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none"
+                >
+                  <option value="market">市价单</option>
+                  <option value="limit">限价单（买一/卖一挂单）</option>
+                </select>
+              </div>
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  超时处理
+                </label>
+                <select
+                  value={formData.limit_fallback ?? 'market'}
+                  onChange={(e) =>
+                    handleInputChange('limit_fallback', e.target.value)
+                  }
+                  disabled={formData.execution_mode !== 'limit'}
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none disabled:opacity-50"
+                >
+                  <option value="market">剩余部分转市价</option>
+                  <option value="cancel">撤单放弃</option>
+                </select>
+              </div>
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  限价偏移 (基点)
+                </label>
+                <input
+                  type="number"
+                  value={formData.limit_offset_bps ?? 0}
+                  onChange={(e) =>
+                    handleInputChange('limit_offset_bps', Number(e.target.value))
+                  }
+                  disabled={formData.execution_mode !== 'limit'}
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none disabled:opacity-50"
+                  min="-50"
+                  max="50"
+                  step="0.5"
+                />
+              </div>
+              <div>
+                <label className="text-sm text-[#EAECEF] block mb-2">
+                  等待成交时间 (秒)
+                </label>
+                <input
+                  type="number"
+                  value={formData.limit_timeout_seconds ?? 30}
+                  onChange={(e) =>
+                    handleInputChange(
+                      'limit_timeout_seconds',
+                      Number(e.target.value)
+                    )
+                  }
+                  disabled={formData.execution_mode !== 'limit'}
+                  className="w-full px-3 py-2 bg-[#0B0E11] border border-[#2B3139] rounded text-[#EAECEF] focus:border-[#F0B90B] focus:outline-none disabled:opacity-50"
+                  min="5"
+                  max="600"
+                />
+              </div>
+            </div>
+            <p className="text-xs text-[#848E9C] mt-2">
+              限价模式下多单挂买一价、空单挂卖一价（偏移为正时向对手价让价，提高成交率），超时后撤单并按设置处理未成交部分；平仓始终为只减仓的市价单。成交均价与决策价格的偏差会记录为滑点
+            </p>
+          </div>
+
           {/* Trading Prompt */}
           <div className="bg-[#0B0E11] border border-[#2B3139] rounded-lg p-5">
             <h3 className="text-lg font-semibold text-[#EAECEF] mb-5 flex items-center gap-2">
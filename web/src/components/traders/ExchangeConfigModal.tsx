@@ -518,6 +518,33 @@ export function ExchangeConfigModal({
                         />
                       </div>
 
+                      {/* 币安/Bybit 测试网开关 */}
+                      {(selectedExchange.id === 'binance' ||
+                        selectedExchange.id === 'bybit') && (
+                        <label
+                          className="flex items-start gap-2 text-sm cursor-pointer"
+                          style={{ color: '#EAECEF' }}
+                        >
+                          <input
+                            type="checkbox"
+                            checked={testnet}
+                            onChange={(e) => setTestnet(e.target.checked)}
+                            className="mt-1"
+                          />
+                          <span>
+                            <span className="font-semibold">
+                              {t('testnet', language)}
+                            </span>
+                            <span
+                              className="block text-xs"
+                              style={{ color: '#848E9C' }}
+                            >
+                              {t('testnetDescription', language)}
+                            </span>
+                          </span>
+                        </label>
+                      )}
+
                       {selectedExchange.id === 'okx' && (
                         <div>
                           <label
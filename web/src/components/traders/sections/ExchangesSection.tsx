@@ -34,7 +34,7 @@ export function ExchangesSection({
           const inUse = isExchangeInUse(exchange.id)
           return (
             <div
-              key={exchange.id}
+              key={`${exchange.id}:${exchange.account_id || 'default'}`}
               className={`flex items-center justify-between p-2 md:p-3 rounded transition-all ${
                 inUse
                   ? 'cursor-not-allowed'
@@ -53,6 +53,7 @@ export function ExchangesSection({
                     style={{ color: '#EAECEF' }}
                   >
                     {getShortName(exchange.name)}
+                    {exchange.label ? ` · ${exchange.label}` : ''}
                   </div>
                   <div className="text-xs" style={{ color: '#848E9C' }}>
                     {exchange.type.toUpperCase()} •{' '}
@@ -62,6 +63,11 @@ export function ExchangesSection({
                         ? t('enabled', language)
                         : t('configured', language)}
                   </div>
+                  {exchange.trade_permission === 'disabled' && (
+                    <div className="text-xs" style={{ color: '#F6465D' }}>
+                      ⚠️ {t('tradePermissionDisabled', language)}
+                    </div>
+                  )}
                 </div>
               </div>
               <div
@@ -1,4 +1,5 @@
-import { Bot, Plus, Radio } from 'lucide-react'
+import { useRef } from 'react'
+import { Bot, Plus, Radio, Upload } from 'lucide-react'
 import { t, type Language } from '../../../i18n/translations'
 
 interface PageHeaderProps {
@@ -10,6 +11,7 @@ interface PageHeaderProps {
   onAddExchange: () => void
   onConfigureSignalSource: () => void
   onCreateTrader: () => void
+  onImportTrader: (file: File) => void
 }
 
 export function PageHeader({
@@ -21,7 +23,9 @@ export function PageHeader({
   onAddExchange,
   onConfigureSignalSource,
   onCreateTrader,
+  onImportTrader,
 }: PageHeaderProps) {
+  const importInputRef = useRef<HTMLInputElement>(null)
   const canCreateTrader =
     configuredModelsCount > 0 && configuredExchangesCount > 0
 
@@ -99,6 +103,31 @@ export function PageHeader({
           {t('signalSource', language)}
         </button>
 
+        <input
+          ref={importInputRef}
+          type="file"
+          accept="application/json,.json"
+          className="hidden"
+          onChange={(e) => {
+            const file = e.target.files?.[0]
+            if (file) onImportTrader(file)
+            e.target.value = ''
+          }}
+        />
+        <button
+          onClick={() => importInputRef.current?.click()}
+          disabled={!canCreateTrader}
+          className="px-3 md:px-4 py-2 rounded text-xs md:text-sm font-semibold transition-all hover:scale-105 disabled:opacity-50 disabled:cursor-not-allowed flex items-center gap-1 md:gap-2 whitespace-nowrap"
+          style={{
+            background: '#2B3139',
+            color: '#EAECEF',
+            border: '1px solid #474D57',
+          }}
+        >
+          <Upload className="w-3 h-3 md:w-4 md:h-4" />
+          {t('importTrader', language)}
+        </button>
+
         <button
           onClick={onCreateTrader}
           disabled={!canCreateTrader}
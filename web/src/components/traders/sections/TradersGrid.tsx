@@ -1,4 +1,4 @@
-import { Bot, BarChart3, Trash2, Pencil } from 'lucide-react'
+import { Bot, BarChart3, Trash2, Pencil, Download } from 'lucide-react'
 import { t, type Language } from '../../../i18n/translations'
 import { getModelDisplayName } from '../index'
 import type { TraderInfo } from '../../../types'
@@ -9,6 +9,7 @@ interface TradersGridProps {
   onTraderSelect: (traderId: string) => void
   onEditTrader: (traderId: string) => void
   onDeleteTrader: (traderId: string) => void
+  onExportTrader: (traderId: string) => void
   onToggleTrader: (traderId: string, running: boolean) => void
 }
 
@@ -18,6 +19,7 @@ export function TradersGrid({
   onTraderSelect,
   onEditTrader,
   onDeleteTrader,
+  onExportTrader,
   onToggleTrader,
 }: TradersGridProps) {
   if (!traders || traders.length === 0) {
@@ -120,13 +122,10 @@ export function TradersGrid({
 
               <button
                 onClick={() => onEditTrader(trader.trader_id)}
-                disabled={trader.is_running}
-                className="px-2 md:px-3 py-1.5 md:py-2 rounded text-xs md:text-sm font-semibold transition-all hover:scale-105 disabled:opacity-50 disabled:cursor-not-allowed whitespace-nowrap flex items-center gap-1"
+                className="px-2 md:px-3 py-1.5 md:py-2 rounded text-xs md:text-sm font-semibold transition-all hover:scale-105 whitespace-nowrap flex items-center gap-1"
                 style={{
-                  background: trader.is_running
-                    ? 'rgba(132, 142, 156, 0.1)'
-                    : 'rgba(255, 193, 7, 0.1)',
-                  color: trader.is_running ? '#848E9C' : '#FFC107',
+                  background: 'rgba(255, 193, 7, 0.1)',
+                  color: '#FFC107',
                 }}
               >
                 <Pencil className="w-3 h-3 md:w-4 md:h-4" />
@@ -153,6 +152,18 @@ export function TradersGrid({
                 {trader.is_running ? t('stop', language) : t('start', language)}
               </button>
 
+              <button
+                onClick={() => onExportTrader(trader.trader_id)}
+                title={t('exportTrader', language)}
+                className="px-2 md:px-3 py-1.5 md:py-2 rounded text-xs md:text-sm font-semibold transition-all hover:scale-105"
+                style={{
+                  background: 'rgba(132, 142, 156, 0.1)',
+                  color: '#848E9C',
+                }}
+              >
+                <Download className="w-3 h-3 md:w-4 md:h-4" />
+              </button>
+
               <button
                 onClick={() => onDeleteTrader(trader.trader_id)}
                 className="px-2 md:px-3 py-1.5 md:py-2 rounded text-xs md:text-sm font-semibold transition-all hover:scale-105"
@@ -41,7 +41,11 @@ interface AuthContextType {
   completeRegistration: (
     userID: string,
     otpCode: string
-  ) => Promise<{ success: boolean; message?: string }>
+  ) => Promise<{
+    success: boolean
+    message?: string
+    recoveryCodes?: string[]
+  }>
   resetPassword: (
     email: string,
     newPassword: string,
@@ -99,10 +103,17 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
       // Note: localStorage cleanup is already done in httpClient
     }
 
+    // Keep React state in sync when httpClient refreshes the access token
+    const handleTokenRefreshed = (event: Event) => {
+      setToken((event as CustomEvent<string>).detail)
+    }
+
     window.addEventListener('unauthorized', handleUnauthorized)
+    window.addEventListener('token-refreshed', handleTokenRefreshed)
 
     return () => {
       window.removeEventListener('unauthorized', handleUnauthorized)
+      window.removeEventListener('token-refreshed', handleTokenRefreshed)
     }
   }, [])
 
@@ -240,6 +251,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         setToken(data.token)
         setUser(userInfo)
         localStorage.setItem('auth_token', data.token)
+        localStorage.setItem('auth_refresh_token', data.refresh_token)
         localStorage.setItem('auth_user', JSON.stringify(userInfo))
 
         // Check and redirect to returnUrl if exists
@@ -284,8 +296,18 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
         setToken(data.token)
         setUser(userInfo)
         localStorage.setItem('auth_token', data.token)
+        localStorage.setItem('auth_refresh_token', data.refresh_token)
         localStorage.setItem('auth_user', JSON.stringify(userInfo))
 
+        // 有恢复码时先由注册页展示（只返回这一次），用户确认后再跳转
+        if (data.recovery_codes?.length) {
+          return {
+            success: true,
+            message: data.message,
+            recoveryCodes: data.recovery_codes as string[],
+          }
+        }
+
         // Check and redirect to returnUrl if exists
         const returnUrl = sessionStorage.getItem('returnUrl')
         if (returnUrl) {
@@ -339,10 +361,15 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
 
   const logout = () => {
     const savedToken = localStorage.getItem('auth_token')
+    const refreshToken = localStorage.getItem('auth_refresh_token')
     if (savedToken) {
       fetch('/api/logout', {
         method: 'POST',
-        headers: { Authorization: `Bearer ${savedToken}` },
+        headers: {
+          'Content-Type': 'application/json',
+          Authorization: `Bearer ${savedToken}`,
+        },
+        body: JSON.stringify({ refresh_token: refreshToken || '' }),
       }).catch(() => {
         /* ignore network errors on logout */
       })
@@ -350,6 +377,7 @@ export function AuthProvider({ children }: { children: React.ReactNode }) {
     setUser(null)
     setToken(null)
     localStorage.removeItem('auth_token')
+    localStorage.removeItem('auth_refresh_token')
     localStorage.removeItem('auth_user')
   }
 
@@ -1,4 +1,4 @@
-import { api } from '../lib/api'
+import { api, TraderRunningConflictError } from '../lib/api'
 import type {
   TraderInfo,
   CreateTraderRequest,
@@ -6,6 +6,11 @@ import type {
   AIModel,
   Exchange,
 } from '../types'
+import {
+  DEFAULT_EXCHANGE_ACCOUNT_ID,
+  PAPER_EXCHANGE_ID,
+  isExchangeAccount,
+} from '../types'
 import { t } from '../i18n/translations'
 import { confirmToast } from '../lib/notify'
 import { toast } from 'sonner'
@@ -93,14 +98,17 @@ export function useTraderActions({
   const handleCreateTrader = async (data: CreateTraderRequest) => {
     try {
       const model = allModels?.find((m) => m.id === data.ai_model_id)
-      const exchange = allExchanges?.find((e) => e.id === data.exchange_id)
+      const exchange = allExchanges?.find((e) =>
+        isExchangeAccount(e, data.exchange_id, data.exchange_account_id)
+      )
 
       if (!model?.enabled) {
         toast.error(t('modelNotConfigured', language))
         return
       }
 
-      if (!exchange?.enabled) {
+      // 模拟盘无需配置交易所
+      if (data.exchange_id !== PAPER_EXCHANGE_ID && !exchange?.enabled) {
         toast.error(t('exchangeNotConfigured', language))
         return
       }
@@ -160,14 +168,16 @@ export function useTraderActions({
         }) || []
 
       const model = enabledModels?.find((m) => m.id === data.ai_model_id)
-      const exchange = enabledExchanges?.find((e) => e.id === data.exchange_id)
+      const exchange = enabledExchanges?.find((e) =>
+        isExchangeAccount(e, data.exchange_id, data.exchange_account_id)
+      )
 
       if (!model) {
         toast.error(t('modelConfigNotExist', language))
         return
       }
 
-      if (!exchange) {
+      if (!exchange && data.exchange_id !== PAPER_EXCHANGE_ID) {
         toast.error(t('exchangeConfigNotExist', language))
         return
       }
@@ -176,6 +186,7 @@ export function useTraderActions({
         name: data.name,
         ai_model_id: data.ai_model_id,
         exchange_id: data.exchange_id,
+        exchange_account_id: data.exchange_account_id,
         initial_balance: data.initial_balance,
         scan_interval_minutes: data.scan_interval_minutes,
         btc_eth_leverage: data.btc_eth_leverage,
@@ -187,13 +198,38 @@ export function useTraderActions({
         is_cross_margin: data.is_cross_margin,
         use_coin_pool: data.use_coin_pool,
         use_oi_top: data.use_oi_top,
+        is_public: data.is_public,
+        max_daily_loss_pct: data.max_daily_loss_pct,
+        daily_loss_flatten: data.daily_loss_flatten,
+        max_position_value_usdt: data.max_position_value_usdt,
+        max_total_exposure_pct: data.max_total_exposure_pct,
+        default_stop_loss_pct: data.default_stop_loss_pct,
+        default_take_profit_pct: data.default_take_profit_pct,
+        cooldown_minutes_after_loss: data.cooldown_minutes_after_loss,
+        execution_mode: data.execution_mode,
+        limit_offset_bps: data.limit_offset_bps,
+        limit_timeout_seconds: data.limit_timeout_seconds,
+        limit_fallback: data.limit_fallback,
       }
 
-      await toast.promise(api.updateTrader(editingTrader.trader_id, request), {
-        loading: '正在保存…',
-        success: '保存成功',
-        error: '保存失败',
-      })
+      let result
+      try {
+        result = await api.updateTrader(editingTrader.trader_id, request)
+      } catch (error) {
+        if (!(error instanceof TraderRunningConflictError)) throw error
+        const ok = await confirmToast(
+          `${error.message}\n确认停止并按新配置重启交易员？`
+        )
+        if (!ok) return
+        result = await api.updateTrader(editingTrader.trader_id, request, true)
+      }
+      if (result.restart_required?.length > 0) {
+        toast.warning(
+          `保存成功，以下配置需重启交易员后生效: ${result.restart_required.join(', ')}`
+        )
+      } else {
+        toast.success('保存成功')
+      }
       setShowEditModal(false)
       setEditingTrader(null)
       // Immediately refresh traders list for better UX
@@ -225,6 +261,45 @@ export function useTraderActions({
     }
   }
 
+  const handleExportTrader = async (traderId: string) => {
+    try {
+      const doc = await api.exportTrader(traderId)
+      const blob = new Blob([JSON.stringify(doc, null, 2)], {
+        type: 'application/json',
+      })
+      const url = URL.createObjectURL(blob)
+      const link = document.createElement('a')
+      link.href = url
+      link.download = `trader-${traderId}.json`
+      link.click()
+      URL.revokeObjectURL(url)
+    } catch (error) {
+      console.error('Failed to export trader:', error)
+      toast.error(t('operationFailed', language))
+    }
+  }
+
+  const handleImportTrader = async (file: File) => {
+    let doc: unknown
+    try {
+      doc = JSON.parse(await file.text())
+    } catch {
+      toast.error(t('importTraderFailed', language))
+      return
+    }
+
+    try {
+      await toast.promise(api.importTrader(doc), {
+        loading: '正在导入…',
+        success: '导入成功',
+        error: (err) => (err instanceof Error ? err.message : '导入失败'),
+      })
+      await mutateTraders()
+    } catch (error) {
+      console.error('Failed to import trader:', error)
+    }
+  }
+
   const handleToggleTrader = async (traderId: string, running: boolean) => {
     try {
       if (running) {
@@ -274,7 +349,7 @@ export function useTraderActions({
     allItems: T[] | undefined
     clearFields: (item: T) => T
     buildRequest: (items: T[]) => any
-    updateApi: (request: any) => Promise<void>
+    updateApi: (request: any) => Promise<unknown>
     refreshApi: () => Promise<T[]>
     setItems: (items: T[]) => void
     closeModal: () => void
@@ -460,19 +535,25 @@ export function useTraderActions({
       }),
       buildRequest: (exchanges) => ({
         exchanges: Object.fromEntries(
-          exchanges.map((exchange) => [
-            exchange.id,
-            {
-              enabled: exchange.enabled,
-              api_key: exchange.apiKey || '',
-              secret_key: exchange.secretKey || '',
-              testnet: exchange.testnet || false,
-              hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
-              aster_user: exchange.asterUser || '',
-              aster_signer: exchange.asterSigner || '',
-              aster_private_key: exchange.asterPrivateKey || '',
-            },
-          ])
+          exchanges
+            .filter(
+              (exchange) =>
+                (exchange.account_id || DEFAULT_EXCHANGE_ACCOUNT_ID) ===
+                DEFAULT_EXCHANGE_ACCOUNT_ID
+            )
+            .map((exchange) => [
+              exchange.id,
+              {
+                enabled: exchange.enabled,
+                api_key: exchange.apiKey || '',
+                secret_key: exchange.secretKey || '',
+                testnet: exchange.testnet || false,
+                hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
+                aster_user: exchange.asterUser || '',
+                aster_signer: exchange.asterSigner || '',
+                aster_private_key: exchange.asterPrivateKey || '',
+              },
+            ])
         ),
       }),
       updateApi: api.updateExchangeConfigsEncrypted,
@@ -548,28 +629,41 @@ export function useTraderActions({
       }
 
       const request = {
+        // exchanges 按交易所ID更新默认账户，其他账户不在此提交
         exchanges: Object.fromEntries(
-          updatedExchanges.map((exchange) => [
-            exchange.id,
-            {
-              enabled: exchange.enabled,
-              api_key: exchange.apiKey || '',
-              secret_key: exchange.secretKey || '',
-              testnet: exchange.testnet || false,
-              hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
-              aster_user: exchange.asterUser || '',
-              aster_signer: exchange.asterSigner || '',
-              aster_private_key: exchange.asterPrivateKey || '',
-            },
-          ])
+          updatedExchanges
+            .filter(
+              (exchange) =>
+                (exchange.account_id || DEFAULT_EXCHANGE_ACCOUNT_ID) ===
+                DEFAULT_EXCHANGE_ACCOUNT_ID
+            )
+            .map((exchange) => [
+              exchange.id,
+              {
+                enabled: exchange.enabled,
+                api_key: exchange.apiKey || '',
+                secret_key: exchange.secretKey || '',
+                testnet: exchange.testnet || false,
+                hyperliquid_wallet_addr: exchange.hyperliquidWalletAddr || '',
+                aster_user: exchange.asterUser || '',
+                aster_signer: exchange.asterSigner || '',
+                aster_private_key: exchange.asterPrivateKey || '',
+              },
+            ])
         ),
       }
 
-      await toast.promise(api.updateExchangeConfigsEncrypted(request), {
+      const savePromise = api.updateExchangeConfigsEncrypted(request)
+      toast.promise(savePromise, {
         loading: '正在更新交易所配置…',
         success: '交易所配置已更新',
         error: '更新交易所配置失败',
       })
+      const result = await savePromise
+      // 例如密钥未开启合约交易权限：可以查看余额，但交易员下单会失败
+      result.warnings?.forEach((warning) =>
+        toast.warning(warning, { duration: 10000 })
+      )
 
       // 重新获取用户配置以确保数据同步
       const refreshedExchanges = await api.getExchangeConfigs()
@@ -625,6 +719,8 @@ export function useTraderActions({
     handleEditTrader,
     handleSaveEditTrader,
     handleDeleteTrader,
+    handleExportTrader,
+    handleImportTrader,
     handleToggleTrader,
     handleAddModel,
     handleAddExchange,
@@ -118,6 +118,15 @@ export const translations = {
     poor: '❌ Poor - Losses exceed gains',
     bestPerformer: 'Best Performer',
     worstPerformer: 'Worst Performer',
+    executionSlippage: 'Execution Slippage',
+    slippageSamples: '{count} fills with recorded price',
+    avgSlippage: 'Avg',
+    worstSlippage: 'Worst',
+    slippageCost: 'Cost',
+    limitFills: 'Limit entries',
+    marketFallbacks: 'Converted to market',
+    fundingFees: 'Funding Fees',
+    fundingFeesHint: 'Paid (-) / received (+) while positions were held, included in PnL',
     symbolPerformance: 'Symbol Performance',
     tradeHistory: 'Trade History',
     completedTrades: 'Recent {count} completed trades',
@@ -140,6 +149,8 @@ export const translations = {
     aiModels: 'AI Models',
     exchanges: 'Exchanges',
     createTrader: 'Create Trader',
+    importTrader: 'Import',
+    exportTrader: 'Export config',
     modelConfiguration: 'Model Configuration',
     configured: 'Configured',
     notConfigured: 'Not Configured',
@@ -157,6 +168,16 @@ export const translations = {
     modelNotConfigured: 'Selected model is not configured',
     exchangeNotConfigured: 'Selected exchange is not configured',
     confirmDeleteTrader: 'Are you sure you want to delete this trader?',
+    emergencyStop: 'Emergency Stop',
+    confirmEmergencyStop:
+      'Stop ALL of your traders now? No new trading cycle will run after this.',
+    confirmEmergencyClosePositions:
+      'Also market-close every open position on the exchange?',
+    emergencyClosePositions: 'Close positions',
+    emergencyStopOnly: 'Stop only',
+    emergencyStopDone: 'All traders stopped',
+    emergencyStopPartial:
+      'All traders stopped, but some positions failed to close. Please retry.',
     status: 'Status',
     start: 'Start',
     stop: 'Stop',
@@ -336,6 +357,8 @@ export const translations = {
     noActiveApis: 'No AI APIs are running yet',
     noLossForModel: 'No losses for this model yet',
     noExchangesConfigured: 'No configured exchanges',
+    tradePermissionDisabled:
+      'API key has no futures trading permission, orders will fail',
     signalSource: 'Signal Source',
     signalSourceConfig: 'Signal Source Configuration',
     coinPoolDescription:
@@ -364,6 +387,9 @@ export const translations = {
     customBaseURLPlaceholder:
       'Custom API base URL, e.g.: https://api.openai.com/v1',
     leaveBlankForDefault: 'Leave blank to use default API address',
+    localAPIKeyOptional: 'Optional for local models (Ollama / vLLM)',
+    localBaseURLRequired:
+      'Required for local models, e.g.: http://localhost:11434/v1',
     modelConfigInfo1:
       '• API Key will be encrypted and stored, please ensure it is valid',
     modelConfigInfo2: '• Base URL is used for custom API server address',
@@ -403,6 +429,12 @@ export const translations = {
       'Exchange configuration does not exist or is not enabled',
     updateTraderFailed: 'Failed to update trader',
     deleteTraderFailed: 'Failed to delete trader',
+    importTraderFailed: 'Failed to import trader',
+    paperTrading: 'Paper',
+    testnet: 'Testnet',
+    circuitBroken: 'Daily loss limit hit',
+    dailyLossBudget: 'Loss budget',
+    realMoneyOnly: 'Real money only',
     operationFailed: 'Operation failed',
     deleteConfigFailed: 'Failed to delete configuration',
     modelNotExist: 'Model does not exist',
@@ -472,6 +504,11 @@ export const translations = {
     authStep3Title: 'Step 3: Verify setup',
     authStep3Desc: 'After setup, continue to enter the 6-digit code',
     setupCompleteContinue: 'I have completed setup, continue',
+    recoveryCodesTitle: 'Save your recovery codes',
+    recoveryCodesHint:
+      'Each code can be used once instead of an authenticator code if you lose your phone. They will not be shown again.',
+    recoveryCodesCopy: 'Copy codes',
+    recoveryCodesContinue: 'I have saved them, continue',
     copy: 'Copy',
     completeRegistration: 'Complete Registration',
     completeRegistrationSubtitle: 'to complete registration',
@@ -973,6 +1010,15 @@ export const translations = {
     poor: '❌ 较差 - 亏损超过盈利',
     bestPerformer: '最佳表现',
     worstPerformer: '最差表现',
+    executionSlippage: '成交滑点',
+    slippageSamples: '{count} 笔记录了成交均价的操作',
+    avgSlippage: '平均',
+    worstSlippage: '最差',
+    slippageCost: '成本',
+    limitFills: '限价开仓',
+    marketFallbacks: '超时转市价',
+    fundingFees: '资金费',
+    fundingFeesHint: '持仓期间支付（-）/ 收到（+）的资金费，已计入盈亏',
     symbolPerformance: '📊 币种表现',
     tradeHistory: '历史成交',
     completedTrades: '最近 {count} 笔已完成交易',
@@ -995,6 +1041,8 @@ export const translations = {
     aiModels: 'AI模型',
     exchanges: '交易所',
     createTrader: '创建交易员',
+    importTrader: '导入',
+    exportTrader: '导出配置',
     modelConfiguration: '模型配置',
     configured: '已配置',
     notConfigured: '未配置',
@@ -1011,6 +1059,13 @@ export const translations = {
     modelNotConfigured: '所选模型未配置',
     exchangeNotConfigured: '所选交易所未配置',
     confirmDeleteTrader: '确定要删除这个交易员吗？',
+    emergencyStop: '紧急停止',
+    confirmEmergencyStop: '确定要立即停止你的所有交易员吗？停止后不会再执行新的交易周期。',
+    confirmEmergencyClosePositions: '是否同时市价平掉交易所上的所有持仓？',
+    emergencyClosePositions: '全部平仓',
+    emergencyStopOnly: '仅停止',
+    emergencyStopDone: '所有交易员已停止',
+    emergencyStopPartial: '所有交易员已停止，但部分持仓平仓失败，请重试',
     status: '状态',
     start: '启动',
     stop: '停止',
@@ -1179,6 +1234,7 @@ export const translations = {
     noActiveApis: '暂无正在运行的 AI API',
     noLossForModel: '该模型暂未出现亏损',
     noExchangesConfigured: '暂无已配置的交易所',
+    tradePermissionDisabled: 'API密钥未开启合约交易权限，下单会失败',
     signalSource: '信号源',
     signalSourceConfig: '信号源配置',
     coinPoolDescription: '用于获取币种池数据的API地址，留空则不使用此信号源',
@@ -1199,6 +1255,8 @@ export const translations = {
     customBaseURL: 'Base URL (可选)',
     customBaseURLPlaceholder: '自定义API基础URL，如: https://api.openai.com/v1',
     leaveBlankForDefault: '留空则使用默认API地址',
+    localAPIKeyOptional: '本地模型（Ollama / vLLM）可不填',
+    localBaseURLRequired: '本地模型必填，如: http://localhost:11434/v1',
     modelConfigInfo1: '• API Key将被加密存储，请确保密钥有效',
     modelConfigInfo2: '• Base URL用于自定义API服务器地址',
     modelConfigInfo3: '• 删除配置后，使用此模型的交易员将无法正常工作',
@@ -1230,6 +1288,12 @@ export const translations = {
     exchangeConfigNotExist: '交易所配置不存在或未启用',
     updateTraderFailed: '更新交易员失败',
     deleteTraderFailed: '删除交易员失败',
+    importTraderFailed: '导入交易员失败',
+    paperTrading: '模拟盘',
+    testnet: '测试网',
+    circuitBroken: '日亏损熔断',
+    dailyLossBudget: '剩余亏损额度',
+    realMoneyOnly: '仅看实盘',
     operationFailed: '操作失败',
     deleteConfigFailed: '删除配置失败',
     modelNotExist: '模型不存在',
@@ -1294,6 +1358,11 @@ export const translations = {
     authStep3Title: '步骤3：验证设置',
     authStep3Desc: '设置完成后，点击下方按钮输入6位验证码',
     setupCompleteContinue: '我已完成设置，继续',
+    recoveryCodesTitle: '保存您的恢复码',
+    recoveryCodesHint:
+      '手机丢失时，每个恢复码可代替一次验证码使用。恢复码只显示这一次，请妥善保存。',
+    recoveryCodesCopy: '复制恢复码',
+    recoveryCodesContinue: '我已保存，继续',
     copy: '复制',
     completeRegistration: '完成注册',
     completeRegistrationSubtitle: '以完成注册',
@@ -3,21 +3,43 @@ import type {
   AccountInfo,
   Position,
   DecisionRecord,
+  DecisionPage,
   Statistics,
   TraderInfo,
+  UpdateTraderResponse,
   TraderConfigData,
   AIModel,
   Exchange,
   CreateTraderRequest,
   UpdateModelConfigRequest,
   UpdateExchangeConfigRequest,
+  UpdateExchangeConfigResponse,
   CompetitionData,
+  EmergencyStopResult,
 } from '../types'
-import { CryptoService } from './crypto'
+import { CryptoService, diagnoseWebCryptoEnvironment } from './crypto'
 import { httpClient } from './httpClient'
 
 const API_BASE = '/api'
 
+// 收益率历史查询范围（from/to 为 RFC3339 或 Unix 时间戳，resolution 如 5m/1h/1d/raw）
+export interface EquityHistoryRange {
+  from?: string
+  to?: string
+  resolution?: string
+}
+
+function equityRangeParams(range?: EquityHistoryRange): URLSearchParams {
+  const params = new URLSearchParams()
+  if (range?.from) params.append('from', range.from)
+  if (range?.to) params.append('to', range.to)
+  if (range?.resolution) params.append('resolution', range.resolution)
+  return params
+}
+
+// 交易员运行中修改AI模型/交易所时返回409，需确认后带 restart=true 重试
+export class TraderRunningConflictError extends Error {}
+
 // Helper function to get auth headers
 function getAuthHeaders(): Record<string, string> {
   const token = localStorage.getItem('auth_token')
@@ -32,6 +54,34 @@ function getAuthHeaders(): Record<string, string> {
   return headers
 }
 
+// 获取敏感配置：支持 WebCrypto 时携带临时公钥，请求服务端加密响应（HTTP 部署下避免明文传输），否则按明文获取
+async function getSensitiveJSON<T>(
+  url: string,
+  errorMessage: string
+): Promise<T> {
+  const headers = getAuthHeaders()
+  if (diagnoseWebCryptoEnvironment().hasSubtleCrypto) {
+    headers['X-Response-Encryption-Key'] =
+      await CryptoService.getResponseKeyHeader()
+  }
+  const res = await httpClient.get(url, headers)
+  if (!res.ok) throw new Error(errorMessage)
+  if (res.headers.get('X-Response-Encrypted') === '1') {
+    return JSON.parse(await CryptoService.decryptResponse(await res.json()))
+  }
+  return res.json()
+}
+
+// 当前登录用户ID（加密敏感数据时写入 AAD，服务端只接受当前用户加密的数据）
+function getCurrentUserId(): string {
+  try {
+    const user = JSON.parse(localStorage.getItem('auth_user') || '{}')
+    return user.id || ''
+  } catch {
+    return ''
+  }
+}
+
 export const api = {
   // AI交易员管理接口
   async getTraders(): Promise<TraderInfo[]> {
@@ -65,6 +115,32 @@ export const api = {
     if (!res.ok) throw new Error('删除交易员失败')
   },
 
+  // 导出交易员配置（不含交易所和AI模型密钥）
+  async exportTrader(traderId: string): Promise<unknown> {
+    const res = await httpClient.get(
+      `${API_BASE}/traders/${traderId}/export`,
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('导出交易员配置失败')
+    return res.json()
+  },
+
+  // 从导出文件导入交易员，校验失败时错误信息包含逐字段说明
+  async importTrader(doc: unknown): Promise<TraderInfo> {
+    const res = await httpClient.post(
+      `${API_BASE}/traders/import`,
+      doc,
+      getAuthHeaders()
+    )
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}))
+      const fields: { field: string; message: string }[] = data.fields || []
+      const detail = fields.map((f) => `${f.field}: ${f.message}`).join('\n')
+      throw new Error(detail || data.error || '导入交易员失败')
+    }
+    return res.json()
+  },
+
   async startTrader(traderId: string): Promise<void> {
     const res = await httpClient.post(
       `${API_BASE}/traders/${traderId}/start`,
@@ -83,6 +159,17 @@ export const api = {
     if (!res.ok) throw new Error('停止交易员失败')
   },
 
+  // 紧急停止当前用户的所有交易员（closePositions=true 时市价平掉全部持仓）
+  async emergencyStop(closePositions: boolean): Promise<EmergencyStopResult> {
+    const res = await httpClient.post(
+      `${API_BASE}/emergency-stop`,
+      { close_positions: closePositions },
+      getAuthHeaders()
+    )
+    if (!res.ok) throw new Error('紧急停止失败')
+    return res.json()
+  },
+
   async updateTraderPrompt(
     traderId: string,
     customPrompt: string
@@ -106,22 +193,25 @@ export const api = {
 
   async updateTrader(
     traderId: string,
-    request: CreateTraderRequest
-  ): Promise<TraderInfo> {
+    request: CreateTraderRequest,
+    restart = false
+  ): Promise<UpdateTraderResponse> {
     const res = await httpClient.put(
-      `${API_BASE}/traders/${traderId}`,
+      `${API_BASE}/traders/${traderId}${restart ? '?restart=true' : ''}`,
       request,
       getAuthHeaders()
     )
+    if (res.status === 409) {
+      const data = await res.json().catch(() => ({}))
+      throw new TraderRunningConflictError(data.error || '交易员运行中')
+    }
     if (!res.ok) throw new Error('更新交易员失败')
     return res.json()
   },
 
   // AI模型配置接口
   async getModelConfigs(): Promise<AIModel[]> {
-    const res = await httpClient.get(`${API_BASE}/models`, getAuthHeaders())
-    if (!res.ok) throw new Error('获取模型配置失败')
-    return res.json()
+    return getSensitiveJSON(`${API_BASE}/models`, '获取模型配置失败')
   },
 
   // 获取系统支持的AI模型列表（无需认证）
@@ -133,13 +223,13 @@ export const api = {
 
   async updateModelConfigs(request: UpdateModelConfigRequest): Promise<void> {
     // 获取RSA公钥
-    const publicKey = await CryptoService.fetchPublicKey()
+    const { publicKey, keyId } = await CryptoService.fetchPublicKey()
 
     // 初始化加密服务
-    await CryptoService.initialize(publicKey)
+    await CryptoService.initialize(publicKey, keyId)
 
     // 获取用户信息（从localStorage或其他地方）
-    const userId = localStorage.getItem('user_id') || ''
+    const userId = getCurrentUserId()
     const sessionId = sessionStorage.getItem('session_id') || ''
 
     // 加密敏感数据
@@ -160,9 +250,7 @@ export const api = {
 
   // 交易所配置接口
   async getExchangeConfigs(): Promise<Exchange[]> {
-    const res = await httpClient.get(`${API_BASE}/exchanges`, getAuthHeaders())
-    if (!res.ok) throw new Error('获取交易所配置失败')
-    return res.json()
+    return getSensitiveJSON(`${API_BASE}/exchanges`, '获取交易所配置失败')
   },
 
   // 获取系统支持的交易所列表（无需认证）
@@ -186,15 +274,15 @@ export const api = {
   // 使用加密传输更新交易所配置
   async updateExchangeConfigsEncrypted(
     request: UpdateExchangeConfigRequest
-  ): Promise<void> {
+  ): Promise<UpdateExchangeConfigResponse> {
     // 获取RSA公钥
-    const publicKey = await CryptoService.fetchPublicKey()
+    const { publicKey, keyId } = await CryptoService.fetchPublicKey()
 
     // 初始化加密服务
-    await CryptoService.initialize(publicKey)
+    await CryptoService.initialize(publicKey, keyId)
 
     // 获取用户信息（从localStorage或其他地方）
-    const userId = localStorage.getItem('user_id') || ''
+    const userId = getCurrentUserId()
     const sessionId = sessionStorage.getItem('session_id') || ''
 
     // 加密敏感数据
@@ -211,6 +299,22 @@ export const api = {
       getAuthHeaders()
     )
     if (!res.ok) throw new Error('更新交易所配置失败')
+    return res.json()
+  },
+
+  // 删除交易所账户（默认账户和仍被交易员使用的账户不可删除）
+  async deleteExchangeAccount(
+    exchangeId: string,
+    accountId: string
+  ): Promise<void> {
+    const res = await httpClient.delete(
+      `${API_BASE}/exchanges/${exchangeId}/accounts/${accountId}`,
+      getAuthHeaders()
+    )
+    if (!res.ok) {
+      const data = await res.json().catch(() => ({}))
+      throw new Error(data.error || '删除交易所账户失败')
+    }
   },
 
   // 获取系统状态（支持trader_id）
@@ -251,12 +355,25 @@ export const api = {
     return res.json()
   },
 
-  // 获取决策日志（支持trader_id）
-  async getDecisions(traderId?: string): Promise<DecisionRecord[]> {
-    const url = traderId
-      ? `${API_BASE}/decisions?trader_id=${traderId}`
-      : `${API_BASE}/decisions`
-    const res = await httpClient.get(url, getAuthHeaders())
+  // 获取决策日志（分页，最新在前；cursor 为上一页返回的 next_cursor）
+  async getDecisions(
+    traderId?: string,
+    cursor?: string,
+    limit: number = 50
+  ): Promise<DecisionPage> {
+    const params = new URLSearchParams()
+    if (traderId) {
+      params.append('trader_id', traderId)
+    }
+    if (cursor) {
+      params.append('cursor', cursor)
+    }
+    params.append('limit', limit.toString())
+
+    const res = await httpClient.get(
+      `${API_BASE}/decisions?${params}`,
+      getAuthHeaders()
+    )
     if (!res.ok) throw new Error('获取决策日志失败')
     return res.json()
   },
@@ -291,20 +408,34 @@ export const api = {
   },
 
   // 获取收益率历史数据（支持trader_id）
-  async getEquityHistory(traderId?: string): Promise<any[]> {
-    const url = traderId
-      ? `${API_BASE}/equity-history?trader_id=${traderId}`
-      : `${API_BASE}/equity-history`
-    const res = await httpClient.get(url, getAuthHeaders())
+  async getEquityHistory(
+    traderId?: string,
+    range?: EquityHistoryRange
+  ): Promise<any[]> {
+    const params = equityRangeParams(range)
+    if (traderId) {
+      params.append('trader_id', traderId)
+    }
+    const res = await httpClient.get(
+      `${API_BASE}/equity-history?${params}`,
+      getAuthHeaders()
+    )
     if (!res.ok) throw new Error('获取历史数据失败')
     return res.json()
   },
 
   // 批量获取多个交易员的历史数据（无需认证）
-  async getEquityHistoryBatch(traderIds: string[]): Promise<any> {
-    const res = await httpClient.post(`${API_BASE}/equity-history-batch`, {
-      trader_ids: traderIds,
-    })
+  async getEquityHistoryBatch(
+    traderIds: string[],
+    range?: EquityHistoryRange
+  ): Promise<any> {
+    const params = equityRangeParams(range)
+    const res = await httpClient.post(
+      `${API_BASE}/equity-history-batch?${params}`,
+      {
+        trader_ids: traderIds,
+      }
+    )
     if (!res.ok) throw new Error('获取批量历史数据失败')
     return res.json()
   },
@@ -345,12 +476,10 @@ export const api = {
     coin_pool_url: string
     oi_top_url: string
   }> {
-    const res = await httpClient.get(
+    return getSensitiveJSON(
       `${API_BASE}/user/signal-sources`,
-      getAuthHeaders()
+      '获取用户信号源配置失败'
     )
-    if (!res.ok) throw new Error('获取用户信号源配置失败')
-    return res.json()
   },
 
   async saveUserSignalSource(
@@ -7,6 +7,11 @@ export interface EncryptedPayload {
   ts?: number // 可选：unix 秒，用于重放保护
 }
 
+export interface ServerPublicKey {
+  publicKey: string
+  keyId?: string // 服务端公钥标识，加密时放入 kid，密钥轮换后服务端据此选择私钥
+}
+
 export interface WebCryptoEnvironmentInfo {
   isBrowser: boolean
   isSecureContext: boolean
@@ -20,8 +25,10 @@ export interface WebCryptoEnvironmentInfo {
 export class CryptoService {
   private static publicKey: CryptoKey | null = null
   private static publicKeyPEM: string | null = null
+  private static keyId: string | undefined
 
-  static async initialize(publicKeyPEM: string) {
+  static async initialize(publicKeyPEM: string, keyId?: string) {
+    this.keyId = keyId
     if (this.publicKey && this.publicKeyPEM === publicKeyPEM) {
       return
     }
@@ -89,12 +96,14 @@ export class CryptoService {
     // 2. 生成 12 字节随机 IV
     const iv = crypto.getRandomValues(new Uint8Array(12))
 
-    // 3. 准备 AAD (额外认证数据)
+    // 3. 准备 AAD (额外认证数据)：服务端校验用户、时间戳并拒绝重复的 nonce
     const ts = Math.floor(Date.now() / 1000)
+    const nonce = crypto.getRandomValues(new Uint8Array(16))
     const aadObject = {
       userId: userId || '',
       sessionId: sessionId || '',
       ts: ts,
+      nonce: this.arrayBufferToBase64Url(nonce.buffer),
       purpose: 'sensitive_data_encryption',
     }
     const aadString = JSON.stringify(aadObject)
@@ -131,10 +140,76 @@ export class CryptoService {
       iv: this.arrayBufferToBase64Url(iv.buffer),
       ciphertext: this.arrayBufferToBase64Url(ciphertext),
       aad: this.arrayBufferToBase64Url(aadBytes.buffer),
+      kid: this.keyId,
       ts: ts,
     }
   }
 
+  // 临时RSA-OAEP密钥对（仅保存在内存中），公钥通过 X-Response-Encryption-Key 请求服务端加密响应
+  private static responseKey: Promise<CryptoKeyPair> | null = null
+
+  static async getResponseKeyHeader(): Promise<string> {
+    if (!this.responseKey) {
+      this.responseKey = crypto.subtle.generateKey(
+        {
+          name: 'RSA-OAEP',
+          modulusLength: 2048,
+          publicExponent: new Uint8Array([1, 0, 1]),
+          hash: 'SHA-256',
+        },
+        false,
+        ['encrypt', 'decrypt']
+      )
+    }
+    const { publicKey } = await this.responseKey
+    const spki = await crypto.subtle.exportKey('spki', publicKey)
+    return this.arrayBufferToBase64Url(spki)
+  }
+
+  // 解密服务端加密的响应（RSA-OAEP 解包 AES 密钥，AES-GCM 解密并校验 AAD）
+  static async decryptResponse(payload: EncryptedPayload): Promise<string> {
+    if (!this.responseKey) {
+      throw new Error('Response key not created')
+    }
+    const { privateKey } = await this.responseKey
+    const rawAesKey = await crypto.subtle.decrypt(
+      { name: 'RSA-OAEP' },
+      privateKey,
+      this.base64UrlToBytes(payload.wrappedKey)
+    )
+    const aesKey = await crypto.subtle.importKey(
+      'raw',
+      rawAesKey,
+      { name: 'AES-GCM' },
+      false,
+      ['decrypt']
+    )
+    const plaintext = await crypto.subtle.decrypt(
+      {
+        name: 'AES-GCM',
+        iv: this.base64UrlToBytes(payload.iv),
+        additionalData: payload.aad
+          ? this.base64UrlToBytes(payload.aad)
+          : undefined,
+        tagLength: 128,
+      },
+      aesKey,
+      this.base64UrlToBytes(payload.ciphertext)
+    )
+    return new TextDecoder().decode(plaintext)
+  }
+
+  private static base64UrlToBytes(value: string): Uint8Array {
+    const base64 = value.replace(/-/g, '+').replace(/_/g, '/')
+    const padded = base64 + '='.repeat((4 - (base64.length % 4)) % 4)
+    const binary = atob(padded)
+    const bytes = new Uint8Array(binary.length)
+    for (let i = 0; i < binary.length; i++) {
+      bytes[i] = binary.charCodeAt(i)
+    }
+    return bytes
+  }
+
   private static arrayBufferToBase64Url(buffer: ArrayBuffer): string {
     const bytes = new Uint8Array(buffer)
     let binary = ''
@@ -147,32 +222,13 @@ export class CryptoService {
       .replace(/=/g, '')
   }
 
-  static async fetchPublicKey(): Promise<string> {
+  static async fetchPublicKey(): Promise<ServerPublicKey> {
     const response = await fetch('/api/crypto/public-key')
     if (!response.ok) {
       throw new Error(`Failed to fetch public key: ${response.statusText}`)
     }
     const data = await response.json()
-    return data.public_key
-  }
-
-  static async decryptSensitiveData(
-    payload: EncryptedPayload
-  ): Promise<string> {
-    const response = await fetch('/api/crypto/decrypt', {
-      method: 'POST',
-      headers: {
-        'Content-Type': 'application/json',
-      },
-      body: JSON.stringify(payload),
-    })
-
-    if (!response.ok) {
-      throw new Error(`Decryption failed: ${response.statusText}`)
-    }
-
-    const result = await response.json()
-    return result.plaintext
+    return { publicKey: data.public_key, keyId: data.key_id }
   }
 }
 
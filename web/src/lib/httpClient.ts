@@ -4,6 +4,7 @@
  * Features:
  * - Unified fetch wrapper
  * - Automatic 401 token expiration handling
+ * - Transparent access token refresh using the stored refresh token
  * - Auth state cleanup on unauthorized
  * - Automatic redirect to login page
  * - Notification shown on login page after redirect
@@ -13,6 +14,9 @@ export class HttpClient {
   // Singleton flag to prevent duplicate 401 handling
   private static isHandling401 = false
 
+  // Shared in-flight refresh so concurrent 401s trigger only one /api/refresh call
+  private static refreshPromise: Promise<string | null> | null = null
+
   /**
    * Reset 401 handling flag (call after successful login)
    */
@@ -40,6 +44,7 @@ export class HttpClient {
 
       // Clean up local storage
       localStorage.removeItem('auth_token')
+      localStorage.removeItem('auth_refresh_token')
       localStorage.removeItem('auth_user')
 
       // Notify global listeners (AuthContext will react to this)
@@ -83,15 +88,68 @@ export class HttpClient {
     return response
   }
 
+  /**
+   * Exchange the stored refresh token for a new token pair.
+   * Returns the new access token, or null if the refresh token is missing or rejected.
+   */
+  private async refreshAccessToken(): Promise<string | null> {
+    if (!HttpClient.refreshPromise) {
+      HttpClient.refreshPromise = (async () => {
+        const refreshToken = localStorage.getItem('auth_refresh_token')
+        if (!refreshToken) return null
+        try {
+          const response = await fetch('/api/refresh', {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json' },
+            body: JSON.stringify({ refresh_token: refreshToken }),
+          })
+          if (!response.ok) return null
+          const data = await response.json()
+          localStorage.setItem('auth_token', data.token)
+          localStorage.setItem('auth_refresh_token', data.refresh_token)
+          // Notify AuthContext so React state picks up the new token
+          window.dispatchEvent(
+            new CustomEvent('token-refreshed', { detail: data.token })
+          )
+          return data.token as string
+        } catch {
+          return null
+        } finally {
+          HttpClient.refreshPromise = null
+        }
+      })()
+    }
+    return HttpClient.refreshPromise
+  }
+
+  /**
+   * Send a request; on 401 for an authenticated request, refresh the access
+   * token once and retry before falling back to the logout flow.
+   */
+  private async send(url: string, init: RequestInit): Promise<Response> {
+    const response = await fetch(url, init)
+    const headers = init.headers as Record<string, string> | undefined
+    if (response.status === 401 && headers?.['Authorization']) {
+      const token = await this.refreshAccessToken()
+      if (token) {
+        const retry = await fetch(url, {
+          ...init,
+          headers: { ...headers, Authorization: `Bearer ${token}` },
+        })
+        return this.handleResponse(retry)
+      }
+    }
+    return this.handleResponse(response)
+  }
+
   /**
    * GET request
    */
   async get(url: string, headers?: Record<string, string>): Promise<Response> {
-    const response = await fetch(url, {
+    return this.send(url, {
       method: 'GET',
       headers,
     })
-    return this.handleResponse(response)
   }
 
   /**
@@ -102,7 +160,7 @@ export class HttpClient {
     body?: any,
     headers?: Record<string, string>
   ): Promise<Response> {
-    const response = await fetch(url, {
+    return this.send(url, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
@@ -110,7 +168,6 @@ export class HttpClient {
       },
       body: body ? JSON.stringify(body) : undefined,
     })
-    return this.handleResponse(response)
   }
 
   /**
@@ -121,7 +178,7 @@ export class HttpClient {
     body?: any,
     headers?: Record<string, string>
   ): Promise<Response> {
-    const response = await fetch(url, {
+    return this.send(url, {
       method: 'PUT',
       headers: {
         'Content-Type': 'application/json',
@@ -129,7 +186,6 @@ export class HttpClient {
       },
       body: body ? JSON.stringify(body) : undefined,
     })
-    return this.handleResponse(response)
   }
 
   /**
@@ -139,19 +195,17 @@ export class HttpClient {
     url: string,
     headers?: Record<string, string>
   ): Promise<Response> {
-    const response = await fetch(url, {
+    return this.send(url, {
       method: 'DELETE',
       headers,
     })
-    return this.handleResponse(response)
   }
 
   /**
    * Generic request method for custom configurations
    */
   async request(url: string, options: RequestInit = {}): Promise<Response> {
-    const response = await fetch(url, options)
-    return this.handleResponse(response)
+    return this.send(url, options)
   }
 }
 
@@ -0,0 +1,29 @@
+/**
+ * OTP 输入工具
+ *
+ * 验证码输入框既接受 6 位 Google Authenticator 验证码，
+ * 也接受 XXXXX-XXXXX 格式的一次性恢复码。
+ */
+
+/**
+ * 规范化输入：纯数字按 OTP 处理（最多 6 位），含字母时按恢复码处理。
+ */
+export function sanitizeOTPInput(value: string): string {
+  if (/[a-z]/i.test(value)) {
+    return value
+      .toUpperCase()
+      .replace(/[^A-Z0-9-]/g, '')
+      .slice(0, 11)
+  }
+  return value.replace(/\D/g, '').slice(0, 6)
+}
+
+/**
+ * 输入是否完整：6 位 OTP 或 10 位恢复码（分隔符可选）。
+ */
+export function isOTPInputComplete(value: string): boolean {
+  if (/^\d{6}$/.test(value)) return true
+  return value.replace(/-/g, '').length === 10
+}
+
+export default { sanitizeOTPInput, isOTPInputComplete }
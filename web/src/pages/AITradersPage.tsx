@@ -19,6 +19,7 @@ import { ExchangesSection } from '../components/traders/sections/ExchangesSectio
 import { TradersGrid } from '../components/traders/sections/TradersGrid'
 import { ModelPerformanceSection } from '../components/traders/sections/ModelPerformanceSection'
 import type { CompetitionData } from '../types'
+import { PAPER_EXCHANGE } from '../types'
 
 interface AITradersPageProps {
   onTraderSelect?: (traderId: string) => void
@@ -90,6 +91,8 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
     handleEditTrader,
     handleSaveEditTrader,
     handleDeleteTrader,
+    handleExportTrader,
+    handleImportTrader,
     handleToggleTrader,
     handleAddModel,
     handleAddExchange,
@@ -135,6 +138,12 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
       }
       return true
     }) || []
+  // 模拟盘无需配置交易所，始终可选
+  const traderExchanges = enabledExchanges.some(
+    (e) => e.id === PAPER_EXCHANGE.id
+  )
+    ? enabledExchanges
+    : [...enabledExchanges, PAPER_EXCHANGE]
 
   // 检查是否需要显示信号源警告
   const showSignalWarning =
@@ -163,6 +172,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
         onAddExchange={handleAddExchange}
         onConfigureSignalSource={() => setShowSignalSourceModal(true)}
         onCreateTrader={() => setShowCreateModal(true)}
+        onImportTrader={handleImportTrader}
       />
 
       {/* Signal Source Warning */}
@@ -204,6 +214,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
         onTraderSelect={handleTraderSelect}
         onEditTrader={handleEditTrader}
         onDeleteTrader={handleDeleteTrader}
+        onExportTrader={handleExportTrader}
         onToggleTrader={handleToggleTrader}
       />
 
@@ -213,7 +224,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
         onClose={() => setShowCreateModal(false)}
         isEditMode={false}
         availableModels={enabledModels}
-        availableExchanges={enabledExchanges}
+        availableExchanges={traderExchanges}
         onSave={handleCreateTrader}
       />
 
@@ -223,7 +234,7 @@ export function AITradersPage({ onTraderSelect }: AITradersPageProps) {
         isEditMode={true}
         traderData={editingTrader}
         availableModels={enabledModels}
-        availableExchanges={enabledExchanges}
+        availableExchanges={traderExchanges}
         onSave={handleSaveEditTrader}
       />
 
@@ -367,6 +367,44 @@ export default function TraderDashboard() {
               <span>Cycles: {status.call_count}</span>
               <span>•</span>
               <span>Runtime: {status.runtime_minutes} min</span>
+              {status.testnet && (
+                <>
+                  <span>•</span>
+                  <span
+                    className="px-1.5 py-0.5 rounded font-semibold"
+                    style={{
+                      background: 'rgba(240, 185, 11, 0.15)',
+                      color: '#F0B90B',
+                    }}
+                  >
+                    {t('testnet', language)}
+                  </span>
+                </>
+              )}
+              {status.circuit_broken && (
+                <>
+                  <span>•</span>
+                  <span
+                    className="px-1.5 py-0.5 rounded font-semibold"
+                    style={{
+                      background: 'rgba(246, 70, 93, 0.15)',
+                      color: '#F6465D',
+                    }}
+                  >
+                    {t('circuitBroken', language)}
+                  </span>
+                </>
+              )}
+              {!status.circuit_broken &&
+                status.daily_loss_budget !== undefined && (
+                  <>
+                    <span>•</span>
+                    <span>
+                      {t('dailyLossBudget', language)}:{' '}
+                      {status.daily_loss_budget.toFixed(2)} USDT
+                    </span>
+                  </>
+                )}
             </>
           )}
         </div>
@@ -884,6 +922,11 @@ function DecisionCard({
                   {action.error}
                 </span>
               )}
+              {action.risk_note && (
+                <span className="text-xs ml-2" style={{ color: '#F0B90B' }}>
+                  🛡️ {action.risk_note}
+                </span>
+              )}
             </div>
           ))}
         </div>
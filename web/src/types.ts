@@ -11,6 +11,27 @@ export interface SystemStatus {
   stop_until: string
   last_reset_time: string
   ai_provider: string
+  testnet?: boolean // 运行在交易所测试网（模拟资金）
+  circuit_broken?: boolean // 当日亏损超限，已熔断停止开新仓
+  max_daily_loss_pct?: number // 最大日亏损百分比（0=关闭）
+  daily_loss_budget?: number // 当日剩余可亏损额度（USDT），仅开启熔断时返回
+  circuit_breaker_resume_at?: string // 熔断自动解除时间
+  protective_orders?: ProtectiveOrders[] // 跟踪中的止盈止损单
+}
+
+// 单个持仓跟踪的止盈止损单
+export interface ProtectiveLeg {
+  price: number
+  order_id?: string // 交易所订单ID
+  software: boolean // 交易所下单失败，由软件监控价格触发
+}
+
+export interface ProtectiveOrders {
+  symbol: string
+  side: 'long' | 'short'
+  quantity: number
+  stop_loss?: ProtectiveLeg
+  take_profit?: ProtectiveLeg
 }
 
 export interface AccountInfo {
@@ -25,6 +46,7 @@ export interface AccountInfo {
   position_count: number
   margin_used: number
   margin_used_pct: number
+  funding_fees?: number // 近7天资金费合计（正数为收到）
 }
 
 export interface Position {
@@ -50,6 +72,11 @@ export interface DecisionAction {
   timestamp: string
   success: boolean
   error?: string
+  risk_note?: string // 风控调整说明（开仓价值超过仓位/敞口上限被缩减）
+  execution_mode?: string // 执行方式：market / limit / limit+market
+  intended_price?: number // 决策时的参考价格
+  fill_price?: number // 实际成交均价
+  slippage_bps?: number // 滑点（基点，正数表示比参考价更差）
 }
 
 export interface AccountSnapshot {
@@ -73,6 +100,20 @@ export interface DecisionRecord {
   execution_log: string[]
   success: boolean
   error_message?: string
+  // 周期耗时分解（旧记录为0）
+  ai_model_used: string
+  ai_latency_ms: number
+  prompt_tokens: number
+  completion_tokens: number
+  exchange_execution_ms: number
+  total_cycle_ms: number
+}
+
+export interface DecisionPage {
+  records: DecisionRecord[]
+  total: number
+  next_cursor: string
+  has_more: boolean
 }
 
 export interface Statistics {
@@ -90,11 +131,20 @@ export interface TraderInfo {
   ai_model: string
   ai_custom_model?: string // 自定义模型名称（完整名称）
   exchange_id?: string
+  exchange_account_id?: string
   is_running?: boolean
   custom_prompt?: string
   use_coin_pool?: boolean
   use_oi_top?: boolean
   system_prompt_template?: string
+  is_public?: boolean
+  last_error?: string // 最近一次异常退出原因（自动重启时记录）
+}
+
+export interface UpdateTraderResponse extends TraderInfo {
+  reloaded: boolean // 是否重新加载了交易员实例
+  applied_live: string[] // 已在运行中实例上立即生效的字段
+  restart_required: string[] // 需要重启交易员才能生效的字段
 }
 
 export interface AIModel {
@@ -105,10 +155,13 @@ export interface AIModel {
   apiKey?: string
   customApiUrl?: string
   customModelName?: string
+  configured?: boolean // 凭证是否已配置（本地模型无需API Key）
 }
 
 export interface Exchange {
   id: string
+  account_id?: string // 交易所账户ID（同一交易所可配置多个账户，默认账户为 'default'）
+  label?: string // 账户备注名
   name: string
   type: 'cex' | 'dex'
   enabled: boolean
@@ -121,13 +174,39 @@ export interface Exchange {
   asterUser?: string
   asterSigner?: string
   asterPrivateKey?: string
+  // API密钥合约交易权限（保存密钥时检测，unknown 表示交易所不提供权限查询）
+  trade_permission?: 'enabled' | 'disabled' | 'unknown'
+}
+
+// 模拟盘交易所：按实时标记价格撮合的虚拟账户，无需配置API密钥
+export const PAPER_EXCHANGE_ID = 'paper'
+export const PAPER_EXCHANGE: Exchange = {
+  id: PAPER_EXCHANGE_ID,
+  name: 'Paper Trading',
+  type: 'cex',
+  enabled: true,
+}
+
+// 判断交易所配置是否为指定账户（账户ID为空时表示默认账户）
+export const DEFAULT_EXCHANGE_ACCOUNT_ID = 'default'
+export function isExchangeAccount(
+  exchange: Exchange,
+  exchangeId: string,
+  accountId?: string
+): boolean {
+  return (
+    exchange.id === exchangeId &&
+    (exchange.account_id || DEFAULT_EXCHANGE_ACCOUNT_ID) ===
+      (accountId || DEFAULT_EXCHANGE_ACCOUNT_ID)
+  )
 }
 
 export interface CreateTraderRequest {
   name: string
   ai_model_id: string
   exchange_id: string
-  initial_balance?: number // 可选：创建时由后端自动获取，编辑时可手动更新
+  exchange_account_id?: string // 交易所账户ID（为空时使用默认账户）
+  initial_balance?: number // 可选：创建时由后端自动获取（模拟盘为虚拟初始资金），编辑时可手动更新
   scan_interval_minutes?: number
   btc_eth_leverage?: number
   altcoin_leverage?: number
@@ -138,6 +217,18 @@ export interface CreateTraderRequest {
   is_cross_margin?: boolean
   use_coin_pool?: boolean
   use_oi_top?: boolean
+  is_public?: boolean // 是否在公开排行榜中展示（默认不公开）
+  max_daily_loss_pct?: number // 最大日亏损百分比，超过后熔断（0=关闭）
+  daily_loss_flatten?: boolean // 熔断时是否平掉所有持仓
+  max_position_value_usdt?: number // 单币种仓位价值上限（USDT，0=不限制）
+  max_total_exposure_pct?: number // 总持仓价值占净值百分比上限（0=不限制）
+  default_stop_loss_pct?: number // 默认止损百分比（AI未给出止损时使用，0=不使用）
+  default_take_profit_pct?: number // 默认止盈百分比（AI未给出止盈时使用，0=不使用）
+  cooldown_minutes_after_loss?: number // 同币种亏损平仓后禁止开仓的分钟数（0=关闭）
+  execution_mode?: 'market' | 'limit' // 开仓执行方式（默认market）
+  limit_offset_bps?: number // 限价偏移（基点，正数向对手价让价）
+  limit_timeout_seconds?: number // 限价单等待成交时间（秒）
+  limit_fallback?: 'market' | 'cancel' // 限价单超时后剩余数量的处理
 }
 
 export interface UpdateModelConfigRequest {
@@ -166,6 +257,29 @@ export interface UpdateExchangeConfigRequest {
       aster_private_key?: string
     }
   }
+  // 按账户更新，account_id 为空时新建账户
+  accounts?: ExchangeAccountUpdate[]
+}
+
+export interface UpdateExchangeConfigResponse {
+  message: string
+  created_accounts?: string[]
+  // 未开启合约交易权限等提示
+  warnings?: string[]
+}
+
+export interface ExchangeAccountUpdate {
+  exchange_id: string
+  account_id?: string
+  label?: string
+  enabled: boolean
+  api_key: string
+  secret_key: string
+  testnet?: boolean
+  hyperliquid_wallet_addr?: string
+  aster_user?: string
+  aster_signer?: string
+  aster_private_key?: string
 }
 
 // Competition related types
@@ -174,6 +288,8 @@ export interface CompetitionTraderData {
   trader_name: string
   ai_model: string
   exchange: string
+  is_paper?: boolean // 模拟盘交易员（虚拟资金）
+  testnet?: boolean // 运行在交易所测试网（模拟资金）
   total_equity: number
   total_pnl: number
   total_pnl_pct: number
@@ -193,6 +309,7 @@ export interface TraderConfigData {
   trader_name: string
   ai_model: string
   exchange_id: string
+  exchange_account_id?: string
   btc_eth_leverage: number
   altcoin_leverage: number
   trading_symbols: string
@@ -205,4 +322,42 @@ export interface TraderConfigData {
   initial_balance: number
   scan_interval_minutes: number
   is_running: boolean
+  is_public?: boolean
+  max_daily_loss_pct?: number
+  daily_loss_flatten?: boolean
+  max_position_value_usdt?: number
+  max_total_exposure_pct?: number
+  default_stop_loss_pct?: number
+  default_take_profit_pct?: number
+  cooldown_minutes_after_loss?: number
+  execution_mode?: 'market' | 'limit'
+  limit_offset_bps?: number
+  limit_timeout_seconds?: number
+  limit_fallback?: 'market' | 'cancel'
+}
+
+// 紧急停止结果
+export interface PositionCloseResult {
+  symbol: string
+  side: string
+  closed: boolean
+  error?: string
+}
+
+export interface EmergencyStopTraderResult {
+  trader_id: string
+  name: string
+  user_id?: string
+  was_running: boolean
+  positions?: PositionCloseResult[]
+  failed_positions: number
+  error?: string
+}
+
+export interface EmergencyStopResult {
+  message: string
+  stopped_traders: number
+  failed_positions: number
+  failed_traders: number
+  traders: EmergencyStopTraderResult[]
 }